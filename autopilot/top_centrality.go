@@ -1,7 +1,9 @@
 package autopilot
 
 import (
+	"bytes"
 	"runtime"
+	"sort"
 
 	"github.com/btcsuite/btcd/btcutil"
 )
@@ -91,3 +93,39 @@ func (g *TopCentrality) NodeScores(graph ChannelGraph, chans []LocalChannel,
 
 	return result, nil
 }
+
+// TopNodes returns the n nodes of the graph with the highest betweenness
+// centrality, ordered by decreasing centrality. Like NodeScores, it
+// recalculates the centrality values of the whole graph on every call.
+func (g *TopCentrality) TopNodes(graph ChannelGraph, n int) ([]NodeID,
+	error) {
+
+	if err := g.centralityMetric.Refresh(graph); err != nil {
+		return nil, err
+	}
+
+	normalize := true
+	centrality := g.centralityMetric.GetMetric(normalize)
+
+	nodes := make([]NodeID, 0, len(centrality))
+	for nodeID := range centrality {
+		nodes = append(nodes, nodeID)
+	}
+
+	// Sort by centrality and break ties by the node ID, so that the
+	// result is deterministic.
+	sort.Slice(nodes, func(i, j int) bool {
+		ci, cj := centrality[nodes[i]], centrality[nodes[j]]
+		if ci != cj {
+			return ci > cj
+		}
+
+		return bytes.Compare(nodes[i][:], nodes[j][:]) < 0
+	})
+
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+
+	return nodes, nil
+}
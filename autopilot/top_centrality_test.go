@@ -104,3 +104,41 @@ func TestTopCentrality(t *testing.T) {
 		require.True(t, success)
 	}
 }
+
+// TestTopCentralityTopNodes tests that the nodes with the highest centrality
+// are returned in order of decreasing centrality.
+func TestTopCentralityTopNodes(t *testing.T) {
+	for _, chanGraph := range chanGraphs {
+		chanGraph := chanGraph
+
+		success := t.Run(chanGraph.name, func(t1 *testing.T) {
+			t1.Parallel()
+
+			graph, err := chanGraph.genFunc(t1)
+			require.NoError(t1, err, "unable to create graph")
+
+			graphNodes := buildTestGraph(
+				t1, graph, centralityTestGraph,
+			)
+
+			topCentrality := NewTopCentrality()
+
+			// Nodes 3 and 6 have the highest centrality in the
+			// test graph.
+			nodes, err := topCentrality.TopNodes(graph, 2)
+			require.NoError(t1, err)
+			require.Equal(t1, []NodeID{
+				NewNodeID(graphNodes[3]),
+				NewNodeID(graphNodes[6]),
+			}, nodes)
+
+			// Asking for more nodes than the graph has returns all
+			// of them.
+			nodes, err = topCentrality.TopNodes(graph, 100)
+			require.NoError(t1, err)
+			require.Len(t1, nodes, centralityTestGraph.nodes)
+		})
+
+		require.True(t, success)
+	}
+}
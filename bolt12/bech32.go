@@ -0,0 +1,98 @@
+package bolt12
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+const (
+	// charset is the set of characters used by the bech32 encoding.
+	charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	// separator separates the human-readable part of an encoded string
+	// from its data.
+	separator = '1'
+)
+
+// ErrInvalidEncoding is returned when a string can't be decoded as a BOLT 12
+// bech32 string.
+var ErrInvalidEncoding = errors.New("invalid bolt12 encoding")
+
+// encodeBech32 encodes the data with the given human-readable part. Unlike
+// BIP 173 strings, BOLT 12 strings don't have a checksum or length limit, as
+// they're expected to be transmitted in QR codes or over onion messages.
+func encodeBech32(hrp string, data []byte) (string, error) {
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.Grow(len(hrp) + 1 + len(converted))
+	b.WriteString(hrp)
+	b.WriteByte(separator)
+	for _, c := range converted {
+		b.WriteByte(charset[c])
+	}
+
+	return b.String(), nil
+}
+
+// decodeBech32 decodes a BOLT 12 bech32 string with the expected
+// human-readable part. The string may be split with a '+' followed by
+// optional whitespace, and may be entirely upper or lower case.
+func decodeBech32(expectedHRP, encoded string) ([]byte, error) {
+	// A '+' may be used to split the string over multiple lines, in which
+	// case it needs to be surrounded by valid bech32 characters.
+	parts := strings.Split(encoded, "+")
+	for i, part := range parts {
+		if i > 0 {
+			part = strings.TrimLeft(part, " \t\r\n")
+		}
+		if len(part) == 0 {
+			return nil, fmt.Errorf("%w: empty string part",
+				ErrInvalidEncoding)
+		}
+		parts[i] = part
+	}
+	encoded = strings.Join(parts, "")
+
+	lower := strings.ToLower(encoded)
+	if encoded != lower && encoded != strings.ToUpper(encoded) {
+		return nil, fmt.Errorf("%w: mixed case", ErrInvalidEncoding)
+	}
+
+	sepIndex := strings.LastIndexByte(lower, separator)
+	if sepIndex < 1 {
+		return nil, fmt.Errorf("%w: missing separator",
+			ErrInvalidEncoding)
+	}
+
+	hrp := lower[:sepIndex]
+	if hrp != expectedHRP {
+		return nil, fmt.Errorf("%w: expected prefix %v, got %v",
+			ErrInvalidEncoding, expectedHRP, hrp)
+	}
+
+	data := make([]byte, 0, len(lower)-sepIndex-1)
+	for _, c := range []byte(lower[sepIndex+1:]) {
+		index := strings.IndexByte(charset, c)
+		if index < 0 {
+			return nil, fmt.Errorf("%w: invalid character %q",
+				ErrInvalidEncoding, c)
+		}
+
+		data = append(data, byte(index))
+	}
+
+	// The conversion also ensures that any padding bits are zero.
+	converted, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+
+	return converted, nil
+}
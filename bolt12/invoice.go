@@ -0,0 +1,520 @@
+package bolt12
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+const (
+	// InvoiceHRP is the human-readable part of an encoded invoice.
+	InvoiceHRP = "lni"
+
+	// DefaultRelativeExpiry is the expiry of an invoice that doesn't
+	// specify a relative expiry.
+	DefaultRelativeExpiry = 7200 * time.Second
+
+	invoicePathsType          tlv.Type = 160
+	invoiceBlindedPayType     tlv.Type = 162
+	invoiceCreatedAtType      tlv.Type = 164
+	invoiceRelativeExpiryType tlv.Type = 166
+	invoicePaymentHashType    tlv.Type = 168
+	invoiceAmountType         tlv.Type = 170
+	invoiceFallbacksType      tlv.Type = 172
+	invoiceFeaturesType       tlv.Type = 174
+	invoiceNodeIDType         tlv.Type = 176
+)
+
+var (
+	// ErrNoPaths is returned when an invoice doesn't contain any blinded
+	// payment paths.
+	ErrNoPaths = errors.New("invoice has no blinded paths")
+
+	// ErrInvoiceMismatch is returned when an invoice doesn't match the
+	// invoice request that it was received for.
+	ErrInvoiceMismatch = errors.New("invoice does not match invoice " +
+		"request")
+)
+
+// Invoice is a BOLT 12 invoice, which is sent in response to an invoice
+// request. It contains all the fields of the invoice request along with the
+// blinded paths that the payment can be made over.
+//
+// NOTE: A decoded invoice retains its original encoding, which is used when
+// it's re-encoded so that the signature remains valid.
+type Invoice struct {
+	// InvoiceRequest is the request that the invoice was created for.
+	InvoiceRequest *InvoiceRequest
+
+	// Paths is the set of blinded paths that the payment can be made
+	// over. The first hop of each path holds the real public key of the
+	// introduction node.
+	Paths []*zpay32.BlindedPaymentPath
+
+	// CreatedAt is the time that the invoice was created at.
+	CreatedAt time.Time
+
+	// RelativeExpiry is the time after CreatedAt that the invoice
+	// expires. If zero, DefaultRelativeExpiry applies.
+	RelativeExpiry time.Duration
+
+	// PaymentHash is the hash of the payment preimage.
+	PaymentHash lntypes.Hash
+
+	// Amount is the amount to be paid.
+	Amount lnwire.MilliSatoshi
+
+	// Features is the set of features that are required to pay the
+	// invoice.
+	Features *lnwire.RawFeatureVector
+
+	// NodeID is the public key that the invoice is signed with.
+	NodeID *btcec.PublicKey
+
+	// Signature is the signature of the invoice by NodeID.
+	Signature *schnorr.Signature
+
+	// fallbacks holds the on-chain fallback addresses, which aren't
+	// supported but are retained as is.
+	fallbacks []byte
+
+	// extraRecords holds any unknown odd records of the invoice.
+	extraRecords records
+
+	// raw holds the records of a decoded invoice, excluding the
+	// signature.
+	raw records
+}
+
+// records returns the TLV records of the invoice, excluding the signature.
+func (i *Invoice) records() (records, error) {
+	if i.raw != nil {
+		return i.raw, nil
+	}
+
+	recs, err := i.InvoiceRequest.records()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(i.Paths) == 0 {
+		return nil, ErrNoPaths
+	}
+
+	// Each path is encoded in two records: the paths themselves and the
+	// payment information that applies to each of them.
+	paths := make([]*record.BlindedPath, 0, len(i.Paths))
+	var payInfo bytes.Buffer
+	for _, path := range i.Paths {
+		if len(path.Hops) == 0 {
+			return nil, record.ErrNoBlindedHops
+		}
+
+		paths = append(paths, &record.BlindedPath{
+			IntroductionNode: path.Hops[0].BlindedNodePub,
+			PathKey:          path.FirstEphemeralBlindingPoint,
+			Hops:             path.Hops,
+		})
+
+		if err := encodePayInfo(&payInfo, path); err != nil {
+			return nil, err
+		}
+	}
+
+	encodedPaths, err := encodeBlindedPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	recs = append(
+		recs,
+		rawRecord{invoicePathsType, encodedPaths},
+		rawRecord{invoiceBlindedPayType, payInfo.Bytes()},
+		rawRecord{
+			invoiceCreatedAtType,
+			encodeTU64(uint64(i.CreatedAt.Unix())),
+		},
+		rawRecord{invoicePaymentHashType, i.PaymentHash[:]},
+		rawRecord{invoiceAmountType, encodeTU64(uint64(i.Amount))},
+	)
+
+	if i.RelativeExpiry != 0 {
+		recs = append(recs, rawRecord{
+			invoiceRelativeExpiryType,
+			encodeTU64(uint64(i.RelativeExpiry.Seconds())),
+		})
+	}
+
+	if len(i.fallbacks) > 0 {
+		recs = append(recs, rawRecord{
+			invoiceFallbacksType, i.fallbacks,
+		})
+	}
+
+	if i.Features != nil && !i.Features.IsEmpty() {
+		recs = append(recs, rawRecord{
+			invoiceFeaturesType, encodeFeatures(i.Features),
+		})
+	}
+
+	if i.NodeID != nil {
+		recs = append(recs, rawRecord{
+			invoiceNodeIDType, i.NodeID.SerializeCompressed(),
+		})
+	}
+
+	recs = append(recs, i.extraRecords...)
+
+	return recs.sorted(), nil
+}
+
+// Sign signs the invoice. The signer must sign with the key that NodeID is
+// set to.
+func (i *Invoice) Sign(signer SignFunc) error {
+	recs, err := i.records()
+	if err != nil {
+		return err
+	}
+
+	root := merkleRoot(recs)
+	i.Signature, err = signer(SignatureTag(invoiceMsgName), root[:])
+
+	return err
+}
+
+// Encode returns the TLV encoding of the signed invoice.
+func (i *Invoice) Encode() ([]byte, error) {
+	if i.Signature == nil {
+		return nil, ErrMissingSignature
+	}
+
+	recs, err := i.records()
+	if err != nil {
+		return nil, err
+	}
+
+	recs = append(recs, encodeSignature(i.Signature))
+
+	return recs.sorted().encode(), nil
+}
+
+// EncodeString returns the bech32 encoding of the signed invoice.
+func (i *Invoice) EncodeString() (string, error) {
+	encoded, err := i.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	return encodeBech32(InvoiceHRP, encoded)
+}
+
+// DecodeInvoice parses the TLV encoding of an invoice.
+func DecodeInvoice(b []byte) (*Invoice, error) {
+	recs, err := decodeRecords(b)
+	if err != nil {
+		return nil, err
+	}
+
+	invreq, err := decodeInvoiceRequestRecords(recs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	i := Invoice{
+		InvoiceRequest: invreq,
+	}
+
+	var (
+		paths   []*record.BlindedPath
+		payInfo []byte
+	)
+	for _, rec := range recs {
+		if rec.typ == signatureType {
+			i.Signature, err = schnorr.ParseSignature(rec.value)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !inRanges(rec.typ, invoiceRanges) {
+			return nil, fmt.Errorf("invoice contains unexpected "+
+				"record type %d", rec.typ)
+		}
+
+		i.raw = append(i.raw, rec)
+
+		if inRanges(rec.typ, invoiceRequestRanges) {
+			continue
+		}
+
+		switch rec.typ {
+		case invoicePathsType:
+			paths, err = decodeBlindedPaths(rec.value)
+
+		case invoiceBlindedPayType:
+			payInfo = rec.value
+
+		case invoiceCreatedAtType:
+			var createdAt uint64
+			createdAt, err = decodeTU64(rec.value)
+			i.CreatedAt = time.Unix(int64(createdAt), 0)
+
+		case invoiceRelativeExpiryType:
+			var expiry uint32
+			expiry, err = decodeTU32(rec.value)
+			i.RelativeExpiry = time.Duration(expiry) * time.Second
+
+		case invoicePaymentHashType:
+			i.PaymentHash, err = lntypes.MakeHash(rec.value)
+
+		case invoiceAmountType:
+			var amt uint64
+			amt, err = decodeTU64(rec.value)
+			i.Amount = lnwire.MilliSatoshi(amt)
+
+		case invoiceFallbacksType:
+			i.fallbacks = rec.value
+
+		case invoiceFeaturesType:
+			i.Features, err = decodeFeatures(rec.value)
+
+		case invoiceNodeIDType:
+			i.NodeID, err = decodePubKey(rec.value)
+
+		default:
+			if rec.typ%2 == 0 {
+				return nil, fmt.Errorf("unknown required "+
+					"invoice record type: %d", rec.typ)
+			}
+
+			i.extraRecords = append(i.extraRecords, rec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice record %d: %w",
+				rec.typ, err)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, ErrNoPaths
+	}
+
+	i.Paths, err = decodePayInfo(payInfo, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.NodeID == nil {
+		return nil, errors.New("invoice missing node id")
+	}
+
+	if i.CreatedAt.IsZero() {
+		return nil, errors.New("invoice missing creation time")
+	}
+
+	if i.Amount == 0 {
+		return nil, errors.New("invoice missing amount")
+	}
+
+	return &i, nil
+}
+
+// DecodeInvoiceString parses the bech32 encoding of an invoice.
+func DecodeInvoiceString(encoded string) (*Invoice, error) {
+	data, err := decodeBech32(InvoiceHRP, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeInvoice(data)
+}
+
+// Verify checks that the invoice is signed by its node ID.
+func (i *Invoice) Verify() error {
+	recs, err := i.records()
+	if err != nil {
+		return err
+	}
+
+	return verifyRecords(invoiceMsgName, recs, i.Signature, i.NodeID)
+}
+
+// ValidateFor checks that the invoice is a valid, signed response to the given
+// invoice request.
+func (i *Invoice) ValidateFor(req *InvoiceRequest) error {
+	reqRecs, err := req.records()
+	if err != nil {
+		return err
+	}
+
+	invoiceRecs, err := i.records()
+	if err != nil {
+		return err
+	}
+
+	// The invoice must mirror all the fields of the request.
+	isRequest := func(typ tlv.Type) bool {
+		return inRanges(typ, invoiceRequestRanges)
+	}
+	if !bytes.Equal(
+		reqRecs.filter(isRequest).encode(),
+		invoiceRecs.filter(isRequest).encode(),
+	) {
+
+		return fmt.Errorf("%w: fields differ", ErrInvoiceMismatch)
+	}
+
+	// If the offer has an issuer ID, then the invoice must be signed by
+	// it. Otherwise, it must be signed by the final blinded node of one
+	// of the offer's paths.
+	offer := req.Offer
+	switch {
+	case offer.IssuerID != nil:
+		if !offer.IssuerID.IsEqual(i.NodeID) {
+			return fmt.Errorf("%w: node id does not match issuer "+
+				"id", ErrInvoiceMismatch)
+		}
+
+	default:
+		var found bool
+		for _, path := range offer.Paths {
+			lastHop := path.Hops[len(path.Hops)-1]
+			if lastHop.BlindedNodePub.IsEqual(i.NodeID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: node id does not match offer "+
+				"paths", ErrInvoiceMismatch)
+		}
+	}
+
+	if i.Amount != req.AmountToPay() {
+		return fmt.Errorf("%w: invoice amount %v does not match "+
+			"requested amount %v", ErrInvoiceMismatch, i.Amount,
+			req.AmountToPay())
+	}
+
+	return i.Verify()
+}
+
+// Expiry returns the time that the invoice expires at.
+func (i *Invoice) Expiry() time.Time {
+	expiry := i.RelativeExpiry
+	if expiry == 0 {
+		expiry = DefaultRelativeExpiry
+	}
+
+	return i.CreatedAt.Add(expiry)
+}
+
+// encodePayInfo writes the payment information of a blinded path.
+//
+// 1) Base fee in msat: uint32 (4 bytes).
+// 2) Proportional fee in PPM: uint32 (4 bytes).
+// 3) CLTV expiry delta: uint16 (2 bytes).
+// 4) HTLC min msat: uint64 (8 bytes).
+// 5) HTLC max msat: uint64 (8 bytes).
+// 6) Feature bit vector length (2 bytes).
+// 7) Feature bit vector (can be zero length).
+func encodePayInfo(w io.Writer, path *zpay32.BlindedPaymentPath) error {
+	var buf [8]byte
+	if err := tlv.EUint32T(w, path.FeeBaseMsat, &buf); err != nil {
+		return err
+	}
+	if err := tlv.EUint32T(w, path.FeeRate, &buf); err != nil {
+		return err
+	}
+	if err := tlv.EUint16T(w, path.CltvExpiryDelta, &buf); err != nil {
+		return err
+	}
+	if err := tlv.EUint64T(w, path.HTLCMinMsat, &buf); err != nil {
+		return err
+	}
+	if err := tlv.EUint64T(w, path.HTLCMaxMsat, &buf); err != nil {
+		return err
+	}
+
+	features := lnwire.EmptyFeatureVector()
+	if path.Features != nil {
+		features = path.Features
+	}
+
+	return features.Encode(w)
+}
+
+// decodePayInfo parses the payment information for each of the blinded paths
+// and combines the two into blinded payment paths.
+func decodePayInfo(payInfo []byte, paths []*record.BlindedPath) (
+	[]*zpay32.BlindedPaymentPath, error) {
+
+	var (
+		r      = bytes.NewReader(payInfo)
+		buf    [8]byte
+		result = make([]*zpay32.BlindedPaymentPath, 0, len(paths))
+	)
+	for _, path := range paths {
+		var payment zpay32.BlindedPaymentPath
+
+		err := tlv.DUint32(r, &payment.FeeBaseMsat, &buf, 4)
+		if err != nil {
+			return nil, err
+		}
+		err = tlv.DUint32(r, &payment.FeeRate, &buf, 4)
+		if err != nil {
+			return nil, err
+		}
+		err = tlv.DUint16(r, &payment.CltvExpiryDelta, &buf, 2)
+		if err != nil {
+			return nil, err
+		}
+		err = tlv.DUint64(r, &payment.HTLCMinMsat, &buf, 8)
+		if err != nil {
+			return nil, err
+		}
+		err = tlv.DUint64(r, &payment.HTLCMaxMsat, &buf, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		payment.Features = lnwire.EmptyFeatureVector()
+		if err := payment.Features.Decode(r); err != nil {
+			return nil, err
+		}
+
+		// The sender addresses the introduction node by its real
+		// public key, which takes the place of its blinded key.
+		payment.FirstEphemeralBlindingPoint = path.PathKey
+		payment.Hops = make(
+			[]*sphinx.BlindedHopInfo, 0, len(path.Hops),
+		)
+		payment.Hops = append(payment.Hops, &sphinx.BlindedHopInfo{
+			BlindedNodePub: path.IntroductionNode,
+			CipherText:     path.Hops[0].CipherText,
+		})
+		payment.Hops = append(payment.Hops, path.Hops[1:]...)
+
+		result = append(result, &payment)
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("blinded pay info does not match " +
+			"number of blinded paths")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package bolt12
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	invoiceErrorFieldType          tlv.Type = 1
+	invoiceErrorSuggestedValueType tlv.Type = 3
+	invoiceErrorMessageType        tlv.Type = 5
+)
+
+// InvoiceError is sent in response to an invoice request or invoice that
+// can't be processed.
+type InvoiceError struct {
+	// ErroneousField is the TLV type of the field that caused the error,
+	// if the error was caused by a specific field.
+	ErroneousField fn.Option[uint64]
+
+	// SuggestedValue is a value for the erroneous field that would be
+	// accepted.
+	SuggestedValue []byte
+
+	// Message is a description of the error.
+	Message string
+}
+
+// Error returns the message of the invoice error.
+func (e *InvoiceError) Error() string {
+	return fmt.Sprintf("invoice error: %v", e.Message)
+}
+
+// Encode returns the TLV encoding of the invoice error.
+func (e *InvoiceError) Encode() []byte {
+	var recs records
+	e.ErroneousField.WhenSome(func(field uint64) {
+		recs = append(recs, rawRecord{
+			invoiceErrorFieldType, encodeTU64(field),
+		})
+	})
+
+	if len(e.SuggestedValue) > 0 {
+		recs = append(recs, rawRecord{
+			invoiceErrorSuggestedValueType, e.SuggestedValue,
+		})
+	}
+
+	recs = append(recs, rawRecord{
+		invoiceErrorMessageType, []byte(e.Message),
+	})
+
+	return recs.encode()
+}
+
+// DecodeInvoiceError parses the TLV encoding of an invoice error.
+func DecodeInvoiceError(b []byte) (*InvoiceError, error) {
+	recs, err := decodeRecords(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var e InvoiceError
+	for _, rec := range recs {
+		switch rec.typ {
+		case invoiceErrorFieldType:
+			var field uint64
+			field, err = decodeTU64(rec.value)
+			e.ErroneousField = fn.Some(field)
+
+		case invoiceErrorSuggestedValueType:
+			e.SuggestedValue = rec.value
+
+		case invoiceErrorMessageType:
+			e.Message, err = decodeUTF8(rec.value)
+
+		default:
+			if rec.typ%2 == 0 {
+				return nil, fmt.Errorf("unknown required "+
+					"invoice error record type: %d",
+					rec.typ)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice error record "+
+				"%d: %w", rec.typ, err)
+		}
+	}
+
+	return &e, nil
+}
@@ -0,0 +1,382 @@
+package bolt12
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	invreqMetadataType  tlv.Type = 0
+	invreqChainType     tlv.Type = 80
+	invreqAmountType    tlv.Type = 82
+	invreqFeaturesType  tlv.Type = 84
+	invreqQuantityType  tlv.Type = 86
+	invreqPayerIDType   tlv.Type = 88
+	invreqPayerNoteType tlv.Type = 89
+)
+
+var (
+	// ErrInvalidQuantity is returned when the quantity of an invoice
+	// request doesn't match what the offer allows.
+	ErrInvalidQuantity = errors.New("invalid quantity")
+
+	// ErrInvalidAmount is returned when the amount of an invoice request
+	// doesn't match the amount of the offer.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrUnsupportedCurrency is returned when an offer's amount is
+	// expressed in a currency other than bitcoin.
+	ErrUnsupportedCurrency = errors.New("offers in a currency other than " +
+		"bitcoin are not supported")
+)
+
+// SignFunc produces a BIP 340 signature over the tagged hash of a message,
+// using the given tag.
+type SignFunc func(tag, msg []byte) (*schnorr.Signature, error)
+
+// KeySigner returns a SignFunc that signs with the given private key.
+func KeySigner(key *btcec.PrivateKey) SignFunc {
+	return func(tag, msg []byte) (*schnorr.Signature, error) {
+		return schnorr.Sign(key, chainhash.TaggedHash(tag, msg)[:])
+	}
+}
+
+// InvoiceRequest is a request for an invoice that is sent to the issuer of an
+// offer. It contains all the fields of the offer along with the details that
+// are specific to this request.
+//
+// NOTE: A decoded invoice request retains its original encoding, which is
+// used when it's re-encoded so that the signature remains valid.
+type InvoiceRequest struct {
+	// Offer is the offer that the invoice is requested for.
+	Offer *Offer
+
+	// Metadata is random data provided by the payer that makes the
+	// request unique.
+	Metadata []byte
+
+	// Chain is the chain that the payment will be made on. If nil, the
+	// payment is made on bitcoin mainnet.
+	Chain *chainhash.Hash
+
+	// Amount is the amount that will be paid. This must be set if the
+	// offer doesn't specify an amount.
+	Amount lnwire.MilliSatoshi
+
+	// Features is the set of features that the payer supports.
+	Features *lnwire.RawFeatureVector
+
+	// Quantity is the number of items that are requested, which must be
+	// set if the offer has a maximum quantity.
+	Quantity fn.Option[uint64]
+
+	// PayerID is a transient public key of the payer, which signs the
+	// request.
+	PayerID *btcec.PublicKey
+
+	// PayerNote is a note from the payer to the issuer.
+	PayerNote string
+
+	// Signature is the payer's signature of the request.
+	Signature *schnorr.Signature
+
+	// extraRecords holds any unknown odd records of the request.
+	extraRecords records
+
+	// raw holds the records of a decoded request, excluding the
+	// signature.
+	raw records
+}
+
+// records returns the TLV records of the invoice request, excluding the
+// signature.
+func (r *InvoiceRequest) records() (records, error) {
+	if r.raw != nil {
+		return r.raw, nil
+	}
+
+	recs, err := r.Offer.records()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.Metadata) > 0 {
+		recs = append(recs, rawRecord{invreqMetadataType, r.Metadata})
+	}
+
+	if r.Chain != nil {
+		recs = append(recs, rawRecord{invreqChainType, r.Chain[:]})
+	}
+
+	if r.Amount != 0 {
+		recs = append(recs, rawRecord{
+			invreqAmountType, encodeTU64(uint64(r.Amount)),
+		})
+	}
+
+	if r.Features != nil && !r.Features.IsEmpty() {
+		recs = append(recs, rawRecord{
+			invreqFeaturesType, encodeFeatures(r.Features),
+		})
+	}
+
+	r.Quantity.WhenSome(func(quantity uint64) {
+		recs = append(recs, rawRecord{
+			invreqQuantityType, encodeTU64(quantity),
+		})
+	})
+
+	if r.PayerID != nil {
+		recs = append(recs, rawRecord{
+			invreqPayerIDType, r.PayerID.SerializeCompressed(),
+		})
+	}
+
+	if r.PayerNote != "" {
+		recs = append(recs, rawRecord{
+			invreqPayerNoteType, []byte(r.PayerNote),
+		})
+	}
+
+	recs = append(recs, r.extraRecords...)
+
+	return recs.sorted(), nil
+}
+
+// Sign signs the invoice request with the payer's key. The PayerID must be
+// set to the public key that the signer signs with.
+func (r *InvoiceRequest) Sign(signer SignFunc) error {
+	recs, err := r.records()
+	if err != nil {
+		return err
+	}
+
+	root := merkleRoot(recs)
+	r.Signature, err = signer(SignatureTag(invoiceRequestMsgName), root[:])
+
+	return err
+}
+
+// Encode returns the TLV encoding of the signed invoice request.
+func (r *InvoiceRequest) Encode() ([]byte, error) {
+	if r.Signature == nil {
+		return nil, ErrMissingSignature
+	}
+
+	recs, err := r.records()
+	if err != nil {
+		return nil, err
+	}
+
+	recs = append(recs, encodeSignature(r.Signature))
+
+	return recs.sorted().encode(), nil
+}
+
+// DecodeInvoiceRequest parses the TLV encoding of an invoice request.
+func DecodeInvoiceRequest(b []byte) (*InvoiceRequest, error) {
+	recs, err := decodeRecords(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeInvoiceRequestRecords(recs, true)
+}
+
+// decodeInvoiceRequestRecords parses the invoice request records out of a TLV
+// stream. If standalone is true, then the stream must only contain the
+// request and its signature, otherwise records outside of the invoice request
+// range are ignored.
+func decodeInvoiceRequestRecords(recs records,
+	standalone bool) (*InvoiceRequest, error) {
+
+	offer, err := decodeOfferRecords(recs)
+	if err != nil {
+		return nil, err
+	}
+
+	r := InvoiceRequest{
+		Offer: offer,
+	}
+	for _, rec := range recs {
+		if standalone && rec.typ == signatureType {
+			r.Signature, err = schnorr.ParseSignature(rec.value)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !inRanges(rec.typ, invoiceRequestRanges) {
+			if standalone {
+				return nil, fmt.Errorf("invoice request "+
+					"contains unexpected record type %d",
+					rec.typ)
+			}
+
+			continue
+		}
+
+		r.raw = append(r.raw, rec)
+
+		if inRanges(rec.typ, offerRanges) {
+			continue
+		}
+
+		switch rec.typ {
+		case invreqMetadataType:
+			r.Metadata = rec.value
+
+		case invreqChainType:
+			r.Chain, err = decodeChainHash(rec.value)
+
+		case invreqAmountType:
+			var amt uint64
+			amt, err = decodeTU64(rec.value)
+			r.Amount = lnwire.MilliSatoshi(amt)
+
+		case invreqFeaturesType:
+			r.Features, err = decodeFeatures(rec.value)
+
+		case invreqQuantityType:
+			var quantity uint64
+			quantity, err = decodeTU64(rec.value)
+			r.Quantity = fn.Some(quantity)
+
+		case invreqPayerIDType:
+			r.PayerID, err = decodePubKey(rec.value)
+
+		case invreqPayerNoteType:
+			r.PayerNote, err = decodeUTF8(rec.value)
+
+		default:
+			if rec.typ%2 == 0 {
+				return nil, fmt.Errorf("unknown required "+
+					"invoice request record type: %d",
+					rec.typ)
+			}
+
+			r.extraRecords = append(r.extraRecords, rec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice request "+
+				"record %d: %w", rec.typ, err)
+		}
+	}
+
+	// Any request needs to at least contain the payer's key, so an empty
+	// set of records can't be valid.
+	if len(r.raw) == 0 {
+		return nil, errors.New("empty invoice request")
+	}
+
+	return &r, nil
+}
+
+// Validate checks that the invoice request is well formed, correctly signed
+// and requests a valid amount and quantity for its offer on the given chain.
+func (r *InvoiceRequest) Validate(params *chaincfg.Params) error {
+	if len(r.Metadata) == 0 {
+		return errors.New("invoice request missing metadata")
+	}
+
+	if r.PayerID == nil {
+		return errors.New("invoice request missing payer id")
+	}
+
+	recs, err := r.records()
+	if err != nil {
+		return err
+	}
+
+	err = verifyRecords(invoiceRequestMsgName, recs, r.Signature, r.PayerID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Offer.Validate(); err != nil {
+		return err
+	}
+
+	chain := chaincfg.MainNetParams.GenesisHash
+	if r.Chain != nil {
+		chain = r.Chain
+	}
+	if *chain != *params.GenesisHash {
+		return fmt.Errorf("invoice request for unsupported chain %v",
+			chain)
+	}
+	if !r.Offer.SupportsChain(params) {
+		return fmt.Errorf("offer does not support chain %v", chain)
+	}
+
+	// The quantity must be set if and only if the offer allows specifying
+	// one, and within the allowed maximum.
+	offerMax, hasMax := r.Offer.QuantityMax.UnwrapOr(0),
+		r.Offer.QuantityMax.IsSome()
+	quantity, hasQuantity := r.Quantity.UnwrapOr(0), r.Quantity.IsSome()
+	switch {
+	case hasMax && !hasQuantity:
+		return fmt.Errorf("%w: quantity is required",
+			ErrInvalidQuantity)
+
+	case !hasMax && hasQuantity:
+		return fmt.Errorf("%w: offer does not allow a quantity",
+			ErrInvalidQuantity)
+
+	case hasQuantity && quantity == 0:
+		return fmt.Errorf("%w: quantity must be positive",
+			ErrInvalidQuantity)
+
+	case hasMax && offerMax != 0 && quantity > offerMax:
+		return fmt.Errorf("%w: quantity %d exceeds maximum of %d",
+			ErrInvalidQuantity, quantity, offerMax)
+	}
+
+	if r.Offer.Currency != "" {
+		return ErrUnsupportedCurrency
+	}
+
+	if r.Offer.Amount != 0 && quantity > math.MaxUint64/r.Offer.Amount {
+		return fmt.Errorf("%w: quantity %d overflows amount",
+			ErrInvalidQuantity, quantity)
+	}
+
+	switch {
+	case r.Offer.Amount == 0 && r.Amount == 0:
+		return fmt.Errorf("%w: amount is required", ErrInvalidAmount)
+
+	case r.Amount != 0 && r.Amount < r.expectedAmount():
+		return fmt.Errorf("%w: amount %v is less than the offer "+
+			"amount of %v", ErrInvalidAmount, r.Amount,
+			r.expectedAmount())
+	}
+
+	return nil
+}
+
+// expectedAmount returns the amount that the offer asks for, given the
+// requested quantity.
+func (r *InvoiceRequest) expectedAmount() lnwire.MilliSatoshi {
+	return lnwire.MilliSatoshi(r.Offer.Amount * r.Quantity.UnwrapOr(1))
+}
+
+// AmountToPay returns the amount that will be paid for the request, which is
+// either the amount set by the payer or the amount asked for by the offer.
+func (r *InvoiceRequest) AmountToPay() lnwire.MilliSatoshi {
+	if r.Amount != 0 {
+		return r.Amount
+	}
+
+	return r.expectedAmount()
+}
@@ -0,0 +1,349 @@
+package bolt12
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestInvoiceRequest creates a signed invoice request for the given offer.
+func newTestInvoiceRequest(t *testing.T, offer *Offer) *InvoiceRequest {
+	t.Helper()
+
+	payerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	req := &InvoiceRequest{
+		Offer:     offer,
+		Metadata:  []byte{4, 5, 6},
+		Chain:     chaincfg.RegressionNetParams.GenesisHash,
+		Features:  lnwire.NewRawFeatureVector(lnwire.MPPOptional),
+		Quantity:  fn.Some(uint64(2)),
+		PayerID:   payerKey.PubKey(),
+		PayerNote: "thanks",
+	}
+	require.NoError(t, req.Sign(KeySigner(payerKey)))
+
+	return req
+}
+
+// newTestPaymentPath creates a blinded payment path with random keys.
+func newTestPaymentPath(t *testing.T) *zpay32.BlindedPaymentPath {
+	t.Helper()
+
+	newKey := func() *btcec.PublicKey {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return key.PubKey()
+	}
+
+	return &zpay32.BlindedPaymentPath{
+		FeeBaseMsat:                 1000,
+		FeeRate:                     10,
+		CltvExpiryDelta:             144,
+		HTLCMinMsat:                 1,
+		HTLCMaxMsat:                 100_000_000,
+		Features:                    lnwire.EmptyFeatureVector(),
+		FirstEphemeralBlindingPoint: newKey(),
+		Hops: []*sphinx.BlindedHopInfo{
+			{BlindedNodePub: newKey(), CipherText: []byte{1, 2}},
+			{BlindedNodePub: newKey(), CipherText: []byte{3}},
+		},
+	}
+}
+
+// TestInvoiceRequest tests encoding and validation of invoice requests.
+func TestInvoiceRequest(t *testing.T) {
+	t.Parallel()
+
+	offer, _ := newTestOffer(t)
+	req := newTestInvoiceRequest(t, offer)
+	params := &chaincfg.RegressionNetParams
+
+	require.NoError(t, req.Validate(params))
+	require.Equal(t, lnwire.MilliSatoshi(20_000), req.AmountToPay())
+
+	encoded, err := req.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodeInvoiceRequest(encoded)
+	require.NoError(t, err)
+	require.NoError(t, decoded.Validate(params))
+	require.Equal(t, req.PayerNote, decoded.PayerNote)
+	require.Equal(t, req.Quantity, decoded.Quantity)
+	require.Equal(t, offer, decoded.Offer)
+
+	// The decoded request must re-encode to exactly the same bytes.
+	reencoded, err := decoded.Encode()
+	require.NoError(t, err)
+	require.Equal(t, encoded, reencoded)
+
+	// A request for another chain is rejected.
+	require.Error(t, decoded.Validate(&chaincfg.MainNetParams))
+
+	// Changing any of the signed fields invalidates the signature.
+	req.PayerNote = "tampered"
+	require.ErrorIs(t, req.Validate(params), ErrInvalidSignature)
+
+	// The quantity must match what the offer allows.
+	tests := []struct {
+		name     string
+		quantity fn.Option[uint64]
+		amount   lnwire.MilliSatoshi
+		err      error
+	}{
+		{
+			name:     "no quantity",
+			quantity: fn.None[uint64](),
+			err:      ErrInvalidQuantity,
+		},
+		{
+			name:     "zero quantity",
+			quantity: fn.Some(uint64(0)),
+			err:      ErrInvalidQuantity,
+		},
+		{
+			name:     "quantity above max",
+			quantity: fn.Some(uint64(6)),
+			err:      ErrInvalidQuantity,
+		},
+		{
+			name:     "amount too low",
+			quantity: fn.Some(uint64(2)),
+			amount:   19_999,
+			err:      ErrInvalidAmount,
+		},
+		{
+			name:     "amount above offer amount",
+			quantity: fn.Some(uint64(2)),
+			amount:   25_000,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			payerKey, err := btcec.NewPrivateKey()
+			require.NoError(t, err)
+
+			req := &InvoiceRequest{
+				Offer:    offer,
+				Metadata: []byte{1},
+				Chain:    params.GenesisHash,
+				Amount:   test.amount,
+				Quantity: test.quantity,
+				PayerID:  payerKey.PubKey(),
+			}
+			require.NoError(t, req.Sign(KeySigner(payerKey)))
+			require.ErrorIs(t, req.Validate(params), test.err)
+		})
+	}
+}
+
+// TestInvoice tests encoding and validation of invoices.
+func TestInvoice(t *testing.T) {
+	t.Parallel()
+
+	offer, issuerKey := newTestOffer(t)
+	req := newTestInvoiceRequest(t, offer)
+
+	invoice := &Invoice{
+		InvoiceRequest: req,
+		Paths: []*zpay32.BlindedPaymentPath{
+			newTestPaymentPath(t), newTestPaymentPath(t),
+		},
+		CreatedAt:      time.Unix(1_700_000_000, 0),
+		RelativeExpiry: time.Hour,
+		PaymentHash:    lntypes.Hash{1, 2, 3},
+		Amount:         req.AmountToPay(),
+		Features:       lnwire.NewRawFeatureVector(lnwire.MPPOptional),
+		NodeID:         issuerKey.PubKey(),
+	}
+	require.NoError(t, invoice.Sign(KeySigner(issuerKey)))
+	require.NoError(t, invoice.ValidateFor(req))
+	require.Equal(t, time.Unix(1_700_003_600, 0), invoice.Expiry())
+
+	encoded, err := invoice.EncodeString()
+	require.NoError(t, err)
+
+	decoded, err := DecodeInvoiceString(encoded)
+	require.NoError(t, err)
+	require.NoError(t, decoded.ValidateFor(req))
+	require.Equal(t, invoice.Paths, decoded.Paths)
+	require.Equal(t, invoice.PaymentHash, decoded.PaymentHash)
+	require.Equal(t, invoice.CreatedAt, decoded.CreatedAt)
+	require.Equal(t, invoice.RelativeExpiry, decoded.RelativeExpiry)
+	require.True(t, invoice.Features.Equals(decoded.Features))
+
+	reencoded, err := decoded.EncodeString()
+	require.NoError(t, err)
+	require.Equal(t, encoded, reencoded)
+
+	// An invoice for a different request isn't accepted.
+	otherReq := newTestInvoiceRequest(t, offer)
+	require.ErrorIs(t, decoded.ValidateFor(otherReq), ErrInvoiceMismatch)
+
+	// Nor is an invoice that is signed by another node.
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	invoice.NodeID = otherKey.PubKey()
+	require.NoError(t, invoice.Sign(KeySigner(otherKey)))
+	require.ErrorIs(t, invoice.ValidateFor(req), ErrInvoiceMismatch)
+
+	// Or one that doesn't have a valid signature.
+	invoice.NodeID = issuerKey.PubKey()
+	require.ErrorIs(t, invoice.ValidateFor(req), ErrInvalidSignature)
+}
+
+// TestMerkleRoot tests that the merkle root commits to every record, and that
+// signature records are excluded.
+func TestMerkleRoot(t *testing.T) {
+	t.Parallel()
+
+	recs := records{
+		{typ: 1, value: []byte{1}},
+		{typ: 2, value: []byte{2}},
+		{typ: 3, value: []byte{3}},
+	}
+	root := merkleRoot(recs)
+	require.NotEqual(t, chainhash.Hash{}, root)
+
+	// Adding a signature doesn't change the root.
+	withSig := append(records{}, recs...)
+	withSig = append(withSig, rawRecord{
+		typ: signatureType, value: []byte{},
+	})
+	require.Equal(t, root, merkleRoot(withSig))
+
+	// Changing or removing any record does.
+	for i := range recs {
+		changed := append(records{}, recs...)
+		changed[i] = rawRecord{typ: changed[i].typ, value: []byte{9}}
+		require.NotEqual(t, root, merkleRoot(changed))
+
+		removed := append(records{}, recs[:i]...)
+		removed = append(removed, recs[i+1:]...)
+		require.NotEqual(t, root, merkleRoot(removed))
+	}
+}
+
+// TestMerkleRootSpecVectors tests the merkle root calculation against the
+// test vectors of the BOLT 12 specification.
+func TestMerkleRootSpecVectors(t *testing.T) {
+	t.Parallel()
+
+	const (
+		tlv1 = "010203e8"
+		tlv2 = "02080000010000020003"
+		tlv3 = "03310266e4598d1d3c415f572a8488830b60f7e744ed9235eb0b1" +
+			"ba93283b315c0351800000000000000010000000000000002"
+	)
+
+	testCases := []struct {
+		name string
+		tlvs string
+		root string
+	}{{
+		name: "one record",
+		tlvs: tlv1,
+		root: "b013756c8fee86503a0b4abdab4cddeb1af5d344ca6fc2fa8b6c" +
+			"08938caa6f93",
+	}, {
+		name: "two records",
+		tlvs: tlv1 + tlv2,
+		root: "c3774abbf4815aa54ccaa026bff6581f01f3be5fe814c620a252" +
+			"534f434bc0d1",
+	}, {
+		name: "three records",
+		tlvs: tlv1 + tlv2 + tlv3,
+		root: "ab2e79b1283b0b31e0b035258de23782df6b89a38cfa7237bde6" +
+			"9aed1a658c5d",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := hex.DecodeString(tc.tlvs)
+			require.NoError(t, err)
+
+			recs, err := decodeRecords(b)
+			require.NoError(t, err)
+
+			root := merkleRoot(recs)
+			require.Equal(t, tc.root, hex.EncodeToString(root[:]))
+		})
+	}
+}
+
+// TestSignatureSpecVector tests the merkle root and signature verification of
+// the signed invoice request of the BOLT 12 specification test vectors.
+func TestSignatureSpecVector(t *testing.T) {
+	t.Parallel()
+
+	const (
+		invReq = "lnr1qqyqqqqqqqqqqqqqqcp4256ypqqkgzshgysy6ct5dpjk6ct" +
+			"5d93kzmpq23ex2ct5d9ek293pqthvwfzadd7jejes8q9lhc4rvjx" +
+			"d022zv5l44g6qah82ru5rdpnpjkppqvjx204vgdzgsqpvcp4mldl" +
+			"3plscny0rt707gvpdh6ndydfacz43euzqhrurageg3n7kafgsek6" +
+			"gz3e9w52parv8gs2hlxzk95tzeswywffxlkeyhml0hh46kndmwf4" +
+			"m6xma3tkq2lu04qz3slje2rfthc89vss"
+
+		root = "608407c18ad9a94d9ea2bcdbe170b6c20c462a7833a197621c91" +
+			"6f78cf18e624"
+
+		sig = "b8f83ea3288cfd6ea510cdb481472575141e8d8744157f98562d1" +
+			"62cc1c472526fdb24befefbdebab4dbb726bbd1b7d8aec057f8" +
+			"fa805187e5950d2bbe0e5642"
+	)
+
+	b, err := decodeBech32("lnr", invReq)
+	require.NoError(t, err)
+
+	req, err := DecodeInvoiceRequest(b)
+	require.NoError(t, err)
+	require.Equal(t, sig, hex.EncodeToString(req.Signature.Serialize()))
+
+	recs, err := req.records()
+	require.NoError(t, err)
+
+	merkle := merkleRoot(recs)
+	require.Equal(t, root, hex.EncodeToString(merkle[:]))
+
+	err = verifyRecords(
+		invoiceRequestMsgName, recs, req.Signature, req.PayerID,
+	)
+	require.NoError(t, err)
+
+	// The signature also covers the request when it is re-encoded from
+	// its decoded fields, while changing any of them invalidates it.
+	req.raw = nil
+	recs, err = req.records()
+	require.NoError(t, err)
+
+	err = verifyRecords(
+		invoiceRequestMsgName, recs, req.Signature, req.PayerID,
+	)
+	require.NoError(t, err)
+
+	req.PayerNote = "forged"
+	recs, err = req.records()
+	require.NoError(t, err)
+
+	err = verifyRecords(
+		invoiceRequestMsgName, recs, req.Signature, req.PayerID,
+	)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,129 @@
+package bolt12
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// signatureType is the TLV type of the signature of invoice requests
+	// and invoices.
+	signatureType tlv.Type = 240
+
+	// invoiceRequestMsgName is the message name that is used in the
+	// signature tag of invoice requests.
+	invoiceRequestMsgName = "invoice_request"
+
+	// invoiceMsgName is the message name that is used in the signature
+	// tag of invoices.
+	invoiceMsgName = "invoice"
+)
+
+var (
+	// ErrMissingSignature is returned when a message that must be signed
+	// doesn't contain a signature.
+	ErrMissingSignature = errors.New("message is not signed")
+
+	// ErrInvalidSignature is returned when the signature of a message
+	// doesn't match its contents.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+var (
+	leafTag   = []byte("LnLeaf")
+	nonceTag  = []byte("LnNonce")
+	branchTag = []byte("LnBranch")
+)
+
+// SignatureTag returns the tag that is used to produce the signature of a
+// message with the given name, as defined in BOLT 12. The digest that is
+// signed is the tagged hash of the merkle root of the message.
+func SignatureTag(msgName string) []byte {
+	return []byte("lightning" + msgName + "signature")
+}
+
+// merkleRoot computes the merkle root of a TLV stream. Signature records are
+// excluded from the tree.
+//
+// Every record contributes two leaves to the tree: the tagged hash of the
+// record itself, and a tagged hash of its type that is keyed by the first
+// record of the stream, which hides the content of records that are omitted
+// when proving the inclusion of others.
+func merkleRoot(recs records) chainhash.Hash {
+	recs = recs.filter(func(typ tlv.Type) bool {
+		return !signatureRange.contains(typ)
+	})
+	if len(recs) == 0 {
+		return chainhash.Hash{}
+	}
+
+	firstRecord := recs[0].encode()
+	nonceKey := make([]byte, 0, len(nonceTag)+len(firstRecord))
+	nonceKey = append(nonceKey, nonceTag...)
+	nonceKey = append(nonceKey, firstRecord...)
+
+	leaves := make([]chainhash.Hash, 0, len(recs)*2)
+	for i := range recs {
+		leaves = append(
+			leaves,
+			*chainhash.TaggedHash(leafTag, recs[i].encode()),
+			*chainhash.TaggedHash(nonceKey, recs[i].encodeType()),
+		)
+	}
+
+	// The tree is built bottom up by combining neighbouring nodes. If a
+	// level has an odd number of nodes, then the last one is carried up,
+	// so the tree is deepest at its lowest order leaves.
+	for step := 1; step < len(leaves); step *= 2 {
+		for i := 0; i+step < len(leaves); i += step * 2 {
+			leaves[i] = branchHash(leaves[i], leaves[i+step])
+		}
+	}
+
+	return leaves[0]
+}
+
+// branchHash combines two nodes of the merkle tree, with the lesser of the two
+// hashes first.
+func branchHash(a, b chainhash.Hash) chainhash.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+
+	return *chainhash.TaggedHash(branchTag, a[:], b[:])
+}
+
+// signatureDigest returns the digest that is signed for a message with the
+// given name and merkle root.
+func signatureDigest(msgName string, root chainhash.Hash) []byte {
+	return chainhash.TaggedHash(SignatureTag(msgName), root[:])[:]
+}
+
+// signRecords signs the TLV stream of a message with the given private key.
+func signRecords(msgName string, recs records,
+	key *btcec.PrivateKey) (*schnorr.Signature, error) {
+
+	return schnorr.Sign(key, signatureDigest(msgName, merkleRoot(recs)))
+}
+
+// verifyRecords verifies that the signature of a message was produced by the
+// given public key.
+func verifyRecords(msgName string, recs records, sig *schnorr.Signature,
+	pubKey *btcec.PublicKey) error {
+
+	if sig == nil {
+		return ErrMissingSignature
+	}
+
+	digest := signatureDigest(msgName, merkleRoot(recs))
+	if !sig.Verify(digest, pubKey) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
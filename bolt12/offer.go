@@ -0,0 +1,422 @@
+package bolt12
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// OfferHRP is the human-readable part of an encoded offer.
+	OfferHRP = "lno"
+
+	// metadataNonceSize is the size of the random nonce that is included
+	// in the metadata of offers that are authenticated by their issuer.
+	metadataNonceSize = 16
+
+	offerChainsType         tlv.Type = 2
+	offerMetadataType       tlv.Type = 4
+	offerCurrencyType       tlv.Type = 6
+	offerAmountType         tlv.Type = 8
+	offerDescriptionType    tlv.Type = 10
+	offerFeaturesType       tlv.Type = 12
+	offerAbsoluteExpiryType tlv.Type = 14
+	offerPathsType          tlv.Type = 16
+	offerIssuerType         tlv.Type = 18
+	offerQuantityMaxType    tlv.Type = 20
+	offerIssuerIDType       tlv.Type = 22
+)
+
+var (
+	// ErrNoDescription is returned when an offer has an amount but no
+	// description.
+	ErrNoDescription = errors.New("offer with an amount requires a " +
+		"description")
+
+	// ErrNoIssuer is returned when an offer has neither an issuer ID nor
+	// any blinded paths, meaning that there is no way to reach it.
+	ErrNoIssuer = errors.New("offer requires an issuer id or blinded " +
+		"paths")
+
+	// ErrCurrencyWithoutAmount is returned when an offer specifies a
+	// currency but no amount.
+	ErrCurrencyWithoutAmount = errors.New("offer with a currency " +
+		"requires an amount")
+)
+
+// Offer is a BOLT 12 offer, which describes what a node is willing to be paid
+// for and how to request an invoice for it. Unlike BOLT 11 invoices, offers
+// don't commit to a payment hash and can be paid any number of times.
+type Offer struct {
+	// Chains is the set of chains that the offer is valid for. If empty,
+	// the offer is only valid for bitcoin mainnet.
+	Chains []chainhash.Hash
+
+	// Metadata is arbitrary data that the issuer included in the offer
+	// for its own use. It is echoed back in invoice requests.
+	Metadata []byte
+
+	// Currency is the ISO 4217 code of the currency that the amount is
+	// expressed in. If empty, the amount is expressed in millisatoshis.
+	Currency string
+
+	// Amount is the expected amount of a single item, expressed either in
+	// millisatoshis or the minor unit of the currency. Zero means that
+	// any amount can be paid.
+	Amount uint64
+
+	// Description is a description of the purpose of the payment.
+	Description string
+
+	// Features is the set of features that are required to pay the offer.
+	Features *lnwire.RawFeatureVector
+
+	// AbsoluteExpiry is the time after which the offer can no longer be
+	// paid. The zero value means that the offer doesn't expire.
+	AbsoluteExpiry time.Time
+
+	// Paths is a set of blinded paths that lead to the issuer. If set,
+	// invoice requests are sent over one of these paths.
+	Paths []*record.BlindedPath
+
+	// Issuer is a description of the issuer of the offer.
+	Issuer string
+
+	// QuantityMax is the maximum quantity of items that may be requested
+	// at once. If set to zero, any quantity may be requested. If not set,
+	// only a single item may be requested.
+	QuantityMax fn.Option[uint64]
+
+	// IssuerID is the public key of the issuer, which signs invoices for
+	// the offer.
+	IssuerID *btcec.PublicKey
+
+	// extraRecords holds any unknown odd records of the offer, which need
+	// to be echoed back unmodified in invoice requests.
+	extraRecords records
+}
+
+// records returns the TLV records of the offer.
+func (o *Offer) records() (records, error) {
+	recs := make(records, 0, 11+len(o.extraRecords))
+
+	if len(o.Chains) > 0 {
+		chains := make([]byte, 0, len(o.Chains)*chainhash.HashSize)
+		for _, chain := range o.Chains {
+			chains = append(chains, chain[:]...)
+		}
+
+		recs = append(recs, rawRecord{offerChainsType, chains})
+	}
+
+	if len(o.Metadata) > 0 {
+		recs = append(recs, rawRecord{offerMetadataType, o.Metadata})
+	}
+
+	if o.Currency != "" {
+		recs = append(recs, rawRecord{
+			offerCurrencyType, []byte(o.Currency),
+		})
+	}
+
+	if o.Amount != 0 {
+		recs = append(recs, rawRecord{
+			offerAmountType, encodeTU64(o.Amount),
+		})
+	}
+
+	if o.Description != "" {
+		recs = append(recs, rawRecord{
+			offerDescriptionType, []byte(o.Description),
+		})
+	}
+
+	if o.Features != nil && !o.Features.IsEmpty() {
+		recs = append(recs, rawRecord{
+			offerFeaturesType, encodeFeatures(o.Features),
+		})
+	}
+
+	if !o.AbsoluteExpiry.IsZero() {
+		recs = append(recs, rawRecord{
+			offerAbsoluteExpiryType,
+			encodeTU64(uint64(o.AbsoluteExpiry.Unix())),
+		})
+	}
+
+	if len(o.Paths) > 0 {
+		paths, err := encodeBlindedPaths(o.Paths)
+		if err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, rawRecord{offerPathsType, paths})
+	}
+
+	if o.Issuer != "" {
+		recs = append(recs, rawRecord{
+			offerIssuerType, []byte(o.Issuer),
+		})
+	}
+
+	o.QuantityMax.WhenSome(func(max uint64) {
+		recs = append(recs, rawRecord{
+			offerQuantityMaxType, encodeTU64(max),
+		})
+	})
+
+	if o.IssuerID != nil {
+		recs = append(recs, rawRecord{
+			offerIssuerIDType, o.IssuerID.SerializeCompressed(),
+		})
+	}
+
+	recs = append(recs, o.extraRecords...)
+
+	return recs.sorted(), nil
+}
+
+// Validate checks that the offer is well formed.
+func (o *Offer) Validate() error {
+	if o.Amount != 0 && o.Description == "" {
+		return ErrNoDescription
+	}
+
+	if o.Currency != "" && o.Amount == 0 {
+		return ErrCurrencyWithoutAmount
+	}
+
+	if o.IssuerID == nil && len(o.Paths) == 0 {
+		return ErrNoIssuer
+	}
+
+	return nil
+}
+
+// SupportsChain returns true if the offer can be paid on the given chain.
+func (o *Offer) SupportsChain(params *chaincfg.Params) bool {
+	// Offers without chains are implicitly for bitcoin mainnet.
+	if len(o.Chains) == 0 {
+		mainnet := chaincfg.MainNetParams.GenesisHash
+		return *params.GenesisHash == *mainnet
+	}
+
+	for _, chain := range o.Chains {
+		if chain == *params.GenesisHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expired returns true if the offer's absolute expiry is before the given
+// time.
+func (o *Offer) Expired(now time.Time) bool {
+	return !o.AbsoluteExpiry.IsZero() && now.After(o.AbsoluteExpiry)
+}
+
+// Encode returns the bech32 encoding of the offer.
+func (o *Offer) Encode() (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+
+	recs, err := o.records()
+	if err != nil {
+		return "", err
+	}
+
+	return encodeBech32(OfferHRP, recs.encode())
+}
+
+// DecodeOffer parses and validates a bech32 encoded offer.
+func DecodeOffer(encoded string) (*Offer, error) {
+	data, err := decodeBech32(OfferHRP, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	recs, err := decodeRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range recs {
+		if !inRanges(rec.typ, offerRanges) {
+			return nil, fmt.Errorf("offer contains record of type "+
+				"%d which is outside the offer range", rec.typ)
+		}
+	}
+
+	offer, err := decodeOfferRecords(recs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := offer.Validate(); err != nil {
+		return nil, err
+	}
+
+	return offer, nil
+}
+
+// decodeOfferRecords parses the offer records out of a TLV stream, ignoring
+// any records that fall outside of the offer ranges.
+func decodeOfferRecords(recs records) (*Offer, error) {
+	var (
+		o   Offer
+		err error
+	)
+	for _, rec := range recs {
+		if !inRanges(rec.typ, offerRanges) {
+			continue
+		}
+
+		switch rec.typ {
+		case offerChainsType:
+			if len(rec.value) == 0 ||
+				len(rec.value)%chainhash.HashSize != 0 {
+
+				return nil, fmt.Errorf("invalid offer chains "+
+					"length: %d", len(rec.value))
+			}
+
+			const size = chainhash.HashSize
+			for i := 0; i < len(rec.value); i += size {
+				chain, err := decodeChainHash(
+					rec.value[i : i+size],
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				o.Chains = append(o.Chains, *chain)
+			}
+
+		case offerMetadataType:
+			o.Metadata = rec.value
+
+		case offerCurrencyType:
+			o.Currency, err = decodeUTF8(rec.value)
+
+		case offerAmountType:
+			o.Amount, err = decodeTU64(rec.value)
+
+		case offerDescriptionType:
+			o.Description, err = decodeUTF8(rec.value)
+
+		case offerFeaturesType:
+			o.Features, err = decodeFeatures(rec.value)
+
+		case offerAbsoluteExpiryType:
+			var expiry uint64
+			expiry, err = decodeTU64(rec.value)
+			o.AbsoluteExpiry = time.Unix(int64(expiry), 0)
+
+		case offerPathsType:
+			o.Paths, err = decodeBlindedPaths(rec.value)
+
+		case offerIssuerType:
+			o.Issuer, err = decodeUTF8(rec.value)
+
+		case offerQuantityMaxType:
+			var max uint64
+			max, err = decodeTU64(rec.value)
+			o.QuantityMax = fn.Some(max)
+
+		case offerIssuerIDType:
+			o.IssuerID, err = decodePubKey(rec.value)
+
+		default:
+			if rec.typ%2 == 0 {
+				return nil, fmt.Errorf("unknown required "+
+					"offer record type: %d", rec.typ)
+			}
+
+			o.extraRecords = append(o.extraRecords, rec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid offer record %d: %w",
+				rec.typ, err)
+		}
+	}
+
+	return &o, nil
+}
+
+// SetAuthMetadata sets the metadata of the offer to a random nonce followed by
+// an HMAC of the nonce and the offer's other fields. This allows the issuer to
+// recognize invoice requests for its own offers without storing them, as the
+// requests echo the offer.
+func (o *Offer) SetAuthMetadata(key [32]byte) error {
+	nonce := make([]byte, metadataNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	mac, err := o.metadataMAC(key, nonce)
+	if err != nil {
+		return err
+	}
+
+	o.Metadata = append(nonce, mac...)
+
+	return nil
+}
+
+// VerifyAuthMetadata returns true if the metadata of the offer was set by
+// SetAuthMetadata with the same key, and none of its fields were changed
+// since.
+func (o *Offer) VerifyAuthMetadata(key [32]byte) bool {
+	if len(o.Metadata) != metadataNonceSize+sha256.Size {
+		return false
+	}
+
+	nonce := o.Metadata[:metadataNonceSize]
+	mac, err := o.metadataMAC(key, nonce)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(mac, o.Metadata[metadataNonceSize:])
+}
+
+// metadataMAC computes the HMAC of the nonce and the fields of the offer,
+// excluding its metadata.
+func (o *Offer) metadataMAC(key [32]byte, nonce []byte) ([]byte, error) {
+	withoutMetadata := *o
+	withoutMetadata.Metadata = nil
+
+	recs, err := withoutMetadata.records()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(nonce)
+	mac.Write(recs.encode())
+
+	return mac.Sum(nil), nil
+}
+
+// decodeUTF8 parses a UTF-8 string.
+func decodeUTF8(value []byte) (string, error) {
+	if !utf8.Valid(value) {
+		return "", errors.New("invalid utf-8 string")
+	}
+
+	return string(value), nil
+}
@@ -0,0 +1,186 @@
+package bolt12
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOffer creates an offer with most fields populated.
+func newTestOffer(t *testing.T) (*Offer, *btcec.PrivateKey) {
+	t.Helper()
+
+	issuerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	return &Offer{
+		Chains: []chainhash.Hash{
+			*chaincfg.RegressionNetParams.GenesisHash,
+		},
+		Metadata:       []byte{1, 2, 3},
+		Amount:         10_000,
+		Description:    "coffee",
+		AbsoluteExpiry: time.Unix(2_000_000_000, 0),
+		Issuer:         "lnd",
+		QuantityMax:    fn.Some(uint64(5)),
+		IssuerID:       issuerKey.PubKey(),
+		extraRecords: records{
+			{typ: 1_000_000_001, value: []byte("unknown")},
+		},
+	}, issuerKey
+}
+
+// TestOfferEncoding tests that offers survive an encoding round trip and that
+// invalid offers are rejected.
+func TestOfferEncoding(t *testing.T) {
+	t.Parallel()
+
+	offer, _ := newTestOffer(t)
+
+	encoded, err := offer.Encode()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(encoded, OfferHRP+"1"))
+
+	decoded, err := DecodeOffer(encoded)
+	require.NoError(t, err)
+	require.Equal(t, offer, decoded)
+
+	require.True(t, decoded.SupportsChain(&chaincfg.RegressionNetParams))
+	require.False(t, decoded.SupportsChain(&chaincfg.MainNetParams))
+	require.False(t, decoded.Expired(time.Unix(1_000_000_000, 0)))
+	require.True(t, decoded.Expired(time.Unix(3_000_000_000, 0)))
+
+	// The encoding may be split over multiple lines and in upper case.
+	split := encoded[:20] + "+\n  " + encoded[20:]
+	decoded, err = DecodeOffer(strings.ToUpper(split))
+	require.NoError(t, err)
+	require.Equal(t, offer, decoded)
+
+	_, err = DecodeOffer(encoded[:20] + "+")
+	require.ErrorIs(t, err, ErrInvalidEncoding)
+
+	_, err = DecodeOffer("lnr1" + encoded[4:])
+	require.ErrorIs(t, err, ErrInvalidEncoding)
+
+	// An offer needs to be reachable, and offers with an amount need a
+	// description.
+	_, err = (&Offer{Description: "test"}).Encode()
+	require.ErrorIs(t, err, ErrNoIssuer)
+
+	_, err = (&Offer{IssuerID: offer.IssuerID, Amount: 1}).Encode()
+	require.ErrorIs(t, err, ErrNoDescription)
+
+	// Offers without chains are only valid on mainnet.
+	mainnetOffer := &Offer{IssuerID: offer.IssuerID}
+	require.True(t, mainnetOffer.SupportsChain(&chaincfg.MainNetParams))
+	require.False(t, mainnetOffer.SupportsChain(
+		&chaincfg.RegressionNetParams,
+	))
+}
+
+// TestOfferUnknownRecords tests that unknown records are only accepted if
+// they're odd and within the offer range.
+func TestOfferUnknownRecords(t *testing.T) {
+	t.Parallel()
+
+	offer, _ := newTestOffer(t)
+	recs, err := offer.records()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		extra rawRecord
+		valid bool
+	}{
+		{
+			name:  "unknown odd",
+			extra: rawRecord{typ: 71, value: []byte{1}},
+			valid: true,
+		},
+		{
+			name:  "unknown even",
+			extra: rawRecord{typ: 70, value: []byte{1}},
+		},
+		{
+			name:  "outside offer range",
+			extra: rawRecord{typ: 81, value: []byte{1}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			withExtra := append(records{test.extra}, recs...)
+			encoded, err := encodeBech32(
+				OfferHRP, withExtra.sorted().encode(),
+			)
+			require.NoError(t, err)
+
+			_, err = DecodeOffer(encoded)
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	// Records must be in ascending order.
+	unsorted := append(records{}, recs[1], recs[0]).encode()
+	encoded, err := encodeBech32(OfferHRP, unsorted)
+	require.NoError(t, err)
+	_, err = DecodeOffer(encoded)
+	require.Error(t, err)
+}
+
+// TestInvoiceError tests encoding and decoding of invoice errors.
+func TestInvoiceError(t *testing.T) {
+	t.Parallel()
+
+	invoiceErr := &InvoiceError{
+		ErroneousField: fn.Some(uint64(invreqAmountType)),
+		SuggestedValue: encodeTU64(1000),
+		Message:        "amount too low",
+	}
+
+	decoded, err := DecodeInvoiceError(invoiceErr.Encode())
+	require.NoError(t, err)
+	require.Equal(t, invoiceErr, decoded)
+}
+
+// TestOfferAuthMetadata tests that offers with authenticated metadata can be
+// recognized, and that any change to the offer is detected.
+func TestOfferAuthMetadata(t *testing.T) {
+	t.Parallel()
+
+	var key, otherKey [32]byte
+	key[0], otherKey[0] = 1, 2
+
+	offer, _ := newTestOffer(t)
+	require.NoError(t, offer.SetAuthMetadata(key))
+	require.True(t, offer.VerifyAuthMetadata(key))
+	require.False(t, offer.VerifyAuthMetadata(otherKey))
+
+	// The offer is still recognized after an encoding round trip.
+	encoded, err := offer.Encode()
+	require.NoError(t, err)
+	decoded, err := DecodeOffer(encoded)
+	require.NoError(t, err)
+	require.True(t, decoded.VerifyAuthMetadata(key))
+
+	// Changing any of the fields invalidates the metadata.
+	decoded.Amount++
+	require.False(t, decoded.VerifyAuthMetadata(key))
+
+	decoded.Amount--
+	decoded.Metadata = decoded.Metadata[1:]
+	require.False(t, decoded.VerifyAuthMetadata(key))
+}
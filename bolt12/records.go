@@ -0,0 +1,273 @@
+package bolt12
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// typeRange is an inclusive range of TLV types.
+type typeRange struct {
+	min, max tlv.Type
+}
+
+// contains returns true if the type falls within the range.
+func (r typeRange) contains(typ tlv.Type) bool {
+	return typ >= r.min && typ <= r.max
+}
+
+// inRanges returns true if the type falls within any of the ranges.
+func inRanges(typ tlv.Type, ranges []typeRange) bool {
+	for _, r := range ranges {
+		if r.contains(typ) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	// offerRanges are the TLV types that may be used by offers.
+	offerRanges = []typeRange{
+		{1, 79},
+		{1000000000, 1999999999},
+	}
+
+	// invoiceRequestRanges are the TLV types that may be used by invoice
+	// requests, which include the fields of the offer.
+	invoiceRequestRanges = []typeRange{
+		{0, 159},
+		{1000000000, 2999999999},
+	}
+
+	// invoiceRanges are the TLV types that may be used by invoices, which
+	// include the fields of the invoice request.
+	invoiceRanges = []typeRange{
+		{0, 239},
+		{1000000000, 3999999999},
+	}
+
+	// signatureRange are the TLV types that hold signatures. These are
+	// excluded when computing the merkle root of a message.
+	signatureRange = typeRange{240, 1000}
+)
+
+// rawRecord is a single record of a TLV stream, kept in its serialized form so
+// that unknown records can be retained and signatures can be computed over
+// the exact bytes that were received.
+type rawRecord struct {
+	typ   tlv.Type
+	value []byte
+}
+
+// encode returns the serialized type, length and value of the record.
+func (r *rawRecord) encode() []byte {
+	var (
+		b   bytes.Buffer
+		buf [8]byte
+	)
+
+	// Writes to a bytes.Buffer can't fail.
+	_ = tlv.WriteVarInt(&b, uint64(r.typ), &buf)
+	_ = tlv.WriteVarInt(&b, uint64(len(r.value)), &buf)
+	b.Write(r.value)
+
+	return b.Bytes()
+}
+
+// encodeType returns the serialized type of the record.
+func (r *rawRecord) encodeType() []byte {
+	var (
+		b   bytes.Buffer
+		buf [8]byte
+	)
+	_ = tlv.WriteVarInt(&b, uint64(r.typ), &buf)
+
+	return b.Bytes()
+}
+
+// records is a TLV stream, sorted by type.
+type records []rawRecord
+
+// decodeRecords parses a TLV stream, requiring that the types are strictly
+// increasing.
+func decodeRecords(b []byte) (records, error) {
+	var (
+		r       = bytes.NewReader(b)
+		buf     [8]byte
+		recs    records
+		lastTyp tlv.Type
+	)
+	for r.Len() > 0 {
+		typ, err := tlv.ReadVarInt(r, &buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(recs) > 0 && tlv.Type(typ) <= lastTyp {
+			return nil, tlv.ErrStreamNotCanonical
+		}
+		lastTyp = tlv.Type(typ)
+
+		length, err := tlv.ReadVarInt(r, &buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if length > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, rawRecord{
+			typ:   tlv.Type(typ),
+			value: value,
+		})
+	}
+
+	return recs, nil
+}
+
+// encode serializes the stream.
+func (r records) encode() []byte {
+	var b bytes.Buffer
+	for i := range r {
+		b.Write(r[i].encode())
+	}
+
+	return b.Bytes()
+}
+
+// sorted returns a copy of the records, sorted by type.
+func (r records) sorted() records {
+	sortedRecs := make(records, len(r))
+	copy(sortedRecs, r)
+	sort.Slice(sortedRecs, func(i, j int) bool {
+		return sortedRecs[i].typ < sortedRecs[j].typ
+	})
+
+	return sortedRecs
+}
+
+// filter returns the records for which the predicate is true.
+func (r records) filter(keep func(tlv.Type) bool) records {
+	var filtered records
+	for _, rec := range r {
+		if keep(rec.typ) {
+			filtered = append(filtered, rec)
+		}
+	}
+
+	return filtered
+}
+
+// encodeTU64 returns the truncated encoding of an integer.
+func encodeTU64(v uint64) []byte {
+	var b bytes.Buffer
+	_ = tlv.ETUint64T(&b, v, &[8]byte{})
+
+	return b.Bytes()
+}
+
+// decodeTU64 parses a truncated integer.
+func decodeTU64(value []byte) (uint64, error) {
+	var v uint64
+	err := tlv.DTUint64(
+		bytes.NewReader(value), &v, &[8]byte{}, uint64(len(value)),
+	)
+
+	return v, err
+}
+
+// decodeTU32 parses a truncated 32 bit integer.
+func decodeTU32(value []byte) (uint32, error) {
+	var v uint32
+	err := tlv.DTUint32(
+		bytes.NewReader(value), &v, &[8]byte{}, uint64(len(value)),
+	)
+
+	return v, err
+}
+
+// encodeFeatures returns the encoding of a feature vector, without its length.
+func encodeFeatures(features *lnwire.RawFeatureVector) []byte {
+	var b bytes.Buffer
+	_ = features.EncodeBase256(&b)
+
+	return b.Bytes()
+}
+
+// decodeFeatures parses a feature vector that is encoded without its length.
+func decodeFeatures(value []byte) (*lnwire.RawFeatureVector, error) {
+	features := lnwire.NewRawFeatureVector()
+	err := features.DecodeBase256(bytes.NewReader(value), len(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+// decodePubKey parses a compressed public key.
+func decodePubKey(value []byte) (*btcec.PublicKey, error) {
+	return btcec.ParsePubKey(value)
+}
+
+// decodeChainHash parses a single chain hash.
+func decodeChainHash(value []byte) (*chainhash.Hash, error) {
+	return chainhash.NewHash(value)
+}
+
+// encodeBlindedPaths returns the encoding of a list of blinded paths.
+func encodeBlindedPaths(paths []*record.BlindedPath) ([]byte, error) {
+	var b bytes.Buffer
+	for _, path := range paths {
+		if err := path.Encode(&b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// decodeBlindedPaths parses a list of blinded paths.
+func decodeBlindedPaths(value []byte) ([]*record.BlindedPath, error) {
+	r := bytes.NewReader(value)
+
+	var paths []*record.BlindedPath
+	for r.Len() > 0 {
+		path, err := record.DecodeBlindedPath(r)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no blinded paths")
+	}
+
+	return paths, nil
+}
+
+// encodeSignature returns the record that holds a signature.
+func encodeSignature(sig *schnorr.Signature) rawRecord {
+	return rawRecord{
+		typ:   signatureType,
+		value: sig.Serialize(),
+	}
+}
@@ -26,6 +26,11 @@ type ChannelAcceptRequest struct {
 	// OpenChanMsg is the actual OpenChannel protocol message that the peer
 	// sent to us.
 	OpenChanMsg *lnwire.OpenChannel
+
+	// DualFund is true if the peer proposed a dual funded channel with
+	// open_channel2, which OpenChanMsg is the single funded equivalent of.
+	// Only then the response can commit funds of our own to the channel.
+	DualFund bool
 }
 
 // ChannelAcceptResponse is a struct containing the response to a request to
@@ -66,6 +71,11 @@ type ChannelAcceptResponse struct {
 	// ZeroConf indicates that the fundee wishes to send min_depth = 0 and
 	// request a zero-conf channel with the counter-party.
 	ZeroConf bool
+
+	// DualFundContribution is the amount we contribute to a dual funded
+	// channel. We don't contribute anything unless an acceptor sets it,
+	// so peers can't lock our wallet's funds by proposing channels.
+	DualFundContribution btcutil.Amount
 }
 
 // NewChannelAcceptResponse is a constructor for a channel accept response,
@@ -18,6 +18,7 @@ const (
 	fieldMinIn           = "min htlc in"
 	fieldInFlightTotal   = "in flight total"
 	fieldUpfrontShutdown = "upfront shutdown"
+	fieldDualFundAmt     = "dual fund contribution"
 )
 
 var (
@@ -166,5 +167,14 @@ func mergeResponse(current,
 		return current, err
 	}
 
+	dualFundAmt, err := mergeInt64(
+		fieldDualFundAmt, int64(current.DualFundContribution),
+		int64(newValue.DualFundContribution),
+	)
+	if err != nil {
+		return current, err
+	}
+	current.DualFundContribution = btcutil.Amount(dualFundAmt)
+
 	return current, nil
 }
@@ -114,6 +114,16 @@ func TestMergeResponse(t *testing.T) {
 			},
 			err: fieldMismatchError(fieldMinDep, 1, 2),
 		},
+		{
+			name: "different dual fund contribution",
+			current: ChannelAcceptResponse{
+				DualFundContribution: 1,
+			},
+			new: ChannelAcceptResponse{
+				DualFundContribution: 2,
+			},
+			err: fieldMismatchError(fieldDualFundAmt, 1, 2),
+		},
 		{
 			name: "merge all values",
 			current: ChannelAcceptResponse{
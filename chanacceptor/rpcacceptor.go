@@ -217,6 +217,8 @@ func (r *RPCAcceptor) receiveResponses(errChan chan error,
 			MinHtlcIn:       resp.MinHtlcIn,
 			MinAcceptDepth:  resp.MinAcceptDepth,
 			ZeroConf:        resp.ZeroConf,
+
+			DualFundContributionSat: resp.DualFundContributionSat,
 		}
 
 		// We have received a decision for one of our channel
@@ -405,6 +407,7 @@ func (r *RPCAcceptor) sendAcceptRequests(errChan chan error,
 				CommitmentType:   commitmentType,
 				WantsZeroConf:    wantsZeroConf,
 				WantsScidAlias:   wantsScidAlias,
+				DualFund:         req.DualFund,
 			}
 
 			if err := r.send(chanAcceptReq); err != nil {
@@ -435,7 +438,7 @@ func (r *RPCAcceptor) sendAcceptRequests(errChan chan error,
 				log.Errorf("Invalid acceptor response: %v", err)
 			}
 
-			requestInfo.response <- NewChannelAcceptResponse(
+			acceptResp := NewChannelAcceptResponse(
 				accept, acceptErr, shutdown,
 				uint16(resp.CsvDelay),
 				uint16(resp.MaxHtlcCount),
@@ -445,6 +448,11 @@ func (r *RPCAcceptor) sendAcceptRequests(errChan chan error,
 				lnwire.MilliSatoshi(resp.MinHtlcIn),
 				resp.ZeroConf,
 			)
+			acceptResp.DualFundContribution = btcutil.Amount(
+				resp.DualFundContributionSat,
+			)
+
+			requestInfo.response <- acceptResp
 
 			// Delete the channel from the acceptRequests map.
 			delete(acceptRequests, pendingID)
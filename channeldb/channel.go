@@ -572,6 +572,13 @@ type ChannelCommitment struct {
 	// commitment height.
 	Htlcs []HTLC
 
+	// SpliceCommitments holds the variants of this commitment that spend
+	// the funding outputs of the channel's pending splice transactions,
+	// keyed by the txid of the splice transaction. Apart from the funding
+	// input, the balances and the signatures, they're identical to this
+	// commitment.
+	SpliceCommitments map[chainhash.Hash]*ChannelCommitment
+
 	// TODO(roasbeef): pending commit pointer?
 	//  * lets just walk through
 }
@@ -867,6 +874,15 @@ type OpenChannel struct {
 	// channel that will be useful to our future selves.
 	Memo []byte
 
+	// confirmedSplice is the funding outpoint of a splice transaction that
+	// confirmed, but that the channel hasn't been switched over to yet.
+	confirmedSplice fn.Option[wire.OutPoint]
+
+	// splicedFundingOutpoint is the outpoint of the funding output that
+	// the commitment transactions spend if the channel has been spliced.
+	// It's unset as long as the original funding output is used.
+	splicedFundingOutpoint fn.Option[wire.OutPoint]
+
 	// TODO(roasbeef): eww
 	Db *ChannelStateDB
 
@@ -1337,6 +1353,40 @@ func (c *OpenChannel) MarkRealScid(realScid lnwire.ShortChannelID) error {
 	return nil
 }
 
+// SetFundingTxn replaces the stored funding transaction of a dual funded
+// channel. The funding transaction is first stored without the witnesses of
+// its inputs, which are only known once tx_signatures were exchanged.
+func (c *OpenChannel) SetFundingTxn(fundingTx *wire.MsgTx) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		channel, err := fetchOpenChannel(
+			chanBucket, &c.FundingOutpoint,
+		)
+		if err != nil {
+			return err
+		}
+
+		channel.FundingTxn = fundingTx
+
+		return putOpenChannel(chanBucket, channel)
+	}, func() {}); err != nil {
+		return err
+	}
+
+	c.FundingTxn = fundingTx
+
+	return nil
+}
+
 // MarkScidAliasNegotiated adds ScidAliasFeatureBit to ChanType in-memory and
 // in the database.
 func (c *OpenChannel) MarkScidAliasNegotiated() error {
@@ -1605,6 +1655,14 @@ func (c *OpenChannel) ChanSyncMsg() (*lnwire.ChannelReestablish, error) {
 		nextTaprootNonce = lnwire.SomeMusig2Nonce(nextNonce.PubNonce)
 	}
 
+	// If the channel was upgraded with dynamic commitments, we'll send the
+	// number of upgrades, so the remote party can apply the last one if
+	// our acknowledgement of it was lost.
+	dynHeight, err := c.dynCommitHeight()
+	if err != nil {
+		return nil, err
+	}
+
 	return &lnwire.ChannelReestablish{
 		ChanID: lnwire.NewChanIDFromOutPoint(
 			c.FundingOutpoint,
@@ -1616,6 +1674,7 @@ func (c *OpenChannel) ChanSyncMsg() (*lnwire.ChannelReestablish, error) {
 			currentCommitSecret[:],
 		),
 		LocalNonce: nextTaprootNonce,
+		DynHeight:  dynHeight,
 	}, nil
 }
 
@@ -2351,13 +2410,21 @@ type HTLC struct {
 	// HTLC. It is stored in the ExtraData field, which is used to store
 	// a TLV stream of additional information associated with the HTLC.
 	BlindingPoint lnwire.BlindingPointRecord
+
+	// CustomRecords is a set of custom TLV records that are attached to
+	// the HTLC.
+	//
+	// Note: this field is not a part of on-disk representation of the
+	// HTLC. It is stored in the ExtraData field, which is used to store
+	// a TLV stream of additional information associated with the HTLC.
+	CustomRecords lnwire.CustomRecords
 }
 
 // serializeExtraData encodes a TLV stream of extra data to be stored with a
 // HTLC. It uses the update_add_htlc TLV types, because this is where extra
-// data is passed with a HTLC. At present blinding points are the only extra
-// data that we will store, and the function is a no-op if a nil blinding
-// point is provided.
+// data is passed with a HTLC. At present blinding points and custom records
+// are the only extra data that we will store, and the function is a no-op if
+// none of them are present.
 //
 // This function MUST be called to persist all HTLC values when they are
 // serialized.
@@ -2369,6 +2436,11 @@ func (h *HTLC) serializeExtraData() error {
 		records = append(records, &b)
 	})
 
+	records, err := h.CustomRecords.ExtendRecordProducers(records)
+	if err != nil {
+		return err
+	}
+
 	return h.ExtraData.PackRecords(records...)
 }
 
@@ -2392,7 +2464,9 @@ func (h *HTLC) deserializeExtraData() error {
 		h.BlindingPoint = tlv.SomeRecordT(blindingPoint)
 	}
 
-	return nil
+	h.CustomRecords, err = lnwire.ExtractCustomRecords(tlvMap)
+
+	return err
 }
 
 // SerializeHtlcs writes out the passed set of HTLC's into the passed writer
@@ -2828,7 +2902,15 @@ func (c *OpenChannel) AppendRemoteCommitChain(diff *CommitDiff) error {
 		if err := serializeCommitDiff(&b2, diff); err != nil {
 			return err
 		}
-		return chanBucket.Put(commitDiffKey, b2.Bytes())
+		err = chanBucket.Put(commitDiffKey, b2.Bytes())
+		if err != nil {
+			return err
+		}
+
+		return putSpliceCommitments(
+			chanBucket, spliceCommitDiffKey,
+			diff.Commitment.SpliceCommitments,
+		)
 	}, func() {})
 }
 
@@ -2863,6 +2945,13 @@ func (c *OpenChannel) RemoteCommitChainTip() (*CommitDiff, error) {
 			return err
 		}
 
+		dcd.Commitment.SpliceCommitments, err = fetchSpliceCommitments(
+			chanBucket, spliceCommitDiffKey,
+		)
+		if err != nil {
+			return err
+		}
+
 		cd = dcd
 		return nil
 	}, func() {
@@ -3045,6 +3134,11 @@ func (c *OpenChannel) AdvanceCommitChainTail(fwdPkg *FwdPkg,
 		if err != nil {
 			return err
 		}
+		newCommit.Commitment.SpliceCommitments, err =
+			fetchSpliceCommitments(chanBucket, spliceCommitDiffKey)
+		if err != nil {
+			return err
+		}
 		err = putChanCommitment(
 			chanBucket, &newCommit.Commitment, false,
 		)
@@ -3054,6 +3148,9 @@ func (c *OpenChannel) AdvanceCommitChainTail(fwdPkg *FwdPkg,
 		if err := chanBucket.Delete(commitDiffKey); err != nil {
 			return err
 		}
+		if err := chanBucket.Delete(spliceCommitDiffKey); err != nil {
+			return err
+		}
 
 		// With the commitment pointer swapped, we can now add the
 		// revoked (prior) state to the revocation log.
@@ -3993,12 +4090,13 @@ func writeChanConfig(b io.Writer, c *ChannelConfig) error {
 }
 
 // fundingTxPresent returns true if expect the funding transcation to be found
-// on disk or already populated within the passed open channel struct.
+// on disk or already populated within the passed open channel struct. Both
+// parties of a dual funded channel know the funding transaction.
 func fundingTxPresent(channel *OpenChannel) bool {
 	chanType := channel.ChanType
+	knowsFundingTx := chanType.IsDualFunder() || channel.IsInitiator
 
-	return chanType.IsSingleFunder() && chanType.HasFundingTx() &&
-		channel.IsInitiator &&
+	return knowsFundingTx && chanType.HasFundingTx() &&
 		!channel.hasChanStatus(ChanStatusRestored)
 }
 
@@ -4015,8 +4113,8 @@ func putChanInfo(chanBucket kvdb.RwBucket, channel *OpenChannel) error {
 		return err
 	}
 
-	// For single funder channels that we initiated, and we have the
-	// funding transaction, then write the funding txn.
+	// For single funder channels that we initiated, and dual funded
+	// channels, we have the funding transaction, so write the funding txn.
 	if fundingTxPresent(channel) {
 		if err := WriteElement(&w, channel.FundingTxn); err != nil {
 			return err
@@ -4142,7 +4240,14 @@ func putChanCommitment(chanBucket kvdb.RwBucket, c *ChannelCommitment,
 		return err
 	}
 
-	return chanBucket.Put(commitKey, b.Bytes())
+	if err := chanBucket.Put(commitKey, b.Bytes()); err != nil {
+		return err
+	}
+
+	return putSpliceCommitments(
+		chanBucket, spliceCommitmentsKeyFor(local),
+		c.SpliceCommitments,
+	)
 }
 
 func putChanCommitments(chanBucket kvdb.RwBucket, channel *OpenChannel) error {
@@ -4214,8 +4319,8 @@ func fetchChanInfo(chanBucket kvdb.RBucket, channel *OpenChannel) error {
 		return err
 	}
 
-	// For single funder channels that we initiated and have the funding
-	// transaction to, read the funding txn.
+	// For single funder channels that we initiated and dual funded
+	// channels, read the funding txn.
 	if fundingTxPresent(channel) {
 		if err := ReadElement(r, &channel.FundingTxn); err != nil {
 			return err
@@ -4244,6 +4349,12 @@ func fetchChanInfo(chanBucket kvdb.RBucket, channel *OpenChannel) error {
 		}
 	}
 
+	// If the channel has been spliced, read the outpoint of the funding
+	// output that is currently used.
+	if err := fetchSplicedFunding(chanBucket, channel); err != nil {
+		return err
+	}
+
 	// Create balance fields in uint64, and Memo field as byte slice.
 	var (
 		localBalance  uint64
@@ -4332,7 +4443,19 @@ func fetchChanCommitment(chanBucket kvdb.RBucket, local bool) (ChannelCommitment
 	}
 
 	r := bytes.NewReader(commitBytes)
-	return deserializeChanCommit(r)
+	c, err := deserializeChanCommit(r)
+	if err != nil {
+		return ChannelCommitment{}, err
+	}
+
+	c.SpliceCommitments, err = fetchSpliceCommitments(
+		chanBucket, spliceCommitmentsKeyFor(local),
+	)
+	if err != nil {
+		return ChannelCommitment{}, err
+	}
+
+	return c, nil
 }
 
 func fetchChanCommitments(chanBucket kvdb.RBucket, channel *OpenChannel) error {
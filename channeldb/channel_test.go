@@ -1328,6 +1328,45 @@ func TestRefresh(t *testing.T) {
 	}
 }
 
+// TestSetFundingTxn tests that the funding transaction of a dual funded
+// channel is persisted for the responder, and can be replaced once the
+// witnesses of its inputs are known.
+func TestSetFundingTxn(t *testing.T) {
+	t.Parallel()
+
+	fullDB, err := MakeTestDB(t)
+	require.NoError(t, err, "unable to make test database")
+
+	cdb := fullDB.ChannelStateDB()
+
+	// Create a dual funded channel we didn't initiate, storing the funding
+	// transaction without witnesses.
+	unsignedTx := channels.TestFundingTx.Copy()
+	unsignedTx.TxIn[0].Witness = nil
+	state := createTestChannel(t, cdb, func(params *testChannelParams) {
+		params.channel.ChanType = DualFunderBit |
+			SingleFunderTweaklessBit
+		params.channel.IsInitiator = false
+		params.channel.FundingTxn = unsignedTx
+	})
+
+	pendingChannels, err := cdb.FetchPendingChannels()
+	require.NoError(t, err)
+	require.Len(t, pendingChannels, 1)
+	require.Equal(t, unsignedTx, pendingChannels[0].FundingTxn)
+
+	// Replace the funding transaction with the signed one.
+	signedTx := channels.TestFundingTx.Copy()
+	signedTx.TxIn[0].Witness = wire.TxWitness{{0x01}, {0x02}}
+	require.NoError(t, state.SetFundingTxn(signedTx))
+	require.Equal(t, signedTx, state.FundingTxn)
+
+	pendingChannels, err = cdb.FetchPendingChannels()
+	require.NoError(t, err)
+	require.Len(t, pendingChannels, 1)
+	require.Equal(t, signedTx, pendingChannels[0].FundingTxn)
+}
+
 // TestCloseInitiator tests the setting of close initiator statuses for
 // cooperative closes and local force closes.
 func TestCloseInitiator(t *testing.T) {
@@ -1642,6 +1681,21 @@ func TestHTLCsExtraData(t *testing.T) {
 		),
 	}
 
+	// Add an endorsement signal to a htlc that also carries a blinding
+	// point.
+	endorsedHTLC := blindingPointHTLC
+	endorsedHTLC.CustomRecords = lnwire.CustomRecords{
+		uint64(lnwire.ExperimentalEndorsementType): {
+			lnwire.ExperimentalEndorsed,
+		},
+	}
+
+	// Add custom records to a htlc.
+	customRecordsHTLC := mockHtlc
+	customRecordsHTLC.CustomRecords = lnwire.CustomRecords{
+		lnwire.MinCustomRecordsTlvType: []byte{1, 2, 3},
+	}
+
 	testCases := []struct {
 		name        string
 		htlcs       []HTLC
@@ -1665,6 +1719,23 @@ func TestHTLCsExtraData(t *testing.T) {
 				mockHtlc,
 			},
 		},
+		{
+			// HTLCs with multiple extra data records.
+			name: "endorsed htlc",
+			htlcs: []HTLC{
+				mockHtlc,
+				endorsedHTLC,
+				blindingPointHTLC,
+			},
+		},
+		{
+			// HTLCs with custom records.
+			name: "custom records",
+			htlcs: []HTLC{
+				customRecordsHTLC,
+				mockHtlc,
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -334,6 +334,14 @@ type DB struct {
 	// noRevLogAmtData if true, means that commitment transaction amount
 	// data should not be stored in the revocation log.
 	noRevLogAmtData bool
+
+	// paymentsSQLStore is an optional SQL store that the payments are kept
+	// in instead of the kv buckets.
+	paymentsSQLStore *SQLPaymentsStore
+
+	// fwdLogSQLStore is an optional SQL store that the forwarding log is
+	// kept in instead of the kv bucket.
+	fwdLogSQLStore *SQLForwardingLogStore
 }
 
 // Open opens or creates channeldb. Any necessary schemas migrations due
@@ -398,11 +406,32 @@ func CreateWithBackend(backend kvdb.Backend,
 	// Set the parent pointer (only used in tests).
 	chanDB.channelStateDB.parent = chanDB
 
+	// The kv data must be migrated before the database can be opened with
+	// the native SQL stores.
+	if !opts.migrateNativeSQL {
+		if err := checkNativeSQLMigrated(backend, &opts); err != nil {
+			backend.Close()
+			return nil, err
+		}
+	}
+
+	// While migrating, the graph is read from the kv buckets and its cache
+	// isn't needed.
+	var graphOpts []GraphOption
+	useGraphCache := opts.UseGraphCache
+	switch {
+	case opts.migrateNativeSQL:
+		useGraphCache = false
+
+	case opts.graphSQLStore != nil:
+		graphOpts = append(graphOpts, WithSQLStore(opts.graphSQLStore))
+	}
+
 	var err error
 	chanDB.graph, err = NewChannelGraph(
 		backend, opts.RejectCacheSize, opts.ChannelCacheSize,
 		opts.BatchCommitInterval, opts.PreAllocCacheNumNodes,
-		opts.UseGraphCache, opts.NoMigration,
+		useGraphCache, opts.NoMigration, graphOpts...,
 	)
 	if err != nil {
 		return nil, err
@@ -423,6 +452,23 @@ func CreateWithBackend(backend kvdb.Backend,
 		}
 	}
 
+	// Migrating the kv data to the native SQL stores can take a long time
+	// on busy nodes, so it is only done as an explicit offline step, after
+	// which the database isn't opened. The backend is left open for the
+	// caller to close.
+	if opts.migrateNativeSQL {
+		if err := chanDB.migrateToNativeSQL(&opts); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNativeSQLMigrationOK
+	}
+
+	// Keep the payments and the forwarding log in the SQL stores if they
+	// are set.
+	chanDB.paymentsSQLStore = opts.paymentsSQLStore
+	chanDB.fwdLogSQLStore = opts.fwdLogSQLStore
+
 	return chanDB, nil
 }
 
@@ -0,0 +1,438 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// dynCommitEpochsKey stores the channel types and configs a channel
+	// used before they were changed by dynamic commitment upgrades.
+	dynCommitEpochsKey = []byte("dyn-commit-epochs-key")
+
+	// dynCommitProposalKey stores the parameters of a dynamic commitment
+	// upgrade we proposed, until it is either applied or rejected.
+	dynCommitProposalKey = []byte("dyn-commit-proposal-key")
+
+	// ErrDynCommitPendingCommitment is returned when a dynamic commitment
+	// upgrade is applied to a channel that has a commitment which hasn't
+	// been revoked yet.
+	ErrDynCommitPendingCommitment = errors.New("channel has unrevoked " +
+		"commitments")
+)
+
+// DynCommitEpoch records the channel type and configs that were in effect for
+// a channel before a dynamic commitment upgrade changed them. The commitment
+// heights mark the first commitment of either chain that was created with the
+// upgraded parameters, so any commitment below them, like a revoked state the
+// remote party might broadcast, has to be reconstructed with the parameters of
+// the epoch.
+type DynCommitEpoch struct {
+	// ChanType is the channel type before the upgrade.
+	ChanType ChannelType
+
+	// LocalChanCfg is our channel config before the upgrade.
+	LocalChanCfg ChannelConfig
+
+	// RemoteChanCfg is the remote party's channel config before the
+	// upgrade.
+	RemoteChanCfg ChannelConfig
+
+	// LocalCommitHeight is the height of our first commitment that was
+	// created with the upgraded parameters.
+	LocalCommitHeight uint64
+
+	// RemoteCommitHeight is the height of the remote party's first
+	// commitment that was created with the upgraded parameters.
+	RemoteCommitHeight uint64
+}
+
+// encode serializes the DynCommitEpoch to the passed io.Writer.
+func (d *DynCommitEpoch) encode(w io.Writer) error {
+	if err := WriteElement(w, d.ChanType); err != nil {
+		return err
+	}
+	if err := writeChanConfig(w, &d.LocalChanCfg); err != nil {
+		return err
+	}
+	if err := writeChanConfig(w, &d.RemoteChanCfg); err != nil {
+		return err
+	}
+
+	return WriteElements(w, d.LocalCommitHeight, d.RemoteCommitHeight)
+}
+
+// decodeDynCommitEpoch deserializes a DynCommitEpoch from the passed
+// io.Reader.
+func decodeDynCommitEpoch(r io.Reader) (*DynCommitEpoch, error) {
+	var d DynCommitEpoch
+	if err := ReadElement(r, &d.ChanType); err != nil {
+		return nil, err
+	}
+	if err := readChanConfig(r, &d.LocalChanCfg); err != nil {
+		return nil, err
+	}
+	if err := readChanConfig(r, &d.RemoteChanCfg); err != nil {
+		return nil, err
+	}
+
+	err := ReadElements(r, &d.LocalCommitHeight, &d.RemoteCommitHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// DynCommitProposal holds the channel type and configs a channel has after a
+// dynamic commitment upgrade we proposed is applied. It is kept until the
+// remote party either accepts or rejects the proposal, so the upgrade can
+// still be applied if the remote party accepted it, but its acknowledgement
+// was lost.
+type DynCommitProposal struct {
+	// ChanType is the channel type after the upgrade.
+	ChanType ChannelType
+
+	// LocalChanCfg is our channel config after the upgrade.
+	LocalChanCfg ChannelConfig
+
+	// RemoteChanCfg is the remote party's channel config after the
+	// upgrade.
+	RemoteChanCfg ChannelConfig
+}
+
+// encode serializes the DynCommitProposal to the passed io.Writer.
+func (d *DynCommitProposal) encode(w io.Writer) error {
+	if err := WriteElement(w, d.ChanType); err != nil {
+		return err
+	}
+	if err := writeChanConfig(w, &d.LocalChanCfg); err != nil {
+		return err
+	}
+
+	return writeChanConfig(w, &d.RemoteChanCfg)
+}
+
+// decodeDynCommitProposal deserializes a DynCommitProposal from the passed
+// io.Reader.
+func decodeDynCommitProposal(r io.Reader) (*DynCommitProposal, error) {
+	var d DynCommitProposal
+	if err := ReadElement(r, &d.ChanType); err != nil {
+		return nil, err
+	}
+	if err := readChanConfig(r, &d.LocalChanCfg); err != nil {
+		return nil, err
+	}
+	if err := readChanConfig(r, &d.RemoteChanCfg); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// putDynCommitEpochs stores the passed epochs in the channel bucket, replacing
+// any that were stored before.
+func putDynCommitEpochs(chanBucket kvdb.RwBucket,
+	epochs []*DynCommitEpoch) error {
+
+	var b bytes.Buffer
+	if err := WriteElement(&b, uint16(len(epochs))); err != nil {
+		return err
+	}
+
+	for _, epoch := range epochs {
+		if err := epoch.encode(&b); err != nil {
+			return err
+		}
+	}
+
+	return chanBucket.Put(dynCommitEpochsKey, b.Bytes())
+}
+
+// fetchDynCommitEpochs reads the epochs stored in the channel bucket, ordered
+// from the oldest to the latest upgrade.
+func fetchDynCommitEpochs(chanBucket kvdb.RBucket) ([]*DynCommitEpoch,
+	error) {
+
+	epochBytes := chanBucket.Get(dynCommitEpochsKey)
+	if epochBytes == nil {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(epochBytes)
+
+	var numEpochs uint16
+	if err := ReadElement(r, &numEpochs); err != nil {
+		return nil, err
+	}
+
+	epochs := make([]*DynCommitEpoch, numEpochs)
+	for i := range epochs {
+		epoch, err := decodeDynCommitEpoch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		epochs[i] = epoch
+	}
+
+	return epochs, nil
+}
+
+// DynCommitEpochs returns the parameters the channel used before each of its
+// dynamic commitment upgrades, ordered from the oldest to the latest upgrade.
+func (c *OpenChannel) DynCommitEpochs() ([]*DynCommitEpoch, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var epochs []*DynCommitEpoch
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		epochs, err = fetchDynCommitEpochs(chanBucket)
+
+		return err
+	}, func() {
+		epochs = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return epochs, nil
+}
+
+// dynCommitHeight returns the number of dynamic commitment upgrades that were
+// applied to the channel, which is None if the channel was never upgraded.
+//
+// NOTE: This method requires the channel lock to be held.
+func (c *OpenChannel) dynCommitHeight() (fn.Option[lnwire.DynHeight], error) {
+	var numEpochs int
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		epochs, err := fetchDynCommitEpochs(chanBucket)
+		numEpochs = len(epochs)
+
+		return err
+	}, func() {
+		numEpochs = 0
+	})
+	switch {
+	// A channel that was closed or isn't fully persisted yet is never
+	// upgraded.
+	case errors.Is(err, ErrNoChanDBExists),
+		errors.Is(err, ErrNoActiveChannels),
+		errors.Is(err, ErrChannelNotFound):
+
+		return fn.None[lnwire.DynHeight](), nil
+
+	case err != nil:
+		return fn.None[lnwire.DynHeight](), err
+
+	case numEpochs == 0:
+		return fn.None[lnwire.DynHeight](), nil
+	}
+
+	return fn.Some(lnwire.DynHeight(numEpochs)), nil
+}
+
+// PutDynCommitProposal stores the parameters of a dynamic commitment upgrade
+// we propose to the remote party, replacing any earlier proposal.
+func (c *OpenChannel) PutDynCommitProposal(p *DynCommitProposal) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var b bytes.Buffer
+	if err := p.encode(&b); err != nil {
+		return err
+	}
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		return chanBucket.Put(dynCommitProposalKey, b.Bytes())
+	}, func() {})
+}
+
+// DynCommitProposal returns the parameters of the dynamic commitment upgrade
+// we proposed, which is None if there is no pending proposal.
+func (c *OpenChannel) DynCommitProposal() (fn.Option[DynCommitProposal],
+	error) {
+
+	c.RLock()
+	defer c.RUnlock()
+
+	var proposal *DynCommitProposal
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		proposalBytes := chanBucket.Get(dynCommitProposalKey)
+		if proposalBytes == nil {
+			return nil
+		}
+
+		proposal, err = decodeDynCommitProposal(
+			bytes.NewReader(proposalBytes),
+		)
+
+		return err
+	}, func() {
+		proposal = nil
+	})
+	if err != nil {
+		return fn.None[DynCommitProposal](), err
+	}
+
+	if proposal == nil {
+		return fn.None[DynCommitProposal](), nil
+	}
+
+	return fn.Some(*proposal), nil
+}
+
+// DeleteDynCommitProposal removes the dynamic commitment upgrade we proposed,
+// once the remote party rejected it.
+func (c *OpenChannel) DeleteDynCommitProposal() error {
+	c.Lock()
+	defer c.Unlock()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		return chanBucket.Delete(dynCommitProposalKey)
+	}, func() {})
+}
+
+// DynCommitEpochAt returns the epoch whose parameters were in effect for the
+// commitment at the given height of the party's commitment chain. If the
+// commitment was created with the current parameters of the channel, None is
+// returned.
+func (c *OpenChannel) DynCommitEpochAt(party lntypes.ChannelParty,
+	height uint64) (fn.Option[DynCommitEpoch], error) {
+
+	epochs, err := c.DynCommitEpochs()
+	if err != nil {
+		return fn.None[DynCommitEpoch](), err
+	}
+
+	// The epochs are ordered by their heights, so the first one that ends
+	// after the commitment is the one it was created in.
+	for _, epoch := range epochs {
+		endHeight := epoch.RemoteCommitHeight
+		if party.IsLocal() {
+			endHeight = epoch.LocalCommitHeight
+		}
+
+		if height < endHeight {
+			return fn.Some(*epoch), nil
+		}
+	}
+
+	return fn.None[DynCommitEpoch](), nil
+}
+
+// ApplyDynCommitment changes the channel type and the channel configs of the
+// channel to the passed ones. The parameters the channel used so far are
+// recorded as an epoch that ends with the current commitments, so the next
+// commitment of either party is the first one to use the new parameters. Any
+// proposal we made is removed, as it was either the one that is applied or
+// is superseded by it.
+//
+// NOTE: All commitments of the channel must have been revoked, which is the
+// case once the channel is quiescent.
+func (c *OpenChannel) ApplyDynCommitment(chanType ChannelType, localCfg,
+	remoteCfg ChannelConfig) error {
+
+	c.Lock()
+	defer c.Unlock()
+
+	epoch := &DynCommitEpoch{
+		ChanType:           c.ChanType,
+		LocalChanCfg:       c.LocalChanCfg,
+		RemoteChanCfg:      c.RemoteChanCfg,
+		LocalCommitHeight:  c.LocalCommitment.CommitHeight + 1,
+		RemoteCommitHeight: c.RemoteCommitment.CommitHeight + 1,
+	}
+
+	err := kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		// A commitment the remote party hasn't revoked yet would have
+		// been created with the old parameters, but be above the end
+		// of the epoch.
+		if chanBucket.Get(commitDiffKey) != nil {
+			return ErrDynCommitPendingCommitment
+		}
+
+		epochs, err := fetchDynCommitEpochs(chanBucket)
+		if err != nil {
+			return err
+		}
+
+		epochs = append(epochs, epoch)
+		if err := putDynCommitEpochs(chanBucket, epochs); err != nil {
+			return err
+		}
+
+		err = chanBucket.Delete(dynCommitProposalKey)
+		if err != nil {
+			return err
+		}
+
+		c.ChanType = chanType
+		c.LocalChanCfg = localCfg
+		c.RemoteChanCfg = remoteCfg
+
+		return putChanInfo(chanBucket, c)
+	}, func() {
+		c.ChanType = epoch.ChanType
+		c.LocalChanCfg = epoch.LocalChanCfg
+		c.RemoteChanCfg = epoch.RemoteChanCfg
+	})
+	if err != nil {
+		c.ChanType = epoch.ChanType
+		c.LocalChanCfg = epoch.LocalChanCfg
+		c.RemoteChanCfg = epoch.RemoteChanCfg
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package channeldb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestApplyDynCommitment tests that the parameters of a channel are replaced
+// by a dynamic commitment upgrade, while the previous ones are kept as an
+// epoch for the commitments that were created with them.
+func TestApplyDynCommitment(t *testing.T) {
+	t.Parallel()
+
+	fullDB, err := MakeTestDB(t)
+	require.NoError(t, err, "unable to make test database")
+
+	cdb := fullDB.ChannelStateDB()
+	channel := createTestChannel(t, cdb, openChannelOption())
+
+	prevType := channel.ChanType
+	prevLocalCfg := channel.LocalChanCfg
+	prevRemoteCfg := channel.RemoteChanCfg
+
+	epoch, err := channel.DynCommitEpochAt(lntypes.Remote, 0)
+	require.NoError(t, err)
+	require.True(t, epoch.IsNone())
+
+	// Upgrade the channel to anchors with a new delay for the remote
+	// party.
+	nextType := SingleFunderTweaklessBit | AnchorOutputsBit |
+		ZeroHtlcTxFeeBit | FrozenBit
+	remoteCfg := prevRemoteCfg
+	remoteCfg.CsvDelay += 10
+
+	// The proposal of the upgrade is kept until it is applied.
+	err = channel.PutDynCommitProposal(&DynCommitProposal{
+		ChanType:      nextType,
+		LocalChanCfg:  prevLocalCfg,
+		RemoteChanCfg: remoteCfg,
+	})
+	require.NoError(t, err)
+
+	proposal, err := channel.DynCommitProposal()
+	require.NoError(t, err)
+	require.Equal(t, nextType, proposal.UnsafeFromSome().ChanType)
+	require.Equal(
+		t, remoteCfg.CsvDelay,
+		proposal.UnsafeFromSome().RemoteChanCfg.CsvDelay,
+	)
+
+	syncMsg, err := channel.ChanSyncMsg()
+	require.NoError(t, err)
+	require.True(t, syncMsg.DynHeight.IsNone())
+
+	err = channel.ApplyDynCommitment(nextType, prevLocalCfg, remoteCfg)
+	require.NoError(t, err)
+	require.Equal(t, nextType, channel.ChanType)
+
+	proposal, err = channel.DynCommitProposal()
+	require.NoError(t, err)
+	require.True(t, proposal.IsNone())
+
+	// The number of upgrades is reported when the channel is
+	// reestablished.
+	syncMsg, err = channel.ChanSyncMsg()
+	require.NoError(t, err)
+	require.Equal(t, fn.Some(lnwire.DynHeight(1)), syncMsg.DynHeight)
+
+	// The upgraded parameters are persisted.
+	require.NoError(t, channel.Refresh())
+	require.Equal(t, nextType, channel.ChanType)
+	require.Equal(t, remoteCfg.CsvDelay, channel.RemoteChanCfg.CsvDelay)
+
+	// The previous parameters apply to the current commitments, but not
+	// the next ones.
+	epochs, err := channel.DynCommitEpochs()
+	require.NoError(t, err)
+	require.Len(t, epochs, 1)
+
+	localHeight := channel.LocalCommitment.CommitHeight
+	remoteHeight := channel.RemoteCommitment.CommitHeight
+	require.Equal(t, localHeight+1, epochs[0].LocalCommitHeight)
+	require.Equal(t, remoteHeight+1, epochs[0].RemoteCommitHeight)
+
+	epoch, err = channel.DynCommitEpochAt(lntypes.Remote, remoteHeight)
+	require.NoError(t, err)
+	prevEpoch, err := epoch.UnwrapOrErr(errors.New("no epoch"))
+	require.NoError(t, err)
+	require.Equal(t, prevType, prevEpoch.ChanType)
+	require.Equal(
+		t, prevRemoteCfg.CsvDelay, prevEpoch.RemoteChanCfg.CsvDelay,
+	)
+
+	epoch, err = channel.DynCommitEpochAt(lntypes.Local, localHeight+1)
+	require.NoError(t, err)
+	require.True(t, epoch.IsNone())
+
+	// A second upgrade adds another epoch.
+	err = channel.ApplyDynCommitment(nextType, prevLocalCfg, prevRemoteCfg)
+	require.NoError(t, err)
+
+	epochs, err = channel.DynCommitEpochs()
+	require.NoError(t, err)
+	require.Len(t, epochs, 2)
+	require.Equal(t, nextType, epochs[1].ChanType)
+}
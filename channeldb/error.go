@@ -102,6 +102,12 @@ var (
 	// to the log not having any recorded events.
 	ErrNoForwardingEvents = fmt.Errorf("no recorded forwarding events")
 
+	// ErrForwardingLogFilters is returned if the kv forwarding log is
+	// filtered on anything other than time, or asked to aggregate fees,
+	// both of which are only supported by the SQL forwarding log.
+	ErrForwardingLogFilters = fmt.Errorf("forwarding log filters and fee " +
+		"summaries require the native SQL database")
+
 	// ErrEdgePolicyOptionalFieldNotFound is an error returned if a channel
 	// policy field is not found in the db even though its message flags
 	// indicate it should be.
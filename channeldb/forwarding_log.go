@@ -2,13 +2,16 @@ package channeldb
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"sort"
 	"time"
 
 	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/kvdb"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
 )
 
 var (
@@ -78,6 +81,18 @@ type ForwardingEvent struct {
 	// AmtOut is the amount of the outgoing HTLC. Subtracting the incoming
 	// amount from this gives the total fees for this payment circuit.
 	AmtOut lnwire.MilliSatoshi
+
+	// IncomingPeer is the public key of the peer of the incoming channel,
+	// if known.
+	//
+	// NOTE: This is only persisted by the SQL forwarding log.
+	IncomingPeer fn.Option[route.Vertex]
+
+	// OutgoingPeer is the public key of the peer of the outgoing channel,
+	// if known.
+	//
+	// NOTE: This is only persisted by the SQL forwarding log.
+	OutgoingPeer fn.Option[route.Vertex]
 }
 
 // encodeForwardingEvent writes out the target forwarding event to the passed
@@ -103,6 +118,12 @@ func decodeForwardingEvent(r io.Reader, f *ForwardingEvent) error {
 // Before inserting, the set of events will be sorted according to their
 // timestamp. This ensures that all writes to disk are sequential.
 func (f *ForwardingLog) AddForwardingEvents(events []ForwardingEvent) error {
+	if f.db.fwdLogSQLStore != nil {
+		return f.db.fwdLogSQLStore.AddForwardingEvents(
+			context.Background(), events,
+		)
+	}
+
 	// Before we create the database transaction, we'll ensure that the set
 	// of forwarding events are properly sorted according to their
 	// timestamp and that no duplicate timestamps exist to avoid collisions
@@ -200,6 +221,36 @@ type ForwardingEventQuery struct {
 
 	// NumMaxEvents is the max number of events to return.
 	NumMaxEvents uint32
+
+	// IncomingChanID, if set, only matches the events that came in through
+	// the given channel.
+	IncomingChanID fn.Option[lnwire.ShortChannelID]
+
+	// OutgoingChanID, if set, only matches the events that went out
+	// through the given channel.
+	OutgoingChanID fn.Option[lnwire.ShortChannelID]
+
+	// Peer, if set, only matches the events that came in from or went out
+	// to the given peer.
+	Peer fn.Option[route.Vertex]
+
+	// MinAmtOut and MaxAmtOut, if set, only match the events that
+	// forwarded at least or at most the given amount.
+	MinAmtOut fn.Option[lnwire.MilliSatoshi]
+	MaxAmtOut fn.Option[lnwire.MilliSatoshi]
+
+	// MinFee and MaxFee, if set, only match the events that earned at
+	// least or at most the given fee.
+	MinFee fn.Option[lnwire.MilliSatoshi]
+	MaxFee fn.Option[lnwire.MilliSatoshi]
+}
+
+// hasFilters returns true if the query filters the events on anything other
+// than their time.
+func (q *ForwardingEventQuery) hasFilters() bool {
+	return q.IncomingChanID.IsSome() || q.OutgoingChanID.IsSome() ||
+		q.Peer.IsSome() || q.MinAmtOut.IsSome() ||
+		q.MaxAmtOut.IsSome() || q.MinFee.IsSome() || q.MaxFee.IsSome()
 }
 
 // ForwardingLogTimeSlice is the response to a forwarding query. It includes
@@ -228,6 +279,16 @@ type ForwardingLogTimeSlice struct {
 //
 // TODO(roasbeef): rename?
 func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, error) {
+	if f.db.fwdLogSQLStore != nil {
+		return f.db.fwdLogSQLStore.Query(context.Background(), q)
+	}
+
+	// The kv log is only indexed by time, so it can't be filtered on
+	// anything else.
+	if q.hasFilters() {
+		return ForwardingLogTimeSlice{}, ErrForwardingLogFilters
+	}
+
 	var resp ForwardingLogTimeSlice
 
 	// If the user provided an index offset, then we'll not know how many
@@ -309,6 +370,41 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 	return resp, nil
 }
 
+// ChannelFeeSummary sums up the forwarding events that went out through a
+// channel on a single day.
+type ChannelFeeSummary struct {
+	// ChanID is the outgoing channel of the events.
+	ChanID lnwire.ShortChannelID
+
+	// Day is the start of the day, in UTC, the events were settled on.
+	Day time.Time
+
+	// NumForwards is the number of events.
+	NumForwards uint64
+
+	// AmtIn is the sum of the amounts of the incoming HTLCs.
+	AmtIn lnwire.MilliSatoshi
+
+	// AmtOut is the sum of the amounts of the outgoing HTLCs.
+	AmtOut lnwire.MilliSatoshi
+
+	// Fee is the sum of the fees that were earned.
+	Fee lnwire.MilliSatoshi
+}
+
+// FeeSummaries returns the fees earned per outgoing channel per day for all
+// events that match the given query, ordered by day and channel. The index
+// offset and max number of events of the query are ignored.
+func (f *ForwardingLog) FeeSummaries(
+	q ForwardingEventQuery) ([]ChannelFeeSummary, error) {
+
+	if f.db.fwdLogSQLStore == nil {
+		return nil, ErrForwardingLogFilters
+	}
+
+	return f.db.fwdLogSQLStore.FeeSummaries(context.Background(), q)
+}
+
 // makeUniqueTimestamps takes a slice of forwarding events, sorts it by the
 // event timestamps and then makes sure there are no duplicates in the
 // timestamps. If duplicates are found, some of the timestamps are increased on
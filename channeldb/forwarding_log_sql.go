@@ -0,0 +1,312 @@
+package channeldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+)
+
+const (
+	// secondsPerDay is the number of seconds in a day, which is the
+	// granularity the fee summaries are aggregated with.
+	secondsPerDay = int64(24 * time.Hour / time.Second)
+)
+
+// SQLForwardingLogQueries is an interface that defines the set of operations
+// that can be executed against the forwarding log SQL database.
+type SQLForwardingLogQueries interface {
+	InsertForwardingEvent(ctx context.Context,
+		arg sqlc.InsertForwardingEventParams) error
+
+	FilterForwardingEvents(ctx context.Context,
+		arg sqlc.FilterForwardingEventsParams) ([]sqlc.ForwardingEvent,
+		error)
+
+	ForwardingFeesPerChannelDay(ctx context.Context,
+		arg sqlc.ForwardingFeesPerChannelDayParams) (
+		[]sqlc.ForwardingFeesPerChannelDayRow, error)
+}
+
+// SQLForwardingLogQueriesTxOptions defines the set of db txn options the
+// SQLForwardingLogQueries understands.
+type SQLForwardingLogQueriesTxOptions struct {
+	// readOnly governs if a read only transaction is needed or not.
+	readOnly bool
+}
+
+// ReadOnly returns true if the transaction should be read only.
+//
+// NOTE: This implements the TxOptions.
+func (a *SQLForwardingLogQueriesTxOptions) ReadOnly() bool {
+	return a.readOnly
+}
+
+// NewSQLForwardingLogQueryReadTx creates a new read transaction option set.
+func NewSQLForwardingLogQueryReadTx() SQLForwardingLogQueriesTxOptions {
+	return SQLForwardingLogQueriesTxOptions{
+		readOnly: true,
+	}
+}
+
+// BatchedSQLForwardingLogQueries is a version of the SQLForwardingLogQueries
+// that's capable of batched database operations.
+type BatchedSQLForwardingLogQueries interface {
+	SQLForwardingLogQueries
+
+	sqldb.BatchedTx[SQLForwardingLogQueries]
+}
+
+// SQLForwardingLogStore is a SQL backed forwarding log. Next to the time of
+// each event, the channels, peers, amounts and fees are stored in indexed
+// columns, so that the log can be filtered and aggregated without a scan over
+// all events.
+type SQLForwardingLogStore struct {
+	db BatchedSQLForwardingLogQueries
+}
+
+// NewSQLForwardingLogStore creates a new SQLForwardingLogStore instance given
+// an open BatchedSQLForwardingLogQueries storage backend.
+func NewSQLForwardingLogStore(
+	db BatchedSQLForwardingLogQueries) *SQLForwardingLogStore {
+
+	return &SQLForwardingLogStore{
+		db: db,
+	}
+}
+
+// AddForwardingEvents adds a series of forwarding events to the database. The
+// events are sorted by their timestamp before they are inserted.
+func (s *SQLForwardingLogStore) AddForwardingEvents(ctx context.Context,
+	events []ForwardingEvent) error {
+
+	// Keep the timestamps unique just like the kv log does, so that the
+	// order of the events is the same for both.
+	makeUniqueTimestamps(events)
+
+	var writeTxOpts SQLForwardingLogQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts,
+		func(db SQLForwardingLogQueries) error {
+			for i := range events {
+				err := insertForwardingEvent(
+					ctx, db, &events[i],
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, func() {},
+	)
+}
+
+// Query returns the forwarding events that match the given query, ordered by
+// their timestamp. Both the start and end time of the query are inclusive.
+func (s *SQLForwardingLogStore) Query(ctx context.Context,
+	q ForwardingEventQuery) (ForwardingLogTimeSlice, error) {
+
+	resp := ForwardingLogTimeSlice{
+		ForwardingEventQuery: q,
+	}
+
+	filter := forwardingEventsFilter(q)
+
+	numMaxEvents := q.NumMaxEvents
+	if numMaxEvents > math.MaxInt32 {
+		numMaxEvents = math.MaxInt32
+	}
+	indexOffset := q.IndexOffset
+	if indexOffset > math.MaxInt32 {
+		indexOffset = math.MaxInt32
+	}
+
+	readTxOpts := NewSQLForwardingLogQueryReadTx()
+	err := s.db.ExecTx(ctx, &readTxOpts,
+		func(db SQLForwardingLogQueries) error {
+			rows, err := db.FilterForwardingEvents(
+				ctx, sqlc.FilterForwardingEventsParams{
+					StartTime:      filter.StartTime,
+					EndTime:        filter.EndTime,
+					IncomingChanID: filter.IncomingChanID,
+					OutgoingChanID: filter.OutgoingChanID,
+					Peer:           filter.Peer,
+					MinAmtMsat:     filter.MinAmtMsat,
+					MaxAmtMsat:     filter.MaxAmtMsat,
+					MinFeeMsat:     filter.MinFeeMsat,
+					MaxFeeMsat:     filter.MaxFeeMsat,
+					NumLimit:       int32(numMaxEvents),
+					NumOffset:      int32(indexOffset),
+				},
+			)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				resp.ForwardingEvents = append(
+					resp.ForwardingEvents,
+					buildForwardingEvent(row),
+				)
+			}
+
+			return nil
+		}, func() {
+			resp.ForwardingEvents = nil
+		},
+	)
+	if err != nil {
+		return ForwardingLogTimeSlice{}, fmt.Errorf("unable to query "+
+			"forwarding events: %w", err)
+	}
+
+	resp.LastIndexOffset = q.IndexOffset +
+		uint32(len(resp.ForwardingEvents))
+
+	return resp, nil
+}
+
+// FeeSummaries returns the fees earned per outgoing channel per day for all
+// events that match the given query, ordered by day and channel. The index
+// offset and max number of events of the query are ignored.
+func (s *SQLForwardingLogStore) FeeSummaries(ctx context.Context,
+	q ForwardingEventQuery) ([]ChannelFeeSummary, error) {
+
+	var summaries []ChannelFeeSummary
+
+	readTxOpts := NewSQLForwardingLogQueryReadTx()
+	err := s.db.ExecTx(ctx, &readTxOpts,
+		func(db SQLForwardingLogQueries) error {
+			rows, err := db.ForwardingFeesPerChannelDay(
+				ctx, forwardingEventsFilter(q),
+			)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				chanID := lnwire.NewShortChanIDFromInt(
+					byteOrder.Uint64(row.OutgoingChanID),
+				)
+				day := time.Unix(row.Day*secondsPerDay, 0).UTC()
+
+				summaries = append(summaries, ChannelFeeSummary{
+					ChanID:      chanID,
+					Day:         day,
+					NumForwards: uint64(row.NumForwards),
+					AmtIn: lnwire.MilliSatoshi(
+						row.TotalAmtInMsat,
+					),
+					AmtOut: lnwire.MilliSatoshi(
+						row.TotalAmtOutMsat,
+					),
+					Fee: lnwire.MilliSatoshi(
+						row.TotalFeeMsat,
+					),
+				})
+			}
+
+			return nil
+		}, func() {
+			summaries = nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query forwarding fee "+
+			"summaries: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// forwardingEventsFilter maps the given query to the filter parameters of the
+// forwarding event queries.
+func forwardingEventsFilter(
+	q ForwardingEventQuery) sqlc.ForwardingFeesPerChannelDayParams {
+
+	msatFilter := func(amt fn.Option[lnwire.MilliSatoshi]) sql.NullInt64 {
+		return fn.MapOptionZ(
+			amt, func(a lnwire.MilliSatoshi) sql.NullInt64 {
+				return sqldb.SQLInt64(int64(a))
+			},
+		)
+	}
+	chanFilter := func(id fn.Option[lnwire.ShortChannelID]) []byte {
+		return fn.MapOptionZ(id, func(s lnwire.ShortChannelID) []byte {
+			return scidBytes(s.ToUint64())
+		})
+	}
+
+	return sqlc.ForwardingFeesPerChannelDayParams{
+		StartTime:      q.StartTime.UnixNano(),
+		EndTime:        q.EndTime.UnixNano(),
+		IncomingChanID: chanFilter(q.IncomingChanID),
+		OutgoingChanID: chanFilter(q.OutgoingChanID),
+		Peer: fn.MapOptionZ(q.Peer, func(v route.Vertex) []byte {
+			return v[:]
+		}),
+		MinAmtMsat: msatFilter(q.MinAmtOut),
+		MaxAmtMsat: msatFilter(q.MaxAmtOut),
+		MinFeeMsat: msatFilter(q.MinFee),
+		MaxFeeMsat: msatFilter(q.MaxFee),
+	}
+}
+
+// insertForwardingEvent inserts the given forwarding event.
+func insertForwardingEvent(ctx context.Context, db SQLForwardingLogQueries,
+	event *ForwardingEvent) error {
+
+	peerBytes := func(peer fn.Option[route.Vertex]) []byte {
+		return fn.MapOptionZ(peer, func(v route.Vertex) []byte {
+			return v[:]
+		})
+	}
+
+	return db.InsertForwardingEvent(ctx, sqlc.InsertForwardingEventParams{
+		TimestampNs:    event.Timestamp.UnixNano(),
+		IncomingChanID: scidBytes(event.IncomingChanID.ToUint64()),
+		OutgoingChanID: scidBytes(event.OutgoingChanID.ToUint64()),
+		IncomingPeer:   peerBytes(event.IncomingPeer),
+		OutgoingPeer:   peerBytes(event.OutgoingPeer),
+		AmtInMsat:      int64(event.AmtIn),
+		AmtOutMsat:     int64(event.AmtOut),
+		FeeMsat:        int64(event.AmtIn) - int64(event.AmtOut),
+	})
+}
+
+// buildForwardingEvent creates a forwarding event from the given database
+// row.
+func buildForwardingEvent(row sqlc.ForwardingEvent) ForwardingEvent {
+	peer := func(b []byte) fn.Option[route.Vertex] {
+		if len(b) != len(route.Vertex{}) {
+			return fn.None[route.Vertex]()
+		}
+
+		var v route.Vertex
+		copy(v[:], b)
+
+		return fn.Some(v)
+	}
+
+	return ForwardingEvent{
+		Timestamp: time.Unix(0, row.TimestampNs),
+		IncomingChanID: lnwire.NewShortChanIDFromInt(
+			byteOrder.Uint64(row.IncomingChanID),
+		),
+		OutgoingChanID: lnwire.NewShortChanIDFromInt(
+			byteOrder.Uint64(row.OutgoingChanID),
+		),
+		AmtIn:        lnwire.MilliSatoshi(row.AmtInMsat),
+		AmtOut:       lnwire.MilliSatoshi(row.AmtOutMsat),
+		IncomingPeer: peer(row.IncomingPeer),
+		OutgoingPeer: peer(row.OutgoingPeer),
+	}
+}
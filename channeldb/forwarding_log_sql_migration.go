@@ -0,0 +1,179 @@
+package channeldb
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	// fwdLogSQLMigratedKey is the key within the meta bucket that is set
+	// once the kv forwarding log has been migrated to the SQL forwarding
+	// log.
+	fwdLogSQLMigratedKey = []byte("forwarding-log-sql-migrated")
+)
+
+// channelPeers returns the peers of all open and closed channels of the given
+// database, keyed by the short channel ids of the channels.
+func channelPeers(
+	c *ChannelStateDB) (map[lnwire.ShortChannelID]route.Vertex, error) {
+
+	peers := make(map[lnwire.ShortChannelID]route.Vertex)
+
+	openChannels, err := c.FetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range openChannels {
+		peer := route.NewVertex(channel.IdentityPub)
+
+		peers[channel.ShortChanID()] = peer
+		if channel.IsZeroConf() {
+			peers[channel.ZeroConfRealScid()] = peer
+		}
+	}
+
+	closedChannels, err := c.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range closedChannels {
+		if channel.RemotePub == nil {
+			continue
+		}
+
+		peers[channel.ShortChanID] = route.NewVertex(channel.RemotePub)
+	}
+
+	return peers, nil
+}
+
+// migrateForwardingLogToSQL copies all events of the kv forwarding log of the
+// given database into the given SQL store in a single transaction. The kv log
+// doesn't record the peers of the events, so they are looked up from the open
+// and closed channels of the database where possible. Once done, a marker is
+// written to the kv database so that the migration only runs once. An empty
+// log isn't migrated. The kv log itself is left untouched.
+func migrateForwardingLogToSQL(db *DB, store *SQLForwardingLogStore) error {
+	pending, err := pendingNativeSQLMigration(
+		db, forwardingLogBucket, metaBucket, fwdLogSQLMigratedKey,
+	)
+	if err != nil {
+		return err
+	}
+	if !pending {
+		return nil
+	}
+
+	log.Infof("Migrating forwarding log to SQL store")
+	startTime := time.Now()
+
+	peers, err := channelPeers(db.ChannelStateDB())
+	if err != nil {
+		return err
+	}
+	lookupPeer := func(
+		chanID lnwire.ShortChannelID) fn.Option[route.Vertex] {
+
+		peer, ok := peers[chanID]
+		if !ok {
+			return fn.None[route.Vertex]()
+		}
+
+		return fn.Some(peer)
+	}
+
+	var (
+		ctx         = context.Background()
+		writeTxOpts SQLForwardingLogQueriesTxOptions
+		numEvents   int
+	)
+	err = store.db.ExecTx(ctx, &writeTxOpts,
+		func(q SQLForwardingLogQueries) error {
+			return kvdb.View(db, func(tx kvdb.RTx) error {
+				logBucket := tx.ReadBucket(forwardingLogBucket)
+				if logBucket == nil {
+					return nil
+				}
+
+				var err error
+				numEvents, err = migrateForwardingEvents(
+					ctx, q, logBucket, lookupPeer,
+				)
+
+				return err
+			}, func() {
+				numEvents = 0
+			})
+		}, func() {
+			numEvents = 0
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = kvdb.Update(db, func(tx kvdb.RwTx) error {
+		meta, err := tx.CreateTopLevelBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		return meta.Put(fwdLogSQLMigratedKey, []byte{})
+	}, func() {})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Migrated %d forwarding events to SQL store (took %v)",
+		numEvents, time.Since(startTime))
+
+	return nil
+}
+
+// migrateForwardingEvents inserts all events of the given kv forwarding log
+// bucket into the SQL forwarding log and returns the number of events that
+// were migrated. The peers of the events are set with the given lookup
+// function.
+func migrateForwardingEvents(ctx context.Context, q SQLForwardingLogQueries,
+	logBucket kvdb.RBucket,
+	lookupPeer func(lnwire.ShortChannelID) fn.Option[route.Vertex]) (int,
+	error) {
+
+	var numEvents int
+	err := logBucket.ForEach(func(k, v []byte) error {
+		timestamp := time.Unix(0, int64(byteOrder.Uint64(k)))
+
+		// Each key may hold several events that were settled at the
+		// same time.
+		r := bytes.NewReader(v)
+		for r.Len() != 0 {
+			var event ForwardingEvent
+			if err := decodeForwardingEvent(r, &event); err != nil {
+				return err
+			}
+
+			event.Timestamp = timestamp
+			event.IncomingPeer = lookupPeer(event.IncomingChanID)
+			event.OutgoingPeer = lookupPeer(event.OutgoingChanID)
+
+			err := insertForwardingEvent(ctx, q, &event)
+			if err != nil {
+				return err
+			}
+			numEvents++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return numEvents, nil
+}
@@ -0,0 +1,401 @@
+package channeldb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTestSQLForwardingLogStore creates a new SQL forwarding log store backed
+// by a fresh sqlite database.
+func makeTestSQLForwardingLogStore(t *testing.T) *SQLForwardingLogStore {
+	db := sqldb.NewTestSqliteDB(t).BaseDB
+
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) SQLForwardingLogQueries {
+			return db.WithTx(tx)
+		},
+	)
+
+	return NewSQLForwardingLogStore(executor)
+}
+
+// TestSQLForwardingLogQuery tests that the events of the SQL forwarding log
+// can be paginated and filtered by channel, peer, amount and fee.
+func TestSQLForwardingLogQuery(t *testing.T) {
+	t.Parallel()
+
+	db, err := MakeTestDB(
+		t, OptionSetForwardingLogSQLStore(
+			makeTestSQLForwardingLogStore(t),
+		),
+	)
+	require.NoError(t, err)
+	fwdLog := db.ForwardingLog()
+
+	var (
+		chan1 = lnwire.NewShortChanIDFromInt(1)
+		chan2 = lnwire.NewShortChanIDFromInt(2)
+		chan3 = lnwire.NewShortChanIDFromInt(3)
+		peer1 = route.Vertex{1}
+		peer2 = route.Vertex{2}
+	)
+
+	// Forward from the first to the second channel and back, with a fee
+	// that grows with each event. The third channel has no known peer.
+	startTime := time.Unix(1000, 0)
+	events := make([]ForwardingEvent, 0, 6)
+	for i := 0; i < 6; i++ {
+		offset := time.Duration(i) * time.Minute
+		event := ForwardingEvent{
+			Timestamp:      startTime.Add(offset),
+			IncomingChanID: chan1,
+			OutgoingChanID: chan2,
+			AmtIn:          lnwire.MilliSatoshi(1000*(i+1) + i),
+			AmtOut:         lnwire.MilliSatoshi(1000 * (i + 1)),
+			IncomingPeer:   fn.Some(peer1),
+			OutgoingPeer:   fn.Some(peer2),
+		}
+		switch i {
+		case 3, 4:
+			event.IncomingChanID = chan2
+			event.OutgoingChanID = chan1
+			event.IncomingPeer = fn.Some(peer2)
+			event.OutgoingPeer = fn.Some(peer1)
+
+		case 5:
+			event.OutgoingChanID = chan3
+			event.OutgoingPeer = fn.None[route.Vertex]()
+		}
+
+		events = append(events, event)
+	}
+	require.NoError(t, fwdLog.AddForwardingEvents(events))
+
+	endTime := startTime.Add(time.Hour)
+	msat := func(amt lnwire.MilliSatoshi) fn.Option[lnwire.MilliSatoshi] {
+		return fn.Some(amt)
+	}
+
+	tests := []struct {
+		name    string
+		query   ForwardingEventQuery
+		indices []int
+	}{
+		{
+			name:    "all events",
+			query:   ForwardingEventQuery{},
+			indices: []int{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name: "pagination",
+			query: ForwardingEventQuery{
+				IndexOffset:  2,
+				NumMaxEvents: 2,
+			},
+			indices: []int{2, 3},
+		},
+		{
+			name: "time range",
+			query: ForwardingEventQuery{
+				StartTime: startTime.Add(time.Minute),
+				EndTime:   startTime.Add(2 * time.Minute),
+			},
+			indices: []int{1, 2},
+		},
+		{
+			name: "incoming channel",
+			query: ForwardingEventQuery{
+				IncomingChanID: fn.Some(chan2),
+			},
+			indices: []int{3, 4},
+		},
+		{
+			name: "outgoing channel",
+			query: ForwardingEventQuery{
+				OutgoingChanID: fn.Some(chan3),
+			},
+			indices: []int{5},
+		},
+		{
+			name: "peer on either side",
+			query: ForwardingEventQuery{
+				Peer: fn.Some(peer2),
+			},
+			indices: []int{0, 1, 2, 3, 4},
+		},
+		{
+			name: "amount range",
+			query: ForwardingEventQuery{
+				MinAmtOut: msat(2000),
+				MaxAmtOut: msat(4000),
+			},
+			indices: []int{1, 2, 3},
+		},
+		{
+			name: "fee range",
+			query: ForwardingEventQuery{
+				MinFee: msat(4),
+				MaxFee: msat(5),
+			},
+			indices: []int{4, 5},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			query := tc.query
+			if query.StartTime.IsZero() {
+				query.StartTime = startTime
+			}
+			if query.EndTime.IsZero() {
+				query.EndTime = endTime
+			}
+			if query.NumMaxEvents == 0 {
+				query.NumMaxEvents = 100
+			}
+
+			resp, err := fwdLog.Query(query)
+			require.NoError(t, err)
+
+			expected := make([]ForwardingEvent, 0, len(tc.indices))
+			for _, i := range tc.indices {
+				expected = append(expected, events[i])
+			}
+
+			require.Len(t, resp.ForwardingEvents, len(expected))
+			for i, event := range resp.ForwardingEvents {
+				require.True(
+					t, expected[i].Timestamp.Equal(
+						event.Timestamp,
+					),
+				)
+				event.Timestamp = expected[i].Timestamp
+				require.Equal(t, expected[i], event)
+			}
+
+			require.Equal(
+				t, query.IndexOffset+uint32(len(expected)),
+				resp.LastIndexOffset,
+			)
+		})
+	}
+}
+
+// TestSQLForwardingLogFeeSummaries tests that the SQL forwarding log sums up
+// the fees per outgoing channel per day.
+func TestSQLForwardingLogFeeSummaries(t *testing.T) {
+	t.Parallel()
+
+	db, err := MakeTestDB(
+		t, OptionSetForwardingLogSQLStore(
+			makeTestSQLForwardingLogStore(t),
+		),
+	)
+	require.NoError(t, err)
+	fwdLog := db.ForwardingLog()
+
+	var (
+		chan1 = lnwire.NewShortChanIDFromInt(1)
+		chan2 = lnwire.NewShortChanIDFromInt(2)
+		day1  = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		day2  = day1.Add(24 * time.Hour)
+	)
+
+	newEvent := func(ts time.Time, outChan lnwire.ShortChannelID,
+		amt, fee lnwire.MilliSatoshi) ForwardingEvent {
+
+		return ForwardingEvent{
+			Timestamp:      ts,
+			IncomingChanID: lnwire.NewShortChanIDFromInt(100),
+			OutgoingChanID: outChan,
+			AmtIn:          amt + fee,
+			AmtOut:         amt,
+		}
+	}
+
+	events := []ForwardingEvent{
+		newEvent(day1.Add(time.Hour), chan1, 1000, 1),
+		newEvent(day1.Add(2*time.Hour), chan1, 2000, 2),
+		newEvent(day1.Add(3*time.Hour), chan2, 3000, 3),
+		newEvent(day2.Add(time.Hour), chan1, 4000, 4),
+	}
+	require.NoError(t, fwdLog.AddForwardingEvents(events))
+
+	summaries, err := fwdLog.FeeSummaries(ForwardingEventQuery{
+		StartTime: day1,
+		EndTime:   day2.Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, summaries, 3)
+
+	expected := []ChannelFeeSummary{
+		{
+			ChanID:      chan1,
+			NumForwards: 2,
+			AmtIn:       3003,
+			AmtOut:      3000,
+			Fee:         3,
+		},
+		{
+			ChanID:      chan2,
+			NumForwards: 1,
+			AmtIn:       3003,
+			AmtOut:      3000,
+			Fee:         3,
+		},
+		{
+			ChanID:      chan1,
+			NumForwards: 1,
+			AmtIn:       4004,
+			AmtOut:      4000,
+			Fee:         4,
+		},
+	}
+	days := []time.Time{day1, day1, day2}
+	for i, summary := range summaries {
+		require.True(t, days[i].Equal(summary.Day))
+
+		summary.Day = time.Time{}
+		require.Equal(t, expected[i], summary)
+	}
+
+	// The filters of the query apply to the summaries as well.
+	summaries, err = fwdLog.FeeSummaries(ForwardingEventQuery{
+		StartTime:      day1,
+		EndTime:        day2.Add(24 * time.Hour),
+		OutgoingChanID: fn.Some(chan2),
+	})
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	require.Equal(t, chan2, summaries[0].ChanID)
+}
+
+// TestForwardingLogFiltersRequireSQL tests that the kv forwarding log refuses
+// queries it can't answer.
+func TestForwardingLogFiltersRequireSQL(t *testing.T) {
+	t.Parallel()
+
+	db, err := MakeTestDB(t)
+	require.NoError(t, err)
+	fwdLog := db.ForwardingLog()
+
+	_, err = fwdLog.Query(ForwardingEventQuery{
+		EndTime:      time.Now(),
+		NumMaxEvents: 10,
+		Peer:         fn.Some(route.Vertex{1}),
+	})
+	require.ErrorIs(t, err, ErrForwardingLogFilters)
+
+	_, err = fwdLog.FeeSummaries(ForwardingEventQuery{
+		EndTime: time.Now(),
+	})
+	require.ErrorIs(t, err, ErrForwardingLogFilters)
+}
+
+// TestSQLForwardingLogMigration tests that the kv forwarding log is migrated
+// to the SQL forwarding log, and that the peers of the events are looked up
+// from the channels of the database.
+func TestSQLForwardingLogMigration(t *testing.T) {
+	t.Parallel()
+
+	kvDB, err := MakeTestDB(t)
+	require.NoError(t, err)
+
+	channel := createTestChannel(
+		t, kvDB.ChannelStateDB(), openChannelOption(),
+	)
+	unknownChan := lnwire.NewShortChanIDFromInt(
+		channel.ShortChanID().ToUint64() + 1,
+	)
+
+	timestamp := time.Unix(1000, 0)
+	events := []ForwardingEvent{
+		{
+			Timestamp:      timestamp,
+			IncomingChanID: channel.ShortChanID(),
+			OutgoingChanID: unknownChan,
+			AmtIn:          2000,
+			AmtOut:         1000,
+		},
+		{
+			Timestamp:      timestamp.Add(time.Second),
+			IncomingChanID: unknownChan,
+			OutgoingChanID: channel.ShortChanID(),
+			AmtIn:          4000,
+			AmtOut:         3000,
+		},
+	}
+	require.NoError(t, kvDB.ForwardingLog().AddForwardingEvents(events))
+
+	// The database can't be opened with a SQL forwarding log before the
+	// kv events were migrated.
+	store := makeTestSQLForwardingLogStore(t)
+	err = checkNativeSQLMigrated(
+		kvDB.Backend, &Options{fwdLogSQLStore: store},
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationRequired)
+
+	// Migrate the kv events with the explicit migration step.
+	_, err = CreateWithBackend(
+		kvDB.Backend, OptionSetForwardingLogSQLStore(store),
+		OptionMigrateNativeSQL(true),
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationOK)
+
+	db, err := CreateWithBackend(
+		kvDB.Backend, OptionSetForwardingLogSQLStore(store),
+	)
+	require.NoError(t, err)
+
+	query := ForwardingEventQuery{
+		StartTime:    timestamp,
+		EndTime:      timestamp.Add(time.Minute),
+		NumMaxEvents: 10,
+	}
+	resp, err := db.ForwardingLog().Query(query)
+	require.NoError(t, err)
+	require.Len(t, resp.ForwardingEvents, 2)
+
+	peer := route.NewVertex(channel.IdentityPub)
+	events[0].IncomingPeer = fn.Some(peer)
+	events[1].OutgoingPeer = fn.Some(peer)
+	for i, event := range resp.ForwardingEvents {
+		require.True(t, events[i].Timestamp.Equal(event.Timestamp))
+		event.Timestamp = events[i].Timestamp
+		require.Equal(t, events[i], event)
+	}
+
+	// The migration only runs once, so an event that is only added to the
+	// SQL log survives migrating again and re-opening the database.
+	err = db.ForwardingLog().AddForwardingEvents([]ForwardingEvent{{
+		Timestamp:      timestamp.Add(2 * time.Second),
+		IncomingChanID: unknownChan,
+		OutgoingChanID: unknownChan,
+	}})
+	require.NoError(t, err)
+
+	_, err = CreateWithBackend(
+		kvDB.Backend, OptionSetForwardingLogSQLStore(store),
+		OptionMigrateNativeSQL(true),
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationOK)
+
+	db, err = CreateWithBackend(
+		kvDB.Backend, OptionSetForwardingLogSQLStore(store),
+	)
+	require.NoError(t, err)
+
+	resp, err = db.ForwardingLog().Query(query)
+	require.NoError(t, err)
+	require.Len(t, resp.ForwardingEvents, 3)
+}
@@ -2,6 +2,7 @@ package channeldb
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
@@ -194,13 +195,32 @@ type ChannelGraph struct {
 
 	chanScheduler batch.Scheduler
 	nodeScheduler batch.Scheduler
+
+	// sqlStore is an optional SQL backed store of the graph. If it is set,
+	// then all graph reads and writes go to the SQL store instead of the
+	// kv buckets.
+	sqlStore *SQLGraphStore
+}
+
+// GraphOption is a functional option that can be used to modify a
+// ChannelGraph.
+type GraphOption func(*ChannelGraph)
+
+// WithSQLStore makes the ChannelGraph store the graph in the given SQL store
+// instead of the kv buckets. Any graph found in the kv buckets must be migrated
+// to the SQL store with the explicit migration first.
+func WithSQLStore(store *SQLGraphStore) GraphOption {
+	return func(g *ChannelGraph) {
+		g.sqlStore = store
+	}
 }
 
 // NewChannelGraph allocates a new ChannelGraph backed by a DB instance. The
 // returned instance has its own unique reject cache and channel cache.
 func NewChannelGraph(db kvdb.Backend, rejectCacheSize, chanCacheSize int,
 	batchCommitInterval time.Duration, preAllocCacheNumNodes int,
-	useGraphCache, noMigrations bool) (*ChannelGraph, error) {
+	useGraphCache, noMigrations bool,
+	opts ...GraphOption) (*ChannelGraph, error) {
 
 	if !noMigrations {
 		if err := initChannelGraph(db); err != nil {
@@ -213,6 +233,10 @@ func NewChannelGraph(db kvdb.Backend, rejectCacheSize, chanCacheSize int,
 		rejectCache: newRejectCache(rejectCacheSize),
 		chanCache:   newChannelCache(chanCacheSize),
 	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
 	g.chanScheduler = batch.NewTimeScheduler(
 		db, &g.cacheMu, batchCommitInterval,
 	)
@@ -333,6 +357,10 @@ var graphTopLevelBuckets = [][]byte{
 // database. The deletion is done in a single transaction, therefore this
 // operation is fully atomic.
 func (c *ChannelGraph) Wipe() error {
+	if c.sqlStore != nil {
+		return c.sqlStore.Wipe(context.Background())
+	}
+
 	err := kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		for _, tlb := range graphTopLevelBuckets {
 			err := tx.DeleteTopLevelBucket(tlb)
@@ -403,7 +431,7 @@ func initChannelGraph(db kvdb.Backend) error {
 // NewPathFindTx returns a new read transaction that can be used for a single
 // path finding session. Will return nil if the graph cache is enabled.
 func (c *ChannelGraph) NewPathFindTx() (kvdb.RTx, error) {
-	if c.graphCache != nil {
+	if c.graphCache != nil || c.sqlStore != nil {
 		return nil, nil
 	}
 
@@ -422,6 +450,10 @@ func (c *ChannelGraph) NewPathFindTx() (kvdb.RTx, error) {
 func (c *ChannelGraph) ForEachChannel(cb func(*models.ChannelEdgeInfo,
 	*models.ChannelEdgePolicy, *models.ChannelEdgePolicy) error) error {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.ForEachChannel(context.Background(), cb)
+	}
+
 	return c.db.View(func(tx kvdb.RTx) error {
 		edges := tx.ReadBucket(edgeBucket)
 		if edges == nil {
@@ -525,6 +557,18 @@ func (c *ChannelGraph) ForEachNodeDirectedChannel(tx kvdb.RTx,
 
 		return cb(directedChannel)
 	}
+
+	if c.sqlStore != nil {
+		return c.sqlStore.ForEachNodeChannel(
+			context.Background(), node,
+			func(e *models.ChannelEdgeInfo, p1,
+				p2 *models.ChannelEdgePolicy) error {
+
+				return dbCallback(nil, e, p1, p2)
+			},
+		)
+	}
+
 	return nodeTraversal(tx, node[:], c.db, dbCallback)
 }
 
@@ -630,6 +674,10 @@ func (c *ChannelGraph) ForEachNodeCached(cb func(node route.Vertex,
 // A channel is disabled when two of the associated ChanelEdgePolicies
 // have their disabled bit on.
 func (c *ChannelGraph) DisabledChannelIDs() ([]uint64, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.DisabledChannelIDs(context.Background())
+	}
+
 	var disabledChanIDs []uint64
 	var chanEdgeFound map[uint64]struct{}
 
@@ -681,6 +729,14 @@ func (c *ChannelGraph) DisabledChannelIDs() ([]uint64, error) {
 func (c *ChannelGraph) ForEachNode(
 	cb func(kvdb.RTx, *LightningNode) error) error {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.ForEachNode(
+			context.Background(), func(node *LightningNode) error {
+				return cb(nil, node)
+			},
+		)
+	}
+
 	traversal := func(tx kvdb.RTx) error {
 		// First grab the nodes bucket which stores the mapping from
 		// pubKey to node information.
@@ -719,6 +775,16 @@ func (c *ChannelGraph) ForEachNode(
 func (c *ChannelGraph) ForEachNodeCacheable(cb func(kvdb.RTx,
 	GraphCacheNode) error) error {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.ForEachNode(
+			context.Background(), func(node *LightningNode) error {
+				return cb(nil, newGraphCacheNode(
+					node.PubKeyBytes, node.Features,
+				))
+			},
+		)
+	}
+
 	traversal := func(tx kvdb.RTx) error {
 		// First grab the nodes bucket which stores the mapping from
 		// pubKey to node information.
@@ -757,6 +823,10 @@ func (c *ChannelGraph) ForEachNodeCacheable(cb func(kvdb.RTx,
 // a path finding algorithm in order to explore the reachability of another
 // node based off the source node.
 func (c *ChannelGraph) SourceNode() (*LightningNode, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.SourceNode(context.Background())
+	}
+
 	var source *LightningNode
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		// First grab the nodes bucket which stores the mapping from
@@ -807,6 +877,10 @@ func (c *ChannelGraph) sourceNode(nodes kvdb.RBucket) (*LightningNode, error) {
 // node is to be used as the center of a star-graph within path finding
 // algorithms.
 func (c *ChannelGraph) SetSourceNode(node *LightningNode) error {
+	if c.sqlStore != nil {
+		return c.sqlStore.SetSourceNode(context.Background(), node)
+	}
+
 	nodePubBytes := node.PubKeyBytes[:]
 
 	return kvdb.Update(c.db, func(tx kvdb.RwTx) error {
@@ -840,6 +914,36 @@ func (c *ChannelGraph) SetSourceNode(node *LightningNode) error {
 func (c *ChannelGraph) AddLightningNode(node *LightningNode,
 	op ...batch.SchedulerOption) error {
 
+	if c.sqlStore != nil {
+		ctx := context.Background()
+		if err := c.sqlStore.AddLightningNode(ctx, node); err != nil {
+			return err
+		}
+
+		if c.graphCache == nil {
+			return nil
+		}
+
+		// The graph cache can't traverse the node's channels through
+		// a kv transaction, so we add the node's features and channels
+		// ourselves.
+		c.graphCache.AddNodeFeatures(
+			newGraphCacheNode(node.PubKeyBytes, node.Features),
+		)
+
+		addChannel := func(info *models.ChannelEdgeInfo,
+			outPolicy, inPolicy *models.ChannelEdgePolicy) error {
+
+			c.graphCache.AddChannel(info, outPolicy, inPolicy)
+
+			return nil
+		}
+
+		return c.sqlStore.ForEachNodeChannel(
+			ctx, node.PubKeyBytes, addChannel,
+		)
+	}
+
 	r := &batch.Request{
 		Update: func(tx kvdb.RwTx) error {
 			if c.graphCache != nil {
@@ -887,6 +991,13 @@ func addLightningNode(tx kvdb.RwTx, node *LightningNode) error {
 // LookupAlias attempts to return the alias as advertised by the target node.
 // TODO(roasbeef): currently assumes that aliases are unique...
 func (c *ChannelGraph) LookupAlias(pub *btcec.PublicKey) (string, error) {
+	if c.sqlStore != nil {
+		var nodePub [33]byte
+		copy(nodePub[:], pub.SerializeCompressed())
+
+		return c.sqlStore.LookupAlias(context.Background(), nodePub)
+	}
+
 	var alias string
 
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
@@ -923,6 +1034,21 @@ func (c *ChannelGraph) LookupAlias(pub *btcec.PublicKey) (string, error) {
 // DeleteLightningNode starts a new database transaction to remove a vertex/node
 // from the database according to the node's public key.
 func (c *ChannelGraph) DeleteLightningNode(nodePub route.Vertex) error {
+	if c.sqlStore != nil {
+		err := c.sqlStore.DeleteLightningNode(
+			context.Background(), nodePub,
+		)
+		if err != nil {
+			return err
+		}
+
+		if c.graphCache != nil {
+			c.graphCache.RemoveNode(nodePub)
+		}
+
+		return nil
+	}
+
 	// TODO(roasbeef): ensure dangling edges are removed...
 	return kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		nodes := tx.ReadWriteBucket(nodeBucket)
@@ -991,6 +1117,24 @@ func (c *ChannelGraph) deleteLightningNode(nodes kvdb.RwBucket,
 func (c *ChannelGraph) AddChannelEdge(edge *models.ChannelEdgeInfo,
 	op ...batch.SchedulerOption) error {
 
+	if c.sqlStore != nil {
+		c.cacheMu.Lock()
+		defer c.cacheMu.Unlock()
+
+		err := c.sqlStore.AddChannelEdge(context.Background(), edge)
+		if err != nil {
+			return err
+		}
+
+		if c.graphCache != nil {
+			c.graphCache.AddChannel(edge, nil, nil)
+		}
+		c.rejectCache.remove(edge.ChannelID)
+		c.chanCache.remove(edge.ChannelID)
+
+		return nil
+	}
+
 	var alreadyExists bool
 	r := &batch.Request{
 		Reset: func() {
@@ -1171,7 +1315,14 @@ func (c *ChannelGraph) HasChannelEdge(
 		return upd1Time, upd2Time, exists, isZombie, nil
 	}
 
-	if err := kvdb.View(c.db, func(tx kvdb.RTx) error {
+	if c.sqlStore != nil {
+		var err error
+		upd1Time, upd2Time, exists, isZombie, err =
+			c.sqlStore.HasChannelEdge(context.Background(), chanID)
+		if err != nil {
+			return time.Time{}, time.Time{}, exists, isZombie, err
+		}
+	} else if err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		edges := tx.ReadBucket(edgeBucket)
 		if edges == nil {
 			return ErrGraphNoEdgesFound
@@ -1245,6 +1396,19 @@ func (c *ChannelGraph) HasChannelEdge(
 // that an edge info hasn't yet been created yet, but someone attempts to update
 // it.
 func (c *ChannelGraph) UpdateChannelEdge(edge *models.ChannelEdgeInfo) error {
+	if c.sqlStore != nil {
+		err := c.sqlStore.UpdateChannelEdge(context.Background(), edge)
+		if err != nil {
+			return err
+		}
+
+		if c.graphCache != nil {
+			c.graphCache.UpdateChannel(edge)
+		}
+
+		return nil
+	}
+
 	// Construct the channel's primary key which is the 8-byte channel ID.
 	var chanKey [8]byte
 	binary.BigEndian.PutUint64(chanKey[:], edge.ChannelID)
@@ -1295,6 +1459,21 @@ func (c *ChannelGraph) PruneGraph(spentOutputs []*wire.OutPoint,
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if c.sqlStore != nil {
+		chansClosed, prunedNodes, err := c.sqlStore.PruneGraph(
+			context.Background(), spentOutputs, blockHash,
+			blockHeight,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		c.removeChannelsFromCacheUnsafe(chansClosed)
+		c.removeNodesFromCacheUnsafe(prunedNodes)
+
+		return chansClosed, nil
+	}
+
 	var chansClosed []*models.ChannelEdgeInfo
 
 	err := kvdb.Update(c.db, func(tx kvdb.RwTx) error {
@@ -1418,6 +1597,21 @@ func (c *ChannelGraph) PruneGraph(spentOutputs []*wire.OutPoint,
 // that we only maintain a graph of reachable nodes. In the event that a pruned
 // node gains more channels, it will be re-added back to the graph.
 func (c *ChannelGraph) PruneGraphNodes() error {
+	if c.sqlStore != nil {
+		prunedNodes, err := c.sqlStore.PruneGraphNodes(
+			context.Background(),
+		)
+		if err != nil {
+			return err
+		}
+
+		c.cacheMu.Lock()
+		c.removeNodesFromCacheUnsafe(prunedNodes)
+		c.cacheMu.Unlock()
+
+		return nil
+	}
+
 	return kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		nodes := tx.ReadWriteBucket(nodeBucket)
 		if nodes == nil {
@@ -1571,6 +1765,19 @@ func (c *ChannelGraph) DisconnectBlockAtHeight(height uint32) (
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if c.sqlStore != nil {
+		removedChans, err := c.sqlStore.DisconnectBlockAtHeight(
+			context.Background(), height,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		c.removeChannelsFromCacheUnsafe(removedChans)
+
+		return removedChans, nil
+	}
+
 	// Keep track of the channels that are removed from the graph.
 	var removedChans []*models.ChannelEdgeInfo
 
@@ -1678,6 +1885,10 @@ func (c *ChannelGraph) DisconnectBlockAtHeight(height uint32) (
 // to tell if the graph is currently in sync with the current best known UTXO
 // state.
 func (c *ChannelGraph) PruneTip() (*chainhash.Hash, uint32, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.PruneTip(context.Background())
+	}
+
 	var (
 		tipHash   chainhash.Hash
 		tipHeight uint32
@@ -1734,6 +1945,20 @@ func (c *ChannelGraph) DeleteChannelEdges(strictZombiePruning, markZombie bool,
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if c.sqlStore != nil {
+		deleted, err := c.sqlStore.DeleteChannelEdges(
+			context.Background(), strictZombiePruning, markZombie,
+			chanIDs...,
+		)
+		if err != nil {
+			return err
+		}
+
+		c.removeChannelsFromCacheUnsafe(deleted)
+
+		return nil
+	}
+
 	err := kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		edges := tx.ReadWriteBucket(edgeBucket)
 		if edges == nil {
@@ -1786,6 +2011,10 @@ func (c *ChannelGraph) DeleteChannelEdges(strictZombiePruning, markZombie bool,
 // passed channel point (outpoint). If the passed channel doesn't exist within
 // the database, then ErrEdgeNotFound is returned.
 func (c *ChannelGraph) ChannelID(chanPoint *wire.OutPoint) (uint64, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.ChannelID(context.Background(), chanPoint)
+	}
+
 	var chanID uint64
 	if err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		var err error
@@ -1832,6 +2061,10 @@ func getChanID(tx kvdb.RTx, chanPoint *wire.OutPoint) (uint64, error) {
 // This represents the "newest" channel from the PoV of the chain. This method
 // can be used by peers to quickly determine if they're graphs are in sync.
 func (c *ChannelGraph) HighestChanID() (uint64, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.HighestChanID(context.Background())
+	}
+
 	var cid uint64
 
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
@@ -1899,6 +2132,12 @@ type ChannelEdge struct {
 func (c *ChannelGraph) ChanUpdatesInHorizon(startTime,
 	endTime time.Time) ([]ChannelEdge, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.ChanUpdatesInHorizon(
+			context.Background(), startTime, endTime,
+		)
+	}
+
 	// To ensure we don't return duplicate ChannelEdges, we'll use an
 	// additional map to keep track of the edges already seen to prevent
 	// re-adding it.
@@ -2049,6 +2288,12 @@ func (c *ChannelGraph) ChanUpdatesInHorizon(startTime,
 func (c *ChannelGraph) NodeUpdatesInHorizon(startTime,
 	endTime time.Time) ([]LightningNode, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.NodeUpdatesInHorizon(
+			context.Background(), startTime, endTime,
+		)
+	}
+
 	var nodesInHorizon []LightningNode
 
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
@@ -2119,6 +2364,24 @@ func (c *ChannelGraph) FilterKnownChanIDs(chansInfo []ChannelUpdateInfo,
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if c.sqlStore != nil {
+		newChanIDs, revivedIDs, err := c.sqlStore.FilterKnownChanIDs(
+			context.Background(), chansInfo, isZombieChan,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chanID := range revivedIDs {
+			err := c.reviveEdgeInCacheUnsafe(nil, chanID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return newChanIDs, nil
+	}
+
 	err := kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		edges := tx.ReadBucket(edgeBucket)
 		if edges == nil {
@@ -2281,6 +2544,13 @@ type BlockChannelRange struct {
 func (c *ChannelGraph) FilterChannelRange(startHeight,
 	endHeight uint32, withTimestamps bool) ([]BlockChannelRange, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.FilterChannelRange(
+			context.Background(), startHeight, endHeight,
+			withTimestamps,
+		)
+	}
+
 	startChanID := &lnwire.ShortChannelID{
 		BlockHeight: startHeight,
 	}
@@ -2435,6 +2705,11 @@ func (c *ChannelGraph) FetchChanInfos(chanIDs []uint64) ([]ChannelEdge, error) {
 // new one will be created.
 func (c *ChannelGraph) fetchChanInfos(tx kvdb.RTx, chanIDs []uint64) (
 	[]ChannelEdge, error) {
+
+	if c.sqlStore != nil {
+		return c.sqlStore.FetchChanInfos(context.Background(), chanIDs)
+	}
+
 	// TODO(roasbeef): sort cids?
 
 	var (
@@ -2563,6 +2838,42 @@ func delEdgeUpdateIndexEntry(edgesBucket kvdb.RwBucket, chanID uint64,
 	return nil
 }
 
+// removeChannelsFromCacheUnsafe removes the given channels from all caches
+// after they were deleted from the SQL store.
+//
+// NOTE: this method MUST only be called if the cacheMu has already been
+// acquired.
+func (c *ChannelGraph) removeChannelsFromCacheUnsafe(
+	infos []*models.ChannelEdgeInfo) {
+
+	for _, info := range infos {
+		if c.graphCache != nil {
+			c.graphCache.RemoveChannel(
+				info.NodeKey1Bytes, info.NodeKey2Bytes,
+				info.ChannelID,
+			)
+		}
+
+		c.rejectCache.remove(info.ChannelID)
+		c.chanCache.remove(info.ChannelID)
+	}
+}
+
+// removeNodesFromCacheUnsafe removes the given nodes from the graph cache
+// after they were pruned from the SQL store.
+//
+// NOTE: this method MUST only be called if the cacheMu has already been
+// acquired.
+func (c *ChannelGraph) removeNodesFromCacheUnsafe(nodes []route.Vertex) {
+	if c.graphCache == nil {
+		return
+	}
+
+	for _, node := range nodes {
+		c.graphCache.RemoveNode(node)
+	}
+}
+
 // delChannelEdgeUnsafe deletes the edge with the given chanID from the graph
 // cache. It then goes on to delete any policy info and edge info for this
 // channel from the DB and finally, if isZombie is true, it will add an entry
@@ -2705,6 +3016,25 @@ func makeZombiePubkeys(info *models.ChannelEdgeInfo,
 func (c *ChannelGraph) UpdateEdgePolicy(edge *models.ChannelEdgePolicy,
 	op ...batch.SchedulerOption) error {
 
+	if c.sqlStore != nil {
+		c.cacheMu.Lock()
+		defer c.cacheMu.Unlock()
+
+		from, to, isUpdate1, err := c.sqlStore.UpdateEdgePolicy(
+			context.Background(), edge,
+		)
+		if err != nil {
+			return err
+		}
+
+		if c.graphCache != nil {
+			c.graphCache.UpdatePolicy(edge, from, to, isUpdate1)
+		}
+		c.updateEdgeCache(edge, isUpdate1)
+
+		return nil
+	}
+
 	var (
 		isUpdate1    bool
 		edgeNotFound bool
@@ -3036,6 +3366,12 @@ func (c *ChannelGraph) FetchLightningNode(nodePub route.Vertex) (*LightningNode,
 func (c *ChannelGraph) fetchLightningNode(tx kvdb.RTx,
 	nodePub route.Vertex) (*LightningNode, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.FetchLightningNode(
+			context.Background(), nodePub,
+		)
+	}
+
 	var node *LightningNode
 	fetch := func(tx kvdb.RTx) error {
 		// First grab the nodes bucket which stores the mapping from
@@ -3136,6 +3472,12 @@ var _ GraphCacheNode = (*graphCacheNode)(nil)
 // with a true boolean. Otherwise, an empty time.Time is returned with a false
 // boolean.
 func (c *ChannelGraph) HasLightningNode(nodePub [33]byte) (time.Time, bool, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.HasLightningNode(
+			context.Background(), nodePub,
+		)
+	}
+
 	var (
 		updateTime time.Time
 		exists     bool
@@ -3275,7 +3617,7 @@ func (c *ChannelGraph) ForEachNodeChannel(nodePub route.Vertex,
 	cb func(kvdb.RTx, *models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
 		*models.ChannelEdgePolicy) error) error {
 
-	return nodeTraversal(nil, nodePub[:], c.db, cb)
+	return c.ForEachNodeChannelTx(nil, nodePub, cb)
 }
 
 // ForEachNodeChannelTx iterates through all channels of the given node,
@@ -3296,6 +3638,17 @@ func (c *ChannelGraph) ForEachNodeChannelTx(tx kvdb.RTx,
 		*models.ChannelEdgePolicy,
 		*models.ChannelEdgePolicy) error) error {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.ForEachNodeChannel(
+			context.Background(), nodePub,
+			func(info *models.ChannelEdgeInfo, p1,
+				p2 *models.ChannelEdgePolicy) error {
+
+				return cb(nil, info, p1, p2)
+			},
+		)
+	}
+
 	return nodeTraversal(tx, nodePub[:], c.db, cb)
 }
 
@@ -3318,6 +3671,12 @@ func (c *ChannelGraph) FetchOtherNode(tx kvdb.RTx,
 		return nil, fmt.Errorf("node not participating in this channel")
 	}
 
+	if c.sqlStore != nil {
+		return c.sqlStore.FetchLightningNode(
+			context.Background(), targetNodeBytes,
+		)
+	}
+
 	var targetNode *LightningNode
 	fetchNodeFunc := func(tx kvdb.RTx) error {
 		// First grab the nodes bucket which stores the mapping from
@@ -3376,6 +3735,12 @@ func (c *ChannelGraph) FetchChannelEdgesByOutpoint(op *wire.OutPoint) (
 	*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
 	*models.ChannelEdgePolicy, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.FetchChannelEdgesByOutpoint(
+			context.Background(), op,
+		)
+	}
+
 	var (
 		edgeInfo *models.ChannelEdgeInfo
 		policy1  *models.ChannelEdgePolicy
@@ -3461,6 +3826,12 @@ func (c *ChannelGraph) FetchChannelEdgesByID(chanID uint64) (
 	*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
 	*models.ChannelEdgePolicy, error) {
 
+	if c.sqlStore != nil {
+		return c.sqlStore.FetchChannelEdgesByID(
+			context.Background(), chanID,
+		)
+	}
+
 	var (
 		edgeInfo  *models.ChannelEdgeInfo
 		policy1   *models.ChannelEdgePolicy
@@ -3560,6 +3931,10 @@ func (c *ChannelGraph) FetchChannelEdgesByID(chanID uint64) (
 // given public key is seen as a public node in the graph from the graph's
 // source node's point of view.
 func (c *ChannelGraph) IsPublicNode(pubKey [33]byte) (bool, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.IsPublicNode(context.Background(), pubKey)
+	}
+
 	var nodeIsPublic bool
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		nodes := tx.ReadBucket(nodeBucket)
@@ -3630,6 +4005,10 @@ func (e *EdgePoint) String() string {
 // returned are the ones that need to be watched on chain to detect channel
 // closes on the resident blockchain.
 func (c *ChannelGraph) ChannelView() ([]EdgePoint, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.ChannelView(context.Background())
+	}
+
 	var edgePoints []EdgePoint
 	if err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		// We're going to iterate over the entire channel index, so
@@ -3700,6 +4079,23 @@ func (c *ChannelGraph) MarkEdgeZombie(chanID uint64,
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if c.sqlStore != nil {
+		err := c.sqlStore.MarkEdgeZombie(
+			context.Background(), chanID, pubKey1, pubKey2,
+		)
+		if err != nil {
+			return err
+		}
+
+		if c.graphCache != nil {
+			c.graphCache.RemoveChannel(pubKey1, pubKey2, chanID)
+		}
+		c.rejectCache.remove(chanID)
+		c.chanCache.remove(chanID)
+
+		return nil
+	}
+
 	err := kvdb.Batch(c.db, func(tx kvdb.RwTx) error {
 		edges := tx.ReadWriteBucket(edgeBucket)
 		if edges == nil {
@@ -3781,15 +4177,31 @@ func (c *ChannelGraph) markEdgeLiveUnsafe(tx kvdb.RwTx, chanID uint64) error {
 	// If the transaction is nil, we'll create a new one. Otherwise, we use
 	// the existing transaction
 	var err error
-	if tx == nil {
+	switch {
+	case c.sqlStore != nil:
+		err = c.sqlStore.MarkEdgeLive(context.Background(), chanID)
+
+	case tx == nil:
 		err = kvdb.Update(c.db, dbFn, func() {})
-	} else {
+
+	default:
 		err = dbFn(tx)
 	}
 	if err != nil {
 		return err
 	}
 
+	return c.reviveEdgeInCacheUnsafe(tx, chanID)
+}
+
+// reviveEdgeInCacheUnsafe updates the caches after the edge with the given
+// channel ID was removed from the zombie index.
+//
+// NOTE: this method MUST only be called if the cacheMu has already been
+// acquired.
+func (c *ChannelGraph) reviveEdgeInCacheUnsafe(tx kvdb.RTx,
+	chanID uint64) error {
+
 	c.rejectCache.remove(chanID)
 	c.chanCache.remove(chanID)
 
@@ -3816,6 +4228,17 @@ func (c *ChannelGraph) markEdgeLiveUnsafe(tx kvdb.RwTx, chanID uint64) error {
 // zombie, then the two node public keys corresponding to this edge are also
 // returned.
 func (c *ChannelGraph) IsZombieEdge(chanID uint64) (bool, [33]byte, [33]byte) {
+	if c.sqlStore != nil {
+		isZombie, pubKey1, pubKey2, err := c.sqlStore.IsZombieEdge(
+			context.Background(), chanID,
+		)
+		if err != nil {
+			return false, [33]byte{}, [33]byte{}
+		}
+
+		return isZombie, pubKey1, pubKey2
+	}
+
 	var (
 		isZombie         bool
 		pubKey1, pubKey2 [33]byte
@@ -3868,6 +4291,10 @@ func isZombieEdge(zombieIndex kvdb.RBucket,
 
 // NumZombies returns the current number of zombie channels in the graph.
 func (c *ChannelGraph) NumZombies() (uint64, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.NumZombies(context.Background())
+	}
+
 	var numZombies uint64
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		edges := tx.ReadBucket(edgeBucket)
@@ -3897,6 +4324,10 @@ func (c *ChannelGraph) NumZombies() (uint64, error) {
 // that we can ignore channel announcements that we know to be closed without
 // having to validate them and fetch a block.
 func (c *ChannelGraph) PutClosedScid(scid lnwire.ShortChannelID) error {
+	if c.sqlStore != nil {
+		return c.sqlStore.PutClosedScid(context.Background(), scid)
+	}
+
 	return kvdb.Update(c.db, func(tx kvdb.RwTx) error {
 		closedScids, err := tx.CreateTopLevelBucket(closedScidBucket)
 		if err != nil {
@@ -3914,6 +4345,10 @@ func (c *ChannelGraph) PutClosedScid(scid lnwire.ShortChannelID) error {
 // closed. This helps avoid having to perform expensive validation checks.
 // TODO: Add an LRU cache to cut down on disc reads.
 func (c *ChannelGraph) IsClosedScid(scid lnwire.ShortChannelID) (bool, error) {
+	if c.sqlStore != nil {
+		return c.sqlStore.IsClosedScid(context.Background(), scid)
+	}
+
 	var isClosed bool
 	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
 		closedScids := tx.ReadBucket(closedScidBucket)
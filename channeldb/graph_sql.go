@@ -0,0 +1,2338 @@
+package channeldb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image/color"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/aliasmgr"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+)
+
+const (
+	// graphQueryPaginationLimit is used in the LIMIT clause of the graph
+	// SQL queries that iterate over all nodes or channels.
+	graphQueryPaginationLimit = 100
+)
+
+// SQLGraphQueries is an interface that defines the set of operations that can
+// be executed against the channel graph SQL database.
+type SQLGraphQueries interface { //nolint:interfacebloat
+	// Node specific methods.
+	UpsertNode(ctx context.Context, arg sqlc.UpsertNodeParams) (int64,
+		error)
+
+	GetNodeByPubKey(ctx context.Context, pubKey []byte) (sqlc.GraphNode,
+		error)
+
+	GetNodeIDByPubKey(ctx context.Context, pubKey []byte) (int64, error)
+
+	ListNodesPaginated(ctx context.Context,
+		arg sqlc.ListNodesPaginatedParams) ([]sqlc.GraphNode, error)
+
+	GetNodesByLastUpdateRange(ctx context.Context,
+		arg sqlc.GetNodesByLastUpdateRangeParams) ([]sqlc.GraphNode,
+		error)
+
+	DeleteNodeByPubKey(ctx context.Context, pubKey []byte) (sql.Result,
+		error)
+
+	DeleteUnconnectedNodes(ctx context.Context) ([][]byte, error)
+
+	DeleteAllNodes(ctx context.Context) error
+
+	InsertNodeFeature(ctx context.Context,
+		arg sqlc.InsertNodeFeatureParams) error
+
+	GetNodeFeatures(ctx context.Context,
+		nodeID int64) ([]sqlc.GraphNodeFeature, error)
+
+	DeleteNodeFeatures(ctx context.Context, nodeID int64) error
+
+	InsertNodeAddress(ctx context.Context,
+		arg sqlc.InsertNodeAddressParams) error
+
+	GetNodeAddresses(ctx context.Context,
+		nodeID int64) ([]sqlc.GraphNodeAddress, error)
+
+	DeleteNodeAddresses(ctx context.Context, nodeID int64) error
+
+	// Source node specific methods.
+	AddSourceNode(ctx context.Context, nodeID int64) error
+
+	GetSourceNodes(ctx context.Context) ([][]byte, error)
+
+	DeleteSourceNodes(ctx context.Context) error
+
+	// Channel specific methods.
+	CreateChannel(ctx context.Context, arg sqlc.CreateChannelParams) (int64,
+		error)
+
+	UpdateChannel(ctx context.Context, arg sqlc.UpdateChannelParams) error
+
+	GetChannelBySCID(ctx context.Context,
+		scid []byte) (sqlc.GetChannelBySCIDRow, error)
+
+	GetChannelByOutpoint(ctx context.Context,
+		outpoint string) (sqlc.GetChannelByOutpointRow, error)
+
+	ListChannelsPaginated(ctx context.Context,
+		arg sqlc.ListChannelsPaginatedParams) (
+		[]sqlc.ListChannelsPaginatedRow, error)
+
+	ListChannelsByNodeID(ctx context.Context,
+		nodeID int64) ([]sqlc.ListChannelsByNodeIDRow, error)
+
+	GetChannelsBySCIDRange(ctx context.Context,
+		arg sqlc.GetChannelsBySCIDRangeParams) (
+		[]sqlc.GetChannelsBySCIDRangeRow, error)
+
+	GetChannelsByPolicyLastUpdateRange(ctx context.Context,
+		arg sqlc.GetChannelsByPolicyLastUpdateRangeParams) (
+		[]sqlc.GetChannelsByPolicyLastUpdateRangeRow, error)
+
+	GetPublicChannelUpdateTimesBySCIDRange(ctx context.Context,
+		arg sqlc.GetPublicChannelUpdateTimesBySCIDRangeParams) (
+		[]sqlc.GetPublicChannelUpdateTimesBySCIDRangeRow, error)
+
+	GetDisabledChannelSCIDs(ctx context.Context) ([][]byte, error)
+
+	HighestSCID(ctx context.Context) ([]byte, error)
+
+	DeleteChannel(ctx context.Context, id int64) error
+
+	DeleteAllChannels(ctx context.Context) error
+
+	// Channel policy specific methods.
+	UpsertChannelPolicy(ctx context.Context,
+		arg sqlc.UpsertChannelPolicyParams) error
+
+	GetChannelPolicies(ctx context.Context,
+		channelID int64) ([]sqlc.GraphChannelPolicy, error)
+
+	ListChannelPoliciesByChannelIDRange(ctx context.Context,
+		arg sqlc.ListChannelPoliciesByChannelIDRangeParams) (
+		[]sqlc.GraphChannelPolicy, error)
+
+	// Zombie index specific methods.
+	UpsertZombieChannel(ctx context.Context,
+		arg sqlc.UpsertZombieChannelParams) error
+
+	GetZombieChannel(ctx context.Context,
+		scid []byte) (sqlc.GraphZombieChannel, error)
+
+	DeleteZombieChannel(ctx context.Context, scid []byte) (sql.Result,
+		error)
+
+	CountZombieChannels(ctx context.Context) (int64, error)
+
+	DeleteAllZombieChannels(ctx context.Context) error
+
+	// Closed SCID specific methods.
+	InsertClosedSCID(ctx context.Context, scid []byte) error
+
+	IsClosedSCID(ctx context.Context, scid []byte) (bool, error)
+
+	DeleteAllClosedSCIDs(ctx context.Context) error
+
+	// Prune log specific methods.
+	UpsertPruneLogEntry(ctx context.Context,
+		arg sqlc.UpsertPruneLogEntryParams) error
+
+	GetPruneTip(ctx context.Context) (sqlc.GraphPruneLog, error)
+
+	DeletePruneLogEntriesFromHeight(ctx context.Context,
+		blockHeight int64) error
+
+	DeleteAllPruneLogEntries(ctx context.Context) error
+}
+
+// SQLGraphQueriesTxOptions defines the set of db txn options the
+// SQLGraphQueries understands.
+type SQLGraphQueriesTxOptions struct {
+	// readOnly governs if a read only transaction is needed or not.
+	readOnly bool
+}
+
+// ReadOnly returns true if the transaction should be read only.
+//
+// NOTE: This implements the TxOptions.
+func (a *SQLGraphQueriesTxOptions) ReadOnly() bool {
+	return a.readOnly
+}
+
+// NewSQLGraphQueryReadTx creates a new read transaction option set.
+func NewSQLGraphQueryReadTx() SQLGraphQueriesTxOptions {
+	return SQLGraphQueriesTxOptions{
+		readOnly: true,
+	}
+}
+
+// BatchedSQLGraphQueries is a version of the SQLGraphQueries that's capable of
+// batched database operations.
+type BatchedSQLGraphQueries interface {
+	SQLGraphQueries
+
+	sqldb.BatchedTx[SQLGraphQueries]
+}
+
+// SQLGraphStore is a SQL backed store of the channel graph. Nodes, channels,
+// policies, the zombie index, the closed SCIDs and the prune log each live in
+// their own indexed tables, so that lookups don't require a full scan of the
+// graph.
+type SQLGraphStore struct {
+	db BatchedSQLGraphQueries
+}
+
+// NewSQLGraphStore creates a new SQLGraphStore instance given an open
+// BatchedSQLGraphQueries storage backend.
+func NewSQLGraphStore(db BatchedSQLGraphQueries) *SQLGraphStore {
+	return &SQLGraphStore{
+		db: db,
+	}
+}
+
+// AddLightningNode inserts the given node into the graph, or updates it if a
+// node with the same public key already exists.
+func (s *SQLGraphStore) AddLightningNode(ctx context.Context,
+	node *LightningNode) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		_, err := upsertNode(ctx, db, node)
+
+		return err
+	}, func() {})
+}
+
+// SetSourceNode inserts or updates the given node and marks it as the source
+// node of the graph.
+func (s *SQLGraphStore) SetSourceNode(ctx context.Context,
+	node *LightningNode) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		return setSourceNode(ctx, db, node)
+	}, func() {})
+}
+
+// SourceNode returns the source node of the graph. ErrSourceNodeNotSet is
+// returned if no source node has been set yet.
+func (s *SQLGraphStore) SourceNode(ctx context.Context) (*LightningNode,
+	error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		source     *LightningNode
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		source, err = getSourceNode(ctx, db)
+
+		return err
+	}, func() {
+		source = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// FetchLightningNode returns the node with the given public key. If the node
+// isn't known, then ErrGraphNodeNotFound is returned.
+func (s *SQLGraphStore) FetchLightningNode(ctx context.Context,
+	nodePub route.Vertex) (*LightningNode, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		node       *LightningNode
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		node, err = getNode(ctx, db, nodePub[:])
+
+		return err
+	}, func() {
+		node = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// HasLightningNode returns the last update time of the node with the given
+// public key along with true if the node is known, and false otherwise.
+func (s *SQLGraphStore) HasLightningNode(ctx context.Context,
+	nodePub [33]byte) (time.Time, bool, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		updateTime time.Time
+		exists     bool
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbNode, err := db.GetNodeByPubKey(ctx, nodePub[:])
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+
+		case err != nil:
+			return err
+		}
+
+		exists = true
+		updateTime = time.Unix(dbNode.LastUpdate.Int64, 0)
+
+		return nil
+	}, func() {
+		updateTime = time.Time{}
+		exists = false
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return updateTime, exists, nil
+}
+
+// LookupAlias returns the alias the node with the given public key announced.
+// ErrNodeAliasNotFound is returned if the node never announced itself.
+func (s *SQLGraphStore) LookupAlias(ctx context.Context,
+	nodePub [33]byte) (string, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		alias      string
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbNode, err := db.GetNodeByPubKey(ctx, nodePub[:])
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNodeAliasNotFound
+
+		case err != nil:
+			return err
+
+		case !dbNode.Alias.Valid:
+			return ErrNodeAliasNotFound
+		}
+
+		alias = dbNode.Alias.String
+
+		return nil
+	}, func() {
+		alias = ""
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return alias, nil
+}
+
+// DeleteLightningNode removes the node with the given public key from the
+// graph. The node must not have any channels left.
+func (s *SQLGraphStore) DeleteLightningNode(ctx context.Context,
+	nodePub route.Vertex) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		res, err := db.DeleteNodeByPubKey(ctx, nodePub[:])
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrGraphNodeNotFound
+		}
+
+		return nil
+	}, func() {})
+}
+
+// ForEachNode calls the passed callback for every node of the graph. The
+// nodes are read page by page, and the callback is called outside of any
+// database transaction, so it is free to query the store itself.
+func (s *SQLGraphStore) ForEachNode(ctx context.Context,
+	cb func(*LightningNode) error) error {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		lastID     int64
+	)
+	for {
+		var nodes []*LightningNode
+		err := s.db.ExecTx(ctx, &readTxOpts, func(
+			db SQLGraphQueries) error {
+
+			dbNodes, err := db.ListNodesPaginated(
+				ctx, sqlc.ListNodesPaginatedParams{
+					ID:    lastID,
+					Limit: graphQueryPaginationLimit,
+				},
+			)
+			if err != nil {
+				return err
+			}
+
+			for _, dbNode := range dbNodes {
+				node, err := buildNode(ctx, db, dbNode)
+				if err != nil {
+					return err
+				}
+
+				nodes = append(nodes, node)
+				lastID = dbNode.ID
+			}
+
+			return nil
+		}, func() {
+			nodes = nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if err := cb(node); err != nil {
+				return err
+			}
+		}
+
+		if len(nodes) < graphQueryPaginationLimit {
+			return nil
+		}
+	}
+}
+
+// NodeUpdatesInHorizon returns all the announced nodes with a last update
+// within the passed time range.
+func (s *SQLGraphStore) NodeUpdatesInHorizon(ctx context.Context, startTime,
+	endTime time.Time) ([]LightningNode, error) {
+
+	var (
+		readTxOpts     = NewSQLGraphQueryReadTx()
+		nodesInHorizon []LightningNode
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbNodes, err := db.GetNodesByLastUpdateRange(
+			ctx, sqlc.GetNodesByLastUpdateRangeParams{
+				StartTime: sqldb.SQLInt64(startTime.Unix()),
+				EndTime:   sqldb.SQLInt64(endTime.Unix()),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, dbNode := range dbNodes {
+			node, err := buildNode(ctx, db, dbNode)
+			if err != nil {
+				return err
+			}
+
+			nodesInHorizon = append(nodesInHorizon, *node)
+		}
+
+		return nil
+	}, func() {
+		nodesInHorizon = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodesInHorizon, nil
+}
+
+// PruneGraphNodes removes all nodes that no longer have any channels, except
+// for the source node. The public keys of the pruned nodes are returned.
+func (s *SQLGraphStore) PruneGraphNodes(ctx context.Context) ([]route.Vertex,
+	error) {
+
+	var (
+		writeTxOpts SQLGraphQueriesTxOptions
+		prunedNodes []route.Vertex
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		prunedNodes, err = pruneGraphNodes(ctx, db)
+
+		return err
+	}, func() {
+		prunedNodes = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prunedNodes, nil
+}
+
+// IsPublicNode determines whether the node with the given public key is seen
+// as public within the graph from the source node's point of view.
+func (s *SQLGraphStore) IsPublicNode(ctx context.Context,
+	pubKey [33]byte) (bool, error) {
+
+	var (
+		readTxOpts   = NewSQLGraphQueryReadTx()
+		nodeIsPublic bool
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		sourceKeys, err := db.GetSourceNodes(ctx)
+		if err != nil {
+			return err
+		}
+		if len(sourceKeys) == 0 {
+			return ErrSourceNodeNotSet
+		}
+		sourcePubKey := sourceKeys[0]
+
+		nodeID, err := db.GetNodeIDByPubKey(ctx, pubKey[:])
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGraphNodeNotFound
+
+		case err != nil:
+			return err
+		}
+
+		dbChans, err := db.ListChannelsByNodeID(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+
+		for _, dbChan := range dbChans {
+			// If this channel doesn't extend to the source node,
+			// we can conclude that the node is publicly advertised
+			// within the graph as the local node knows of it.
+			if !bytes.Equal(dbChan.Node1PubKey, sourcePubKey) &&
+				!bytes.Equal(dbChan.Node2PubKey, sourcePubKey) {
+
+				nodeIsPublic = true
+				return nil
+			}
+
+			// Since the channel _does_ extend to the source node,
+			// it also needs to be announced.
+			if len(dbChan.GraphChannel.Node1Signature) != 0 {
+				nodeIsPublic = true
+				return nil
+			}
+		}
+
+		return nil
+	}, func() {
+		nodeIsPublic = false
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return nodeIsPublic, nil
+}
+
+// ForEachNodeChannel calls the passed callback for every channel of the node
+// with the given public key. The first policy passed to the callback is the
+// outgoing policy of the node, the second one the incoming policy. Unknown
+// policies are passed as nil values.
+func (s *SQLGraphStore) ForEachNodeChannel(ctx context.Context,
+	nodePub route.Vertex, cb func(*models.ChannelEdgeInfo,
+		*models.ChannelEdgePolicy,
+		*models.ChannelEdgePolicy) error) error {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		channels   []ChannelEdge
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		nodeID, err := db.GetNodeIDByPubKey(ctx, nodePub[:])
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+
+		case err != nil:
+			return err
+		}
+
+		dbChans, err := db.ListChannelsByNodeID(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+
+		for _, dbChan := range dbChans {
+			info, p1, p2, err := buildChannel(
+				ctx, db, dbChan.GraphChannel,
+				dbChan.Node1PubKey, dbChan.Node2PubKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			// Order the policies from the point of view of the
+			// target node.
+			outPolicy, inPolicy := p1, p2
+			if info.NodeKey2Bytes == nodePub {
+				outPolicy, inPolicy = p2, p1
+			}
+
+			channels = append(channels, ChannelEdge{
+				Info:    info,
+				Policy1: outPolicy,
+				Policy2: inPolicy,
+			})
+		}
+
+		return nil
+	}, func() {
+		channels = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		err := cb(channel.Info, channel.Policy1, channel.Policy2)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddChannelEdge adds a new channel to the graph. Shell nodes are created for
+// any of the two nodes that aren't known yet. ErrEdgeAlreadyExist is returned
+// if the channel is already known.
+func (s *SQLGraphStore) AddChannelEdge(ctx context.Context,
+	edge *models.ChannelEdgeInfo) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		return insertChannel(ctx, db, edge)
+	}, func() {})
+}
+
+// UpdateChannelEdge updates the static information of an existing channel.
+// ErrEdgeNotFound is returned if the channel isn't known.
+func (s *SQLGraphStore) UpdateChannelEdge(ctx context.Context,
+	edge *models.ChannelEdgeInfo) error {
+
+	if len(edge.ExtraOpaqueData) > MaxAllowedExtraOpaqueBytes {
+		return ErrTooManyExtraOpaqueBytes(len(edge.ExtraOpaqueData))
+	}
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		dbChan, err := db.GetChannelBySCID(
+			ctx, scidBytes(edge.ChannelID),
+		)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEdgeNotFound
+
+		case err != nil:
+			return err
+		}
+
+		node1Sig, node2Sig, btc1Sig, btc2Sig := authProofSigs(edge)
+
+		return db.UpdateChannel(ctx, sqlc.UpdateChannelParams{
+			ID:                dbChan.GraphChannel.ID,
+			ChainHash:         edge.ChainHash[:],
+			Outpoint:          edge.ChannelPoint.String(),
+			Capacity:          int64(edge.Capacity),
+			BitcoinKey1:       edge.BitcoinKey1Bytes[:],
+			BitcoinKey2:       edge.BitcoinKey2Bytes[:],
+			Features:          nonNilBytes(edge.Features),
+			Node1Signature:    node1Sig,
+			Node2Signature:    node2Sig,
+			Bitcoin1Signature: btc1Sig,
+			Bitcoin2Signature: btc2Sig,
+			ExtraOpaqueData:   edge.ExtraOpaqueData,
+		})
+	}, func() {})
+}
+
+// HasChannelEdge returns the last update times of both policies of the
+// channel with the given ID along with true if the channel is known. If it
+// isn't, the zombie index is checked and its result is returned as the second
+// boolean.
+func (s *SQLGraphStore) HasChannelEdge(ctx context.Context,
+	chanID uint64) (time.Time, time.Time, bool, bool, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		upd1Time   time.Time
+		upd2Time   time.Time
+		exists     bool
+		isZombie   bool
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		_, p1, p2, err := getChannelBySCID(ctx, db, chanID)
+		switch {
+		// If the channel doesn't exist, then we'll also check our
+		// zombie index.
+		case errors.Is(err, ErrEdgeNotFound):
+			isZombie, _, _, err = isZombieChannel(ctx, db, chanID)
+
+			return err
+
+		case err != nil:
+			return err
+		}
+
+		exists = true
+
+		// As we may have only one of the policies, only set the update
+		// time if the policy was found in the database.
+		if p1 != nil {
+			upd1Time = p1.LastUpdate
+		}
+		if p2 != nil {
+			upd2Time = p2.LastUpdate
+		}
+
+		return nil
+	}, func() {
+		upd1Time = time.Time{}
+		upd2Time = time.Time{}
+		exists = false
+		isZombie = false
+	})
+	if err != nil {
+		return time.Time{}, time.Time{}, false, false, err
+	}
+
+	return upd1Time, upd2Time, exists, isZombie, nil
+}
+
+// FetchChannelEdgesByID returns the channel with the given ID along with both
+// of its policies. If the channel is marked as a zombie, then ErrZombieEdge is
+// returned along with a channel info that only contains the node keys.
+func (s *SQLGraphStore) FetchChannelEdgesByID(ctx context.Context,
+	chanID uint64) (*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+	*models.ChannelEdgePolicy, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		edgeInfo   *models.ChannelEdgeInfo
+		policy1    *models.ChannelEdgePolicy
+		policy2    *models.ChannelEdgePolicy
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		edgeInfo, policy1, policy2, err = getChannelBySCID(
+			ctx, db, chanID,
+		)
+		if !errors.Is(err, ErrEdgeNotFound) {
+			return err
+		}
+
+		// The channel doesn't exist, so we'll check whether we've
+		// previously marked it as a zombie.
+		isZombie, pubKey1, pubKey2, err := isZombieChannel(
+			ctx, db, chanID,
+		)
+		switch {
+		case err != nil:
+			return err
+
+		case !isZombie:
+			return ErrEdgeNotFound
+		}
+
+		edgeInfo = &models.ChannelEdgeInfo{
+			NodeKey1Bytes: pubKey1,
+			NodeKey2Bytes: pubKey2,
+		}
+
+		return ErrZombieEdge
+	}, func() {
+		edgeInfo = nil
+		policy1 = nil
+		policy2 = nil
+	})
+	if errors.Is(err, ErrZombieEdge) {
+		return edgeInfo, nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return edgeInfo, policy1, policy2, nil
+}
+
+// FetchChannelEdgesByOutpoint returns the channel with the given funding
+// outpoint along with both of its policies. ErrEdgeNotFound is returned if the
+// channel isn't known.
+func (s *SQLGraphStore) FetchChannelEdgesByOutpoint(ctx context.Context,
+	op *wire.OutPoint) (*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+	*models.ChannelEdgePolicy, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		edgeInfo   *models.ChannelEdgeInfo
+		policy1    *models.ChannelEdgePolicy
+		policy2    *models.ChannelEdgePolicy
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbChan, err := db.GetChannelByOutpoint(ctx, op.String())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("%w: op=%v", ErrEdgeNotFound, op)
+
+		case err != nil:
+			return err
+		}
+
+		edgeInfo, policy1, policy2, err = buildChannel(
+			ctx, db, dbChan.GraphChannel, dbChan.Node1PubKey,
+			dbChan.Node2PubKey,
+		)
+
+		return err
+	}, func() {
+		edgeInfo = nil
+		policy1 = nil
+		policy2 = nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return edgeInfo, policy1, policy2, nil
+}
+
+// ChannelID returns the short channel ID of the channel with the given
+// funding outpoint. ErrEdgeNotFound is returned if the channel isn't known.
+func (s *SQLGraphStore) ChannelID(ctx context.Context,
+	chanPoint *wire.OutPoint) (uint64, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		chanID     uint64
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbChan, err := db.GetChannelByOutpoint(ctx, chanPoint.String())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEdgeNotFound
+
+		case err != nil:
+			return err
+		}
+
+		chanID = byteOrder.Uint64(dbChan.GraphChannel.Scid)
+
+		return nil
+	}, func() {
+		chanID = 0
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return chanID, nil
+}
+
+// HighestChanID returns the highest known short channel ID of the graph, or
+// zero if no channels are known.
+func (s *SQLGraphStore) HighestChanID(ctx context.Context) (uint64, error) {
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		chanID     uint64
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		scid, err := db.HighestSCID(ctx)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+
+		case err != nil:
+			return err
+		}
+
+		chanID = byteOrder.Uint64(scid)
+
+		return nil
+	}, func() {
+		chanID = 0
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return chanID, nil
+}
+
+// ForEachChannel calls the passed callback for every channel of the graph
+// along with both of its policies. Unknown policies are passed as nil values.
+// The channels are read page by page, and the callback is called outside of
+// any database transaction.
+func (s *SQLGraphStore) ForEachChannel(ctx context.Context,
+	cb func(*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+		*models.ChannelEdgePolicy) error) error {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		lastID     int64
+	)
+	for {
+		var (
+			channels []ChannelEdge
+			numRows  int
+		)
+		err := s.db.ExecTx(ctx, &readTxOpts, func(
+			db SQLGraphQueries) error {
+
+			var err error
+			channels, numRows, lastID, err = listChannelsPage(
+				ctx, db, lastID,
+			)
+
+			return err
+		}, func() {
+			channels = nil
+			numRows = 0
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, channel := range channels {
+			err := cb(
+				channel.Info, channel.Policy1, channel.Policy2,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if numRows < graphQueryPaginationLimit {
+			return nil
+		}
+	}
+}
+
+// UpdateEdgePolicy inserts or updates the policy of one direction of a known
+// channel. The public keys of the node that announced the policy and of the
+// node on the other end are returned, along with true if the policy belongs to
+// node 1 of the channel. ErrEdgeNotFound is returned if the channel isn't
+// known.
+func (s *SQLGraphStore) UpdateEdgePolicy(ctx context.Context,
+	edge *models.ChannelEdgePolicy) (route.Vertex, route.Vertex, bool,
+	error) {
+
+	var (
+		writeTxOpts SQLGraphQueriesTxOptions
+		from, to    route.Vertex
+		isUpdate1   bool
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		from, to, isUpdate1, err = upsertEdgePolicy(ctx, db, edge)
+
+		return err
+	}, func() {
+		from, to = route.Vertex{}, route.Vertex{}
+		isUpdate1 = false
+	})
+	if err != nil {
+		return route.Vertex{}, route.Vertex{}, false, err
+	}
+
+	return from, to, isUpdate1, nil
+}
+
+// DeleteChannelEdges removes the channels with the given IDs from the graph
+// and optionally marks them as zombies. The deleted channels are returned. If
+// any of the channels isn't known, then ErrEdgeNotFound is returned and none
+// of the channels are deleted.
+func (s *SQLGraphStore) DeleteChannelEdges(ctx context.Context,
+	strictZombiePruning, markZombie bool, chanIDs ...uint64) (
+	[]*models.ChannelEdgeInfo, error) {
+
+	var (
+		writeTxOpts SQLGraphQueriesTxOptions
+		deleted     []*models.ChannelEdgeInfo
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		for _, chanID := range chanIDs {
+			info, err := deleteChannel(
+				ctx, db, chanID, markZombie,
+				strictZombiePruning,
+			)
+			if err != nil {
+				return err
+			}
+
+			deleted = append(deleted, info)
+		}
+
+		return nil
+	}, func() {
+		deleted = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// PruneGraph removes all channels whose funding outpoint was spent in the
+// given block, records the block in the prune log and then prunes all nodes
+// that no longer have any channels. The closed channels and the public keys of
+// the pruned nodes are returned.
+func (s *SQLGraphStore) PruneGraph(ctx context.Context,
+	spentOutputs []*wire.OutPoint, blockHash *chainhash.Hash,
+	blockHeight uint32) ([]*models.ChannelEdgeInfo, []route.Vertex, error) {
+
+	var (
+		writeTxOpts SQLGraphQueriesTxOptions
+		chansClosed []*models.ChannelEdgeInfo
+		prunedNodes []route.Vertex
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		for _, chanPoint := range spentOutputs {
+			dbChan, err := db.GetChannelByOutpoint(
+				ctx, chanPoint.String(),
+			)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				continue
+
+			case err != nil:
+				return err
+			}
+
+			info, err := buildChannelInfo(
+				dbChan.GraphChannel, dbChan.Node1PubKey,
+				dbChan.Node2PubKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			err = db.DeleteChannel(ctx, dbChan.GraphChannel.ID)
+			if err != nil {
+				return err
+			}
+
+			chansClosed = append(chansClosed, info)
+		}
+
+		err := db.UpsertPruneLogEntry(
+			ctx, sqlc.UpsertPruneLogEntryParams{
+				BlockHeight: int64(blockHeight),
+				BlockHash:   blockHash[:],
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		prunedNodes, err = pruneGraphNodes(ctx, db)
+
+		return err
+	}, func() {
+		chansClosed = nil
+		prunedNodes = nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return chansClosed, prunedNodes, nil
+}
+
+// DisconnectBlockAtHeight removes all channels confirmed at or above the given
+// height, up until the SCID alias range, and all prune log entries at or above
+// the given height. The removed channels are returned.
+func (s *SQLGraphStore) DisconnectBlockAtHeight(ctx context.Context,
+	height uint32) ([]*models.ChannelEdgeInfo, error) {
+
+	// Every channel having a ShortChannelID starting at 'height' will no
+	// longer be confirmed. We stop right before the SCID alias range.
+	startShortChanID := lnwire.ShortChannelID{
+		BlockHeight: height,
+	}
+	endChanID := aliasmgr.StartingAlias.ToUint64() - 1
+
+	var (
+		writeTxOpts  SQLGraphQueriesTxOptions
+		removedChans []*models.ChannelEdgeInfo
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		dbChans, err := db.GetChannelsBySCIDRange(
+			ctx, sqlc.GetChannelsBySCIDRangeParams{
+				StartScid: scidBytes(
+					startShortChanID.ToUint64(),
+				),
+				EndScid: scidBytes(endChanID),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, dbChan := range dbChans {
+			info, err := buildChannelInfo(
+				dbChan.GraphChannel, dbChan.Node1PubKey,
+				dbChan.Node2PubKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			err = db.DeleteChannel(ctx, dbChan.GraphChannel.ID)
+			if err != nil {
+				return err
+			}
+
+			removedChans = append(removedChans, info)
+		}
+
+		return db.DeletePruneLogEntriesFromHeight(ctx, int64(height))
+	}, func() {
+		removedChans = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return removedChans, nil
+}
+
+// PruneTip returns the hash and height of the latest block that was used to
+// prune the graph. ErrGraphNeverPruned is returned if the prune log is empty.
+func (s *SQLGraphStore) PruneTip(ctx context.Context) (*chainhash.Hash,
+	uint32, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		tipHash    chainhash.Hash
+		tipHeight  uint32
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		pruneTip, err := db.GetPruneTip(ctx)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGraphNeverPruned
+
+		case err != nil:
+			return err
+		}
+
+		copy(tipHash[:], pruneTip.BlockHash)
+		tipHeight = uint32(pruneTip.BlockHeight)
+
+		return nil
+	}, func() {
+		tipHash = chainhash.Hash{}
+		tipHeight = 0
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &tipHash, tipHeight, nil
+}
+
+// ChanUpdatesInHorizon returns all channels that have at least one policy
+// with a last update within the passed time range, ordered by their earliest
+// update within the range.
+func (s *SQLGraphStore) ChanUpdatesInHorizon(ctx context.Context, startTime,
+	endTime time.Time) ([]ChannelEdge, error) {
+
+	var (
+		readTxOpts     = NewSQLGraphQueryReadTx()
+		edgesInHorizon []ChannelEdge
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		dbChans, err := db.GetChannelsByPolicyLastUpdateRange(
+			ctx, sqlc.GetChannelsByPolicyLastUpdateRangeParams{
+				StartTime: startTime.Unix(),
+				EndTime:   endTime.Unix(),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, dbChan := range dbChans {
+			channel, err := buildChannelEdge(
+				ctx, db, dbChan.GraphChannel,
+				dbChan.Node1PubKey, dbChan.Node2PubKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			edgesInHorizon = append(edgesInHorizon, *channel)
+		}
+
+		return nil
+	}, func() {
+		edgesInHorizon = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return edgesInHorizon, nil
+}
+
+// FilterKnownChanIDs returns the subset of the passed channels that are
+// neither known nor known zombies. Zombies that the passed update timestamps
+// would resurrect are removed from the zombie index and returned as the second
+// slice.
+func (s *SQLGraphStore) FilterKnownChanIDs(ctx context.Context,
+	chansInfo []ChannelUpdateInfo,
+	isZombieChan func(time.Time, time.Time) bool) ([]uint64, []uint64,
+	error) {
+
+	var (
+		writeTxOpts SQLGraphQueriesTxOptions
+		newChanIDs  []uint64
+		revivedIDs  []uint64
+	)
+	err := s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		for _, info := range chansInfo {
+			scid := info.ShortChannelID.ToUint64()
+
+			// If the channel is already known, skip it.
+			_, err := db.GetChannelBySCID(ctx, scidBytes(scid))
+			switch {
+			case err == nil:
+				continue
+
+			case !errors.Is(err, sql.ErrNoRows):
+				return err
+			}
+
+			isZombie, _, _, err := isZombieChannel(ctx, db, scid)
+			if err != nil {
+				return err
+			}
+
+			if isZombie {
+				isStillZombie := isZombieChan(
+					info.Node1UpdateTimestamp,
+					info.Node2UpdateTimestamp,
+				)
+
+				// If we would still consider the channel a
+				// zombie given the latest update timestamps,
+				// then we skip it.
+				if isStillZombie {
+					continue
+				}
+
+				// Otherwise, the timestamps could bring it back
+				// from the dead, so we mark it alive and let it
+				// be queried from our peer.
+				_, err := db.DeleteZombieChannel(
+					ctx, scidBytes(scid),
+				)
+				if err != nil {
+					return err
+				}
+
+				revivedIDs = append(revivedIDs, scid)
+			}
+
+			newChanIDs = append(newChanIDs, scid)
+		}
+
+		return nil
+	}, func() {
+		newChanIDs = nil
+		revivedIDs = nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newChanIDs, revivedIDs, nil
+}
+
+// FilterChannelRange returns the IDs of all announced channels that were
+// confirmed within the passed block range, grouped by block height. If
+// withTimestamps is true, then the last update times of the policies are
+// included as well.
+func (s *SQLGraphStore) FilterChannelRange(ctx context.Context, startHeight,
+	endHeight uint32, withTimestamps bool) ([]BlockChannelRange, error) {
+
+	startChanID := lnwire.ShortChannelID{
+		BlockHeight: startHeight,
+	}
+	endChanID := lnwire.ShortChannelID{
+		BlockHeight: endHeight,
+		TxIndex:     0x00ffffff,
+		TxPosition:  0xffff,
+	}
+
+	var (
+		readTxOpts       = NewSQLGraphQueryReadTx()
+		channelsPerBlock map[uint32][]ChannelUpdateInfo
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		rows, err := db.GetPublicChannelUpdateTimesBySCIDRange(
+			ctx, sqlc.GetPublicChannelUpdateTimesBySCIDRangeParams{
+				StartScid: scidBytes(startChanID.ToUint64()),
+				EndScid:   scidBytes(endChanID.ToUint64()),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			cid := lnwire.NewShortChanIDFromInt(
+				byteOrder.Uint64(row.Scid),
+			)
+			chanInfo := NewChannelUpdateInfo(
+				cid, time.Time{}, time.Time{},
+			)
+
+			if withTimestamps && row.Node1LastUpdate.Valid {
+				chanInfo.Node1UpdateTimestamp = time.Unix(
+					row.Node1LastUpdate.Int64, 0,
+				)
+			}
+			if withTimestamps && row.Node2LastUpdate.Valid {
+				chanInfo.Node2UpdateTimestamp = time.Unix(
+					row.Node2LastUpdate.Int64, 0,
+				)
+			}
+
+			channelsPerBlock[cid.BlockHeight] = append(
+				channelsPerBlock[cid.BlockHeight], chanInfo,
+			)
+		}
+
+		return nil
+	}, func() {
+		channelsPerBlock = make(map[uint32][]ChannelUpdateInfo)
+	})
+	switch {
+	case err != nil:
+		return nil, err
+
+	// If we don't know of any channels yet, then there's nothing to
+	// filter, so we'll return an empty slice.
+	case len(channelsPerBlock) == 0:
+		return nil, nil
+	}
+
+	// Return the channel ranges in ascending block height order.
+	blocks := make([]uint32, 0, len(channelsPerBlock))
+	for block := range channelsPerBlock {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i] < blocks[j]
+	})
+
+	channelRanges := make([]BlockChannelRange, 0, len(channelsPerBlock))
+	for _, block := range blocks {
+		channelRanges = append(channelRanges, BlockChannelRange{
+			Height:   block,
+			Channels: channelsPerBlock[block],
+		})
+	}
+
+	return channelRanges, nil
+}
+
+// FetchChanInfos returns the channels with the given IDs along with their
+// policies and nodes. Unknown channels are skipped.
+func (s *SQLGraphStore) FetchChanInfos(ctx context.Context,
+	chanIDs []uint64) ([]ChannelEdge, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		chanEdges  []ChannelEdge
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		for _, chanID := range chanIDs {
+			dbChan, err := db.GetChannelBySCID(
+				ctx, scidBytes(chanID),
+			)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				continue
+
+			case err != nil:
+				return err
+			}
+
+			channel, err := buildChannelEdge(
+				ctx, db, dbChan.GraphChannel,
+				dbChan.Node1PubKey, dbChan.Node2PubKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			chanEdges = append(chanEdges, *channel)
+		}
+
+		return nil
+	}, func() {
+		chanEdges = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chanEdges, nil
+}
+
+// DisabledChannelIDs returns the IDs of all channels that both of their nodes
+// disabled.
+func (s *SQLGraphStore) DisabledChannelIDs(ctx context.Context) ([]uint64,
+	error) {
+
+	var (
+		readTxOpts      = NewSQLGraphQueryReadTx()
+		disabledChanIDs []uint64
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		scids, err := db.GetDisabledChannelSCIDs(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, scid := range scids {
+			disabledChanIDs = append(
+				disabledChanIDs, byteOrder.Uint64(scid),
+			)
+		}
+
+		return nil
+	}, func() {
+		disabledChanIDs = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return disabledChanIDs, nil
+}
+
+// ChannelView returns the funding outpoint and script of every channel of the
+// graph.
+func (s *SQLGraphStore) ChannelView(ctx context.Context) ([]EdgePoint, error) {
+	var edgePoints []EdgePoint
+	err := s.ForEachChannel(ctx, func(info *models.ChannelEdgeInfo,
+		_, _ *models.ChannelEdgePolicy) error {
+
+		pkScript, err := genMultiSigP2WSH(
+			info.BitcoinKey1Bytes[:], info.BitcoinKey2Bytes[:],
+		)
+		if err != nil {
+			return err
+		}
+
+		edgePoints = append(edgePoints, EdgePoint{
+			FundingPkScript: pkScript,
+			OutPoint:        info.ChannelPoint,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return edgePoints, nil
+}
+
+// MarkEdgeZombie marks the channel with the given ID as a zombie that can
+// only be resurrected by the given node keys.
+func (s *SQLGraphStore) MarkEdgeZombie(ctx context.Context, chanID uint64,
+	pubKey1, pubKey2 [33]byte) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		return markChannelZombie(ctx, db, chanID, pubKey1, pubKey2)
+	}, func() {})
+}
+
+// MarkEdgeLive removes the channel with the given ID from the zombie index.
+// ErrZombieEdgeNotFound is returned if the channel isn't a zombie.
+func (s *SQLGraphStore) MarkEdgeLive(ctx context.Context, chanID uint64) error {
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		res, err := db.DeleteZombieChannel(ctx, scidBytes(chanID))
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrZombieEdgeNotFound
+		}
+
+		return nil
+	}, func() {})
+}
+
+// IsZombieEdge returns whether the channel with the given ID is a zombie,
+// along with the node keys that can resurrect it.
+func (s *SQLGraphStore) IsZombieEdge(ctx context.Context, chanID uint64) (
+	bool, [33]byte, [33]byte, error) {
+
+	var (
+		readTxOpts       = NewSQLGraphQueryReadTx()
+		isZombie         bool
+		pubKey1, pubKey2 [33]byte
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		isZombie, pubKey1, pubKey2, err = isZombieChannel(
+			ctx, db, chanID,
+		)
+
+		return err
+	}, func() {
+		isZombie = false
+		pubKey1 = [33]byte{}
+		pubKey2 = [33]byte{}
+	})
+	if err != nil {
+		return false, [33]byte{}, [33]byte{}, err
+	}
+
+	return isZombie, pubKey1, pubKey2, nil
+}
+
+// NumZombies returns the number of zombie channels.
+func (s *SQLGraphStore) NumZombies(ctx context.Context) (uint64, error) {
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		numZombies uint64
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		count, err := db.CountZombieChannels(ctx)
+		if err != nil {
+			return err
+		}
+
+		numZombies = uint64(count)
+
+		return nil
+	}, func() {
+		numZombies = 0
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return numZombies, nil
+}
+
+// PutClosedScid stores the SCID of a closed channel.
+func (s *SQLGraphStore) PutClosedScid(ctx context.Context,
+	scid lnwire.ShortChannelID) error {
+
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		return db.InsertClosedSCID(ctx, scidBytes(scid.ToUint64()))
+	}, func() {})
+}
+
+// IsClosedScid returns whether the given SCID belongs to a closed channel.
+func (s *SQLGraphStore) IsClosedScid(ctx context.Context,
+	scid lnwire.ShortChannelID) (bool, error) {
+
+	var (
+		readTxOpts = NewSQLGraphQueryReadTx()
+		isClosed   bool
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLGraphQueries) error {
+		var err error
+		isClosed, err = db.IsClosedSCID(
+			ctx, scidBytes(scid.ToUint64()),
+		)
+
+		return err
+	}, func() {
+		isClosed = false
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return isClosed, nil
+}
+
+// Wipe deletes the whole channel graph in a single transaction.
+func (s *SQLGraphStore) Wipe(ctx context.Context) error {
+	var writeTxOpts SQLGraphQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &writeTxOpts, func(db SQLGraphQueries) error {
+		// Channels have to be deleted before the nodes they reference.
+		// Policies, features, addresses and the source node are
+		// deleted along with their channels and nodes.
+		deleteFns := []func(context.Context) error{
+			db.DeleteAllChannels,
+			db.DeleteAllNodes,
+			db.DeleteAllZombieChannels,
+			db.DeleteAllClosedSCIDs,
+			db.DeleteAllPruneLogEntries,
+		}
+		for _, deleteFn := range deleteFns {
+			if err := deleteFn(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func() {})
+}
+
+// scidBytes returns the 8 byte big endian serialization of a short channel ID,
+// which is how SCIDs are stored in the graph tables.
+func scidBytes(chanID uint64) []byte {
+	var b [8]byte
+	byteOrder.PutUint64(b[:], chanID)
+
+	return b[:]
+}
+
+// nonNilBytes returns the given byte slice, or an empty one if it is nil, so
+// that it can be written to a NOT NULL column.
+func nonNilBytes(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+
+	return b
+}
+
+// upsertNode inserts or updates the given node along with its features and
+// addresses and returns the ID of the node. Only the public key is stored for
+// shell nodes that we never received a node announcement for.
+func upsertNode(ctx context.Context, db SQLGraphQueries,
+	node *LightningNode) (int64, error) {
+
+	params := sqlc.UpsertNodeParams{
+		PubKey: node.PubKeyBytes[:],
+	}
+	if node.HaveNodeAnnouncement {
+		if len(node.AuthSigBytes) > 80 {
+			return 0, fmt.Errorf("max sig len allowed is 80, had "+
+				"%v", len(node.AuthSigBytes))
+		}
+
+		if len(node.ExtraOpaqueData) > MaxAllowedExtraOpaqueBytes {
+			return 0, ErrTooManyExtraOpaqueBytes(
+				len(node.ExtraOpaqueData),
+			)
+		}
+
+		params.LastUpdate = sqldb.SQLInt64(node.LastUpdate.Unix())
+		params.Alias = sql.NullString{
+			String: node.Alias,
+			Valid:  true,
+		}
+		params.Color = sqldb.SQLInt32(
+			int32(node.Color.R)<<16 | int32(node.Color.G)<<8 |
+				int32(node.Color.B),
+		)
+		params.Signature = node.AuthSigBytes
+		params.ExtraOpaqueData = node.ExtraOpaqueData
+	}
+
+	nodeID, err := db.UpsertNode(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("unable to upsert node %x: %w",
+			node.PubKeyBytes, err)
+	}
+
+	// Replace any features and addresses of a previous announcement.
+	if err := db.DeleteNodeFeatures(ctx, nodeID); err != nil {
+		return 0, err
+	}
+	if err := db.DeleteNodeAddresses(ctx, nodeID); err != nil {
+		return 0, err
+	}
+
+	if !node.HaveNodeAnnouncement {
+		return nodeID, nil
+	}
+
+	if node.Features != nil {
+		for bit := range node.Features.Features() {
+			err := db.InsertNodeFeature(
+				ctx, sqlc.InsertNodeFeatureParams{
+					NodeID:     nodeID,
+					FeatureBit: int32(bit),
+				},
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for i, address := range node.Addresses {
+		var b bytes.Buffer
+		if err := serializeAddr(&b, address); err != nil {
+			return 0, err
+		}
+
+		err := db.InsertNodeAddress(ctx, sqlc.InsertNodeAddressParams{
+			NodeID:   nodeID,
+			Position: int32(i),
+			Address:  b.Bytes(),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return nodeID, nil
+}
+
+// getOrCreateShellNode returns the ID of the node with the given public key,
+// inserting a shell node if the node isn't known yet.
+func getOrCreateShellNode(ctx context.Context, db SQLGraphQueries,
+	pubKey [33]byte) (int64, error) {
+
+	nodeID, err := db.GetNodeIDByPubKey(ctx, pubKey[:])
+	switch {
+	case err == nil:
+		return nodeID, nil
+
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	return upsertNode(ctx, db, &LightningNode{
+		PubKeyBytes:          pubKey,
+		HaveNodeAnnouncement: false,
+	})
+}
+
+// getNode returns the node with the given public key. ErrGraphNodeNotFound is
+// returned if the node isn't known.
+func getNode(ctx context.Context, db SQLGraphQueries,
+	pubKey []byte) (*LightningNode, error) {
+
+	dbNode, err := db.GetNodeByPubKey(ctx, pubKey)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrGraphNodeNotFound
+
+	case err != nil:
+		return nil, err
+	}
+
+	return buildNode(ctx, db, dbNode)
+}
+
+// buildNode converts a node row into a LightningNode, fetching its features
+// and addresses.
+func buildNode(ctx context.Context, db SQLGraphQueries,
+	dbNode sqlc.GraphNode) (*LightningNode, error) {
+
+	// Always populate a feature vector, even if we don't have a node
+	// announcement and short circuit below.
+	node := &LightningNode{
+		LastUpdate: time.Unix(dbNode.LastUpdate.Int64, 0),
+		Features:   lnwire.EmptyFeatureVector(),
+	}
+	copy(node.PubKeyBytes[:], dbNode.PubKey)
+
+	if !dbNode.LastUpdate.Valid {
+		return node, nil
+	}
+
+	node.HaveNodeAnnouncement = true
+	node.Alias = dbNode.Alias.String
+	node.Color = color.RGBA{
+		R: uint8(dbNode.Color.Int32 >> 16),
+		G: uint8(dbNode.Color.Int32 >> 8),
+		B: uint8(dbNode.Color.Int32),
+	}
+	node.AuthSigBytes = dbNode.Signature
+	node.ExtraOpaqueData = dbNode.ExtraOpaqueData
+
+	dbFeatures, err := db.GetNodeFeatures(ctx, dbNode.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rawFeatures := lnwire.NewRawFeatureVector()
+	for _, dbFeature := range dbFeatures {
+		rawFeatures.Set(lnwire.FeatureBit(dbFeature.FeatureBit))
+	}
+	node.Features = lnwire.NewFeatureVector(rawFeatures, lnwire.Features)
+
+	dbAddresses, err := db.GetNodeAddresses(ctx, dbNode.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]net.Addr, 0, len(dbAddresses))
+	for _, dbAddress := range dbAddresses {
+		address, err := deserializeAddr(
+			bytes.NewReader(dbAddress.Address),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, address)
+	}
+	node.Addresses = addresses
+
+	return node, nil
+}
+
+// setSourceNode inserts or updates the given node and marks it as the only
+// source node of the graph.
+func setSourceNode(ctx context.Context, db SQLGraphQueries,
+	node *LightningNode) error {
+
+	nodeID, err := upsertNode(ctx, db, node)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DeleteSourceNodes(ctx); err != nil {
+		return err
+	}
+
+	return db.AddSourceNode(ctx, nodeID)
+}
+
+// getSourceNode returns the source node of the graph. ErrSourceNodeNotSet is
+// returned if no source node has been set yet.
+func getSourceNode(ctx context.Context,
+	db SQLGraphQueries) (*LightningNode, error) {
+
+	sourceKeys, err := db.GetSourceNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceKeys) == 0 {
+		return nil, ErrSourceNodeNotSet
+	}
+
+	return getNode(ctx, db, sourceKeys[0])
+}
+
+// pruneGraphNodes removes all nodes without channels, except for the source
+// node, and returns their public keys.
+func pruneGraphNodes(ctx context.Context,
+	db SQLGraphQueries) ([]route.Vertex, error) {
+
+	log.Trace("Pruning nodes from graph with no open channels")
+
+	// We never want to prune our own node, so we require it to be set
+	// just like the kv graph does.
+	sourceKeys, err := db.GetSourceNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceKeys) == 0 {
+		return nil, ErrSourceNodeNotSet
+	}
+
+	prunedKeys, err := db.DeleteUnconnectedNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prunedNodes := make([]route.Vertex, 0, len(prunedKeys))
+	for _, pubKey := range prunedKeys {
+		var nodePub route.Vertex
+		copy(nodePub[:], pubKey)
+
+		log.Infof("Pruned unconnected node %x from channel graph",
+			nodePub[:])
+
+		prunedNodes = append(prunedNodes, nodePub)
+	}
+
+	if len(prunedNodes) > 0 {
+		log.Infof("Pruned %v unconnected nodes from the channel graph",
+			len(prunedNodes))
+	}
+
+	return prunedNodes, nil
+}
+
+// authProofSigs returns the four signatures of the channel announcement of
+// the given channel, or nil values if the channel wasn't announced.
+func authProofSigs(edge *models.ChannelEdgeInfo) ([]byte, []byte, []byte,
+	[]byte) {
+
+	proof := edge.AuthProof
+	if proof == nil || proof.IsEmpty() {
+		return nil, nil, nil, nil
+	}
+
+	return proof.NodeSig1Bytes, proof.NodeSig2Bytes,
+		proof.BitcoinSig1Bytes, proof.BitcoinSig2Bytes
+}
+
+// insertChannel adds the given channel to the graph, creating shell nodes for
+// any of its nodes that aren't known yet. ErrEdgeAlreadyExist is returned if
+// the channel is already known.
+func insertChannel(ctx context.Context, db SQLGraphQueries,
+	edge *models.ChannelEdgeInfo) error {
+
+	if len(edge.ExtraOpaqueData) > MaxAllowedExtraOpaqueBytes {
+		return ErrTooManyExtraOpaqueBytes(len(edge.ExtraOpaqueData))
+	}
+
+	scid := scidBytes(edge.ChannelID)
+
+	// First, check if this channel has already been created, as this
+	// method is meant to be idempotent.
+	_, err := db.GetChannelBySCID(ctx, scid)
+	switch {
+	case err == nil:
+		return ErrEdgeAlreadyExist
+
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	node1ID, err := getOrCreateShellNode(ctx, db, edge.NodeKey1Bytes)
+	if err != nil {
+		return fmt.Errorf("unable to create shell node for: %x: %w",
+			edge.NodeKey1Bytes, err)
+	}
+	node2ID, err := getOrCreateShellNode(ctx, db, edge.NodeKey2Bytes)
+	if err != nil {
+		return fmt.Errorf("unable to create shell node for: %x: %w",
+			edge.NodeKey2Bytes, err)
+	}
+
+	node1Sig, node2Sig, btc1Sig, btc2Sig := authProofSigs(edge)
+
+	_, err = db.CreateChannel(ctx, sqlc.CreateChannelParams{
+		Scid:              scid,
+		ChainHash:         edge.ChainHash[:],
+		NodeID1:           node1ID,
+		NodeID2:           node2ID,
+		Outpoint:          edge.ChannelPoint.String(),
+		Capacity:          int64(edge.Capacity),
+		BitcoinKey1:       edge.BitcoinKey1Bytes[:],
+		BitcoinKey2:       edge.BitcoinKey2Bytes[:],
+		Features:          nonNilBytes(edge.Features),
+		Node1Signature:    node1Sig,
+		Node2Signature:    node2Sig,
+		Bitcoin1Signature: btc1Sig,
+		Bitcoin2Signature: btc2Sig,
+		ExtraOpaqueData:   edge.ExtraOpaqueData,
+	})
+
+	return err
+}
+
+// upsertEdgePolicy inserts or updates the policy of one direction of a known
+// channel. The direction is taken from the channel flags of the policy.
+func upsertEdgePolicy(ctx context.Context, db SQLGraphQueries,
+	edge *models.ChannelEdgePolicy) (route.Vertex, route.Vertex, bool,
+	error) {
+
+	var from, to route.Vertex
+
+	if len(edge.ExtraOpaqueData) > MaxAllowedExtraOpaqueBytes {
+		return from, to, false, ErrTooManyExtraOpaqueBytes(
+			len(edge.ExtraOpaqueData),
+		)
+	}
+
+	dbChan, err := db.GetChannelBySCID(ctx, scidBytes(edge.ChannelID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return from, to, false, ErrEdgeNotFound
+
+	case err != nil:
+		return from, to, false, err
+	}
+
+	// Depending on the direction flag, either the first or the second
+	// node of the channel announced the policy.
+	isUpdate1 := edge.ChannelFlags&lnwire.ChanUpdateDirection == 0
+	nodeID := dbChan.GraphChannel.NodeID1
+	copy(from[:], dbChan.Node1PubKey)
+	copy(to[:], dbChan.Node2PubKey)
+	if !isUpdate1 {
+		nodeID = dbChan.GraphChannel.NodeID2
+		from, to = to, from
+	}
+
+	err = db.UpsertChannelPolicy(ctx, sqlc.UpsertChannelPolicyParams{
+		ChannelID:       dbChan.GraphChannel.ID,
+		NodeID:          nodeID,
+		LastUpdate:      edge.LastUpdate.Unix(),
+		MessageFlags:    int16(edge.MessageFlags),
+		ChannelFlags:    int16(edge.ChannelFlags),
+		Disabled:        edge.IsDisabled(),
+		Timelock:        int32(edge.TimeLockDelta),
+		BaseFeeMsat:     int64(edge.FeeBaseMSat),
+		FeePpm:          int64(edge.FeeProportionalMillionths),
+		MinHtlcMsat:     int64(edge.MinHTLC),
+		MaxHtlcMsat:     int64(edge.MaxHTLC),
+		Signature:       nonNilBytes(edge.SigBytes),
+		ExtraOpaqueData: edge.ExtraOpaqueData,
+	})
+	if err != nil {
+		return from, to, false, err
+	}
+
+	return from, to, isUpdate1, nil
+}
+
+// buildChannelInfo converts a channel row and the public keys of its nodes
+// into a ChannelEdgeInfo.
+func buildChannelInfo(dbChan sqlc.GraphChannel, node1Pub,
+	node2Pub []byte) (*models.ChannelEdgeInfo, error) {
+
+	chanPoint, err := wire.NewOutPointFromString(dbChan.Outpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.ChannelEdgeInfo{
+		ChannelID:       byteOrder.Uint64(dbChan.Scid),
+		Features:        dbChan.Features,
+		ChannelPoint:    *chanPoint,
+		Capacity:        btcutil.Amount(dbChan.Capacity),
+		ExtraOpaqueData: dbChan.ExtraOpaqueData,
+	}
+	copy(info.ChainHash[:], dbChan.ChainHash)
+	copy(info.NodeKey1Bytes[:], node1Pub)
+	copy(info.NodeKey2Bytes[:], node2Pub)
+	copy(info.BitcoinKey1Bytes[:], dbChan.BitcoinKey1)
+	copy(info.BitcoinKey2Bytes[:], dbChan.BitcoinKey2)
+
+	proof := &models.ChannelAuthProof{
+		NodeSig1Bytes:    dbChan.Node1Signature,
+		NodeSig2Bytes:    dbChan.Node2Signature,
+		BitcoinSig1Bytes: dbChan.Bitcoin1Signature,
+		BitcoinSig2Bytes: dbChan.Bitcoin2Signature,
+	}
+	if !proof.IsEmpty() {
+		info.AuthProof = proof
+	}
+
+	return info, nil
+}
+
+// buildPolicy converts a policy row into a ChannelEdgePolicy.
+func buildPolicy(chanID uint64, dbPolicy sqlc.GraphChannelPolicy,
+	toNode []byte) *models.ChannelEdgePolicy {
+
+	policy := &models.ChannelEdgePolicy{
+		SigBytes:     dbPolicy.Signature,
+		ChannelID:    chanID,
+		LastUpdate:   time.Unix(dbPolicy.LastUpdate, 0),
+		MessageFlags: lnwire.ChanUpdateMsgFlags(dbPolicy.MessageFlags),
+		ChannelFlags: lnwire.ChanUpdateChanFlags(
+			dbPolicy.ChannelFlags,
+		),
+		TimeLockDelta: uint16(dbPolicy.Timelock),
+		MinHTLC:       lnwire.MilliSatoshi(dbPolicy.MinHtlcMsat),
+		FeeBaseMSat:   lnwire.MilliSatoshi(dbPolicy.BaseFeeMsat),
+		FeeProportionalMillionths: lnwire.MilliSatoshi(
+			dbPolicy.FeePpm,
+		),
+		ExtraOpaqueData: dbPolicy.ExtraOpaqueData,
+	}
+	copy(policy.ToNode[:], toNode)
+
+	// The max HTLC is only part of the update if the message flags say
+	// so.
+	if policy.MessageFlags.HasMaxHtlc() {
+		policy.MaxHTLC = lnwire.MilliSatoshi(dbPolicy.MaxHtlcMsat)
+	}
+
+	return policy
+}
+
+// splitPolicies sorts the given policy rows of a channel into the policies of
+// node 1 and node 2.
+func splitPolicies(dbChan sqlc.GraphChannel, node1Pub, node2Pub []byte,
+	dbPolicies []sqlc.GraphChannelPolicy) (*models.ChannelEdgePolicy,
+	*models.ChannelEdgePolicy) {
+
+	var (
+		chanID           = byteOrder.Uint64(dbChan.Scid)
+		policy1, policy2 *models.ChannelEdgePolicy
+	)
+	for _, dbPolicy := range dbPolicies {
+		switch dbPolicy.NodeID {
+		case dbChan.NodeID1:
+			policy1 = buildPolicy(chanID, dbPolicy, node2Pub)
+
+		case dbChan.NodeID2:
+			policy2 = buildPolicy(chanID, dbPolicy, node1Pub)
+		}
+	}
+
+	return policy1, policy2
+}
+
+// buildChannel converts a channel row into a ChannelEdgeInfo and fetches both
+// of its policies.
+func buildChannel(ctx context.Context, db SQLGraphQueries,
+	dbChan sqlc.GraphChannel, node1Pub, node2Pub []byte) (
+	*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+	*models.ChannelEdgePolicy, error) {
+
+	info, err := buildChannelInfo(dbChan, node1Pub, node2Pub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dbPolicies, err := db.GetChannelPolicies(ctx, dbChan.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	policy1, policy2 := splitPolicies(
+		dbChan, node1Pub, node2Pub, dbPolicies,
+	)
+
+	return info, policy1, policy2, nil
+}
+
+// buildChannelEdge converts a channel row into a ChannelEdge, fetching both of
+// its policies and nodes.
+func buildChannelEdge(ctx context.Context, db SQLGraphQueries,
+	dbChan sqlc.GraphChannel, node1Pub, node2Pub []byte) (*ChannelEdge,
+	error) {
+
+	info, policy1, policy2, err := buildChannel(
+		ctx, db, dbChan, node1Pub, node2Pub,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	node1, err := getNode(ctx, db, node1Pub)
+	if err != nil {
+		return nil, err
+	}
+	node2, err := getNode(ctx, db, node2Pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChannelEdge{
+		Info:    info,
+		Policy1: policy1,
+		Policy2: policy2,
+		Node1:   node1,
+		Node2:   node2,
+	}, nil
+}
+
+// getChannelBySCID returns the channel with the given ID along with both of
+// its policies. ErrEdgeNotFound is returned if the channel isn't known.
+func getChannelBySCID(ctx context.Context, db SQLGraphQueries,
+	chanID uint64) (*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+	*models.ChannelEdgePolicy, error) {
+
+	dbChan, err := db.GetChannelBySCID(ctx, scidBytes(chanID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil, nil, ErrEdgeNotFound
+
+	case err != nil:
+		return nil, nil, nil, err
+	}
+
+	return buildChannel(
+		ctx, db, dbChan.GraphChannel, dbChan.Node1PubKey,
+		dbChan.Node2PubKey,
+	)
+}
+
+// listChannelsPage returns the page of channels following the channel with
+// the given row ID, along with the number of rows read and the row ID of the
+// last channel of the page.
+func listChannelsPage(ctx context.Context, db SQLGraphQueries,
+	lastID int64) ([]ChannelEdge, int, int64, error) {
+
+	dbChans, err := db.ListChannelsPaginated(
+		ctx, sqlc.ListChannelsPaginatedParams{
+			ID:    lastID,
+			Limit: graphQueryPaginationLimit,
+		},
+	)
+	if err != nil {
+		return nil, 0, lastID, err
+	}
+	if len(dbChans) == 0 {
+		return nil, 0, lastID, nil
+	}
+
+	// Fetch the policies of the whole page at once instead of querying
+	// them channel by channel.
+	firstID := dbChans[0].GraphChannel.ID
+	newLastID := dbChans[len(dbChans)-1].GraphChannel.ID
+	dbPolicies, err := db.ListChannelPoliciesByChannelIDRange(
+		ctx, sqlc.ListChannelPoliciesByChannelIDRangeParams{
+			StartID: firstID,
+			EndID:   newLastID,
+		},
+	)
+	if err != nil {
+		return nil, 0, lastID, err
+	}
+
+	policiesByChan := make(map[int64][]sqlc.GraphChannelPolicy)
+	for _, dbPolicy := range dbPolicies {
+		policiesByChan[dbPolicy.ChannelID] = append(
+			policiesByChan[dbPolicy.ChannelID], dbPolicy,
+		)
+	}
+
+	channels := make([]ChannelEdge, 0, len(dbChans))
+	for _, dbChan := range dbChans {
+		info, err := buildChannelInfo(
+			dbChan.GraphChannel, dbChan.Node1PubKey,
+			dbChan.Node2PubKey,
+		)
+		if err != nil {
+			return nil, 0, lastID, err
+		}
+
+		policy1, policy2 := splitPolicies(
+			dbChan.GraphChannel, dbChan.Node1PubKey,
+			dbChan.Node2PubKey,
+			policiesByChan[dbChan.GraphChannel.ID],
+		)
+
+		channels = append(channels, ChannelEdge{
+			Info:    info,
+			Policy1: policy1,
+			Policy2: policy2,
+		})
+	}
+
+	return channels, len(dbChans), newLastID, nil
+}
+
+// deleteChannel removes the channel with the given ID from the graph and
+// optionally marks it as a zombie. ErrEdgeNotFound is returned if the channel
+// isn't known.
+func deleteChannel(ctx context.Context, db SQLGraphQueries, chanID uint64,
+	markZombie, strictZombie bool) (*models.ChannelEdgeInfo, error) {
+
+	dbChan, err := db.GetChannelBySCID(ctx, scidBytes(chanID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrEdgeNotFound
+
+	case err != nil:
+		return nil, err
+	}
+
+	info, policy1, policy2, err := buildChannel(
+		ctx, db, dbChan.GraphChannel, dbChan.Node1PubKey,
+		dbChan.Node2PubKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The policies of the channel are deleted along with it.
+	if err := db.DeleteChannel(ctx, dbChan.GraphChannel.ID); err != nil {
+		return nil, err
+	}
+
+	if !markZombie {
+		return info, nil
+	}
+
+	nodeKey1, nodeKey2 := info.NodeKey1Bytes, info.NodeKey2Bytes
+	if strictZombie {
+		nodeKey1, nodeKey2 = makeZombiePubkeys(info, policy1, policy2)
+	}
+
+	err = markChannelZombie(ctx, db, chanID, nodeKey1, nodeKey2)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// markChannelZombie adds the channel with the given ID to the zombie index.
+func markChannelZombie(ctx context.Context, db SQLGraphQueries,
+	chanID uint64, pubKey1, pubKey2 [33]byte) error {
+
+	return db.UpsertZombieChannel(ctx, sqlc.UpsertZombieChannelParams{
+		Scid:     scidBytes(chanID),
+		NodeKey1: pubKey1[:],
+		NodeKey2: pubKey2[:],
+	})
+}
+
+// isZombieChannel returns whether the channel with the given ID is in the
+// zombie index, along with the node keys that can resurrect it.
+func isZombieChannel(ctx context.Context, db SQLGraphQueries,
+	chanID uint64) (bool, [33]byte, [33]byte, error) {
+
+	var pubKey1, pubKey2 [33]byte
+
+	zombie, err := db.GetZombieChannel(ctx, scidBytes(chanID))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return false, pubKey1, pubKey2, nil
+
+	case err != nil:
+		return false, pubKey1, pubKey2, err
+	}
+
+	copy(pubKey1[:], zombie.NodeKey1)
+	copy(pubKey2[:], zombie.NodeKey2)
+
+	return true, pubKey1, pubKey2, nil
+}
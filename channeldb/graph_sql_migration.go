@@ -0,0 +1,299 @@
+package channeldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+)
+
+var (
+	// graphSQLMigratedKey is the key within the graph meta bucket that is
+	// set once the kv graph has been migrated to the SQL graph store.
+	graphSQLMigratedKey = []byte("graph-sql-migrated")
+)
+
+// graphPendingSQLMigration returns true if the kv graph of the given database
+// holds any nodes that haven't been migrated to the SQL graph store yet.
+func graphPendingSQLMigration(db kvdb.Backend) (bool, error) {
+	var pending bool
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		graphMeta := tx.ReadBucket(graphMetaBucket)
+		if graphMeta != nil &&
+			graphMeta.Get(graphSQLMigratedKey) != nil {
+
+			return nil
+		}
+
+		nodes := tx.ReadBucket(nodeBucket)
+		if nodes == nil {
+			return nil
+		}
+
+		// The node bucket also holds the nested index buckets, which
+		// don't have a value and don't count as graph data.
+		c := nodes.ReadCursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				pending = true
+				return nil
+			}
+		}
+
+		return nil
+	}, func() {
+		pending = false
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return pending, nil
+}
+
+// migrateGraphToSQL copies the nodes, channels, policies, zombie index, closed
+// SCIDs and prune log of the kv graph into the given SQL store in a single
+// transaction. The passed graph must not use the SQL store yet, so that it
+// reads from the kv buckets. Once done, a marker is written to the kv graph so
+// that the migration only runs once. An empty graph isn't migrated. The kv
+// graph itself is left untouched.
+func migrateGraphToSQL(kvGraph *ChannelGraph, store *SQLGraphStore) error {
+	pending, err := graphPendingSQLMigration(kvGraph.db)
+	if err != nil {
+		return err
+	}
+	if !pending {
+		return nil
+	}
+
+	log.Infof("Migrating channel graph to SQL store")
+	startTime := time.Now()
+
+	var (
+		ctx    = context.Background()
+		txOpts SQLGraphQueriesTxOptions
+
+		numNodes, numChannels, numPolicies int
+		numZombies, numClosed, numPruned   int
+	)
+	err = store.db.ExecTx(ctx, &txOpts, func(db SQLGraphQueries) error {
+		err := kvGraph.ForEachNode(func(_ kvdb.RTx,
+			node *LightningNode) error {
+
+			if _, err := upsertNode(ctx, db, node); err != nil {
+				return err
+			}
+			numNodes++
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to migrate nodes: %w", err)
+		}
+
+		sourceNode, err := kvGraph.SourceNode()
+		switch {
+		// A graph without a source node has nothing more to migrate
+		// here.
+		case errors.Is(err, ErrSourceNodeNotSet):
+
+		case err != nil:
+			return fmt.Errorf("unable to fetch source node: %w",
+				err)
+
+		default:
+			err := setSourceNode(ctx, db, sourceNode)
+			if err != nil {
+				return fmt.Errorf("unable to migrate source "+
+					"node: %w", err)
+			}
+		}
+
+		err = kvGraph.ForEachChannel(func(info *models.ChannelEdgeInfo,
+			policy1, policy2 *models.ChannelEdgePolicy) error {
+
+			err := insertChannel(ctx, db, info)
+			if err != nil && !errors.Is(err, ErrEdgeAlreadyExist) {
+				return err
+			}
+			numChannels++
+
+			for _, policy := range []*models.ChannelEdgePolicy{
+				policy1, policy2,
+			} {
+				if policy == nil {
+					continue
+				}
+
+				_, _, _, err := upsertEdgePolicy(
+					ctx, db, policy,
+				)
+				if err != nil {
+					return err
+				}
+				numPolicies++
+			}
+
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrGraphNoEdgesFound) {
+			return fmt.Errorf("unable to migrate channels: %w", err)
+		}
+
+		return kvdb.View(kvGraph.db, func(tx kvdb.RTx) error {
+			numZombies, err = migrateZombieIndex(ctx, db, tx)
+			if err != nil {
+				return fmt.Errorf("unable to migrate zombie "+
+					"index: %w", err)
+			}
+
+			numClosed, err = migrateClosedSCIDs(ctx, db, tx)
+			if err != nil {
+				return fmt.Errorf("unable to migrate closed "+
+					"SCIDs: %w", err)
+			}
+
+			numPruned, err = migratePruneLog(ctx, db, tx)
+			if err != nil {
+				return fmt.Errorf("unable to migrate prune "+
+					"log: %w", err)
+			}
+
+			return nil
+		}, func() {
+			numZombies, numClosed, numPruned = 0, 0, 0
+		})
+	}, func() {
+		numNodes, numChannels, numPolicies = 0, 0, 0
+		numZombies, numClosed, numPruned = 0, 0, 0
+	})
+	if err != nil {
+		return err
+	}
+
+	err = kvdb.Update(kvGraph.db, func(tx kvdb.RwTx) error {
+		graphMeta, err := tx.CreateTopLevelBucket(graphMetaBucket)
+		if err != nil {
+			return err
+		}
+
+		return graphMeta.Put(graphSQLMigratedKey, []byte{})
+	}, func() {})
+	if err != nil {
+		return fmt.Errorf("unable to mark graph as migrated: %w", err)
+	}
+
+	log.Infof("Migrated channel graph to SQL store (took %v): %d nodes, "+
+		"%d channels, %d policies, %d zombies, %d closed SCIDs, %d "+
+		"prune log entries", time.Since(startTime), numNodes,
+		numChannels, numPolicies, numZombies, numClosed, numPruned)
+
+	return nil
+}
+
+// migrateZombieIndex copies the zombie index of the kv graph into the SQL
+// store and returns the number of migrated zombies.
+func migrateZombieIndex(ctx context.Context, db SQLGraphQueries,
+	tx kvdb.RTx) (int, error) {
+
+	edges := tx.ReadBucket(edgeBucket)
+	if edges == nil {
+		return 0, nil
+	}
+	zombieIndex := edges.NestedReadBucket(zombieBucket)
+	if zombieIndex == nil {
+		return 0, nil
+	}
+
+	var numZombies int
+	err := zombieIndex.ForEach(func(k, v []byte) error {
+		if len(k) != 8 || len(v) != 66 {
+			return fmt.Errorf("invalid zombie entry %x: %x", k, v)
+		}
+
+		err := db.UpsertZombieChannel(
+			ctx, sqlc.UpsertZombieChannelParams{
+				Scid:     k,
+				NodeKey1: v[:33],
+				NodeKey2: v[33:],
+			},
+		)
+		if err != nil {
+			return err
+		}
+		numZombies++
+
+		return nil
+	})
+
+	return numZombies, err
+}
+
+// migrateClosedSCIDs copies the closed SCIDs of the kv graph into the SQL
+// store and returns the number of migrated SCIDs.
+func migrateClosedSCIDs(ctx context.Context, db SQLGraphQueries,
+	tx kvdb.RTx) (int, error) {
+
+	closedScids := tx.ReadBucket(closedScidBucket)
+	if closedScids == nil {
+		return 0, nil
+	}
+
+	var numClosed int
+	err := closedScids.ForEach(func(k, _ []byte) error {
+		if len(k) != 8 {
+			return fmt.Errorf("invalid closed SCID %x", k)
+		}
+
+		if err := db.InsertClosedSCID(ctx, k); err != nil {
+			return err
+		}
+		numClosed++
+
+		return nil
+	})
+
+	return numClosed, err
+}
+
+// migratePruneLog copies the prune log of the kv graph into the SQL store and
+// returns the number of migrated entries.
+func migratePruneLog(ctx context.Context, db SQLGraphQueries,
+	tx kvdb.RTx) (int, error) {
+
+	graphMeta := tx.ReadBucket(graphMetaBucket)
+	if graphMeta == nil {
+		return 0, nil
+	}
+	pruneBucket := graphMeta.NestedReadBucket(pruneLogBucket)
+	if pruneBucket == nil {
+		return 0, nil
+	}
+
+	var numPruned int
+	err := pruneBucket.ForEach(func(k, v []byte) error {
+		if len(k) != 4 || len(v) != pruneTipBytes {
+			return fmt.Errorf("invalid prune log entry %x: %x", k,
+				v)
+		}
+
+		err := db.UpsertPruneLogEntry(
+			ctx, sqlc.UpsertPruneLogEntryParams{
+				BlockHeight: int64(byteOrder.Uint32(k)),
+				BlockHash:   v,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		numPruned++
+
+		return nil
+	})
+
+	return numPruned, err
+}
@@ -0,0 +1,439 @@
+package channeldb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTestSQLGraphStore creates a new SQL graph store backed by a fresh
+// sqlite database.
+func makeTestSQLGraphStore(t *testing.T) *SQLGraphStore {
+	db := sqldb.NewTestSqliteDB(t).BaseDB
+
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) SQLGraphQueries {
+			return db.WithTx(tx)
+		},
+	)
+
+	return NewSQLGraphStore(executor)
+}
+
+// makeTestGraphWithStore creates a new ChannelGraph on top of the given kv
+// backend that keeps its graph in the given SQL store.
+func makeTestGraphWithStore(t *testing.T, backend kvdb.Backend,
+	store *SQLGraphStore) *ChannelGraph {
+
+	opts := DefaultOptions()
+	graph, err := NewChannelGraph(
+		backend, opts.RejectCacheSize, opts.ChannelCacheSize,
+		opts.BatchCommitInterval, opts.PreAllocCacheNumNodes,
+		true, false, WithSQLStore(store),
+	)
+	require.NoError(t, err)
+
+	return graph
+}
+
+// makeTestKVBackend creates a new kv backend for testing purposes.
+func makeTestKVBackend(t *testing.T) kvdb.Backend {
+	backend, backendCleanup, err := kvdb.GetTestBackend(t.TempDir(), "cgr")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = backend.Close()
+		backendCleanup()
+	})
+
+	return backend
+}
+
+// makeSQLTestGraph creates a new ChannelGraph that keeps its graph in a fresh
+// sqlite database.
+func makeSQLTestGraph(t *testing.T) *ChannelGraph {
+	return makeTestGraphWithStore(
+		t, makeTestKVBackend(t), makeTestSQLGraphStore(t),
+	)
+}
+
+// TestSQLGraphNodes tests that nodes can be added, fetched, looked up and
+// deleted through a SQL backed channel graph.
+func TestSQLGraphNodes(t *testing.T) {
+	t.Parallel()
+
+	graph := makeSQLTestGraph(t)
+
+	// Without a source node, fetching it should fail.
+	_, err := graph.SourceNode()
+	require.ErrorIs(t, err, ErrSourceNodeNotSet)
+
+	sourceNode, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.SetSourceNode(sourceNode))
+
+	dbSource, err := graph.SourceNode()
+	require.NoError(t, err)
+	require.NoError(t, compareNodes(sourceNode, dbSource))
+
+	node, err := createTestVertex(nil)
+	require.NoError(t, err)
+	node.ExtraOpaqueData = []byte{1, 2, 3}
+	require.NoError(t, graph.AddLightningNode(node))
+
+	dbNode, err := graph.FetchLightningNode(node.PubKeyBytes)
+	require.NoError(t, err)
+	require.NoError(t, compareNodes(node, dbNode))
+	require.Equal(t, node.Features, dbNode.Features)
+
+	updateTime, exists, err := graph.HasLightningNode(node.PubKeyBytes)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, node.LastUpdate, updateTime)
+
+	nodePub, err := node.PubKey()
+	require.NoError(t, err)
+	alias, err := graph.LookupAlias(nodePub)
+	require.NoError(t, err)
+	require.Equal(t, node.Alias, alias)
+
+	// A newer announcement should replace the stored node.
+	node.Alias = "updated"
+	node.LastUpdate = node.LastUpdate.Add(time.Second)
+	node.Addresses = node.Addresses[:1]
+	require.NoError(t, graph.AddLightningNode(node))
+
+	dbNode, err = graph.FetchLightningNode(node.PubKeyBytes)
+	require.NoError(t, err)
+	require.NoError(t, compareNodes(node, dbNode))
+
+	var numNodes int
+	err = graph.ForEachNode(func(_ kvdb.RTx, _ *LightningNode) error {
+		numNodes++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, numNodes)
+
+	require.NoError(t, graph.DeleteLightningNode(node.PubKeyBytes))
+
+	_, err = graph.FetchLightningNode(node.PubKeyBytes)
+	require.ErrorIs(t, err, ErrGraphNodeNotFound)
+
+	err = graph.DeleteLightningNode(node.PubKeyBytes)
+	require.ErrorIs(t, err, ErrGraphNodeNotFound)
+}
+
+// TestSQLGraphChannels tests that channels and their policies can be added,
+// fetched and deleted through a SQL backed channel graph.
+func TestSQLGraphChannels(t *testing.T) {
+	t.Parallel()
+
+	graph := makeSQLTestGraph(t)
+
+	sourceNode, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.SetSourceNode(sourceNode))
+
+	node1, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.AddLightningNode(node1))
+
+	// The second node is only known from the channel, so a shell node is
+	// created for it.
+	node2, err := createTestVertex(nil)
+	require.NoError(t, err)
+
+	edgeInfo, edge1, edge2 := createChannelEdge(nil, node1, node2)
+	require.NoError(t, graph.AddChannelEdge(edgeInfo))
+	require.ErrorIs(
+		t, graph.AddChannelEdge(edgeInfo), ErrEdgeAlreadyExist,
+	)
+
+	shellNode, err := graph.FetchLightningNode(node2.PubKeyBytes)
+	require.NoError(t, err)
+	require.False(t, shellNode.HaveNodeAnnouncement)
+
+	// Once the node announces itself, the shell node is replaced.
+	require.NoError(t, graph.AddLightningNode(node2))
+	assertNodeInCache(t, graph, node2, testFeatures)
+
+	// Before any policies are known, the channel is returned without
+	// them.
+	dbInfo, dbPolicy1, dbPolicy2, err := graph.FetchChannelEdgesByID(
+		edgeInfo.ChannelID,
+	)
+	require.NoError(t, err)
+	assertEdgeInfoEqual(t, edgeInfo, dbInfo)
+	require.Nil(t, dbPolicy1)
+	require.Nil(t, dbPolicy2)
+
+	require.NoError(t, graph.UpdateEdgePolicy(edge1))
+	require.NoError(t, graph.UpdateEdgePolicy(edge2))
+
+	dbInfo, dbPolicy1, dbPolicy2, err = graph.FetchChannelEdgesByOutpoint(
+		&edgeInfo.ChannelPoint,
+	)
+	require.NoError(t, err)
+	assertEdgeInfoEqual(t, edgeInfo, dbInfo)
+	require.NoError(t, compareEdgePolicies(edge1, dbPolicy1))
+	require.NoError(t, compareEdgePolicies(edge2, dbPolicy2))
+
+	upd1, upd2, exists, isZombie, err := graph.HasChannelEdge(
+		edgeInfo.ChannelID,
+	)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.False(t, isZombie)
+	require.Equal(t, edge1.LastUpdate, upd1)
+	require.Equal(t, edge2.LastUpdate, upd2)
+
+	chanID, err := graph.ChannelID(&edgeInfo.ChannelPoint)
+	require.NoError(t, err)
+	require.Equal(t, edgeInfo.ChannelID, chanID)
+
+	highestChanID, err := graph.HighestChanID()
+	require.NoError(t, err)
+	require.Equal(t, edgeInfo.ChannelID, highestChanID)
+
+	var numChans int
+	err = graph.ForEachNodeChannel(node1.PubKeyBytes,
+		func(_ kvdb.RTx, info *models.ChannelEdgeInfo,
+			outPolicy, inPolicy *models.ChannelEdgePolicy) error {
+
+			numChans++
+			assertEdgeInfoEqual(t, edgeInfo, info)
+
+			// The outgoing policy is the one of the node we're
+			// iterating over.
+			require.Equal(
+				t, node1.PubKeyBytes == edgeInfo.NodeKey1Bytes,
+				outPolicy.ChannelFlags&
+					lnwire.ChanUpdateDirection == 0,
+			)
+			require.NotNil(t, inPolicy)
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numChans)
+
+	chans, err := graph.ChanUpdatesInHorizon(
+		time.Unix(0, 0), time.Unix(500000, 0),
+	)
+	require.NoError(t, err)
+	require.Len(t, chans, 1)
+
+	// The channel and both of its policies should be in the graph cache.
+	assertNodeInCache(t, graph, node1, testFeatures)
+	assertEdgeWithPolicyInCache(t, graph, edgeInfo, edge1, true)
+	assertEdgeWithPolicyInCache(t, graph, edgeInfo, edge2, false)
+
+	// Deleting the channel and marking it as a zombie should move it to
+	// the zombie index.
+	require.NoError(
+		t, graph.DeleteChannelEdges(false, true, edgeInfo.ChannelID),
+	)
+	assertNoEdge(t, graph, edgeInfo.ChannelID)
+	assertNumZombies(t, graph, 1)
+
+	_, _, _, err = graph.FetchChannelEdgesByID(edgeInfo.ChannelID)
+	require.ErrorIs(t, err, ErrZombieEdge)
+
+	err = graph.DeleteChannelEdges(false, true, edgeInfo.ChannelID)
+	require.ErrorIs(t, err, ErrEdgeNotFound)
+
+	require.NoError(t, graph.MarkEdgeLive(edgeInfo.ChannelID))
+	assertNumZombies(t, graph, 0)
+	require.ErrorIs(
+		t, graph.MarkEdgeLive(edgeInfo.ChannelID),
+		ErrZombieEdgeNotFound,
+	)
+
+	_, _, _, err = graph.FetchChannelEdgesByID(edgeInfo.ChannelID)
+	require.ErrorIs(t, err, ErrEdgeNotFound)
+}
+
+// TestSQLGraphPruning tests that pruning and disconnecting blocks from a SQL
+// backed channel graph removes the affected channels and nodes and updates
+// the prune log.
+func TestSQLGraphPruning(t *testing.T) {
+	t.Parallel()
+
+	graph := makeSQLTestGraph(t)
+
+	_, _, err := graph.PruneTip()
+	require.ErrorIs(t, err, ErrGraphNeverPruned)
+
+	sourceNode, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.SetSourceNode(sourceNode))
+
+	node1, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.AddLightningNode(node1))
+	node2, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.AddLightningNode(node2))
+
+	edgeInfo1, _ := createEdge(100, 0, 0, 0, node1, node2)
+	require.NoError(t, graph.AddChannelEdge(&edgeInfo1))
+	edgeInfo2, _ := createEdge(101, 0, 0, 1, sourceNode, node1)
+	require.NoError(t, graph.AddChannelEdge(&edgeInfo2))
+	edgeInfo3, _ := createEdge(102, 0, 0, 2, sourceNode, node2)
+	require.NoError(t, graph.AddChannelEdge(&edgeInfo3))
+
+	chanRanges, err := graph.FilterChannelRange(0, 200, false)
+	require.NoError(t, err)
+	require.Len(t, chanRanges, 3)
+	require.EqualValues(t, 100, chanRanges[0].Height)
+
+	// Pruning the first channel shouldn't prune any nodes, as all of them
+	// still have channels left.
+	blockHash := chainhash.Hash{1}
+	closed, err := graph.PruneGraph(
+		[]*wire.OutPoint{&edgeInfo1.ChannelPoint}, &blockHash, 100,
+	)
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+	require.Equal(t, edgeInfo1.ChannelID, closed[0].ChannelID)
+	assertPruneTip(t, graph, &blockHash, 100)
+	assertNumChans(t, graph, 2)
+	assertNumNodes(t, graph, 3)
+
+	// Disconnecting the block of the last channel should remove it.
+	removed, err := graph.DisconnectBlockAtHeight(102)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, edgeInfo3.ChannelID, removed[0].ChannelID)
+	assertNumChans(t, graph, 1)
+
+	// Now that node2 is unconnected, it should be pruned, while the
+	// source node is kept.
+	require.NoError(t, graph.PruneGraphNodes())
+	assertNumNodes(t, graph, 2)
+	assertNodeNotInCache(t, graph, node2.PubKeyBytes)
+
+	// The closed SCID index should be queryable as well.
+	scid := lnwire.NewShortChanIDFromInt(edgeInfo1.ChannelID)
+	isClosed, err := graph.IsClosedScid(scid)
+	require.NoError(t, err)
+	require.False(t, isClosed)
+
+	require.NoError(t, graph.PutClosedScid(scid))
+	isClosed, err = graph.IsClosedScid(scid)
+	require.NoError(t, err)
+	require.True(t, isClosed)
+}
+
+// TestSQLGraphMigration tests that an existing kv graph is migrated to the
+// SQL store by the explicit migration.
+func TestSQLGraphMigration(t *testing.T) {
+	t.Parallel()
+
+	backend := makeTestKVBackend(t)
+
+	// Fill the kv graph first.
+	opts := DefaultOptions()
+	kvGraph, err := NewChannelGraph(
+		backend, opts.RejectCacheSize, opts.ChannelCacheSize,
+		opts.BatchCommitInterval, opts.PreAllocCacheNumNodes,
+		true, false,
+	)
+	require.NoError(t, err)
+
+	sourceNode, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, kvGraph.SetSourceNode(sourceNode))
+
+	node1, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, kvGraph.AddLightningNode(node1))
+	node2, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, kvGraph.AddLightningNode(node2))
+
+	edgeInfo, edge1, edge2 := createChannelEdge(nil, node1, node2)
+	require.NoError(t, kvGraph.AddChannelEdge(edgeInfo))
+	require.NoError(t, kvGraph.UpdateEdgePolicy(edge1))
+	require.NoError(t, kvGraph.UpdateEdgePolicy(edge2))
+
+	zombieID := edgeInfo.ChannelID + 1
+	require.NoError(
+		t, kvGraph.MarkEdgeZombie(
+			zombieID, node1.PubKeyBytes, node2.PubKeyBytes,
+		),
+	)
+
+	closedScid := lnwire.NewShortChanIDFromInt(edgeInfo.ChannelID + 2)
+	require.NoError(t, kvGraph.PutClosedScid(closedScid))
+
+	blockHash := chainhash.Hash{2}
+	_, err = kvGraph.PruneGraph(nil, &blockHash, 1234)
+	require.NoError(t, err)
+
+	// The kv graph must be migrated before the graph can be opened with a
+	// SQL store.
+	pending, err := graphPendingSQLMigration(backend)
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	store := makeTestSQLGraphStore(t)
+	require.NoError(t, migrateGraphToSQL(kvGraph, store))
+
+	pending, err = graphPendingSQLMigration(backend)
+	require.NoError(t, err)
+	require.False(t, pending)
+
+	graph := makeTestGraphWithStore(t, backend, store)
+
+	dbSource, err := graph.SourceNode()
+	require.NoError(t, err)
+	require.NoError(t, compareNodes(sourceNode, dbSource))
+
+	for _, node := range []*LightningNode{node1, node2} {
+		dbNode, err := graph.FetchLightningNode(node.PubKeyBytes)
+		require.NoError(t, err)
+		require.NoError(t, compareNodes(node, dbNode))
+	}
+
+	dbInfo, dbPolicy1, dbPolicy2, err := graph.FetchChannelEdgesByID(
+		edgeInfo.ChannelID,
+	)
+	require.NoError(t, err)
+	assertEdgeInfoEqual(t, edgeInfo, dbInfo)
+	require.NoError(t, compareEdgePolicies(edge1, dbPolicy1))
+	require.NoError(t, compareEdgePolicies(edge2, dbPolicy2))
+
+	isZombie, pub1, pub2 := graph.IsZombieEdge(zombieID)
+	require.True(t, isZombie)
+	require.Equal(t, node1.PubKeyBytes, pub1)
+	require.Equal(t, node2.PubKeyBytes, pub2)
+
+	isClosed, err := graph.IsClosedScid(closedScid)
+	require.NoError(t, err)
+	require.True(t, isClosed)
+
+	assertPruneTip(t, graph, &blockHash, 1234)
+
+	// The migration should only run once, so a node that is only added to
+	// the SQL store should survive migrating again and re-opening the
+	// graph.
+	node3, err := createTestVertex(nil)
+	require.NoError(t, err)
+	require.NoError(t, graph.AddLightningNode(node3))
+	require.NoError(t, migrateGraphToSQL(kvGraph, store))
+
+	graph = makeTestGraphWithStore(t, backend, store)
+	assertNumNodes(t, graph, 4)
+	assertNumChans(t, graph, 1)
+}
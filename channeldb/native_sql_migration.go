@@ -0,0 +1,130 @@
+package channeldb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+)
+
+var (
+	// ErrNativeSQLMigrationOK signals that the kv data was migrated to the
+	// native SQL stores as requested. The database isn't opened afterwards.
+	ErrNativeSQLMigrationOK = errors.New("native SQL migration successful")
+
+	// ErrNativeSQLMigrationRequired is returned when the database is
+	// opened with a native SQL store while the kv database still holds data
+	// that hasn't been migrated to it.
+	ErrNativeSQLMigrationRequired = errors.New("kv data must be migrated " +
+		"to native SQL first")
+)
+
+// pendingNativeSQLMigration returns true if the given top level bucket of the
+// kv database holds any data while the marker that is set in the given meta
+// bucket once the data was migrated to native SQL is missing.
+func pendingNativeSQLMigration(db kvdb.Backend, dataBucket, metaBucket,
+	markerKey []byte) (bool, error) {
+
+	var pending bool
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		meta := tx.ReadBucket(metaBucket)
+		if meta != nil && meta.Get(markerKey) != nil {
+			return nil
+		}
+
+		data := tx.ReadBucket(dataBucket)
+		if data == nil {
+			return nil
+		}
+
+		k, _ := data.ReadCursor().First()
+		pending = k != nil
+
+		return nil
+	}, func() {
+		pending = false
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return pending, nil
+}
+
+// migrateToNativeSQL migrates the kv data of the database to the native SQL
+// stores that are set in the given options. Data that was migrated before is
+// skipped. The channel graph of the database must not use the SQL store, so
+// that it reads from the kv buckets.
+func (d *DB) migrateToNativeSQL(opts *Options) error {
+	if opts.graphSQLStore != nil {
+		err := migrateGraphToSQL(d.graph, opts.graphSQLStore)
+		if err != nil {
+			return fmt.Errorf("unable to migrate channel graph to "+
+				"SQL: %w", err)
+		}
+	}
+
+	if opts.paymentsSQLStore != nil {
+		err := migratePaymentsToSQL(d.Backend, opts.paymentsSQLStore)
+		if err != nil {
+			return fmt.Errorf("unable to migrate payments to "+
+				"SQL: %w", err)
+		}
+	}
+
+	if opts.fwdLogSQLStore != nil {
+		err := migrateForwardingLogToSQL(d, opts.fwdLogSQLStore)
+		if err != nil {
+			return fmt.Errorf("unable to migrate forwarding "+
+				"log to SQL: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkNativeSQLMigrated returns ErrNativeSQLMigrationRequired if the kv
+// database still holds data that needs to be migrated to one of the native SQL
+// stores set in the given options.
+func checkNativeSQLMigrated(db kvdb.Backend, opts *Options) error {
+	if opts.graphSQLStore != nil {
+		pending, err := graphPendingSQLMigration(db)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return fmt.Errorf("%w: channel graph",
+				ErrNativeSQLMigrationRequired)
+		}
+	}
+
+	if opts.paymentsSQLStore != nil {
+		pending, err := pendingNativeSQLMigration(
+			db, paymentsRootBucket, metaBucket,
+			paymentsSQLMigratedKey,
+		)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return fmt.Errorf("%w: payments",
+				ErrNativeSQLMigrationRequired)
+		}
+	}
+
+	if opts.fwdLogSQLStore != nil {
+		pending, err := pendingNativeSQLMigration(
+			db, forwardingLogBucket, metaBucket,
+			fwdLogSQLMigratedKey,
+		)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return fmt.Errorf("%w: forwarding log",
+				ErrNativeSQLMigrationRequired)
+		}
+	}
+
+	return nil
+}
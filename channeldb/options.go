@@ -82,6 +82,22 @@ type Options struct {
 	// storeFinalHtlcResolutions determines whether to persistently store
 	// the final resolution of incoming htlcs.
 	storeFinalHtlcResolutions bool
+
+	// graphSQLStore is an optional SQL store that the channel graph is
+	// kept in instead of the kv buckets.
+	graphSQLStore *SQLGraphStore
+
+	// paymentsSQLStore is an optional SQL store that the payments are kept
+	// in instead of the kv buckets.
+	paymentsSQLStore *SQLPaymentsStore
+
+	// fwdLogSQLStore is an optional SQL store that the forwarding log is
+	// kept in instead of the kv bucket.
+	fwdLogSQLStore *SQLForwardingLogStore
+
+	// migrateNativeSQL migrates the kv data to the native SQL stores that
+	// are set instead of opening the database if set to true.
+	migrateNativeSQL bool
 }
 
 // DefaultOptions returns an Options populated with default values.
@@ -134,6 +150,34 @@ func OptionSetUseGraphCache(use bool) OptionModifier {
 	}
 }
 
+// OptionSetGraphSQLStore sets the SQL store the channel graph is kept in. Any
+// existing kv graph must be migrated to it with OptionMigrateNativeSQL first.
+func OptionSetGraphSQLStore(store *SQLGraphStore) OptionModifier {
+	return func(o *Options) {
+		o.graphSQLStore = store
+	}
+}
+
+// OptionSetPaymentsSQLStore sets the SQL store the payments are kept in. Any
+// existing kv payments must be migrated to it with OptionMigrateNativeSQL
+// first.
+func OptionSetPaymentsSQLStore(store *SQLPaymentsStore) OptionModifier {
+	return func(o *Options) {
+		o.paymentsSQLStore = store
+	}
+}
+
+// OptionSetForwardingLogSQLStore sets the SQL store the forwarding log is kept
+// in. Any existing kv forwarding events must be migrated to it with
+// OptionMigrateNativeSQL first.
+func OptionSetForwardingLogSQLStore(
+	store *SQLForwardingLogStore) OptionModifier {
+
+	return func(o *Options) {
+		o.fwdLogSQLStore = store
+	}
+}
+
 // OptionNoRevLogAmtData sets the NoRevLogAmtData option to the given value. If
 // it is set to true then amount data will not be stored in the revocation log.
 func OptionNoRevLogAmtData(noAmtData bool) OptionModifier {
@@ -195,6 +239,15 @@ func OptionDryRunMigration(dryRun bool) OptionModifier {
 	}
 }
 
+// OptionMigrateNativeSQL controls whether the kv data is migrated to the native
+// SQL stores that are set. If set, the database isn't opened and
+// ErrNativeSQLMigrationOK is returned once the migration succeeded.
+func OptionMigrateNativeSQL(migrate bool) OptionModifier {
+	return func(o *Options) {
+		o.migrateNativeSQL = migrate
+	}
+}
+
 // OptionKeepFailedPaymentAttempts controls whether failed payment attempts are
 // kept on disk after a payment settles.
 func OptionKeepFailedPaymentAttempts(keepFailedPaymentAttempts bool) OptionModifier {
@@ -2,6 +2,7 @@ package channeldb
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -195,6 +196,10 @@ type PaymentCreationInfo struct {
 
 	// PaymentRequest is the full payment request, if any.
 	PaymentRequest []byte
+
+	// Rebalance indicates that this is a circular payment to ourselves
+	// that moves liquidity between our own channels.
+	Rebalance bool
 }
 
 // htlcBucketKey creates a composite key from prefix and id where the result is
@@ -210,6 +215,10 @@ func htlcBucketKey(prefix, id []byte) []byte {
 //
 // nolint: dupl
 func (d *DB) FetchPayments() ([]*MPPayment, error) {
+	if d.paymentsSQLStore != nil {
+		return d.paymentsSQLStore.FetchPayments(context.Background())
+	}
+
 	var payments []*MPPayment
 
 	err := kvdb.View(d, func(tx kvdb.RTx) error {
@@ -516,6 +525,12 @@ type PaymentsResponse struct {
 // to a subset of payments by the payments query, containing an offset
 // index and a maximum number of returned payments.
 func (d *DB) QueryPayments(query PaymentsQuery) (PaymentsResponse, error) {
+	if d.paymentsSQLStore != nil {
+		return d.paymentsSQLStore.QueryPayments(
+			context.Background(), query,
+		)
+	}
+
 	var resp PaymentsResponse
 
 	if err := kvdb.View(d, func(tx kvdb.RTx) error {
@@ -735,6 +750,12 @@ func fetchPaymentWithSequenceNumber(tx kvdb.RTx, paymentHash lntypes.Hash,
 func (d *DB) DeletePayment(paymentHash lntypes.Hash,
 	failedHtlcsOnly bool) error {
 
+	if d.paymentsSQLStore != nil {
+		return d.paymentsSQLStore.DeletePayment(
+			context.Background(), paymentHash, failedHtlcsOnly,
+		)
+	}
+
 	return kvdb.Update(d, func(tx kvdb.RwTx) error {
 		payments := tx.ReadWriteBucket(paymentsRootBucket)
 		if payments == nil {
@@ -824,6 +845,12 @@ func (d *DB) DeletePayment(paymentHash lntypes.Hash,
 // failedHtlsOnly is set, the payment itself won't be deleted, only failed HTLC
 // attempts.
 func (d *DB) DeletePayments(failedOnly, failedHtlcsOnly bool) error {
+	if d.paymentsSQLStore != nil {
+		return d.paymentsSQLStore.DeletePayments(
+			context.Background(), failedOnly, failedHtlcsOnly,
+		)
+	}
+
 	return kvdb.Update(d, func(tx kvdb.RwTx) error {
 		payments := tx.ReadWriteBucket(paymentsRootBucket)
 		if payments == nil {
@@ -1010,7 +1037,13 @@ func serializePaymentCreationInfo(w io.Writer, c *PaymentCreationInfo) error {
 		return err
 	}
 
-	return nil
+	// The rebalance flag is only written for rebalances, so that the
+	// creation info of all other payments keeps its original format.
+	if !c.Rebalance {
+		return nil
+	}
+
+	return binary.Write(w, byteOrder, c.Rebalance)
 }
 
 func deserializePaymentCreationInfo(r io.Reader) (*PaymentCreationInfo, error) {
@@ -1046,6 +1079,18 @@ func deserializePaymentCreationInfo(r io.Reader) (*PaymentCreationInfo, error) {
 	}
 	c.PaymentRequest = payReq
 
+	err = binary.Read(r, byteOrder, &c.Rebalance)
+
+	switch {
+	// Payments that aren't rebalances don't have the flag set, in which
+	// case we can just return.
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return c, nil
+
+	case err != nil:
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -1172,6 +1217,12 @@ func serializeHop(w io.Writer, h *route.Hop) error {
 		)
 	}
 
+	if h.TrampolineOnion != nil {
+		records = append(records, record.NewTrampolineOnionRecord(
+			&h.TrampolineOnion,
+		))
+	}
+
 	// Final sanity check to absolutely rule out custom records that are not
 	// custom and write into the standard range.
 	if err := h.CustomRecords.Validate(); err != nil {
@@ -1350,6 +1401,13 @@ func deserializeHop(r io.Reader) (*route.Hop, error) {
 		h.TotalAmtMsat = lnwire.MilliSatoshi(totalAmtMsatInt)
 	}
 
+	trampolineType := uint64(record.TrampolineOnionType)
+	if onion, ok := tlvMap[trampolineType]; ok {
+		delete(tlvMap, trampolineType)
+
+		h.TrampolineOnion = onion
+	}
+
 	h.CustomRecords = tlvMap
 
 	return h, nil
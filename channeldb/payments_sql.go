@@ -0,0 +1,1143 @@
+package channeldb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+)
+
+const (
+	// paymentsQueryPaginationLimit is used in the LIMIT clause of the
+	// payments SQL queries that iterate over a range of payments.
+	paymentsQueryPaginationLimit = 100
+)
+
+// SQLPaymentsQueries is an interface that defines the set of operations that
+// can be executed against the payments SQL database.
+type SQLPaymentsQueries interface { //nolint:interfacebloat
+	// Payment specific methods.
+	NextPaymentSequenceNum(ctx context.Context) (int64, error)
+
+	SetPaymentSequenceNum(ctx context.Context, currentValue int64) error
+
+	InsertPayment(ctx context.Context, arg sqlc.InsertPaymentParams) (int64,
+		error)
+
+	FetchPayment(ctx context.Context,
+		paymentIdentifier []byte) (sqlc.Payment, error)
+
+	FetchPaymentsByStatus(ctx context.Context,
+		status int16) ([]sqlc.Payment, error)
+
+	FilterPayments(ctx context.Context,
+		arg sqlc.FilterPaymentsParams) ([]sqlc.Payment, error)
+
+	CountPayments(ctx context.Context) (int64, error)
+
+	UpdatePaymentStatus(ctx context.Context,
+		arg sqlc.UpdatePaymentStatusParams) error
+
+	UpdatePaymentFailureReason(ctx context.Context,
+		arg sqlc.UpdatePaymentFailureReasonParams) error
+
+	SetPaymentDestination(ctx context.Context,
+		arg sqlc.SetPaymentDestinationParams) error
+
+	DeletePaymentByID(ctx context.Context, id int64) error
+
+	DeletePaymentsByIdentifier(ctx context.Context,
+		paymentIdentifier []byte) error
+
+	DeletePaymentsByStatus(ctx context.Context, status int16) error
+
+	DeleteAllPayments(ctx context.Context) error
+
+	// HTLC attempt specific methods.
+	InsertHTLCAttempt(ctx context.Context,
+		arg sqlc.InsertHTLCAttemptParams) (int64, error)
+
+	GetHTLCAttemptID(ctx context.Context,
+		arg sqlc.GetHTLCAttemptIDParams) (int64, error)
+
+	FetchHTLCAttempts(ctx context.Context,
+		paymentID int64) ([]sqlc.PaymentHtlcAttempt, error)
+
+	SettleHTLCAttempt(ctx context.Context,
+		arg sqlc.SettleHTLCAttemptParams) error
+
+	InsertHTLCAttemptFailure(ctx context.Context,
+		arg sqlc.InsertHTLCAttemptFailureParams) error
+
+	FetchHTLCAttemptFailures(ctx context.Context,
+		paymentID int64) ([]sqlc.PaymentHtlcAttemptFailure, error)
+
+	DeleteFailedHTLCAttempts(ctx context.Context, paymentID int64) error
+
+	DeleteFailedHTLCAttemptsByStatus(ctx context.Context,
+		status int16) error
+
+	// Route specific methods.
+	InsertRouteHop(ctx context.Context,
+		arg sqlc.InsertRouteHopParams) error
+
+	FetchRouteHops(ctx context.Context,
+		paymentID int64) ([]sqlc.PaymentRouteHop, error)
+
+	InsertRouteHopCustomRecord(ctx context.Context,
+		arg sqlc.InsertRouteHopCustomRecordParams) error
+
+	FetchRouteHopCustomRecords(ctx context.Context,
+		paymentID int64) ([]sqlc.PaymentRouteHopCustomRecord, error)
+}
+
+// SQLPaymentsQueriesTxOptions defines the set of db txn options the
+// SQLPaymentsQueries understands.
+type SQLPaymentsQueriesTxOptions struct {
+	// readOnly governs if a read only transaction is needed or not.
+	readOnly bool
+}
+
+// ReadOnly returns true if the transaction should be read only.
+//
+// NOTE: This implements the TxOptions.
+func (a *SQLPaymentsQueriesTxOptions) ReadOnly() bool {
+	return a.readOnly
+}
+
+// NewSQLPaymentsQueryReadTx creates a new read transaction option set.
+func NewSQLPaymentsQueryReadTx() SQLPaymentsQueriesTxOptions {
+	return SQLPaymentsQueriesTxOptions{
+		readOnly: true,
+	}
+}
+
+// BatchedSQLPaymentsQueries is a version of the SQLPaymentsQueries that's
+// capable of batched database operations.
+type BatchedSQLPaymentsQueries interface {
+	SQLPaymentsQueries
+
+	sqldb.BatchedTx[SQLPaymentsQueries]
+}
+
+// SQLPaymentsStore is a SQL backed store of the payments we sent. Payments,
+// HTLC attempts, their failures and the hops of their routes each live in
+// their own table. The payments are indexed by their creation time, status
+// and destination, so that they can be queried without a full scan.
+type SQLPaymentsStore struct {
+	db BatchedSQLPaymentsQueries
+}
+
+// NewSQLPaymentsStore creates a new SQLPaymentsStore instance given an open
+// BatchedSQLPaymentsQueries storage backend.
+func NewSQLPaymentsStore(db BatchedSQLPaymentsQueries) *SQLPaymentsStore {
+	return &SQLPaymentsStore{
+		db: db,
+	}
+}
+
+// InitPayment checks or records the given PaymentCreationInfo with the DB,
+// making sure it does not already exist as an in-flight payment. A failed
+// payment is replaced by the new one, dropping its HTLC attempts and failure
+// reason.
+func (s *SQLPaymentsStore) InitPayment(ctx context.Context,
+	paymentHash lntypes.Hash, info *PaymentCreationInfo) error {
+
+	var (
+		txOpts    SQLPaymentsQueriesTxOptions
+		updateErr error
+	)
+	err := s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		row, payment, err := fetchPaymentByHash(ctx, db, paymentHash)
+		switch {
+		// If we already have this payment, we'll check the status to
+		// decide whether we allow retrying the payment. A retried
+		// payment is removed along with its attempts, so that it can
+		// be inserted again with a new sequence number.
+		case err == nil:
+			if err := payment.Status.initializable(); err != nil {
+				updateErr = err
+				return nil
+			}
+
+			err := db.DeletePaymentByID(ctx, row.ID)
+			if err != nil {
+				return err
+			}
+
+		case !errors.Is(err, ErrPaymentNotInitiated):
+			return err
+		}
+
+		seqNum, err := db.NextPaymentSequenceNum(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.InsertPayment(ctx, sqlc.InsertPaymentParams{
+			SequenceNum:       seqNum,
+			PaymentIdentifier: info.PaymentIdentifier[:],
+			AmountMsat:        int64(info.Value),
+			CreatedAt:         info.CreationTime.UTC(),
+			PaymentRequest:    info.PaymentRequest,
+			Status:            int16(StatusInitiated),
+			Rebalance:         info.Rebalance,
+		})
+
+		return err
+	}, func() {
+		updateErr = nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to init payment: %w", err)
+	}
+
+	return updateErr
+}
+
+// RegisterAttempt atomically records the provided HTLCAttemptInfo.
+func (s *SQLPaymentsStore) RegisterAttempt(ctx context.Context,
+	paymentHash lntypes.Hash, attempt *HTLCAttemptInfo) (*MPPayment,
+	error) {
+
+	var (
+		txOpts  SQLPaymentsQueriesTxOptions
+		payment *MPPayment
+	)
+	err := s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		row, current, err := fetchPaymentByHash(ctx, db, paymentHash)
+		if err != nil {
+			return err
+		}
+
+		// Check if registering the new attempt is allowed.
+		if err := validateNewAttempt(current, attempt); err != nil {
+			return err
+		}
+
+		err = insertHTLCAttempt(ctx, db, row.ID, &HTLCAttempt{
+			HTLCAttemptInfo: *attempt,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The destination of the payment is taken from the first
+		// attempt that is registered for it.
+		if dest := routeDestination(&attempt.Route); dest != nil {
+			err := db.SetPaymentDestination(
+				ctx, sqlc.SetPaymentDestinationParams{
+					ID:          row.ID,
+					Destination: dest,
+				},
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		payment, err = refreshPaymentStatus(ctx, db, paymentHash)
+
+		return err
+	}, func() {
+		payment = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// SettleAttempt marks the given attempt settled with the preimage.
+func (s *SQLPaymentsStore) SettleAttempt(ctx context.Context,
+	paymentHash lntypes.Hash, attemptID uint64,
+	settleInfo *HTLCSettleInfo) (*MPPayment, error) {
+
+	return s.updateAttempt(
+		ctx, paymentHash, attemptID,
+		func(db SQLPaymentsQueries, id int64) error {
+			return db.SettleHTLCAttempt(
+				ctx, sqlc.SettleHTLCAttemptParams{
+					ID:             id,
+					SettlePreimage: settleInfo.Preimage[:],
+					SettleTime: sqldb.SQLTime(
+						settleInfo.SettleTime.UTC(),
+					),
+				},
+			)
+		},
+	)
+}
+
+// FailAttempt marks the given payment attempt failed.
+func (s *SQLPaymentsStore) FailAttempt(ctx context.Context,
+	paymentHash lntypes.Hash, attemptID uint64,
+	failInfo *HTLCFailInfo) (*MPPayment, error) {
+
+	return s.updateAttempt(
+		ctx, paymentHash, attemptID,
+		func(db SQLPaymentsQueries, id int64) error {
+			return insertHTLCAttemptFailure(ctx, db, id, failInfo)
+		},
+	)
+}
+
+// updateAttempt applies the given update to an in-flight HTLC attempt of the
+// payment and returns the updated payment.
+func (s *SQLPaymentsStore) updateAttempt(ctx context.Context,
+	paymentHash lntypes.Hash, attemptID uint64,
+	update func(db SQLPaymentsQueries, id int64) error) (*MPPayment,
+	error) {
+
+	var (
+		txOpts  SQLPaymentsQueriesTxOptions
+		payment *MPPayment
+	)
+	err := s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		row, current, err := fetchPaymentByHash(ctx, db, paymentHash)
+		if err != nil {
+			return err
+		}
+
+		// We can only update attempts of in-flight payments. We allow
+		// updating attempts even if the payment has reached a terminal
+		// condition, since the HTLC outcomes must still be updated.
+		if err := current.Status.updatable(); err != nil {
+			return err
+		}
+
+		htlc, err := current.GetAttempt(attemptID)
+		if err != nil {
+			return fmt.Errorf("HTLC with ID %v not registered",
+				attemptID)
+		}
+
+		// Make sure the shard is not already failed or settled.
+		switch {
+		case htlc.Failure != nil:
+			return ErrAttemptAlreadyFailed
+
+		case htlc.Settle != nil:
+			return ErrAttemptAlreadySettled
+		}
+
+		id, err := db.GetHTLCAttemptID(ctx, sqlc.GetHTLCAttemptIDParams{
+			PaymentID: row.ID,
+			AttemptID: int64(attemptID),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := update(db, id); err != nil {
+			return err
+		}
+
+		payment, err = refreshPaymentStatus(ctx, db, paymentHash)
+
+		return err
+	}, func() {
+		payment = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// Fail transitions a payment into the Failed state, and records the reason the
+// payment failed. ErrPaymentNotInitiated is returned if the payment is not
+// known.
+func (s *SQLPaymentsStore) Fail(ctx context.Context, paymentHash lntypes.Hash,
+	reason FailureReason) (*MPPayment, error) {
+
+	var (
+		txOpts  SQLPaymentsQueriesTxOptions
+		payment *MPPayment
+	)
+	err := s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		row, err := db.FetchPayment(ctx, paymentHash[:])
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPaymentNotInitiated
+		} else if err != nil {
+			return err
+		}
+
+		err = db.UpdatePaymentFailureReason(
+			ctx, sqlc.UpdatePaymentFailureReasonParams{
+				ID: row.ID,
+				FailureReason: sql.NullInt16{
+					Int16: int16(reason),
+					Valid: true,
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		payment, err = refreshPaymentStatus(ctx, db, paymentHash)
+
+		return err
+	}, func() {
+		payment = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// FetchPayment returns the payment with the given payment hash.
+// ErrPaymentNotInitiated is returned if the payment is not known.
+func (s *SQLPaymentsStore) FetchPayment(ctx context.Context,
+	paymentHash lntypes.Hash) (*MPPayment, error) {
+
+	var (
+		readTxOpts = NewSQLPaymentsQueryReadTx()
+		payment    *MPPayment
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLPaymentsQueries) error {
+		var err error
+		_, payment, err = fetchPaymentByHash(ctx, db, paymentHash)
+
+		return err
+	}, func() {
+		payment = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// FetchInFlightPayments returns all payments that haven't reached a terminal
+// state yet, ordered by their sequence number.
+func (s *SQLPaymentsStore) FetchInFlightPayments(
+	ctx context.Context) ([]*MPPayment, error) {
+
+	var (
+		readTxOpts = NewSQLPaymentsQueryReadTx()
+		inFlights  []*MPPayment
+	)
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLPaymentsQueries) error {
+		for _, status := range []PaymentStatus{
+			StatusInitiated, StatusInFlight,
+		} {
+			rows, err := db.FetchPaymentsByStatus(
+				ctx, int16(status),
+			)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				payment, err := buildPayment(ctx, db, row)
+				if err != nil {
+					return err
+				}
+
+				inFlights = append(inFlights, payment)
+			}
+		}
+
+		return nil
+	}, func() {
+		inFlights = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(inFlights, func(i, j int) bool {
+		return inFlights[i].SequenceNum < inFlights[j].SequenceNum
+	})
+
+	return inFlights, nil
+}
+
+// FetchPayments returns all payments, including legacy duplicate payments,
+// ordered by their sequence number.
+func (s *SQLPaymentsStore) FetchPayments(ctx context.Context) ([]*MPPayment,
+	error) {
+
+	resp, err := s.QueryPayments(ctx, PaymentsQuery{
+		MaxPayments:       ^uint64(0),
+		IncludeIncomplete: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Payments, nil
+}
+
+// QueryPayments returns the payments that match the given query. The query is
+// answered from the indexes on the sequence number, status and creation time
+// of the payments, so only the returned payments are read from the database.
+func (s *SQLPaymentsStore) QueryPayments(ctx context.Context,
+	query PaymentsQuery) (PaymentsResponse, error) {
+
+	var (
+		readTxOpts = NewSQLPaymentsQueryReadTx()
+		resp       PaymentsResponse
+	)
+
+	params := sqlc.FilterPaymentsParams{
+		Reverse: query.Reversed,
+	}
+
+	// To keep compatibility with the old API, we only return
+	// non-succeeded payments if requested.
+	if !query.IncludeIncomplete {
+		params.Status = sql.NullInt16{
+			Int16: int16(StatusSucceeded),
+			Valid: true,
+		}
+	}
+
+	// The creation date filters are expressed in unix seconds and are both
+	// inclusive, so the end of the range is the start of the next second.
+	if query.CreationDateStart != 0 {
+		params.CreatedAfter = sqldb.SQLTime(
+			time.Unix(query.CreationDateStart, 0).UTC(),
+		)
+	}
+	if query.CreationDateEnd != 0 {
+		params.CreatedBefore = sqldb.SQLTime(
+			time.Unix(query.CreationDateEnd+1, 0).UTC(),
+		)
+	}
+
+	err := s.db.ExecTx(ctx, &readTxOpts, func(db SQLPaymentsQueries) error {
+		indexOffset := query.IndexOffset
+		for uint64(len(resp.Payments)) < query.MaxPayments {
+			// A zero offset starts with the oldest payment, or ends
+			// with the most recent one when paginating backwards.
+			params.IndexOffsetAfter = sql.NullInt64{}
+			params.IndexOffsetBefore = sql.NullInt64{}
+			switch {
+			case !query.Reversed:
+				params.IndexOffsetAfter = sqldb.SQLInt64(
+					indexOffset,
+				)
+
+			case indexOffset != 0:
+				params.IndexOffsetBefore = sqldb.SQLInt64(
+					indexOffset,
+				)
+			}
+
+			limit := query.MaxPayments - uint64(len(resp.Payments))
+			if limit > paymentsQueryPaginationLimit {
+				limit = paymentsQueryPaginationLimit
+			}
+			params.NumLimit = int32(limit)
+
+			rows, err := db.FilterPayments(ctx, params)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				payment, err := buildPayment(ctx, db, row)
+				if err != nil {
+					return err
+				}
+
+				resp.Payments = append(resp.Payments, payment)
+				indexOffset = payment.SequenceNum
+			}
+
+			if uint64(len(rows)) < limit {
+				break
+			}
+		}
+
+		if query.CountTotal {
+			totalPayments, err := db.CountPayments(ctx)
+			if err != nil {
+				return fmt.Errorf("error counting payments: %w",
+					err)
+			}
+
+			resp.TotalCount = uint64(totalPayments)
+		}
+
+		return nil
+	}, func() {
+		resp = PaymentsResponse{}
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	// Need to swap the payments slice order if reversed order.
+	if query.Reversed {
+		for l, r := 0, len(resp.Payments)-1; l < r; l, r = l+1, r-1 {
+			resp.Payments[l], resp.Payments[r] =
+				resp.Payments[r], resp.Payments[l]
+		}
+	}
+
+	// Set the first and last index of the returned payments so that the
+	// caller can resume from this point later on.
+	if len(resp.Payments) > 0 {
+		resp.FirstIndexOffset = resp.Payments[0].SequenceNum
+		resp.LastIndexOffset =
+			resp.Payments[len(resp.Payments)-1].SequenceNum
+	}
+
+	return resp, nil
+}
+
+// DeletePayment deletes the payment with the given payment hash, along with
+// any legacy duplicate payments. If failedHtlcsOnly is set, only the failed
+// HTLC attempts of the payment are deleted.
+func (s *SQLPaymentsStore) DeletePayment(ctx context.Context,
+	paymentHash lntypes.Hash, failedHtlcsOnly bool) error {
+
+	var txOpts SQLPaymentsQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		row, payment, err := fetchPaymentByHash(ctx, db, paymentHash)
+		if err != nil {
+			return err
+		}
+
+		// If the payment has inflight HTLCs, we cannot safely delete
+		// the payment information, so we return an error.
+		if err := payment.Status.removable(); err != nil {
+			return fmt.Errorf("payment '%v' has inflight HTLCs "+
+				"and therefore cannot be deleted: %w",
+				paymentHash.String(), err)
+		}
+
+		if failedHtlcsOnly {
+			return db.DeleteFailedHTLCAttempts(ctx, row.ID)
+		}
+
+		return db.DeletePaymentsByIdentifier(ctx, paymentHash[:])
+	}, func() {})
+}
+
+// DeletePayments deletes all completed and failed payments. If failedOnly is
+// set, only failed payments are deleted. If failedHtlcsOnly is set, the
+// payments themselves are kept and only their failed HTLC attempts are
+// deleted.
+func (s *SQLPaymentsStore) DeletePayments(ctx context.Context, failedOnly,
+	failedHtlcsOnly bool) error {
+
+	// Payments with inflight HTLCs cannot be safely deleted, which leaves
+	// the payments with any of the following statuses.
+	statuses := []PaymentStatus{StatusFailed}
+	if !failedOnly {
+		statuses = append(statuses, StatusInitiated, StatusSucceeded)
+	}
+
+	var txOpts SQLPaymentsQueriesTxOptions
+
+	return s.db.ExecTx(ctx, &txOpts, func(db SQLPaymentsQueries) error {
+		for _, status := range statuses {
+			var err error
+			if failedHtlcsOnly {
+				err = db.DeleteFailedHTLCAttemptsByStatus(
+					ctx, int16(status),
+				)
+			} else {
+				err = db.DeletePaymentsByStatus(
+					ctx, int16(status),
+				)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func() {})
+}
+
+// fetchPaymentByHash returns the database row and the full payment with the
+// given payment hash. ErrPaymentNotInitiated is returned if the payment is not
+// known.
+func fetchPaymentByHash(ctx context.Context, db SQLPaymentsQueries,
+	paymentHash lntypes.Hash) (sqlc.Payment, *MPPayment, error) {
+
+	row, err := db.FetchPayment(ctx, paymentHash[:])
+	if errors.Is(err, sql.ErrNoRows) {
+		return row, nil, ErrPaymentNotInitiated
+	} else if err != nil {
+		return row, nil, err
+	}
+
+	payment, err := buildPayment(ctx, db, row)
+	if err != nil {
+		return row, nil, err
+	}
+
+	return row, payment, nil
+}
+
+// refreshPaymentStatus reads back the payment with the given payment hash
+// after it was updated and stores its new status, so that the payment can be
+// filtered by it.
+func refreshPaymentStatus(ctx context.Context, db SQLPaymentsQueries,
+	paymentHash lntypes.Hash) (*MPPayment, error) {
+
+	row, payment, err := fetchPaymentByHash(ctx, db, paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if row.Status == int16(payment.Status) {
+		return payment, nil
+	}
+
+	err = db.UpdatePaymentStatus(ctx, sqlc.UpdatePaymentStatusParams{
+		ID:     row.ID,
+		Status: int16(payment.Status),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// insertPayment inserts the given payment along with all its HTLC attempts.
+// This is used to migrate payments, which is why the sequence number and
+// status of the payment are taken as is.
+func insertPayment(ctx context.Context, db SQLPaymentsQueries,
+	payment *MPPayment, duplicate bool) error {
+
+	var failureReason sql.NullInt16
+	if payment.FailureReason != nil {
+		failureReason = sql.NullInt16{
+			Int16: int16(*payment.FailureReason),
+			Valid: true,
+		}
+	}
+
+	var destination []byte
+	if len(payment.HTLCs) != 0 {
+		destination = routeDestination(&payment.HTLCs[0].Route)
+	}
+
+	info := payment.Info
+	id, err := db.InsertPayment(ctx, sqlc.InsertPaymentParams{
+		SequenceNum:       int64(payment.SequenceNum),
+		PaymentIdentifier: info.PaymentIdentifier[:],
+		Duplicate:         duplicate,
+		AmountMsat:        int64(info.Value),
+		CreatedAt:         info.CreationTime.UTC(),
+		PaymentRequest:    info.PaymentRequest,
+		FailureReason:     failureReason,
+		Status:            int16(payment.Status),
+		Destination:       destination,
+		Rebalance:         info.Rebalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range payment.HTLCs {
+		err := insertHTLCAttempt(ctx, db, id, &payment.HTLCs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// routeDestination returns the public key of the final hop of the given
+// route, or nil if the route has no hops.
+func routeDestination(rt *route.Route) []byte {
+	if len(rt.Hops) == 0 {
+		return nil
+	}
+
+	return rt.FinalHop().PubKeyBytes[:]
+}
+
+// insertHTLCAttempt inserts the given HTLC attempt of a payment along with the
+// hops of its route and its settle or failure info, if any.
+func insertHTLCAttempt(ctx context.Context, db SQLPaymentsQueries,
+	paymentID int64, htlc *HTLCAttempt) error {
+
+	var hash []byte
+	if htlc.Hash != nil {
+		hash = htlc.Hash[:]
+	}
+
+	params := sqlc.InsertHTLCAttemptParams{
+		PaymentID:          paymentID,
+		AttemptID:          int64(htlc.AttemptID),
+		SessionKey:         htlc.sessionKey[:],
+		AttemptTime:        htlc.AttemptTime.UTC(),
+		Hash:               hash,
+		RouteTotalTimeLock: int64(htlc.Route.TotalTimeLock),
+		RouteTotalAmount:   int64(htlc.Route.TotalAmount),
+		RouteSourceKey:     htlc.Route.SourcePubKey[:],
+	}
+	if htlc.Settle != nil {
+		params.SettlePreimage = htlc.Settle.Preimage[:]
+		params.SettleTime = sqldb.SQLTime(htlc.Settle.SettleTime.UTC())
+	}
+
+	id, err := db.InsertHTLCAttempt(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	for i, hop := range htlc.Route.Hops {
+		if err := insertRouteHop(ctx, db, id, i, hop); err != nil {
+			return err
+		}
+	}
+
+	if htlc.Failure != nil {
+		return insertHTLCAttemptFailure(ctx, db, id, htlc.Failure)
+	}
+
+	return nil
+}
+
+// insertRouteHop inserts the hop at the given position of the route of an
+// HTLC attempt, along with its custom records.
+func insertRouteHop(ctx context.Context, db SQLPaymentsQueries,
+	attemptID int64, index int, hop *route.Hop) error {
+
+	// Rule out custom records that are not custom and write into the
+	// standard range.
+	if err := hop.CustomRecords.Validate(); err != nil {
+		return err
+	}
+
+	params := sqlc.InsertRouteHopParams{
+		HtlcAttemptID:    attemptID,
+		HopIndex:         int32(index),
+		PubKey:           hop.PubKeyBytes[:],
+		Scid:             scidBytes(hop.ChannelID),
+		OutgoingTimeLock: int64(hop.OutgoingTimeLock),
+		AmtToForward:     int64(hop.AmtToForward),
+		LegacyPayload:    hop.LegacyPayload,
+		EncryptedData:    hop.EncryptedData,
+		Metadata:         hop.Metadata,
+		TrampolineOnion:  hop.TrampolineOnion,
+	}
+	if hop.MPP != nil {
+		paymentAddr := hop.MPP.PaymentAddr()
+		params.MppPaymentAddr = paymentAddr[:]
+		params.MppTotalMsat = sqldb.SQLInt64(hop.MPP.TotalMsat())
+	}
+	if hop.AMP != nil {
+		rootShare, setID := hop.AMP.RootShare(), hop.AMP.SetID()
+		params.AmpRootShare = rootShare[:]
+		params.AmpSetID = setID[:]
+		params.AmpChildIndex = sqldb.SQLInt64(hop.AMP.ChildIndex())
+	}
+	if hop.BlindingPoint != nil {
+		params.BlindingPoint = hop.BlindingPoint.SerializeCompressed()
+	}
+	if hop.TotalAmtMsat != 0 {
+		params.TotalAmtMsat = sqldb.SQLInt64(hop.TotalAmtMsat)
+	}
+
+	if err := db.InsertRouteHop(ctx, params); err != nil {
+		return err
+	}
+
+	for key, value := range hop.CustomRecords {
+		err := db.InsertRouteHopCustomRecord(
+			ctx, sqlc.InsertRouteHopCustomRecordParams{
+				HtlcAttemptID: attemptID,
+				HopIndex:      int32(index),
+				Key:           int64(key),
+				Value:         nonNilBytes(value),
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertHTLCAttemptFailure records the failure of the HTLC attempt with the
+// given ID.
+func insertHTLCAttemptFailure(ctx context.Context, db SQLPaymentsQueries,
+	attemptID int64, failure *HTLCFailInfo) error {
+
+	var failureMsg []byte
+	if failure.Message != nil {
+		var b bytes.Buffer
+		err := lnwire.EncodeFailureMessage(&b, failure.Message, 0)
+		if err != nil {
+			return err
+		}
+		failureMsg = b.Bytes()
+	}
+
+	return db.InsertHTLCAttemptFailure(
+		ctx, sqlc.InsertHTLCAttemptFailureParams{
+			HtlcAttemptID:      attemptID,
+			FailTime:           failure.FailTime.UTC(),
+			Reason:             int16(failure.Reason),
+			FailureSourceIndex: int64(failure.FailureSourceIndex),
+			FailureMsg:         failureMsg,
+		},
+	)
+}
+
+// buildPayment reads the HTLC attempts of the given payment row and assembles
+// the full payment.
+func buildPayment(ctx context.Context, db SQLPaymentsQueries,
+	row sqlc.Payment) (*MPPayment, error) {
+
+	identifier, err := lntypes.MakeHash(row.PaymentIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	htlcs, err := fetchHTLCAttempts(ctx, db, row.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failureReason *FailureReason
+	if row.FailureReason.Valid {
+		reason := FailureReason(row.FailureReason.Int16)
+		failureReason = &reason
+	}
+
+	payment := &MPPayment{
+		SequenceNum: uint64(row.SequenceNum),
+		Info: &PaymentCreationInfo{
+			PaymentIdentifier: identifier,
+			Value:             lnwire.MilliSatoshi(row.AmountMsat),
+			CreationTime:      row.CreatedAt.Local(),
+			PaymentRequest:    nonNilBytes(row.PaymentRequest),
+			Rebalance:         row.Rebalance,
+		},
+		HTLCs:         htlcs,
+		FailureReason: failureReason,
+	}
+
+	// Legacy duplicate payments keep the status they were migrated with,
+	// as their state can't be derived from their attempts.
+	if row.Duplicate {
+		payment.Status = PaymentStatus(row.Status)
+
+		return payment, nil
+	}
+
+	if err := payment.setState(); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// fetchHTLCAttempts returns all HTLC attempts of the payment with the given ID
+// ordered by their attempt ID.
+func fetchHTLCAttempts(ctx context.Context, db SQLPaymentsQueries,
+	paymentID int64) ([]HTLCAttempt, error) {
+
+	rows, err := db.FetchHTLCAttempts(ctx, paymentID)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	// Collect the custom records of each hop, so that they can be added
+	// to the hops as they are built.
+	type hopKey struct {
+		attemptID int64
+		index     int32
+	}
+	recordRows, err := db.FetchRouteHopCustomRecords(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	customRecords := make(map[hopKey]record.CustomSet)
+	for _, r := range recordRows {
+		key := hopKey{attemptID: r.HtlcAttemptID, index: r.HopIndex}
+		if customRecords[key] == nil {
+			customRecords[key] = make(record.CustomSet)
+		}
+		customRecords[key][uint64(r.Key)] = nonNilBytes(r.Value)
+	}
+
+	hopRows, err := db.FetchRouteHops(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	hops := make(map[int64][]*route.Hop)
+	for _, h := range hopRows {
+		key := hopKey{attemptID: h.HtlcAttemptID, index: h.HopIndex}
+		hop, err := buildRouteHop(h, customRecords[key])
+		if err != nil {
+			return nil, err
+		}
+
+		hops[h.HtlcAttemptID] = append(hops[h.HtlcAttemptID], hop)
+	}
+
+	failureRows, err := db.FetchHTLCAttemptFailures(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	failures := make(map[int64]*HTLCFailInfo, len(failureRows))
+	for _, f := range failureRows {
+		failure, err := buildHTLCFailInfo(f)
+		if err != nil {
+			return nil, err
+		}
+
+		failures[f.HtlcAttemptID] = failure
+	}
+
+	htlcs := make([]HTLCAttempt, 0, len(rows))
+	for _, row := range rows {
+		htlc := HTLCAttempt{
+			HTLCAttemptInfo: HTLCAttemptInfo{
+				AttemptID: uint64(row.AttemptID),
+				Route: route.Route{
+					TotalTimeLock: uint32(
+						row.RouteTotalTimeLock,
+					),
+					TotalAmount: lnwire.MilliSatoshi(
+						row.RouteTotalAmount,
+					),
+					Hops: hops[row.ID],
+				},
+				AttemptTime: row.AttemptTime.Local(),
+			},
+			Failure: failures[row.ID],
+		}
+		copy(htlc.sessionKey[:], row.SessionKey)
+		copy(htlc.Route.SourcePubKey[:], row.RouteSourceKey)
+
+		if row.Hash != nil {
+			hash, err := lntypes.MakeHash(row.Hash)
+			if err != nil {
+				return nil, err
+			}
+			htlc.Hash = &hash
+		}
+
+		if row.SettlePreimage != nil {
+			preimage, err := lntypes.MakePreimage(
+				row.SettlePreimage,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			htlc.Settle = &HTLCSettleInfo{
+				Preimage:   preimage,
+				SettleTime: row.SettleTime.Time.Local(),
+			}
+		}
+
+		htlcs = append(htlcs, htlc)
+	}
+
+	return htlcs, nil
+}
+
+// buildRouteHop creates a route hop from the given database row and custom
+// records.
+func buildRouteHop(row sqlc.PaymentRouteHop,
+	customRecords record.CustomSet) (*route.Hop, error) {
+
+	if len(row.Scid) != 8 {
+		return nil, fmt.Errorf("invalid hop scid: %x", row.Scid)
+	}
+
+	hop := &route.Hop{
+		ChannelID:        byteOrder.Uint64(row.Scid),
+		OutgoingTimeLock: uint32(row.OutgoingTimeLock),
+		AmtToForward:     lnwire.MilliSatoshi(row.AmtToForward),
+		LegacyPayload:    row.LegacyPayload,
+		EncryptedData:    row.EncryptedData,
+		Metadata:         row.Metadata,
+		TrampolineOnion:  row.TrampolineOnion,
+		CustomRecords:    customRecords,
+	}
+	copy(hop.PubKeyBytes[:], row.PubKey)
+
+	if row.MppPaymentAddr != nil {
+		var paymentAddr [32]byte
+		copy(paymentAddr[:], row.MppPaymentAddr)
+
+		totalMsat := lnwire.MilliSatoshi(row.MppTotalMsat.Int64)
+		hop.MPP = record.NewMPP(totalMsat, paymentAddr)
+	}
+
+	if row.AmpRootShare != nil {
+		var rootShare, setID [32]byte
+		copy(rootShare[:], row.AmpRootShare)
+		copy(setID[:], row.AmpSetID)
+
+		hop.AMP = record.NewAMP(
+			rootShare, setID, uint32(row.AmpChildIndex.Int64),
+		)
+	}
+
+	if row.BlindingPoint != nil {
+		var err error
+		hop.BlindingPoint, err = btcec.ParsePubKey(row.BlindingPoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blinding point: %w",
+				err)
+		}
+	}
+
+	if row.TotalAmtMsat.Valid {
+		hop.TotalAmtMsat = lnwire.MilliSatoshi(row.TotalAmtMsat.Int64)
+	}
+
+	return hop, nil
+}
+
+// buildHTLCFailInfo creates the failure info of an HTLC attempt from the given
+// database row.
+func buildHTLCFailInfo(row sqlc.PaymentHtlcAttemptFailure) (*HTLCFailInfo,
+	error) {
+
+	failure := &HTLCFailInfo{
+		FailTime:           row.FailTime.Local(),
+		Reason:             HTLCFailReason(row.Reason),
+		FailureSourceIndex: uint32(row.FailureSourceIndex),
+	}
+
+	if len(row.FailureMsg) != 0 {
+		var err error
+		failure.Message, err = lnwire.DecodeFailureMessage(
+			bytes.NewReader(row.FailureMsg), 0,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return failure, nil
+}
@@ -0,0 +1,178 @@
+package channeldb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+)
+
+var (
+	// paymentsSQLMigratedKey is the key within the meta bucket that is set
+	// once the kv payments have been migrated to the SQL payments store.
+	paymentsSQLMigratedKey = []byte("payments-sql-migrated")
+)
+
+// paymentsMigratedToSQL returns true if the kv payments of the given database
+// have already been migrated to the SQL payments store.
+func paymentsMigratedToSQL(db kvdb.Backend) (bool, error) {
+	var migrated bool
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		meta := tx.ReadBucket(metaBucket)
+		if meta == nil {
+			return nil
+		}
+
+		migrated = meta.Get(paymentsSQLMigratedKey) != nil
+
+		return nil
+	}, func() {
+		migrated = false
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return migrated, nil
+}
+
+// migratePaymentsToSQL copies all payments of the kv database, including
+// legacy duplicate payments, along with their HTLC attempts into the given SQL
+// store in a single transaction. The payments keep their sequence numbers, and
+// the SQL sequence continues after the highest one handed out by the kv
+// store. Once done, a marker is written to the kv database so that the
+// migration only runs once. The kv payments themselves are left untouched.
+func migratePaymentsToSQL(db kvdb.Backend, store *SQLPaymentsStore) error {
+	migrated, err := paymentsMigratedToSQL(db)
+	if err != nil {
+		return err
+	}
+	if migrated {
+		return nil
+	}
+
+	log.Infof("Migrating payments to SQL store")
+	startTime := time.Now()
+
+	// The kv store reserves sequence numbers in blocks, so its sequence
+	// may be ahead of the highest sequence number of any payment. The
+	// sequence can only be read from a writable bucket.
+	var kvSeqNum uint64
+	err = kvdb.Update(db, func(tx kvdb.RwTx) error {
+		payments := tx.ReadWriteBucket(paymentsRootBucket)
+		if payments != nil {
+			kvSeqNum = payments.Sequence()
+		}
+
+		return nil
+	}, func() {
+		kvSeqNum = 0
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		ctx    = context.Background()
+		txOpts SQLPaymentsQueriesTxOptions
+
+		numPayments, numDuplicates, numAttempts int
+	)
+	err = store.db.ExecTx(ctx, &txOpts, func(q SQLPaymentsQueries) error {
+		return kvdb.View(db, func(tx kvdb.RTx) error {
+			payments := tx.ReadBucket(paymentsRootBucket)
+			if payments == nil {
+				return nil
+			}
+
+			lastSeqNum := kvSeqNum
+
+			err := payments.ForEach(func(k, _ []byte) error {
+				bucket := payments.NestedReadBucket(k)
+				if bucket == nil {
+					return fmt.Errorf("non bucket " +
+						"element in payments bucket")
+				}
+
+				payment, err := fetchPayment(bucket)
+				if err != nil {
+					return err
+				}
+
+				// For older versions of lnd, duplicate
+				// payments to a payment hash were possible.
+				duplicates, err := fetchDuplicatePayments(
+					bucket,
+				)
+				if err != nil {
+					return err
+				}
+
+				err = insertPayment(ctx, q, payment, false)
+				if err != nil {
+					return fmt.Errorf("unable to migrate "+
+						"payment %v: %w",
+						payment.Info.PaymentIdentifier,
+						err)
+				}
+				numPayments++
+				numAttempts += len(payment.HTLCs)
+
+				if payment.SequenceNum > lastSeqNum {
+					lastSeqNum = payment.SequenceNum
+				}
+
+				for _, dup := range duplicates {
+					err := insertPayment(ctx, q, dup, true)
+					if err != nil {
+						id := dup.Info.PaymentIdentifier
+						return fmt.Errorf("unable to "+
+							"migrate duplicate "+
+							"payment %v: %w", id,
+							err)
+					}
+					numDuplicates++
+					numAttempts += len(dup.HTLCs)
+
+					if dup.SequenceNum > lastSeqNum {
+						lastSeqNum = dup.SequenceNum
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			return q.SetPaymentSequenceNum(ctx, int64(lastSeqNum))
+		}, func() {
+			numPayments, numDuplicates, numAttempts = 0, 0, 0
+		})
+	}, func() {
+		numPayments, numDuplicates, numAttempts = 0, 0, 0
+	})
+	if err != nil {
+		return err
+	}
+
+	err = kvdb.Update(db, func(tx kvdb.RwTx) error {
+		meta, err := tx.CreateTopLevelBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		return meta.Put(paymentsSQLMigratedKey, []byte{})
+	}, func() {})
+	if err != nil {
+		return fmt.Errorf("unable to mark payments as migrated: %w",
+			err)
+	}
+
+	log.Infof("Migrated payments to SQL store (took %v): %d payments, %d "+
+		"duplicate payments, %d HTLC attempts", time.Since(startTime),
+		numPayments, numDuplicates, numAttempts)
+
+	return nil
+}
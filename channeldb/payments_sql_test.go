@@ -0,0 +1,516 @@
+package channeldb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTestSQLPaymentsStore creates a new SQL payments store backed by a fresh
+// sqlite database.
+func makeTestSQLPaymentsStore(t *testing.T) *SQLPaymentsStore {
+	db := sqldb.NewTestSqliteDB(t).BaseDB
+
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) SQLPaymentsQueries {
+			return db.WithTx(tx)
+		},
+	)
+
+	return NewSQLPaymentsStore(executor)
+}
+
+// makeSQLTestPaymentsDB creates a new channel database that keeps its
+// payments in a fresh sqlite database.
+func makeSQLTestPaymentsDB(t *testing.T) *DB {
+	db, err := MakeTestDB(
+		t, OptionSetPaymentsSQLStore(makeTestSQLPaymentsStore(t)),
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+// TestSQLPaymentControl tests the life cycle of a payment that is kept in the
+// SQL payments store.
+func TestSQLPaymentControl(t *testing.T) {
+	t.Parallel()
+
+	db := makeSQLTestPaymentsDB(t)
+	pControl := NewPaymentControl(db)
+
+	info, attempt, preimg, err := genInfo()
+	require.NoError(t, err)
+
+	// Payments that weren't initiated can't be fetched.
+	_, err = pControl.FetchPayment(info.PaymentIdentifier)
+	require.ErrorIs(t, err, ErrPaymentNotInitiated)
+
+	require.NoError(t, pControl.InitPayment(info.PaymentIdentifier, info))
+	assertPaymentStatus(
+		t, pControl, info.PaymentIdentifier, StatusInitiated,
+	)
+	assertPaymentInfo(t, pControl, info.PaymentIdentifier, info, nil, nil)
+
+	// A second init is refused while the payment is pending.
+	err = pControl.InitPayment(info.PaymentIdentifier, info)
+	require.ErrorIs(t, err, ErrPaymentExists)
+
+	payment, err := pControl.FetchPayment(info.PaymentIdentifier)
+	require.NoError(t, err)
+	firstSeqNum := payment.SequenceNum
+
+	// Fail the payment and initiate it once more, which should hand out a
+	// new sequence number.
+	failReason := FailureReasonNoRoute
+	_, err = pControl.Fail(info.PaymentIdentifier, failReason)
+	require.NoError(t, err)
+	assertPaymentStatus(t, pControl, info.PaymentIdentifier, StatusFailed)
+	assertPaymentInfo(
+		t, pControl, info.PaymentIdentifier, info, &failReason, nil,
+	)
+
+	require.NoError(t, pControl.InitPayment(info.PaymentIdentifier, info))
+	payment, err = pControl.FetchPayment(info.PaymentIdentifier)
+	require.NoError(t, err)
+	require.Greater(t, payment.SequenceNum, firstSeqNum)
+	assertPaymentInfo(t, pControl, info.PaymentIdentifier, info, nil, nil)
+
+	// Register an attempt and fail it.
+	_, err = pControl.RegisterAttempt(info.PaymentIdentifier, attempt)
+	require.NoError(t, err)
+	assertPaymentStatus(
+		t, pControl, info.PaymentIdentifier, StatusInFlight,
+	)
+
+	// Registering the same attempt twice is refused.
+	_, err = pControl.RegisterAttempt(info.PaymentIdentifier, attempt)
+	require.Error(t, err)
+
+	htlcReason := HTLCFailUnreadable
+	_, err = pControl.FailAttempt(
+		info.PaymentIdentifier, attempt.AttemptID,
+		&HTLCFailInfo{
+			Reason: htlcReason,
+		},
+	)
+	require.NoError(t, err)
+	assertPaymentInfo(
+		t, pControl, info.PaymentIdentifier, info, nil, &htlcStatus{
+			HTLCAttemptInfo: attempt,
+			failure:         &htlcReason,
+		},
+	)
+
+	// Failing the attempt once more is refused.
+	_, err = pControl.FailAttempt(
+		info.PaymentIdentifier, attempt.AttemptID,
+		&HTLCFailInfo{
+			Reason: htlcReason,
+		},
+	)
+	require.ErrorIs(t, err, ErrAttemptAlreadyFailed)
+
+	// Register a second attempt, which is listed as in-flight.
+	attempt.AttemptID = 1
+	_, err = pControl.RegisterAttempt(info.PaymentIdentifier, attempt)
+	require.NoError(t, err)
+
+	inFlight, err := pControl.FetchInFlightPayments()
+	require.NoError(t, err)
+	require.Len(t, inFlight, 1)
+	require.Equal(
+		t, info.PaymentIdentifier, inFlight[0].Info.PaymentIdentifier,
+	)
+
+	// Settle the attempt, which completes the payment.
+	payment, err = pControl.SettleAttempt(
+		info.PaymentIdentifier, attempt.AttemptID,
+		&HTLCSettleInfo{
+			Preimage: preimg,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, payment.HTLCs, 2)
+	require.NoError(
+		t, assertRouteEqual(&payment.HTLCs[1].Route, &attempt.Route),
+	)
+
+	assertPaymentStatus(
+		t, pControl, info.PaymentIdentifier, StatusSucceeded,
+	)
+	assertPaymentInfo(
+		t, pControl, info.PaymentIdentifier, info, nil, &htlcStatus{
+			HTLCAttemptInfo: attempt,
+			settle:          &preimg,
+		},
+	)
+
+	inFlight, err = pControl.FetchInFlightPayments()
+	require.NoError(t, err)
+	require.Empty(t, inFlight)
+
+	// A succeeded payment can't be initiated again.
+	err = pControl.InitPayment(info.PaymentIdentifier, info)
+	require.ErrorIs(t, err, ErrAlreadyPaid)
+}
+
+// TestSQLRebalancePayment asserts that the SQL payments store keeps the
+// rebalance flag of payments.
+func TestSQLRebalancePayment(t *testing.T) {
+	t.Parallel()
+
+	db := makeSQLTestPaymentsDB(t)
+	pControl := NewPaymentControl(db)
+
+	info, _, _, err := genInfo()
+	require.NoError(t, err)
+	info.Rebalance = true
+
+	require.NoError(t, pControl.InitPayment(info.PaymentIdentifier, info))
+
+	payment, err := pControl.FetchPayment(info.PaymentIdentifier)
+	require.NoError(t, err)
+	require.True(t, payment.Info.Rebalance)
+
+	payments, err := db.FetchPayments()
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	require.True(t, payments[0].Info.Rebalance)
+}
+
+// TestSQLQueryPayments tests that payments kept in the SQL payments store can
+// be paginated and filtered by creation time and status.
+func TestSQLQueryPayments(t *testing.T) {
+	t.Parallel()
+
+	db := makeSQLTestPaymentsDB(t)
+	pControl := NewPaymentControl(db)
+
+	// Create five payments with sequence numbers 1 to 5, of which the
+	// second one is deleted and the fourth one succeeds.
+	for i := 0; i < 5; i++ {
+		info, attempt, preimg, err := genInfo()
+		require.NoError(t, err)
+		info.CreationTime = time.Unix(int64(i+1), 0)
+
+		err = pControl.InitPayment(info.PaymentIdentifier, info)
+		require.NoError(t, err)
+
+		switch i {
+		case 1:
+			_, err = pControl.Fail(
+				info.PaymentIdentifier, FailureReasonNoRoute,
+			)
+			require.NoError(t, err)
+
+			err = db.DeletePayment(info.PaymentIdentifier, false)
+			require.NoError(t, err)
+
+		case 3:
+			_, err = pControl.RegisterAttempt(
+				info.PaymentIdentifier, attempt,
+			)
+			require.NoError(t, err)
+
+			_, err = pControl.SettleAttempt(
+				info.PaymentIdentifier, attempt.AttemptID,
+				&HTLCSettleInfo{
+					Preimage: preimg,
+				},
+			)
+			require.NoError(t, err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          PaymentsQuery
+		expectedSeqNrs []uint64
+		totalCount     uint64
+	}{
+		{
+			name: "all payments",
+			query: PaymentsQuery{
+				MaxPayments:       10,
+				IncludeIncomplete: true,
+			},
+			expectedSeqNrs: []uint64{1, 3, 4, 5},
+		},
+		{
+			name: "forwards with offset",
+			query: PaymentsQuery{
+				IndexOffset:       1,
+				MaxPayments:       2,
+				IncludeIncomplete: true,
+			},
+			expectedSeqNrs: []uint64{3, 4},
+		},
+		{
+			name: "reversed from the end",
+			query: PaymentsQuery{
+				MaxPayments:       2,
+				Reversed:          true,
+				IncludeIncomplete: true,
+			},
+			expectedSeqNrs: []uint64{4, 5},
+		},
+		{
+			name: "reversed with offset",
+			query: PaymentsQuery{
+				IndexOffset:       4,
+				MaxPayments:       10,
+				Reversed:          true,
+				IncludeIncomplete: true,
+			},
+			expectedSeqNrs: []uint64{1, 3},
+		},
+		{
+			name: "succeeded payments only",
+			query: PaymentsQuery{
+				MaxPayments: 10,
+			},
+			expectedSeqNrs: []uint64{4},
+		},
+		{
+			name: "creation time range",
+			query: PaymentsQuery{
+				MaxPayments:       10,
+				IncludeIncomplete: true,
+				CreationDateStart: 3,
+				CreationDateEnd:   4,
+			},
+			expectedSeqNrs: []uint64{3, 4},
+		},
+		{
+			name: "count total",
+			query: PaymentsQuery{
+				MaxPayments:       1,
+				IncludeIncomplete: true,
+				CountTotal:        true,
+			},
+			expectedSeqNrs: []uint64{1},
+			totalCount:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := db.QueryPayments(tt.query)
+			require.NoError(t, err)
+
+			seqNrs := make([]uint64, 0, len(resp.Payments))
+			for _, payment := range resp.Payments {
+				seqNrs = append(seqNrs, payment.SequenceNum)
+			}
+			require.Equal(t, tt.expectedSeqNrs, seqNrs)
+
+			require.Equal(
+				t, tt.expectedSeqNrs[0], resp.FirstIndexOffset,
+			)
+			require.Equal(
+				t, tt.expectedSeqNrs[len(tt.expectedSeqNrs)-1],
+				resp.LastIndexOffset,
+			)
+			require.Equal(t, tt.totalCount, resp.TotalCount)
+		})
+	}
+}
+
+// TestSQLDeletePayments tests that payments and their failed HTLC attempts can
+// be deleted from the SQL payments store.
+func TestSQLDeletePayments(t *testing.T) {
+	t.Parallel()
+
+	db := makeSQLTestPaymentsDB(t)
+	pControl := NewPaymentControl(db)
+
+	payments := []*payment{
+		{status: StatusFailed},
+		{status: StatusSucceeded},
+		{status: StatusInFlight},
+	}
+	createTestPayments(t, pControl, payments)
+	assertPayments(t, db, payments)
+
+	// Delete HTLC attempts for failed payments only.
+	require.NoError(t, db.DeletePayments(true, true))
+	payments[0].htlcs = 0
+	assertPayments(t, db, payments)
+
+	// Delete failed attempts for all payments, which leaves the in-flight
+	// payment untouched.
+	require.NoError(t, db.DeletePayments(false, true))
+	payments[1].htlcs = 1
+	assertPayments(t, db, payments)
+
+	// The in-flight payment can't be deleted.
+	err := db.DeletePayment(payments[2].id, false)
+	require.ErrorIs(t, err, ErrPaymentInFlight)
+
+	require.NoError(t, db.DeletePayments(true, false))
+	assertPayments(t, db, payments[1:])
+
+	require.NoError(t, db.DeletePayment(payments[1].id, false))
+	assertPayments(t, db, payments[2:])
+}
+
+// TestSQLPaymentsMigration tests that the kv payments, including legacy
+// duplicate payments, are migrated to the SQL payments store.
+func TestSQLPaymentsMigration(t *testing.T) {
+	t.Parallel()
+
+	kvDB, err := MakeTestDB(t)
+	require.NoError(t, err)
+
+	payments := []*payment{
+		{status: StatusFailed},
+		{status: StatusSucceeded},
+		{status: StatusInFlight},
+	}
+	createTestPayments(t, NewPaymentControl(kvDB), payments)
+
+	// Add a legacy duplicate to the succeeded payment.
+	var dupPreimage lntypes.Preimage
+	dupPreimage[0] = 1
+	appendLegacyDuplicatePayment(
+		t, kvDB, payments[1].id, 100, dupPreimage,
+		time.Unix(1_600_000_000, 0),
+	)
+
+	kvPayments, err := kvDB.FetchPayments()
+	require.NoError(t, err)
+	require.Len(t, kvPayments, 4)
+
+	// The database can't be opened with a SQL payments store before the
+	// kv payments were migrated.
+	store := makeTestSQLPaymentsStore(t)
+	err = checkNativeSQLMigrated(
+		kvDB.Backend, &Options{paymentsSQLStore: store},
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationRequired)
+
+	// Migrate the kv payments with the explicit migration step.
+	_, err = CreateWithBackend(
+		kvDB.Backend, OptionSetPaymentsSQLStore(store),
+		OptionMigrateNativeSQL(true),
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationOK)
+
+	db, err := CreateWithBackend(
+		kvDB.Backend, OptionSetPaymentsSQLStore(store),
+	)
+	require.NoError(t, err)
+
+	sqlPayments, err := db.FetchPayments()
+	require.NoError(t, err)
+	require.Len(t, sqlPayments, len(kvPayments))
+
+	for i, kvPayment := range kvPayments {
+		sqlPayment := sqlPayments[i]
+
+		require.Equal(t, kvPayment.SequenceNum, sqlPayment.SequenceNum)
+		require.Equal(t, kvPayment.Status, sqlPayment.Status)
+		require.Equal(
+			t, kvPayment.FailureReason, sqlPayment.FailureReason,
+		)
+		require.Equal(
+			t, kvPayment.Info.PaymentIdentifier,
+			sqlPayment.Info.PaymentIdentifier,
+		)
+		require.Equal(t, kvPayment.Info.Value, sqlPayment.Info.Value)
+		require.True(
+			t, kvPayment.Info.CreationTime.Equal(
+				sqlPayment.Info.CreationTime,
+			),
+		)
+		require.Len(t, sqlPayment.HTLCs, len(kvPayment.HTLCs))
+
+		for j, htlc := range kvPayment.HTLCs {
+			sqlHtlc := sqlPayment.HTLCs[j]
+
+			require.Equal(t, htlc.AttemptID, sqlHtlc.AttemptID)
+			require.NoError(
+				t, assertRouteEqual(
+					&htlc.Route, &sqlHtlc.Route,
+				),
+			)
+			require.Equal(
+				t, htlc.Settle == nil, sqlHtlc.Settle == nil,
+			)
+			require.Equal(
+				t, htlc.Failure == nil, sqlHtlc.Failure == nil,
+			)
+		}
+	}
+
+	// New payments continue after the highest migrated sequence number.
+	info, _, _, err := genInfo()
+	require.NoError(t, err)
+
+	pControl := NewPaymentControl(db)
+	require.NoError(t, pControl.InitPayment(info.PaymentIdentifier, info))
+
+	payment, err := pControl.FetchPayment(info.PaymentIdentifier)
+	require.NoError(t, err)
+	require.Greater(t, payment.SequenceNum, uint64(100))
+
+	// The migration only runs once, so the new payment survives migrating
+	// again and re-opening the database.
+	_, err = CreateWithBackend(
+		kvDB.Backend, OptionSetPaymentsSQLStore(store),
+		OptionMigrateNativeSQL(true),
+	)
+	require.ErrorIs(t, err, ErrNativeSQLMigrationOK)
+
+	db, err = CreateWithBackend(
+		kvDB.Backend, OptionSetPaymentsSQLStore(store),
+	)
+	require.NoError(t, err)
+
+	sqlPayments, err = db.FetchPayments()
+	require.NoError(t, err)
+	require.Len(t, sqlPayments, len(kvPayments)+1)
+}
+
+// appendLegacyDuplicatePayment adds a legacy duplicate payment with the given
+// creation time to an existing payment. Unlike appendDuplicatePayment, the
+// creation time is written in unix seconds like lnd did, so that it can be
+// kept in the SQL payments store.
+func appendLegacyDuplicatePayment(t *testing.T, db *DB,
+	paymentHash lntypes.Hash, seqNr uint64, preImg lntypes.Preimage,
+	creationTime time.Time) {
+
+	appendDuplicatePayment(t, db, paymentHash, seqNr, preImg)
+
+	var sequenceKey [8]byte
+	byteOrder.PutUint64(sequenceKey[:], seqNr)
+
+	err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		bucket, err := fetchPaymentBucketUpdate(tx, paymentHash)
+		if err != nil {
+			return err
+		}
+
+		dup := bucket.NestedReadWriteBucket(duplicatePaymentsBucket)
+		paymentBucket := dup.NestedReadWriteBucket(sequenceKey[:])
+
+		// The creation time follows the payment hash and the value.
+		info := paymentBucket.Get(duplicatePaymentCreationInfoKey)
+		info = append([]byte(nil), info...)
+		byteOrder.PutUint64(
+			info[lntypes.HashSize+8:], uint64(creationTime.Unix()),
+		)
+
+		return paymentBucket.Put(duplicatePaymentCreationInfoKey, info)
+	}, func() {})
+	require.NoError(t, err)
+}
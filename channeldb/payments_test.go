@@ -118,6 +118,33 @@ func makeFakeInfo() (*PaymentCreationInfo, *HTLCAttemptInfo) {
 	return c, &a.HTLCAttemptInfo
 }
 
+// TestRebalanceCreationInfoSerialization asserts that the rebalance flag of
+// the creation info survives serialization, and that creation info without the
+// flag can still be read.
+func TestRebalanceCreationInfoSerialization(t *testing.T) {
+	t.Parallel()
+
+	c, _ := makeFakeInfo()
+
+	var b bytes.Buffer
+	require.NoError(t, serializePaymentCreationInfo(&b, c))
+	legacyLen := b.Len()
+
+	newCreationInfo, err := deserializePaymentCreationInfo(&b)
+	require.NoError(t, err)
+	require.False(t, newCreationInfo.Rebalance)
+
+	c.Rebalance = true
+
+	b.Reset()
+	require.NoError(t, serializePaymentCreationInfo(&b, c))
+	require.Equal(t, legacyLen+1, b.Len())
+
+	newCreationInfo, err = deserializePaymentCreationInfo(&b)
+	require.NoError(t, err)
+	require.Equal(t, c, newCreationInfo)
+}
+
 func TestSentPaymentSerialization(t *testing.T) {
 	t.Parallel()
 
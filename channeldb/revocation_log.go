@@ -26,6 +26,7 @@ const (
 	revLogCommitTxHashType     tlv.Type = 2
 	revLogOurBalanceType       tlv.Type = 3
 	revLogTheirBalanceType     tlv.Type = 4
+	revLogFeePerKwType         tlv.Type = 5
 )
 
 var (
@@ -224,9 +225,18 @@ type RevocationLog struct {
 	// this field, it could be the case that the field is not present for
 	// all revocation logs.
 	TheirBalance *lnwire.MilliSatoshi
+
+	// FeePerKw is the fee rate of the commitment in sat/kw, which
+	// determines the outputs of the second level HTLC transactions of the
+	// commitment.
+	//
+	// NOTE: this is a pointer so that it is clear if the value is zero or
+	// nil. Revocation logs written before this field was added don't
+	// include it.
+	FeePerKw *btcutil.Amount
 }
 
-// putRevocationLog uses the fields `CommitTx` and `Htlcs` from a
+// putRevocationLog uses the fields `CommitTx`, `Htlcs` and `FeePerKw` from a
 // ChannelCommitment to construct a revocation log entry and saves them to
 // disk. It also saves our output index and their output index, which are
 // useful when creating breach retribution.
@@ -241,11 +251,13 @@ func putRevocationLog(bucket kvdb.RwBucket, commit *ChannelCommitment,
 		return ErrOutputIndexTooBig
 	}
 
+	feePerKw := commit.FeePerKw
 	rl := &RevocationLog{
 		OurOutputIndex:   uint16(ourOutputIndex),
 		TheirOutputIndex: uint16(theirOutputIndex),
 		CommitTxHash:     commit.CommitTx.TxHash(),
 		HTLCEntries:      make([]*HTLCEntry, 0, len(commit.Htlcs)),
+		FeePerKw:         &feePerKw,
 	}
 
 	if !noAmtData {
@@ -332,6 +344,13 @@ func serializeRevocationLog(w io.Writer, rl *RevocationLog) error {
 		))
 	}
 
+	if rl.FeePerKw != nil {
+		feePerKw := uint64(*rl.FeePerKw)
+		records = append(records, tlv.MakeBigSizeRecord(
+			revLogFeePerKwType, &feePerKw,
+		))
+	}
+
 	// Create the tlv stream.
 	tlvStream, err := tlv.NewStream(records...)
 	if err != nil {
@@ -380,6 +399,7 @@ func deserializeRevocationLog(r io.Reader) (RevocationLog, error) {
 		rl           RevocationLog
 		ourBalance   uint64
 		theirBalance uint64
+		feePerKw     uint64
 	)
 
 	// Create the tlv stream.
@@ -397,6 +417,7 @@ func deserializeRevocationLog(r io.Reader) (RevocationLog, error) {
 		tlv.MakeBigSizeRecord(
 			revLogTheirBalanceType, &theirBalance,
 		),
+		tlv.MakeBigSizeRecord(revLogFeePerKwType, &feePerKw),
 	)
 	if err != nil {
 		return rl, err
@@ -418,6 +439,11 @@ func deserializeRevocationLog(r io.Reader) (RevocationLog, error) {
 		rl.TheirBalance = &rb
 	}
 
+	if t, ok := parsedTypes[revLogFeePerKwType]; ok && t == nil {
+		fee := btcutil.Amount(feePerKw)
+		rl.FeePerKw = &fee
+	}
+
 	// Read the HTLC entries.
 	rl.HTLCEntries, err = deserializeHTLCEntries(r)
 
@@ -124,6 +124,38 @@ var (
 		// Remote Balance.
 		0x4, 0x3, 0xfd, 0x0b, 0xb8,
 	}
+
+	feePerKw = testChannelCommit.FeePerKw
+
+	testRevocationLogWithFee = RevocationLog{
+		OurOutputIndex:   0,
+		TheirOutputIndex: 1,
+		CommitTxHash:     testChannelCommit.CommitTx.TxHash(),
+		HTLCEntries:      []*HTLCEntry{&testHTLCEntry},
+		OurBalance:       &localBalance,
+		TheirBalance:     &remoteBalance,
+		FeePerKw:         &feePerKw,
+	}
+	testRevocationLogWithFeeBytes = []byte{
+		// Body length 57.
+		0x39,
+		// OurOutputIndex tlv.
+		0x0, 0x2, 0x0, 0x0,
+		// TheirOutputIndex tlv.
+		0x1, 0x2, 0x0, 0x1,
+		// CommitTxHash tlv.
+		0x2, 0x20,
+		0x28, 0x76, 0x2, 0x59, 0x1d, 0x9d, 0x64, 0x86,
+		0x6e, 0x60, 0x29, 0x23, 0x1d, 0x5e, 0xc5, 0xe6,
+		0xbd, 0xf7, 0xd3, 0x9b, 0x16, 0x7d, 0x0, 0xff,
+		0xc8, 0x22, 0x51, 0xb1, 0x5b, 0xa0, 0xbf, 0xd,
+		// OurBalance.
+		0x3, 0x3, 0xfd, 0x23, 0x28,
+		// Remote Balance.
+		0x4, 0x3, 0xfd, 0x0b, 0xb8,
+		// FeePerKw.
+		0x5, 0x3, 0xfd, 0x13, 0x88,
+	}
 )
 
 func TestWriteTLVStream(t *testing.T) {
@@ -258,6 +290,11 @@ func TestSerializeAndDeserializeRevLog(t *testing.T) {
 			revLog:      testRevocationLogWithAmts,
 			revLogBytes: testRevocationLogWithAmtsBytes,
 		},
+		{
+			name:        "with fee rate",
+			revLog:      testRevocationLogWithFee,
+			revLogBytes: testRevocationLogWithFeeBytes,
+		},
 	}
 
 	for _, test := range tests {
@@ -429,6 +466,11 @@ func TestPutRevocationLog(t *testing.T) {
 	testCommitDust := testChannelCommit
 	testCommitDust.Htlcs = append(testCommitDust.Htlcs, testHtlcDust)
 
+	// The fee rate of the commitment is saved whether or not the amount
+	// data is.
+	testRevocationLogNoAmtsWithFee := testRevocationLogNoAmts
+	testRevocationLogNoAmtsWithFee.FeePerKw = &feePerKw
+
 	testCases := []struct {
 		name        string
 		commit      ChannelCommitment
@@ -445,7 +487,7 @@ func TestPutRevocationLog(t *testing.T) {
 			ourIndex:    0,
 			theirIndex:  1,
 			expectedErr: nil,
-			expectedLog: testRevocationLogWithAmts,
+			expectedLog: testRevocationLogWithFee,
 		},
 		{
 			// Test a normal put operation.
@@ -455,7 +497,7 @@ func TestPutRevocationLog(t *testing.T) {
 			theirIndex:  1,
 			noAmtData:   true,
 			expectedErr: nil,
-			expectedLog: testRevocationLogNoAmts,
+			expectedLog: testRevocationLogNoAmtsWithFee,
 		},
 		{
 			// Test our index too big.
@@ -491,7 +533,7 @@ func TestPutRevocationLog(t *testing.T) {
 			ourIndex:    0,
 			theirIndex:  1,
 			expectedErr: nil,
-			expectedLog: testRevocationLogWithAmts,
+			expectedLog: testRevocationLogWithFee,
 		},
 		{
 			// Test dust htlc is not saved.
@@ -501,7 +543,7 @@ func TestPutRevocationLog(t *testing.T) {
 			theirIndex:  1,
 			noAmtData:   true,
 			expectedErr: nil,
-			expectedLog: testRevocationLogNoAmts,
+			expectedLog: testRevocationLogNoAmtsWithFee,
 		},
 	}
 
@@ -0,0 +1,735 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/kvdb"
+)
+
+var (
+	// pendingSplicesKey stores the splice transactions of a channel that
+	// have been negotiated, but haven't confirmed yet.
+	pendingSplicesKey = []byte("pending-splices-key")
+
+	// splicedFundingKey stores the outpoint of the funding output the
+	// channel's commitment transactions spend once it has been spliced.
+	splicedFundingKey = []byte("spliced-funding-key")
+
+	// spliceCommitmentsKey stores the variants of a party's commitment
+	// that spend the funding outputs of the pending splice transactions.
+	// Like chanCommitmentKey, it's suffixed with 0x00 for our commitment
+	// and 0x01 for the commitment of the remote party.
+	spliceCommitmentsKey = []byte("splice-commitments-key")
+
+	// spliceCommitDiffKey stores the variants of the commitment within
+	// the pending commit diff of the remote party.
+	spliceCommitDiffKey = []byte("splice-commit-diff-key")
+
+	// confirmedSpliceKey stores the funding outpoint of a splice
+	// transaction that confirmed before the channel was switched over to
+	// its funding output.
+	confirmedSpliceKey = []byte("confirmed-splice-key")
+
+	// ErrSpliceCandidateNotFound is returned when a splice transaction
+	// isn't known for a channel.
+	ErrSpliceCandidateNotFound = errors.New("splice candidate not found")
+
+	// ErrSpliceCommitmentNotFound is returned when a commitment doesn't
+	// have a variant that spends the funding output of a splice
+	// transaction.
+	ErrSpliceCommitmentNotFound = errors.New("splice commitment not found")
+)
+
+// SpliceCandidate is a negotiated splice transaction of a channel that hasn't
+// confirmed yet. Since a splice can be fee bumped, a channel may have several
+// candidates that double spend each other, only one of which will confirm.
+// For each candidate, the channel's commitments hold signed variants that
+// spend its funding output, so the channel can be force closed no matter
+// which one confirms.
+type SpliceCandidate struct {
+	// FundingTx is the splice transaction that creates the new funding
+	// output. It's fully signed once the witnesses have been exchanged
+	// with the remote party.
+	FundingTx *wire.MsgTx
+
+	// FundingOutputIndex is the index of the new funding output within
+	// FundingTx.
+	FundingOutputIndex uint32
+
+	// Capacity is the value of the new funding output.
+	Capacity btcutil.Amount
+
+	// LocalContribution is the amount our balance changes by with the
+	// splice. It's negative if we splice funds out of the channel.
+	LocalContribution btcutil.Amount
+
+	// RemoteContribution is the amount the remote party's balance changes
+	// by with the splice.
+	RemoteContribution btcutil.Amount
+
+	// BroadcastHeight is the height at which the splice transaction was
+	// broadcast, or zero if it hasn't been broadcast yet.
+	BroadcastHeight uint32
+}
+
+// FundingOutpoint returns the outpoint of the new funding output created by
+// the splice transaction.
+func (s *SpliceCandidate) FundingOutpoint() wire.OutPoint {
+	return wire.OutPoint{
+		Hash:  s.FundingTx.TxHash(),
+		Index: s.FundingOutputIndex,
+	}
+}
+
+// encode serializes the SpliceCandidate to the passed io.Writer.
+func (s *SpliceCandidate) encode(w io.Writer) error {
+	return WriteElements(w,
+		s.FundingTx, s.FundingOutputIndex, s.Capacity,
+		int64(s.LocalContribution), int64(s.RemoteContribution),
+		s.BroadcastHeight,
+	)
+}
+
+// decodeSpliceCandidate deserializes a SpliceCandidate from the passed
+// io.Reader.
+func decodeSpliceCandidate(r io.Reader) (*SpliceCandidate, error) {
+	var (
+		s                 SpliceCandidate
+		localContribution int64
+		remoteContrib     int64
+	)
+	err := ReadElements(r,
+		&s.FundingTx, &s.FundingOutputIndex, &s.Capacity,
+		&localContribution, &remoteContrib, &s.BroadcastHeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.LocalContribution = btcutil.Amount(localContribution)
+	s.RemoteContribution = btcutil.Amount(remoteContrib)
+
+	return &s, nil
+}
+
+// putSpliceCandidates stores the passed splice candidates in the channel
+// bucket, replacing any that were stored before.
+func putSpliceCandidates(chanBucket kvdb.RwBucket,
+	candidates []*SpliceCandidate) error {
+
+	if len(candidates) == 0 {
+		return chanBucket.Delete(pendingSplicesKey)
+	}
+
+	var b bytes.Buffer
+	if err := WriteElement(&b, uint16(len(candidates))); err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		if err := candidate.encode(&b); err != nil {
+			return err
+		}
+	}
+
+	return chanBucket.Put(pendingSplicesKey, b.Bytes())
+}
+
+// fetchSpliceCandidates reads the splice candidates stored in the channel
+// bucket.
+func fetchSpliceCandidates(chanBucket kvdb.RBucket) ([]*SpliceCandidate,
+	error) {
+
+	candidateBytes := chanBucket.Get(pendingSplicesKey)
+	if candidateBytes == nil {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(candidateBytes)
+
+	var numCandidates uint16
+	if err := ReadElement(r, &numCandidates); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*SpliceCandidate, numCandidates)
+	for i := range candidates {
+		candidate, err := decodeSpliceCandidate(r)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates[i] = candidate
+	}
+
+	return candidates, nil
+}
+
+// spliceCommitmentsKeyFor returns the key under which the splice variants of
+// our own or the remote party's commitment are stored.
+func spliceCommitmentsKeyFor(local bool) []byte {
+	key := make([]byte, len(spliceCommitmentsKey)+1)
+	copy(key, spliceCommitmentsKey)
+	if !local {
+		key[len(spliceCommitmentsKey)] = 0x01
+	}
+
+	return key
+}
+
+// putSpliceCommitments stores the passed splice variants of a commitment under
+// the given key, or deletes the key if there are none.
+func putSpliceCommitments(chanBucket kvdb.RwBucket, key []byte,
+	commits map[chainhash.Hash]*ChannelCommitment) error {
+
+	if len(commits) == 0 {
+		return chanBucket.Delete(key)
+	}
+
+	txids := make([]chainhash.Hash, 0, len(commits))
+	for txid := range commits {
+		txids = append(txids, txid)
+	}
+	sort.Slice(txids, func(i, j int) bool {
+		return bytes.Compare(txids[i][:], txids[j][:]) < 0
+	})
+
+	var b bytes.Buffer
+	if err := WriteElement(&b, uint16(len(txids))); err != nil {
+		return err
+	}
+
+	for _, txid := range txids {
+		if err := WriteElement(&b, txid); err != nil {
+			return err
+		}
+
+		if err := serializeChanCommit(&b, commits[txid]); err != nil {
+			return err
+		}
+	}
+
+	return chanBucket.Put(key, b.Bytes())
+}
+
+// fetchSpliceCommitments reads the splice variants of a commitment stored
+// under the given key. A nil map is returned if there are none.
+func fetchSpliceCommitments(chanBucket kvdb.RBucket,
+	key []byte) (map[chainhash.Hash]*ChannelCommitment, error) {
+
+	commitBytes := chanBucket.Get(key)
+	if commitBytes == nil {
+		return nil, nil
+	}
+
+	r := bytes.NewReader(commitBytes)
+
+	var numCommits uint16
+	if err := ReadElement(r, &numCommits); err != nil {
+		return nil, err
+	}
+
+	commits := make(map[chainhash.Hash]*ChannelCommitment, numCommits)
+	for i := 0; i < int(numCommits); i++ {
+		var txid chainhash.Hash
+		if err := ReadElement(r, &txid); err != nil {
+			return nil, err
+		}
+
+		commit, err := deserializeChanCommit(r)
+		if err != nil {
+			return nil, err
+		}
+
+		commits[txid] = &commit
+	}
+
+	return commits, nil
+}
+
+// SpliceCommitment returns the variant of the commitment that spends the
+// funding output of the splice transaction with the given txid.
+func (c *ChannelCommitment) SpliceCommitment(
+	txid chainhash.Hash) (*ChannelCommitment, error) {
+
+	commit, ok := c.SpliceCommitments[txid]
+	if !ok {
+		return nil, fmt.Errorf("%w: height=%v, txid=%v",
+			ErrSpliceCommitmentNotFound, c.CommitHeight, txid)
+	}
+
+	return commit, nil
+}
+
+// fetchSplicedFunding reads the outpoint of the funding output of a spliced
+// channel into the passed channel, if the channel has been spliced, along with
+// the outpoint of a splice transaction that confirmed, but hasn't been
+// promoted yet.
+func fetchSplicedFunding(chanBucket kvdb.RBucket, channel *OpenChannel) error {
+	readOutpoint := func(key []byte) (fn.Option[wire.OutPoint], error) {
+		outpointBytes := chanBucket.Get(key)
+		if outpointBytes == nil {
+			return fn.None[wire.OutPoint](), nil
+		}
+
+		var outpoint wire.OutPoint
+		err := ReadElement(bytes.NewReader(outpointBytes), &outpoint)
+		if err != nil {
+			return fn.None[wire.OutPoint](), err
+		}
+
+		return fn.Some(outpoint), nil
+	}
+
+	var err error
+	channel.splicedFundingOutpoint, err = readOutpoint(splicedFundingKey)
+	if err != nil {
+		return err
+	}
+
+	channel.confirmedSplice, err = readOutpoint(confirmedSpliceKey)
+
+	return err
+}
+
+// CurrentFundingOutpoint returns the outpoint of the funding output that the
+// channel's commitment transactions spend. This differs from FundingOutpoint,
+// which identifies the channel, once the channel has been spliced.
+func (c *OpenChannel) CurrentFundingOutpoint() wire.OutPoint {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.splicedFundingOutpoint.UnwrapOr(c.FundingOutpoint)
+}
+
+// ConfirmedSplice returns the funding outpoint of the splice transaction that
+// confirmed for the channel, if the channel hasn't been switched over to it
+// yet.
+func (c *OpenChannel) ConfirmedSplice() fn.Option[wire.OutPoint] {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.confirmedSplice
+}
+
+// SpliceCandidates returns the splice transactions of the channel that have
+// been negotiated, but haven't confirmed yet.
+func (c *OpenChannel) SpliceCandidates() ([]*SpliceCandidate, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var candidates []*SpliceCandidate
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		candidates, err = fetchSpliceCandidates(chanBucket)
+
+		return err
+	}, func() {
+		candidates = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// AddSpliceCandidate persists a negotiated splice transaction for the
+// channel, along with the variants of our and the remote party's current
+// commitment that spend its funding output. A candidate that was stored
+// before for the same transaction is replaced.
+//
+// NOTE: The channel must not have a pending commitment for the remote party,
+// which is the case while it's quiescent.
+func (c *OpenChannel) AddSpliceCandidate(candidate *SpliceCandidate,
+	localCommit, remoteCommit *ChannelCommitment) error {
+
+	c.Lock()
+	defer c.Unlock()
+
+	txid := candidate.FundingTx.TxHash()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		if chanBucket.Get(commitDiffKey) != nil {
+			return fmt.Errorf("unable to add splice candidate " +
+				"with pending remote commitment")
+		}
+
+		candidates, err := fetchSpliceCandidates(chanBucket)
+		if err != nil {
+			return err
+		}
+
+		candidates = fn.Filter(func(s *SpliceCandidate) bool {
+			return s.FundingTx.TxHash() != txid
+		}, candidates)
+		candidates = append(candidates, candidate)
+
+		err = putSpliceCandidates(chanBucket, candidates)
+		if err != nil {
+			return err
+		}
+
+		if err := fetchChanCommitments(chanBucket, c); err != nil {
+			return err
+		}
+
+		addVariant := func(commit, variant *ChannelCommitment) {
+			if commit.SpliceCommitments == nil {
+				commit.SpliceCommitments = make(
+					map[chainhash.Hash]*ChannelCommitment,
+				)
+			}
+			commit.SpliceCommitments[txid] = variant
+		}
+		addVariant(&c.LocalCommitment, localCommit)
+		addVariant(&c.RemoteCommitment, remoteCommit)
+
+		return putChanCommitments(chanBucket, c)
+	}, func() {})
+}
+
+// MarkSpliceBroadcast records the height at which the splice transaction with
+// the given txid was broadcast.
+func (c *OpenChannel) MarkSpliceBroadcast(txid chainhash.Hash,
+	height uint32) error {
+
+	c.Lock()
+	defer c.Unlock()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		candidates, err := fetchSpliceCandidates(chanBucket)
+		if err != nil {
+			return err
+		}
+
+		candidate, err := fn.Find(func(s *SpliceCandidate) bool {
+			return s.FundingTx.TxHash() == txid
+		}, candidates).UnwrapOrErr(ErrSpliceCandidateNotFound)
+		if err != nil {
+			return err
+		}
+		candidate.BroadcastHeight = height
+
+		return putSpliceCandidates(chanBucket, candidates)
+	}, func() {})
+}
+
+// RemoveSpliceCandidates removes all splice candidates of the channel along
+// with the variants of its commitments that spend their funding outputs. This
+// is used when a splice is aborted before its transaction was broadcast.
+func (c *OpenChannel) RemoveSpliceCandidates() error {
+	c.Lock()
+	defer c.Unlock()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := putSpliceCandidates(chanBucket, nil); err != nil {
+			return err
+		}
+
+		err = chanBucket.Delete(spliceCommitDiffKey)
+		if err != nil {
+			return err
+		}
+
+		if err := fetchChanCommitments(chanBucket, c); err != nil {
+			return err
+		}
+		c.LocalCommitment.SpliceCommitments = nil
+		c.RemoteCommitment.SpliceCommitments = nil
+
+		return putChanCommitments(chanBucket, c)
+	}, func() {})
+}
+
+// MarkSpliceConfirmed records that the splice transaction with the given txid
+// confirmed. The channel keeps using its current funding output until the
+// splice is promoted, which happens once both parties locked it, but the
+// chain watcher needs to watch the new funding output right away. Marking a
+// splice that has already been promoted is a no-op.
+func (c *OpenChannel) MarkSpliceConfirmed(txid chainhash.Hash) error {
+	c.Lock()
+	defer c.Unlock()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		// The splice may have been promoted by another instance of
+		// the channel, so we'll refresh it first.
+		if err := fetchChanInfo(chanBucket, c); err != nil {
+			return err
+		}
+
+		current := c.splicedFundingOutpoint.UnwrapOr(c.FundingOutpoint)
+		if current.Hash == txid {
+			return nil
+		}
+
+		candidates, err := fetchSpliceCandidates(chanBucket)
+		if err != nil {
+			return err
+		}
+
+		candidate, err := fn.Find(func(s *SpliceCandidate) bool {
+			return s.FundingTx.TxHash() == txid
+		}, candidates).UnwrapOrErr(ErrSpliceCandidateNotFound)
+		if err != nil {
+			return err
+		}
+
+		fundingPoint := candidate.FundingOutpoint()
+
+		var b bytes.Buffer
+		if err := WriteElement(&b, fundingPoint); err != nil {
+			return err
+		}
+		err = chanBucket.Put(confirmedSpliceKey, b.Bytes())
+		if err != nil {
+			return err
+		}
+
+		c.confirmedSplice = fn.Some(fundingPoint)
+
+		return nil
+	}, func() {})
+}
+
+// ApplyConfirmedSplice switches the in-memory state of the channel over to
+// the splice transaction that confirmed, if the splice hasn't been promoted
+// yet. The channel's commitments are replaced by their variants that spend
+// the new funding output, which allows the chain watcher to match and resolve
+// the commitment transactions that spend it. Nothing is written to disk.
+func (c *OpenChannel) ApplyConfirmedSplice() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.confirmedSplice.IsNone() {
+		return nil
+	}
+	fundingPoint := c.confirmedSplice.UnwrapOr(wire.OutPoint{})
+
+	var candidates []*SpliceCandidate
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		candidates, err = fetchSpliceCandidates(chanBucket)
+
+		return err
+	}, func() {
+		candidates = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	candidate, err := fn.Find(func(s *SpliceCandidate) bool {
+		return s.FundingOutpoint() == fundingPoint
+	}, candidates).UnwrapOrErr(ErrSpliceCandidateNotFound)
+	if err != nil {
+		return err
+	}
+
+	localCommit, err := c.LocalCommitment.SpliceCommitment(
+		fundingPoint.Hash,
+	)
+	if err != nil {
+		return err
+	}
+	remoteCommit, err := c.RemoteCommitment.SpliceCommitment(
+		fundingPoint.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	c.Capacity = candidate.Capacity
+	c.LocalCommitment = *localCommit
+	c.RemoteCommitment = *remoteCommit
+	c.splicedFundingOutpoint = fn.Some(fundingPoint)
+	c.confirmedSplice = fn.None[wire.OutPoint]()
+
+	return nil
+}
+
+// PromoteConfirmedSplice promotes the splice transaction that confirmed for
+// the channel, if there is one that hasn't been promoted yet.
+func (c *OpenChannel) PromoteConfirmedSplice() error {
+	confirmed := c.ConfirmedSplice()
+	if confirmed.IsNone() {
+		return nil
+	}
+
+	fundingPoint := confirmed.UnwrapOr(wire.OutPoint{})
+
+	return c.PromoteSplice(fundingPoint.Hash)
+}
+
+// PromoteSplice switches the channel over to the funding output created by
+// the splice transaction with the given txid, which must have confirmed. The
+// channel's capacity is updated, and its commitments, including a pending
+// commitment of the remote party, are replaced by their variants that spend
+// the new funding output. All candidates are removed afterwards. Promoting a
+// splice that has already been promoted is a no-op.
+func (c *OpenChannel) PromoteSplice(txid chainhash.Hash) error {
+	c.Lock()
+	defer c.Unlock()
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		// The channel state may have been promoted by another
+		// instance, so we'll refresh it first.
+		if err := fetchChanInfo(chanBucket, c); err != nil {
+			return err
+		}
+		if err := fetchChanCommitments(chanBucket, c); err != nil {
+			return err
+		}
+
+		current := c.splicedFundingOutpoint.UnwrapOr(c.FundingOutpoint)
+		if current.Hash == txid {
+			return nil
+		}
+
+		candidates, err := fetchSpliceCandidates(chanBucket)
+		if err != nil {
+			return err
+		}
+
+		candidate, err := fn.Find(func(s *SpliceCandidate) bool {
+			return s.FundingTx.TxHash() == txid
+		}, candidates).UnwrapOrErr(ErrSpliceCandidateNotFound)
+		if err != nil {
+			return err
+		}
+
+		localCommit, err := c.LocalCommitment.SpliceCommitment(txid)
+		if err != nil {
+			return err
+		}
+		remoteCommit, err := c.RemoteCommitment.SpliceCommitment(txid)
+		if err != nil {
+			return err
+		}
+
+		// A pending commitment of the remote party is replaced by its
+		// variant as well. The commitment signature message is kept,
+		// as it carries the signatures for all variants.
+		if tipBytes := chanBucket.Get(commitDiffKey); tipBytes != nil {
+			diff, err := deserializeCommitDiff(
+				bytes.NewReader(tipBytes),
+			)
+			if err != nil {
+				return err
+			}
+
+			diff.Commitment.SpliceCommitments, err =
+				fetchSpliceCommitments(
+					chanBucket, spliceCommitDiffKey,
+				)
+			if err != nil {
+				return err
+			}
+
+			tipCommit, err := diff.Commitment.SpliceCommitment(txid)
+			if err != nil {
+				return err
+			}
+			diff.Commitment = *tipCommit
+
+			var b bytes.Buffer
+			if err := serializeCommitDiff(&b, diff); err != nil {
+				return err
+			}
+			err = chanBucket.Put(commitDiffKey, b.Bytes())
+			if err != nil {
+				return err
+			}
+		}
+		if err := chanBucket.Delete(spliceCommitDiffKey); err != nil {
+			return err
+		}
+
+		fundingPoint := candidate.FundingOutpoint()
+
+		var b bytes.Buffer
+		if err := WriteElement(&b, fundingPoint); err != nil {
+			return err
+		}
+		err = chanBucket.Put(splicedFundingKey, b.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := chanBucket.Delete(confirmedSpliceKey); err != nil {
+			return err
+		}
+
+		c.Capacity = candidate.Capacity
+		c.LocalCommitment = *localCommit
+		c.LocalCommitment.SpliceCommitments = nil
+		c.RemoteCommitment = *remoteCommit
+		c.RemoteCommitment.SpliceCommitments = nil
+
+		if err := putChanInfo(chanBucket, c); err != nil {
+			return err
+		}
+		if err := putChanCommitments(chanBucket, c); err != nil {
+			return err
+		}
+
+		c.splicedFundingOutpoint = fn.Some(fundingPoint)
+		c.confirmedSplice = fn.None[wire.OutPoint]()
+
+		return putSpliceCandidates(chanBucket, nil)
+	}, func() {})
+}
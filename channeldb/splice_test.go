@@ -0,0 +1,280 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSpliceCandidate returns a splice candidate for a splice transaction
+// with the given lock time, which makes its txid unique.
+func newTestSpliceCandidate(lockTime uint32) *SpliceCandidate {
+	spliceTx := wire.NewMsgTx(2)
+	spliceTx.LockTime = lockTime
+	spliceTx.AddTxIn(&wire.TxIn{})
+	spliceTx.AddTxOut(&wire.TxOut{Value: 15_000, PkScript: []byte{1}})
+
+	return &SpliceCandidate{
+		FundingTx:          spliceTx,
+		Capacity:           15_000,
+		LocalContribution:  6_000,
+		RemoteContribution: -1_000,
+	}
+}
+
+// newTestSpliceCommitment returns a variant of the given commitment that
+// spends the funding output of the splice candidate.
+func newTestSpliceCommitment(commit *ChannelCommitment,
+	candidate *SpliceCandidate) *ChannelCommitment {
+
+	commitTx := wire.NewMsgTx(2)
+	commitTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: candidate.FundingOutpoint(),
+	})
+
+	variant := *commit
+	variant.SpliceCommitments = nil
+	variant.CommitTx = commitTx
+	variant.CommitSig = []byte{1, 2, 3}
+	variant.LocalBalance += lnwire.NewMSatFromSatoshis(
+		candidate.LocalContribution,
+	)
+	variant.RemoteBalance += lnwire.NewMSatFromSatoshis(
+		candidate.RemoteContribution,
+	)
+
+	return &variant
+}
+
+// addTestSpliceCandidate adds the splice candidate to the channel, along with
+// the variants of its current commitments.
+func addTestSpliceCandidate(t *testing.T, channel *OpenChannel,
+	candidate *SpliceCandidate) {
+
+	t.Helper()
+
+	err := channel.AddSpliceCandidate(
+		candidate,
+		newTestSpliceCommitment(&channel.LocalCommitment, candidate),
+		newTestSpliceCommitment(&channel.RemoteCommitment, candidate),
+	)
+	require.NoError(t, err)
+}
+
+// TestSpliceCandidates asserts that splice candidates and the variants of the
+// commitments spending their funding outputs are persisted, and that
+// promoting one of them switches the channel over to its funding output.
+func TestSpliceCandidates(t *testing.T) {
+	t.Parallel()
+
+	fullDB, err := MakeTestDB(t)
+	require.NoError(t, err, "unable to make test database")
+
+	cdb := fullDB.ChannelStateDB()
+
+	channel := createTestChannel(t, cdb, openChannelOption())
+
+	origFunding := channel.FundingOutpoint
+	require.Equal(t, origFunding, channel.CurrentFundingOutpoint())
+
+	candidates, err := channel.SpliceCandidates()
+	require.NoError(t, err)
+	require.Empty(t, candidates)
+
+	// Add two candidates, then replace the first one.
+	first := newTestSpliceCandidate(1)
+	second := newTestSpliceCandidate(2)
+	firstTxid := first.FundingTx.TxHash()
+	secondTxid := second.FundingTx.TxHash()
+	addTestSpliceCandidate(t, channel, first)
+	addTestSpliceCandidate(t, channel, second)
+	addTestSpliceCandidate(t, channel, first)
+
+	require.NoError(t, channel.MarkSpliceBroadcast(firstTxid, 101))
+	err = channel.MarkSpliceBroadcast(chainhash.Hash{}, 101)
+	require.ErrorIs(t, err, ErrSpliceCandidateNotFound)
+
+	candidates, err = channel.SpliceCandidates()
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	require.Equal(
+		t, second.FundingOutpoint(), candidates[0].FundingOutpoint(),
+	)
+	require.Equal(
+		t, first.FundingOutpoint(), candidates[1].FundingOutpoint(),
+	)
+	require.EqualValues(t, 101, candidates[1].BroadcastHeight)
+	require.Equal(
+		t, first.RemoteContribution, candidates[1].RemoteContribution,
+	)
+
+	// Both commitments carry a variant for each candidate.
+	dbChannel, err := cdb.FetchChannel(nil, origFunding)
+	require.NoError(t, err)
+	require.Len(t, dbChannel.LocalCommitment.SpliceCommitments, 2)
+	require.Len(t, dbChannel.RemoteCommitment.SpliceCommitments, 2)
+	require.Equal(
+		t, channel.LocalCommitment.SpliceCommitments[firstTxid].
+			CommitTx.TxHash(),
+		dbChannel.LocalCommitment.SpliceCommitments[firstTxid].
+			CommitTx.TxHash(),
+	)
+
+	// A new commitment for the remote party carries its variants through
+	// the commit diff, until the remote party revokes its prior state.
+	remoteCommit := channel.RemoteCommitment
+	remoteCommit.CommitHeight++
+	remoteCommit.SpliceCommitments = map[chainhash.Hash]*ChannelCommitment{
+		secondTxid: newTestSpliceCommitment(&remoteCommit, second),
+	}
+	remoteCommit.SpliceCommitments[secondTxid].CommitHeight =
+		remoteCommit.CommitHeight
+	commitDiff := &CommitDiff{
+		Commitment: remoteCommit,
+		CommitSig: &lnwire.CommitSig{
+			ChanID:    lnwire.NewChanIDFromOutPoint(origFunding),
+			CommitSig: wireSig,
+		},
+	}
+	require.NoError(t, channel.AppendRemoteCommitChain(commitDiff))
+
+	tip, err := channel.RemoteCommitChainTip()
+	require.NoError(t, err)
+	require.Len(t, tip.Commitment.SpliceCommitments, 1)
+	require.Equal(
+		t, remoteCommit.SpliceCommitments[secondTxid].
+			CommitTx.TxHash(),
+		tip.Commitment.SpliceCommitments[secondTxid].
+			CommitTx.TxHash(),
+	)
+
+	// Confirming an unknown splice fails.
+	unknown := newTestSpliceCandidate(3)
+	err = channel.MarkSpliceConfirmed(unknown.FundingTx.TxHash())
+	require.ErrorIs(t, err, ErrSpliceCandidateNotFound)
+
+	// Confirming the second candidate only marks it, so the chain
+	// watcher can switch its in-memory state over to the new funding
+	// output.
+	require.NoError(t, channel.MarkSpliceConfirmed(secondTxid))
+	require.Equal(
+		t, fn.Some(second.FundingOutpoint()), channel.ConfirmedSplice(),
+	)
+
+	dbChannel, err = cdb.FetchChannel(nil, origFunding)
+	require.NoError(t, err)
+	require.Equal(
+		t, fn.Some(second.FundingOutpoint()),
+		dbChannel.ConfirmedSplice(),
+	)
+	require.NoError(t, dbChannel.ApplyConfirmedSplice())
+	require.Equal(
+		t, second.FundingOutpoint(), dbChannel.CurrentFundingOutpoint(),
+	)
+	require.Equal(
+		t, channel.LocalCommitment.SpliceCommitments[secondTxid].
+			CommitTx.TxHash(),
+		dbChannel.LocalCommitment.CommitTx.TxHash(),
+	)
+
+	// The state on disk is unchanged.
+	dbChannel, err = cdb.FetchChannel(nil, origFunding)
+	require.NoError(t, err)
+	require.Equal(t, origFunding, dbChannel.CurrentFundingOutpoint())
+
+	// Promoting an unknown splice fails.
+	err = channel.PromoteSplice(unknown.FundingTx.TxHash())
+	require.ErrorIs(t, err, ErrSpliceCandidateNotFound)
+
+	localBalance := channel.LocalCommitment.LocalBalance
+	remoteBalance := channel.LocalCommitment.RemoteBalance
+	localVariant := channel.LocalCommitment.SpliceCommitments[secondTxid]
+
+	// Promote the confirmed candidate, which should update the channel
+	// state both in memory and on disk.
+	require.NoError(t, channel.PromoteConfirmedSplice())
+
+	assertPromoted := func(c *OpenChannel) {
+		t.Helper()
+
+		require.Equal(t, origFunding, c.FundingOutpoint)
+		require.Equal(
+			t, second.FundingOutpoint(), c.CurrentFundingOutpoint(),
+		)
+		require.True(t, c.ConfirmedSplice().IsNone())
+		require.Equal(t, btcutil.Amount(15_000), c.Capacity)
+		require.Equal(
+			t, localBalance+lnwire.NewMSatFromSatoshis(6_000),
+			c.LocalCommitment.LocalBalance,
+		)
+		require.Equal(
+			t, remoteBalance-lnwire.NewMSatFromSatoshis(1_000),
+			c.LocalCommitment.RemoteBalance,
+		)
+		require.Equal(
+			t, localVariant.CommitTx.TxHash(),
+			c.LocalCommitment.CommitTx.TxHash(),
+		)
+		require.Empty(t, c.LocalCommitment.SpliceCommitments)
+		require.Empty(t, c.RemoteCommitment.SpliceCommitments)
+
+		// The pending commitment of the remote party was replaced by
+		// its variant, while the signature message was kept.
+		tip, err := c.RemoteCommitChainTip()
+		require.NoError(t, err)
+		require.Equal(
+			t, remoteCommit.SpliceCommitments[secondTxid].
+				CommitTx.TxHash(),
+			tip.Commitment.CommitTx.TxHash(),
+		)
+		require.Empty(t, tip.Commitment.SpliceCommitments)
+		require.Equal(t, wireSig, tip.CommitSig.CommitSig)
+
+		candidates, err := c.SpliceCandidates()
+		require.NoError(t, err)
+		require.Empty(t, candidates)
+	}
+	assertPromoted(channel)
+
+	dbChannel, err = cdb.FetchChannel(nil, origFunding)
+	require.NoError(t, err)
+	assertPromoted(dbChannel)
+
+	// Promoting the splice again is a no-op.
+	require.NoError(t, dbChannel.PromoteSplice(secondTxid))
+	assertPromoted(dbChannel)
+}
+
+// TestRemoveSpliceCandidates asserts that removing the splice candidates of a
+// channel also removes the variants of its commitments.
+func TestRemoveSpliceCandidates(t *testing.T) {
+	t.Parallel()
+
+	fullDB, err := MakeTestDB(t)
+	require.NoError(t, err, "unable to make test database")
+
+	cdb := fullDB.ChannelStateDB()
+
+	channel := createTestChannel(t, cdb, openChannelOption())
+	addTestSpliceCandidate(t, channel, newTestSpliceCandidate(1))
+	require.Len(t, channel.LocalCommitment.SpliceCommitments, 1)
+
+	require.NoError(t, channel.RemoveSpliceCandidates())
+
+	dbChannel, err := cdb.FetchChannel(nil, channel.FundingOutpoint)
+	require.NoError(t, err)
+
+	for _, c := range []*OpenChannel{channel, dbChannel} {
+		require.Empty(t, c.LocalCommitment.SpliceCommitments)
+		require.Empty(t, c.RemoteCommitment.SpliceCommitments)
+
+		candidates, err := c.SpliceCandidates()
+		require.NoError(t, err)
+		require.Empty(t, candidates)
+	}
+}
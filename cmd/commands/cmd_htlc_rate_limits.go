@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var htlcRateLimitsCommand = cli.Command{
+	Name:     "htlcratelimits",
+	Category: "Channels",
+	Usage: "Show the in-flight htlcs and the htlc rate limit counters " +
+		"of every peer.",
+	Description: `
+	Returns the total value of the htlcs that each peer has in flight with
+	us and the number of htlc adds of each peer and each of its channels
+	that were allowed or failed back by the htlc rate limiter. Htlc rate
+	limiting must be activated with htlcswitch.ratelimit.active.`,
+	Action: actionDecorator(htlcRateLimits),
+}
+
+func htlcRateLimits(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+	resp, err := client.HtlcRateLimits(
+		ctxc, &routerrpc.HtlcRateLimitsRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
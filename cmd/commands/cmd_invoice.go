@@ -116,6 +116,30 @@ var AddInvoiceCommand = cli.Command{
 				"use on a blinded path. The flag may be " +
 				"specified multiple times.",
 		},
+		cli.StringSliceFlag{
+			Name: "blinded_intro_node",
+			Usage: "The pub key (in hex) of a node that may be " +
+				"used as the introduction node of a blinded " +
+				"path. If set, only paths starting at one of " +
+				"the given nodes are used. The flag may be " +
+				"specified multiple times.",
+		},
+		cli.StringFlag{
+			Name: "blinded_intro_selection",
+			Usage: "The policy used to rank the candidate " +
+				"blinded paths: 'probability' prefers the " +
+				"paths most likely to succeed, 'centrality' " +
+				"prefers well connected introduction nodes.",
+		},
+		cli.BoolFlag{
+			Name: "preview",
+			Usage: "only preview the blinded paths that would be " +
+				"included in the invoice, along with the " +
+				"real hops they were built from, without " +
+				"creating the invoice. A later invoice may " +
+				"use different paths. This option requires " +
+				"`--blind` to be set.",
+		},
 	},
 	Action: actionDecorator(addInvoice),
 }
@@ -172,6 +196,33 @@ func addInvoice(ctx *cli.Context) error {
 			err)
 	}
 
+	if ctx.Bool("preview") {
+		if !ctx.Bool("blind") {
+			return fmt.Errorf("the `--preview` option is only " +
+				"used if the `--blind` option is set")
+		}
+
+		valueMsat := amtMsat
+		if valueMsat == 0 {
+			valueMsat = amt * 1000
+		}
+
+		req := &lnrpc.PreviewBlindedPathsRequest{
+			ValueMsat:         valueMsat,
+			Expiry:            ctx.Int64("expiry"),
+			CltvExpiry:        ctx.Uint64("cltv_expiry_delta"),
+			BlindedPathConfig: blindedPathCfg,
+		}
+		resp, err := client.PreviewBlindedPaths(ctxc, req)
+		if err != nil {
+			return err
+		}
+
+		printRespJSON(resp)
+
+		return nil
+	}
+
 	invoice := &lnrpc.Invoice{
 		Memo:              ctx.String("memo"),
 		RPreimage:         preimage,
@@ -202,7 +253,9 @@ func parseBlindedPathCfg(ctx *cli.Context) (*lnrpc.BlindedPathConfig, error) {
 		if ctx.IsSet("min_real_blinded_hops") ||
 			ctx.IsSet("num_blinded_hops") ||
 			ctx.IsSet("max_blinded_paths") ||
-			ctx.IsSet("blinded_path_omit_node") {
+			ctx.IsSet("blinded_path_omit_node") ||
+			ctx.IsSet("blinded_intro_node") ||
+			ctx.IsSet("blinded_intro_selection") {
 
 			return nil, fmt.Errorf("blinded path options are " +
 				"only used if the `--blind` options is set")
@@ -239,6 +292,31 @@ func parseBlindedPathCfg(ctx *cli.Context) (*lnrpc.BlindedPathConfig, error) {
 		)
 	}
 
+	for _, pubKey := range ctx.StringSlice("blinded_intro_node") {
+		pubKeyBytes, err := hex.DecodeString(pubKey)
+		if err != nil {
+			return nil, err
+		}
+
+		blindCfg.IntroductionNodes = append(
+			blindCfg.IntroductionNodes, pubKeyBytes,
+		)
+	}
+
+	switch ctx.String("blinded_intro_selection") {
+	case "", "probability":
+		blindCfg.IntroNodeSelection =
+			lnrpc.IntroNodeSelection_INTRO_NODE_SELECTION_PROBABILITY
+
+	case "centrality":
+		blindCfg.IntroNodeSelection =
+			lnrpc.IntroNodeSelection_INTRO_NODE_SELECTION_CENTRALITY
+
+	default:
+		return nil, fmt.Errorf("unknown introduction node selection "+
+			"policy: %v", ctx.String("blinded_intro_selection"))
+	}
+
 	return &blindCfg, nil
 }
 
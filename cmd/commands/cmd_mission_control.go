@@ -12,6 +12,14 @@ import (
 	"github.com/urfave/cli"
 )
 
+// mcNamespaceFlag selects the mission control namespace that a command
+// operates on.
+var mcNamespaceFlag = cli.StringFlag{
+	Name: "mc_namespace",
+	Usage: "(optional) the mission control namespace to use, the " +
+		"default namespace is used if empty",
+}
+
 var getCfgCommand = cli.Command{
 	Name:     "getmccfg",
 	Category: "Mission Control",
@@ -19,6 +27,7 @@ var getCfgCommand = cli.Command{
 	Description: `
 	Returns the config currently being used by mission control.
 	`,
+	Flags:  []cli.Flag{mcNamespaceFlag},
 	Action: actionDecorator(getCfg),
 }
 
@@ -30,7 +39,11 @@ func getCfg(ctx *cli.Context) error {
 	client := routerrpc.NewRouterClient(conn)
 
 	resp, err := client.GetMissionControlConfig(
-		ctxc, &routerrpc.GetMissionControlConfigRequest{},
+		ctxc, &routerrpc.GetMissionControlConfigRequest{
+			MissionControlNamespace: ctx.String(
+				mcNamespaceFlag.Name,
+			),
+		},
 	)
 	if err != nil {
 		return err
@@ -50,6 +63,7 @@ var setCfgCommand = cli.Command{
         probability that payment routes will succeed. The estimator type must be
         provided to set estimator-related parameters.`,
 	Flags: []cli.Flag{
+		mcNamespaceFlag,
 		// General settings.
 		cli.UintFlag{
 			Name: "pmtnr",
@@ -124,8 +138,11 @@ func setCfg(ctx *cli.Context) error {
 
 	// Fetch current mission control config which we update to create our
 	// response.
+	namespace := ctx.String(mcNamespaceFlag.Name)
 	mcCfg, err := client.GetMissionControlConfig(
-		ctxc, &routerrpc.GetMissionControlConfigRequest{},
+		ctxc, &routerrpc.GetMissionControlConfigRequest{
+			MissionControlNamespace: namespace,
+		},
 	)
 	if err != nil {
 		return err
@@ -262,7 +279,8 @@ func setCfg(ctx *cli.Context) error {
 
 	_, err = client.SetMissionControlConfig(
 		ctxc, &routerrpc.SetMissionControlConfigRequest{
-			Config: mcCfg.Config,
+			Config:                  mcCfg.Config,
+			MissionControlNamespace: namespace,
 		},
 	)
 
@@ -273,6 +291,7 @@ var queryMissionControlCommand = cli.Command{
 	Name:     "querymc",
 	Category: "Mission Control",
 	Usage:    "Query the internal mission control state.",
+	Flags:    []cli.Flag{mcNamespaceFlag},
 	Action:   actionDecorator(queryMissionControl),
 }
 
@@ -283,7 +302,9 @@ func queryMissionControl(ctx *cli.Context) error {
 
 	client := routerrpc.NewRouterClient(conn)
 
-	req := &routerrpc.QueryMissionControlRequest{}
+	req := &routerrpc.QueryMissionControlRequest{
+		MissionControlNamespace: ctx.String(mcNamespaceFlag.Name),
+	}
 	snapshot, err := client.QueryMissionControl(ctxc, req)
 	if err != nil {
 		return err
@@ -355,6 +376,7 @@ var resetMissionControlCommand = cli.Command{
 	Name:     "resetmc",
 	Category: "Mission Control",
 	Usage:    "Reset internal mission control state.",
+	Flags:    []cli.Flag{mcNamespaceFlag},
 	Action:   actionDecorator(resetMissionControl),
 }
 
@@ -365,7 +387,9 @@ func resetMissionControl(ctx *cli.Context) error {
 
 	client := routerrpc.NewRouterClient(conn)
 
-	req := &routerrpc.ResetMissionControlRequest{}
+	req := &routerrpc.ResetMissionControlRequest{
+		MissionControlNamespace: ctx.String(mcNamespaceFlag.Name),
+	}
 	_, err := client.ResetMissionControl(ctxc, req)
 
 	return err
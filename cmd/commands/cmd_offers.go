@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var addOfferCommand = cli.Command{
+	Name:     "addoffer",
+	Category: "Invoices",
+	Usage:    "Create a reusable BOLT 12 offer.",
+	Description: `
+	Create a BOLT 12 offer that can be paid any number of times. Payers
+	request an invoice for the offer from our node over onion messages,
+	which requires both onion messages and route blinding to be enabled.
+
+	If no amount is given, the payer chooses the amount to pay.
+	`,
+	ArgsUsage: "description",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "a description of what the offer is for",
+		},
+		cli.Uint64Flag{
+			Name:  "amt_msat",
+			Usage: "the amount to pay per item in millisatoshis",
+		},
+		cli.StringFlag{
+			Name:  "issuer",
+			Usage: "an optional description of the issuer",
+		},
+		cli.Int64Flag{
+			Name: "absolute_expiry",
+			Usage: "the unix timestamp after which the offer can " +
+				"no longer be paid",
+		},
+		cli.Uint64Flag{
+			Name: "quantity_max",
+			Usage: "the maximum number of items that can be paid " +
+				"for at once, zero allows any quantity",
+		},
+	},
+	Action: actionDecorator(addOffer),
+}
+
+func addOffer(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	description := ctx.String("description")
+	if ctx.NArg() > 0 {
+		description = ctx.Args().First()
+	}
+
+	req := &lnrpc.AddOfferRequest{
+		Description:    description,
+		AmountMsat:     ctx.Uint64("amt_msat"),
+		Issuer:         ctx.String("issuer"),
+		AbsoluteExpiry: ctx.Int64("absolute_expiry"),
+	}
+	if ctx.IsSet("quantity_max") {
+		quantityMax := ctx.Uint64("quantity_max")
+		req.QuantityMax = &quantityMax
+	}
+
+	resp, err := client.AddOffer(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var fetchInvoiceCommand = cli.Command{
+	Name:     "fetchinvoice",
+	Category: "Payments",
+	Usage:    "Request an invoice for a BOLT 12 offer.",
+	Description: `
+	Request an invoice for a BOLT 12 offer from its issuer over onion
+	messages. The returned invoice can be paid with payinvoice.
+
+	If the issuer isn't a peer of our node, the request can be routed
+	through a set of intermediate nodes, the first of which must be a peer.
+	`,
+	ArgsUsage: "offer",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "offer",
+			Usage: "the offer to request an invoice for",
+		},
+		cli.Uint64Flag{
+			Name: "amt_msat",
+			Usage: "the amount to pay in millisatoshis, required " +
+				"if the offer doesn't specify an amount",
+		},
+		cli.Uint64Flag{
+			Name: "quantity",
+			Usage: "the number of items to pay for, required if " +
+				"the offer allows paying for multiple items",
+		},
+		cli.StringFlag{
+			Name:  "payer_note",
+			Usage: "an optional note for the issuer",
+		},
+		cli.Uint64Flag{
+			Name: "timeout",
+			Usage: "the number of seconds to wait for the " +
+				"invoice (default: 30)",
+		},
+		cli.StringSliceFlag{
+			Name: "hop",
+			Usage: "the hex encoded public key of a node to route " +
+				"the request through, can be specified " +
+				"multiple times",
+		},
+	},
+	Action: actionDecorator(fetchInvoice),
+}
+
+func fetchInvoice(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	offer := ctx.String("offer")
+	if ctx.NArg() > 0 {
+		offer = ctx.Args().First()
+	}
+	if offer == "" {
+		return fmt.Errorf("offer argument missing")
+	}
+
+	req := &lnrpc.FetchInvoiceRequest{
+		Offer:          offer,
+		AmountMsat:     ctx.Uint64("amt_msat"),
+		PayerNote:      ctx.String("payer_note"),
+		TimeoutSeconds: uint32(ctx.Uint64("timeout")),
+	}
+	if ctx.IsSet("quantity") {
+		quantity := ctx.Uint64("quantity")
+		req.Quantity = &quantity
+	}
+
+	for _, hop := range ctx.StringSlice("hop") {
+		hopKey, err := hex.DecodeString(hop)
+		if err != nil {
+			return fmt.Errorf("unable to decode hop: %w", err)
+		}
+
+		req.Hops = append(req.Hops, hopKey)
+	}
+
+	resp, err := client.FetchInvoice(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var sendOnionMessageCommand = cli.Command{
+	Name:     "sendonionmessage",
+	Category: "Peers",
+	Usage:    "Send an onion message to a node.",
+	Description: `
+	Send an onion message to the node with the given public key. The message
+	can optionally be routed through a set of intermediate nodes, the first
+	of which must be a peer of our node.
+
+	The contents of the message are passed as a list of TLV records in the
+	form type:hexdata, where each type must be at least 64.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "dest",
+			Usage: "the hex encoded public key of the recipient",
+		},
+		cli.StringSliceFlag{
+			Name: "hop",
+			Usage: "the hex encoded public key of a node to route " +
+				"the message through, can be specified " +
+				"multiple times",
+		},
+		cli.StringSliceFlag{
+			Name: "tlv",
+			Usage: "a record of the message in the form " +
+				"type:hexdata, can be specified multiple times",
+		},
+		cli.BoolFlag{
+			Name: "reply_path",
+			Usage: "include a blinded reply path back to our " +
+				"node in the message",
+		},
+	},
+	Action: actionDecorator(sendOnionMessage),
+}
+
+func sendOnionMessage(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	dest, err := hex.DecodeString(ctx.String("dest"))
+	if err != nil {
+		return fmt.Errorf("unable to decode dest: %w", err)
+	}
+
+	req := &lnrpc.SendOnionMessageRequest{
+		Destination:      dest,
+		FinalHopTlvs:     make(map[uint64][]byte),
+		IncludeReplyPath: ctx.Bool("reply_path"),
+	}
+
+	for _, hop := range ctx.StringSlice("hop") {
+		hopKey, err := hex.DecodeString(hop)
+		if err != nil {
+			return fmt.Errorf("unable to decode hop: %w", err)
+		}
+
+		req.Hops = append(req.Hops, hopKey)
+	}
+
+	for _, record := range ctx.StringSlice("tlv") {
+		parts := strings.SplitN(record, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid tlv record %v, expected "+
+				"type:hexdata", record)
+		}
+
+		recordType, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid tlv type: %w", err)
+		}
+
+		recordData, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid tlv data: %w", err)
+		}
+
+		req.FinalHopTlvs[recordType] = recordData
+	}
+
+	resp, err := client.SendOnionMessage(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var subscribeOnionMessagesCommand = cli.Command{
+	Name:     "subscribeonionmessages",
+	Category: "Peers",
+	Usage:    "Subscribe to onion messages sent to our node.",
+	Action:   actionDecorator(subscribeOnionMessages),
+}
+
+func subscribeOnionMessages(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	stream, err := client.SubscribeOnionMessages(
+		ctxc, &lnrpc.SubscribeOnionMessagesRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		printRespJSON(msg)
+	}
+}
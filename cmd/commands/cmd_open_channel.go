@@ -282,6 +282,13 @@ var openChannelCommand = cli.Command{
 				has no bearing on the channel's operation. Max
 				allowed length is 500 characters`,
 		},
+		cli.BoolFlag{
+			Name: "dual_fund",
+			Usage: "(optional) open a dual funded channel, which " +
+				"allows the remote node to contribute funds " +
+				"of its own. Requires both nodes to enable " +
+				"protocol.dual-fund",
+		},
 	},
 	Action: actionDecorator(openChannel),
 }
@@ -326,6 +333,7 @@ func openChannel(ctx *cli.Context) error {
 		RemoteChanReserveSat:       ctx.Uint64("remote_reserve_sats"),
 		FundMax:                    ctx.Bool("fundmax"),
 		Memo:                       ctx.String("memo"),
+		DualFund:                   ctx.Bool("dual_fund"),
 	}
 
 	switch {
@@ -100,6 +100,25 @@ var (
 		Usage: "(optional) expresses time preference (range -1 to 1)",
 	}
 
+	minCostFlowFlag = cli.BoolFlag{
+		Name: "min_cost_flow",
+		Usage: "if set, multi-path payments are split up front by " +
+			"solving a min-cost flow over the graph instead of " +
+			"repeatedly halving the amount",
+	}
+
+	trampolineNodeFlag = cli.StringFlag{
+		Name: "trampoline_node",
+		Usage: "(optional) the hex encoded pubkey of a trampoline node " +
+			"that finds the route to the destination for us",
+	}
+
+	trampolineFeeMsatFlag = cli.Int64Flag{
+		Name: "trampoline_fee_msat",
+		Usage: "(optional) the fee in msat that is paid to the " +
+			"trampoline node on top of the payment amount",
+	}
+
 	introductionNodeFlag = cli.StringFlag{
 		Name: "introduction_node",
 		Usage: "(blinded paths) the hex encoded, cleartext node ID " +
@@ -199,7 +218,8 @@ func PaymentFlags() []cli.Flag {
 		},
 		dataFlag, inflightUpdatesFlag, maxPartsFlag, jsonFlag,
 		maxShardSizeSatFlag, maxShardSizeMsatFlag, ampFlag,
-		timePrefFlag,
+		timePrefFlag, minCostFlowFlag, mcNamespaceFlag,
+		trampolineNodeFlag, trampolineFeeMsatFlag,
 	}
 }
 
@@ -525,6 +545,19 @@ func SendPaymentRequest(ctx *cli.Context, req *routerrpc.SendPaymentRequest,
 	req.AllowSelfPayment = ctx.Bool("allow_self_payment")
 
 	req.MaxParts = uint32(ctx.Uint(maxPartsFlag.Name))
+	req.MinCostFlow = ctx.Bool(minCostFlowFlag.Name)
+	req.MissionControlNamespace = ctx.String(mcNamespaceFlag.Name)
+
+	if ctx.IsSet(trampolineNodeFlag.Name) {
+		trampolineNode, err := route.NewVertexFromStr(
+			ctx.String(trampolineNodeFlag.Name),
+		)
+		if err != nil {
+			return err
+		}
+		req.TrampolineNode = trampolineNode[:]
+	}
+	req.TrampolineFeeMsat = ctx.Int64(trampolineFeeMsatFlag.Name)
 
 	switch {
 	// If the max shard size is specified, then it should either be in sat
@@ -1490,6 +1523,11 @@ var forwardingHistoryCommand = cli.Command{
 	Finally, callers can skip a series of events using the --index_offset
 	parameter. Each response will contain the offset index of the last
 	entry. Using this callers can manually paginate within a time slice.
+
+	If lnd runs with the native SQL database, the events can also be
+	filtered by channel, peer, amount and fee, and the fees earned per
+	outgoing channel per day can be added to the response with
+	--aggregate_fees.
 	`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
@@ -1515,6 +1553,46 @@ var forwardingHistoryCommand = cli.Command{
 			Usage: "skip the peer alias lookup per forwarding " +
 				"event in order to improve performance",
 		},
+		cli.Uint64Flag{
+			Name: "incoming_chan_id",
+			Usage: "(optional) only return events that came in " +
+				"through this channel",
+		},
+		cli.Uint64Flag{
+			Name: "outgoing_chan_id",
+			Usage: "(optional) only return events that went out " +
+				"through this channel",
+		},
+		cli.StringFlag{
+			Name: "peer",
+			Usage: "(optional) only return events that came in " +
+				"from or went out to the peer with this pubkey",
+		},
+		cli.Uint64Flag{
+			Name: "min_amt_msat",
+			Usage: "(optional) only return events that forwarded " +
+				"at least this amount",
+		},
+		cli.Uint64Flag{
+			Name: "max_amt_msat",
+			Usage: "(optional) only return events that forwarded " +
+				"at most this amount",
+		},
+		cli.Uint64Flag{
+			Name: "min_fee_msat",
+			Usage: "(optional) only return events that earned at " +
+				"least this fee",
+		},
+		cli.Uint64Flag{
+			Name: "max_fee_msat",
+			Usage: "(optional) only return events that earned at " +
+				"most this fee",
+		},
+		cli.BoolFlag{
+			Name: "aggregate_fees",
+			Usage: "also return the fees earned per outgoing " +
+				"channel per day for all matching events",
+		},
 	},
 	Action: actionDecorator(forwardingHistory),
 }
@@ -1594,6 +1672,14 @@ func forwardingHistory(ctx *cli.Context) error {
 		IndexOffset:     indexOffset,
 		NumMaxEvents:    maxEvents,
 		PeerAliasLookup: lookupPeerAlias,
+		IncomingChanId:  ctx.Uint64("incoming_chan_id"),
+		OutgoingChanId:  ctx.Uint64("outgoing_chan_id"),
+		Peer:            ctx.String("peer"),
+		MinAmtMsat:      ctx.Uint64("min_amt_msat"),
+		MaxAmtMsat:      ctx.Uint64("max_amt_msat"),
+		MinFeeMsat:      ctx.Uint64("min_fee_msat"),
+		MaxFeeMsat:      ctx.Uint64("max_fee_msat"),
+		AggregateFees:   ctx.Bool("aggregate_fees"),
 	}
 	resp, err := client.ForwardingHistory(ctxc, req)
 	if err != nil {
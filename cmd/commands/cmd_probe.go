@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var startProbingCommand = cli.Command{
+	Name:     "startprobing",
+	Category: "Payments",
+	Usage:    "Start sending background probes to feed mission control.",
+	Description: `
+	Start a background probing run that periodically sends probes with a
+	random payment hash to a set of destinations, or to the nodes with the
+	highest betweenness centrality in the graph. The probes can't be
+	settled, but their results are fed into mission control, which
+	improves the success estimates of later payments.
+
+	Only one probing run can be active at a time. Use subscribeprobes to
+	follow the results of the probes and stopprobing to stop the run.`,
+	ArgsUsage: "(--dest=X [--dest=Y] | --num_centrality_nodes=N) " +
+		"--amt_msat=A",
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name: "dest",
+			Usage: "the identity pubkey of a node to probe; can " +
+				"be specified multiple times in the same " +
+				"command",
+		},
+		cli.UintFlag{
+			Name: "num_centrality_nodes",
+			Usage: "the number of nodes with the highest " +
+				"betweenness centrality to probe",
+		},
+		cli.Int64Flag{
+			Name:  "amt_msat",
+			Usage: "the amount in millisatoshis of each probe",
+		},
+		cli.Int64Flag{
+			Name: "fee_limit_msat",
+			Usage: "the maximum routing fee in millisatoshis of " +
+				"the probed routes; defaults to the probe " +
+				"amount",
+		},
+		cli.DurationFlag{
+			Name:  "interval",
+			Usage: "the time between two probes",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "the maximum time spent on a single probe",
+		},
+		cli.UintFlag{
+			Name: "max_probes",
+			Usage: "the number of probes after which probing " +
+				"stops; if not set, probing continues until " +
+				"it is stopped",
+		},
+		cli.StringFlag{
+			Name: "mission_control_namespace",
+			Usage: "the mission control namespace that the " +
+				"probe results are fed into",
+		},
+	},
+	Action: actionDecorator(startProbing),
+}
+
+func startProbing(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	if ctx.NArg() != 0 || ctx.NumFlags() == 0 {
+		return cli.ShowCommandHelp(ctx, "startprobing")
+	}
+
+	req := &routerrpc.StartProbingRequest{
+		NumCentralityNodes: uint32(ctx.Uint("num_centrality_nodes")),
+		AmtMsat:            ctx.Int64("amt_msat"),
+		FeeLimitMsat:       ctx.Int64("fee_limit_msat"),
+		IntervalSeconds:    uint32(ctx.Duration("interval").Seconds()),
+		TimeoutSeconds:     uint32(ctx.Duration("timeout").Seconds()),
+		MaxProbes:          uint32(ctx.Uint("max_probes")),
+		MissionControlNamespace: ctx.String(
+			"mission_control_namespace",
+		),
+	}
+
+	for _, dest := range ctx.StringSlice("dest") {
+		destBytes, err := hex.DecodeString(dest)
+		if err != nil {
+			return fmt.Errorf("invalid destination %v: %w", dest,
+				err)
+		}
+
+		req.Destinations = append(req.Destinations, destBytes)
+	}
+
+	if len(req.Destinations) == 0 && req.NumCentralityNodes == 0 {
+		return errors.New("either dest or num_centrality_nodes must " +
+			"be set")
+	}
+
+	client := routerrpc.NewRouterClient(conn)
+	resp, err := client.StartProbing(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var stopProbingCommand = cli.Command{
+	Name:     "stopprobing",
+	Category: "Payments",
+	Usage:    "Stop the active background probing run.",
+	Action:   actionDecorator(stopProbing),
+}
+
+func stopProbing(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+	resp, err := client.StopProbing(
+		ctxc, &routerrpc.StopProbingRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var subscribeProbesCommand = cli.Command{
+	Name:     "subscribeprobes",
+	Category: "Payments",
+	Usage:    "Follow the results of the background probes.",
+	Action:   actionDecorator(subscribeProbes),
+}
+
+func subscribeProbes(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+	stream, err := client.SubscribeProbeResults(
+		ctxc, &routerrpc.SubscribeProbeResultsRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		result, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		printRespJSON(result)
+	}
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var rebalanceChannelCommand = cli.Command{
+	Name:     "rebalancechannel",
+	Category: "Channels",
+	Usage:    "Move liquidity between our own channels.",
+	Description: `
+	Move liquidity out of one or more of our channels into another one of
+	our channels by paying ourselves along circular routes. The amount may
+	be split across the outgoing channels.
+
+	The rebalance is recorded as a payment with the rebalance flag set, so
+	that it can be told apart from regular payments.`,
+	ArgsUsage: "--outgoing_chan_id=X [--outgoing_chan_id=Y] " +
+		"--incoming_chan_id=Z --amt=N --max_fee_ppm=P",
+	Flags: []cli.Flag{
+		cli.Int64SliceFlag{
+			Name: "outgoing_chan_id",
+			Usage: "short channel id of the channel to move " +
+				"liquidity out of; can be specified multiple " +
+				"times in the same command",
+			Value: &cli.Int64Slice{},
+		},
+		cli.Uint64Flag{
+			Name: "incoming_chan_id",
+			Usage: "short channel id of the channel to move " +
+				"liquidity into",
+		},
+		cli.Int64Flag{
+			Name:  "amt",
+			Usage: "number of satoshis to move",
+		},
+		cli.Uint64Flag{
+			Name: "max_fee_ppm",
+			Usage: "the maximum fee to pay, expressed in parts " +
+				"per million of the amount",
+		},
+		cli.DurationFlag{
+			Name: "timeout",
+			Usage: "the maximum amount of time we should spend " +
+				"trying to fulfill the rebalance, failing " +
+				"after the timeout has elapsed",
+			Value: paymentTimeout,
+		},
+		maxPartsFlag, inflightUpdatesFlag, jsonFlag,
+	},
+	Action: actionDecorator(rebalanceChannel),
+}
+
+func rebalanceChannel(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		_ = cli.ShowCommandHelp(ctx, "rebalancechannel")
+		return nil
+	}
+
+	printJSON := ctx.Bool(jsonFlag.Name)
+	req := &routerrpc.RebalanceChannelRequest{
+		IncomingChanId: ctx.Uint64("incoming_chan_id"),
+		Amt:            ctx.Int64("amt"),
+		MaxFeePpm:      ctx.Uint64("max_fee_ppm"),
+		MaxParts:       uint32(ctx.Uint(maxPartsFlag.Name)),
+		NoInflightUpdates: !ctx.Bool(inflightUpdatesFlag.Name) &&
+			printJSON,
+	}
+
+	for _, chanID := range ctx.Int64Slice("outgoing_chan_id") {
+		req.OutgoingChanIds = append(
+			req.OutgoingChanIds, uint64(chanID),
+		)
+	}
+
+	timeout := ctx.Duration("timeout")
+	if timeout <= 0 {
+		return errors.New("timeout must be greater than zero")
+	}
+	req.TimeoutSeconds = int32(timeout.Seconds())
+
+	routerClient := routerrpc.NewRouterClient(conn)
+	stream, err := routerClient.RebalanceChannel(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	client := lnrpc.NewLightningClient(conn)
+	finalState, err := PrintLivePayment(ctxc, stream, client, printJSON)
+	if err != nil {
+		return err
+	}
+
+	// If we get a payment error back, we pass an error up to main which
+	// eventually calls fatal() and returns with a non-zero exit code.
+	if finalState.Status != lnrpc.Payment_SUCCEEDED {
+		return errors.New(finalState.Status.String())
+	}
+
+	return nil
+}
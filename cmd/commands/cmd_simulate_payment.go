@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/urfave/cli"
+)
+
+var simulatePaymentCommand = cli.Command{
+	Name:     "simulatepayment",
+	Category: "Payments",
+	Usage:    "Simulate paying an invoice without sending anything.",
+	Description: `
+	Plan the shards of a payment to an invoice the same way payinvoice
+	would, without sending anything. Every shard is checked against the
+	current bandwidth, htlc slots, max in flight, channel reserve and dust
+	exposure of our channels.
+
+	The output contains the planned shards, the expected fee and success
+	probability and the reason why each of our channels was skipped.`,
+	ArgsUsage: "pay_req",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pay_req",
+			Usage: "a zpay32 encoded payment request to simulate",
+		},
+		cli.Int64Flag{
+			Name: "amt",
+			Usage: "(optional) number of satoshis to fulfill the " +
+				"invoice",
+		},
+		cli.Int64Flag{
+			Name: "fee_limit",
+			Usage: "maximum fee allowed in satoshis when " +
+				"sending the payment",
+		},
+		cli.Int64Flag{
+			Name: "fee_limit_percent",
+			Usage: "percentage of the payment's amount used as " +
+				"the maximum fee allowed when sending the " +
+				"payment",
+		},
+		cltvLimitFlag,
+		lastHopFlag,
+		cli.Int64SliceFlag{
+			Name: "outgoing_chan_id",
+			Usage: "short channel id of the outgoing channel to " +
+				"use for the first hop of the payment; can " +
+				"be specified multiple times in the same " +
+				"command",
+			Value: &cli.Int64Slice{},
+		},
+		maxPartsFlag, maxShardSizeMsatFlag, timePrefFlag,
+		minCostFlowFlag, mcNamespaceFlag,
+	},
+	Action: actionDecorator(simulatePayment),
+}
+
+func simulatePayment(ctx *cli.Context) error {
+	ctxc := getContext()
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	var payReq string
+	switch {
+	case ctx.IsSet("pay_req"):
+		payReq = ctx.String("pay_req")
+	case ctx.Args().Present():
+		payReq = ctx.Args().First()
+	default:
+		return fmt.Errorf("pay_req argument missing")
+	}
+
+	req := &routerrpc.SendPaymentRequest{
+		PaymentRequest:          StripPrefix(payReq),
+		Amt:                     ctx.Int64("amt"),
+		CltvLimit:               int32(ctx.Int(cltvLimitFlag.Name)),
+		MaxParts:                uint32(ctx.Uint(maxPartsFlag.Name)),
+		MaxShardSizeMsat:        ctx.Uint64(maxShardSizeMsatFlag.Name),
+		TimePref:                ctx.Float64(timePrefFlag.Name),
+		MinCostFlow:             ctx.Bool(minCostFlowFlag.Name),
+		MissionControlNamespace: ctx.String(mcNamespaceFlag.Name),
+	}
+
+	for _, chanID := range ctx.Int64Slice("outgoing_chan_id") {
+		req.OutgoingChanIds = append(
+			req.OutgoingChanIds, uint64(chanID),
+		)
+	}
+
+	if ctx.IsSet(lastHopFlag.Name) {
+		lastHop, err := route.NewVertexFromStr(
+			ctx.String(lastHopFlag.Name),
+		)
+		if err != nil {
+			return err
+		}
+		req.LastHopPubkey = lastHop[:]
+	}
+
+	// The fee limit is based on the amount of the invoice, unless it is
+	// overridden.
+	amt := req.Amt
+	if amt == 0 {
+		client := lnrpc.NewLightningClient(conn)
+		decodeResp, err := client.DecodePayReq(
+			ctxc, &lnrpc.PayReqString{PayReq: req.PaymentRequest},
+		)
+		if err != nil {
+			return err
+		}
+		amt = decodeResp.NumSatoshis
+	}
+
+	feeLimit, err := retrieveFeeLimit(ctx, amt)
+	if err != nil {
+		return err
+	}
+	req.FeeLimitSat = feeLimit
+
+	routerClient := routerrpc.NewRouterClient(conn)
+	resp, err := routerClient.SimulatePayment(
+		ctxc, &routerrpc.SimulatePaymentRequest{
+			Payment: req,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var spliceChannelCommand = cli.Command{
+	Name:     "splicechannel",
+	Category: "Channels",
+	Usage:    "Resize an active channel by splicing funds in or out.",
+	Description: `
+	Splice funds into or out of an existing channel, without closing it.
+
+	A positive --amt is spliced into the channel from the on-chain wallet.
+	A negative --amt is spliced out of our channel balance and sent to the
+	address given with --addr, or to a new wallet address if none is set.
+	We pay the fees of the splice transaction in both cases.
+
+	The channel keeps forwarding payments while the splice transaction
+	confirms. Splicing is experimental, only works between lnd nodes and
+	requires the protocol.splice option of dev builds on both nodes. Only
+	private, non-taproot channels can be spliced, only the initiator
+	contributes funds and splice transactions can't be replaced with RBF.
+
+	The format for a channel_point is 'funding_txid:output_index'.`,
+	ArgsUsage: "funding_txid output_index",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "funding_txid",
+			Usage: "the txid of the channel's funding transaction",
+		},
+		cli.IntFlag{
+			Name: "output_index",
+			Usage: "the output index for the funding output of " +
+				"the funding transaction",
+		},
+		cli.StringFlag{
+			Name: "chan_point",
+			Usage: "(optional) the channel point. If set, " +
+				"funding_txid and output_index flags and " +
+				"positional arguments will be ignored",
+		},
+		cli.Int64Flag{
+			Name: "amt",
+			Usage: "the amount in satoshis to splice in, or to " +
+				"splice out if negative",
+		},
+		cli.Int64Flag{
+			Name: "conf_target",
+			Usage: "(optional) the number of blocks that the " +
+				"transaction *should* confirm in, will be " +
+				"used for fee estimation",
+		},
+		cli.Uint64Flag{
+			Name: "sat_per_vbyte",
+			Usage: "(optional) a manual fee expressed in " +
+				"sat/vbyte that should be used when crafting " +
+				"the transaction; default is a conf-target " +
+				"of 6 blocks",
+		},
+		cli.StringFlag{
+			Name: "addr",
+			Usage: "(optional) the address the funds of a " +
+				"splice-out are sent to",
+		},
+	},
+	Action: actionDecorator(spliceChannel),
+}
+
+func spliceChannel(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments and flags were provided.
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		return cli.ShowCommandHelp(ctx, "splicechannel")
+	}
+
+	if !ctx.IsSet("amt") || ctx.Int64("amt") == 0 {
+		return fmt.Errorf("a non-zero amt must be specified")
+	}
+
+	channelPoint, err := parseChannelPoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.SpliceChannel(ctxc, &lnrpc.SpliceChannelRequest{
+		ChannelPoint: channelPoint,
+		Amount:       ctx.Int64("amt"),
+		TargetConf:   int32(ctx.Int64("conf_target")),
+		SatPerVbyte:  ctx.Uint64("sat_per_vbyte"),
+		Address:      ctx.String("addr"),
+	})
+	if err != nil {
+		return err
+	}
+
+	txid, err := chainhash.NewHash(resp.Txid)
+	if err != nil {
+		return err
+	}
+
+	printJSON(struct {
+		SpliceTxid string `json:"splice_txid"`
+	}{
+		SpliceTxid: txid.String(),
+	})
+
+	return nil
+}
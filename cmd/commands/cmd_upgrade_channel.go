@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var upgradeChannelCommand = cli.Command{
+	Name:     "upgradechannel",
+	Category: "Channels",
+	Usage: "Upgrade the commitment type or the parameters of an active " +
+		"channel.",
+	Description: `
+	Change the commitment type or the parameters of an existing channel
+	with a dynamic commitment upgrade, without closing it.
+
+	Channels can be upgraded to the static_remote_key and anchors
+	commitment types. Upgrades to taproot channels aren't supported yet,
+	as they need a new funding output. Parameters that aren't set are left
+	unchanged. The channel is quiesced while the upgrade is negotiated,
+	and the remote party may reject any of the proposed parameters.
+	Dynamic commitments are experimental and require the
+	protocol.dyn-commitments option of dev builds on both nodes.
+
+	The format for a channel_point is 'funding_txid:output_index'.`,
+	ArgsUsage: "funding_txid output_index",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "funding_txid",
+			Usage: "the txid of the channel's funding transaction",
+		},
+		cli.IntFlag{
+			Name: "output_index",
+			Usage: "the output index for the funding output of " +
+				"the funding transaction",
+		},
+		cli.StringFlag{
+			Name: "chan_point",
+			Usage: "(optional) the channel point. If set, " +
+				"funding_txid and output_index flags and " +
+				"positional arguments will be ignored",
+		},
+		cli.StringFlag{
+			Name: "channel_type",
+			Usage: fmt.Sprintf("(optional) the new commitment "+
+				"type of the channel, either %q or %q",
+				channelTypeTweakless, channelTypeAnchors),
+		},
+		cli.Uint64Flag{
+			Name: "dust_limit",
+			Usage: "(optional) the new dust limit in satoshis of " +
+				"our commitment transaction",
+		},
+		cli.Uint64Flag{
+			Name: "remote_max_value_in_flight_msat",
+			Usage: "(optional) the new maximum value in msat the " +
+				"remote party may have pending with us",
+		},
+		cli.Uint64Flag{
+			Name: "remote_reserve_sats",
+			Usage: "(optional) the new reserve in satoshis the " +
+				"remote party has to keep in the channel",
+		},
+		cli.Uint64Flag{
+			Name: "remote_csv_delay",
+			Usage: "(optional) the new delay in blocks of the " +
+				"remote party's outputs on its commitment " +
+				"transaction",
+		},
+		cli.Uint64Flag{
+			Name: "remote_max_htlcs",
+			Usage: "(optional) the new maximum number of HTLCs " +
+				"the remote party may offer us",
+		},
+	},
+	Action: actionDecorator(upgradeChannel),
+}
+
+func upgradeChannel(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments and flags were provided.
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		return cli.ShowCommandHelp(ctx, "upgradechannel")
+	}
+
+	channelPoint, err := parseChannelPoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &lnrpc.UpgradeChannelRequest{
+		ChannelPoint: channelPoint,
+		DustLimitSat: ctx.Uint64("dust_limit"),
+		RemoteMaxValueInFlightMsat: ctx.Uint64(
+			"remote_max_value_in_flight_msat",
+		),
+		RemoteChanReserveSat: ctx.Uint64("remote_reserve_sats"),
+		RemoteCsvDelay:       uint32(ctx.Uint64("remote_csv_delay")),
+		RemoteMaxHtlcs:       uint32(ctx.Uint64("remote_max_htlcs")),
+	}
+
+	switch channelType := ctx.String("channel_type"); channelType {
+	case "":
+
+	case channelTypeTweakless:
+		req.CommitmentType = lnrpc.CommitmentType_STATIC_REMOTE_KEY
+
+	case channelTypeAnchors:
+		req.CommitmentType = lnrpc.CommitmentType_ANCHORS
+
+	default:
+		return fmt.Errorf("unsupported channel type %v", channelType)
+	}
+
+	resp, err := client.UpgradeChannel(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -930,6 +930,13 @@ var closeChannelCommand = cli.Command{
 				"success pobability of the negotiation if " +
 				"set higher",
 		},
+		cli.BoolFlag{
+			Name: "bump_fee",
+			Usage: "(optional) replace an in-flight cooperative " +
+				"close transaction with one paying the new " +
+				"fee rate; only supported by channels " +
+				"negotiated with simple-close",
+		},
 	},
 	Action: actionDecorator(closeChannel),
 }
@@ -967,6 +974,7 @@ func closeChannel(ctx *cli.Context) error {
 		SatPerVbyte:     ctx.Uint64(feeRateFlag),
 		DeliveryAddress: ctx.String("delivery_addr"),
 		MaxFeePerVbyte:  ctx.Uint64("max_fee_rate"),
+		BumpFee:         ctx.Bool("bump_fee"),
 	}
 
 	// After parsing the request, we'll spin up a goroutine that will
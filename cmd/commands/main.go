@@ -511,6 +511,12 @@ func Main() {
 		deletePaymentsCommand,
 		sendCustomCommand,
 		subscribeCustomCommand,
+		sendOnionMessageCommand,
+		subscribeOnionMessagesCommand,
+		addOfferCommand,
+		fetchInvoiceCommand,
+		spliceChannelCommand,
+		upgradeChannelCommand,
 		fishCompletionCommand,
 		listAliasesCommand,
 		estimateRouteFeeCommand,
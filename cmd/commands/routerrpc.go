@@ -13,5 +13,11 @@ func routerCommands() []cli.Command {
 		getCfgCommand,
 		setCfgCommand,
 		updateChanStatusCommand,
+		rebalanceChannelCommand,
+		startProbingCommand,
+		stopProbingCommand,
+		subscribeProbesCommand,
+		simulatePaymentCommand,
+		htlcRateLimitsCommand,
 	}
 }
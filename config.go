@@ -420,6 +420,8 @@ type Config struct {
 
 	RejectPush bool `long:"rejectpush" description:"If true, lnd will not accept channel opening requests with non-zero push amounts. This should prevent accidental pushes to merchant nodes."`
 
+	DualFundContribution int64 `long:"dualfundcontribution" description:"The maximum amount in satoshis a channel acceptor may contribute to dual funded channels opened by remote peers. Nothing is contributed unless a channel acceptor approves it. Requires protocol.dual-fund."`
+
 	RejectHTLC bool `long:"rejecthtlc" description:"If true, lnd will not forward any HTLCs that are meant as onward payments. This option will still allow lnd to send HTLCs and receive HTLCs but lnd won't be used as a hop."`
 
 	AcceptPositiveInboundFees bool `long:"accept-positive-inbound-fees" description:"If true, lnd will also allow setting positive inbound fees. By default, lnd only allows to set negative inbound fees (an inbound \"discount\") to remain backwards compatible with senders whose implementations do not yet support inbound fees."`
@@ -490,6 +492,8 @@ type Config struct {
 
 	Htlcswitch *lncfg.Htlcswitch `group:"htlcswitch" namespace:"htlcswitch"`
 
+	OnionMsg *lncfg.OnionMsg `group:"onionmsg" namespace:"onionmsg"`
+
 	GRPC *GRPCConfig `group:"grpc" namespace:"grpc"`
 
 	// LogWriter is the root logger that all of the daemon's subloggers are
@@ -696,7 +700,8 @@ func DefaultConfig() Config {
 			SubBatchDelay:         discovery.DefaultSubBatchDelay,
 		},
 		Invoices: &lncfg.Invoices{
-			HoldExpiryDelta: lncfg.DefaultHoldInvoiceExpiryDelta,
+			HoldExpiryDelta:     lncfg.DefaultHoldInvoiceExpiryDelta,
+			HtlcAcceptorTimeout: lncfg.DefaultHtlcAcceptorTimeout,
 		},
 		Routing: &lncfg.Routing{
 			BlindedPaths: lncfg.BlindedPaths{
@@ -706,6 +711,23 @@ func DefaultConfig() Config {
 				PolicyIncreaseMultiplier: lncfg.DefaultBlindedPathPolicyIncreaseMultiplier,
 				PolicyDecreaseMultiplier: lncfg.DefaultBlindedPathPolicyDecreaseMultiplier,
 			},
+			FeeAutopilot: lncfg.FeeAutopilot{
+				Interval:           lncfg.DefaultFeeAutopilotInterval,
+				CoolDown:           lncfg.DefaultFeeAutopilotCoolDown,
+				ForwardingWindow:   lncfg.DefaultFeeAutopilotForwardingWindow,
+				LowLiquidityRatio:  lncfg.DefaultFeeAutopilotLowLiquidityRatio,
+				HighLiquidityRatio: lncfg.DefaultFeeAutopilotHighLiquidityRatio,
+				MinDailyFlowRatio:  lncfg.DefaultFeeAutopilotMinDailyFlowRatio,
+				MinFeeRate:         lncfg.DefaultFeeAutopilotMinFeeRate,
+				MaxFeeRate:         lncfg.DefaultFeeAutopilotMaxFeeRate,
+				FeeRateStep:        lncfg.DefaultFeeAutopilotFeeRateStep,
+				MinInboundFeeRate:  lncfg.DefaultFeeAutopilotMinInboundFeeRate,
+				InboundFeeRateStep: lncfg.DefaultFeeAutopilotInboundFeeRateStep,
+			},
+			PathCache: lncfg.PathCache{
+				Size: lncfg.DefaultPathCacheSize,
+				TTL:  lncfg.DefaultPathCacheTTL,
+			},
 		},
 		MaxOutgoingCltvExpiry:     htlcswitch.DefaultMaxOutgoingCltvExpiry,
 		MaxChannelFeeAllocation:   htlcswitch.DefaultMaxLinkFeeAllocation,
@@ -727,7 +749,20 @@ func DefaultConfig() Config {
 		Sweeper: lncfg.DefaultSweeperConfig(),
 		Htlcswitch: &lncfg.Htlcswitch{
 			MailboxDeliveryTimeout: htlcswitch.DefaultMailboxDeliveryTimeout,
+			Jamming: &lncfg.Jamming{
+				ProtectedPercentage:  htlcswitch.DefaultProtectedPercentage,
+				RevenueWindow:        htlcswitch.DefaultRevenueWindow,
+				ReputationMultiplier: htlcswitch.DefaultReputationMultiplier,
+				ResolutionPeriod:     htlcswitch.DefaultResolutionPeriod,
+			},
+			RateLimit: &lncfg.HtlcRateLimit{
+				PeerAddRate:  htlcswitch.DefaultPeerAddRate,
+				PeerAddBurst: htlcswitch.DefaultPeerAddBurst,
+				ChanAddRate:  htlcswitch.DefaultChannelAddRate,
+				ChanAddBurst: htlcswitch.DefaultChannelAddBurst,
+			},
 		},
+		OnionMsg: lncfg.DefaultOnionMsg(),
 		GRPC: &GRPCConfig{
 			ServerPingTime:    defaultGrpcServerPingTime,
 			ServerPingTimeout: defaultGrpcServerPingTimeout,
@@ -1062,6 +1097,17 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 		)
 	}
 
+	// Contributing to dual funded channels requires signaling support
+	// for them.
+	switch {
+	case cfg.DualFundContribution < 0:
+		return nil, mkErr("dual fund contribution must not be negative")
+
+	case cfg.DualFundContribution > 0 && !cfg.ProtocolOptions.DualFund():
+		return nil, mkErr("dual fund contribution requires " +
+			"protocol.dual-fund")
+	}
+
 	// Ensure that the amount data for revoked commitment transactions is
 	// stored if the watchtower client is active.
 	if cfg.DB.NoRevLogAmtData && cfg.WtClient.Active {
@@ -1692,6 +1738,16 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 		return nil, mkErr("custom-message: %v", err)
 	}
 
+	// Onion messages can either be handled natively or passed on to an
+	// external daemon through the custom message APIs, but not both.
+	if cfg.ProtocolOptions.OnionMessages() &&
+		lnwire.IsCustomOverride(lnwire.MsgOnionMessage) {
+
+		return nil, mkErr("protocol.onion-messages can't be set "+
+			"when %d is a protocol.custom-message override",
+			lnwire.MsgOnionMessage)
+	}
+
 	// Validate the subconfigs for workers, caches, and the tower client.
 	err = lncfg.Validate(
 		cfg.Workers,
@@ -1704,6 +1760,7 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 		cfg.RemoteSigner,
 		cfg.Sweeper,
 		cfg.Htlcswitch,
+		cfg.OnionMsg,
 		cfg.Invoices,
 		cfg.Routing,
 	)
@@ -1008,6 +1008,58 @@ func (d *DefaultDatabaseBuilder) BuildDatabase(
 		)
 	}
 
+	// Keep the channel graph, the payments and the forwarding log in the
+	// native SQL store if the flag is set. Any existing kv data must be
+	// migrated to it with the explicit offline migration first.
+	if d.cfg.DB.UseNativeSQL {
+		graphExecutor := sqldb.NewTransactionExecutor(
+			dbs.NativeSQLStore,
+			func(tx *sql.Tx) channeldb.SQLGraphQueries {
+				return dbs.NativeSQLStore.WithTx(tx)
+			},
+		)
+
+		dbOptions = append(
+			dbOptions, channeldb.OptionSetGraphSQLStore(
+				channeldb.NewSQLGraphStore(graphExecutor),
+			),
+		)
+
+		paymentsExecutor := sqldb.NewTransactionExecutor(
+			dbs.NativeSQLStore,
+			func(tx *sql.Tx) channeldb.SQLPaymentsQueries {
+				return dbs.NativeSQLStore.WithTx(tx)
+			},
+		)
+
+		dbOptions = append(
+			dbOptions, channeldb.OptionSetPaymentsSQLStore(
+				channeldb.NewSQLPaymentsStore(paymentsExecutor),
+			),
+		)
+
+		fwdLogExecutor := sqldb.NewTransactionExecutor(
+			dbs.NativeSQLStore,
+			func(tx *sql.Tx) channeldb.SQLForwardingLogQueries {
+				return dbs.NativeSQLStore.WithTx(tx)
+			},
+		)
+
+		dbOptions = append(
+			dbOptions, channeldb.OptionSetForwardingLogSQLStore(
+				channeldb.NewSQLForwardingLogStore(
+					fwdLogExecutor,
+				),
+			),
+		)
+	}
+
+	if d.cfg.DB.MigrateNativeSQL {
+		dbOptions = append(
+			dbOptions, channeldb.OptionMigrateNativeSQL(true),
+		)
+	}
+
 	// Otherwise, we'll open two instances, one for the state we only need
 	// locally, and the other for things we want to ensure are replicated.
 	dbs.GraphDB, err = channeldb.CreateWithBackend(
@@ -1021,6 +1073,21 @@ func (d *DefaultDatabaseBuilder) BuildDatabase(
 		d.logger.Infof("Graph DB dry run migration successful")
 		return nil, nil, err
 
+	// The explicit migration to native SQL doesn't open the DB.
+	case err == channeldb.ErrNativeSQLMigrationOK:
+		cleanUp()
+
+		d.logger.Infof("Migration to native SQL successful")
+		return nil, nil, err
+
+	case errors.Is(err, channeldb.ErrNativeSQLMigrationRequired):
+		cleanUp()
+
+		err = fmt.Errorf("unable to open graph DB: %w, run lnd with "+
+			"--db.migrate-native-sql once", err)
+		d.logger.Error(err)
+		return nil, nil, err
+
 	case err != nil:
 		cleanUp()
 
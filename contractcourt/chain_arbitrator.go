@@ -299,6 +299,12 @@ func (a *arbChannel) NewAnchorResolutions() (*lnwallet.AnchorResolutions,
 		return nil, err
 	}
 
+	// The commitments of a confirmed splice that hasn't been promoted yet
+	// spend its funding output, so their anchors are the ones to resolve.
+	if err := channel.ApplyConfirmedSplice(); err != nil {
+		return nil, err
+	}
+
 	chanMachine, err := lnwallet.NewLightningChannel(
 		a.c.cfg.Signer, channel, nil,
 	)
@@ -344,6 +350,13 @@ func (a *arbChannel) ForceCloseChan() (*lnwallet.LocalForceCloseSummary, error)
 		return nil, err
 	}
 
+	// The funding output of a confirmed splice is the only one left to
+	// close the channel with. With the link gone, we can promote the
+	// splice ourselves.
+	if err := channel.PromoteConfirmedSplice(); err != nil {
+		return nil, err
+	}
+
 	// Finally, we'll force close the channel completing
 	// the force close workflow.
 	chanMachine, err := lnwallet.NewLightningChannel(
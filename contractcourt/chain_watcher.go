@@ -2,6 +2,7 @@ package contractcourt
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"slices"
 	"sync"
@@ -274,8 +275,13 @@ func (c *chainWatcher) Start() error {
 		chanState.FundingOutpoint)
 
 	// First, we'll register for a notification to be dispatched if the
-	// funding output is spent.
-	fundingOut := &chanState.FundingOutpoint
+	// funding output is spent. If the channel has been spliced, this is
+	// the funding output of the latest confirmed splice, even if it
+	// hasn't been promoted yet.
+	fundingOut := chanState.CurrentFundingOutpoint()
+	chanState.ConfirmedSplice().WhenSome(func(op wire.OutPoint) {
+		fundingOut = op
+	})
 
 	// As a height hint, we'll try to use the opening height, but if the
 	// channel isn't yet open, then we'll use the height it was broadcast
@@ -328,7 +334,7 @@ func (c *chainWatcher) Start() error {
 	}
 
 	spendNtfn, err := c.cfg.notifier.RegisterSpendNtfn(
-		fundingOut, c.fundingPkScript, c.heightHint,
+		&fundingOut, c.fundingPkScript, c.heightHint,
 	)
 	if err != nil {
 		return err
@@ -524,6 +530,14 @@ func newChainSet(chanState *channeldb.OpenChannel) (*chainSet, error) {
 			"chan_point=%v", chanState.FundingOutpoint)
 	}
 
+	// If a splice confirmed that hasn't been promoted yet, the
+	// commitments spending its funding output are the variants of the
+	// ones on disk.
+	if err := chanState.ApplyConfirmedSplice(); err != nil {
+		return nil, fmt.Errorf("unable to apply confirmed splice for "+
+			"chan_point=%v: %w", chanState.FundingOutpoint, err)
+	}
+
 	log.Tracef("ChannelPoint(%v): local_commit_type=%v, local_commit=%v",
 		chanState.FundingOutpoint, chanState.ChanType,
 		spew.Sdump(localCommit))
@@ -554,6 +568,17 @@ func newChainSet(chanState *channeldb.OpenChannel) (*chainSet, error) {
 	var remotePendingCommit *channeldb.ChannelCommitment
 	if remoteChainTip != nil {
 		remotePendingCommit = &remoteChainTip.Commitment
+
+		// The pending commitment has a variant for the confirmed
+		// splice as well, unless the splice was promoted already.
+		fundingTxid := chanState.CurrentFundingOutpoint().Hash
+		spliceCommit, err := remotePendingCommit.SpliceCommitment(
+			fundingTxid,
+		)
+		if err == nil {
+			remotePendingCommit = spliceCommit
+		}
+
 		log.Tracef("ChannelPoint(%v): remote_pending_commit_type=%v, "+
 			"remote_pending_commit=%v", chanState.FundingOutpoint,
 			chanState.ChanType,
@@ -636,10 +661,32 @@ func (c *chainWatcher) closeObserver(spendNtfn *chainntnfs.SpendEvent) {
 			return
 		}
 
+		// A splice transaction spends the funding output without
+		// closing the channel, in which case we'll continue to watch
+		// the funding output it created.
+		spliced, err := c.handleSplice(commitSpend)
+		if err != nil {
+			log.Errorf("Unable to handle splice: %v", err)
+			return
+		}
+
+		if spliced {
+			return
+		}
+
 		// Otherwise, the remote party might have broadcast a prior
 		// revoked state...!!!
 		commitTxBroadcast := commitSpend.SpendingTx
 
+		// A dynamic commitment upgrade may have changed the channel
+		// type and configs since we started watching the channel, so
+		// we'll make sure to act on the latest ones.
+		if err := c.cfg.chanState.Refresh(); err != nil {
+			log.Warnf("Unable to refresh state of "+
+				"ChannelPoint(%v): %v", c.cfg.chanState.
+				FundingOutpoint, err)
+		}
+
 		// First, we'll construct the chainset which includes all the
 		// data we need to dispatch an event to our subscribers about
 		// this possible channel close event.
@@ -752,6 +799,64 @@ func (c *chainWatcher) closeObserver(spendNtfn *chainntnfs.SpendEvent) {
 	}
 }
 
+// handleSplice checks whether the passed spend is a confirmed splice of the
+// channel. If so, the splice is marked as confirmed, and a new close observer
+// is launched to watch its funding output. The splice itself is promoted by
+// the link once both parties sent splice_locked, as the link still updates
+// the channel in the meantime. If the spend isn't a splice, false is returned.
+func (c *chainWatcher) handleSplice(
+	commitSpend *chainntnfs.SpendDetail) (bool, error) {
+
+	// The funding keys of the channel aren't changed by a splice, so any
+	// splice transaction has an output paying to the funding script. This
+	// allows us to skip the database lookup for regular closes.
+	spendingTx := commitSpend.SpendingTx
+	fundingIdx := -1
+	for i, txOut := range spendingTx.TxOut {
+		if bytes.Equal(txOut.PkScript, c.fundingPkScript) {
+			fundingIdx = i
+			break
+		}
+	}
+	if fundingIdx == -1 {
+		return false, nil
+	}
+
+	// Marking the splice as confirmed is a no-op if the link already
+	// promoted it, and fails if the transaction isn't a splice of the
+	// channel.
+	txid := spendingTx.TxHash()
+	err := c.cfg.chanState.MarkSpliceConfirmed(txid)
+	switch {
+	case errors.Is(err, channeldb.ErrSpliceCandidateNotFound):
+		return false, nil
+
+	case err != nil:
+		return false, err
+	}
+
+	fundingOut := wire.OutPoint{
+		Hash:  txid,
+		Index: uint32(fundingIdx),
+	}
+
+	log.Infof("ChannelPoint(%v) spliced, watching new funding output %v",
+		c.cfg.chanState.FundingOutpoint, fundingOut)
+
+	c.heightHint = uint32(commitSpend.SpendingHeight)
+	spendNtfn, err := c.cfg.notifier.RegisterSpendNtfn(
+		&fundingOut, c.fundingPkScript, c.heightHint,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	c.wg.Add(1)
+	go c.closeObserver(spendNtfn)
+
+	return true, nil
+}
+
 // handleKnownLocalState checks whether the passed spend is a local state that
 // is known to us (the current state). If so we will act on this state using
 // the passed chainSet. If this is not a known local state, false is returned.
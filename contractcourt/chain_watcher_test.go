@@ -7,9 +7,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lntest/mock"
 	"github.com/lightningnetwork/lnd/lnwallet"
@@ -599,3 +601,115 @@ func TestChainWatcherLocalForceCloseDetect(t *testing.T) {
 		})
 	}
 }
+
+// TestChainWatcherSplice tests that the chain watcher doesn't treat a splice
+// transaction as a channel close, and goes on to watch the funding output
+// created by the splice, even though the splice hasn't been promoted yet.
+func TestChainWatcherSplice(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, err := lnwallet.CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit,
+	)
+	require.NoError(t, err, "unable to create test channels")
+
+	aliceNotifier := &mock.ChainNotifier{
+		SpendChan: make(chan *chainntnfs.SpendDetail),
+		EpochChan: make(chan *chainntnfs.BlockEpoch),
+		ConfChan:  make(chan *chainntnfs.TxConfirmation),
+	}
+	aliceChainWatcher, err := newChainWatcher(chainWatcherConfig{
+		chanState:           aliceChannel.State(),
+		notifier:            aliceNotifier,
+		signer:              aliceChannel.Signer,
+		extractStateNumHint: lnwallet.GetStateNumHint,
+	})
+	require.NoError(t, err, "unable to create chain watcher")
+	require.NoError(t, aliceChainWatcher.Start())
+	defer aliceChainWatcher.Stop()
+
+	chanEvents := aliceChainWatcher.SubscribeChannelEvents()
+
+	// Alice splices funds into the channel, and both parties exchange
+	// signatures for the commitments spending the new funding output.
+	const spliceIn = btcutil.Amount(500_000)
+	_, fundingOutput, err := lnwallet.SpliceFundingOutput(
+		aliceChannel.State(), aliceChannel.Capacity+spliceIn,
+	)
+	require.NoError(t, err)
+
+	fundingPoint := aliceChannel.State().FundingOutpoint
+	spliceTx := wire.NewMsgTx(2)
+	spliceTx.AddTxIn(wire.NewTxIn(&fundingPoint, nil, nil))
+	spliceTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	spliceTx.AddTxOut(fundingOutput)
+
+	aliceCandidate, aliceSigs, err := aliceChannel.SignSpliceCommitment(
+		&lnwallet.SpliceTx{
+			Tx:                spliceTx,
+			LocalContribution: spliceIn,
+		},
+	)
+	require.NoError(t, err)
+	bobCandidate, bobSigs, err := bobChannel.SignSpliceCommitment(
+		&lnwallet.SpliceTx{
+			Tx:                 spliceTx,
+			RemoteContribution: spliceIn,
+		},
+	)
+	require.NoError(t, err)
+
+	err = aliceChannel.ReceiveSpliceCommitSig(aliceCandidate, bobSigs)
+	require.NoError(t, err)
+	err = bobChannel.ReceiveSpliceCommitSig(bobCandidate, aliceSigs)
+	require.NoError(t, err)
+
+	// Once the splice transaction spends the funding output, the chain
+	// watcher should switch over to the new funding output without
+	// dispatching any close events.
+	spliceTxid := spliceTx.TxHash()
+	aliceNotifier.SpendChan <- &chainntnfs.SpendDetail{
+		SpentOutPoint:  &fundingPoint,
+		SpenderTxHash:  &spliceTxid,
+		SpendingTx:     spliceTx,
+		SpendingHeight: 100,
+	}
+
+	// If Bob now broadcasts his commitment spending the new funding
+	// output, the chain watcher should detect the remote close.
+	bobCommit := bobChannel.State().LocalCommitment.
+		SpliceCommitments[spliceTxid].CommitTx
+	bobTxHash := bobCommit.TxHash()
+	aliceNotifier.SpendChan <- &chainntnfs.SpendDetail{
+		SpenderTxHash: &bobTxHash,
+		SpendingTx:    bobCommit,
+	}
+
+	select {
+	case uniClose := <-chanEvents.RemoteUnilateralClosure:
+		require.NotNil(t, uniClose.CommitResolution)
+
+	case <-chanEvents.CooperativeClosure:
+		t.Fatalf("splice detected as cooperative close")
+
+	case <-time.After(time.Second * 15):
+		t.Fatalf("didn't receive unilateral close event")
+	}
+
+	require.Equal(
+		t, wire.OutPoint{Hash: spliceTxid},
+		aliceChannel.State().CurrentFundingOutpoint(),
+	)
+
+	// The splice was only marked as confirmed on disk, as its promotion
+	// is left to the link.
+	dbChannel, err := aliceChannel.State().Db.FetchChannel(
+		nil, fundingPoint,
+	)
+	require.NoError(t, err)
+	require.Equal(t, fundingPoint, dbChannel.CurrentFundingOutpoint())
+	require.Equal(
+		t, fn.Some(wire.OutPoint{Hash: spliceTxid}),
+		dbChannel.ConfirmedSplice(),
+	)
+}
@@ -72,6 +72,15 @@ var deps = depDesc{
 	lnwire.KeysendOptional: {
 		lnwire.TLVOnionPayloadOptional: {},
 	},
+	lnwire.SpliceOptional: {
+		lnwire.QuiescenceOptional: {},
+	},
+	lnwire.DynCommitmentsOptional: {
+		lnwire.QuiescenceOptional: {},
+	},
+	lnwire.SimpleCloseOptional: {
+		lnwire.ShutdownAnySegwitOptional: {},
+	},
 	lnwire.ZeroConfOptional: {
 		lnwire.ScidAliasOptional: {},
 	},
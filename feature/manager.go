@@ -63,6 +63,29 @@ type Config struct {
 	// NoRouteBlinding unsets route blinding feature bits.
 	NoRouteBlinding bool
 
+	// NoOnionMessages unsets any bits signaling support for forwarding
+	// and receiving onion messages.
+	NoOnionMessages bool
+
+	// NoTrampolineRouting unsets any bits signaling support for
+	// forwarding payments as a trampoline node.
+	NoTrampolineRouting bool
+
+	// NoSplice unsets any bits signaling support for splicing.
+	NoSplice bool
+
+	// NoDualFund unsets any bits signaling support for dual funded
+	// channels.
+	NoDualFund bool
+
+	// NoDynCommitments unsets any bits signaling support for dynamic
+	// commitment upgrades.
+	NoDynCommitments bool
+
+	// NoSimpleClose unsets any bits signaling support for the simple
+	// close protocol.
+	NoSimpleClose bool
+
 	// CustomFeatures is a set of custom features to advertise in each
 	// set.
 	CustomFeatures map[Set][]lnwire.FeatureBit
@@ -192,6 +215,30 @@ func newManager(cfg Config, desc setDesc) (*Manager, error) {
 			raw.Unset(lnwire.Bolt11BlindedPathsOptional)
 			raw.Unset(lnwire.Bolt11BlindedPathsRequired)
 		}
+		if cfg.NoOnionMessages {
+			raw.Unset(lnwire.OnionMessagesOptional)
+			raw.Unset(lnwire.OnionMessagesRequired)
+		}
+		if cfg.NoTrampolineRouting {
+			raw.Unset(lnwire.TrampolineRoutingOptional)
+			raw.Unset(lnwire.TrampolineRoutingRequired)
+		}
+		if cfg.NoSplice {
+			raw.Unset(lnwire.SpliceOptional)
+			raw.Unset(lnwire.SpliceRequired)
+		}
+		if cfg.NoDualFund {
+			raw.Unset(lnwire.DualFundOptional)
+			raw.Unset(lnwire.DualFundRequired)
+		}
+		if cfg.NoDynCommitments {
+			raw.Unset(lnwire.DynCommitmentsOptional)
+			raw.Unset(lnwire.DynCommitmentsRequired)
+		}
+		if cfg.NoSimpleClose {
+			raw.Unset(lnwire.SimpleCloseOptional)
+			raw.Unset(lnwire.SimpleCloseRequired)
+		}
 		for _, custom := range cfg.CustomFeatures[set] {
 			if custom > set.Maximum() {
 				return nil, fmt.Errorf("feature bit: %v "+
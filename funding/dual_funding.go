@@ -0,0 +1,1237 @@
+package funding
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/chanacceptor"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/lnpeer"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// This file implements the dual funded channel establishment protocol. The
+// initiator proposes the channel with open_channel2, and the responder
+// answers with accept_channel2, optionally contributing funds of its own.
+// Both parties then construct the funding transaction interactively,
+// exchange signatures for their commitment transactions and finally their
+// signatures for the funding transaction, after which it's broadcast.
+//
+// The temporary channel ID of open_channel2 is derived from the initiator's
+// revocation basepoint. From accept_channel2 on, the channel is referred to
+// by its permanent channel ID, which is derived from the revocation basepoints
+// of both parties rather than the funding outpoint. Internally, we keep using
+// the channel ID derived from the funding outpoint for all channels, so the
+// peer translates between the two for the messages it exchanges.
+
+var (
+	// errDualFundNotSupported is returned if a dual funded channel is
+	// proposed while either party doesn't signal support for it.
+	errDualFundNotSupported = errors.New("dual funded channels not " +
+		"supported by both parties")
+)
+
+// dualFundChanReserve returns the channel reserve of a dual funded channel.
+// Rather than being negotiated, it's derived by both parties from the
+// capacity of the channel.
+func dualFundChanReserve(capacity,
+	dustLimit btcutil.Amount) btcutil.Amount {
+
+	reserve := capacity / 100
+	if reserve < dustLimit {
+		return dustLimit
+	}
+
+	return reserve
+}
+
+// isFundingTxSigned returns true if all inputs of the passed funding
+// transaction have a witness.
+func isFundingTxSigned(tx *wire.MsgTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	for _, txIn := range tx.TxIn {
+		if len(txIn.Witness) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateDualFundRequest checks that a locally initiated channel open can be
+// carried out as a dual funded channel.
+func validateDualFundRequest(msg *InitFundingMsg,
+	commitType lnwallet.CommitmentType, zeroConf bool) error {
+
+	switch {
+	case !hasFeatures(
+		msg.Peer.LocalFeatures(), msg.Peer.RemoteFeatures(),
+		lnwire.DualFundOptional,
+	):
+		return errDualFundNotSupported
+
+	case msg.PushAmt != 0:
+		return errors.New("dual funded channels can't push funds")
+
+	case msg.SubtractFees || msg.FundUpToMaxAmt != 0:
+		return errors.New("dual funded channels must be opened with " +
+			"a fixed amount")
+
+	case msg.ChanFunder != nil || len(msg.Outpoints) > 0:
+		return errors.New("dual funded channels are funded by coin " +
+			"selection of the internal wallet")
+
+	case msg.PendingChanID != zeroID:
+		return errors.New("the pending channel ID of dual funded " +
+			"channels is derived from the revocation basepoint")
+
+	case commitType.IsTaproot():
+		return errors.New("taproot channels can't be dual funded")
+
+	case zeroConf:
+		return errors.New("zero-conf channels can't be dual funded")
+	}
+
+	return nil
+}
+
+// sendOpenChannel2 proposes the dual funded channel of the passed reservation
+// to the remote peer.
+func (f *Manager) sendOpenChannel2(resCtx *reservationWithCtx,
+	pendingChanID PendingChanID, commitFeePerKw chainfee.SatPerKWeight,
+	msg *InitFundingMsg) {
+
+	var channelFlags lnwire.FundingFlag
+	if !msg.Private {
+		channelFlags = lnwire.FFAnnounceChannel
+	}
+
+	ourContribution := resCtx.reservation.OurContribution()
+	revocationPoint := ourContribution.RevocationBasePoint.PubKey
+	openChan := &lnwire.OpenChannel2{
+		ChainHash:            *f.cfg.Wallet.Cfg.NetParams.GenesisHash,
+		PendingChannelID:     pendingChanID,
+		FundingFeePerKWeight: uint32(msg.FundingFeePerKw),
+		CommitFeePerKWeight:  uint32(commitFeePerKw),
+		FundingAmount:        resCtx.reservation.Capacity(),
+		DustLimit:            ourContribution.DustLimit,
+		MaxValueInFlight:     resCtx.remoteMaxValue,
+		HtlcMinimum:          resCtx.remoteMinHtlc,
+		CsvDelay:             resCtx.remoteCsvDelay,
+		MaxAcceptedHTLCs:     resCtx.remoteMaxHtlcs,
+		LockTime:             resCtx.lockTime,
+		FundingKey:           ourContribution.MultiSigKey.PubKey,
+		RevocationPoint:      revocationPoint,
+		PaymentPoint:         ourContribution.PaymentBasePoint.PubKey,
+		DelayedPaymentPoint:  ourContribution.DelayBasePoint.PubKey,
+		HtlcPoint:            ourContribution.HtlcBasePoint.PubKey,
+		FirstCommitmentPoint: ourContribution.FirstCommitmentPoint,
+		SecondCommitmentPoint: ourContribution.
+			SecondCommitmentPoint,
+		ChannelFlags:          channelFlags,
+		UpfrontShutdownScript: ourContribution.UpfrontShutdown,
+		ChannelType:           resCtx.channelType,
+	}
+
+	if err := resCtx.peer.SendMessage(true, openChan); err != nil {
+		e := fmt.Errorf("unable to send open_channel2 message: %w",
+			err)
+		log.Errorf(e.Error())
+
+		_, err := f.cancelReservationCtx(
+			resCtx.peer.IdentityKey(), pendingChanID, false,
+		)
+		if err != nil {
+			log.Errorf("unable to cancel reservation: %v", err)
+		}
+
+		msg.Err <- e
+	}
+}
+
+// fundeeProcessOpenChannel2 handles a dual funded channel proposed by the
+// remote peer. We only contribute funds if a channel acceptor asks us to, so
+// that peers can't lock up our wallet's coins by proposing channels, and
+// respond with accept_channel2. Afterwards, we wait for the initiator to start
+// the construction of the funding transaction.
+//
+//nolint:funlen
+func (f *Manager) fundeeProcessOpenChannel2(peer lnpeer.Peer,
+	msg *lnwire.OpenChannel2) {
+
+	peerPubKey := peer.IdentityKey()
+	peerIDKey := newSerializedKey(peerPubKey)
+	cid := newChanIdentifier(msg.PendingChannelID)
+	remoteAmt := msg.FundingAmount
+
+	if !hasFeatures(
+		peer.LocalFeatures(), peer.RemoteFeatures(),
+		lnwire.DualFundOptional,
+	) {
+
+		f.failFundingFlow(peer, cid, errDualFundNotSupported)
+		return
+	}
+
+	if f.IsPendingChannel(msg.PendingChannelID, peer) {
+		err := fmt.Errorf("pending channel %x already exists",
+			msg.PendingChannelID[:])
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	// We'll reject any requests to create channels until we're fully
+	// synced to the network as we won't be able to properly validate the
+	// confirmation of the funding transaction.
+	isSynced, _, err := f.cfg.Wallet.IsSynced()
+	if err != nil || !isSynced {
+		if err != nil {
+			log.Errorf("unable to query wallet: %v", err)
+		}
+		err := errors.New("Synchronizing blockchain")
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	if remoteAmt > f.cfg.MaxChanSize {
+		f.failFundingFlow(
+			peer, cid,
+			lnwallet.ErrChanTooLarge(remoteAmt, f.cfg.MaxChanSize),
+		)
+		return
+	}
+
+	chanType, commitType, err := negotiateCommitmentType(
+		msg.ChannelType, peer.LocalFeatures(), peer.RemoteFeatures(),
+	)
+	if err != nil {
+		log.Errorf("channel type negotiation failed: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	var scid bool
+	if chanType != nil {
+		featureVec := lnwire.RawFeatureVector(*chanType)
+		scid = featureVec.IsSet(lnwire.ScidAliasRequired)
+
+		if featureVec.IsSet(lnwire.ZeroConfRequired) {
+			err := errors.New("zero-conf channels can't be dual " +
+				"funded")
+			f.failFundingFlow(peer, cid, err)
+			return
+		}
+	}
+
+	public := msg.ChannelFlags&lnwire.FFAnnounceChannel != 0
+	switch {
+	case public && scid:
+		err := errors.New("option-scid-alias chantype for public " +
+			"channel")
+		f.failFundingFlow(peer, cid, err)
+		return
+
+	case commitType.IsTaproot():
+		err := errors.New("taproot channels can't be dual funded")
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	// Our channel acceptors only understand open_channel, so we present
+	// them the equivalent single funded proposal.
+	acceptorResp := f.cfg.OpenChannelPredicate.Accept(
+		&chanacceptor.ChannelAcceptRequest{
+			Node: peerPubKey,
+			OpenChanMsg: &lnwire.OpenChannel{
+				ChainHash:        msg.ChainHash,
+				PendingChannelID: msg.PendingChannelID,
+				FundingAmount:    remoteAmt,
+				DustLimit:        msg.DustLimit,
+				MaxValueInFlight: msg.MaxValueInFlight,
+				HtlcMinimum:      msg.HtlcMinimum,
+				FeePerKiloWeight: msg.CommitFeePerKWeight,
+				CsvDelay:         msg.CsvDelay,
+				MaxAcceptedHTLCs: msg.MaxAcceptedHTLCs,
+				FundingKey:       msg.FundingKey,
+				RevocationPoint:  msg.RevocationPoint,
+				PaymentPoint:     msg.PaymentPoint,
+				DelayedPaymentPoint: msg.
+					DelayedPaymentPoint,
+				HtlcPoint:            msg.HtlcPoint,
+				FirstCommitmentPoint: msg.FirstCommitmentPoint,
+				ChannelFlags:         msg.ChannelFlags,
+				UpfrontShutdownScript: msg.
+					UpfrontShutdownScript,
+				ChannelType: msg.ChannelType,
+			},
+			DualFund: true,
+		},
+	)
+	if acceptorResp.RejectChannel() {
+		f.failFundingFlow(peer, cid, acceptorResp.ChanAcceptError)
+		return
+	}
+
+	// We only contribute funds if a channel acceptor asked us to, up to
+	// our configured maximum and as long as the channel doesn't exceed our
+	// maximum channel size.
+	localAmt := acceptorResp.DualFundContribution
+	if localAmt > f.cfg.DualFundContribution {
+		localAmt = f.cfg.DualFundContribution
+	}
+	if remoteAmt+localAmt > f.cfg.MaxChanSize {
+		localAmt = f.cfg.MaxChanSize - remoteAmt
+	}
+
+	log.Infof("Recv'd dual funding request(remote_amt=%v, local_amt=%v, "+
+		"delay=%v, pendingId=%x) from peer(%x)", remoteAmt, localAmt,
+		msg.CsvDelay, msg.PendingChannelID,
+		peerPubKey.SerializeCompressed())
+
+	req := &lnwallet.InitFundingReserveMsg{
+		ChainHash:        &msg.ChainHash,
+		PendingChanID:    msg.PendingChannelID,
+		NodeID:           peerPubKey,
+		NodeAddr:         peer.Address(),
+		LocalFundingAmt:  localAmt,
+		RemoteFundingAmt: remoteAmt,
+		CommitFeePerKw: chainfee.SatPerKWeight(
+			msg.CommitFeePerKWeight,
+		),
+		FundingFeePerKw: chainfee.SatPerKWeight(
+			msg.FundingFeePerKWeight,
+		),
+		Flags:           msg.ChannelFlags,
+		MinConfs:        1,
+		CommitType:      commitType,
+		OptionScidAlias: scid,
+		ScidAliasFeature: hasFeatures(
+			peer.LocalFeatures(), peer.RemoteFeatures(),
+			lnwire.ScidAliasOptional,
+		),
+		DualFund: true,
+	}
+
+	reservation, err := f.cfg.Wallet.InitChannelReservation(req)
+
+	// If our wallet can't cover the contribution, we still accept the
+	// channel, just without funds of our own.
+	if err != nil && localAmt > 0 {
+		log.Warnf("Unable to contribute %v to dual funded channel, "+
+			"accepting without contribution: %v", localAmt, err)
+
+		req.LocalFundingAmt = 0
+		reservation, err = f.cfg.Wallet.InitChannelReservation(req)
+	}
+	if err != nil {
+		log.Errorf("Unable to initialize reservation: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	capacity := reservation.Capacity()
+	if capacity < f.cfg.MinChanSize {
+		err := lnwallet.ErrChanTooSmall(capacity, f.cfg.MinChanSize)
+		if cancelErr := reservation.Cancel(); cancelErr != nil {
+			log.Errorf("unable to cancel reservation: %v",
+				cancelErr)
+		}
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	numConfsReq := f.cfg.NumRequiredConfs(capacity, 0)
+	if acceptorResp.MinAcceptDepth != 0 {
+		numConfsReq = acceptorResp.MinAcceptDepth
+	}
+	reservation.SetNumConfsRequired(numConfsReq)
+
+	// Neither party negotiates the channel reserve of a dual funded
+	// channel, so it's derived from the capacity and the larger dust
+	// limit.
+	maxDustLimit := reservation.OurContribution().DustLimit
+	if msg.DustLimit > maxDustLimit {
+		maxDustLimit = msg.DustLimit
+	}
+	chanReserve := dualFundChanReserve(capacity, maxDustLimit)
+
+	remoteCsvDelay := f.cfg.RequiredRemoteDelay(capacity)
+	if acceptorResp.CSVDelay != 0 {
+		remoteCsvDelay = acceptorResp.CSVDelay
+	}
+
+	remoteMaxValue := f.cfg.RequiredRemoteMaxValue(capacity)
+	if acceptorResp.InFlightTotal != 0 {
+		remoteMaxValue = acceptorResp.InFlightTotal
+	}
+
+	maxHtlcs := f.cfg.RequiredRemoteMaxHTLCs(capacity)
+	if acceptorResp.HtlcLimit != 0 {
+		maxHtlcs = acceptorResp.HtlcLimit
+	}
+
+	minHtlc := f.cfg.DefaultMinHtlcIn
+	if acceptorResp.MinHtlcIn != 0 {
+		minHtlc = acceptorResp.MinHtlcIn
+	}
+
+	ourContribution := reservation.OurContribution()
+	forwardingPolicy := f.defaultForwardingPolicy(
+		ourContribution.ChannelStateBounds,
+	)
+
+	f.resMtx.Lock()
+	if _, ok := f.activeReservations[peerIDKey]; !ok {
+		f.activeReservations[peerIDKey] = make(pendingChannels)
+	}
+	resCtx := &reservationWithCtx{
+		reservation:       reservation,
+		chanAmt:           capacity,
+		forwardingPolicy:  *forwardingPolicy,
+		remoteCsvDelay:    remoteCsvDelay,
+		remoteMinHtlc:     minHtlc,
+		remoteMaxValue:    remoteMaxValue,
+		remoteMaxHtlcs:    maxHtlcs,
+		remoteChanReserve: chanReserve,
+		maxLocalCsv:       f.cfg.MaxLocalCSVDelay,
+		channelType:       chanType,
+		lockTime:          msg.LockTime,
+		err:               make(chan error, 1),
+		peer:              peer,
+	}
+	f.activeReservations[peerIDKey][msg.PendingChannelID] = resCtx
+	f.resMtx.Unlock()
+
+	defer resCtx.updateTimestamp()
+
+	err = reservation.CommitConstraints(
+		&channeldb.ChannelStateBounds{
+			ChanReserve:      chanReserve,
+			MaxPendingAmount: msg.MaxValueInFlight,
+			MinHTLC:          msg.HtlcMinimum,
+			MaxAcceptedHtlcs: msg.MaxAcceptedHTLCs,
+		},
+		&channeldb.CommitmentParams{
+			DustLimit: msg.DustLimit,
+			CsvDelay:  msg.CsvDelay,
+		},
+		f.cfg.MaxLocalCSVDelay, true,
+	)
+	if err != nil {
+		log.Errorf("Unacceptable channel constraints: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	shutdown, err := getUpfrontShutdownScript(
+		f.cfg.EnableUpfrontShutdown, peer, acceptorResp.UpfrontShutdown,
+		f.selectShutdownScript,
+	)
+	if err != nil {
+		f.failFundingFlow(
+			peer, cid,
+			fmt.Errorf("getUpfrontShutdownScript error: %w", err),
+		)
+		return
+	}
+	reservation.SetOurUpfrontShutdown(shutdown)
+
+	remoteContribution := &lnwallet.ChannelContribution{
+		FundingAmount:         remoteAmt,
+		FirstCommitmentPoint:  msg.FirstCommitmentPoint,
+		SecondCommitmentPoint: msg.SecondCommitmentPoint,
+		ChannelConfig: &channeldb.ChannelConfig{
+			ChannelStateBounds: channeldb.ChannelStateBounds{
+				MaxPendingAmount: remoteMaxValue,
+				ChanReserve:      chanReserve,
+				MinHTLC:          minHtlc,
+				MaxAcceptedHtlcs: maxHtlcs,
+			},
+			CommitmentParams: channeldb.CommitmentParams{
+				DustLimit: msg.DustLimit,
+				CsvDelay:  remoteCsvDelay,
+			},
+			MultiSigKey: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.FundingKey),
+			},
+			RevocationBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.RevocationPoint),
+			},
+			PaymentBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.PaymentPoint),
+			},
+			DelayBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.DelayedPaymentPoint),
+			},
+			HtlcBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.HtlcPoint),
+			},
+		},
+		UpfrontShutdown: msg.UpfrontShutdownScript,
+	}
+
+	err = f.startInteractiveTx(resCtx, cid, false, remoteContribution)
+	if err != nil {
+		log.Errorf("Unable to start funding transaction "+
+			"negotiation: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	ourContribution = reservation.OurContribution()
+	revocationPoint := ourContribution.RevocationBasePoint.PubKey
+	acceptChan := &lnwire.AcceptChannel2{
+		PendingChannelID:      msg.PendingChannelID,
+		FundingAmount:         reservation.Capacity() - remoteAmt,
+		DustLimit:             ourContribution.DustLimit,
+		MaxValueInFlight:      remoteMaxValue,
+		HtlcMinimum:           minHtlc,
+		MinAcceptDepth:        uint32(numConfsReq),
+		CsvDelay:              remoteCsvDelay,
+		MaxAcceptedHTLCs:      maxHtlcs,
+		FundingKey:            ourContribution.MultiSigKey.PubKey,
+		RevocationPoint:       revocationPoint,
+		PaymentPoint:          ourContribution.PaymentBasePoint.PubKey,
+		DelayedPaymentPoint:   ourContribution.DelayBasePoint.PubKey,
+		HtlcPoint:             ourContribution.HtlcBasePoint.PubKey,
+		FirstCommitmentPoint:  ourContribution.FirstCommitmentPoint,
+		SecondCommitmentPoint: ourContribution.SecondCommitmentPoint,
+		UpfrontShutdownScript: ourContribution.UpfrontShutdown,
+		ChannelType:           chanType,
+	}
+
+	log.Infof("Sending accept_channel2 for pending_id(%x), "+
+		"contribution=%v", msg.PendingChannelID,
+		acceptChan.FundingAmount)
+
+	if err := peer.SendMessage(true, acceptChan); err != nil {
+		log.Errorf("unable to send accept_channel2 to peer: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+}
+
+// funderProcessAcceptChannel2 handles the remote peer's response to our
+// open_channel2, after which we start constructing the funding transaction.
+func (f *Manager) funderProcessAcceptChannel2(peer lnpeer.Peer,
+	msg *lnwire.AcceptChannel2) {
+
+	pendingChanID := msg.PendingChannelID
+	peerKey := peer.IdentityKey()
+
+	resCtx, err := f.getReservationCtx(peerKey, pendingChanID)
+	if err != nil || !resCtx.reservation.IsInteractive() {
+		log.Warnf("Can't find dual funded reservation (peerKey:%x, "+
+			"chan_id:%v)", peerKey.SerializeCompressed(),
+			pendingChanID)
+		return
+	}
+
+	defer resCtx.updateTimestamp()
+
+	log.Infof("Recv'd accept_channel2 for pending_id(%x), "+
+		"contribution=%v", pendingChanID[:], msg.FundingAmount)
+
+	cid := newChanIdentifier(pendingChanID)
+
+	// The channel type must be echoed back if we proposed one explicitly.
+	if resCtx.channelType != nil {
+		if msg.ChannelType == nil {
+			err := errors.New("explicit channel type not echoed " +
+				"back")
+			f.failFundingFlow(peer, cid, err)
+			return
+		}
+
+		proposedFeatures := lnwire.RawFeatureVector(*resCtx.channelType)
+		ackedFeatures := lnwire.RawFeatureVector(*msg.ChannelType)
+		if !proposedFeatures.Equals(&ackedFeatures) {
+			err := errors.New("channel type mismatch")
+			f.failFundingFlow(peer, cid, err)
+			return
+		}
+	}
+
+	if msg.MinAcceptDepth > chainntnfs.MaxNumConfs {
+		err := lnwallet.ErrNumConfsTooLarge(
+			msg.MinAcceptDepth, chainntnfs.MaxNumConfs,
+		)
+		log.Warnf("Unacceptable channel constraints: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	minDepth := msg.MinAcceptDepth
+	if minDepth == 0 {
+		minDepth = 1
+	}
+	resCtx.reservation.SetNumConfsRequired(uint16(minDepth))
+
+	capacity := resCtx.reservation.Capacity() + msg.FundingAmount
+	if capacity > f.cfg.MaxChanSize {
+		f.failFundingFlow(
+			peer, cid,
+			lnwallet.ErrChanTooLarge(capacity, f.cfg.MaxChanSize),
+		)
+		return
+	}
+
+	err = resCtx.reservation.SetRemoteFundingAmt(msg.FundingAmount)
+	if err != nil {
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+	resCtx.chanAmt = capacity
+
+	// Now that the capacity of the channel is known, both parties derive
+	// the channel reserve from it.
+	maxDustLimit := resCtx.reservation.OurContribution().DustLimit
+	if msg.DustLimit > maxDustLimit {
+		maxDustLimit = msg.DustLimit
+	}
+	chanReserve := dualFundChanReserve(capacity, maxDustLimit)
+	resCtx.remoteChanReserve = chanReserve
+
+	err = resCtx.reservation.CommitConstraints(
+		&channeldb.ChannelStateBounds{
+			ChanReserve:      chanReserve,
+			MaxPendingAmount: msg.MaxValueInFlight,
+			MinHTLC:          msg.HtlcMinimum,
+			MaxAcceptedHtlcs: msg.MaxAcceptedHTLCs,
+		},
+		&channeldb.CommitmentParams{
+			DustLimit: msg.DustLimit,
+			CsvDelay:  msg.CsvDelay,
+		},
+		resCtx.maxLocalCsv, false,
+	)
+	if err != nil {
+		log.Warnf("Unacceptable channel constraints: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	remoteContribution := &lnwallet.ChannelContribution{
+		FundingAmount:         msg.FundingAmount,
+		FirstCommitmentPoint:  msg.FirstCommitmentPoint,
+		SecondCommitmentPoint: msg.SecondCommitmentPoint,
+		ChannelConfig: &channeldb.ChannelConfig{
+			ChannelStateBounds: channeldb.ChannelStateBounds{
+				MaxPendingAmount: resCtx.remoteMaxValue,
+				ChanReserve:      chanReserve,
+				MinHTLC:          resCtx.remoteMinHtlc,
+				MaxAcceptedHtlcs: resCtx.remoteMaxHtlcs,
+			},
+			CommitmentParams: channeldb.CommitmentParams{
+				DustLimit: msg.DustLimit,
+				CsvDelay:  resCtx.remoteCsvDelay,
+			},
+			MultiSigKey: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.FundingKey),
+			},
+			RevocationBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.RevocationPoint),
+			},
+			PaymentBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.PaymentPoint),
+			},
+			DelayBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.DelayedPaymentPoint),
+			},
+			HtlcBasePoint: keychain.KeyDescriptor{
+				PubKey: copyPubKey(msg.HtlcPoint),
+			},
+		},
+		UpfrontShutdown: msg.UpfrontShutdownScript,
+	}
+
+	err = f.startInteractiveTx(resCtx, cid, true, remoteContribution)
+	if err != nil {
+		log.Errorf("Unable to start funding transaction "+
+			"negotiation: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	// As the initiator, we send the first message of the negotiation.
+	if err := f.continueInteractiveTx(resCtx, cid); err != nil {
+		log.Errorf("Unable to negotiate funding transaction: %v", err)
+		f.failFundingFlow(peer, cid, err)
+	}
+}
+
+// startInteractiveTx processes the remote party's contribution to a dual
+// funded channel, which pauses the funding flow of the wallet until the
+// funding transaction has been negotiated. It then prepares the negotiation
+// of the funding transaction with our contributed inputs and outputs, which
+// refers to the channel by its permanent channel ID.
+func (f *Manager) startInteractiveTx(resCtx *reservationWithCtx,
+	cid *chanIdentifier, initiator bool,
+	remoteContribution *lnwallet.ChannelContribution) error {
+
+	ourContribution := resCtx.reservation.OurContribution()
+	chanID := lnwire.NewChanIDFromRevocationBasepoints(
+		ourContribution.RevocationBasePoint.PubKey,
+		remoteContribution.RevocationBasePoint.PubKey,
+	)
+
+	err := resCtx.reservation.ProcessContribution(remoteContribution)
+
+	var interactiveErr *lnwallet.InteractiveTxRequired
+	if !errors.As(err, &interactiveErr) {
+		if err == nil {
+			err = errors.New("funding transaction isn't " +
+				"negotiated interactively")
+		}
+
+		return err
+	}
+
+	intent := interactiveErr.Intent
+	outputs, err := intent.LocalOutputs()
+	if err != nil {
+		return err
+	}
+
+	f.resMtx.Lock()
+	defer f.resMtx.Unlock()
+
+	if _, ok := f.signedReservations[chanID]; ok {
+		return fmt.Errorf("channel %v already exists", chanID)
+	}
+	f.signedReservations[chanID] = cid.tempChanID
+
+	resCtx.dualFundChanID = chanID
+	resCtx.interactiveIntent = intent
+	resCtx.constructor = interactivetx.NewConstructor(interactivetx.Config{
+		ChanID:    chanID,
+		Initiator: initiator,
+		LockTime:  resCtx.lockTime,
+		Inputs:    intent.LocalInputs(),
+		Outputs:   outputs,
+	})
+
+	return nil
+}
+
+// processInteractiveTxMsg handles the remote party's next message of the
+// funding transaction negotiation of a dual funded channel.
+func (f *Manager) processInteractiveTxMsg(peer lnpeer.Peer,
+	msg lnwire.Message) {
+
+	//nolint:forcetypeassert
+	chanID := msg.(lnwire.LinkUpdater).TargetChanID()
+
+	f.resMtx.RLock()
+	pendingChanID, ok := f.signedReservations[chanID]
+	f.resMtx.RUnlock()
+	if !ok {
+		log.Warnf("Received %v for unknown dual funded channel %v",
+			msg.MsgType(), chanID)
+		return
+	}
+	cid := newChanIdentifier(pendingChanID)
+
+	resCtx, err := f.getReservationCtx(peer.IdentityKey(), pendingChanID)
+	if err != nil || resCtx.constructor == nil {
+		log.Warnf("Received %v for unknown dual funded channel %v",
+			msg.MsgType(), chanID)
+		return
+	}
+
+	defer resCtx.updateTimestamp()
+
+	if err := resCtx.constructor.Receive(msg); err != nil {
+		log.Errorf("Funding transaction negotiation for "+
+			"pending_id(%x) failed: %v", pendingChanID[:], err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	if err := f.continueInteractiveTx(resCtx, cid); err != nil {
+		log.Errorf("Unable to negotiate funding transaction: %v", err)
+		f.failFundingFlow(peer, cid, err)
+	}
+}
+
+// continueInteractiveTx sends our next message of the funding transaction
+// negotiation if it's our turn. Once the funding transaction is complete, we
+// sign the remote party's commitment transaction spending it.
+func (f *Manager) continueInteractiveTx(resCtx *reservationWithCtx,
+	cid *chanIdentifier) error {
+
+	constructor := resCtx.constructor
+	if constructor.OurTurn() {
+		msg, err := constructor.Send()
+		if err != nil {
+			return err
+		}
+
+		if err := resCtx.peer.SendMessage(true, msg); err != nil {
+			return err
+		}
+	}
+
+	if !constructor.IsComplete() {
+		return nil
+	}
+
+	fundingTx, err := constructor.Tx()
+	if err != nil {
+		return err
+	}
+
+	err = resCtx.reservation.ProcessInteractiveTx(fundingTx)
+	if err != nil {
+		return err
+	}
+
+	// Once the channel is known to the peer, it translates the messages
+	// of the channel to the channel ID derived from the funding outpoint,
+	// which is what we use internally, so we'll recognize the channel by
+	// both IDs from now on.
+	outPoint := resCtx.reservation.FundingOutpoint()
+	channelID := lnwire.NewChanIDFromOutPoint(*outPoint)
+
+	f.resMtx.Lock()
+	f.signedReservations[channelID] = cid.tempChanID
+	f.resMtx.Unlock()
+
+	log.Infof("Negotiated funding transaction with ChannelPoint(%v) for "+
+		"pending_id(%x)", outPoint, cid.tempChanID[:])
+
+	_, sig := resCtx.reservation.OurSignatures()
+	commitSig, err := lnwire.NewSigFromSignature(sig)
+	if err != nil {
+		return err
+	}
+
+	return resCtx.peer.SendMessage(true, &lnwire.CommitSig{
+		ChanID:    resCtx.dualFundChanID,
+		CommitSig: commitSig,
+	})
+}
+
+// signedDualFundReservation returns the dual funded reservation whose funding
+// transaction has been negotiated for the passed permanent channel ID, which
+// is either the one used on the wire or the one derived from the funding
+// outpoint.
+func (f *Manager) signedDualFundReservation(peer lnpeer.Peer,
+	chanID lnwire.ChannelID) (*reservationWithCtx, *chanIdentifier, error) {
+
+	f.resMtx.RLock()
+	pendingChanID, ok := f.signedReservations[chanID]
+	f.resMtx.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown channel %v", chanID)
+	}
+
+	cid := newChanIdentifier(pendingChanID)
+
+	resCtx, err := f.getReservationCtx(peer.IdentityKey(), pendingChanID)
+	if err != nil {
+		return nil, cid, err
+	}
+
+	if resCtx.constructor == nil || !resCtx.constructor.IsComplete() {
+		return nil, cid, fmt.Errorf("funding transaction of channel "+
+			"%v not negotiated", chanID)
+	}
+
+	outPoint := resCtx.reservation.FundingOutpoint()
+	cid.setChanID(lnwire.NewChanIDFromOutPoint(*outPoint))
+
+	return resCtx, cid, nil
+}
+
+// processDualFundCommitSig handles the remote party's signature for our
+// commitment transaction of a dual funded channel. Once it's verified, the
+// channel is persisted, and we sign our inputs of the funding transaction if
+// we're expected to do so first.
+func (f *Manager) processDualFundCommitSig(peer lnpeer.Peer,
+	msg *lnwire.CommitSig) {
+
+	resCtx, cid, err := f.signedDualFundReservation(peer, msg.ChanID)
+	if err != nil {
+		log.Warnf("Unable to process commitment signature: %v", err)
+		if cid != nil {
+			f.failFundingFlow(peer, cid, err)
+		}
+		return
+	}
+
+	defer resCtx.updateTimestamp()
+
+	if resCtx.completeChan != nil {
+		err := errors.New("duplicate commitment signature")
+		f.failDualFundingFlow(resCtx, cid, err)
+		return
+	}
+
+	commitSig, err := msg.CommitSig.ToSignature()
+	if err != nil {
+		log.Errorf("unable to parse signature: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	completeChan, err := resCtx.reservation.CompleteReservation(
+		nil, commitSig,
+	)
+	if err != nil {
+		log.Errorf("Unable to complete reservation: %v", err)
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+	resCtx.completeChan = completeChan
+
+	// Now that the channel is persisted, the peer can look up the channel
+	// ID it's referred to by on the wire.
+	err = peer.AddPendingChannel(cid.chanID, f.quit)
+	if err != nil {
+		log.Errorf("Unable to add pending channel %v with peer %x: %v",
+			cid.chanID, peer.IdentityKey().SerializeCompressed(),
+			err)
+	}
+
+	err = f.saveInitialForwardingPolicy(
+		cid.chanID, &resCtx.forwardingPolicy,
+	)
+	if err != nil {
+		log.Errorf("Unable to store the forwarding policy: %v", err)
+	}
+
+	if !resCtx.constructor.LocalSignsFirst() {
+		return
+	}
+
+	if err := f.sendDualFundTxSignatures(resCtx, cid); err != nil {
+		log.Errorf("Unable to sign funding transaction: %v", err)
+		f.failDualFundingFlow(resCtx, cid, err)
+	}
+}
+
+// sendDualFundTxSignatures signs our inputs of the funding transaction of a
+// dual funded channel and sends the signatures to the remote party.
+func (f *Manager) sendDualFundTxSignatures(resCtx *reservationWithCtx,
+	cid *chanIdentifier) error {
+
+	intent := resCtx.interactiveIntent
+	fundingTx := intent.FundingTx()
+
+	witnesses, err := intent.SignInputs(
+		fundingTx, resCtx.constructor.PrevOutFetcher(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// The witnesses are ordered like our inputs in the funding
+	// transaction, which is ordered by serial ID.
+	ourInputs := make(map[wire.OutPoint]struct{})
+	for _, in := range resCtx.constructor.LocalInputs() {
+		ourInputs[in.OutPoint] = struct{}{}
+	}
+
+	var i int
+	for _, txIn := range fundingTx.TxIn {
+		if _, ok := ourInputs[txIn.PreviousOutPoint]; !ok {
+			continue
+		}
+
+		txIn.Witness = witnesses[i]
+		i++
+	}
+
+	err = resCtx.peer.SendMessage(true, &lnwire.TxSignatures{
+		ChannelID: resCtx.dualFundChanID,
+		TxID:      fundingTx.TxHash(),
+		Witnesses: witnesses,
+	})
+	if err != nil {
+		return err
+	}
+
+	// From now on the remote party is able to broadcast the funding
+	// transaction, so we must not forget about the channel anymore.
+	resCtx.txSigsSent = true
+
+	return nil
+}
+
+// processDualFundTxSignatures handles the remote party's signatures for its
+// inputs of the funding transaction of a dual funded channel. Once both
+// parties signed, the funding transaction is broadcast, and we wait for the
+// channel to confirm.
+func (f *Manager) processDualFundTxSignatures(peer lnpeer.Peer,
+	msg *lnwire.TxSignatures) {
+
+	resCtx, cid, err := f.signedDualFundReservation(peer, msg.ChannelID)
+	if err != nil {
+		log.Warnf("Unable to process funding transaction "+
+			"signatures: %v", err)
+		return
+	}
+
+	defer resCtx.updateTimestamp()
+
+	if resCtx.completeChan == nil {
+		err := errors.New("funding transaction signatures received " +
+			"before commitment signature")
+		f.failFundingFlow(peer, cid, err)
+		return
+	}
+
+	fundingTx := resCtx.interactiveIntent.FundingTx()
+	if msg.TxID != fundingTx.TxHash() {
+		err := fmt.Errorf("tx_signatures for unknown transaction %v",
+			msg.TxID)
+		f.failDualFundingFlow(resCtx, cid, err)
+		return
+	}
+
+	// The remote party must have signed first if we didn't send our
+	// signatures yet, and we must respond with our own afterwards.
+	if !resCtx.txSigsSent && resCtx.constructor.LocalSignsFirst() {
+		err := errors.New("remote party signed funding transaction " +
+			"out of order")
+		f.failDualFundingFlow(resCtx, cid, err)
+		return
+	}
+
+	remoteInputs := resCtx.constructor.RemoteInputs()
+	if len(msg.Witnesses) != len(remoteInputs) {
+		err := fmt.Errorf("expected %d witnesses, got %d",
+			len(remoteInputs), len(msg.Witnesses))
+		f.failDualFundingFlow(resCtx, cid, err)
+		return
+	}
+
+	theirInputs := make(map[wire.OutPoint]struct{})
+	for _, in := range remoteInputs {
+		theirInputs[in.OutPoint] = struct{}{}
+	}
+
+	var i int
+	for _, txIn := range fundingTx.TxIn {
+		if _, ok := theirInputs[txIn.PreviousOutPoint]; !ok {
+			continue
+		}
+
+		txIn.Witness = msg.Witnesses[i]
+		i++
+	}
+
+	err = resCtx.constructor.VerifyInputs(fundingTx, remoteInputs)
+	if err != nil {
+		f.failDualFundingFlow(resCtx, cid, err)
+		return
+	}
+
+	if !resCtx.txSigsSent {
+		if err := f.sendDualFundTxSignatures(resCtx, cid); err != nil {
+			log.Errorf("Unable to sign funding transaction: %v",
+				err)
+			f.failDualFundingFlow(resCtx, cid, err)
+			return
+		}
+	}
+
+	f.finalizeDualFundedChannel(resCtx, cid)
+}
+
+// finalizeDualFundedChannel persists and broadcasts the fully signed funding
+// transaction of a dual funded channel, and hands the channel over to wait
+// for its confirmation.
+func (f *Manager) finalizeDualFundedChannel(resCtx *reservationWithCtx,
+	cid *chanIdentifier) {
+
+	completeChan := resCtx.completeChan
+	fundingTx := resCtx.interactiveIntent.FundingTx()
+	fundingPoint := completeChan.FundingOutpoint
+	peerKey := resCtx.peer.IdentityKey()
+
+	f.deleteReservationCtx(peerKey, cid.tempChanID)
+
+	if err := completeChan.SetFundingTxn(fundingTx); err != nil {
+		log.Errorf("Unable to store funding tx for "+
+			"ChannelPoint(%v): %v", fundingPoint, err)
+	}
+
+	f.localDiscoverySignals.Store(cid.chanID, make(chan struct{}))
+
+	log.Infof("Broadcasting funding tx %v for ChannelPoint(%v)",
+		fundingTx.TxHash(), fundingPoint)
+
+	label := labels.MakeLabel(labels.LabelTypeChannelOpen, nil)
+	if err := f.cfg.PublishTransaction(fundingTx, label); err != nil {
+		// The remote party may broadcast the transaction as well, and
+		// we retry on startup, so we'll watch the channel regardless.
+		log.Errorf("Unable to broadcast funding tx %v for "+
+			"ChannelPoint(%v): %v", fundingTx.TxHash(),
+			fundingPoint, err)
+	}
+
+	if err := f.cfg.WatchNewChannel(completeChan, peerKey); err != nil {
+		log.Errorf("Unable to send new ChannelPoint(%v) for "+
+			"arbitration: %v", fundingPoint, err)
+	}
+
+	// Only the initiator has a caller waiting for updates.
+	if resCtx.updates != nil {
+		upd := &lnrpc.OpenStatusUpdate{
+			Update: &lnrpc.OpenStatusUpdate_ChanPending{
+				ChanPending: &lnrpc.PendingUpdate{
+					Txid:        fundingPoint.Hash[:],
+					OutputIndex: fundingPoint.Index,
+				},
+			},
+			PendingChanId: cid.tempChanID[:],
+		}
+
+		select {
+		case resCtx.updates <- upd:
+		case <-f.quit:
+			return
+		}
+	}
+
+	f.cfg.NotifyPendingOpenChannelEvent(fundingPoint, completeChan)
+
+	f.wg.Add(1)
+	go f.advanceFundingState(completeChan, cid.tempChanID, resCtx.updates)
+}
+
+// failDualFundingFlow fails the funding flow of a dual funded channel. If the
+// channel has already been persisted, but we haven't signed the funding
+// transaction yet, the channel is forgotten. Once our signatures were sent,
+// the remote party is able to broadcast the funding transaction, so we keep
+// waiting for the channel to confirm.
+func (f *Manager) failDualFundingFlow(resCtx *reservationWithCtx,
+	cid *chanIdentifier, fundingErr error) {
+
+	completeChan := resCtx.completeChan
+	switch {
+	case completeChan == nil:
+		f.failFundingFlow(resCtx.peer, cid, fundingErr)
+
+	case resCtx.txSigsSent:
+		log.Errorf("Dual funded ChannelPoint(%v) failed after "+
+			"signing the funding transaction, waiting for it to "+
+			"confirm: %v", completeChan.FundingOutpoint, fundingErr)
+
+		f.deleteReservationCtx(
+			resCtx.peer.IdentityKey(), cid.tempChanID,
+		)
+
+		f.wg.Add(1)
+		go f.advanceFundingState(
+			completeChan, cid.tempChanID, resCtx.updates,
+		)
+
+	default:
+		// The reservation was already completed in the wallet, so we
+		// need to release our inputs and forget about the persisted
+		// channel ourselves.
+		resCtx.interactiveIntent.Cancel()
+
+		f.deleteReservationCtx(
+			resCtx.peer.IdentityKey(), cid.tempChanID,
+		)
+
+		localBalance := completeChan.LocalCommitment.LocalBalance
+		closeInfo := &channeldb.ChannelCloseSummary{
+			ChanPoint:      completeChan.FundingOutpoint,
+			ChainHash:      completeChan.ChainHash,
+			RemotePub:      completeChan.IdentityPub,
+			CloseType:      channeldb.FundingCanceled,
+			Capacity:       completeChan.Capacity,
+			SettledBalance: localBalance.ToSatoshis(),
+			RemoteCurrentRevocation: completeChan.
+				RemoteCurrentRevocation,
+			RemoteNextRevocation: completeChan.RemoteNextRevocation,
+			LocalChanConfig:      completeChan.LocalChanCfg,
+		}
+		err := completeChan.CloseChannel(
+			closeInfo, channeldb.ChanStatusLocalCloseInitiator,
+		)
+		if err != nil {
+			log.Errorf("Failed closing channel %v: %v",
+				completeChan.FundingOutpoint, err)
+		}
+
+		select {
+		case resCtx.err <- fundingErr:
+		default:
+		}
+
+		f.failFundingFlow(resCtx.peer, cid, fundingErr)
+	}
+}
+
+// IsInteractiveFunding returns true if the passed channel ID identifies a
+// dual funded channel whose funding transaction or commitment signatures are
+// currently negotiated with the passed peer. The channel ID is the permanent
+// channel ID used on the wire or, once the funding transaction is known, the
+// channel ID derived from the funding outpoint.
+func (f *Manager) IsInteractiveFunding(chanID lnwire.ChannelID,
+	peer lnpeer.Peer) bool {
+
+	_, ok := f.interactivePendingChanID(chanID, peer)
+
+	return ok
+}
+
+// interactivePendingChanID returns the pending channel ID of the dual funded
+// channel identified by the passed permanent channel ID, if its funding
+// transaction or commitment signatures are currently negotiated with the
+// passed peer.
+func (f *Manager) interactivePendingChanID(chanID lnwire.ChannelID,
+	peer lnpeer.Peer) (PendingChanID, bool) {
+
+	peerIDKey := newSerializedKey(peer.IdentityKey())
+
+	f.resMtx.RLock()
+	defer f.resMtx.RUnlock()
+
+	pendingChanID, ok := f.signedReservations[chanID]
+	if !ok {
+		return PendingChanID{}, false
+	}
+
+	resCtx, ok := f.activeReservations[peerIDKey][pendingChanID]
+	if !ok || !resCtx.reservation.IsInteractive() {
+		return PendingChanID{}, false
+	}
+
+	return pendingChanID, true
+}
+
+// forgetDualFundChanIDs removes the permanent channel IDs of the passed dual
+// funded reservation from the signedReservations map.
+//
+// NOTE: This method must be called with resMtx held.
+func (f *Manager) forgetDualFundChanIDs(resCtx *reservationWithCtx) {
+	if resCtx.constructor == nil {
+		return
+	}
+
+	// The funding outpoint is only set once the funding transaction has
+	// been negotiated, but deleting the channel ID of an empty outpoint
+	// is harmless.
+	outPoint := resCtx.reservation.FundingOutpoint()
+	delete(f.signedReservations, resCtx.dualFundChanID)
+	delete(f.signedReservations, lnwire.NewChanIDFromOutPoint(*outPoint))
+}
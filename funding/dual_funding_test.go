@@ -0,0 +1,432 @@
+package funding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	acpt "github.com/lightningnetwork/lnd/chanacceptor"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntest/mock"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// addDualFundCoin replaces the coins of the node's wallet with a single coin
+// of the given value, whose previous transaction can be fetched from the
+// wallet as required to contribute it to a dual funded channel. The coin is
+// locked to the key of the mock signer, so the node is able to sign for it.
+func addDualFundCoin(t *testing.T, node *testNode, value btcutil.Amount) {
+	t.Helper()
+
+	pubKeyHash := btcutil.Hash160(
+		alicePrivKey.PubKey().SerializeCompressed(),
+	)
+	pkScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).AddData(pubKeyHash).Script()
+	require.NoError(t, err)
+
+	walletController := node.fundingMgr.cfg.Wallet.WalletController
+	wc, ok := walletController.(*mock.WalletController)
+	require.True(t, ok)
+
+	prevTx := wire.NewMsgTx(2)
+	prevTx.AddTxIn(&wire.TxIn{})
+	prevTx.AddTxOut(wire.NewTxOut(int64(value), pkScript))
+
+	wc.Utxos = []*lnwallet.Utxo{{
+		AddressType:   lnwallet.WitnessPubKey,
+		Value:         value,
+		PkScript:      pkScript,
+		Confirmations: 6,
+		OutPoint:      wire.OutPoint{Hash: prevTx.TxHash()},
+	}}
+	wc.Txs = map[chainhash.Hash]*wire.MsgTx{prevTx.TxHash(): prevTx}
+}
+
+// mockDualFundAcceptor is a channel acceptor that accepts all channels and
+// asks us to contribute the given amount to dual funded ones.
+type mockDualFundAcceptor struct {
+	contribution btcutil.Amount
+}
+
+func (m *mockDualFundAcceptor) Accept(
+	req *acpt.ChannelAcceptRequest) *acpt.ChannelAcceptResponse {
+
+	if !req.DualFund {
+		return &acpt.ChannelAcceptResponse{}
+	}
+
+	return &acpt.ChannelAcceptResponse{
+		DualFundContribution: m.contribution,
+	}
+}
+
+// relayFundingMsgs relays the messages sent by either node to the other one
+// until the quit channel is closed. The messages of each direction are
+// delivered in order by a dedicated goroutine, so a node sending several
+// messages in a row can't deadlock the relay. The channel IDs of the relayed
+// messages are sent to the passed channel.
+func relayFundingMsgs(alice, bob *testNode, chanIDs chan lnwire.ChannelID,
+	quit chan struct{}) {
+
+	relay := func(from, to *testNode) {
+		queue := make(chan lnwire.Message, 100)
+		go func() {
+			for {
+				select {
+				case msg := <-queue:
+					fundingMgr := to.fundingMgr
+					fundingMgr.ProcessFundingMsg(msg, from)
+
+				case <-quit:
+					return
+				}
+			}
+		}()
+
+		go func() {
+			for {
+				select {
+				case msg := <-from.msgChan:
+					recordChanID(msg, chanIDs)
+					queue <- msg
+
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+
+	relay(alice, bob)
+	relay(bob, alice)
+}
+
+// recordChanID sends the channel ID of the passed message to the passed
+// channel, if it refers to a channel, without blocking.
+func recordChanID(msg lnwire.Message, chanIDs chan lnwire.ChannelID) {
+	var chanID lnwire.ChannelID
+	switch msg := msg.(type) {
+	case lnwire.LinkUpdater:
+		chanID = msg.TargetChanID()
+
+	case *lnwire.TxSignatures:
+		chanID = msg.ChannelID
+
+	default:
+		return
+	}
+
+	select {
+	case chanIDs <- chanID:
+	default:
+	}
+}
+
+// TestFundingManagerDualFunding tests that a dual funded channel is opened
+// with both parties contributing inputs to the interactively constructed
+// funding transaction, which both parties sign and broadcast.
+func TestFundingManagerDualFunding(t *testing.T) {
+	t.Parallel()
+
+	const (
+		aliceAmt = btcutil.Amount(500_000)
+		bobAmt   = btcutil.Amount(300_000)
+	)
+
+	alice, bob := setupFundingManagers(t, func(cfg *Config) {
+		cfg.DualFundContribution = bobAmt
+	})
+	t.Cleanup(func() {
+		tearDownFundingManagers(t, alice, bob)
+	})
+
+	// Bob only contributes to the channel because his channel acceptor
+	// asks him to.
+	bob.fundingMgr.cfg.OpenChannelPredicate = &mockDualFundAcceptor{
+		contribution: bobAmt,
+	}
+
+	featureBits := []lnwire.FeatureBit{
+		lnwire.DualFundOptional,
+		lnwire.StaticRemoteKeyOptional,
+	}
+	for _, node := range []*testNode{alice, bob} {
+		node.localFeatures = featureBits
+		node.remoteFeatures = featureBits
+	}
+
+	addDualFundCoin(t, alice, btcutil.SatoshiPerBitcoin)
+	addDualFundCoin(t, bob, btcutil.SatoshiPerBitcoin/2)
+
+	updateChan := make(chan *lnrpc.OpenStatusUpdate)
+	errChan := make(chan error, 1)
+	alice.fundingMgr.InitFundingWorkflow(&InitFundingMsg{
+		Peer:            bob,
+		TargetPubkey:    bob.privKey.PubKey(),
+		ChainHash:       *fundingNetParams.GenesisHash,
+		LocalFundingAmt: aliceAmt,
+		FundingFeePerKw: 1000,
+		MinConfs:        1,
+		Private:         true,
+		DualFund:        true,
+		Updates:         updateChan,
+		Err:             errChan,
+	})
+
+	// Alice proposes the channel with open_channel2, and Bob responds
+	// with his contribution.
+	openChan := assertDualFundMsgSent[*lnwire.OpenChannel2](
+		t, alice.msgChan, errChan,
+	)
+	require.Equal(t, aliceAmt, openChan.FundingAmount)
+	tempChanID := lnwire.NewTempChanIDFromRevocationBasepoint(
+		openChan.RevocationPoint,
+	)
+	require.EqualValues(t, tempChanID, openChan.PendingChannelID)
+	bob.fundingMgr.ProcessFundingMsg(openChan, alice)
+
+	acceptChan := assertDualFundMsgSent[*lnwire.AcceptChannel2](
+		t, bob.msgChan, errChan,
+	)
+	require.Equal(t, bobAmt, acceptChan.FundingAmount)
+	require.Equal(t, openChan.PendingChannelID, acceptChan.PendingChannelID)
+
+	// From here on, the funding transaction is negotiated and signed by
+	// both parties, referring to the channel by the channel ID derived
+	// from their revocation basepoints.
+	chanIDs := make(chan lnwire.ChannelID, 100)
+	quit := make(chan struct{})
+	defer close(quit)
+	relayFundingMsgs(alice, bob, chanIDs, quit)
+
+	alice.fundingMgr.ProcessFundingMsg(acceptChan, bob)
+
+	var pendingUpdate *lnrpc.OpenStatusUpdate
+	select {
+	case pendingUpdate = <-updateChan:
+	case err := <-errChan:
+		t.Fatalf("dual funding failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("alice did not send OpenStatusUpdate_ChanPending")
+	}
+	require.IsType(
+		t, &lnrpc.OpenStatusUpdate_ChanPending{}, pendingUpdate.Update,
+	)
+
+	// Both parties broadcast the same, fully signed funding transaction,
+	// which spends the coins of both parties.
+	var aliceTx, bobTx *wire.MsgTx
+	for _, node := range []*testNode{alice, bob} {
+		select {
+		case tx := <-node.publTxChan:
+			if node == alice {
+				aliceTx = tx
+			} else {
+				bobTx = tx
+			}
+
+		case <-time.After(5 * time.Second):
+			t.Fatalf("funding tx not published")
+		}
+	}
+	require.Equal(t, aliceTx.TxHash(), bobTx.TxHash())
+	require.Len(t, aliceTx.TxIn, 2)
+	require.True(t, isFundingTxSigned(aliceTx))
+
+	chanID := lnwire.NewChanIDFromRevocationBasepoints(
+		openChan.RevocationPoint, acceptChan.RevocationPoint,
+	)
+	require.NotEmpty(t, chanIDs)
+	for len(chanIDs) > 0 {
+		require.Equal(t, chanID, <-chanIDs)
+	}
+
+	// Both parties persisted the pending channel, with their
+	// contributions as their initial balances. Alice pays the commitment
+	// fee as the initiator.
+	for _, node := range []*testNode{alice, bob} {
+		select {
+		case <-node.mockChanEvent.pendingOpenEvent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("pending channel event not sent")
+		}
+
+		assertNumPendingReservations(t, node, alicePubKey, 0)
+		assertNumPendingReservations(t, node, bobPubKey, 0)
+
+		channels, err := node.fundingMgr.cfg.ChannelDB.
+			FetchPendingChannels()
+		require.NoError(t, err)
+		require.Len(t, channels, 1)
+
+		channel := channels[0]
+		require.True(t, channel.ChanType.IsDualFunder())
+		require.Equal(t, aliceAmt+bobAmt, channel.Capacity)
+		require.Equal(t, aliceTx.TxHash(), channel.FundingTxn.TxHash())
+		require.True(t, isFundingTxSigned(channel.FundingTxn))
+
+		localBalance := channel.LocalCommitment.LocalBalance
+		if node == bob {
+			require.False(t, channel.IsInitiator)
+			require.Equal(
+				t, lnwire.NewMSatFromSatoshis(bobAmt),
+				localBalance,
+			)
+		} else {
+			require.True(t, channel.IsInitiator)
+			require.Equal(
+				t, aliceAmt-channel.LocalCommitment.CommitFee,
+				localBalance.ToSatoshis(),
+			)
+		}
+	}
+}
+
+// TestFundingManagerDualFundingContribution tests that we only contribute to a
+// dual funded channel proposed by a peer if a channel acceptor asks us to, and
+// never more than our configured maximum.
+func TestFundingManagerDualFundingContribution(t *testing.T) {
+	t.Parallel()
+
+	const maxContribution = btcutil.Amount(300_000)
+
+	tests := []struct {
+		name         string
+		acceptor     acpt.ChannelAcceptor
+		contribution btcutil.Amount
+	}{{
+		name:         "no acceptor",
+		contribution: 0,
+	}, {
+		name: "acceptor below maximum",
+		acceptor: &mockDualFundAcceptor{
+			contribution: maxContribution / 2,
+		},
+		contribution: maxContribution / 2,
+	}, {
+		name: "acceptor above maximum",
+		acceptor: &mockDualFundAcceptor{
+			contribution: maxContribution * 2,
+		},
+		contribution: maxContribution,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testDualFundingContribution(
+				t, maxContribution, test.acceptor,
+				test.contribution,
+			)
+		})
+	}
+}
+
+func testDualFundingContribution(t *testing.T, maxContribution btcutil.Amount,
+	acceptor acpt.ChannelAcceptor, contribution btcutil.Amount) {
+
+	alice, bob := setupFundingManagers(t, func(cfg *Config) {
+		cfg.DualFundContribution = maxContribution
+	})
+	t.Cleanup(func() {
+		tearDownFundingManagers(t, alice, bob)
+	})
+
+	if acceptor != nil {
+		bob.fundingMgr.cfg.OpenChannelPredicate = acceptor
+	}
+
+	featureBits := []lnwire.FeatureBit{
+		lnwire.DualFundOptional,
+		lnwire.StaticRemoteKeyOptional,
+	}
+	for _, node := range []*testNode{alice, bob} {
+		node.localFeatures = featureBits
+		node.remoteFeatures = featureBits
+	}
+
+	addDualFundCoin(t, alice, btcutil.SatoshiPerBitcoin)
+	addDualFundCoin(t, bob, btcutil.SatoshiPerBitcoin)
+
+	errChan := make(chan error, 1)
+	alice.fundingMgr.InitFundingWorkflow(&InitFundingMsg{
+		Peer:            bob,
+		TargetPubkey:    bob.privKey.PubKey(),
+		ChainHash:       *fundingNetParams.GenesisHash,
+		LocalFundingAmt: 500_000,
+		FundingFeePerKw: 1000,
+		MinConfs:        1,
+		Private:         true,
+		DualFund:        true,
+		Updates:         make(chan *lnrpc.OpenStatusUpdate),
+		Err:             errChan,
+	})
+
+	openChan := assertDualFundMsgSent[*lnwire.OpenChannel2](
+		t, alice.msgChan, errChan,
+	)
+	bob.fundingMgr.ProcessFundingMsg(openChan, alice)
+
+	acceptChan := assertDualFundMsgSent[*lnwire.AcceptChannel2](
+		t, bob.msgChan, errChan,
+	)
+	require.Equal(t, contribution, acceptChan.FundingAmount)
+}
+
+// TestFundingManagerDualFundingNotSupported tests that a dual funded channel
+// can't be opened if the remote peer doesn't signal support for it.
+func TestFundingManagerDualFundingNotSupported(t *testing.T) {
+	t.Parallel()
+
+	alice, bob := setupFundingManagers(t)
+	t.Cleanup(func() {
+		tearDownFundingManagers(t, alice, bob)
+	})
+
+	errChan := make(chan error, 1)
+	alice.fundingMgr.InitFundingWorkflow(&InitFundingMsg{
+		Peer:            bob,
+		TargetPubkey:    bob.privKey.PubKey(),
+		ChainHash:       *fundingNetParams.GenesisHash,
+		LocalFundingAmt: 500_000,
+		FundingFeePerKw: 1000,
+		DualFund:        true,
+		Updates:         make(chan *lnrpc.OpenStatusUpdate),
+		Err:             errChan,
+	})
+
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, errDualFundNotSupported)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("dual funding request not rejected")
+	}
+}
+
+// assertDualFundMsgSent asserts that a message of the given type is sent to
+// the passed message channel.
+func assertDualFundMsgSent[T lnwire.Message](t *testing.T,
+	msgChan chan lnwire.Message, errChan chan error) T {
+
+	t.Helper()
+
+	var msg lnwire.Message
+	select {
+	case msg = <-msgChan:
+	case err := <-errChan:
+		t.Fatalf("dual funding failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message not sent")
+	}
+
+	typedMsg, ok := msg.(T)
+	require.Truef(t, ok, "unexpected message %T", msg)
+
+	return typedMsg
+}
@@ -18,6 +18,11 @@ type Controller interface {
 	// IsPendingChannel returns whether a particular 32-byte identifier
 	// represents a pending channel in the Controller implementation.
 	IsPendingChannel([32]byte, lnpeer.Peer) bool
+
+	// IsInteractiveFunding returns whether a particular channel ID
+	// represents a dual funded channel whose funding transaction is
+	// currently negotiated in the Controller implementation.
+	IsInteractiveFunding(lnwire.ChannelID, lnpeer.Peer) bool
 }
 
 // aliasHandler is an interface that abstracts the managing of aliases.
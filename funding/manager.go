@@ -34,6 +34,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwallet/chanfunding"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"golang.org/x/crypto/salsa20"
 )
@@ -166,6 +167,30 @@ type reservationWithCtx struct {
 	// the channel.
 	channelType *lnwire.ChannelType
 
+	// lockTime is the lock time of the funding transaction of a dual
+	// funded channel.
+	lockTime uint32
+
+	// interactiveIntent and constructor are used to negotiate the funding
+	// transaction of a dual funded channel. They're nil for single funded
+	// channels.
+	interactiveIntent *chanfunding.InteractiveIntent
+	constructor       *interactivetx.Constructor
+
+	// completeChan is the dual funded channel that was persisted once the
+	// commitment signatures were exchanged. It's broadcast after the
+	// funding transaction has been signed by both parties.
+	completeChan *channeldb.OpenChannel
+
+	// txSigsSent is true once we sent our signatures for the funding
+	// transaction of a dual funded channel.
+	txSigsSent bool
+
+	// dualFundChanID is the permanent channel ID of a dual funded channel,
+	// which is derived from the revocation basepoints of both parties and
+	// used on the wire once accept_channel2 was exchanged.
+	dualFundChanID lnwire.ChannelID
+
 	updateMtx   sync.RWMutex
 	lastUpdated time.Time
 
@@ -295,6 +320,11 @@ type InitFundingMsg struct {
 	// support explicit channel type negotiation.
 	ChannelType *lnwire.ChannelType
 
+	// DualFund signals that the channel should be opened with
+	// open_channel2, allowing the remote peer to contribute funds to the
+	// channel.
+	DualFund bool
+
 	// Memo is any arbitrary information we wish to store locally about the
 	// channel that will be useful to our future selves.
 	Memo []byte
@@ -544,6 +574,12 @@ type Config struct {
 	// backed funding flow to not use utxos still being swept by the sweeper
 	// subsystem.
 	IsSweeperOutpoint func(wire.OutPoint) bool
+
+	// DualFundContribution is the maximum amount we contribute to dual
+	// funded channels opened by remote peers. The amount contributed is
+	// set by the channel acceptor. If the wallet can't cover it, we accept
+	// the channel without contributing.
+	DualFundContribution btcutil.Amount
 }
 
 // Manager acts as an orchestrator/bridge between the wallet's
@@ -593,7 +629,11 @@ type Manager struct {
 	// ID of a funding reservation to its temporary channel ID. This is
 	// required as mid funding flow, we switch to referencing the channel
 	// by its full channel ID once the commitment transactions have been
-	// signed by both parties.
+	// signed by both parties. Dual funded channels are added once
+	// accept_channel2 was exchanged, both by the channel ID derived from
+	// the revocation basepoints, which is used on the wire, and later by
+	// the channel ID derived from the funding outpoint, which is used
+	// internally.
 	signedReservations map[lnwire.ChannelID]PendingChanID
 
 	// resMtx guards both of the maps above to ensure that all access is
@@ -724,11 +764,20 @@ func (f *Manager) start() error {
 
 			// Rebroadcast the funding transaction for any pending
 			// channel that we initiated. No error will be returned
-			// if the transaction already has been broadcast.
+			// if the transaction already has been broadcast. Both
+			// parties of a dual funded channel know the funding
+			// transaction, once it's been signed.
 			chanType := channel.ChanType
-			if chanType.IsSingleFunder() &&
+			switch {
+			case chanType.IsSingleFunder() &&
 				chanType.HasFundingTx() &&
-				channel.IsInitiator {
+				channel.IsInitiator:
+
+				f.rebroadcastFundingTx(channel)
+
+			case chanType.IsDualFunder() &&
+				chanType.HasFundingTx() &&
+				isFundingTxSigned(channel.FundingTxn):
 
 				f.rebroadcastFundingTx(channel)
 			}
@@ -1021,6 +1070,24 @@ func (f *Manager) reservationCoordinator() {
 			case *lnwire.FundingSigned:
 				f.funderProcessFundingSigned(fmsg.peer, msg)
 
+			case *lnwire.OpenChannel2:
+				f.fundeeProcessOpenChannel2(fmsg.peer, msg)
+
+			case *lnwire.AcceptChannel2:
+				f.funderProcessAcceptChannel2(fmsg.peer, msg)
+
+			case *lnwire.TxAddInput, *lnwire.TxAddOutput,
+				*lnwire.TxRemoveInput, *lnwire.TxRemoveOutput,
+				*lnwire.TxComplete, *lnwire.TxAbort:
+
+				f.processInteractiveTxMsg(fmsg.peer, msg)
+
+			case *lnwire.CommitSig:
+				f.processDualFundCommitSig(fmsg.peer, msg)
+
+			case *lnwire.TxSignatures:
+				f.processDualFundTxSignatures(fmsg.peer, msg)
+
 			case *lnwire.ChannelReady:
 				f.wg.Add(1)
 				go f.handleChannelReady(fmsg.peer, msg)
@@ -4623,6 +4690,26 @@ func (f *Manager) handleInitFundingMsg(msg *InitFundingMsg) {
 		scidFeatureVal = true
 	}
 
+	// A dual funded channel needs the lock time of its funding transaction
+	// upfront, for which we use the current height to discourage fee
+	// sniping.
+	var lockTime uint32
+	if msg.DualFund {
+		err := validateDualFundRequest(msg, commitType, zeroConf)
+		if err != nil {
+			log.Errorf("invalid dual funding request: %v", err)
+			msg.Err <- err
+			return
+		}
+
+		_, bestHeight, err := f.cfg.Wallet.Cfg.ChainIO.GetBestBlock()
+		if err != nil {
+			msg.Err <- err
+			return
+		}
+		lockTime = uint32(bestHeight)
+	}
+
 	req := &lnwallet.InitFundingReserveMsg{
 		ChainHash:         &msg.ChainHash,
 		PendingChanID:     chanID,
@@ -4662,6 +4749,8 @@ func (f *Manager) handleInitFundingMsg(msg *InitFundingMsg) {
 		OptionScidAlias:  scid,
 		ScidAliasFeature: scidFeatureVal,
 		Memo:             msg.Memo,
+		DualFund:         msg.DualFund,
+		Initiator:        msg.DualFund,
 	}
 
 	reservation, err := f.cfg.Wallet.InitChannelReservation(req)
@@ -4670,6 +4759,15 @@ func (f *Manager) handleInitFundingMsg(msg *InitFundingMsg) {
 		return
 	}
 
+	// The temporary channel ID of a dual funded channel isn't random, but
+	// derived from our revocation basepoint.
+	if msg.DualFund {
+		ourContribution := reservation.OurContribution()
+		chanID = lnwire.NewTempChanIDFromRevocationBasepoint(
+			ourContribution.RevocationBasePoint.PubKey,
+		)
+	}
+
 	if zeroConf {
 		// Store the alias for zero-conf channels in the underlying
 		// partial channel state.
@@ -4766,6 +4864,7 @@ func (f *Manager) handleInitFundingMsg(msg *InitFundingMsg) {
 		remoteChanReserve: chanReserve,
 		maxLocalCsv:       maxCSV,
 		channelType:       chanType,
+		lockTime:          lockTime,
 		reservation:       reservation,
 		peer:              msg.Peer,
 		updates:           msg.Updates,
@@ -4811,7 +4910,16 @@ func (f *Manager) handleInitFundingMsg(msg *InitFundingMsg) {
 	}
 
 	log.Infof("Starting funding workflow with %v for pending_id(%x), "+
-		"committype=%v", msg.Peer.Address(), chanID, commitType)
+		"committype=%v, dual_fund=%v", msg.Peer.Address(), chanID,
+		commitType, msg.DualFund)
+
+	// A dual funded channel is proposed with open_channel2, which carries
+	// the funding fee rate and lock time instead of a push amount and a
+	// channel reserve.
+	if msg.DualFund {
+		f.sendOpenChannel2(resCtx, chanID, commitFeePerKw, msg)
+		return
+	}
 
 	fundingOpen := lnwire.OpenChannel{
 		ChainHash:             *f.cfg.Wallet.Cfg.NetParams.GenesisHash,
@@ -4873,6 +4981,12 @@ func (f *Manager) handleErrorMsg(peer lnpeer.Peer, msg *lnwire.Error) {
 	chanID := msg.ChanID
 	peerKey := peer.IdentityKey()
 
+	// A dual funded channel is referred to by its permanent channel ID
+	// once accept_channel2 was exchanged.
+	if pendingChanID, ok := f.interactivePendingChanID(chanID, peer); ok {
+		chanID = pendingChanID
+	}
+
 	// First, we'll attempt to retrieve and cancel the funding workflow
 	// that this error was tied to. If we're unable to do so, then we'll
 	// exit early as this was an unwarranted error.
@@ -4986,6 +5100,7 @@ func (f *Manager) cancelReservationCtx(peerKey *btcec.PublicKey,
 			err)
 	}
 
+	f.forgetDualFundChanIDs(ctx)
 	delete(nodeReservations, pendingChanID)
 
 	// If this was the last active reservation for this peer, delete the
@@ -5010,6 +5125,9 @@ func (f *Manager) deleteReservationCtx(peerKey *btcec.PublicKey,
 		// No reservations for this node.
 		return
 	}
+	if ctx, ok := nodeReservations[pendingChanID]; ok {
+		f.forgetDualFundChanIDs(ctx)
+	}
 	delete(nodeReservations, pendingChanID)
 
 	// If this was the last active reservation for this peer, delete the
@@ -5050,7 +5168,9 @@ func (f *Manager) IsPendingChannel(pendingChanID PendingChanID,
 	_, ok := f.activeReservations[peerIDKey][pendingChanID]
 	f.resMtx.RUnlock()
 
-	return ok
+	// A dual funded channel is referred to by its permanent channel ID
+	// once accept_channel2 was exchanged.
+	return ok || f.IsInteractiveFunding(pendingChanID, peer)
 }
 
 func copyPubKey(pub *btcec.PublicKey) *btcec.PublicKey {
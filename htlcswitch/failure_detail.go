@@ -54,6 +54,11 @@ const (
 	// OutgoingFailureForwardsDisabled is returned when the switch is
 	// configured to disallow forwards.
 	OutgoingFailureForwardsDisabled
+
+	// OutgoingFailureResourceBucketFull is returned when the resource
+	// bucket that a forwarded htlc was assigned to doesn't have enough
+	// slots or liquidity left on the outgoing channel.
+	OutgoingFailureResourceBucketFull
 )
 
 // FailureString returns the string representation of a failure detail.
@@ -91,6 +96,40 @@ func (fd OutgoingFailure) FailureString() string {
 	case OutgoingFailureForwardsDisabled:
 		return "node configured to disallow forwards"
 
+	case OutgoingFailureResourceBucketFull:
+		return "resource bucket of outgoing channel is full"
+
+	default:
+		return "unknown failure detail"
+	}
+}
+
+// IncomingFailure is an enum which is used to enrich failures which occur on
+// our incoming link with additional metadata.
+type IncomingFailure int
+
+const (
+	// IncomingFailureNone is returned when the wire message contains
+	// sufficient information.
+	IncomingFailureNone IncomingFailure = iota
+
+	// IncomingFailureRateLimited is returned when an htlc is failed
+	// because its peer or channel exceeded its add rate or the value that
+	// its peer may have in flight.
+	IncomingFailureRateLimited
+)
+
+// FailureString returns the string representation of a failure detail.
+//
+// Note: it is part of the FailureDetail interface.
+func (fd IncomingFailure) FailureString() string {
+	switch fd {
+	case IncomingFailureNone:
+		return "no failure detail"
+
+	case IncomingFailureRateLimited:
+		return "htlc rate limit of incoming peer exceeded"
+
 	default:
 		return "unknown failure detail"
 	}
@@ -3,49 +3,81 @@ package htlcswitch
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lightningnetwork/lnd/channeldb/models"
 )
 
+// heldHtlc is an intercepted forward together with its position in the
+// interceptor chain.
+type heldHtlc struct {
+	fwd InterceptedForward
+
+	// holder is the interceptor that the forward is currently offered to.
+	// It is zero if the forward waits for an interceptor to register.
+	holder InterceptorID
+
+	// pos is the chain position of the last interceptor that the forward
+	// was offered to.
+	pos chainPosition
+
+	// offers counts how often the forward was offered to an interceptor.
+	// It is used to discard timeouts of earlier offers.
+	offers uint64
+
+	// timeout fires if the holder doesn't resolve the forward in time.
+	timeout *time.Timer
+}
+
+// stopTimeout stops the timeout of the current offer, if any.
+func (h *heldHtlc) stopTimeout() {
+	if h.timeout != nil {
+		h.timeout.Stop()
+		h.timeout = nil
+	}
+}
+
 // heldHtlcSet keeps track of outstanding intercepted forwards. It exposes
 // several methods to manipulate the underlying map structure in a consistent
 // way.
 type heldHtlcSet struct {
-	set map[models.CircuitKey]InterceptedForward
+	set map[models.CircuitKey]*heldHtlc
 }
 
 func newHeldHtlcSet() *heldHtlcSet {
 	return &heldHtlcSet{
-		set: make(map[models.CircuitKey]InterceptedForward),
+		set: make(map[models.CircuitKey]*heldHtlc),
 	}
 }
 
 // forEach iterates over all held forwards and calls the given callback for each
 // of them.
 func (h *heldHtlcSet) forEach(cb func(InterceptedForward)) {
-	for _, fwd := range h.set {
-		cb(fwd)
+	for _, held := range h.set {
+		cb(held.fwd)
 	}
 }
 
 // popAll calls the callback for each forward and removes them from the set.
 func (h *heldHtlcSet) popAll(cb func(InterceptedForward)) {
-	for _, fwd := range h.set {
-		cb(fwd)
+	for _, held := range h.set {
+		held.stopTimeout()
+		cb(held.fwd)
 	}
 
-	h.set = make(map[models.CircuitKey]InterceptedForward)
+	h.set = make(map[models.CircuitKey]*heldHtlc)
 }
 
 // popAutoFails calls the callback for each forward that has an auto-fail height
 // equal or less then the specified pop height and removes them from the set.
 func (h *heldHtlcSet) popAutoFails(height uint32, cb func(InterceptedForward)) {
-	for key, fwd := range h.set {
-		if uint32(fwd.Packet().AutoFailHeight) > height {
+	for key, held := range h.set {
+		if uint32(held.fwd.Packet().AutoFailHeight) > height {
 			continue
 		}
 
-		cb(fwd)
+		held.stopTimeout()
+		cb(held.fwd)
 
 		delete(h.set, key)
 	}
@@ -53,14 +85,42 @@ func (h *heldHtlcSet) popAutoFails(height uint32, cb func(InterceptedForward)) {
 
 // pop returns the specified forward and removes it from the set.
 func (h *heldHtlcSet) pop(key models.CircuitKey) (InterceptedForward, error) {
-	intercepted, ok := h.set[key]
+	held, ok := h.set[key]
 	if !ok {
 		return nil, fmt.Errorf("fwd %v not found", key)
 	}
 
+	held.stopTimeout()
 	delete(h.set, key)
 
-	return intercepted, nil
+	return held.fwd, nil
+}
+
+// get returns the specified forward and its chain state without removing it
+// from the set.
+func (h *heldHtlcSet) get(key models.CircuitKey) (*heldHtlc, error) {
+	held, ok := h.set[key]
+	if !ok {
+		return nil, fmt.Errorf("fwd %v not found", key)
+	}
+
+	return held, nil
+}
+
+// heldBy returns the forwards that are currently offered to the given
+// interceptor. A zero id returns the forwards that wait for an interceptor to
+// register.
+func (h *heldHtlcSet) heldBy(
+	holder InterceptorID) map[models.CircuitKey]*heldHtlc {
+
+	heldBy := make(map[models.CircuitKey]*heldHtlc)
+	for key, held := range h.set {
+		if held.holder == holder {
+			heldBy[key] = held
+		}
+	}
+
+	return heldBy
 }
 
 // exists tests whether the specified forward is part of the set.
@@ -83,7 +143,7 @@ func (h *heldHtlcSet) push(key models.CircuitKey,
 		return errors.New("htlc already exists in set")
 	}
 
-	h.set[key] = fwd
+	h.set[key] = &heldHtlc{fwd: fwd}
 
 	return nil
 }
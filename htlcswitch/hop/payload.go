@@ -117,6 +117,11 @@ type Payload struct {
 	// totalAmtMsat holds the info provided in total_amount_msat when
 	// parsed from a TLV onion payload.
 	totalAmtMsat lnwire.MilliSatoshi
+
+	// trampolineOnion is the serialized inner trampoline onion packet
+	// that is included in the final hop payload when we're being used as
+	// a trampoline node.
+	trampolineOnion []byte
 }
 
 // NewLegacyPayload builds a Payload from the amount, cltv, and next hop
@@ -148,6 +153,7 @@ func ParseTLVPayload(r io.Reader) (*Payload, map[tlv.Type][]byte, error) {
 		encryptedData []byte
 		blindingPoint *btcec.PublicKey
 		metadata      []byte
+		trampoline    []byte
 	)
 
 	tlvStream, err := tlv.NewStream(
@@ -160,6 +166,7 @@ func ParseTLVPayload(r io.Reader) (*Payload, map[tlv.Type][]byte, error) {
 		amp.Record(),
 		record.NewMetadataRecord(&metadata),
 		record.NewTotalAmtMsatBlinded(&totalAmtMsat),
+		record.NewTrampolineOnionRecord(&trampoline),
 	)
 	if err != nil {
 		return nil, nil, err
@@ -196,6 +203,12 @@ func ParseTLVPayload(r io.Reader) (*Payload, map[tlv.Type][]byte, error) {
 		metadata = nil
 	}
 
+	// If no trampoline onion was parsed, set the field on our resulting
+	// payload to nil.
+	if _, ok := parsedTypes[record.TrampolineOnionType]; !ok {
+		trampoline = nil
+	}
+
 	// Filter out the custom records.
 	customRecords := NewCustomRecords(parsedTypes)
 
@@ -205,13 +218,14 @@ func ParseTLVPayload(r io.Reader) (*Payload, map[tlv.Type][]byte, error) {
 			AmountToForward: lnwire.MilliSatoshi(amt),
 			OutgoingCTLV:    cltv,
 		},
-		MPP:           mpp,
-		AMP:           amp,
-		metadata:      metadata,
-		encryptedData: encryptedData,
-		blindingPoint: blindingPoint,
-		customRecords: customRecords,
-		totalAmtMsat:  lnwire.MilliSatoshi(totalAmtMsat),
+		MPP:             mpp,
+		AMP:             amp,
+		metadata:        metadata,
+		encryptedData:   encryptedData,
+		blindingPoint:   blindingPoint,
+		customRecords:   customRecords,
+		totalAmtMsat:    lnwire.MilliSatoshi(totalAmtMsat),
+		trampolineOnion: trampoline,
 	}, parsedTypes, nil
 }
 
@@ -275,6 +289,7 @@ func ValidateParsedPayloadTypes(parsedTypes tlv.TypeMap,
 	_, hasAMP := parsedTypes[record.AMPOnionType]
 	_, hasEncryptedData := parsedTypes[record.EncryptedDataOnionType]
 	_, hasBlinding := parsedTypes[record.BlindingPointOnionType]
+	_, hasTrampoline := parsedTypes[record.TrampolineOnionType]
 
 	// All cleartext hops (including final hop) and the final hop in a
 	// blinded path require the forwading amount and expiry TLVs to be set.
@@ -375,6 +390,24 @@ func ValidateParsedPayloadTypes(parsedTypes tlv.TypeMap,
 			Violation: IncludedViolation,
 			FinalHop:  isFinalHop,
 		}
+
+	// A trampoline onion may only be delivered to the final hop of the
+	// outer route, which is the trampoline node itself.
+	case !isFinalHop && hasTrampoline:
+		return ErrInvalidPayload{
+			Type:      record.TrampolineOnionType,
+			Violation: IncludedViolation,
+			FinalHop:  isFinalHop,
+		}
+
+	// Trampoline onions can't be combined with AMP, since the trampoline
+	// can't reassemble the shards on behalf of the recipient.
+	case hasTrampoline && hasAMP:
+		return ErrInvalidPayload{
+			Type:      record.AMPOnionType,
+			Violation: IncludedViolation,
+			FinalHop:  isFinalHop,
+		}
 	}
 
 	return nil
@@ -427,6 +460,12 @@ func (h *Payload) TotalAmtMsat() lnwire.MilliSatoshi {
 	return h.totalAmtMsat
 }
 
+// TrampolineOnion returns the serialized inner trampoline onion packet parsed
+// from the onion payload, or nil if the payload doesn't carry one.
+func (h *Payload) TrampolineOnion() []byte {
+	return h.trampolineOnion
+}
+
 // getMinRequiredViolation checks for unrecognized required (even) fields in the
 // standard range and returns the lowest required type. Always returning the
 // lowest required type allows a failure message to be deterministic.
@@ -35,6 +35,7 @@ type decodePayloadTest struct {
 	shouldHaveBlinding bool
 	shouldHaveMetadata bool
 	shouldHaveTotalAmt bool
+	shouldHaveTramp    bool
 }
 
 var decodePayloadTests = []decodePayloadTest{
@@ -353,6 +354,39 @@ var decodePayloadTests = []decodePayloadTest{
 		},
 		shouldHaveMetadata: true,
 	},
+	{
+		name:       "final hop with trampoline onion",
+		isFinalHop: true,
+		payload: []byte{
+			// amount
+			0x02, 0x00,
+			// cltv
+			0x04, 0x00,
+			// trampoline onion
+			0x14, 0x03, 0x01, 0x02, 0x03,
+		},
+		shouldHaveTramp: true,
+	},
+	{
+		name:       "intermediate hop with trampoline onion",
+		isFinalHop: false,
+		payload: []byte{
+			// amount
+			0x02, 0x00,
+			// cltv
+			0x04, 0x00,
+			// next hop id
+			0x06, 0x08,
+			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			// trampoline onion
+			0x14, 0x03, 0x01, 0x02, 0x03,
+		},
+		expErr: hop.ErrInvalidPayload{
+			Type:      record.TrampolineOnionType,
+			Violation: hop.IncludedViolation,
+			FinalHop:  false,
+		},
+	},
 	{
 		name:       "final hop with total amount",
 		isFinalHop: true,
@@ -621,6 +655,12 @@ func testDecodeHopPayloadValidation(t *testing.T, test decodePayloadTest) {
 		require.Zero(t, p.TotalAmtMsat())
 	}
 
+	if test.shouldHaveTramp {
+		require.Equal(t, []byte{1, 2, 3}, p.TrampolineOnion())
+	} else {
+		require.Nil(t, p.TrampolineOnion())
+	}
+
 	// Convert expected nil map to empty map, because we always expect an
 	// initiated map from the payload.
 	expCustomRecords := make(record.CustomSet)
@@ -0,0 +1,558 @@
+package hop
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// TrampolineOnionVersion is the only version of the trampoline onion
+	// packet that is currently defined.
+	TrampolineOnionVersion byte = 0
+
+	// TrampolineRoutingInfoSize is the size of the routing info of the
+	// trampoline onion packets that we construct. Trampoline onions are
+	// carried inside the final hop payload of a regular onion, so they
+	// are much smaller than the fixed 1300 bytes used for regular onions.
+	TrampolineRoutingInfoSize = 400
+
+	// MaxTrampolineRoutingInfoSize is the largest routing info that we'll
+	// accept in a trampoline onion that we need to process.
+	MaxTrampolineRoutingInfoSize = 650
+
+	// trampolineHMACSize is the size of the HMACs used within the
+	// trampoline onion.
+	trampolineHMACSize = sha256.Size
+
+	// trampolinePubKeySize is the size of the serialized ephemeral key.
+	trampolinePubKeySize = btcec.PubKeyBytesLenCompressed
+)
+
+var (
+	// ErrInvalidTrampolineOnion is returned when a trampoline onion can't
+	// be decoded or has an invalid version.
+	ErrInvalidTrampolineOnion = errors.New("invalid trampoline onion")
+
+	// ErrTrampolineHMACMismatch is returned when the HMAC of a trampoline
+	// onion doesn't match the one that we compute over the packet.
+	ErrTrampolineHMACMismatch = errors.New("trampoline onion hmac " +
+		"mismatch")
+
+	// ErrTrampolinePayloadTooLarge is returned when the hop payloads that
+	// are to be included in a trampoline onion don't fit in the routing
+	// info.
+	ErrTrampolinePayloadTooLarge = errors.New("trampoline payloads " +
+		"exceed routing info size")
+)
+
+// TrampolineOnionPacket is the inner onion that is delivered to a trampoline
+// node in its final hop payload. It follows the construction of a regular
+// Sphinx packet, but has a variable size routing info.
+type TrampolineOnionPacket struct {
+	// Version is the version of the trampoline onion.
+	Version byte
+
+	// EphemeralKey is the public key that the processing node uses to
+	// derive its shared secret.
+	EphemeralKey *btcec.PublicKey
+
+	// RoutingInfo holds the encrypted hop payloads.
+	RoutingInfo []byte
+
+	// HeaderMAC is the HMAC over the routing info and the associated data
+	// that authenticates the packet for the processing node.
+	HeaderMAC [trampolineHMACSize]byte
+}
+
+// Encode serializes the trampoline onion packet to the passed io.Writer.
+func (t *TrampolineOnionPacket) Encode(w io.Writer) error {
+	if _, err := w.Write([]byte{t.Version}); err != nil {
+		return err
+	}
+
+	ephemeral := t.EphemeralKey.SerializeCompressed()
+	if _, err := w.Write(ephemeral); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.RoutingInfo); err != nil {
+		return err
+	}
+
+	_, err := w.Write(t.HeaderMAC[:])
+
+	return err
+}
+
+// DecodeTrampolineOnionPacket decodes a serialized trampoline onion packet.
+// Since the routing info has a variable size, the full serialized packet must
+// be provided.
+func DecodeTrampolineOnionPacket(b []byte) (*TrampolineOnionPacket, error) {
+	overhead := 1 + trampolinePubKeySize + trampolineHMACSize
+	if len(b) <= overhead ||
+		len(b)-overhead > MaxTrampolineRoutingInfoSize {
+
+		return nil, fmt.Errorf("%w: size %v", ErrInvalidTrampolineOnion,
+			len(b))
+	}
+
+	if b[0] != TrampolineOnionVersion {
+		return nil, fmt.Errorf("%w: version %v",
+			ErrInvalidTrampolineOnion, b[0])
+	}
+
+	ephemeralKey, err := btcec.ParsePubKey(
+		b[1 : 1+trampolinePubKeySize],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTrampolineOnion, err)
+	}
+
+	var (
+		routingInfoStart = 1 + trampolinePubKeySize
+		routingInfoEnd   = len(b) - trampolineHMACSize
+	)
+	packet := &TrampolineOnionPacket{
+		Version:      b[0],
+		EphemeralKey: ephemeralKey,
+		RoutingInfo: append(
+			[]byte(nil), b[routingInfoStart:routingInfoEnd]...,
+		),
+	}
+	copy(packet.HeaderMAC[:], b[routingInfoEnd:])
+
+	return packet, nil
+}
+
+// TrampolineHopPayload pairs a trampoline node with the serialized TLV payload
+// that is delivered to it.
+type TrampolineHopPayload struct {
+	// NodePub is the public key of the trampoline node.
+	NodePub *btcec.PublicKey
+
+	// Payload is the TLV payload for the trampoline node.
+	Payload []byte
+}
+
+// frameSize returns the number of bytes that the hop occupies within the
+// routing info.
+func (h *TrampolineHopPayload) frameSize() int {
+	return int(tlv.VarIntSize(uint64(len(h.Payload)))) + len(h.Payload) +
+		trampolineHMACSize
+}
+
+// NewTrampolineOnionPacket constructs a trampoline onion that routes through
+// the given trampoline hops. The associated data, which is the payment hash,
+// is committed to in every hop's HMAC.
+func NewTrampolineOnionPacket(hops []TrampolineHopPayload,
+	sessionKey *btcec.PrivateKey,
+	assocData []byte) (*TrampolineOnionPacket, error) {
+
+	if len(hops) == 0 {
+		return nil, errors.New("trampoline route of length zero")
+	}
+
+	var totalSize int
+	for i := range hops {
+		totalSize += hops[i].frameSize()
+	}
+	if totalSize > TrampolineRoutingInfoSize {
+		return nil, ErrTrampolinePayloadTooLarge
+	}
+
+	sharedSecrets, err := trampolineSharedSecrets(hops, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	filler := trampolineFiller(hops, sharedSecrets)
+
+	// Initialize the routing info with pseudo-random bytes derived from
+	// the session key so that the unused tail of the packet can't be told
+	// apart from encrypted payloads.
+	var sessionSecret [32]byte
+	copy(sessionSecret[:], sessionKey.Serialize())
+	routingInfo := trampolineStream(
+		trampolineKey("pad", sessionSecret), TrampolineRoutingInfoSize,
+	)
+
+	var nextHMAC [trampolineHMACSize]byte
+	for i := len(hops) - 1; i >= 0; i-- {
+		rhoKey := trampolineKey("rho", sharedSecrets[i])
+		muKey := trampolineKey("mu", sharedSecrets[i])
+
+		var frame bytes.Buffer
+		err := tlv.WriteVarInt(
+			&frame, uint64(len(hops[i].Payload)), &[8]byte{},
+		)
+		if err != nil {
+			return nil, err
+		}
+		frame.Write(hops[i].Payload)
+		frame.Write(nextHMAC[:])
+
+		// Shift the routing info to the right to make room for this
+		// hop's frame, then obfuscate it with the hop's stream.
+		copy(routingInfo[frame.Len():], routingInfo)
+		copy(routingInfo, frame.Bytes())
+
+		stream := trampolineStream(rhoKey, TrampolineRoutingInfoSize)
+		xorBytes(routingInfo, routingInfo, stream)
+
+		// The filler is placed at the end of the innermost layer so
+		// that the HMACs of all hops remain valid as the packet is
+		// peeled.
+		if i == len(hops)-1 {
+			copy(routingInfo[len(routingInfo)-len(filler):], filler)
+		}
+
+		nextHMAC = trampolineMAC(muKey, routingInfo, assocData)
+	}
+
+	return &TrampolineOnionPacket{
+		Version:      TrampolineOnionVersion,
+		EphemeralKey: sessionKey.PubKey(),
+		RoutingInfo:  routingInfo,
+		HeaderMAC:    nextHMAC,
+	}, nil
+}
+
+// trampolineSharedSecrets derives the shared secret for each of the hops,
+// blinding the ephemeral key after every hop.
+func trampolineSharedSecrets(hops []TrampolineHopPayload,
+	sessionKey *btcec.PrivateKey) ([][32]byte, error) {
+
+	var (
+		secrets      = make([][32]byte, len(hops))
+		ephemeralKey = sessionKey
+	)
+	for i := range hops {
+		ecdh := &sphinx.PrivKeyECDH{PrivKey: ephemeralKey}
+		secret, err := ecdh.ECDH(hops[i].NodePub)
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = secret
+
+		blindingFactor := trampolineBlindingFactor(
+			ephemeralKey.PubKey(), secret,
+		)
+
+		var scalar btcec.ModNScalar
+		scalar.Set(&ephemeralKey.Key)
+		scalar.Mul(&blindingFactor)
+		ephemeralKey = btcec.PrivKeyFromScalar(&scalar)
+	}
+
+	return secrets, nil
+}
+
+// trampolineFiller generates the filler bytes that take the place of the
+// frames that are shifted out of the routing info as it is processed by all
+// but the final hop.
+func trampolineFiller(hops []TrampolineHopPayload,
+	sharedSecrets [][32]byte) []byte {
+
+	var fillerSize int
+	for i := 0; i < len(hops)-1; i++ {
+		fillerSize += hops[i].frameSize()
+	}
+	filler := make([]byte, fillerSize)
+
+	fillerStart := TrampolineRoutingInfoSize
+	for i := 0; i < len(hops)-1; i++ {
+		fillerEnd := TrampolineRoutingInfoSize + hops[i].frameSize()
+
+		stream := trampolineStream(
+			trampolineKey("rho", sharedSecrets[i]),
+			2*TrampolineRoutingInfoSize,
+		)
+		xorBytes(filler, filler, stream[fillerStart:fillerEnd])
+
+		fillerStart -= hops[i].frameSize()
+	}
+
+	return filler
+}
+
+// TrampolinePayload is the decrypted payload of a trampoline onion for our
+// node.
+type TrampolinePayload struct {
+	// AmountToForward is the amount that should be delivered to the
+	// outgoing node.
+	AmountToForward lnwire.MilliSatoshi
+
+	// OutgoingCTLV is the absolute expiry of the HTLC that the outgoing
+	// node should receive.
+	OutgoingCTLV uint32
+
+	// OutgoingNodeID is the node that the payment should be forwarded to.
+	// It is nil if we are the final node of the trampoline route.
+	OutgoingNodeID *btcec.PublicKey
+
+	// MPP holds the payment address and total amount that should be sent
+	// to the recipient, if it is not trampoline aware.
+	MPP *record.MPP
+
+	// Metadata is the payment metadata for the recipient.
+	Metadata []byte
+
+	// NextOnion is the serialized trampoline onion for the outgoing node.
+	// It is nil if the outgoing node is the final recipient, in which
+	// case the payment should be delivered as a regular payment.
+	NextOnion []byte
+}
+
+// ParseTrampolinePayload parses the TLV payload of a trampoline hop.
+func ParseTrampolinePayload(r io.Reader) (*TrampolinePayload, error) {
+	var (
+		amt      uint64
+		cltv     uint32
+		nodeID   *btcec.PublicKey
+		mpp      = &record.MPP{}
+		metadata []byte
+	)
+
+	tlvStream, err := tlv.NewStream(
+		record.NewAmtToFwdRecord(&amt),
+		record.NewLockTimeRecord(&cltv),
+		mpp.Record(),
+		record.NewMetadataRecord(&metadata),
+		record.NewOutgoingNodeIDRecord(&nodeID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := tlvStream.DecodeWithParsedTypesP2P(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := parsedTypes[record.AmtOnionType]; !ok {
+		return nil, ErrInvalidPayload{
+			Type:      record.AmtOnionType,
+			Violation: OmittedViolation,
+		}
+	}
+
+	if _, ok := parsedTypes[record.LockTimeOnionType]; !ok {
+		return nil, ErrInvalidPayload{
+			Type:      record.LockTimeOnionType,
+			Violation: OmittedViolation,
+		}
+	}
+
+	if _, ok := parsedTypes[record.MPPOnionType]; !ok {
+		mpp = nil
+	}
+
+	if _, ok := parsedTypes[record.MetadataOnionType]; !ok {
+		metadata = nil
+	}
+
+	violatingType := getMinRequiredViolation(parsedTypes)
+	if violatingType != nil {
+		return nil, ErrInvalidPayload{
+			Type:      *violatingType,
+			Violation: RequiredViolation,
+		}
+	}
+
+	return &TrampolinePayload{
+		AmountToForward: lnwire.MilliSatoshi(amt),
+		OutgoingCTLV:    cltv,
+		OutgoingNodeID:  nodeID,
+		MPP:             mpp,
+		Metadata:        metadata,
+	}, nil
+}
+
+// TrampolineProcessor decrypts the trampoline onions that are delivered to
+// our node.
+type TrampolineProcessor struct {
+	onionKey sphinx.SingleKeyECDH
+}
+
+// NewTrampolineProcessor creates a trampoline processor that uses the given
+// key to derive the shared secrets of the trampoline onions.
+func NewTrampolineProcessor(
+	onionKey sphinx.SingleKeyECDH) *TrampolineProcessor {
+
+	return &TrampolineProcessor{
+		onionKey: onionKey,
+	}
+}
+
+// DecodeTrampolineOnion decrypts the layer of the serialized trampoline onion
+// that is intended for our node, and returns our payload along with the onion
+// for the next trampoline node, if any. The associated data must match the
+// data that the onion was constructed with.
+func (p *TrampolineProcessor) DecodeTrampolineOnion(onion []byte,
+	assocData []byte) (*TrampolinePayload, error) {
+
+	packet, err := DecodeTrampolineOnionPacket(onion)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSecret, err := p.onionKey.ECDH(packet.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	muKey := trampolineKey("mu", sharedSecret)
+	mac := trampolineMAC(muKey, packet.RoutingInfo, assocData)
+	if !hmac.Equal(mac[:], packet.HeaderMAC[:]) {
+		return nil, ErrTrampolineHMACMismatch
+	}
+
+	// Decrypt the routing info, padded with zeroes so that we can shift
+	// out our frame while keeping the size of the packet constant.
+	routingInfoSize := len(packet.RoutingInfo)
+	decrypted := make([]byte, 2*routingInfoSize)
+	copy(decrypted, packet.RoutingInfo)
+	xorBytes(decrypted, decrypted, trampolineStream(
+		trampolineKey("rho", sharedSecret), 2*routingInfoSize,
+	))
+
+	r := bytes.NewReader(decrypted)
+	payloadLen, err := tlv.ReadVarInt(r, &[8]byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Our frame, including the HMAC for the next hop, must fit within the
+	// routing info that we received.
+	payloadStart := len(decrypted) - r.Len()
+	if payloadLen > uint64(routingInfoSize-payloadStart-
+		trampolineHMACSize) {
+
+		return nil, fmt.Errorf("%w: payload length %v",
+			ErrInvalidTrampolineOnion, payloadLen)
+	}
+	payloadEnd := payloadStart + int(payloadLen)
+	payload, err := ParseTrampolinePayload(
+		bytes.NewReader(decrypted[payloadStart:payloadEnd]),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextHMAC [trampolineHMACSize]byte
+	copy(nextHMAC[:], decrypted[payloadEnd:])
+
+	// A zero HMAC signals that we are the final node of the trampoline
+	// route.
+	if nextHMAC == ([trampolineHMACSize]byte{}) {
+		return payload, nil
+	}
+
+	if payload.OutgoingNodeID == nil {
+		return nil, ErrInvalidPayload{
+			Type:      record.OutgoingNodeIDOnionType,
+			Violation: OmittedViolation,
+		}
+	}
+
+	blindingFactor := trampolineBlindingFactor(
+		packet.EphemeralKey, sharedSecret,
+	)
+	var ephemeralJ, nextJ btcec.JacobianPoint
+	packet.EphemeralKey.AsJacobian(&ephemeralJ)
+	btcec.ScalarMultNonConst(&blindingFactor, &ephemeralJ, &nextJ)
+	nextJ.ToAffine()
+
+	nextStart := payloadEnd + trampolineHMACSize
+	nextPacket := &TrampolineOnionPacket{
+		Version:      TrampolineOnionVersion,
+		EphemeralKey: btcec.NewPublicKey(&nextJ.X, &nextJ.Y),
+		RoutingInfo:  decrypted[nextStart : nextStart+routingInfoSize],
+		HeaderMAC:    nextHMAC,
+	}
+
+	var b bytes.Buffer
+	if err := nextPacket.Encode(&b); err != nil {
+		return nil, err
+	}
+	payload.NextOnion = b.Bytes()
+
+	return payload, nil
+}
+
+// trampolineBlindingFactor computes the factor that is used to blind the
+// ephemeral key for the next hop.
+func trampolineBlindingFactor(ephemeralKey *btcec.PublicKey,
+	sharedSecret [32]byte) btcec.ModNScalar {
+
+	h := sha256.New()
+	h.Write(ephemeralKey.SerializeCompressed())
+	h.Write(sharedSecret[:])
+
+	var factor btcec.ModNScalar
+	factor.SetByteSlice(h.Sum(nil))
+
+	return factor
+}
+
+// trampolineKey derives a key of the given type from a shared secret.
+func trampolineKey(keyType string, secret [32]byte) [32]byte {
+	mac := hmac.New(sha256.New, []byte(keyType))
+	mac.Write(secret[:])
+
+	var key [32]byte
+	copy(key[:], mac.Sum(nil))
+
+	return key
+}
+
+// trampolineMAC computes the HMAC over the routing info and associated data.
+func trampolineMAC(key [32]byte, routingInfo,
+	assocData []byte) [trampolineHMACSize]byte {
+
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(routingInfo)
+	mac.Write(assocData)
+
+	var h [trampolineHMACSize]byte
+	copy(h[:], mac.Sum(nil))
+
+	return h
+}
+
+// trampolineStream generates a ChaCha20 stream of the given length using a
+// zero nonce.
+func trampolineStream(key [32]byte, length int) []byte {
+	var nonce [chacha20.NonceSize]byte
+
+	// The key and nonce have the correct sizes, so this can't fail.
+	cipher, _ := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+
+	stream := make([]byte, length)
+	cipher.XORKeyStream(stream, stream)
+
+	return stream
+}
+
+// xorBytes sets dst to the XOR of a and b over the length of the shortest
+// slice.
+func xorBytes(dst, a, b []byte) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+}
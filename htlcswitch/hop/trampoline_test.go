@@ -0,0 +1,149 @@
+package hop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTrampolinePayload serializes a trampoline hop payload for testing.
+func encodeTrampolinePayload(t *testing.T, amt uint64, cltv uint32,
+	nodeID *btcec.PublicKey, mpp *record.MPP) []byte {
+
+	records := []tlv.Record{
+		record.NewAmtToFwdRecord(&amt),
+		record.NewLockTimeRecord(&cltv),
+	}
+	if mpp != nil {
+		records = append(records, mpp.Record())
+	}
+	if nodeID != nil {
+		records = append(
+			records, record.NewOutgoingNodeIDRecord(&nodeID),
+		)
+	}
+
+	stream, err := tlv.NewStream(records...)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, stream.Encode(&b))
+
+	return b.Bytes()
+}
+
+// TestTrampolineOnionRoundTrip asserts that a trampoline onion can be peeled
+// by each of the trampoline nodes that it was constructed for, and that each
+// of them recovers its own payload.
+func TestTrampolineOnionRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	const numHops = 3
+
+	var (
+		privKeys    = make([]*btcec.PrivateKey, numHops)
+		hops        = make([]TrampolineHopPayload, numHops)
+		paymentHash = bytes.Repeat([]byte{1}, 32)
+		mpp         = record.NewMPP(1000, [32]byte{2})
+	)
+	for i := 0; i < numHops; i++ {
+		privKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		privKeys[i] = privKey
+	}
+
+	for i := 0; i < numHops; i++ {
+		var (
+			nodeID  *btcec.PublicKey
+			hopMPP  *record.MPP
+			amtBase = uint64(1000 + 10*(numHops-1-i))
+		)
+		if i < numHops-1 {
+			nodeID = privKeys[i+1].PubKey()
+		} else {
+			hopMPP = mpp
+		}
+
+		hops[i] = TrampolineHopPayload{
+			NodePub: privKeys[i].PubKey(),
+			Payload: encodeTrampolinePayload(
+				t, amtBase, uint32(100+i), nodeID, hopMPP,
+			),
+		}
+	}
+
+	sessionKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	packet, err := NewTrampolineOnionPacket(hops, sessionKey, paymentHash)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, packet.Encode(&b))
+	onion := b.Bytes()
+
+	for i := 0; i < numHops; i++ {
+		processor := NewTrampolineProcessor(
+			&sphinx.PrivKeyECDH{PrivKey: privKeys[i]},
+		)
+
+		// An onion that commits to different associated data must be
+		// rejected.
+		_, err := processor.DecodeTrampolineOnion(
+			onion, bytes.Repeat([]byte{2}, 32),
+		)
+		require.ErrorIs(t, err, ErrTrampolineHMACMismatch)
+
+		payload, err := processor.DecodeTrampolineOnion(
+			onion, paymentHash,
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, lnwire.MilliSatoshi(1000+10*(numHops-1-i)),
+			payload.AmountToForward)
+		require.Equal(t, uint32(100+i), payload.OutgoingCTLV)
+
+		// All but the last hop must be given the next node and onion,
+		// which must have the same size as the incoming onion.
+		if i < numHops-1 {
+			require.True(
+				t, privKeys[i+1].PubKey().IsEqual(
+					payload.OutgoingNodeID,
+				),
+			)
+			require.Len(t, payload.NextOnion, len(onion))
+			require.Nil(t, payload.MPP)
+
+			onion = payload.NextOnion
+
+			continue
+		}
+
+		require.Nil(t, payload.NextOnion)
+		require.Nil(t, payload.OutgoingNodeID)
+		require.Equal(t, mpp, payload.MPP)
+	}
+}
+
+// TestTrampolineOnionTooLarge asserts that we refuse to create a trampoline
+// onion for payloads that don't fit in the routing info.
+func TestTrampolineOnionTooLarge(t *testing.T) {
+	t.Parallel()
+
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	hops := []TrampolineHopPayload{{
+		NodePub: privKey.PubKey(),
+		Payload: make([]byte, TrampolineRoutingInfoSize),
+	}}
+
+	_, err = NewTrampolineOnionPacket(hops, privKey, nil)
+	require.ErrorIs(t, err, ErrTrampolinePayloadTooLarge)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch/hop"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -23,6 +24,18 @@ var (
 	// code is attempted.
 	ErrUnsupportedFailureCode = errors.New("unsupported failure code")
 
+	// ErrInvalidModifiedChan is returned when an intercepted forward is
+	// resumed on an outgoing channel that doesn't lead to the same peer
+	// as the requested outgoing channel.
+	ErrInvalidModifiedChan = errors.New("modified outgoing channel must " +
+		"lead to the same peer")
+
+	// ErrInvalidModifiedAmount is returned when an intercepted forward is
+	// resumed with an outgoing amount that is zero or exceeds the incoming
+	// amount.
+	ErrInvalidModifiedAmount = errors.New("modified outgoing amount must " +
+		"be positive and not exceed the incoming amount")
+
 	errBlockStreamStopped = errors.New("block epoch stream stopped")
 )
 
@@ -31,8 +44,12 @@ var (
 // intercepts forward requests. A reference to the Switch is held in order
 // to communicate back the interception result where the options are:
 // Resume - forwards the original request to the switch as is.
+// ResumeModified - forwards a modified request to the switch.
 // Settle - routes UpdateFulfillHTLC to the originating link.
 // Fail - routes UpdateFailHTLC to the originating link.
+// Multiple interceptors can be registered. They form a chain that a forward
+// passes through in order of priority, where a resume hands the forward on to
+// the next interceptor and only the last one releases it to the switch.
 type InterceptableSwitch struct {
 	started atomic.Bool
 	stopped atomic.Bool
@@ -50,16 +67,28 @@ type InterceptableSwitch struct {
 
 	onchainIntercepted chan InterceptedForward
 
-	// interceptorRegistration is a channel that we use to synchronize
-	// client connect and disconnect.
-	interceptorRegistration chan ForwardInterceptor
+	// chainUpdates is a channel that we use to synchronize changes to the
+	// interceptor chain with the main loop.
+	chainUpdates chan func()
+
+	// timeouts receives the interceptor timeouts of held forwards.
+	timeouts chan *interceptTimeout
 
 	// requireInterceptor indicates whether processing should block if no
 	// interceptor is connected.
 	requireInterceptor bool
 
-	// interceptor is the handler for intercepted packets.
-	interceptor ForwardInterceptor
+	// interceptors is the chain of registered interceptors, ordered by
+	// priority and registration.
+	interceptors []*chainInterceptor
+
+	// nextInterceptorID is the id of the most recently registered
+	// interceptor.
+	nextInterceptorID InterceptorID
+
+	// legacyID is the id of the interceptor that is registered through
+	// SetInterceptor.
+	legacyID InterceptorID
 
 	// heldHtlcSet keeps track of outstanding intercepted forwards.
 	heldHtlcSet *heldHtlcSet
@@ -109,6 +138,11 @@ const (
 
 	// FwdActionFail fails the intercepted packet back to the sender.
 	FwdActionFail
+
+	// FwdActionResumeModified forwards the intercepted packet to the
+	// switch with a modified outgoing channel, amount or set of custom
+	// records.
+	FwdActionResumeModified
 )
 
 // FwdResolution defines the action to be taken on an intercepted packet.
@@ -116,6 +150,11 @@ type FwdResolution struct {
 	// Key is the incoming circuit key of the htlc.
 	Key models.CircuitKey
 
+	// InterceptorID is the interceptor that resolves the htlc. If it is
+	// set, the resolution is rejected unless the htlc is currently held by
+	// this interceptor.
+	InterceptorID InterceptorID
+
 	// Action is the action to take on the intercepted htlc.
 	Action FwdAction
 
@@ -130,6 +169,19 @@ type FwdResolution struct {
 	// FailureCode is the failure code that is to be passed back to the
 	// sender if action is FwdActionFail.
 	FailureCode lnwire.FailCode
+
+	// OutgoingChanID is the channel that the htlc is forwarded on if
+	// action is FwdActionResumeModified. It must lead to the same peer as
+	// the requested outgoing channel.
+	OutgoingChanID fn.Option[lnwire.ShortChannelID]
+
+	// OutAmountMsat is the amount that is forwarded if action is
+	// FwdActionResumeModified.
+	OutAmountMsat fn.Option[lnwire.MilliSatoshi]
+
+	// OutWireCustomRecords are the custom records that are attached to
+	// the outgoing update_add_htlc if action is FwdActionResumeModified.
+	OutWireCustomRecords fn.Option[lnwire.CustomRecords]
 }
 
 type fwdResolution struct {
@@ -175,30 +227,34 @@ func NewInterceptableSwitch(cfg *InterceptableSwitchConfig) (
 	}
 
 	return &InterceptableSwitch{
-		htlcSwitch:              cfg.Switch,
-		intercepted:             make(chan *interceptedPackets),
-		onchainIntercepted:      make(chan InterceptedForward),
-		interceptorRegistration: make(chan ForwardInterceptor),
-		heldHtlcSet:             newHeldHtlcSet(),
-		resolutionChan:          make(chan *fwdResolution),
-		requireInterceptor:      cfg.RequireInterceptor,
-		cltvRejectDelta:         cfg.CltvRejectDelta,
-		cltvInterceptDelta:      cfg.CltvInterceptDelta,
-		notifier:                cfg.Notifier,
+		htlcSwitch:         cfg.Switch,
+		intercepted:        make(chan *interceptedPackets),
+		onchainIntercepted: make(chan InterceptedForward),
+		chainUpdates:       make(chan func()),
+		timeouts:           make(chan *interceptTimeout),
+		heldHtlcSet:        newHeldHtlcSet(),
+		resolutionChan:     make(chan *fwdResolution),
+		requireInterceptor: cfg.RequireInterceptor,
+		cltvRejectDelta:    cfg.CltvRejectDelta,
+		cltvInterceptDelta: cfg.CltvInterceptDelta,
+		notifier:           cfg.Notifier,
 
 		quit: make(chan struct{}),
 	}, nil
 }
 
 // SetInterceptor sets the ForwardInterceptor to be used. A nil argument
-// unregisters the current interceptor.
+// unregisters the current interceptor. The interceptor is registered in the
+// interceptor chain with the default priority and without a filter or
+// timeout.
 func (s *InterceptableSwitch) SetInterceptor(
 	interceptor ForwardInterceptor) {
 
 	// Synchronize setting the handler with the main loop to prevent race
-	// conditions.
+	// conditions. Don't wait for the update to complete, because replaying
+	// the held htlcs may require the caller to receive them.
 	select {
-	case s.interceptorRegistration <- interceptor:
+	case s.chainUpdates <- func() { s.setInterceptor(interceptor) }:
 
 	case <-s.quit:
 	}
@@ -272,9 +328,14 @@ func (s *InterceptableSwitch) run() error {
 
 	for {
 		select {
-		// An interceptor registration or de-registration came in.
-		case interceptor := <-s.interceptorRegistration:
-			s.setInterceptor(interceptor)
+		// An interceptor registration, update or de-registration came
+		// in.
+		case update := <-s.chainUpdates:
+			update()
+
+		// An interceptor didn't resolve a held forward in time.
+		case timeout := <-s.timeouts:
+			s.handleTimeout(timeout)
 
 		case packets := <-s.intercepted:
 			var notIntercepted []*htlcPacket
@@ -345,64 +406,101 @@ func (s *InterceptableSwitch) failExpiredHtlcs() {
 	)
 }
 
-func (s *InterceptableSwitch) sendForward(fwd InterceptedForward) {
-	err := s.interceptor(fwd.Packet())
-	if err != nil {
-		// Only log the error. If we couldn't send the packet, we assume
-		// that the interceptor will reconnect so that we can retry.
-		log.Debugf("Interceptor cannot handle forward: %v", err)
-	}
-}
-
 func (s *InterceptableSwitch) setInterceptor(interceptor ForwardInterceptor) {
-	s.interceptor = interceptor
+	legacy := s.findInterceptor(s.legacyID)
 
-	// Replay all currently held htlcs. When an interceptor is not required,
-	// there may be none because they've been cleared after the previous
-	// disconnect.
-	if interceptor != nil {
-		log.Debugf("Interceptor connected")
+	if interceptor == nil {
+		if legacy != nil {
+			log.Debugf("Interceptor disconnected")
 
-		s.heldHtlcSet.forEach(s.sendForward)
+			s.removeInterceptor(s.legacyID)
+		}
+		s.legacyID = 0
 
 		return
 	}
 
-	// The interceptor disconnects. If an interceptor is required, keep the
-	// held htlcs.
-	if s.requireInterceptor {
-		log.Infof("Interceptor disconnected, retaining held packets")
+	// Replace the handler of an interceptor that is still registered and
+	// replay the htlcs that it currently holds.
+	if legacy != nil {
+		legacy.reg.Interceptor = interceptor
+		for _, held := range s.heldHtlcSet.heldBy(s.legacyID) {
+			s.sendForward(legacy, held.fwd)
+		}
 
 		return
 	}
 
-	// Interceptor is not required. Release held forwards.
-	log.Infof("Interceptor disconnected, resolving held packets")
+	log.Debugf("Interceptor connected")
 
-	s.heldHtlcSet.popAll(func(fwd InterceptedForward) {
-		err := fwd.Resume()
-		if err != nil {
-			log.Errorf("Failed to resume hold forward %v", err)
-		}
+	s.legacyID = s.addInterceptor(InterceptorRegistration{
+		Interceptor:   interceptor,
+		TimeoutAction: FwdActionResume,
 	})
 }
 
 // resolve processes a HTLC given the resolution type specified by the
 // intercepting client.
 func (s *InterceptableSwitch) resolve(res *FwdResolution) error {
-	intercepted, err := s.heldHtlcSet.pop(res.Key)
+	held, err := s.heldHtlcSet.get(res.Key)
 	if err != nil {
 		return err
 	}
 
+	if res.InterceptorID != 0 && res.InterceptorID != held.holder {
+		return ErrFwdNotHeld
+	}
+
 	switch res.Action {
+	// Pass the forward on to the next interceptor in the chain.
 	case FwdActionResume:
-		return intercepted.Resume()
+		return s.advance(res.Key, held)
+
+	case FwdActionResumeModified:
+		fwd, ok := held.fwd.(modifiableForward)
+		if !ok {
+			err := held.fwd.ResumeModified(
+				res.OutgoingChanID, res.OutAmountMsat,
+				res.OutWireCustomRecords,
+			)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.heldHtlcSet.pop(res.Key)
+
+			return err
+		}
+
+		// An invalid modification keeps the htlc held, so that the
+		// interceptor can still resolve it differently.
+		modified, err := fwd.modified(
+			res.OutgoingChanID, res.OutAmountMsat,
+			res.OutWireCustomRecords,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Later interceptors in the chain see the modified forward.
+		held.fwd = modified
+
+		return s.advance(res.Key, held)
 
 	case FwdActionSettle:
+		intercepted, err := s.heldHtlcSet.pop(res.Key)
+		if err != nil {
+			return err
+		}
+
 		return intercepted.Settle(res.Preimage)
 
 	case FwdActionFail:
+		intercepted, err := s.heldHtlcSet.pop(res.Key)
+		if err != nil {
+			return err
+		}
+
 		if len(res.FailureMessage) > 0 {
 			return intercepted.Fail(res.FailureMessage)
 		}
@@ -538,7 +636,7 @@ func (s *InterceptableSwitch) forward(
 
 	// If there is no interceptor currently registered, configuration and packet
 	// replay status determine how the packet is handled.
-	if s.interceptor == nil {
+	if len(s.interceptors) == 0 {
 		// Process normally if an interceptor is not required.
 		if !s.requireInterceptor {
 			return false, nil
@@ -568,13 +666,25 @@ func (s *InterceptableSwitch) forward(
 		return true, nil
 	}
 
-	// There is an interceptor registered. We can forward the packet right now.
-	// Hold it in the queue too to track what is outstanding.
+	// There are interceptors registered. Process normally if none of them
+	// is interested in the packet.
+	next := s.nextInterceptor(fwd.Packet(), chainPosition{})
+	if next == nil {
+		return false, nil
+	}
+
+	// We can forward the packet right now. Hold it in the queue too to
+	// track what is outstanding.
 	if err := s.heldHtlcSet.push(inKey, fwd); err != nil {
 		return false, err
 	}
 
-	s.sendForward(fwd)
+	held, err := s.heldHtlcSet.get(inKey)
+	if err != nil {
+		return false, err
+	}
+
+	s.offer(inKey, held, next)
 
 	return true, nil
 }
@@ -631,6 +741,8 @@ func (f *interceptedForward) Packet() InterceptedPacket {
 		CustomRecords:  f.packet.customRecords,
 		OnionBlob:      f.htlc.OnionBlob,
 		AutoFailHeight: f.autoFailHeight,
+
+		InWireCustomRecords: f.packet.inWireCustomRecords,
 	}
 }
 
@@ -641,6 +753,100 @@ func (f *interceptedForward) Resume() error {
 	return f.htlcSwitch.ForwardPackets(nil, f.packet)
 }
 
+// modifiableForward is an intercepted forward whose outgoing htlc can be
+// modified before it is passed on to the next interceptor in the chain.
+type modifiableForward interface {
+	// modified returns a copy of the forward with the given modifications
+	// applied.
+	modified(outgoingChanID fn.Option[lnwire.ShortChannelID],
+		outAmountMsat fn.Option[lnwire.MilliSatoshi],
+		outWireCustomRecords fn.Option[lnwire.CustomRecords]) (
+		InterceptedForward, error)
+}
+
+// ResumeModified resumes the default behavior with a modified outgoing
+// channel, amount or set of custom records on the outgoing htlc. The switch
+// still enforces the forwarding policy of the outgoing channel, so a modified
+// amount must leave at least the fee that the channel charges.
+func (f *interceptedForward) ResumeModified(
+	outgoingChanID fn.Option[lnwire.ShortChannelID],
+	outAmountMsat fn.Option[lnwire.MilliSatoshi],
+	outWireCustomRecords fn.Option[lnwire.CustomRecords]) error {
+
+	fwd, err := f.modified(
+		outgoingChanID, outAmountMsat, outWireCustomRecords,
+	)
+	if err != nil {
+		return err
+	}
+
+	return fwd.Resume()
+}
+
+// modified returns a copy of the forward with a modified outgoing channel,
+// amount or set of custom records. The intercepted htlc stays intact.
+func (f *interceptedForward) modified(
+	outgoingChanID fn.Option[lnwire.ShortChannelID],
+	outAmountMsat fn.Option[lnwire.MilliSatoshi],
+	outWireCustomRecords fn.Option[lnwire.CustomRecords]) (
+	InterceptedForward, error) {
+
+	// Validate all modifications before we apply any of them.
+	if outgoingChanID.IsSome() {
+		chanID := outgoingChanID.UnwrapOr(f.packet.outgoingChanID)
+		requestedPeer := f.htlcSwitch.linkPeer(f.packet.outgoingChanID)
+		peer := f.htlcSwitch.linkPeer(chanID)
+		if peer.IsNone() || peer != requestedPeer {
+			return nil, ErrInvalidModifiedChan
+		}
+	}
+
+	if outAmountMsat.IsSome() {
+		amount := outAmountMsat.UnwrapOr(0)
+		if amount == 0 || amount > f.packet.incomingAmount {
+			return nil, ErrInvalidModifiedAmount
+		}
+	}
+
+	var err error
+	outWireCustomRecords.WhenSome(func(records lnwire.CustomRecords) {
+		err = records.Validate()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the modifications to copies of the htlc and the packet.
+	htlc := *f.htlc
+	packet := *f.packet
+	packet.htlc = &htlc
+
+	outgoingChanID.WhenSome(func(chanID lnwire.ShortChannelID) {
+		packet.outgoingChanID = chanID
+		packet.strictOutgoingChan = true
+	})
+
+	outAmountMsat.WhenSome(func(amount lnwire.MilliSatoshi) {
+		htlc.Amount = amount
+		packet.amount = amount
+	})
+
+	outWireCustomRecords.WhenSome(func(records lnwire.CustomRecords) {
+		htlc.CustomRecords = records.Copy()
+	})
+
+	log.Debugf("Modified forward %v: outgoing_chan=%v, amount=%v, "+
+		"custom_records=%v", packet.inKey(), packet.outgoingChanID,
+		packet.amount, len(htlc.CustomRecords))
+
+	return &interceptedForward{
+		htlc:           &htlc,
+		packet:         &packet,
+		htlcSwitch:     f.htlcSwitch,
+		autoFailHeight: f.autoFailHeight,
+	}, nil
+}
+
 // Fail notifies the intention to Fail an existing hold forward with an
 // encrypted failure reason.
 func (f *interceptedForward) Fail(reason []byte) error {
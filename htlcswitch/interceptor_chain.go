@@ -0,0 +1,475 @@
+package htlcswitch
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// ErrInterceptorNotFound is returned when an interceptor is updated
+	// that isn't registered.
+	ErrInterceptorNotFound = errors.New("interceptor not found")
+
+	// ErrFwdNotHeld is returned when an interceptor tries to resolve a
+	// forward that is currently offered to a different interceptor.
+	ErrFwdNotHeld = errors.New("forward not held by this interceptor")
+)
+
+// InterceptorID identifies an interceptor that is registered with the
+// InterceptableSwitch. The zero value is never assigned.
+type InterceptorID uint64
+
+// InterceptFilter selects the forwards that are offered to an interceptor.
+// An empty filter matches all forwards.
+type InterceptFilter struct {
+	// IncomingChanIDs restricts interception to htlcs that arrive on one
+	// of the given channels.
+	IncomingChanIDs []lnwire.ShortChannelID
+
+	// MinAmount is the minimum incoming amount of intercepted htlcs.
+	MinAmount lnwire.MilliSatoshi
+
+	// MaxAmount is the maximum incoming amount of intercepted htlcs. A
+	// zero value means that there is no maximum.
+	MaxAmount lnwire.MilliSatoshi
+
+	// CustomRecordTypes restricts interception to htlcs that carry all of
+	// the given custom record types, either in the onion payload or in the
+	// update_add_htlc message.
+	CustomRecordTypes []uint64
+}
+
+// matches returns true if the packet passes the filter.
+func (f *InterceptFilter) matches(packet InterceptedPacket) bool {
+	if len(f.IncomingChanIDs) > 0 {
+		var found bool
+		for _, chanID := range f.IncomingChanIDs {
+			if chanID == packet.IncomingCircuit.ChanID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if packet.IncomingAmount < f.MinAmount {
+		return false
+	}
+
+	if f.MaxAmount != 0 && packet.IncomingAmount > f.MaxAmount {
+		return false
+	}
+
+	for _, recordType := range f.CustomRecordTypes {
+		_, inPayload := packet.CustomRecords[recordType]
+		_, inWire := packet.InWireCustomRecords[recordType]
+		if !inPayload && !inWire {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InterceptorRegistration describes an interceptor and its place in the
+// interceptor chain.
+type InterceptorRegistration struct {
+	// Interceptor is the handler that intercepted forwards are sent to.
+	Interceptor ForwardInterceptor
+
+	// Priority determines the order in which interceptors see a forward.
+	// Interceptors with a lower priority see it first. Interceptors with
+	// equal priority are ordered by registration.
+	Priority uint32
+
+	// Filter selects the forwards that are offered to the interceptor.
+	Filter InterceptFilter
+
+	// Timeout is the time that the interceptor has to resolve an offered
+	// forward. A zero value disables the timeout.
+	Timeout time.Duration
+
+	// TimeoutAction is applied to a forward when the timeout expires. It
+	// must be FwdActionResume, which passes the forward on to the next
+	// interceptor in the chain, or FwdActionFail.
+	TimeoutAction FwdAction
+}
+
+// validate checks that the registration is usable.
+func (r *InterceptorRegistration) validate() error {
+	if r.Interceptor == nil {
+		return errors.New("interceptor not set")
+	}
+
+	switch r.TimeoutAction {
+	case FwdActionResume, FwdActionFail:
+
+	default:
+		return fmt.Errorf("unsupported timeout action %v",
+			r.TimeoutAction)
+	}
+
+	if r.Filter.MaxAmount != 0 && r.Filter.MinAmount > r.Filter.MaxAmount {
+		return fmt.Errorf("min amount %v exceeds max amount %v",
+			r.Filter.MinAmount, r.Filter.MaxAmount)
+	}
+
+	return nil
+}
+
+// chainPosition is the position of an interceptor in the chain. The zero
+// value is the start of the chain, before any registered interceptor.
+type chainPosition struct {
+	priority uint32
+	id       InterceptorID
+}
+
+// before returns true if p comes before other in the chain.
+func (p chainPosition) before(other chainPosition) bool {
+	if p.priority != other.priority {
+		return p.priority < other.priority
+	}
+
+	return p.id < other.id
+}
+
+// chainInterceptor is an interceptor that is registered in the chain.
+type chainInterceptor struct {
+	id  InterceptorID
+	reg InterceptorRegistration
+}
+
+// position returns the position of the interceptor in the chain.
+func (c *chainInterceptor) position() chainPosition {
+	return chainPosition{
+		priority: c.reg.Priority,
+		id:       c.id,
+	}
+}
+
+// interceptTimeout signals that an interceptor didn't resolve an offered
+// forward in time.
+type interceptTimeout struct {
+	key    models.CircuitKey
+	holder InterceptorID
+	offer  uint64
+	action FwdAction
+}
+
+// AddInterceptor registers an interceptor in the chain and returns its id.
+// Forwards that wait for an interceptor to register are offered to the chain
+// right away.
+func (s *InterceptableSwitch) AddInterceptor(
+	reg InterceptorRegistration) (InterceptorID, error) {
+
+	if err := reg.validate(); err != nil {
+		return 0, err
+	}
+
+	var id InterceptorID
+	err := s.updateChain(func() error {
+		id = s.addInterceptor(reg)
+
+		return nil
+	})
+
+	return id, err
+}
+
+// UpdateInterceptor replaces the registration of an interceptor. Forwards
+// that are currently offered to the interceptor remain held by it.
+func (s *InterceptableSwitch) UpdateInterceptor(id InterceptorID,
+	reg InterceptorRegistration) error {
+
+	if err := reg.validate(); err != nil {
+		return err
+	}
+
+	return s.updateChain(func() error {
+		return s.updateInterceptor(id, reg)
+	})
+}
+
+// RemoveInterceptor removes an interceptor from the chain. The forwards that
+// it holds are passed on to the next interceptor, unless an interceptor is
+// required. In that case they are held until an interceptor registers.
+func (s *InterceptableSwitch) RemoveInterceptor(id InterceptorID) {
+	_ = s.updateChain(func() error {
+		s.removeInterceptor(id)
+
+		return nil
+	})
+}
+
+// updateChain executes the given update on the main loop to prevent race
+// conditions.
+func (s *InterceptableSwitch) updateChain(update func() error) error {
+	errChan := make(chan error, 1)
+	apply := func() {
+		errChan <- update()
+	}
+
+	select {
+	case s.chainUpdates <- apply:
+
+	case <-s.quit:
+		return errors.New("interceptable switch quit")
+	}
+
+	select {
+	case err := <-errChan:
+		return err
+
+	case <-s.quit:
+		return errors.New("interceptable switch quit")
+	}
+}
+
+// addInterceptor inserts an interceptor into the chain and offers the waiting
+// forwards to the chain.
+func (s *InterceptableSwitch) addInterceptor(
+	reg InterceptorRegistration) InterceptorID {
+
+	s.nextInterceptorID++
+	id := s.nextInterceptorID
+
+	s.interceptors = append(s.interceptors, &chainInterceptor{
+		id:  id,
+		reg: reg,
+	})
+	s.sortInterceptors()
+
+	log.Debugf("Interceptor %v registered: priority=%v, timeout=%v", id,
+		reg.Priority, reg.Timeout)
+
+	// Offer the forwards that were kept while no interceptor was
+	// registered from the start of the chain.
+	for key, held := range s.heldHtlcSet.heldBy(0) {
+		held.pos = chainPosition{}
+		if err := s.advance(key, held); err != nil {
+			log.Errorf("Failed to resume hold forward %v", err)
+		}
+	}
+
+	return id
+}
+
+// updateInterceptor replaces the registration of an interceptor.
+func (s *InterceptableSwitch) updateInterceptor(id InterceptorID,
+	reg InterceptorRegistration) error {
+
+	interceptor := s.findInterceptor(id)
+	if interceptor == nil {
+		return ErrInterceptorNotFound
+	}
+
+	interceptor.reg = reg
+	s.sortInterceptors()
+
+	// Forwards held by the interceptor continue down the chain from its
+	// new position.
+	for _, held := range s.heldHtlcSet.heldBy(id) {
+		held.pos = interceptor.position()
+	}
+
+	log.Debugf("Interceptor %v updated: priority=%v, timeout=%v", id,
+		reg.Priority, reg.Timeout)
+
+	return nil
+}
+
+// removeInterceptor removes an interceptor from the chain and releases the
+// forwards that it holds.
+func (s *InterceptableSwitch) removeInterceptor(id InterceptorID) {
+	for i, interceptor := range s.interceptors {
+		if interceptor.id != id {
+			continue
+		}
+
+		s.interceptors = append(
+			s.interceptors[:i], s.interceptors[i+1:]...,
+		)
+
+		break
+	}
+
+	held := s.heldHtlcSet.heldBy(id)
+	if len(held) == 0 {
+		log.Debugf("Interceptor %v removed", id)
+
+		return
+	}
+
+	// If an interceptor is required, keep the held htlcs until an
+	// interceptor registers again.
+	if s.requireInterceptor {
+		log.Infof("Interceptor %v removed, retaining %v held packets",
+			id, len(held))
+
+		for _, h := range held {
+			h.stopTimeout()
+			h.holder = 0
+		}
+
+		return
+	}
+
+	// Interceptor is not required. Pass the held forwards on to the next
+	// interceptor in the chain.
+	log.Infof("Interceptor %v removed, resolving %v held packets", id,
+		len(held))
+
+	for key, h := range held {
+		if err := s.advance(key, h); err != nil {
+			log.Errorf("Failed to resume hold forward %v", err)
+		}
+	}
+}
+
+// sortInterceptors orders the chain by priority and registration.
+func (s *InterceptableSwitch) sortInterceptors() {
+	sort.Slice(s.interceptors, func(i, j int) bool {
+		return s.interceptors[i].position().before(
+			s.interceptors[j].position(),
+		)
+	})
+}
+
+// findInterceptor returns the registered interceptor with the given id or nil
+// if there is none.
+func (s *InterceptableSwitch) findInterceptor(
+	id InterceptorID) *chainInterceptor {
+
+	for _, interceptor := range s.interceptors {
+		if interceptor.id == id {
+			return interceptor
+		}
+	}
+
+	return nil
+}
+
+// nextInterceptor returns the first interceptor after the given position whose
+// filter matches the packet or nil if there is none.
+func (s *InterceptableSwitch) nextInterceptor(packet InterceptedPacket,
+	pos chainPosition) *chainInterceptor {
+
+	for _, interceptor := range s.interceptors {
+		if !pos.before(interceptor.position()) {
+			continue
+		}
+
+		if interceptor.reg.Filter.matches(packet) {
+			return interceptor
+		}
+	}
+
+	return nil
+}
+
+// offer hands a held forward to the given interceptor and arms the timeout of
+// the interceptor.
+func (s *InterceptableSwitch) offer(key models.CircuitKey, held *heldHtlc,
+	interceptor *chainInterceptor) {
+
+	held.stopTimeout()
+	held.holder = interceptor.id
+	held.pos = interceptor.position()
+	held.offers++
+
+	if interceptor.reg.Timeout > 0 {
+		timeout := &interceptTimeout{
+			key:    key,
+			holder: interceptor.id,
+			offer:  held.offers,
+			action: interceptor.reg.TimeoutAction,
+		}
+
+		held.timeout = time.AfterFunc(interceptor.reg.Timeout, func() {
+			select {
+			case s.timeouts <- timeout:
+
+			case <-s.quit:
+			}
+		})
+	}
+
+	s.sendForward(interceptor, held.fwd)
+}
+
+// sendForward sends the forward to the interceptor.
+func (s *InterceptableSwitch) sendForward(interceptor *chainInterceptor,
+	fwd InterceptedForward) {
+
+	err := interceptor.reg.Interceptor(fwd.Packet())
+	if err != nil {
+		// Only log the error. If we couldn't send the packet, we assume
+		// that the interceptor will reconnect so that we can retry.
+		log.Debugf("Interceptor %v cannot handle forward: %v",
+			interceptor.id, err)
+	}
+}
+
+// advance offers a held forward to the next matching interceptor in the chain.
+// If there is none, the forward is released to the switch.
+func (s *InterceptableSwitch) advance(key models.CircuitKey,
+	held *heldHtlc) error {
+
+	next := s.nextInterceptor(held.fwd.Packet(), held.pos)
+	if next != nil {
+		s.offer(key, held, next)
+
+		return nil
+	}
+
+	fwd, err := s.heldHtlcSet.pop(key)
+	if err != nil {
+		return err
+	}
+
+	return fwd.Resume()
+}
+
+// handleTimeout applies the timeout action of an interceptor that didn't
+// resolve a forward in time.
+func (s *InterceptableSwitch) handleTimeout(timeout *interceptTimeout) {
+	held, err := s.heldHtlcSet.get(timeout.key)
+	if err != nil {
+		return
+	}
+
+	// Ignore timeouts of offers that have been resolved in the meantime.
+	if held.holder != timeout.holder || held.offers != timeout.offer {
+		return
+	}
+	held.timeout = nil
+
+	log.Debugf("Interceptor %v timed out on forward %v", timeout.holder,
+		timeout.key)
+
+	if timeout.action == FwdActionFail {
+		fwd, err := s.heldHtlcSet.pop(timeout.key)
+		if err != nil {
+			return
+		}
+
+		err = fwd.FailWithCode(lnwire.CodeTemporaryChannelFailure)
+		if err != nil {
+			log.Errorf("Cannot fail packet: %v", err)
+		}
+
+		return
+	}
+
+	if err := s.advance(timeout.key, held); err != nil {
+		log.Errorf("Failed to resume hold forward %v", err)
+	}
+}
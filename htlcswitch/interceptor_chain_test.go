@@ -0,0 +1,117 @@
+package htlcswitch
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInterceptFilter tests that the filter of an interceptor selects the
+// expected packets.
+func TestInterceptFilter(t *testing.T) {
+	t.Parallel()
+
+	chanID := lnwire.NewShortChanIDFromInt(1)
+	packet := InterceptedPacket{
+		IncomingCircuit: models.CircuitKey{
+			ChanID: chanID,
+		},
+		IncomingAmount: 1000,
+		CustomRecords: map[uint64][]byte{
+			lnwire.MinCustomRecordsTlvType: {1},
+		},
+		InWireCustomRecords: lnwire.CustomRecords{
+			lnwire.MinCustomRecordsTlvType + 1: {2},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		filter  InterceptFilter
+		matches bool
+	}{
+		{
+			name:    "empty filter",
+			matches: true,
+		},
+		{
+			name: "matching channel",
+			filter: InterceptFilter{
+				IncomingChanIDs: []lnwire.ShortChannelID{
+					lnwire.NewShortChanIDFromInt(2), chanID,
+				},
+			},
+			matches: true,
+		},
+		{
+			name: "other channel",
+			filter: InterceptFilter{
+				IncomingChanIDs: []lnwire.ShortChannelID{
+					lnwire.NewShortChanIDFromInt(2),
+				},
+			},
+		},
+		{
+			name: "amount in range",
+			filter: InterceptFilter{
+				MinAmount: 1000,
+				MaxAmount: 1000,
+			},
+			matches: true,
+		},
+		{
+			name: "amount below minimum",
+			filter: InterceptFilter{
+				MinAmount: 1001,
+			},
+		},
+		{
+			name: "amount above maximum",
+			filter: InterceptFilter{
+				MaxAmount: 999,
+			},
+		},
+		{
+			name: "payload and wire records",
+			filter: InterceptFilter{
+				CustomRecordTypes: []uint64{
+					lnwire.MinCustomRecordsTlvType,
+					lnwire.MinCustomRecordsTlvType + 1,
+				},
+			},
+			matches: true,
+		},
+		{
+			name: "missing record",
+			filter: InterceptFilter{
+				CustomRecordTypes: []uint64{
+					lnwire.MinCustomRecordsTlvType + 2,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.matches, tc.filter.matches(packet))
+		})
+	}
+}
+
+// TestChainPosition tests the ordering of interceptors in the chain.
+func TestChainPosition(t *testing.T) {
+	t.Parallel()
+
+	start := chainPosition{}
+	low := chainPosition{priority: 0, id: 2}
+	lowLater := chainPosition{priority: 0, id: 3}
+	high := chainPosition{priority: 1, id: 1}
+
+	require.True(t, start.before(low))
+	require.True(t, low.before(lowLater))
+	require.True(t, lowLater.before(high))
+	require.False(t, high.before(low))
+	require.False(t, low.before(low))
+}
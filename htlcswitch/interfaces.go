@@ -45,6 +45,20 @@ type InvoiceDatabase interface {
 	HodlUnsubscribeAll(subscriber chan<- interface{})
 }
 
+// TrampolineForwarder is an interface to the sub-system that forwards the
+// payments that are delivered to us as a trampoline node.
+type TrampolineForwarder interface {
+	// NotifyTrampolineHtlc hands an incoming htlc that carries a
+	// trampoline onion to the forwarder. The return value describes how
+	// the htlc should be resolved. If the htlc cannot be resolved
+	// immediately, the resolution is sent on the passed in hodlChan later.
+	NotifyTrampolineHtlc(htlc *TrampolineHtlc,
+		hodlChan chan<- interface{}) invoices.HtlcResolution
+
+	// HodlUnsubscribeAll unsubscribes from all htlc resolutions.
+	HodlUnsubscribeAll(subscriber chan<- interface{})
+}
+
 // packetHandler is an interface used exclusively by the Switch to handle
 // htlcPacket and pass them to the link implementation.
 type packetHandler interface {
@@ -141,6 +155,11 @@ type ChannelUpdateHandler interface {
 	// parameter.
 	MayAddOutgoingHtlc(lnwire.MilliSatoshi) error
 
+	// AvailableOutgoingHtlcSlots returns the number of outgoing htlcs that
+	// may still be added to the channel before it reaches its maximum
+	// number of htlcs in flight.
+	AvailableOutgoingHtlcSlots() (uint16, error)
+
 	// EnableAdds sets the ChannelUpdateHandler state to allow
 	// UpdateAddHtlc's in the specified direction. It returns true if the
 	// state was changed and false if the desired state was already set
@@ -168,6 +187,19 @@ type ChannelUpdateHandler interface {
 	// will only ever be called once. If no CommitSig is owed in the
 	// argument's LinkDirection, then we will call this hook immediately.
 	OnCommitOnce(LinkDirection, func())
+
+	// InitStfu starts quiescing the channel. The returned channel is sent
+	// the party that initiated quiescence once the channel is quiescent,
+	// or an error if the channel can't be quiesced.
+	InitStfu() <-chan fn.Result[lntypes.ChannelParty]
+
+	// QuiescenceInitiator returns the party that initiated quiescence, or
+	// an error if the channel isn't quiescent.
+	QuiescenceInitiator() fn.Result[lntypes.ChannelParty]
+
+	// ResumeUpdates ends quiescence of the channel, allowing both parties
+	// to send updates again.
+	ResumeUpdates()
 }
 
 // CommitHookID is a value that is used to uniquely identify hooks in the
@@ -266,6 +298,10 @@ type ChannelLink interface {
 	CheckHtlcTransit(payHash [32]byte, amt lnwire.MilliSatoshi,
 		timeout uint32, heightNow uint32) *LinkError
 
+	// getOutgoingLimits returns the maximum number of htlcs and the
+	// maximum value in flight that we may offer to the remote peer.
+	getOutgoingLimits() ChannelLimits
+
 	// Stats return the statistics of channel link. Number of updates,
 	// total sent/received milli-satoshis.
 	Stats() (uint64, lnwire.MilliSatoshi, lnwire.MilliSatoshi)
@@ -318,6 +354,16 @@ type InterceptableHtlcForwarder interface {
 	// SetInterceptor sets a ForwardInterceptor.
 	SetInterceptor(interceptor ForwardInterceptor)
 
+	// AddInterceptor registers an interceptor in the interceptor chain
+	// and returns its id.
+	AddInterceptor(reg InterceptorRegistration) (InterceptorID, error)
+
+	// UpdateInterceptor replaces the registration of an interceptor.
+	UpdateInterceptor(id InterceptorID, reg InterceptorRegistration) error
+
+	// RemoveInterceptor removes an interceptor from the chain.
+	RemoveInterceptor(id InterceptorID)
+
 	// Resolve resolves an intercepted packet.
 	Resolve(res *FwdResolution) error
 }
@@ -361,6 +407,10 @@ type InterceptedPacket struct {
 	// were included in the payload.
 	CustomRecords record.CustomSet
 
+	// InWireCustomRecords are the custom records that were attached to
+	// the incoming update_add_htlc message.
+	InWireCustomRecords lnwire.CustomRecords
+
 	// OnionBlob is the onion packet for the next hop
 	OnionBlob [lnwire.OnionPacketSize]byte
 
@@ -373,7 +423,7 @@ type InterceptedPacket struct {
 // htlc. It contains all the information about the packet which accordingly
 // the interceptor decides if to hold or not.
 // In addition this interface allows a later resolution by calling either
-// Resume, Settle or Fail.
+// Resume, ResumeModified, Settle or Fail.
 type InterceptedForward interface {
 	// Packet returns the intercepted packet.
 	Packet() InterceptedPacket
@@ -383,6 +433,13 @@ type InterceptedForward interface {
 	// this htlc which usually means forward it.
 	Resume() error
 
+	// ResumeModified notifies the intention to resume an existing hold
+	// forward with a modified outgoing channel, amount or set of custom
+	// records. Values that are not set are left unchanged.
+	ResumeModified(outgoingChanID fn.Option[lnwire.ShortChannelID],
+		outAmountMsat fn.Option[lnwire.MilliSatoshi],
+		outWireCustomRecords fn.Option[lnwire.CustomRecords]) error
+
 	// Settle notifies the intention to settle an existing hold
 	// forward with a given preimage.
 	Settle(lntypes.Preimage) error
@@ -30,6 +30,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/queue"
+	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/ticker"
 )
 
@@ -280,9 +281,24 @@ type ChannelLinkConfig struct {
 	// invalid.
 	DisallowRouteBlinding bool
 
+	// TrampolineForwarder forwards the payments that are delivered to us
+	// as a trampoline node. If it is nil, any htlcs that carry a
+	// trampoline onion are failed back as if their payload was invalid.
+	TrampolineForwarder TrampolineForwarder
+
 	// MaxFeeExposure is the threshold in milli-satoshis after which we'll
 	// restrict the flow of HTLCs and fee updates.
 	MaxFeeExposure lnwire.MilliSatoshi
+
+	// DisallowQuiescence is a flag that can be used to disable the
+	// quiescence protocol, which is the case if the remote party doesn't
+	// support it.
+	DisallowQuiescence bool
+
+	// RateLimiter, if set, limits the rate at which the peer may add htlcs
+	// and the value that it may have in flight with us. Htlcs exceeding
+	// the limits are failed back.
+	RateLimiter *HtlcRateLimiter
 }
 
 // channelLink is the service which drives a channel's commitment update
@@ -362,6 +378,11 @@ type channelLink struct {
 	// resolving those htlcs when we receive a message on hodlQueue.
 	hodlMap map[models.CircuitKey]hodlHtlc
 
+	// rateLimitedAdds holds the decisions of the rate limiter on the adds
+	// that it rejected when we received them, by htlc index. The adds are
+	// failed back once they are locked in.
+	rateLimitedAdds map[uint64]RateLimitDecision
+
 	// log is a link-specific logging instance.
 	log btclog.Logger
 
@@ -385,6 +406,16 @@ type channelLink struct {
 	// our next CommitSig.
 	incomingCommitHooks hookMap
 
+	// quiescer tracks whether the channel is quiescent, during which no
+	// updates may be sent by either party.
+	quiescer *quiescer
+
+	// quiescenceReqs is used to request quiescence of the channel.
+	quiescenceReqs chan StfuReq
+
+	// resumeReqs is used to end quiescence of the channel.
+	resumeReqs chan struct{}
+
 	wg   sync.WaitGroup
 	quit chan struct{}
 }
@@ -457,17 +488,36 @@ func NewChannelLink(cfg ChannelLinkConfig,
 		cfg.MaxFeeExposure = DefaultMaxFeeExposure
 	}
 
-	return &channelLink{
+	l := &channelLink{
 		cfg:                 cfg,
 		channel:             channel,
 		hodlMap:             make(map[models.CircuitKey]hodlHtlc),
+		rateLimitedAdds:     make(map[uint64]RateLimitDecision),
 		hodlQueue:           queue.NewConcurrentQueue(10),
 		log:                 build.NewPrefixLog(logPrefix, log),
 		flushHooks:          newHookMap(),
 		outgoingCommitHooks: newHookMap(),
 		incomingCommitHooks: newHookMap(),
+		quiescenceReqs:      make(chan StfuReq),
+		resumeReqs:          make(chan struct{}),
 		quit:                make(chan struct{}),
 	}
+
+	channelInitiator := lntypes.Remote
+	if channel.IsInitiator() {
+		channelInitiator = lntypes.Local
+	}
+
+	chanID := lnwire.NewChanIDFromOutPoint(channel.ChannelPoint())
+	l.quiescer = newQuiescer(QuiescerCfg{
+		chanID:           chanID,
+		channelInitiator: channelInitiator,
+		sendMsg: func(msg lnwire.Stfu) error {
+			return l.cfg.Peer.SendMessage(false, &msg)
+		},
+	})
+
+	return l
 }
 
 // A compile time check to ensure channelLink implements the ChannelLink
@@ -543,6 +593,21 @@ func (l *channelLink) Start() error {
 		}()
 	}
 
+	// The incoming htlcs that are already on the channel count towards the
+	// in-flight value of the peer.
+	if l.cfg.RateLimiter != nil {
+		htlcs := make(map[uint64]lnwire.MilliSatoshi)
+		for _, htlc := range l.channel.ActiveHtlcs() {
+			if htlc.Incoming {
+				htlcs[htlc.HtlcIndex] = htlc.Amt
+			}
+		}
+
+		l.cfg.RateLimiter.AddChannel(
+			l.cfg.Peer.PubKey(), l.channel.ChannelPoint(), htlcs,
+		)
+	}
+
 	l.updateFeeTimer = time.NewTimer(l.randomFeeUpdateTimeout())
 
 	l.wg.Add(1)
@@ -566,6 +631,11 @@ func (l *channelLink) Stop() {
 	// As the link is stopping, we are no longer interested in htlc
 	// resolutions coming from the invoice registry.
 	l.cfg.Registry.HodlUnsubscribeAll(l.hodlQueue.ChanIn())
+	if l.cfg.TrampolineForwarder != nil {
+		l.cfg.TrampolineForwarder.HodlUnsubscribeAll(
+			l.hodlQueue.ChanIn(),
+		)
+	}
 
 	if l.cfg.ChainEvents.Cancel != nil {
 		l.cfg.ChainEvents.Cancel()
@@ -588,6 +658,10 @@ func (l *channelLink) Stop() {
 	close(l.quit)
 	l.wg.Wait()
 
+	if l.cfg.RateLimiter != nil {
+		l.cfg.RateLimiter.RemoveChannel(l.channel.ChannelPoint())
+	}
+
 	// Now that the htlcManager has completely exited, reset the packet
 	// courier. This allows the mailbox to revaluate any lingering Adds that
 	// were delivered but didn't make it on a commitment to be failed back
@@ -695,6 +769,68 @@ func (l *channelLink) OnCommitOnce(direction LinkDirection, hook func()) {
 	}
 }
 
+// InitStfu starts quiescing the channel. The returned channel is sent the
+// party that initiated quiescence once the channel is quiescent, or an error
+// if the channel can't be quiesced.
+//
+// NOTE: Part of the ChannelUpdateHandler interface.
+func (l *channelLink) InitStfu() <-chan fn.Result[lntypes.ChannelParty] {
+	req, out := fn.NewReq[fn.Unit, fn.Result[lntypes.ChannelParty]](
+		fn.Unit{},
+	)
+
+	if l.cfg.DisallowQuiescence {
+		req.Resolve(fn.Err[lntypes.ChannelParty](
+			ErrQuiescenceNotSupported,
+		))
+
+		return out
+	}
+
+	select {
+	case l.quiescenceReqs <- req:
+	case <-l.quit:
+		req.Resolve(fn.Err[lntypes.ChannelParty](ErrLinkShuttingDown))
+	}
+
+	return out
+}
+
+// QuiescenceInitiator returns the party that initiated quiescence, or an
+// error if the channel isn't quiescent.
+//
+// NOTE: Part of the ChannelUpdateHandler interface.
+func (l *channelLink) QuiescenceInitiator() fn.Result[lntypes.ChannelParty] {
+	return l.quiescer.QuiescenceInitiator()
+}
+
+// ResumeUpdates ends quiescence of the channel, allowing both parties to send
+// updates again.
+//
+// NOTE: Part of the ChannelUpdateHandler interface.
+func (l *channelLink) ResumeUpdates() {
+	select {
+	case l.resumeReqs <- struct{}{}:
+	case <-l.quit:
+	}
+}
+
+// sendOwedStfu sends stfu to the remote party if we owe one and all of our
+// updates are committed.
+func (l *channelLink) sendOwedStfu() {
+	if !l.quiescer.OweStfu() {
+		return
+	}
+
+	err := l.quiescer.SendOwedStfu(
+		l.channel.NumPendingUpdates(lntypes.Local),
+	)
+	if err != nil {
+		l.failf(LinkFailureError{code: ErrInternalError},
+			"unable to send stfu: %v", err)
+	}
+}
+
 // isReestablished returns true if the link has successfully completed the
 // channel reestablishment dance.
 func (l *channelLink) isReestablished() bool {
@@ -1227,6 +1363,16 @@ func (l *channelLink) htlcManager() {
 		}
 	})
 
+	// A dynamic commitment upgrade that was applied while the link was
+	// down, or while the channel was reestablished because the dyn_ack of
+	// our proposal was lost, requires a new commitment even if there are
+	// no updates to sign.
+	if l.channel.OweDynCommitment() {
+		if !l.updateCommitTxOrFail() {
+			return
+		}
+	}
+
 	// We've successfully reestablished the channel, mark it as such to
 	// allow the switch to forward HTLCs in the outbound direction.
 	l.markReestablished()
@@ -1280,6 +1426,10 @@ func (l *channelLink) htlcManager() {
 	}
 
 	for {
+		// If quiescence was requested, we'll send stfu as soon as all
+		// of our updates are committed.
+		l.sendOwedStfu()
+
 		// We must always check if we failed at some point processing
 		// the last update before processing the next.
 		if l.failed {
@@ -1287,6 +1437,17 @@ func (l *channelLink) htlcManager() {
 			return
 		}
 
+		// While quiescing, we may not send any updates, so we don't
+		// read any packets from the switch or resolutions of HTLCs.
+		var (
+			downstream <-chan *htlcPacket
+			hodlQueue  <-chan interface{}
+		)
+		if l.quiescer.CanSendUpdates() {
+			downstream = l.downstream
+			hodlQueue = l.hodlQueue.ChanOut()
+		}
+
 		// If the previous event resulted in a non-empty batch, resume
 		// the batch ticker so that it can be cleared. Otherwise pause
 		// the ticker to prevent waking up the htlcManager while the
@@ -1336,8 +1497,11 @@ func (l *channelLink) htlcManager() {
 			l.updateFeeTimer.Reset(l.randomFeeUpdateTimeout())
 
 			// If we're not the initiator of the channel, don't we
-			// don't control the fees, so we can ignore this.
-			if !l.channel.IsInitiator() {
+			// don't control the fees, so we can ignore this. We
+			// also can't update the fee while quiescing.
+			if !l.channel.IsInitiator() ||
+				!l.quiescer.CanSendUpdates() {
+
 				continue
 			}
 
@@ -1418,7 +1582,7 @@ func (l *channelLink) htlcManager() {
 		// A message from the switch was just received. This indicates
 		// that the link is an intermediate hop in a multi-hop HTLC
 		// circuit.
-		case pkt := <-l.downstream:
+		case pkt := <-downstream:
 			l.handleDownstreamPkt(pkt)
 
 		// A message from the connected peer was just received. This
@@ -1429,7 +1593,7 @@ func (l *channelLink) htlcManager() {
 
 		// A htlc resolution is received. This means that we now have a
 		// resolution for a previously accepted htlc.
-		case hodlItem := <-l.hodlQueue.ChanOut():
+		case hodlItem := <-hodlQueue:
 			htlcResolution := hodlItem.(invoices.HtlcResolution)
 			err := l.processHodlQueue(htlcResolution)
 			switch err {
@@ -1455,6 +1619,25 @@ func (l *channelLink) htlcManager() {
 				)
 			}
 
+		// A request to quiesce the channel was received. We'll send
+		// stfu once all of our updates are committed.
+		case req := <-l.quiescenceReqs:
+			l.quiescer.InitStfu(req)
+
+		// Quiescence ended, so both parties may send updates again.
+		case <-l.resumeReqs:
+			l.log.Debugf("Resuming channel updates")
+			l.quiescer.Resume()
+
+			// A dynamic commitment upgrade applied while the
+			// channel was quiescent requires a new commitment,
+			// even if there are no updates to sign.
+			if l.channel.OweCommitment() {
+				if !l.updateCommitTxOrFail() {
+					return
+				}
+			}
+
 		case <-l.quit:
 			return
 		}
@@ -1537,6 +1720,18 @@ func (l *channelLink) processHtlcResolution(resolution invoices.HtlcResolution,
 		)
 		return nil
 
+	// Trampoline htlcs are failed with the failure that the trampoline
+	// forwarder picked for them.
+	case *TrampolineFailResolution:
+		l.log.Debugf("received trampoline failure for %v: %v",
+			circuitKey, res.Failure)
+
+		l.sendHTLCError(
+			htlc.pd, NewLinkError(res.Failure), htlc.obfuscator,
+			true,
+		)
+		return nil
+
 	// Fail if we do not get a settle of fail resolution, since we
 	// are only expecting to handle settles and fails.
 	default:
@@ -1552,16 +1747,28 @@ func getResolutionFailure(resolution *invoices.HtlcFailResolution,
 
 	// If the resolution has been resolved as part of a MPP timeout,
 	// we need to fail the htlc with lnwire.FailMppTimeout.
-	if resolution.Outcome == invoices.ResultMppTimeout {
+	switch resolution.Outcome {
+	case invoices.ResultMppTimeout:
 		return NewDetailedLinkError(
 			&lnwire.FailMPPTimeout{}, resolution.Outcome,
 		)
+
+	// The htlc acceptor may reject htlcs with a node failure.
+	case invoices.ResultHtlcAcceptorTemporaryFailure:
+		return NewDetailedLinkError(
+			&lnwire.FailTemporaryNodeFailure{}, resolution.Outcome,
+		)
+
+	case invoices.ResultHtlcAcceptorPermanentFailure:
+		return NewDetailedLinkError(
+			&lnwire.FailPermanentNodeFailure{}, resolution.Outcome,
+		)
 	}
 
-	// If the htlc is not a MPP timeout, we fail it with
-	// FailIncorrectDetails. This error is sent for invoice payment
-	// failures such as underpayment/ expiry too soon and hodl invoices
-	// (which return FailIncorrectDetails to avoid leaking information).
+	// Otherwise, we fail it with FailIncorrectDetails. This error is sent
+	// for invoice payment failures such as underpayment/ expiry too soon
+	// and hodl invoices (which return FailIncorrectDetails to avoid leaking
+	// information).
 	incorrectDetails := lnwire.NewFailIncorrectDetails(
 		amount, uint32(resolution.AcceptHeight),
 	)
@@ -1938,6 +2145,19 @@ func (l *channelLink) cleanupSpuriousResponse(pkt *htlcPacket) {
 // updates from the upstream peer. The upstream peer is the peer whom we have a
 // direct channel with, updating our respective commitment chains.
 func (l *channelLink) handleUpstreamMsg(msg lnwire.Message) {
+	// The remote party may not send any updates once it sent stfu.
+	switch msg.(type) {
+	case *lnwire.UpdateAddHTLC, *lnwire.UpdateFulfillHTLC,
+		*lnwire.UpdateFailMalformedHTLC, *lnwire.UpdateFailHTLC,
+		*lnwire.UpdateFee:
+
+		if !l.quiescer.CanRecvUpdates() {
+			l.failf(LinkFailureError{code: ErrInvalidUpdate},
+				"update received after stfu: %T", msg)
+			return
+		}
+	}
+
 	switch msg := msg.(type) {
 
 	case *lnwire.UpdateAddHTLC:
@@ -2009,6 +2229,12 @@ func (l *channelLink) handleUpstreamMsg(msg lnwire.Message) {
 			return
 		}
 
+		// Charge the add against the rate limits of its peer and
+		// channel as soon as we receive it. The protocol doesn't allow
+		// us to refuse an add, so a rejected add is still locked in,
+		// but it is failed back without being forwarded or settled.
+		l.chargeRateLimits(index, msg.Amount)
+
 		l.log.Tracef("receive upstream htlc with payment hash(%x), "+
 			"assigning index: %v", msg.PaymentHash[:], index)
 
@@ -2207,6 +2433,7 @@ func (l *channelLink) handleUpstreamMsg(msg lnwire.Message) {
 			CommitSig:  msg.CommitSig,
 			HtlcSigs:   msg.HtlcSigs,
 			PartialSig: msg.PartialSig,
+			SpliceSigs: msg.SpliceSigs.ValOpt().UnwrapOr(nil),
 		})
 		if err != nil {
 			// If we were unable to reconstruct their proposed
@@ -2273,6 +2500,12 @@ func (l *channelLink) handleUpstreamMsg(msg lnwire.Message) {
 		// Notify the incoming htlcs of which the resolutions were
 		// locked in.
 		for id, settled := range finalHTLCs {
+			if l.cfg.RateLimiter != nil {
+				l.cfg.RateLimiter.ResolveHtlc(
+					l.channel.ChannelPoint(), id,
+				)
+			}
+
 			l.cfg.HtlcNotifier.NotifyFinalHtlcEvent(
 				models.CircuitKey{
 					ChanID: l.ShortChanID(),
@@ -2451,6 +2684,21 @@ func (l *channelLink) handleUpstreamMsg(msg lnwire.Message) {
 		// Update the mailbox's feerate as well.
 		l.mailBox.SetFeeRate(fee)
 
+	case *lnwire.Stfu:
+		if l.cfg.DisallowQuiescence {
+			l.log.Warnf("Ignoring stfu, quiescence not supported")
+			return
+		}
+
+		err := l.quiescer.RecvStfu(
+			*msg, l.channel.NumPendingUpdates(lntypes.Remote),
+		)
+		if err != nil {
+			l.failf(LinkFailureError{code: ErrInvalidUpdate},
+				"unable to handle stfu: %v", err)
+			return
+		}
+
 	// In the case where we receive a warning message from our peer, just
 	// log it and move on. We choose not to disconnect from our peer,
 	// although we "MAY" do so according to the specification.
@@ -2639,6 +2887,7 @@ func (l *channelLink) updateCommitTx() error {
 		CommitSig:  newCommit.CommitSig,
 		HtlcSigs:   newCommit.HtlcSigs,
 		PartialSig: newCommit.PartialSig,
+		SpliceSigs: lnwire.MaybeSpliceCommitSigs(newCommit.SpliceSigs),
 	}
 	l.cfg.Peer.SendMessage(false, commitSig)
 
@@ -2728,6 +2977,15 @@ func (l *channelLink) MayAddOutgoingHtlc(amt lnwire.MilliSatoshi) error {
 	return l.channel.MayAddOutgoingHtlc(amt)
 }
 
+// AvailableOutgoingHtlcSlots returns the number of outgoing htlcs that may
+// still be added to the channel before it reaches its maximum number of htlcs
+// in flight.
+//
+// NOTE: Part of the ChannelUpdateHandler interface.
+func (l *channelLink) AvailableOutgoingHtlcSlots() (uint16, error) {
+	return l.channel.AvailableOutgoingHtlcSlots()
+}
+
 // getDustSum is a wrapper method that calls the underlying channel's dust sum
 // method.
 //
@@ -2738,6 +2996,19 @@ func (l *channelLink) getDustSum(whoseCommit lntypes.ChannelParty,
 	return l.channel.GetDustSum(whoseCommit, dryRunFee)
 }
 
+// getOutgoingLimits returns the maximum number of htlcs and the maximum value
+// in flight that the remote peer accepts from us.
+//
+// NOTE: Part of the ChannelLink interface.
+func (l *channelLink) getOutgoingLimits() ChannelLimits {
+	remoteCfg := l.channel.State().RemoteChanCfg
+
+	return ChannelLimits{
+		MaxHtlcs:    remoteCfg.MaxAcceptedHtlcs,
+		MaxInFlight: remoteCfg.MaxPendingAmount,
+	}
+}
+
 // getFeeRate is a wrapper method that retrieves the underlying channel's
 // feerate.
 //
@@ -3462,6 +3733,11 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 			continue
 		}
 
+		// Look up whether the add exceeded the rate limits of its
+		// peer or channel when we received it. This also forgets the
+		// decision if the add is failed back for another reason.
+		rateLimited := l.rateLimited(pd)
+
 		// An incoming HTLC add has been full-locked in. As a result we
 		// can now examine the forwarding details of the HTLC, and the
 		// HTLC itself to decide if: we should forward it, cancel it,
@@ -3594,8 +3870,44 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 			continue
 		}
 
-		switch fwdInfo.NextHop {
-		case hop.Exit:
+		// Fail the htlc back if its peer or channel exceeded its rate
+		// limits when we received it. A temporary channel failure is
+		// only valid for forwards, so we fail the htlc with a temporary
+		// node failure if we are its final hop.
+		if rateLimited {
+			var failure lnwire.FailureMessage = &lnwire.
+				FailTemporaryNodeFailure{}
+			if fwdInfo.NextHop != hop.Exit {
+				failure = lnwire.NewTemporaryChannelFailure(nil)
+			}
+
+			l.sendHTLCError(
+				pd, NewDetailedLinkError(
+					failure, IncomingFailureRateLimited,
+				), obfuscator, fwdInfo.NextHop == hop.Exit,
+			)
+
+			continue
+		}
+
+		switch {
+		// The htlc carries a trampoline onion, so it is held until
+		// the payment is forwarded along a route of our own.
+		case fwdInfo.NextHop == hop.Exit &&
+			pld.TrampolineOnion() != nil:
+
+			err := l.processTrampolineHop(
+				pd, obfuscator, fwdInfo, pld,
+			)
+			if err != nil {
+				l.failf(LinkFailureError{
+					code: ErrInternalError,
+				}, err.Error()) //nolint
+
+				return
+			}
+
+		case fwdInfo.NextHop == hop.Exit:
 			err := l.processExitHop(
 				pd, obfuscator, fwdInfo, heightNow, pld,
 			)
@@ -3664,6 +3976,10 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 					outgoingTimeout: fwdInfo.OutgoingCTLV,
 					customRecords:   pld.CustomRecords(),
 					inboundFee:      inboundFee,
+					incomingEndorsed: isEndorsed(
+						pd.CustomRecords,
+					),
+					inWireCustomRecords: pd.CustomRecords,
 				}
 				switchPackets = append(
 					switchPackets, updatePacket,
@@ -3732,6 +4048,10 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 					outgoingTimeout: fwdInfo.OutgoingCTLV,
 					customRecords:   pld.CustomRecords(),
 					inboundFee:      inboundFee,
+					incomingEndorsed: isEndorsed(
+						pd.CustomRecords,
+					),
+					inWireCustomRecords: pd.CustomRecords,
 				}
 
 				fwdPkg.FwdFilter.Set(idx)
@@ -3769,6 +4089,140 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 	l.forwardBatch(replay, switchPackets...)
 }
 
+// processTrampolineHop handles an htlc for which this link is the exit hop of
+// the outer onion, but which carries a trampoline onion that instructs us to
+// forward the payment. The htlc is handed to the trampoline forwarder, which
+// holds it until all htlcs of the payment have arrived and the payment was
+// forwarded.
+func (l *channelLink) processTrampolineHop(pd *lnwallet.PaymentDescriptor,
+	obfuscator hop.ErrorEncrypter, fwdInfo hop.ForwardingInfo,
+	pld *hop.Payload) error {
+
+	// If hodl.ExitSettle is requested, we will not validate the final hop's
+	// ADD, nor will we forward the payment.
+	if l.cfg.HodlMask.Active(hodl.ExitSettle) {
+		l.log.Warnf(hodl.ExitSettle.Warning())
+
+		return nil
+	}
+
+	// Fail back the htlc as if the payload was invalid if we don't route
+	// trampoline payments, or if it was sent over a blinded route, which
+	// we don't support for trampoline payments.
+	if l.cfg.TrampolineForwarder == nil ||
+		obfuscator.Type() != hop.EncrypterTypeSphinx {
+
+		failure := lnwire.NewInvalidOnionPayload(
+			uint64(record.TrampolineOnionType), 0,
+		)
+		l.sendHTLCError(pd, NewLinkError(failure), obfuscator, true)
+
+		l.log.Errorf("rejected htlc(%x) with trampoline onion",
+			pd.RHash[:])
+
+		return nil
+	}
+
+	// The htlcs of a trampoline payment are grouped by the payment
+	// address of the outer onion, so it must be present.
+	mpp := pld.MultiPath()
+	if mpp == nil {
+		failure := lnwire.NewInvalidOnionPayload(
+			uint64(record.MPPOnionType), 0,
+		)
+		l.sendHTLCError(pd, NewLinkError(failure), obfuscator, true)
+
+		return nil
+	}
+
+	// The outer onion must deliver the amount and expiry that the sender
+	// committed to.
+	if pd.Amount < fwdInfo.AmountToForward {
+		failure := NewLinkError(
+			lnwire.NewFinalIncorrectHtlcAmount(pd.Amount),
+		)
+		l.sendHTLCError(pd, failure, obfuscator, true)
+
+		return nil
+	}
+
+	if pd.Timeout < fwdInfo.OutgoingCTLV {
+		failure := NewLinkError(
+			lnwire.NewFinalIncorrectCltvExpiry(pd.Timeout),
+		)
+		l.sendHTLCError(pd, failure, obfuscator, true)
+
+		return nil
+	}
+
+	// Notify the trampoline forwarder of the htlc. If we crash right
+	// after this, this code will be re-executed after restart. We will
+	// receive back a resolution event.
+	circuitKey := models.CircuitKey{
+		ChanID: l.ShortChanID(),
+		HtlcID: pd.HtlcIndex,
+	}
+
+	event := l.cfg.TrampolineForwarder.NotifyTrampolineHtlc(
+		&TrampolineHtlc{
+			CircuitKey:  circuitKey,
+			PaymentHash: lntypes.Hash(pd.RHash),
+			Amount:      pd.Amount,
+			Expiry:      pd.Timeout,
+			MPP:         mpp,
+			Onion:       pld.TrampolineOnion(),
+		}, l.hodlQueue.ChanIn(),
+	)
+
+	htlc := hodlHtlc{
+		pd:         pd,
+		obfuscator: obfuscator,
+	}
+
+	// If the event is nil, the htlc is held until the payment was
+	// forwarded, so we save the payment descriptor for future reference.
+	if event == nil {
+		l.hodlMap[circuitKey] = htlc
+		return nil
+	}
+
+	return l.processHtlcResolution(event, htlc)
+}
+
+// chargeRateLimits charges an add that we just received against the rate
+// limits of its peer and channel, and remembers the add if it exceeds them.
+func (l *channelLink) chargeRateLimits(htlcIndex uint64,
+	amt lnwire.MilliSatoshi) {
+
+	if l.cfg.RateLimiter == nil {
+		return
+	}
+
+	decision := l.cfg.RateLimiter.AddHtlc(
+		l.channel.ChannelPoint(), htlcIndex, amt,
+	)
+	if decision == RateLimitAllow {
+		return
+	}
+
+	l.log.Debugf("Rate limiting htlc with index %d of %v: %v",
+		htlcIndex, amt, decision)
+
+	l.rateLimitedAdds[htlcIndex] = decision
+}
+
+// rateLimited returns true if the locked in htlc exceeded the rate limits of
+// its peer or channel when we received it, in which case it must be failed
+// back. The decision is forgotten once it was looked up.
+func (l *channelLink) rateLimited(pd *lnwallet.PaymentDescriptor) bool {
+	if _, ok := l.rateLimitedAdds[pd.HtlcIndex]; !ok {
+		return false
+	}
+	delete(l.rateLimitedAdds, pd.HtlcIndex)
+
+	return true
+}
+
 // processExitHop handles an htlc for which this link is the exit hop. It
 // returns a boolean indicating whether the commitment tx needs an update.
 func (l *channelLink) processExitHop(pd *lnwallet.PaymentDescriptor,
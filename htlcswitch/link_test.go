@@ -7502,3 +7502,74 @@ func TestLinkFlushHooksCalled(t *testing.T) {
 	ctx.receiveRevAndAckAliceToBob()
 	assertHookCalled(true)
 }
+
+// TestLinkQuiescence tests that the link quiesces the channel when requested,
+// and responds to stfu messages of the remote party.
+func TestLinkQuiescence(t *testing.T) {
+	t.Parallel()
+
+	harness, err := newSingleLinkTestHarness(
+		t, 5*btcutil.SatoshiPerBitcoin, btcutil.SatoshiPerBitcoin,
+	)
+	require.NoError(t, err)
+	require.NoError(t, harness.start(), "could not start link")
+
+	aliceLink := harness.aliceLink
+
+	//nolint:forcetypeassert
+	aliceMsgs := aliceLink.(*channelLink).cfg.Peer.(*mockPeer).sentMsgs
+
+	receiveStfu := func() *lnwire.Stfu {
+		t.Helper()
+
+		select {
+		case msg := <-aliceMsgs:
+			stfu, ok := msg.(*lnwire.Stfu)
+			require.True(t, ok, "expected stfu, got %T", msg)
+
+			return stfu
+
+		case <-time.After(5 * time.Second):
+			t.Fatalf("did not receive stfu")
+		}
+
+		return nil
+	}
+
+	// Alice initiates quiescence, and sends stfu right away since she
+	// doesn't have any pending updates.
+	respChan := aliceLink.InitStfu()
+	require.True(t, receiveStfu().Initiator)
+	require.True(t, aliceLink.QuiescenceInitiator().IsErr())
+
+	aliceLink.HandleChannelUpdate(&lnwire.Stfu{
+		ChanID: aliceLink.ChanID(),
+	})
+
+	select {
+	case resp := <-respChan:
+		initiator, err := resp.Unpack()
+		require.NoError(t, err)
+		require.Equal(t, lntypes.Local, initiator)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("channel not quiescent")
+	}
+
+	aliceLink.ResumeUpdates()
+	require.Eventually(t, func() bool {
+		return aliceLink.QuiescenceInitiator().IsErr()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Now Bob initiates quiescence, to which Alice responds with her own
+	// stfu.
+	aliceLink.HandleChannelUpdate(&lnwire.Stfu{
+		ChanID:    aliceLink.ChanID(),
+		Initiator: true,
+	})
+	require.False(t, receiveStfu().Initiator)
+
+	initiator, err := aliceLink.QuiescenceInitiator().Unpack()
+	require.NoError(t, err)
+	require.Equal(t, lntypes.Remote, initiator)
+}
@@ -733,6 +733,8 @@ type mockChannelLink struct {
 
 	checkHtlcForwardResult *LinkError
 
+	outgoingLimits ChannelLimits
+
 	failAliasUpdate func(sid lnwire.ShortChannelID,
 		incoming bool) *lnwire.ChannelUpdate
 
@@ -822,6 +824,10 @@ func (f *mockChannelLink) getDustSum(whoseCommit lntypes.ChannelParty,
 	return 0
 }
 
+func (f *mockChannelLink) getOutgoingLimits() ChannelLimits {
+	return f.outgoingLimits
+}
+
 func (f *mockChannelLink) getFeeRate() chainfee.SatPerKWeight {
 	return 0
 }
@@ -923,6 +929,7 @@ func (f *mockChannelLink) ChannelPoint() wire.OutPoint {
 func (f *mockChannelLink) Stop()                                        {}
 func (f *mockChannelLink) EligibleToForward() bool                      { return f.eligible }
 func (f *mockChannelLink) MayAddOutgoingHtlc(lnwire.MilliSatoshi) error { return nil }
+func (f *mockChannelLink) AvailableOutgoingHtlcSlots() (uint16, error)  { return 0, nil }
 func (f *mockChannelLink) setLiveShortChanID(sid lnwire.ShortChannelID) { f.shortChanID = sid }
 func (f *mockChannelLink) IsUnadvertised() bool                         { return f.unadvertised }
 func (f *mockChannelLink) UpdateShortChanID() (lnwire.ShortChannelID, error) {
@@ -949,6 +956,20 @@ func (f *mockChannelLink) OnFlushedOnce(func()) {
 func (f *mockChannelLink) OnCommitOnce(LinkDirection, func()) {
 	// TODO(proofofkeags): Implement
 }
+func (f *mockChannelLink) InitStfu() <-chan fn.Result[lntypes.ChannelParty] {
+	out := make(chan fn.Result[lntypes.ChannelParty], 1)
+	out <- fn.Err[lntypes.ChannelParty](ErrQuiescenceNotSupported)
+
+	return out
+}
+
+func (f *mockChannelLink) QuiescenceInitiator() (
+	r fn.Result[lntypes.ChannelParty]) {
+
+	return fn.Err[lntypes.ChannelParty](ErrQuiescenceNotSupported)
+}
+func (f *mockChannelLink) ResumeUpdates() {
+}
 
 var _ ChannelLink = (*mockChannelLink)(nil)
 
@@ -98,6 +98,16 @@ type htlcPacket struct {
 	// were included in the payload.
 	customRecords record.CustomSet
 
+	// inWireCustomRecords are the custom records that were attached to the
+	// incoming update_add_htlc message.
+	inWireCustomRecords lnwire.CustomRecords
+
+	// strictOutgoingChan is true if the htlc may only be forwarded over
+	// the channel that outgoingChanID refers to, instead of over any
+	// channel to the same peer. It is set when an interceptor picked the
+	// outgoing channel of the htlc.
+	strictOutgoingChan bool
+
 	// originalOutgoingChanID is used when sending back failure messages.
 	// It is only used for forwarded Adds on option_scid_alias channels.
 	// This is to avoid possible confusion if a payer uses the public SCID
@@ -107,6 +117,10 @@ type htlcPacket struct {
 
 	// inboundFee is the fee schedule of the incoming channel.
 	inboundFee models.InboundFee
+
+	// incomingEndorsed is true if the incoming htlc carried a positive
+	// endorsement signal.
+	incomingEndorsed bool
 }
 
 // inKey returns the circuit key used to identify the incoming htlc.
@@ -0,0 +1,279 @@
+package htlcswitch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// ErrStfuAlreadySent is returned when we try to send an stfu message
+	// after we already sent one.
+	ErrStfuAlreadySent = errors.New("stfu already sent")
+
+	// ErrStfuAlreadyRcvd is returned when we receive a second stfu message
+	// from the remote party.
+	ErrStfuAlreadyRcvd = errors.New("stfu already received")
+
+	// ErrPendingRemoteUpdates is returned when the remote party sends stfu
+	// while it still has updates pending.
+	ErrPendingRemoteUpdates = errors.New("stfu received with pending " +
+		"remote updates")
+
+	// ErrQuiescenceNotSupported is returned when quiescence is requested
+	// for a link that doesn't support it.
+	ErrQuiescenceNotSupported = errors.New("quiescence not supported")
+)
+
+// StfuReq is a request to quiesce the channel. Once the channel is quiescent,
+// the party that initiated quiescence is sent over the response channel.
+type StfuReq = fn.Req[fn.Unit, fn.Result[lntypes.ChannelParty]]
+
+// QuiescerCfg holds the dependencies of a quiescer.
+type QuiescerCfg struct {
+	// chanID is the channel that is quiesced.
+	chanID lnwire.ChannelID
+
+	// channelInitiator is the party that opened the channel. It takes
+	// precedence if both parties initiate quiescence at the same time.
+	channelInitiator lntypes.ChannelParty
+
+	// sendMsg sends an stfu message to the remote party.
+	sendMsg func(lnwire.Stfu) error
+}
+
+// quiescer tracks the quiescence state of a channel. A channel is quiescent
+// once both parties sent stfu, after which neither party may send updates
+// until quiescence is ended. All methods are safe for concurrent use.
+type quiescer struct {
+	cfg QuiescerCfg
+
+	// localInit and remoteInit record which parties asked to quiesce the
+	// channel.
+	localInit  bool
+	remoteInit bool
+
+	// sent and received record whether stfu was sent to or received from
+	// the remote party.
+	sent     bool
+	received bool
+
+	// activeQuiescenceReq is the pending request to quiesce the channel,
+	// which is resolved once the channel is quiescent.
+	activeQuiescenceReq fn.Option[StfuReq]
+
+	sync.RWMutex
+}
+
+// newQuiescer creates a new quiescer for the channel.
+func newQuiescer(cfg QuiescerCfg) *quiescer {
+	return &quiescer{
+		cfg: cfg,
+	}
+}
+
+// RecvStfu is called when we receive an stfu message from the remote party.
+// The number of updates the remote party has pending must be zero, as it must
+// not send stfu before all of its updates are committed.
+func (q *quiescer) RecvStfu(msg lnwire.Stfu,
+	numPendingRemoteUpdates uint64) error {
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.received {
+		return fmt.Errorf("%w for channel %v", ErrStfuAlreadyRcvd,
+			q.cfg.chanID)
+	}
+
+	if numPendingRemoteUpdates != 0 {
+		return fmt.Errorf("%w: %d updates pending",
+			ErrPendingRemoteUpdates, numPendingRemoteUpdates)
+	}
+
+	q.received = true
+	q.remoteInit = msg.Initiator
+
+	q.tryResolveStfuReq()
+
+	return nil
+}
+
+// OweStfu returns true if the remote party asked to quiesce the channel, and
+// we haven't responded with stfu yet.
+func (q *quiescer) OweStfu() bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.oweStfu()
+}
+
+// oweStfu returns true if we need to send stfu.
+//
+// NOTE: This method requires the quiescer lock to be held.
+func (q *quiescer) oweStfu() bool {
+	return (q.received || q.localInit) && !q.sent
+}
+
+// SendOwedStfu sends stfu to the remote party if we owe it one, and we don't
+// have any pending updates left.
+func (q *quiescer) SendOwedStfu(numPendingLocalUpdates uint64) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if !q.oweStfu() || numPendingLocalUpdates != 0 {
+		return nil
+	}
+
+	if q.sent {
+		return ErrStfuAlreadySent
+	}
+
+	err := q.cfg.sendMsg(lnwire.Stfu{
+		ChanID:    q.cfg.chanID,
+		Initiator: q.localInit,
+	})
+	if err != nil {
+		return err
+	}
+
+	q.sent = true
+
+	q.tryResolveStfuReq()
+
+	return nil
+}
+
+// CanSendUpdates returns true if we may send updates to the remote party.
+func (q *quiescer) CanSendUpdates() bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	return !q.sent && !q.localInit
+}
+
+// CanRecvUpdates returns true if the remote party may send us updates.
+func (q *quiescer) CanRecvUpdates() bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	return !q.received
+}
+
+// IsQuiescent returns true once both parties sent stfu.
+func (q *quiescer) IsQuiescent() bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.isQuiescent()
+}
+
+// isQuiescent returns true once both parties sent stfu.
+//
+// NOTE: This method requires the quiescer lock to be held.
+func (q *quiescer) isQuiescent() bool {
+	return q.sent && q.received
+}
+
+// QuiescenceInitiator returns the party that initiated quiescence once the
+// channel is quiescent. If both parties initiated it, the party that opened
+// the channel wins.
+func (q *quiescer) QuiescenceInitiator() fn.Result[lntypes.ChannelParty] {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.quiescenceInitiator()
+}
+
+// quiescenceInitiator returns the party that initiated quiescence.
+//
+// NOTE: This method requires the quiescer lock to be held.
+func (q *quiescer) quiescenceInitiator() fn.Result[lntypes.ChannelParty] {
+	switch {
+	case !q.isQuiescent():
+		return fn.Err[lntypes.ChannelParty](
+			fmt.Errorf("channel %v isn't quiescent", q.cfg.chanID),
+		)
+
+	case q.localInit && q.remoteInit:
+		return fn.Ok(q.cfg.channelInitiator)
+
+	case q.localInit:
+		return fn.Ok(lntypes.Local)
+
+	case q.remoteInit:
+		return fn.Ok(lntypes.Remote)
+
+	// The channel was put into quiescence without either party initiating
+	// it, which happens when it's restored with a pending splice.
+	default:
+		return fn.Ok(q.cfg.channelInitiator)
+	}
+}
+
+// InitStfu starts quiescing the channel. The request is resolved once the
+// channel is quiescent. The caller needs to call SendOwedStfu afterwards to
+// send stfu once all local updates are committed.
+func (q *quiescer) InitStfu(req StfuReq) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.activeQuiescenceReq.IsSome() {
+		req.Resolve(fn.Err[lntypes.ChannelParty](
+			fmt.Errorf("quiescence already requested for "+
+				"channel %v", q.cfg.chanID),
+		))
+
+		return
+	}
+
+	if q.sent {
+		req.Resolve(fn.Err[lntypes.ChannelParty](
+			ErrStfuAlreadySent,
+		))
+
+		return
+	}
+
+	q.localInit = true
+	q.activeQuiescenceReq = fn.Some(req)
+}
+
+// tryResolveStfuReq resolves the pending quiescence request once the channel
+// is quiescent.
+//
+// NOTE: This method requires the quiescer lock to be held.
+func (q *quiescer) tryResolveStfuReq() {
+	if !q.isQuiescent() {
+		return
+	}
+
+	q.activeQuiescenceReq.WhenSome(func(req StfuReq) {
+		req.Resolve(q.quiescenceInitiator())
+		q.activeQuiescenceReq = fn.None[StfuReq]()
+	})
+}
+
+// Resume ends quiescence, allowing both parties to send updates again. Any
+// pending quiescence request is failed.
+func (q *quiescer) Resume() {
+	q.Lock()
+	defer q.Unlock()
+
+	q.activeQuiescenceReq.WhenSome(func(req StfuReq) {
+		req.Resolve(fn.Err[lntypes.ChannelParty](
+			fmt.Errorf("quiescence of channel %v ended",
+				q.cfg.chanID),
+		))
+	})
+
+	q.activeQuiescenceReq = fn.None[StfuReq]()
+	q.localInit = false
+	q.remoteInit = false
+	q.sent = false
+	q.received = false
+}
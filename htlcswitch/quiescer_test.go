@@ -0,0 +1,132 @@
+package htlcswitch
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+var quiescerTestChanID = lnwire.ChannelID{1}
+
+// newTestQuiescer creates a quiescer for a channel opened by us, which
+// records the stfu messages it sends.
+func newTestQuiescer() (*quiescer, *[]lnwire.Stfu) {
+	var sent []lnwire.Stfu
+
+	q := newQuiescer(QuiescerCfg{
+		chanID:           quiescerTestChanID,
+		channelInitiator: lntypes.Local,
+		sendMsg: func(msg lnwire.Stfu) error {
+			sent = append(sent, msg)
+			return nil
+		},
+	})
+
+	return q, &sent
+}
+
+// TestQuiescerLocalInit tests quiescence initiated by us.
+func TestQuiescerLocalInit(t *testing.T) {
+	t.Parallel()
+
+	q, sent := newTestQuiescer()
+
+	req, respChan := fn.NewReq[fn.Unit, fn.Result[lntypes.ChannelParty]](
+		fn.Unit{},
+	)
+	q.InitStfu(req)
+
+	// We may not send updates anymore, but the remote party still may.
+	require.False(t, q.CanSendUpdates())
+	require.True(t, q.CanRecvUpdates())
+
+	// Our stfu is only sent once our updates are committed.
+	require.NoError(t, q.SendOwedStfu(1))
+	require.Empty(t, *sent)
+
+	require.NoError(t, q.SendOwedStfu(0))
+	require.Len(t, *sent, 1)
+	require.True(t, (*sent)[0].Initiator)
+	require.False(t, q.IsQuiescent())
+
+	// A second quiescence request is rejected.
+	req2, respChan2 := fn.NewReq[fn.Unit, fn.Result[lntypes.ChannelParty]](
+		fn.Unit{},
+	)
+	q.InitStfu(req2)
+	require.True(t, (<-respChan2).IsErr())
+
+	require.NoError(t, q.RecvStfu(lnwire.Stfu{
+		ChanID: quiescerTestChanID,
+	}, 0))
+	require.True(t, q.IsQuiescent())
+	require.False(t, q.CanRecvUpdates())
+
+	initiator, err := (<-respChan).Unpack()
+	require.NoError(t, err)
+	require.Equal(t, lntypes.Local, initiator)
+
+	// Once resumed, both parties may send updates again.
+	q.Resume()
+	require.True(t, q.CanSendUpdates())
+	require.True(t, q.CanRecvUpdates())
+	require.False(t, q.IsQuiescent())
+}
+
+// TestQuiescerRemoteInit tests quiescence initiated by the remote party.
+func TestQuiescerRemoteInit(t *testing.T) {
+	t.Parallel()
+
+	q, sent := newTestQuiescer()
+
+	// The remote party may not send stfu with pending updates.
+	err := q.RecvStfu(lnwire.Stfu{
+		ChanID:    quiescerTestChanID,
+		Initiator: true,
+	}, 1)
+	require.ErrorIs(t, err, ErrPendingRemoteUpdates)
+
+	require.NoError(t, q.RecvStfu(lnwire.Stfu{
+		ChanID:    quiescerTestChanID,
+		Initiator: true,
+	}, 0))
+	require.False(t, q.CanRecvUpdates())
+	require.True(t, q.OweStfu())
+
+	err = q.RecvStfu(lnwire.Stfu{ChanID: quiescerTestChanID}, 0)
+	require.ErrorIs(t, err, ErrStfuAlreadyRcvd)
+
+	require.NoError(t, q.SendOwedStfu(0))
+	require.Len(t, *sent, 1)
+	require.False(t, (*sent)[0].Initiator)
+	require.False(t, q.OweStfu())
+
+	initiator, err := q.QuiescenceInitiator().Unpack()
+	require.NoError(t, err)
+	require.Equal(t, lntypes.Remote, initiator)
+}
+
+// TestQuiescerTieBreak tests that the channel opener initiates quiescence if
+// both parties request it at the same time.
+func TestQuiescerTieBreak(t *testing.T) {
+	t.Parallel()
+
+	q, _ := newTestQuiescer()
+
+	req, respChan := fn.NewReq[fn.Unit, fn.Result[lntypes.ChannelParty]](
+		fn.Unit{},
+	)
+	q.InitStfu(req)
+	require.NoError(t, q.SendOwedStfu(0))
+	require.NoError(t, q.RecvStfu(lnwire.Stfu{
+		ChanID:    quiescerTestChanID,
+		Initiator: true,
+	}, 0))
+
+	initiator, err := (<-respChan).Unpack()
+	require.NoError(t, err)
+	require.Equal(t, lntypes.Local, initiator)
+}
@@ -0,0 +1,461 @@
+package htlcswitch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultPeerAddRate is the default number of htlcs per second that a
+	// peer may add across all of its channels with us.
+	DefaultPeerAddRate = 10
+
+	// DefaultPeerAddBurst is the default number of htlcs that a peer may
+	// add in a burst across all of its channels with us.
+	DefaultPeerAddBurst = 100
+
+	// DefaultChannelAddRate is the default number of htlcs per second that
+	// a peer may add on a single channel.
+	DefaultChannelAddRate = 5
+
+	// DefaultChannelAddBurst is the default number of htlcs that a peer
+	// may add in a burst on a single channel.
+	DefaultChannelAddBurst = 50
+)
+
+// errInvalidAddBurst is returned if an add rate is configured without a burst
+// that allows at least a single htlc.
+var errInvalidAddBurst = errors.New("add burst must be positive if the " +
+	"add rate is limited")
+
+// HtlcRateLimiterConfig holds the parameters of the htlc rate limiter. A zero
+// rate or in-flight value disables the respective limit.
+type HtlcRateLimiterConfig struct {
+	// PeerAddRate is the number of htlcs per second that a peer may add
+	// across all of its channels with us.
+	PeerAddRate float64
+
+	// PeerAddBurst is the number of htlcs that a peer may add in a burst
+	// across all of its channels with us.
+	PeerAddBurst int
+
+	// ChannelAddRate is the number of htlcs per second that a peer may add
+	// on a single channel.
+	ChannelAddRate float64
+
+	// ChannelAddBurst is the number of htlcs that a peer may add in a
+	// burst on a single channel.
+	ChannelAddBurst int
+
+	// PeerMaxInFlight is the total value of the htlcs that a peer may have
+	// in flight across all of its channels with us.
+	PeerMaxInFlight lnwire.MilliSatoshi
+
+	// Clock is used to refill the token buckets.
+	Clock clock.Clock
+}
+
+// Validate checks that the parameters of the htlc rate limiter are sane.
+func (c *HtlcRateLimiterConfig) Validate() error {
+	if c.PeerAddRate < 0 || c.ChannelAddRate < 0 {
+		return fmt.Errorf("add rates must not be negative")
+	}
+
+	if (c.PeerAddRate > 0 && c.PeerAddBurst <= 0) ||
+		(c.ChannelAddRate > 0 && c.ChannelAddBurst <= 0) {
+
+		return errInvalidAddBurst
+	}
+
+	return nil
+}
+
+// RateLimitDecision is the decision of the htlc rate limiter on an incoming
+// htlc.
+type RateLimitDecision uint8
+
+const (
+	// RateLimitAllow allows the htlc to be processed.
+	RateLimitAllow RateLimitDecision = iota
+
+	// RateLimitAddRate fails the htlc because its peer or channel exceeded
+	// its add rate.
+	RateLimitAddRate
+
+	// RateLimitInFlight fails the htlc because it would exceed the value
+	// that its peer may have in flight.
+	RateLimitInFlight
+)
+
+// String returns a human-readable representation of the decision.
+func (d RateLimitDecision) String() string {
+	switch d {
+	case RateLimitAllow:
+		return "allow"
+
+	case RateLimitAddRate:
+		return "add rate exceeded"
+
+	case RateLimitInFlight:
+		return "in-flight value exceeded"
+
+	default:
+		return "unknown"
+	}
+}
+
+// RateLimitCounters counts the decisions of the htlc rate limiter.
+type RateLimitCounters struct {
+	// Allowed is the number of htlcs that were allowed.
+	Allowed uint64
+
+	// AddRateLimited is the number of htlcs that were failed because their
+	// peer or channel exceeded its add rate.
+	AddRateLimited uint64
+
+	// InFlightLimited is the number of htlcs that were failed because
+	// they would exceed the value that their peer may have in flight.
+	InFlightLimited uint64
+}
+
+// count adds a decision to the counters.
+func (c *RateLimitCounters) count(decision RateLimitDecision) {
+	switch decision {
+	case RateLimitAllow:
+		c.Allowed++
+
+	case RateLimitAddRate:
+		c.AddRateLimited++
+
+	case RateLimitInFlight:
+		c.InFlightLimited++
+	}
+}
+
+// ChannelRateLimitStats reports the state of the htlc rate limiter for a
+// channel.
+type ChannelRateLimitStats struct {
+	// ChanPoint is the funding outpoint of the channel.
+	ChanPoint wire.OutPoint
+
+	// InFlight is the value of the incoming htlcs of the channel.
+	InFlight lnwire.MilliSatoshi
+
+	// Counters are the decisions on the htlcs of the channel.
+	Counters RateLimitCounters
+}
+
+// PeerRateLimitStats reports the state of the htlc rate limiter for a peer.
+type PeerRateLimitStats struct {
+	// Peer is the public key of the peer.
+	Peer route.Vertex
+
+	// InFlight is the value of the incoming htlcs of the peer across all
+	// of its active channels.
+	InFlight lnwire.MilliSatoshi
+
+	// Counters are the decisions on the htlcs of the peer, including
+	// those of channels that are no longer active. They are reset once
+	// the peer has no active channels left.
+	Counters RateLimitCounters
+
+	// Channels holds the stats of the active channels of the peer.
+	Channels []ChannelRateLimitStats
+}
+
+// channelLimits tracks the add rate and the in-flight htlcs of an active
+// channel.
+type channelLimits struct {
+	peer route.Vertex
+
+	// adds is the token bucket of the add rate, nil if it isn't limited.
+	adds *rate.Limiter
+
+	// htlcs holds the value of the incoming htlcs that are in flight on
+	// the channel, by htlc index.
+	htlcs map[uint64]lnwire.MilliSatoshi
+
+	inFlight lnwire.MilliSatoshi
+
+	counters RateLimitCounters
+}
+
+// peerLimits tracks the add rate and the in-flight value of a peer.
+type peerLimits struct {
+	// adds is the token bucket of the add rate, nil if it isn't limited.
+	adds *rate.Limiter
+
+	inFlight lnwire.MilliSatoshi
+
+	counters RateLimitCounters
+
+	// numChannels is the number of active channels of the peer. The limits
+	// of the peer are dropped once it reaches zero.
+	numChannels int
+}
+
+// HtlcRateLimiter limits the rate at which our peers may add htlcs, per peer
+// and per channel, and the value that each peer may have in flight with us.
+// The add rates are enforced with token buckets that refill at the configured
+// rate. The in-flight value is a budget that incoming htlcs take from when
+// they are received and return to once their resolution is locked in.
+type HtlcRateLimiter struct {
+	cfg *HtlcRateLimiterConfig
+
+	// peers holds the limits of every peer that has an active channel.
+	peers map[route.Vertex]*peerLimits
+
+	// channels holds the limits of the active channels.
+	channels map[wire.OutPoint]*channelLimits
+
+	mtx sync.Mutex
+}
+
+// NewHtlcRateLimiter creates a new htlc rate limiter from the given config.
+func NewHtlcRateLimiter(cfg *HtlcRateLimiterConfig) (*HtlcRateLimiter,
+	error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &HtlcRateLimiter{
+		cfg:      cfg,
+		peers:    make(map[route.Vertex]*peerLimits),
+		channels: make(map[wire.OutPoint]*channelLimits),
+	}, nil
+}
+
+// newLimiter returns a token bucket for the given rate, or nil if the rate is
+// zero and therefore not limited.
+func newLimiter(addRate float64, burst int) *rate.Limiter {
+	if addRate == 0 {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(addRate), burst)
+}
+
+// peerLimits returns the limits of the peer, creating them if needed. The
+// caller must hold the mutex.
+func (r *HtlcRateLimiter) peerLimits(peer route.Vertex) *peerLimits {
+	limits, ok := r.peers[peer]
+	if !ok {
+		limits = &peerLimits{
+			adds: newLimiter(r.cfg.PeerAddRate, r.cfg.PeerAddBurst),
+		}
+		r.peers[peer] = limits
+	}
+
+	return limits
+}
+
+// AddChannel starts tracking an active channel of the peer. The incoming
+// htlcs that are already on the channel count towards the in-flight value of
+// the peer.
+func (r *HtlcRateLimiter) AddChannel(peer route.Vertex,
+	chanPoint wire.OutPoint, htlcs map[uint64]lnwire.MilliSatoshi) {
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	// Keep the limits of the peer if the channel is re-added, so that a
+	// restart of its link doesn't reset them.
+	if oldPeer, ok := r.untrackChannel(chanPoint); ok && oldPeer != peer {
+		r.prunePeer(oldPeer)
+	}
+
+	channel := &channelLimits{
+		peer: peer,
+		adds: newLimiter(
+			r.cfg.ChannelAddRate, r.cfg.ChannelAddBurst,
+		),
+		htlcs: make(map[uint64]lnwire.MilliSatoshi, len(htlcs)),
+	}
+	for index, amt := range htlcs {
+		channel.htlcs[index] = amt
+		channel.inFlight += amt
+	}
+	r.channels[chanPoint] = channel
+
+	limits := r.peerLimits(peer)
+	limits.inFlight += channel.inFlight
+	limits.numChannels++
+}
+
+// RemoveChannel stops tracking a channel, which releases the in-flight value
+// of its htlcs.
+func (r *HtlcRateLimiter) RemoveChannel(chanPoint wire.OutPoint) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.removeChannel(chanPoint)
+}
+
+// removeChannel stops tracking a channel and drops the limits of its peer if
+// it has no active channels left. The caller must hold the mutex.
+func (r *HtlcRateLimiter) removeChannel(chanPoint wire.OutPoint) {
+	if peer, ok := r.untrackChannel(chanPoint); ok {
+		r.prunePeer(peer)
+	}
+}
+
+// untrackChannel stops tracking a channel, keeping the limits of its peer. It
+// returns the peer of the channel and whether the channel was tracked. The
+// caller must hold the mutex.
+func (r *HtlcRateLimiter) untrackChannel(
+	chanPoint wire.OutPoint) (route.Vertex, bool) {
+
+	channel, ok := r.channels[chanPoint]
+	if !ok {
+		return route.Vertex{}, false
+	}
+	delete(r.channels, chanPoint)
+
+	limits := r.peerLimits(channel.peer)
+	limits.inFlight -= channel.inFlight
+	limits.numChannels--
+
+	return channel.peer, true
+}
+
+// prunePeer drops the limits of the peer if it has no active channels left and
+// therefore no htlcs in flight. The caller must hold the mutex.
+func (r *HtlcRateLimiter) prunePeer(peer route.Vertex) {
+	limits, ok := r.peers[peer]
+	if !ok {
+		return
+	}
+
+	if limits.numChannels == 0 && limits.inFlight == 0 {
+		delete(r.peers, peer)
+	}
+}
+
+// AddHtlc decides whether an incoming htlc on the channel may be processed.
+// Allowed htlcs take a token from the add buckets of their peer and channel,
+// and count towards the in-flight value of the peer until they are resolved.
+// Htlcs that are already tracked are allowed without being charged again.
+func (r *HtlcRateLimiter) AddHtlc(chanPoint wire.OutPoint, htlcIndex uint64,
+	amt lnwire.MilliSatoshi) RateLimitDecision {
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	channel, ok := r.channels[chanPoint]
+	if !ok {
+		return RateLimitAllow
+	}
+
+	if _, ok := channel.htlcs[htlcIndex]; ok {
+		return RateLimitAllow
+	}
+
+	peer := r.peerLimits(channel.peer)
+	now := r.cfg.Clock.Now()
+
+	var decision RateLimitDecision
+	switch {
+	// Check the in-flight budget first, so that htlcs that are failed
+	// because of it don't use up the add rate of the peer.
+	case r.cfg.PeerMaxInFlight != 0 &&
+		peer.inFlight+amt > r.cfg.PeerMaxInFlight:
+
+		decision = RateLimitInFlight
+
+	// Only take a token from the peer bucket if the channel bucket has one
+	// left, so that a rate limited channel doesn't drain the budget of the
+	// other channels of the peer.
+	case channel.adds != nil && channel.adds.TokensAt(now) < 1,
+		peer.adds != nil && !peer.adds.AllowN(now, 1):
+
+		decision = RateLimitAddRate
+
+	case channel.adds != nil:
+		channel.adds.AllowN(now, 1)
+	}
+
+	channel.counters.count(decision)
+	peer.counters.count(decision)
+
+	if decision != RateLimitAllow {
+		return decision
+	}
+
+	channel.htlcs[htlcIndex] = amt
+	channel.inFlight += amt
+	peer.inFlight += amt
+
+	return decision
+}
+
+// ResolveHtlc releases the in-flight value of an incoming htlc on the channel
+// once its resolution is locked in. Htlcs that aren't tracked are ignored.
+func (r *HtlcRateLimiter) ResolveHtlc(chanPoint wire.OutPoint,
+	htlcIndex uint64) {
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	channel, ok := r.channels[chanPoint]
+	if !ok {
+		return
+	}
+
+	amt, ok := channel.htlcs[htlcIndex]
+	if !ok {
+		return
+	}
+	delete(channel.htlcs, htlcIndex)
+
+	channel.inFlight -= amt
+	r.peerLimits(channel.peer).inFlight -= amt
+}
+
+// Stats returns the state of the rate limiter for every peer that has an
+// active channel, ordered by peer.
+func (r *HtlcRateLimiter) Stats() []PeerRateLimitStats {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	channels := make(map[route.Vertex][]ChannelRateLimitStats)
+	for chanPoint, channel := range r.channels {
+		channels[channel.peer] = append(
+			channels[channel.peer], ChannelRateLimitStats{
+				ChanPoint: chanPoint,
+				InFlight:  channel.inFlight,
+				Counters:  channel.counters,
+			},
+		)
+	}
+
+	stats := make([]PeerRateLimitStats, 0, len(r.peers))
+	for peer, limits := range r.peers {
+		peerChannels := channels[peer]
+		sort.Slice(peerChannels, func(i, j int) bool {
+			return peerChannels[i].ChanPoint.String() <
+				peerChannels[j].ChanPoint.String()
+		})
+
+		stats = append(stats, PeerRateLimitStats{
+			Peer:     peer,
+			InFlight: limits.inFlight,
+			Counters: limits.counters,
+			Channels: peerChannels,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Peer.String() < stats[j].Peer.String()
+	})
+
+	return stats
+}
@@ -0,0 +1,250 @@
+package htlcswitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testRateLimitPeer  = route.Vertex{1}
+	testRateLimitPeer2 = route.Vertex{2}
+
+	testRateLimitChan = wire.OutPoint{
+		Hash: chainhash.Hash{1},
+	}
+	testRateLimitChan2 = wire.OutPoint{
+		Hash:  chainhash.Hash{1},
+		Index: 1,
+	}
+)
+
+// newTestRateLimiter creates an htlc rate limiter with the given config that
+// uses the given clock.
+func newTestRateLimiter(t *testing.T, cfg HtlcRateLimiterConfig,
+	testClock clock.Clock) *HtlcRateLimiter {
+
+	cfg.Clock = testClock
+	limiter, err := NewHtlcRateLimiter(&cfg)
+	require.NoError(t, err)
+
+	return limiter
+}
+
+// TestHtlcRateLimiterConfig tests the validation of the rate limiter config.
+func TestHtlcRateLimiterConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewHtlcRateLimiter(&HtlcRateLimiterConfig{
+		PeerAddRate: -1,
+	})
+	require.Error(t, err)
+
+	_, err = NewHtlcRateLimiter(&HtlcRateLimiterConfig{
+		ChannelAddRate: 1,
+	})
+	require.ErrorIs(t, err, errInvalidAddBurst)
+
+	// All limits may be disabled.
+	_, err = NewHtlcRateLimiter(&HtlcRateLimiterConfig{})
+	require.NoError(t, err)
+}
+
+// TestHtlcRateLimiterAddRate tests that the adds of a channel are limited by
+// the channel bucket and that the adds across the channels of a peer are
+// limited by the peer bucket.
+func TestHtlcRateLimiterAddRate(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1000, 0))
+	limiter := newTestRateLimiter(t, HtlcRateLimiterConfig{
+		PeerAddRate:     1,
+		PeerAddBurst:    3,
+		ChannelAddRate:  1,
+		ChannelAddBurst: 2,
+	}, testClock)
+
+	limiter.AddChannel(testRateLimitPeer, testRateLimitChan, nil)
+	limiter.AddChannel(testRateLimitPeer, testRateLimitChan2, nil)
+
+	// The first channel may add its burst, after which it is limited.
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan, 0, 1),
+	)
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan, 1, 1),
+	)
+	require.Equal(
+		t, RateLimitAddRate, limiter.AddHtlc(testRateLimitChan, 2, 1),
+	)
+
+	// The failed add didn't use up the budget of the peer, so the second
+	// channel may still add one htlc before the peer is limited.
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan2, 0, 1),
+	)
+	require.Equal(
+		t, RateLimitAddRate, limiter.AddHtlc(testRateLimitChan2, 1, 1),
+	)
+
+	// An htlc that is already tracked isn't charged again, as is the case
+	// when it is replayed.
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan, 0, 1),
+	)
+
+	// Htlcs on channels that aren't tracked are always allowed.
+	require.Equal(t, RateLimitAllow, limiter.AddHtlc(wire.OutPoint{}, 0, 1))
+
+	// Once the buckets refilled, the peer may add again.
+	testClock.SetTime(testClock.Now().Add(time.Second))
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan2, 1, 1),
+	)
+
+	stats := limiter.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, RateLimitCounters{
+		Allowed:        4,
+		AddRateLimited: 2,
+	}, stats[0].Counters)
+	require.Equal(t, lnwire.MilliSatoshi(4), stats[0].InFlight)
+
+	require.Len(t, stats[0].Channels, 2)
+	require.Equal(t, ChannelRateLimitStats{
+		ChanPoint: testRateLimitChan,
+		InFlight:  2,
+		Counters: RateLimitCounters{
+			Allowed:        2,
+			AddRateLimited: 1,
+		},
+	}, stats[0].Channels[0])
+	require.Equal(t, ChannelRateLimitStats{
+		ChanPoint: testRateLimitChan2,
+		InFlight:  2,
+		Counters: RateLimitCounters{
+			Allowed:        2,
+			AddRateLimited: 1,
+		},
+	}, stats[0].Channels[1])
+}
+
+// TestHtlcRateLimiterInFlight tests that the in-flight value of a peer is
+// limited across its channels, including the htlcs that were already on a
+// channel when it was added, and that resolved htlcs release their value.
+func TestHtlcRateLimiterInFlight(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1000, 0))
+	limiter := newTestRateLimiter(t, HtlcRateLimiterConfig{
+		PeerMaxInFlight: 1000,
+	}, testClock)
+
+	limiter.AddChannel(
+		testRateLimitPeer, testRateLimitChan,
+		map[uint64]lnwire.MilliSatoshi{0: 400},
+	)
+	limiter.AddChannel(testRateLimitPeer, testRateLimitChan2, nil)
+	limiter.AddChannel(testRateLimitPeer2, wire.OutPoint{}, nil)
+
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan2, 0, 500),
+	)
+	require.Equal(
+		t, RateLimitInFlight,
+		limiter.AddHtlc(testRateLimitChan, 1, 200),
+	)
+
+	// The budget of the other peer is unaffected.
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(wire.OutPoint{}, 0, 900),
+	)
+
+	// Resolving the htlc that was on the channel when it was added frees
+	// up the budget.
+	limiter.ResolveHtlc(testRateLimitChan, 0)
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan, 1, 200),
+	)
+
+	// Resolving an unknown htlc is ignored.
+	limiter.ResolveHtlc(testRateLimitChan, 5)
+
+	stats := limiter.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, testRateLimitPeer, stats[0].Peer)
+	require.Equal(t, lnwire.MilliSatoshi(700), stats[0].InFlight)
+	require.Equal(t, RateLimitCounters{
+		Allowed:         2,
+		InFlightLimited: 1,
+	}, stats[0].Counters)
+	require.Equal(t, testRateLimitPeer2, stats[1].Peer)
+	require.Equal(t, lnwire.MilliSatoshi(900), stats[1].InFlight)
+
+	// Removing a channel releases the value of its htlcs and stops the
+	// limits from being applied to it.
+	limiter.RemoveChannel(testRateLimitChan2)
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan2, 1, 5000),
+	)
+
+	stats = limiter.Stats()
+	require.Equal(t, lnwire.MilliSatoshi(200), stats[0].InFlight)
+	require.Len(t, stats[0].Channels, 1)
+	require.Equal(t, testRateLimitChan, stats[0].Channels[0].ChanPoint)
+}
+
+// TestHtlcRateLimiterPrune tests that the limits of a peer are kept while it
+// has active channels, including across a re-add of a channel, and are dropped
+// once its last channel is removed.
+func TestHtlcRateLimiterPrune(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1000, 0))
+	limiter := newTestRateLimiter(t, HtlcRateLimiterConfig{
+		PeerAddRate:  1,
+		PeerAddBurst: 1,
+	}, testClock)
+
+	limiter.AddChannel(testRateLimitPeer, testRateLimitChan, nil)
+	limiter.AddChannel(testRateLimitPeer, testRateLimitChan2, nil)
+	limiter.AddChannel(testRateLimitPeer2, wire.OutPoint{}, nil)
+
+	require.Equal(
+		t, RateLimitAllow, limiter.AddHtlc(testRateLimitChan, 0, 100),
+	)
+
+	// Re-adding the channel, as happens when its link restarts, keeps the
+	// exhausted bucket of the peer.
+	limiter.AddChannel(
+		testRateLimitPeer, testRateLimitChan,
+		map[uint64]lnwire.MilliSatoshi{0: 100},
+	)
+	require.Equal(
+		t, RateLimitAddRate, limiter.AddHtlc(testRateLimitChan, 1, 100),
+	)
+
+	// The peer is kept while it has an active channel left.
+	limiter.RemoveChannel(testRateLimitChan)
+	stats := limiter.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, testRateLimitPeer, stats[0].Peer)
+	require.Zero(t, stats[0].InFlight)
+	require.Equal(t, RateLimitCounters{
+		Allowed:        1,
+		AddRateLimited: 1,
+	}, stats[0].Counters)
+
+	// Removing the last channel of the peer drops its limits.
+	limiter.RemoveChannel(testRateLimitChan2)
+	stats = limiter.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, testRateLimitPeer2, stats[0].Peer)
+	require.Len(t, limiter.peers, 1)
+}
@@ -0,0 +1,946 @@
+package htlcswitch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/channelnotifier"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+)
+
+const (
+	// DefaultProtectedPercentage is the default share of the slots and
+	// liquidity of an outgoing channel that is reserved for endorsed htlcs
+	// from peers with good reputation.
+	DefaultProtectedPercentage = 50
+
+	// DefaultRevenueWindow is the default period over which the fees that
+	// an outgoing channel earns us are tracked.
+	DefaultRevenueWindow = 14 * 24 * time.Hour
+
+	// DefaultReputationMultiplier is the default multiple of the revenue
+	// window over which the reputation of a peer is tracked.
+	DefaultReputationMultiplier = 12
+
+	// DefaultResolutionPeriod is the default time within which we expect
+	// a forwarded htlc to resolve. Endorsed htlcs that are held longer are
+	// charged an opportunity cost against the reputation of their peer.
+	DefaultResolutionPeriod = 90 * time.Second
+
+	// expectedBlockTime is the expected time between two blocks, used to
+	// estimate how long an htlc may be held until it expires.
+	expectedBlockTime = 10 * time.Minute
+
+	// reputationFlushInterval is the interval at which the reputation of
+	// our peers and the revenue of our channels are written to disk.
+	reputationFlushInterval = 10 * time.Minute
+)
+
+var (
+	// errInvalidProtectedPercentage is returned if the protected share of
+	// the channel resources isn't a percentage.
+	errInvalidProtectedPercentage = errors.New("protected percentage " +
+		"must be between 0 and 100")
+
+	// errInvalidResourceWindow is returned if one of the periods of the
+	// resource manager isn't positive.
+	errInvalidResourceWindow = errors.New("revenue window, reputation " +
+		"multiplier and resolution period must be positive")
+
+	// resourceManagerBucket is the top level bucket that holds the
+	// reputation of our peers and the revenue of our channels.
+	resourceManagerBucket = []byte("resource-manager")
+
+	// peerReputationBucket is the sub bucket that maps the public key of
+	// a peer to its reputation.
+	peerReputationBucket = []byte("peer-reputation")
+
+	// channelRevenueBucket is the sub bucket that maps the short channel
+	// ID of an outgoing channel to its revenue.
+	channelRevenueBucket = []byte("channel-revenue")
+)
+
+// ResourceManagerConfig holds the dependencies and the parameters of the
+// resource manager.
+type ResourceManagerConfig struct {
+	// MonitorOnly, if set, only logs the decisions of the resource
+	// manager. All htlcs are forwarded and the endorsement signal of the
+	// incoming htlc is relayed unchanged.
+	MonitorOnly bool
+
+	// ProtectedPercentage is the share of the slots and liquidity of each
+	// outgoing channel that is reserved for endorsed htlcs from peers with
+	// good reputation. All other htlcs share the remaining resources.
+	ProtectedPercentage uint8
+
+	// RevenueWindow is the period over which the fees that an outgoing
+	// channel earns us are tracked.
+	RevenueWindow time.Duration
+
+	// ReputationMultiplier is the multiple of the revenue window over
+	// which the reputation of a peer is tracked.
+	ReputationMultiplier uint8
+
+	// ResolutionPeriod is the time within which we expect a forwarded htlc
+	// to resolve. Endorsed htlcs that are held longer are charged an
+	// opportunity cost against the reputation of their peer.
+	ResolutionPeriod time.Duration
+
+	// SubscribeHtlcEvents subscribes to the events of the htlc notifier,
+	// which are used to learn when forwarded htlcs are resolved.
+	SubscribeHtlcEvents func() (*subscribe.Client, error)
+
+	// SubscribeChannelEvents subscribes to the events of the channel
+	// notifier, which are used to forget the state of closed channels.
+	SubscribeChannelEvents func() (*subscribe.Client, error)
+
+	// HasOpenChannels returns true if we have any open channels with the
+	// given peer. The reputation of a peer is forgotten once its last
+	// channel closed.
+	HasOpenChannels func(peer route.Vertex) (bool, error)
+
+	// DB, if set, persists the reputation of our peers and the revenue of
+	// our channels, so that they survive a restart. Otherwise they are
+	// only kept in memory, and every restart resets them.
+	DB kvdb.Backend
+
+	// Clock is used to time the resolution of htlcs and to decay the
+	// reputation and revenue values.
+	Clock clock.Clock
+}
+
+// Validate checks that the parameters of the resource manager are sane.
+func (c *ResourceManagerConfig) Validate() error {
+	if c.ProtectedPercentage > 100 {
+		return errInvalidProtectedPercentage
+	}
+
+	if c.RevenueWindow <= 0 || c.ReputationMultiplier == 0 ||
+		c.ResolutionPeriod <= 0 {
+
+		return errInvalidResourceWindow
+	}
+
+	return nil
+}
+
+// ResourceBucket is the share of the resources of an outgoing channel that a
+// forwarded htlc is assigned to.
+type ResourceBucket uint8
+
+const (
+	// BucketGeneral is the share of resources that is used by unendorsed
+	// htlcs and by htlcs from peers without good reputation.
+	BucketGeneral ResourceBucket = iota
+
+	// BucketProtected is the share of resources that is reserved for
+	// endorsed htlcs from peers with good reputation.
+	BucketProtected
+)
+
+// String returns a human-readable representation of the bucket.
+func (b ResourceBucket) String() string {
+	switch b {
+	case BucketGeneral:
+		return "general"
+
+	case BucketProtected:
+		return "protected"
+
+	default:
+		return "unknown"
+	}
+}
+
+// ChannelLimits are the resources of an outgoing channel that htlcs compete
+// for.
+type ChannelLimits struct {
+	// MaxHtlcs is the maximum number of htlcs that we may offer on the
+	// channel.
+	MaxHtlcs uint16
+
+	// MaxInFlight is the maximum value that we may have in flight on the
+	// channel.
+	MaxInFlight lnwire.MilliSatoshi
+}
+
+// ProposedHtlc is an htlc that we were asked to forward.
+type ProposedHtlc struct {
+	// IncomingCircuit identifies the incoming htlc.
+	IncomingCircuit models.CircuitKey
+
+	// IncomingPeer is the peer that offered us the htlc.
+	IncomingPeer route.Vertex
+
+	// OutgoingChannel is the channel that the htlc is forwarded on.
+	OutgoingChannel lnwire.ShortChannelID
+
+	// OutgoingLimits are the resources of the outgoing channel.
+	OutgoingLimits ChannelLimits
+
+	// IncomingAmount is the amount of the incoming htlc.
+	IncomingAmount lnwire.MilliSatoshi
+
+	// OutgoingAmount is the amount of the outgoing htlc.
+	OutgoingAmount lnwire.MilliSatoshi
+
+	// IncomingExpiry is the expiry height of the incoming htlc.
+	IncomingExpiry uint32
+
+	// CurrentHeight is the current block height.
+	CurrentHeight uint32
+
+	// Endorsed is true if the incoming htlc was endorsed by our peer.
+	Endorsed bool
+}
+
+// fee returns the fee that we earn if the htlc settles.
+func (p *ProposedHtlc) fee() lnwire.MilliSatoshi {
+	if p.IncomingAmount < p.OutgoingAmount {
+		return 0
+	}
+
+	return p.IncomingAmount - p.OutgoingAmount
+}
+
+// ForwardDecision is the outcome of the evaluation of a proposed htlc.
+type ForwardDecision struct {
+	// Forward is false if the htlc has to be failed because its bucket
+	// lacks the resources to carry it.
+	Forward bool
+
+	// Bucket is the bucket that the htlc was assigned to.
+	Bucket ResourceBucket
+
+	// OutgoingEndorsed is true if the outgoing htlc should be endorsed.
+	OutgoingEndorsed bool
+}
+
+// String returns a human-readable representation of the decision.
+func (d ForwardDecision) String() string {
+	return fmt.Sprintf("forward=%v, bucket=%v, outgoing_endorsed=%v",
+		d.Forward, d.Bucket, d.OutgoingEndorsed)
+}
+
+// isEndorsed returns true if the custom records of an htlc carry a positive
+// experimental endorsement signal.
+func isEndorsed(records lnwire.CustomRecords) bool {
+	signal, ok := records[uint64(lnwire.ExperimentalEndorsementType)]
+
+	return ok && len(signal) == 1 &&
+		signal[0] == lnwire.ExperimentalEndorsed
+}
+
+// withEndorsement returns a copy of the custom records of an htlc that carries
+// the given experimental endorsement signal.
+func withEndorsement(records lnwire.CustomRecords,
+	endorsed bool) lnwire.CustomRecords {
+
+	signal := lnwire.ExperimentalUnendorsed
+	if endorsed {
+		signal = lnwire.ExperimentalEndorsed
+	}
+
+	records = records.Copy()
+	if records == nil {
+		records = make(lnwire.CustomRecords)
+	}
+	records[uint64(lnwire.ExperimentalEndorsementType)] = []byte{signal}
+
+	return records
+}
+
+// decayingAverage is a value whose past contributions decay exponentially,
+// with the window of the average as time constant.
+type decayingAverage struct {
+	value      float64
+	lastUpdate time.Time
+	window     time.Duration
+}
+
+// getValue returns the value of the average at the given time.
+func (d *decayingAverage) getValue(now time.Time) float64 {
+	if !now.After(d.lastUpdate) {
+		return d.value
+	}
+
+	if !d.lastUpdate.IsZero() {
+		elapsed := now.Sub(d.lastUpdate)
+		d.value *= math.Exp(-float64(elapsed) / float64(d.window))
+	}
+	d.lastUpdate = now
+
+	return d.value
+}
+
+// add adds a contribution to the average at the given time.
+func (d *decayingAverage) add(now time.Time, value float64) {
+	d.value = d.getValue(now) + value
+}
+
+// pendingHtlc is a forwarded htlc that hasn't been resolved yet.
+type pendingHtlc struct {
+	ProposedHtlc
+
+	// bucket is the bucket that the htlc was assigned to.
+	bucket ResourceBucket
+
+	// addedAt is the time at which the htlc was forwarded.
+	addedAt time.Time
+
+	// outgoingClosed is true if the outgoing channel of the htlc closed
+	// while the htlc was pending. Its resolution then no longer counts
+	// towards the usage and revenue of the channel.
+	outgoingClosed bool
+
+	// peerPruned is true if the last channel with the incoming peer of
+	// the htlc closed while the htlc was pending. Its resolution then no
+	// longer counts towards the reputation of the peer.
+	peerPruned bool
+}
+
+// channelUsage tracks the general bucket resources that are in use on an
+// outgoing channel.
+type channelUsage struct {
+	htlcs    uint16
+	inFlight lnwire.MilliSatoshi
+	revenue  *decayingAverage
+}
+
+// ResourceManager protects our channels against jamming. It tracks the
+// reputation of our peers from the resolution times and fees of the htlcs
+// they forward to us, and the revenue of our outgoing channels. Endorsed htlcs
+// from peers whose reputation exceeds the revenue of the outgoing channel may
+// use all of its resources, while all other htlcs compete for a limited
+// general share of the slots and liquidity of the channel.
+//
+// The reputation and revenue values are written to the database periodically
+// and on shutdown, and are loaded again on startup. The htlcs that are pending
+// when we shut down aren't persisted, so their resolution isn't accounted for.
+// The state of a channel is forgotten once it closes, and the reputation of a
+// peer once its last channel closes.
+type ResourceManager struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *ResourceManagerConfig
+
+	// reputation holds the reputation of each incoming peer.
+	reputation map[route.Vertex]*decayingAverage
+
+	// channels holds the revenue and general bucket usage of each
+	// outgoing channel.
+	channels map[lnwire.ShortChannelID]*channelUsage
+
+	// pending holds the forwarded htlcs that haven't been resolved yet,
+	// keyed by their incoming circuit.
+	pending map[models.CircuitKey]*pendingHtlc
+
+	mtx sync.Mutex
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// NewResourceManager creates a new resource manager from the given config.
+func NewResourceManager(cfg *ResourceManagerConfig) (*ResourceManager,
+	error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &ResourceManager{
+		cfg:        cfg,
+		reputation: make(map[route.Vertex]*decayingAverage),
+		channels:   make(map[lnwire.ShortChannelID]*channelUsage),
+		pending:    make(map[models.CircuitKey]*pendingHtlc),
+		quit:       make(chan struct{}),
+	}, nil
+}
+
+// Start loads the persisted reputation and revenue values, and subscribes to
+// htlc and channel events to learn about the resolution of forwarded htlcs
+// and about closed channels.
+func (r *ResourceManager) Start() error {
+	var err error
+	r.started.Do(func() {
+		log.Infof("ResourceManager starting (monitor only: %v)",
+			r.cfg.MonitorOnly)
+
+		if err = r.loadReputation(); err != nil {
+			return
+		}
+
+		var htlcClient, chanClient *subscribe.Client
+		htlcClient, err = r.cfg.SubscribeHtlcEvents()
+		if err != nil {
+			return
+		}
+
+		chanClient, err = r.cfg.SubscribeChannelEvents()
+		if err != nil {
+			htlcClient.Cancel()
+			return
+		}
+
+		r.wg.Add(1)
+		go r.handleEvents(htlcClient, chanClient)
+	})
+
+	return err
+}
+
+// Stop stops the resource manager and writes the reputation and revenue
+// values to disk.
+func (r *ResourceManager) Stop() error {
+	var err error
+	r.stopped.Do(func() {
+		log.Info("ResourceManager shutting down...")
+		defer log.Debug("ResourceManager shutdown complete")
+
+		close(r.quit)
+		r.wg.Wait()
+
+		err = r.flushReputation()
+	})
+
+	return err
+}
+
+// handleEvents resolves the pending htlcs from the htlc events of the
+// notifier, forgets the state of closed channels and periodically writes the
+// reputation and revenue values to disk.
+//
+// NOTE: This MUST be run as a goroutine.
+func (r *ResourceManager) handleEvents(htlcClient,
+	chanClient *subscribe.Client) {
+
+	defer r.wg.Done()
+	defer htlcClient.Cancel()
+	defer chanClient.Cancel()
+
+	flushTicker := time.NewTicker(reputationFlushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case e, ok := <-chanClient.Updates():
+			if !ok {
+				return
+			}
+
+			event, ok := e.(channelnotifier.ClosedChannelEvent)
+			if ok && event.CloseSummary != nil {
+				r.pruneChannel(event.CloseSummary)
+			}
+
+		case <-flushTicker.C:
+			if err := r.flushReputation(); err != nil {
+				log.Errorf("Unable to write reputation to "+
+					"disk: %v", err)
+			}
+
+		case e, ok := <-htlcClient.Updates():
+			if !ok {
+				return
+			}
+
+			switch event := e.(type) {
+			case *SettleEvent:
+				r.ResolveHtlc(
+					event.IncomingCircuit, true,
+					event.Timestamp,
+				)
+
+			case *ForwardingFailEvent:
+				r.ResolveHtlc(
+					event.IncomingCircuit, false,
+					event.Timestamp,
+				)
+
+			// Failures of the incoming link concern htlcs that we
+			// didn't forward.
+			case *LinkFailEvent:
+				if !event.Incoming {
+					r.ResolveHtlc(
+						event.IncomingCircuit, false,
+						event.Timestamp,
+					)
+				}
+
+			// The final resolution of an incoming htlc covers
+			// forwards that were resolved without any of the
+			// events above, like on-chain timeouts.
+			case *FinalHtlcEvent:
+				r.ResolveHtlc(
+					event.CircuitKey, event.Settled,
+					event.Timestamp,
+				)
+			}
+
+		case <-htlcClient.Quit():
+			return
+
+		case <-chanClient.Quit():
+			return
+
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// pruneChannel forgets the revenue and resource usage of a closed channel. If
+// it was our last channel with the peer, the reputation of the peer is
+// forgotten as well. Pending htlcs on the channel or from the peer no longer
+// count towards the forgotten state once they resolve.
+func (r *ResourceManager) pruneChannel(
+	summary *channeldb.ChannelCloseSummary) {
+
+	var peer route.Vertex
+	copy(peer[:], summary.RemotePub.SerializeCompressed())
+
+	hasChannels, err := r.cfg.HasOpenChannels(peer)
+	if err != nil {
+		log.Errorf("Unable to fetch channels of peer %x: %v", peer[:],
+			err)
+
+		// Keep the reputation of the peer rather than losing it.
+		hasChannels = true
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	chanID := summary.ShortChanID
+	delete(r.channels, chanID)
+	if !hasChannels {
+		delete(r.reputation, peer)
+	}
+
+	for _, htlc := range r.pending {
+		if htlc.OutgoingChannel == chanID {
+			htlc.outgoingClosed = true
+		}
+		if !hasChannels && htlc.IncomingPeer == peer {
+			htlc.peerPruned = true
+		}
+	}
+
+	log.Debugf("Pruned channel %v (peer %x pruned: %v)", chanID, peer[:],
+		!hasChannels)
+}
+
+// peerReputation returns the reputation of an incoming peer.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (r *ResourceManager) peerReputation(
+	peer route.Vertex) *decayingAverage {
+
+	reputation, ok := r.reputation[peer]
+	if !ok {
+		reputation = &decayingAverage{
+			window: r.cfg.RevenueWindow *
+				time.Duration(r.cfg.ReputationMultiplier),
+		}
+		r.reputation[peer] = reputation
+	}
+
+	return reputation
+}
+
+// channelUsage returns the resource usage of an outgoing channel.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (r *ResourceManager) channelUsage(
+	chanID lnwire.ShortChannelID) *channelUsage {
+
+	usage, ok := r.channels[chanID]
+	if !ok {
+		usage = &channelUsage{
+			revenue: &decayingAverage{
+				window: r.cfg.RevenueWindow,
+			},
+		}
+		r.channels[chanID] = usage
+	}
+
+	return usage
+}
+
+// maxOpportunityCost returns the opportunity cost that an endorsed htlc is
+// charged if it is held until the given number of blocks pass.
+func (r *ResourceManager) maxOpportunityCost(fee lnwire.MilliSatoshi,
+	blocks uint32) float64 {
+
+	holdTime := time.Duration(blocks) * expectedBlockTime
+
+	return float64(fee) * math.Ceil(
+		float64(holdTime)/float64(r.cfg.ResolutionPeriod),
+	)
+}
+
+// inFlightRisk returns the opportunity cost of the pending endorsed htlcs of
+// a peer if they are all held until they expire.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (r *ResourceManager) inFlightRisk(peer route.Vertex,
+	height uint32) float64 {
+
+	var risk float64
+	for _, htlc := range r.pending {
+		if htlc.IncomingPeer != peer || htlc.peerPruned ||
+			!htlc.Endorsed || htlc.IncomingExpiry <= height {
+
+			continue
+		}
+
+		risk += r.maxOpportunityCost(
+			htlc.fee(), htlc.IncomingExpiry-height,
+		)
+	}
+
+	return risk
+}
+
+// goodReputation returns true if the reputation of the incoming peer of an
+// htlc covers the revenue of the outgoing channel, after accounting for the
+// risk of its pending htlcs and of the htlc itself.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (r *ResourceManager) goodReputation(htlc *ProposedHtlc,
+	now time.Time) bool {
+
+	reputation := r.peerReputation(htlc.IncomingPeer).getValue(now)
+	revenue := r.channelUsage(htlc.OutgoingChannel).revenue.getValue(now)
+
+	risk := r.inFlightRisk(htlc.IncomingPeer, htlc.CurrentHeight)
+	if htlc.IncomingExpiry > htlc.CurrentHeight {
+		risk += r.maxOpportunityCost(
+			htlc.fee(), htlc.IncomingExpiry-htlc.CurrentHeight,
+		)
+	}
+
+	return reputation-risk >= revenue
+}
+
+// generalLimits returns the share of the resources of an outgoing channel that
+// is available to the general bucket.
+func (r *ResourceManager) generalLimits(limits ChannelLimits) ChannelLimits {
+	share := uint64(100 - r.cfg.ProtectedPercentage)
+
+	return ChannelLimits{
+		MaxHtlcs: uint16(uint64(limits.MaxHtlcs) * share / 100),
+		MaxInFlight: lnwire.MilliSatoshi(
+			uint64(limits.MaxInFlight) / 100 * share,
+		),
+	}
+}
+
+// AddHtlc decides whether a proposed htlc may use the resources of its
+// outgoing channel, and whether the outgoing htlc should be endorsed. If the
+// htlc is forwarded, it is tracked until it is resolved. In monitor only mode
+// the decision is only logged, and the htlc is forwarded with its endorsement
+// signal unchanged.
+func (r *ResourceManager) AddHtlc(htlc *ProposedHtlc) ForwardDecision {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := r.cfg.Clock.Now()
+	usage := r.channelUsage(htlc.OutgoingChannel)
+
+	decision := ForwardDecision{
+		Forward: true,
+		Bucket:  BucketGeneral,
+	}
+	if htlc.Endorsed && r.goodReputation(htlc, now) {
+		decision.Bucket = BucketProtected
+		decision.OutgoingEndorsed = true
+	} else {
+		limits := r.generalLimits(htlc.OutgoingLimits)
+		decision.Forward = usage.htlcs < limits.MaxHtlcs &&
+			usage.inFlight+htlc.OutgoingAmount <=
+				limits.MaxInFlight
+	}
+
+	log.Debugf("Resource decision for htlc %v from peer %x to channel "+
+		"%v (endorsed=%v): %v", htlc.IncomingCircuit,
+		htlc.IncomingPeer[:], htlc.OutgoingChannel, htlc.Endorsed,
+		decision)
+
+	if r.cfg.MonitorOnly {
+		if !decision.Forward {
+			log.Infof("Monitor only: would fail htlc %v to "+
+				"channel %v, general bucket is full",
+				htlc.IncomingCircuit, htlc.OutgoingChannel)
+		}
+
+		decision.Forward = true
+		decision.OutgoingEndorsed = htlc.Endorsed
+	}
+
+	if !decision.Forward {
+		return decision
+	}
+
+	if decision.Bucket == BucketGeneral {
+		usage.htlcs++
+		usage.inFlight += htlc.OutgoingAmount
+	}
+	r.pending[htlc.IncomingCircuit] = &pendingHtlc{
+		ProposedHtlc: *htlc,
+		bucket:       decision.Bucket,
+		addedAt:      now,
+	}
+
+	return decision
+}
+
+// ResolveHtlc releases the resources of a forwarded htlc and updates the
+// reputation of its incoming peer and the revenue of its outgoing channel.
+// Resolutions of htlcs that aren't tracked are ignored.
+func (r *ResourceManager) ResolveHtlc(key models.CircuitKey, settled bool,
+	resolvedAt time.Time) {
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	htlc, ok := r.pending[key]
+	if !ok {
+		return
+	}
+	delete(r.pending, key)
+
+	fee := htlc.fee()
+	if !htlc.outgoingClosed {
+		usage := r.channelUsage(htlc.OutgoingChannel)
+		if htlc.bucket == BucketGeneral {
+			usage.htlcs--
+			usage.inFlight -= htlc.OutgoingAmount
+		}
+
+		if settled {
+			usage.revenue.add(resolvedAt, float64(fee))
+		}
+	}
+
+	// The effective fees of an htlc are the fees that it earned us, minus
+	// an opportunity cost for each resolution period beyond the first
+	// that an endorsed htlc held our resources.
+	var effectiveFees float64
+	if settled {
+		effectiveFees = float64(fee)
+	}
+
+	holdTime := resolvedAt.Sub(htlc.addedAt)
+	if htlc.Endorsed && holdTime > r.cfg.ResolutionPeriod {
+		periods := math.Ceil(
+			float64(holdTime-r.cfg.ResolutionPeriod) /
+				float64(r.cfg.ResolutionPeriod),
+		)
+		effectiveFees -= float64(fee) * periods
+	}
+
+	if !htlc.peerPruned {
+		reputation := r.peerReputation(htlc.IncomingPeer)
+		reputation.add(resolvedAt, effectiveFees)
+	}
+
+	log.Debugf("Resolved htlc %v from peer %x (settled=%v) after %v, "+
+		"effective fees: %v msat", key, htlc.IncomingPeer[:], settled,
+		holdTime, effectiveFees)
+}
+
+// serializeAverage encodes the value of a decaying average and the time of its
+// last update.
+func serializeAverage(d *decayingAverage) []byte {
+	var (
+		b          [16]byte
+		lastUpdate int64
+	)
+	if !d.lastUpdate.IsZero() {
+		lastUpdate = d.lastUpdate.UnixNano()
+	}
+
+	binary.BigEndian.PutUint64(b[:8], math.Float64bits(d.value))
+	binary.BigEndian.PutUint64(b[8:], uint64(lastUpdate))
+
+	return b[:]
+}
+
+// deserializeAverage decodes a decaying average with the given window.
+func deserializeAverage(b []byte,
+	window time.Duration) (*decayingAverage, error) {
+
+	if len(b) != 16 {
+		return nil, fmt.Errorf("invalid decaying average length: %v",
+			len(b))
+	}
+
+	d := &decayingAverage{
+		value:  math.Float64frombits(binary.BigEndian.Uint64(b[:8])),
+		window: window,
+	}
+	lastUpdate := int64(binary.BigEndian.Uint64(b[8:]))
+	if lastUpdate != 0 {
+		d.lastUpdate = time.Unix(0, lastUpdate)
+	}
+
+	return d, nil
+}
+
+// loadReputation loads the reputation of our peers and the revenue of our
+// channels from the database.
+func (r *ResourceManager) loadReputation() error {
+	if r.cfg.DB == nil {
+		return nil
+	}
+
+	reputationWindow := r.cfg.RevenueWindow *
+		time.Duration(r.cfg.ReputationMultiplier)
+
+	reputation := make(map[route.Vertex]*decayingAverage)
+	channels := make(map[lnwire.ShortChannelID]*channelUsage)
+	err := kvdb.View(r.cfg.DB, func(tx kvdb.RTx) error {
+		topBucket := tx.ReadBucket(resourceManagerBucket)
+		if topBucket == nil {
+			return nil
+		}
+
+		peers := topBucket.NestedReadBucket(peerReputationBucket)
+		if peers != nil {
+			err := peers.ForEach(func(k, v []byte) error {
+				peer, err := route.NewVertexFromBytes(k)
+				if err != nil {
+					return err
+				}
+
+				reputation[peer], err = deserializeAverage(
+					v, reputationWindow,
+				)
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		revenues := topBucket.NestedReadBucket(channelRevenueBucket)
+		if revenues == nil {
+			return nil
+		}
+
+		return revenues.ForEach(func(k, v []byte) error {
+			if len(k) != 8 {
+				return fmt.Errorf("invalid channel id "+
+					"length: %v", len(k))
+			}
+
+			revenue, err := deserializeAverage(
+				v, r.cfg.RevenueWindow,
+			)
+			if err != nil {
+				return err
+			}
+
+			chanID := lnwire.NewShortChanIDFromInt(
+				binary.BigEndian.Uint64(k),
+			)
+			channels[chanID] = &channelUsage{revenue: revenue}
+
+			return nil
+		})
+	}, func() {
+		reputation = make(map[route.Vertex]*decayingAverage)
+		channels = make(map[lnwire.ShortChannelID]*channelUsage)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to load reputation: %w", err)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.reputation = reputation
+	r.channels = channels
+
+	log.Infof("Loaded reputation of %v peers and revenue of %v channels",
+		len(reputation), len(channels))
+
+	return nil
+}
+
+// flushReputation writes the reputation of our peers and the revenue of our
+// channels to the database, replacing the values that were written before.
+// State that was pruned since then is therefore removed from disk as well.
+func (r *ResourceManager) flushReputation() error {
+	if r.cfg.DB == nil {
+		return nil
+	}
+
+	// Serialize the values first, so that the mutex isn't held while
+	// writing to disk.
+	r.mtx.Lock()
+	peers := make(map[route.Vertex][]byte, len(r.reputation))
+	for peer, reputation := range r.reputation {
+		peers[peer] = serializeAverage(reputation)
+	}
+	revenues := make(map[lnwire.ShortChannelID][]byte, len(r.channels))
+	for chanID, usage := range r.channels {
+		revenues[chanID] = serializeAverage(usage.revenue)
+	}
+	r.mtx.Unlock()
+
+	return kvdb.Update(r.cfg.DB, func(tx kvdb.RwTx) error {
+		err := tx.DeleteTopLevelBucket(resourceManagerBucket)
+		if err != nil && !errors.Is(err, kvdb.ErrBucketNotFound) {
+			return err
+		}
+
+		topBucket, err := tx.CreateTopLevelBucket(
+			resourceManagerBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		peerBucket, err := topBucket.CreateBucket(peerReputationBucket)
+		if err != nil {
+			return err
+		}
+		for peer, value := range peers {
+			if err := peerBucket.Put(peer[:], value); err != nil {
+				return err
+			}
+		}
+
+		revenueBucket, err := topBucket.CreateBucket(
+			channelRevenueBucket,
+		)
+		if err != nil {
+			return err
+		}
+		for chanID, value := range revenues {
+			var k [8]byte
+			binary.BigEndian.PutUint64(k[:], chanID.ToUint64())
+
+			if err := revenueBucket.Put(k[:], value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func() {})
+}
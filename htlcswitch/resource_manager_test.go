@@ -0,0 +1,499 @@
+package htlcswitch
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/channelnotifier"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testResourcePeer = route.Vertex{1}
+
+	testResourceChan  = lnwire.NewShortChanIDFromInt(10)
+	testResourceChan2 = lnwire.NewShortChanIDFromInt(11)
+)
+
+// newTestResourceManager creates a resource manager that reserves half of the
+// channel resources for protected htlcs and expects htlcs to resolve within a
+// minute.
+func newTestResourceManager(t *testing.T, testClock clock.Clock,
+	monitorOnly bool) *ResourceManager {
+
+	mgr, err := NewResourceManager(&ResourceManagerConfig{
+		MonitorOnly:          monitorOnly,
+		ProtectedPercentage:  50,
+		RevenueWindow:        time.Hour,
+		ReputationMultiplier: 10,
+		ResolutionPeriod:     time.Minute,
+		Clock:                testClock,
+	})
+	require.NoError(t, err)
+
+	return mgr
+}
+
+// testProposedHtlc returns an htlc from the test peer that earns a fee of
+// 1000 msat and expires in one block.
+func testProposedHtlc(htlcID uint64, outgoing lnwire.ShortChannelID,
+	amt lnwire.MilliSatoshi, endorsed bool) *ProposedHtlc {
+
+	return &ProposedHtlc{
+		IncomingCircuit: models.CircuitKey{
+			ChanID: lnwire.NewShortChanIDFromInt(1),
+			HtlcID: htlcID,
+		},
+		IncomingPeer:    testResourcePeer,
+		OutgoingChannel: outgoing,
+		OutgoingLimits: ChannelLimits{
+			MaxHtlcs:    4,
+			MaxInFlight: 10_000_000,
+		},
+		IncomingAmount: amt + 1000,
+		OutgoingAmount: amt,
+		IncomingExpiry: 101,
+		CurrentHeight:  100,
+		Endorsed:       endorsed,
+	}
+}
+
+// TestResourceManagerConfig tests the validation of the resource manager
+// config.
+func TestResourceManagerConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &ResourceManagerConfig{
+		ProtectedPercentage:  101,
+		RevenueWindow:        time.Hour,
+		ReputationMultiplier: 1,
+		ResolutionPeriod:     time.Minute,
+	}
+	require.ErrorIs(t, cfg.Validate(), errInvalidProtectedPercentage)
+
+	cfg.ProtectedPercentage = 100
+	require.NoError(t, cfg.Validate())
+
+	cfg.ReputationMultiplier = 0
+	require.ErrorIs(t, cfg.Validate(), errInvalidResourceWindow)
+}
+
+// TestResourceManagerGeneralBucket tests that htlcs in the general bucket are
+// limited to the unprotected share of the slots and liquidity of the outgoing
+// channel, and that resolved htlcs release their resources.
+func TestResourceManagerGeneralBucket(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestResourceManager(
+		t, clock.NewTestClock(time.Unix(1, 0)), false,
+	)
+
+	// The general bucket has two slots and 5_000_000 msat of liquidity.
+	decision := mgr.AddHtlc(
+		testProposedHtlc(0, testResourceChan, 1_000_000, false),
+	)
+	require.True(t, decision.Forward)
+	require.Equal(t, BucketGeneral, decision.Bucket)
+	require.False(t, decision.OutgoingEndorsed)
+
+	// An htlc that exceeds the liquidity of the bucket is failed.
+	decision = mgr.AddHtlc(
+		testProposedHtlc(1, testResourceChan, 4_500_000, false),
+	)
+	require.False(t, decision.Forward)
+
+	decision = mgr.AddHtlc(
+		testProposedHtlc(2, testResourceChan, 1_000_000, false),
+	)
+	require.True(t, decision.Forward)
+
+	// Both slots are taken now.
+	decision = mgr.AddHtlc(
+		testProposedHtlc(3, testResourceChan, 1_000_000, false),
+	)
+	require.False(t, decision.Forward)
+
+	// Other channels have their own buckets.
+	decision = mgr.AddHtlc(
+		testProposedHtlc(3, testResourceChan2, 1_000_000, false),
+	)
+	require.True(t, decision.Forward)
+
+	// Once an htlc resolves, its slot can be used again. Resolutions of
+	// unknown htlcs are ignored.
+	now := mgr.cfg.Clock.Now()
+	mgr.ResolveHtlc(models.CircuitKey{HtlcID: 100}, true, now)
+	mgr.ResolveHtlc(
+		testProposedHtlc(0, testResourceChan, 0, false).IncomingCircuit,
+		false, now,
+	)
+
+	decision = mgr.AddHtlc(
+		testProposedHtlc(4, testResourceChan, 1_000_000, false),
+	)
+	require.True(t, decision.Forward)
+}
+
+// TestResourceManagerReputation tests that endorsed htlcs are only protected
+// if the reputation of their peer covers the revenue of the outgoing channel
+// and the risk of its pending htlcs, and that slow htlcs hurt the reputation.
+func TestResourceManagerReputation(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1, 0))
+	mgr := newTestResourceManager(t, testClock, false)
+
+	// Without any reputation, an endorsed htlc ends up in the general
+	// bucket and is forwarded unendorsed.
+	htlc := testProposedHtlc(0, testResourceChan, 1_000_000, true)
+	decision := mgr.AddHtlc(htlc)
+	require.True(t, decision.Forward)
+	require.Equal(t, BucketGeneral, decision.Bucket)
+	require.False(t, decision.OutgoingEndorsed)
+	mgr.ResolveHtlc(htlc.IncomingCircuit, false, testClock.Now())
+
+	// Build up reputation by quickly settling 20 htlcs over another
+	// channel, each earning 1000 msat of fees.
+	for i := uint64(1); i <= 20; i++ {
+		htlc := testProposedHtlc(i, testResourceChan2, 1000, false)
+		htlc.OutgoingLimits.MaxHtlcs = 100
+
+		require.True(t, mgr.AddHtlc(htlc).Forward)
+		mgr.ResolveHtlc(htlc.IncomingCircuit, true, testClock.Now())
+	}
+
+	// The reputation of 20_000 msat covers the risk of two endorsed htlcs
+	// of 10_000 msat each, as they may be held for ten resolution periods.
+	var protected []*ProposedHtlc
+	for i := uint64(21); i <= 22; i++ {
+		htlc := testProposedHtlc(i, testResourceChan, 1_000_000, true)
+		decision := mgr.AddHtlc(htlc)
+		require.True(t, decision.Forward)
+		require.Equal(t, BucketProtected, decision.Bucket)
+		require.True(t, decision.OutgoingEndorsed)
+
+		protected = append(protected, htlc)
+	}
+
+	// Protected htlcs don't use the general bucket.
+	require.Zero(t, mgr.channels[testResourceChan].htlcs)
+
+	// A third endorsed htlc exceeds the reputation of the peer.
+	decision = mgr.AddHtlc(
+		testProposedHtlc(23, testResourceChan, 1_000_000, true),
+	)
+	require.Equal(t, BucketGeneral, decision.Bucket)
+	require.False(t, decision.OutgoingEndorsed)
+
+	// Holding one of the protected htlcs for half an hour costs the peer
+	// its reputation, even though the htlc settles eventually.
+	testClock.SetTime(testClock.Now().Add(30 * time.Minute))
+	mgr.ResolveHtlc(protected[0].IncomingCircuit, true, testClock.Now())
+	mgr.ResolveHtlc(protected[1].IncomingCircuit, true, testClock.Now())
+
+	decision = mgr.AddHtlc(
+		testProposedHtlc(24, testResourceChan, 1_000_000, true),
+	)
+	require.Equal(t, BucketGeneral, decision.Bucket)
+	require.False(t, decision.OutgoingEndorsed)
+}
+
+// TestResourceManagerMonitorOnly tests that the resource manager forwards all
+// htlcs with their endorsement signal unchanged in monitor only mode, while
+// still tracking their resources.
+func TestResourceManagerMonitorOnly(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestResourceManager(
+		t, clock.NewTestClock(time.Unix(1, 0)), true,
+	)
+
+	for i := uint64(0); i < 4; i++ {
+		decision := mgr.AddHtlc(
+			testProposedHtlc(i, testResourceChan, 1_000_000, true),
+		)
+		require.True(t, decision.Forward)
+		require.Equal(t, BucketGeneral, decision.Bucket)
+		require.True(t, decision.OutgoingEndorsed)
+	}
+
+	require.EqualValues(t, 4, mgr.channels[testResourceChan].htlcs)
+}
+
+// TestResourceManagerEvents tests that the resource manager resolves htlcs
+// from the events of the htlc notifier.
+func TestResourceManagerEvents(t *testing.T) {
+	t.Parallel()
+
+	ntfnServer := subscribe.NewServer()
+	require.NoError(t, ntfnServer.Start())
+	t.Cleanup(func() {
+		require.NoError(t, ntfnServer.Stop())
+	})
+
+	testClock := clock.NewTestClock(time.Unix(1, 0))
+	mgr := newTestResourceManager(t, testClock, false)
+	mgr.cfg.SubscribeHtlcEvents = ntfnServer.Subscribe
+	mgr.cfg.SubscribeChannelEvents = ntfnServer.Subscribe
+	mgr.cfg.HasOpenChannels = func(route.Vertex) (bool, error) {
+		return false, nil
+	}
+
+	require.NoError(t, mgr.Start())
+	t.Cleanup(func() {
+		require.NoError(t, mgr.Stop())
+	})
+
+	settled := testProposedHtlc(0, testResourceChan, 1_000_000, false)
+	failed := testProposedHtlc(1, testResourceChan, 1_000_000, false)
+	incomingFail := testProposedHtlc(2, testResourceChan, 1_000_000, false)
+	for _, htlc := range []*ProposedHtlc{settled, failed, incomingFail} {
+		htlc.OutgoingLimits.MaxHtlcs = 100
+		require.True(t, mgr.AddHtlc(htlc).Forward)
+	}
+
+	numPending := func() int {
+		mgr.mtx.Lock()
+		defer mgr.mtx.Unlock()
+
+		return len(mgr.pending)
+	}
+
+	events := []interface{}{
+		&SettleEvent{
+			HtlcKey: HtlcKey{
+				IncomingCircuit: settled.IncomingCircuit,
+			},
+			Timestamp: testClock.Now(),
+		},
+		&ForwardingFailEvent{
+			HtlcKey: HtlcKey{
+				IncomingCircuit: failed.IncomingCircuit,
+			},
+			Timestamp: testClock.Now(),
+		},
+
+		// Failures of the incoming link don't resolve forwards.
+		&LinkFailEvent{
+			HtlcKey: HtlcKey{
+				IncomingCircuit: incomingFail.IncomingCircuit,
+			},
+			Incoming:  true,
+			Timestamp: testClock.Now(),
+		},
+	}
+	for _, event := range events {
+		require.NoError(t, ntfnServer.SendUpdate(event))
+	}
+
+	require.Eventually(t, func() bool {
+		return numPending() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The final resolution of the incoming htlc releases the rest.
+	require.NoError(t, ntfnServer.SendUpdate(&FinalHtlcEvent{
+		CircuitKey: incomingFail.IncomingCircuit,
+		Timestamp:  testClock.Now(),
+	}))
+
+	require.Eventually(t, func() bool {
+		return numPending() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Closing the channel forgets its state.
+	peerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	require.NoError(t, ntfnServer.SendUpdate(
+		channelnotifier.ClosedChannelEvent{
+			CloseSummary: &channeldb.ChannelCloseSummary{
+				ShortChanID: testResourceChan,
+				RemotePub:   peerKey.PubKey(),
+			},
+		},
+	))
+
+	require.Eventually(t, func() bool {
+		mgr.mtx.Lock()
+		defer mgr.mtx.Unlock()
+
+		_, ok := mgr.channels[testResourceChan]
+
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestResourceManagerPrune tests that the state of a channel is forgotten
+// once it closes, and the reputation of a peer once its last channel closes,
+// including when pending htlcs resolve afterwards.
+func TestResourceManagerPrune(t *testing.T) {
+	t.Parallel()
+
+	peerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	peer := route.NewVertex(peerKey.PubKey())
+
+	testClock := clock.NewTestClock(time.Unix(1, 0))
+	mgr := newTestResourceManager(t, testClock, false)
+
+	hasChannels := true
+	mgr.cfg.HasOpenChannels = func(node route.Vertex) (bool, error) {
+		require.Equal(t, peer, node)
+
+		return hasChannels, nil
+	}
+
+	// Settle an htlc to build up some reputation and revenue, and leave
+	// an htlc pending on each of the channels.
+	var htlcs []*ProposedHtlc
+	for i, chanID := range []lnwire.ShortChannelID{
+		testResourceChan, testResourceChan, testResourceChan2,
+	} {
+		htlc := testProposedHtlc(uint64(i), chanID, 1000, false)
+		htlc.IncomingPeer = peer
+		require.True(t, mgr.AddHtlc(htlc).Forward)
+
+		htlcs = append(htlcs, htlc)
+	}
+	mgr.ResolveHtlc(htlcs[0].IncomingCircuit, true, testClock.Now())
+
+	closeSummary := func(chanID lnwire.ShortChannelID) *channeldb.
+		ChannelCloseSummary {
+
+		return &channeldb.ChannelCloseSummary{
+			ShortChanID: chanID,
+			RemotePub:   peerKey.PubKey(),
+		}
+	}
+
+	// Closing the second channel forgets its state, but not the
+	// reputation of the peer, as we still have a channel with it. The
+	// pending htlc of the closed channel doesn't bring its state back.
+	mgr.pruneChannel(closeSummary(testResourceChan2))
+	require.NotContains(t, mgr.channels, testResourceChan2)
+	require.Contains(t, mgr.reputation, peer)
+
+	mgr.ResolveHtlc(htlcs[2].IncomingCircuit, true, testClock.Now())
+	require.NotContains(t, mgr.channels, testResourceChan2)
+	require.Contains(t, mgr.reputation, peer)
+
+	// Closing the last channel also forgets the reputation of the peer.
+	hasChannels = false
+	mgr.pruneChannel(closeSummary(testResourceChan))
+	require.Empty(t, mgr.channels)
+	require.Empty(t, mgr.reputation)
+
+	mgr.ResolveHtlc(htlcs[1].IncomingCircuit, true, testClock.Now())
+	require.Empty(t, mgr.channels)
+	require.Empty(t, mgr.reputation)
+	require.Empty(t, mgr.pending)
+}
+
+// TestResourceManagerPersistence tests that the reputation of our peers and
+// the revenue of our channels survive a restart, and that pruned state is
+// removed from disk.
+func TestResourceManagerPersistence(t *testing.T) {
+	t.Parallel()
+
+	db, err := kvdb.Create(
+		kvdb.BoltBackendName, filepath.Join(t.TempDir(), "rep.db"),
+		true, kvdb.DefaultDBTimeout,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	testClock := clock.NewTestClock(time.Unix(1, 0))
+	newManager := func() *ResourceManager {
+		mgr := newTestResourceManager(t, testClock, false)
+		mgr.cfg.DB = db
+		mgr.cfg.HasOpenChannels = func(route.Vertex) (bool, error) {
+			return true, nil
+		}
+		require.NoError(t, mgr.loadReputation())
+
+		return mgr
+	}
+
+	mgr := newManager()
+	for i, chanID := range []lnwire.ShortChannelID{
+		testResourceChan, testResourceChan2,
+	} {
+		htlc := testProposedHtlc(uint64(i), chanID, 1000, false)
+		require.True(t, mgr.AddHtlc(htlc).Forward)
+		mgr.ResolveHtlc(htlc.IncomingCircuit, true, testClock.Now())
+	}
+	require.NoError(t, mgr.Stop())
+
+	// After a restart, the values decay from where they were left.
+	testClock.SetTime(testClock.Now().Add(time.Hour))
+	now := testClock.Now()
+
+	restarted := newManager()
+	require.Len(t, restarted.reputation, 1)
+	require.Len(t, restarted.channels, 2)
+	require.InDelta(
+		t, mgr.reputation[testResourcePeer].getValue(now),
+		restarted.reputation[testResourcePeer].getValue(now), 1e-9,
+	)
+	for chanID, usage := range mgr.channels {
+		require.InDelta(
+			t, usage.revenue.getValue(now),
+			restarted.channels[chanID].revenue.getValue(now), 1e-9,
+		)
+	}
+
+	// A closed channel is removed from disk on the next flush.
+	peerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	restarted.pruneChannel(&channeldb.ChannelCloseSummary{
+		ShortChanID: testResourceChan2,
+		RemotePub:   peerKey.PubKey(),
+	})
+	require.NoError(t, restarted.flushReputation())
+
+	restarted = newManager()
+	require.Len(t, restarted.reputation, 1)
+	require.Len(t, restarted.channels, 1)
+	require.Contains(t, restarted.channels, testResourceChan)
+}
+
+// TestEndorsementSignal tests that the experimental endorsement signal is read
+// from and written to the custom records of an htlc.
+func TestEndorsementSignal(t *testing.T) {
+	t.Parallel()
+
+	endorsementType := uint64(lnwire.ExperimentalEndorsementType)
+
+	require.False(t, isEndorsed(nil))
+	require.False(t, isEndorsed(lnwire.CustomRecords{
+		endorsementType: {lnwire.ExperimentalUnendorsed},
+	}))
+	require.False(t, isEndorsed(lnwire.CustomRecords{
+		endorsementType: {lnwire.ExperimentalEndorsed, 0},
+	}))
+
+	// Setting the signal doesn't modify the records it is given, and keeps
+	// the other records.
+	records := lnwire.CustomRecords{
+		lnwire.MinCustomRecordsTlvType: {1, 2, 3},
+	}
+	endorsed := withEndorsement(records, true)
+	require.True(t, isEndorsed(endorsed))
+	require.Len(t, endorsed, 2)
+	require.Len(t, records, 1)
+
+	require.False(t, isEndorsed(withEndorsement(endorsed, false)))
+	require.True(t, isEndorsed(withEndorsement(nil, true)))
+}
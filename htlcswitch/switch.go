@@ -26,6 +26,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/ticker"
 )
 
@@ -225,6 +226,12 @@ type Config struct {
 
 	// IsAlias returns whether or not a given SCID is an alias.
 	IsAlias func(scid lnwire.ShortChannelID) bool
+
+	// ResourceManager, if set, protects our channels against jamming by
+	// limiting the slots and liquidity that forwarded htlcs may use on
+	// their outgoing channel based on their endorsement signal and the
+	// reputation of their incoming peer.
+	ResourceManager *ResourceManager
 }
 
 // Switch is the central messaging bus for all incoming/outgoing HTLCs.
@@ -2177,6 +2184,34 @@ func (s *Switch) GetLinkByShortID(chanID lnwire.ShortChannelID) (ChannelLink,
 	return link, nil
 }
 
+// DustExposureExceeded returns true if adding an outgoing HTLC of the given
+// amount to the link with the target short channel ID would push its dust
+// exposure over the fee exposure threshold, in which case the switch would
+// fail the HTLC.
+func (s *Switch) DustExposureExceeded(chanID lnwire.ShortChannelID,
+	amount lnwire.MilliSatoshi) (bool, error) {
+
+	link, err := s.GetLinkByShortID(chanID)
+	if err != nil {
+		return false, err
+	}
+
+	return s.dustExceedsFeeThreshold(link, amount, false), nil
+}
+
+// linkPeer returns the public key of the peer of the link with the given short
+// channel id, if the link is known to the switch.
+func (s *Switch) linkPeer(
+	chanID lnwire.ShortChannelID) fn.Option[route.Vertex] {
+
+	link, err := s.GetLinkByShortID(chanID)
+	if err != nil {
+		return fn.None[route.Vertex]()
+	}
+
+	return fn.Some(route.Vertex(link.PeerPubKey()))
+}
+
 // getLinkByShortID attempts to return the link which possesses the target
 // short channel ID.
 //
@@ -2836,6 +2871,12 @@ func (s *Switch) handlePacketAdd(packet *htlcPacket,
 	interfaceLinks, _ := s.getLinks(targetPeerKey)
 	s.indexMtx.RUnlock()
 
+	// If an interceptor picked the outgoing channel of the htlc, we don't
+	// try the other channels to the same peer.
+	if packet.strictOutgoingChan {
+		interfaceLinks = []ChannelLink{targetLink}
+	}
+
 	// We'll keep track of any HTLC failures during the link selection
 	// process. This way we can return the error for precise link that the
 	// sender selected, while optimistically trying all links to utilize
@@ -2956,6 +2997,36 @@ func (s *Switch) handlePacketAdd(packet *htlcPacket,
 		return s.failAddPacket(packet, linkErr)
 	}
 
+	// If jamming mitigation is enabled, check that the bucket of the htlc
+	// has resources left on the destination link. An endorsed htlc is only
+	// forwarded as endorsed if its incoming peer has good reputation. The
+	// endorsement signal is only relayed while jamming mitigation is on.
+	if s.cfg.ResourceManager != nil {
+		decision := s.cfg.ResourceManager.AddHtlc(&ProposedHtlc{
+			IncomingCircuit: packet.inKey(),
+			IncomingPeer:    incomingLink.PeerPubKey(),
+			OutgoingChannel: destination.ShortChanID(),
+			OutgoingLimits:  destination.getOutgoingLimits(),
+			IncomingAmount:  packet.incomingAmount,
+			OutgoingAmount:  packet.amount,
+			IncomingExpiry:  packet.incomingTimeout,
+			CurrentHeight:   atomic.LoadUint32(&s.bestHeight),
+			Endorsed:        packet.incomingEndorsed,
+		})
+		if !decision.Forward {
+			linkErr := NewDetailedLinkError(
+				&lnwire.FailTemporaryChannelFailure{},
+				OutgoingFailureResourceBucketFull,
+			)
+
+			return s.failAddPacket(packet, linkErr)
+		}
+
+		htlc.CustomRecords = withEndorsement(
+			htlc.CustomRecords, decision.OutgoingEndorsed,
+		)
+	}
+
 	// Send the packet to the destination channel link which manages the
 	// channel.
 	packet.outgoingChanID = destination.ShortChanID()
@@ -3012,6 +3083,11 @@ func (s *Switch) handlePacketSettle(packet *htlcPacket) error {
 			circuit.IncomingAmount-circuit.OutgoingAmount,
 			circuit.Incoming.ChanID, circuit.Outgoing.ChanID)
 
+		// Record the peers of both channels, so that the forwarding
+		// log can be queried by peer.
+		incomingPeer := s.linkPeer(circuit.Incoming.ChanID)
+		outgoingPeer := s.linkPeer(circuit.Outgoing.ChanID)
+
 		s.fwdEventMtx.Lock()
 		s.pendingFwdingEvents = append(
 			s.pendingFwdingEvents,
@@ -3021,6 +3097,8 @@ func (s *Switch) handlePacketSettle(packet *htlcPacket) error {
 				OutgoingChanID: circuit.Outgoing.ChanID,
 				AmtIn:          circuit.IncomingAmount,
 				AmtOut:         circuit.OutgoingAmount,
+				IncomingPeer:   incomingPeer,
+				OutgoingPeer:   outgoingPeer,
 			},
 		)
 		s.fwdEventMtx.Unlock()
@@ -17,6 +17,7 @@ import (
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/contractcourt"
 	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch/hodl"
@@ -4199,6 +4200,311 @@ func TestSwitchHoldForward(t *testing.T) {
 	}
 }
 
+// TestSwitchHoldForwardModified tests that an intercepted forward can be
+// resumed with a modified outgoing channel, amount and set of custom records.
+func TestSwitchHoldForwardModified(t *testing.T) {
+	t.Parallel()
+
+	c := newInterceptableSwitchTestContext(t)
+	defer c.finish()
+
+	// Add a second channel to Bob and a channel to Carol.
+	chanID3, bobChanID2 := genID()
+	bobChannelLink2 := newMockChannelLink(
+		c.s, chanID3, bobChanID2, emptyScid, c.bobChannelLink.peer,
+		true, false, false, false,
+	)
+	require.NoError(t, c.s.AddLink(bobChannelLink2))
+
+	carolPeer, err := newMockServer(
+		t, "carol", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err)
+
+	chanID4, carolChanID := genID()
+	carolChannelLink := newMockChannelLink(
+		c.s, chanID4, carolChanID, emptyScid, carolPeer, true, false,
+		false, false,
+	)
+	require.NoError(t, c.s.AddLink(carolChannelLink))
+
+	notifier := &mock.ChainNotifier{
+		EpochChan: make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	notifier.EpochChan <- &chainntnfs.BlockEpoch{Height: testStartingHeight}
+
+	switchForwardInterceptor, err := NewInterceptableSwitch(
+		&InterceptableSwitchConfig{
+			Switch:             c.s,
+			CltvRejectDelta:    c.cltvRejectDelta,
+			CltvInterceptDelta: c.cltvInterceptDelta,
+			Notifier:           notifier,
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, switchForwardInterceptor.Start())
+	defer func() {
+		require.NoError(t, switchForwardInterceptor.Stop())
+	}()
+
+	switchForwardInterceptor.SetInterceptor(
+		c.forwardInterceptor.InterceptForwardHtlc,
+	)
+	linkQuit := make(chan struct{})
+
+	packet := c.createTestPacket()
+	packet.incomingAmount = 1000
+	packet.amount = 900
+	packet.htlc.(*lnwire.UpdateAddHTLC).Amount = 900
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false, packet,
+	))
+	key := c.forwardInterceptor.getIntercepted().IncomingCircuit
+
+	// Resuming on a channel to a different peer is rejected, and the htlc
+	// remains held.
+	err = switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:            key,
+		Action:         FwdActionResumeModified,
+		OutgoingChanID: fn.Some(carolChanID),
+	})
+	require.ErrorIs(t, err, ErrInvalidModifiedChan)
+
+	// An amount that exceeds the incoming amount is rejected as well.
+	err = switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionResumeModified,
+		OutAmountMsat: fn.Some(lnwire.MilliSatoshi(1001)),
+	})
+	require.ErrorIs(t, err, ErrInvalidModifiedAmount)
+
+	// Custom records must be in the custom type range.
+	err = switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:    key,
+		Action: FwdActionResumeModified,
+		OutWireCustomRecords: fn.Some(lnwire.CustomRecords{
+			1: []byte{1},
+		}),
+	})
+	require.Error(t, err)
+
+	assertNumCircuits(t, c.s, 0, 0)
+
+	// Resume the htlc on Bob's second channel with a lower amount and a
+	// custom record.
+	customRecords := lnwire.CustomRecords{
+		lnwire.MinCustomRecordsTlvType: []byte{1, 2, 3},
+	}
+	require.NoError(t, switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:                  key,
+		Action:               FwdActionResumeModified,
+		OutgoingChanID:       fn.Some(bobChanID2),
+		OutAmountMsat:        fn.Some(lnwire.MilliSatoshi(800)),
+		OutWireCustomRecords: fn.Some(customRecords),
+	}))
+
+	assertOutgoingLinkReceive(t, c.bobChannelLink, false)
+	receivedPkt := assertOutgoingLinkReceive(t, bobChannelLink2, true)
+	assertNumCircuits(t, c.s, 1, 1)
+
+	htlc, ok := receivedPkt.htlc.(*lnwire.UpdateAddHTLC)
+	require.True(t, ok)
+	require.Equal(t, lnwire.MilliSatoshi(800), htlc.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(800), receivedPkt.amount)
+	require.Equal(t, customRecords, htlc.CustomRecords)
+
+	// The intercepted htlc itself is not modified.
+	require.Equal(
+		t, lnwire.MilliSatoshi(900),
+		packet.htlc.(*lnwire.UpdateAddHTLC).Amount,
+	)
+}
+
+// TestSwitchInterceptorChain tests that forwards pass through multiple
+// interceptors in order of priority and that filters and timeouts are applied.
+func TestSwitchInterceptorChain(t *testing.T) {
+	t.Parallel()
+
+	c := newInterceptableSwitchTestContext(t)
+	defer c.finish()
+
+	notifier := &mock.ChainNotifier{
+		EpochChan: make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	notifier.EpochChan <- &chainntnfs.BlockEpoch{Height: testStartingHeight}
+
+	switchForwardInterceptor, err := NewInterceptableSwitch(
+		&InterceptableSwitchConfig{
+			Switch:             c.s,
+			CltvRejectDelta:    c.cltvRejectDelta,
+			CltvInterceptDelta: c.cltvInterceptDelta,
+			Notifier:           notifier,
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, switchForwardInterceptor.Start())
+	defer func() {
+		require.NoError(t, switchForwardInterceptor.Stop())
+	}()
+
+	// Use buffered channels, so that offering a forward to the next
+	// interceptor doesn't block the resolution of the previous one.
+	first := &mockForwardInterceptor{
+		t:               t,
+		interceptedChan: make(chan InterceptedPacket, 1),
+	}
+	second := &mockForwardInterceptor{
+		t:               t,
+		interceptedChan: make(chan InterceptedPacket, 1),
+	}
+
+	// Invalid registrations are rejected.
+	_, err = switchForwardInterceptor.AddInterceptor(
+		InterceptorRegistration{},
+	)
+	require.Error(t, err)
+
+	_, err = switchForwardInterceptor.AddInterceptor(
+		InterceptorRegistration{
+			Interceptor:   first.InterceptForwardHtlc,
+			TimeoutAction: FwdActionSettle,
+		},
+	)
+	require.Error(t, err)
+
+	// Register the second interceptor first. The first interceptor still
+	// sees forwards before it because of its lower priority, but only if
+	// they pass its filter.
+	secondReg := InterceptorRegistration{
+		Interceptor: second.InterceptForwardHtlc,
+		Priority:    10,
+	}
+	secondID, err := switchForwardInterceptor.AddInterceptor(secondReg)
+	require.NoError(t, err)
+
+	firstID, err := switchForwardInterceptor.AddInterceptor(
+		InterceptorRegistration{
+			Interceptor: first.InterceptForwardHtlc,
+			Priority:    5,
+			Filter: InterceptFilter{
+				MinAmount: 500,
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	err = switchForwardInterceptor.UpdateInterceptor(
+		firstID+secondID, secondReg,
+	)
+	require.ErrorIs(t, err, ErrInterceptorNotFound)
+
+	linkQuit := make(chan struct{})
+
+	// A large htlc is offered to the first interceptor.
+	packet := c.createTestPacket()
+	packet.incomingAmount = 1000
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false, packet,
+	))
+	key := first.getIntercepted().IncomingCircuit
+
+	// The second interceptor can't resolve it yet.
+	err = switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionResume,
+		InterceptorID: secondID,
+	})
+	require.ErrorIs(t, err, ErrFwdNotHeld)
+
+	// Resuming hands the htlc on to the second interceptor.
+	require.NoError(t, switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionResume,
+		InterceptorID: firstID,
+	}))
+	require.Equal(t, key, second.getIntercepted().IncomingCircuit)
+	assertOutgoingLinkReceive(t, c.bobChannelLink, false)
+
+	// Only the last interceptor releases the htlc to the switch.
+	require.NoError(t, switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionResume,
+		InterceptorID: secondID,
+	}))
+	receivedPkt := assertOutgoingLinkReceive(t, c.bobChannelLink, true)
+	assertNumCircuits(t, c.s, 1, 1)
+
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false,
+		c.createSettlePacket(receivedPkt.outgoingHTLCID),
+	))
+	assertOutgoingLinkReceive(t, c.aliceChannelLink, true)
+	assertNumCircuits(t, c.s, 0, 0)
+
+	// A small htlc skips the first interceptor.
+	packet = c.createTestPacket()
+	packet.incomingAmount = 100
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false, packet,
+	))
+	key = second.getIntercepted().IncomingCircuit
+
+	require.NoError(t, switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionFail,
+		FailureCode:   lnwire.CodeTemporaryChannelFailure,
+		InterceptorID: secondID,
+	}))
+	assertOutgoingLinkReceive(t, c.aliceChannelLink, true)
+	assertNumCircuits(t, c.s, 0, 0)
+
+	// Let the second interceptor fail htlcs that it doesn't resolve in
+	// time.
+	secondReg.Timeout = 100 * time.Millisecond
+	secondReg.TimeoutAction = FwdActionFail
+	require.NoError(t, switchForwardInterceptor.UpdateInterceptor(
+		secondID, secondReg,
+	))
+
+	packet = c.createTestPacket()
+	packet.incomingAmount = 100
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false, packet,
+	))
+	_ = second.getIntercepted()
+
+	assertOutgoingLinkReceive(t, c.bobChannelLink, false)
+	assertOutgoingLinkReceive(t, c.aliceChannelLink, true)
+	assertNumCircuits(t, c.s, 0, 0)
+
+	// Removing the first interceptor hands its held htlcs on to the second
+	// one.
+	packet = c.createTestPacket()
+	packet.incomingAmount = 1000
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false, packet,
+	))
+	key = first.getIntercepted().IncomingCircuit
+
+	switchForwardInterceptor.RemoveInterceptor(firstID)
+	require.Equal(t, key, second.getIntercepted().IncomingCircuit)
+
+	require.NoError(t, switchForwardInterceptor.Resolve(&FwdResolution{
+		Key:           key,
+		Action:        FwdActionResume,
+		InterceptorID: secondID,
+	}))
+	receivedPkt = assertOutgoingLinkReceive(t, c.bobChannelLink, true)
+	assertNumCircuits(t, c.s, 1, 1)
+
+	require.NoError(t, switchForwardInterceptor.ForwardPackets(
+		linkQuit, false,
+		c.createSettlePacket(receivedPkt.outgoingHTLCID),
+	))
+	assertOutgoingLinkReceive(t, c.aliceChannelLink, true)
+	assertNumCircuits(t, c.s, 0, 0)
+}
+
 func TestInterceptableSwitchWatchDog(t *testing.T) {
 	t.Parallel()
 
@@ -5593,3 +5899,132 @@ func testSwitchAliasInterceptFail(t *testing.T, zeroConf bool) {
 
 	require.NoError(t, interceptSwitch.Stop())
 }
+
+// TestSwitchResourceManager tests that the switch fails htlcs whose resource
+// bucket is full on the outgoing link, and that endorsed htlcs from peers
+// without good reputation are forwarded unendorsed.
+func TestSwitchResourceManager(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err)
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err)
+
+	s, err := initSwitchWithTempDB(t, testStartingHeight)
+	require.NoError(t, err)
+
+	s.cfg.ResourceManager, err = NewResourceManager(
+		&ResourceManagerConfig{
+			ProtectedPercentage:  50,
+			RevenueWindow:        time.Hour,
+			ReputationMultiplier: 2,
+			ResolutionPeriod:     time.Minute,
+			Clock:                clock.NewTestClock(time.Unix(1, 0)),
+		},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Start())
+	t.Cleanup(func() {
+		require.NoError(t, s.Stop())
+	})
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+
+	aliceLink := newMockChannelLink(
+		s, chanID1, aliceChanID, emptyScid, alicePeer, true, false,
+		false, false,
+	)
+	bobLink := newMockChannelLink(
+		s, chanID2, bobChanID, emptyScid, bobPeer, true, false, false,
+		false,
+	)
+
+	// Half of Bob's four slots are available to the general bucket.
+	bobLink.outgoingLimits = ChannelLimits{
+		MaxHtlcs:    4,
+		MaxInFlight: lnwire.MaxMilliSatoshi,
+	}
+	require.NoError(t, s.AddLink(aliceLink))
+	require.NoError(t, s.AddLink(bobLink))
+
+	forward := func(htlcID uint64, endorsed bool) {
+		t.Helper()
+
+		preimage, err := genPreimage()
+		require.NoError(t, err)
+
+		htlc := &lnwire.UpdateAddHTLC{
+			PaymentHash: sha256.Sum256(preimage[:]),
+			Amount:      1000,
+		}
+
+		packet := &htlcPacket{
+			incomingChanID:   aliceChanID,
+			incomingHTLCID:   htlcID,
+			outgoingChanID:   bobChanID,
+			obfuscator:       NewMockObfuscator(),
+			incomingAmount:   2000,
+			amount:           1000,
+			incomingTimeout:  testStartingHeight + 100,
+			outgoingTimeout:  testStartingHeight + 60,
+			incomingEndorsed: endorsed,
+			htlc:             htlc,
+		}
+		require.NoError(t, s.ForwardPackets(nil, packet))
+	}
+
+	// An unendorsed htlc is forwarded with an explicit unendorsed signal.
+	endorsementType := uint64(lnwire.ExperimentalEndorsementType)
+	forward(0, false)
+	select {
+	case p := <-bobLink.packets:
+		htlc, ok := p.htlc.(*lnwire.UpdateAddHTLC)
+		require.True(t, ok)
+		require.Equal(
+			t, []byte{lnwire.ExperimentalUnendorsed},
+			htlc.CustomRecords[endorsementType],
+		)
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("htlc wasn't forwarded")
+	}
+
+	// Alice has no reputation yet, so her endorsed htlc is forwarded
+	// unendorsed in the general bucket.
+	forward(1, true)
+	select {
+	case p := <-bobLink.packets:
+		htlc, ok := p.htlc.(*lnwire.UpdateAddHTLC)
+		require.True(t, ok)
+		require.Equal(
+			t, []byte{lnwire.ExperimentalUnendorsed},
+			htlc.CustomRecords[endorsementType],
+		)
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("htlc wasn't forwarded")
+	}
+
+	// The general bucket is now full, so the next htlc is failed back.
+	forward(2, false)
+	select {
+	case p := <-aliceLink.packets:
+		require.NotNil(t, p.linkFailure)
+		require.Equal(
+			t, OutgoingFailureResourceBucketFull,
+			p.linkFailure.FailureDetail,
+		)
+		assertFailureCode(
+			t, p.linkFailure, lnwire.CodeTemporaryChannelFailure,
+		)
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("no timely reply from switch")
+	}
+}
@@ -0,0 +1,539 @@
+package htlcswitch
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/htlcswitch/hop"
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// TrampolineMinCltvDelta is the minimum number of blocks that we
+	// require between the expiry of an incoming trampoline htlc and the
+	// expiry that the sender asked us to deliver to the outgoing node. Our
+	// own route needs to fit within this delta, and it gives us time to
+	// claim the incoming htlc on chain.
+	TrampolineMinCltvDelta = 80
+
+	// DefaultTrampolineMppTimeout is the default time that we wait for all
+	// htlcs of a trampoline payment to arrive before we fail them back.
+	DefaultTrampolineMppTimeout = 120 * time.Second
+)
+
+// TrampolinePaymentRequest describes the payment that we send to forward a
+// payment that was delivered to us as a trampoline node.
+type TrampolinePaymentRequest struct {
+	// PaymentHash is the payment hash of the incoming htlcs.
+	PaymentHash lntypes.Hash
+
+	// Target is the node that the payment should be forwarded to.
+	Target route.Vertex
+
+	// Amount is the amount that the target should receive.
+	Amount lnwire.MilliSatoshi
+
+	// FinalCltv is the absolute expiry of the htlcs that the target should
+	// receive.
+	FinalCltv uint32
+
+	// FeeLimit is the maximum routing fee that we can pay, which is the
+	// difference between the incoming amount and Amount.
+	FeeLimit lnwire.MilliSatoshi
+
+	// CltvLimit is the maximum absolute expiry of the outgoing htlcs.
+	CltvLimit uint32
+
+	// MPP holds the payment address and total amount for the target, if
+	// it is the final recipient of the payment.
+	MPP *record.MPP
+
+	// Metadata is the payment metadata for the target.
+	Metadata []byte
+
+	// TrampolineOnion is the trampoline onion for the target. It is nil if
+	// the target is the final recipient of the payment.
+	TrampolineOnion []byte
+}
+
+// TrampolinePaymentResult is the outcome of a payment that we sent to forward
+// a trampoline payment.
+type TrampolinePaymentResult struct {
+	// Preimage is the preimage of the payment, which is set if the payment
+	// succeeded.
+	Preimage *lntypes.Preimage
+
+	// Failure is the failure that the incoming htlcs are failed back with
+	// if the payment failed.
+	Failure lnwire.FailureMessage
+}
+
+// TrampolineHtlc is an incoming htlc for which we are the final hop of the
+// outer onion, and which carries a trampoline onion that instructs us to
+// forward the payment.
+type TrampolineHtlc struct {
+	// CircuitKey identifies the incoming htlc.
+	CircuitKey models.CircuitKey
+
+	// PaymentHash is the payment hash of the htlc.
+	PaymentHash lntypes.Hash
+
+	// Amount is the amount of the htlc.
+	Amount lnwire.MilliSatoshi
+
+	// Expiry is the absolute expiry of the htlc.
+	Expiry uint32
+
+	// MPP is the mpp record of the outer onion, which groups the htlcs of
+	// a trampoline payment.
+	MPP *record.MPP
+
+	// Onion is the serialized trampoline onion of the htlc.
+	Onion []byte
+}
+
+// TrampolineFailResolution is an implementation of the HtlcResolution
+// interface which is returned when a trampoline htlc is failed.
+type TrampolineFailResolution struct {
+	// circuitKey is the key of the htlc for which we have a resolution.
+	circuitKey models.CircuitKey
+
+	// Failure is the failure that the htlc is failed back with.
+	Failure lnwire.FailureMessage
+}
+
+// CircuitKey returns the circuit key for the htlc that we have a resolution
+// for.
+//
+// NOTE: Part of the HtlcResolution interface.
+func (f *TrampolineFailResolution) CircuitKey() models.CircuitKey {
+	return f.circuitKey
+}
+
+// TrampolineRouterConfig houses the dependencies of the TrampolineRouter.
+type TrampolineRouterConfig struct {
+	// DecodeTrampolineOnion decodes a trampoline onion that was delivered
+	// to us using the given associated data.
+	DecodeTrampolineOnion func(onion, assocData []byte) (
+		*hop.TrampolinePayload, error)
+
+	// SendPayment sends the payment that forwards a trampoline payment,
+	// retrying it until it succeeds or fails for good. If a payment with
+	// the same hash was already sent, its result is returned instead. The
+	// returned channel receives the result once the payment is resolved.
+	SendPayment func(req *TrampolinePaymentRequest) (
+		<-chan *TrampolinePaymentResult, error)
+
+	// LookupPayment returns a channel that receives the result of the
+	// payment with the given hash if it is in flight or succeeded, which
+	// happens if we forwarded it before a restart. It returns a nil
+	// channel if there is no such payment.
+	LookupPayment func(paymentHash lntypes.Hash) (
+		<-chan *TrampolinePaymentResult, error)
+
+	// MppTimeout is the time that we wait for all htlcs of a trampoline
+	// payment to arrive before we fail them back.
+	MppTimeout time.Duration
+
+	// Clock is used to time out incomplete trampoline payments.
+	Clock clock.Clock
+}
+
+// trampolineSetKey identifies the htlcs that make up a trampoline payment.
+type trampolineSetKey struct {
+	paymentHash lntypes.Hash
+	paymentAddr [32]byte
+}
+
+// trampolineSet is the set of incoming htlcs of a trampoline payment.
+type trampolineSet struct {
+	// onion is the trampoline onion that all htlcs of the set carry.
+	onion []byte
+
+	// payload is the decoded trampoline onion.
+	payload *hop.TrampolinePayload
+
+	// total is the total amount of the htlcs that the sender committed to
+	// in the outer onion.
+	total lnwire.MilliSatoshi
+
+	// htlcs are the incoming htlcs of the set that are held.
+	htlcs map[models.CircuitKey]*TrampolineHtlc
+
+	// sent is true once we've sent the outgoing payment for the set.
+	sent bool
+}
+
+// amount returns the sum of the amounts of the htlcs in the set.
+func (s *trampolineSet) amount() lnwire.MilliSatoshi {
+	var amt lnwire.MilliSatoshi
+	for _, htlc := range s.htlcs {
+		amt += htlc.Amount
+	}
+
+	return amt
+}
+
+// TrampolineRouter forwards the payments that are delivered to us as a
+// trampoline node. The incoming htlcs of a payment are held until all of them
+// have arrived, after which the payment is sent to the next node through the
+// payment lifecycle of the router, which retries it along different routes.
+// Once the outgoing payment is resolved, the incoming htlcs are settled or
+// failed through the links that hold them.
+type TrampolineRouter struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *TrampolineRouterConfig
+
+	// sets are the trampoline payments that we currently hold htlcs for.
+	sets map[trampolineSetKey]*trampolineSet
+
+	// setKeys maps every held htlc to the set that it belongs to.
+	setKeys map[models.CircuitKey]trampolineSetKey
+
+	// subscribers maps every held htlc to the channel over which its
+	// resolution is delivered.
+	subscribers map[models.CircuitKey]chan<- interface{}
+
+	sync.Mutex
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// A compile time check to ensure TrampolineRouter implements the
+// TrampolineForwarder interface.
+var _ TrampolineForwarder = (*TrampolineRouter)(nil)
+
+// NewTrampolineRouter creates a new trampoline router.
+func NewTrampolineRouter(cfg *TrampolineRouterConfig) *TrampolineRouter {
+	return &TrampolineRouter{
+		cfg:         cfg,
+		sets:        make(map[trampolineSetKey]*trampolineSet),
+		setKeys:     make(map[models.CircuitKey]trampolineSetKey),
+		subscribers: make(map[models.CircuitKey]chan<- interface{}),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start starts the trampoline router.
+func (t *TrampolineRouter) Start() error {
+	t.started.Do(func() {
+		log.Info("TrampolineRouter starting")
+	})
+
+	return nil
+}
+
+// Stop stops the trampoline router. Held htlcs are left to the links, which
+// notify us of them again once we're restarted.
+func (t *TrampolineRouter) Stop() error {
+	t.stopped.Do(func() {
+		log.Info("TrampolineRouter shutting down...")
+		defer log.Debug("TrampolineRouter shutdown complete")
+
+		close(t.quit)
+		t.wg.Wait()
+	})
+
+	return nil
+}
+
+// NotifyTrampolineHtlc adds an incoming trampoline htlc to the set of htlcs
+// of its payment. Once all htlcs of the payment have arrived, the outgoing
+// payment is sent. If the htlc can't be forwarded, a resolution is returned
+// right away. Otherwise, nil is returned and the resolution is sent on the
+// passed hodlChan once the outgoing payment is resolved.
+//
+// NOTE: Part of the TrampolineForwarder interface.
+func (t *TrampolineRouter) NotifyTrampolineHtlc(htlc *TrampolineHtlc,
+	hodlChan chan<- interface{}) invoices.HtlcResolution {
+
+	t.Lock()
+	defer t.Unlock()
+
+	// If we already hold the htlc, the link was restarted and we only need
+	// to deliver the resolution to its new subscription.
+	if _, ok := t.setKeys[htlc.CircuitKey]; ok {
+		t.subscribers[htlc.CircuitKey] = hodlChan
+		return nil
+	}
+
+	setKey := trampolineSetKey{
+		paymentHash: htlc.PaymentHash,
+		paymentAddr: htlc.MPP.PaymentAddr(),
+	}
+
+	set, ok := t.sets[setKey]
+	switch {
+	case !ok:
+		payload, failure := t.decodeOnion(htlc)
+		if failure != nil {
+			return &TrampolineFailResolution{
+				circuitKey: htlc.CircuitKey,
+				Failure:    failure,
+			}
+		}
+
+		// If we already forwarded the payment before a restart, its
+		// htlcs are resolved with the result of that payment.
+		results, err := t.cfg.LookupPayment(htlc.PaymentHash)
+		if err != nil {
+			log.Errorf("Unable to look up trampoline payment %v: "+
+				"%v", htlc.PaymentHash, err)
+
+			return &TrampolineFailResolution{
+				circuitKey: htlc.CircuitKey,
+				Failure: &lnwire.
+					FailTemporaryTrampolineFailure{},
+			}
+		}
+
+		set = &trampolineSet{
+			onion:   htlc.Onion,
+			payload: payload,
+			total:   htlc.MPP.TotalMsat(),
+			htlcs:   make(map[models.CircuitKey]*TrampolineHtlc),
+			sent:    results != nil,
+		}
+		t.sets[setKey] = set
+
+		t.wg.Add(1)
+		if results != nil {
+			go t.waitForPayment(setKey, set, results)
+		} else {
+			go t.expireSet(setKey, set)
+		}
+
+	// All htlcs of a payment must carry the same trampoline onion and
+	// commit to the same total amount.
+	case !bytes.Equal(set.onion, htlc.Onion) ||
+		set.total != htlc.MPP.TotalMsat():
+
+		return &TrampolineFailResolution{
+			circuitKey: htlc.CircuitKey,
+			Failure: lnwire.NewInvalidOnionPayload(
+				uint64(record.TrampolineOnionType), 0,
+			),
+		}
+	}
+
+	set.htlcs[htlc.CircuitKey] = htlc
+	t.setKeys[htlc.CircuitKey] = setKey
+	t.subscribers[htlc.CircuitKey] = hodlChan
+
+	log.Debugf("Holding trampoline htlc %v of payment %v, received %v "+
+		"of %v", htlc.CircuitKey, htlc.PaymentHash, set.amount(),
+		set.total)
+
+	// Htlcs that arrive after the payment was sent are resolved along
+	// with the others.
+	if !set.sent && set.amount() >= set.total {
+		t.sendPayment(setKey, set)
+	}
+
+	return nil
+}
+
+// HodlUnsubscribeAll cancels the subscription of the passed subscriber to the
+// resolutions of the htlcs that it holds.
+//
+// NOTE: Part of the TrampolineForwarder interface.
+func (t *TrampolineRouter) HodlUnsubscribeAll(subscriber chan<- interface{}) {
+	t.Lock()
+	defer t.Unlock()
+
+	for circuitKey, sub := range t.subscribers {
+		if sub == subscriber {
+			delete(t.subscribers, circuitKey)
+		}
+	}
+}
+
+// decodeOnion decodes the trampoline onion of the passed htlc. If the onion
+// can't be forwarded, the failure to return to the sender is returned.
+func (t *TrampolineRouter) decodeOnion(htlc *TrampolineHtlc) (
+	*hop.TrampolinePayload, lnwire.FailureMessage) {
+
+	payload, err := t.cfg.DecodeTrampolineOnion(
+		htlc.Onion, htlc.PaymentHash[:],
+	)
+	if err != nil {
+		log.Debugf("Unable to decode trampoline onion of %v: %v",
+			htlc.CircuitKey, err)
+
+		return nil, lnwire.NewInvalidOnionPayload(
+			uint64(record.TrampolineOnionType), 0,
+		)
+	}
+
+	// We don't accept payments as the final node of a trampoline route.
+	if payload.OutgoingNodeID == nil {
+		return nil, lnwire.NewInvalidOnionPayload(
+			uint64(record.OutgoingNodeIDOnionType), 0,
+		)
+	}
+
+	return payload, nil
+}
+
+// sendPayment sends the outgoing payment of a complete set of htlcs. The
+// caller must hold the lock of the router.
+func (t *TrampolineRouter) sendPayment(setKey trampolineSetKey,
+	set *trampolineSet) {
+
+	set.sent = true
+
+	// Our route needs to fit between the expiry that the sender asked us
+	// to deliver and the earliest expiry of the incoming htlcs.
+	minExpiry := uint32(0)
+	for _, htlc := range set.htlcs {
+		if minExpiry == 0 || htlc.Expiry < minExpiry {
+			minExpiry = htlc.Expiry
+		}
+	}
+
+	payload := set.payload
+	switch {
+	case set.total < payload.AmountToForward:
+		t.resolveSet(setKey, set, &TrampolinePaymentResult{
+			Failure: &lnwire.FailTrampolineFeeInsufficient{},
+		})
+
+		return
+
+	case minExpiry < payload.OutgoingCTLV+TrampolineMinCltvDelta:
+		t.resolveSet(setKey, set, &TrampolinePaymentResult{
+			Failure: &lnwire.FailTrampolineExpiryTooSoon{},
+		})
+
+		return
+	}
+
+	results, err := t.cfg.SendPayment(&TrampolinePaymentRequest{
+		PaymentHash:     setKey.paymentHash,
+		Target:          route.NewVertex(payload.OutgoingNodeID),
+		Amount:          payload.AmountToForward,
+		FinalCltv:       payload.OutgoingCTLV,
+		FeeLimit:        set.total - payload.AmountToForward,
+		CltvLimit:       minExpiry - TrampolineMinCltvDelta,
+		MPP:             payload.MPP,
+		Metadata:        payload.Metadata,
+		TrampolineOnion: payload.NextOnion,
+	})
+	if err != nil {
+		log.Errorf("Unable to forward trampoline payment %v: %v",
+			setKey.paymentHash, err)
+
+		t.resolveSet(setKey, set, &TrampolinePaymentResult{
+			Failure: &lnwire.FailTemporaryTrampolineFailure{},
+		})
+
+		return
+	}
+
+	log.Debugf("Forwarding trampoline payment %v of %v htlcs to %x",
+		setKey.paymentHash, len(set.htlcs),
+		payload.OutgoingNodeID.SerializeCompressed())
+
+	t.wg.Add(1)
+	go t.waitForPayment(setKey, set, results)
+}
+
+// waitForPayment waits for the outgoing payment of a set of htlcs to be
+// resolved and resolves the htlcs accordingly.
+//
+// NOTE: This MUST be run as a goroutine.
+func (t *TrampolineRouter) waitForPayment(setKey trampolineSetKey,
+	set *trampolineSet, results <-chan *TrampolinePaymentResult) {
+
+	defer t.wg.Done()
+
+	var result *TrampolinePaymentResult
+	select {
+	case result = <-results:
+	case <-t.quit:
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.resolveSet(setKey, set, result)
+}
+
+// expireSet fails back the htlcs of a set if not all htlcs of the payment
+// arrived in time.
+//
+// NOTE: This MUST be run as a goroutine.
+func (t *TrampolineRouter) expireSet(setKey trampolineSetKey,
+	set *trampolineSet) {
+
+	defer t.wg.Done()
+
+	select {
+	case <-t.cfg.Clock.TickAfter(t.cfg.MppTimeout):
+	case <-t.quit:
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	// Nothing to do if the payment was sent in the meantime.
+	if t.sets[setKey] != set || set.sent {
+		return
+	}
+
+	log.Debugf("Trampoline payment %v timed out with %v of %v received",
+		setKey.paymentHash, set.amount(), set.total)
+
+	t.resolveSet(setKey, set, &TrampolinePaymentResult{
+		Failure: &lnwire.FailMPPTimeout{},
+	})
+}
+
+// resolveSet delivers the resolution of all htlcs of a set to their
+// subscribers and forgets about the set. The caller must hold the lock of the
+// router.
+func (t *TrampolineRouter) resolveSet(setKey trampolineSetKey,
+	set *trampolineSet, result *TrampolinePaymentResult) {
+
+	for circuitKey := range set.htlcs {
+		var resolution invoices.HtlcResolution
+		if result.Preimage != nil {
+			resolution = invoices.NewSettleResolution(
+				*result.Preimage, circuitKey, 0,
+				invoices.ResultSettled,
+			)
+		} else {
+			resolution = &TrampolineFailResolution{
+				circuitKey: circuitKey,
+				Failure:    result.Failure,
+			}
+		}
+
+		// If the link holding the htlc is offline, it notifies us of
+		// the htlc again once it's back, at which point we look up the
+		// result of the outgoing payment again.
+		if subscriber, ok := t.subscribers[circuitKey]; ok {
+			select {
+			case subscriber <- resolution:
+			case <-t.quit:
+			}
+		}
+
+		delete(t.setKeys, circuitKey)
+		delete(t.subscribers, circuitKey)
+	}
+
+	delete(t.sets, setKey)
+}
@@ -0,0 +1,179 @@
+package htlcswitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/htlcswitch/hop"
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/stretchr/testify/require"
+)
+
+// trampolineRouterHarness holds a trampoline router whose outgoing payments
+// are recorded for the test.
+type trampolineRouterHarness struct {
+	router *TrampolineRouter
+
+	// requests receives the outgoing payments that the router sends.
+	requests chan *TrampolinePaymentRequest
+
+	// results delivers the results of the outgoing payments.
+	results chan *TrampolinePaymentResult
+
+	// ticks signals that the router waits for an mpp timeout.
+	ticks chan time.Duration
+	clock *clock.TestClock
+}
+
+// newTrampolineRouterHarness creates a trampoline router that forwards
+// payments of 1000 msat to a fixed node.
+func newTrampolineRouterHarness(t *testing.T) *trampolineRouterHarness {
+	t.Helper()
+
+	nodeKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	h := &trampolineRouterHarness{
+		requests: make(chan *TrampolinePaymentRequest, 1),
+		results:  make(chan *TrampolinePaymentResult, 1),
+		ticks:    make(chan time.Duration, 1),
+	}
+	h.clock = clock.NewTestClockWithTickSignal(time.Unix(0, 0), h.ticks)
+
+	h.router = NewTrampolineRouter(&TrampolineRouterConfig{
+		DecodeTrampolineOnion: func(_, _ []byte) (
+			*hop.TrampolinePayload, error) {
+
+			return &hop.TrampolinePayload{
+				AmountToForward: 1000,
+				OutgoingCTLV:    100,
+				OutgoingNodeID:  nodeKey.PubKey(),
+			}, nil
+		},
+		SendPayment: func(req *TrampolinePaymentRequest) (
+			<-chan *TrampolinePaymentResult, error) {
+
+			h.requests <- req
+
+			return h.results, nil
+		},
+		LookupPayment: func(lntypes.Hash) (
+			<-chan *TrampolinePaymentResult, error) {
+
+			return nil, nil
+		},
+		MppTimeout: time.Minute,
+		Clock:      h.clock,
+	})
+	require.NoError(t, h.router.Start())
+	t.Cleanup(func() {
+		require.NoError(t, h.router.Stop())
+	})
+
+	return h
+}
+
+// newTrampolineHtlc returns a trampoline htlc with the given index that is
+// part of a trampoline payment of 1100 msat.
+func newTrampolineHtlc(idx uint64,
+	amt lnwire.MilliSatoshi) *TrampolineHtlc {
+
+	return &TrampolineHtlc{
+		CircuitKey: models.CircuitKey{
+			ChanID: lnwire.NewShortChanIDFromInt(1),
+			HtlcID: idx,
+		},
+		PaymentHash: lntypes.Hash{1},
+		Amount:      amt,
+		Expiry:      200,
+		MPP:         record.NewMPP(1100, [32]byte{2}),
+		Onion:       []byte{3},
+	}
+}
+
+// TestTrampolineRouterMPP asserts that the trampoline router collects all
+// htlcs of a trampoline payment before it forwards the payment, and settles
+// all of them once the outgoing payment succeeded.
+func TestTrampolineRouterMPP(t *testing.T) {
+	t.Parallel()
+
+	h := newTrampolineRouterHarness(t)
+	hodlChan := make(chan interface{}, 2)
+
+	// The first shard doesn't complete the payment, so it is held without
+	// forwarding the payment.
+	resolution := h.router.NotifyTrampolineHtlc(
+		newTrampolineHtlc(0, 600), hodlChan,
+	)
+	require.Nil(t, resolution)
+
+	select {
+	case <-h.requests:
+		t.Fatal("payment forwarded before all htlcs arrived")
+	default:
+	}
+
+	// Shards with a different onion can't be part of the same payment.
+	htlc := newTrampolineHtlc(1, 500)
+	htlc.Onion = []byte{4}
+	resolution = h.router.NotifyTrampolineHtlc(htlc, hodlChan)
+	require.IsType(t, &TrampolineFailResolution{}, resolution)
+
+	// The second shard completes the payment, which is forwarded with
+	// the amount that is left after our fee.
+	resolution = h.router.NotifyTrampolineHtlc(
+		newTrampolineHtlc(2, 500), hodlChan,
+	)
+	require.Nil(t, resolution)
+
+	req := <-h.requests
+	require.Equal(t, lnwire.MilliSatoshi(1000), req.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(100), req.FeeLimit)
+	require.Equal(t, uint32(200-TrampolineMinCltvDelta), req.CltvLimit)
+
+	// Once the payment succeeded, both shards are settled.
+	preimage := lntypes.Preimage{5}
+	h.results <- &TrampolinePaymentResult{Preimage: &preimage}
+
+	for i := 0; i < 2; i++ {
+		item := <-hodlChan
+		settle, ok := item.(*invoices.HtlcSettleResolution)
+		require.True(t, ok)
+		require.Equal(t, preimage, settle.Preimage)
+	}
+}
+
+// TestTrampolineRouterMppTimeout asserts that the htlcs of a trampoline
+// payment are failed back if not all of them arrive in time.
+func TestTrampolineRouterMppTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := newTrampolineRouterHarness(t)
+	hodlChan := make(chan interface{}, 1)
+
+	resolution := h.router.NotifyTrampolineHtlc(
+		newTrampolineHtlc(0, 600), hodlChan,
+	)
+	require.Nil(t, resolution)
+
+	// Wait for the router to start the timeout before moving the clock.
+	<-h.ticks
+	h.clock.SetTime(time.Unix(0, 0).Add(time.Minute))
+
+	item := <-hodlChan
+	fail, ok := item.(*TrampolineFailResolution)
+	require.True(t, ok)
+	require.Equal(t, &lnwire.FailMPPTimeout{}, fail.Failure)
+
+	select {
+	case <-h.requests:
+		t.Fatal("incomplete payment forwarded")
+	default:
+	}
+}
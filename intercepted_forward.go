@@ -3,6 +3,7 @@ package lnd
 import (
 	"errors"
 
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -51,6 +52,15 @@ func (f *interceptedForward) Resume() error {
 	return ErrCannotResume
 }
 
+// ResumeModified notifies the intention to resume an existing hold forward
+// with a modified outgoing channel, amount or set of custom records.
+func (f *interceptedForward) ResumeModified(
+	_ fn.Option[lnwire.ShortChannelID], _ fn.Option[lnwire.MilliSatoshi],
+	_ fn.Option[lnwire.CustomRecords]) error {
+
+	return ErrCannotResume
+}
+
 // Fail notifies the intention to fail an existing hold forward with an
 // encrypted failure reason.
 func (f *interceptedForward) Fail(_ []byte) error {
@@ -0,0 +1,424 @@
+package invoices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+)
+
+var (
+	// ErrHtlcAcceptorExists is returned when an htlc acceptor is set while
+	// another one is registered.
+	ErrHtlcAcceptorExists = errors.New("htlc acceptor already exists")
+
+	// ErrHtlcNotHeld is returned when the htlc acceptor resolves an htlc
+	// that isn't held for it.
+	ErrHtlcNotHeld = errors.New("htlc not held by htlc acceptor")
+
+	// ErrUnsupportedAcceptorFailure is returned when the htlc acceptor
+	// rejects an htlc with a failure code that can't be used for htlcs
+	// that pay to our invoices.
+	ErrUnsupportedAcceptorFailure = errors.New("unsupported failure code")
+)
+
+// ExitHopHtlc describes an htlc that pays to one of our invoices. It is
+// offered to the htlc acceptor before the invoice registry processes it.
+type ExitHopHtlc struct {
+	// CircuitKey identifies the htlc.
+	CircuitKey CircuitKey
+
+	// InvoiceRef is the reference of the invoice that the htlc pays to.
+	InvoiceRef InvoiceRef
+
+	// Hash is the payment hash of the htlc.
+	Hash lntypes.Hash
+
+	// AmtPaid is the amount of the htlc.
+	AmtPaid lnwire.MilliSatoshi
+
+	// Expiry is the absolute expiry height of the htlc.
+	Expiry uint32
+
+	// CurrentHeight is the block height at which the htlc arrived.
+	CurrentHeight int32
+
+	// CustomRecords are the custom records of the onion payload.
+	CustomRecords record.CustomSet
+
+	// MPP is the multi-path payment record of the onion payload, if any.
+	MPP *record.MPP
+
+	// AMP is the atomic multi-path payment record of the onion payload, if
+	// any.
+	AMP *record.AMP
+
+	// Metadata is the payment metadata of the onion payload.
+	Metadata []byte
+
+	// TotalAmtMsat is the total amount of the payment that the htlc is a
+	// part of, as set by the sender.
+	TotalAmtMsat lnwire.MilliSatoshi
+}
+
+// HtlcAcceptor is a function that is invoked by the invoice registry for
+// every htlc that pays to one of our invoices while the acceptor is
+// registered. The htlc is held until it is resolved through ResolveHeldHtlc.
+type HtlcAcceptor func(ExitHopHtlc) error
+
+// HtlcAcceptAction defines the decisions of the htlc acceptor.
+type HtlcAcceptAction uint8
+
+const (
+	// HtlcAcceptActionAccept hands the htlc to the invoice registry, which
+	// processes it as usual.
+	HtlcAcceptActionAccept HtlcAcceptAction = iota
+
+	// HtlcAcceptActionReject fails the htlc back to the sender.
+	HtlcAcceptActionReject
+
+	// HtlcAcceptActionHold keeps holding the htlc and restarts its
+	// acceptor timeout. Held htlcs are still failed once they get within
+	// the hold expiry delta of their expiry height.
+	HtlcAcceptActionHold
+)
+
+// HtlcAcceptorResolution is the decision of the htlc acceptor on a held htlc.
+type HtlcAcceptorResolution struct {
+	// CircuitKey identifies the htlc.
+	CircuitKey CircuitKey
+
+	// Action is the decision on the htlc.
+	Action HtlcAcceptAction
+
+	// FailureCode is the failure that the htlc is rejected with if Action
+	// is HtlcAcceptActionReject. It defaults to
+	// incorrect_or_unknown_payment_details.
+	FailureCode lnwire.FailCode
+}
+
+// acceptorHeldHtlc is an htlc that is held for the htlc acceptor.
+type acceptorHeldHtlc struct {
+	ctx invoiceUpdateCtx
+
+	// hodlChan is the most recent channel that the htlc was notified
+	// with. It is passed on to the registry when the htlc is accepted.
+	hodlChan chan<- interface{}
+
+	// cancelTimeout cancels the acceptor timeout of the htlc.
+	cancelTimeout chan struct{}
+}
+
+// stopTimeout cancels the acceptor timeout of the htlc.
+func (h *acceptorHeldHtlc) stopTimeout() {
+	if h.cancelTimeout != nil {
+		close(h.cancelTimeout)
+		h.cancelTimeout = nil
+	}
+}
+
+// htlcAcceptorState tracks the registered htlc acceptor and the htlcs that
+// are held for it.
+type htlcAcceptorState struct {
+	sync.Mutex
+
+	acceptor HtlcAcceptor
+
+	held map[CircuitKey]*acceptorHeldHtlc
+}
+
+// exitHopHtlc returns the description of the htlc that is offered to the htlc
+// acceptor.
+func (i *invoiceUpdateCtx) exitHopHtlc() ExitHopHtlc {
+	return ExitHopHtlc{
+		CircuitKey:    i.circuitKey,
+		InvoiceRef:    i.invoiceRef(),
+		Hash:          i.hash,
+		AmtPaid:       i.amtPaid,
+		Expiry:        i.expiry,
+		CurrentHeight: i.currentHeight,
+		CustomRecords: i.customRecords,
+		MPP:           i.mpp,
+		AMP:           i.amp,
+		Metadata:      i.metadata,
+		TotalAmtMsat:  i.totalAmtMsat,
+	}
+}
+
+// SetHtlcAcceptor registers the htlc acceptor. A nil argument unregisters the
+// current acceptor and fails all htlcs that are held for it.
+func (i *InvoiceRegistry) SetHtlcAcceptor(acceptor HtlcAcceptor) error {
+	i.acceptor.Lock()
+
+	if acceptor != nil {
+		defer i.acceptor.Unlock()
+
+		if i.acceptor.acceptor != nil {
+			return ErrHtlcAcceptorExists
+		}
+
+		log.Debugf("Htlc acceptor registered")
+		i.acceptor.acceptor = acceptor
+
+		return nil
+	}
+
+	held := i.acceptor.held
+	for _, h := range held {
+		h.stopTimeout()
+	}
+	i.acceptor.acceptor = nil
+	i.acceptor.held = make(map[CircuitKey]*acceptorHeldHtlc)
+	i.acceptor.Unlock()
+
+	log.Infof("Htlc acceptor unregistered, failing %v held htlcs",
+		len(held))
+
+	for _, h := range held {
+		i.notifyHodlSubscribers(
+			h.ctx.failRes(ResultHtlcAcceptorUnavailable),
+		)
+	}
+
+	return nil
+}
+
+// offerToAcceptor holds the htlc and offers it to the htlc acceptor. It
+// returns false if there is no acceptor or the htlc isn't offerable, in which
+// case the registry handles it as usual.
+func (i *InvoiceRegistry) offerToAcceptor(ctx invoiceUpdateCtx,
+	hodlChan chan<- interface{}) bool {
+
+	i.acceptor.Lock()
+
+	acceptor := i.acceptor.acceptor
+	if acceptor == nil {
+		i.acceptor.Unlock()
+
+		return false
+	}
+
+	// The htlc is replayed while it is still held, for example because
+	// the link restarted. Deliver the decision to the new subscriber too.
+	if h, ok := i.acceptor.held[ctx.circuitKey]; ok {
+		h.hodlChan = hodlChan
+		i.hodlSubscribe(hodlChan, ctx.circuitKey)
+		i.acceptor.Unlock()
+
+		return true
+	}
+
+	if !i.offerable(&ctx) {
+		i.acceptor.Unlock()
+
+		return false
+	}
+
+	h := &acceptorHeldHtlc{
+		ctx:      ctx,
+		hodlChan: hodlChan,
+	}
+	i.acceptor.held[ctx.circuitKey] = h
+	i.hodlSubscribe(hodlChan, ctx.circuitKey)
+	i.startAcceptorTimeout(h)
+
+	i.acceptor.Unlock()
+
+	// However the acceptor decides, the htlc mustn't be held until it
+	// times out on chain. The expiry watcher is called outside the lock
+	// because it calls back into expireAcceptorHtlc.
+	i.expiryWatcher.AddHeldHtlc(ctx.circuitKey, ctx.expiry)
+
+	ctx.log("offered to htlc acceptor")
+
+	if err := acceptor(ctx.exitHopHtlc()); err != nil {
+		// Only log the error. The htlc is failed when the acceptor
+		// doesn't resolve it in time.
+		log.Debugf("Htlc acceptor cannot handle htlc %v: %v",
+			ctx.circuitKey, err)
+	}
+
+	return true
+}
+
+// offerable returns true if the htlc is offered to the htlc acceptor. Htlcs
+// that already reached their invoice were accepted before. Htlcs that don't pay
+// to an invoice are failed by the registry anyway, unless they are spontaneous
+// payments that create their invoice on the fly. The latter also keeps htlcs
+// that are forwarded rather than paid to us away from the acceptor when they
+// are resolved on chain.
+func (i *InvoiceRegistry) offerable(ctx *invoiceUpdateCtx) bool {
+	invoice, err := i.idb.LookupInvoice(
+		context.Background(), ctx.invoiceRef(),
+	)
+	switch {
+	case errors.Is(err, ErrInvoiceNotFound):
+		if ctx.amp != nil {
+			return i.cfg.AcceptAMP
+		}
+
+		_, keysend := ctx.customRecords[record.KeySendType]
+
+		return i.cfg.AcceptKeySend && keysend
+
+	case err != nil:
+		return false
+	}
+
+	_, ok := invoice.Htlcs[ctx.circuitKey]
+
+	return !ok
+}
+
+// startAcceptorTimeout fails the htlc if the acceptor doesn't resolve it
+// within the configured timeout. The caller must hold the acceptor lock.
+func (i *InvoiceRegistry) startAcceptorTimeout(h *acceptorHeldHtlc) {
+	h.stopTimeout()
+
+	timeout := i.cfg.HtlcAcceptorTimeout
+	if timeout == 0 {
+		return
+	}
+
+	cancel := make(chan struct{})
+	h.cancelTimeout = cancel
+	expiry := i.cfg.Clock.TickAfter(timeout)
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+
+		select {
+		case <-expiry:
+
+		case <-cancel:
+			return
+
+		case <-i.quit:
+			return
+		}
+
+		i.acceptor.Lock()
+		current, ok := i.acceptor.held[h.ctx.circuitKey]
+		if !ok || current != h || h.cancelTimeout != cancel {
+			i.acceptor.Unlock()
+
+			return
+		}
+		delete(i.acceptor.held, h.ctx.circuitKey)
+		h.cancelTimeout = nil
+		i.acceptor.Unlock()
+
+		h.ctx.log("htlc acceptor timed out")
+
+		i.notifyHodlSubscribers(
+			h.ctx.failRes(ResultHtlcAcceptorTimeout),
+		)
+	}()
+}
+
+// expireAcceptorHtlc fails an htlc that is held by the htlc acceptor because it
+// is about to expire. It is a no-op if the htlc isn't held anymore.
+func (i *InvoiceRegistry) expireAcceptorHtlc(key CircuitKey) {
+	i.acceptor.Lock()
+	h, ok := i.acceptor.held[key]
+	if !ok {
+		i.acceptor.Unlock()
+
+		return
+	}
+	delete(i.acceptor.held, key)
+	h.stopTimeout()
+	i.acceptor.Unlock()
+
+	h.ctx.log("htlc acceptor held htlc until close to its expiry")
+
+	i.notifyHodlSubscribers(h.ctx.failRes(ResultHtlcAcceptorExpiry))
+}
+
+// ResolveHeldHtlc applies the decision of the htlc acceptor to a held htlc.
+func (i *InvoiceRegistry) ResolveHeldHtlc(res HtlcAcceptorResolution) error {
+	i.acceptor.Lock()
+
+	h, ok := i.acceptor.held[res.CircuitKey]
+	if !ok {
+		i.acceptor.Unlock()
+
+		return ErrHtlcNotHeld
+	}
+
+	var outcome FailResolutionResult
+	switch res.Action {
+	case HtlcAcceptActionHold:
+		i.startAcceptorTimeout(h)
+		i.acceptor.Unlock()
+
+		return nil
+
+	case HtlcAcceptActionReject:
+		var err error
+		outcome, err = acceptorFailure(res.FailureCode)
+		if err != nil {
+			i.acceptor.Unlock()
+
+			return err
+		}
+
+	case HtlcAcceptActionAccept:
+
+	default:
+		i.acceptor.Unlock()
+
+		return fmt.Errorf("unknown htlc acceptor action %v",
+			res.Action)
+	}
+
+	delete(i.acceptor.held, res.CircuitKey)
+	h.stopTimeout()
+	i.acceptor.Unlock()
+
+	if res.Action == HtlcAcceptActionReject {
+		h.ctx.log(fmt.Sprintf("rejected by htlc acceptor: %v", outcome))
+		i.notifyHodlSubscribers(h.ctx.failRes(outcome))
+
+		return nil
+	}
+
+	h.ctx.log("accepted by htlc acceptor")
+
+	// Process the htlc as if it just arrived. A nil resolution means that
+	// the registry holds the htlc and notifies the subscribers later.
+	resolution, err := i.notifyExitHopHtlc(h.ctx, h.hodlChan)
+	if err != nil {
+		return err
+	}
+
+	if resolution != nil {
+		i.notifyHodlSubscribers(resolution)
+	}
+
+	return nil
+}
+
+// acceptorFailure maps the failure code of a rejection to a resolution
+// result.
+func acceptorFailure(code lnwire.FailCode) (FailResolutionResult, error) {
+	switch code {
+	case 0, lnwire.CodeIncorrectOrUnknownPaymentDetails:
+		return ResultHtlcAcceptorRejected, nil
+
+	case lnwire.CodeTemporaryNodeFailure:
+		return ResultHtlcAcceptorTemporaryFailure, nil
+
+	case lnwire.CodePermanentNodeFailure:
+		return ResultHtlcAcceptorPermanentFailure, nil
+
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedAcceptorFailure,
+			code)
+	}
+}
@@ -58,6 +58,28 @@ func (b invoiceExpiryHeight) expired(currentHeight, delta uint32) bool {
 	return currentHeight+delta >= b.expiryHeight
 }
 
+// Compile time assertion that htlcExpiryHeight implements invoiceExpiry.
+var _ invoiceExpiry = (*htlcExpiryHeight)(nil)
+
+// htlcExpiryHeight holds the expiry height of an htlc that is held by the
+// htlc acceptor, which is used to fail the htlc before it times out.
+type htlcExpiryHeight struct {
+	circuitKey   CircuitKey
+	expiryHeight uint32
+}
+
+// Less implements PriorityQueueItem.Less such that the top item in the
+// priority queue is the lowest block height.
+func (h htlcExpiryHeight) Less(other queue.PriorityQueueItem) bool {
+	return h.expiryHeight < other.(*htlcExpiryHeight).expiryHeight
+}
+
+// expired returns a boolean that indicates whether this entry has expired,
+// taking our expiry delta into account.
+func (h htlcExpiryHeight) expired(currentHeight, delta uint32) bool {
+	return currentHeight+delta >= h.expiryHeight
+}
+
 // InvoiceExpiryWatcher handles automatic invoice cancellation of expired
 // invoices. Upon start InvoiceExpiryWatcher will retrieve all pending (not yet
 // settled or canceled) invoices invoices to its watching queue. When a new
@@ -91,6 +113,10 @@ type InvoiceExpiryWatcher struct {
 	// cancelInvoice is a template method that cancels an expired invoice.
 	cancelInvoice func(lntypes.Hash, bool) error
 
+	// expireHtlc is a template method that fails an htlc that is held by
+	// the htlc acceptor once it is about to expire.
+	expireHtlc func(CircuitKey)
+
 	// timestampExpiryQueue holds invoiceExpiry items and is used to find
 	// the next invoice to expire.
 	timestampExpiryQueue queue.PriorityQueue
@@ -104,6 +130,12 @@ type InvoiceExpiryWatcher struct {
 	// active htlcs.
 	blockExpiryQueue queue.PriorityQueue
 
+	// htlcExpiryQueue holds htlcExpiryHeight items and is used to find
+	// the next htlc held by the htlc acceptor that expires based on block
+	// height. Htlcs that the acceptor resolved in the meantime stay in the
+	// queue, expiring them is a no-op.
+	htlcExpiryQueue queue.PriorityQueue
+
 	// newInvoices channel is used to wake up the main loop when a new
 	// invoices is added.
 	newInvoices chan []invoiceExpiry
@@ -133,9 +165,11 @@ func NewInvoiceExpiryWatcher(clock clock.Clock,
 // Start starts the subscription handler and the main loop. Start() will
 // return with error if InvoiceExpiryWatcher is already started. Start()
 // expects a cancellation function passed that will be use to cancel expired
-// invoices by their payment hash.
+// invoices by their payment hash, and a function that fails expiring htlcs
+// that are held by the htlc acceptor by their circuit key.
 func (ew *InvoiceExpiryWatcher) Start(
-	cancelInvoice func(lntypes.Hash, bool) error) error {
+	cancelInvoice func(lntypes.Hash, bool) error,
+	expireHtlc func(CircuitKey)) error {
 
 	ew.Lock()
 	defer ew.Unlock()
@@ -146,6 +180,7 @@ func (ew *InvoiceExpiryWatcher) Start(
 
 	ew.started = true
 	ew.cancelInvoice = cancelInvoice
+	ew.expireHtlc = expireHtlc
 
 	ntfn, err := ew.notifier.RegisterBlockEpochNtfn(&chainntnfs.BlockEpoch{
 		Height: int32(ew.currentHeight),
@@ -274,6 +309,16 @@ func (ew *InvoiceExpiryWatcher) AddInvoices(invoices ...invoiceExpiry) {
 	}
 }
 
+// AddHeldHtlc adds an htlc that is held by the htlc acceptor to the
+// InvoiceExpiryWatcher, so that it is failed once it gets within the expiry
+// delta of its expiry height.
+func (ew *InvoiceExpiryWatcher) AddHeldHtlc(key CircuitKey, expiry uint32) {
+	ew.AddInvoices(&htlcExpiryHeight{
+		circuitKey:   key,
+		expiryHeight: expiry,
+	})
+}
+
 // nextTimestampExpiry returns a Time chan to wait on until the next invoice
 // expires. If there are no active invoices, then it'll simply wait
 // indefinitely.
@@ -342,6 +387,22 @@ func (ew *InvoiceExpiryWatcher) cancelNextHeightExpiredInvoice() {
 	ew.blockExpiryQueue.Pop()
 }
 
+// expireHeldHtlcs fails all htlcs held by the htlc acceptor that reached their
+// expiry delta and removes them from the expiry queue.
+func (ew *InvoiceExpiryWatcher) expireHeldHtlcs() {
+	for !ew.htlcExpiryQueue.Empty() {
+		top := ew.htlcExpiryQueue.Top().(*htlcExpiryHeight)
+		if !top.expired(ew.currentHeight, ew.blockExpiryDelta) {
+			return
+		}
+
+		if ew.expireHtlc != nil {
+			ew.expireHtlc(top.circuitKey)
+		}
+		ew.htlcExpiryQueue.Pop()
+	}
+}
+
 // expireInvoice attempts to expire an invoice and logs an error if we get an
 // unexpected error.
 func (ew *InvoiceExpiryWatcher) expireInvoice(hash lntypes.Hash, force bool) {
@@ -380,6 +441,11 @@ func (ew *InvoiceExpiryWatcher) pushInvoices(invoices []invoiceExpiry) {
 				ew.blockExpiryQueue.Push(expiry)
 			}
 
+		case *htlcExpiryHeight:
+			if expiry != nil {
+				ew.htlcExpiryQueue.Push(expiry)
+			}
+
 		default:
 			log.Errorf("unexpected queue item: %T", inv)
 		}
@@ -400,8 +466,10 @@ func (ew *InvoiceExpiryWatcher) mainLoop(blockNtfns *chainntnfs.BlockEpochEvent)
 	cancelNext := ew.cancelNextExpiredInvoice
 
 	for {
-		// Cancel any invoices that may have expired.
+		// Cancel any invoices that may have expired, and fail the
+		// held htlcs that are about to expire.
 		cancelNext()
+		ew.expireHeldHtlcs()
 
 		select {
 		case newInvoices := <-ew.newInvoices:
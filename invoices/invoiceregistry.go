@@ -74,6 +74,10 @@ type RegistryConfig struct {
 	// KeysendHoldTime indicates for how long we want to accept and hold
 	// spontaneous keysend payments.
 	KeysendHoldTime time.Duration
+
+	// HtlcAcceptorTimeout is the time that the htlc acceptor has to decide
+	// on an htlc before it is failed. A zero value disables the timeout.
+	HtlcAcceptorTimeout time.Duration
 }
 
 // htlcReleaseEvent describes an htlc auto-release event. It is used to release
@@ -129,6 +133,10 @@ type InvoiceRegistry struct {
 	// carried.
 	invoiceEvents chan *invoiceEvent
 
+	// acceptor holds the registered htlc acceptor and the htlcs that wait
+	// for its decision.
+	acceptor htlcAcceptorState
+
 	// hodlSubscriptionsMux locks the hodlSubscriptions and
 	// hodlReverseSubscriptions. Using a separate mutex for these maps is
 	// necessary to avoid deadlocks in the registry when processing invoice
@@ -173,6 +181,9 @@ func NewRegistry(idb InvoiceDB, expiryWatcher *InvoiceExpiryWatcher,
 		hodlReverseSubscriptions: make(
 			map[chan<- interface{}]map[CircuitKey]struct{},
 		),
+		acceptor: htlcAcceptorState{
+			held: make(map[CircuitKey]*acceptorHeldHtlc),
+		},
 		cfg:                 cfg,
 		htlcAutoReleaseChan: make(chan *htlcReleaseEvent),
 		expiryWatcher:       expiryWatcher,
@@ -230,7 +241,8 @@ func (i *InvoiceRegistry) Start() error {
 			return i.cancelInvoiceImpl(
 				context.Background(), hash, force,
 			)
-		})
+		}, i.expireAcceptorHtlc,
+	)
 	if err != nil {
 		return err
 	}
@@ -933,6 +945,23 @@ func (i *InvoiceRegistry) NotifyExitHopHtlc(rHash lntypes.Hash,
 		totalAmtMsat:         payload.TotalAmtMsat(),
 	}
 
+	// If an htlc acceptor is registered, it decides whether the htlc is
+	// processed at all. Until then, the htlc is held.
+	if i.offerToAcceptor(ctx, hodlChan) {
+		return nil, nil
+	}
+
+	return i.notifyExitHopHtlc(ctx, hodlChan)
+}
+
+// notifyExitHopHtlc processes an exit hop htlc. It returns a nil resolution if
+// the htlc is held, in which case the resolution is sent to hodlChan later.
+func (i *InvoiceRegistry) notifyExitHopHtlc(ctx invoiceUpdateCtx,
+	hodlChan chan<- interface{}) (HtlcResolution, error) {
+
+	circuitKey := ctx.circuitKey
+	currentHeight := ctx.currentHeight
+
 	switch {
 	// If we are accepting spontaneous AMP payments and this payload
 	// contains an AMP record, create an AMP invoice that will be settled
@@ -102,6 +102,14 @@ func TestInvoiceRegistry(t *testing.T) {
 			name: "SpontaneousAmpPayment",
 			test: testSpontaneousAmpPayment,
 		},
+		{
+			name: "HtlcAcceptor",
+			test: testHtlcAcceptor,
+		},
+		{
+			name: "HtlcAcceptorExpiry",
+			test: testHtlcAcceptorExpiry,
+		},
 	}
 
 	makeKeyValueDB := func(t *testing.T) (invpkg.InvoiceDB,
@@ -2086,3 +2094,272 @@ func testSpontaneousAmpPaymentImpl(
 		}
 	}
 }
+
+// testHtlcAcceptor tests that htlcs paying to our invoices are held for the
+// htlc acceptor and resolved according to its decisions.
+func testHtlcAcceptor(t *testing.T,
+	makeDB func(t *testing.T) (invpkg.InvoiceDB, *clock.TestClock)) {
+
+	t.Parallel()
+	defer timeout()()
+
+	const acceptorTimeout = time.Minute
+
+	cfg := defaultRegistryConfig()
+	cfg.HtlcAcceptorTimeout = acceptorTimeout
+	ctx := newTestContext(t, &cfg, makeDB)
+
+	offered := make(chan invpkg.ExitHopHtlc, 1)
+	acceptor := func(htlc invpkg.ExitHopHtlc) error {
+		offered <- htlc
+		return nil
+	}
+
+	require.NoError(t, ctx.registry.SetHtlcAcceptor(acceptor))
+	require.ErrorIs(
+		t, ctx.registry.SetHtlcAcceptor(acceptor),
+		invpkg.ErrHtlcAcceptorExists,
+	)
+
+	testInvoice := newInvoice(t, false)
+	_, err := ctx.registry.AddInvoice(
+		context.Background(), testInvoice, testInvoicePaymentHash,
+	)
+	require.NoError(t, err)
+
+	// notify notifies the registry of an htlc that pays to the invoice and
+	// asserts that it is held for the acceptor.
+	notify := func(htlcID uint64) chan interface{} {
+		t.Helper()
+
+		hodlChan := make(chan interface{}, 1)
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			testInvoicePaymentHash, testInvoice.Terms.Value,
+			testHtlcExpiry, testCurrentHeight,
+			getCircuitKey(htlcID), hodlChan, testPayload,
+		)
+		require.NoError(t, err)
+		require.Nil(t, resolution)
+
+		select {
+		case htlc := <-offered:
+			require.Equal(t, getCircuitKey(htlcID), htlc.CircuitKey)
+			require.Equal(t, testInvoicePaymentHash, htlc.Hash)
+			require.Equal(t, testInvoice.Terms.Value, htlc.AmtPaid)
+
+		case <-time.After(testTimeout):
+			t.Fatal("htlc not offered to acceptor")
+		}
+
+		return hodlChan
+	}
+
+	// resolution returns the resolution that is delivered for a held htlc.
+	resolution := func(hodlChan chan interface{}) invpkg.HtlcResolution {
+		t.Helper()
+
+		select {
+		case res := <-hodlChan:
+			return res.(invpkg.HtlcResolution)
+
+		case <-time.After(testTimeout):
+			t.Fatal("no resolution received")
+		}
+
+		return nil
+	}
+
+	// Htlcs to unknown invoices aren't offered to the acceptor.
+	res, err := ctx.registry.NotifyExitHopHtlc(
+		lntypes.Hash{1}, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(10), make(chan interface{}),
+		testPayload,
+	)
+	require.NoError(t, err)
+	checkFailResolution(t, res, invpkg.ResultInvoiceNotFound)
+
+	// A rejected htlc is failed with the requested failure.
+	hodlChan := notify(0)
+	require.NoError(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey:  getCircuitKey(0),
+			Action:      invpkg.HtlcAcceptActionReject,
+			FailureCode: lnwire.CodeTemporaryNodeFailure,
+		},
+	))
+	checkFailResolution(
+		t, resolution(hodlChan),
+		invpkg.ResultHtlcAcceptorTemporaryFailure,
+	)
+
+	require.ErrorIs(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey: getCircuitKey(0),
+			Action:     invpkg.HtlcAcceptActionAccept,
+		},
+	), invpkg.ErrHtlcNotHeld)
+
+	// Failures that don't fit an exit hop are refused and leave the htlc
+	// held.
+	hodlChan = notify(1)
+	require.ErrorIs(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey:  getCircuitKey(1),
+			Action:      invpkg.HtlcAcceptActionReject,
+			FailureCode: lnwire.CodeTemporaryChannelFailure,
+		},
+	), invpkg.ErrUnsupportedAcceptorFailure)
+
+	// Holding the htlc restarts its timeout, after which it is failed.
+	start := ctx.clock.Now()
+	ctx.clock.SetTime(start.Add(acceptorTimeout / 2))
+	require.NoError(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey: getCircuitKey(1),
+			Action:     invpkg.HtlcAcceptActionHold,
+		},
+	))
+
+	ctx.clock.SetTime(start.Add(acceptorTimeout))
+	select {
+	case <-hodlChan:
+		t.Fatal("held htlc failed before its timeout")
+
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ctx.clock.SetTime(start.Add(acceptorTimeout * 2))
+	checkFailResolution(
+		t, resolution(hodlChan), invpkg.ResultHtlcAcceptorTimeout,
+	)
+
+	// Unregistering the acceptor fails the htlcs held for it.
+	hodlChan = notify(2)
+	require.NoError(t, ctx.registry.SetHtlcAcceptor(nil))
+	checkFailResolution(
+		t, resolution(hodlChan), invpkg.ResultHtlcAcceptorUnavailable,
+	)
+
+	// An accepted htlc is processed by the registry as usual.
+	require.NoError(t, ctx.registry.SetHtlcAcceptor(acceptor))
+	hodlChan = notify(3)
+	require.NoError(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey: getCircuitKey(3),
+			Action:     invpkg.HtlcAcceptActionAccept,
+		},
+	))
+	checkSettleResolution(t, resolution(hodlChan), testInvoicePreimage)
+
+	// A replay of the settled htlc isn't offered to the acceptor again.
+	res, err = ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(3), make(chan interface{}),
+		testPayload,
+	)
+	require.NoError(t, err)
+	checkSettleResolution(t, res, testInvoicePreimage)
+}
+
+// testHtlcAcceptorExpiry tests that htlcs held for the htlc acceptor are failed
+// once they get within the expiry delta of their expiry height, even if the
+// acceptor timeout is disabled and the acceptor keeps holding them.
+func testHtlcAcceptorExpiry(t *testing.T,
+	makeDB func(t *testing.T) (invpkg.InvoiceDB, *clock.TestClock)) {
+
+	t.Parallel()
+	defer timeout()()
+
+	cfg := defaultRegistryConfig()
+	cfg.HtlcAcceptorTimeout = 0
+	ctx := newTestContext(t, &cfg, makeDB)
+
+	offered := make(chan invpkg.ExitHopHtlc, 1)
+	require.NoError(t, ctx.registry.SetHtlcAcceptor(
+		func(htlc invpkg.ExitHopHtlc) error {
+			offered <- htlc
+			return nil
+		},
+	))
+
+	testInvoice := newInvoice(t, false)
+	_, err := ctx.registry.AddInvoice(
+		context.Background(), testInvoice, testInvoicePaymentHash,
+	)
+	require.NoError(t, err)
+
+	hodlChan := make(chan interface{}, 1)
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value,
+		testHtlcExpiry, testCurrentHeight, getCircuitKey(0), hodlChan,
+		testPayload,
+	)
+	require.NoError(t, err)
+	require.Nil(t, resolution)
+
+	select {
+	case <-offered:
+	case <-time.After(testTimeout):
+		t.Fatal("htlc not offered to acceptor")
+	}
+
+	// hold lets the acceptor keep holding the htlc.
+	hold := func() {
+		t.Helper()
+
+		require.NoError(t, ctx.registry.ResolveHeldHtlc(
+			invpkg.HtlcAcceptorResolution{
+				CircuitKey: getCircuitKey(0),
+				Action:     invpkg.HtlcAcceptActionHold,
+			},
+		))
+	}
+
+	// assertHeld asserts that the htlc is still held.
+	assertHeld := func() {
+		t.Helper()
+
+		select {
+		case <-hodlChan:
+			t.Fatal("held htlc failed before its expiry")
+
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// Without an acceptor timeout the htlc is held for as long as the
+	// acceptor wants, as long as it isn't close to its expiry.
+	hold()
+	ctx.clock.SetTime(ctx.clock.Now().Add(24 * time.Hour))
+	assertHeld()
+
+	ctx.notifier.blockChan <- &chainntnfs.BlockEpoch{
+		Height: int32(testHtlcExpiry) - 1,
+	}
+	hold()
+	assertHeld()
+
+	// Once the htlc reaches the expiry delta, which is zero in the test
+	// context, it is failed whatever the acceptor decided.
+	ctx.notifier.blockChan <- &chainntnfs.BlockEpoch{
+		Height: int32(testHtlcExpiry),
+	}
+
+	select {
+	case res := <-hodlChan:
+		checkFailResolution(
+			t, res.(invpkg.HtlcResolution),
+			invpkg.ResultHtlcAcceptorExpiry,
+		)
+
+	case <-time.After(testTimeout):
+		t.Fatal("expired htlc not failed")
+	}
+
+	require.ErrorIs(t, ctx.registry.ResolveHeldHtlc(
+		invpkg.HtlcAcceptorResolution{
+			CircuitKey: getCircuitKey(0),
+			Action:     invpkg.HtlcAcceptActionHold,
+		},
+	), invpkg.ErrHtlcNotHeld)
+}
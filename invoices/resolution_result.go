@@ -120,6 +120,31 @@ const (
 	// ResultAmpReconstruction is returned when the derived child
 	// hash/preimage pairs were invalid for at least one HTLC in the set.
 	ResultAmpReconstruction
+
+	// ResultHtlcAcceptorRejected is returned when the htlc acceptor
+	// rejects an htlc.
+	ResultHtlcAcceptorRejected
+
+	// ResultHtlcAcceptorTemporaryFailure is returned when the htlc
+	// acceptor rejects an htlc with a temporary node failure.
+	ResultHtlcAcceptorTemporaryFailure
+
+	// ResultHtlcAcceptorPermanentFailure is returned when the htlc
+	// acceptor rejects an htlc with a permanent node failure.
+	ResultHtlcAcceptorPermanentFailure
+
+	// ResultHtlcAcceptorTimeout is returned when the htlc acceptor doesn't
+	// resolve an htlc in time.
+	ResultHtlcAcceptorTimeout
+
+	// ResultHtlcAcceptorUnavailable is returned when the htlc acceptor
+	// unregisters while it holds an htlc.
+	ResultHtlcAcceptorUnavailable
+
+	// ResultHtlcAcceptorExpiry is returned when an htlc that is held by
+	// the htlc acceptor gets within the hold expiry delta of its expiry
+	// height.
+	ResultHtlcAcceptorExpiry
 )
 
 // String returns a string representation of the result.
@@ -189,6 +214,24 @@ func (f FailResolutionResult) FailureString() string {
 	case ResultAmpReconstruction:
 		return "amp reconstruction failed"
 
+	case ResultHtlcAcceptorRejected:
+		return "rejected by htlc acceptor"
+
+	case ResultHtlcAcceptorTemporaryFailure:
+		return "temporary failure from htlc acceptor"
+
+	case ResultHtlcAcceptorPermanentFailure:
+		return "permanent failure from htlc acceptor"
+
+	case ResultHtlcAcceptorTimeout:
+		return "htlc acceptor timeout"
+
+	case ResultHtlcAcceptorUnavailable:
+		return "htlc acceptor unavailable"
+
+	case ResultHtlcAcceptorExpiry:
+		return "htlc acceptor held htlc too close to expiry"
+
 	default:
 		return "unknown failure resolution result"
 	}
@@ -87,6 +87,8 @@ type DB struct {
 
 	UseNativeSQL bool `long:"use-native-sql" description:"Use native SQL for tables that already support it."`
 
+	MigrateNativeSQL bool `long:"migrate-native-sql" description:"Migrate the channel graph, the payments and the forwarding log from the kv store to the native SQL tables, then exit. This is an offline step that may take a long time on busy nodes and must be run once before lnd is started with use-native-sql on a database that holds kv data. Requires use-native-sql."`
+
 	NoGraphCache bool `long:"no-graph-cache" description:"Don't use the in-memory graph cache for path finding. Much slower but uses less RAM. Can only be used with a bolt database backend."`
 
 	PruneRevocation bool `long:"prune-revocation" description:"Run the optional migration that prunes the revocation logs to save disk space."`
@@ -150,6 +152,10 @@ func (db *DB) Validate() error {
 			PostgresBackend, SqliteBackend)
 	}
 
+	if db.MigrateNativeSQL && !db.UseNativeSQL {
+		return fmt.Errorf("migrate-native-sql requires use-native-sql")
+	}
+
 	// The path finding uses a manual read transaction that's open for a
 	// potentially long time. That works fine with the locking model of
 	// bbolt but can lead to locks or rolled back transactions with etcd or
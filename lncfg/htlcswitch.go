@@ -16,6 +16,36 @@ var (
 //nolint:lll
 type Htlcswitch struct {
 	MailboxDeliveryTimeout time.Duration `long:"mailboxdeliverytimeout" description:"The timeout value when delivering HTLCs to a channel link. Setting this value too small will result in local payment failures if large number of payments are sent over a short period."`
+
+	Jamming *Jamming `group:"jamming" namespace:"jamming"`
+
+	RateLimit *HtlcRateLimit `group:"ratelimit" namespace:"ratelimit"`
+}
+
+// Jamming holds the configuration options for the mitigation of channel
+// jamming attacks.
+//
+//nolint:lll
+type Jamming struct {
+	Active               bool          `long:"active" description:"If true, forwarded HTLCs are assigned to resource buckets based on their endorsement signal and the reputation of their incoming peer. Only endorsed HTLCs from peers with good reputation may use the protected share of the slots and liquidity of the outgoing channel."`
+	MonitorOnly          bool          `long:"monitor-only" description:"If true, the decisions of the jamming mitigation are only logged. All HTLCs are forwarded and their endorsement signal is relayed unchanged."`
+	ProtectedPercentage  uint8         `long:"protected-percentage" description:"The percentage of the slots and liquidity of each outgoing channel that is reserved for endorsed HTLCs from peers with good reputation."`
+	RevenueWindow        time.Duration `long:"revenue-window" description:"The period over which the fees that an outgoing channel earns are tracked."`
+	ReputationMultiplier uint8         `long:"reputation-multiplier" description:"The multiple of the revenue window over which the reputation of a peer is tracked."`
+	ResolutionPeriod     time.Duration `long:"resolution-period" description:"The time within which a forwarded HTLC is expected to resolve. Endorsed HTLCs that take longer are charged an opportunity cost against the reputation of their peer."`
+}
+
+// HtlcRateLimit holds the configuration options for limiting the rate at which
+// peers may add HTLCs to our channels.
+//
+//nolint:lll
+type HtlcRateLimit struct {
+	Active              bool    `long:"active" description:"If true, HTLCs that a peer adds faster than the configured rates or beyond its in-flight budget are failed back with a temporary channel failure, or with a temporary node failure if we are their final hop."`
+	PeerAddRate         float64 `long:"peer-add-rate" description:"The number of HTLCs per second that a peer may add across all of its channels with us. Set to 0 to disable the limit."`
+	PeerAddBurst        int     `long:"peer-add-burst" description:"The number of HTLCs that a peer may add in a burst across all of its channels with us before the peer-add-rate applies."`
+	ChanAddRate         float64 `long:"chan-add-rate" description:"The number of HTLCs per second that a peer may add to a single channel with us. Set to 0 to disable the limit."`
+	ChanAddBurst        int     `long:"chan-add-burst" description:"The number of HTLCs that a peer may add to a single channel in a burst before the chan-add-rate applies."`
+	PeerMaxInFlightMsat uint64  `long:"peer-max-inflight-msat" description:"The maximum total value in millisatoshis of the unresolved HTLCs that a peer may have added across all of its channels with us. Set to 0 to disable the limit."`
 }
 
 // Validate checks the values configured for htlcswitch.
@@ -30,5 +60,55 @@ func (h *Htlcswitch) Validate() error {
 			MaxMailboxDeliveryTimeout)
 	}
 
+	if h.Jamming != nil && h.Jamming.Active {
+		if err := h.Jamming.validate(); err != nil {
+			return fmt.Errorf("invalid jamming config: %w", err)
+		}
+	}
+
+	if h.RateLimit != nil && h.RateLimit.Active {
+		if err := h.RateLimit.validate(); err != nil {
+			return fmt.Errorf("invalid ratelimit config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the jamming mitigation config options are sane.
+func (j *Jamming) validate() error {
+	switch {
+	case j.ProtectedPercentage > 100:
+		return fmt.Errorf("protected-percentage must not exceed 100")
+
+	case j.RevenueWindow <= 0:
+		return fmt.Errorf("revenue-window must be positive")
+
+	case j.ReputationMultiplier == 0:
+		return fmt.Errorf("reputation-multiplier must be positive")
+
+	case j.ResolutionPeriod <= 0:
+		return fmt.Errorf("resolution-period must be positive")
+	}
+
+	return nil
+}
+
+// validate checks that the htlc rate limit config options are sane.
+func (r *HtlcRateLimit) validate() error {
+	switch {
+	case r.PeerAddRate < 0:
+		return fmt.Errorf("peer-add-rate must not be negative")
+
+	case r.PeerAddRate > 0 && r.PeerAddBurst <= 0:
+		return fmt.Errorf("peer-add-burst must be positive")
+
+	case r.ChanAddRate < 0:
+		return fmt.Errorf("chan-add-rate must not be negative")
+
+	case r.ChanAddRate > 0 && r.ChanAddBurst <= 0:
+		return fmt.Errorf("chan-add-burst must be positive")
+	}
+
 	return nil
 }
@@ -1,5 +1,7 @@
 package lncfg
 
+import "time"
+
 const (
 	// DefaultHoldInvoiceExpiryDelta defines the number of blocks before the
 	// expiry height of a hold invoice's htlc that lnd will automatically
@@ -32,6 +34,11 @@ const (
 	// used to decrease certain blinded hop policy values in order to add a
 	// probing buffer.
 	DefaultBlindedPathPolicyDecreaseMultiplier = 0.9
+
+	// DefaultHtlcAcceptorTimeout is the default time that the htlc
+	// acceptor has to decide on an htlc that pays to one of our invoices
+	// before the htlc is failed.
+	DefaultHtlcAcceptorTimeout = time.Minute
 )
 
 // Invoices holds the configuration options for invoices.
@@ -39,6 +46,8 @@ const (
 //nolint:lll
 type Invoices struct {
 	HoldExpiryDelta uint32 `long:"holdexpirydelta" description:"The number of blocks before a hold invoice's htlc expires that the invoice should be canceled to prevent a force close. Force closes will not be prevented if this value is not greater than DefaultIncomingBroadcastDelta."`
+
+	HtlcAcceptorTimeout time.Duration `long:"htlcacceptortimeout" description:"The time that the htlc acceptor of the invoices RPC has to accept, reject or hold an htlc that pays to one of our invoices. Htlcs that aren't resolved in time are failed back. Set to 0 to disable the timeout. Held htlcs are always failed once they get within holdexpirydelta of their expiry height."`
 }
 
 // Validate checks that the various invoice config options are sane.
@@ -0,0 +1,93 @@
+package lncfg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultOnionMsgPeerBurst is the default number of onion messages
+	// that a single peer can send us in a burst.
+	DefaultOnionMsgPeerBurst = 20
+
+	// DefaultOnionMsgPeerInterval is the default interval at which a
+	// peer's onion message budget is replenished by one message.
+	DefaultOnionMsgPeerInterval = 250 * time.Millisecond
+
+	// DefaultOnionMsgMaxPending is the default number of onion messages
+	// that can be waiting to be processed across all peers.
+	DefaultOnionMsgMaxPending = 500
+
+	// DefaultInvoiceRequestPeerBurst is the default number of BOLT 12
+	// invoice requests that a single peer can deliver to us in a burst.
+	DefaultInvoiceRequestPeerBurst = 10
+
+	// DefaultInvoiceRequestPeerInterval is the default interval at which
+	// a peer's invoice request budget is replenished by one request.
+	DefaultInvoiceRequestPeerInterval = time.Second
+
+	// DefaultMaxPendingInvoiceRequests is the default number of invoice
+	// requests that are processed at the same time.
+	DefaultMaxPendingInvoiceRequests = 20
+)
+
+// OnionMsg holds the configuration options for relaying onion messages.
+//
+//nolint:lll
+type OnionMsg struct {
+	PeerBurst int `long:"peer-burst" description:"The maximum number of onion messages that a single peer can send us in a burst before further messages from that peer are dropped."`
+
+	PeerInterval time.Duration `long:"peer-interval" description:"The interval at which a peer's budget of onion messages is replenished by one message."`
+
+	MaxPending int `long:"max-pending" description:"The maximum number of onion messages from all peers that can be waiting to be processed. Messages that arrive when this limit is reached are dropped."`
+
+	InvoiceRequestPeerBurst int `long:"invreq-peer-burst" description:"The maximum number of BOLT 12 invoice requests that a single peer can deliver to us in a burst before further requests delivered by that peer are dropped."`
+
+	InvoiceRequestPeerInterval time.Duration `long:"invreq-peer-interval" description:"The interval at which a peer's budget of invoice requests is replenished by one request."`
+
+	MaxPendingInvoiceRequests int `long:"max-pending-invreqs" description:"The maximum number of invoice requests that are processed at the same time. Invoice requests that arrive when this limit is reached are dropped."`
+}
+
+// DefaultOnionMsg returns the default onion message configuration.
+func DefaultOnionMsg() *OnionMsg {
+	return &OnionMsg{
+		PeerBurst:    DefaultOnionMsgPeerBurst,
+		PeerInterval: DefaultOnionMsgPeerInterval,
+		MaxPending:   DefaultOnionMsgMaxPending,
+
+		InvoiceRequestPeerBurst:    DefaultInvoiceRequestPeerBurst,
+		InvoiceRequestPeerInterval: DefaultInvoiceRequestPeerInterval,
+		MaxPendingInvoiceRequests:  DefaultMaxPendingInvoiceRequests,
+	}
+}
+
+// Validate checks the values configured for onion messages.
+func (o *OnionMsg) Validate() error {
+	if o.PeerBurst <= 0 {
+		return fmt.Errorf("onionmsg.peer-burst must be positive")
+	}
+
+	if o.PeerInterval <= 0 {
+		return fmt.Errorf("onionmsg.peer-interval must be positive")
+	}
+
+	if o.MaxPending <= 0 {
+		return fmt.Errorf("onionmsg.max-pending must be positive")
+	}
+
+	if o.InvoiceRequestPeerBurst <= 0 {
+		return fmt.Errorf("onionmsg.invreq-peer-burst must be positive")
+	}
+
+	if o.InvoiceRequestPeerInterval <= 0 {
+		return fmt.Errorf("onionmsg.invreq-peer-interval must be " +
+			"positive")
+	}
+
+	if o.MaxPendingInvoiceRequests <= 0 {
+		return fmt.Errorf("onionmsg.max-pending-invreqs must be " +
+			"positive")
+	}
+
+	return nil
+}
@@ -7,3 +7,15 @@ package lncfg
 // features that also require a build-tag to activate.
 type ExperimentalProtocol struct {
 }
+
+// DynCommitments returns true if we have enabled support for dynamic
+// commitment upgrades, which are only available in dev builds.
+func (p ExperimentalProtocol) DynCommitments() bool {
+	return false
+}
+
+// Splice returns true if we have enabled support for splicing channels, which
+// is only available in dev builds.
+func (p ExperimentalProtocol) Splice() bool {
+	return false
+}
@@ -5,5 +5,30 @@ package lncfg
 
 // ExperimentalProtocol is a sub-config that houses any experimental protocol
 // features that also require a build-tag to activate.
+//
+//nolint:lll
 type ExperimentalProtocol struct {
+	// DynCommitmentsOption should be set if we want to signal support for
+	// dynamic commitment upgrades of open channels. The feature bits of
+	// dynamic commitments aren't assigned by the spec yet, so they're
+	// only advertised by dev builds.
+	DynCommitmentsOption bool `long:"dyn-commitments" description:"enable experimental support for upgrading the parameters and type of open channels with dynamic commitments"`
+
+	// SpliceOption should be set if we want to signal support for
+	// splicing funds into and out of open channels. The splice signatures
+	// are exchanged in a format that differs from the spec, so splicing
+	// is signaled with experimental feature bits that are only advertised
+	// by dev builds.
+	SpliceOption bool `long:"splice" description:"enable experimental support for splicing funds into and out of private, non-taproot channels with other lnd nodes; only the initiator contributes funds and splice transactions can't be replaced with RBF"`
+}
+
+// DynCommitments returns true if we have enabled support for dynamic
+// commitment upgrades.
+func (p ExperimentalProtocol) DynCommitments() bool {
+	return p.DynCommitmentsOption
+}
+
+// Splice returns true if we have enabled support for splicing channels.
+func (p ExperimentalProtocol) Splice() bool {
+	return p.SpliceOption
 }
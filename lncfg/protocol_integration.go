@@ -66,6 +66,25 @@ type ProtocolOptions struct {
 	// NoRouteBlindingOption disables forwarding of payments in blinded routes.
 	NoRouteBlindingOption bool `long:"no-route-blinding" description:"do not forward payments that are a part of a blinded route"`
 
+	// OnionMessagesOption should be set if we want to signal support for
+	// forwarding and receiving onion messages.
+	OnionMessagesOption bool `long:"onion-messages" description:"enable support for forwarding and receiving onion messages"`
+
+	// TrampolineRoutingOption should be set if we want to forward payments
+	// as a trampoline node.
+	TrampolineRoutingOption bool `long:"trampoline-routing" description:"enable forwarding payments as a trampoline node"`
+
+
+	// DualFundOption should be set if we want to signal support for dual
+	// funded channels, whose funding transaction is constructed
+	// interactively.
+	DualFundOption bool `long:"dual-fund" description:"enable support for dual funded channels opened with open_channel2"`
+
+	// SimpleCloseOption should be set if we want to signal support for
+	// the simple close protocol, which allows cooperative close
+	// transactions to be replaced with higher fee versions.
+	SimpleCloseOption bool `long:"simple-close" description:"enable support for RBF-able cooperative closes with closing_complete and closing_sig"`
+
 	// CustomMessage allows the custom message APIs to handle messages with
 	// the provided protocol numbers, which fall outside the custom message
 	// number range.
@@ -123,6 +142,29 @@ func (l *ProtocolOptions) NoRouteBlinding() bool {
 	return l.NoRouteBlindingOption
 }
 
+// OnionMessages returns true if we have enabled support for forwarding and
+// receiving onion messages.
+func (l *ProtocolOptions) OnionMessages() bool {
+	return l.OnionMessagesOption
+}
+
+// TrampolineRouting returns true if we have enabled forwarding payments as a
+// trampoline node.
+func (l *ProtocolOptions) TrampolineRouting() bool {
+	return l.TrampolineRoutingOption
+}
+
+// DualFund returns true if we have enabled support for dual funded channels.
+func (l *ProtocolOptions) DualFund() bool {
+	return l.DualFundOption
+}
+
+// SimpleClose returns true if we have enabled support for the simple close
+// protocol.
+func (l *ProtocolOptions) SimpleClose() bool {
+	return l.SimpleCloseOption
+}
+
 // CustomMessageOverrides returns the set of protocol messages that we override
 // to allow custom handling.
 func (l ProtocolOptions) CustomMessageOverrides() []uint16 {
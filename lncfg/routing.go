@@ -1,6 +1,66 @@
 package lncfg
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultFeeAutopilotInterval is the default interval at which the fee
+	// autopilot reconsiders the fees of our channels.
+	DefaultFeeAutopilotInterval = time.Hour
+
+	// DefaultFeeAutopilotCoolDown is the default minimum time between two
+	// fee updates of the same channel by the fee autopilot.
+	DefaultFeeAutopilotCoolDown = 24 * time.Hour
+
+	// DefaultFeeAutopilotForwardingWindow is the default time span of the
+	// forwarding history that the fee autopilot takes into account.
+	DefaultFeeAutopilotForwardingWindow = 24 * time.Hour
+
+	// DefaultFeeAutopilotLowLiquidityRatio is the default ratio of local
+	// balance to capacity below which a channel is considered depleted.
+	DefaultFeeAutopilotLowLiquidityRatio = 0.2
+
+	// DefaultFeeAutopilotHighLiquidityRatio is the default ratio of local
+	// balance to capacity above which a channel is considered to have
+	// excess local liquidity.
+	DefaultFeeAutopilotHighLiquidityRatio = 0.8
+
+	// DefaultFeeAutopilotMinDailyFlowRatio is the default ratio of the
+	// amount forwarded per day in one direction of a channel to its
+	// capacity at or above which the channel counts as forwarding.
+	DefaultFeeAutopilotMinDailyFlowRatio = 0.01
+
+	// DefaultFeeAutopilotMinFeeRate is the default lower bound of the
+	// outbound fee rate set by the fee autopilot, in parts per million.
+	DefaultFeeAutopilotMinFeeRate = 1
+
+	// DefaultFeeAutopilotMaxFeeRate is the default upper bound of the
+	// outbound fee rate set by the fee autopilot, in parts per million.
+	DefaultFeeAutopilotMaxFeeRate = 2500
+
+	// DefaultFeeAutopilotFeeRateStep is the default fraction by which the
+	// fee autopilot changes the outbound fee rate in a single update.
+	DefaultFeeAutopilotFeeRateStep = 0.1
+
+	// DefaultFeeAutopilotMinInboundFeeRate is the default largest inbound
+	// discount given by the fee autopilot, in parts per million.
+	DefaultFeeAutopilotMinInboundFeeRate = -100
+
+	// DefaultFeeAutopilotInboundFeeRateStep is the default amount by which
+	// the fee autopilot changes the inbound fee rate in a single update,
+	// in parts per million.
+	DefaultFeeAutopilotInboundFeeRateStep = 10
+
+	// DefaultPathCacheSize is the default maximum number of destinations
+	// that the path cache holds.
+	DefaultPathCacheSize = 1000
+
+	// DefaultPathCacheTTL is the default time after which a cached path
+	// expires.
+	DefaultPathCacheTTL = 10 * time.Minute
+)
 
 // Routing holds the configuration options for routing.
 //
@@ -11,6 +71,10 @@ type Routing struct {
 	StrictZombiePruning bool `long:"strictgraphpruning" description:"If true, then the graph will be pruned more aggressively for zombies. In practice this means that edges with a single stale edge will be considered a zombie."`
 
 	BlindedPaths BlindedPaths `group:"blinding" namespace:"blinding"`
+
+	FeeAutopilot FeeAutopilot `group:"feeautopilot" namespace:"feeautopilot"`
+
+	PathCache PathCache `group:"pathcache" namespace:"pathcache"`
 }
 
 // BlindedPaths holds the configuration options for blinded path construction.
@@ -24,6 +88,36 @@ type BlindedPaths struct {
 	PolicyDecreaseMultiplier float64 `long:"policy-decrease-multiplier" description:"The amount by which to decrease certain policy values of hops on a blinded path in order to add a probing buffer."`
 }
 
+// FeeAutopilot holds the configuration options for the fee autopilot that
+// adjusts the fees of our channels.
+//
+//nolint:lll
+type FeeAutopilot struct {
+	Active             bool          `long:"active" description:"If true, the outbound and inbound fee rates of our channels are adjusted automatically based on their local balance and forwarding history."`
+	DryRun             bool          `long:"dry-run" description:"If true, the fee autopilot only logs the fee updates it would make instead of applying them."`
+	Interval           time.Duration `long:"interval" description:"The interval at which the fees of our channels are reconsidered."`
+	CoolDown           time.Duration `long:"cool-down" description:"The minimum time between two fee updates of the same channel."`
+	ForwardingWindow   time.Duration `long:"forwarding-window" description:"The time span of the forwarding history that is taken into account."`
+	LowLiquidityRatio  float64       `long:"low-liquidity-ratio" description:"The ratio of local balance to capacity below which a channel is considered depleted. Depleted channels that still forward get more expensive and a larger inbound discount."`
+	HighLiquidityRatio float64       `long:"high-liquidity-ratio" description:"The ratio of local balance to capacity above which a channel is considered to have excess local liquidity. Such channels get cheaper if they don't forward and lose their inbound discount."`
+	MinDailyFlowRatio  float64       `long:"min-daily-flow-ratio" description:"The ratio of the amount forwarded per day in one direction of a channel to its capacity at or above which the channel counts as forwarding in that direction. Channels with less volume are treated as if they didn't forward."`
+	MinFeeRate         uint32        `long:"min-fee-rate" description:"The lower bound of the outbound fee rate in parts per million."`
+	MaxFeeRate         uint32        `long:"max-fee-rate" description:"The upper bound of the outbound fee rate in parts per million."`
+	FeeRateStep        float64       `long:"fee-rate-step" description:"The fraction by which the outbound fee rate is raised or lowered in a single update."`
+	MinInboundFeeRate  int32         `long:"min-inbound-fee-rate" description:"The largest inbound discount as a negative fee rate in parts per million. If set to 0, the inbound fees of our channels are left untouched."`
+	InboundFeeRateStep uint32        `long:"inbound-fee-rate-step" description:"The amount in parts per million by which the inbound fee rate is changed in a single update."`
+}
+
+// PathCache holds the configuration options for the persistent cache of
+// pre-computed paths to the destinations that were paid before.
+//
+//nolint:lll
+type PathCache struct {
+	Active bool          `long:"active" description:"If true, the paths to destinations that were paid before are pre-computed and cached per destination and amount range, so that repeated payments to the same destination can skip path finding. The destinations are persisted, and their paths are pre-computed again after a restart and whenever a channel update or payment failure invalidated them."`
+	Size   int           `long:"size" description:"The maximum number of cached destinations. If the cache is full, the least recently used destination is evicted."`
+	TTL    time.Duration `long:"ttl" description:"The time after which a cached path expires and is pre-computed again, so that better paths are eventually picked up."`
+}
+
 // Validate checks that the various routing config options are sane.
 //
 // NOTE: this is part of the Validator interface.
@@ -46,5 +140,52 @@ func (r *Routing) Validate() error {
 			"multiplier must be in the range (0,1]")
 	}
 
+	if r.FeeAutopilot.Active {
+		if err := r.FeeAutopilot.validate(); err != nil {
+			return fmt.Errorf("invalid fee autopilot config: %w",
+				err)
+		}
+	}
+
+	if r.PathCache.Active {
+		if r.PathCache.Size <= 0 {
+			return fmt.Errorf("the path cache size must be " +
+				"positive")
+		}
+
+		if r.PathCache.TTL <= 0 {
+			return fmt.Errorf("the path cache ttl must be " +
+				"positive")
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the fee autopilot config options are sane.
+func (f *FeeAutopilot) validate() error {
+	switch {
+	case f.Interval <= 0:
+		return fmt.Errorf("interval must be positive")
+
+	case f.LowLiquidityRatio < 0 || f.HighLiquidityRatio > 1 ||
+		f.LowLiquidityRatio >= f.HighLiquidityRatio:
+
+		return fmt.Errorf("the liquidity ratios must satisfy 0 <= " +
+			"low-liquidity-ratio < high-liquidity-ratio <= 1")
+
+	case f.MinDailyFlowRatio < 0:
+		return fmt.Errorf("min-daily-flow-ratio must not be negative")
+
+	case f.MinFeeRate > f.MaxFeeRate:
+		return fmt.Errorf("min-fee-rate must not be above max-fee-rate")
+
+	case f.FeeRateStep <= 0:
+		return fmt.Errorf("fee-rate-step must be positive")
+
+	case f.MinInboundFeeRate > 0:
+		return fmt.Errorf("min-inbound-fee-rate must not be positive")
+	}
+
 	return nil
 }
@@ -31,6 +31,10 @@ type WtClient struct {
 	// MaxUpdates is the maximum number of updates to be backed up in a
 	// single tower sessions.
 	MaxUpdates uint16 `long:"max-updates" description:"The maximum number of updates to be backed up in a single session."`
+
+	// SweepHtlcs determines whether the revoked HTLC outputs of our
+	// channels should be backed up in addition to the commitment outputs.
+	SweepHtlcs bool `long:"sweep-htlcs" description:"Whether to also back up the revoked HTLC outputs of channels, which requires towers that support sweeping them."`
 }
 
 // DefaultWtClientCfg returns the WtClient config struct with some default
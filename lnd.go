@@ -446,7 +446,9 @@ func Main(cfg *Config, lisCfg ListenerCfg, implCfg *ImplementationCfg,
 
 	dbs, cleanUp, err := implCfg.DatabaseBuilder.BuildDatabase(ctx)
 	switch {
-	case err == channeldb.ErrDryRunMigrationOK:
+	case err == channeldb.ErrDryRunMigrationOK,
+		err == channeldb.ErrNativeSQLMigrationOK:
+
 		ltndLog.Infof("%v, exiting", err)
 		return nil
 	case err != nil:
@@ -541,6 +543,7 @@ func Main(cfg *Config, lisCfg ListenerCfg, implCfg *ImplementationCfg,
 			BlockFetcher:   activeChainControl.ChainIO,
 			DB:             dbs.TowerServerDB,
 			EpochRegistrar: activeChainControl.ChainNotifier,
+			SpendRegistrar: activeChainControl.ChainNotifier,
 			Net:            cfg.net,
 			NewAddress: func() (btcutil.Address, error) {
 				return activeChainControl.Wallet.NewAddress(
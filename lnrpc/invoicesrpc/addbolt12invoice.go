@@ -0,0 +1,132 @@
+package invoicesrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/bolt12"
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnutils"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// Bolt12InvoiceData contains the required data to create a BOLT 12 invoice in
+// response to an invoice request.
+type Bolt12InvoiceData struct {
+	// Request is the validated invoice request that the invoice is
+	// created for.
+	Request *bolt12.InvoiceRequest
+
+	// NodeID is the key that the invoice is signed with. This must match
+	// the issuer ID of the offer.
+	NodeID *btcec.PublicKey
+
+	// Signer signs the invoice with the private key of NodeID.
+	Signer bolt12.SignFunc
+
+	// Expiry is the relative expiry of the invoice. If zero,
+	// bolt12.DefaultRelativeExpiry is used.
+	Expiry time.Duration
+
+	// BlindedPathCfg holds the config values to use when constructing the
+	// blinded paths of the invoice, which are mandatory for BOLT 12
+	// invoices.
+	BlindedPathCfg *BlindedPathConfig
+}
+
+// AddBolt12Invoice creates a BOLT 12 invoice for the given invoice request and
+// adds it to the invoice database. The returned invoice is signed and ready to
+// be sent to the payer.
+func AddBolt12Invoice(ctx context.Context, cfg *AddInvoiceConfig,
+	data *Bolt12InvoiceData) (*bolt12.Invoice, error) {
+
+	if data.BlindedPathCfg == nil {
+		return nil, fmt.Errorf("blinded path config required for " +
+			"BOLT 12 invoices")
+	}
+
+	expiry := data.Expiry
+	if expiry == 0 {
+		expiry = bolt12.DefaultRelativeExpiry
+	}
+
+	amtMSat := data.Request.AmountToPay()
+
+	var paymentPreimage lntypes.Preimage
+	if _, err := rand.Read(paymentPreimage[:]); err != nil {
+		return nil, err
+	}
+	paymentHash := paymentPreimage.Hash()
+
+	// As with BOLT 11 invoices with blinded paths, the payment address is
+	// only used as the path ID of the blinded paths, which is how the
+	// registry matches incoming payments to the invoice.
+	var paymentAddr [32]byte
+	if _, err := rand.Read(paymentAddr[:]); err != nil {
+		return nil, err
+	}
+
+	paths, err := buildBlindedPaymentPaths(
+		cfg, data.BlindedPathCfg, amtMSat, paymentAddr[:], expiry,
+		uint64(cfg.DefaultCLTVExpiry),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	creationDate := time.Now()
+	invoice := &bolt12.Invoice{
+		InvoiceRequest: data.Request,
+		Paths:          paths,
+		CreatedAt:      creationDate,
+		RelativeExpiry: expiry,
+		PaymentHash:    paymentHash,
+		Amount:         amtMSat,
+		Features: lnwire.NewRawFeatureVector(
+			lnwire.MPPOptional,
+		),
+		NodeID: data.NodeID,
+	}
+	if err := invoice.Sign(data.Signer); err != nil {
+		return nil, err
+	}
+
+	payReqString, err := invoice.EncodeString()
+	if err != nil {
+		return nil, err
+	}
+
+	memo := data.Request.Offer.Description
+	if len(memo) > invoices.MaxMemoSize {
+		memo = memo[:invoices.MaxMemoSize]
+	}
+
+	newInvoice := &invoices.Invoice{
+		CreationDate:   creationDate,
+		Memo:           []byte(memo),
+		PaymentRequest: []byte(payReqString),
+		Terms: invoices.ContractTerm{
+			FinalCltvDelta:  zpay32.DefaultAssumedFinalCLTVDelta,
+			Expiry:          expiry,
+			Value:           amtMSat,
+			PaymentPreimage: &paymentPreimage,
+			PaymentAddr:     paymentAddr,
+			Features:        cfg.GenInvoiceFeatures(),
+		},
+	}
+
+	log.Tracef("[addbolt12invoice] adding new invoice %v",
+		lnutils.SpewLogClosure(newInvoice))
+
+	_, err = cfg.AddInvoice(ctx, newInvoice, paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
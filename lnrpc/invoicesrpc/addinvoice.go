@@ -361,32 +361,9 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		options = append(options, zpay32.FallbackAddr(addr))
 	}
 
-	var expiry time.Duration
-	switch {
-	// An invoice expiry has been provided by the caller.
-	case invoice.Expiry > 0:
-
-		// We'll ensure that the specified expiry is restricted to sane
-		// number of seconds. As a result, we'll reject an invoice with
-		// an expiry greater than 1 year.
-		maxExpiry := time.Hour * 24 * 365
-		expSeconds := invoice.Expiry
-
-		if float64(expSeconds) > maxExpiry.Seconds() {
-			return nil, nil, fmt.Errorf("expiry of %v seconds "+
-				"greater than max expiry of %v seconds",
-				float64(expSeconds), maxExpiry.Seconds())
-		}
-
-		expiry = time.Duration(invoice.Expiry) * time.Second
-
-	// If no custom expiry is provided, use the default MPP expiry.
-	case !invoice.Amp:
-		expiry = DefaultInvoiceExpiry
-
-	// Otherwise, use the default AMP expiry.
-	default:
-		expiry = DefaultAMPInvoiceExpiry
+	expiry, err := invoice.expiry()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	options = append(options, zpay32.Expiry(expiry))
@@ -404,25 +381,11 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		options = append(options, zpay32.Description(invoice.Memo))
 	}
 
-	if invoice.CltvExpiry > routing.MaxCLTVDelta {
-		return nil, nil, fmt.Errorf("CLTV delta of %v is too large, "+
-			"max accepted is: %v", invoice.CltvExpiry,
-			math.MaxUint16)
-	}
-
 	// We'll use our current default CLTV value unless one was specified as
 	// an option on the command line when creating an invoice.
-	cltvExpiryDelta := uint64(cfg.DefaultCLTVExpiry)
-	if invoice.CltvExpiry != 0 {
-		// Disallow user-chosen final CLTV deltas below the required
-		// minimum.
-		if invoice.CltvExpiry < routing.MinCLTVDelta {
-			return nil, nil, fmt.Errorf("CLTV delta of %v must be "+
-				"greater than minimum of %v",
-				invoice.CltvExpiry, routing.MinCLTVDelta)
-		}
-
-		cltvExpiryDelta = invoice.CltvExpiry
+	cltvExpiryDelta, err := invoice.cltvExpiryDelta(cfg.DefaultCLTVExpiry)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Only include a final CLTV expiry delta if this is not a blinded
@@ -501,47 +464,9 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	}
 
 	if blind {
-		blindCfg := invoice.BlindedPathCfg
-
-		// Use the 10-min-per-block assumption to get a rough estimate
-		// of the number of blocks until the invoice expires. We want
-		// to make sure that the blinded path definitely does not expire
-		// before the invoice does, and so we add a healthy buffer.
-		invoiceExpiry := uint32(expiry.Minutes() / 10)
-		blindedPathExpiry := invoiceExpiry * 2
-
-		// Add BlockPadding to the finalCltvDelta so that the receiving
-		// node does not reject the HTLC if some blocks are mined while
-		// the payment is in-flight. Note that unlike vanilla invoices,
-		// with blinded paths, the recipient is responsible for adding
-		// this block padding instead of the sender.
-		finalCLTVDelta := uint32(cltvExpiryDelta)
-		finalCLTVDelta += uint32(routing.BlockPadding)
-
-		//nolint:lll
-		paths, err := blindedpath.BuildBlindedPaymentPaths(
-			&blindedpath.BuildBlindedPathCfg{
-				FindRoutes:              cfg.QueryBlindedRoutes,
-				FetchChannelEdgesByID:   cfg.Graph.FetchChannelEdgesByID,
-				FetchOurOpenChannels:    cfg.ChanDB.FetchAllOpenChannels,
-				PathID:                  paymentAddr[:],
-				ValueMsat:               invoice.Value,
-				BestHeight:              cfg.BestHeight,
-				MinFinalCLTVExpiryDelta: finalCLTVDelta,
-				BlocksUntilExpiry:       blindedPathExpiry,
-				AddPolicyBuffer: func(
-					p *blindedpath.BlindedHopPolicy) (
-					*blindedpath.BlindedHopPolicy, error) {
-
-					//nolint:lll
-					return blindedpath.AddPolicyBuffer(
-						p, blindCfg.RoutePolicyIncrMultiplier,
-						blindCfg.RoutePolicyDecrMultiplier,
-					)
-				},
-				MinNumHops:            blindCfg.MinNumPathHops,
-				DefaultDummyHopPolicy: blindCfg.DefaultDummyHopPolicy,
-			},
+		paths, err := buildBlindedPaymentPaths(
+			cfg, invoice.BlindedPathCfg, invoice.Value,
+			paymentAddr[:], expiry, cltvExpiryDelta,
 		)
 		if err != nil {
 			return nil, nil, err
@@ -621,6 +546,154 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	return &paymentHash, newInvoice, nil
 }
 
+// expiry returns the expiry of the invoice, applying the default expiry if the
+// caller didn't provide one.
+func (d *AddInvoiceData) expiry() (time.Duration, error) {
+	switch {
+	// An invoice expiry has been provided by the caller.
+	case d.Expiry > 0:
+
+		// We'll ensure that the specified expiry is restricted to sane
+		// number of seconds. As a result, we'll reject an invoice with
+		// an expiry greater than 1 year.
+		maxExpiry := time.Hour * 24 * 365
+		expSeconds := d.Expiry
+
+		if float64(expSeconds) > maxExpiry.Seconds() {
+			return 0, fmt.Errorf("expiry of %v seconds greater "+
+				"than max expiry of %v seconds",
+				float64(expSeconds), maxExpiry.Seconds())
+		}
+
+		return time.Duration(d.Expiry) * time.Second, nil
+
+	// If no custom expiry is provided, use the default MPP expiry.
+	case !d.Amp:
+		return DefaultInvoiceExpiry, nil
+
+	// Otherwise, use the default AMP expiry.
+	default:
+		return DefaultAMPInvoiceExpiry, nil
+	}
+}
+
+// cltvExpiryDelta returns the final CLTV expiry delta of the invoice. The
+// passed default is used if the caller didn't provide one.
+func (d *AddInvoiceData) cltvExpiryDelta(defaultDelta uint32) (uint64,
+	error) {
+
+	if d.CltvExpiry > routing.MaxCLTVDelta {
+		return 0, fmt.Errorf("CLTV delta of %v is too large, max "+
+			"accepted is: %v", d.CltvExpiry, math.MaxUint16)
+	}
+
+	if d.CltvExpiry == 0 {
+		return uint64(defaultDelta), nil
+	}
+
+	// Disallow user-chosen final CLTV deltas below the required minimum.
+	if d.CltvExpiry < routing.MinCLTVDelta {
+		return 0, fmt.Errorf("CLTV delta of %v must be greater than "+
+			"minimum of %v", d.CltvExpiry, routing.MinCLTVDelta)
+	}
+
+	return d.CltvExpiry, nil
+}
+
+// PreviewBlindedPaths builds the blinded paths that AddInvoice would add to an
+// invoice with the given data, without creating the invoice. Each path is
+// returned along with the real route that it was built from, so that the
+// receiver can check the privacy and payability of the paths beforehand. The
+// paths are built with a random path ID, so they can't be used to pay an
+// invoice.
+func PreviewBlindedPaths(cfg *AddInvoiceConfig, invoice *AddInvoiceData) (
+	[]*blindedpath.BlindedPathPreview, error) {
+
+	if invoice.BlindedPathCfg == nil {
+		return nil, errors.New("blinded path config required")
+	}
+
+	expiry, err := invoice.expiry()
+	if err != nil {
+		return nil, err
+	}
+
+	cltvExpiryDelta, err := invoice.cltvExpiryDelta(cfg.DefaultCLTVExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathID [32]byte
+	if _, err := rand.Read(pathID[:]); err != nil {
+		return nil, err
+	}
+
+	return blindedpath.BuildBlindedPathPreviews(
+		newBlindedPathCfg(
+			cfg, invoice.BlindedPathCfg, invoice.Value, pathID[:],
+			expiry, cltvExpiryDelta,
+		),
+	)
+}
+
+// buildBlindedPaymentPaths builds the blinded paths to our node that are
+// included in an invoice. The payment address is used as the path ID.
+func buildBlindedPaymentPaths(cfg *AddInvoiceConfig,
+	blindCfg *BlindedPathConfig, value lnwire.MilliSatoshi,
+	paymentAddr []byte, expiry time.Duration,
+	cltvExpiryDelta uint64) ([]*zpay32.BlindedPaymentPath, error) {
+
+	return blindedpath.BuildBlindedPaymentPaths(
+		newBlindedPathCfg(
+			cfg, blindCfg, value, paymentAddr, expiry,
+			cltvExpiryDelta,
+		),
+	)
+}
+
+// newBlindedPathCfg returns the config that is used to build the blinded
+// paths to our node for an invoice with the given parameters.
+func newBlindedPathCfg(cfg *AddInvoiceConfig, blindCfg *BlindedPathConfig,
+	value lnwire.MilliSatoshi, pathID []byte, expiry time.Duration,
+	cltvExpiryDelta uint64) *blindedpath.BuildBlindedPathCfg {
+
+	// Use the 10-min-per-block assumption to get a rough estimate of the
+	// number of blocks until the invoice expires. We want to make sure
+	// that the blinded path definitely does not expire before the invoice
+	// does, and so we add a healthy buffer.
+	invoiceExpiry := uint32(expiry.Minutes() / 10)
+	blindedPathExpiry := invoiceExpiry * 2
+
+	// Add BlockPadding to the finalCltvDelta so that the receiving node
+	// does not reject the HTLC if some blocks are mined while the payment
+	// is in-flight. Note that unlike vanilla invoices, with blinded paths,
+	// the recipient is responsible for adding this block padding instead
+	// of the sender.
+	finalCLTVDelta := uint32(cltvExpiryDelta)
+	finalCLTVDelta += uint32(routing.BlockPadding)
+
+	return &blindedpath.BuildBlindedPathCfg{
+		FindRoutes:              cfg.QueryBlindedRoutes,
+		FetchChannelEdgesByID:   cfg.Graph.FetchChannelEdgesByID,
+		FetchOurOpenChannels:    cfg.ChanDB.FetchAllOpenChannels,
+		PathID:                  pathID,
+		ValueMsat:               value,
+		BestHeight:              cfg.BestHeight,
+		MinFinalCLTVExpiryDelta: finalCLTVDelta,
+		BlocksUntilExpiry:       blindedPathExpiry,
+		AddPolicyBuffer: func(p *blindedpath.BlindedHopPolicy) (
+			*blindedpath.BlindedHopPolicy, error) {
+
+			return blindedpath.AddPolicyBuffer(
+				p, blindCfg.RoutePolicyIncrMultiplier,
+				blindCfg.RoutePolicyDecrMultiplier,
+			)
+		},
+		MinNumHops:            blindCfg.MinNumPathHops,
+		DefaultDummyHopPolicy: blindCfg.DefaultDummyHopPolicy,
+	}
+}
+
 // chanCanBeHopHint returns true if the target channel is eligible to be a hop
 // hint.
 func chanCanBeHopHint(channel *HopHintInfo, cfg *SelectHopHintsCfg) (
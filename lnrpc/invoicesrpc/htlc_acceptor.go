@@ -0,0 +1,178 @@
+//go:build invoicesrpc
+// +build invoicesrpc
+
+package invoicesrpc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// htlcAcceptor is a helper struct that handles the lifecycle of an RPC htlc
+// acceptor streaming session. It is created when the stream opens and
+// unregisters itself when the stream closes, which fails all htlcs that are
+// still held for it.
+type htlcAcceptor struct {
+	// stream is the bidirectional RPC stream.
+	stream Invoices_HtlcAcceptorServer
+
+	registry *invoices.InvoiceRegistry
+
+	// sendMu serializes sending on the stream, as htlcs are offered
+	// concurrently by the links.
+	sendMu sync.Mutex
+}
+
+// newHtlcAcceptor creates a new htlcAcceptor.
+func newHtlcAcceptor(registry *invoices.InvoiceRegistry,
+	stream Invoices_HtlcAcceptorServer) *htlcAcceptor {
+
+	return &htlcAcceptor{
+		stream:   stream,
+		registry: registry,
+	}
+}
+
+// run registers the htlc acceptor with the invoice registry, so that it is
+// offered the htlcs that pay to our invoices, and delivers the decisions of
+// the client to the registry until the stream closes.
+func (h *htlcAcceptor) run() error {
+	err := h.registry.SetHtlcAcceptor(h.onHtlc)
+	if errors.Is(err, invoices.ErrHtlcAcceptorExists) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := h.registry.SetHtlcAcceptor(nil); err != nil {
+			log.Errorf("Unable to unregister htlc acceptor: %v",
+				err)
+		}
+	}()
+
+	for {
+		resp, err := h.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if err := h.resolveFromClient(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// onHtlc is the function that is called by the invoice registry for every
+// htlc that pays to one of our invoices. The registry holds the htlc until
+// the client resolves it.
+func (h *htlcAcceptor) onHtlc(htlc invoices.ExitHopHtlc) error {
+	req := &HtlcAcceptorRequest{
+		CircuitKey: &CircuitKey{
+			ChanId: htlc.CircuitKey.ChanID.ToUint64(),
+			HtlcId: htlc.CircuitKey.HtlcID,
+		},
+		PaymentHash:   htlc.Hash[:],
+		AmtPaidMsat:   uint64(htlc.AmtPaid),
+		Expiry:        htlc.Expiry,
+		CurrentHeight: htlc.CurrentHeight,
+		CustomRecords: htlc.CustomRecords,
+		Metadata:      htlc.Metadata,
+	}
+
+	if htlc.MPP != nil {
+		addr := htlc.MPP.PaymentAddr()
+		req.MppRecord = &lnrpc.MPPRecord{
+			PaymentAddr:  addr[:],
+			TotalAmtMsat: int64(htlc.MPP.TotalMsat()),
+		}
+	}
+
+	if htlc.AMP != nil {
+		rootShare := htlc.AMP.RootShare()
+		setID := htlc.AMP.SetID()
+		req.AmpRecord = &lnrpc.AMPRecord{
+			RootShare:  rootShare[:],
+			SetId:      setID[:],
+			ChildIndex: htlc.AMP.ChildIndex(),
+		}
+	}
+
+	log.Tracef("Sending htlc %v to htlc acceptor", htlc.CircuitKey)
+
+	h.sendMu.Lock()
+	defer h.sendMu.Unlock()
+
+	return h.stream.Send(req)
+}
+
+// resolveFromClient hands a decision of the client to the invoice registry.
+func (h *htlcAcceptor) resolveFromClient(in *HtlcAcceptorResponse) error {
+	if in.CircuitKey == nil {
+		return status.Errorf(codes.InvalidArgument,
+			"CircuitKey missing from HtlcAcceptorResponse")
+	}
+
+	res := invoices.HtlcAcceptorResolution{
+		CircuitKey: invoices.CircuitKey{
+			ChanID: lnwire.NewShortChanIDFromInt(
+				in.CircuitKey.ChanId,
+			),
+			HtlcID: in.CircuitKey.HtlcId,
+		},
+	}
+
+	switch in.Action {
+	case HtlcAcceptAction_ACCEPT:
+		res.Action = invoices.HtlcAcceptActionAccept
+
+	case HtlcAcceptAction_HOLD:
+		res.Action = invoices.HtlcAcceptActionHold
+
+	case HtlcAcceptAction_REJECT:
+		res.Action = invoices.HtlcAcceptActionReject
+
+		switch in.FailureCode {
+		// Default to IncorrectOrUnknownPaymentDetails.
+		case 0, lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS:
+			res.FailureCode =
+				lnwire.CodeIncorrectOrUnknownPaymentDetails
+
+		case lnrpc.Failure_TEMPORARY_NODE_FAILURE:
+			res.FailureCode = lnwire.CodeTemporaryNodeFailure
+
+		case lnrpc.Failure_PERMANENT_NODE_FAILURE:
+			res.FailureCode = lnwire.CodePermanentNodeFailure
+
+		default:
+			return status.Errorf(
+				codes.InvalidArgument,
+				"unsupported failure code: %v", in.FailureCode,
+			)
+		}
+
+	default:
+		return status.Errorf(codes.InvalidArgument,
+			"unknown htlc accept action: %v", in.Action)
+	}
+
+	err := h.registry.ResolveHeldHtlc(res)
+
+	// The htlc may have timed out or been resolved on chain while the
+	// decision was in flight, which doesn't warrant ending the stream.
+	if errors.Is(err, invoices.ErrHtlcNotHeld) {
+		log.Debugf("Htlc acceptor resolved htlc %v that is no longer "+
+			"held", res.CircuitKey)
+
+		return nil
+	}
+
+	return err
+}
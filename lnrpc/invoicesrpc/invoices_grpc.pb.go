@@ -39,6 +39,15 @@ type InvoicesClient interface {
 	// LookupInvoiceV2 attempts to look up at invoice. An invoice can be refrenced
 	// using either its payment hash, payment address, or set ID.
 	LookupInvoiceV2(ctx context.Context, in *LookupInvoiceMsg, opts ...grpc.CallOption) (*lnrpc.Invoice, error)
+	// HtlcAcceptor dispatches a bi-directional streaming RPC in which htlcs that
+	// pay to one of our invoices are offered to the client before the invoice is
+	// updated. The client must respond to each htlc with a decision to accept,
+	// reject or hold it. Held htlcs that aren't resolved within the configured
+	// timeout are failed, as are all held htlcs when the stream ends. Htlcs are
+	// also failed once they get within the hold expiry delta of their expiry
+	// height, however long the client holds them. Only one htlc acceptor can be
+	// registered at a time.
+	HtlcAcceptor(ctx context.Context, opts ...grpc.CallOption) (Invoices_HtlcAcceptorClient, error)
 }
 
 type invoicesClient struct {
@@ -117,6 +126,37 @@ func (c *invoicesClient) LookupInvoiceV2(ctx context.Context, in *LookupInvoiceM
 	return out, nil
 }
 
+func (c *invoicesClient) HtlcAcceptor(ctx context.Context, opts ...grpc.CallOption) (Invoices_HtlcAcceptorClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Invoices_ServiceDesc.Streams[1], "/invoicesrpc.Invoices/HtlcAcceptor", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &invoicesHtlcAcceptorClient{stream}
+	return x, nil
+}
+
+type Invoices_HtlcAcceptorClient interface {
+	Send(*HtlcAcceptorResponse) error
+	Recv() (*HtlcAcceptorRequest, error)
+	grpc.ClientStream
+}
+
+type invoicesHtlcAcceptorClient struct {
+	grpc.ClientStream
+}
+
+func (x *invoicesHtlcAcceptorClient) Send(m *HtlcAcceptorResponse) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *invoicesHtlcAcceptorClient) Recv() (*HtlcAcceptorRequest, error) {
+	m := new(HtlcAcceptorRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // InvoicesServer is the server API for Invoices service.
 // All implementations must embed UnimplementedInvoicesServer
 // for forward compatibility
@@ -141,6 +181,15 @@ type InvoicesServer interface {
 	// LookupInvoiceV2 attempts to look up at invoice. An invoice can be refrenced
 	// using either its payment hash, payment address, or set ID.
 	LookupInvoiceV2(context.Context, *LookupInvoiceMsg) (*lnrpc.Invoice, error)
+	// HtlcAcceptor dispatches a bi-directional streaming RPC in which htlcs that
+	// pay to one of our invoices are offered to the client before the invoice is
+	// updated. The client must respond to each htlc with a decision to accept,
+	// reject or hold it. Held htlcs that aren't resolved within the configured
+	// timeout are failed, as are all held htlcs when the stream ends. Htlcs are
+	// also failed once they get within the hold expiry delta of their expiry
+	// height, however long the client holds them. Only one htlc acceptor can be
+	// registered at a time.
+	HtlcAcceptor(Invoices_HtlcAcceptorServer) error
 	mustEmbedUnimplementedInvoicesServer()
 }
 
@@ -163,6 +212,9 @@ func (UnimplementedInvoicesServer) SettleInvoice(context.Context, *SettleInvoice
 func (UnimplementedInvoicesServer) LookupInvoiceV2(context.Context, *LookupInvoiceMsg) (*lnrpc.Invoice, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LookupInvoiceV2 not implemented")
 }
+func (UnimplementedInvoicesServer) HtlcAcceptor(Invoices_HtlcAcceptorServer) error {
+	return status.Errorf(codes.Unimplemented, "method HtlcAcceptor not implemented")
+}
 func (UnimplementedInvoicesServer) mustEmbedUnimplementedInvoicesServer() {}
 
 // UnsafeInvoicesServer may be embedded to opt out of forward compatibility for this service.
@@ -269,6 +321,32 @@ func _Invoices_LookupInvoiceV2_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Invoices_HtlcAcceptor_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InvoicesServer).HtlcAcceptor(&invoicesHtlcAcceptorServer{stream})
+}
+
+type Invoices_HtlcAcceptorServer interface {
+	Send(*HtlcAcceptorRequest) error
+	Recv() (*HtlcAcceptorResponse, error)
+	grpc.ServerStream
+}
+
+type invoicesHtlcAcceptorServer struct {
+	grpc.ServerStream
+}
+
+func (x *invoicesHtlcAcceptorServer) Send(m *HtlcAcceptorRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *invoicesHtlcAcceptorServer) Recv() (*HtlcAcceptorResponse, error) {
+	m := new(HtlcAcceptorResponse)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Invoices_ServiceDesc is the grpc.ServiceDesc for Invoices service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -299,6 +377,12 @@ var Invoices_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Invoices_SubscribeSingleInvoice_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "HtlcAcceptor",
+			Handler:       _Invoices_HtlcAcceptor_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "invoicesrpc/invoices.proto",
 }
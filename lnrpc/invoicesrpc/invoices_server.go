@@ -65,6 +65,10 @@ var (
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/invoicesrpc.Invoices/HtlcAcceptor": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
 	}
 
 	// DefaultInvoicesMacFilename is the default name of the invoices
@@ -446,3 +450,10 @@ func (s *Server) LookupInvoiceV2(ctx context.Context,
 
 	return CreateRPCInvoice(&invoice, s.cfg.ChainParams)
 }
+
+// HtlcAcceptor is a bidirectional stream that offers the htlcs that pay to our
+// invoices to the caller, which decides whether they are accepted, rejected or
+// held. Only one htlc acceptor can be active at a time.
+func (s *Server) HtlcAcceptor(stream Invoices_HtlcAcceptorServer) error {
+	return newHtlcAcceptor(s.cfg.InvoiceRegistry, stream).run()
+}
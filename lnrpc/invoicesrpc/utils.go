@@ -3,9 +3,11 @@ package invoicesrpc
 import (
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/bolt12"
 	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -31,6 +33,21 @@ func decodePayReq(invoice *invoices.Invoice,
 		}, nil
 	}
 
+	// BOLT 12 invoices only carry the payment hash that is needed here,
+	// the remaining fields don't apply to them.
+	if strings.HasPrefix(paymentRequest, bolt12.InvoiceHRP) {
+		decoded, err := bolt12.DecodeInvoiceString(paymentRequest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode payment "+
+				"request: %v", err)
+		}
+
+		hash := [32]byte(decoded.PaymentHash)
+		return &zpay32.Invoice{
+			PaymentHash: &hash,
+		}, nil
+	}
+
 	var err error
 	decoded, err := zpay32.Decode(paymentRequest, activeNetParams)
 	if err != nil {
@@ -0,0 +1,199 @@
+package routerrpc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/routing"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// externalPathfinderTimeout is the time that a payment waits for the external
+// pathfinder to respond to a route request, before it falls back to our own
+// path finding.
+const externalPathfinderTimeout = time.Minute
+
+var (
+	// errPathfinderTimeout is returned when the external pathfinder doesn't
+	// respond to a route request in time.
+	errPathfinderTimeout = errors.New("external pathfinder didn't " +
+		"respond in time")
+
+	// errPathfinderExited is returned when the stream of the external
+	// pathfinder closes while a route request is pending.
+	errPathfinderExited = errors.New("external pathfinder exited")
+)
+
+// externalPathfinder is a helper struct that handles the lifecycle of an RPC
+// external pathfinder streaming session. It is created when the stream opens
+// and unregisters itself when the stream closes.
+type externalPathfinder struct {
+	// stream is the bidirectional RPC stream.
+	stream Router_ExternalPathfinderServer
+
+	backend *RouterBackend
+
+	// nextID is the id of the next route request.
+	nextID uint64
+
+	// pending holds the channels that the responses to the route requests
+	// in flight are delivered on, by request id.
+	pending map[uint64]chan *ExternalRouteResponse
+
+	// mu guards nextID, pending and sending on the stream.
+	mu sync.Mutex
+
+	quit chan struct{}
+}
+
+// A compile time check to ensure externalPathfinder implements the
+// routing.ExternalPathFinder interface.
+var _ routing.ExternalPathFinder = (*externalPathfinder)(nil)
+
+// newExternalPathfinder creates a new externalPathfinder.
+func newExternalPathfinder(backend *RouterBackend,
+	stream Router_ExternalPathfinderServer) *externalPathfinder {
+
+	return &externalPathfinder{
+		stream:  stream,
+		backend: backend,
+		pending: make(map[uint64]chan *ExternalRouteResponse),
+		quit:    make(chan struct{}),
+	}
+}
+
+// run registers the external pathfinder with the router, so that it receives
+// the route requests of payments, and delivers the responses of the client to
+// the pending requests until the stream closes.
+func (e *externalPathfinder) run() error {
+	e.backend.ExternalPathFinding.SetPathFinder(e)
+	defer e.backend.ExternalPathFinding.SetPathFinder(nil)
+	defer close(e.quit)
+
+	for {
+		resp, err := e.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		e.resolveFromClient(resp)
+	}
+}
+
+// resolveFromClient delivers a response of the client to the route request it
+// answers. Responses to requests that are no longer pending are dropped.
+func (e *externalPathfinder) resolveFromClient(resp *ExternalRouteResponse) {
+	e.mu.Lock()
+	respChan, ok := e.pending[resp.RequestId]
+	delete(e.pending, resp.RequestId)
+	e.mu.Unlock()
+
+	if !ok {
+		log.Debugf("Dropping external pathfinder response to unknown "+
+			"request %v", resp.RequestId)
+
+		return
+	}
+
+	respChan <- resp
+}
+
+// FindRoutes sends the route request to the client and returns the routes of
+// its response.
+//
+// NOTE: This is part of the routing.ExternalPathFinder interface.
+func (e *externalPathfinder) FindRoutes(
+	req *routing.ExternalRouteRequest) ([]*route.Route, error) {
+
+	respChan := make(chan *ExternalRouteResponse, 1)
+
+	e.mu.Lock()
+	e.nextID++
+	id := e.nextID
+	e.pending[id] = respChan
+	err := e.stream.Send(marshallExternalRouteRequest(id, req))
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, id)
+		e.mu.Unlock()
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *ExternalRouteResponse
+	select {
+	case resp = <-respChan:
+
+	case <-time.After(externalPathfinderTimeout):
+		return nil, errPathfinderTimeout
+
+	case <-e.quit:
+		return nil, errPathfinderExited
+	}
+
+	// Routes that can't be parsed are skipped, so that the payment can
+	// still try the others.
+	routes := make([]*route.Route, 0, len(resp.Routes))
+	for _, rpcRoute := range resp.Routes {
+		rt, err := e.backend.UnmarshallRoute(rpcRoute)
+		if err != nil {
+			log.Warnf("Skipping invalid route of external "+
+				"pathfinder: %v", err)
+
+			continue
+		}
+
+		routes = append(routes, rt)
+	}
+
+	return routes, nil
+}
+
+// marshallExternalRouteRequest converts a route request of a payment session
+// into its RPC representation.
+func marshallExternalRouteRequest(id uint64,
+	req *routing.ExternalRouteRequest) *ExternalRouteRequest {
+
+	rpcReq := &ExternalRouteRequest{
+		RequestId:       id,
+		PaymentHash:     req.PaymentIdentifier[:],
+		Dest:            req.Target[:],
+		AmtMsat:         uint64(req.Amount),
+		TotalAmtMsat:    uint64(req.TotalAmount),
+		FeeLimitMsat:    uint64(req.FeeLimit),
+		ActiveShards:    req.ActiveShards,
+		MaxParts:        req.MaxParts,
+		CurrentHeight:   req.Height,
+		CltvLimit:       req.CltvLimit,
+		FinalCltvDelta:  uint32(req.FinalCLTVDelta),
+		OutgoingChanIds: req.OutgoingChannelIDs,
+		RouteHints:      invoicesrpc.CreateRPCRouteHints(req.RouteHints),
+	}
+
+	if req.MaxShardAmt != nil {
+		rpcReq.MaxShardSizeMsat = uint64(*req.MaxShardAmt)
+	}
+
+	if req.LastHop != nil {
+		rpcReq.LastHopPubkey = req.LastHop[:]
+	}
+
+	for i := range req.ExcludedPairs {
+		pair := &req.ExcludedPairs[i]
+		rpcReq.ExcludedPairs = append(
+			rpcReq.ExcludedPairs, &lnrpc.NodePair{
+				From: pair.From[:],
+				To:   pair.To[:],
+			},
+		)
+	}
+
+	return rpcReq
+}
@@ -2,8 +2,10 @@ package routerrpc
 
 import (
 	"errors"
+	"time"
 
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
@@ -30,6 +32,10 @@ type forwardInterceptor struct {
 	stream Router_HtlcInterceptorServer
 
 	htlcSwitch htlcswitch.InterceptableHtlcForwarder
+
+	// id is the id of the interceptor in the interceptor chain of the
+	// switch.
+	id htlcswitch.InterceptorID
 }
 
 // newForwardInterceptor creates a new forwardInterceptor.
@@ -49,9 +55,19 @@ func newForwardInterceptor(htlcSwitch htlcswitch.InterceptableHtlcForwarder,
 // To coordinate all this and make sure it is safe for concurrent access all
 // packets are sent to the main where they are handled.
 func (r *forwardInterceptor) run() error {
-	// Register our interceptor so we receive all forwarded packets.
-	r.htlcSwitch.SetInterceptor(r.onIntercept)
-	defer r.htlcSwitch.SetInterceptor(nil)
+	// Register our interceptor so we receive all forwarded packets until
+	// the client configures a filter.
+	id, err := r.htlcSwitch.AddInterceptor(
+		htlcswitch.InterceptorRegistration{
+			Interceptor:   r.onIntercept,
+			TimeoutAction: htlcswitch.FwdActionResume,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	r.id = id
+	defer r.htlcSwitch.RemoveInterceptor(id)
 
 	for {
 		resp, err := r.stream.Recv()
@@ -91,6 +107,7 @@ func (r *forwardInterceptor) onIntercept(
 		CustomRecords:           htlc.CustomRecords,
 		OnionBlob:               htlc.OnionBlob[:],
 		AutoFailHeight:          htlc.AutoFailHeight,
+		InWireCustomRecords:     htlc.InWireCustomRecords,
 	}
 
 	return r.stream.Send(interceptionRequest)
@@ -100,6 +117,10 @@ func (r *forwardInterceptor) onIntercept(
 func (r *forwardInterceptor) resolveFromClient(
 	in *ForwardHtlcInterceptResponse) error {
 
+	if in.InterceptorConfig != nil {
+		return r.configure(in.InterceptorConfig)
+	}
+
 	if in.IncomingCircuitKey == nil {
 		return status.Errorf(codes.InvalidArgument,
 			"CircuitKey missing from ForwardHtlcInterceptResponse")
@@ -114,11 +135,46 @@ func (r *forwardInterceptor) resolveFromClient(
 
 	switch in.Action {
 	case ResolveHoldForwardAction_RESUME:
-		return r.htlcSwitch.Resolve(&htlcswitch.FwdResolution{
+		return r.resolve(&htlcswitch.FwdResolution{
 			Key:    circuitKey,
 			Action: htlcswitch.FwdActionResume,
 		})
 
+	case ResolveHoldForwardAction_RESUME_MODIFIED:
+		res := &htlcswitch.FwdResolution{
+			Key:    circuitKey,
+			Action: htlcswitch.FwdActionResumeModified,
+		}
+
+		if in.OutChanId != 0 {
+			res.OutgoingChanID = fn.Some(
+				lnwire.NewShortChanIDFromInt(in.OutChanId),
+			)
+		}
+
+		if in.OutAmountMsat != 0 {
+			res.OutAmountMsat = fn.Some(
+				lnwire.MilliSatoshi(in.OutAmountMsat),
+			)
+		}
+
+		if len(in.OutWireCustomRecords) > 0 {
+			customRecords := lnwire.CustomRecords(
+				in.OutWireCustomRecords,
+			)
+			if err := customRecords.Validate(); err != nil {
+				return status.Errorf(
+					codes.InvalidArgument,
+					"invalid out wire custom records: %v",
+					err,
+				)
+			}
+
+			res.OutWireCustomRecords = fn.Some(customRecords)
+		}
+
+		return r.resolve(res)
+
 	case ResolveHoldForwardAction_FAIL:
 		// Fail with an encrypted reason.
 		if in.FailureMessage != nil {
@@ -142,7 +198,7 @@ func (r *forwardInterceptor) resolveFromClient(
 				)
 			}
 
-			return r.htlcSwitch.Resolve(&htlcswitch.FwdResolution{
+			return r.resolve(&htlcswitch.FwdResolution{
 				Key:            circuitKey,
 				Action:         htlcswitch.FwdActionFail,
 				FailureMessage: in.FailureMessage,
@@ -171,7 +227,7 @@ func (r *forwardInterceptor) resolveFromClient(
 			)
 		}
 
-		return r.htlcSwitch.Resolve(&htlcswitch.FwdResolution{
+		return r.resolve(&htlcswitch.FwdResolution{
 			Key:         circuitKey,
 			Action:      htlcswitch.FwdActionFail,
 			FailureCode: code,
@@ -186,7 +242,7 @@ func (r *forwardInterceptor) resolveFromClient(
 			return err
 		}
 
-		return r.htlcSwitch.Resolve(&htlcswitch.FwdResolution{
+		return r.resolve(&htlcswitch.FwdResolution{
 			Key:      circuitKey,
 			Action:   htlcswitch.FwdActionSettle,
 			Preimage: preimage,
@@ -199,3 +255,55 @@ func (r *forwardInterceptor) resolveFromClient(
 		)
 	}
 }
+
+// resolve passes a resolution of the client on to the switch.
+func (r *forwardInterceptor) resolve(res *htlcswitch.FwdResolution) error {
+	res.InterceptorID = r.id
+
+	return r.htlcSwitch.Resolve(res)
+}
+
+// configure updates the registration of the interceptor in the interceptor
+// chain.
+func (r *forwardInterceptor) configure(cfg *InterceptorConfig) error {
+	reg := htlcswitch.InterceptorRegistration{
+		Interceptor: r.onIntercept,
+		Priority:    cfg.Priority,
+		Filter: htlcswitch.InterceptFilter{
+			MinAmount:         lnwire.MilliSatoshi(cfg.MinAmountMsat),
+			MaxAmount:         lnwire.MilliSatoshi(cfg.MaxAmountMsat),
+			CustomRecordTypes: cfg.CustomRecordTypes,
+		},
+		Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+
+	for _, chanID := range cfg.IncomingChanIds {
+		reg.Filter.IncomingChanIDs = append(
+			reg.Filter.IncomingChanIDs,
+			lnwire.NewShortChanIDFromInt(chanID),
+		)
+	}
+
+	switch cfg.TimeoutAction {
+	case InterceptorTimeoutAction_TIMEOUT_RESUME:
+		reg.TimeoutAction = htlcswitch.FwdActionResume
+
+	case InterceptorTimeoutAction_TIMEOUT_FAIL:
+		reg.TimeoutAction = htlcswitch.FwdActionFail
+
+	default:
+		return status.Errorf(
+			codes.InvalidArgument,
+			"unrecognized timeout action %v", cfg.TimeoutAction,
+		)
+	}
+
+	if err := r.htlcSwitch.UpdateInterceptor(r.id, reg); err != nil {
+		return status.Errorf(
+			codes.InvalidArgument, "invalid interceptor config: %v",
+			err,
+		)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	math "math"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
@@ -14,10 +15,13 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/wire"
 	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/bolt12"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/feature"
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/record"
@@ -25,6 +29,8 @@ import (
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/subscribe"
 	"github.com/lightningnetwork/lnd/zpay32"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -62,6 +68,16 @@ type RouterBackend struct {
 
 	MissionControl MissionControl
 
+	// GetMissionControl returns the mission control of the given
+	// namespace, creating the namespace if it doesn't exist yet. It is
+	// only used by calls that write to mission control.
+	GetMissionControl func(namespace string) (MissionControl, error)
+
+	// FetchMissionControl returns the mission control of an existing
+	// namespace. It is used by calls that only read from mission control,
+	// so that they don't create namespaces.
+	FetchMissionControl func(namespace string) (MissionControl, error)
+
 	// ActiveNetParams are the network parameters of the primary network
 	// that the route is operating on. This is necessary so we can ensure
 	// that we receive payment requests that send to destinations on our
@@ -88,6 +104,23 @@ type RouterBackend struct {
 	// by letting the router register a ForwardInterceptor.
 	InterceptableForwarder htlcswitch.InterceptableHtlcForwarder
 
+	// ExternalPathFinding exposes the ability to take over the path
+	// finding of payments by registering an external path finder.
+	ExternalPathFinding *routing.ExternalPathFinding
+
+	// ProbeService sends background probes to feed mission control. It is
+	// nil if background probing isn't available.
+	ProbeService *routing.ProbeService
+
+	// HtlcRateLimiter limits the rate at which peers add htlcs to our
+	// channels. It is nil if htlc rate limiting isn't active.
+	HtlcRateLimiter *htlcswitch.HtlcRateLimiter
+
+	// AddInvoice adds an invoice to the invoice registry. It is used to
+	// receive the circular payments of channel rebalances.
+	AddInvoice func(ctx context.Context, invoice *invoices.Invoice,
+		paymentHash lntypes.Hash) (uint64, error)
+
 	// SetChannelEnabled exposes the ability to manually enable a channel.
 	SetChannelEnabled func(wire.OutPoint) error
 
@@ -108,10 +141,9 @@ type RouterBackend struct {
 
 // MissionControl defines the mission control dependencies of routerrpc.
 type MissionControl interface {
-	// GetProbability is expected to return the success probability of a
-	// payment from fromNode to toNode.
-	GetProbability(fromNode, toNode route.Vertex,
-		amt lnwire.MilliSatoshi, capacity btcutil.Amount) float64
+	// MissionController allows payments to report their results to and
+	// base their path finding on this mission control.
+	routing.MissionController
 
 	// ResetHistory resets the history of MissionControl returning it to a
 	// state as if no payment attempts have been made.
@@ -139,6 +171,51 @@ type MissionControl interface {
 	SetConfig(cfg *routing.MissionControlConfig) error
 }
 
+// errNamespacesUnsupported is returned if a mission control namespace other
+// than the default one is requested, but namespaces aren't supported.
+var errNamespacesUnsupported = errors.New("mission control namespaces are " +
+	"not supported")
+
+// missionControl returns the mission control of an existing namespace. The
+// default mission control is returned if the namespace is empty. It must be
+// used by calls that only read from mission control.
+func (r *RouterBackend) missionControl(namespace string) (MissionControl,
+	error) {
+
+	if namespace == "" || namespace == routing.DefaultMissionControlNamespace {
+		return r.MissionControl, nil
+	}
+
+	if r.FetchMissionControl == nil {
+		return nil, errNamespacesUnsupported
+	}
+
+	mc, err := r.FetchMissionControl(namespace)
+	if errors.Is(err, routing.ErrUnknownMissionControlNamespace) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return mc, err
+}
+
+// createMissionControl returns the mission control of the given namespace,
+// creating the namespace if it doesn't exist yet. The default mission control
+// is returned if the namespace is empty. It must only be used by calls that
+// write to mission control.
+func (r *RouterBackend) createMissionControl(namespace string) (
+	MissionControl, error) {
+
+	if namespace == "" || namespace == routing.DefaultMissionControlNamespace {
+		return r.MissionControl, nil
+	}
+
+	if r.GetMissionControl == nil {
+		return nil, errNamespacesUnsupported
+	}
+
+	return r.GetMissionControl(namespace)
+}
+
 // QueryRoutes attempts to query the daemons' Channel Router for a possible
 // route to a target destination capable of carrying a specific amount of
 // satoshis within the route's flow. The returned route contains the full
@@ -839,6 +916,21 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		payIntent.MaxShardAmt = &shardAmtMsat
 	}
 
+	// Use the min-cost flow splitter for multi-path payments if requested.
+	payIntent.MinCostFlow = rpcPayReq.MinCostFlow
+
+	// Payments in the default namespace use the mission control of the
+	// router, so we only need to override it for other namespaces.
+	if rpcPayReq.MissionControlNamespace != "" {
+		mc, err := r.createMissionControl(
+			rpcPayReq.MissionControlNamespace,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payIntent.MissionControl = mc
+	}
+
 	// Take fee limit from request.
 	payIntent.FeeLimit, err = lnrpc.UnmarshallAmt(
 		rpcPayReq.FeeLimitSat, rpcPayReq.FeeLimitMsat,
@@ -878,10 +970,19 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		return nil, err
 	}
 
+	switch {
+	// BOLT 12 invoices can only be paid over the blinded paths that they
+	// contain, so they're handled separately from BOLT 11 invoices.
+	case isBolt12Invoice(rpcPayReq.PaymentRequest):
+		err := r.setBolt12PaymentIntent(payIntent, rpcPayReq, reqAmt)
+		if err != nil {
+			return nil, err
+		}
+
 	// If the payment request field isn't blank, then the details of the
 	// invoice are encoded entirely within the encoded payReq.  So we'll
 	// attempt to decode it, populating the payment accordingly.
-	if rpcPayReq.PaymentRequest != "" {
+	case rpcPayReq.PaymentRequest != "":
 		switch {
 
 		case len(rpcPayReq.Dest) > 0:
@@ -1021,7 +1122,8 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 				payIntent.DestFeatures = pathFeatures.Clone()
 			}
 		}
-	} else {
+
+	default:
 		// Otherwise, If the payment request field was not specified
 		// (and a custom route wasn't specified), construct the payment
 		// from the other fields.
@@ -1151,6 +1253,34 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		return nil, err
 	}
 
+	// If a trampoline node is set, the payment is only routed to the
+	// trampoline node, which finds the route to the destination for us.
+	if len(rpcPayReq.TrampolineNode) > 0 {
+		if rpcPayReq.Amp {
+			return nil, errors.New("trampoline payments can't be " +
+				"AMP payments")
+		}
+
+		trampolineNode, err := route.NewVertexFromBytes(
+			rpcPayReq.TrampolineNode,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payIntent.TrampolineNode = &trampolineNode
+
+		if rpcPayReq.TrampolineFeeMsat < 0 {
+			return nil, errors.New("trampoline fee must not be " +
+				"negative")
+		}
+		payIntent.TrampolineFee = lnwire.MilliSatoshi(
+			rpcPayReq.TrampolineFeeMsat,
+		)
+	} else if rpcPayReq.TrampolineFeeMsat != 0 {
+		return nil, errors.New("trampoline fee set without " +
+			"trampoline node")
+	}
+
 	// Check for disallowed payments to self.
 	if !rpcPayReq.AllowSelfPayment && payIntent.Target == r.SelfNode {
 		return nil, errors.New("self-payments not allowed")
@@ -1159,6 +1289,228 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 	return payIntent, nil
 }
 
+// rebalanceFeatures are the features of the invoices that rebalances pay to,
+// which allow the rebalance to be split across several channels.
+var rebalanceFeatures = lnwire.NewFeatureVector(
+	lnwire.NewRawFeatureVector(
+		lnwire.TLVOnionPayloadRequired, lnwire.PaymentAddrRequired,
+		lnwire.MPPOptional,
+	), lnwire.Features,
+)
+
+// extractIntentFromRebalanceRequest adds the invoice that a channel rebalance
+// pays to and returns the circular payment to ourselves that moves the
+// liquidity of the rebalance.
+func (r *RouterBackend) extractIntentFromRebalanceRequest(ctx context.Context,
+	req *RebalanceChannelRequest) (*routing.LightningPayment, error) {
+
+	if len(req.OutgoingChanIds) == 0 {
+		return nil, errors.New("outgoing_chan_ids must be specified")
+	}
+
+	if req.TimeoutSeconds == 0 {
+		return nil, errors.New("timeout_seconds must be specified")
+	}
+
+	amt, err := lnrpc.UnmarshallAmt(req.Amt, req.AmtMsat)
+	if err != nil {
+		return nil, err
+	}
+	if amt == 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	// The last hop of the circular routes is the peer of the incoming
+	// channel. If we have several channels with that peer, the liquidity
+	// may arrive through any of them.
+	lastHop, err := r.fetchChannelPeer(req.IncomingChanId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chanID := range req.OutgoingChanIds {
+		if chanID == req.IncomingChanId {
+			return nil, fmt.Errorf("channel %v can't be both an "+
+				"outgoing and the incoming channel", chanID)
+		}
+
+		peer, err := r.fetchChannelPeer(chanID)
+		if err != nil {
+			return nil, err
+		}
+
+		// A route out to the peer of the incoming channel could
+		// return through the outgoing channel right away, which
+		// wouldn't move any liquidity.
+		if peer == lastHop {
+			return nil, fmt.Errorf("outgoing channel %v is with "+
+				"the peer of the incoming channel", chanID)
+		}
+	}
+
+	maxParts := req.MaxParts
+	if maxParts == 0 {
+		maxParts = DefaultMaxParts
+	}
+
+	var (
+		preimage    lntypes.Preimage
+		paymentAddr [32]byte
+	)
+	if _, err := rand.Read(preimage[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(paymentAddr[:]); err != nil {
+		return nil, err
+	}
+	paymentHash := preimage.Hash()
+
+	// Add the invoice that receives the rebalance on the incoming
+	// channel.
+	invoice := &invoices.Invoice{
+		CreationDate: time.Now(),
+		Memo: []byte(fmt.Sprintf("rebalance into channel %v",
+			req.IncomingChanId)),
+		Terms: invoices.ContractTerm{
+			FinalCltvDelta:  int32(r.DefaultFinalCltvDelta),
+			Expiry:          invoicesrpc.DefaultInvoiceExpiry,
+			Value:           amt,
+			PaymentPreimage: &preimage,
+			PaymentAddr:     paymentAddr,
+			Features:        rebalanceFeatures.Clone(),
+		},
+	}
+	if _, err := r.AddInvoice(ctx, invoice, paymentHash); err != nil {
+		return nil, fmt.Errorf("unable to add rebalance invoice: %w",
+			err)
+	}
+
+	payIntent := &routing.LightningPayment{
+		Target:             r.SelfNode,
+		Amount:             amt,
+		FeeLimit:           amt * lnwire.MilliSatoshi(req.MaxFeePpm) / 1e6,
+		CltvLimit:          r.MaxTotalTimelock,
+		FinalCLTVDelta:     r.DefaultFinalCltvDelta,
+		PaymentAddr:        &paymentAddr,
+		DestFeatures:       rebalanceFeatures.Clone(),
+		OutgoingChannelIDs: req.OutgoingChanIds,
+		LastHop:            &lastHop,
+		MaxParts:           maxParts,
+		PayAttemptTimeout: time.Second *
+			time.Duration(req.TimeoutSeconds),
+		Rebalance: true,
+	}
+	if err := payIntent.SetPaymentHash(paymentHash); err != nil {
+		return nil, err
+	}
+
+	return payIntent, nil
+}
+
+// fetchChannelPeer returns the peer of our channel with the given id, or an
+// error if the channel isn't one of ours.
+func (r *RouterBackend) fetchChannelPeer(chanID uint64) (route.Vertex,
+	error) {
+
+	node1, node2, err := r.FetchChannelEndpoints(chanID)
+	if err != nil {
+		return route.Vertex{}, err
+	}
+
+	switch r.SelfNode {
+	case node1:
+		return node2, nil
+
+	case node2:
+		return node1, nil
+
+	default:
+		return route.Vertex{}, fmt.Errorf("channel %v is not one of "+
+			"our channels", chanID)
+	}
+}
+
+// isBolt12Invoice returns true if the payment request is a BOLT 12 invoice.
+func isBolt12Invoice(payReq string) bool {
+	return strings.HasPrefix(strings.ToLower(payReq), bolt12.InvoiceHRP)
+}
+
+// setBolt12PaymentIntent populates the payment intent from a BOLT 12 invoice.
+// The invoice has been validated by the payer when it was fetched, but it is
+// verified again here as it's passed in by the caller.
+func (r *RouterBackend) setBolt12PaymentIntent(
+	payIntent *routing.LightningPayment, rpcPayReq *SendPaymentRequest,
+	reqAmt lnwire.MilliSatoshi) error {
+
+	switch {
+	case len(rpcPayReq.Dest) > 0:
+		return errors.New("dest and payment_request cannot appear " +
+			"together")
+
+	case len(rpcPayReq.PaymentHash) > 0:
+		return errors.New("payment_hash and payment_request cannot " +
+			"appear together")
+
+	case rpcPayReq.FinalCltvDelta != 0:
+		return errors.New("final_cltv_delta and payment_request " +
+			"cannot appear together")
+
+	case rpcPayReq.Amp:
+		return errors.New("BOLT 12 invoices can't be paid with AMP")
+
+	case reqAmt != 0:
+		return errors.New("amount must not be specified when paying " +
+			"a BOLT 12 invoice")
+	}
+
+	invoice, err := bolt12.DecodeInvoiceString(rpcPayReq.PaymentRequest)
+	if err != nil {
+		return err
+	}
+
+	if err := invoice.Verify(); err != nil {
+		return err
+	}
+
+	err = invoice.InvoiceRequest.Validate(r.ActiveNetParams)
+	if err != nil {
+		return err
+	}
+
+	validUntil := invoice.Expiry()
+	if time.Now().After(validUntil) {
+		return fmt.Errorf("invoice expired. Valid until %v", validUntil)
+	}
+
+	payIntent.Amount = invoice.Amount
+	if err := payIntent.SetPaymentHash(invoice.PaymentHash); err != nil {
+		return err
+	}
+
+	features := lnwire.NewFeatureVector(invoice.Features, lnwire.Features)
+	if !features.HasFeature(lnwire.MPPOptional) {
+		payIntent.MaxParts = 1
+	}
+
+	pathSet, err := BuildBlindedPathSet(invoice.Paths)
+	if err != nil {
+		return err
+	}
+	payIntent.BlindedPathSet = pathSet
+
+	copy(payIntent.Target[:], pathSet.TargetPubKey().SerializeCompressed())
+
+	payIntent.DestFeatures = features
+	if pathFeatures := pathSet.Features(); !pathFeatures.IsEmpty() {
+		payIntent.DestFeatures = pathFeatures.Clone()
+	}
+
+	payIntent.FinalCLTVDelta = zpay32.DefaultAssumedFinalCLTVDelta
+	payIntent.PaymentRequest = []byte(rpcPayReq.PaymentRequest)
+
+	return nil
+}
+
 // BuildBlindedPathSet marshals a set of zpay32.BlindedPaymentPath and uses
 // the result to build a new routing.BlindedPaymentPathSet.
 func BuildBlindedPathSet(paths []*zpay32.BlindedPaymentPath) (
@@ -1593,6 +1945,15 @@ func marshallWireError(msg lnwire.FailureMessage,
 		response.Code = lnrpc.Failure_INVALID_ONION_BLINDING
 		response.OnionSha_256 = onionErr.OnionSHA256[:]
 
+	case *lnwire.FailTemporaryTrampolineFailure:
+		response.Code = lnrpc.Failure_TEMPORARY_TRAMPOLINE_FAILURE
+
+	case *lnwire.FailTrampolineFeeInsufficient:
+		response.Code = lnrpc.Failure_TRAMPOLINE_FEE_INSUFFICIENT
+
+	case *lnwire.FailTrampolineExpiryTooSoon:
+		response.Code = lnrpc.Failure_TRAMPOLINE_EXPIRY_TOO_SOON
+
 	case nil:
 		response.Code = lnrpc.Failure_UNKNOWN_FAILURE
 
@@ -1691,6 +2052,7 @@ func (r *RouterBackend) MarshallPayment(payment *channeldb.MPPayment) (
 		Htlcs:           htlcs,
 		PaymentIndex:    payment.SequenceNum,
 		FailureReason:   failureReason,
+		Rebalance:       payment.Info.Rebalance,
 	}, nil
 }
 
@@ -1754,3 +2116,164 @@ func marshallPaymentFailureReason(reason *channeldb.FailureReason) (
 
 	return 0, errors.New("unknown failure reason")
 }
+
+// unmarshallProbeConfig unmarshalls a probing request into the config of a
+// probing run, applying the defaults for fields that are unset.
+func unmarshallProbeConfig(req *StartProbingRequest) (*routing.ProbeConfig,
+	error) {
+
+	if req.AmtMsat <= 0 {
+		return nil, errors.New("probe amount must be greater than zero")
+	}
+
+	if req.FeeLimitMsat < 0 {
+		return nil, errors.New("fee limit must not be negative")
+	}
+
+	cfg := &routing.ProbeConfig{
+		NumCentralityNodes:      int(req.NumCentralityNodes),
+		Amount:                  lnwire.MilliSatoshi(req.AmtMsat),
+		FeeLimit:                lnwire.MilliSatoshi(req.FeeLimitMsat),
+		Interval:                routing.DefaultProbeInterval,
+		ProbeTimeout:            routing.DefaultProbeTimeout,
+		MaxProbes:               req.MaxProbes,
+		MissionControlNamespace: req.MissionControlNamespace,
+	}
+
+	if cfg.FeeLimit == 0 {
+		cfg.FeeLimit = cfg.Amount
+	}
+
+	if req.IntervalSeconds != 0 {
+		cfg.Interval = time.Duration(req.IntervalSeconds) * time.Second
+	}
+
+	if req.TimeoutSeconds != 0 {
+		cfg.ProbeTimeout = time.Duration(req.TimeoutSeconds) *
+			time.Second
+	}
+
+	for _, dest := range req.Destinations {
+		vertex, err := route.NewVertexFromBytes(dest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination: %w", err)
+		}
+
+		cfg.Destinations = append(cfg.Destinations, vertex)
+	}
+
+	return cfg, nil
+}
+
+// marshallProbeResult marshalls the result of a probe into its rpc type.
+func (r *RouterBackend) marshallProbeResult(
+	result *routing.ProbeResult) (*ProbeResult, error) {
+
+	failureReason, err := marshallPaymentFailureReason(
+		result.FailureReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcResult := &ProbeResult{
+		Destination:        result.Destination[:],
+		AmtMsat:            int64(result.Amount),
+		PaymentHash:        result.PaymentHash[:],
+		ReachedDestination: result.Reached,
+		FailureReason:      failureReason,
+		TimestampNs:        result.Timestamp.UnixNano(),
+		DurationNs:         result.Duration.Nanoseconds(),
+	}
+
+	if result.Route != nil {
+		rpcResult.Route, err = r.MarshallRoute(result.Route)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rpcResult, nil
+}
+
+// marshallChannelSkipReason marshalls the reason why a channel was skipped
+// during a payment simulation to the corresponding rpc type.
+func marshallChannelSkipReason(
+	reason routing.ChannelSkipReason) ChannelSkipReason {
+
+	switch reason {
+	case routing.ChannelSkipLinkNotFound:
+		return ChannelSkipReason_SKIP_REASON_LINK_NOT_FOUND
+
+	case routing.ChannelSkipNotEligible:
+		return ChannelSkipReason_SKIP_REASON_NOT_ELIGIBLE
+
+	case routing.ChannelSkipMaxHtlcs:
+		return ChannelSkipReason_SKIP_REASON_MAX_HTLCS
+
+	case routing.ChannelSkipMaxInFlight:
+		return ChannelSkipReason_SKIP_REASON_MAX_IN_FLIGHT
+
+	case routing.ChannelSkipChannelReserve:
+		return ChannelSkipReason_SKIP_REASON_CHANNEL_RESERVE
+
+	case routing.ChannelSkipBelowMinHtlc:
+		return ChannelSkipReason_SKIP_REASON_BELOW_MIN_HTLC
+
+	case routing.ChannelSkipDustExposure:
+		return ChannelSkipReason_SKIP_REASON_DUST_EXPOSURE
+
+	case routing.ChannelSkipInsufficientBandwidth:
+		return ChannelSkipReason_SKIP_REASON_INSUFFICIENT_BANDWIDTH
+
+	case routing.ChannelSkipDustExposureCheckFailed:
+		return ChannelSkipReason_SKIP_REASON_DUST_EXPOSURE_CHECK_FAILED
+
+	default:
+		return ChannelSkipReason_SKIP_REASON_UNKNOWN
+	}
+}
+
+// marshallPaymentSimulation marshalls the outcome of a payment simulation into
+// its rpc type.
+func (r *RouterBackend) marshallPaymentSimulation(
+	sim *routing.PaymentSimulation) (*SimulatePaymentResponse, error) {
+
+	failureReason, err := marshallPaymentFailureReason(sim.FailureReason)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &SimulatePaymentResponse{
+		TotalFeeMsat:       int64(sim.TotalFees),
+		SuccessProbability: sim.Probability,
+		FailureReason:      failureReason,
+	}
+
+	for _, shard := range sim.Shards {
+		rpcRoute, err := r.MarshallRoute(shard.Route)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Shards = append(resp.Shards, &SimulatedShard{
+			Route:              rpcRoute,
+			SuccessProbability: shard.Probability,
+		})
+	}
+
+	for _, skipped := range sim.SkippedChannels {
+		resp.SkippedChannels = append(
+			resp.SkippedChannels, &SkippedChannel{
+				ChanId:  skipped.ChannelID,
+				AmtMsat: int64(skipped.Amount),
+				Reason: marshallChannelSkipReason(
+					skipped.Reason,
+				),
+				Error: skipped.Err.Error(),
+			},
+		)
+	}
+
+	return resp, nil
+}
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -475,3 +481,324 @@ func testUnmarshalAMP(t *testing.T, test unmarshalAMPTest) {
 		t.Fatalf("test case has non-standard outcome")
 	}
 }
+
+// TestExtractIntentFromRebalanceRequest asserts that rebalance requests are
+// validated and turned into circular payments to an invoice of our own.
+func TestExtractIntentFromRebalanceRequest(t *testing.T) {
+	t.Parallel()
+
+	// Our channels 1 and 2 are with node1 and node2. Channel 3 is with
+	// node1 as well, and channel 4 isn't one of ours.
+	endpoints := map[uint64][2]route.Vertex{
+		1: {sourceKey, node1},
+		2: {node2, sourceKey},
+		3: {sourceKey, node1},
+		4: {node1, node2},
+	}
+
+	var (
+		invoice     *invoices.Invoice
+		invoiceHash lntypes.Hash
+	)
+	backend := &RouterBackend{
+		SelfNode: sourceKey,
+		FetchChannelEndpoints: func(chanID uint64) (route.Vertex,
+			route.Vertex, error) {
+
+			nodes, ok := endpoints[chanID]
+			if !ok {
+				return route.Vertex{}, route.Vertex{},
+					errors.New("unknown channel")
+			}
+
+			return nodes[0], nodes[1], nil
+		},
+		AddInvoice: func(_ context.Context, inv *invoices.Invoice,
+			hash lntypes.Hash) (uint64, error) {
+
+			invoice, invoiceHash = inv, hash
+
+			return 1, nil
+		},
+		MaxTotalTimelock:      1000,
+		DefaultFinalCltvDelta: 40,
+	}
+
+	testCases := []struct {
+		name string
+		req  *RebalanceChannelRequest
+		err  string
+	}{
+		{
+			name: "no outgoing channels",
+			req: &RebalanceChannelRequest{
+				IncomingChanId: 2,
+				Amt:            1000,
+				TimeoutSeconds: 60,
+			},
+			err: "outgoing_chan_ids must be specified",
+		},
+		{
+			name: "no timeout",
+			req: &RebalanceChannelRequest{
+				OutgoingChanIds: []uint64{1},
+				IncomingChanId:  2,
+				Amt:             1000,
+			},
+			err: "timeout_seconds must be specified",
+		},
+		{
+			name: "no amount",
+			req: &RebalanceChannelRequest{
+				OutgoingChanIds: []uint64{1},
+				IncomingChanId:  2,
+				TimeoutSeconds:  60,
+			},
+			err: "amount must be greater than zero",
+		},
+		{
+			name: "incoming channel not ours",
+			req: &RebalanceChannelRequest{
+				OutgoingChanIds: []uint64{1},
+				IncomingChanId:  4,
+				Amt:             1000,
+				TimeoutSeconds:  60,
+			},
+			err: "channel 4 is not one of our channels",
+		},
+		{
+			name: "incoming channel is outgoing",
+			req: &RebalanceChannelRequest{
+				OutgoingChanIds: []uint64{1, 2},
+				IncomingChanId:  2,
+				Amt:             1000,
+				TimeoutSeconds:  60,
+			},
+			err: "channel 2 can't be both an outgoing and the " +
+				"incoming channel",
+		},
+		{
+			name: "outgoing channel with incoming peer",
+			req: &RebalanceChannelRequest{
+				OutgoingChanIds: []uint64{3},
+				IncomingChanId:  1,
+				Amt:             1000,
+				TimeoutSeconds:  60,
+			},
+			err: "outgoing channel 3 is with the peer of the " +
+				"incoming channel",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := backend.extractIntentFromRebalanceRequest(
+			context.Background(), tc.req,
+		)
+		require.ErrorContains(t, err, tc.err, tc.name)
+	}
+	require.Nil(t, invoice)
+
+	payment, err := backend.extractIntentFromRebalanceRequest(
+		context.Background(), &RebalanceChannelRequest{
+			OutgoingChanIds: []uint64{1, 3},
+			IncomingChanId:  2,
+			Amt:             100_000,
+			MaxFeePpm:       500,
+			TimeoutSeconds:  60,
+		},
+	)
+	require.NoError(t, err)
+
+	// The payment is a rebalance to ourselves that arrives from the peer
+	// of the incoming channel.
+	require.True(t, payment.Rebalance)
+	require.Equal(t, sourceKey, payment.Target)
+	require.Equal(t, node2, *payment.LastHop)
+	require.Equal(t, []uint64{1, 3}, payment.OutgoingChannelIDs)
+	require.Equal(t, lnwire.MilliSatoshi(100_000_000), payment.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(50_000), payment.FeeLimit)
+	require.Equal(t, uint32(DefaultMaxParts), payment.MaxParts)
+	require.True(t, payment.DestFeatures.HasFeature(lnwire.MPPOptional))
+
+	// The invoice pays out the amount of the rebalance to the payment.
+	require.NotNil(t, invoice)
+	require.Equal(t, payment.Identifier(), [32]byte(invoiceHash))
+	require.Equal(t, invoiceHash, invoice.Terms.PaymentPreimage.Hash())
+	require.Equal(t, *payment.PaymentAddr, invoice.Terms.PaymentAddr)
+	require.Equal(t, payment.Amount, invoice.Terms.Value)
+}
+
+// TestMissionControlNamespace asserts that the mission control of a namespace
+// is only looked up for namespaces other than the default one, and that only
+// the calls that write to mission control create namespaces.
+func TestMissionControlNamespace(t *testing.T) {
+	t.Parallel()
+
+	defaultMc := &mockMissionControl{}
+	namespacedMc := &mockMissionControl{}
+
+	var namespaces []string
+	backend := &RouterBackend{
+		MissionControl: defaultMc,
+	}
+
+	// Without namespace support, only the default namespace can be used.
+	_, err := backend.missionControl("probes")
+	require.ErrorIs(t, err, errNamespacesUnsupported)
+	_, err = backend.createMissionControl("probes")
+	require.ErrorIs(t, err, errNamespacesUnsupported)
+
+	created := make(map[string]bool)
+	backend.GetMissionControl = func(namespace string) (MissionControl,
+		error) {
+
+		namespaces = append(namespaces, namespace)
+		created[namespace] = true
+
+		return namespacedMc, nil
+	}
+	backend.FetchMissionControl = func(namespace string) (MissionControl,
+		error) {
+
+		namespaces = append(namespaces, namespace)
+		if !created[namespace] {
+			return nil, routing.ErrUnknownMissionControlNamespace
+		}
+
+		return namespacedMc, nil
+	}
+
+	for _, namespace := range []string{
+		"", routing.DefaultMissionControlNamespace,
+	} {
+		mc, err := backend.missionControl(namespace)
+		require.NoError(t, err)
+		require.Same(t, defaultMc, mc)
+
+		mc, err = backend.createMissionControl(namespace)
+		require.NoError(t, err)
+		require.Same(t, defaultMc, mc)
+	}
+	require.Empty(t, namespaces)
+
+	// Reading from a namespace that doesn't exist fails and doesn't
+	// create it.
+	_, err = backend.missionControl("probes")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Empty(t, created)
+
+	mc, err := backend.createMissionControl("probes")
+	require.NoError(t, err)
+	require.Same(t, namespacedMc, mc)
+
+	mc, err = backend.missionControl("probes")
+	require.NoError(t, err)
+	require.Same(t, namespacedMc, mc)
+	require.Equal(t, []string{"probes", "probes", "probes"}, namespaces)
+}
+
+// TestUnmarshallProbeConfig asserts that probing requests are unmarshalled
+// with the defaults applied to unset fields.
+func TestUnmarshallProbeConfig(t *testing.T) {
+	t.Parallel()
+
+	dest := route.Vertex{2, 3}
+	cfg, err := unmarshallProbeConfig(&StartProbingRequest{
+		Destinations:            [][]byte{dest[:]},
+		AmtMsat:                 1000,
+		MaxProbes:               10,
+		MissionControlNamespace: "probes",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &routing.ProbeConfig{
+		Destinations:            []route.Vertex{dest},
+		Amount:                  1000,
+		FeeLimit:                1000,
+		Interval:                routing.DefaultProbeInterval,
+		ProbeTimeout:            routing.DefaultProbeTimeout,
+		MaxProbes:               10,
+		MissionControlNamespace: "probes",
+	}, cfg)
+
+	cfg, err = unmarshallProbeConfig(&StartProbingRequest{
+		NumCentralityNodes: 5,
+		AmtMsat:            1000,
+		FeeLimitMsat:       10,
+		IntervalSeconds:    30,
+		TimeoutSeconds:     20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, cfg.NumCentralityNodes)
+	require.Equal(t, lnwire.MilliSatoshi(10), cfg.FeeLimit)
+	require.Equal(t, 30*time.Second, cfg.Interval)
+	require.Equal(t, 20*time.Second, cfg.ProbeTimeout)
+
+	_, err = unmarshallProbeConfig(&StartProbingRequest{
+		NumCentralityNodes: 5,
+	})
+	require.Error(t, err)
+
+	_, err = unmarshallProbeConfig(&StartProbingRequest{
+		Destinations: [][]byte{{1, 2, 3}},
+		AmtMsat:      1000,
+	})
+	require.Error(t, err)
+}
+
+// TestMarshallPaymentSimulation asserts that the outcome of a payment
+// simulation is marshalled into its rpc type.
+func TestMarshallPaymentSimulation(t *testing.T) {
+	t.Parallel()
+
+	backend := &RouterBackend{
+		FetchChannelCapacity: func(uint64) (btcutil.Amount, error) {
+			return 100_000, nil
+		},
+	}
+
+	rt := &route.Route{
+		TotalAmount:  1010,
+		SourcePubKey: sourceKey,
+		Hops: []*route.Hop{
+			{
+				PubKeyBytes:  node1,
+				ChannelID:    1,
+				AmtToForward: 1000,
+			},
+		},
+	}
+
+	resp, err := backend.marshallPaymentSimulation(
+		&routing.PaymentSimulation{
+			Shards: []*routing.SimulatedShard{{
+				Route:       rt,
+				Probability: 0.5,
+			}},
+			TotalFees:   10,
+			Probability: 0.5,
+			SkippedChannels: []*routing.SkippedChannel{{
+				ChannelID: 2,
+				Amount:    1000,
+				Reason:    routing.ChannelSkipDustExposure,
+				Err:       errors.New("dust"),
+			}},
+		},
+	)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Shards, 1)
+	require.EqualValues(t, 1, resp.Shards[0].Route.Hops[0].ChanId)
+	require.Equal(t, 0.5, resp.Shards[0].SuccessProbability)
+	require.EqualValues(t, 10, resp.TotalFeeMsat)
+	require.Equal(t, 0.5, resp.SuccessProbability)
+	require.Equal(
+		t, lnrpc.PaymentFailureReason_FAILURE_REASON_NONE,
+		resp.FailureReason,
+	)
+	require.Equal(t, []*SkippedChannel{{
+		ChanId:  2,
+		AmtMsat: 1000,
+		Reason:  ChannelSkipReason_SKIP_REASON_DUST_EXPOSURE,
+		Error:   "dust",
+	}}, resp.SkippedChannels)
+}
@@ -116,6 +116,48 @@ type RouterClient interface {
 	// channel to stay disabled until a subsequent manual request of either
 	// "enable" or "auto".
 	UpdateChanStatus(ctx context.Context, in *UpdateChanStatusRequest, opts ...grpc.CallOption) (*UpdateChanStatusResponse, error)
+	// ExternalPathfinder dispatches a bi-directional streaming RPC in which the
+	// route requests of payments are sent to the client and the client responds
+	// with candidate routes. The routes are validated and tried by the payment
+	// lifecycle of lnd, which takes care of multi-path payments and retries.
+	// Only one external pathfinder can be registered at a time. While none is
+	// registered, lnd falls back to its own path finding.
+	ExternalPathfinder(ctx context.Context, opts ...grpc.CallOption) (Router_ExternalPathfinderClient, error)
+	// lncli: `rebalancechannel`
+	// RebalanceChannel moves liquidity out of one or more of our channels into
+	// another one of our channels by paying ourselves along circular routes. The
+	// amount may be split across the outgoing channels. The rebalance is recorded
+	// as a payment with the rebalance flag set. The call returns a stream of
+	// payment updates.
+	RebalanceChannel(ctx context.Context, in *RebalanceChannelRequest, opts ...grpc.CallOption) (Router_RebalanceChannelClient, error)
+	// lncli: `startprobing`
+	// StartProbing starts a background probing run that periodically sends
+	// probes with a random payment hash to a set of destinations or to the nodes
+	// with the highest betweenness centrality in the graph. The results of the
+	// probes are fed into the selected mission control. Only one probing run can
+	// be active at a time.
+	StartProbing(ctx context.Context, in *StartProbingRequest, opts ...grpc.CallOption) (*StartProbingResponse, error)
+	// lncli: `stopprobing`
+	// StopProbing stops the active probing run.
+	StopProbing(ctx context.Context, in *StopProbingRequest, opts ...grpc.CallOption) (*StopProbingResponse, error)
+	// lncli: `subscribeprobes`
+	// SubscribeProbeResults streams the result of every probe that is sent by
+	// the active probing run.
+	SubscribeProbeResults(ctx context.Context, in *SubscribeProbeResultsRequest, opts ...grpc.CallOption) (Router_SubscribeProbeResultsClient, error)
+	// lncli: `simulatepayment`
+	// SimulatePayment plans the shards of a payment the same way SendPaymentV2
+	// would, without sending anything. Every shard is checked against the
+	// current bandwidth, htlc slots, max in flight, channel reserve and dust
+	// exposure of our channels. The response contains the planned shards, the
+	// expected fee and success probability and the reason why each of our
+	// channels was skipped.
+	SimulatePayment(ctx context.Context, in *SimulatePaymentRequest, opts ...grpc.CallOption) (*SimulatePaymentResponse, error)
+	// lncli: `htlcratelimits`
+	// HtlcRateLimits returns the htlcs that each peer has in flight with us and
+	// the number of htlc adds of each peer that were allowed or failed back by
+	// the htlc rate limiter. It returns an error if htlc rate limiting isn't
+	// active.
+	HtlcRateLimits(ctx context.Context, in *HtlcRateLimitsRequest, opts ...grpc.CallOption) (*HtlcRateLimitsResponse, error)
 }
 
 type routerClient struct {
@@ -451,6 +493,137 @@ func (c *routerClient) UpdateChanStatus(ctx context.Context, in *UpdateChanStatu
 	return out, nil
 }
 
+func (c *routerClient) ExternalPathfinder(ctx context.Context, opts ...grpc.CallOption) (Router_ExternalPathfinderClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Router_ServiceDesc.Streams[7], "/routerrpc.Router/ExternalPathfinder", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routerExternalPathfinderClient{stream}
+	return x, nil
+}
+
+type Router_ExternalPathfinderClient interface {
+	Send(*ExternalRouteResponse) error
+	Recv() (*ExternalRouteRequest, error)
+	grpc.ClientStream
+}
+
+type routerExternalPathfinderClient struct {
+	grpc.ClientStream
+}
+
+func (x *routerExternalPathfinderClient) Send(m *ExternalRouteResponse) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *routerExternalPathfinderClient) Recv() (*ExternalRouteRequest, error) {
+	m := new(ExternalRouteRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routerClient) RebalanceChannel(ctx context.Context, in *RebalanceChannelRequest, opts ...grpc.CallOption) (Router_RebalanceChannelClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Router_ServiceDesc.Streams[8], "/routerrpc.Router/RebalanceChannel", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routerRebalanceChannelClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Router_RebalanceChannelClient interface {
+	Recv() (*lnrpc.Payment, error)
+	grpc.ClientStream
+}
+
+type routerRebalanceChannelClient struct {
+	grpc.ClientStream
+}
+
+func (x *routerRebalanceChannelClient) Recv() (*lnrpc.Payment, error) {
+	m := new(lnrpc.Payment)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routerClient) StartProbing(ctx context.Context, in *StartProbingRequest, opts ...grpc.CallOption) (*StartProbingResponse, error) {
+	out := new(StartProbingResponse)
+	err := c.cc.Invoke(ctx, "/routerrpc.Router/StartProbing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) StopProbing(ctx context.Context, in *StopProbingRequest, opts ...grpc.CallOption) (*StopProbingResponse, error) {
+	out := new(StopProbingResponse)
+	err := c.cc.Invoke(ctx, "/routerrpc.Router/StopProbing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) SubscribeProbeResults(ctx context.Context, in *SubscribeProbeResultsRequest, opts ...grpc.CallOption) (Router_SubscribeProbeResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Router_ServiceDesc.Streams[9], "/routerrpc.Router/SubscribeProbeResults", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routerSubscribeProbeResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Router_SubscribeProbeResultsClient interface {
+	Recv() (*ProbeResult, error)
+	grpc.ClientStream
+}
+
+type routerSubscribeProbeResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *routerSubscribeProbeResultsClient) Recv() (*ProbeResult, error) {
+	m := new(ProbeResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routerClient) SimulatePayment(ctx context.Context, in *SimulatePaymentRequest, opts ...grpc.CallOption) (*SimulatePaymentResponse, error) {
+	out := new(SimulatePaymentResponse)
+	err := c.cc.Invoke(ctx, "/routerrpc.Router/SimulatePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) HtlcRateLimits(ctx context.Context, in *HtlcRateLimitsRequest, opts ...grpc.CallOption) (*HtlcRateLimitsResponse, error) {
+	out := new(HtlcRateLimitsResponse)
+	err := c.cc.Invoke(ctx, "/routerrpc.Router/HtlcRateLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RouterServer is the server API for Router service.
 // All implementations must embed UnimplementedRouterServer
 // for forward compatibility
@@ -552,6 +725,48 @@ type RouterServer interface {
 	// channel to stay disabled until a subsequent manual request of either
 	// "enable" or "auto".
 	UpdateChanStatus(context.Context, *UpdateChanStatusRequest) (*UpdateChanStatusResponse, error)
+	// ExternalPathfinder dispatches a bi-directional streaming RPC in which the
+	// route requests of payments are sent to the client and the client responds
+	// with candidate routes. The routes are validated and tried by the payment
+	// lifecycle of lnd, which takes care of multi-path payments and retries.
+	// Only one external pathfinder can be registered at a time. While none is
+	// registered, lnd falls back to its own path finding.
+	ExternalPathfinder(Router_ExternalPathfinderServer) error
+	// lncli: `rebalancechannel`
+	// RebalanceChannel moves liquidity out of one or more of our channels into
+	// another one of our channels by paying ourselves along circular routes. The
+	// amount may be split across the outgoing channels. The rebalance is recorded
+	// as a payment with the rebalance flag set. The call returns a stream of
+	// payment updates.
+	RebalanceChannel(*RebalanceChannelRequest, Router_RebalanceChannelServer) error
+	// lncli: `startprobing`
+	// StartProbing starts a background probing run that periodically sends
+	// probes with a random payment hash to a set of destinations or to the nodes
+	// with the highest betweenness centrality in the graph. The results of the
+	// probes are fed into the selected mission control. Only one probing run can
+	// be active at a time.
+	StartProbing(context.Context, *StartProbingRequest) (*StartProbingResponse, error)
+	// lncli: `stopprobing`
+	// StopProbing stops the active probing run.
+	StopProbing(context.Context, *StopProbingRequest) (*StopProbingResponse, error)
+	// lncli: `subscribeprobes`
+	// SubscribeProbeResults streams the result of every probe that is sent by
+	// the active probing run.
+	SubscribeProbeResults(*SubscribeProbeResultsRequest, Router_SubscribeProbeResultsServer) error
+	// lncli: `simulatepayment`
+	// SimulatePayment plans the shards of a payment the same way SendPaymentV2
+	// would, without sending anything. Every shard is checked against the
+	// current bandwidth, htlc slots, max in flight, channel reserve and dust
+	// exposure of our channels. The response contains the planned shards, the
+	// expected fee and success probability and the reason why each of our
+	// channels was skipped.
+	SimulatePayment(context.Context, *SimulatePaymentRequest) (*SimulatePaymentResponse, error)
+	// lncli: `htlcratelimits`
+	// HtlcRateLimits returns the htlcs that each peer has in flight with us and
+	// the number of htlc adds of each peer that were allowed or failed back by
+	// the htlc rate limiter. It returns an error if htlc rate limiting isn't
+	// active.
+	HtlcRateLimits(context.Context, *HtlcRateLimitsRequest) (*HtlcRateLimitsResponse, error)
 	mustEmbedUnimplementedRouterServer()
 }
 
@@ -613,6 +828,27 @@ func (UnimplementedRouterServer) HtlcInterceptor(Router_HtlcInterceptorServer) e
 func (UnimplementedRouterServer) UpdateChanStatus(context.Context, *UpdateChanStatusRequest) (*UpdateChanStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateChanStatus not implemented")
 }
+func (UnimplementedRouterServer) ExternalPathfinder(Router_ExternalPathfinderServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExternalPathfinder not implemented")
+}
+func (UnimplementedRouterServer) RebalanceChannel(*RebalanceChannelRequest, Router_RebalanceChannelServer) error {
+	return status.Errorf(codes.Unimplemented, "method RebalanceChannel not implemented")
+}
+func (UnimplementedRouterServer) StartProbing(context.Context, *StartProbingRequest) (*StartProbingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartProbing not implemented")
+}
+func (UnimplementedRouterServer) StopProbing(context.Context, *StopProbingRequest) (*StopProbingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopProbing not implemented")
+}
+func (UnimplementedRouterServer) SubscribeProbeResults(*SubscribeProbeResultsRequest, Router_SubscribeProbeResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeProbeResults not implemented")
+}
+func (UnimplementedRouterServer) SimulatePayment(context.Context, *SimulatePaymentRequest) (*SimulatePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SimulatePayment not implemented")
+}
+func (UnimplementedRouterServer) HtlcRateLimits(context.Context, *HtlcRateLimitsRequest) (*HtlcRateLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HtlcRateLimits not implemented")
+}
 func (UnimplementedRouterServer) mustEmbedUnimplementedRouterServer() {}
 
 // UnsafeRouterServer may be embedded to opt out of forward compatibility for this service.
@@ -976,6 +1212,146 @@ func _Router_UpdateChanStatus_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Router_ExternalPathfinder_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RouterServer).ExternalPathfinder(&routerExternalPathfinderServer{stream})
+}
+
+type Router_ExternalPathfinderServer interface {
+	Send(*ExternalRouteRequest) error
+	Recv() (*ExternalRouteResponse, error)
+	grpc.ServerStream
+}
+
+type routerExternalPathfinderServer struct {
+	grpc.ServerStream
+}
+
+func (x *routerExternalPathfinderServer) Send(m *ExternalRouteRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *routerExternalPathfinderServer) Recv() (*ExternalRouteResponse, error) {
+	m := new(ExternalRouteResponse)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Router_RebalanceChannel_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RebalanceChannelRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouterServer).RebalanceChannel(m, &routerRebalanceChannelServer{stream})
+}
+
+type Router_RebalanceChannelServer interface {
+	Send(*lnrpc.Payment) error
+	grpc.ServerStream
+}
+
+type routerRebalanceChannelServer struct {
+	grpc.ServerStream
+}
+
+func (x *routerRebalanceChannelServer) Send(m *lnrpc.Payment) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Router_StartProbing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartProbingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).StartProbing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routerrpc.Router/StartProbing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).StartProbing(ctx, req.(*StartProbingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_StopProbing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopProbingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).StopProbing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routerrpc.Router/StopProbing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).StopProbing(ctx, req.(*StopProbingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_SubscribeProbeResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeProbeResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouterServer).SubscribeProbeResults(m, &routerSubscribeProbeResultsServer{stream})
+}
+
+type Router_SubscribeProbeResultsServer interface {
+	Send(*ProbeResult) error
+	grpc.ServerStream
+}
+
+type routerSubscribeProbeResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *routerSubscribeProbeResultsServer) Send(m *ProbeResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Router_SimulatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulatePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).SimulatePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routerrpc.Router/SimulatePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).SimulatePayment(ctx, req.(*SimulatePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_HtlcRateLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HtlcRateLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).HtlcRateLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routerrpc.Router/HtlcRateLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).HtlcRateLimits(ctx, req.(*HtlcRateLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Router_ServiceDesc is the grpc.ServiceDesc for Router service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -1027,6 +1403,22 @@ var Router_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateChanStatus",
 			Handler:    _Router_UpdateChanStatus_Handler,
 		},
+		{
+			MethodName: "StartProbing",
+			Handler:    _Router_StartProbing_Handler,
+		},
+		{
+			MethodName: "StopProbing",
+			Handler:    _Router_StopProbing_Handler,
+		},
+		{
+			MethodName: "SimulatePayment",
+			Handler:    _Router_SimulatePayment_Handler,
+		},
+		{
+			MethodName: "HtlcRateLimits",
+			Handler:    _Router_HtlcRateLimits_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1065,6 +1457,22 @@ var Router_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ExternalPathfinder",
+			Handler:       _Router_ExternalPathfinder_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "RebalanceChannel",
+			Handler:       _Router_RebalanceChannel_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeProbeResults",
+			Handler:       _Router_SubscribeProbeResults_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "routerrpc/router.proto",
 }
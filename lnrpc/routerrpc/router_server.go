@@ -16,6 +16,7 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
@@ -44,10 +45,10 @@ const (
 var (
 	errServerShuttingDown = errors.New("routerrpc server shutting down")
 
-	// ErrInterceptorAlreadyExists is an error returned when a new stream is
-	// opened and there is already one active interceptor. The user must
-	// disconnect prior to open another stream.
-	ErrInterceptorAlreadyExists = errors.New("interceptor already exists")
+	// ErrPathfinderAlreadyExists is an error returned when a new external
+	// pathfinder stream is opened while another one is active.
+	ErrPathfinderAlreadyExists = errors.New("external pathfinder already " +
+		"exists")
 
 	errMissingPaymentAttempt = errors.New("missing payment attempt")
 
@@ -142,6 +143,34 @@ var (
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/routerrpc.Router/ExternalPathfinder": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/routerrpc.Router/RebalanceChannel": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/routerrpc.Router/StartProbing": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/routerrpc.Router/StopProbing": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/routerrpc.Router/SubscribeProbeResults": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/routerrpc.Router/SimulatePayment": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/routerrpc.Router/HtlcRateLimits": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
 	}
 
 	// DefaultRouterMacFilename is the default name of the router macaroon
@@ -162,7 +191,7 @@ type ServerShell struct {
 type Server struct {
 	started                  int32 // To be used atomically.
 	shutdown                 int32 // To be used atomically.
-	forwardInterceptorActive int32 // To be used atomically.
+	externalPathfinderActive int32 // To be used atomically.
 
 	// Required by the grpc-gateway/v2 library for forward compatibility.
 	// Must be after the atomically used variables to not break struct
@@ -332,6 +361,29 @@ func (s *Server) SendPaymentV2(req *SendPaymentRequest,
 		return err
 	}
 
+	// The payment context is influenced by two user-provided parameters,
+	// the cancelable flag and the payment attempt timeout.
+	// If the payment is cancelable, we will use the stream context as the
+	// payment context. That way, if the user ends the stream, the payment
+	// loop will be canceled.
+	// The second context parameter is the timeout. If the user provides a
+	// timeout, we will additionally wrap the context in a deadline. If the
+	// user provided 'cancelable' and ends the stream before the timeout is
+	// reached the payment will be canceled.
+	ctx := context.Background()
+	if req.Cancelable {
+		ctx = stream.Context()
+	}
+
+	return s.sendPayment(ctx, payment, stream, req.NoInflightUpdates)
+}
+
+// sendPayment initializes the payment in the database, sends it
+// asynchronously and streams its updates to the client.
+func (s *Server) sendPayment(ctx context.Context,
+	payment *routing.LightningPayment, stream Router_TrackPaymentV2Server,
+	noInflightUpdates bool) error {
+
 	// Get the payment hash.
 	payHash := payment.Identifier()
 
@@ -361,25 +413,32 @@ func (s *Server) SendPaymentV2(req *SendPaymentRequest,
 		return err
 	}
 
-	// The payment context is influenced by two user-provided parameters,
-	// the cancelable flag and the payment attempt timeout.
-	// If the payment is cancelable, we will use the stream context as the
-	// payment context. That way, if the user ends the stream, the payment
-	// loop will be canceled.
-	// The second context parameter is the timeout. If the user provides a
-	// timeout, we will additionally wrap the context in a deadline. If the
-	// user provided 'cancelable' and ends the stream before the timeout is
-	// reached the payment will be canceled.
-	ctx := context.Background()
-	if req.Cancelable {
-		ctx = stream.Context()
-	}
-
 	// Send the payment asynchronously.
 	s.cfg.Router.SendPaymentAsync(ctx, payment, paySession, shardTracker)
 
 	// Track the payment and return.
-	return s.trackPayment(sub, payHash, stream, req.NoInflightUpdates)
+	return s.trackPayment(sub, payHash, stream, noInflightUpdates)
+}
+
+// RebalanceChannel moves liquidity out of one or more of our channels into
+// another one of our channels by paying ourselves along circular routes. The
+// call returns a stream of payment updates.
+func (s *Server) RebalanceChannel(req *RebalanceChannelRequest,
+	stream Router_RebalanceChannelServer) error {
+
+	payment, err := s.cfg.RouterBackend.extractIntentFromRebalanceRequest(
+		stream.Context(), req,
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Rebalancing %v into channel %v from channels %v",
+		payment.Amount, req.IncomingChanId, req.OutgoingChanIds)
+
+	return s.sendPayment(
+		context.Background(), payment, stream, req.NoInflightUpdates,
+	)
 }
 
 // EstimateRouteFee allows callers to obtain an expected value w.r.t how much it
@@ -892,11 +951,17 @@ func (s *Server) SendToRouteV2(ctx context.Context,
 func (s *Server) ResetMissionControl(ctx context.Context,
 	req *ResetMissionControlRequest) (*ResetMissionControlResponse, error) {
 
-	err := s.cfg.RouterBackend.MissionControl.ResetHistory()
+	mc, err := s.cfg.RouterBackend.missionControl(
+		req.MissionControlNamespace,
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := mc.ResetHistory(); err != nil {
+		return nil, err
+	}
+
 	return &ResetMissionControlResponse{}, nil
 }
 
@@ -905,8 +970,15 @@ func (s *Server) GetMissionControlConfig(ctx context.Context,
 	req *GetMissionControlConfigRequest) (*GetMissionControlConfigResponse,
 	error) {
 
+	mc, err := s.cfg.RouterBackend.missionControl(
+		req.MissionControlNamespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Query the current mission control config.
-	cfg := s.cfg.RouterBackend.MissionControl.GetConfig()
+	cfg := mc.GetConfig()
 	resp := &GetMissionControlConfigResponse{
 		Config: &MissionControlConfig{
 			MaximumPaymentResults: uint32(cfg.MaxMcHistory),
@@ -1038,16 +1110,29 @@ func (s *Server) SetMissionControlConfig(ctx context.Context,
 			req.Config.Model)
 	}
 
-	return &SetMissionControlConfigResponse{},
-		s.cfg.RouterBackend.MissionControl.SetConfig(mcCfg)
+	mc, err := s.cfg.RouterBackend.createMissionControl(
+		req.MissionControlNamespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetMissionControlConfigResponse{}, mc.SetConfig(mcCfg)
 }
 
 // QueryMissionControl exposes the internal mission control state to callers. It
 // is a development feature.
 func (s *Server) QueryMissionControl(_ context.Context,
-	_ *QueryMissionControlRequest) (*QueryMissionControlResponse, error) {
+	req *QueryMissionControlRequest) (*QueryMissionControlResponse, error) {
+
+	mc, err := s.cfg.RouterBackend.missionControl(
+		req.MissionControlNamespace,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	snapshot := s.cfg.RouterBackend.MissionControl.GetHistorySnapshot()
+	snapshot := mc.GetHistorySnapshot()
 
 	rpcPairs := make([]*PairHistory, 0, len(snapshot.Pairs))
 	for _, p := range snapshot.Pairs {
@@ -1513,24 +1598,172 @@ func (s *Server) SubscribeHtlcEvents(_ *SubscribeHtlcEventsRequest,
 // HtlcInterceptor is a bidirectional stream for streaming interception
 // requests to the caller.
 // Upon connection, it does the following:
-// 1. Check if there is already a live stream, if yes it rejects the request.
-// 2. Registered a ForwardInterceptor
-// 3. Delivers to the caller every √√ and detect his answer.
-// It uses a local implementation of holdForwardsStore to keep all the hold
-// forwards and find them when manual resolution is later needed.
+// 1. Registers a ForwardInterceptor in the interceptor chain of the switch.
+// 2. Delivers to the caller every √√ and detect his answer.
+// Multiple streams can be active at the same time, each one being a separate
+// interceptor in the chain.
 func (s *Server) HtlcInterceptor(stream Router_HtlcInterceptorServer) error {
-	// We ensure there is only one interceptor at a time.
-	if !atomic.CompareAndSwapInt32(&s.forwardInterceptorActive, 0, 1) {
-		return ErrInterceptorAlreadyExists
-	}
-	defer atomic.CompareAndSwapInt32(&s.forwardInterceptorActive, 1, 0)
-
 	// Run the forward interceptor.
 	return newForwardInterceptor(
 		s.cfg.RouterBackend.InterceptableForwarder, stream,
 	).run()
 }
 
+// ExternalPathfinder is a bidirectional stream that hands the route requests
+// of payments to the caller, which responds with candidate routes. Only one
+// external pathfinder can be active at a time.
+func (s *Server) ExternalPathfinder(
+	stream Router_ExternalPathfinderServer) error {
+
+	if s.cfg.RouterBackend.ExternalPathFinding == nil {
+		return status.Error(codes.Unimplemented, "external path "+
+			"finding is not available")
+	}
+
+	// We ensure there is only one external pathfinder at a time.
+	if !atomic.CompareAndSwapInt32(&s.externalPathfinderActive, 0, 1) {
+		return ErrPathfinderAlreadyExists
+	}
+	defer atomic.CompareAndSwapInt32(&s.externalPathfinderActive, 1, 0)
+
+	// Run the external pathfinder.
+	return newExternalPathfinder(s.cfg.RouterBackend, stream).run()
+}
+
+// errProbingUnavailable is returned by the probing calls if background
+// probing isn't available.
+var errProbingUnavailable = status.Error(
+	codes.Unimplemented, "background probing is not available",
+)
+
+// StartProbing starts a background probing run that periodically sends probes
+// to a set of destinations or to the nodes with the highest betweenness
+// centrality, and feeds their results into the selected mission control.
+func (s *Server) StartProbing(_ context.Context,
+	req *StartProbingRequest) (*StartProbingResponse, error) {
+
+	probeService := s.cfg.RouterBackend.ProbeService
+	if probeService == nil {
+		return nil, errProbingUnavailable
+	}
+
+	cfg, err := unmarshallProbeConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = probeService.StartProbing(cfg)
+	if errors.Is(err, routing.ErrProbingActive) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &StartProbingResponse{}, nil
+}
+
+// StopProbing stops the active background probing run.
+func (s *Server) StopProbing(_ context.Context,
+	_ *StopProbingRequest) (*StopProbingResponse, error) {
+
+	probeService := s.cfg.RouterBackend.ProbeService
+	if probeService == nil {
+		return nil, errProbingUnavailable
+	}
+
+	numProbes, err := probeService.StopProbing()
+	if errors.Is(err, routing.ErrProbingNotActive) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &StopProbingResponse{
+		NumProbes: numProbes,
+	}, nil
+}
+
+// SubscribeProbeResults streams the result of every probe that is sent by the
+// background probing run.
+func (s *Server) SubscribeProbeResults(_ *SubscribeProbeResultsRequest,
+	stream Router_SubscribeProbeResultsServer) error {
+
+	probeService := s.cfg.RouterBackend.ProbeService
+	if probeService == nil {
+		return errProbingUnavailable
+	}
+
+	sub, err := probeService.SubscribeProbeResults()
+	if err != nil {
+		return err
+	}
+	defer sub.Cancel()
+
+	for {
+		select {
+		case update := <-sub.Updates():
+			result, ok := update.(*routing.ProbeResult)
+			if !ok {
+				return fmt.Errorf("unexpected probe result "+
+					"type: %T", update)
+			}
+
+			rpcResult, err := s.cfg.RouterBackend.
+				marshallProbeResult(result)
+			if err != nil {
+				return err
+			}
+
+			if err := stream.Send(rpcResult); err != nil {
+				return err
+			}
+
+		case <-sub.Quit():
+			return errServerShuttingDown
+
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+
+		case <-s.quit:
+			return errServerShuttingDown
+		}
+	}
+}
+
+// SimulatePayment plans the shards of a payment the same way SendPaymentV2
+// would, without sending anything.
+func (s *Server) SimulatePayment(_ context.Context,
+	req *SimulatePaymentRequest) (*SimulatePaymentResponse, error) {
+
+	if req.Payment == nil {
+		return nil, status.Error(
+			codes.InvalidArgument, "payment must be specified",
+		)
+	}
+
+	// Nothing is sent during a simulation, so the payment doesn't need a
+	// timeout.
+	if req.Payment.TimeoutSeconds == 0 {
+		req.Payment.TimeoutSeconds = 1
+	}
+
+	payment, err := s.cfg.RouterBackend.extractIntentFromSendRequest(
+		req.Payment,
+	)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sim, err := s.cfg.Router.SimulatePayment(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cfg.RouterBackend.marshallPaymentSimulation(sim)
+}
+
 func extractOutPoint(req *UpdateChanStatusRequest) (*wire.OutPoint, error) {
 	chanPoint := req.GetChanPoint()
 	txid, err := lnrpc.GetChanPointFundingTxid(chanPoint)
@@ -1571,3 +1804,58 @@ func (s *Server) UpdateChanStatus(_ context.Context,
 	}
 	return &UpdateChanStatusResponse{}, nil
 }
+
+// HtlcRateLimits returns the in-flight htlcs and the counters of the htlc rate
+// limiter for every peer that has an active channel with us.
+func (s *Server) HtlcRateLimits(_ context.Context,
+	_ *HtlcRateLimitsRequest) (*HtlcRateLimitsResponse, error) {
+
+	rateLimiter := s.cfg.RouterBackend.HtlcRateLimiter
+	if rateLimiter == nil {
+		return nil, status.Error(
+			codes.FailedPrecondition, "htlc rate limiting is not active",
+		)
+	}
+
+	stats := rateLimiter.Stats()
+	resp := &HtlcRateLimitsResponse{
+		Peers: make([]*PeerHtlcRateLimit, 0, len(stats)),
+	}
+	for _, peer := range stats {
+		rpcPeer := &PeerHtlcRateLimit{
+			Peer:         peer.Peer[:],
+			InFlightMsat: uint64(peer.InFlight),
+			Counters:     rpcRateLimitCounters(peer.Counters),
+			Channels: make(
+				[]*ChannelHtlcRateLimit, 0, len(peer.Channels),
+			),
+		}
+		for _, channel := range peer.Channels {
+			rpcPeer.Channels = append(
+				rpcPeer.Channels, &ChannelHtlcRateLimit{
+					ChanPoint:    channel.ChanPoint.String(),
+					InFlightMsat: uint64(channel.InFlight),
+					Counters: rpcRateLimitCounters(
+						channel.Counters,
+					),
+				},
+			)
+		}
+
+		resp.Peers = append(resp.Peers, rpcPeer)
+	}
+
+	return resp, nil
+}
+
+// rpcRateLimitCounters converts the counters of the htlc rate limiter to their
+// rpc representation.
+func rpcRateLimitCounters(
+	counters htlcswitch.RateLimitCounters) *HtlcRateLimitCounters {
+
+	return &HtlcRateLimitCounters{
+		Allowed:         counters.Allowed,
+		AddRateLimited:  counters.AddRateLimited,
+		InFlightLimited: counters.InFlightLimited,
+	}
+}
@@ -154,6 +154,10 @@ func rpcFailReason(linkErr *htlcswitch.LinkError) (lnrpc.Failure_FailureCode,
 		fd, err := rpcOutgoingFailure(failureDetail)
 		return wireCode, fd, err
 
+	case htlcswitch.IncomingFailure:
+		fd, err := rpcIncomingFailure(failureDetail)
+		return wireCode, fd, err
+
 	default:
 		return 0, 0, fmt.Errorf("unknown failure "+
 			"detail type: %T", linkErr.FailureDetail)
@@ -210,6 +214,15 @@ func rpcFailureResolution(invoiceFailure invoices.FailResolutionResult) (
 	case invoices.ResultMppInProgress:
 		return FailureDetail_MPP_IN_PROGRESS, nil
 
+	case invoices.ResultHtlcAcceptorRejected,
+		invoices.ResultHtlcAcceptorTemporaryFailure,
+		invoices.ResultHtlcAcceptorPermanentFailure,
+		invoices.ResultHtlcAcceptorTimeout,
+		invoices.ResultHtlcAcceptorUnavailable,
+		invoices.ResultHtlcAcceptorExpiry:
+
+		return FailureDetail_HTLC_ACCEPTOR_REJECTED, nil
+
 	default:
 		return 0, fmt.Errorf("unknown fail resolution: %v",
 			invoiceFailure.FailureString())
@@ -254,8 +267,28 @@ func rpcOutgoingFailure(failureDetail htlcswitch.OutgoingFailure) (
 	case htlcswitch.OutgoingFailureForwardsDisabled:
 		return FailureDetail_FORWARDS_DISABLED, nil
 
+	case htlcswitch.OutgoingFailureResourceBucketFull:
+		return FailureDetail_RESOURCE_BUCKET_FULL, nil
+
 	default:
 		return 0, fmt.Errorf("unknown outgoing failure "+
 			"detail: %v", failureDetail.FailureString())
 	}
 }
+
+// rpcIncomingFailure maps an incoming failure to a rpc FailureDetail.
+func rpcIncomingFailure(failureDetail htlcswitch.IncomingFailure) (
+	FailureDetail, error) {
+
+	switch failureDetail {
+	case htlcswitch.IncomingFailureNone:
+		return FailureDetail_NO_DETAIL, nil
+
+	case htlcswitch.IncomingFailureRateLimited:
+		return FailureDetail_RATE_LIMITED, nil
+
+	default:
+		return 0, fmt.Errorf("unknown incoming failure "+
+			"detail: %v", failureDetail.FailureString())
+	}
+}
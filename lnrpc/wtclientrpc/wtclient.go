@@ -584,7 +584,9 @@ func marshallTower(tower *wtclient.RegisteredTower, policyType PolicyType,
 }
 
 func blobTypeToPolicyType(t blob.Type) (PolicyType, error) {
-	switch t {
+	// Whether the revoked HTLC outputs are backed up as well doesn't
+	// affect the channel type a policy applies to.
+	switch t &^ blob.Type(blob.FlagHtlcOutputs) {
 	case blob.TypeAltruistTaprootCommit:
 		return PolicyType_TAPROOT, nil
 
@@ -32,6 +32,9 @@ type WalletController struct {
 	PublishedTransactions chan *wire.MsgTx
 	index                 uint32
 	Utxos                 []*lnwallet.Utxo
+
+	// Txs are the wallet transactions returned by FetchTx.
+	Txs map[chainhash.Hash]*wire.MsgTx
 }
 
 // A compile time check to ensure this mocked WalletController implements the
@@ -281,8 +284,8 @@ func (w *WalletController) Stop() error {
 	return nil
 }
 
-func (w *WalletController) FetchTx(chainhash.Hash) (*wire.MsgTx, error) {
-	return nil, nil
+func (w *WalletController) FetchTx(txid chainhash.Hash) (*wire.MsgTx, error) {
+	return w.Txs[txid], nil
 }
 
 func (w *WalletController) RemoveDescendants(*wire.MsgTx) error {
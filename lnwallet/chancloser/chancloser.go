@@ -140,6 +140,12 @@ type ChanCloseCfg struct {
 	// FeeEstimator is used to estimate the absolute starting co-op close
 	// fee.
 	FeeEstimator CoopFeeEstimator
+
+	// SimpleClose indicates that the closing transaction is negotiated
+	// with the simple close protocol, in which each party pays the fee of
+	// its own closing transaction with closing_complete and closing_sig,
+	// and may replace it with a higher fee one.
+	SimpleClose bool
 }
 
 // ChanCloser is a state machine that handles the cooperative channel closure
@@ -215,6 +221,20 @@ type ChanCloser struct {
 	// we use to handle a specific race condition caused by the independent
 	// message processing queues.
 	cachedClosingSigned fn.Option[lnwire.ClosingSigned]
+
+	// pendingClosingComplete is the closing transaction we last proposed
+	// with the simple close protocol, which the remote party hasn't signed
+	// yet.
+	pendingClosingComplete fn.Option[closingProposal]
+
+	// lastClosingCompleteFee is the fee of the closing transaction we last
+	// proposed with the simple close protocol. Replacements must pay a
+	// higher fee.
+	lastClosingCompleteFee fn.Option[btcutil.Amount]
+
+	// cachedClosingComplete is a cached copy of a received
+	// ClosingComplete, which arrived before the channel was flushed.
+	cachedClosingComplete fn.Option[lnwire.ClosingComplete]
 }
 
 // calcCoopCloseFee computes an "ideal" absolute co-op close fee given the
@@ -702,6 +722,12 @@ func (c *ChanCloser) ReceiveClosingSigned( //nolint:funlen
 
 	noClosing := fn.None[lnwire.ClosingSigned]()
 
+	// The closing transaction is negotiated with closing_complete and
+	// closing_sig under the simple close protocol.
+	if c.cfg.SimpleClose {
+		return noClosing, ErrInvalidState
+	}
+
 	switch c.state {
 	case closeAwaitingFlush:
 		// If we hit this case it either means there's a protocol
@@ -0,0 +1,511 @@
+package chancloser
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnutils"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+var (
+	// ErrCloseFeeTooLow is returned when a replacement of our closing
+	// transaction doesn't pay a higher fee than the one it replaces.
+	ErrCloseFeeTooLow = fmt.Errorf("closing fee must exceed the fee of " +
+		"the replaced closing transaction")
+
+	// ErrClosingCompletePending is returned when we attempt to replace our
+	// closing transaction before the remote party signed the last one.
+	ErrClosingCompletePending = fmt.Errorf("awaiting closing_sig for " +
+		"previous closing_complete")
+
+	// ErrInvalidClosingSig is returned when the signatures of a
+	// closing_complete or closing_sig message are unacceptable.
+	ErrInvalidClosingSig = fmt.Errorf("invalid closing signatures")
+)
+
+// simpleCloseSequence is the sequence of the funding input of closing
+// transactions negotiated with the simple close protocol, which signals that
+// they may be replaced.
+const simpleCloseSequence = mempool.MaxRBFSequence
+
+// closingSigType identifies which outputs a closing transaction that's signed
+// with closing_complete and closing_sig includes.
+type closingSigType uint8
+
+const (
+	// closerAndClosee is a closing transaction with the outputs of both
+	// parties.
+	closerAndClosee closingSigType = iota
+
+	// closerNoClosee is a closing transaction without the output of the
+	// party receiving closing_complete.
+	closerNoClosee
+
+	// noCloserClosee is a closing transaction without the output of the
+	// party sending closing_complete.
+	noCloserClosee
+)
+
+// omittedOutput returns the party whose output is left off the closing
+// transaction, given the party that sent closing_complete.
+func (t closingSigType) omittedOutput(
+	closer lntypes.ChannelParty) fn.Option[lntypes.ChannelParty] {
+
+	switch t {
+	case closerNoClosee:
+		return fn.Some(closer.CounterParty())
+
+	case noCloserClosee:
+		return fn.Some(closer)
+
+	default:
+		return fn.None[lntypes.ChannelParty]()
+	}
+}
+
+// closingSigsByType returns the signatures of the passed closing sigs by the
+// type of closing transaction they sign.
+func closingSigsByType(
+	sigs lnwire.ClosingSigs) map[closingSigType]lnwire.Sig {
+
+	sigsByType := make(map[closingSigType]lnwire.Sig)
+
+	sigs.CloserAndClosee.WhenSome(
+		func(sig tlv.RecordT[tlv.TlvType3, lnwire.Sig]) {
+			sigsByType[closerAndClosee] = sig.Val
+		},
+	)
+	sigs.CloserNoClosee.WhenSome(
+		func(sig tlv.RecordT[tlv.TlvType1, lnwire.Sig]) {
+			sigsByType[closerNoClosee] = sig.Val
+		},
+	)
+	sigs.NoCloserClosee.WhenSome(
+		func(sig tlv.RecordT[tlv.TlvType2, lnwire.Sig]) {
+			sigsByType[noCloserClosee] = sig.Val
+		},
+	)
+
+	return sigsByType
+}
+
+// newClosingSigs creates the closing sigs of a closing_complete or closing_sig
+// message from signatures by type.
+func newClosingSigs(
+	sigsByType map[closingSigType]lnwire.Sig) lnwire.ClosingSigs {
+
+	var sigs lnwire.ClosingSigs
+	for sigType, sig := range sigsByType {
+		switch sigType {
+		case closerAndClosee:
+			sigs.CloserAndClosee = tlv.SomeRecordT(
+				tlv.NewRecordT[tlv.TlvType3](sig),
+			)
+
+		case closerNoClosee:
+			sigs.CloserNoClosee = tlv.SomeRecordT(
+				tlv.NewRecordT[tlv.TlvType1](sig),
+			)
+
+		case noCloserClosee:
+			sigs.NoCloserClosee = tlv.SomeRecordT(
+				tlv.NewRecordT[tlv.TlvType2](sig),
+			)
+		}
+	}
+
+	return sigs
+}
+
+// simpleCloseOpts returns the options to create the closing transaction of
+// the given type with, which is paid for by the closer.
+func simpleCloseOpts(closer lntypes.ChannelParty, sigType closingSigType,
+	sequence uint32) []lnwallet.ChanCloseOpt {
+
+	opts := []lnwallet.ChanCloseOpt{
+		lnwallet.WithCustomPayer(closer),
+		lnwallet.WithCustomCloseSequence(sequence),
+	}
+	sigType.omittedOutput(closer).WhenSome(
+		func(party lntypes.ChannelParty) {
+			opts = append(opts, lnwallet.WithOmittedOutput(party))
+		},
+	)
+
+	return opts
+}
+
+// closingProposal is a closing transaction we proposed with closing_complete
+// that the remote party hasn't signed yet.
+type closingProposal struct {
+	// fee is the absolute fee we pay for the closing transaction.
+	fee btcutil.Amount
+
+	// sequence is the sequence of the funding input.
+	sequence uint32
+
+	// sigs are our signatures of the closing transaction by type.
+	sigs map[closingSigType]input.Signature
+}
+
+// SimpleClose returns true if the closing transaction is negotiated with the
+// simple close protocol rather than with closing_signed.
+func (c *ChanCloser) SimpleClose() bool {
+	return c.cfg.SimpleClose
+}
+
+// DetachCloseRequest detaches the close request from the state machine, so
+// that it's no longer notified about closing transactions that replace the
+// current one.
+func (c *ChanCloser) DetachCloseRequest() {
+	c.closeReq = nil
+}
+
+// BeginSimpleClose is the simple close protocol counterpart of
+// BeginNegotiation. If we requested the close, it returns the closing_complete
+// that proposes our closing transaction. Otherwise, we wait for the remote
+// party to propose theirs.
+func (c *ChanCloser) BeginSimpleClose() (fn.Option[lnwire.ClosingComplete],
+	error) {
+
+	noClosingComplete := fn.None[lnwire.ClosingComplete]()
+
+	if c.state != closeAwaitingFlush {
+		return noClosingComplete, ErrInvalidState
+	}
+
+	// Just like with the legacy negotiation, we mark the channel as
+	// cooperatively closed before a closing transaction exists.
+	err := c.cfg.Channel.MarkCoopBroadcasted(nil, c.closer)
+	if err != nil {
+		return noClosingComplete, err
+	}
+
+	c.state = closeFeeNegotiation
+
+	if c.closeReq == nil {
+		return noClosingComplete, nil
+	}
+
+	closingComplete, err := c.proposeClosingComplete(c.idealFeeRate)
+	if err != nil {
+		return noClosingComplete, fmt.Errorf("unable to sign co op "+
+			"close tx: %w", err)
+	}
+
+	return fn.Some(*closingComplete), nil
+}
+
+// CachedClosingComplete returns the closing_complete of the remote party that
+// arrived before the channel was flushed, and needs to be processed now that
+// the negotiation started.
+func (c *ChanCloser) CachedClosingComplete() fn.Option[lnwire.ClosingComplete] {
+	cached := c.cachedClosingComplete
+	c.cachedClosingComplete = fn.None[lnwire.ClosingComplete]()
+
+	return cached
+}
+
+// BumpFee replaces our closing transaction with one that pays the target fee
+// rate of the passed close request, which is notified about the outcome from
+// now on. This is only possible with the simple close protocol.
+func (c *ChanCloser) BumpFee(req *htlcswitch.ChanClose) (
+	*lnwire.ClosingComplete, error) {
+
+	if !c.cfg.SimpleClose {
+		return nil, ErrChanAlreadyClosing
+	}
+
+	switch c.state {
+	case closeFeeNegotiation, closeFinished:
+
+	default:
+		return nil, fmt.Errorf("%w: close negotiation not started",
+			ErrInvalidState)
+	}
+
+	closingComplete, err := c.proposeClosingComplete(req.TargetFeePerKw)
+	if err != nil {
+		return nil, err
+	}
+
+	c.closeReq = req
+
+	return closingComplete, nil
+}
+
+// proposeClosingComplete signs a closing transaction that pays the given fee
+// rate from our own balance.
+func (c *ChanCloser) proposeClosingComplete(
+	feeRate chainfee.SatPerKWeight) (*lnwire.ClosingComplete, error) {
+
+	if c.pendingClosingComplete.IsSome() {
+		return nil, ErrClosingCompletePending
+	}
+
+	localDust := c.cfg.Channel.LocalBalanceDust()
+	remoteDust := c.cfg.Channel.RemoteBalanceDust()
+
+	localTxOut := &wire.TxOut{PkScript: c.localDeliveryScript}
+	remoteTxOut := &wire.TxOut{PkScript: c.remoteDeliveryScript}
+
+	var sigTypes []closingSigType
+	switch {
+	case localDust && remoteDust:
+		return nil, fmt.Errorf("outputs of both parties are dust")
+
+	// If our own output is dust, it's left off the closing transaction,
+	// so its value goes to fees.
+	case localDust:
+		sigTypes = []closingSigType{noCloserClosee}
+
+	case remoteDust:
+		sigTypes = []closingSigType{closerNoClosee}
+		remoteTxOut = nil
+
+	// We also sign the version without the output of the remote party, in
+	// case they prefer to leave it off.
+	default:
+		sigTypes = []closingSigType{closerAndClosee, closerNoClosee}
+	}
+
+	var fee btcutil.Amount
+	if !localDust {
+		fee = c.cfg.FeeEstimator.EstimateFee(
+			c.cfg.Channel.ChanType(), localTxOut, remoteTxOut,
+			feeRate,
+		)
+	}
+
+	lastFee := c.lastClosingCompleteFee.UnwrapOr(0)
+	if c.lastClosingCompleteFee.IsSome() && fee <= lastFee {
+		return nil, fmt.Errorf("%w: %v <= %v", ErrCloseFeeTooLow, fee,
+			lastFee)
+	}
+
+	sigs := make(map[closingSigType]input.Signature, len(sigTypes))
+	wireSigs := make(map[closingSigType]lnwire.Sig, len(sigTypes))
+	for _, sigType := range sigTypes {
+		sig, _, _, err := c.cfg.Channel.CreateCloseProposal(
+			fee, c.localDeliveryScript, c.remoteDeliveryScript,
+			simpleCloseOpts(
+				lntypes.Local, sigType, simpleCloseSequence,
+			)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		wireSig, err := lnwire.NewSigFromSignature(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		sigs[sigType] = sig
+		wireSigs[sigType] = wireSig
+	}
+
+	c.pendingClosingComplete = fn.Some(closingProposal{
+		fee:      fee,
+		sequence: simpleCloseSequence,
+		sigs:     sigs,
+	})
+	c.lastClosingCompleteFee = fn.Some(fee)
+
+	chancloserLog.Infof("ChannelPoint(%v): proposing closing tx paying "+
+		"fee of %v sat", c.chanPoint, int64(fee))
+
+	return &lnwire.ClosingComplete{
+		ChannelID:   c.cid,
+		FeeSatoshis: fee,
+		Sequence:    simpleCloseSequence,
+		ClosingSigs: newClosingSigs(wireSigs),
+	}, nil
+}
+
+// ReceiveClosingComplete signs and broadcasts the closing transaction the
+// remote party proposed, which they pay for. The returned closing_sig carries
+// our signature back to them. Any closing transaction we broadcast before is
+// replaced.
+func (c *ChanCloser) ReceiveClosingComplete(msg lnwire.ClosingComplete) (
+	fn.Option[lnwire.ClosingSig], error) {
+
+	noClosingSig := fn.None[lnwire.ClosingSig]()
+
+	if !c.cfg.SimpleClose {
+		return noClosingSig, ErrInvalidState
+	}
+
+	switch c.state {
+	// Just like closing_signed, the message may arrive before the link
+	// finished flushing the channel, so we'll process it once it did.
+	case closeAwaitingFlush:
+		c.cachedClosingComplete = fn.Some(msg)
+		return noClosingSig, nil
+
+	case closeFeeNegotiation, closeFinished:
+
+	default:
+		return noClosingSig, ErrInvalidState
+	}
+
+	// We only sign a closing transaction without our own output if it's
+	// dust anyway.
+	candidates := []closingSigType{closerAndClosee, noCloserClosee}
+	if c.cfg.Channel.LocalBalanceDust() {
+		candidates = []closingSigType{closerNoClosee}
+	}
+
+	remoteSigs := closingSigsByType(msg.ClosingSigs)
+
+	var (
+		sigType       closingSigType
+		remoteWireSig lnwire.Sig
+		found         bool
+	)
+	for _, candidate := range candidates {
+		remoteWireSig, found = remoteSigs[candidate]
+		if found {
+			sigType = candidate
+			break
+		}
+	}
+	if !found {
+		return noClosingSig, fmt.Errorf("%w: no signature of an "+
+			"acceptable closing tx", ErrInvalidClosingSig)
+	}
+
+	remoteSig, err := remoteWireSig.ToSignature()
+	if err != nil {
+		return noClosingSig, err
+	}
+
+	opts := simpleCloseOpts(lntypes.Remote, sigType, msg.Sequence)
+	localSig, _, _, err := c.cfg.Channel.CreateCloseProposal(
+		msg.FeeSatoshis, c.localDeliveryScript, c.remoteDeliveryScript,
+		opts...,
+	)
+	if err != nil {
+		return noClosingSig, err
+	}
+
+	closeTx, _, err := c.cfg.Channel.CompleteCooperativeClose(
+		localSig, remoteSig, c.localDeliveryScript,
+		c.remoteDeliveryScript, msg.FeeSatoshis, opts...,
+	)
+	if err != nil {
+		return noClosingSig, err
+	}
+
+	chancloserLog.Infof("ChannelPoint(%v): signed closing tx paying fee "+
+		"of %v sat proposed by remote party", c.chanPoint,
+		int64(msg.FeeSatoshis))
+
+	// The remote party broadcasts the transaction as well, so we don't
+	// fail if we can't, e.g. because it doesn't pay enough fees to
+	// replace the closing transaction we broadcast before.
+	if err := c.publishClosingTx(closeTx); err != nil {
+		chancloserLog.Warnf("ChannelPoint(%v): unable to publish "+
+			"closing tx: %v", c.chanPoint, err)
+	}
+
+	wireSig, err := lnwire.NewSigFromSignature(localSig)
+	if err != nil {
+		return noClosingSig, err
+	}
+
+	return fn.Some(lnwire.ClosingSig{
+		ChannelID: c.cid,
+		ClosingSigs: newClosingSigs(map[closingSigType]lnwire.Sig{
+			sigType: wireSig,
+		}),
+	}), nil
+}
+
+// ReceiveClosingSig completes the closing transaction we proposed with the
+// signature of the remote party, and broadcasts it.
+func (c *ChanCloser) ReceiveClosingSig(msg lnwire.ClosingSig) error {
+	if !c.cfg.SimpleClose {
+		return ErrInvalidState
+	}
+
+	proposal, err := c.pendingClosingComplete.UnwrapOrErr(fmt.Errorf(
+		"%w: closing_sig without closing_complete", ErrInvalidState,
+	))
+	if err != nil {
+		return err
+	}
+
+	remoteSigs := closingSigsByType(msg.ClosingSigs)
+	if len(remoteSigs) != 1 {
+		return fmt.Errorf("%w: expected one signature, got %v",
+			ErrInvalidClosingSig, len(remoteSigs))
+	}
+
+	var (
+		sigType       closingSigType
+		remoteWireSig lnwire.Sig
+	)
+	for t, sig := range remoteSigs {
+		sigType, remoteWireSig = t, sig
+	}
+
+	localSig, ok := proposal.sigs[sigType]
+	if !ok {
+		return fmt.Errorf("%w: signature of closing tx we didn't "+
+			"propose", ErrInvalidClosingSig)
+	}
+
+	remoteSig, err := remoteWireSig.ToSignature()
+	if err != nil {
+		return err
+	}
+
+	closeTx, _, err := c.cfg.Channel.CompleteCooperativeClose(
+		localSig, remoteSig, c.localDeliveryScript,
+		c.remoteDeliveryScript, proposal.fee,
+		simpleCloseOpts(lntypes.Local, sigType, proposal.sequence)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	c.pendingClosingComplete = fn.None[closingProposal]()
+
+	return c.publishClosingTx(closeTx)
+}
+
+// publishClosingTx persists and broadcasts the passed closing transaction,
+// which replaces any closing transaction that was published before.
+func (c *ChanCloser) publishClosingTx(closeTx *wire.MsgTx) error {
+	// Before publishing the closing tx, we persist it to the database,
+	// such that it can be republished if something goes wrong.
+	err := c.cfg.Channel.MarkCoopBroadcasted(closeTx, c.closer)
+	if err != nil {
+		return err
+	}
+
+	c.closingTx = closeTx
+	c.state = closeFinished
+
+	chancloserLog.Infof("Broadcasting cooperative close tx: %v",
+		lnutils.SpewLogClosure(closeTx))
+
+	chanID := c.cfg.Channel.ShortChanID()
+	closeLabel := labels.MakeLabel(
+		labels.LabelTypeChannelClose, &chanID,
+	)
+
+	return c.cfg.BroadcastTx(closeTx, closeLabel)
+}
@@ -0,0 +1,218 @@
+package chancloser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/contractcourt"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnutils"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSimpleCloseChan is a mock channel that produces real ECDSA signatures,
+// so that they can be carried by closing_complete and closing_sig.
+type mockSimpleCloseChan struct {
+	*mockChannel
+
+	signKey *btcec.PrivateKey
+}
+
+func newMockSimpleCloseChan(t *testing.T,
+	initiator bool) *mockSimpleCloseChan {
+
+	signKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	return &mockSimpleCloseChan{
+		mockChannel: &mockChannel{
+			initiator: initiator,
+			chanType:  channeldb.SingleFunderTweaklessBit,
+		},
+		signKey: signKey,
+	}
+}
+
+func (m *mockSimpleCloseChan) CreateCloseProposal(fee btcutil.Amount,
+	_, _ []byte, _ ...lnwallet.ChanCloseOpt) (input.Signature,
+	*chainhash.Hash, btcutil.Amount, error) {
+
+	sig := ecdsa.Sign(m.signKey, chainhash.DoubleHashB([]byte{1}))
+
+	return sig, nil, fee, nil
+}
+
+func (m *mockSimpleCloseChan) CompleteCooperativeClose(_, _ input.Signature,
+	_, _ []byte, fee btcutil.Amount,
+	_ ...lnwallet.ChanCloseOpt) (*wire.MsgTx, btcutil.Amount, error) {
+
+	// We encode the fee in the closing transaction, so that the
+	// transactions of different proposals can be told apart.
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(&wire.TxOut{Value: int64(fee)})
+
+	return tx, fee, nil
+}
+
+// newSimpleClosers creates the closers of Alice, who requests the close, and
+// Bob for a channel that's closed with the simple close protocol. The
+// returned channel receives the transactions they broadcast.
+func newSimpleClosers(t *testing.T) (*ChanCloser, *ChanCloser,
+	chan *wire.MsgTx) {
+
+	broadcasts := make(chan *wire.MsgTx, 10)
+	newCfg := func(initiator bool) ChanCloseCfg {
+		return ChanCloseCfg{
+			Channel: newMockSimpleCloseChan(t, initiator),
+			BroadcastTx: func(tx *wire.MsgTx, _ string) error {
+				broadcasts <- tx
+				return nil
+			},
+			MaxFee:       chainfee.SatPerKWeight(10_000),
+			FeeEstimator: &SimpleCoopFeeEstimator{},
+			DisableChannel: func(wire.OutPoint) error {
+				return nil
+			},
+			ChainParams: &chaincfg.RegressionNetParams,
+			SimpleClose: true,
+		}
+	}
+
+	idealFee := chainfee.SatPerKWeight(506)
+	closeReq := &htlcswitch.ChanClose{
+		CloseType:      contractcourt.CloseRegular,
+		TargetFeePerKw: idealFee,
+		Updates:        make(chan interface{}, 2),
+		Err:            make(chan error, 1),
+	}
+
+	// Both parties deliver their funds to a P2WPKH script.
+	aliceScript := append([]byte{0x00, 0x14}, bytes.Repeat([]byte{1}, 20)...)
+	bobScript := append([]byte{0x00, 0x14}, bytes.Repeat([]byte{2}, 20)...)
+
+	aliceCloser := NewChanCloser(
+		newCfg(true), aliceScript, idealFee, 0, closeReq, lntypes.Local,
+	)
+	aliceCloser.initFeeBaseline()
+
+	bobCloser := NewChanCloser(
+		newCfg(false), bobScript, idealFee, 0, nil, lntypes.Remote,
+	)
+	bobCloser.initFeeBaseline()
+
+	return aliceCloser, bobCloser, broadcasts
+}
+
+// TestSimpleClose tests that a closing transaction is negotiated with
+// closing_complete and closing_sig, and that the party that proposed it can
+// replace it with one that pays a higher fee.
+func TestSimpleClose(t *testing.T) {
+	t.Parallel()
+
+	aliceCloser, bobCloser, broadcasts := newSimpleClosers(t)
+
+	// Alice kicks off the close with a shutdown message, which Bob answers
+	// with his own.
+	msg, err := aliceCloser.ShutdownChan()
+	require.NoError(t, err)
+
+	oShutdown, err := bobCloser.ReceiveShutdown(*msg)
+	require.NoError(t, err)
+	require.True(t, oShutdown.IsSome())
+
+	_, err = aliceCloser.ReceiveShutdown(oShutdown.UnwrapOrFail(t))
+	require.NoError(t, err)
+
+	// As Bob didn't request the close, he doesn't propose a closing
+	// transaction, while Alice does.
+	oClosingComplete, err := bobCloser.BeginSimpleClose()
+	require.NoError(t, err)
+	require.True(t, oClosingComplete.IsNone())
+
+	oClosingComplete, err = aliceCloser.BeginSimpleClose()
+	require.NoError(t, err)
+	closingComplete := oClosingComplete.UnwrapOrFail(t)
+
+	require.EqualValues(
+		t, simpleCloseSequence, closingComplete.Sequence,
+	)
+	require.True(t, closingComplete.CloserAndClosee.IsSome())
+	require.True(t, closingComplete.CloserNoClosee.IsSome())
+	require.True(t, closingComplete.NoCloserClosee.IsNone())
+
+	// Alice can't replace her proposal before Bob signed it.
+	_, err = aliceCloser.BumpFee(&htlcswitch.ChanClose{
+		TargetFeePerKw: chainfee.SatPerKWeight(1_000),
+	})
+	require.ErrorIs(t, err, ErrClosingCompletePending)
+
+	// Bob signs the version with both outputs, and broadcasts it.
+	oClosingSig, err := bobCloser.ReceiveClosingComplete(closingComplete)
+	require.NoError(t, err)
+	closingSig := oClosingSig.UnwrapOrFail(t)
+	require.True(t, closingSig.CloserAndClosee.IsSome())
+
+	bobTx, err := lnutils.RecvOrTimeout(broadcasts, time.Second)
+	require.NoError(t, err)
+
+	// Alice completes the transaction with Bob's signature, and broadcasts
+	// it as well.
+	require.NoError(t, aliceCloser.ReceiveClosingSig(closingSig))
+
+	aliceTx, err := lnutils.RecvOrTimeout(broadcasts, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, (*bobTx).TxHash(), (*aliceTx).TxHash())
+
+	// Signing the same closing_sig twice isn't possible.
+	err = aliceCloser.ReceiveClosingSig(closingSig)
+	require.ErrorIs(t, err, ErrInvalidState)
+
+	// A replacement needs to pay a higher fee than the transaction it
+	// replaces.
+	_, err = aliceCloser.BumpFee(&htlcswitch.ChanClose{
+		TargetFeePerKw: chainfee.SatPerKWeight(253),
+	})
+	require.ErrorIs(t, err, ErrCloseFeeTooLow)
+
+	bumpReq := &htlcswitch.ChanClose{
+		TargetFeePerKw: chainfee.SatPerKWeight(1_000),
+	}
+	bumpComplete, err := aliceCloser.BumpFee(bumpReq)
+	require.NoError(t, err)
+	require.Greater(
+		t, bumpComplete.FeeSatoshis, closingComplete.FeeSatoshis,
+	)
+
+	// Bob signs the replacement, after which both broadcast it.
+	oClosingSig, err = bobCloser.ReceiveClosingComplete(*bumpComplete)
+	require.NoError(t, err)
+
+	bumpTx, err := lnutils.RecvOrTimeout(broadcasts, time.Second)
+	require.NoError(t, err)
+	require.NotEqual(t, (*bobTx).TxHash(), (*bumpTx).TxHash())
+
+	require.NoError(t, aliceCloser.ReceiveClosingSig(
+		oClosingSig.UnwrapOrFail(t),
+	))
+
+	closingTx, err := aliceCloser.ClosingTx()
+	require.NoError(t, err)
+	require.Equal(t, (*bumpTx).TxHash(), closingTx.TxHash())
+
+	// Legacy fee negotiation isn't possible for a simple close.
+	_, err = bobCloser.ReceiveClosingSigned(lnwire.ClosingSigned{})
+	require.ErrorIs(t, err, ErrInvalidState)
+}
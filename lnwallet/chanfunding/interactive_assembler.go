@@ -0,0 +1,395 @@
+package chanfunding
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+)
+
+// interactiveInputSequence is the sequence number of the inputs we add to an
+// interactively constructed funding transaction. It signals replaceability,
+// as required by the interactive transaction construction protocol.
+const interactiveInputSequence = wire.MaxTxInSequenceNum - 2
+
+// InteractiveConfig is the main config of the InteractiveAssembler.
+type InteractiveConfig struct {
+	// CoinSource is what the InteractiveAssembler uses to list/locate
+	// coins.
+	CoinSource CoinSource
+
+	// CoinSelectionStrategy is the strategy that is used for selecting
+	// coins when funding a transaction.
+	CoinSelectionStrategy wallet.CoinSelectionStrategy
+
+	// CoinSelectLocker allows the InteractiveAssembler to gain exclusive
+	// access to the current set of coins returned by the CoinSource.
+	CoinSelectLocker CoinSelectionLocker
+
+	// CoinLeaser is what the InteractiveAssembler uses to lease the coins
+	// it contributes, so they aren't used elsewhere while the funding
+	// transaction is negotiated.
+	CoinLeaser OutputLeaser
+
+	// Signer is used to sign our inputs of the funding transaction.
+	Signer input.Signer
+
+	// FetchTx returns the wallet transaction with the given txid. The
+	// previous transactions of our inputs are sent to the remote party.
+	FetchTx func(chainhash.Hash) (*wire.MsgTx, error)
+
+	// DustLimit is the current dust limit. We'll use this to ensure that
+	// we don't create a dust change output.
+	DustLimit btcutil.Amount
+
+	// Initiator is true if we sent open_channel2. The initiator adds the
+	// funding output and pays for the common fields of the transaction.
+	Initiator bool
+}
+
+// InteractiveAssembler is an Assembler that contributes the wallet inputs for
+// our side of a dual funded channel. Unlike the WalletAssembler, it only
+// knows about our part of the funding transaction. The remote party's inputs
+// and outputs are learned while the transaction is constructed interactively.
+type InteractiveAssembler struct {
+	cfg InteractiveConfig
+}
+
+// NewInteractiveAssembler creates a new InteractiveAssembler from a fully
+// populated config.
+func NewInteractiveAssembler(cfg InteractiveConfig) *InteractiveAssembler {
+	return &InteractiveAssembler{
+		cfg: cfg,
+	}
+}
+
+// ProvisionChannel selects and leases the wallet inputs that pay for our
+// contribution to the funding output, and our share of the fees of the
+// funding transaction.
+//
+// NOTE: To cancel the funding flow the Cancel() method on the returned Intent,
+// MUST be called.
+//
+// NOTE: This is a part of the chanfunding.Assembler interface.
+func (i *InteractiveAssembler) ProvisionChannel(r *Request) (Intent, error) {
+	if r.Musig2 {
+		return nil, fmt.Errorf("taproot channels can't be dual " +
+			"funded yet")
+	}
+
+	intent := &InteractiveIntent{
+		ShimIntent: ShimIntent{
+			localFundingAmt:  r.LocalAmt,
+			remoteFundingAmt: r.RemoteAmt,
+		},
+		initiator:  i.cfg.Initiator,
+		coinLeaser: i.cfg.CoinLeaser,
+		signer:     i.cfg.Signer,
+	}
+
+	// If we don't contribute anything, there's nothing to select.
+	if r.LocalAmt == 0 {
+		return intent, nil
+	}
+
+	err := i.cfg.CoinSelectLocker.WithCoinSelectLock(func() error {
+		coins, err := i.cfg.CoinSource.ListCoins(
+			r.MinConfs, math.MaxInt32,
+		)
+		if err != nil {
+			return err
+		}
+
+		// The inputs of an interactively constructed transaction must
+		// be native segwit, so its txid can't be malleated.
+		coins = fn.Filter(func(coin wallet.Coin) bool {
+			return txscript.IsWitnessProgram(coin.PkScript)
+		}, coins)
+
+		// The initiator pays for the funding output, the responder
+		// only for its own inputs and change.
+		var weightEstimate input.TxWeightEstimator
+		if i.cfg.Initiator {
+			weightEstimate.AddP2WSHOutput()
+		}
+
+		selectedCoins, changeAmt, err := CoinSelect(
+			r.FeeRate, r.LocalAmt, i.cfg.DustLimit, coins,
+			i.cfg.CoinSelectionStrategy, weightEstimate,
+			P2TRChangeAddress,
+		)
+		if err != nil {
+			return err
+		}
+
+		if changeAmt != 0 {
+			changeAddr, err := r.ChangeAddr()
+			if err != nil {
+				return err
+			}
+			changeScript, err := txscript.PayToAddrScript(
+				changeAddr,
+			)
+			if err != nil {
+				return err
+			}
+
+			intent.localOutputs = append(
+				intent.localOutputs, &interactivetx.Output{
+					Value:    changeAmt,
+					PkScript: changeScript,
+				},
+			)
+		}
+
+		for _, coin := range selectedCoins {
+			prevTx, err := i.cfg.FetchTx(coin.OutPoint.Hash)
+			if err != nil {
+				return fmt.Errorf("unable to fetch previous "+
+					"transaction of %v: %w", coin.OutPoint,
+					err)
+			}
+			if prevTx == nil {
+				return fmt.Errorf("previous transaction of "+
+					"%v not found", coin.OutPoint)
+			}
+
+			_, err = i.cfg.CoinLeaser.LeaseOutput(
+				LndInternalLockID, coin.OutPoint,
+				DefaultReservationTimeout,
+			)
+			if err != nil {
+				return err
+			}
+
+			prevOutput := coin.TxOut
+			intent.localInputs = append(
+				intent.localInputs, &interactivetx.Input{
+					OutPoint:   coin.OutPoint,
+					PrevTx:     prevTx,
+					PrevOutput: &prevOutput,
+					Sequence:   interactiveInputSequence,
+				},
+			)
+		}
+
+		return nil
+	})
+	if err != nil {
+		intent.Cancel()
+
+		return nil, err
+	}
+
+	return intent, nil
+}
+
+// A compile-time check to ensure InteractiveAssembler meets the Assembler
+// interface.
+var _ Assembler = (*InteractiveAssembler)(nil)
+
+// InteractiveIntent is an intent created by the InteractiveAssembler. It holds
+// our contribution to a funding transaction that is constructed interactively
+// with the remote party.
+//
+// Steps to final channel provisioning:
+//  1. Call BindKeys to notify the intent which keys to use when constructing
+//     the multi-sig output.
+//  2. If the remote contribution wasn't known when the intent was created,
+//     call SetRemoteFundingAmt.
+//  3. Negotiate the funding transaction using LocalInputs and LocalOutputs.
+//  4. Call SetFundingTx with the negotiated transaction to obtain the
+//     channel point.
+//  5. Sign our inputs with SignInputs.
+//
+// If any of these steps fail, then the Cancel method MUST be called.
+type InteractiveIntent struct {
+	ShimIntent
+
+	// initiator is true if we add the funding output to the transaction.
+	initiator bool
+
+	// localInputs are the wallet inputs we contribute.
+	localInputs []*interactivetx.Input
+
+	// localOutputs are the change outputs we contribute.
+	localOutputs []*interactivetx.Output
+
+	// fundingTx is the negotiated funding transaction.
+	fundingTx *wire.MsgTx
+
+	coinLeaser OutputLeaser
+
+	signer input.Signer
+}
+
+// BindKeys binds the keys of the 2-of-2 multi-sig funding output.
+func (i *InteractiveIntent) BindKeys(localKey *keychain.KeyDescriptor,
+	remoteKey *btcec.PublicKey) {
+
+	i.localKey = localKey
+	i.remoteKey = remoteKey
+}
+
+// SetRemoteFundingAmt records the amount the remote party contributes to the
+// funding output.
+func (i *InteractiveIntent) SetRemoteFundingAmt(amt btcutil.Amount) {
+	i.remoteFundingAmt = amt
+}
+
+// LocalInputs returns the inputs we contribute to the funding transaction.
+func (i *InteractiveIntent) LocalInputs() []*interactivetx.Input {
+	return i.localInputs
+}
+
+// LocalOutputs returns the outputs we contribute to the funding transaction.
+// If we're the initiator, this includes the funding output, so the keys must
+// be bound already.
+func (i *InteractiveIntent) LocalOutputs() ([]*interactivetx.Output, error) {
+	outputs := i.localOutputs
+	if !i.initiator {
+		return outputs, nil
+	}
+
+	_, fundingOutput, err := i.FundingOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]*interactivetx.Output{{
+		Value:    btcutil.Amount(fundingOutput.Value),
+		PkScript: fundingOutput.PkScript,
+	}}, outputs...), nil
+}
+
+// SetFundingTx records the negotiated funding transaction, and locates the
+// funding output within it.
+func (i *InteractiveIntent) SetFundingTx(tx *wire.MsgTx) error {
+	_, fundingOutput, err := i.FundingOutput()
+	if err != nil {
+		return err
+	}
+
+	for idx, txOut := range tx.TxOut {
+		if txOut.Value == fundingOutput.Value &&
+			bytes.Equal(txOut.PkScript, fundingOutput.PkScript) {
+
+			i.fundingTx = tx
+			i.chanPoint = &wire.OutPoint{
+				Hash:  tx.TxHash(),
+				Index: uint32(idx),
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("funding output not found in transaction")
+}
+
+// FundingTx returns the negotiated funding transaction. It's nil until
+// SetFundingTx is called.
+func (i *InteractiveIntent) FundingTx() *wire.MsgTx {
+	return i.fundingTx
+}
+
+// SignInputs returns the witnesses for our inputs of the passed transaction,
+// ordered like the inputs in the transaction.
+func (i *InteractiveIntent) SignInputs(tx *wire.MsgTx,
+	prevOuts txscript.PrevOutputFetcher) ([]wire.TxWitness, error) {
+
+	ourInputs := make(map[wire.OutPoint]*interactivetx.Input)
+	for _, in := range i.localInputs {
+		ourInputs[in.OutPoint] = in
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+
+	var witnesses []wire.TxWitness
+	for idx, txIn := range tx.TxIn {
+		in, ok := ourInputs[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+
+		signDesc := &input.SignDescriptor{
+			Output:            in.PrevOutput,
+			HashType:          txscript.SigHashAll,
+			SigHashes:         sigHashes,
+			PrevOutputFetcher: prevOuts,
+			InputIndex:        idx,
+		}
+		if txscript.IsPayToTaproot(in.PrevOutput.PkScript) {
+			signDesc.HashType = txscript.SigHashDefault
+		}
+
+		inputScript, err := i.signer.ComputeInputScript(tx, signDesc)
+		if err != nil {
+			return nil, err
+		}
+
+		witnesses = append(witnesses, inputScript.Witness)
+	}
+
+	return witnesses, nil
+}
+
+// Inputs returns the outpoints of the inputs we contribute.
+//
+// NOTE: This method satisfies the chanfunding.Intent interface.
+func (i *InteractiveIntent) Inputs() []wire.OutPoint {
+	return fn.Map(func(in *interactivetx.Input) wire.OutPoint {
+		return in.OutPoint
+	}, i.localInputs)
+}
+
+// Outputs returns the outputs we contribute, including the funding output if
+// we're the initiator and the keys are bound already.
+//
+// NOTE: This method satisfies the chanfunding.Intent interface.
+func (i *InteractiveIntent) Outputs() []*wire.TxOut {
+	var outputs []*wire.TxOut
+	if i.initiator {
+		if _, fundingOutput, err := i.FundingOutput(); err == nil {
+			outputs = append(outputs, fundingOutput)
+		}
+	}
+
+	for _, out := range i.localOutputs {
+		outputs = append(
+			outputs, wire.NewTxOut(int64(out.Value), out.PkScript),
+		)
+	}
+
+	return outputs
+}
+
+// Cancel releases the leases of the inputs we selected.
+//
+// NOTE: This method satisfies the chanfunding.Intent interface.
+func (i *InteractiveIntent) Cancel() {
+	for _, in := range i.localInputs {
+		err := i.coinLeaser.ReleaseOutput(
+			LndInternalLockID, in.OutPoint,
+		)
+		if err != nil {
+			log.Warnf("Failed to release UTXO %s (%v))",
+				in.OutPoint, err)
+		}
+	}
+
+	i.ShimIntent.Cancel()
+}
+
+// A compile-time check to ensure InteractiveIntent meets the Intent interface.
+var _ Intent = (*InteractiveIntent)(nil)
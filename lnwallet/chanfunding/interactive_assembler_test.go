@@ -0,0 +1,207 @@
+package chanfunding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/btcsuite/btcwallet/wtxmgr"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/stretchr/testify/require"
+)
+
+// mockInteractiveWallet implements the wallet interfaces the
+// InteractiveAssembler depends on.
+type mockInteractiveWallet struct {
+	coins  []wallet.Coin
+	prevTx *wire.MsgTx
+	leased map[wire.OutPoint]struct{}
+}
+
+func (m *mockInteractiveWallet) ListCoins(int32, int32) ([]wallet.Coin,
+	error) {
+
+	return m.coins, nil
+}
+
+func (m *mockInteractiveWallet) CoinFromOutPoint(wire.OutPoint) (*wallet.Coin,
+	error) {
+
+	return nil, nil
+}
+
+func (m *mockInteractiveWallet) WithCoinSelectLock(f func() error) error {
+	return f()
+}
+
+func (m *mockInteractiveWallet) LeaseOutput(_ wtxmgr.LockID, o wire.OutPoint,
+	_ time.Duration) (time.Time, error) {
+
+	m.leased[o] = struct{}{}
+
+	return time.Now(), nil
+}
+
+func (m *mockInteractiveWallet) ReleaseOutput(_ wtxmgr.LockID,
+	o wire.OutPoint) error {
+
+	delete(m.leased, o)
+
+	return nil
+}
+
+func (m *mockInteractiveWallet) FetchTx(chainhash.Hash) (*wire.MsgTx, error) {
+	return m.prevTx, nil
+}
+
+// newMockInteractiveWallet creates a wallet with a single P2WKH coin of the
+// given value.
+func newMockInteractiveWallet(t *testing.T,
+	value btcutil.Amount) *mockInteractiveWallet {
+
+	_, pubKey := btcec.PrivKeyFromBytes(localPrivkey)
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(pubKey.SerializeCompressed()), &params,
+	)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+
+	prevTx := wire.NewMsgTx(2)
+	prevTx.AddTxOut(wire.NewTxOut(int64(value), pkScript))
+
+	return &mockInteractiveWallet{
+		coins: []wallet.Coin{{
+			TxOut: *prevTx.TxOut[0],
+			OutPoint: wire.OutPoint{
+				Hash: prevTx.TxHash(),
+			},
+		}},
+		prevTx: prevTx,
+		leased: make(map[wire.OutPoint]struct{}),
+	}
+}
+
+// newTestInteractiveAssembler creates an InteractiveAssembler backed by the
+// passed mock wallet.
+func newTestInteractiveAssembler(w *mockInteractiveWallet,
+	initiator bool) *InteractiveAssembler {
+
+	return NewInteractiveAssembler(InteractiveConfig{
+		CoinSource:            w,
+		CoinSelectionStrategy: wallet.CoinSelectionLargest,
+		CoinSelectLocker:      w,
+		CoinLeaser:            w,
+		FetchTx:               w.FetchTx,
+		DustLimit:             btcutil.Amount(354),
+		Initiator:             initiator,
+	})
+}
+
+// TestInteractiveIntent tests that the InteractiveAssembler contributes
+// wallet inputs and a change output, that the initiator adds the funding
+// output, and that the funding output is located in the negotiated
+// transaction.
+func TestInteractiveIntent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		localAmt  = btcutil.Amount(500_000)
+		remoteAmt = btcutil.Amount(300_000)
+	)
+
+	w := newMockInteractiveWallet(t, btcutil.SatoshiPerBitcoin)
+	a := newTestInteractiveAssembler(w, true)
+
+	changeAddr, err := btcutil.NewAddressTaproot(
+		make([]byte, 32), &params,
+	)
+	require.NoError(t, err)
+
+	intent, err := a.ProvisionChannel(&Request{
+		LocalAmt: localAmt,
+		FeeRate:  chainfee.FeePerKwFloor,
+		ChangeAddr: func() (btcutil.Address, error) {
+			return changeAddr, nil
+		},
+	})
+	require.NoError(t, err)
+
+	interactiveIntent, ok := intent.(*InteractiveIntent)
+	require.True(t, ok)
+
+	// The single wallet coin should be contributed and leased.
+	inputs := interactiveIntent.LocalInputs()
+	require.Len(t, inputs, 1)
+	require.Equal(t, w.coins[0].OutPoint, inputs[0].OutPoint)
+	require.Equal(t, w.prevTx, inputs[0].PrevTx)
+	require.Contains(t, w.leased, w.coins[0].OutPoint)
+
+	// The funding output can't be added before the keys are bound.
+	_, err = interactiveIntent.LocalOutputs()
+	require.Error(t, err)
+
+	_, localPubkey := btcec.PrivKeyFromBytes(localPrivkey)
+	_, remotePubkey := btcec.PrivKeyFromBytes(remotePrivkey)
+	interactiveIntent.BindKeys(
+		&keychain.KeyDescriptor{PubKey: localPubkey}, remotePubkey,
+	)
+	interactiveIntent.SetRemoteFundingAmt(remoteAmt)
+
+	// As the initiator, we add the funding output of the full capacity
+	// along with our change.
+	outputs, err := interactiveIntent.LocalOutputs()
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+	require.Equal(t, localAmt+remoteAmt, outputs[0].Value)
+
+	// The remainder of the coin goes towards our share of the fee.
+	fee := btcutil.SatoshiPerBitcoin - localAmt - outputs[1].Value
+	require.Positive(t, fee)
+
+	// Once the transaction is negotiated, the funding output is located
+	// in it to obtain the channel point.
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&inputs[0].OutPoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(int64(outputs[1].Value), outputs[1].PkScript))
+	tx.AddTxOut(wire.NewTxOut(int64(outputs[0].Value), outputs[0].PkScript))
+	require.NoError(t, interactiveIntent.SetFundingTx(tx))
+
+	chanPoint, err := interactiveIntent.ChanPoint()
+	require.NoError(t, err)
+	require.Equal(t, wire.OutPoint{Hash: tx.TxHash(), Index: 1}, *chanPoint)
+
+	// Canceling the intent releases the lease.
+	interactiveIntent.Cancel()
+	require.Empty(t, w.leased)
+}
+
+// TestInteractiveIntentNoContribution tests that a responder that doesn't
+// contribute funds neither adds inputs nor the funding output.
+func TestInteractiveIntentNoContribution(t *testing.T) {
+	t.Parallel()
+
+	w := newMockInteractiveWallet(t, btcutil.SatoshiPerBitcoin)
+	a := newTestInteractiveAssembler(w, false)
+
+	intent, err := a.ProvisionChannel(&Request{
+		RemoteAmt: chanCapacity,
+		FeeRate:   chainfee.FeePerKwFloor,
+	})
+	require.NoError(t, err)
+
+	interactiveIntent, ok := intent.(*InteractiveIntent)
+	require.True(t, ok)
+	require.Empty(t, interactiveIntent.LocalInputs())
+
+	outputs, err := interactiveIntent.LocalOutputs()
+	require.NoError(t, err)
+	require.Empty(t, outputs)
+	require.Empty(t, w.leased)
+}
@@ -206,6 +206,7 @@ func PayDescsFromRemoteLogUpdates(chanID lnwire.ShortChannelID, height uint64,
 					Index:  uint16(i),
 				},
 				BlindingPoint: wireMsg.BlindingPoint,
+				CustomRecords: wireMsg.CustomRecords,
 			}
 			pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 			copy(pd.OnionBlob[:], wireMsg.OnionBlob[:])
@@ -345,6 +346,11 @@ type commitment struct {
 	// view.
 	outgoingHTLCIndex map[int32]*PaymentDescriptor
 	incomingHTLCIndex map[int32]*PaymentDescriptor
+
+	// spliceCommits holds the variants of this commitment that spend the
+	// funding outputs of the channel's pending splice transactions, keyed
+	// by the txid of the splice transaction.
+	spliceCommits map[chainhash.Hash]*commitment
 }
 
 // locateOutputIndex is a small helper function to locate the output index of a
@@ -523,6 +529,7 @@ func (c *commitment) toDiskCommit(
 			LogIndex:      htlc.LogIndex,
 			Incoming:      false,
 			BlindingPoint: htlc.BlindingPoint,
+			CustomRecords: htlc.CustomRecords,
 		}
 		copy(h.OnionBlob[:], htlc.OnionBlob)
 
@@ -548,6 +555,7 @@ func (c *commitment) toDiskCommit(
 			LogIndex:      htlc.LogIndex,
 			Incoming:      true,
 			BlindingPoint: htlc.BlindingPoint,
+			CustomRecords: htlc.CustomRecords,
 		}
 		copy(h.OnionBlob[:], htlc.OnionBlob)
 		if whoseCommit.IsLocal() && htlc.sig != nil {
@@ -557,6 +565,18 @@ func (c *commitment) toDiskCommit(
 		commit.Htlcs = append(commit.Htlcs, h)
 	}
 
+	if len(c.spliceCommits) != 0 {
+		commit.SpliceCommitments = make(
+			map[chainhash.Hash]*channeldb.ChannelCommitment,
+			len(c.spliceCommits),
+		)
+	}
+	for txid, spliceCommit := range c.spliceCommits {
+		commit.SpliceCommitments[txid] = spliceCommit.toDiskCommit(
+			whoseCommit,
+		)
+	}
+
 	return commit
 }
 
@@ -647,6 +667,7 @@ func (lc *LightningChannel) diskHtlcToPayDesc(feeRate chainfee.SatPerKWeight,
 		theirPkScript:      theirP2WSH,
 		theirWitnessScript: theirWitnessScript,
 		BlindingPoint:      htlc.BlindingPoint,
+		CustomRecords:      htlc.CustomRecords,
 	}, nil
 }
 
@@ -760,6 +781,26 @@ func (lc *LightningChannel) diskCommitToMemCommit(
 		commit.dustLimit = lc.channelState.RemoteChanCfg.DustLimit
 	}
 
+	// The variants of the commitment for pending splice transactions are
+	// restored the same way, using the same commitment points.
+	if len(diskCommit.SpliceCommitments) != 0 {
+		commit.spliceCommits = make(
+			map[chainhash.Hash]*commitment,
+			len(diskCommit.SpliceCommitments),
+		)
+	}
+	for txid, diskSpliceCommit := range diskCommit.SpliceCommitments {
+		spliceCommit, err := lc.diskCommitToMemCommit(
+			whoseCommit, diskSpliceCommit, localCommitPoint,
+			remoteCommitPoint,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		commit.spliceCommits[txid] = spliceCommit
+	}
+
 	return commit, nil
 }
 
@@ -860,6 +901,18 @@ type LightningChannel struct {
 	// opts is the set of options that channel was initialized with.
 	opts *channelOpts
 
+	// dynCommitEpoch is set once a dynamic commitment upgrade has been
+	// applied to the channel. It marks the heights of the first
+	// commitments that use the upgraded parameters, which we owe to and
+	// are owed by the remote party.
+	dynCommitEpoch fn.Option[channeldb.DynCommitEpoch]
+
+	// pendingSplices are the splice transactions of the channel that have
+	// been negotiated, but haven't been promoted yet. Every commitment
+	// created while they're pending has a variant that spends the funding
+	// output of each of them.
+	pendingSplices []*pendingSplice
+
 	sync.RWMutex
 }
 
@@ -987,6 +1040,19 @@ func NewLightningChannel(signer input.Signer,
 		return nil, err
 	}
 
+	// If a dynamic commitment upgrade was applied, but the commitments
+	// using the upgraded parameters haven't been created yet, we need to
+	// restore the epoch of the upgrade so we still owe them.
+	if err := lc.restoreDynCommitEpoch(); err != nil {
+		return nil, err
+	}
+
+	// Restore the splice transactions that are still pending, so we keep
+	// creating commitments that spend their funding outputs.
+	if err := lc.restorePendingSplices(); err != nil {
+		return nil, err
+	}
+
 	// Create the sign descriptor which we'll be using very frequently to
 	// request a signature for the 2-of-2 multi-sig from the signer in
 	// order to complete channel state transitions.
@@ -1092,6 +1158,7 @@ func (lc *LightningChannel) logUpdateToPayDesc(logUpdate *channeldb.LogUpdate,
 			LogIndex:              logUpdate.LogIndex,
 			addCommitHeightRemote: commitHeight,
 			BlindingPoint:         wireMsg.BlindingPoint,
+			CustomRecords:         wireMsg.CustomRecords,
 		}
 		pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 		copy(pd.OnionBlob[:], wireMsg.OnionBlob[:])
@@ -1290,6 +1357,7 @@ func (lc *LightningChannel) remoteLogUpdateToPayDesc(logUpdate *channeldb.LogUpd
 			LogIndex:             logUpdate.LogIndex,
 			addCommitHeightLocal: commitHeight,
 			BlindingPoint:        wireMsg.BlindingPoint,
+			CustomRecords:        wireMsg.CustomRecords,
 		}
 		pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 		copy(pd.OnionBlob, wireMsg.OnionBlob[:])
@@ -1858,6 +1926,19 @@ type HtlcRetribution struct {
 	// this HTLC was offered by us. This flag is used determine the exact
 	// witness type should be used to sweep the output.
 	IsIncoming bool
+
+	// PaymentHash is the payment hash of the HTLC.
+	PaymentHash [32]byte
+
+	// RefundTimeout is the absolute timeout of the HTLC, after which the
+	// sender of the HTLC can reclaim it.
+	RefundTimeout uint32
+
+	// SecondLevelTx is the unsigned second level HTLC transaction the
+	// breaching party can spend this HTLC output with. It is only known
+	// for channels without anchors, and is nil if the revocation log
+	// doesn't hold the fee rate of the commitment.
+	SecondLevelTx *wire.MsgTx
 }
 
 // BreachRetribution contains all the data necessary to bring a channel
@@ -1965,6 +2046,25 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 		revocationPreimage[:],
 	)
 
+	// A dynamic commitment upgrade may have changed the channel type and
+	// the channel configs since the revoked state was created, in which
+	// case we'll reconstruct it with the parameters of that time. The
+	// view of the channel only holds the fields required for that.
+	epoch, err := chanState.DynCommitEpochAt(lntypes.Remote, stateNum)
+	if err != nil {
+		return nil, err
+	}
+	epoch.WhenSome(func(e channeldb.DynCommitEpoch) {
+		chanState = &channeldb.OpenChannel{
+			ChanType:      e.ChanType,
+			ChainHash:     chanState.ChainHash,
+			IsInitiator:   chanState.IsInitiator,
+			LocalChanCfg:  e.LocalChanCfg,
+			RemoteChanCfg: e.RemoteChanCfg,
+			ThawHeight:    chanState.ThawHeight,
+		}
+	})
+
 	// With the commitment point generated, we can now generate the four
 	// keys we'll need to reconstruct the commitment state,
 	keyRing := DeriveCommitmentKeys(
@@ -2010,6 +2110,21 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	// If the returned *RevocationLog is non-nil, use it to derive the info
 	// we need.
 	if revokedLog != nil {
+		// The breach transaction may be the variant of the revoked
+		// commitment that spends the funding output of a splice, whose
+		// outputs are at different indexes.
+		if spendTx != nil &&
+			spendTx.TxHash() != revokedLog.CommitTxHash {
+
+			revokedLog, err = spliceRevocationLog(
+				revokedLog, spendTx, chanState, keyRing,
+				ourScript, theirScript,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		br, ourAmt, theirAmt, err = createBreachRetribution(
 			revokedLog, spendTx, chanState, keyRing,
 			commitmentSecret, leaseExpiry,
@@ -2142,10 +2257,12 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 }
 
 // createHtlcRetribution is a helper function to construct an HtlcRetribution
-// based on the passed params.
+// based on the passed params. The fee rate of the commitment is only needed to
+// rebuild the second level HTLC transaction of channels without anchors.
 func createHtlcRetribution(chanState *channeldb.OpenChannel,
 	keyRing *CommitmentKeyRing, commitHash chainhash.Hash,
 	commitmentSecret *btcec.PrivateKey, leaseExpiry uint32,
+	feePerKw fn.Option[chainfee.SatPerKWeight],
 	htlc *channeldb.HTLCEntry) (HtlcRetribution, error) {
 
 	var emptyRetribution HtlcRetribution
@@ -2214,18 +2331,70 @@ func createHtlcRetribution(chanState *channeldb.OpenChannel,
 		copy(secondLevelTapTweak[:], scriptTree.TapTweak())
 	}
 
+	htlcOutPoint := wire.OutPoint{
+		Hash:  commitHash,
+		Index: uint32(htlc.OutputIndex),
+	}
+
+	// The second level transactions of channels without anchors are fully
+	// determined by the fee rate of the commitment, as we signed them with
+	// SIGHASH_ALL. Those of the later channel types are signed with
+	// SIGHASH_SINGLE|ANYONECANPAY, which lets the breaching party change
+	// their txid, so we can't rebuild them.
+	var secondLevelTx *wire.MsgTx
+	if feePerKw.IsSome() && !chanState.ChanType.HasAnchors() {
+		secondLevelTx, err = createRemoteSecondLevelTx(
+			chanState, keyRing, htlcOutPoint,
+			feePerKw.UnwrapOr(0), leaseExpiry, htlc,
+		)
+		if err != nil {
+			return emptyRetribution, err
+		}
+	}
+
 	return HtlcRetribution{
-		SignDesc: signDesc,
-		OutPoint: wire.OutPoint{
-			Hash:  commitHash,
-			Index: uint32(htlc.OutputIndex),
-		},
+		SignDesc:                 signDesc,
+		OutPoint:                 htlcOutPoint,
 		SecondLevelWitnessScript: secondLevelWitnessScript,
 		IsIncoming:               htlc.Incoming,
 		SecondLevelTapTweak:      secondLevelTapTweak,
+		SecondLevelTx:            secondLevelTx,
+		PaymentHash:              htlc.RHash,
+		RefundTimeout:            htlc.RefundTimeout,
 	}, nil
 }
 
+// createRemoteSecondLevelTx rebuilds the unsigned second level transaction the
+// remote party can spend the given HTLC output of their commitment with. HTLCs
+// offered by the remote party are spent with an HTLC timeout transaction, and
+// the ones they accepted with an HTLC success transaction.
+func createRemoteSecondLevelTx(chanState *channeldb.OpenChannel,
+	keyRing *CommitmentKeyRing, htlcOutPoint wire.OutPoint,
+	feePerKw chainfee.SatPerKWeight, leaseExpiry uint32,
+	htlc *channeldb.HTLCEntry) (*wire.MsgTx, error) {
+
+	chanType := chanState.ChanType
+	isRemoteInitiator := !chanState.IsInitiator
+	csvDelay := uint32(chanState.RemoteChanCfg.CsvDelay)
+
+	if htlc.Incoming {
+		outputAmt := htlc.Amt - HtlcTimeoutFee(chanType, feePerKw)
+
+		return CreateHtlcTimeoutTx(
+			chanType, isRemoteInitiator, htlcOutPoint, outputAmt,
+			htlc.RefundTimeout, csvDelay, leaseExpiry,
+			keyRing.RevocationKey, keyRing.ToLocalKey,
+		)
+	}
+
+	outputAmt := htlc.Amt - HtlcSuccessFee(chanType, feePerKw)
+
+	return CreateHtlcSuccessTx(
+		chanType, isRemoteInitiator, htlcOutPoint, outputAmt, csvDelay,
+		leaseExpiry, keyRing.RevocationKey, keyRing.ToLocalKey,
+	)
+}
+
 // createBreachRetribution creates a partially initiated BreachRetribution
 // using a RevocationLog. Returns the constructed retribution, our amount,
 // their amount, and a possible non-nil error. If the spendTx parameter is
@@ -2240,12 +2409,19 @@ func createBreachRetribution(revokedLog *channeldb.RevocationLog,
 
 	commitHash := revokedLog.CommitTxHash
 
+	// Revocation logs written by older versions don't hold the fee rate of
+	// the commitment.
+	feePerKw := fn.None[chainfee.SatPerKWeight]()
+	if revokedLog.FeePerKw != nil {
+		feePerKw = fn.Some(chainfee.SatPerKWeight(*revokedLog.FeePerKw))
+	}
+
 	// Create the htlc retributions.
 	htlcRetributions := make([]HtlcRetribution, len(revokedLog.HTLCEntries))
 	for i, htlc := range revokedLog.HTLCEntries {
 		hr, err := createHtlcRetribution(
 			chanState, keyRing, commitHash,
-			commitmentSecret, leaseExpiry, htlc,
+			commitmentSecret, leaseExpiry, feePerKw, htlc,
 		)
 		if err != nil {
 			return nil, 0, 0, err
@@ -2392,7 +2568,9 @@ func createBreachRetributionLegacy(revokedLog *channeldb.ChannelCommitment,
 		}
 		hr, err := createHtlcRetribution(
 			chanState, keyRing, commitHash,
-			commitmentSecret, leaseExpiry, entry,
+			commitmentSecret, leaseExpiry,
+			fn.Some(chainfee.SatPerKWeight(revokedLog.FeePerKw)),
+			entry,
 		)
 		if err != nil {
 			return nil, 0, 0, err
@@ -2539,6 +2717,7 @@ func (lc *LightningChannel) fetchCommitmentView(
 	commitTx, err := lc.commitBuilder.createUnsignedCommitmentTx(
 		ourBalance, theirBalance, whoseCommitChain, feePerKw,
 		nextHeight, filteredHTLCView, keyRing,
+		fundingTxIn(lc.channelState), lc.channelState.Capacity,
 	)
 	if err != nil {
 		return nil, err
@@ -2611,6 +2790,23 @@ func (lc *LightningChannel) fetchCommitmentView(
 		return nil, err
 	}
 
+	// Finally, we'll create the variants of the commitment that spend the
+	// funding outputs of the pending splice transactions.
+	for _, splice := range lc.pendingSplices {
+		spliceCommit, err := lc.spliceCommitment(
+			c, splice, ourBalance, theirBalance, filteredHTLCView,
+			keyRing,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.spliceCommits == nil {
+			c.spliceCommits = make(map[chainhash.Hash]*commitment)
+		}
+		c.spliceCommits[splice.txid] = spliceCommit
+	}
+
 	return c, nil
 }
 
@@ -2618,7 +2814,8 @@ func (lc *LightningChannel) fetchCommitmentView(
 // returned by this function uses a max sequence number, so it isn't able to be
 // used with RBF by default.
 func fundingTxIn(chanState *channeldb.OpenChannel) wire.TxIn {
-	return *wire.NewTxIn(&chanState.FundingOutpoint, nil, nil)
+	fundingPoint := chanState.CurrentFundingOutpoint()
+	return *wire.NewTxIn(&fundingPoint, nil, nil)
 }
 
 // evaluateHTLCView processes all update entries in both HTLC update logs,
@@ -3105,9 +3302,9 @@ func genRemoteHtlcSigJobs(keyRing *CommitmentKeyRing,
 // validate this new state. This function is called right before sending the
 // new commitment to the remote party. The commit diff returned contains all
 // information necessary for retransmission.
-func (lc *LightningChannel) createCommitDiff(
-	newCommit *commitment, commitSig lnwire.Sig,
-	htlcSigs []lnwire.Sig) (*channeldb.CommitDiff, error) {
+func (lc *LightningChannel) createCommitDiff(newCommit *commitment,
+	commitSig lnwire.Sig, htlcSigs []lnwire.Sig,
+	spliceSigs []lnwire.SpliceCommitSig) (*channeldb.CommitDiff, error) {
 
 	// First, we need to convert the funding outpoint into the ID that's
 	// used on the wire to identify this channel. We'll use this shortly
@@ -3159,6 +3356,7 @@ func (lc *LightningChannel) createCommitDiff(
 				Expiry:        pd.Timeout,
 				PaymentHash:   pd.RHash,
 				BlindingPoint: pd.BlindingPoint,
+				CustomRecords: pd.CustomRecords,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -3236,8 +3434,9 @@ func (lc *LightningChannel) createCommitDiff(
 			ChanID: lnwire.NewChanIDFromOutPoint(
 				lc.channelState.FundingOutpoint,
 			),
-			CommitSig: commitSig,
-			HtlcSigs:  htlcSigs,
+			CommitSig:  commitSig,
+			HtlcSigs:   htlcSigs,
+			SpliceSigs: lnwire.MaybeSpliceCommitSigs(spliceSigs),
 		},
 		LogUpdates:        logUpdates,
 		OpenedCircuitKeys: openCircuitKeys,
@@ -3297,6 +3496,7 @@ func (lc *LightningChannel) getUnsignedAckedUpdates() []channeldb.LogUpdate {
 				Expiry:        pd.Timeout,
 				PaymentHash:   pd.RHash,
 				BlindingPoint: pd.BlindingPoint,
+				CustomRecords: pd.CustomRecords,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -3671,7 +3871,10 @@ func (lc *LightningChannel) validateCommitmentSanity(theirLogCounter,
 		return err
 	}
 
-	return nil
+	// Finally, while splices are pending, the signatures for the variants
+	// of the commitment are sent along with the regular ones, which limits
+	// the number of HTLCs that fit into a single CommitSig message.
+	return lc.validateSpliceHtlcs(filteredView)
 }
 
 // CommitSigs holds the set of related signatures for a new commitment
@@ -3689,6 +3892,11 @@ type CommitSigs struct {
 	// PartialSig is the musig2 partial signature for taproot commitment
 	// transactions.
 	PartialSig lnwire.OptPartialSigWithNonceTLV
+
+	// SpliceSigs holds the signatures for the variants of the commitment
+	// transaction that spend the funding outputs of pending splice
+	// transactions.
+	SpliceSigs []lnwire.SpliceCommitSig
 }
 
 // NewCommitState wraps the various signatures needed to properly
@@ -3875,10 +4083,19 @@ func (lc *LightningChannel) SignNextCommitment() (*NewCommitState, error) {
 		htlcSigs = append(htlcSigs, jobResp.Sig)
 	}
 
+	// If there are pending splices, we'll also sign the variants of the
+	// new commitment that spend their funding outputs.
+	spliceSigs, err := lc.signSpliceCommitments(newCommitView, keyRing)
+	if err != nil {
+		return nil, err
+	}
+
 	// As we're about to proposer a new commitment state for the remote
 	// party, we'll write this pending state to disk before we exit, so we
 	// can retransmit it if necessary.
-	commitDiff, err := lc.createCommitDiff(newCommitView, sig, htlcSigs)
+	commitDiff, err := lc.createCommitDiff(
+		newCommitView, sig, htlcSigs, spliceSigs,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -3900,6 +4117,7 @@ func (lc *LightningChannel) SignNextCommitment() (*NewCommitState, error) {
 			CommitSig:  sig,
 			HtlcSigs:   htlcSigs,
 			PartialSig: lnwire.MaybePartialSigWithNonce(partialSig),
+			SpliceSigs: spliceSigs,
 		},
 		PendingHTLCs: commitDiff.Commitment.Htlcs,
 	}, nil
@@ -3940,6 +4158,13 @@ func (lc *LightningChannel) ProcessChanSyncMsg(
 		closedCircuits []models.CircuitKey
 	)
 
+	// Before we retransmit anything, we make sure both parties use the
+	// same channel parameters, which isn't the case if the dyn_ack of an
+	// upgrade we proposed was lost.
+	if err := lc.syncDynCommitment(msg); err != nil {
+		return nil, nil, nil, err
+	}
+
 	// If the remote party included the optional fields, then we'll verify
 	// their correctness first, as it will influence our decisions below.
 	hasRecoveryOptions := msg.LocalUnrevokedCommitPoint != nil
@@ -4103,6 +4328,9 @@ func (lc *LightningChannel) ProcessChanSyncMsg(
 					CommitSig:  newCommit.CommitSig,
 					HtlcSigs:   newCommit.HtlcSigs,
 					PartialSig: newCommit.PartialSig,
+					SpliceSigs: lnwire.MaybeSpliceCommitSigs( //nolint:lll
+						newCommit.SpliceSigs,
+					),
 				}
 
 				updates = append(updates, commitSig)
@@ -4303,6 +4531,19 @@ func (lc *LightningChannel) computeView(view *htlcView,
 		theirBalance += lnwire.NewMSatFromSatoshis(
 			commitChain.tip().fee)
 	}
+
+	// The balance of the initiator of a commitment created before a
+	// dynamic commitment upgrade to anchors doesn't have the value of the
+	// anchors deducted yet.
+	anchorValue := lc.dynCommitAnchorValue(
+		whoseCommitChain, commitChain.tip().height,
+	)
+	if lc.channelState.IsInitiator {
+		ourBalance -= anchorValue
+	} else {
+		theirBalance -= anchorValue
+	}
+
 	nextHeight := commitChain.tip().height + 1
 
 	// Initiate feePerKw to the last committed fee for this chain as we'll
@@ -4704,6 +4945,12 @@ func (lc *LightningChannel) ReceiveNewCommitment(commitSigs *CommitSigs) error {
 		lc.log.Warnf("empty commit sig message received")
 	}
 
+	// The remote party may have signed the commitment before it switched
+	// over to a splice that we've already promoted, in which case the
+	// signatures for our current funding output are among the splice
+	// signatures.
+	commitSigs = lc.currentFundingSigs(commitSigs)
+
 	// Determine the last update on the local log that has been locked in.
 	localACKedIndex := lc.remoteCommitChain.tail().ourMessageIndex
 	localHtlcIndex := lc.remoteCommitChain.tail().ourHtlcIndex
@@ -4922,6 +5169,15 @@ func (lc *LightningChannel) ReceiveNewCommitment(commitSigs *CommitSigs) error {
 		}
 	}
 
+	// The variants of the new commitment that spend the funding outputs
+	// of pending splices must be signed as well.
+	err = lc.verifySpliceCommitSigs(
+		localCommitmentView, keyRing, commitSigs.SpliceSigs,
+	)
+	if err != nil {
+		return err
+	}
+
 	// The signature checks out, so we can now add the new commitment to
 	// our local commitment chain. For regular channels, we can just
 	// serialize the ECDSA sig. For taproot channels, we'll serialize the
@@ -5058,13 +5314,28 @@ func (lc *LightningChannel) oweCommitment(issuer lntypes.ChannelParty) bool {
 			lastLocalCommit.ourMessageIndex
 	}
 
+	// After a dynamic commitment upgrade, a commitment that uses the
+	// upgraded parameters is owed even if there are no updates.
+	upgradePending := fn.MapOptionZ(
+		lc.dynCommitEpoch, func(e channeldb.DynCommitEpoch) bool {
+			if issuer.IsLocal() {
+				return lastRemoteCommit.height <
+					e.RemoteCommitHeight
+			}
+
+			return lastLocalCommit.height < e.LocalCommitHeight
+		},
+	)
+
 	// If any of the conditions above is true, we owe a commitment
 	// signature.
-	oweCommitment := localUpdatesPending || remoteUpdatesPending
+	oweCommitment := localUpdatesPending || remoteUpdatesPending ||
+		upgradePending
 
 	lc.log.Tracef("%v owes commit: %v (local updates: %v, "+
-		"remote updates %v)", perspective, oweCommitment,
-		localUpdatesPending, remoteUpdatesPending)
+		"remote updates %v, upgrade pending %v)", perspective,
+		oweCommitment, localUpdatesPending, remoteUpdatesPending,
+		upgradePending)
 
 	return oweCommitment
 }
@@ -5080,6 +5351,28 @@ func (lc *LightningChannel) PendingLocalUpdateCount() uint64 {
 	return lc.localUpdateLog.logIndex - lastRemoteCommit.ourMessageIndex
 }
 
+// NumPendingUpdates returns the number of updates issued by the given party
+// that aren't yet irrevocably committed to both commitment transactions.
+func (lc *LightningChannel) NumPendingUpdates(
+	issuer lntypes.ChannelParty) uint64 {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	localTail := lc.localCommitChain.tail()
+	remoteTail := lc.remoteCommitChain.tail()
+
+	if issuer.IsLocal() {
+		return lc.localUpdateLog.logIndex - min(
+			localTail.ourMessageIndex, remoteTail.ourMessageIndex,
+		)
+	}
+
+	return lc.remoteUpdateLog.logIndex - min(
+		localTail.theirMessageIndex, remoteTail.theirMessageIndex,
+	)
+}
+
 // RevokeCurrentCommitment revokes the next lowest unrevoked commitment
 // transaction in the local commitment chain. As a result the edge of our
 // revocation window is extended by one, and the tail of our local commitment
@@ -5302,6 +5595,7 @@ func (lc *LightningChannel) ReceiveRevocation(revMsg *lnwire.RevokeAndAck) (
 				Expiry:        pd.Timeout,
 				PaymentHash:   pd.RHash,
 				BlindingPoint: pd.BlindingPoint,
+				CustomRecords: pd.CustomRecords,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -5632,6 +5926,63 @@ func (lc *LightningChannel) MayAddOutgoingHtlc(amt lnwire.MilliSatoshi) error {
 	return nil
 }
 
+// AvailableOutgoingHtlcSlots returns the number of outgoing htlcs that can
+// still be added to the channel before our pending updates reach the maximum
+// number of htlcs in flight. Only the slots are checked, so an htlc may still
+// be rejected for other reasons, like the value in flight or our balance.
+func (lc *LightningChannel) AvailableOutgoingHtlcSlots() (uint16, error) {
+	lc.RLock()
+	defer lc.RUnlock()
+
+	// Our htlcs have to fit on both commitments, so we take the smaller
+	// number of free slots of the two, using the same views as when
+	// validating an htlc that we add.
+	maxHtlcs := lc.channelState.LocalChanCfg.MaxAcceptedHtlcs
+	slots := maxHtlcs
+	views := []struct {
+		theirLogCounter uint64
+		whoseCommit     lntypes.ChannelParty
+	}{
+		{
+			theirLogCounter: lc.localCommitChain.tail().
+				theirMessageIndex,
+			whoseCommit: lntypes.Remote,
+		},
+		{
+			theirLogCounter: lc.remoteUpdateLog.logIndex,
+			whoseCommit:     lntypes.Local,
+		},
+	}
+	for _, v := range views {
+		view := lc.fetchHTLCView(
+			v.theirLogCounter, lc.localUpdateLog.logIndex,
+		)
+		_, _, _, filteredView, err := lc.computeView(
+			view, v.whoseCommit, false,
+			fn.None[chainfee.SatPerKWeight](),
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		var numInFlight uint16
+		for _, entry := range filteredView.ourUpdates {
+			if entry.EntryType == Add {
+				numInFlight++
+			}
+		}
+
+		if numInFlight >= maxHtlcs {
+			return 0, nil
+		}
+		if maxHtlcs-numInFlight < slots {
+			slots = maxHtlcs - numInFlight
+		}
+	}
+
+	return slots, nil
+}
+
 // htlcAddDescriptor returns a payment descriptor for the htlc and open key
 // provided to add to our local update log.
 func (lc *LightningChannel) htlcAddDescriptor(htlc *lnwire.UpdateAddHTLC,
@@ -5647,6 +5998,7 @@ func (lc *LightningChannel) htlcAddDescriptor(htlc *lnwire.UpdateAddHTLC,
 		OnionBlob:      htlc.OnionBlob[:],
 		OpenCircuitKey: openKey,
 		BlindingPoint:  htlc.BlindingPoint,
+		CustomRecords:  htlc.CustomRecords,
 	}
 }
 
@@ -5705,6 +6057,7 @@ func (lc *LightningChannel) ReceiveHTLC(htlc *lnwire.UpdateAddHTLC) (uint64, err
 		HtlcIndex:     lc.remoteUpdateLog.htlcCounter,
 		OnionBlob:     htlc.OnionBlob[:],
 		BlindingPoint: htlc.BlindingPoint,
+		CustomRecords: htlc.CustomRecords,
 	}
 
 	localACKedIndex := lc.remoteCommitChain.tail().ourMessageIndex
@@ -7314,6 +7667,18 @@ func NewLocalForceCloseSummary(chanState *channeldb.OpenChannel,
 // close process.
 type chanCloseOpt struct {
 	musigSession *MusigSession
+
+	// customSequence is an optional sequence of the funding input of the
+	// closing transaction.
+	customSequence fn.Option[uint32]
+
+	// customPayer is an optional party that pays the fee of the closing
+	// transaction instead of the channel initiator.
+	customPayer fn.Option[lntypes.ChannelParty]
+
+	// omittedOutput is an optional party whose output is left off the
+	// closing transaction, even if it isn't dust.
+	omittedOutput fn.Option[lntypes.ChannelParty]
 }
 
 // ChanCloseOpt is a closure type that cen be used to modify the set of default
@@ -7334,6 +7699,65 @@ func WithCoopCloseMusigSession(session *MusigSession) ChanCloseOpt {
 	}
 }
 
+// WithCustomCloseSequence can be used to set the sequence of the funding input
+// of the closing transaction.
+func WithCustomCloseSequence(sequence uint32) ChanCloseOpt {
+	return func(opts *chanCloseOpt) {
+		opts.customSequence = fn.Some(sequence)
+	}
+}
+
+// WithCustomPayer can be used to make the passed party pay the fee of the
+// closing transaction, rather than the channel initiator. As used by the
+// simple close protocol, this also allows a closing transaction to be signed
+// after the channel was closed, so it can be replaced by a higher fee one.
+func WithCustomPayer(payer lntypes.ChannelParty) ChanCloseOpt {
+	return func(opts *chanCloseOpt) {
+		opts.customPayer = fn.Some(payer)
+	}
+}
+
+// WithOmittedOutput can be used to leave the output of the passed party off
+// the closing transaction, in which case its balance goes to fees.
+func WithOmittedOutput(party lntypes.ChannelParty) ChanCloseOpt {
+	return func(opts *chanCloseOpt) {
+		opts.omittedOutput = fn.Some(party)
+	}
+}
+
+// feePayer returns the party that pays the fee of the closing transaction.
+func (o *chanCloseOpt) feePayer(isInitiator bool) lntypes.ChannelParty {
+	initiator := lntypes.Remote
+	if isInitiator {
+		initiator = lntypes.Local
+	}
+
+	return o.customPayer.UnwrapOr(initiator)
+}
+
+// closeTxOpts returns the options to create the closing transaction with.
+func (o *chanCloseOpt) closeTxOpts(
+	chanType channeldb.ChannelType) []CloseTxOpt {
+
+	var closeTxOpts []CloseTxOpt
+
+	// If this is a taproot channel, then we use an RBF'able funding input.
+	if chanType.IsTaproot() {
+		closeTxOpts = append(closeTxOpts, WithRBFCloseTx())
+	}
+
+	o.customSequence.WhenSome(func(sequence uint32) {
+		closeTxOpts = append(closeTxOpts, WithCustomTxInSequence(
+			sequence,
+		))
+	})
+	o.omittedOutput.WhenSome(func(party lntypes.ChannelParty) {
+		closeTxOpts = append(closeTxOpts, WithOmittedCloseOutput(party))
+	})
+
+	return closeTxOpts
+}
+
 // CreateCloseProposal is used by both parties in a cooperative channel close
 // workflow to generate proposed close transactions and signatures. This method
 // should only be executed once all pending HTLCs (if any) on the channel have
@@ -7352,39 +7776,35 @@ func (lc *LightningChannel) CreateCloseProposal(proposedFee btcutil.Amount,
 	lc.Lock()
 	defer lc.Unlock()
 
-	// If we're already closing the channel, then ignore this request.
-	if lc.isClosed {
-		// TODO(roasbeef): check to ensure no pending payments
-		return nil, nil, 0, ErrChanClosing
-	}
-
 	opts := defaultCloseOpts()
 	for _, optFunc := range closeOpts {
 		optFunc(opts)
 	}
 
+	// If we're already closing the channel, then ignore this request,
+	// unless the closing transaction may be replaced.
+	if lc.isClosed && opts.customPayer.IsNone() {
+		// TODO(roasbeef): check to ensure no pending payments
+		return nil, nil, 0, ErrChanClosing
+	}
+
 	// Get the final balances after subtracting the proposed fee, taking
 	// care not to persist the adjusted balance, as the feeRate may change
 	// during the channel closing process.
 	ourBalance, theirBalance, err := CoopCloseBalance(
 		lc.channelState.ChanType, lc.channelState.IsInitiator,
 		proposedFee, lc.channelState.LocalCommitment,
+		opts.feePayer(lc.channelState.IsInitiator),
 	)
 	if err != nil {
 		return nil, nil, 0, err
 	}
 
-	var closeTxOpts []CloseTxOpt
-
-	// If this is a taproot channel, then we use an RBF'able funding input.
-	if lc.channelState.ChanType.IsTaproot() {
-		closeTxOpts = append(closeTxOpts, WithRBFCloseTx())
-	}
-
 	closeTx := CreateCooperativeCloseTx(
 		fundingTxIn(lc.channelState), lc.channelState.LocalChanCfg.DustLimit,
 		lc.channelState.RemoteChanCfg.DustLimit, ourBalance, theirBalance,
-		localDeliveryScript, remoteDeliveryScript, closeTxOpts...,
+		localDeliveryScript, remoteDeliveryScript,
+		opts.closeTxOpts(lc.channelState.ChanType)...,
 	)
 
 	// Ensure that the transaction doesn't explicitly violate any
@@ -7436,40 +7856,37 @@ func (lc *LightningChannel) CompleteCooperativeClose(
 	lc.Lock()
 	defer lc.Unlock()
 
-	// If the channel is already closing, then ignore this request.
-	if lc.isClosed {
-		// TODO(roasbeef): check to ensure no pending payments
-		return nil, 0, ErrChanClosing
-	}
-
 	opts := defaultCloseOpts()
 	for _, optFunc := range closeOpts {
 		optFunc(opts)
 	}
 
+	// If the channel is already closing, then ignore this request, unless
+	// the closing transaction may be replaced.
+	if lc.isClosed && opts.customPayer.IsNone() {
+		// TODO(roasbeef): check to ensure no pending payments
+		return nil, 0, ErrChanClosing
+	}
+
 	// Get the final balances after subtracting the proposed fee.
 	ourBalance, theirBalance, err := CoopCloseBalance(
 		lc.channelState.ChanType, lc.channelState.IsInitiator,
 		proposedFee, lc.channelState.LocalCommitment,
+		opts.feePayer(lc.channelState.IsInitiator),
 	)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	var closeTxOpts []CloseTxOpt
-
-	// If this is a taproot channel, then we use an RBF'able funding input.
-	if lc.channelState.ChanType.IsTaproot() {
-		closeTxOpts = append(closeTxOpts, WithRBFCloseTx())
-	}
-
 	// Create the transaction used to return the current settled balance
-	// on this active channel back to both parties. In this current model,
-	// the initiator pays full fees for the cooperative close transaction.
+	// on this active channel back to both parties. Unless a custom payer
+	// is set, the initiator pays full fees for the cooperative close
+	// transaction.
 	closeTx := CreateCooperativeCloseTx(
 		fundingTxIn(lc.channelState), lc.channelState.LocalChanCfg.DustLimit,
 		lc.channelState.RemoteChanCfg.DustLimit, ourBalance, theirBalance,
-		localDeliveryScript, remoteDeliveryScript, closeTxOpts...,
+		localDeliveryScript, remoteDeliveryScript,
+		opts.closeTxOpts(lc.channelState.ChanType)...,
 	)
 
 	// Ensure that the transaction doesn't explicitly validate any
@@ -8166,6 +8583,14 @@ type closeTxOpts struct {
 	// enableRBF indicates whether the cooperative close tx should signal
 	// RBF or not.
 	enableRBF bool
+
+	// customSequence is an optional sequence of the funding input, which
+	// takes precedence over enableRBF.
+	customSequence fn.Option[uint32]
+
+	// omittedOutput is an optional party whose output is left off the
+	// cooperative close tx.
+	omittedOutput fn.Option[lntypes.ChannelParty]
 }
 
 // defaultCloseTxOpts returns a closeTxOpts struct with default values.
@@ -8186,6 +8611,22 @@ func WithRBFCloseTx() CloseTxOpt {
 	}
 }
 
+// WithCustomTxInSequence sets the sequence of the funding input of the
+// cooperative close tx.
+func WithCustomTxInSequence(sequence uint32) CloseTxOpt {
+	return func(o *closeTxOpts) {
+		o.customSequence = fn.Some(sequence)
+	}
+}
+
+// WithOmittedCloseOutput leaves the output of the passed party off the
+// cooperative close tx.
+func WithOmittedCloseOutput(party lntypes.ChannelParty) CloseTxOpt {
+	return func(o *closeTxOpts) {
+		o.omittedOutput = fn.Some(party)
+	}
+}
+
 // CreateCooperativeCloseTx creates a transaction which if signed by both
 // parties, then broadcast cooperatively closes an active channel. The creation
 // of the closure transaction is modified by a boolean indicating if the party
@@ -8207,6 +8648,9 @@ func CreateCooperativeCloseTx(fundingTxIn wire.TxIn,
 	if opts.enableRBF {
 		fundingTxIn.Sequence = mempool.MaxRBFSequence
 	}
+	opts.customSequence.WhenSome(func(sequence uint32) {
+		fundingTxIn.Sequence = sequence
+	})
 
 	// Construct the transaction to perform a cooperative closure of the
 	// channel. In the event that one side doesn't have any settled funds
@@ -8217,13 +8661,20 @@ func CreateCooperativeCloseTx(fundingTxIn wire.TxIn,
 
 	// Create both cooperative closure outputs, properly respecting the
 	// dust limits of both parties.
-	if ourBalance >= localDust {
+	omitted := func(party lntypes.ChannelParty) bool {
+		return fn.MapOptionZ(
+			opts.omittedOutput, func(p lntypes.ChannelParty) bool {
+				return p == party
+			},
+		)
+	}
+	if ourBalance >= localDust && !omitted(lntypes.Local) {
 		closeTx.AddTxOut(&wire.TxOut{
 			PkScript: ourDeliveryScript,
 			Value:    int64(ourBalance),
 		})
 	}
-	if theirBalance >= remoteDust {
+	if theirBalance >= remoteDust && !omitted(lntypes.Remote) {
 		closeTx.AddTxOut(&wire.TxOut{
 			PkScript: theirDeliveryScript,
 			Value:    int64(theirBalance),
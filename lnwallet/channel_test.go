@@ -9578,6 +9578,50 @@ func TestMayAddOutgoingHtlc(t *testing.T) {
 	require.NoError(t, aliceChannel.MayAddOutgoingHtlc(0))
 }
 
+// TestAvailableOutgoingHtlcSlots tests that the free outgoing htlc slots of a
+// channel shrink with each htlc that we add, and that the htlcs of the remote
+// party don't take up our slots.
+func TestAvailableOutgoingHtlcSlots(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, err := CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit,
+	)
+	require.NoError(t, err)
+
+	maxHtlcs := aliceChannel.channelState.LocalChanCfg.MaxAcceptedHtlcs
+	slots, err := aliceChannel.AvailableOutgoingHtlcSlots()
+	require.NoError(t, err)
+	require.Equal(t, maxHtlcs, slots)
+
+	// Each htlc that Alice adds takes one of her slots, even before it is
+	// committed to.
+	for i := 0; i < 2; i++ {
+		htlc, _ := createHTLC(i, lnwire.MilliSatoshi(5000000))
+		_, err = aliceChannel.AddHTLC(htlc, nil)
+		require.NoError(t, err)
+		_, err = bobChannel.ReceiveHTLC(htlc)
+		require.NoError(t, err)
+	}
+
+	slots, err = aliceChannel.AvailableOutgoingHtlcSlots()
+	require.NoError(t, err)
+	require.Equal(t, maxHtlcs-2, slots)
+
+	// The htlcs that Bob received don't take up his outgoing slots.
+	slots, err = bobChannel.AvailableOutgoingHtlcSlots()
+	require.NoError(t, err)
+	require.Equal(
+		t, bobChannel.channelState.LocalChanCfg.MaxAcceptedHtlcs, slots,
+	)
+
+	// Once all slots are taken, none are left.
+	aliceChannel.channelState.LocalChanCfg.MaxAcceptedHtlcs = 2
+	slots, err = aliceChannel.AvailableOutgoingHtlcSlots()
+	require.NoError(t, err)
+	require.Zero(t, slots)
+}
+
 // TestIsChannelClean tests that IsChannelClean returns the expected values
 // in different channel states.
 func TestIsChannelClean(t *testing.T) {
@@ -9925,7 +9969,7 @@ func TestCreateHtlcRetribution(t *testing.T) {
 
 	// Create a dummy private key and an HTLC amount for testing.
 	dummyPrivate, _ := btcec.PrivKeyFromBytes([]byte{1})
-	testAmt := btcutil.Amount(100)
+	testAmt := btcutil.Amount(100_000)
 
 	// Create a test channel.
 	aliceChannel, _, err := CreateTestChannels(
@@ -9946,9 +9990,10 @@ func TestCreateHtlcRetribution(t *testing.T) {
 	}
 
 	// Create the htlc retribution.
+	feePerKw := fn.Some(chainfee.SatPerKWeight(1000))
 	hr, err := createHtlcRetribution(
 		aliceChannel.channelState, keyRing, commitHash,
-		dummyPrivate, leaseExpiry, htlc,
+		dummyPrivate, leaseExpiry, feePerKw, htlc,
 	)
 	// Expect no error.
 	require.NoError(t, err)
@@ -9958,6 +10003,48 @@ func TestCreateHtlcRetribution(t *testing.T) {
 	require.Equal(t, commitHash, hr.OutPoint.Hash)
 	require.EqualValues(t, htlc.OutputIndex, hr.OutPoint.Index)
 	require.Equal(t, htlc.Incoming, hr.IsIncoming)
+
+	// As the channel has no anchors, the second level transaction is
+	// rebuilt from the fee rate of the commitment.
+	require.NotNil(t, hr.SecondLevelTx)
+	require.Len(t, hr.SecondLevelTx.TxIn, 1)
+	require.Equal(
+		t, hr.OutPoint, hr.SecondLevelTx.TxIn[0].PreviousOutPoint,
+	)
+	require.EqualValues(t, htlc.RefundTimeout, hr.SecondLevelTx.LockTime)
+
+	htlcFee := HtlcTimeoutFee(
+		aliceChannel.channelState.ChanType, feePerKw.UnwrapOr(0),
+	)
+	require.EqualValues(
+		t, testAmt-htlcFee, hr.SecondLevelTx.TxOut[0].Value,
+	)
+
+	// Without the fee rate, the second level transaction can't be rebuilt.
+	hr, err = createHtlcRetribution(
+		aliceChannel.channelState, keyRing, commitHash,
+		dummyPrivate, leaseExpiry, fn.None[chainfee.SatPerKWeight](),
+		htlc,
+	)
+	require.NoError(t, err)
+	require.Nil(t, hr.SecondLevelTx)
+
+	// Neither can the second level transactions of anchor channels.
+	anchorChannel, _, err := CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit|
+			channeldb.AnchorOutputsBit|channeldb.ZeroHtlcTxFeeBit,
+	)
+	require.NoError(t, err)
+
+	leaseExpiry, keyRing, commitHash = deriveDummyRetributionParams(
+		anchorChannel.channelState,
+	)
+	hr, err = createHtlcRetribution(
+		anchorChannel.channelState, keyRing, commitHash,
+		dummyPrivate, leaseExpiry, feePerKw, htlc,
+	)
+	require.NoError(t, err)
+	require.Nil(t, hr.SecondLevelTx)
 }
 
 // TestCreateBreachRetribution checks that `createBreachRetribution` behaves as
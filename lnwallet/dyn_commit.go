@@ -0,0 +1,430 @@
+package lnwallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// ErrDynCommitUnsupportedChanType is returned when a dynamic
+	// commitment upgrade is attempted for a channel type, or to a channel
+	// type, that can't be upgraded to without changing the funding output.
+	ErrDynCommitUnsupportedChanType = errors.New("channel type can't be " +
+		"upgraded dynamically")
+
+	// ErrDynCommitChannelNotSynced is returned when a dynamic commitment
+	// upgrade is attempted for a channel that has pending updates or
+	// unrevoked commitments.
+	ErrDynCommitChannelNotSynced = errors.New("channel must be quiescent " +
+		"to be upgraded")
+
+	// ErrDynCommitKeysChanged is returned when a dynamic commitment upgrade
+	// changes any of the keys of the channel.
+	ErrDynCommitKeysChanged = errors.New("channel keys can't be changed " +
+		"dynamically")
+
+	// ErrDynCommitHeightMismatch is returned when the remote party reports
+	// a number of dynamic commitment upgrades during channel
+	// reestablishment that we can't reconcile with ours.
+	ErrDynCommitHeightMismatch = errors.New("dynamic commitment height " +
+		"mismatch")
+)
+
+// commitFormatBits are the bits of a channel type that determine the format of
+// its commitment transactions. These are the only bits that may be changed by
+// a dynamic commitment upgrade.
+const commitFormatBits = channeldb.SingleFunderTweaklessBit |
+	channeldb.AnchorOutputsBit | channeldb.ZeroHtlcTxFeeBit
+
+// ValidateDynCommitChanType returns an error if a channel of the current type
+// can't be upgraded to the new type in place. The funding output of the
+// channel is kept, so only upgrades to a newer commitment format that spends
+// a regular funding output are supported. Upgrades to or from taproot channels
+// aren't supported, as they need a new MuSig2 funding output that the
+// channel would first have to be moved to on chain.
+func ValidateDynCommitChanType(current, next channeldb.ChannelType) error {
+	switch {
+	// Taproot channels use a different funding output.
+	case current.IsTaproot(), next.IsTaproot():
+		return fmt.Errorf("%w: taproot channels need a new funding "+
+			"output", ErrDynCommitUnsupportedChanType)
+
+	// Leased channels have their lease expiry enforced by the commitment
+	// scripts.
+	case current.HasLeaseExpiration(), next.HasLeaseExpiration():
+		return fmt.Errorf("%w: leased channels can't be upgraded",
+			ErrDynCommitUnsupportedChanType)
+
+	// Only the commitment format may change.
+	case current&^commitFormatBits != next&^commitFormatBits:
+		return fmt.Errorf("%w: only the commitment format can be "+
+			"changed", ErrDynCommitUnsupportedChanType)
+
+	// Any new commitment format must be tweakless, and anchor outputs are
+	// only supported with zero-fee HTLC transactions.
+	case !next.IsTweakless(),
+		next.HasAnchors() != next.ZeroHtlcTxFee():
+
+		return fmt.Errorf("%w: invalid commitment format",
+			ErrDynCommitUnsupportedChanType)
+
+	// Channels can't be downgraded to an older commitment format.
+	case current.HasAnchors() && !next.HasAnchors():
+		return fmt.Errorf("%w: anchors can't be removed",
+			ErrDynCommitUnsupportedChanType)
+	}
+
+	return nil
+}
+
+// DynCommitChanType returns the type a channel of the current type has after a
+// dynamic commitment upgrade to the commitment format of the passed channel
+// type. Only upgrades to static remote key and zero-fee HTLC anchor
+// commitments are supported.
+func DynCommitChanType(current channeldb.ChannelType,
+	next lnwire.ChannelType) (channeldb.ChannelType, error) {
+
+	features := lnwire.RawFeatureVector(next)
+
+	var format channeldb.ChannelType
+	switch {
+	case features.OnlyContains(lnwire.StaticRemoteKeyRequired):
+		format = channeldb.SingleFunderTweaklessBit
+
+	case features.OnlyContains(
+		lnwire.AnchorsZeroFeeHtlcTxRequired,
+		lnwire.StaticRemoteKeyRequired,
+	):
+		format = channeldb.SingleFunderTweaklessBit |
+			channeldb.AnchorOutputsBit | channeldb.ZeroHtlcTxFeeBit
+
+	default:
+		return 0, ErrDynCommitUnsupportedChanType
+	}
+
+	chanType := current&^commitFormatBits | format
+	if err := ValidateDynCommitChanType(current, chanType); err != nil {
+		return 0, err
+	}
+
+	return chanType, nil
+}
+
+// sameChanKeys returns true if both channel configs use the same keys.
+func sameChanKeys(a, b *channeldb.ChannelConfig) bool {
+	return a.MultiSigKey.PubKey.IsEqual(b.MultiSigKey.PubKey) &&
+		a.RevocationBasePoint.PubKey.IsEqual(
+			b.RevocationBasePoint.PubKey,
+		) &&
+		a.PaymentBasePoint.PubKey.IsEqual(b.PaymentBasePoint.PubKey) &&
+		a.DelayBasePoint.PubKey.IsEqual(b.DelayBasePoint.PubKey) &&
+		a.HtlcBasePoint.PubKey.IsEqual(b.HtlcBasePoint.PubKey)
+}
+
+// commitCost returns the amount the channel initiator pays for a commitment of
+// the given type, which is the commitment fee plus the value of any anchors.
+// All HTLCs are assumed to be above the dust limit.
+func commitCost(chanType channeldb.ChannelType, feePerKw chainfee.SatPerKWeight,
+	numHtlcs int) btcutil.Amount {
+
+	weight := CommitWeight(chanType) +
+		lntypes.WeightUnit(numHtlcs)*input.HTLCWeight
+	cost := feePerKw.FeeForWeight(weight)
+
+	if chanType.HasAnchors() {
+		cost += 2 * AnchorSize
+	}
+
+	return cost
+}
+
+// ValidateDynCommitment returns an error if the channel can't be upgraded to
+// the passed channel type and channel configs in its current state.
+func (lc *LightningChannel) ValidateDynCommitment(
+	chanType channeldb.ChannelType, localCfg,
+	remoteCfg channeldb.ChannelConfig) error {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	return lc.validateDynCommitment(chanType, &localCfg, &remoteCfg)
+}
+
+// validateDynCommitment returns an error if the channel can't be upgraded to
+// the passed channel type and channel configs.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) validateDynCommitment(
+	chanType channeldb.ChannelType, localCfg,
+	remoteCfg *channeldb.ChannelConfig) error {
+
+	chanState := lc.channelState
+
+	err := ValidateDynCommitChanType(chanState.ChanType, chanType)
+	if err != nil {
+		return err
+	}
+
+	if !sameChanKeys(&chanState.LocalChanCfg, localCfg) ||
+		!sameChanKeys(&chanState.RemoteChanCfg, remoteCfg) {
+
+		return ErrDynCommitKeysChanged
+	}
+
+	if lc.localCommitChain.hasUnackedCommitment() ||
+		lc.remoteCommitChain.hasUnackedCommitment() ||
+		lc.oweCommitment(lntypes.Local) ||
+		lc.oweCommitment(lntypes.Remote) {
+
+		return ErrDynCommitChannelNotSynced
+	}
+
+	// A new commitment format may increase the amount the channel
+	// initiator pays for the commitment, which it must be able to afford
+	// without dipping below its reserve.
+	localCommit := lc.localCommitChain.tip()
+	feePerKw := localCommit.feePerKw
+	numHtlcs := len(localCommit.incomingHTLCs) +
+		len(localCommit.outgoingHTLCs)
+
+	prevCost := commitCost(chanState.ChanType, feePerKw, numHtlcs)
+	nextCost := commitCost(chanType, feePerKw, numHtlcs)
+	if nextCost <= prevCost {
+		return nil
+	}
+
+	initiatorBalance := localCommit.theirBalance.ToSatoshis()
+	initiatorReserve := remoteCfg.ChanReserve
+	if chanState.IsInitiator {
+		initiatorBalance = localCommit.ourBalance.ToSatoshis()
+		initiatorReserve = localCfg.ChanReserve
+	}
+
+	if initiatorBalance+prevCost < nextCost+initiatorReserve {
+		return fmt.Errorf("channel initiator can't afford commitment "+
+			"of type %v: balance=%v, cost=%v, reserve=%v",
+			chanType, initiatorBalance, nextCost, initiatorReserve)
+	}
+
+	return nil
+}
+
+// ApplyDynCommitment upgrades the channel to the passed channel type and
+// channel configs, which both parties agreed on in a dynamic commitment
+// negotiation. The upgraded parameters apply to the next commitment of either
+// party, which both parties owe each other from now on, even without any
+// updates.
+//
+// NOTE: The channel must be quiescent.
+func (lc *LightningChannel) ApplyDynCommitment(
+	chanType channeldb.ChannelType, localCfg,
+	remoteCfg channeldb.ChannelConfig) error {
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	err := lc.validateDynCommitment(chanType, &localCfg, &remoteCfg)
+	if err != nil {
+		return err
+	}
+
+	epoch := channeldb.DynCommitEpoch{
+		ChanType:           lc.channelState.ChanType,
+		LocalChanCfg:       lc.channelState.LocalChanCfg,
+		RemoteChanCfg:      lc.channelState.RemoteChanCfg,
+		LocalCommitHeight:  lc.localCommitChain.tip().height + 1,
+		RemoteCommitHeight: lc.remoteCommitChain.tip().height + 1,
+	}
+
+	err = lc.channelState.ApplyDynCommitment(chanType, localCfg, remoteCfg)
+	if err != nil {
+		return err
+	}
+
+	lc.dynCommitEpoch = fn.Some(epoch)
+
+	lc.log.Infof("Applied dynamic commitment upgrade to type %v, "+
+		"local_csv_delay=%v, remote_csv_delay=%v", chanType,
+		localCfg.CsvDelay, remoteCfg.CsvDelay)
+
+	return nil
+}
+
+// ProposeDynCommitment records the channel type and channel configs of a
+// dynamic commitment upgrade we propose to the remote party. If the remote
+// party accepts the proposal, but its dyn_ack is lost, the upgrade is applied
+// once the channel is reestablished.
+//
+// NOTE: The channel must be quiescent.
+func (lc *LightningChannel) ProposeDynCommitment(
+	chanType channeldb.ChannelType, localCfg,
+	remoteCfg channeldb.ChannelConfig) error {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	err := lc.validateDynCommitment(chanType, &localCfg, &remoteCfg)
+	if err != nil {
+		return err
+	}
+
+	return lc.channelState.PutDynCommitProposal(
+		&channeldb.DynCommitProposal{
+			ChanType:      chanType,
+			LocalChanCfg:  localCfg,
+			RemoteChanCfg: remoteCfg,
+		},
+	)
+}
+
+// CancelDynCommitment removes the dynamic commitment upgrade we proposed,
+// once the remote party rejected it.
+func (lc *LightningChannel) CancelDynCommitment() error {
+	return lc.channelState.DeleteDynCommitProposal()
+}
+
+// syncDynCommitment reconciles the dynamic commitment upgrades of the channel
+// with the number of upgrades the remote party reported in its
+// channel_reestablish message. The responder of an upgrade applies it before
+// it sends dyn_ack, so if that message is lost, the responder is one upgrade
+// ahead of us and we apply the upgrade we proposed now.
+func (lc *LightningChannel) syncDynCommitment(
+	msg *lnwire.ChannelReestablish) error {
+
+	epochs, err := lc.channelState.DynCommitEpochs()
+	if err != nil {
+		return err
+	}
+	localHeight := uint64(len(epochs))
+
+	remoteHeight := uint64(msg.DynHeight.UnwrapOr(0))
+
+	proposal, err := lc.channelState.DynCommitProposal()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	// Both parties use the same parameters, so any proposal of ours was
+	// either rejected or never received, and we'll drop it.
+	case remoteHeight == localHeight:
+		if proposal.IsNone() {
+			return nil
+		}
+
+		lc.log.Infof("Dropping dynamic commitment proposal that " +
+			"wasn't accepted")
+
+		return lc.CancelDynCommitment()
+
+	// The remote party accepted our last proposal, but we never received
+	// its dyn_ack, so we apply the upgrade before any commitment with the
+	// new parameters is retransmitted.
+	case remoteHeight == localHeight+1 && proposal.IsSome():
+		p := proposal.UnsafeFromSome()
+
+		lc.log.Infof("Applying dynamic commitment upgrade whose " +
+			"dyn_ack was lost")
+
+		return lc.ApplyDynCommitment(
+			p.ChanType, p.LocalChanCfg, p.RemoteChanCfg,
+		)
+
+	// We accepted the last proposal of the remote party, which it didn't
+	// know yet when it sent its channel_reestablish. It applies the
+	// upgrade once it receives ours.
+	case remoteHeight+1 == localHeight:
+		return nil
+
+	default:
+		return fmt.Errorf("%w: local=%v, remote=%v",
+			ErrDynCommitHeightMismatch, localHeight, remoteHeight)
+	}
+}
+
+// OweDynCommitment returns true if we owe the remote party its first
+// commitment that uses the parameters of a dynamic commitment upgrade, which
+// is the case even if there are no updates to sign.
+func (lc *LightningChannel) OweDynCommitment() bool {
+	lc.RLock()
+	defer lc.RUnlock()
+
+	remoteHeight := lc.remoteCommitChain.tip().height
+
+	return fn.MapOptionZ(
+		lc.dynCommitEpoch, func(e channeldb.DynCommitEpoch) bool {
+			return remoteHeight < e.RemoteCommitHeight
+		},
+	)
+}
+
+// restoreDynCommitEpoch restores the epoch of the latest dynamic commitment
+// upgrade, if the first commitment using the upgraded parameters hasn't been
+// created yet for either party.
+func (lc *LightningChannel) restoreDynCommitEpoch() error {
+	epochs, err := lc.channelState.DynCommitEpochs()
+	switch {
+	// A channel that isn't persisted yet, like one that is still being
+	// funded, can't have been upgraded.
+	case errors.Is(err, channeldb.ErrNoChanDBExists),
+		errors.Is(err, channeldb.ErrNoActiveChannels),
+		errors.Is(err, channeldb.ErrChannelNotFound):
+
+		return nil
+
+	case err != nil:
+		return err
+	}
+
+	if len(epochs) == 0 {
+		return nil
+	}
+
+	epoch := epochs[len(epochs)-1]
+	if lc.localCommitChain.tip().height >= epoch.LocalCommitHeight &&
+		lc.remoteCommitChain.tip().height >= epoch.RemoteCommitHeight {
+
+		return nil
+	}
+
+	lc.dynCommitEpoch = fn.Some(*epoch)
+
+	return nil
+}
+
+// dynCommitAnchorValue returns the value of the anchors that still needs to be
+// deducted from the initiator's balance of the commitment at the given height,
+// which is the case if the commitment was created before a dynamic commitment
+// upgrade to anchor outputs.
+func (lc *LightningChannel) dynCommitAnchorValue(
+	whoseCommitChain lntypes.ChannelParty,
+	height uint64) lnwire.MilliSatoshi {
+
+	if !lc.channelState.ChanType.HasAnchors() {
+		return 0
+	}
+
+	return fn.MapOptionZ(
+		lc.dynCommitEpoch,
+		func(e channeldb.DynCommitEpoch) lnwire.MilliSatoshi {
+			endHeight := e.RemoteCommitHeight
+			if whoseCommitChain.IsLocal() {
+				endHeight = e.LocalCommitHeight
+			}
+
+			if e.ChanType.HasAnchors() || height >= endHeight {
+				return 0
+			}
+
+			return lnwire.NewMSatFromSatoshis(2 * AnchorSize)
+		},
+	)
+}
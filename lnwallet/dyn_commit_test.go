@@ -0,0 +1,193 @@
+package lnwallet
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateDynCommitChanType tests that only upgrades to a newer
+// commitment format are accepted.
+func TestValidateDynCommitChanType(t *testing.T) {
+	t.Parallel()
+
+	const (
+		legacy  = channeldb.SingleFunderBit
+		tweak   = channeldb.SingleFunderTweaklessBit
+		anchors = tweak | channeldb.AnchorOutputsBit |
+			channeldb.ZeroHtlcTxFeeBit
+		taproot = anchors | channeldb.SimpleTaprootFeatureBit
+	)
+
+	testCases := []struct {
+		name    string
+		current channeldb.ChannelType
+		next    channeldb.ChannelType
+		valid   bool
+	}{
+		{"legacy to tweakless", legacy, tweak, true},
+		{"legacy to anchors", legacy, anchors, true},
+		{"tweakless to anchors", tweak, anchors, true},
+		{"anchors to anchors", anchors, anchors, true},
+		{"anchors to tweakless", anchors, tweak, false},
+		{"tweakless to legacy", tweak, legacy, false},
+		{
+			"anchors without zero fee htlcs", tweak,
+			tweak | channeldb.AnchorOutputsBit, false,
+		},
+		{"anchors to taproot", anchors, taproot, false},
+		{
+			"scid alias changed", tweak,
+			anchors | channeldb.ScidAliasChanBit, false,
+		},
+		{
+			"lease", tweak | channeldb.LeaseExpirationBit,
+			anchors | channeldb.LeaseExpirationBit, false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDynCommitChanType(tc.current, tc.next)
+			if tc.valid {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrDynCommitUnsupportedChanType)
+		})
+	}
+}
+
+// TestDynCommitChanType tests the conversion of the channel type of a dynamic
+// commitment proposal.
+func TestDynCommitChanType(t *testing.T) {
+	t.Parallel()
+
+	current := channeldb.SingleFunderBit | channeldb.ScidAliasChanBit
+
+	next, err := DynCommitChanType(current, lnwire.ChannelType(
+		*lnwire.NewRawFeatureVector(
+			lnwire.StaticRemoteKeyRequired,
+			lnwire.AnchorsZeroFeeHtlcTxRequired,
+		),
+	))
+	require.NoError(t, err)
+	require.Equal(t, channeldb.SingleFunderTweaklessBit|
+		channeldb.AnchorOutputsBit|channeldb.ZeroHtlcTxFeeBit|
+		channeldb.ScidAliasChanBit, next)
+
+	_, err = DynCommitChanType(current, lnwire.ChannelType(
+		*lnwire.NewRawFeatureVector(
+			lnwire.SimpleTaprootChannelsRequiredStaging,
+		),
+	))
+	require.ErrorIs(t, err, ErrDynCommitUnsupportedChanType)
+}
+
+// TestApplyDynCommitment tests that a legacy channel is upgraded to anchor
+// commitments in place, and that revoked states created before the upgrade
+// are still punished with the parameters they were created with.
+func TestApplyDynCommitment(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, err := CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit,
+	)
+	require.NoError(t, err)
+
+	oldBobDelay := aliceChannel.channelState.RemoteChanCfg.CsvDelay
+	breachTx := aliceChannel.channelState.RemoteCommitment.CommitTx
+	require.Len(t, breachTx.TxOut, 2)
+
+	// Pending updates must be committed before the channel is upgraded.
+	htlc, _ := createHTLC(0, lnwire.NewMSatFromSatoshis(10_000))
+	_, err = aliceChannel.AddHTLC(htlc, nil)
+	require.NoError(t, err)
+	_, err = bobChannel.ReceiveHTLC(htlc)
+	require.NoError(t, err)
+
+	nextType := channeldb.SingleFunderTweaklessBit |
+		channeldb.AnchorOutputsBit | channeldb.ZeroHtlcTxFeeBit
+	err = aliceChannel.ValidateDynCommitment(
+		nextType, aliceChannel.channelState.LocalChanCfg,
+		aliceChannel.channelState.RemoteChanCfg,
+	)
+	require.ErrorIs(t, err, ErrDynCommitChannelNotSynced)
+
+	require.NoError(t, ForceStateTransition(aliceChannel, bobChannel))
+
+	// Alice upgrades the channel to anchors, and increases the delay of
+	// Bob's outputs.
+	aliceLocalCfg := aliceChannel.channelState.LocalChanCfg
+	aliceRemoteCfg := aliceChannel.channelState.RemoteChanCfg
+	aliceRemoteCfg.CsvDelay = oldBobDelay + 10
+
+	bobLocalCfg := bobChannel.channelState.LocalChanCfg
+	bobRemoteCfg := bobChannel.channelState.RemoteChanCfg
+	bobLocalCfg.CsvDelay = oldBobDelay + 10
+
+	// The keys of the channel can't be changed.
+	badCfg := aliceLocalCfg
+	badCfg.MultiSigKey = aliceRemoteCfg.MultiSigKey
+	err = aliceChannel.ApplyDynCommitment(nextType, badCfg, aliceRemoteCfg)
+	require.ErrorIs(t, err, ErrDynCommitKeysChanged)
+
+	err = aliceChannel.ApplyDynCommitment(
+		nextType, aliceLocalCfg, aliceRemoteCfg,
+	)
+	require.NoError(t, err)
+	err = bobChannel.ApplyDynCommitment(nextType, bobLocalCfg, bobRemoteCfg)
+	require.NoError(t, err)
+
+	// Both parties owe each other a commitment with the new parameters,
+	// even though there are no updates.
+	require.True(t, aliceChannel.OweCommitment())
+	require.True(t, bobChannel.OweCommitment())
+
+	require.NoError(t, ForceStateTransition(aliceChannel, bobChannel))
+	require.False(t, aliceChannel.OweCommitment())
+	require.False(t, bobChannel.OweCommitment())
+
+	// The new commitments have anchors, and the parameters survive a
+	// restart.
+	for _, channel := range []*LightningChannel{aliceChannel, bobChannel} {
+		chanState := channel.channelState
+		require.NoError(t, chanState.Refresh())
+		require.Equal(t, nextType, chanState.ChanType)
+
+		commitTx := chanState.LocalCommitment.CommitTx
+		var numAnchors int
+		for _, txOut := range commitTx.TxOut {
+			if txOut.Value == int64(AnchorSize) {
+				numAnchors++
+			}
+		}
+		require.Equal(t, 2, numAnchors)
+	}
+	require.Equal(
+		t, oldBobDelay+10,
+		aliceChannel.channelState.RemoteChanCfg.CsvDelay,
+	)
+
+	// Bob's first commitment was revoked before the upgrade, so its
+	// breach retribution uses the legacy format and Bob's old delay.
+	br, err := NewBreachRetribution(
+		aliceChannel.channelState, 0, 100, breachTx,
+	)
+	require.NoError(t, err)
+	require.Equal(t, breachTx.TxHash(), br.BreachTxHash)
+	require.EqualValues(t, oldBobDelay, br.RemoteDelay)
+	require.Zero(t, br.LocalDelay)
+	require.NotNil(t, br.LocalOutputSignDesc)
+	require.NotNil(t, br.RemoteOutputSignDesc)
+
+	epoch, err := aliceChannel.channelState.DynCommitEpochAt(
+		lntypes.Remote, 0,
+	)
+	require.NoError(t, err)
+	require.True(t, epoch.IsSome())
+}
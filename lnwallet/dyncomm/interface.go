@@ -0,0 +1,39 @@
+package dyncomm
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// Channel abstracts away from the core channel state machine by exposing an
+// interface that requires only the methods we need to carry out a dynamic
+// commitment upgrade.
+type Channel interface {
+	// ChannelPoint returns the channel point of the target channel.
+	ChannelPoint() wire.OutPoint
+
+	// State returns the persistent state of the channel.
+	State() *channeldb.OpenChannel
+
+	// ValidateDynCommitment returns an error if the channel can't be
+	// upgraded to the passed channel type and channel configs in its
+	// current state.
+	ValidateDynCommitment(chanType channeldb.ChannelType, localCfg,
+		remoteCfg channeldb.ChannelConfig) error
+
+	// ProposeDynCommitment records the channel type and channel configs
+	// of an upgrade we propose, so it can still be applied if the remote
+	// party's acceptance is lost.
+	ProposeDynCommitment(chanType channeldb.ChannelType, localCfg,
+		remoteCfg channeldb.ChannelConfig) error
+
+	// CancelDynCommitment removes the upgrade we proposed once the remote
+	// party rejected it.
+	CancelDynCommitment() error
+
+	// ApplyDynCommitment upgrades the channel to the passed channel type
+	// and channel configs, which apply to the next commitment of either
+	// party.
+	ApplyDynCommitment(chanType channeldb.ChannelType, localCfg,
+		remoteCfg channeldb.ChannelConfig) error
+}
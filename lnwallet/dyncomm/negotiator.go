@@ -0,0 +1,557 @@
+package dyncomm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// minNumHtlcs is the smallest max_accepted_htlcs value we accept, which
+	// matches the limit enforced when the channel was opened.
+	minNumHtlcs = 5
+
+	// maxNumHtlcs is the largest max_accepted_htlcs value allowed by
+	// BOLT-02.
+	maxNumHtlcs = input.MaxHTLCNumber / 2
+)
+
+var (
+	// ErrNegotiationInProgress is returned when a dynamic commitment
+	// negotiation is initiated while another one is still in progress.
+	ErrNegotiationInProgress = errors.New("dynamic commitment " +
+		"negotiation already in progress")
+
+	// ErrEmptyProposal is returned when a dynamic commitment upgrade is
+	// initiated without changing any of the channel's parameters.
+	ErrEmptyProposal = errors.New("dynamic commitment proposal doesn't " +
+		"change any parameters")
+
+	// ErrProposalRejected is returned when the remote party rejects our
+	// dynamic commitment proposal.
+	ErrProposalRejected = errors.New("dynamic commitment proposal " +
+		"rejected")
+
+	// ErrInvalidProposal is returned when a dynamic commitment proposal
+	// we initiate contains parameters we'd reject ourselves.
+	ErrInvalidProposal = errors.New("invalid dynamic commitment proposal")
+)
+
+// paramNames are the names of the parameters of a DynPropose message, indexed
+// by the TLV type of their records, which are the bits of a DynReject message.
+var paramNames = []string{
+	lnwire.DPDustLimitSatoshis:        "dust_limit",
+	lnwire.DPMaxHtlcValueInFlightMsat: "max_value_in_flight",
+	lnwire.DPChannelReserveSatoshis:   "channel_reserve",
+	lnwire.DPToSelfDelay:              "csv_delay",
+	lnwire.DPMaxAcceptedHtlcs:         "max_accepted_htlcs",
+	lnwire.DPFundingPubkey:            "funding_key",
+	lnwire.DPChannelType:              "channel_type",
+	lnwire.DPKickoffFeerate:           "kickoff_feerate",
+}
+
+// rejectedParams returns the names of the parameters that are rejected by the
+// passed DynReject bit vector.
+func rejectedParams(rejections *lnwire.RawFeatureVector) string {
+	var names []string
+	for bit, name := range paramNames {
+		if rejections.IsSet(lnwire.FeatureBit(bit)) {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// negotiationState is the state of a dynamic commitment negotiation.
+type negotiationState uint8
+
+const (
+	// stateIdle is the state before the negotiation was initiated by
+	// either party.
+	stateIdle negotiationState = iota
+
+	// stateAwaitingResponse is the state after we sent dyn_propose, until
+	// the remote party responds with dyn_ack or dyn_reject.
+	stateAwaitingResponse
+
+	// stateDone is the final state once the proposal was either accepted
+	// and applied to the channel, or rejected.
+	stateDone
+)
+
+// String returns a human readable representation of the negotiation state.
+func (s negotiationState) String() string {
+	switch s {
+	case stateIdle:
+		return "Idle"
+
+	case stateAwaitingResponse:
+		return "AwaitingResponse"
+
+	case stateDone:
+		return "Done"
+
+	default:
+		return fmt.Sprintf("Unknown(%d)", s)
+	}
+}
+
+// Request describes the parameters of a dynamic commitment upgrade we
+// initiate. Parameters that are None are kept as they are.
+type Request struct {
+	// DustLimit is the new dust limit of our commitment transaction.
+	DustLimit fn.Option[btcutil.Amount]
+
+	// MaxValueInFlight is the new maximum value of HTLCs the remote party
+	// may have pending with us.
+	MaxValueInFlight fn.Option[lnwire.MilliSatoshi]
+
+	// ChannelReserve is the new reserve the remote party has to keep in
+	// the channel.
+	ChannelReserve fn.Option[btcutil.Amount]
+
+	// CsvDelay is the new delay of the remote party's outputs on its
+	// commitment transaction.
+	CsvDelay fn.Option[uint16]
+
+	// MaxAcceptedHTLCs is the new maximum number of HTLCs the remote party
+	// may offer us.
+	MaxAcceptedHTLCs fn.Option[uint16]
+
+	// ChannelType is the new type of the channel, which determines the
+	// format of its commitment transactions.
+	ChannelType fn.Option[lnwire.ChannelType]
+}
+
+// Config holds the dependencies of a Negotiator.
+type Config struct {
+	// Channel is the channel that is upgraded.
+	Channel Channel
+
+	// MaxLocalCSVDelay is the maximum delay we accept for our outputs on
+	// our commitment transaction.
+	MaxLocalCSVDelay uint16
+
+	// LocalFeatures are the features we advertised to the remote party.
+	LocalFeatures *lnwire.FeatureVector
+
+	// RemoteFeatures are the features the remote party advertised to us.
+	RemoteFeatures *lnwire.FeatureVector
+}
+
+// params are the channel type and the channel configs the channel has after
+// a proposal was applied.
+type params struct {
+	chanType  channeldb.ChannelType
+	localCfg  channeldb.ChannelConfig
+	remoteCfg channeldb.ChannelConfig
+}
+
+// Negotiator drives the negotiation of a dynamic commitment upgrade with the
+// remote party. The initiator proposes the new parameters with dyn_propose,
+// which the responder either accepts with dyn_ack, or rejects with dyn_reject
+// naming the parameters it doesn't accept. Once accepted, both parties apply
+// the new parameters to the channel, and the next commitment of either party
+// is created with them.
+//
+// The Negotiator expects the channel to be quiescent during the negotiation,
+// which the caller needs to ensure with stfu before the negotiation is
+// initiated.
+type Negotiator struct {
+	cfg Config
+
+	chanID lnwire.ChannelID
+
+	state negotiationState
+
+	// proposed are the parameters of the proposal we sent, which are
+	// applied once the remote party accepts them.
+	proposed *params
+
+	// applied is true once the negotiated parameters were applied to the
+	// channel.
+	applied bool
+
+	sync.Mutex
+}
+
+// NewNegotiator creates a new Negotiator for the passed channel.
+func NewNegotiator(cfg Config) *Negotiator {
+	chanPoint := cfg.Channel.ChannelPoint()
+
+	return &Negotiator{
+		cfg:    cfg,
+		chanID: lnwire.NewChanIDFromOutPoint(chanPoint),
+	}
+}
+
+// Done returns true once the negotiation is completed, either because the
+// proposal was applied or rejected.
+func (n *Negotiator) Done() bool {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.state == stateDone
+}
+
+// Applied returns true if the negotiated parameters were applied to the
+// channel.
+func (n *Negotiator) Applied() bool {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.applied
+}
+
+// Initiate starts a dynamic commitment upgrade of the channel by returning
+// the dyn_propose message that is sent to the remote party.
+func (n *Negotiator) Initiate(req Request) (*lnwire.DynPropose, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.state != stateIdle {
+		return nil, ErrNegotiationInProgress
+	}
+
+	msg := &lnwire.DynPropose{
+		ChanID:           n.chanID,
+		Initiator:        true,
+		DustLimit:        req.DustLimit,
+		MaxValueInFlight: req.MaxValueInFlight,
+		ChannelReserve:   req.ChannelReserve,
+		CsvDelay:         req.CsvDelay,
+		MaxAcceptedHTLCs: req.MaxAcceptedHTLCs,
+		ChannelType:      req.ChannelType,
+	}
+
+	if msg.DustLimit.IsNone() && msg.MaxValueInFlight.IsNone() &&
+		msg.ChannelReserve.IsNone() && msg.CsvDelay.IsNone() &&
+		msg.MaxAcceptedHTLCs.IsNone() && msg.ChannelType.IsNone() {
+
+		return nil, ErrEmptyProposal
+	}
+
+	// We check our own proposal just like the remote party will, so we
+	// don't quiesce the channel for a proposal that is bound to be
+	// rejected.
+	proposed, rejections, err := n.evaluateProposal(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	if !rejections.IsEmpty() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProposal,
+			rejectedParams(rejections))
+	}
+
+	// The proposal is recorded before it is sent, so the upgrade can
+	// still be applied when the channel is reestablished if the remote
+	// party accepts it, but its dyn_ack is lost.
+	err = n.cfg.Channel.ProposeDynCommitment(
+		proposed.chanType, proposed.localCfg, proposed.remoteCfg,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to record proposal: %w", err)
+	}
+
+	n.proposed = proposed
+	n.state = stateAwaitingResponse
+
+	dynLog.Infof("ChannelPoint(%v): proposing dynamic commitment "+
+		"upgrade to type %v", n.cfg.Channel.ChannelPoint(),
+		proposed.chanType)
+
+	return msg, nil
+}
+
+// ProcessMsg processes a dynamic commitment message of the remote party, and
+// returns the messages we send in response. The messages need to be sent even
+// if an error is returned.
+func (n *Negotiator) ProcessMsg(msg lnwire.Message) ([]lnwire.Message,
+	error) {
+
+	n.Lock()
+	defer n.Unlock()
+
+	switch msg := msg.(type) {
+	case *lnwire.DynPropose:
+		return n.processDynPropose(msg)
+
+	case *lnwire.DynAck:
+		if n.state != stateAwaitingResponse {
+			return nil, n.unexpectedMsg(msg)
+		}
+
+		n.state = stateDone
+
+		if msg.LocalNonce.IsSome() {
+			return nil, fmt.Errorf("unexpected nonce in dyn_ack")
+		}
+
+		err := n.apply(n.proposed)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+
+	case *lnwire.DynReject:
+		if n.state != stateAwaitingResponse {
+			return nil, n.unexpectedMsg(msg)
+		}
+
+		n.state = stateDone
+
+		if err := n.cfg.Channel.CancelDynCommitment(); err != nil {
+			return nil, fmt.Errorf("unable to remove rejected "+
+				"proposal: %w", err)
+		}
+
+		if msg.UpdateRejections.IsEmpty() {
+			return nil, ErrProposalRejected
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrProposalRejected,
+			rejectedParams(&msg.UpdateRejections))
+
+	default:
+		return nil, n.unexpectedMsg(msg)
+	}
+}
+
+// unexpectedMsg returns the error for a message that isn't expected in the
+// current state.
+func (n *Negotiator) unexpectedMsg(msg lnwire.Message) error {
+	return fmt.Errorf("unexpected %v message in state %v", msg.MsgType(),
+		n.state)
+}
+
+// processDynPropose handles a dynamic commitment upgrade proposed by the
+// remote party, which we either accept and apply, or reject.
+func (n *Negotiator) processDynPropose(
+	msg *lnwire.DynPropose) ([]lnwire.Message, error) {
+
+	if n.state != stateIdle {
+		return nil, ErrNegotiationInProgress
+	}
+
+	if !msg.Initiator {
+		return nil, fmt.Errorf("dyn_propose of responder received " +
+			"without a pending proposal")
+	}
+
+	n.state = stateDone
+
+	// If we can't evaluate or apply the proposal, we reject it as a whole
+	// without naming any parameters, so the remote party isn't left
+	// waiting for our response.
+	rejectAll := []lnwire.Message{&lnwire.DynReject{
+		ChanID:           n.chanID,
+		UpdateRejections: *lnwire.NewRawFeatureVector(),
+	}}
+
+	proposed, rejections, err := n.evaluateProposal(msg, false)
+	if err != nil {
+		return rejectAll, err
+	}
+
+	if !rejections.IsEmpty() {
+		dynLog.Infof("ChannelPoint(%v): rejecting dynamic commitment "+
+			"proposal: %s", n.cfg.Channel.ChannelPoint(),
+			rejectedParams(rejections))
+
+		return []lnwire.Message{&lnwire.DynReject{
+			ChanID:           n.chanID,
+			UpdateRejections: *rejections,
+		}}, nil
+	}
+
+	// We apply the proposal before acknowledging it, as the remote party
+	// will sign our next commitment with the new parameters as soon as
+	// it receives our dyn_ack.
+	if err := n.apply(proposed); err != nil {
+		return rejectAll, err
+	}
+
+	return []lnwire.Message{&lnwire.DynAck{
+		ChanID: n.chanID,
+	}}, nil
+}
+
+// apply applies the negotiated parameters to the channel.
+func (n *Negotiator) apply(p *params) error {
+	err := n.cfg.Channel.ApplyDynCommitment(
+		p.chanType, p.localCfg, p.remoteCfg,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to apply dynamic commitment: %w", err)
+	}
+
+	n.applied = true
+
+	return nil
+}
+
+// evaluateProposal returns the parameters the channel has after the proposal
+// is applied, along with the DynReject bit vector of the proposed parameters
+// that aren't acceptable. An error is only returned if the proposal can't be
+// evaluated in the current state of the channel.
+func (n *Negotiator) evaluateProposal(msg *lnwire.DynPropose,
+	sentByUs bool) (*params, *lnwire.RawFeatureVector, error) {
+
+	chanState := n.cfg.Channel.State()
+	rejections := lnwire.NewRawFeatureVector()
+	reject := func(tlvType tlv.Type) {
+		rejections.Set(lnwire.FeatureBit(tlvType))
+	}
+
+	p := &params{
+		chanType:  chanState.ChanType,
+		localCfg:  chanState.LocalChanCfg,
+		remoteCfg: chanState.RemoteChanCfg,
+	}
+
+	// The dust limit applies to the commitment of the sender, while all
+	// other parameters are constraints the sender imposes on the
+	// recipient.
+	senderCfg, recipientCfg := &p.remoteCfg, &p.localCfg
+	if sentByUs {
+		senderCfg, recipientCfg = &p.localCfg, &p.remoteCfg
+	}
+
+	msg.DustLimit.WhenSome(func(dustLimit btcutil.Amount) {
+		senderCfg.DustLimit = dustLimit
+
+		minDust := lnwallet.DustLimitForSize(input.UnknownWitnessSize)
+		if dustLimit < minDust || dustLimit > 3*minDust {
+			reject(lnwire.DPDustLimitSatoshis)
+		}
+	})
+
+	msg.MaxValueInFlight.WhenSome(func(maxValue lnwire.MilliSatoshi) {
+		recipientCfg.MaxPendingAmount = maxValue
+	})
+
+	msg.ChannelReserve.WhenSome(func(reserve btcutil.Amount) {
+		recipientCfg.ChanReserve = reserve
+
+		if reserve > chanState.Capacity/5 {
+			reject(lnwire.DPChannelReserveSatoshis)
+		}
+	})
+
+	msg.CsvDelay.WhenSome(func(csvDelay uint16) {
+		recipientCfg.CsvDelay = csvDelay
+
+		if csvDelay == 0 ||
+			(!sentByUs && csvDelay > n.cfg.MaxLocalCSVDelay) {
+
+			reject(lnwire.DPToSelfDelay)
+		}
+	})
+
+	msg.MaxAcceptedHTLCs.WhenSome(func(maxHtlcs uint16) {
+		recipientCfg.MaxAcceptedHtlcs = maxHtlcs
+
+		if maxHtlcs < minNumHtlcs || maxHtlcs > maxNumHtlcs {
+			reject(lnwire.DPMaxAcceptedHtlcs)
+		}
+	})
+
+	// The reserve the sender imposes must be at least the dust limit of
+	// its commitment, so it's never trimmed.
+	if senderCfg.DustLimit > recipientCfg.ChanReserve {
+		msg.DustLimit.WhenSome(func(btcutil.Amount) {
+			reject(lnwire.DPDustLimitSatoshis)
+		})
+		msg.ChannelReserve.WhenSome(func(btcutil.Amount) {
+			reject(lnwire.DPChannelReserveSatoshis)
+		})
+	}
+
+	// The recipient must be able to have at least minNumHtlcs HTLCs of
+	// the minimum size in flight.
+	minInFlight := minNumHtlcs * recipientCfg.MinHTLC
+	if msg.MaxValueInFlight.IsSome() &&
+		recipientCfg.MaxPendingAmount < minInFlight {
+
+		reject(lnwire.DPMaxHtlcValueInFlightMsat)
+	}
+
+	// Rotating the funding key requires a new funding output, and we
+	// don't support channel types that require a kickoff transaction.
+	if msg.FundingKey.IsSome() {
+		reject(lnwire.DPFundingPubkey)
+	}
+	if msg.KickoffFeerate.IsSome() {
+		reject(lnwire.DPKickoffFeerate)
+	}
+
+	msg.ChannelType.WhenSome(func(chanType lnwire.ChannelType) {
+		nextType, err := lnwallet.DynCommitChanType(
+			chanState.ChanType, chanType,
+		)
+		if err != nil {
+			reject(lnwire.DPChannelType)
+			return
+		}
+
+		if nextType.HasAnchors() && !n.anchorsSupported() {
+			reject(lnwire.DPChannelType)
+			return
+		}
+
+		p.chanType = nextType
+	})
+
+	if !rejections.IsEmpty() {
+		return p, rejections, nil
+	}
+
+	// Finally, we make sure the channel can be upgraded in its current
+	// state. A channel type the initiator can't afford is rejected, but
+	// any other failure means the channel isn't quiescent, which the
+	// caller must ensure.
+	err := n.cfg.Channel.ValidateDynCommitment(
+		p.chanType, p.localCfg, p.remoteCfg,
+	)
+	switch {
+	case err == nil:
+
+	case errors.Is(err, lnwallet.ErrDynCommitChannelNotSynced),
+		errors.Is(err, lnwallet.ErrDynCommitKeysChanged):
+
+		return nil, nil, err
+
+	default:
+		if msg.ChannelType.IsNone() {
+			return nil, nil, err
+		}
+
+		dynLog.Debugf("ChannelPoint(%v): channel type not acceptable: "+
+			"%v", n.cfg.Channel.ChannelPoint(), err)
+
+		reject(lnwire.DPChannelType)
+	}
+
+	return p, rejections, nil
+}
+
+// anchorsSupported returns true if both parties signal support for anchor
+// outputs with zero-fee HTLC transactions.
+func (n *Negotiator) anchorsSupported() bool {
+	return n.cfg.LocalFeatures.HasFeature(
+		lnwire.AnchorsZeroFeeHtlcTxOptional,
+	) && n.cfg.RemoteFeatures.HasFeature(
+		lnwire.AnchorsZeroFeeHtlcTxOptional,
+	)
+}
@@ -0,0 +1,340 @@
+package dyncomm
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+const testMaxCSVDelay = 100
+
+// dynTestContext holds two channel parties with a negotiator each.
+type dynTestContext struct {
+	t *testing.T
+
+	aliceChannel *lnwallet.LightningChannel
+	bobChannel   *lnwallet.LightningChannel
+
+	alice *Negotiator
+	bob   *Negotiator
+}
+
+// newDynTestContext creates two parties of a legacy channel with a negotiator
+// each. Anchors are only supported by Bob if bobAnchors is set.
+func newDynTestContext(t *testing.T, bobAnchors bool) *dynTestContext {
+	aliceChannel, bobChannel, err := lnwallet.CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit,
+	)
+	require.NoError(t, err)
+
+	aliceFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.AnchorsZeroFeeHtlcTxOptional,
+		), lnwire.Features,
+	)
+	bobFeatures := lnwire.NewFeatureVector(nil, lnwire.Features)
+	if bobAnchors {
+		bobFeatures = aliceFeatures
+	}
+
+	return &dynTestContext{
+		t:            t,
+		aliceChannel: aliceChannel,
+		bobChannel:   bobChannel,
+		alice: NewNegotiator(Config{
+			Channel:          aliceChannel,
+			MaxLocalCSVDelay: testMaxCSVDelay,
+			LocalFeatures:    aliceFeatures,
+			RemoteFeatures:   bobFeatures,
+		}),
+		bob: NewNegotiator(Config{
+			Channel:          bobChannel,
+			MaxLocalCSVDelay: testMaxCSVDelay,
+			LocalFeatures:    bobFeatures,
+			RemoteFeatures:   aliceFeatures,
+		}),
+	}
+}
+
+// negotiate sends Alice's proposal to Bob, and returns Bob's response after
+// delivering it to Alice.
+func (c *dynTestContext) negotiate(req Request) (lnwire.Message, error) {
+	propose, err := c.alice.Initiate(req)
+	require.NoError(c.t, err)
+
+	msgs, err := c.bob.ProcessMsg(propose)
+	require.NoError(c.t, err)
+	require.Len(c.t, msgs, 1)
+	require.True(c.t, c.bob.Done())
+
+	_, err = c.alice.ProcessMsg(msgs[0])
+	require.True(c.t, c.alice.Done())
+
+	return msgs[0], err
+}
+
+// reestablish exchanges the channel_reestablish messages of Alice and Bob, as
+// if they reconnected, and creates new negotiators for them.
+func (c *dynTestContext) reestablish() {
+	aliceSync, err := c.aliceChannel.State().ChanSyncMsg()
+	require.NoError(c.t, err)
+	bobSync, err := c.bobChannel.State().ChanSyncMsg()
+	require.NoError(c.t, err)
+
+	msgs, _, _, err := c.aliceChannel.ProcessChanSyncMsg(bobSync)
+	require.NoError(c.t, err)
+	require.Empty(c.t, msgs)
+
+	msgs, _, _, err = c.bobChannel.ProcessChanSyncMsg(aliceSync)
+	require.NoError(c.t, err)
+	require.Empty(c.t, msgs)
+
+	c.alice = NewNegotiator(c.alice.cfg)
+	c.bob = NewNegotiator(c.bob.cfg)
+}
+
+// anchorsChanType returns the channel type of anchor channels.
+func anchorsChanType() lnwire.ChannelType {
+	return lnwire.ChannelType(*lnwire.NewRawFeatureVector(
+		lnwire.StaticRemoteKeyRequired,
+		lnwire.AnchorsZeroFeeHtlcTxRequired,
+	))
+}
+
+// taprootChanType returns the channel type of simple taproot channels.
+func taprootChanType() lnwire.ChannelType {
+	return lnwire.ChannelType(*lnwire.NewRawFeatureVector(
+		lnwire.SimpleTaprootChannelsRequiredStaging,
+	))
+}
+
+// TestNegotiatorUpgrade tests that a legacy channel is upgraded to anchors
+// along with new channel parameters, and that the upgraded channel is able
+// to transition to a new state.
+func TestNegotiatorUpgrade(t *testing.T) {
+	t.Parallel()
+
+	c := newDynTestContext(t, true)
+
+	aliceState := c.aliceChannel.State()
+	bobState := c.bobChannel.State()
+
+	dustLimit := lnwallet.DustLimitForSize(input.UnknownWitnessSize) + 100
+	resp, err := c.negotiate(Request{
+		DustLimit:        fn.Some(dustLimit),
+		MaxValueInFlight: fn.Some(lnwire.MilliSatoshi(100_000_000)),
+		CsvDelay:         fn.Some(uint16(50)),
+		MaxAcceptedHTLCs: fn.Some(uint16(30)),
+		ChannelType:      fn.Some(anchorsChanType()),
+	})
+	require.NoError(t, err)
+	require.IsType(t, &lnwire.DynAck{}, resp)
+	require.True(t, c.alice.Applied())
+	require.True(t, c.bob.Applied())
+
+	// Both parties agree on the new parameters from their perspective.
+	anchorType := channeldb.SingleFunderTweaklessBit |
+		channeldb.AnchorOutputsBit | channeldb.ZeroHtlcTxFeeBit
+	require.Equal(t, anchorType, aliceState.ChanType)
+	require.Equal(t, anchorType, bobState.ChanType)
+	require.Equal(t, dustLimit, aliceState.LocalChanCfg.DustLimit)
+	require.EqualValues(t, 50, bobState.LocalChanCfg.CsvDelay)
+	require.EqualValues(t, 50, aliceState.RemoteChanCfg.CsvDelay)
+	require.EqualValues(t, 30, bobState.LocalChanCfg.MaxAcceptedHtlcs)
+	require.EqualValues(
+		t, 100_000_000, bobState.LocalChanCfg.MaxPendingAmount,
+	)
+	require.Equal(t, aliceState.LocalChanCfg, bobState.RemoteChanCfg)
+	require.Equal(t, aliceState.RemoteChanCfg, bobState.LocalChanCfg)
+
+	// The new commitments are signed without any updates.
+	require.True(t, c.aliceChannel.OweCommitment())
+	err = lnwallet.ForceStateTransition(c.aliceChannel, c.bobChannel)
+	require.NoError(t, err)
+	require.Len(t, aliceState.LocalCommitment.CommitTx.TxOut, 4)
+}
+
+// TestNegotiatorReject tests that unacceptable parameters are rejected, and
+// the channel is left untouched.
+func TestNegotiatorReject(t *testing.T) {
+	t.Parallel()
+
+	c := newDynTestContext(t, true)
+	aliceState := c.aliceChannel.State()
+	prevType := aliceState.ChanType
+	prevCfg := aliceState.RemoteChanCfg
+
+	// Bob doesn't accept a CSV delay above his maximum, while the other
+	// parameters are acceptable.
+	resp, err := c.negotiate(Request{
+		CsvDelay:         fn.Some(uint16(testMaxCSVDelay + 1)),
+		MaxAcceptedHTLCs: fn.Some(uint16(20)),
+		ChannelType:      fn.Some(anchorsChanType()),
+	})
+	require.ErrorIs(t, err, ErrProposalRejected)
+	require.ErrorContains(t, err, "csv_delay")
+
+	reject, ok := resp.(*lnwire.DynReject)
+	require.True(t, ok)
+	require.True(t, reject.UpdateRejections.IsSet(
+		lnwire.FeatureBit(lnwire.DPToSelfDelay),
+	))
+	require.False(t, reject.UpdateRejections.IsSet(
+		lnwire.FeatureBit(lnwire.DPChannelType),
+	))
+	require.False(t, reject.UpdateRejections.IsSet(
+		lnwire.FeatureBit(lnwire.DPMaxAcceptedHtlcs),
+	))
+
+	require.False(t, c.alice.Applied())
+	require.False(t, c.bob.Applied())
+	require.Equal(t, prevType, aliceState.ChanType)
+	require.Equal(t, prevCfg, aliceState.RemoteChanCfg)
+	require.False(t, c.aliceChannel.OweCommitment())
+
+	// The rejected proposal isn't kept.
+	proposal, err := aliceState.DynCommitProposal()
+	require.NoError(t, err)
+	require.True(t, proposal.IsNone())
+}
+
+// TestNegotiatorLostAck tests that an upgrade whose dyn_ack is lost is applied
+// by the initiator when the channel is reestablished, while a proposal that
+// never reached the responder is dropped.
+func TestNegotiatorLostAck(t *testing.T) {
+	t.Parallel()
+
+	c := newDynTestContext(t, true)
+	aliceState := c.aliceChannel.State()
+	bobState := c.bobChannel.State()
+	prevType := aliceState.ChanType
+
+	req := Request{
+		CsvDelay:    fn.Some(uint16(50)),
+		ChannelType: fn.Some(anchorsChanType()),
+	}
+
+	// Alice's proposal doesn't reach Bob, so it is dropped once the
+	// channel is reestablished.
+	_, err := c.alice.Initiate(req)
+	require.NoError(t, err)
+
+	c.reestablish()
+
+	proposal, err := aliceState.DynCommitProposal()
+	require.NoError(t, err)
+	require.True(t, proposal.IsNone())
+	require.Equal(t, prevType, aliceState.ChanType)
+
+	// This time Bob accepts and applies the proposal, but his dyn_ack
+	// doesn't reach Alice.
+	propose, err := c.alice.Initiate(req)
+	require.NoError(t, err)
+
+	msgs, err := c.bob.ProcessMsg(propose)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.IsType(t, &lnwire.DynAck{}, msgs[0])
+	require.True(t, c.bob.Applied())
+	require.False(t, c.alice.Applied())
+
+	// Bob reports the upgrade when the channel is reestablished, so Alice
+	// applies her proposal.
+	bobSync, err := bobState.ChanSyncMsg()
+	require.NoError(t, err)
+	require.Equal(t, fn.Some(lnwire.DynHeight(1)), bobSync.DynHeight)
+
+	c.reestablish()
+
+	anchorType := channeldb.SingleFunderTweaklessBit |
+		channeldb.AnchorOutputsBit | channeldb.ZeroHtlcTxFeeBit
+	require.Equal(t, anchorType, aliceState.ChanType)
+	require.Equal(t, aliceState.LocalChanCfg, bobState.RemoteChanCfg)
+	require.Equal(t, aliceState.RemoteChanCfg, bobState.LocalChanCfg)
+
+	proposal, err = aliceState.DynCommitProposal()
+	require.NoError(t, err)
+	require.True(t, proposal.IsNone())
+
+	// Both parties owe each other a commitment with the new parameters,
+	// which they are able to sign and verify.
+	require.True(t, c.aliceChannel.OweDynCommitment())
+	require.True(t, c.bobChannel.OweDynCommitment())
+	err = lnwallet.ForceStateTransition(c.aliceChannel, c.bobChannel)
+	require.NoError(t, err)
+	require.Len(t, aliceState.LocalCommitment.CommitTx.TxOut, 4)
+	require.False(t, c.aliceChannel.OweDynCommitment())
+	require.False(t, c.bobChannel.OweDynCommitment())
+}
+
+// TestNegotiatorInvalidRequest tests that we don't propose parameters we'd
+// reject ourselves.
+func TestNegotiatorInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	// The reserve must not exceed a fifth of the 10 BTC test channel.
+	tooLargeReserve := btcutil.Amount(5 * btcutil.SatoshiPerBitcoin)
+
+	testCases := []struct {
+		name            string
+		req             Request
+		noRemoteAnchors bool
+		err             error
+	}{
+		{
+			name: "empty",
+			err:  ErrEmptyProposal,
+		},
+		{
+			name: "dust limit too small",
+			req: Request{
+				DustLimit: fn.Some(btcutil.Amount(1)),
+			},
+			err: ErrInvalidProposal,
+		},
+		{
+			name: "reserve too large",
+			req: Request{
+				ChannelReserve: fn.Some(tooLargeReserve),
+			},
+			err: ErrInvalidProposal,
+		},
+		{
+			name: "too few htlcs",
+			req: Request{
+				MaxAcceptedHTLCs: fn.Some(uint16(minNumHtlcs - 1)),
+			},
+			err: ErrInvalidProposal,
+		},
+		{
+			name: "taproot",
+			req: Request{
+				ChannelType: fn.Some(taprootChanType()),
+			},
+			err: ErrInvalidProposal,
+		},
+		{
+			name: "anchors not supported by remote",
+			req: Request{
+				ChannelType: fn.Some(anchorsChanType()),
+			},
+			noRemoteAnchors: true,
+			err:             ErrInvalidProposal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newDynTestContext(t, !tc.noRemoteAnchors)
+
+			_, err := c.alice.Initiate(tc.req)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,637 @@
+package interactivetx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// MaxAdditions is the maximum number of inputs or outputs each party
+	// may add during a negotiation.
+	MaxAdditions = 4096
+
+	// maxSequence is the maximum sequence number an input may use. Inputs
+	// must signal replaceability, so the transaction can be fee bumped.
+	maxSequence = wire.MaxTxInSequenceNum - 2
+)
+
+var (
+	// ErrNotOurTurn is returned when a message is sent or received out of
+	// turn.
+	ErrNotOurTurn = errors.New("message out of turn")
+
+	// ErrComplete is returned when a message is sent or received after
+	// the negotiation completed.
+	ErrComplete = errors.New("negotiation already complete")
+
+	// ErrNotComplete is returned when the transaction is requested before
+	// the negotiation completed.
+	ErrNotComplete = errors.New("negotiation not complete")
+
+	// ErrAborted is returned when the remote party aborted the
+	// negotiation.
+	ErrAborted = errors.New("negotiation aborted by remote party")
+)
+
+// SharedInput is the current funding output of a channel that is spent by a
+// splice transaction. Both parties know about the output, so it doesn't have
+// to be sent along with its previous transaction.
+type SharedInput struct {
+	// OutPoint is the outpoint of the funding output.
+	OutPoint wire.OutPoint
+
+	// Output is the funding output itself.
+	Output *wire.TxOut
+}
+
+// Input is an input of the transaction that is being negotiated.
+type Input struct {
+	// SerialID uniquely identifies the input within the negotiation.
+	SerialID uint64
+
+	// OutPoint is the outpoint that is spent by the input.
+	OutPoint wire.OutPoint
+
+	// PrevTx is the transaction that created the spent output. It's nil
+	// for the shared input.
+	PrevTx *wire.MsgTx
+
+	// PrevOutput is the output that is spent.
+	PrevOutput *wire.TxOut
+
+	// Sequence is the sequence number of the input.
+	Sequence uint32
+
+	// Shared is true if the input spends the current funding output of
+	// the channel.
+	Shared bool
+}
+
+// Output is an output of the transaction that is being negotiated.
+type Output struct {
+	// SerialID uniquely identifies the output within the negotiation.
+	SerialID uint64
+
+	// Value is the value of the output.
+	Value btcutil.Amount
+
+	// PkScript is the script the output pays to.
+	PkScript []byte
+}
+
+// Config holds the parameters of an interactive transaction negotiation.
+type Config struct {
+	// ChanID is the channel the transaction is negotiated for. For a dual
+	// funded channel open, this is the temporary channel ID.
+	ChanID lnwire.ChannelID
+
+	// Initiator is true if we initiated the negotiation. The initiator
+	// sends the first message and uses even serial IDs.
+	Initiator bool
+
+	// LockTime is the lock time of the negotiated transaction.
+	LockTime uint32
+
+	// SharedInput is the funding output spent by a splice transaction. The
+	// remote party may only add it by referring to its txid.
+	SharedInput fn.Option[SharedInput]
+
+	// Inputs are the inputs we contribute to the transaction.
+	Inputs []*Input
+
+	// Outputs are the outputs we contribute to the transaction.
+	Outputs []*Output
+}
+
+// Constructor drives the interactive construction of a transaction with the
+// remote party. Both parties take turns sending messages that add or remove
+// inputs and outputs, until both consecutively send tx_complete.
+type Constructor struct {
+	cfg Config
+
+	// pendingInputs and pendingOutputs are the local contributions that
+	// haven't been sent to the remote party yet.
+	pendingInputs  []*Input
+	pendingOutputs []*Output
+
+	// nextSerialID is the next serial ID we use for a local addition.
+	nextSerialID uint64
+
+	inputs  map[uint64]*Input
+	outputs map[uint64]*Output
+
+	// numRemoteInputs and numRemoteOutputs count the additions of the
+	// remote party, including those that were removed again.
+	numRemoteInputs  int
+	numRemoteOutputs int
+
+	ourTurn bool
+
+	localComplete  bool
+	remoteComplete bool
+}
+
+// NewConstructor creates a new Constructor for the passed negotiation.
+func NewConstructor(cfg Config) *Constructor {
+	c := &Constructor{
+		cfg:            cfg,
+		pendingInputs:  cfg.Inputs,
+		pendingOutputs: cfg.Outputs,
+		inputs:         make(map[uint64]*Input),
+		outputs:        make(map[uint64]*Output),
+		ourTurn:        cfg.Initiator,
+	}
+
+	if !cfg.Initiator {
+		c.nextSerialID = 1
+	}
+
+	return c
+}
+
+// OurTurn returns true if we're expected to send the next message.
+func (c *Constructor) OurTurn() bool {
+	return c.ourTurn && !c.IsComplete()
+}
+
+// IsComplete returns true once both parties consecutively sent tx_complete.
+func (c *Constructor) IsComplete() bool {
+	return c.localComplete && c.remoteComplete
+}
+
+// isLocalSerialID returns true if the serial ID belongs to our additions.
+func (c *Constructor) isLocalSerialID(serialID uint64) bool {
+	isEven := serialID%2 == 0
+	return isEven == c.cfg.Initiator
+}
+
+// Send returns the next message we send to the remote party. Our pending
+// inputs are added first, then our pending outputs. Once all of them were
+// sent, we send tx_complete.
+func (c *Constructor) Send() (lnwire.Message, error) {
+	if c.IsComplete() {
+		return nil, ErrComplete
+	}
+	if !c.ourTurn {
+		return nil, ErrNotOurTurn
+	}
+
+	c.ourTurn = false
+
+	switch {
+	case len(c.pendingInputs) > 0:
+		in := c.pendingInputs[0]
+		c.pendingInputs = c.pendingInputs[1:]
+
+		msg, err := c.addLocalInput(in)
+		if err != nil {
+			return nil, err
+		}
+
+		c.localComplete = false
+		c.remoteComplete = false
+
+		return msg, nil
+
+	case len(c.pendingOutputs) > 0:
+		out := c.pendingOutputs[0]
+		c.pendingOutputs = c.pendingOutputs[1:]
+
+		out.SerialID = c.nextSerialID
+		c.nextSerialID += 2
+		c.outputs[out.SerialID] = out
+
+		c.localComplete = false
+		c.remoteComplete = false
+
+		return &lnwire.TxAddOutput{
+			ChannelID: c.cfg.ChanID,
+			SerialID:  out.SerialID,
+			Amount:    out.Value,
+			Script:    out.PkScript,
+		}, nil
+
+	default:
+		c.localComplete = true
+
+		return &lnwire.TxComplete{
+			ChannelID: c.cfg.ChanID,
+		}, nil
+	}
+}
+
+// addLocalInput assigns a serial ID to the local input, and returns the
+// tx_add_input message that adds it.
+func (c *Constructor) addLocalInput(in *Input) (*lnwire.TxAddInput, error) {
+	in.SerialID = c.nextSerialID
+	c.nextSerialID += 2
+	c.inputs[in.SerialID] = in
+
+	msg := &lnwire.TxAddInput{
+		ChannelID: c.cfg.ChanID,
+		SerialID:  in.SerialID,
+		PrevTxOut: in.OutPoint.Index,
+		Sequence:  in.Sequence,
+	}
+
+	if in.Shared {
+		msg.SharedInputTxid = tlv.SomeRecordT(
+			tlv.NewPrimitiveRecord[tlv.TlvType0, [32]byte](
+				in.OutPoint.Hash,
+			),
+		)
+
+		return msg, nil
+	}
+
+	var prevTx bytes.Buffer
+	if err := in.PrevTx.Serialize(&prevTx); err != nil {
+		return nil, err
+	}
+	msg.PrevTx = prevTx.Bytes()
+
+	return msg, nil
+}
+
+// Receive processes a message of the remote party.
+func (c *Constructor) Receive(msg lnwire.Message) error {
+	if abort, ok := msg.(*lnwire.TxAbort); ok {
+		abortErr := &lnwire.Error{
+			ChanID: abort.ChannelID,
+			Data:   abort.Data,
+		}
+
+		return fmt.Errorf("%w: %v", ErrAborted, abortErr)
+	}
+
+	if c.IsComplete() {
+		return ErrComplete
+	}
+	if c.ourTurn {
+		return ErrNotOurTurn
+	}
+
+	var err error
+	switch msg := msg.(type) {
+	case *lnwire.TxAddInput:
+		err = c.receiveAddInput(msg)
+
+	case *lnwire.TxAddOutput:
+		err = c.receiveAddOutput(msg)
+
+	case *lnwire.TxRemoveInput:
+		err = c.receiveRemove(msg.SerialID, true)
+
+	case *lnwire.TxRemoveOutput:
+		err = c.receiveRemove(msg.SerialID, false)
+
+	case *lnwire.TxComplete:
+		c.remoteComplete = true
+
+		if c.IsComplete() {
+			err = c.validateTx()
+		}
+
+	default:
+		err = fmt.Errorf("unexpected message: %T", msg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, ok := msg.(*lnwire.TxComplete); !ok {
+		c.localComplete = false
+		c.remoteComplete = false
+	}
+
+	c.ourTurn = true
+
+	return nil
+}
+
+// checkRemoteSerialID ensures that a serial ID added by the remote party has
+// the right parity and isn't in use yet.
+func (c *Constructor) checkRemoteSerialID(serialID uint64, isInput bool) error {
+	if c.isLocalSerialID(serialID) {
+		return fmt.Errorf("serial id %v has wrong parity", serialID)
+	}
+
+	_, inputExists := c.inputs[serialID]
+	_, outputExists := c.outputs[serialID]
+	if (isInput && inputExists) || (!isInput && outputExists) {
+		return fmt.Errorf("duplicate serial id %v", serialID)
+	}
+
+	return nil
+}
+
+// receiveAddInput processes a tx_add_input message.
+func (c *Constructor) receiveAddInput(msg *lnwire.TxAddInput) error {
+	c.numRemoteInputs++
+	if c.numRemoteInputs > MaxAdditions {
+		return fmt.Errorf("too many inputs added")
+	}
+
+	if err := c.checkRemoteSerialID(msg.SerialID, true); err != nil {
+		return err
+	}
+
+	if msg.Sequence > maxSequence {
+		return fmt.Errorf("input %v doesn't signal replaceability",
+			msg.SerialID)
+	}
+
+	in := &Input{
+		SerialID: msg.SerialID,
+		Sequence: msg.Sequence,
+	}
+
+	sharedTxid := msg.SharedInputTxid.ValOpt()
+	switch {
+	case sharedTxid.IsSome():
+		shared, err := c.cfg.SharedInput.UnwrapOrErr(
+			fmt.Errorf("unexpected shared input"),
+		)
+		if err != nil {
+			return err
+		}
+
+		txid := sharedTxid.UnsafeFromSome()
+		if txid != shared.OutPoint.Hash ||
+			msg.PrevTxOut != shared.OutPoint.Index {
+
+			return fmt.Errorf("shared input doesn't spend " +
+				"funding output")
+		}
+
+		in.OutPoint = shared.OutPoint
+		in.PrevOutput = shared.Output
+		in.Shared = true
+
+	default:
+		prevTx := &wire.MsgTx{}
+		err := prevTx.Deserialize(bytes.NewReader(msg.PrevTx))
+		if err != nil {
+			return fmt.Errorf("invalid prev tx: %w", err)
+		}
+
+		if int(msg.PrevTxOut) >= len(prevTx.TxOut) {
+			return fmt.Errorf("invalid prev tx output index %v",
+				msg.PrevTxOut)
+		}
+
+		prevOut := prevTx.TxOut[msg.PrevTxOut]
+		if !txscript.IsWitnessProgram(prevOut.PkScript) {
+			return fmt.Errorf("input %v doesn't spend a segwit "+
+				"output", msg.SerialID)
+		}
+
+		in.OutPoint = wire.OutPoint{
+			Hash:  prevTx.TxHash(),
+			Index: msg.PrevTxOut,
+		}
+		in.PrevTx = prevTx
+		in.PrevOutput = prevOut
+	}
+
+	for _, existing := range c.inputs {
+		if existing.OutPoint == in.OutPoint {
+			return fmt.Errorf("duplicate input %v", in.OutPoint)
+		}
+	}
+
+	c.inputs[in.SerialID] = in
+
+	return nil
+}
+
+// receiveAddOutput processes a tx_add_output message.
+func (c *Constructor) receiveAddOutput(msg *lnwire.TxAddOutput) error {
+	c.numRemoteOutputs++
+	if c.numRemoteOutputs > MaxAdditions {
+		return fmt.Errorf("too many outputs added")
+	}
+
+	if err := c.checkRemoteSerialID(msg.SerialID, false); err != nil {
+		return err
+	}
+
+	if msg.Amount <= 0 || msg.Amount > btcutil.MaxSatoshi {
+		return fmt.Errorf("invalid output amount %v", msg.Amount)
+	}
+
+	if txscript.GetScriptClass(msg.Script) == txscript.NonStandardTy {
+		return fmt.Errorf("output %v has non-standard script",
+			msg.SerialID)
+	}
+
+	c.outputs[msg.SerialID] = &Output{
+		SerialID: msg.SerialID,
+		Value:    msg.Amount,
+		PkScript: msg.Script,
+	}
+
+	return nil
+}
+
+// receiveRemove processes a tx_remove_input or tx_remove_output message.
+func (c *Constructor) receiveRemove(serialID uint64, isInput bool) error {
+	if c.isLocalSerialID(serialID) {
+		return fmt.Errorf("can't remove serial id %v of other party",
+			serialID)
+	}
+
+	if isInput {
+		if _, ok := c.inputs[serialID]; !ok {
+			return fmt.Errorf("unknown input %v", serialID)
+		}
+		delete(c.inputs, serialID)
+
+		return nil
+	}
+
+	if _, ok := c.outputs[serialID]; !ok {
+		return fmt.Errorf("unknown output %v", serialID)
+	}
+	delete(c.outputs, serialID)
+
+	return nil
+}
+
+// validateTx checks the final transaction once the negotiation completed.
+func (c *Constructor) validateTx() error {
+	if len(c.inputs) == 0 || len(c.outputs) == 0 {
+		return fmt.Errorf("transaction has no inputs or outputs")
+	}
+
+	var inputValue, outputValue btcutil.Amount
+	for _, in := range c.inputs {
+		inputValue += btcutil.Amount(in.PrevOutput.Value)
+	}
+	for _, out := range c.outputs {
+		outputValue += out.Value
+	}
+
+	if outputValue > inputValue {
+		return fmt.Errorf("outputs value %v exceeds inputs value %v",
+			outputValue, inputValue)
+	}
+
+	return nil
+}
+
+// Inputs returns all inputs of the transaction, sorted by serial ID.
+func (c *Constructor) Inputs() []*Input {
+	inputs := make([]*Input, 0, len(c.inputs))
+	for _, in := range c.inputs {
+		inputs = append(inputs, in)
+	}
+
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].SerialID < inputs[j].SerialID
+	})
+
+	return inputs
+}
+
+// Outputs returns all outputs of the transaction, sorted by serial ID.
+func (c *Constructor) Outputs() []*Output {
+	outputs := make([]*Output, 0, len(c.outputs))
+	for _, out := range c.outputs {
+		outputs = append(outputs, out)
+	}
+
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].SerialID < outputs[j].SerialID
+	})
+
+	return outputs
+}
+
+// LocalInputs returns the inputs we contributed, sorted by serial ID. These
+// are the inputs we need to send witnesses for in tx_signatures. The shared
+// input is signed separately, so it isn't included.
+func (c *Constructor) LocalInputs() []*Input {
+	return fn.Filter(func(in *Input) bool {
+		return !in.Shared && c.isLocalSerialID(in.SerialID)
+	}, c.Inputs())
+}
+
+// RemoteInputs returns the inputs the remote party contributed, sorted by
+// serial ID, excluding the shared input.
+func (c *Constructor) RemoteInputs() []*Input {
+	return fn.Filter(func(in *Input) bool {
+		return !in.Shared && !c.isLocalSerialID(in.SerialID)
+	}, c.Inputs())
+}
+
+// Tx returns the negotiated transaction once the negotiation is complete. The
+// inputs and outputs are ordered by their serial IDs.
+func (c *Constructor) Tx() (*wire.MsgTx, error) {
+	if !c.IsComplete() {
+		return nil, ErrNotComplete
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.LockTime = c.cfg.LockTime
+
+	for _, in := range c.Inputs() {
+		txIn := wire.NewTxIn(&in.OutPoint, nil, nil)
+		txIn.Sequence = in.Sequence
+		tx.AddTxIn(txIn)
+	}
+
+	for _, out := range c.Outputs() {
+		tx.AddTxOut(wire.NewTxOut(int64(out.Value), out.PkScript))
+	}
+
+	return tx, nil
+}
+
+// LocalSignsFirst returns true if we need to send tx_signatures before the
+// remote party. The party that contributed the lower input value sends its
+// signatures first, with the initiator going first on a tie. The shared input
+// counts towards the initiator.
+func (c *Constructor) LocalSignsFirst() bool {
+	var localValue, remoteValue btcutil.Amount
+	for _, in := range c.inputs {
+		value := btcutil.Amount(in.PrevOutput.Value)
+
+		switch {
+		case in.Shared && c.cfg.Initiator:
+			localValue += value
+
+		case in.Shared:
+			remoteValue += value
+
+		case c.isLocalSerialID(in.SerialID):
+			localValue += value
+
+		default:
+			remoteValue += value
+		}
+	}
+
+	if localValue == remoteValue {
+		return c.cfg.Initiator
+	}
+
+	return localValue < remoteValue
+}
+
+// PrevOutFetcher returns a fetcher for the outputs spent by the inputs of the
+// transaction.
+func (c *Constructor) PrevOutFetcher() *txscript.MultiPrevOutFetcher {
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, in := range c.inputs {
+		fetcher.AddPrevOut(in.OutPoint, in.PrevOutput)
+	}
+
+	return fetcher
+}
+
+// VerifyInputs checks that the witnesses of the passed inputs of the signed
+// transaction are valid.
+func (c *Constructor) VerifyInputs(tx *wire.MsgTx, inputs []*Input) error {
+	prevOuts := c.PrevOutFetcher()
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+
+	for _, in := range inputs {
+		idx := -1
+		for i, txIn := range tx.TxIn {
+			if txIn.PreviousOutPoint == in.OutPoint {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return fmt.Errorf("input %v not found in transaction",
+				in.OutPoint)
+		}
+
+		vm, err := txscript.NewEngine(
+			in.PrevOutput.PkScript, tx, idx,
+			txscript.StandardVerifyFlags, nil, sigHashes,
+			in.PrevOutput.Value, prevOuts,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := vm.Execute(); err != nil {
+			return fmt.Errorf("invalid witness for input %v: %w",
+				in.OutPoint, err)
+		}
+	}
+
+	return nil
+}
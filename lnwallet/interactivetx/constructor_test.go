@@ -0,0 +1,282 @@
+package interactivetx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// testPkScript is a P2WPKH script used for the test inputs and
+	// outputs.
+	testPkScript = append(
+		[]byte{0x00, 0x14}, make([]byte, 20)...,
+	)
+
+	testChanID = lnwire.ChannelID{1}
+)
+
+// newTestInput returns an input that spends an output of the given value.
+func newTestInput(value int64, lockTime uint32) *Input {
+	prevTx := wire.NewMsgTx(2)
+	prevTx.LockTime = lockTime
+	prevTx.AddTxIn(&wire.TxIn{})
+	prevTx.AddTxOut(wire.NewTxOut(value, testPkScript))
+
+	return &Input{
+		OutPoint:   wire.OutPoint{Hash: prevTx.TxHash()},
+		PrevTx:     prevTx,
+		PrevOutput: prevTx.TxOut[0],
+		Sequence:   wire.MaxTxInSequenceNum - 2,
+	}
+}
+
+// negotiate runs the negotiation between both constructors until it's
+// complete.
+func negotiate(t *testing.T, initiator, responder *Constructor) {
+	t.Helper()
+
+	sender, receiver := initiator, responder
+	for i := 0; !initiator.IsComplete(); i++ {
+		require.Less(t, i, 100, "negotiation doesn't terminate")
+
+		msg, err := sender.Send()
+		require.NoError(t, err)
+		require.NoError(t, receiver.Receive(msg))
+
+		sender, receiver = receiver, sender
+	}
+
+	require.True(t, responder.IsComplete())
+}
+
+// TestConstructor tests that both parties of a negotiation end up with the
+// same transaction.
+func TestConstructor(t *testing.T) {
+	t.Parallel()
+
+	sharedOutput := wire.NewTxOut(100_000, testPkScript)
+	sharedInput := SharedInput{
+		OutPoint: wire.OutPoint{Hash: chainhash.Hash{9}, Index: 1},
+		Output:   sharedOutput,
+	}
+
+	aliceInput := newTestInput(50_000, 1)
+	bobInput := newTestInput(20_000, 2)
+
+	alice := NewConstructor(Config{
+		ChanID:      testChanID,
+		Initiator:   true,
+		LockTime:    500,
+		SharedInput: fn.Some(sharedInput),
+		Inputs: []*Input{
+			{
+				OutPoint:   sharedInput.OutPoint,
+				PrevOutput: sharedOutput,
+				Sequence:   wire.MaxTxInSequenceNum - 2,
+				Shared:     true,
+			},
+			aliceInput,
+		},
+		Outputs: []*Output{
+			{Value: 145_000, PkScript: testPkScript},
+		},
+	})
+	bob := NewConstructor(Config{
+		ChanID:      testChanID,
+		LockTime:    500,
+		SharedInput: fn.Some(sharedInput),
+		Inputs:      []*Input{bobInput},
+		Outputs: []*Output{
+			{Value: 19_000, PkScript: testPkScript},
+		},
+	})
+
+	// Only the initiator may send the first message.
+	_, err := bob.Send()
+	require.ErrorIs(t, err, ErrNotOurTurn)
+
+	_, err = alice.Tx()
+	require.ErrorIs(t, err, ErrNotComplete)
+
+	negotiate(t, alice, bob)
+
+	aliceTx, err := alice.Tx()
+	require.NoError(t, err)
+	bobTx, err := bob.Tx()
+	require.NoError(t, err)
+	require.Equal(t, aliceTx.TxHash(), bobTx.TxHash())
+
+	require.Len(t, aliceTx.TxIn, 3)
+	require.Len(t, aliceTx.TxOut, 2)
+	require.Equal(t, sharedInput.OutPoint, aliceTx.TxIn[0].PreviousOutPoint)
+	require.Equal(t, uint32(500), aliceTx.LockTime)
+
+	// Each party signs its own inputs, excluding the shared one.
+	require.Len(t, alice.LocalInputs(), 1)
+	require.Equal(t, aliceInput.OutPoint, alice.LocalInputs()[0].OutPoint)
+	require.Len(t, bob.RemoteInputs(), 1)
+	require.Equal(t, aliceInput.OutPoint, bob.RemoteInputs()[0].OutPoint)
+	require.Len(t, bob.LocalInputs(), 1)
+	require.Equal(t, bobInput.OutPoint, bob.LocalInputs()[0].OutPoint)
+
+	// Bob contributed less, so he signs first.
+	require.False(t, alice.LocalSignsFirst())
+	require.True(t, bob.LocalSignsFirst())
+
+	// No more messages can be exchanged once the negotiation is complete.
+	_, err = alice.Send()
+	require.ErrorIs(t, err, ErrComplete)
+}
+
+// TestConstructorRemoteViolations tests that invalid messages of the remote
+// party are rejected.
+func TestConstructorRemoteViolations(t *testing.T) {
+	t.Parallel()
+
+	prevTx := newTestInput(10_000, 1).PrevTx
+
+	addInput := func(serialID uint64) *lnwire.TxAddInput {
+		msg := &lnwire.TxAddInput{
+			ChannelID: testChanID,
+			SerialID:  serialID,
+			PrevTxOut: 0,
+			Sequence:  wire.MaxTxInSequenceNum - 2,
+		}
+
+		var b bytes.Buffer
+		require.NoError(t, prevTx.Serialize(&b))
+		msg.PrevTx = b.Bytes()
+
+		return msg
+	}
+
+	testCases := []struct {
+		name   string
+		msgs   []lnwire.Message
+		errStr string
+	}{
+		{
+			name:   "wrong parity",
+			msgs:   []lnwire.Message{addInput(1)},
+			errStr: "wrong parity",
+		},
+		{
+			name: "duplicate serial id",
+			msgs: []lnwire.Message{
+				addInput(0), addInput(0),
+			},
+			errStr: "duplicate serial id",
+		},
+		{
+			name: "duplicate input",
+			msgs: []lnwire.Message{
+				addInput(0), addInput(2),
+			},
+			errStr: "duplicate input",
+		},
+		{
+			name: "final sequence",
+			msgs: []lnwire.Message{
+				&lnwire.TxAddInput{
+					ChannelID: testChanID,
+					Sequence:  wire.MaxTxInSequenceNum,
+				},
+			},
+			errStr: "replaceability",
+		},
+		{
+			name: "unknown remove",
+			msgs: []lnwire.Message{
+				&lnwire.TxRemoveInput{
+					ChannelID: testChanID,
+					SerialID:  2,
+				},
+			},
+			errStr: "unknown input",
+		},
+		{
+			name: "invalid output amount",
+			msgs: []lnwire.Message{
+				&lnwire.TxAddOutput{
+					ChannelID: testChanID,
+					Script:    testPkScript,
+				},
+			},
+			errStr: "invalid output amount",
+		},
+		{
+			name: "outputs exceed inputs",
+			msgs: []lnwire.Message{
+				addInput(0),
+				&lnwire.TxAddOutput{
+					ChannelID: testChanID,
+					SerialID:  2,
+					Amount:    btcutil.Amount(20_000),
+					Script:    testPkScript,
+				},
+				&lnwire.TxComplete{ChannelID: testChanID},
+			},
+			errStr: "exceeds inputs value",
+		},
+		{
+			name: "unexpected shared input",
+			msgs: []lnwire.Message{
+				func() lnwire.Message {
+					msg := addInput(0)
+					msg.PrevTx = nil
+					msg.SharedInputTxid = tlv.SomeRecordT(
+						tlv.NewPrimitiveRecord[
+							tlv.TlvType0, [32]byte,
+						](chainhash.Hash{1}),
+					)
+
+					return msg
+				}(),
+			},
+			errStr: "unexpected shared input",
+		},
+		{
+			name: "abort",
+			msgs: []lnwire.Message{
+				&lnwire.TxAbort{
+					ChannelID: testChanID,
+					Data:      []byte("bye"),
+				},
+			},
+			errStr: ErrAborted.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			responder := NewConstructor(Config{
+				ChanID: testChanID,
+			})
+
+			var err error
+			for _, msg := range tc.msgs {
+				err = responder.Receive(msg)
+				if err != nil {
+					break
+				}
+
+				// Respond with tx_complete so it's the
+				// initiator's turn again.
+				_, err = responder.Send()
+				require.NoError(t, err)
+			}
+
+			require.ErrorContains(t, err, tc.errStr)
+		})
+	}
+}
@@ -124,6 +124,10 @@ type ChannelContribution struct {
 	// send to the remote party.
 	FirstCommitmentPoint *btcec.PublicKey
 
+	// SecondCommitmentPoint is the commitment point of the second
+	// commitment transaction. It's sent upfront by dual funded channels.
+	SecondCommitmentPoint *btcec.PublicKey
+
 	// ChannelConfig is the concrete contribution that this node is
 	// offering to the channel. This includes all the various constraints
 	// such as the min HTLC, and also all the keys which will be used for
@@ -267,10 +271,36 @@ func NewChannelReservation(capacity, localFundingAmt btcutil.Amount,
 	// Used to cut down on verbosity.
 	defaultDust := DustLimitUnknownWitness()
 
+	switch {
+	// For a dual funded channel, each party starts out with the amount it
+	// contributed, and the initiator pays for the commitment fee. The
+	// remote contribution of the initiator's reservation is only known
+	// once the responder accepted, and is set with SetRemoteFundingAmt.
+	case req.DualFund:
+		ourBalance = localFundingMSat
+		theirBalance = capacityMSat - localFundingMSat
+		initiator = req.Initiator
+
+		funderBalance := &theirBalance
+		if initiator {
+			funderBalance = &ourBalance
+		}
+
+		// If the initiator doesn't have enough funds to actually pay
+		// the fees, then we'll bail out early.
+		if *funderBalance < feeMSat {
+			return nil, ErrFunderBalanceDust(
+				int64(commitFee),
+				int64(funderBalance.ToSatoshis()),
+				int64(2*defaultDust),
+			)
+		}
+		*funderBalance -= feeMSat
+
 	// If we're the responder to a single-funder reservation, then we have
 	// no initial balance in the channel unless the remote party is pushing
 	// some funds to us within the first commitment state.
-	if localFundingAmt == 0 {
+	case localFundingAmt == 0:
 		ourBalance = req.PushMSat
 		theirBalance = capacityMSat - feeMSat - req.PushMSat
 		initiator = false
@@ -283,7 +313,8 @@ func NewChannelReservation(capacity, localFundingAmt btcutil.Amount,
 				int64(2*defaultDust),
 			)
 		}
-	} else {
+
+	default:
 		// TODO(roasbeef): need to rework fee structure in general and
 		// also when we "unlock" dual funder within the daemon
 
@@ -344,7 +375,19 @@ func NewChannelReservation(capacity, localFundingAmt btcutil.Amount,
 	// If either of the balances are zero at this point, or we have a
 	// non-zero push amt (there's no pushing for dual funder), then this is
 	// a single-funder channel.
-	if ourBalance == 0 || theirBalance == 0 || req.PushMSat != 0 {
+	isSingleFunder := ourBalance == 0 || theirBalance == 0 ||
+		req.PushMSat != 0
+	switch {
+	// A channel opened with open_channel2 is always dual funded, even if
+	// only one party contributes funds. Unlike the legacy dual funder
+	// workflow, the party that sent open_channel2 is the initiator.
+	case req.DualFund:
+		chanType |= channeldb.DualFunderBit
+		if req.CommitType.HasStaticRemoteKey() {
+			chanType |= channeldb.SingleFunderTweaklessBit
+		}
+
+	case isSingleFunder:
 		// Both the tweakless type and the anchor type is tweakless,
 		// hence set the bit.
 		if req.CommitType.HasStaticRemoteKey() {
@@ -373,7 +416,8 @@ func NewChannelReservation(capacity, localFundingAmt btcutil.Amount,
 		default:
 			chanType |= channeldb.NoFundingTxBit
 		}
-	} else {
+
+	default:
 		// Otherwise, this is a dual funder channel, and no side is
 		// technically the "initiator"
 		initiator = false
@@ -618,6 +662,63 @@ func (r *ChannelReservation) ProcessPsbt() error {
 	return <-errChan
 }
 
+// IsInteractive returns true if the funding transaction of this reservation
+// is constructed interactively with the remote party, as for a channel
+// opened with open_channel2.
+func (r *ChannelReservation) IsInteractive() bool {
+	_, ok := r.fundingIntent.(*chanfunding.InteractiveIntent)
+	return ok
+}
+
+// SetRemoteFundingAmt records the amount the remote party contributes to a
+// dual funded channel we initiated, once it accepted the channel. The remote
+// party starts out with its full contribution, as we pay the commitment fee.
+func (r *ChannelReservation) SetRemoteFundingAmt(amt btcutil.Amount) error {
+	r.Lock()
+	defer r.Unlock()
+
+	intent, ok := r.fundingIntent.(*chanfunding.InteractiveIntent)
+	if !ok || !r.partialState.IsInitiator {
+		return errors.New("remote funding amount can only be set " +
+			"by the initiator of a dual funded channel")
+	}
+
+	intent.SetRemoteFundingAmt(amt)
+
+	remoteBalance := lnwire.NewMSatFromSatoshis(amt)
+	r.partialState.Capacity = intent.LocalFundingAmt() + amt
+	r.partialState.LocalCommitment.RemoteBalance = remoteBalance
+	r.partialState.RemoteCommitment.RemoteBalance = remoteBalance
+	r.partialState.InitialRemoteBalance = remoteBalance
+	r.theirContribution.FundingAmount = amt
+
+	return nil
+}
+
+// ProcessInteractiveTx continues a previously paused funding flow once the
+// funding transaction has been negotiated with the remote party. Afterwards,
+// our signature for the remote commitment transaction is available via
+// OurSignatures.
+func (r *ChannelReservation) ProcessInteractiveTx(fundingTx *wire.MsgTx) error {
+	intent, ok := r.fundingIntent.(*chanfunding.InteractiveIntent)
+	if !ok {
+		return errors.New("reservation isn't funded interactively")
+	}
+
+	if err := intent.SetFundingTx(fundingTx); err != nil {
+		return err
+	}
+
+	errChan := make(chan error, 1)
+
+	r.wallet.msgChan <- &continueContributionMsg{
+		pendingFundingID: r.reservationID,
+		err:              errChan,
+	}
+
+	return <-errChan
+}
+
 // RemoteCanceled informs the PSBT funding state machine that the remote peer
 // has canceled the pending reservation, likely due to a timeout.
 func (r *ChannelReservation) RemoteCanceled() {
@@ -0,0 +1,964 @@
+package lnwallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// ErrSpliceUnsupportedChanType is returned when a splice is attempted
+	// for a channel type that can't be spliced yet.
+	ErrSpliceUnsupportedChanType = errors.New("splicing is not " +
+		"supported for this channel type")
+
+	// ErrSpliceChannelNotClean is returned when a splice is attempted for
+	// a channel that has pending updates.
+	ErrSpliceChannelNotClean = errors.New("channel must be clean to be " +
+		"spliced")
+
+	// ErrInvalidSpliceSig is returned when the remote party's signature
+	// for a splice commitment or the shared funding input is invalid.
+	ErrInvalidSpliceSig = errors.New("invalid splice signature")
+)
+
+// SpliceTx describes a splice transaction that has been negotiated with the
+// remote party.
+type SpliceTx struct {
+	// Tx is the splice transaction. It spends the current funding output
+	// of the channel and creates the new one.
+	Tx *wire.MsgTx
+
+	// FundingOutputIndex is the index of the new funding output in Tx.
+	FundingOutputIndex uint32
+
+	// LocalContribution is the amount our balance changes by. It's
+	// negative if we splice funds out of the channel, and includes the
+	// share of the fees we pay for the splice transaction.
+	LocalContribution btcutil.Amount
+
+	// RemoteContribution is the amount the remote party's balance changes
+	// by.
+	RemoteContribution btcutil.Amount
+}
+
+// SpliceFundingOutput returns the witness script and the funding output for a
+// channel with the given capacity, using the channel's multi-sig keys.
+func SpliceFundingOutput(chanState *channeldb.OpenChannel,
+	capacity btcutil.Amount) ([]byte, *wire.TxOut, error) {
+
+	localKey := chanState.LocalChanCfg.MultiSigKey.PubKey
+	remoteKey := chanState.RemoteChanCfg.MultiSigKey.PubKey
+
+	return input.GenFundingPkScript(
+		localKey.SerializeCompressed(), remoteKey.SerializeCompressed(),
+		int64(capacity),
+	)
+}
+
+// pendingSplice is a splice transaction of the channel that has been signed
+// by both parties, but hasn't been promoted yet. Every commitment of the
+// channel has a variant that spends its funding output instead of the current
+// one, so the channel can be force closed should the splice confirm.
+type pendingSplice struct {
+	// candidate is the persisted splice candidate.
+	candidate *channeldb.SpliceCandidate
+
+	// txid is the txid of the splice transaction.
+	txid chainhash.Hash
+
+	// fundingTxIn is the funding output of the splice transaction as the
+	// input of a commitment transaction.
+	fundingTxIn wire.TxIn
+
+	// signDesc is the sign descriptor for the funding output of the
+	// splice transaction. The sighashes must be set before signing.
+	signDesc *input.SignDescriptor
+}
+
+// newPendingSplice creates a pendingSplice for the given splice candidate.
+func newPendingSplice(chanState *channeldb.OpenChannel,
+	candidate *channeldb.SpliceCandidate) (*pendingSplice, error) {
+
+	witnessScript, fundingOutput, err := SpliceFundingOutput(
+		chanState, candidate.Capacity,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fundingPoint := candidate.FundingOutpoint()
+
+	return &pendingSplice{
+		candidate:   candidate,
+		txid:        fundingPoint.Hash,
+		fundingTxIn: *wire.NewTxIn(&fundingPoint, nil, nil),
+		signDesc: &input.SignDescriptor{
+			KeyDesc:       chanState.LocalChanCfg.MultiSigKey,
+			WitnessScript: witnessScript,
+			Output:        fundingOutput,
+			HashType:      txscript.SigHashAll,
+			InputIndex:    0,
+		},
+	}, nil
+}
+
+// restorePendingSplices restores the splice candidates of the channel, whose
+// variants are created for every new commitment until one of them is promoted.
+func (lc *LightningChannel) restorePendingSplices() error {
+	candidates, err := lc.channelState.SpliceCandidates()
+	switch {
+	// A channel that isn't persisted yet, like one that is still being
+	// funded, can't have been spliced.
+	case errors.Is(err, channeldb.ErrNoChanDBExists),
+		errors.Is(err, channeldb.ErrNoActiveChannels),
+		errors.Is(err, channeldb.ErrChannelNotFound):
+
+		return nil
+
+	case err != nil:
+		return err
+	}
+
+	for _, candidate := range candidates {
+		splice, err := newPendingSplice(lc.channelState, candidate)
+		if err != nil {
+			return err
+		}
+
+		lc.pendingSplices = append(lc.pendingSplices, splice)
+	}
+
+	return nil
+}
+
+// checkSpliceable returns an error if the channel can't be spliced in its
+// current state.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) checkSpliceable() error {
+	if lc.channelState.ChanType.IsTaproot() {
+		return ErrSpliceUnsupportedChanType
+	}
+
+	// The variants of the current commitments are signed during the
+	// splice negotiation, so there can't be any commitment in flight.
+	if lc.localCommitChain.hasUnackedCommitment() ||
+		lc.remoteCommitChain.hasUnackedCommitment() ||
+		lc.oweCommitment(lntypes.Local) ||
+		lc.oweCommitment(lntypes.Remote) {
+
+		return ErrSpliceChannelNotClean
+	}
+
+	// The signatures for the new variants are sent along with the ones
+	// for the variants of any other pending splice, so the HTLCs of the
+	// channel must still fit into a single CommitSig message.
+	numHtlcs := len(lc.localCommitChain.tail().incomingHTLCs) +
+		len(lc.localCommitChain.tail().outgoingHTLCs)
+	maxHtlcs := lnwire.MaxSpliceCommitHtlcs(len(lc.pendingSplices) + 1)
+	if numHtlcs > maxHtlcs {
+		return fmt.Errorf("%w: %v htlcs exceed limit of %v for "+
+			"splicing", ErrMaxHTLCNumber, numHtlcs, maxHtlcs)
+	}
+
+	return nil
+}
+
+// validateSpliceHtlcs returns an error if the HTLCs of the given commitment
+// view and of its variants for the pending splices don't fit into a single
+// CommitSig message.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) validateSpliceHtlcs(view *htlcView) error {
+	if len(lc.pendingSplices) == 0 {
+		return nil
+	}
+
+	numHtlcs := len(view.ourUpdates) + len(view.theirUpdates)
+	maxHtlcs := lnwire.MaxSpliceCommitHtlcs(len(lc.pendingSplices))
+	if numHtlcs > maxHtlcs {
+		return fmt.Errorf("%w: %v htlcs exceed limit of %v while "+
+			"splice is pending", ErrMaxHTLCNumber, numHtlcs,
+			maxHtlcs)
+	}
+
+	return nil
+}
+
+// spliceCommitment creates the variant of the given commitment that spends the
+// funding output of the pending splice. The passed balances are the ones of
+// the commitment before subtracting the commitment fee, to which the
+// contributions of both parties to the splice are added.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) spliceCommitment(c *commitment,
+	splice *pendingSplice, ourBalance, theirBalance lnwire.MilliSatoshi,
+	view *htlcView, keyRing *CommitmentKeyRing) (*commitment, error) {
+
+	// The contributions may be negative, so the balances are computed as
+	// signed amounts.
+	localContribution := lnwire.NewMSatFromSatoshis(
+		splice.candidate.LocalContribution,
+	)
+	remoteContribution := lnwire.NewMSatFromSatoshis(
+		splice.candidate.RemoteContribution,
+	)
+	ourSpliceBalance := int64(ourBalance) + int64(localContribution)
+	theirSpliceBalance := int64(theirBalance) + int64(remoteContribution)
+
+	if ourSpliceBalance < 0 || theirSpliceBalance < 0 {
+		return nil, fmt.Errorf("splice leaves negative balance: "+
+			"local=%v, remote=%v", ourSpliceBalance,
+			theirSpliceBalance)
+	}
+
+	// The variant carries the same HTLCs at possibly different output
+	// indexes, so we'll work on copies of them that don't carry the
+	// signatures for the original commitment.
+	spliceView := &htlcView{feePerKw: view.feePerKw}
+	for _, htlc := range view.ourUpdates {
+		htlcCopy := *htlc
+		htlcCopy.sig = nil
+		spliceView.ourUpdates = append(
+			spliceView.ourUpdates, &htlcCopy,
+		)
+	}
+	for _, htlc := range view.theirUpdates {
+		htlcCopy := *htlc
+		htlcCopy.sig = nil
+		spliceView.theirUpdates = append(
+			spliceView.theirUpdates, &htlcCopy,
+		)
+	}
+
+	commitTx, err := lc.commitBuilder.createUnsignedCommitmentTx(
+		lnwire.MilliSatoshi(ourSpliceBalance),
+		lnwire.MilliSatoshi(theirSpliceBalance), c.whoseCommit,
+		c.feePerKw, c.height, spliceView, keyRing, splice.fundingTxIn,
+		splice.candidate.Capacity,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	spliceCommit := &commitment{
+		ourBalance:        commitTx.ourBalance,
+		theirBalance:      commitTx.theirBalance,
+		txn:               commitTx.txn,
+		fee:               commitTx.fee,
+		ourMessageIndex:   c.ourMessageIndex,
+		ourHtlcIndex:      c.ourHtlcIndex,
+		theirMessageIndex: c.theirMessageIndex,
+		theirHtlcIndex:    c.theirHtlcIndex,
+		height:            c.height,
+		feePerKw:          c.feePerKw,
+		dustLimit:         c.dustLimit,
+		whoseCommit:       c.whoseCommit,
+	}
+
+	spliceCommit.outgoingHTLCs = make(
+		[]PaymentDescriptor, len(spliceView.ourUpdates),
+	)
+	for i, htlc := range spliceView.ourUpdates {
+		spliceCommit.outgoingHTLCs[i] = *htlc
+	}
+	spliceCommit.incomingHTLCs = make(
+		[]PaymentDescriptor, len(spliceView.theirUpdates),
+	)
+	for i, htlc := range spliceView.theirUpdates {
+		spliceCommit.incomingHTLCs[i] = *htlc
+	}
+
+	err = spliceCommit.populateHtlcIndexes(
+		lc.channelState.ChanType, commitTx.cltvs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return spliceCommit, nil
+}
+
+// tailSpliceCommitment creates the variant of the given commitment at the tail
+// of a commitment chain that spends the funding output of the pending splice.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) tailSpliceCommitment(c *commitment,
+	splice *pendingSplice, keyRing *CommitmentKeyRing) (*commitment,
+	error) {
+
+	// The commitment fee is paid by the initiator, so we'll add it back
+	// to get the balances before subtracting it.
+	ourBalance, theirBalance := c.ourBalance, c.theirBalance
+	if lc.channelState.IsInitiator {
+		ourBalance += lnwire.NewMSatFromSatoshis(c.fee)
+	} else {
+		theirBalance += lnwire.NewMSatFromSatoshis(c.fee)
+	}
+
+	view := &htlcView{feePerKw: c.feePerKw}
+	for i := range c.outgoingHTLCs {
+		view.ourUpdates = append(view.ourUpdates, &c.outgoingHTLCs[i])
+	}
+	for i := range c.incomingHTLCs {
+		view.theirUpdates = append(
+			view.theirUpdates, &c.incomingHTLCs[i],
+		)
+	}
+
+	return lc.spliceCommitment(
+		c, splice, ourBalance, theirBalance, view, keyRing,
+	)
+}
+
+// signSpliceCommitment signs the variant of a remote commitment that spends
+// the funding output of the pending splice, along with its HTLCs.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) signSpliceCommitment(commit *commitment,
+	splice *pendingSplice,
+	keyRing *CommitmentKeyRing) (lnwire.SpliceCommitSig, error) {
+
+	var leaseExpiry uint32
+	if lc.channelState.ChanType.HasLeaseExpiration() {
+		leaseExpiry = lc.channelState.ThawHeight
+	}
+	sigBatch, cancelChan, err := genRemoteHtlcSigJobs(
+		keyRing, lc.channelState.ChanType, !lc.channelState.IsInitiator,
+		leaseExpiry, &lc.channelState.LocalChanCfg,
+		&lc.channelState.RemoteChanCfg, commit,
+	)
+	if err != nil {
+		return lnwire.SpliceCommitSig{}, err
+	}
+	lc.sigPool.SubmitSignBatch(sigBatch)
+
+	signDesc := *splice.signDesc
+	signDesc.SigHashes = input.NewTxSigHashesV0Only(commit.txn)
+	rawSig, err := lc.Signer.SignOutputRaw(commit.txn, &signDesc)
+	if err != nil {
+		close(cancelChan)
+		return lnwire.SpliceCommitSig{}, err
+	}
+	sig, err := lnwire.NewSigFromSignature(rawSig)
+	if err != nil {
+		close(cancelChan)
+		return lnwire.SpliceCommitSig{}, err
+	}
+
+	sort.Slice(sigBatch, func(i, j int) bool {
+		return sigBatch[i].OutputIndex < sigBatch[j].OutputIndex
+	})
+
+	htlcSigs := make([]lnwire.Sig, 0, len(sigBatch))
+	for _, htlcSigJob := range sigBatch {
+		jobResp := <-htlcSigJob.Resp
+		if jobResp.Err != nil {
+			close(cancelChan)
+			return lnwire.SpliceCommitSig{}, jobResp.Err
+		}
+
+		htlcSigs = append(htlcSigs, jobResp.Sig)
+	}
+
+	return lnwire.SpliceCommitSig{
+		FundingTxID: splice.txid,
+		CommitSig:   sig,
+		HtlcSigs:    htlcSigs,
+	}, nil
+}
+
+// signSpliceCommitments signs the variants of the new remote commitment for
+// all pending splices.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) signSpliceCommitments(newCommit *commitment,
+	keyRing *CommitmentKeyRing) ([]lnwire.SpliceCommitSig, error) {
+
+	spliceSigs := make([]lnwire.SpliceCommitSig, 0, len(lc.pendingSplices))
+	for _, splice := range lc.pendingSplices {
+		spliceCommit, ok := newCommit.spliceCommits[splice.txid]
+		if !ok {
+			return nil, fmt.Errorf("missing commitment for "+
+				"splice %v", splice.txid)
+		}
+
+		sig, err := lc.signSpliceCommitment(
+			spliceCommit, splice, keyRing,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		spliceSigs = append(spliceSigs, sig)
+	}
+
+	return spliceSigs, nil
+}
+
+// verifySpliceCommitSig verifies the remote party's signatures for the variant
+// of a local commitment that spends the funding output of the pending splice,
+// and stores them within the commitment.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) verifySpliceCommitSig(commit *commitment,
+	splice *pendingSplice, keyRing *CommitmentKeyRing,
+	sig lnwire.SpliceCommitSig) error {
+
+	var leaseExpiry uint32
+	if lc.channelState.ChanType.HasLeaseExpiration() {
+		leaseExpiry = lc.channelState.ThawHeight
+	}
+	verifyJobs, err := genHtlcSigValidationJobs(
+		commit, keyRing, sig.HtlcSigs, lc.channelState.ChanType,
+		lc.channelState.IsInitiator, leaseExpiry,
+		&lc.channelState.LocalChanCfg, &lc.channelState.RemoteChanCfg,
+	)
+	if err != nil {
+		return err
+	}
+
+	cancelChan := make(chan struct{})
+	verifyResps := lc.sigPool.SubmitVerifyBatch(verifyJobs, cancelChan)
+
+	witnessScript := splice.signDesc.WitnessScript
+	capacity := int64(splice.candidate.Capacity)
+	prevFetcher := txscript.NewCannedPrevOutputFetcher(
+		witnessScript, capacity,
+	)
+	hashCache := txscript.NewTxSigHashes(commit.txn, prevFetcher)
+	sigHash, err := txscript.CalcWitnessSigHash(
+		witnessScript, hashCache, txscript.SigHashAll, commit.txn, 0,
+		capacity,
+	)
+	if err != nil {
+		close(cancelChan)
+		return err
+	}
+
+	cSig, err := sig.CommitSig.ToSignature()
+	if err != nil {
+		close(cancelChan)
+		return err
+	}
+
+	verifyKey := lc.channelState.RemoteChanCfg.MultiSigKey.PubKey
+	if !cSig.Verify(sigHash, verifyKey) {
+		close(cancelChan)
+		return fmt.Errorf("%w: commitment %v of splice %v",
+			ErrInvalidSpliceSig, commit.txn.TxHash(), splice.txid)
+	}
+
+	for i := 0; i < len(verifyJobs); i++ {
+		htlcErr := <-verifyResps
+		if htlcErr != nil {
+			close(cancelChan)
+			return fmt.Errorf("%w: htlc %v of splice %v",
+				ErrInvalidSpliceSig, htlcErr.HtlcIndex,
+				splice.txid)
+		}
+	}
+
+	commit.sig = sig.CommitSig.ToSignatureBytes()
+
+	return nil
+}
+
+// verifySpliceCommitSigs verifies the remote party's signatures for the
+// variants of the new local commitment for all pending splices.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) verifySpliceCommitSigs(newCommit *commitment,
+	keyRing *CommitmentKeyRing, sigs []lnwire.SpliceCommitSig) error {
+
+	for _, splice := range lc.pendingSplices {
+		spliceCommit, ok := newCommit.spliceCommits[splice.txid]
+		if !ok {
+			return fmt.Errorf("missing commitment for splice %v",
+				splice.txid)
+		}
+
+		sig, err := fn.Find(func(sig lnwire.SpliceCommitSig) bool {
+			return sig.FundingTxID == splice.txid
+		}, sigs).UnwrapOrErr(fmt.Errorf("%w: missing signature for "+
+			"splice %v", ErrInvalidSpliceSig, splice.txid))
+		if err != nil {
+			return err
+		}
+
+		err = lc.verifySpliceCommitSig(
+			spliceCommit, splice, keyRing, sig,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// currentFundingSigs returns the signatures for the commitment spending the
+// current funding output of the channel. If the remote party signed the
+// commitment before it promoted the splice we've already switched over to,
+// those are found among its splice signatures rather than the regular ones.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) currentFundingSigs(
+	commitSigs *CommitSigs) *CommitSigs {
+
+	fundingTxid := lc.channelState.CurrentFundingOutpoint().Hash
+	spliceSig := fn.Find(func(sig lnwire.SpliceCommitSig) bool {
+		return sig.FundingTxID == fundingTxid
+	}, commitSigs.SpliceSigs)
+
+	spliceSig.WhenSome(func(sig lnwire.SpliceCommitSig) {
+		commitSigs = &CommitSigs{
+			CommitSig:  sig.CommitSig,
+			HtlcSigs:   sig.HtlcSigs,
+			PartialSig: commitSigs.PartialSig,
+			SpliceSigs: commitSigs.SpliceSigs,
+		}
+	})
+
+	return commitSigs
+}
+
+// spliceCapacity returns the value of the new funding output of the splice
+// transaction, after checking that it matches the contributions of both
+// parties.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) spliceCapacity(splice *SpliceTx) (btcutil.Amount,
+	error) {
+
+	if int(splice.FundingOutputIndex) >= len(splice.Tx.TxOut) {
+		return 0, fmt.Errorf("invalid funding output index %v",
+			splice.FundingOutputIndex)
+	}
+
+	fundingOutput := splice.Tx.TxOut[splice.FundingOutputIndex]
+	capacity := btcutil.Amount(fundingOutput.Value)
+
+	expectedCapacity := lc.channelState.Capacity +
+		splice.LocalContribution + splice.RemoteContribution
+	if capacity != expectedCapacity {
+		return 0, fmt.Errorf("funding output value %v doesn't match "+
+			"expected capacity %v", capacity, expectedCapacity)
+	}
+
+	_, expectedOutput, err := SpliceFundingOutput(
+		lc.channelState, capacity,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if string(expectedOutput.PkScript) != string(fundingOutput.PkScript) {
+		return 0, fmt.Errorf("invalid funding output script")
+	}
+
+	return capacity, nil
+}
+
+// spliceRevocationLog returns a copy of the revocation log of a revoked remote
+// commitment whose output indexes point into the given breach transaction,
+// which is the variant of the commitment that spends the funding output of a
+// splice. As the variant carries the same outputs with different values, its
+// outputs are located by their scripts, and HTLCs additionally by their
+// amounts.
+func spliceRevocationLog(revokedLog *channeldb.RevocationLog,
+	spendTx *wire.MsgTx, chanState *channeldb.OpenChannel,
+	keyRing *CommitmentKeyRing, ourScript,
+	theirScript input.ScriptDescriptor) (*channeldb.RevocationLog, error) {
+
+	spliceLog := *revokedLog
+	spliceLog.CommitTxHash = spendTx.TxHash()
+	spliceLog.OurOutputIndex = channeldb.OutputIndexEmpty
+	spliceLog.TheirOutputIndex = channeldb.OutputIndexEmpty
+	spliceLog.HTLCEntries = make(
+		[]*channeldb.HTLCEntry, 0, len(revokedLog.HTLCEntries),
+	)
+
+	for i, txOut := range spendTx.TxOut {
+		switch {
+		case bytes.Equal(txOut.PkScript, ourScript.PkScript()):
+			spliceLog.OurOutputIndex = uint16(i)
+
+		case bytes.Equal(txOut.PkScript, theirScript.PkScript()):
+			spliceLog.TheirOutputIndex = uint16(i)
+		}
+	}
+
+	// HTLCs with the same script and amount can be matched in any order,
+	// so we'll only need to make sure each output is used once.
+	usedOutputs := make(map[int]struct{})
+	for _, htlc := range revokedLog.HTLCEntries {
+		scriptInfo, err := genHtlcScript(
+			chanState.ChanType, htlc.Incoming, lntypes.Remote,
+			htlc.RefundTimeout, htlc.RHash, keyRing,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		pkScript := scriptInfo.PkScript()
+		outputIndex := -1
+		for i, txOut := range spendTx.TxOut {
+			if _, ok := usedOutputs[i]; ok {
+				continue
+			}
+
+			if txOut.Value == int64(htlc.Amt) &&
+				bytes.Equal(txOut.PkScript, pkScript) {
+
+				outputIndex = i
+				break
+			}
+		}
+		if outputIndex == -1 {
+			return nil, fmt.Errorf("htlc %x not found in breach "+
+				"transaction %v", htlc.RHash[:],
+				spliceLog.CommitTxHash)
+		}
+		usedOutputs[outputIndex] = struct{}{}
+
+		htlcCopy := *htlc
+		htlcCopy.OutputIndex = uint16(outputIndex)
+		spliceLog.HTLCEntries = append(spliceLog.HTLCEntries, &htlcCopy)
+	}
+
+	return &spliceLog, nil
+}
+
+// SignSpliceCommitment creates the variant of the remote party's current
+// commitment that spends the funding output of the negotiated splice
+// transaction, and signs it along with its HTLCs. The returned splice
+// candidate is only persisted by ReceiveSpliceCommitSig, once the remote
+// party's signatures for our own variant have been received.
+func (lc *LightningChannel) SignSpliceCommitment(
+	splice *SpliceTx) (*channeldb.SpliceCandidate, *CommitSigs, error) {
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	if err := lc.checkSpliceable(); err != nil {
+		return nil, nil, err
+	}
+
+	capacity, err := lc.spliceCapacity(splice)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidate := &channeldb.SpliceCandidate{
+		FundingTx:          splice.Tx,
+		FundingOutputIndex: splice.FundingOutputIndex,
+		Capacity:           capacity,
+		LocalContribution:  splice.LocalContribution,
+		RemoteContribution: splice.RemoteContribution,
+	}
+	pending, err := newPendingSplice(lc.channelState, candidate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyRing := DeriveCommitmentKeys(
+		lc.channelState.RemoteCurrentRevocation, lntypes.Remote,
+		lc.channelState.ChanType, &lc.channelState.LocalChanCfg,
+		&lc.channelState.RemoteChanCfg,
+	)
+	remoteCommit, err := lc.tailSpliceCommitment(
+		lc.remoteCommitChain.tail(), pending, keyRing,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig, err := lc.signSpliceCommitment(remoteCommit, pending, keyRing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return candidate, &CommitSigs{
+		CommitSig: sig.CommitSig,
+		HtlcSigs:  sig.HtlcSigs,
+	}, nil
+}
+
+// ReceiveSpliceCommitSig verifies the remote party's signatures for the
+// variant of our current commitment that spends the funding output of the
+// splice candidate, and persists the candidate along with the variants of the
+// current commitments of both parties once they're valid. From this point on,
+// every new commitment also gets a variant for the candidate, so the channel
+// can be force closed should its splice transaction confirm.
+func (lc *LightningChannel) ReceiveSpliceCommitSig(
+	candidate *channeldb.SpliceCandidate, commitSigs *CommitSigs) error {
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	if err := lc.checkSpliceable(); err != nil {
+		return err
+	}
+
+	pending, err := newPendingSplice(lc.channelState, candidate)
+	if err != nil {
+		return err
+	}
+
+	revPreimage, err := lc.channelState.RevocationProducer.AtIndex(
+		lc.currentHeight,
+	)
+	if err != nil {
+		return err
+	}
+	commitPoint := input.ComputeCommitmentPoint(revPreimage[:])
+	localKeyRing := DeriveCommitmentKeys(
+		commitPoint, lntypes.Local, lc.channelState.ChanType,
+		&lc.channelState.LocalChanCfg, &lc.channelState.RemoteChanCfg,
+	)
+	localTail := lc.localCommitChain.tail()
+	localCommit, err := lc.tailSpliceCommitment(
+		localTail, pending, localKeyRing,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = lc.verifySpliceCommitSig(
+		localCommit, pending, localKeyRing, lnwire.SpliceCommitSig{
+			FundingTxID: pending.txid,
+			CommitSig:   commitSigs.CommitSig,
+			HtlcSigs:    commitSigs.HtlcSigs,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	remoteKeyRing := DeriveCommitmentKeys(
+		lc.channelState.RemoteCurrentRevocation, lntypes.Remote,
+		lc.channelState.ChanType, &lc.channelState.LocalChanCfg,
+		&lc.channelState.RemoteChanCfg,
+	)
+	remoteTail := lc.remoteCommitChain.tail()
+	remoteCommit, err := lc.tailSpliceCommitment(
+		remoteTail, pending, remoteKeyRing,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = lc.channelState.AddSpliceCandidate(
+		candidate, localCommit.toDiskCommit(lntypes.Local),
+		remoteCommit.toDiskCommit(lntypes.Remote),
+	)
+	if err != nil {
+		return err
+	}
+
+	if localTail.spliceCommits == nil {
+		localTail.spliceCommits = make(map[chainhash.Hash]*commitment)
+	}
+	localTail.spliceCommits[pending.txid] = localCommit
+
+	if remoteTail.spliceCommits == nil {
+		remoteTail.spliceCommits = make(map[chainhash.Hash]*commitment)
+	}
+	remoteTail.spliceCommits[pending.txid] = remoteCommit
+
+	pendingSplices := make([]*pendingSplice, 0, len(lc.pendingSplices)+1)
+	for _, splice := range lc.pendingSplices {
+		if splice.txid != pending.txid {
+			pendingSplices = append(pendingSplices, splice)
+		}
+	}
+	lc.pendingSplices = append(pendingSplices, pending)
+
+	return nil
+}
+
+// RemoveSpliceCandidates removes all splice candidates of the channel along
+// with the variants of its commitments, which is the case if a splice is
+// aborted before its transaction has been broadcast.
+func (lc *LightningChannel) RemoveSpliceCandidates() error {
+	lc.Lock()
+	defer lc.Unlock()
+
+	if err := lc.channelState.RemoveSpliceCandidates(); err != nil {
+		return err
+	}
+
+	for _, chain := range []*commitmentChain{
+		lc.localCommitChain, lc.remoteCommitChain,
+	} {
+
+		for e := chain.commitments.Front(); e != nil; e = e.Next() {
+			e.Value.spliceCommits = nil
+		}
+	}
+
+	lc.pendingSplices = nil
+
+	return nil
+}
+
+// SignSharedInput signs the input of the splice transaction that spends the
+// current funding output of the channel.
+func (lc *LightningChannel) SignSharedInput(spliceTx *wire.MsgTx,
+	inputIndex int) (lnwire.Sig, error) {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	signDesc, err := lc.sharedInputSignDesc(spliceTx, inputIndex)
+	if err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	rawSig, err := lc.Signer.SignOutputRaw(spliceTx, signDesc)
+	if err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	return lnwire.NewSigFromSignature(rawSig)
+}
+
+// SharedInputWitness verifies the remote party's signature for the input of
+// the splice transaction that spends the current funding output, and returns
+// the witness for the input that combines it with our own signature.
+func (lc *LightningChannel) SharedInputWitness(spliceTx *wire.MsgTx,
+	inputIndex int, ourSig, theirSig lnwire.Sig) (wire.TxWitness, error) {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	signDesc, err := lc.sharedInputSignDesc(spliceTx, inputIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	sigHash, err := txscript.CalcWitnessSigHash(
+		signDesc.WitnessScript, signDesc.SigHashes,
+		txscript.SigHashAll, spliceTx, inputIndex,
+		signDesc.Output.Value,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	theirSignature, err := theirSig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	verifyKey := lc.channelState.RemoteChanCfg.MultiSigKey.PubKey
+	if !theirSignature.Verify(sigHash, verifyKey) {
+		return nil, fmt.Errorf("%w: shared input of %v",
+			ErrInvalidSpliceSig, spliceTx.TxHash())
+	}
+
+	ourSignature, err := ourSig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	return input.SpendMultiSig(
+		signDesc.WitnessScript,
+		lc.channelState.LocalChanCfg.MultiSigKey.PubKey.
+			SerializeCompressed(),
+		ourSignature,
+		lc.channelState.RemoteChanCfg.MultiSigKey.PubKey.
+			SerializeCompressed(),
+		theirSignature,
+	), nil
+}
+
+// sharedInputSignDesc returns the sign descriptor for the input of the splice
+// transaction that spends the current funding output.
+//
+// NOTE: This method requires the channel lock to be held.
+func (lc *LightningChannel) sharedInputSignDesc(spliceTx *wire.MsgTx,
+	inputIndex int) (*input.SignDescriptor, error) {
+
+	if lc.channelState.ChanType.IsTaproot() {
+		return nil, ErrSpliceUnsupportedChanType
+	}
+
+	if inputIndex < 0 || inputIndex >= len(spliceTx.TxIn) {
+		return nil, fmt.Errorf("invalid input index %v", inputIndex)
+	}
+
+	fundingPoint := lc.channelState.CurrentFundingOutpoint()
+	if spliceTx.TxIn[inputIndex].PreviousOutPoint != fundingPoint {
+		return nil, fmt.Errorf("input %v doesn't spend funding "+
+			"output %v", inputIndex, fundingPoint)
+	}
+
+	return &input.SignDescriptor{
+		KeyDesc:       lc.channelState.LocalChanCfg.MultiSigKey,
+		WitnessScript: lc.signDesc.WitnessScript,
+		Output:        &lc.fundingOutput,
+		HashType:      txscript.SigHashAll,
+		SigHashes:     input.NewTxSigHashesV0Only(spliceTx),
+		InputIndex:    inputIndex,
+	}, nil
+}
+
+// PromoteSplice switches the channel over to the funding output of the
+// confirmed splice transaction with the given txid. Every commitment of both
+// commitment chains, including any that hasn't been revoked or acked yet, is
+// replaced by its variant for the splice, so updates can continue without
+// interruption.
+func (lc *LightningChannel) PromoteSplice(txid chainhash.Hash) error {
+	lc.Lock()
+	defer lc.Unlock()
+
+	// The splice may already have been promoted, in which case there's
+	// nothing left to do.
+	if lc.channelState.CurrentFundingOutpoint().Hash == txid {
+		return nil
+	}
+
+	// Make sure that every commitment has a variant before modifying
+	// anything, so a failure leaves the channel untouched.
+	chains := []*commitmentChain{lc.localCommitChain, lc.remoteCommitChain}
+	for _, chain := range chains {
+		for e := chain.commitments.Front(); e != nil; e = e.Next() {
+			_, ok := e.Value.spliceCommits[txid]
+			if !ok {
+				return fmt.Errorf("%w: height %v",
+					channeldb.ErrSpliceCommitmentNotFound,
+					e.Value.height)
+			}
+		}
+	}
+
+	if err := lc.channelState.PromoteSplice(txid); err != nil {
+		return err
+	}
+
+	for _, chain := range chains {
+		for e := chain.commitments.Front(); e != nil; e = e.Next() {
+			spliceCommit := e.Value.spliceCommits[txid]
+			spliceCommit.spliceCommits = nil
+			e.Value = spliceCommit
+		}
+	}
+
+	lc.pendingSplices = nil
+	lc.Capacity = lc.channelState.Capacity
+
+	return lc.createSignDesc()
+}
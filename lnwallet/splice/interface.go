@@ -0,0 +1,82 @@
+package splice
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// Channel abstracts away from the core channel state machine by exposing an
+// interface that requires only the methods we need to carry out a splice.
+type Channel interface {
+	// ChannelPoint returns the channel point of the target channel.
+	ChannelPoint() wire.OutPoint
+
+	// State returns the persistent state of the channel.
+	State() *channeldb.OpenChannel
+
+	// FundingTxOut returns the current funding output of the channel.
+	FundingTxOut() *wire.TxOut
+
+	// SignSpliceCommitment creates the variant of the remote party's
+	// current commitment that spends the funding output of the splice
+	// transaction, and signs it along with its HTLCs.
+	SignSpliceCommitment(splice *lnwallet.SpliceTx) (
+		*channeldb.SpliceCandidate, *lnwallet.CommitSigs, error)
+
+	// ReceiveSpliceCommitSig verifies the remote party's signatures for
+	// the variant of our current commitment that spends the funding
+	// output of the splice candidate, and persists the candidate.
+	ReceiveSpliceCommitSig(candidate *channeldb.SpliceCandidate,
+		commitSigs *lnwallet.CommitSigs) error
+
+	// RemoveSpliceCandidates removes the splice candidates of the channel
+	// along with the variants of its commitments.
+	RemoveSpliceCandidates() error
+
+	// SignSharedInput signs the input of the splice transaction that
+	// spends the current funding output.
+	SignSharedInput(spliceTx *wire.MsgTx, inputIndex int) (lnwire.Sig,
+		error)
+
+	// SharedInputWitness returns the witness for the input of the splice
+	// transaction that spends the current funding output.
+	SharedInputWitness(spliceTx *wire.MsgTx, inputIndex int, ourSig,
+		theirSig lnwire.Sig) (wire.TxWitness, error)
+
+	// PromoteSplice switches the channel over to the funding output of
+	// the confirmed splice transaction.
+	PromoteSplice(txid chainhash.Hash) error
+}
+
+// Wallet is the interface to the on-chain wallet that funds splice-ins, and
+// signs and publishes splice transactions.
+type Wallet interface {
+	// FundSplice selects and leases wallet inputs that pay for amt, and
+	// the fee of the transaction described by the weight estimate at the
+	// given fee rate. A change output is returned if the change isn't
+	// dust.
+	FundSplice(amt btcutil.Amount, feeRate chainfee.SatPerKWeight,
+		weightEstimate input.TxWeightEstimator) ([]*interactivetx.Input,
+		fn.Option[*interactivetx.Output], error)
+
+	// ReleaseInputs releases the leases of inputs that were selected by
+	// FundSplice.
+	ReleaseInputs(inputs []*interactivetx.Input)
+
+	// SignInputs returns the witnesses for the passed inputs of the
+	// transaction, in the same order.
+	SignInputs(tx *wire.MsgTx, inputs []*interactivetx.Input,
+		prevOuts txscript.PrevOutputFetcher) ([]wire.TxWitness, error)
+
+	// PublishTransaction broadcasts the passed transaction.
+	PublishTransaction(tx *wire.MsgTx, label string) error
+}
@@ -0,0 +1,841 @@
+package splice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+var (
+	// ErrSpliceInProgress is returned when a splice is initiated while
+	// another one is still being negotiated.
+	ErrSpliceInProgress = errors.New("splice already in progress")
+
+	// ErrInvalidSpliceAmount is returned when a splice is initiated with
+	// an amount of zero.
+	ErrInvalidSpliceAmount = errors.New("splice amount must not be zero")
+
+	// ErrNoDeliveryScript is returned when funds are spliced out without
+	// specifying where they are sent to.
+	ErrNoDeliveryScript = errors.New("splice-out requires a delivery " +
+		"script")
+
+	// ErrBelowChanReserve is returned when a splice-out would take a
+	// party's balance below its channel reserve.
+	ErrBelowChanReserve = errors.New("splice-out dips balance below " +
+		"channel reserve")
+
+	// ErrFundingKeyRotation is returned when the remote party proposes a
+	// new funding key for the spliced channel, which isn't supported.
+	ErrFundingKeyRotation = errors.New("funding key rotation not " +
+		"supported")
+
+	// ErrUnsupportedContribution is returned when the remote party
+	// contributes funds to a splice we initiated, which isn't supported.
+	ErrUnsupportedContribution = errors.New("acceptor contributions not " +
+		"supported")
+)
+
+// spliceState is the state of a splice negotiation.
+type spliceState uint8
+
+const (
+	// stateIdle is the state before the splice was initiated by either
+	// party.
+	stateIdle spliceState = iota
+
+	// stateAwaitingAck is the state after we sent splice_init, until the
+	// remote party responds with splice_ack.
+	stateAwaitingAck
+
+	// stateNegotiating is the state during which the inputs and outputs
+	// of the splice transaction are negotiated.
+	stateNegotiating
+
+	// stateAwaitingCommitSig is the state after we sent our signature
+	// for the remote party's commitment spending the new funding output,
+	// until we receive theirs.
+	stateAwaitingCommitSig
+
+	// stateAwaitingTxSigs is the state during which both parties exchange
+	// the signatures for their inputs of the splice transaction.
+	stateAwaitingTxSigs
+
+	// stateAwaitingLocked is the state after the splice transaction was
+	// broadcast, until it confirmed and both parties sent splice_locked.
+	stateAwaitingLocked
+
+	// stateLocked is the final state once both parties sent
+	// splice_locked.
+	stateLocked
+)
+
+// String returns a human readable representation of the splice state.
+func (s spliceState) String() string {
+	switch s {
+	case stateIdle:
+		return "Idle"
+
+	case stateAwaitingAck:
+		return "AwaitingAck"
+
+	case stateNegotiating:
+		return "Negotiating"
+
+	case stateAwaitingCommitSig:
+		return "AwaitingCommitSig"
+
+	case stateAwaitingTxSigs:
+		return "AwaitingTxSigs"
+
+	case stateAwaitingLocked:
+		return "AwaitingLocked"
+
+	case stateLocked:
+		return "Locked"
+
+	default:
+		return fmt.Sprintf("Unknown(%d)", s)
+	}
+}
+
+// Request describes a splice we initiate.
+type Request struct {
+	// Amount is the amount the channel's capacity changes by. It's
+	// positive for a splice-in, which is funded by the wallet, and
+	// negative for a splice-out.
+	Amount btcutil.Amount
+
+	// FeeRate is the fee rate of the splice transaction.
+	FeeRate chainfee.SatPerKWeight
+
+	// DeliveryScript is the script the funds of a splice-out are sent
+	// to.
+	DeliveryScript []byte
+}
+
+// Config holds the dependencies of a Splicer.
+type Config struct {
+	// Channel is the channel that is spliced.
+	Channel Channel
+
+	// Wallet funds splice-ins, and signs and publishes the splice
+	// transaction.
+	Wallet Wallet
+
+	// BestHeight returns the current best block height. It's used as the
+	// lock time of the splice transaction, and recorded as its broadcast
+	// height.
+	BestHeight func() (uint32, error)
+}
+
+// Splicer drives the negotiation of a splice with the remote party, from
+// splice_init until both parties sent splice_locked. Either party may
+// initiate the splice, but only the initiator contributes inputs and outputs
+// to the splice transaction, and pays all of its fees.
+//
+// The Splicer expects the channel to be quiescent during the negotiation. Once
+// the splice transaction is broadcast, the channel can be updated again, as
+// every new commitment also gets a variant that spends the new funding output.
+type Splicer struct {
+	cfg Config
+
+	chanID lnwire.ChannelID
+
+	state spliceState
+
+	feeRate  chainfee.SatPerKWeight
+	lockTime uint32
+
+	// localContribution and remoteContribution are the amounts the
+	// balances of both parties change by.
+	localContribution  btcutil.Amount
+	remoteContribution btcutil.Amount
+
+	// walletInputs and walletOutputs are the inputs and outputs we
+	// contribute to the splice transaction, besides the shared funding
+	// input and output.
+	walletInputs  []*interactivetx.Input
+	walletOutputs []*interactivetx.Output
+
+	constructor *interactivetx.Constructor
+
+	spliceTx  *lnwallet.SpliceTx
+	candidate *channeldb.SpliceCandidate
+
+	// sharedSig is our signature for the shared funding input, which is
+	// set once we sent tx_signatures.
+	sharedSig fn.Option[lnwire.Sig]
+
+	localLocked  bool
+	remoteLocked bool
+
+	sync.Mutex
+}
+
+// NewSplicer creates a new Splicer for the passed channel.
+func NewSplicer(cfg Config) *Splicer {
+	chanPoint := cfg.Channel.ChannelPoint()
+
+	return &Splicer{
+		cfg:    cfg,
+		chanID: lnwire.NewChanIDFromOutPoint(chanPoint),
+	}
+}
+
+// ResumeSplicer creates a Splicer for a splice transaction that was
+// broadcast, but hasn't been locked by both parties yet.
+func ResumeSplicer(cfg Config, candidate *channeldb.SpliceCandidate) *Splicer {
+	s := NewSplicer(cfg)
+	s.state = stateAwaitingLocked
+	s.candidate = candidate
+	s.spliceTx = &lnwallet.SpliceTx{
+		Tx:                 candidate.FundingTx,
+		FundingOutputIndex: candidate.FundingOutputIndex,
+		LocalContribution:  candidate.LocalContribution,
+		RemoteContribution: candidate.RemoteContribution,
+	}
+
+	return s
+}
+
+// Initiate starts a splice of the channel by returning the splice_init
+// message that is sent to the remote party. For a splice-in, the inputs
+// funding it are selected from the wallet right away.
+func (s *Splicer) Initiate(req Request) (*lnwire.SpliceInit, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.state != stateIdle {
+		return nil, ErrSpliceInProgress
+	}
+
+	switch {
+	case req.Amount == 0:
+		return nil, ErrInvalidSpliceAmount
+
+	case req.Amount < 0 && len(req.DeliveryScript) == 0:
+		return nil, ErrNoDeliveryScript
+	}
+
+	height, err := s.cfg.BestHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	// As the initiator, we pay the fees for the shared input and output
+	// of the splice transaction, as well as for our own contributions.
+	var weightEstimate input.TxWeightEstimator
+	weightEstimate.AddWitnessInput(input.MultiSigWitnessSize)
+	weightEstimate.AddP2WSHOutput()
+
+	if req.Amount > 0 {
+		inputs, change, err := s.cfg.Wallet.FundSplice(
+			req.Amount, req.FeeRate, weightEstimate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fund splice: %w", err)
+		}
+
+		s.walletInputs = inputs
+		change.WhenSome(func(out *interactivetx.Output) {
+			s.walletOutputs = append(s.walletOutputs, out)
+		})
+		s.localContribution = req.Amount
+	} else {
+		weightEstimate.AddOutput(req.DeliveryScript)
+		fee := req.FeeRate.FeeForWeight(weightEstimate.Weight())
+
+		s.walletOutputs = append(s.walletOutputs, &interactivetx.Output{
+			Value:    -req.Amount,
+			PkScript: req.DeliveryScript,
+		})
+		s.localContribution = req.Amount - fee
+
+		chanState := s.cfg.Channel.State()
+		balance := chanState.LocalCommitment.LocalBalance.ToSatoshis()
+		if balance+s.localContribution <
+			chanState.LocalChanCfg.ChanReserve {
+
+			return nil, fmt.Errorf("%w: balance=%v, splice=%v",
+				ErrBelowChanReserve, balance,
+				s.localContribution)
+		}
+	}
+
+	s.feeRate = req.FeeRate
+	s.lockTime = height
+	s.state = stateAwaitingAck
+
+	spliceLog.Infof("ChannelPoint(%v): initiating splice with "+
+		"contribution=%v, fee_rate=%v", s.cfg.Channel.ChannelPoint(),
+		s.localContribution, s.feeRate)
+
+	return &lnwire.SpliceInit{
+		ChannelID:           s.chanID,
+		FundingContribution: s.localContribution,
+		FeeRate:             uint32(s.feeRate),
+		LockTime:            s.lockTime,
+		FundingKey:          s.localFundingKey(),
+	}, nil
+}
+
+// ProcessMsg processes a splice message of the remote party, and returns the
+// messages we send in response.
+func (s *Splicer) ProcessMsg(msg lnwire.Message) ([]lnwire.Message, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	switch msg := msg.(type) {
+	case *lnwire.SpliceInit:
+		return s.processSpliceInit(msg)
+
+	case *lnwire.SpliceAck:
+		return s.processSpliceAck(msg)
+
+	case *lnwire.TxAddInput, *lnwire.TxAddOutput, *lnwire.TxRemoveInput,
+		*lnwire.TxRemoveOutput, *lnwire.TxComplete:
+
+		if s.state != stateNegotiating {
+			return nil, s.unexpectedMsg(msg)
+		}
+
+		if err := s.constructor.Receive(msg); err != nil {
+			return nil, err
+		}
+
+		return s.continueNegotiation()
+
+	case *lnwire.TxAbort:
+		if s.state >= stateAwaitingLocked {
+			return nil, s.unexpectedMsg(msg)
+		}
+
+		return nil, fmt.Errorf("%w: %s", interactivetx.ErrAborted,
+			msg.Data)
+
+	case *lnwire.CommitSig:
+		return s.processCommitSig(msg)
+
+	case *lnwire.TxSignatures:
+		return s.processTxSignatures(msg)
+
+	case *lnwire.SpliceLocked:
+		if s.state != stateAwaitingLocked && s.state != stateLocked {
+			return nil, s.unexpectedMsg(msg)
+		}
+
+		if msg.SpliceTxID != s.spliceTx.Tx.TxHash() {
+			return nil, fmt.Errorf("splice_locked for unknown "+
+				"splice %v", msg.SpliceTxID)
+		}
+
+		s.remoteLocked = true
+		s.checkLocked()
+
+		return nil, nil
+
+	default:
+		return nil, s.unexpectedMsg(msg)
+	}
+}
+
+// unexpectedMsg returns the error for a message that isn't expected in the
+// current state.
+func (s *Splicer) unexpectedMsg(msg lnwire.Message) error {
+	return fmt.Errorf("unexpected %v message in state %v", msg.MsgType(),
+		s.state)
+}
+
+// localFundingKey returns our key of the funding output's multisig, which
+// isn't changed by a splice.
+func (s *Splicer) localFundingKey() *btcec.PublicKey {
+	return s.cfg.Channel.State().LocalChanCfg.MultiSigKey.PubKey
+}
+
+// checkFundingKey checks that the remote party didn't rotate its funding key.
+func (s *Splicer) checkFundingKey(key *btcec.PublicKey) error {
+	remoteKey := s.cfg.Channel.State().RemoteChanCfg.MultiSigKey.PubKey
+	if key == nil || !key.IsEqual(remoteKey) {
+		return ErrFundingKeyRotation
+	}
+
+	return nil
+}
+
+// processSpliceInit handles a splice initiated by the remote party.
+func (s *Splicer) processSpliceInit(
+	msg *lnwire.SpliceInit) ([]lnwire.Message, error) {
+
+	if s.state != stateIdle {
+		return nil, ErrSpliceInProgress
+	}
+
+	if err := s.checkFundingKey(msg.FundingKey); err != nil {
+		return nil, err
+	}
+
+	chanState := s.cfg.Channel.State()
+	balance := chanState.LocalCommitment.RemoteBalance.ToSatoshis()
+	if msg.FundingContribution < 0 &&
+		balance+msg.FundingContribution <
+			chanState.RemoteChanCfg.ChanReserve {
+
+		return nil, fmt.Errorf("%w: balance=%v, splice=%v",
+			ErrBelowChanReserve, balance, msg.FundingContribution)
+	}
+
+	s.feeRate = chainfee.SatPerKWeight(msg.FeeRate)
+	s.lockTime = msg.LockTime
+	s.remoteContribution = msg.FundingContribution
+	s.constructor = interactivetx.NewConstructor(interactivetx.Config{
+		ChanID:      s.chanID,
+		LockTime:    s.lockTime,
+		SharedInput: fn.Some(s.sharedInput()),
+	})
+	s.state = stateNegotiating
+
+	spliceLog.Infof("ChannelPoint(%v): remote party initiated splice "+
+		"with contribution=%v, fee_rate=%v",
+		s.cfg.Channel.ChannelPoint(), s.remoteContribution, s.feeRate)
+
+	return []lnwire.Message{
+		&lnwire.SpliceAck{
+			ChannelID:  s.chanID,
+			FundingKey: s.localFundingKey(),
+		},
+	}, nil
+}
+
+// processSpliceAck handles the remote party's response to our splice_init,
+// after which we start negotiating the splice transaction.
+func (s *Splicer) processSpliceAck(
+	msg *lnwire.SpliceAck) ([]lnwire.Message, error) {
+
+	if s.state != stateAwaitingAck {
+		return nil, s.unexpectedMsg(msg)
+	}
+
+	if err := s.checkFundingKey(msg.FundingKey); err != nil {
+		return nil, err
+	}
+
+	if msg.FundingContribution != 0 {
+		return nil, ErrUnsupportedContribution
+	}
+
+	sharedInput := s.sharedInput()
+	fundingOutput, err := s.fundingOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := []*interactivetx.Input{{
+		OutPoint:   sharedInput.OutPoint,
+		PrevOutput: sharedInput.Output,
+		Sequence:   spliceInputSequence,
+		Shared:     true,
+	}}
+	inputs = append(inputs, s.walletInputs...)
+
+	outputs := []*interactivetx.Output{{
+		Value:    btcutil.Amount(fundingOutput.Value),
+		PkScript: fundingOutput.PkScript,
+	}}
+	outputs = append(outputs, s.walletOutputs...)
+
+	s.constructor = interactivetx.NewConstructor(interactivetx.Config{
+		ChanID:      s.chanID,
+		Initiator:   true,
+		LockTime:    s.lockTime,
+		SharedInput: fn.Some(sharedInput),
+		Inputs:      inputs,
+		Outputs:     outputs,
+	})
+	s.state = stateNegotiating
+
+	return s.continueNegotiation()
+}
+
+// sharedInput returns the current funding output of the channel, which is
+// spent by the splice transaction.
+func (s *Splicer) sharedInput() interactivetx.SharedInput {
+	return interactivetx.SharedInput{
+		OutPoint: s.cfg.Channel.State().CurrentFundingOutpoint(),
+		Output:   s.cfg.Channel.FundingTxOut(),
+	}
+}
+
+// fundingOutput returns the funding output created by the splice
+// transaction, whose value reflects the contributions of both parties.
+func (s *Splicer) fundingOutput() (*wire.TxOut, error) {
+	chanState := s.cfg.Channel.State()
+	capacity := chanState.Capacity + s.localContribution +
+		s.remoteContribution
+
+	_, fundingOutput, err := lnwallet.SpliceFundingOutput(
+		chanState, capacity,
+	)
+
+	return fundingOutput, err
+}
+
+// continueNegotiation sends our next message of the negotiation if it's our
+// turn. Once the splice transaction is complete, we sign the remote party's
+// commitment spending its funding output.
+func (s *Splicer) continueNegotiation() ([]lnwire.Message, error) {
+	var msgs []lnwire.Message
+	if s.constructor.OurTurn() {
+		msg, err := s.constructor.Send()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if !s.constructor.IsComplete() {
+		return msgs, nil
+	}
+
+	tx, err := s.constructor.Tx()
+	if err != nil {
+		return nil, err
+	}
+
+	fundingOutput, err := s.fundingOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	fundingIndex := -1
+	for i, txOut := range tx.TxOut {
+		if txOut.Value == fundingOutput.Value &&
+			bytes.Equal(txOut.PkScript, fundingOutput.PkScript) {
+
+			fundingIndex = i
+			break
+		}
+	}
+	if fundingIndex == -1 {
+		return nil, fmt.Errorf("splice transaction %v doesn't create "+
+			"the funding output", tx.TxHash())
+	}
+
+	s.spliceTx = &lnwallet.SpliceTx{
+		Tx:                 tx,
+		FundingOutputIndex: uint32(fundingIndex),
+		LocalContribution:  s.localContribution,
+		RemoteContribution: s.remoteContribution,
+	}
+
+	candidate, commitSigs, err := s.cfg.Channel.SignSpliceCommitment(
+		s.spliceTx,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.candidate = candidate
+	s.state = stateAwaitingCommitSig
+
+	spliceLog.Infof("ChannelPoint(%v): negotiated splice transaction %v",
+		s.cfg.Channel.ChannelPoint(), tx.TxHash())
+
+	return append(msgs, &lnwire.CommitSig{
+		ChanID:    s.chanID,
+		CommitSig: commitSigs.CommitSig,
+		HtlcSigs:  commitSigs.HtlcSigs,
+	}), nil
+}
+
+// processCommitSig handles the remote party's signatures for the variant of
+// our commitment spending the funding output of the splice transaction.
+func (s *Splicer) processCommitSig(
+	msg *lnwire.CommitSig) ([]lnwire.Message, error) {
+
+	if s.state != stateAwaitingCommitSig {
+		return nil, s.unexpectedMsg(msg)
+	}
+
+	err := s.cfg.Channel.ReceiveSpliceCommitSig(
+		s.candidate, &lnwallet.CommitSigs{
+			CommitSig: msg.CommitSig,
+			HtlcSigs:  msg.HtlcSigs,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.state = stateAwaitingTxSigs
+
+	if !s.constructor.LocalSignsFirst() {
+		return nil, nil
+	}
+
+	txSigs, err := s.signTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return []lnwire.Message{txSigs}, nil
+}
+
+// sharedInputIndex returns the index of the shared funding input in the
+// splice transaction.
+func (s *Splicer) sharedInputIndex() int {
+	return inputIndex(
+		s.spliceTx.Tx, s.cfg.Channel.State().CurrentFundingOutpoint(),
+	)
+}
+
+// signTx signs our inputs of the splice transaction, including the shared
+// funding input, and returns the tx_signatures message carrying the
+// signatures.
+func (s *Splicer) signTx() (*lnwire.TxSignatures, error) {
+	tx := s.spliceTx.Tx
+	localInputs := s.constructor.LocalInputs()
+
+	witnesses, err := s.cfg.Wallet.SignInputs(
+		tx, localInputs, s.constructor.PrevOutFetcher(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSig, err := s.cfg.Channel.SignSharedInput(
+		tx, s.sharedInputIndex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.sharedSig = fn.Some(sharedSig)
+
+	for i, in := range localInputs {
+		tx.TxIn[inputIndex(tx, in.OutPoint)].Witness = witnesses[i]
+	}
+
+	return &lnwire.TxSignatures{
+		ChannelID: s.chanID,
+		TxID:      tx.TxHash(),
+		Witnesses: witnesses,
+		SharedInputSig: tlv.SomeRecordT(
+			tlv.NewRecordT[tlv.TlvType0](sharedSig),
+		),
+	}, nil
+}
+
+// inputIndex returns the index of the input spending the passed outpoint.
+func inputIndex(tx *wire.MsgTx, outPoint wire.OutPoint) int {
+	for i, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint == outPoint {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// processTxSignatures handles the remote party's signatures for its inputs of
+// the splice transaction. Once both parties signed, the splice transaction is
+// broadcast.
+func (s *Splicer) processTxSignatures(
+	msg *lnwire.TxSignatures) ([]lnwire.Message, error) {
+
+	if s.state != stateAwaitingTxSigs {
+		return nil, s.unexpectedMsg(msg)
+	}
+
+	tx := s.spliceTx.Tx
+	txid := tx.TxHash()
+	if msg.TxID != txid {
+		return nil, fmt.Errorf("tx_signatures for unknown transaction "+
+			"%v", msg.TxID)
+	}
+
+	remoteInputs := s.constructor.RemoteInputs()
+	if len(msg.Witnesses) != len(remoteInputs) {
+		return nil, fmt.Errorf("expected %d witnesses, got %d",
+			len(remoteInputs), len(msg.Witnesses))
+	}
+
+	theirSharedSig, err := msg.SharedInputSig.ValOpt().UnwrapOrErr(
+		errors.New("shared input signature missing"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, in := range remoteInputs {
+		tx.TxIn[inputIndex(tx, in.OutPoint)].Witness = msg.Witnesses[i]
+	}
+
+	err = s.constructor.VerifyInputs(tx, remoteInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the remote party signed first, we respond with our own
+	// signatures.
+	var msgs []lnwire.Message
+	if s.sharedSig.IsNone() {
+		txSigs, err := s.signTx()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, txSigs)
+	}
+
+	ourSharedSig, err := s.sharedSig.UnwrapOrErr(
+		errors.New("shared input not signed"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedIndex := s.sharedInputIndex()
+	witness, err := s.cfg.Channel.SharedInputWitness(
+		tx, sharedIndex, ourSharedSig, theirSharedSig,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tx.TxIn[sharedIndex].Witness = witness
+
+	// Record the broadcast height of the candidate before broadcasting
+	// it, so it isn't dropped on restart.
+	height, err := s.cfg.BestHeight()
+	if err != nil {
+		return nil, err
+	}
+	s.candidate.BroadcastHeight = height
+	err = s.cfg.Channel.State().MarkSpliceBroadcast(txid, height)
+	if err != nil {
+		return nil, err
+	}
+	s.state = stateAwaitingLocked
+
+	spliceLog.Infof("ChannelPoint(%v): broadcasting splice transaction "+
+		"%v", s.cfg.Channel.ChannelPoint(), txid)
+
+	shortChanID := s.cfg.Channel.State().ShortChanID()
+	label := labels.MakeLabel(labels.LabelTypeChannelSplice, &shortChanID)
+	if err := s.cfg.Wallet.PublishTransaction(tx, label); err != nil {
+		// The transaction may still be broadcast by the remote party,
+		// so we'll continue to wait for its confirmation.
+		spliceLog.Errorf("ChannelPoint(%v): unable to publish splice "+
+			"transaction %v: %v", s.cfg.Channel.ChannelPoint(),
+			txid, err)
+	}
+
+	return msgs, nil
+}
+
+// SpliceTx returns the splice transaction once it has been negotiated.
+func (s *Splicer) SpliceTx() fn.Option[*wire.MsgTx] {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.spliceTx == nil {
+		return fn.None[*wire.MsgTx]()
+	}
+
+	return fn.Some(s.spliceTx.Tx)
+}
+
+// IsBroadcast returns true once the splice transaction is fully signed and
+// was broadcast. From then on, the splice can't be aborted anymore.
+func (s *Splicer) IsBroadcast() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.state >= stateAwaitingLocked
+}
+
+// SpliceConfirmed is called once the splice transaction with the passed txid
+// has sufficient confirmations. It returns the splice_locked message that is
+// sent to the remote party.
+func (s *Splicer) SpliceConfirmed(
+	txid chainhash.Hash) (*lnwire.SpliceLocked, error) {
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.state != stateAwaitingLocked && s.state != stateLocked {
+		return nil, fmt.Errorf("splice confirmed in state %v", s.state)
+	}
+
+	if txid != s.spliceTx.Tx.TxHash() {
+		return nil, fmt.Errorf("unknown splice %v confirmed", txid)
+	}
+
+	s.localLocked = true
+	s.checkLocked()
+
+	return &lnwire.SpliceLocked{
+		ChannelID:  s.chanID,
+		SpliceTxID: txid,
+	}, nil
+}
+
+// checkLocked transitions to the final state once both parties sent
+// splice_locked.
+func (s *Splicer) checkLocked() {
+	if s.localLocked && s.remoteLocked {
+		s.state = stateLocked
+	}
+}
+
+// IsLocked returns true once both parties sent splice_locked. The caller then
+// promotes the splice.
+func (s *Splicer) IsLocked() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.state == stateLocked
+}
+
+// Abort aborts the splice negotiation, releasing the wallet inputs we
+// selected and removing any persisted splice candidates. It returns the
+// tx_abort message that is sent to the remote party. A splice can't be
+// aborted once its transaction was broadcast.
+func (s *Splicer) Abort(reason error) (*lnwire.TxAbort, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.state >= stateAwaitingLocked {
+		return nil, fmt.Errorf("splice transaction already broadcast")
+	}
+
+	spliceLog.Infof("ChannelPoint(%v): aborting splice in state %v: %v",
+		s.cfg.Channel.ChannelPoint(), s.state, reason)
+
+	s.cfg.Wallet.ReleaseInputs(s.walletInputs)
+
+	if s.state >= stateAwaitingCommitSig {
+		err := s.cfg.Channel.RemoveSpliceCandidates()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.state = stateIdle
+
+	return &lnwire.TxAbort{
+		ChannelID: s.chanID,
+		Data:      []byte(reason.Error()),
+	}, nil
+}
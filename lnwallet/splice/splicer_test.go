@@ -0,0 +1,377 @@
+package splice
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHeight = 100
+
+	testFeeRate = chainfee.SatPerKWeight(2500)
+)
+
+// mockWallet is a wallet that holds a single P2WPKH coin.
+type mockWallet struct {
+	t *testing.T
+
+	privKey  *btcec.PrivateKey
+	pkScript []byte
+	coin     *interactivetx.Input
+
+	released  []*interactivetx.Input
+	published []*wire.MsgTx
+}
+
+// newMockWallet creates a wallet with a coin of the given value.
+func newMockWallet(t *testing.T, value btcutil.Amount) *mockWallet {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	pkScript, err := input.WitnessPubKeyHash(
+		privKey.PubKey().SerializeCompressed(),
+	)
+	require.NoError(t, err)
+
+	prevTx := wire.NewMsgTx(2)
+	prevTx.AddTxIn(&wire.TxIn{})
+	prevTx.AddTxOut(wire.NewTxOut(int64(value), pkScript))
+
+	return &mockWallet{
+		t:        t,
+		privKey:  privKey,
+		pkScript: pkScript,
+		coin: &interactivetx.Input{
+			OutPoint:   wire.OutPoint{Hash: prevTx.TxHash()},
+			PrevTx:     prevTx,
+			PrevOutput: prevTx.TxOut[0],
+			Sequence:   spliceInputSequence,
+		},
+	}
+}
+
+// FundSplice returns the coin of the wallet, and sends the change back to
+// the wallet.
+func (m *mockWallet) FundSplice(amt btcutil.Amount,
+	feeRate chainfee.SatPerKWeight,
+	weightEstimate input.TxWeightEstimator) ([]*interactivetx.Input,
+	fn.Option[*interactivetx.Output], error) {
+
+	weightEstimate.AddP2WKHInput()
+	weightEstimate.AddP2WKHOutput()
+	fee := feeRate.FeeForWeight(weightEstimate.Weight())
+
+	change := &interactivetx.Output{
+		Value:    btcutil.Amount(m.coin.PrevOutput.Value) - amt - fee,
+		PkScript: m.pkScript,
+	}
+
+	return []*interactivetx.Input{m.coin}, fn.Some(change), nil
+}
+
+// ReleaseInputs records the released inputs.
+func (m *mockWallet) ReleaseInputs(inputs []*interactivetx.Input) {
+	m.released = append(m.released, inputs...)
+}
+
+// SignInputs signs the passed inputs with the key of the wallet.
+func (m *mockWallet) SignInputs(tx *wire.MsgTx, inputs []*interactivetx.Input,
+	prevOuts txscript.PrevOutputFetcher) ([]wire.TxWitness, error) {
+
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+
+	var witnesses []wire.TxWitness
+	for _, in := range inputs {
+		witness, err := txscript.WitnessSignature(
+			tx, sigHashes, inputIndex(tx, in.OutPoint),
+			in.PrevOutput.Value, in.PrevOutput.PkScript,
+			txscript.SigHashAll, m.privKey, true,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		witnesses = append(witnesses, witness)
+	}
+
+	return witnesses, nil
+}
+
+// PublishTransaction records the published transaction.
+func (m *mockWallet) PublishTransaction(tx *wire.MsgTx, _ string) error {
+	m.published = append(m.published, tx)
+	return nil
+}
+
+// spliceTestContext holds the splicers of both parties of a channel.
+type spliceTestContext struct {
+	t *testing.T
+
+	aliceChannel, bobChannel *lnwallet.LightningChannel
+	aliceWallet, bobWallet   *mockWallet
+	alice, bob               *Splicer
+}
+
+// newSpliceTestContext creates two channel parties with a splicer each.
+func newSpliceTestContext(t *testing.T) *spliceTestContext {
+	aliceChannel, bobChannel, err := lnwallet.CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit|
+			channeldb.AnchorOutputsBit,
+	)
+	require.NoError(t, err)
+
+	bestHeight := func() (uint32, error) {
+		return testHeight, nil
+	}
+
+	ctx := &spliceTestContext{
+		t:            t,
+		aliceChannel: aliceChannel,
+		bobChannel:   bobChannel,
+		aliceWallet:  newMockWallet(t, 5_000_000),
+		bobWallet:    newMockWallet(t, 5_000_000),
+	}
+	ctx.alice = NewSplicer(Config{
+		Channel:    aliceChannel,
+		Wallet:     ctx.aliceWallet,
+		BestHeight: bestHeight,
+	})
+	ctx.bob = NewSplicer(Config{
+		Channel:    bobChannel,
+		Wallet:     ctx.bobWallet,
+		BestHeight: bestHeight,
+	})
+
+	return ctx
+}
+
+// deliver passes the message to the receiving splicer, and delivers its
+// responses until no messages are left.
+func (c *spliceTestContext) deliver(msg lnwire.Message, toBob bool) error {
+	type delivery struct {
+		msg   lnwire.Message
+		toBob bool
+	}
+
+	queue := []delivery{{msg, toBob}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		receiver := c.alice
+		if next.toBob {
+			receiver = c.bob
+		}
+
+		msgs, err := receiver.ProcessMsg(next.msg)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			queue = append(queue, delivery{msg, !next.toBob})
+		}
+	}
+
+	return nil
+}
+
+// lock confirms the splice transaction for both parties, and exchanges
+// splice_locked.
+func (c *spliceTestContext) lock(spliceTx *wire.MsgTx) {
+	txid := spliceTx.TxHash()
+
+	aliceLocked, err := c.alice.SpliceConfirmed(txid)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.deliver(aliceLocked, true))
+	require.False(c.t, c.alice.IsLocked())
+
+	bobLocked, err := c.bob.SpliceConfirmed(txid)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.deliver(bobLocked, false))
+
+	require.True(c.t, c.alice.IsLocked())
+	require.True(c.t, c.bob.IsLocked())
+
+	require.NoError(c.t, c.aliceChannel.PromoteSplice(txid))
+	require.NoError(c.t, c.bobChannel.PromoteSplice(txid))
+}
+
+// TestSplicer tests splicing funds in and out of a channel.
+func TestSplicer(t *testing.T) {
+	t.Parallel()
+
+	deliveryScript, err := input.WitnessPubKeyHash(make([]byte, 33))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		amount btcutil.Amount
+	}{
+		{
+			name:   "splice in",
+			amount: 1_000_000,
+		},
+		{
+			name:   "splice out",
+			amount: -1_000_000,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := newSpliceTestContext(t)
+
+			oldFundingOutput := ctx.aliceChannel.FundingTxOut()
+			oldCapacity := ctx.aliceChannel.Capacity
+			oldBalance := ctx.aliceChannel.State().LocalCommitment.
+				LocalBalance
+
+			spliceInit, err := ctx.alice.Initiate(Request{
+				Amount:         tc.amount,
+				FeeRate:        testFeeRate,
+				DeliveryScript: deliveryScript,
+			})
+			require.NoError(t, err)
+			require.Equal(
+				t, ctx.aliceChannel.State().LocalChanCfg.
+					MultiSigKey.PubKey,
+				spliceInit.FundingKey,
+			)
+
+			// A second splice can't be initiated while the first
+			// one is in progress.
+			_, err = ctx.alice.Initiate(Request{
+				Amount:  tc.amount,
+				FeeRate: testFeeRate,
+			})
+			require.ErrorIs(t, err, ErrSpliceInProgress)
+
+			require.NoError(t, ctx.deliver(spliceInit, true))
+
+			// The splice transaction is published by both
+			// parties once it's signed.
+			require.Len(t, ctx.aliceWallet.published, 1)
+			require.Len(t, ctx.bobWallet.published, 1)
+			spliceTx := ctx.aliceWallet.published[0]
+			require.Equal(
+				t, spliceTx.TxHash(),
+				ctx.bobWallet.published[0].TxHash(),
+			)
+			require.True(t, ctx.alice.IsBroadcast())
+
+			// All inputs of the splice transaction must be
+			// validly signed.
+			prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+			prevOuts.AddPrevOut(
+				ctx.aliceChannel.State().FundingOutpoint,
+				oldFundingOutput,
+			)
+			prevOuts.AddPrevOut(
+				ctx.aliceWallet.coin.OutPoint,
+				ctx.aliceWallet.coin.PrevOutput,
+			)
+			sigHashes := txscript.NewTxSigHashes(spliceTx, prevOuts)
+			for i, txIn := range spliceTx.TxIn {
+				prevOut := prevOuts.FetchPrevOutput(
+					txIn.PreviousOutPoint,
+				)
+				vm, err := txscript.NewEngine(
+					prevOut.PkScript, spliceTx, i,
+					txscript.StandardVerifyFlags, nil,
+					sigHashes, prevOut.Value, prevOuts,
+				)
+				require.NoError(t, err)
+				require.NoError(t, vm.Execute())
+			}
+
+			candidates, err := ctx.bobChannel.State().
+				SpliceCandidates()
+			require.NoError(t, err)
+			require.Len(t, candidates, 1)
+			require.EqualValues(
+				t, testHeight, candidates[0].BroadcastHeight,
+			)
+
+			ctx.lock(spliceTx)
+
+			// Alice pays all fees of the splice, so her balance
+			// changes by more than the splice amount for a
+			// splice-out.
+			aliceState := ctx.aliceChannel.State()
+			contribution := aliceState.LocalCommitment.
+				LocalBalance.ToSatoshis() -
+				oldBalance.ToSatoshis()
+			require.Equal(
+				t, oldCapacity+contribution,
+				ctx.aliceChannel.Capacity,
+			)
+			if tc.amount > 0 {
+				require.Equal(t, tc.amount, contribution)
+			} else {
+				require.Less(t, contribution, tc.amount)
+			}
+		})
+	}
+}
+
+// TestSplicerAbort tests that a splice can be aborted by the remote party
+// before its transaction was signed.
+func TestSplicerAbort(t *testing.T) {
+	t.Parallel()
+
+	ctx := newSpliceTestContext(t)
+
+	spliceInit, err := ctx.alice.Initiate(Request{
+		Amount:  500_000,
+		FeeRate: testFeeRate,
+	})
+	require.NoError(t, err)
+
+	msgs, err := ctx.bob.ProcessMsg(spliceInit)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	// Bob aborts the negotiation, after which Alice releases her inputs.
+	abort, err := ctx.bob.Abort(ErrUnsupportedContribution)
+	require.NoError(t, err)
+
+	_, err = ctx.alice.ProcessMsg(abort)
+	require.ErrorIs(t, err, interactivetx.ErrAborted)
+
+	_, err = ctx.alice.Abort(err)
+	require.NoError(t, err)
+	require.Equal(
+		t, []*interactivetx.Input{ctx.aliceWallet.coin},
+		ctx.aliceWallet.released,
+	)
+
+	// Both parties may start a new splice afterwards.
+	_, err = ctx.alice.Initiate(Request{
+		Amount:  500_000,
+		FeeRate: testFeeRate,
+	})
+	require.NoError(t, err)
+
+	// A splice with a rotated funding key is rejected.
+	_, err = ctx.bob.ProcessMsg(&lnwire.SpliceInit{
+		ChannelID:           spliceInit.ChannelID,
+		FundingContribution: 500_000,
+		FundingKey:          ctx.bobWallet.privKey.PubKey(),
+	})
+	require.ErrorIs(t, err, ErrFundingKeyRotation)
+}
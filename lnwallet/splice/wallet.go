@@ -0,0 +1,237 @@
+package splice
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/chanfunding"
+	"github.com/lightningnetwork/lnd/lnwallet/interactivetx"
+)
+
+// spliceInputSequence is the sequence number of the inputs we add to a splice
+// transaction. It signals replaceability, so the splice can be fee bumped.
+const spliceInputSequence = wire.MaxTxInSequenceNum - 2
+
+// WalletConfig holds the dependencies of the wallet that funds splices.
+type WalletConfig struct {
+	// CoinSource is used to list and locate the coins of the wallet.
+	CoinSource chanfunding.CoinSource
+
+	// CoinSelectionStrategy is the strategy that is used for selecting
+	// coins when funding a splice-in.
+	CoinSelectionStrategy wallet.CoinSelectionStrategy
+
+	// CoinSelectLocker allows us to gain exclusive access to the current
+	// set of coins returned by the CoinSource.
+	CoinSelectLocker chanfunding.CoinSelectionLocker
+
+	// CoinLeaser is used to lease the coins that are selected for a
+	// splice-in, so they aren't used elsewhere during the negotiation.
+	CoinLeaser chanfunding.OutputLeaser
+
+	// Signer is used to sign the wallet inputs of splice transactions.
+	Signer input.Signer
+
+	// FetchTx returns the wallet transaction with the given txid. It's
+	// used to look up the previous transactions of our inputs, which are
+	// sent to the remote party.
+	FetchTx func(chainhash.Hash) (*wire.MsgTx, error)
+
+	// ChangeScript returns a new script the change of a splice-in is sent
+	// to.
+	ChangeScript func() ([]byte, error)
+
+	// PublishTransaction broadcasts a fully signed splice transaction.
+	PublishTransaction func(*wire.MsgTx, string) error
+
+	// MinConfs is the number of confirmations a coin needs to be used for
+	// a splice-in.
+	MinConfs int32
+
+	// DustLimit is the current dust limit. We'll use this to ensure that
+	// we don't create a dust change output.
+	DustLimit btcutil.Amount
+}
+
+// walletSource funds and signs splice transactions using the on-chain wallet.
+type walletSource struct {
+	cfg WalletConfig
+}
+
+// NewWallet creates a new Wallet backed by the on-chain wallet described by
+// the passed config.
+func NewWallet(cfg WalletConfig) Wallet {
+	return &walletSource{
+		cfg: cfg,
+	}
+}
+
+// FundSplice selects and leases wallet inputs that pay for amt, and the fee
+// of the transaction described by the weight estimate at the given fee rate.
+//
+// NOTE: Part of the Wallet interface.
+func (w *walletSource) FundSplice(amt btcutil.Amount,
+	feeRate chainfee.SatPerKWeight,
+	weightEstimate input.TxWeightEstimator) ([]*interactivetx.Input,
+	fn.Option[*interactivetx.Output], error) {
+
+	var (
+		inputs []*interactivetx.Input
+		change fn.Option[*interactivetx.Output]
+	)
+	err := w.cfg.CoinSelectLocker.WithCoinSelectLock(func() error {
+		coins, err := w.cfg.CoinSource.ListCoins(
+			w.cfg.MinConfs, math.MaxInt32,
+		)
+		if err != nil {
+			return err
+		}
+
+		// The inputs of an interactively constructed transaction must
+		// be native segwit, so its txid can't be malleated.
+		coins = fn.Filter(func(coin wallet.Coin) bool {
+			return txscript.IsWitnessProgram(coin.PkScript)
+		}, coins)
+
+		selectedCoins, changeAmt, err := chanfunding.CoinSelect(
+			feeRate, amt, w.cfg.DustLimit, coins,
+			w.cfg.CoinSelectionStrategy, weightEstimate,
+			chanfunding.P2TRChangeAddress,
+		)
+		if err != nil {
+			return err
+		}
+
+		if changeAmt != 0 {
+			changeScript, err := w.cfg.ChangeScript()
+			if err != nil {
+				return err
+			}
+
+			change = fn.Some(&interactivetx.Output{
+				Value:    changeAmt,
+				PkScript: changeScript,
+			})
+		}
+
+		for _, coin := range selectedCoins {
+			prevTx, err := w.cfg.FetchTx(coin.OutPoint.Hash)
+			if err != nil {
+				return fmt.Errorf("unable to fetch previous "+
+					"transaction of %v: %w", coin.OutPoint,
+					err)
+			}
+
+			prevOutput := coin.TxOut
+			inputs = append(inputs, &interactivetx.Input{
+				OutPoint:   coin.OutPoint,
+				PrevTx:     prevTx,
+				PrevOutput: &prevOutput,
+				Sequence:   spliceInputSequence,
+			})
+		}
+
+		// Lease the coins, so they aren't selected elsewhere while the
+		// splice is negotiated.
+		for _, in := range inputs {
+			_, err := w.cfg.CoinLeaser.LeaseOutput(
+				chanfunding.LndInternalLockID, in.OutPoint,
+				chanfunding.DefaultReservationTimeout,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		w.ReleaseInputs(inputs)
+
+		return nil, change, err
+	}
+
+	return inputs, change, nil
+}
+
+// ReleaseInputs releases the leases of inputs that were selected by
+// FundSplice.
+//
+// NOTE: Part of the Wallet interface.
+func (w *walletSource) ReleaseInputs(inputs []*interactivetx.Input) {
+	for _, in := range inputs {
+		err := w.cfg.CoinLeaser.ReleaseOutput(
+			chanfunding.LndInternalLockID, in.OutPoint,
+		)
+		if err != nil {
+			spliceLog.Warnf("Failed to release UTXO %v: %v",
+				in.OutPoint, err)
+		}
+	}
+}
+
+// SignInputs returns the witnesses for the passed inputs of the transaction,
+// in the same order.
+//
+// NOTE: Part of the Wallet interface.
+func (w *walletSource) SignInputs(tx *wire.MsgTx,
+	inputs []*interactivetx.Input,
+	prevOuts txscript.PrevOutputFetcher) ([]wire.TxWitness, error) {
+
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+
+	witnesses := make([]wire.TxWitness, 0, len(inputs))
+	for _, in := range inputs {
+		inputIndex := -1
+		for i, txIn := range tx.TxIn {
+			if txIn.PreviousOutPoint == in.OutPoint {
+				inputIndex = i
+				break
+			}
+		}
+		if inputIndex == -1 {
+			return nil, fmt.Errorf("input %v not found in "+
+				"transaction", in.OutPoint)
+		}
+
+		signDesc := &input.SignDescriptor{
+			Output:            in.PrevOutput,
+			HashType:          txscript.SigHashAll,
+			SigHashes:         sigHashes,
+			PrevOutputFetcher: prevOuts,
+			InputIndex:        inputIndex,
+		}
+		if txscript.IsPayToTaproot(in.PrevOutput.PkScript) {
+			signDesc.HashType = txscript.SigHashDefault
+		}
+
+		inputScript, err := w.cfg.Signer.ComputeInputScript(
+			tx, signDesc,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		witnesses = append(witnesses, inputScript.Witness)
+	}
+
+	return witnesses, nil
+}
+
+// PublishTransaction broadcasts the passed transaction.
+//
+// NOTE: Part of the Wallet interface.
+func (w *walletSource) PublishTransaction(tx *wire.MsgTx, label string) error {
+	return w.cfg.PublishTransaction(tx, label)
+}
+
+// A compile-time check to ensure walletSource meets the Wallet interface.
+var _ Wallet = (*walletSource)(nil)
@@ -0,0 +1,272 @@
+package lnwallet
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// assertSpendsOutput asserts that the input of the transaction at the given
+// index validly spends the passed output.
+func assertSpendsOutput(t *testing.T, tx *wire.MsgTx, inputIndex int,
+	prevOut *wire.TxOut) {
+
+	t.Helper()
+
+	prevFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	prevFetcher.AddPrevOut(tx.TxIn[inputIndex].PreviousOutPoint, prevOut)
+
+	vm, err := txscript.NewEngine(
+		prevOut.PkScript, tx, inputIndex,
+		txscript.StandardVerifyFlags, nil,
+		input.NewTxSigHashesV0Only(tx), prevOut.Value,
+		prevFetcher,
+	)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+}
+
+// addTestHTLC adds an HTLC of the given amount from Alice to Bob.
+func addTestHTLC(t *testing.T, aliceChannel, bobChannel *LightningChannel,
+	id int, amt btcutil.Amount) {
+
+	t.Helper()
+
+	htlc, _ := createHTLC(id, lnwire.NewMSatFromSatoshis(amt))
+	_, err := aliceChannel.AddHTLC(htlc, nil)
+	require.NoError(t, err)
+	_, err = bobChannel.ReceiveHTLC(htlc)
+	require.NoError(t, err)
+}
+
+// TestSplice tests that both parties of a channel with HTLCs can sign the
+// commitments spending the funding output of a splice transaction, sign the
+// splice transaction itself, and continue to update the channel while the
+// splice confirms, up to promoting it in the middle of a commitment update.
+func TestSplice(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, err := CreateTestChannels(
+		t, channeldb.SingleFunderTweaklessBit|
+			channeldb.AnchorOutputsBit,
+	)
+	require.NoError(t, err, "unable to create test channels")
+
+	const (
+		spliceIn = btcutil.Amount(1_000_000)
+		htlcAmt  = btcutil.Amount(100_000)
+	)
+
+	// The channel already carries an HTLC before it's spliced.
+	addTestHTLC(t, aliceChannel, bobChannel, 0, htlcAmt)
+	require.NoError(t, ForceStateTransition(aliceChannel, bobChannel))
+
+	oldFundingPoint := aliceChannel.channelState.CurrentFundingOutpoint()
+	oldFundingOutput := aliceChannel.fundingOutput
+	oldCapacity := aliceChannel.Capacity
+
+	bobBalance := bobChannel.channelState.LocalCommitment.LocalBalance
+
+	// Alice splices funds into the channel, using an input of her wallet.
+	newCapacity := oldCapacity + spliceIn
+	_, fundingOutput, err := SpliceFundingOutput(
+		aliceChannel.channelState, newCapacity,
+	)
+	require.NoError(t, err)
+
+	spliceTx := wire.NewMsgTx(2)
+	spliceTx.AddTxIn(wire.NewTxIn(&oldFundingPoint, nil, nil))
+	spliceTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+		Hash: chainhash.Hash{1},
+	}, nil, nil))
+	spliceTx.AddTxOut(fundingOutput)
+	spliceTxid := spliceTx.TxHash()
+
+	aliceSplice := &SpliceTx{
+		Tx:                spliceTx,
+		LocalContribution: spliceIn,
+	}
+	bobSplice := &SpliceTx{
+		Tx:                 spliceTx,
+		RemoteContribution: spliceIn,
+	}
+
+	// A splice whose funding output doesn't match the contributions is
+	// rejected.
+	_, _, err = aliceChannel.SignSpliceCommitment(&SpliceTx{
+		Tx:                spliceTx,
+		LocalContribution: spliceIn - 1,
+	})
+	require.ErrorContains(t, err, "doesn't match expected capacity")
+
+	// Both parties sign each other's commitment spending the new funding
+	// output.
+	aliceCandidate, aliceSigs, err := aliceChannel.SignSpliceCommitment(
+		aliceSplice,
+	)
+	require.NoError(t, err)
+	require.Len(t, aliceSigs.HtlcSigs, 1)
+
+	bobCandidate, bobSigs, err := bobChannel.SignSpliceCommitment(
+		bobSplice,
+	)
+	require.NoError(t, err)
+
+	// An invalid signature is rejected.
+	err = aliceChannel.ReceiveSpliceCommitSig(aliceCandidate, aliceSigs)
+	require.ErrorIs(t, err, ErrInvalidSpliceSig)
+
+	err = aliceChannel.ReceiveSpliceCommitSig(aliceCandidate, bobSigs)
+	require.NoError(t, err)
+	err = bobChannel.ReceiveSpliceCommitSig(bobCandidate, aliceSigs)
+	require.NoError(t, err)
+
+	// Both parties now hold the same variants of the current commitments,
+	// which survive a restart.
+	bobChannel, err = restartChannel(bobChannel)
+	require.NoError(t, err)
+	require.Len(t, bobChannel.pendingSplices, 1)
+
+	require.Equal(
+		t, aliceChannel.localCommitChain.tail().
+			spliceCommits[spliceTxid].txn.TxHash(),
+		bobChannel.remoteCommitChain.tail().
+			spliceCommits[spliceTxid].txn.TxHash(),
+	)
+	require.Equal(
+		t, bobChannel.localCommitChain.tail().
+			spliceCommits[spliceTxid].txn.TxHash(),
+		aliceChannel.remoteCommitChain.tail().
+			spliceCommits[spliceTxid].txn.TxHash(),
+	)
+
+	// Next, both parties sign the shared funding input of the splice
+	// transaction.
+	aliceInputSig, err := aliceChannel.SignSharedInput(spliceTx, 0)
+	require.NoError(t, err)
+	bobInputSig, err := bobChannel.SignSharedInput(spliceTx, 0)
+	require.NoError(t, err)
+
+	_, err = aliceChannel.SignSharedInput(spliceTx, 1)
+	require.ErrorContains(t, err, "doesn't spend funding output")
+
+	_, err = aliceChannel.SharedInputWitness(
+		spliceTx, 0, aliceInputSig, aliceInputSig,
+	)
+	require.ErrorIs(t, err, ErrInvalidSpliceSig)
+
+	witness, err := aliceChannel.SharedInputWitness(
+		spliceTx, 0, aliceInputSig, bobInputSig,
+	)
+	require.NoError(t, err)
+
+	spliceTx.TxIn[0].Witness = witness
+	assertSpendsOutput(t, spliceTx, 0, &oldFundingOutput)
+
+	// While the splice confirms, the channel can still be updated, with
+	// both parties signing the variants of the new commitments.
+	revokedCommit := aliceChannel.remoteCommitChain.tail()
+	revokedVariant := revokedCommit.spliceCommits[spliceTxid].txn
+
+	addTestHTLC(t, aliceChannel, bobChannel, 1, htlcAmt)
+	require.NoError(t, ForceStateTransition(aliceChannel, bobChannel))
+
+	// Should Bob broadcast the revoked variant, Alice can still punish
+	// him, as its outputs are located by their scripts.
+	br, err := NewBreachRetribution(
+		aliceChannel.channelState, revokedCommit.height, 0,
+		revokedVariant,
+	)
+	require.NoError(t, err)
+	require.Equal(t, revokedVariant.TxHash(), br.BreachTxHash)
+	require.Len(t, br.HtlcRetributions, 1)
+
+	htlcIndex := br.HtlcRetributions[0].OutPoint.Index
+	htlcOutput := revokedVariant.TxOut[htlcIndex]
+	require.EqualValues(t, htlcAmt, htlcOutput.Value)
+	require.Equal(
+		t, br.HtlcRetributions[0].SignDesc.Output.PkScript,
+		htlcOutput.PkScript,
+	)
+
+	remoteOutput := revokedVariant.TxOut[br.RemoteOutpoint.Index]
+	require.Equal(
+		t, br.RemoteOutputSignDesc.Output.PkScript,
+		remoteOutput.PkScript,
+	)
+
+	// Alice sends another HTLC, and promotes the splice after Bob received
+	// her signatures, but before he revoked his prior commitment.
+	addTestHTLC(t, aliceChannel, bobChannel, 2, htlcAmt)
+
+	aliceNewCommit, err := aliceChannel.SignNextCommitment()
+	require.NoError(t, err)
+	require.Len(t, aliceNewCommit.SpliceSigs, 1)
+	err = bobChannel.ReceiveNewCommitment(aliceNewCommit.CommitSigs)
+	require.NoError(t, err)
+
+	err = aliceChannel.PromoteSplice(chainhash.Hash{2})
+	require.ErrorIs(t, err, channeldb.ErrSpliceCommitmentNotFound)
+	require.NoError(t, aliceChannel.PromoteSplice(spliceTxid))
+
+	// Bob hasn't promoted the splice yet, so Alice must pick the signature
+	// for the variant of her commitment.
+	bobRevocation, _, _, err := bobChannel.RevokeCurrentCommitment()
+	require.NoError(t, err)
+	bobNewCommit, err := bobChannel.SignNextCommitment()
+	require.NoError(t, err)
+
+	_, _, _, _, err = aliceChannel.ReceiveRevocation(bobRevocation)
+	require.NoError(t, err)
+	err = aliceChannel.ReceiveNewCommitment(bobNewCommit.CommitSigs)
+	require.NoError(t, err)
+
+	aliceRevocation, _, _, err := aliceChannel.RevokeCurrentCommitment()
+	require.NoError(t, err)
+	_, _, _, _, err = bobChannel.ReceiveRevocation(aliceRevocation)
+	require.NoError(t, err)
+
+	require.NoError(t, bobChannel.PromoteSplice(spliceTxid))
+
+	newFundingPoint := wire.OutPoint{Hash: spliceTxid}
+	for _, channel := range []*LightningChannel{aliceChannel, bobChannel} {
+		require.Equal(t, newCapacity, channel.Capacity)
+		require.Equal(
+			t, newFundingPoint,
+			channel.channelState.CurrentFundingOutpoint(),
+		)
+		require.Equal(t, oldFundingPoint, channel.ChannelPoint())
+		require.Empty(t, channel.pendingSplices)
+		require.Len(t, channel.channelState.ActiveHtlcs(), 3)
+	}
+
+	require.Equal(
+		t, bobBalance,
+		bobChannel.channelState.LocalCommitment.LocalBalance,
+	)
+
+	// The signed commitment of both parties must spend the new funding
+	// output.
+	aliceCommit, err := aliceChannel.getSignedCommitTx()
+	require.NoError(t, err)
+	assertSpendsOutput(t, aliceCommit, 0, fundingOutput)
+
+	bobCommit, err := bobChannel.getSignedCommitTx()
+	require.NoError(t, err)
+	assertSpendsOutput(t, bobCommit, 0, fundingOutput)
+
+	// Finally, the channel can be used as usual after the splice.
+	addTestHTLC(t, aliceChannel, bobChannel, 3, spliceIn+htlcAmt)
+	require.NoError(t, ForceStateTransition(aliceChannel, bobChannel))
+
+	aliceCommit, err = aliceChannel.getSignedCommitTx()
+	require.NoError(t, err)
+	assertSpendsOutput(t, aliceCommit, 0, fundingOutput)
+}
@@ -58,6 +58,12 @@ var (
 	// the construction of a PSBT outside of lnd's wallet.
 	ErrPsbtFundingRequired = errors.New("PSBT funding required")
 
+	// ErrInteractiveTxRequired is the error that is returned during the
+	// contribution handling process if the funding transaction needs to be
+	// constructed interactively with the remote party.
+	ErrInteractiveTxRequired = errors.New("interactive funding " +
+		"transaction construction required")
+
 	// ErrReservedValueInvalidated is returned if we try to publish a
 	// transaction that would take the walletbalance below what we require
 	// to keep around to fee bump our open anchor channels.
@@ -89,6 +95,22 @@ func (p *PsbtFundingRequired) Error() string {
 	return ErrPsbtFundingRequired.Error()
 }
 
+// InteractiveTxRequired is a type that implements the error interface and
+// contains the intent whose funding transaction needs to be constructed
+// interactively with the remote party.
+type InteractiveTxRequired struct {
+	// Intent is the pending interactive funding intent that needs to be
+	// negotiated if the wrapping error is returned.
+	Intent *chanfunding.InteractiveIntent
+}
+
+// Error returns the underlying error.
+//
+// NOTE: This method is part of the error interface.
+func (i *InteractiveTxRequired) Error() string {
+	return ErrInteractiveTxRequired.Error()
+}
+
 // InitFundingReserveMsg is the first message sent to initiate the workflow
 // required to open a payment channel with a remote peer. The initial required
 // parameters are configurable across channels. These parameters are to be
@@ -210,6 +232,16 @@ type InitFundingReserveMsg struct {
 	// channel that will be useful to our future selves.
 	Memo []byte
 
+	// DualFund signals that the channel is opened with open_channel2, so
+	// the funding transaction is constructed interactively, and both
+	// parties may contribute funds.
+	DualFund bool
+
+	// Initiator is true if we sent open_channel2. It's only consulted for
+	// dual funded channels, as the initiator of a single funded channel is
+	// always the party contributing the funds.
+	Initiator bool
+
 	// err is a channel in which all errors will be sent across. Will be
 	// nil if this initial set is successful.
 	//
@@ -871,7 +903,33 @@ func (l *LightningWallet) handleFundingReserveRequest(req *InitFundingReserveMsg
 			CoinSelectionStrategy: l.Cfg.CoinSelectionStrategy,
 		}
 		req.ChanFunder = chanfunding.NewWalletAssembler(cfg)
+
+		// A dual funded channel only knows about our inputs upfront,
+		// the rest of the funding transaction is negotiated later.
+		if req.DualFund {
+			req.ChanFunder = chanfunding.NewInteractiveAssembler(
+				chanfunding.InteractiveConfig{
+					CoinSource:            cfg.CoinSource,
+					CoinSelectionStrategy: cfg.CoinSelectionStrategy,
+					CoinSelectLocker:      l,
+					CoinLeaser:            l,
+					Signer:                l.Cfg.Signer,
+					FetchTx:               l.FetchTx,
+					DustLimit:             cfg.DustLimit,
+					Initiator:             req.Initiator,
+				},
+			)
+		}
 	} else {
+		_, isInteractive :=
+			req.ChanFunder.(*chanfunding.InteractiveAssembler)
+		if req.DualFund && !isInteractive {
+			req.err <- fmt.Errorf("dual funded channels require " +
+				"an interactive assembler")
+			req.resp <- nil
+			return
+		}
+
 		_, isPsbtFunder := req.ChanFunder.(*chanfunding.PsbtAssembler)
 		enforceNewReservedValue = !isPsbtFunder
 	}
@@ -1378,6 +1436,15 @@ func (l *LightningWallet) initOurContribution(reservation *ChannelReservation,
 		firstPreimage[:],
 	)
 
+	// Dual funded channels send the second commitment point upfront as
+	// well.
+	secondPreimage, err := producer.AtIndex(1)
+	if err != nil {
+		return err
+	}
+	reservation.ourContribution.SecondCommitmentPoint =
+		input.ComputeCommitmentPoint(secondPreimage[:])
+
 	reservation.partialState.RevocationProducer = producer
 	reservation.ourContribution.CommitmentParams.DustLimit =
 		DustLimitUnknownWitness()
@@ -1610,6 +1677,22 @@ func (l *LightningWallet) handleContributionMsg(req *addContributionMsg) {
 		}
 		return
 
+	// The funding transaction of a dual funded channel is negotiated with
+	// the remote party. With both keys known, the funding output can be
+	// created, so we pause the process until the transaction has been
+	// negotiated.
+	case *chanfunding.InteractiveIntent:
+		fundingIntent.BindKeys(
+			&ourContribution.MultiSigKey,
+			theirContribution.MultiSigKey.PubKey,
+		)
+
+		// Exit early because we can't continue the funding flow yet.
+		req.err <- &InteractiveTxRequired{
+			Intent: fundingIntent,
+		}
+		return
+
 	case *chanfunding.FullIntent:
 		// Now that we know their public key, we can bind theirs as
 		// well as ours to the funding intent.
@@ -1819,6 +1902,24 @@ func (l *LightningWallet) handleChanPointReady(req *continueContributionMsg) {
 		}
 	}
 
+	// If the funding transaction was negotiated interactively, the
+	// channel point is known now. Our inputs are signed separately once
+	// the commitment signatures have been exchanged.
+	interactiveIntent, isInteractive :=
+		intent.(*chanfunding.InteractiveIntent)
+	if isInteractive {
+		chanPointPtr, err := interactiveIntent.ChanPoint()
+		if err != nil {
+			req.err <- fmt.Errorf("unable to obtain chan "+
+				"point: %v", err)
+			return
+		}
+
+		pendingReservation.partialState.FundingOutpoint = *chanPointPtr
+		pendingReservation.fundingTx = interactiveIntent.FundingTx()
+		chanPoint = *chanPointPtr
+	}
+
 	// Initialize an empty sha-chain for them, tracking the current pending
 	// revocation hash (we don't yet know the preimage so we can't add it
 	// to the chain).
@@ -1861,12 +1962,22 @@ func (l *LightningWallet) handleChanPointReady(req *continueContributionMsg) {
 	// obfuscator then use it to encode the current state number within
 	// both commitment transactions.
 	var stateObfuscator [StateHintSize]byte
-	if chanState.ChanType.IsSingleFunder() {
+	switch {
+	case chanState.ChanType.IsSingleFunder() || chanState.IsInitiator:
 		stateObfuscator = DeriveStateHintObfuscator(
 			ourContribution.PaymentBasePoint.PubKey,
 			theirContribution.PaymentBasePoint.PubKey,
 		)
-	} else {
+
+	// Channels opened with open_channel2 have an initiator, whose key
+	// comes first.
+	case isInteractive:
+		stateObfuscator = DeriveStateHintObfuscator(
+			theirContribution.PaymentBasePoint.PubKey,
+			ourContribution.PaymentBasePoint.PubKey,
+		)
+
+	default:
 		ourSer := ourContribution.PaymentBasePoint.PubKey.SerializeCompressed()
 		theirSer := theirContribution.PaymentBasePoint.PubKey.SerializeCompressed()
 		switch bytes.Compare(ourSer, theirSer) {
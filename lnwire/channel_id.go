@@ -1,10 +1,13 @@
 package lnwire
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"math"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
@@ -52,6 +55,46 @@ func NewChanIDFromOutPoint(op wire.OutPoint) ChannelID {
 	return cid
 }
 
+// NewTempChanIDFromRevocationBasepoint returns the temporary channel ID the
+// initiator of a dual funded channel uses in open_channel2, which is the
+// SHA256 of 33 zero bytes followed by its revocation basepoint.
+func NewTempChanIDFromRevocationBasepoint(
+	basepoint *btcec.PublicKey) ChannelID {
+
+	var zeroPoint [btcec.PubKeyBytesLenCompressed]byte
+
+	h := sha256.New()
+	h.Write(zeroPoint[:])
+	h.Write(basepoint.SerializeCompressed())
+
+	var cid ChannelID
+	copy(cid[:], h.Sum(nil))
+
+	return cid
+}
+
+// NewChanIDFromRevocationBasepoints returns the channel ID of a dual funded
+// channel, which is the SHA256 of the revocation basepoints of both parties,
+// lesser one first. Unlike the channel ID of single funded channels, it
+// doesn't depend on the funding outpoint, so it's used from accept_channel2 on
+// for the lifetime of the channel.
+func NewChanIDFromRevocationBasepoints(a, b *btcec.PublicKey) ChannelID {
+	aBytes := a.SerializeCompressed()
+	bBytes := b.SerializeCompressed()
+	if bytes.Compare(aBytes, bBytes) > 0 {
+		aBytes, bBytes = bBytes, aBytes
+	}
+
+	h := sha256.New()
+	h.Write(aBytes)
+	h.Write(bBytes)
+
+	var cid ChannelID
+	copy(cid[:], h.Sum(nil))
+
+	return cid
+}
+
 // xorTxid performs the transformation needed to transform an OutPoint into a
 // ChannelID. To do this, we expect the cid parameter to contain the txid
 // unaltered and the outputIndex to be the output index
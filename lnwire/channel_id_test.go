@@ -1,6 +1,13 @@
 package lnwire
 
-import "testing"
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/require"
+)
 
 // TestChannelIDOutPointConversion ensures that the IsChanPoint always
 // recognizes its seed OutPoint for all possible values of an output index.
@@ -70,3 +77,35 @@ func TestGenPossibleOutPoints(t *testing.T) {
 		t.Fatalf("possible outpoints did not contain the root outpoint")
 	}
 }
+
+// TestChanIDFromRevocationBasepoints ensures that the channel ID of a dual
+// funded channel is derived from the revocation basepoints regardless of the
+// order they're passed in, and that it differs from the temporary channel ID.
+func TestChanIDFromRevocationBasepoints(t *testing.T) {
+	t.Parallel()
+
+	privA, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	privB, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	a, b := privA.PubKey(), privB.PubKey()
+
+	cid := NewChanIDFromRevocationBasepoints(a, b)
+	require.Equal(t, cid, NewChanIDFromRevocationBasepoints(b, a))
+
+	lesser, greater := a.SerializeCompressed(), b.SerializeCompressed()
+	if bytes.Compare(lesser, greater) > 0 {
+		lesser, greater = greater, lesser
+	}
+	expected := sha256.Sum256(append(lesser, greater...))
+	require.Equal(t, ChannelID(expected), cid)
+
+	var zeroPoint [btcec.PubKeyBytesLenCompressed]byte
+	expected = sha256.Sum256(
+		append(zeroPoint[:], a.SerializeCompressed()...),
+	)
+	tempID := NewTempChanIDFromRevocationBasepoint(a)
+	require.Equal(t, ChannelID(expected), tempID)
+	require.NotEqual(t, cid, tempID)
+}
@@ -45,6 +45,11 @@ type CommitSig struct {
 	// being signed for. In this case, the above Sig type MUST be blank.
 	PartialSig OptPartialSigWithNonceTLV
 
+	// SpliceSigs carries the signatures for the variants of the new
+	// commitment that spend the funding outputs of pending splice
+	// transactions, one for each of them.
+	SpliceSigs OptSpliceCommitSigsTLV
+
 	// ExtraData is the set of data that was appended to this message to
 	// fill out the full maximum transport message size. These fields can
 	// be used to specify optional data such as custom TLV fields.
@@ -82,7 +87,8 @@ func (c *CommitSig) Decode(r io.Reader, pver uint32) error {
 	}
 
 	partialSig := c.PartialSig.Zero()
-	typeMap, err := tlvRecords.ExtractRecords(&partialSig)
+	spliceSigs := c.SpliceSigs.Zero()
+	typeMap, err := tlvRecords.ExtractRecords(&partialSig, &spliceSigs)
 	if err != nil {
 		return err
 	}
@@ -91,6 +97,9 @@ func (c *CommitSig) Decode(r io.Reader, pver uint32) error {
 	if val, ok := typeMap[c.PartialSig.TlvType()]; ok && val == nil {
 		c.PartialSig = tlv.SomeRecordT(partialSig)
 	}
+	if val, ok := typeMap[c.SpliceSigs.TlvType()]; ok && val == nil {
+		c.SpliceSigs = tlv.SomeRecordT(spliceSigs)
+	}
 
 	if len(tlvRecords) != 0 {
 		c.ExtraData = tlvRecords
@@ -104,10 +113,13 @@ func (c *CommitSig) Decode(r io.Reader, pver uint32) error {
 //
 // This is part of the lnwire.Message interface.
 func (c *CommitSig) Encode(w *bytes.Buffer, pver uint32) error {
-	recordProducers := make([]tlv.RecordProducer, 0, 1)
+	recordProducers := make([]tlv.RecordProducer, 0, 2)
 	c.PartialSig.WhenSome(func(sig PartialSigWithNonceTLV) {
 		recordProducers = append(recordProducers, &sig)
 	})
+	c.SpliceSigs.WhenSome(func(sigs SpliceCommitSigsTLV) {
+		recordProducers = append(recordProducers, &sigs)
+	})
 	err := EncodeMessageExtraData(&c.ExtraData, recordProducers...)
 	if err != nil {
 		return err
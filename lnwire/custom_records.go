@@ -12,6 +12,20 @@ const (
 	// MinCustomRecordsTlvType is the minimum custom records TLV type as
 	// defined in BOLT 01.
 	MinCustomRecordsTlvType = 65536
+
+	// ExperimentalEndorsementType is the TLV type of the custom record
+	// that carries the experimental endorsement signal of an htlc, as
+	// defined in bLIP-04.
+	ExperimentalEndorsementType tlv.Type = 106823
+
+	// ExperimentalUnendorsed is the value of the experimental endorsement
+	// record of an htlc that the sender doesn't vouch for.
+	ExperimentalUnendorsed uint8 = 0
+
+	// ExperimentalEndorsed is the value of the experimental endorsement
+	// record of an htlc that the sender expects to resolve quickly and
+	// vouches for with its reputation.
+	ExperimentalEndorsed uint8 = 1
 )
 
 // CustomRecords stores a set of custom key/value pairs. Map keys are TLV types
@@ -57,6 +71,22 @@ func ParseCustomRecords(b tlv.Blob) (CustomRecords, error) {
 	return NewCustomRecords(typeMap)
 }
 
+// ExtractCustomRecords returns the entries of a tlv.TypeMap, as returned when
+// extracting the known records of a message's extra data, that are in the
+// custom type range. All other entries are ignored.
+func ExtractCustomRecords(tlvMap tlv.TypeMap) (CustomRecords, error) {
+	customTypes := make(tlv.TypeMap)
+	for tlvType, value := range tlvMap {
+		if uint64(tlvType) < MinCustomRecordsTlvType {
+			continue
+		}
+
+		customTypes[tlvType] = value
+	}
+
+	return NewCustomRecords(customTypes)
+}
+
 // Validate checks that all custom records are in the custom type range.
 func (c CustomRecords) Validate() error {
 	if c == nil {
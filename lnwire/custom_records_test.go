@@ -196,3 +196,24 @@ func serializeRecordProducers(t *testing.T,
 
 	return b.Bytes()
 }
+
+// TestExtractCustomRecords tests that only the entries in the custom type
+// range are extracted from a tlv.TypeMap.
+func TestExtractCustomRecords(t *testing.T) {
+	t.Parallel()
+
+	records, err := ExtractCustomRecords(tlv.TypeMap{
+		1:     nil,
+		123:   []byte{1, 2, 3},
+		65536: []byte{4, 5, 6},
+	})
+	require.NoError(t, err)
+	require.Equal(t, CustomRecords{65536: {4, 5, 6}}, records)
+
+	// A map without custom records results in nil custom records.
+	records, err = ExtractCustomRecords(tlv.TypeMap{
+		1: nil,
+	})
+	require.NoError(t, err)
+	require.Nil(t, records)
+}
@@ -161,6 +161,16 @@ const (
 	// addresses for cooperative closure addresses.
 	ShutdownAnySegwitOptional FeatureBit = 27
 
+	// DualFundRequired is a required feature bit that signals that the
+	// node supports opening dual funded channels with open_channel2 and
+	// the interactive transaction construction protocol.
+	DualFundRequired FeatureBit = 28
+
+	// DualFundOptional is an optional feature bit that signals that the
+	// node supports opening dual funded channels with open_channel2 and
+	// the interactive transaction construction protocol.
+	DualFundOptional FeatureBit = 29
+
 	// AMPRequired is a required feature bit that signals that the receiver
 	// of a payment supports accepts spontaneous payments, i.e.
 	// sender-generated preimages according to BOLT XX.
@@ -171,6 +181,22 @@ const (
 	// sender-generated preimages according to BOLT XX.
 	AMPOptional FeatureBit = 31
 
+	// QuiescenceRequired is a required feature bit that signals that the
+	// node supports pausing channel updates with the stfu message.
+	QuiescenceRequired FeatureBit = 34
+
+	// QuiescenceOptional is an optional feature bit that signals that the
+	// node supports pausing channel updates with the stfu message.
+	QuiescenceOptional FeatureBit = 35
+
+	// OnionMessagesRequired is a required feature bit that signals that
+	// the node is able to forward and receive onion messages.
+	OnionMessagesRequired FeatureBit = 38
+
+	// OnionMessagesOptional is an optional feature bit that signals that
+	// the node is able to forward and receive onion messages.
+	OnionMessagesOptional FeatureBit = 39
+
 	// ExplicitChannelTypeRequired is a required bit that denotes that a
 	// connection established with this node is to use explicit channel
 	// commitment types for negotiation instead of the existing implicit
@@ -223,6 +249,14 @@ const (
 	// able and willing to accept keysend payments.
 	KeysendOptional = 55
 
+	// TrampolineRoutingRequired is a required feature bit that signals
+	// that the node is able to forward payments as a trampoline node.
+	TrampolineRoutingRequired FeatureBit = 56
+
+	// TrampolineRoutingOptional is an optional feature bit that signals
+	// that the node is able to forward payments as a trampoline node.
+	TrampolineRoutingOptional FeatureBit = 57
+
 	// ScriptEnforcedLeaseRequired is a required feature bit that signals
 	// that the node requires channels having zero-fee second-level HTLC
 	// transactions, which also imply anchor commitments, along with an
@@ -241,6 +275,48 @@ const (
 	// TODO: Decide on actual feature bit value.
 	ScriptEnforcedLeaseOptional FeatureBit = 2023
 
+	// SimpleCloseRequired is a required feature bit that signals that the
+	// node supports the simple close protocol, in which each party pays
+	// the fee of its own cooperative close transaction, and may replace it
+	// with a higher fee version.
+	SimpleCloseRequired FeatureBit = 60
+
+	// SimpleCloseOptional is an optional feature bit that signals that the
+	// node supports the simple close protocol, in which each party pays
+	// the fee of its own cooperative close transaction, and may replace it
+	// with a higher fee version.
+	SimpleCloseOptional FeatureBit = 61
+
+	// SpliceRequired is a required feature bit that signals that the node
+	// supports splicing funds into and out of a channel while it stays
+	// open. This is an experimental bit that is only advertised by dev
+	// builds, as the splice signatures are exchanged in a format that
+	// differs from the spec. Only private, non-taproot channels can be
+	// spliced, only the initiator contributes funds and splice
+	// transactions can't be replaced with RBF.
+	SpliceRequired FeatureBit = 162
+
+	// SpliceOptional is an optional feature bit that signals that the node
+	// supports splicing funds into and out of a channel while it stays
+	// open. This is an experimental bit that is only advertised by dev
+	// builds, as the splice signatures are exchanged in a format that
+	// differs from the spec. Only private, non-taproot channels can be
+	// spliced, only the initiator contributes funds and splice
+	// transactions can't be replaced with RBF.
+	SpliceOptional FeatureBit = 163
+
+	// DynCommitmentsRequired is a required feature bit that signals that
+	// the node supports changing the parameters and the type of a channel
+	// with a dynamic commitment upgrade. The bit isn't assigned by the
+	// spec yet, so it is only advertised by dev builds.
+	DynCommitmentsRequired FeatureBit = 160
+
+	// DynCommitmentsOptional is an optional feature bit that signals that
+	// the node supports changing the parameters and the type of a channel
+	// with a dynamic commitment upgrade. The bit isn't assigned by the
+	// spec yet, so it is only advertised by dev builds.
+	DynCommitmentsOptional FeatureBit = 161
+
 	// SimpleTaprootChannelsRequiredFinal is a required bit that indicates
 	// the node is able to create taproot-native channels. This is the
 	// final feature bit to be used once the channel type is finalized.
@@ -317,6 +393,8 @@ var Features = map[FeatureBit]string{
 	AnchorsZeroFeeHtlcTxOptional:         "anchors-zero-fee-htlc-tx",
 	WumboChannelsRequired:                "wumbo-channels",
 	WumboChannelsOptional:                "wumbo-channels",
+	DualFundRequired:                     "dual-fund",
+	DualFundOptional:                     "dual-fund",
 	AMPRequired:                          "amp",
 	AMPOptional:                          "amp",
 	PaymentMetadataOptional:              "payment-metadata",
@@ -341,6 +419,18 @@ var Features = map[FeatureBit]string{
 	SimpleTaprootChannelsOptionalStaging: "simple-taproot-chans-x",
 	Bolt11BlindedPathsOptional:           "bolt-11-blinded-paths",
 	Bolt11BlindedPathsRequired:           "bolt-11-blinded-paths",
+	QuiescenceRequired:                   "quiescence",
+	QuiescenceOptional:                   "quiescence",
+	OnionMessagesRequired:                "onion-messages",
+	OnionMessagesOptional:                "onion-messages",
+	TrampolineRoutingRequired:            "trampoline-routing",
+	TrampolineRoutingOptional:            "trampoline-routing",
+	SimpleCloseRequired:                  "simple-close",
+	SimpleCloseOptional:                  "simple-close",
+	SpliceRequired:                       "splice-experimental",
+	SpliceOptional:                       "splice-experimental",
+	DynCommitmentsRequired:               "dyn-commitments",
+	DynCommitmentsOptional:               "dyn-commitments",
 }
 
 // RawFeatureVector represents a set of feature bits as defined in BOLT-09.  A
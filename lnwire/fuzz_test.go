@@ -246,6 +246,17 @@ func FuzzGossipTimestampRange(f *testing.F) {
 	})
 }
 
+func FuzzOnionMessage(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgOnionMessage.
+		data = prefixWithMsgType(data, MsgOnionMessage)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages!
+		harness(t, data)
+	})
+}
+
 func FuzzInit(f *testing.F) {
 	f.Fuzz(func(t *testing.T, data []byte) {
 		// Prefix with MsgInit.
@@ -911,3 +922,135 @@ func FuzzClosingComplete(f *testing.F) {
 		harness(t, data)
 	})
 }
+
+func FuzzTxAddInput(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxAddInput.
+		data = prefixWithMsgType(data, MsgTxAddInput)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxAddOutput(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxAddOutput.
+		data = prefixWithMsgType(data, MsgTxAddOutput)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxRemoveInput(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxRemoveInput.
+		data = prefixWithMsgType(data, MsgTxRemoveInput)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxRemoveOutput(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxRemoveOutput.
+		data = prefixWithMsgType(data, MsgTxRemoveOutput)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxComplete(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxComplete.
+		data = prefixWithMsgType(data, MsgTxComplete)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxSignatures(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxSignatures.
+		data = prefixWithMsgType(data, MsgTxSignatures)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzTxAbort(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgTxAbort.
+		data = prefixWithMsgType(data, MsgTxAbort)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzSpliceInit(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgSpliceInit.
+		data = prefixWithMsgType(data, MsgSpliceInit)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzSpliceAck(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgSpliceAck.
+		data = prefixWithMsgType(data, MsgSpliceAck)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzSpliceLocked(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgSpliceLocked.
+		data = prefixWithMsgType(data, MsgSpliceLocked)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzOpenChannel2(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgOpenChannel2.
+		data = prefixWithMsgType(data, MsgOpenChannel2)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
+
+func FuzzAcceptChannel2(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Prefix with MsgAcceptChannel2.
+		data = prefixWithMsgType(data, MsgAcceptChannel2)
+
+		// Pass the message into our general fuzz harness for wire
+		// messages.
+		harness(t, data)
+	})
+}
@@ -0,0 +1,100 @@
+package lnwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MaxInteractiveTxWitnessItems is the maximum number of witness stack items
+// we'll decode for a single input of an interactively constructed
+// transaction.
+const MaxInteractiveTxWitnessItems = 500
+
+// writeU16Bytes writes the passed byte slice to the buffer, prefixed with its
+// length encoded as a uint16.
+func writeU16Bytes(buf *bytes.Buffer, b []byte) error {
+	if len(b) > math.MaxUint16 {
+		return fmt.Errorf("cannot write %d bytes with a u16 length "+
+			"prefix", len(b))
+	}
+
+	if err := WriteUint16(buf, uint16(len(b))); err != nil {
+		return err
+	}
+
+	return WriteBytes(buf, b)
+}
+
+// readU16Bytes reads a byte slice that is prefixed with its length encoded as
+// a uint16. A nil slice is returned if the length is zero.
+func readU16Bytes(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint16(l[:])
+	if length == 0 {
+		return nil, nil
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// serializeWitness returns the witness stack serialized in the same format
+// that's used within a bitcoin transaction.
+func serializeWitness(witness wire.TxWitness) ([]byte, error) {
+	var b bytes.Buffer
+	if err := wire.WriteVarInt(&b, 0, uint64(len(witness))); err != nil {
+		return nil, err
+	}
+
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(&b, 0, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// deserializeWitness parses a witness stack that was serialized with
+// serializeWitness.
+func deserializeWitness(b []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(b)
+
+	numItems, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if numItems > MaxInteractiveTxWitnessItems {
+		return nil, fmt.Errorf("too many witness items: %d", numItems)
+	}
+
+	witness := make(wire.TxWitness, numItems)
+	for i := range witness {
+		witness[i], err = wire.ReadVarBytes(
+			r, 0, MaxMsgBody, "witness item",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after witness",
+			r.Len())
+	}
+
+	return witness, nil
+}
@@ -116,6 +116,35 @@ func somePartialSigWithNonce(t *testing.T,
 	)
 }
 
+func someSpliceCommitSigs(t *testing.T,
+	r *rand.Rand) OptSpliceCommitSigsTLV {
+
+	sigs := make([]SpliceCommitSig, 1+r.Intn(2))
+	for i := range sigs {
+		if _, err := r.Read(sigs[i].FundingTxID[:]); err != nil {
+			t.Fatal(err)
+		}
+
+		sig, err := NewSigFromSignature(testSig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs[i].CommitSig = sig
+
+		// Only create the slice if there are any htlc signatures, as
+		// an empty slice is decoded as nil.
+		numHtlcSigs := r.Intn(5)
+		if numHtlcSigs > 0 {
+			sigs[i].HtlcSigs = make([]Sig, numHtlcSigs)
+		}
+		for j := range sigs[i].HtlcSigs {
+			sigs[i].HtlcSigs[j] = sig
+		}
+	}
+
+	return MaybeSpliceCommitSigs(sigs)
+}
+
 func randAlias(r *rand.Rand) NodeAlias {
 	var a NodeAlias
 	for i := range a {
@@ -897,6 +926,12 @@ func TestLightningWireProtocol(t *testing.T) {
 				req.PartialSig = somePartialSigWithNonce(t, r)
 			}
 
+			// Attach signatures for pending splices if they still
+			// fit into the message.
+			if numSigs < 500 && r.Int31()%2 == 0 {
+				req.SpliceSigs = someSpliceCommitSigs(t, r)
+			}
+
 			v[0] = reflect.ValueOf(*req)
 		},
 		MsgRevokeAndAck: func(v []reflect.Value, r *rand.Rand) {
@@ -1387,8 +1422,281 @@ func TestLightningWireProtocol(t *testing.T) {
 				)
 			}
 
+			// Add custom records 50% of the time.
+			if r.Int31()%2 == 0 {
+				value := make([]byte, 8)
+				_, err := r.Read(value)
+				require.NoError(t, err)
+
+				req.CustomRecords = CustomRecords{
+					MinCustomRecordsTlvType: value,
+				}
+			}
+
+			// Set an endorsement signal 50% of the time, since not
+			// all peers signal endorsement.
+			if r.Int31()%2 == 0 {
+				if req.CustomRecords == nil {
+					req.CustomRecords = CustomRecords{}
+				}
+
+				endorsementType := uint64(
+					ExperimentalEndorsementType,
+				)
+				req.CustomRecords[endorsementType] = []byte{
+					uint8(r.Int31n(2)),
+				}
+			}
+
 			v[0] = reflect.ValueOf(*req)
 		},
+		MsgOnionMessage: func(v []reflect.Value, r *rand.Rand) {
+			pathKey, err := randPubKey()
+			require.NoError(t, err)
+
+			// Onion message packets are variable length, so we'll
+			// generate a blob of random size.
+			blob := make([]byte, r.Intn(MaxOnionMessageBlobSize))
+			_, err = r.Read(blob)
+			require.NoError(t, err)
+
+			req := NewOnionMessage(pathKey, blob)
+
+			v[0] = reflect.ValueOf(*req)
+		},
+		MsgTxAddInput: func(v []reflect.Value, r *rand.Rand) {
+			req := TxAddInput{
+				SerialID:  r.Uint64(),
+				PrevTxOut: r.Uint32(),
+				Sequence:  r.Uint32(),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			// Either spend the shared funding input, or an input
+			// whose previous transaction is included.
+			if r.Intn(2) == 0 {
+				txid := req.SharedInputTxid.Zero()
+				_, err := r.Read(txid.Val[:])
+				require.NoError(t, err)
+
+				req.SharedInputTxid = tlv.SomeRecordT(txid)
+			} else {
+				req.PrevTx = make([]byte, r.Intn(1000)+1)
+				_, err := r.Read(req.PrevTx)
+				require.NoError(t, err)
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxAddOutput: func(v []reflect.Value, r *rand.Rand) {
+			req := TxAddOutput{
+				SerialID: r.Uint64(),
+				Amount:   btcutil.Amount(r.Int63()),
+				Script:   make([]byte, r.Intn(34)+1),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			_, err = r.Read(req.Script)
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxRemoveInput: func(v []reflect.Value, r *rand.Rand) {
+			req := TxRemoveInput{
+				SerialID: r.Uint64(),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxRemoveOutput: func(v []reflect.Value, r *rand.Rand) {
+			req := TxRemoveOutput{
+				SerialID: r.Uint64(),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxComplete: func(v []reflect.Value, r *rand.Rand) {
+			var req TxComplete
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgSpliceLocked: func(v []reflect.Value, r *rand.Rand) {
+			var req SpliceLocked
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			_, err = r.Read(req.SpliceTxID[:])
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxSignatures: func(v []reflect.Value, r *rand.Rand) {
+			var req TxSignatures
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			_, err = r.Read(req.TxID[:])
+			require.NoError(t, err)
+
+			numWitnesses := r.Intn(4)
+			for i := 0; i < numWitnesses; i++ {
+				witness := make(wire.TxWitness, r.Intn(3)+1)
+				for j := range witness {
+					witness[j] = make([]byte, r.Intn(73)+1)
+					_, err := r.Read(witness[j])
+					require.NoError(t, err)
+				}
+
+				req.Witnesses = append(req.Witnesses, witness)
+			}
+
+			if r.Intn(2) == 0 {
+				sig := req.SharedInputSig.Zero()
+				_, err := r.Read(sig.Val.bytes[:])
+				require.NoError(t, err)
+
+				req.SharedInputSig = tlv.SomeRecordT(sig)
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgTxAbort: func(v []reflect.Value, r *rand.Rand) {
+			var req TxAbort
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			if r.Intn(2) == 0 {
+				req.Data = make([]byte, r.Intn(100)+1)
+				_, err := r.Read(req.Data)
+				require.NoError(t, err)
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgSpliceInit: func(v []reflect.Value, r *rand.Rand) {
+			req := SpliceInit{
+				FundingContribution: btcutil.Amount(
+					r.Int63() - r.Int63(),
+				),
+				FeeRate:  r.Uint32(),
+				LockTime: r.Uint32(),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			req.FundingKey, err = randPubKey()
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgSpliceAck: func(v []reflect.Value, r *rand.Rand) {
+			req := SpliceAck{
+				FundingContribution: btcutil.Amount(r.Int63()),
+			}
+
+			_, err := r.Read(req.ChannelID[:])
+			require.NoError(t, err)
+
+			req.FundingKey, err = randPubKey()
+			require.NoError(t, err)
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgOpenChannel2: func(v []reflect.Value, r *rand.Rand) {
+			req := OpenChannel2{
+				FundingFeePerKWeight: r.Uint32(),
+				CommitFeePerKWeight:  r.Uint32(),
+				FundingAmount:        btcutil.Amount(r.Int63()),
+				DustLimit:            btcutil.Amount(r.Int63()),
+				MaxValueInFlight:     MilliSatoshi(r.Int63()),
+				HtlcMinimum:          MilliSatoshi(r.Int31()),
+				CsvDelay:             uint16(r.Int31()),
+				MaxAcceptedHTLCs:     uint16(r.Int31()),
+				LockTime:             r.Uint32(),
+				ChannelFlags:         FundingFlag(uint8(r.Int31())),
+			}
+
+			_, err := r.Read(req.ChainHash[:])
+			require.NoError(t, err)
+
+			_, err = r.Read(req.PendingChannelID[:])
+			require.NoError(t, err)
+
+			keys := []**btcec.PublicKey{
+				&req.FundingKey, &req.RevocationPoint,
+				&req.PaymentPoint, &req.DelayedPaymentPoint,
+				&req.HtlcPoint, &req.FirstCommitmentPoint,
+				&req.SecondCommitmentPoint,
+			}
+			for _, key := range keys {
+				*key, err = randPubKey()
+				require.NoError(t, err)
+			}
+
+			// 1/2 chance empty TLV records.
+			if r.Intn(2) == 0 {
+				req.UpfrontShutdownScript, err = randDeliveryAddress(r)
+				require.NoError(t, err)
+
+				req.ChannelType = new(ChannelType)
+				*req.ChannelType = ChannelType(*randRawFeatureVector(r))
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
+		MsgAcceptChannel2: func(v []reflect.Value, r *rand.Rand) {
+			req := AcceptChannel2{
+				FundingAmount:    btcutil.Amount(r.Int63()),
+				DustLimit:        btcutil.Amount(r.Int63()),
+				MaxValueInFlight: MilliSatoshi(r.Int63()),
+				HtlcMinimum:      MilliSatoshi(r.Int31()),
+				MinAcceptDepth:   uint32(r.Int31()),
+				CsvDelay:         uint16(r.Int31()),
+				MaxAcceptedHTLCs: uint16(r.Int31()),
+			}
+
+			_, err := r.Read(req.PendingChannelID[:])
+			require.NoError(t, err)
+
+			keys := []**btcec.PublicKey{
+				&req.FundingKey, &req.RevocationPoint,
+				&req.PaymentPoint, &req.DelayedPaymentPoint,
+				&req.HtlcPoint, &req.FirstCommitmentPoint,
+				&req.SecondCommitmentPoint,
+			}
+			for _, key := range keys {
+				*key, err = randPubKey()
+				require.NoError(t, err)
+			}
+
+			// 1/2 chance empty TLV records.
+			if r.Intn(2) == 0 {
+				req.UpfrontShutdownScript, err = randDeliveryAddress(r)
+				require.NoError(t, err)
+
+				req.ChannelType = new(ChannelType)
+				*req.ChannelType = ChannelType(*randRawFeatureVector(r))
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
 	}
 
 	// With the above types defined, we'll now generate a slice of
@@ -1617,6 +1925,84 @@ func TestLightningWireProtocol(t *testing.T) {
 				return mainScenario(&m)
 			},
 		},
+		{
+			msgType: MsgTxAddInput,
+			scenario: func(m TxAddInput) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxAddOutput,
+			scenario: func(m TxAddOutput) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxRemoveInput,
+			scenario: func(m TxRemoveInput) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxRemoveOutput,
+			scenario: func(m TxRemoveOutput) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxComplete,
+			scenario: func(m TxComplete) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxSignatures,
+			scenario: func(m TxSignatures) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgTxAbort,
+			scenario: func(m TxAbort) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgSpliceInit,
+			scenario: func(m SpliceInit) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgSpliceAck,
+			scenario: func(m SpliceAck) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgSpliceLocked,
+			scenario: func(m SpliceLocked) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgOpenChannel2,
+			scenario: func(m OpenChannel2) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgAcceptChannel2,
+			scenario: func(m AcceptChannel2) bool {
+				return mainScenario(&m)
+			},
+		},
+		{
+			msgType: MsgOnionMessage,
+			scenario: func(m OnionMessage) bool {
+				return mainScenario(&m)
+			},
+		},
 	}
 	for _, test := range tests {
 		var config *quick.Config
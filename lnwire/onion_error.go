@@ -81,6 +81,9 @@ const (
 	CodeInvalidOnionPayload                       = FlagPerm | 22
 	CodeMPPTimeout                       FailCode = 23
 	CodeInvalidBlinding                           = FlagBadOnion | FlagPerm | 24 //nolint:lll
+	CodeTemporaryTrampolineFailure                = FlagNode | 25
+	CodeTrampolineFeeInsufficient                 = FlagNode | 51
+	CodeTrampolineExpiryTooSoon                   = FlagNode | 52
 )
 
 // String returns the string representation of the failure code.
@@ -161,6 +164,15 @@ func (c FailCode) String() string {
 	case CodeInvalidBlinding:
 		return "InvalidBlinding"
 
+	case CodeTemporaryTrampolineFailure:
+		return "TemporaryTrampolineFailure"
+
+	case CodeTrampolineFeeInsufficient:
+		return "TrampolineFeeInsufficient"
+
+	case CodeTrampolineExpiryTooSoon:
+		return "TrampolineExpiryTooSoon"
+
 	default:
 		return "<unknown>"
 	}
@@ -1281,6 +1293,67 @@ func NewInvalidBlinding(onion []byte) *FailInvalidBlinding {
 	return &FailInvalidBlinding{OnionSHA256: sha256.Sum256(onion)}
 }
 
+// FailTemporaryTrampolineFailure is returned by a trampoline node if it was
+// unable to forward the payment to the next trampoline node or recipient.
+//
+// NOTE: May only be returned by trampoline nodes.
+type FailTemporaryTrampolineFailure struct{}
+
+// Code returns the failure unique code.
+//
+// NOTE: Part of the FailureMessage interface.
+func (f *FailTemporaryTrampolineFailure) Code() FailCode {
+	return CodeTemporaryTrampolineFailure
+}
+
+// Returns a human readable string describing the target FailureMessage.
+//
+// NOTE: Implements the error interface.
+func (f *FailTemporaryTrampolineFailure) Error() string {
+	return f.Code().String()
+}
+
+// FailTrampolineFeeInsufficient is returned by a trampoline node if the fee
+// that was left for it by the sender is too low to reach the next node.
+//
+// NOTE: May only be returned by trampoline nodes.
+type FailTrampolineFeeInsufficient struct{}
+
+// Code returns the failure unique code.
+//
+// NOTE: Part of the FailureMessage interface.
+func (f *FailTrampolineFeeInsufficient) Code() FailCode {
+	return CodeTrampolineFeeInsufficient
+}
+
+// Returns a human readable string describing the target FailureMessage.
+//
+// NOTE: Implements the error interface.
+func (f *FailTrampolineFeeInsufficient) Error() string {
+	return f.Code().String()
+}
+
+// FailTrampolineExpiryTooSoon is returned by a trampoline node if the cltv
+// budget that was left for it by the sender is too low to reach the next
+// node.
+//
+// NOTE: May only be returned by trampoline nodes.
+type FailTrampolineExpiryTooSoon struct{}
+
+// Code returns the failure unique code.
+//
+// NOTE: Part of the FailureMessage interface.
+func (f *FailTrampolineExpiryTooSoon) Code() FailCode {
+	return CodeTrampolineExpiryTooSoon
+}
+
+// Returns a human readable string describing the target FailureMessage.
+//
+// NOTE: Implements the error interface.
+func (f *FailTrampolineExpiryTooSoon) Error() string {
+	return f.Code().String()
+}
+
 // DecodeFailure decodes, validates, and parses the lnwire onion failure, for
 // the provided protocol version.
 func DecodeFailure(r io.Reader, pver uint32) (FailureMessage, error) {
@@ -1502,6 +1575,15 @@ func makeEmptyOnionError(code FailCode) (FailureMessage, error) {
 	case CodeInvalidBlinding:
 		return &FailInvalidBlinding{}, nil
 
+	case CodeTemporaryTrampolineFailure:
+		return &FailTemporaryTrampolineFailure{}, nil
+
+	case CodeTrampolineFeeInsufficient:
+		return &FailTrampolineFeeInsufficient{}, nil
+
+	case CodeTrampolineExpiryTooSoon:
+		return &FailTrampolineExpiryTooSoon{}, nil
+
 	default:
 		return nil, errors.Errorf("unknown error code: %v", code)
 	}
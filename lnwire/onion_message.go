@@ -0,0 +1,99 @@
+package lnwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// MaxOnionMessageBlobSize is the largest onion message packet that can be
+// carried within an OnionMessage while still fitting within a single
+// transport message alongside the message type and path key.
+const MaxOnionMessageBlobSize = MaxMsgBody - btcec.PubKeyBytesLenCompressed - 2
+
+// OnionMessage is a message that carries an onion encrypted packet between
+// nodes that isn't tied to any payment or channel. Each hop is only able to
+// peel off its own layer of the onion, learning only the next hop the message
+// should be relayed to, or that it is the final recipient.
+type OnionMessage struct {
+	// PathKey is the ephemeral public key that the receiving node should
+	// use to decrypt the encrypted data destined for it within the onion.
+	PathKey *btcec.PublicKey
+
+	// OnionBlob is the raw serialized sphinx packet of the onion message.
+	OnionBlob []byte
+}
+
+// NewOnionMessage creates a new OnionMessage with the passed path key and
+// onion packet.
+func NewOnionMessage(pathKey *btcec.PublicKey, blob []byte) *OnionMessage {
+	return &OnionMessage{
+		PathKey:   pathKey,
+		OnionBlob: blob,
+	}
+}
+
+// A compile time check to ensure OnionMessage implements the lnwire.Message
+// interface.
+var _ Message = (*OnionMessage)(nil)
+
+// Encode serializes the target OnionMessage into the passed io.Writer.
+// Serialization will observe the rules defined by the passed protocol version.
+//
+// This is a part of the lnwire.Message interface.
+func (o *OnionMessage) Encode(w *bytes.Buffer, _ uint32) error {
+	if len(o.OnionBlob) > MaxOnionMessageBlobSize {
+		return fmt.Errorf("onion message blob too large: %d bytes, "+
+			"max is %d", len(o.OnionBlob), MaxOnionMessageBlobSize)
+	}
+
+	if err := WritePublicKey(w, o.PathKey); err != nil {
+		return err
+	}
+
+	if err := WriteUint16(w, uint16(len(o.OnionBlob))); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, o.OnionBlob)
+}
+
+// Decode deserializes the serialized OnionMessage stored in the passed
+// io.Reader into the target OnionMessage using the deserialization rules
+// defined by the passed protocol version.
+//
+// This is a part of the lnwire.Message interface.
+func (o *OnionMessage) Decode(r io.Reader, _ uint32) error {
+	if err := ReadElement(r, &o.PathKey); err != nil {
+		return err
+	}
+
+	var blobLen [2]byte
+	if _, err := io.ReadFull(r, blobLen[:]); err != nil {
+		return err
+	}
+
+	length := binary.BigEndian.Uint16(blobLen[:])
+	if int(length) > MaxOnionMessageBlobSize {
+		return fmt.Errorf("onion message blob too large: %d bytes, "+
+			"max is %d", length, MaxOnionMessageBlobSize)
+	}
+
+	o.OnionBlob = make([]byte, length)
+	if _, err := io.ReadFull(r, o.OnionBlob); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as an OnionMessage on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (o *OnionMessage) MsgType() MessageType {
+	return MsgOnionMessage
+}
@@ -0,0 +1,46 @@
+package lnwire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOnionMessageCustomOverride tests that onion messages are decoded as
+// custom messages when their type has been overridden, and as onion messages
+// otherwise.
+//
+// NOTE: this test modifies the global set of custom overrides, so it must not
+// be run in parallel.
+func TestOnionMessageCustomOverride(t *testing.T) {
+	pathKey, err := randPubKey()
+	require.NoError(t, err)
+
+	msg := NewOnionMessage(pathKey, []byte{1, 2, 3})
+
+	var b bytes.Buffer
+	_, err = WriteMessage(&b, msg, 0)
+	require.NoError(t, err)
+	rawMsg := b.Bytes()
+
+	// Without any override set, we expect to decode the native message.
+	decoded, err := ReadMessage(bytes.NewReader(rawMsg), 0)
+	require.NoError(t, err)
+	require.Equal(t, msg, decoded)
+
+	// Once the type is overridden, the message should be delivered as a
+	// custom message carrying the raw payload.
+	require.NoError(t, SetCustomOverrides([]uint16{MsgOnionMessage}))
+	t.Cleanup(func() {
+		require.NoError(t, SetCustomOverrides(nil))
+	})
+
+	decoded, err = ReadMessage(bytes.NewReader(rawMsg), 0)
+	require.NoError(t, err)
+
+	custom, ok := decoded.(*Custom)
+	require.True(t, ok)
+	require.EqualValues(t, MsgOnionMessage, custom.Type)
+	require.Equal(t, rawMsg[2:], custom.Data)
+}
@@ -0,0 +1,270 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// OpenChannel2 is the message the initiator of a dual funded channel sends to
+// propose opening a channel whose funding transaction is constructed
+// interactively by both parties. Unlike OpenChannel, both sides may
+// contribute funds, and the funding transaction is only known once the
+// interactive transaction construction protocol has completed.
+type OpenChannel2 struct {
+	// ChainHash is the target chain that the initiator wishes to open a
+	// channel within.
+	ChainHash chainhash.Hash
+
+	// PendingChannelID serves to uniquely identify the future channel
+	// until the funding transaction has been negotiated. It's used as the
+	// channel ID of the interactive transaction construction messages.
+	PendingChannelID [32]byte
+
+	// FundingFeePerKWeight is the fee rate in sat/kw the funding
+	// transaction should pay.
+	FundingFeePerKWeight uint32
+
+	// CommitFeePerKWeight is the initial fee rate in sat/kw the initiator
+	// will pay for the commitment transactions.
+	CommitFeePerKWeight uint32
+
+	// FundingAmount is the amount of satoshis the initiator contributes
+	// to the funding output.
+	FundingAmount btcutil.Amount
+
+	// DustLimit is the specific dust limit the sender of this message
+	// would like enforced on their version of the commitment transaction.
+	DustLimit btcutil.Amount
+
+	// MaxValueInFlight represents the maximum amount of coins that can be
+	// pending within the channel at any given time.
+	MaxValueInFlight MilliSatoshi
+
+	// HtlcMinimum is the smallest HTLC that the sender of this message
+	// will accept.
+	HtlcMinimum MilliSatoshi
+
+	// CsvDelay is the number of blocks to use for the relative time lock
+	// in the pay-to-self output of both commitment transactions.
+	CsvDelay uint16
+
+	// MaxAcceptedHTLCs is the total number of incoming HTLC's that the
+	// sender of this channel will accept.
+	MaxAcceptedHTLCs uint16
+
+	// LockTime is the lock time of the funding transaction.
+	LockTime uint32
+
+	// FundingKey is the key that should be used on behalf of the sender
+	// within the 2-of-2 multi-sig output that it contained within the
+	// funding transaction.
+	FundingKey *btcec.PublicKey
+
+	// RevocationPoint is the base revocation point for the sending party.
+	RevocationPoint *btcec.PublicKey
+
+	// PaymentPoint is the base payment point for the sending party.
+	PaymentPoint *btcec.PublicKey
+
+	// DelayedPaymentPoint is the delay point for the sending party.
+	DelayedPaymentPoint *btcec.PublicKey
+
+	// HtlcPoint is the base point used to derive the set of keys for this
+	// party that will be used within the HTLC public key scripts.
+	HtlcPoint *btcec.PublicKey
+
+	// FirstCommitmentPoint is the first commitment point for the sending
+	// party.
+	FirstCommitmentPoint *btcec.PublicKey
+
+	// SecondCommitmentPoint is the second commitment point for the sending
+	// party. Since no channel_ready needs to carry it, it's sent upfront.
+	SecondCommitmentPoint *btcec.PublicKey
+
+	// ChannelFlags is a bit-field which allows the initiator of the
+	// channel to specify further behavior surrounding the channel.
+	ChannelFlags FundingFlag
+
+	// UpfrontShutdownScript is the script to which the channel funds should
+	// be paid when mutually closing the channel. This field is optional.
+	UpfrontShutdownScript DeliveryAddress
+
+	// ChannelType is the explicit channel type the initiator wishes to
+	// open.
+	ChannelType *ChannelType
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure OpenChannel2 implements the lnwire.Message
+// interface.
+var _ Message = (*OpenChannel2)(nil)
+
+// Encode serializes the target OpenChannel2 into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (o *OpenChannel2) Encode(w *bytes.Buffer, _ uint32) error {
+	err := encodeDualFundTLVs(
+		&o.ExtraData, &o.UpfrontShutdownScript, o.ChannelType,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteBytes(w, o.ChainHash[:]); err != nil {
+		return err
+	}
+
+	if err := WriteBytes(w, o.PendingChannelID[:]); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, o.FundingFeePerKWeight); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, o.CommitFeePerKWeight); err != nil {
+		return err
+	}
+
+	if err := WriteSatoshi(w, o.FundingAmount); err != nil {
+		return err
+	}
+
+	if err := WriteSatoshi(w, o.DustLimit); err != nil {
+		return err
+	}
+
+	if err := WriteMilliSatoshi(w, o.MaxValueInFlight); err != nil {
+		return err
+	}
+
+	if err := WriteMilliSatoshi(w, o.HtlcMinimum); err != nil {
+		return err
+	}
+
+	if err := WriteUint16(w, o.CsvDelay); err != nil {
+		return err
+	}
+
+	if err := WriteUint16(w, o.MaxAcceptedHTLCs); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, o.LockTime); err != nil {
+		return err
+	}
+
+	keys := []*btcec.PublicKey{
+		o.FundingKey, o.RevocationPoint, o.PaymentPoint,
+		o.DelayedPaymentPoint, o.HtlcPoint, o.FirstCommitmentPoint,
+		o.SecondCommitmentPoint,
+	}
+	for _, key := range keys {
+		if err := WritePublicKey(w, key); err != nil {
+			return err
+		}
+	}
+
+	if err := WriteFundingFlag(w, o.ChannelFlags); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, o.ExtraData)
+}
+
+// Decode deserializes a serialized OpenChannel2 message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (o *OpenChannel2) Decode(r io.Reader, _ uint32) error {
+	var tlvRecords ExtraOpaqueData
+	err := ReadElements(r,
+		o.ChainHash[:],
+		o.PendingChannelID[:],
+		&o.FundingFeePerKWeight,
+		&o.CommitFeePerKWeight,
+		&o.FundingAmount,
+		&o.DustLimit,
+		&o.MaxValueInFlight,
+		&o.HtlcMinimum,
+		&o.CsvDelay,
+		&o.MaxAcceptedHTLCs,
+		&o.LockTime,
+		&o.FundingKey,
+		&o.RevocationPoint,
+		&o.PaymentPoint,
+		&o.DelayedPaymentPoint,
+		&o.HtlcPoint,
+		&o.FirstCommitmentPoint,
+		&o.SecondCommitmentPoint,
+		&o.ChannelFlags,
+		&tlvRecords,
+	)
+	if err != nil {
+		return err
+	}
+
+	o.ChannelType, err = decodeDualFundTLVs(
+		tlvRecords, &o.UpfrontShutdownScript,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		o.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as an OpenChannel2 on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (o *OpenChannel2) MsgType() MessageType {
+	return MsgOpenChannel2
+}
+
+// encodeDualFundTLVs packs the optional TLV records shared by OpenChannel2 and
+// AcceptChannel2 into the passed extra data.
+func encodeDualFundTLVs(extraData *ExtraOpaqueData,
+	shutdownScript *DeliveryAddress, chanType *ChannelType) error {
+
+	var recordProducers []tlv.RecordProducer
+	if len(*shutdownScript) != 0 {
+		recordProducers = append(recordProducers, shutdownScript)
+	}
+	if chanType != nil {
+		recordProducers = append(recordProducers, chanType)
+	}
+
+	return EncodeMessageExtraData(extraData, recordProducers...)
+}
+
+// decodeDualFundTLVs parses the optional TLV records shared by OpenChannel2
+// and AcceptChannel2 out of the passed extra data.
+func decodeDualFundTLVs(tlvRecords ExtraOpaqueData,
+	shutdownScript *DeliveryAddress) (*ChannelType, error) {
+
+	var chanType ChannelType
+	typeMap, err := tlvRecords.ExtractRecords(shutdownScript, &chanType)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, ok := typeMap[ChannelTypeRecordType]; ok && val == nil {
+		return &chanType, nil
+	}
+
+	return nil, nil
+}
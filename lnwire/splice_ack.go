@@ -0,0 +1,88 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// SpliceAck is sent in response to a SpliceInit message to accept the
+// proposed splice. Once sent, the initiator starts the interactive
+// construction of the splice transaction.
+type SpliceAck struct {
+	// ChannelID identifies the channel that is spliced.
+	ChannelID ChannelID
+
+	// FundingContribution is the amount the acceptor adds to its balance
+	// in the channel.
+	FundingContribution btcutil.Amount
+
+	// FundingKey is the key of the acceptor in the 2-of-2 multisig of the
+	// new funding output.
+	FundingKey *btcec.PublicKey
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure SpliceAck implements the lnwire.Message
+// interface.
+var _ Message = (*SpliceAck)(nil)
+
+// Encode serializes the target SpliceAck into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceAck) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, s.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteSatoshi(w, s.FundingContribution); err != nil {
+		return err
+	}
+
+	if err := WritePublicKey(w, s.FundingKey); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, s.ExtraData)
+}
+
+// Decode deserializes a serialized SpliceAck message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceAck) Decode(r io.Reader, _ uint32) error {
+	var tlvRecords ExtraOpaqueData
+	err := ReadElements(r,
+		&s.ChannelID, &s.FundingContribution, &s.FundingKey,
+		&tlvRecords,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		s.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a SpliceAck on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceAck) MsgType() MessageType {
+	return MsgSpliceAck
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (s *SpliceAck) TargetChanID() ChannelID {
+	return s.ChannelID
+}
@@ -0,0 +1,186 @@
+package lnwire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// spliceCommitSigBaseLen is the length of a serialized SpliceCommitSig
+	// without any htlc signatures: the funding txid, the commitment
+	// signature and the number of htlc signatures.
+	spliceCommitSigBaseLen = 32 + 64 + 2
+
+	// commitSigBaseLen is the length of a serialized CommitSig without any
+	// htlc signatures or tlv records.
+	commitSigBaseLen = 32 + 64 + 2
+
+	// spliceCommitSigsRecordOverhead is the maximum length of the type and
+	// length of the SpliceCommitSigs record, plus its number of entries.
+	spliceCommitSigsRecordOverhead = 1 + 5 + 2
+)
+
+type (
+	// SpliceCommitSigsType is the type of the tlv record that carries the
+	// signatures for the commitments spending the funding outputs of
+	// pending splice transactions.
+	SpliceCommitSigsType = tlv.TlvType1
+
+	// SpliceCommitSigsTLV is a tlv record for the signatures of the
+	// commitments of pending splice transactions.
+	SpliceCommitSigsTLV = tlv.RecordT[
+		SpliceCommitSigsType, SpliceCommitSigs,
+	]
+
+	// OptSpliceCommitSigsTLV is an optional tlv record for the signatures
+	// of the commitments of pending splice transactions.
+	OptSpliceCommitSigsTLV = tlv.OptionalRecordT[
+		SpliceCommitSigsType, SpliceCommitSigs,
+	]
+)
+
+// SpliceCommitSig holds the signatures for a commitment transaction that
+// spends the funding output of a pending splice transaction rather than the
+// current funding output of the channel.
+type SpliceCommitSig struct {
+	// FundingTxID is the txid of the splice transaction whose funding
+	// output the commitment spends.
+	FundingTxID chainhash.Hash
+
+	// CommitSig is the signature for the commitment transaction.
+	CommitSig Sig
+
+	// HtlcSigs are the signatures for the second level htlc transactions
+	// of the commitment, in the same order as in CommitSig.
+	HtlcSigs []Sig
+}
+
+// SpliceCommitSigs is the set of commitment signatures for all pending splice
+// transactions of a channel.
+type SpliceCommitSigs []SpliceCommitSig
+
+// MaxSpliceCommitHtlcs returns the maximum number of htlcs a commitment may
+// carry such that the signatures for it and for its variants spending the
+// funding outputs of the given number of pending splices fit into a single
+// CommitSig message.
+func MaxSpliceCommitHtlcs(numSplices int) int {
+	avail := MaxMsgBody - commitSigBaseLen -
+		spliceCommitSigsRecordOverhead -
+		numSplices*spliceCommitSigBaseLen
+	if avail < 0 {
+		return 0
+	}
+
+	return avail / (64 * (numSplices + 1))
+}
+
+// MaybeSpliceCommitSigs is a helper function that returns an optional
+// SpliceCommitSigs record, which is only set if there are any signatures.
+func MaybeSpliceCommitSigs(sigs []SpliceCommitSig) OptSpliceCommitSigsTLV {
+	if len(sigs) == 0 {
+		var none OptSpliceCommitSigsTLV
+		return none
+	}
+
+	return tlv.SomeRecordT(
+		tlv.NewRecordT[SpliceCommitSigsType](SpliceCommitSigs(sigs)),
+	)
+}
+
+// size returns the length of the encoded signatures.
+func (s *SpliceCommitSigs) size() uint64 {
+	size := uint64(2)
+	for _, sig := range *s {
+		size += spliceCommitSigBaseLen + uint64(64*len(sig.HtlcSigs))
+	}
+
+	return size
+}
+
+// Record returns the tlv record for the splice commitment signatures.
+func (s *SpliceCommitSigs) Record() tlv.Record {
+	return tlv.MakeDynamicRecord(
+		(SpliceCommitSigsType)(nil).TypeVal(), s, s.size,
+		spliceCommitSigsEncoder, spliceCommitSigsDecoder,
+	)
+}
+
+// spliceCommitSigsEncoder encodes the splice commitment signatures as the
+// number of entries followed by the funding txid, commitment signature and
+// htlc signatures of each entry.
+func spliceCommitSigsEncoder(w io.Writer, val interface{}, _ *[8]byte) error {
+	if v, ok := val.(*SpliceCommitSigs); ok {
+		var b bytes.Buffer
+		if err := WriteUint16(&b, uint16(len(*v))); err != nil {
+			return err
+		}
+
+		for _, sig := range *v {
+			err := WriteBytes(&b, sig.FundingTxID[:])
+			if err != nil {
+				return err
+			}
+
+			if err := WriteSig(&b, sig.CommitSig); err != nil {
+				return err
+			}
+
+			if err := WriteSigs(&b, sig.HtlcSigs); err != nil {
+				return err
+			}
+		}
+
+		_, err := w.Write(b.Bytes())
+
+		return err
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "lnwire.SpliceCommitSigs")
+}
+
+// spliceCommitSigsDecoder decodes the splice commitment signatures.
+func spliceCommitSigsDecoder(r io.Reader, val interface{}, _ *[8]byte,
+	l uint64) error {
+
+	v, ok := val.(*SpliceCommitSigs)
+	if !ok {
+		return tlv.NewTypeForDecodingErr(
+			val, "lnwire.SpliceCommitSigs", l, l,
+		)
+	}
+
+	lr := io.LimitReader(r, int64(l))
+
+	var numSigs uint16
+	if err := ReadElement(lr, &numSigs); err != nil {
+		return err
+	}
+	if 2+uint64(numSigs)*spliceCommitSigBaseLen > l {
+		return fmt.Errorf("too many splice commit sigs: %d", numSigs)
+	}
+
+	sigs := make(SpliceCommitSigs, numSigs)
+	for i := range sigs {
+		err := ReadElements(lr,
+			sigs[i].FundingTxID[:],
+			&sigs[i].CommitSig,
+			&sigs[i].HtlcSigs,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sigs.size() != l {
+		return fmt.Errorf("invalid splice commit sigs length: "+
+			"expected %d, got %d", sigs.size(), l)
+	}
+
+	*v = sigs
+
+	return nil
+}
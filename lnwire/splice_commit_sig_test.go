@@ -0,0 +1,67 @@
+package lnwire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMaxSpliceCommitHtlcs tests that a CommitSig carrying the maximum number
+// of htlc signatures for the given number of pending splices still fits into
+// a single message, while one more htlc doesn't.
+func TestMaxSpliceCommitHtlcs(t *testing.T) {
+	t.Parallel()
+
+	sig, err := NewSigFromSignature(testSig)
+	require.NoError(t, err)
+
+	newCommitSig := func(numSplices, numHtlcs int) *CommitSig {
+		htlcSigs := make([]Sig, numHtlcs)
+		for i := range htlcSigs {
+			htlcSigs[i] = sig
+		}
+
+		spliceSigs := make([]SpliceCommitSig, numSplices)
+		for i := range spliceSigs {
+			spliceSigs[i] = SpliceCommitSig{
+				FundingTxID: [32]byte{byte(i)},
+				CommitSig:   sig,
+				HtlcSigs:    htlcSigs,
+			}
+		}
+
+		msg := NewCommitSig()
+		msg.CommitSig = sig
+		msg.HtlcSigs = htlcSigs
+		msg.SpliceSigs = MaybeSpliceCommitSigs(spliceSigs)
+
+		return msg
+	}
+
+	for numSplices := 1; numSplices <= 3; numSplices++ {
+		maxHtlcs := MaxSpliceCommitHtlcs(numSplices)
+
+		var b bytes.Buffer
+		_, err := WriteMessage(
+			&b, newCommitSig(numSplices, maxHtlcs), 0,
+		)
+		require.NoError(t, err)
+
+		// The message must decode to the same signatures.
+		msg, err := ReadMessage(&b, 0)
+		require.NoError(t, err)
+
+		commitSig, ok := msg.(*CommitSig)
+		require.True(t, ok)
+		spliceSigs := commitSig.SpliceSigs.UnwrapOrFailV(t)
+		require.Len(t, spliceSigs, numSplices)
+		require.Len(t, spliceSigs[0].HtlcSigs, maxHtlcs)
+
+		b.Reset()
+		_, err = WriteMessage(
+			&b, newCommitSig(numSplices, maxHtlcs+1), 0,
+		)
+		require.Error(t, err)
+	}
+}
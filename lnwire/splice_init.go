@@ -0,0 +1,101 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// SpliceInit is sent by the initiator of a splice once the channel is
+// quiescent, to propose splicing funds into or out of the channel.
+type SpliceInit struct {
+	// ChannelID identifies the channel that is spliced.
+	ChannelID ChannelID
+
+	// FundingContribution is the amount the initiator adds to its balance
+	// in the channel. It's negative when funds are spliced out.
+	FundingContribution btcutil.Amount
+
+	// FeeRate is the fee rate in sat/kw the splice transaction should pay.
+	FeeRate uint32
+
+	// LockTime is the lock time of the splice transaction.
+	LockTime uint32
+
+	// FundingKey is the key of the initiator in the 2-of-2 multisig of
+	// the new funding output.
+	FundingKey *btcec.PublicKey
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure SpliceInit implements the lnwire.Message
+// interface.
+var _ Message = (*SpliceInit)(nil)
+
+// Encode serializes the target SpliceInit into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceInit) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, s.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteSatoshi(w, s.FundingContribution); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, s.FeeRate); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, s.LockTime); err != nil {
+		return err
+	}
+
+	if err := WritePublicKey(w, s.FundingKey); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, s.ExtraData)
+}
+
+// Decode deserializes a serialized SpliceInit message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceInit) Decode(r io.Reader, _ uint32) error {
+	var tlvRecords ExtraOpaqueData
+	err := ReadElements(r,
+		&s.ChannelID, &s.FundingContribution, &s.FeeRate, &s.LockTime,
+		&s.FundingKey, &tlvRecords,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		s.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a SpliceInit on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceInit) MsgType() MessageType {
+	return MsgSpliceInit
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (s *SpliceInit) TargetChanID() ChannelID {
+	return s.ChannelID
+}
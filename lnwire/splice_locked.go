@@ -0,0 +1,82 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// SpliceLocked is sent by both parties once the splice transaction has
+// reached the required number of confirmations. The channel switches over to
+// the new funding output once both parties have sent it.
+type SpliceLocked struct {
+	// ChannelID identifies the channel that is spliced.
+	ChannelID ChannelID
+
+	// SpliceTxID is the txid of the splice transaction that confirmed.
+	SpliceTxID chainhash.Hash
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure SpliceLocked implements the lnwire.Message
+// interface.
+var _ Message = (*SpliceLocked)(nil)
+
+// Encode serializes the target SpliceLocked into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceLocked) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, s.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteBytes(w, s.SpliceTxID[:]); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, s.ExtraData)
+}
+
+// Decode deserializes a serialized SpliceLocked message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceLocked) Decode(r io.Reader, _ uint32) error {
+	if err := ReadElements(r, &s.ChannelID); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, s.SpliceTxID[:]); err != nil {
+		return err
+	}
+
+	var tlvRecords ExtraOpaqueData
+	if err := ReadElements(r, &tlvRecords); err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		s.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a SpliceLocked on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (s *SpliceLocked) MsgType() MessageType {
+	return MsgSpliceLocked
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (s *SpliceLocked) TargetChanID() ChannelID {
+	return s.ChannelID
+}
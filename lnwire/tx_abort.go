@@ -0,0 +1,81 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+)
+
+// TxAbort is sent by either party to abort the interactive construction of a
+// transaction, or the signing of an already constructed one.
+type TxAbort struct {
+	// ChannelID identifies the channel the transaction is negotiated for.
+	ChannelID ChannelID
+
+	// Data is an optional human readable reason for the abort.
+	Data []byte
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure TxAbort implements the lnwire.Message
+// interface.
+var _ Message = (*TxAbort)(nil)
+
+// Encode serializes the target TxAbort into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAbort) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, t.ChannelID); err != nil {
+		return err
+	}
+
+	if err := writeU16Bytes(w, t.Data); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, t.ExtraData)
+}
+
+// Decode deserializes a serialized TxAbort message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAbort) Decode(r io.Reader, _ uint32) error {
+	if err := ReadElements(r, &t.ChannelID); err != nil {
+		return err
+	}
+
+	var err error
+	t.Data, err = readU16Bytes(r)
+	if err != nil {
+		return err
+	}
+
+	var tlvRecords ExtraOpaqueData
+	if err := ReadElements(r, &tlvRecords); err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		t.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a TxAbort on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAbort) MsgType() MessageType {
+	return MsgTxAbort
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (t *TxAbort) TargetChanID() ChannelID {
+	return t.ChannelID
+}
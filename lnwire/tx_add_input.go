@@ -0,0 +1,138 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// TxAddInput is sent during the interactive construction of a transaction to
+// add an input to the transaction that is being negotiated.
+type TxAddInput struct {
+	// ChannelID identifies the channel the transaction is negotiated for.
+	ChannelID ChannelID
+
+	// SerialID uniquely identifies the input within the negotiation. The
+	// initiator of the negotiation uses even serial IDs, the other party
+	// odd ones. Inputs of the final transaction are sorted by serial ID.
+	SerialID uint64
+
+	// PrevTx is the serialized transaction that contains the output that
+	// is spent by this input. It's empty when SharedInputTxid is set.
+	PrevTx []byte
+
+	// PrevTxOut is the index of the output within PrevTx that is spent.
+	PrevTxOut uint32
+
+	// Sequence is the sequence number of the input.
+	Sequence uint32
+
+	// SharedInputTxid is set instead of PrevTx if the input spends the
+	// current funding output of the channel, which both parties already
+	// know about.
+	SharedInputTxid tlv.OptionalRecordT[tlv.TlvType0, [32]byte]
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure TxAddInput implements the lnwire.Message
+// interface.
+var _ Message = (*TxAddInput)(nil)
+
+// Encode serializes the target TxAddInput into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddInput) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, t.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteUint64(w, t.SerialID); err != nil {
+		return err
+	}
+
+	if err := writeU16Bytes(w, t.PrevTx); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, t.PrevTxOut); err != nil {
+		return err
+	}
+
+	if err := WriteUint32(w, t.Sequence); err != nil {
+		return err
+	}
+
+	var recordProducers []tlv.RecordProducer
+	t.SharedInputTxid.WhenSome(
+		func(txid tlv.RecordT[tlv.TlvType0, [32]byte]) {
+			recordProducers = append(recordProducers, &txid)
+		},
+	)
+
+	err := EncodeMessageExtraData(&t.ExtraData, recordProducers...)
+	if err != nil {
+		return err
+	}
+
+	return WriteBytes(w, t.ExtraData)
+}
+
+// Decode deserializes a serialized TxAddInput message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddInput) Decode(r io.Reader, _ uint32) error {
+	if err := ReadElements(r, &t.ChannelID, &t.SerialID); err != nil {
+		return err
+	}
+
+	var err error
+	t.PrevTx, err = readU16Bytes(r)
+	if err != nil {
+		return err
+	}
+
+	if err := ReadElements(r, &t.PrevTxOut, &t.Sequence); err != nil {
+		return err
+	}
+
+	var tlvRecords ExtraOpaqueData
+	if err := ReadElements(r, &tlvRecords); err != nil {
+		return err
+	}
+
+	sharedTxid := t.SharedInputTxid.Zero()
+	typeMap, err := tlvRecords.ExtractRecords(&sharedTxid)
+	if err != nil {
+		return err
+	}
+
+	if val, ok := typeMap[t.SharedInputTxid.TlvType()]; ok && val == nil {
+		t.SharedInputTxid = tlv.SomeRecordT(sharedTxid)
+	}
+
+	if len(tlvRecords) != 0 {
+		t.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a TxAddInput on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddInput) MsgType() MessageType {
+	return MsgTxAddInput
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (t *TxAddInput) TargetChanID() ChannelID {
+	return t.ChannelID
+}
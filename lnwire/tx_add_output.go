@@ -0,0 +1,99 @@
+package lnwire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// TxAddOutput is sent during the interactive construction of a transaction to
+// add an output to the transaction that is being negotiated.
+type TxAddOutput struct {
+	// ChannelID identifies the channel the transaction is negotiated for.
+	ChannelID ChannelID
+
+	// SerialID uniquely identifies the output within the negotiation. The
+	// initiator of the negotiation uses even serial IDs, the other party
+	// odd ones. Outputs of the final transaction are sorted by serial ID.
+	SerialID uint64
+
+	// Amount is the value of the output.
+	Amount btcutil.Amount
+
+	// Script is the script the output pays to.
+	Script []byte
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure TxAddOutput implements the lnwire.Message
+// interface.
+var _ Message = (*TxAddOutput)(nil)
+
+// Encode serializes the target TxAddOutput into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddOutput) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, t.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteUint64(w, t.SerialID); err != nil {
+		return err
+	}
+
+	if err := WriteSatoshi(w, t.Amount); err != nil {
+		return err
+	}
+
+	if err := writeU16Bytes(w, t.Script); err != nil {
+		return err
+	}
+
+	return WriteBytes(w, t.ExtraData)
+}
+
+// Decode deserializes a serialized TxAddOutput message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddOutput) Decode(r io.Reader, _ uint32) error {
+	err := ReadElements(r, &t.ChannelID, &t.SerialID, &t.Amount)
+	if err != nil {
+		return err
+	}
+
+	t.Script, err = readU16Bytes(r)
+	if err != nil {
+		return err
+	}
+
+	var tlvRecords ExtraOpaqueData
+	if err := ReadElements(r, &tlvRecords); err != nil {
+		return err
+	}
+
+	if len(tlvRecords) != 0 {
+		t.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a TxAddOutput on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxAddOutput) MsgType() MessageType {
+	return MsgTxAddOutput
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (t *TxAddOutput) TargetChanID() ChannelID {
+	return t.ChannelID
+}
@@ -0,0 +1,155 @@
+package lnwire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// TxSignatures is sent once the interactive construction of a transaction is
+// complete and both parties have exchanged commitment signatures. It carries
+// the witnesses of all inputs the sender contributed, ordered by serial ID.
+type TxSignatures struct {
+	// ChannelID identifies the channel the transaction is negotiated for.
+	ChannelID ChannelID
+
+	// TxID is the txid of the transaction the witnesses are for.
+	TxID chainhash.Hash
+
+	// Witnesses are the witnesses of the sender's inputs, in the order of
+	// their serial IDs.
+	Witnesses []wire.TxWitness
+
+	// SharedInputSig is the sender's signature for the shared funding
+	// input that is spent by a splice transaction.
+	SharedInputSig tlv.OptionalRecordT[tlv.TlvType0, Sig]
+
+	// ExtraData is the set of data that was appended to this message to
+	// fill out the full maximum transport message size. These fields can
+	// be used to specify optional data such as custom TLV fields.
+	ExtraData ExtraOpaqueData
+}
+
+// A compile time check to ensure TxSignatures implements the lnwire.Message
+// interface.
+var _ Message = (*TxSignatures)(nil)
+
+// Encode serializes the target TxSignatures into the passed io.Writer.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxSignatures) Encode(w *bytes.Buffer, _ uint32) error {
+	if err := WriteChannelID(w, t.ChannelID); err != nil {
+		return err
+	}
+
+	if err := WriteBytes(w, t.TxID[:]); err != nil {
+		return err
+	}
+
+	if len(t.Witnesses) > MaxInteractiveTxWitnessItems {
+		return fmt.Errorf("too many witnesses: %d", len(t.Witnesses))
+	}
+
+	if err := WriteUint16(w, uint16(len(t.Witnesses))); err != nil {
+		return err
+	}
+
+	for _, witness := range t.Witnesses {
+		witnessBytes, err := serializeWitness(witness)
+		if err != nil {
+			return err
+		}
+
+		if err := writeU16Bytes(w, witnessBytes); err != nil {
+			return err
+		}
+	}
+
+	var recordProducers []tlv.RecordProducer
+	t.SharedInputSig.WhenSome(func(sig tlv.RecordT[tlv.TlvType0, Sig]) {
+		recordProducers = append(recordProducers, &sig)
+	})
+
+	err := EncodeMessageExtraData(&t.ExtraData, recordProducers...)
+	if err != nil {
+		return err
+	}
+
+	return WriteBytes(w, t.ExtraData)
+}
+
+// Decode deserializes a serialized TxSignatures message stored in the passed
+// io.Reader.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxSignatures) Decode(r io.Reader, _ uint32) error {
+	if err := ReadElements(r, &t.ChannelID); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, t.TxID[:]); err != nil {
+		return err
+	}
+
+	var numWitnesses uint16
+	if err := ReadElements(r, &numWitnesses); err != nil {
+		return err
+	}
+	if numWitnesses > MaxInteractiveTxWitnessItems {
+		return fmt.Errorf("too many witnesses: %d", numWitnesses)
+	}
+
+	if numWitnesses > 0 {
+		t.Witnesses = make([]wire.TxWitness, numWitnesses)
+	}
+	for i := range t.Witnesses {
+		witnessBytes, err := readU16Bytes(r)
+		if err != nil {
+			return err
+		}
+
+		t.Witnesses[i], err = deserializeWitness(witnessBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	var tlvRecords ExtraOpaqueData
+	if err := ReadElements(r, &tlvRecords); err != nil {
+		return err
+	}
+
+	sharedSig := t.SharedInputSig.Zero()
+	typeMap, err := tlvRecords.ExtractRecords(&sharedSig)
+	if err != nil {
+		return err
+	}
+
+	if val, ok := typeMap[t.SharedInputSig.TlvType()]; ok && val == nil {
+		t.SharedInputSig = tlv.SomeRecordT(sharedSig)
+	}
+
+	if len(tlvRecords) != 0 {
+		t.ExtraData = tlvRecords
+	}
+
+	return nil
+}
+
+// MsgType returns the MessageType code which uniquely identifies this message
+// as a TxSignatures on the wire.
+//
+// This is part of the lnwire.Message interface.
+func (t *TxSignatures) MsgType() MessageType {
+	return MsgTxSignatures
+}
+
+// TargetChanID returns the channel id of the link for which this message is
+// intended.
+func (t *TxSignatures) TargetChanID() ChannelID {
+	return t.ChannelID
+}
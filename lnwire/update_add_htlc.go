@@ -72,6 +72,11 @@ type UpdateAddHTLC struct {
 	// next hop for this htlc.
 	BlindingPoint BlindingPointRecord
 
+	// CustomRecords maps TLV types to byte slices, storing arbitrary data
+	// intended for inclusion in the ExtraData field of the UpdateAddHTLC
+	// message.
+	CustomRecords CustomRecords
+
 	// ExtraData is the set of data that was appended to this message to
 	// fill out the full maximum transport message size. These fields can
 	// be used to specify optional data such as custom TLV fields.
@@ -114,6 +119,11 @@ func (c *UpdateAddHTLC) Decode(r io.Reader, pver uint32) error {
 		c.BlindingPoint = tlv.SomeRecordT(blindingRecord)
 	}
 
+	c.CustomRecords, err = ExtractCustomRecords(tlvMap)
+	if err != nil {
+		return err
+	}
+
 	// Set extra data to nil if we didn't parse anything out of it so that
 	// we can use assert.Equal in tests.
 	if len(tlvMap) == 0 {
@@ -152,7 +162,8 @@ func (c *UpdateAddHTLC) Encode(w *bytes.Buffer, pver uint32) error {
 		return err
 	}
 
-	// Only include blinding point in extra data if present.
+	// Only include blinding point and custom records in extra data if
+	// present.
 	var records []tlv.RecordProducer
 
 	c.BlindingPoint.WhenSome(func(b tlv.RecordT[BlindingPointTlvType,
@@ -161,7 +172,12 @@ func (c *UpdateAddHTLC) Encode(w *bytes.Buffer, pver uint32) error {
 		records = append(records, &b)
 	})
 
-	err := EncodeMessageExtraData(&c.ExtraData, records...)
+	records, err := c.CustomRecords.ExtendRecordProducers(records)
+	if err != nil {
+		return err
+	}
+
+	err = EncodeMessageExtraData(&c.ExtraData, records...)
 	if err != nil {
 		return err
 	}
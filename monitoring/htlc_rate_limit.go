@@ -0,0 +1,40 @@
+package monitoring
+
+import (
+	"sync"
+
+	"github.com/lightningnetwork/lnd/htlcswitch"
+)
+
+var (
+	// htlcRateLimitMtx guards htlcRateLimitSource.
+	htlcRateLimitMtx sync.Mutex
+
+	// htlcRateLimitSource returns the current stats of the htlc rate
+	// limiter. It is nil if htlc rate limiting isn't active.
+	htlcRateLimitSource func() []htlcswitch.PeerRateLimitStats
+)
+
+// SetHtlcRateLimitSource sets the function that provides the stats of the
+// htlc rate limiter, which are exported as Prometheus metrics if monitoring is
+// enabled.
+func SetHtlcRateLimitSource(source func() []htlcswitch.PeerRateLimitStats) {
+	htlcRateLimitMtx.Lock()
+	defer htlcRateLimitMtx.Unlock()
+
+	htlcRateLimitSource = source
+}
+
+// htlcRateLimitStats returns the current stats of the htlc rate limiter, or
+// nil if no source is set.
+func htlcRateLimitStats() []htlcswitch.PeerRateLimitStats {
+	htlcRateLimitMtx.Lock()
+	source := htlcRateLimitSource
+	htlcRateLimitMtx.Unlock()
+
+	if source == nil {
+		return nil
+	}
+
+	return source()
+}
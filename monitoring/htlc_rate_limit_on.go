@@ -0,0 +1,71 @@
+//go:build monitoring
+// +build monitoring
+
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// htlcAddsDesc describes the number of htlc adds of a peer by the
+	// decision of the htlc rate limiter.
+	htlcAddsDesc = prometheus.NewDesc(
+		"lnd_htlc_rate_limit_adds_total",
+		"The number of htlc adds of a peer by the decision of the "+
+			"htlc rate limiter.",
+		[]string{"peer", "result"}, nil,
+	)
+
+	// htlcInFlightDesc describes the total value of the htlcs that a peer
+	// has in flight with us.
+	htlcInFlightDesc = prometheus.NewDesc(
+		"lnd_htlc_rate_limit_in_flight_msat",
+		"The total value of the htlcs that a peer has in flight with "+
+			"us in millisatoshis.",
+		[]string{"peer"}, nil,
+	)
+)
+
+// htlcRateLimitCollector exports the stats of the htlc rate limiter as
+// Prometheus metrics.
+type htlcRateLimitCollector struct{}
+
+// A compile time check to ensure htlcRateLimitCollector implements the
+// prometheus.Collector interface.
+var _ prometheus.Collector = (*htlcRateLimitCollector)(nil)
+
+// Describe sends the descriptors of the metrics of the collector to the
+// provided channel.
+func (c *htlcRateLimitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- htlcAddsDesc
+	ch <- htlcInFlightDesc
+}
+
+// Collect sends the current stats of the htlc rate limiter to the provided
+// channel.
+func (c *htlcRateLimitCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, peer := range htlcRateLimitStats() {
+		peerLabel := peer.Peer.String()
+
+		results := []struct {
+			name  string
+			count uint64
+		}{
+			{"allowed", peer.Counters.Allowed},
+			{"add_rate_limited", peer.Counters.AddRateLimited},
+			{"in_flight_limited", peer.Counters.InFlightLimited},
+		}
+		for _, result := range results {
+			ch <- prometheus.MustNewConstMetric(
+				htlcAddsDesc, prometheus.CounterValue,
+				float64(result.count), peerLabel, result.name,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			htlcInFlightDesc, prometheus.GaugeValue,
+			float64(peer.InFlight), peerLabel,
+		)
+	}
+}
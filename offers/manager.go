@@ -0,0 +1,620 @@
+package offers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/bolt12"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/onionmessage"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+	"github.com/lightningnetwork/lnd/tlv"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// InvoiceRequestType is the onion message TLV type that holds an
+	// invoice request.
+	InvoiceRequestType tlv.Type = 64
+
+	// InvoiceType is the onion message TLV type that holds an invoice.
+	InvoiceType tlv.Type = 66
+
+	// InvoiceErrorType is the onion message TLV type that holds an
+	// invoice error.
+	InvoiceErrorType tlv.Type = 68
+
+	// DefaultFetchTimeout is the default time that we'll wait for the
+	// issuer of an offer to reply to our invoice request.
+	DefaultFetchTimeout = 30 * time.Second
+
+	// payerMetadataSize is the size of the random metadata that we add to
+	// our invoice requests.
+	payerMetadataSize = 16
+
+	// limiterPruneInterval is how often we'll remove the rate limiters of
+	// peers that have fully replenished their invoice request budget.
+	limiterPruneInterval = time.Minute
+)
+
+var (
+	// ErrUnknownOffer is returned when an invoice request is received for
+	// an offer that wasn't created by us.
+	ErrUnknownOffer = errors.New("unknown offer")
+
+	// ErrOfferExpired is returned when an offer has passed its absolute
+	// expiry.
+	ErrOfferExpired = errors.New("offer expired")
+
+	// ErrUnsupportedChain is returned when an offer can't be paid on the
+	// chain that we're running on.
+	ErrUnsupportedChain = errors.New("offer does not support our chain")
+
+	// ErrInvoiceExpired is returned when the invoice that we receive has
+	// already expired.
+	ErrInvoiceExpired = errors.New("invoice expired")
+
+	// ErrManagerShuttingDown is returned when the manager is stopped while
+	// waiting for an invoice.
+	ErrManagerShuttingDown = errors.New("offers manager shutting down")
+)
+
+// Messenger is the subset of the onion messenger that is used to exchange
+// invoice requests and invoices.
+type Messenger interface {
+	// SendOnionMessage sends an onion message.
+	SendOnionMessage(msg *onionmessage.OutgoingMessage) error
+
+	// SubscribeMessages returns a subscription client that is notified of
+	// the onion messages that we receive.
+	SubscribeMessages() (*subscribe.Client, error)
+
+	// BuildReplyPath creates a blinded path back to our node over the
+	// given hops.
+	BuildReplyPath(hops []*btcec.PublicKey,
+		pathID []byte) (*record.BlindedPath, error)
+}
+
+// Config holds the set of dependencies that the Manager requires.
+type Config struct {
+	// ChainParams are the parameters of the chain that we're running on.
+	ChainParams *chaincfg.Params
+
+	// NodeKeyECDH is our node's identity key. It is used as the issuer ID
+	// of our offers and to authenticate their metadata.
+	NodeKeyECDH keychain.SingleKeyECDH
+
+	// Messenger is used to send and receive onion messages.
+	Messenger Messenger
+
+	// CreateInvoice creates, stores and signs an invoice for a validated
+	// invoice request for one of our offers.
+	CreateInvoice func(req *bolt12.InvoiceRequest) (*bolt12.Invoice,
+		error)
+
+	// Clock is used to check offer and invoice expiry.
+	Clock clock.Clock
+
+	// MaxConcurrentRequests is the maximum number of invoice requests
+	// that are processed at the same time. Invoice requests that arrive
+	// while this many are being processed are dropped.
+	MaxConcurrentRequests int
+
+	// PeerRequestBurst is the number of invoice requests that we'll
+	// accept from a single peer in a burst before we start to drop them.
+	// The peer is the one that delivered the request to us, which isn't
+	// necessarily its sender.
+	PeerRequestBurst int
+
+	// PeerRequestInterval is the interval at which a peer's budget of
+	// invoice requests is replenished by one.
+	PeerRequestInterval time.Duration
+}
+
+// FetchRequest holds the parameters of an invoice request for a remote offer.
+type FetchRequest struct {
+	// Offer is the offer to request an invoice for.
+	Offer *bolt12.Offer
+
+	// Amount is the amount to pay. It is required if the offer doesn't
+	// specify an amount, and may be used to pay more than the offer asks
+	// for otherwise.
+	Amount lnwire.MilliSatoshi
+
+	// Quantity is the number of items to pay for. It must be set if and
+	// only if the offer specifies a maximum quantity.
+	Quantity fn.Option[uint64]
+
+	// PayerNote is an optional note for the issuer.
+	PayerNote string
+
+	// Hops is an optional list of nodes that the invoice request is sent
+	// through before reaching the offer's destination. The reply path
+	// uses the same nodes in the reverse order.
+	Hops []*btcec.PublicKey
+}
+
+// reply is an invoice or invoice error that we received over one of our reply
+// paths.
+type reply struct {
+	invoice      []byte
+	invoiceError []byte
+}
+
+// Manager creates offers, replies to the invoice requests that are received
+// for them and fetches invoices for remote offers. Offers are stateless: their
+// metadata authenticates them so that invoice requests can be validated
+// without storing the offers that we've handed out. As every invoice request
+// that we serve creates an invoice, the requests that each peer delivers to us
+// are rate limited, and only a limited number of them is processed at once.
+type Manager struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *Config
+
+	// metadataKey is the key used to authenticate the metadata of our
+	// offers.
+	metadataKey [32]byte
+
+	// pending maps the path ID of the reply path of each invoice request
+	// that is awaiting a reply to the channel that the reply is delivered
+	// on.
+	pending   map[[32]byte]chan *reply
+	pendingMu sync.Mutex
+
+	// requestSlots limits the number of invoice requests that are
+	// processed concurrently, holding an entry for each of them.
+	requestSlots chan struct{}
+
+	// limiters holds the invoice request rate limiter for each peer that
+	// has delivered an invoice request to us recently. It is only
+	// accessed by the message handler goroutine.
+	limiters map[route.Vertex]*rate.Limiter
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a new offers Manager.
+func NewManager(cfg *Config) *Manager {
+	return &Manager{
+		cfg:     cfg,
+		pending: make(map[[32]byte]chan *reply),
+		requestSlots: make(
+			chan struct{}, cfg.MaxConcurrentRequests,
+		),
+		limiters: make(map[route.Vertex]*rate.Limiter),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start starts the Manager.
+func (m *Manager) Start() error {
+	var err error
+	m.started.Do(func() {
+		log.Info("Offers manager starting")
+
+		// The metadata key is derived from a shared secret with
+		// ourselves, which only we can compute.
+		m.metadataKey, err = m.cfg.NodeKeyECDH.ECDH(
+			m.cfg.NodeKeyECDH.PubKey(),
+		)
+		if err != nil {
+			return
+		}
+
+		var client *subscribe.Client
+		client, err = m.cfg.Messenger.SubscribeMessages()
+		if err != nil {
+			return
+		}
+
+		m.wg.Add(1)
+		go m.messageHandler(client)
+	})
+
+	return err
+}
+
+// Stop stops the Manager.
+func (m *Manager) Stop() error {
+	m.stopped.Do(func() {
+		log.Info("Offers manager shutting down...")
+		defer log.Debug("Offers manager shutdown complete")
+
+		close(m.quit)
+		m.wg.Wait()
+	})
+
+	return nil
+}
+
+// CreateOffer completes the given offer so that invoice requests can be sent
+// to us for it. The issuer ID, chains and metadata of the offer are set by the
+// manager.
+func (m *Manager) CreateOffer(offer *bolt12.Offer) (string, error) {
+	offer.IssuerID = m.cfg.NodeKeyECDH.PubKey()
+	offer.Paths = nil
+
+	// Offers without chains are implicitly for bitcoin mainnet.
+	offer.Chains = nil
+	if !offer.SupportsChain(m.cfg.ChainParams) {
+		offer.Chains = append(
+			offer.Chains, *m.cfg.ChainParams.GenesisHash,
+		)
+	}
+
+	if err := offer.SetAuthMetadata(m.metadataKey); err != nil {
+		return "", err
+	}
+
+	return offer.Encode()
+}
+
+// FetchInvoice requests an invoice for a remote offer and waits for the
+// issuer's reply until the context is canceled. The returned invoice has been
+// validated against the invoice request.
+func (m *Manager) FetchInvoice(ctx context.Context,
+	req *FetchRequest) (*bolt12.Invoice, error) {
+
+	offer := req.Offer
+	if err := offer.Validate(); err != nil {
+		return nil, err
+	}
+	if !offer.SupportsChain(m.cfg.ChainParams) {
+		return nil, ErrUnsupportedChain
+	}
+	if offer.Expired(m.cfg.Clock.Now()) {
+		return nil, ErrOfferExpired
+	}
+
+	// Each request is signed with a fresh payer key, so that our requests
+	// can't be linked to each other or to our node.
+	payerKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make([]byte, payerMetadataSize)
+	if _, err := rand.Read(metadata); err != nil {
+		return nil, err
+	}
+
+	invReq := &bolt12.InvoiceRequest{
+		Offer:     offer,
+		Metadata:  metadata,
+		Amount:    req.Amount,
+		Features:  lnwire.NewRawFeatureVector(),
+		Quantity:  req.Quantity,
+		PayerID:   payerKey.PubKey(),
+		PayerNote: req.PayerNote,
+	}
+
+	// The chain is implicit for offers that are only valid on mainnet.
+	if len(offer.Chains) > 0 {
+		invReq.Chain = m.cfg.ChainParams.GenesisHash
+	}
+
+	if err := invReq.Sign(bolt12.KeySigner(payerKey)); err != nil {
+		return nil, err
+	}
+
+	// Validate the request like the issuer will, so that an invalid
+	// amount or quantity is reported without a round trip.
+	if err := invReq.Validate(m.cfg.ChainParams); err != nil {
+		return nil, err
+	}
+
+	encoded, err := invReq.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	var pathID [32]byte
+	if _, err := rand.Read(pathID[:]); err != nil {
+		return nil, err
+	}
+
+	replyHops := make([]*btcec.PublicKey, 0, len(req.Hops))
+	for i := len(req.Hops) - 1; i >= 0; i-- {
+		replyHops = append(replyHops, req.Hops[i])
+	}
+
+	replyPath, err := m.cfg.Messenger.BuildReplyPath(
+		replyHops, pathID[:],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build reply path: %w", err)
+	}
+
+	msg := &onionmessage.OutgoingMessage{
+		Hops:      req.Hops,
+		ReplyPath: replyPath,
+		FinalHopTLVs: map[tlv.Type][]byte{
+			InvoiceRequestType: encoded,
+		},
+	}
+	if len(offer.Paths) > 0 {
+		msg.BlindedPath = offer.Paths[0]
+	} else {
+		msg.Destination = offer.IssuerID
+	}
+
+	replies := make(chan *reply, 1)
+	m.pendingMu.Lock()
+	m.pending[pathID] = replies
+	m.pendingMu.Unlock()
+
+	defer func() {
+		m.pendingMu.Lock()
+		delete(m.pending, pathID)
+		m.pendingMu.Unlock()
+	}()
+
+	if err := m.cfg.Messenger.SendOnionMessage(msg); err != nil {
+		return nil, fmt.Errorf("unable to send invoice request: %w",
+			err)
+	}
+
+	select {
+	case r := <-replies:
+		return m.processReply(invReq, r)
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-m.quit:
+		return nil, ErrManagerShuttingDown
+	}
+}
+
+// processReply validates the reply that we received for an invoice request.
+func (m *Manager) processReply(req *bolt12.InvoiceRequest,
+	r *reply) (*bolt12.Invoice, error) {
+
+	if r.invoice == nil {
+		invoiceErr, err := bolt12.DecodeInvoiceError(r.invoiceError)
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice error: %w",
+				err)
+		}
+
+		return nil, invoiceErr
+	}
+
+	invoice, err := bolt12.DecodeInvoice(r.invoice)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+
+	if err := invoice.ValidateFor(req); err != nil {
+		return nil, err
+	}
+
+	if !m.cfg.Clock.Now().Before(invoice.Expiry()) {
+		return nil, ErrInvoiceExpired
+	}
+
+	return invoice, nil
+}
+
+// messageHandler dispatches the onion messages that we receive to the
+// handler for their type.
+//
+// NOTE: This MUST be run as a goroutine.
+func (m *Manager) messageHandler(client *subscribe.Client) {
+	defer m.wg.Done()
+	defer client.Cancel()
+
+	pruneTicker := time.NewTicker(limiterPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case update, ok := <-client.Updates():
+			if !ok {
+				return
+			}
+
+			msg, ok := update.(*onionmessage.ReceivedMessage)
+			if !ok {
+				continue
+			}
+
+			m.handleMessage(msg)
+
+		case <-pruneTicker.C:
+			m.pruneLimiters()
+
+		case <-client.Quit():
+			return
+
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// handleMessage handles a single onion message.
+func (m *Manager) handleMessage(msg *onionmessage.ReceivedMessage) {
+	tlvs := msg.FinalHopTLVs
+
+	if invReq, ok := tlvs[InvoiceRequestType]; ok {
+		// Every invoice request that we serve creates and stores an
+		// invoice, so each peer may only deliver a limited number of
+		// them.
+		if !m.allowRequest(msg.Peer) {
+			log.Debugf("Dropping invoice request from peer=%v: "+
+				"rate limit exceeded", msg.Peer)
+
+			return
+		}
+
+		// Creating an invoice involves pathfinding for its blinded
+		// paths, so we don't block the handling of other messages on
+		// it, as long as there's a free slot to process it in.
+		select {
+		case m.requestSlots <- struct{}{}:
+
+		default:
+			log.Debugf("Dropping invoice request from peer=%v: "+
+				"too many pending requests", msg.Peer)
+
+			return
+		}
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			defer func() {
+				<-m.requestSlots
+			}()
+
+			m.handleInvoiceRequest(invReq, msg.ReplyPath)
+		}()
+
+		return
+	}
+
+	invoice, isInvoice := tlvs[InvoiceType]
+	invoiceErr, isInvoiceErr := tlvs[InvoiceErrorType]
+	if !isInvoice && !isInvoiceErr {
+		return
+	}
+
+	// Replies are only accepted over the reply paths that we created for
+	// our own invoice requests.
+	var pathID [32]byte
+	if len(msg.PathID) != len(pathID) {
+		log.Debugf("Ignoring invoice reply with invalid path ID")
+		return
+	}
+	copy(pathID[:], msg.PathID)
+
+	m.pendingMu.Lock()
+	replies, ok := m.pending[pathID]
+	m.pendingMu.Unlock()
+
+	if !ok {
+		log.Debugf("Ignoring invoice reply for unknown path ID %x",
+			pathID)
+		return
+	}
+
+	r := &reply{invoiceError: invoiceErr}
+	if isInvoice {
+		r = &reply{invoice: invoice}
+	}
+
+	// Only the first reply is delivered, any others are dropped.
+	select {
+	case replies <- r:
+	default:
+	}
+}
+
+// allowRequest returns true if the peer has budget left to deliver another
+// invoice request, consuming one unit of its budget.
+func (m *Manager) allowRequest(peer route.Vertex) bool {
+	limiter, ok := m.limiters[peer]
+	if !ok {
+		limiter = rate.NewLimiter(
+			rate.Every(m.cfg.PeerRequestInterval),
+			m.cfg.PeerRequestBurst,
+		)
+		m.limiters[peer] = limiter
+	}
+
+	return limiter.Allow()
+}
+
+// pruneLimiters removes the rate limiters of all peers that have their full
+// budget available, as they are equivalent to a freshly created limiter.
+func (m *Manager) pruneLimiters() {
+	for peer, limiter := range m.limiters {
+		if limiter.Tokens() >= float64(limiter.Burst()) {
+			delete(m.limiters, peer)
+		}
+	}
+}
+
+// handleInvoiceRequest replies to an invoice request for one of our offers
+// with an invoice, or an invoice error if the request can't be served.
+func (m *Manager) handleInvoiceRequest(encoded []byte,
+	replyPath *record.BlindedPath) {
+
+	if replyPath == nil {
+		log.Debugf("Ignoring invoice request without reply path")
+		return
+	}
+
+	var replyTLVs map[tlv.Type][]byte
+	invoice, err := m.createInvoice(encoded)
+	if err != nil {
+		log.Debugf("Rejecting invoice request: %v", err)
+
+		invoiceErr := &bolt12.InvoiceError{Message: err.Error()}
+		replyTLVs = map[tlv.Type][]byte{
+			InvoiceErrorType: invoiceErr.Encode(),
+		}
+	} else {
+		encodedInvoice, err := invoice.Encode()
+		if err != nil {
+			log.Errorf("Unable to encode invoice: %v", err)
+			return
+		}
+
+		log.Infof("Sending invoice with payment hash %v in reply "+
+			"to invoice request", invoice.PaymentHash)
+
+		replyTLVs = map[tlv.Type][]byte{
+			InvoiceType: encodedInvoice,
+		}
+	}
+
+	err = m.cfg.Messenger.SendOnionMessage(&onionmessage.OutgoingMessage{
+		BlindedPath:  replyPath,
+		FinalHopTLVs: replyTLVs,
+	})
+	if err != nil {
+		log.Debugf("Unable to reply to invoice request: %v", err)
+	}
+}
+
+// createInvoice validates an invoice request and creates an invoice for it.
+func (m *Manager) createInvoice(encoded []byte) (*bolt12.Invoice, error) {
+	req, err := bolt12.DecodeInvoiceRequest(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Validate(m.cfg.ChainParams); err != nil {
+		return nil, err
+	}
+
+	offer := req.Offer
+	ourKey := m.cfg.NodeKeyECDH.PubKey()
+	if offer.IssuerID == nil || !offer.IssuerID.IsEqual(ourKey) ||
+		!offer.VerifyAuthMetadata(m.metadataKey) {
+
+		return nil, ErrUnknownOffer
+	}
+
+	if offer.Expired(m.cfg.Clock.Now()) {
+		return nil, ErrOfferExpired
+	}
+
+	return m.cfg.CreateInvoice(req)
+}
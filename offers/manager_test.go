@@ -0,0 +1,294 @@
+package offers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/bolt12"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/onionmessage"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Unix(1_700_000_000, 0)
+
+// mockNetwork connects mock messengers, delivering onion messages directly to
+// their destination.
+type mockNetwork struct {
+	mu    sync.Mutex
+	nodes map[route.Vertex]*mockMessenger
+
+	// pathIDs holds the path ID of each reply path that was built.
+	pathIDs map[*record.BlindedPath][]byte
+}
+
+// mockMessenger is a mock implementation of the Messenger interface.
+type mockMessenger struct {
+	net    *mockNetwork
+	key    *btcec.PublicKey
+	server *subscribe.Server
+}
+
+// newMockMessenger creates a messenger for the given node in the network.
+func (n *mockNetwork) newMockMessenger(t *testing.T,
+	key *btcec.PublicKey) *mockMessenger {
+
+	server := subscribe.NewServer()
+	require.NoError(t, server.Start())
+	t.Cleanup(func() {
+		require.NoError(t, server.Stop())
+	})
+
+	m := &mockMessenger{
+		net:    n,
+		key:    key,
+		server: server,
+	}
+
+	n.mu.Lock()
+	n.nodes[route.NewVertex(key)] = m
+	n.mu.Unlock()
+
+	return m
+}
+
+func (m *mockMessenger) SendOnionMessage(
+	msg *onionmessage.OutgoingMessage) error {
+
+	m.net.mu.Lock()
+	defer m.net.mu.Unlock()
+
+	dest := msg.Destination
+	var pathID []byte
+	if msg.BlindedPath != nil {
+		dest = msg.BlindedPath.IntroductionNode
+		pathID = m.net.pathIDs[msg.BlindedPath]
+	}
+
+	receiver, ok := m.net.nodes[route.NewVertex(dest)]
+	if !ok {
+		return onionmessage.ErrInvalidDestination
+	}
+
+	return receiver.server.SendUpdate(&onionmessage.ReceivedMessage{
+		Peer:         route.NewVertex(m.key),
+		PathID:       pathID,
+		ReplyPath:    msg.ReplyPath,
+		FinalHopTLVs: msg.FinalHopTLVs,
+	})
+}
+
+func (m *mockMessenger) SubscribeMessages() (*subscribe.Client, error) {
+	return m.server.Subscribe()
+}
+
+func (m *mockMessenger) BuildReplyPath(_ []*btcec.PublicKey,
+	pathID []byte) (*record.BlindedPath, error) {
+
+	path := &record.BlindedPath{IntroductionNode: m.key}
+
+	m.net.mu.Lock()
+	m.net.pathIDs[path] = pathID
+	m.net.mu.Unlock()
+
+	return path, nil
+}
+
+// newTestManager creates and starts a manager for a new node in the network.
+// Invoices are created by signing them with the node's key. The options are
+// applied to the manager's config before it is created.
+func newTestManager(t *testing.T, net *mockNetwork,
+	opts ...func(*Config)) *Manager {
+
+	t.Helper()
+
+	nodeKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	introKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	createInvoice := func(
+		req *bolt12.InvoiceRequest) (*bolt12.Invoice, error) {
+
+		path := &zpay32.BlindedPaymentPath{
+			CltvExpiryDelta:             80,
+			HTLCMaxMsat:                 1_000_000,
+			Features:                    lnwire.EmptyFeatureVector(),
+			FirstEphemeralBlindingPoint: introKey.PubKey(),
+			Hops: []*sphinx.BlindedHopInfo{{
+				BlindedNodePub: introKey.PubKey(),
+				CipherText:     []byte{1},
+			}},
+		}
+
+		invoice := &bolt12.Invoice{
+			InvoiceRequest: req,
+			Paths:          []*zpay32.BlindedPaymentPath{path},
+			CreatedAt:      testTime,
+			PaymentHash:    lntypes.Hash{1},
+			Amount:         req.AmountToPay(),
+			NodeID:         nodeKey.PubKey(),
+		}
+		err := invoice.Sign(bolt12.KeySigner(nodeKey))
+
+		return invoice, err
+	}
+
+	cfg := &Config{
+		ChainParams:   &chaincfg.RegressionNetParams,
+		NodeKeyECDH:   &keychain.PrivKeyECDH{PrivKey: nodeKey},
+		Messenger:     net.newMockMessenger(t, nodeKey.PubKey()),
+		CreateInvoice: createInvoice,
+		Clock:         clock.NewTestClock(testTime),
+
+		MaxConcurrentRequests: 10,
+		PeerRequestBurst:      10,
+		PeerRequestInterval:   time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	m := NewManager(cfg)
+	require.NoError(t, m.Start())
+	t.Cleanup(func() {
+		require.NoError(t, m.Stop())
+	})
+
+	return m
+}
+
+// TestFetchInvoice tests that invoices can be fetched for offers created by
+// another node, and that invalid invoice requests are rejected by the issuer.
+func TestFetchInvoice(t *testing.T) {
+	t.Parallel()
+
+	net := &mockNetwork{
+		nodes:   make(map[route.Vertex]*mockMessenger),
+		pathIDs: make(map[*record.BlindedPath][]byte),
+	}
+	issuer := newTestManager(t, net)
+	payer := newTestManager(t, net)
+
+	encoded, err := issuer.CreateOffer(&bolt12.Offer{
+		Amount:      1000,
+		Description: "coffee",
+	})
+	require.NoError(t, err)
+
+	offer, err := bolt12.DecodeOffer(encoded)
+	require.NoError(t, err)
+	require.True(t, offer.SupportsChain(&chaincfg.RegressionNetParams))
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), DefaultFetchTimeout,
+	)
+	defer cancel()
+
+	invoice, err := payer.FetchInvoice(ctx, &FetchRequest{
+		Offer:     offer,
+		PayerNote: "thanks",
+	})
+	require.NoError(t, err)
+	require.Equal(t, lnwire.MilliSatoshi(1000), invoice.Amount)
+	require.Equal(t, "thanks", invoice.InvoiceRequest.PayerNote)
+
+	// An offer that wasn't created by the issuer is rejected with an
+	// invoice error, even though it names the issuer's key.
+	forged := *offer
+	forged.Amount = 1
+	_, err = payer.FetchInvoice(ctx, &FetchRequest{Offer: &forged})
+	var invoiceErr *bolt12.InvoiceError
+	require.ErrorAs(t, err, &invoiceErr)
+	require.Contains(t, invoiceErr.Message, ErrUnknownOffer.Error())
+
+	// Invalid requests are rejected before they're sent.
+	_, err = payer.FetchInvoice(ctx, &FetchRequest{
+		Offer:  offer,
+		Amount: 999,
+	})
+	require.ErrorIs(t, err, bolt12.ErrInvalidAmount)
+
+	// Expired offers are rejected.
+	expiring := &bolt12.Offer{
+		Description:    "expired",
+		AbsoluteExpiry: testTime.Add(-time.Second),
+	}
+	encoded, err = issuer.CreateOffer(expiring)
+	require.NoError(t, err)
+	offer, err = bolt12.DecodeOffer(encoded)
+	require.NoError(t, err)
+
+	_, err = payer.FetchInvoice(ctx, &FetchRequest{
+		Offer:  offer,
+		Amount: 1000,
+	})
+	require.ErrorIs(t, err, ErrOfferExpired)
+}
+
+// TestInvoiceRequestLimits tests that the invoice requests that a peer
+// delivers above its budget are dropped, as well as those that arrive while
+// the maximum number of requests is being processed.
+func TestInvoiceRequestLimits(t *testing.T) {
+	t.Parallel()
+
+	net := &mockNetwork{
+		nodes:   make(map[route.Vertex]*mockMessenger),
+		pathIDs: make(map[*record.BlindedPath][]byte),
+	}
+	issuer := newTestManager(t, net, func(cfg *Config) {
+		cfg.PeerRequestBurst = 1
+		cfg.PeerRequestInterval = time.Hour
+	})
+	payer := newTestManager(t, net)
+	otherPayer := newTestManager(t, net)
+
+	encoded, err := issuer.CreateOffer(&bolt12.Offer{
+		Amount:      1000,
+		Description: "coffee",
+	})
+	require.NoError(t, err)
+
+	offer, err := bolt12.DecodeOffer(encoded)
+	require.NoError(t, err)
+
+	fetch := func(m *Manager) error {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 200*time.Millisecond,
+		)
+		defer cancel()
+
+		_, err := m.FetchInvoice(ctx, &FetchRequest{Offer: offer})
+
+		return err
+	}
+
+	// The first request is served, while the second one from the same
+	// peer exceeds its budget and is never answered.
+	require.NoError(t, fetch(payer))
+	require.ErrorIs(t, fetch(payer), context.DeadlineExceeded)
+
+	// Other peers have their own budget.
+	require.NoError(t, fetch(otherPayer))
+
+	// Once all request slots are taken, further requests are dropped
+	// regardless of the budget of the peer that delivered them.
+	for i := 0; i < cap(issuer.requestSlots); i++ {
+		issuer.requestSlots <- struct{}{}
+	}
+	newPayer := newTestManager(t, net)
+	require.ErrorIs(t, fetch(newPayer), context.DeadlineExceeded)
+}
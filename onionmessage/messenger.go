@@ -0,0 +1,705 @@
+package onionmessage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/msgmux"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+	"github.com/lightningnetwork/lnd/tlv"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// EndpointName is the name that the messenger registers itself under
+	// with a msgmux.Router.
+	EndpointName = "onion-message"
+
+	// limiterPruneInterval is how often we'll remove the rate limiters of
+	// peers that have fully replenished their budget.
+	limiterPruneInterval = time.Minute
+)
+
+var (
+	// ErrNoForwardingTarget is returned when the encrypted data of an
+	// intermediate hop doesn't tell us where to forward the message.
+	ErrNoForwardingTarget = errors.New("onion message has no next node " +
+		"id or short channel id")
+
+	// ErrTooManySelfHops is returned when an onion message is repeatedly
+	// forwarded to ourselves.
+	ErrTooManySelfHops = errors.New("onion message forwarded to " +
+		"ourselves too many times")
+
+	// ErrInvalidDestination is returned when an outgoing message doesn't
+	// specify exactly one destination.
+	ErrInvalidDestination = errors.New("exactly one of destination or " +
+		"blinded path must be set")
+)
+
+// Config holds the set of dependencies that the Messenger requires.
+type Config struct {
+	// NodeKeyECDH is our node's identity key, which is used to peel the
+	// onion messages that are sent to us.
+	NodeKeyECDH keychain.SingleKeyECDH
+
+	// SendToPeer sends an onion message to a connected peer.
+	SendToPeer func(peer route.Vertex, msg *lnwire.OnionMessage) error
+
+	// ScidToPeer resolves the short channel ID of one of our channels to
+	// the peer on the other side of it. This is used to forward messages
+	// whose encrypted data identifies the next hop by channel.
+	ScidToPeer func(scid lnwire.ShortChannelID) (route.Vertex, error)
+
+	// PeerBurst is the number of onion messages that we'll accept from a
+	// single peer in a burst before we start to drop them.
+	PeerBurst int
+
+	// PeerInterval is the interval at which a peer's budget of onion
+	// messages is replenished by one.
+	PeerInterval time.Duration
+
+	// MaxPending is the maximum number of onion messages from all peers
+	// that can be waiting to be processed. Messages that arrive when the
+	// queue is full are dropped.
+	MaxPending int
+}
+
+// ReceivedMessage is an onion message for which we're the final recipient.
+type ReceivedMessage struct {
+	// Peer is the peer that delivered the message to us. This isn't
+	// necessarily the sender of the message.
+	Peer route.Vertex
+
+	// PathID is the path ID that we set in the blinded path that the
+	// message was sent over, if any. It can be used to verify that the
+	// message was sent over a path we created for a specific purpose.
+	PathID []byte
+
+	// ReplyPath is an optional blinded path that we can use to respond to
+	// the sender.
+	ReplyPath *record.BlindedPath
+
+	// FinalHopTLVs holds the application specific contents of the
+	// message.
+	FinalHopTLVs map[tlv.Type][]byte
+}
+
+// OutgoingMessage describes an onion message that we want to send.
+type OutgoingMessage struct {
+	// Destination is the node that the message should be delivered to.
+	// Exactly one of Destination and BlindedPath must be set.
+	Destination *btcec.PublicKey
+
+	// BlindedPath is a blinded path provided by the recipient that the
+	// message should be delivered to. Exactly one of Destination and
+	// BlindedPath must be set.
+	BlindedPath *record.BlindedPath
+
+	// Hops is an optional list of intermediate nodes that the message is
+	// relayed through before it reaches the destination or the
+	// introduction node of the blinded path. The first hop, or the
+	// destination if no hops are set, must be one of our peers.
+	Hops []*btcec.PublicKey
+
+	// ReplyPath is an optional blinded path that the recipient can use to
+	// respond to the message.
+	ReplyPath *record.BlindedPath
+
+	// FinalHopTLVs holds the application specific contents of the
+	// message. All types must be at least MinFinalHopType.
+	FinalHopTLVs map[tlv.Type][]byte
+}
+
+// incomingMsg is an onion message received from a peer that is waiting to be
+// processed.
+type incomingMsg struct {
+	peer route.Vertex
+	msg  *lnwire.OnionMessage
+}
+
+// Messenger is responsible for sending, receiving and relaying onion
+// messages. It implements the msgmux.Endpoint interface so that incoming
+// onion messages can be routed to it by each peer. Every peer has a budget of
+// onion messages that it can send us, and any messages above that budget are
+// dropped.
+type Messenger struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *Config
+
+	// router is used to peel our layer off incoming onion messages.
+	router *sphinx.Router
+
+	// ourVertex is our node's public key.
+	ourVertex route.Vertex
+
+	// limiters holds the rate limiter for each peer that has sent us an
+	// onion message recently.
+	limiters   map[route.Vertex]*rate.Limiter
+	limitersMu sync.Mutex
+
+	// incoming is the queue of onion messages waiting to be processed.
+	incoming chan *incomingMsg
+
+	// msgServer is used to deliver the messages that we receive to any
+	// subscribers.
+	msgServer *subscribe.Server
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// A compile-time check to ensure Messenger implements the msgmux.Endpoint
+// interface.
+var _ msgmux.Endpoint = (*Messenger)(nil)
+
+// NewMessenger creates a new onion message Messenger.
+func NewMessenger(cfg *Config) *Messenger {
+	return &Messenger{
+		cfg: cfg,
+		router: sphinx.NewRouter(
+			cfg.NodeKeyECDH, sphinx.NewMemoryReplayLog(),
+		),
+		ourVertex: route.NewVertex(cfg.NodeKeyECDH.PubKey()),
+		limiters:  make(map[route.Vertex]*rate.Limiter),
+		incoming:  make(chan *incomingMsg, cfg.MaxPending),
+		msgServer: subscribe.NewServer(),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start starts the Messenger.
+func (m *Messenger) Start() error {
+	var err error
+	m.started.Do(func() {
+		log.Info("Onion messenger starting")
+
+		if err = m.msgServer.Start(); err != nil {
+			return
+		}
+
+		m.wg.Add(1)
+		go m.messageHandler()
+	})
+
+	return err
+}
+
+// Stop stops the Messenger.
+func (m *Messenger) Stop() error {
+	var err error
+	m.stopped.Do(func() {
+		log.Info("Onion messenger shutting down...")
+		defer log.Debug("Onion messenger shutdown complete")
+
+		close(m.quit)
+		m.wg.Wait()
+
+		err = m.msgServer.Stop()
+	})
+
+	return err
+}
+
+// Name returns the name of this endpoint.
+//
+// NOTE: Part of the msgmux.Endpoint interface.
+func (m *Messenger) Name() msgmux.EndpointName {
+	return EndpointName
+}
+
+// CanHandle returns true if the target message is an onion message.
+//
+// NOTE: Part of the msgmux.Endpoint interface.
+func (m *Messenger) CanHandle(msg msgmux.PeerMsg) bool {
+	_, ok := msg.Message.(*lnwire.OnionMessage)
+	return ok
+}
+
+// SendMessage queues an incoming onion message for processing, if the peer
+// that sent it is within its budget. Messages that exceed the budget are
+// dropped, but are still considered to be handled.
+//
+// NOTE: Part of the msgmux.Endpoint interface.
+func (m *Messenger) SendMessage(msg msgmux.PeerMsg) bool {
+	onionMsg, ok := msg.Message.(*lnwire.OnionMessage)
+	if !ok {
+		return false
+	}
+
+	peer := route.NewVertex(&msg.PeerPub)
+	if !m.allow(peer) {
+		log.Debugf("Dropping onion message from peer=%v: rate limit "+
+			"exceeded", peer)
+
+		return true
+	}
+
+	select {
+	case m.incoming <- &incomingMsg{peer: peer, msg: onionMsg}:
+
+	case <-m.quit:
+
+	default:
+		log.Debugf("Dropping onion message from peer=%v: too many "+
+			"pending messages", peer)
+	}
+
+	return true
+}
+
+// allow returns true if the peer has budget left to send us another onion
+// message, consuming one unit of its budget.
+func (m *Messenger) allow(peer route.Vertex) bool {
+	m.limitersMu.Lock()
+	defer m.limitersMu.Unlock()
+
+	limiter, ok := m.limiters[peer]
+	if !ok {
+		limiter = rate.NewLimiter(
+			rate.Every(m.cfg.PeerInterval), m.cfg.PeerBurst,
+		)
+		m.limiters[peer] = limiter
+	}
+
+	return limiter.Allow()
+}
+
+// pruneLimiters removes the rate limiters of all peers that have their full
+// budget available, as they are equivalent to a freshly created limiter.
+func (m *Messenger) pruneLimiters() {
+	m.limitersMu.Lock()
+	defer m.limitersMu.Unlock()
+
+	for peer, limiter := range m.limiters {
+		if limiter.Tokens() >= float64(limiter.Burst()) {
+			delete(m.limiters, peer)
+		}
+	}
+}
+
+// messageHandler is the main goroutine that processes incoming onion
+// messages.
+//
+// NOTE: MUST be run as a goroutine.
+func (m *Messenger) messageHandler() {
+	defer m.wg.Done()
+
+	pruneTicker := time.NewTicker(limiterPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case in := <-m.incoming:
+			err := m.processMessage(in.peer, in.msg)
+			if err != nil {
+				log.Debugf("Unable to process onion message "+
+					"from peer=%v: %v", in.peer, err)
+			}
+
+		case <-pruneTicker.C:
+			m.pruneLimiters()
+
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// processMessage peels our layer off the onion message and then either
+// delivers it to our subscribers or relays it to the next hop. If the next hop
+// is ourselves, we'll continue to peel layers until the message either leaves
+// our node or reaches its destination.
+func (m *Messenger) processMessage(peer route.Vertex,
+	msg *lnwire.OnionMessage) error {
+
+	for i := 0; i < sphinx.NumMaxHops; i++ {
+		received, nextNode, nextMsg, err := m.peelMessage(msg)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case received != nil:
+			received.Peer = peer
+
+			log.Debugf("Received onion message from peer=%v", peer)
+
+			return m.msgServer.SendUpdate(received)
+
+		case nextNode == m.ourVertex:
+			msg = nextMsg
+
+		default:
+			log.Tracef("Relaying onion message from peer=%v to "+
+				"peer=%v", peer, nextNode)
+
+			return m.cfg.SendToPeer(nextNode, nextMsg)
+		}
+	}
+
+	return ErrTooManySelfHops
+}
+
+// peelMessage decrypts our layer of an onion message. If we're the final
+// recipient, the received message is returned. Otherwise, the next node along
+// with the message that should be sent to it are returned.
+func (m *Messenger) peelMessage(msg *lnwire.OnionMessage) (*ReceivedMessage,
+	route.Vertex, *lnwire.OnionMessage, error) {
+
+	var pkt sphinx.OnionPacket
+	if err := pkt.Decode(bytes.NewReader(msg.OnionBlob)); err != nil {
+		return nil, route.Vertex{}, nil, fmt.Errorf("unable to "+
+			"decode onion packet: %w", err)
+	}
+
+	processed, err := m.router.ReconstructOnionPacket(
+		&pkt, nil, sphinx.WithBlindingPoint(msg.PathKey),
+	)
+	if err != nil {
+		return nil, route.Vertex{}, nil, fmt.Errorf("unable to "+
+			"process onion packet: %w", err)
+	}
+
+	payload, err := DecodePayload(processed.Payload.Payload)
+	if err != nil {
+		return nil, route.Vertex{}, nil, fmt.Errorf("unable to "+
+			"decode payload: %w", err)
+	}
+
+	if len(payload.EncryptedData) == 0 {
+		return nil, route.Vertex{}, nil, ErrNoEncryptedData
+	}
+
+	decrypted, err := m.router.DecryptBlindedHopData(
+		msg.PathKey, payload.EncryptedData,
+	)
+	if err != nil {
+		return nil, route.Vertex{}, nil, fmt.Errorf("unable to "+
+			"decrypt encrypted data: %w", err)
+	}
+
+	routeData, err := record.DecodeBlindedRouteData(
+		bytes.NewReader(decrypted),
+	)
+	if err != nil {
+		return nil, route.Vertex{}, nil, fmt.Errorf("unable to "+
+			"decode encrypted data: %w", err)
+	}
+
+	// If we're the final hop, then we'll hand the contents of the
+	// message to the caller.
+	if processed.Action == sphinx.ExitNode {
+		received := &ReceivedMessage{
+			ReplyPath:    payload.ReplyPath,
+			FinalHopTLVs: payload.FinalHopTLVs,
+		}
+		routeData.PathID.WhenSome(
+			func(r tlv.RecordT[tlv.TlvType6, []byte]) {
+				received.PathID = r.Val
+			},
+		)
+
+		return received, route.Vertex{}, nil, nil
+	}
+
+	// Intermediate hops only receive encrypted data, anything else is
+	// meant for the recipient and signals a malformed message.
+	if payload.ReplyPath != nil || len(payload.FinalHopTLVs) != 0 {
+		return nil, route.Vertex{}, nil, ErrUnexpectedFinalHopData
+	}
+
+	nextNode, err := m.nextNode(routeData)
+	if err != nil {
+		return nil, route.Vertex{}, nil, err
+	}
+
+	// The next path key is either explicitly provided, which happens when
+	// the sender's path is joined with a blinded path provided by the
+	// recipient, or derived from the current one.
+	var nextPathKey *btcec.PublicKey
+	routeData.NextBlindingOverride.WhenSome(
+		func(r tlv.RecordT[tlv.TlvType8, *btcec.PublicKey]) {
+			nextPathKey = r.Val
+		},
+	)
+	if nextPathKey == nil {
+		nextPathKey, err = m.router.NextEphemeral(msg.PathKey)
+		if err != nil {
+			return nil, route.Vertex{}, nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	if err := processed.NextPacket.Encode(&b); err != nil {
+		return nil, route.Vertex{}, nil, err
+	}
+
+	return nil, nextNode, lnwire.NewOnionMessage(nextPathKey, b.Bytes()),
+		nil
+}
+
+// nextNode determines the node that a message should be relayed to from the
+// encrypted data of an intermediate hop.
+func (m *Messenger) nextNode(data *record.BlindedRouteData) (route.Vertex,
+	error) {
+
+	var (
+		nextNode route.Vertex
+		found    bool
+	)
+	data.NextNodeID.WhenSome(
+		func(r tlv.RecordT[tlv.TlvType4, *btcec.PublicKey]) {
+			nextNode = route.NewVertex(r.Val)
+			found = true
+		},
+	)
+	if found {
+		return nextNode, nil
+	}
+
+	var (
+		scid    lnwire.ShortChannelID
+		hasScid bool
+	)
+	data.ShortChannelID.WhenSome(
+		func(r tlv.RecordT[tlv.TlvType2, lnwire.ShortChannelID]) {
+			scid = r.Val
+			hasScid = true
+		},
+	)
+	if !hasScid {
+		return route.Vertex{}, ErrNoForwardingTarget
+	}
+
+	nextNode, err := m.cfg.ScidToPeer(scid)
+	if err != nil {
+		return route.Vertex{}, fmt.Errorf("unable to resolve next "+
+			"hop for scid=%v: %w", scid, err)
+	}
+
+	return nextNode, nil
+}
+
+// SubscribeMessages returns a subscription client that is notified of every
+// onion message for which we're the final recipient. Updates are delivered as
+// *ReceivedMessage.
+func (m *Messenger) SubscribeMessages() (*subscribe.Client, error) {
+	return m.msgServer.Subscribe()
+}
+
+// SendOnionMessage builds an onion message for the given destination and
+// sends it to the first hop.
+func (m *Messenger) SendOnionMessage(msg *OutgoingMessage) error {
+	firstHop, onionMsg, err := BuildOnionMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	firstVertex := route.NewVertex(firstHop)
+
+	// If the first hop is our own node, which happens when we are the
+	// introduction node of the destination's blinded path, then we'll
+	// process the message as if a peer sent it to us.
+	if firstVertex == m.ourVertex {
+		return m.processMessage(m.ourVertex, onionMsg)
+	}
+
+	return m.cfg.SendToPeer(firstVertex, onionMsg)
+}
+
+// BuildReplyPath creates a blinded path that leads back to our node, which
+// can be handed out so that others can send us onion messages. The path
+// starts at the first of the passed hops and passes through all of them
+// before reaching us. If no hops are passed, we'll be the introduction node
+// of the path. The path ID is encrypted for ourselves and returned with any
+// message that reaches us over the path.
+func (m *Messenger) BuildReplyPath(hops []*btcec.PublicKey,
+	pathID []byte) (*record.BlindedPath, error) {
+
+	nodes := make([]*btcec.PublicKey, 0, len(hops)+1)
+	nodes = append(nodes, hops...)
+	nodes = append(nodes, m.cfg.NodeKeyECDH.PubKey())
+
+	finalData := &record.BlindedRouteData{}
+	if len(pathID) > 0 {
+		finalData.PathID = tlv.SomeRecordT(
+			tlv.NewPrimitiveRecord[tlv.TlvType6](pathID),
+		)
+	}
+
+	return buildBlindedPath(nodes, finalData)
+}
+
+// BuildOnionMessage creates the onion message for an outgoing message and
+// returns it along with the node that it should be sent to.
+func BuildOnionMessage(msg *OutgoingMessage) (*btcec.PublicKey,
+	*lnwire.OnionMessage, error) {
+
+	if (msg.Destination == nil) == (msg.BlindedPath == nil) {
+		return nil, nil, ErrInvalidDestination
+	}
+
+	// The unblinded portion of the path is made up of the intermediate
+	// hops, along with the destination if we aren't sending to a blinded
+	// path.
+	unblinded := make([]*btcec.PublicKey, 0, len(msg.Hops)+1)
+	unblinded = append(unblinded, msg.Hops...)
+	if msg.Destination != nil {
+		unblinded = append(unblinded, msg.Destination)
+	}
+
+	var (
+		firstHop  *btcec.PublicKey
+		pathKey   *btcec.PublicKey
+		onionHops []*sphinx.BlindedHopInfo
+	)
+
+	// We blind the portion of the path that we build ourselves, so that
+	// all hops process the message the same way. If we're sending to a
+	// blinded path, the last of our hops hands the message over to the
+	// introduction node along with the path key of the recipient's path.
+	if len(unblinded) > 0 {
+		finalData := &record.BlindedRouteData{}
+		if msg.BlindedPath != nil {
+			finalData = &record.BlindedRouteData{
+				NextNodeID: tlv.SomeRecordT(
+					tlv.NewPrimitiveRecord[tlv.TlvType4](
+						msg.BlindedPath.IntroductionNode,
+					),
+				),
+				NextBlindingOverride: tlv.SomeRecordT(
+					tlv.NewPrimitiveRecord[tlv.TlvType8](
+						msg.BlindedPath.PathKey,
+					),
+				),
+			}
+		}
+
+		ourPath, err := buildBlindedPath(unblinded, finalData)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		firstHop = ourPath.IntroductionNode
+		pathKey = ourPath.PathKey
+		onionHops = ourPath.Hops
+	}
+
+	if msg.BlindedPath != nil {
+		if firstHop == nil {
+			firstHop = msg.BlindedPath.IntroductionNode
+			pathKey = msg.BlindedPath.PathKey
+		}
+
+		onionHops = append(onionHops, msg.BlindedPath.Hops...)
+	}
+
+	if len(onionHops) > sphinx.NumMaxHops {
+		return nil, nil, fmt.Errorf("onion message path of %d hops "+
+			"exceeds maximum of %d", len(onionHops),
+			sphinx.NumMaxHops)
+	}
+
+	var path sphinx.PaymentPath
+	for i, hop := range onionHops {
+		payload := &Payload{
+			EncryptedData: hop.CipherText,
+		}
+
+		// Only the final hop receives the reply path and contents of
+		// the message.
+		if i == len(onionHops)-1 {
+			payload.ReplyPath = msg.ReplyPath
+			payload.FinalHopTLVs = msg.FinalHopTLVs
+		}
+
+		payloadBytes, err := payload.Encode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hopPayload, err := sphinx.NewTLVHopPayload(payloadBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		path[i] = sphinx.OnionHop{
+			NodePub:    *hop.BlindedNodePub,
+			HopPayload: hopPayload,
+		}
+	}
+
+	sessionKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkt, err := sphinx.NewOnionPacket(
+		&path, sessionKey, nil, sphinx.DeterministicPacketFiller,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var b bytes.Buffer
+	if err := pkt.Encode(&b); err != nil {
+		return nil, nil, err
+	}
+
+	return firstHop, lnwire.NewOnionMessage(pathKey, b.Bytes()), nil
+}
+
+// buildBlindedPath creates a blinded path over the given nodes, where each
+// node relays the message to the next one using its node ID, and the last
+// node receives the given encrypted data.
+func buildBlindedPath(nodes []*btcec.PublicKey,
+	finalData *record.BlindedRouteData) (*record.BlindedPath, error) {
+
+	hopInfo := make([]*sphinx.HopInfo, len(nodes))
+	for i, node := range nodes {
+		data := finalData
+		if i < len(nodes)-1 {
+			data = &record.BlindedRouteData{
+				NextNodeID: tlv.SomeRecordT(
+					tlv.NewPrimitiveRecord[tlv.TlvType4](
+						nodes[i+1],
+					),
+				),
+			}
+		}
+
+		plainText, err := record.EncodeBlindedRouteData(data)
+		if err != nil {
+			return nil, err
+		}
+
+		hopInfo[i] = &sphinx.HopInfo{
+			NodePub:   node,
+			PlainText: plainText,
+		}
+	}
+
+	sessionKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := sphinx.BuildBlindedPath(sessionKey, hopInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return record.NewBlindedPath(path), nil
+}
@@ -0,0 +1,374 @@
+package onionmessage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/msgmux"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+// testNetwork is a set of messengers that are able to send onion messages to
+// each other directly.
+type testNetwork struct {
+	t *testing.T
+
+	nodes map[route.Vertex]*Messenger
+
+	// scids maps a short channel ID to the node that is reached over it.
+	scids map[lnwire.ShortChannelID]route.Vertex
+}
+
+// newTestNetwork creates a network of numNodes messengers.
+func newTestNetwork(t *testing.T, numNodes, burst int) (*testNetwork,
+	[]*Messenger) {
+
+	t.Helper()
+
+	network := &testNetwork{
+		t:     t,
+		nodes: make(map[route.Vertex]*Messenger),
+		scids: make(map[lnwire.ShortChannelID]route.Vertex),
+	}
+
+	messengers := make([]*Messenger, numNodes)
+	for i := range messengers {
+		privKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		ourVertex := route.NewVertex(privKey.PubKey())
+		messengers[i] = NewMessenger(&Config{
+			NodeKeyECDH: &keychain.PrivKeyECDH{PrivKey: privKey},
+			SendToPeer: func(peer route.Vertex,
+				msg *lnwire.OnionMessage) error {
+
+				return network.deliver(ourVertex, peer, msg)
+			},
+			ScidToPeer: func(scid lnwire.ShortChannelID) (
+				route.Vertex, error) {
+
+				peer, ok := network.scids[scid]
+				if !ok {
+					return route.Vertex{}, fmt.Errorf(
+						"unknown scid")
+				}
+
+				return peer, nil
+			},
+			PeerBurst:    burst,
+			PeerInterval: time.Hour,
+			MaxPending:   10,
+		})
+
+		network.nodes[ourVertex] = messengers[i]
+
+		require.NoError(t, messengers[i].Start())
+		t.Cleanup(func() {
+			require.NoError(t, messengers[i].Stop())
+		})
+	}
+
+	return network, messengers
+}
+
+// deliver passes an onion message from one node to another, as the peer's
+// message router would.
+func (n *testNetwork) deliver(from, to route.Vertex,
+	msg *lnwire.OnionMessage) error {
+
+	target, ok := n.nodes[to]
+	if !ok {
+		return fmt.Errorf("unknown peer: %v", to)
+	}
+
+	fromPub, err := btcec.ParsePubKey(from[:])
+	require.NoError(n.t, err)
+
+	peerMsg := msgmux.PeerMsg{
+		Message: msg,
+		PeerPub: *fromPub,
+	}
+	require.True(n.t, target.CanHandle(peerMsg))
+	require.True(n.t, target.SendMessage(peerMsg))
+
+	return nil
+}
+
+// subscribe creates a subscription for the messages received by a messenger.
+func subscribeMessages(t *testing.T, m *Messenger) *subscribe.Client {
+	t.Helper()
+
+	client, err := m.SubscribeMessages()
+	require.NoError(t, err)
+	t.Cleanup(client.Cancel)
+
+	return client
+}
+
+// receiveMessage waits for a message to be delivered to a subscription.
+func receiveMessage(t *testing.T, client *subscribe.Client) *ReceivedMessage {
+	t.Helper()
+
+	select {
+	case update := <-client.Updates():
+		msg, ok := update.(*ReceivedMessage)
+		require.True(t, ok)
+
+		return msg
+
+	case <-time.After(testTimeout):
+		t.Fatalf("message not received")
+	}
+
+	return nil
+}
+
+// assertNoMessage asserts that no message is delivered to a subscription.
+func assertNoMessage(t *testing.T, client *subscribe.Client) {
+	t.Helper()
+
+	select {
+	case <-client.Updates():
+		t.Fatalf("unexpected message received")
+
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestSendOnionMessage tests sending onion messages directly to a node and to
+// a blinded path, optionally through a set of intermediate hops.
+func TestSendOnionMessage(t *testing.T) {
+	t.Parallel()
+
+	_, nodes := newTestNetwork(t, 4, 10)
+	alice, bob, carol, dave := nodes[0], nodes[1], nodes[2], nodes[3]
+	pubKey := func(m *Messenger) *btcec.PublicKey {
+		return m.cfg.NodeKeyECDH.PubKey()
+	}
+
+	daveMsgs := subscribeMessages(t, dave)
+	aliceMsgs := subscribeMessages(t, alice)
+
+	contents := map[tlv.Type][]byte{
+		MinFinalHopType:     []byte("hello"),
+		MinFinalHopType + 1: []byte("dave"),
+	}
+
+	// Alice will include a reply path back to her through Carol.
+	replyPath, err := alice.BuildReplyPath(
+		[]*btcec.PublicKey{pubKey(carol)}, []byte{1, 2, 3},
+	)
+	require.NoError(t, err)
+
+	// First, we'll send a message directly to Dave.
+	require.NoError(t, alice.SendOnionMessage(&OutgoingMessage{
+		Destination:  pubKey(dave),
+		FinalHopTLVs: contents,
+	}))
+
+	received := receiveMessage(t, daveMsgs)
+	require.Equal(t, route.NewVertex(pubKey(alice)), received.Peer)
+	require.Equal(t, contents, received.FinalHopTLVs)
+	require.Nil(t, received.ReplyPath)
+	require.Empty(t, received.PathID)
+
+	// Next, send it through Bob and Carol with the reply path attached.
+	require.NoError(t, alice.SendOnionMessage(&OutgoingMessage{
+		Destination:  pubKey(dave),
+		Hops:         []*btcec.PublicKey{pubKey(bob), pubKey(carol)},
+		ReplyPath:    replyPath,
+		FinalHopTLVs: contents,
+	}))
+
+	received = receiveMessage(t, daveMsgs)
+	require.Equal(t, route.NewVertex(pubKey(carol)), received.Peer)
+	require.Equal(t, contents, received.FinalHopTLVs)
+	require.Equal(t, replyPath, received.ReplyPath)
+
+	// Dave can now use the reply path to respond to Alice, first by
+	// sending it directly to the introduction node and then through Bob.
+	reply := map[tlv.Type][]byte{
+		MinFinalHopType: []byte("hi alice"),
+	}
+	for _, hops := range [][]*btcec.PublicKey{
+		nil, {pubKey(bob)},
+	} {
+		require.NoError(t, dave.SendOnionMessage(&OutgoingMessage{
+			BlindedPath:  received.ReplyPath,
+			Hops:         hops,
+			FinalHopTLVs: reply,
+		}))
+
+		aliceReceived := receiveMessage(t, aliceMsgs)
+		require.Equal(
+			t, route.NewVertex(pubKey(carol)), aliceReceived.Peer,
+		)
+		require.Equal(t, []byte{1, 2, 3}, aliceReceived.PathID)
+		require.Equal(t, reply, aliceReceived.FinalHopTLVs)
+	}
+
+	// Alice is also able to send a message to her own blinded path, in
+	// which case it'll be routed through Carol back to her.
+	require.NoError(t, alice.SendOnionMessage(&OutgoingMessage{
+		BlindedPath:  replyPath,
+		FinalHopTLVs: reply,
+	}))
+	aliceReceived := receiveMessage(t, aliceMsgs)
+	require.Equal(t, []byte{1, 2, 3}, aliceReceived.PathID)
+
+	// A blinded path that is introduced by the sender itself is processed
+	// locally.
+	ownPath, err := dave.BuildReplyPath(nil, []byte{4})
+	require.NoError(t, err)
+	require.NoError(t, dave.SendOnionMessage(&OutgoingMessage{
+		BlindedPath:  ownPath,
+		FinalHopTLVs: reply,
+	}))
+	received = receiveMessage(t, daveMsgs)
+	require.Equal(t, []byte{4}, received.PathID)
+
+	// Messages must have exactly one destination.
+	err = alice.SendOnionMessage(&OutgoingMessage{})
+	require.ErrorIs(t, err, ErrInvalidDestination)
+
+	err = alice.SendOnionMessage(&OutgoingMessage{
+		Destination: pubKey(dave),
+		BlindedPath: replyPath,
+	})
+	require.ErrorIs(t, err, ErrInvalidDestination)
+}
+
+// TestForwardByScid tests that onion messages are relayed to the next hop
+// when it is identified by a short channel ID.
+func TestForwardByScid(t *testing.T) {
+	t.Parallel()
+
+	network, nodes := newTestNetwork(t, 3, 10)
+	alice, bob, carol := nodes[0], nodes[1], nodes[2]
+
+	bobPub := bob.cfg.NodeKeyECDH.PubKey()
+	carolPub := carol.cfg.NodeKeyECDH.PubKey()
+
+	scid := lnwire.NewShortChanIDFromInt(1234)
+	network.scids[scid] = route.NewVertex(carolPub)
+
+	// Carol creates a blinded path where Bob forwards to her using the
+	// channel between them.
+	bobData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{
+			ShortChannelID: tlv.SomeRecordT(
+				tlv.NewRecordT[tlv.TlvType2](scid),
+			),
+		},
+	)
+	require.NoError(t, err)
+
+	carolData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{},
+	)
+	require.NoError(t, err)
+
+	sessionKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	path, err := sphinx.BuildBlindedPath(sessionKey, []*sphinx.HopInfo{
+		{NodePub: bobPub, PlainText: bobData},
+		{NodePub: carolPub, PlainText: carolData},
+	})
+	require.NoError(t, err)
+
+	carolMsgs := subscribeMessages(t, carol)
+
+	contents := map[tlv.Type][]byte{
+		MinFinalHopType: []byte("hello"),
+	}
+	require.NoError(t, alice.SendOnionMessage(&OutgoingMessage{
+		BlindedPath:  record.NewBlindedPath(path),
+		FinalHopTLVs: contents,
+	}))
+
+	received := receiveMessage(t, carolMsgs)
+	require.Equal(t, route.NewVertex(bobPub), received.Peer)
+	require.Equal(t, contents, received.FinalHopTLVs)
+}
+
+// TestPeerRateLimit tests that onion messages from a peer that has exhausted
+// its budget are dropped.
+func TestPeerRateLimit(t *testing.T) {
+	t.Parallel()
+
+	_, nodes := newTestNetwork(t, 3, 2)
+	alice, bob, carol := nodes[0], nodes[1], nodes[2]
+	bobMsgs := subscribeMessages(t, bob)
+
+	send := func(sender *Messenger) {
+		require.NoError(t, sender.SendOnionMessage(&OutgoingMessage{
+			Destination: bob.cfg.NodeKeyECDH.PubKey(),
+		}))
+	}
+
+	// Alice is able to send two messages before her budget is exhausted.
+	send(alice)
+	receiveMessage(t, bobMsgs)
+	send(alice)
+	receiveMessage(t, bobMsgs)
+
+	send(alice)
+	assertNoMessage(t, bobMsgs)
+
+	// Carol has her own budget, so her messages are still delivered.
+	send(carol)
+	receiveMessage(t, bobMsgs)
+
+	// Once the limiters are pruned, only Alice's exhausted limiter should
+	// remain.
+	bob.pruneLimiters()
+	bob.limitersMu.Lock()
+	require.Len(t, bob.limiters, 2)
+	bob.limitersMu.Unlock()
+}
+
+// TestPayloadEncoding tests encoding and decoding of onion message payloads.
+func TestPayloadEncoding(t *testing.T) {
+	t.Parallel()
+
+	payload := &Payload{
+		EncryptedData: []byte{1, 2, 3},
+		FinalHopTLVs: map[tlv.Type][]byte{
+			MinFinalHopType:      {4},
+			MinFinalHopType + 11: {5, 6},
+		},
+	}
+
+	encoded, err := payload.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodePayload(encoded)
+	require.NoError(t, err)
+	require.Equal(t, payload, decoded)
+
+	// Types below the final hop range can't be used for message contents.
+	payload.FinalHopTLVs[6] = []byte{7}
+	_, err = payload.Encode()
+	require.Error(t, err)
+
+	// Unknown even types below the final hop range must be rejected,
+	// while odd ones are ignored.
+	_, err = DecodePayload([]byte{6, 1, 0})
+	require.Error(t, err)
+
+	decoded, err = DecodePayload([]byte{7, 1, 0})
+	require.NoError(t, err)
+	require.Equal(t, &Payload{}, decoded)
+}
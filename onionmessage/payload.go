@@ -0,0 +1,155 @@
+package onionmessage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// replyPathType is the TLV type of the reply path that a sender can
+	// include for the recipient of an onion message.
+	replyPathType tlv.Type = 2
+
+	// encryptedDataType is the TLV type of the encrypted recipient data
+	// that is provided for each hop by the creator of a blinded path.
+	encryptedDataType tlv.Type = 4
+
+	// MinFinalHopType is the smallest TLV type that is reserved for the
+	// contents of a message that is delivered to its final recipient.
+	// Types below this value are reserved for the onion message protocol
+	// itself.
+	MinFinalHopType tlv.Type = 64
+)
+
+var (
+	// ErrNoEncryptedData is returned when an onion message payload
+	// doesn't contain any encrypted recipient data, which is required for
+	// every hop.
+	ErrNoEncryptedData = errors.New("onion message payload missing " +
+		"encrypted recipient data")
+
+	// ErrUnexpectedFinalHopData is returned when a hop that isn't the
+	// final recipient of an onion message receives a payload containing
+	// records intended for the recipient.
+	ErrUnexpectedFinalHopData = errors.New("onion message payload for " +
+		"intermediate hop contains final hop data")
+)
+
+// Payload is the per-hop payload of an onion message.
+type Payload struct {
+	// ReplyPath is an optional blinded path that the recipient can use to
+	// respond to the message.
+	ReplyPath *record.BlindedPath
+
+	// EncryptedData is the encrypted recipient data for this hop that was
+	// created by the creator of the blinded path.
+	EncryptedData []byte
+
+	// FinalHopTLVs holds the application specific records that are
+	// delivered to the final recipient of the message. All types are at
+	// least MinFinalHopType.
+	FinalHopTLVs map[tlv.Type][]byte
+}
+
+// Encode serializes the payload as a TLV stream.
+func (p *Payload) Encode() ([]byte, error) {
+	records := make([]tlv.Record, 0, 2+len(p.FinalHopTLVs))
+
+	if p.ReplyPath != nil {
+		records = append(
+			records,
+			record.NewBlindedPathRecord(replyPathType, p.ReplyPath),
+		)
+	}
+
+	if len(p.EncryptedData) > 0 {
+		records = append(
+			records, tlv.MakePrimitiveRecord(
+				encryptedDataType, &p.EncryptedData,
+			),
+		)
+	}
+
+	for typ, value := range p.FinalHopTLVs {
+		if typ < MinFinalHopType {
+			return nil, fmt.Errorf("final hop TLV type %d is "+
+				"below the minimum of %d", typ,
+				MinFinalHopType)
+		}
+
+		records = append(
+			records, tlv.MakeStaticRecord(
+				typ, nil, uint64(len(value)),
+				tlv.StubEncoder(value), nil,
+			),
+		)
+	}
+
+	tlv.SortRecords(records)
+
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := stream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// DecodePayload parses an onion message payload from its TLV encoding.
+func DecodePayload(payload []byte) (*Payload, error) {
+	var (
+		p             Payload
+		replyPath     record.BlindedPath
+		encryptedData []byte
+	)
+
+	stream, err := tlv.NewStream(
+		record.NewBlindedPathRecord(replyPathType, &replyPath),
+		tlv.MakePrimitiveRecord(encryptedDataType, &encryptedData),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypesP2P(
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for typ, value := range parsedTypes {
+		switch {
+		case typ == replyPathType:
+			p.ReplyPath = &replyPath
+
+		case typ == encryptedDataType:
+			p.EncryptedData = encryptedData
+
+		// Unknown records that fall within the range reserved for the
+		// recipient are handed up to the application.
+		case typ >= MinFinalHopType:
+			if p.FinalHopTLVs == nil {
+				p.FinalHopTLVs = make(map[tlv.Type][]byte)
+			}
+			p.FinalHopTLVs[typ] = value
+
+		// Any other unknown even types are required to be understood,
+		// so we'll fail if we run into one.
+		case typ%2 == 0:
+			return nil, fmt.Errorf("unknown required onion "+
+				"message payload type: %d", typ)
+		}
+	}
+
+	return &p, nil
+}
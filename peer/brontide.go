@@ -41,6 +41,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwallet/chancloser"
+	"github.com/lightningnetwork/lnd/lnwallet/splice"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/msgmux"
 	"github.com/lightningnetwork/lnd/netann"
@@ -383,15 +384,34 @@ type Config struct {
 	// invalid.
 	DisallowRouteBlinding bool
 
+	// TrampolineForwarder forwards the payments that are delivered to us
+	// as a trampoline node. It is nil if we refuse to forward them. This
+	// value will be passed to created links.
+	TrampolineForwarder htlcswitch.TrampolineForwarder
+
+	// HtlcRateLimiter limits the rate at which the peer may add htlcs to
+	// our channels. It is nil if rate limiting is disabled.
+	HtlcRateLimiter *htlcswitch.HtlcRateLimiter
+
 	// MaxFeeExposure limits the number of outstanding fees in a channel.
 	// This value will be passed to created links.
 	MaxFeeExposure lnwire.MilliSatoshi
 
+	// MaxLocalCSVDelay is the maximum delay we accept for our outputs when
+	// the remote party proposes a new one in a dynamic commitment upgrade.
+	MaxLocalCSVDelay uint16
+
 	// MsgRouter is an optional instance of the main message router that
 	// the peer will use. If None, then a new default version will be used
 	// in place.
 	MsgRouter fn.Option[msgmux.Router]
 
+	// OnionMessageEndpoint is an optional endpoint that handles the onion
+	// messages sent by this peer. If set, it'll be registered with the
+	// peer's message router, unless a global message router is used in
+	// which case the endpoint is expected to already be registered.
+	OnionMessageEndpoint fn.Option[msgmux.Endpoint]
+
 	// Quit is the server's quit channel. If this is closed, we halt operation.
 	Quit chan struct{}
 }
@@ -509,6 +529,43 @@ type Brontide struct {
 	// well as lnwire.ClosingSigned messages.
 	chanCloseMsgs chan *closeMsg
 
+	// activeSplices keeps track of the channels that are being spliced.
+	// Any splice messages are directed to the splicer of the channel. It's
+	// also read by the readHandler to route commitment signatures for a
+	// splice transaction to the splicer instead of the link.
+	activeSplices *lnutils.SyncMap[lnwire.ChannelID, *splice.Splicer]
+
+	// spliceReqs holds the local requests of splices whose transaction
+	// hasn't been broadcast yet, so they can be notified about the
+	// outcome.
+	spliceReqs map[lnwire.ChannelID]*SpliceReq
+
+	// localSpliceReqs is a channel in which any local requests to splice
+	// a particular channel are sent over, once it's quiescent.
+	localSpliceReqs chan *SpliceReq
+
+	// spliceMsgs is a channel that any splice related messages of the
+	// remote party are sent over.
+	spliceMsgs chan *spliceMsg
+
+	// spliceConfs is a channel over which the confirmations of splice
+	// transactions are signaled.
+	spliceConfs chan *spliceConf
+
+	// activeDynCommits keeps track of the channels whose dynamic
+	// commitment upgrade is being negotiated. The messages of the
+	// negotiation are handled by the readHandler, so the upgrade is
+	// applied before any commitment signature that follows it.
+	activeDynCommits *lnutils.SyncMap[lnwire.ChannelID, *dynCommit]
+
+	// localDynCommitReqs is a channel in which any local requests to
+	// upgrade a particular channel are sent over, once it's quiescent.
+	localDynCommitReqs chan *DynCommitReq
+
+	// wireChanIDs translates the channel IDs of dual funded channels
+	// between the ID we use internally and the one used on the wire.
+	wireChanIDs wireChanIDs
+
 	// remoteFeatures is the feature vector received from the peer during
 	// the connection handshake.
 	remoteFeatures *lnwire.FeatureVector
@@ -581,12 +638,23 @@ func NewBrontide(cfg Config) *Brontide {
 		localCloseChanReqs: make(chan *htlcswitch.ChanClose),
 		linkFailures:       make(chan linkFailureReport),
 		chanCloseMsgs:      make(chan *closeMsg),
-		resentChanSyncMsg:  make(map[lnwire.ChannelID]struct{}),
-		startReady:         make(chan struct{}),
-		quit:               make(chan struct{}),
-		log:                build.NewPrefixLog(logPrefix, peerLog),
-		msgRouter:          msgRouter,
-		globalMsgRouter:    globalMsgRouter,
+		activeSplices: &lnutils.SyncMap[
+			lnwire.ChannelID, *splice.Splicer,
+		]{},
+		spliceReqs:        make(map[lnwire.ChannelID]*SpliceReq),
+		localSpliceReqs:   make(chan *SpliceReq),
+		spliceMsgs:        make(chan *spliceMsg),
+		spliceConfs:       make(chan *spliceConf),
+		resentChanSyncMsg: make(map[lnwire.ChannelID]struct{}),
+		startReady:        make(chan struct{}),
+		quit:              make(chan struct{}),
+		log:               build.NewPrefixLog(logPrefix, peerLog),
+		msgRouter:         msgRouter,
+		globalMsgRouter:   globalMsgRouter,
+		activeDynCommits: &lnutils.SyncMap[
+			lnwire.ChannelID, *dynCommit,
+		]{},
+		localDynCommitReqs: make(chan *DynCommitReq),
 	}
 
 	if cfg.Conn != nil && cfg.Conn.RemoteAddr() != nil {
@@ -772,6 +840,22 @@ func (p *Brontide) Start() error {
 		router.Start()
 	})
 
+	// If we have our own message router, then we'll register the onion
+	// message endpoint with it so that the onion messages sent by this
+	// peer can be relayed.
+	if !p.globalMsgRouter {
+		var err error
+		p.cfg.OnionMessageEndpoint.WhenSome(func(e msgmux.Endpoint) {
+			p.msgRouter.WhenSome(func(r msgmux.Router) {
+				err = r.RegisterEndpoint(e)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("unable to register onion message "+
+				"endpoint: %w", err)
+		}
+	}
+
 	msgs, err := p.loadActiveChannels(activeChans)
 	if err != nil {
 		return fmt.Errorf("unable to load channels: %w", err)
@@ -887,6 +971,8 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 	scidAliasNegotiated := p.hasNegotiatedScidAlias()
 
 	for _, dbChan := range chans {
+		p.wireChanIDs.add(dbChan)
+
 		hasScidFeature := dbChan.ChanType.HasScidAliasFeature()
 		if scidAliasNegotiated && !hasScidFeature {
 			// We'll request and store an alias, making sure that a
@@ -1137,6 +1223,13 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 			return nil, shutdownInfoErr
 		}
 
+		// Resume waiting for the confirmation of a splice, before the
+		// link is started. The link remains quiescent until the
+		// splice is locked.
+		if err := p.loadSplice(lnChan); err != nil {
+			return nil, fmt.Errorf("unable to load splice: %w", err)
+		}
+
 		// Subscribe to the set of on-chain events for this channel.
 		chainEvents, err := p.cfg.ChainArb.SubscribeChannelEvents(
 			chanPoint,
@@ -1237,6 +1330,11 @@ func (p *Brontide) addLink(chanPoint *wire.OutPoint,
 		PreviouslySentShutdown:  shutdownMsg,
 		DisallowRouteBlinding:   p.cfg.DisallowRouteBlinding,
 		MaxFeeExposure:          p.cfg.MaxFeeExposure,
+		DisallowQuiescence: !p.cfg.Features.HasFeature(
+			lnwire.QuiescenceOptional,
+		) || !p.remoteFeatures.HasFeature(lnwire.QuiescenceOptional),
+		TrampolineForwarder: p.cfg.TrampolineForwarder,
+		RateLimiter:         p.cfg.HtlcRateLimiter,
 	}
 
 	// Before adding our new link, purge the switch of any pending or live
@@ -1852,6 +1950,10 @@ out:
 			}
 		}
 
+		// Dual funded channels are referred to by a different
+		// channel ID on the wire than the one we use internally.
+		p.wireChanIDs.fromWireMsg(nextMsg)
+
 		// If a message router is active, then we'll try to have it
 		// handle this message. If it can, then we're able to skip the
 		// rest of the message handling logic.
@@ -1891,6 +1993,8 @@ out:
 
 		case *lnwire.OpenChannel,
 			*lnwire.AcceptChannel,
+			*lnwire.OpenChannel2,
+			*lnwire.AcceptChannel2,
 			*lnwire.FundingCreated,
 			*lnwire.FundingSigned,
 			*lnwire.ChannelReady:
@@ -1910,6 +2014,104 @@ out:
 				break out
 			}
 
+		case *lnwire.ClosingComplete:
+			select {
+			case p.chanCloseMsgs <- &closeMsg{msg.ChannelID, msg}:
+			case <-p.quit:
+				break out
+			}
+
+		case *lnwire.ClosingSig:
+			select {
+			case p.chanCloseMsgs <- &closeMsg{msg.ChannelID, msg}:
+			case <-p.quit:
+				break out
+			}
+
+		case *lnwire.SpliceInit,
+			*lnwire.SpliceAck,
+			*lnwire.SpliceLocked,
+			*lnwire.TxAddInput,
+			*lnwire.TxAddOutput,
+			*lnwire.TxRemoveInput,
+			*lnwire.TxRemoveOutput,
+			*lnwire.TxComplete,
+			*lnwire.TxSignatures,
+			*lnwire.TxAbort:
+
+			// The interactive transaction messages are also used
+			// to negotiate the funding transaction of dual funded
+			// channels, which is done by the funding manager.
+			//
+			//nolint:forcetypeassert
+			cid := nextMsg.(lnwire.LinkUpdater).TargetChanID()
+			fundingMgr := p.cfg.FundingManager
+			if fundingMgr.IsInteractiveFunding(cid, p) {
+				fundingMgr.ProcessFundingMsg(nextMsg, p)
+				break
+			}
+
+			splcMsg := &spliceMsg{
+				cid, nextMsg, make(chan struct{}),
+			}
+			select {
+			case p.spliceMsgs <- splcMsg:
+			case <-p.quit:
+				break out
+			}
+
+			select {
+			case <-splcMsg.done:
+			case <-p.quit:
+				break out
+			}
+
+		// Dynamic commitment messages are handled right away, so an
+		// accepted upgrade is applied before we process the commitment
+		// signature the remote party sends for it.
+		case *lnwire.DynPropose:
+			p.handleDynCommitMsg(msg.ChanID, msg)
+
+		case *lnwire.DynAck:
+			p.handleDynCommitMsg(msg.ChanID, msg)
+
+		case *lnwire.DynReject:
+			p.handleDynCommitMsg(msg.ChanID, msg)
+
+		// The first commitment signatures of a dual funded channel are
+		// exchanged with the funding manager. While a splice
+		// transaction is negotiated, the channel is quiescent, so any
+		// commitment signature is for the splice transaction rather
+		// than a regular channel update.
+		case *lnwire.CommitSig:
+			fundingMgr := p.cfg.FundingManager
+			if fundingMgr.IsInteractiveFunding(msg.ChanID, p) {
+				fundingMgr.ProcessFundingMsg(msg, p)
+				break
+			}
+
+			if p.spliceNegotiating(msg.ChanID) {
+				splcMsg := &spliceMsg{
+					msg.ChanID, msg, make(chan struct{}),
+				}
+				select {
+				case p.spliceMsgs <- splcMsg:
+				case <-p.quit:
+					break out
+				}
+
+				select {
+				case <-splcMsg.done:
+				case <-p.quit:
+					break out
+				}
+
+				break
+			}
+
+			targetChan = msg.ChanID
+			isLinkUpdate = p.hasChannel(targetChan)
+
 		case *lnwire.Warning:
 			targetChan = msg.ChanID
 			isLinkUpdate = p.handleWarningOrError(targetChan, msg)
@@ -1973,6 +2175,12 @@ out:
 				p.log.Errorf("%v", err)
 			}
 
+		// If the message router didn't handle an onion message, then
+		// we don't relay onion messages, so we'll just ignore it.
+		case *lnwire.OnionMessage:
+			p.log.Debugf("Ignoring onion message, onion messages " +
+				"are not enabled")
+
 		default:
 			// If the message we received is unknown to us, store
 			// the type to track the failure.
@@ -2154,6 +2362,18 @@ func messageSummary(msg lnwire.Message) string {
 			msg.PendingChannelID[:], msg.ChannelReserve, msg.CsvDelay,
 			msg.MinAcceptDepth)
 
+	case *lnwire.OpenChannel2:
+		return fmt.Sprintf("temp_chan_id=%x, chain=%v, csv=%v, amt=%v, "+
+			"funding_fee_rate=%v, locktime=%v, flags=%v",
+			msg.PendingChannelID[:], msg.ChainHash, msg.CsvDelay,
+			msg.FundingAmount, msg.FundingFeePerKWeight,
+			msg.LockTime, msg.ChannelFlags)
+
+	case *lnwire.AcceptChannel2:
+		return fmt.Sprintf("temp_chan_id=%x, amt=%v, csv=%v, "+
+			"num_confs=%v", msg.PendingChannelID[:],
+			msg.FundingAmount, msg.CsvDelay, msg.MinAcceptDepth)
+
 	case *lnwire.FundingCreated:
 		return fmt.Sprintf("temp_chan_id=%x, chan_point=%v",
 			msg.PendingChannelID[:], msg.FundingPoint)
@@ -2173,6 +2393,13 @@ func messageSummary(msg lnwire.Message) string {
 		return fmt.Sprintf("chan_id=%v, fee_sat=%v", msg.ChannelID,
 			msg.FeeSatoshis)
 
+	case *lnwire.ClosingComplete:
+		return fmt.Sprintf("chan_id=%v, fee_sat=%v, sequence=%v",
+			msg.ChannelID, msg.FeeSatoshis, msg.Sequence)
+
+	case *lnwire.ClosingSig:
+		return fmt.Sprintf("chan_id=%v", msg.ChannelID)
+
 	case *lnwire.UpdateAddHTLC:
 		var blindingPoint []byte
 		msg.BlindingPoint.WhenSome(
@@ -2277,6 +2504,41 @@ func messageSummary(msg lnwire.Message) string {
 		return fmt.Sprintf("chan_id=%v, initiator=%v", msg.ChanID,
 			msg.Initiator)
 
+	case *lnwire.DynPropose:
+		return fmt.Sprintf("chan_id=%v, initiator=%v", msg.ChanID,
+			msg.Initiator)
+
+	case *lnwire.DynAck:
+		return fmt.Sprintf("chan_id=%v", msg.ChanID)
+
+	case *lnwire.DynReject:
+		return fmt.Sprintf("chan_id=%v", msg.ChanID)
+
+	case *lnwire.SpliceInit:
+		return fmt.Sprintf("chan_id=%v, contribution=%v, "+
+			"fee_rate=%v", msg.ChannelID, msg.FundingContribution,
+			msg.FeeRate)
+
+	case *lnwire.SpliceAck:
+		return fmt.Sprintf("chan_id=%v, contribution=%v",
+			msg.ChannelID, msg.FundingContribution)
+
+	case *lnwire.SpliceLocked:
+		return fmt.Sprintf("chan_id=%v, splice_txid=%v", msg.ChannelID,
+			msg.SpliceTxID)
+
+	case *lnwire.TxAddInput:
+		return fmt.Sprintf("chan_id=%v, serial_id=%v", msg.ChannelID,
+			msg.SerialID)
+
+	case *lnwire.TxAddOutput:
+		return fmt.Sprintf("chan_id=%v, serial_id=%v, amt=%v",
+			msg.ChannelID, msg.SerialID, msg.Amount)
+
+	case *lnwire.TxSignatures:
+		return fmt.Sprintf("chan_id=%v, txid=%v, num_witnesses=%v",
+			msg.ChannelID, msg.TxID, len(msg.Witnesses))
+
 	case *lnwire.Custom:
 		return fmt.Sprintf("type=%d", msg.Type)
 	}
@@ -2340,6 +2602,7 @@ func (p *Brontide) logWireMessage(msg lnwire.Message, read bool) {
 func (p *Brontide) writeMessage(msg lnwire.Message) error {
 	// Only log the message on the first attempt.
 	if msg != nil {
+		msg = p.wireChanIDs.toWireMsg(msg)
 		p.logWireMessage(msg, false)
 	}
 
@@ -2695,6 +2958,26 @@ out:
 		case closeMsg := <-p.chanCloseMsgs:
 			p.handleCloseMsg(closeMsg)
 
+		// We've received a local request to splice a channel that has
+		// been quiesced, so we'll kick off the splice negotiation.
+		case req := <-p.localSpliceReqs:
+			p.handleLocalSpliceReq(req)
+
+		// We've received a splice related message from the remote
+		// peer, which advances the splicer of the channel.
+		case msg := <-p.spliceMsgs:
+			p.handleSpliceMsg(msg)
+
+		// A splice transaction has sufficient confirmations, so we'll
+		// send splice_locked.
+		case conf := <-p.spliceConfs:
+			p.handleSpliceConf(conf)
+
+		// We've received a local request to upgrade a channel that has
+		// been quiesced, so we'll propose the upgrade.
+		case req := <-p.localDynCommitReqs:
+			p.handleLocalDynCommitReq(req)
+
 		// The channel reannounce delay has elapsed, broadcast the
 		// reenabled channel updates to the network. This should only
 		// fire once, so we set the reenableTimeout channel to nil to
@@ -3057,9 +3340,11 @@ func (p *Brontide) restartCoopClose(lnChan *lnwallet.LightningChannel) (
 	if err != nil && err != channeldb.ErrNoCloseTx {
 		// An error other than ErrNoCloseTx was encountered.
 		return nil, err
-	} else if err == nil {
+	} else if err == nil && !p.simpleCloseEnabled(lnChan) {
 		// This channel has already completed the coop close
-		// negotiation.
+		// negotiation. With the simple close protocol, we restart it
+		// anyway, so that the closing transaction can still be
+		// replaced.
 		return nil, nil
 	}
 
@@ -3169,6 +3454,7 @@ func (p *Brontide) createChanCloser(channel *lnwallet.LightningChannel,
 			},
 			ChainParams: &p.cfg.Wallet.Cfg.NetParams,
 			Quit:        p.quit,
+			SimpleClose: p.simpleCloseEnabled(channel),
 		},
 		deliveryScript,
 		fee,
@@ -3180,11 +3466,51 @@ func (p *Brontide) createChanCloser(channel *lnwallet.LightningChannel,
 	return chanCloser, nil
 }
 
+// simpleCloseEnabled returns true if the closing transaction of the channel
+// is negotiated with the simple close protocol, which both parties need to
+// support. Taproot channels are still closed with closing_signed, as the
+// closing signatures of those require musig2 nonces that the simple close
+// messages don't carry.
+func (p *Brontide) simpleCloseEnabled(
+	channel *lnwallet.LightningChannel) bool {
+
+	return p.cfg.Features.HasFeature(lnwire.SimpleCloseOptional) &&
+		p.remoteFeatures.HasFeature(lnwire.SimpleCloseOptional) &&
+		!channel.State().ChanType.IsTaproot()
+}
+
+// bumpCoopCloseFee replaces our closing transaction of a channel that is
+// closed with the simple close protocol with one paying the fee rate of the
+// passed close request.
+func (p *Brontide) bumpCoopCloseFee(chanCloser *chancloser.ChanCloser,
+	req *htlcswitch.ChanClose) {
+
+	closingComplete, err := chanCloser.BumpFee(req)
+	if err != nil {
+		p.log.Errorf("Unable to bump fee of coop close of "+
+			"ChannelPoint(%v): %v", req.ChanPoint, err)
+		req.Err <- err
+
+		return
+	}
+
+	p.queueMsg(closingComplete, nil)
+}
+
 // handleLocalCloseReq kicks-off the workflow to execute a cooperative or
 // forced unilateral closure of the channel initiated by a local subsystem.
 func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 	chanID := lnwire.NewChanIDFromOutPoint(*req.ChanPoint)
 
+	// If we're already cooperatively closing the channel, then a new
+	// request replaces our closing transaction with one paying the new fee
+	// rate, which is only possible with the simple close protocol.
+	chanCloser, closing := p.activeChanCloses[chanID]
+	if closing && req.CloseType == contractcourt.CloseRegular {
+		p.bumpCoopCloseFee(chanCloser, req)
+		return
+	}
+
 	channel, ok := p.activeChannels.Load(chanID)
 
 	// Though this function can't be called for pending channels, we still
@@ -3419,9 +3745,13 @@ func (p *Brontide) finalizeChanClosure(chanCloser *chancloser.ChanCloser) {
 	chanPoint := chanCloser.Channel().ChannelPoint()
 	p.WipeChannel(&chanPoint)
 
-	// Also clear the activeChanCloses map of this channel.
+	// Also clear the activeChanCloses map of this channel, unless the
+	// closing transaction may still be replaced with the simple close
+	// protocol.
 	cid := lnwire.NewChanIDFromOutPoint(chanPoint)
-	delete(p.activeChanCloses, cid)
+	if !chanCloser.SimpleClose() {
+		delete(p.activeChanCloses, cid)
+	}
 
 	// Next, we'll launch a goroutine which will request to be notified by
 	// the ChainNotifier once the closure transaction obtains a single
@@ -3454,6 +3784,30 @@ func (p *Brontide) finalizeChanClosure(chanCloser *chancloser.ChanCloser) {
 		}
 	}
 
+	// With the simple close protocol, the closing transaction may be
+	// replaced, so we'll wait for any transaction spending the funding
+	// output instead. Only the request that led to this closing
+	// transaction is notified, as any later one is notified on its own.
+	if chanCloser.SimpleClose() {
+		if closeReq == nil {
+			return
+		}
+		chanCloser.DetachCloseRequest()
+
+		fundingScript := chanCloser.Channel().FundingTxOut().PkScript
+		go waitForCoopCloseSpend(
+			chanCloser.NegotiationHeight(), notifier, errChan,
+			&chanPoint, fundingScript, func(txid chainhash.Hash) {
+				closeReq.Updates <- &ChannelCloseUpdate{
+					ClosingTxid: txid[:],
+					Success:     true,
+				}
+			},
+		)
+
+		return
+	}
+
 	go WaitForChanToClose(chanCloser.NegotiationHeight(), notifier, errChan,
 		&chanPoint, &closingTxid, closingTx.TxOut[0].PkScript, func() {
 			// Respond to the local subsystem which requested the
@@ -3507,12 +3861,46 @@ func WaitForChanToClose(bestHeight uint32, notifier chainntnfs.ChainNotifier,
 	cb()
 }
 
+// waitForCoopCloseSpend uses the passed notifier to wait until the funding
+// output of the channel is spent by a confirmed transaction, and then executes
+// the callback with the txid of the closing transaction. Any error is sent
+// over the errChan.
+func waitForCoopCloseSpend(bestHeight uint32,
+	notifier chainntnfs.ChainNotifier, errChan chan error,
+	chanPoint *wire.OutPoint, fundingScript []byte,
+	cb func(chainhash.Hash)) {
+
+	peerLog.Infof("Waiting for spend of ChannelPoint(%v) by cooperative "+
+		"close", chanPoint)
+
+	spendNtfn, err := notifier.RegisterSpendNtfn(
+		chanPoint, fundingScript, bestHeight,
+	)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	// In the case that the ChainNotifier is shutting down, all subscriber
+	// notification channels will be closed, generating a nil receive.
+	spend, ok := <-spendNtfn.Spend
+	if !ok {
+		return
+	}
+
+	peerLog.Infof("ChannelPoint(%v) is now closed at height %v",
+		chanPoint, spend.SpendingHeight)
+
+	cb(*spend.SpenderTxHash)
+}
+
 // WipeChannel removes the passed channel point from all indexes associated with
 // the peer and the switch.
 func (p *Brontide) WipeChannel(chanPoint *wire.OutPoint) {
 	chanID := lnwire.NewChanIDFromOutPoint(*chanPoint)
 
 	p.activeChannels.Delete(chanID)
+	p.wireChanIDs.remove(chanID)
 
 	// Instruct the HtlcSwitch to close this link as the channel is no
 	// longer active.
@@ -3855,7 +4243,7 @@ func (p *Brontide) StartTime() time.Time {
 // handleCloseMsg is called when a new cooperative channel closure related
 // message is received from the remote peer. We'll use this message to advance
 // the chan closer state machine.
-func (p *Brontide) handleCloseMsg(msg *closeMsg) {
+func (p *Brontide) handleCloseMsg(msg *closeMsg) { //nolint:funlen
 	link := p.fetchLinkFromKeyAndCid(msg.cid)
 
 	// We'll now fetch the matching closing state machine in order to continue,
@@ -3877,6 +4265,10 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 		return
 	}
 
+	// Keep track of the current closing transaction, so we'll know
+	// whether processing the message replaced it.
+	prevClosingTx, _ := chanCloser.ClosingTx()
+
 	handleErr := func(err error) {
 		err = fmt.Errorf("unable to process close msg: %w", err)
 		p.log.Error(err)
@@ -3934,6 +4326,11 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 		})
 
 		beginNegotiation := func() {
+			if chanCloser.SimpleClose() {
+				p.beginSimpleClose(chanCloser, msg.cid, handleErr)
+				return
+			}
+
 			oClosingSigned, err := chanCloser.BeginNegotiation()
 			if err != nil {
 				handleErr(err)
@@ -3969,13 +4366,35 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 			p.queueMsg(&msg, nil)
 		})
 
+	case *lnwire.ClosingComplete:
+		oClosingSig, err := chanCloser.ReceiveClosingComplete(*typed)
+		if err != nil {
+			handleErr(err)
+			return
+		}
+
+		oClosingSig.WhenSome(func(msg lnwire.ClosingSig) {
+			p.queueMsg(&msg, nil)
+		})
+
+	case *lnwire.ClosingSig:
+		if err := chanCloser.ReceiveClosingSig(*typed); err != nil {
+			handleErr(err)
+			return
+		}
+
 	default:
 		panic("impossible closeMsg type")
 	}
 
 	// If we haven't finished close negotiations, then we'll continue as we
-	// can't yet finalize the closure.
-	if _, err := chanCloser.ClosingTx(); err != nil {
+	// can't yet finalize the closure. With the simple close protocol, we
+	// finalize every new closing transaction, as it replaces the last one.
+	closingTx, err := chanCloser.ClosingTx()
+	if err != nil {
+		return
+	}
+	if chanCloser.SimpleClose() && prevClosingTx == closingTx {
 		return
 	}
 
@@ -3985,6 +4404,34 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 	p.finalizeChanClosure(chanCloser)
 }
 
+// beginSimpleClose starts the simple close negotiation of a flushed channel.
+// A closing_complete of the remote party that arrived before the channel was
+// flushed is handed back to the channelManager to be processed.
+func (p *Brontide) beginSimpleClose(chanCloser *chancloser.ChanCloser,
+	cid lnwire.ChannelID, handleErr func(error)) {
+
+	oClosingComplete, err := chanCloser.BeginSimpleClose()
+	if err != nil {
+		handleErr(err)
+		return
+	}
+
+	oClosingComplete.WhenSome(func(msg lnwire.ClosingComplete) {
+		p.queueMsg(&msg, nil)
+	})
+
+	chanCloser.CachedClosingComplete().WhenSome(
+		func(msg lnwire.ClosingComplete) {
+			go func() {
+				select {
+				case p.chanCloseMsgs <- &closeMsg{cid, &msg}:
+				case <-p.quit:
+				}
+			}()
+		},
+	)
+}
+
 // HandleLocalCloseChanReqs accepts a *htlcswitch.ChanClose and passes it onto
 // the channelManager goroutine, which will shut down the link and possibly
 // close the channel.
@@ -4163,6 +4610,7 @@ func (p *Brontide) addActiveChannel(c *lnpeer.NewChannel) error {
 
 	// Store the channel in the activeChannels map.
 	p.activeChannels.Store(chanID, lnChan)
+	p.wireChanIDs.add(c.OpenChannel)
 
 	p.log.Infof("New channel active ChannelPoint(%v) with peer", chanPoint)
 
@@ -4268,6 +4716,24 @@ func (p *Brontide) handleNewPendingChannel(req *newChannelMsg) {
 	// `addedChannels`.
 	p.activeChannels.Store(chanID, nil)
 	p.addedChannels.Store(chanID, struct{}{})
+
+	// Dual funded channels are referred to by a different channel ID on
+	// the wire, which we learn from the channel that was just persisted.
+	if p.remoteFeatures == nil ||
+		!p.remoteFeatures.HasFeature(lnwire.DualFundOptional) {
+
+		return
+	}
+
+	dbChan, err := p.cfg.ChannelDB.FetchChannelByID(nil, chanID)
+	if err != nil {
+		p.log.Debugf("Unable to fetch pending channel(%v): %v",
+			chanID, err)
+
+		return
+	}
+
+	p.wireChanIDs.add(dbChan)
 }
 
 // handleRemovePendingChannel takes a `newChannelMsg` request and removes it
@@ -4296,6 +4762,7 @@ func (p *Brontide) handleRemovePendingChannel(req *newChannelMsg) {
 	// Remove the record of this pending channel.
 	p.activeChannels.Delete(chanID)
 	p.addedChannels.Delete(chanID)
+	p.wireChanIDs.remove(chanID)
 }
 
 // sendLinkUpdateMsg sends a message that updates the channel to the
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
@@ -1457,3 +1458,57 @@ func TestRemovePendingChannel(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+// TestWireChanIDs asserts that the channel IDs of dual funded channels are
+// translated between the ID used internally and the one used on the wire,
+// while the IDs of other channels are left untouched.
+func TestWireChanIDs(t *testing.T) {
+	t.Parallel()
+
+	localKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remoteKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	dualFunded := &channeldb.OpenChannel{
+		ChanType:        channeldb.DualFunderBit,
+		FundingOutpoint: wire.OutPoint{Index: 1},
+	}
+	dualFunded.LocalChanCfg.RevocationBasePoint.PubKey = localKey.PubKey()
+	dualFunded.RemoteChanCfg.RevocationBasePoint.PubKey =
+		remoteKey.PubKey()
+
+	singleFunded := &channeldb.OpenChannel{
+		ChanType:        channeldb.SingleFunderTweaklessBit,
+		FundingOutpoint: wire.OutPoint{Index: 2},
+	}
+
+	var ids wireChanIDs
+	ids.add(dualFunded)
+	ids.add(singleFunded)
+
+	chanID := lnwire.NewChanIDFromOutPoint(dualFunded.FundingOutpoint)
+	wireID := lnwire.NewChanIDFromRevocationBasepoints(
+		localKey.PubKey(), remoteKey.PubKey(),
+	)
+
+	// Outgoing messages are copied, so the sender's message keeps our
+	// internal channel ID.
+	msg := &lnwire.CommitSig{ChanID: chanID}
+	wireMsg := ids.toWireMsg(msg)
+	require.Equal(t, wireID, wireMsg.(*lnwire.CommitSig).ChanID)
+	require.Equal(t, chanID, msg.ChanID)
+
+	// Incoming messages are translated in place.
+	ids.fromWireMsg(wireMsg)
+	require.Equal(t, chanID, wireMsg.(*lnwire.CommitSig).ChanID)
+
+	// Single funded channels use the same ID internally and on the wire.
+	singleID := lnwire.NewChanIDFromOutPoint(singleFunded.FundingOutpoint)
+	singleMsg := &lnwire.Stfu{ChanID: singleID}
+	require.Same(t, singleMsg, ids.toWireMsg(singleMsg))
+
+	// Once the channel is removed, its ID isn't translated anymore.
+	ids.remove(chanID)
+	require.Same(t, msg, ids.toWireMsg(msg))
+}
@@ -0,0 +1,279 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnpeer"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/dyncomm"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// ErrDynCommitNotSupported is returned when a dynamic commitment upgrade is
+// requested, but either we or the remote party don't support it.
+var ErrDynCommitNotSupported = errors.New("dynamic commitments not " +
+	"supported")
+
+// DynCommitReq is a request to upgrade the parameters or the type of a
+// channel with the peer.
+type DynCommitReq struct {
+	// ChanPoint is the channel point of the channel that is upgraded.
+	ChanPoint wire.OutPoint
+
+	// Params are the parameters of the channel that are changed.
+	Params dyncomm.Request
+
+	// Err receives the outcome of the upgrade, which is nil once the
+	// upgrade was applied. It must be buffered.
+	Err chan error
+}
+
+// dynCommit is a dynamic commitment upgrade that is being negotiated.
+type dynCommit struct {
+	negotiator *dyncomm.Negotiator
+
+	// req is the local request of the upgrade, which is nil if the remote
+	// party initiated it.
+	req *DynCommitReq
+}
+
+// checkUpgradable returns an error if the channel can't be upgraded.
+func (p *Brontide) checkUpgradable(chanID lnwire.ChannelID) (
+	*lnwallet.LightningChannel, error) {
+
+	if !p.cfg.Features.HasFeature(lnwire.DynCommitmentsOptional) ||
+		!p.remoteFeatures.HasFeature(lnwire.DynCommitmentsOptional) {
+
+		return nil, ErrDynCommitNotSupported
+	}
+
+	lnChan, ok := p.activeChannels.Load(chanID)
+	if !ok || lnChan == nil {
+		return nil, fmt.Errorf("channel %v not active", chanID)
+	}
+
+	if _, ok := p.activeDynCommits.Load(chanID); ok {
+		return nil, dyncomm.ErrNegotiationInProgress
+	}
+
+	if _, ok := p.activeSplices.Load(chanID); ok {
+		return nil, fmt.Errorf("channel %v is being spliced", chanID)
+	}
+
+	return lnChan, nil
+}
+
+// UpgradeChannel quiesces the channel and proposes a dynamic commitment
+// upgrade of it. The outcome of the upgrade is delivered over the error
+// channel of the request.
+func (p *Brontide) UpgradeChannel(req *DynCommitReq) error {
+	chanID := lnwire.NewChanIDFromOutPoint(req.ChanPoint)
+	if _, err := p.checkUpgradable(chanID); err != nil {
+		return err
+	}
+
+	link := p.fetchLinkFromKeyAndCid(chanID)
+	if link == nil {
+		return fmt.Errorf("link for channel %v not found", chanID)
+	}
+
+	// The parameters of the channel may only change while there are no
+	// pending updates, so we'll wait until the channel is quiescent.
+	select {
+	case res := <-link.InitStfu():
+		initiator, err := res.Unpack()
+		if err != nil {
+			return err
+		}
+
+		// If both parties initiated quiescence at the same time, the
+		// channel opener wins and is the one to propose an upgrade.
+		if initiator != lntypes.Local {
+			return fmt.Errorf("quiescence of channel %v initiated "+
+				"by remote party", chanID)
+		}
+
+	case <-p.quit:
+		return lnpeer.ErrPeerExiting
+	}
+
+	select {
+	case p.localDynCommitReqs <- req:
+		return nil
+
+	case <-p.quit:
+		return lnpeer.ErrPeerExiting
+	}
+}
+
+// dynCommitCfg returns the config of a negotiator for the passed channel.
+func (p *Brontide) dynCommitCfg(
+	lnChan *lnwallet.LightningChannel) dyncomm.Config {
+
+	return dyncomm.Config{
+		Channel:          lnChan,
+		MaxLocalCSVDelay: p.cfg.MaxLocalCSVDelay,
+		LocalFeatures:    p.cfg.Features,
+		RemoteFeatures:   p.remoteFeatures,
+	}
+}
+
+// handleLocalDynCommitReq proposes the upgrade of a channel that has been
+// quiesced on our request.
+func (p *Brontide) handleLocalDynCommitReq(req *DynCommitReq) {
+	chanID := lnwire.NewChanIDFromOutPoint(req.ChanPoint)
+
+	err := p.startDynCommit(chanID, req)
+	if err == nil {
+		return
+	}
+
+	p.log.Errorf("Unable to upgrade ChannelPoint(%v): %v", req.ChanPoint,
+		err)
+
+	if link := p.fetchLinkFromKeyAndCid(chanID); link != nil {
+		link.ResumeUpdates()
+	}
+
+	req.Err <- err
+}
+
+// startDynCommit creates a negotiator for the channel, and sends dyn_propose
+// to the remote party.
+func (p *Brontide) startDynCommit(chanID lnwire.ChannelID,
+	req *DynCommitReq) error {
+
+	lnChan, err := p.checkUpgradable(chanID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := p.activeChanCloses[chanID]; ok {
+		return fmt.Errorf("channel %v is being closed", chanID)
+	}
+
+	negotiator := dyncomm.NewNegotiator(p.dynCommitCfg(lnChan))
+	propose, err := negotiator.Initiate(req.Params)
+	if err != nil {
+		return err
+	}
+
+	p.activeDynCommits.Store(chanID, &dynCommit{
+		negotiator: negotiator,
+		req:        req,
+	})
+	p.queueMsg(propose, nil)
+
+	return nil
+}
+
+// handleDynCommitMsg advances the dynamic commitment negotiation of a channel
+// with a message of the remote party. Once the negotiation is done, the link
+// resumes updates, and signs a new commitment if the upgrade was applied.
+//
+// A dyn_ack that is lost because the connection drops right after it was sent
+// leaves the responder with an upgraded channel. The initiator recorded its
+// proposal, which it applies once the responder reports the upgrade in its
+// channel_reestablish message.
+func (p *Brontide) handleDynCommitMsg(chanID lnwire.ChannelID,
+	msg lnwire.Message) {
+
+	dc, ok := p.activeDynCommits.Load(chanID)
+	if !ok {
+		if _, isPropose := msg.(*lnwire.DynPropose); !isPropose {
+			p.log.Warnf("Ignoring %v for ChannelPoint(%v) without "+
+				"active negotiation", msg.MsgType(), chanID)
+
+			return
+		}
+
+		var err error
+		dc, err = p.handleRemoteDynPropose(chanID)
+		if err != nil {
+			p.log.Warnf("Unable to handle dyn_propose for "+
+				"ChannelPoint(%v): %v", chanID, err)
+
+			p.queueMsg(&lnwire.DynReject{
+				ChanID:           chanID,
+				UpdateRejections: *lnwire.NewRawFeatureVector(),
+			}, nil)
+
+			// If the remote party quiesced the channel to upgrade
+			// it, we'll resume updates now that the upgrade was
+			// rejected.
+			link := p.fetchLinkFromKeyAndCid(chanID)
+			if link != nil {
+				link.ResumeUpdates()
+			}
+
+			return
+		}
+	}
+
+	msgs, err := dc.negotiator.ProcessMsg(msg)
+	for _, msg := range msgs {
+		p.queueMsg(msg, nil)
+	}
+
+	if err == nil && !dc.negotiator.Done() {
+		return
+	}
+
+	p.activeDynCommits.Delete(chanID)
+
+	switch {
+	case err != nil:
+		p.log.Errorf("Dynamic commitment upgrade of ChannelPoint(%v) "+
+			"failed: %v", chanID, err)
+
+	case dc.negotiator.Applied():
+		p.log.Infof("Upgraded ChannelPoint(%v) with dynamic "+
+			"commitment", chanID)
+	}
+
+	// The link signs the first commitment with the upgraded parameters
+	// once it resumes updates.
+	if link := p.fetchLinkFromKeyAndCid(chanID); link != nil {
+		link.ResumeUpdates()
+	}
+
+	if dc.req != nil {
+		dc.req.Err <- err
+	}
+}
+
+// handleRemoteDynPropose creates a negotiator for an upgrade proposed by the
+// remote party, which requires the channel to have been quiesced on their
+// request.
+func (p *Brontide) handleRemoteDynPropose(
+	chanID lnwire.ChannelID) (*dynCommit, error) {
+
+	lnChan, err := p.checkUpgradable(chanID)
+	if err != nil {
+		return nil, err
+	}
+
+	link := p.fetchLinkFromKeyAndCid(chanID)
+	if link == nil {
+		return nil, fmt.Errorf("link not found")
+	}
+
+	initiator, err := link.QuiescenceInitiator().Unpack()
+	if err != nil {
+		return nil, err
+	}
+	if initiator != lntypes.Remote {
+		return nil, fmt.Errorf("dyn_propose received, but quiescence "+
+			"initiated by %v", initiator)
+	}
+
+	dc := &dynCommit{
+		negotiator: dyncomm.NewNegotiator(p.dynCommitCfg(lnChan)),
+	}
+	p.activeDynCommits.Store(chanID, dc)
+
+	return dc, nil
+}
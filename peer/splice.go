@@ -0,0 +1,575 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnpeer"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwallet/splice"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// ErrSpliceNotSupported is returned when a splice is requested, but
+	// either we or the remote party don't support splicing.
+	ErrSpliceNotSupported = errors.New("splicing not supported")
+
+	// ErrSplicePublicChannel is returned when a splice is requested for a
+	// public channel. Splicing public channels isn't supported yet, as
+	// the spliced channel would need to be re-announced.
+	ErrSplicePublicChannel = errors.New("splicing public channels is " +
+		"not supported")
+)
+
+// SpliceReq is a request to splice funds into or out of a channel with the
+// peer.
+type SpliceReq struct {
+	// ChanPoint is the channel point of the channel that is spliced.
+	ChanPoint wire.OutPoint
+
+	// Amount is the amount the channel's capacity changes by. It's
+	// positive for a splice-in, and negative for a splice-out.
+	Amount btcutil.Amount
+
+	// FeeRate is the fee rate of the splice transaction.
+	FeeRate chainfee.SatPerKWeight
+
+	// DeliveryScript is the script the funds of a splice-out are sent to.
+	// If it isn't set, the funds are sent to a new wallet address.
+	DeliveryScript []byte
+
+	// Txid receives the txid of the splice transaction once it's been
+	// broadcast. It must be buffered.
+	Txid chan chainhash.Hash
+
+	// Err receives an error if the splice fails before its transaction
+	// was broadcast. It must be buffered.
+	Err chan error
+}
+
+// spliceMsg is a splice related message of the remote party.
+type spliceMsg struct {
+	cid lnwire.ChannelID
+	msg lnwire.Message
+
+	// done is closed once the message has been handled. The readHandler
+	// waits for it, so the channel updates of the remote party that
+	// follow tx_signatures or splice_locked are only processed once
+	// updates were resumed or the splice was promoted.
+	done chan struct{}
+}
+
+// spliceConf signals that a splice transaction has sufficient confirmations.
+type spliceConf struct {
+	cid  lnwire.ChannelID
+	txid chainhash.Hash
+}
+
+// checkSpliceable returns an error if the channel can't be spliced. Splicing
+// is experimental, so both parties must signal the experimental splice
+// feature bits, and only private, non-taproot channels can be spliced. The
+// splicer additionally rejects any contributions of the acceptor.
+func (p *Brontide) checkSpliceable(chanID lnwire.ChannelID) (
+	*lnwallet.LightningChannel, error) {
+
+	if !p.cfg.Features.HasFeature(lnwire.SpliceOptional) ||
+		!p.remoteFeatures.HasFeature(lnwire.SpliceOptional) {
+
+		return nil, ErrSpliceNotSupported
+	}
+
+	lnChan, ok := p.activeChannels.Load(chanID)
+	if !ok || lnChan == nil {
+		return nil, fmt.Errorf("channel %v not active", chanID)
+	}
+
+	if lnChan.State().ChannelFlags&lnwire.FFAnnounceChannel != 0 {
+		return nil, ErrSplicePublicChannel
+	}
+
+	if lnChan.State().ChanType.IsTaproot() {
+		return nil, lnwallet.ErrSpliceUnsupportedChanType
+	}
+
+	if _, ok := p.activeSplices.Load(chanID); ok {
+		return nil, splice.ErrSpliceInProgress
+	}
+
+	return lnChan, nil
+}
+
+// Splice quiesces the channel and starts splicing it. The channel remains
+// quiescent until the splice transaction is broadcast. The result of the
+// splice is delivered over the channels of the request.
+func (p *Brontide) Splice(req *SpliceReq) error {
+	chanID := lnwire.NewChanIDFromOutPoint(req.ChanPoint)
+	if _, err := p.checkSpliceable(chanID); err != nil {
+		return err
+	}
+
+	link := p.fetchLinkFromKeyAndCid(chanID)
+	if link == nil {
+		return fmt.Errorf("link for channel %v not found", chanID)
+	}
+
+	// No updates may be sent over the channel while the splice
+	// transaction is negotiated, so we'll wait until the channel is
+	// quiescent.
+	select {
+	case res := <-link.InitStfu():
+		initiator, err := res.Unpack()
+		if err != nil {
+			return err
+		}
+
+		// If both parties initiated quiescence at the same time, the
+		// channel opener wins and is the one to start the splice.
+		if initiator != lntypes.Local {
+			return fmt.Errorf("quiescence of channel %v initiated "+
+				"by remote party", chanID)
+		}
+
+	case <-p.quit:
+		return lnpeer.ErrPeerExiting
+	}
+
+	select {
+	case p.localSpliceReqs <- req:
+		return nil
+
+	case <-p.quit:
+		return lnpeer.ErrPeerExiting
+	}
+}
+
+// spliceCfg returns the config of a splicer for the passed channel.
+func (p *Brontide) spliceCfg(lnChan *lnwallet.LightningChannel) splice.Config {
+	wallet := p.cfg.Wallet
+
+	return splice.Config{
+		Channel: lnChan,
+		Wallet: splice.NewWallet(splice.WalletConfig{
+			CoinSource: lnwallet.NewCoinSource(wallet, nil),
+			CoinSelectionStrategy: wallet.Cfg.
+				CoinSelectionStrategy,
+			CoinSelectLocker: wallet,
+			CoinLeaser:       wallet,
+			Signer:           wallet.Cfg.Signer,
+			FetchTx:          wallet.FetchTx,
+			ChangeScript: func() ([]byte, error) {
+				addr, err := wallet.NewAddress(
+					lnwallet.TaprootPubkey, true,
+					lnwallet.DefaultAccountName,
+				)
+				if err != nil {
+					return nil, err
+				}
+
+				return txscript.PayToAddrScript(addr)
+			},
+			PublishTransaction: wallet.PublishTransaction,
+			MinConfs:           1,
+			DustLimit: lnwallet.DustLimitForSize(
+				input.P2TRSize,
+			),
+		}),
+		BestHeight: p.cfg.BestBlockView.BestHeight,
+	}
+}
+
+// handleLocalSpliceReq starts the splice of a channel that has been quiesced
+// on our request.
+func (p *Brontide) handleLocalSpliceReq(req *SpliceReq) {
+	chanID := lnwire.NewChanIDFromOutPoint(req.ChanPoint)
+
+	err := p.startSplice(chanID, req)
+	if err == nil {
+		return
+	}
+
+	p.log.Errorf("Unable to splice ChannelPoint(%v): %v", req.ChanPoint,
+		err)
+
+	if link := p.fetchLinkFromKeyAndCid(chanID); link != nil {
+		link.ResumeUpdates()
+	}
+
+	req.Err <- err
+}
+
+// startSplice creates a splicer for the channel, and sends splice_init to the
+// remote party.
+func (p *Brontide) startSplice(chanID lnwire.ChannelID, req *SpliceReq) error {
+	lnChan, err := p.checkSpliceable(chanID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := p.activeChanCloses[chanID]; ok {
+		return fmt.Errorf("channel %v is being closed", chanID)
+	}
+
+	deliveryScript := req.DeliveryScript
+	if req.Amount < 0 && len(deliveryScript) == 0 {
+		deliveryScript, err = p.genDeliveryScript()
+		if err != nil {
+			return err
+		}
+	}
+
+	splicer := splice.NewSplicer(p.spliceCfg(lnChan))
+	spliceInit, err := splicer.Initiate(splice.Request{
+		Amount:         req.Amount,
+		FeeRate:        req.FeeRate,
+		DeliveryScript: deliveryScript,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.activeSplices.Store(chanID, splicer)
+	p.spliceReqs[chanID] = req
+	p.queueMsg(spliceInit, nil)
+
+	return nil
+}
+
+// spliceNegotiating returns true if a splice transaction is negotiated for
+// the channel. During that time, the channel is quiescent, so any commitment
+// signature the remote party sends is for the splice transaction. Once the
+// splice transaction is broadcast, commitment signatures are regular channel
+// updates again, which cover the pending splice as well.
+func (p *Brontide) spliceNegotiating(chanID lnwire.ChannelID) bool {
+	splicer, ok := p.activeSplices.Load(chanID)
+
+	return ok && !splicer.IsBroadcast()
+}
+
+// handleSpliceMsg advances the splicer of a channel with a message of the
+// remote party.
+func (p *Brontide) handleSpliceMsg(msg *spliceMsg) {
+	defer close(msg.done)
+
+	splicer, ok := p.activeSplices.Load(msg.cid)
+	if !ok {
+		var err error
+		splicer, err = p.handleMsgWithoutSplice(msg)
+		if err != nil {
+			p.log.Warnf("Unable to handle %v for "+
+				"ChannelPoint(%v): %v", msg.msg.MsgType(),
+				msg.cid, err)
+
+			p.queueMsg(&lnwire.TxAbort{
+				ChannelID: msg.cid,
+				Data:      []byte(err.Error()),
+			}, nil)
+
+			// The remote party quiesced the channel to splice it,
+			// so we'll resume updates now that the splice failed.
+			link := p.fetchLinkFromKeyAndCid(msg.cid)
+			if link != nil {
+				link.ResumeUpdates()
+			}
+
+			return
+		}
+
+		// The message didn't start a new splice, so there's nothing
+		// left to do.
+		if splicer == nil {
+			return
+		}
+	}
+
+	wasBroadcast := splicer.IsBroadcast()
+
+	msgs, err := splicer.ProcessMsg(msg.msg)
+	if err != nil {
+		p.abortSplice(msg.cid, splicer, err)
+		return
+	}
+
+	for _, msg := range msgs {
+		p.queueMsg(msg, nil)
+	}
+
+	if !wasBroadcast && splicer.IsBroadcast() {
+		p.onSpliceBroadcast(msg.cid, splicer)
+	}
+
+	if splicer.IsLocked() {
+		p.finalizeSplice(msg.cid, splicer)
+	}
+}
+
+// handleMsgWithoutSplice handles a splice message for a channel that isn't
+// being spliced. A new splicer is returned if the remote party initiated a
+// splice.
+func (p *Brontide) handleMsgWithoutSplice(msg *spliceMsg) (*splice.Splicer,
+	error) {
+
+	switch m := msg.msg.(type) {
+	// The remote party initiates a splice, which requires the channel to
+	// have been quiesced on their request.
+	case *lnwire.SpliceInit:
+		lnChan, err := p.checkSpliceable(msg.cid)
+		if err != nil {
+			return nil, err
+		}
+
+		link := p.fetchLinkFromKeyAndCid(msg.cid)
+		if link == nil {
+			return nil, fmt.Errorf("link not found")
+		}
+
+		initiator, err := link.QuiescenceInitiator().Unpack()
+		if err != nil {
+			return nil, err
+		}
+		if initiator != lntypes.Remote {
+			return nil, fmt.Errorf("splice_init received, but "+
+				"quiescence initiated by %v", initiator)
+		}
+
+		splicer := splice.NewSplicer(p.spliceCfg(lnChan))
+		p.activeSplices.Store(msg.cid, splicer)
+
+		return splicer, nil
+
+	// The remote party may resend splice_locked after we already promoted
+	// the splice, for example if the chain watcher promoted it while we
+	// were offline. We respond with our own, so they can finalize the
+	// splice as well.
+	case *lnwire.SpliceLocked:
+		lnChan, ok := p.activeChannels.Load(msg.cid)
+		if ok && lnChan != nil &&
+			lnChan.State().CurrentFundingOutpoint().Hash ==
+				m.SpliceTxID {
+
+			p.queueMsg(&lnwire.SpliceLocked{
+				ChannelID:  msg.cid,
+				SpliceTxID: m.SpliceTxID,
+			}, nil)
+		}
+
+		return nil, nil
+
+	// An abort for a splice we already aborted is expected, as both
+	// parties send tx_abort.
+	case *lnwire.TxAbort:
+		return nil, nil
+
+	default:
+		p.log.Warnf("Ignoring %v for ChannelPoint(%v) without active "+
+			"splice", msg.msg.MsgType(), msg.cid)
+
+		return nil, nil
+	}
+}
+
+// abortSplice aborts the splice of a channel, and lets the link resume
+// updates. A splice whose transaction was broadcast can't be aborted anymore.
+func (p *Brontide) abortSplice(chanID lnwire.ChannelID,
+	splicer *splice.Splicer, reason error) {
+
+	p.log.Errorf("Splice of ChannelPoint(%v) failed: %v", chanID, reason)
+
+	abort, err := splicer.Abort(reason)
+	if err != nil {
+		p.log.Errorf("Unable to abort splice of ChannelPoint(%v): %v",
+			chanID, err)
+
+		return
+	}
+
+	p.queueMsg(abort, nil)
+	p.activeSplices.Delete(chanID)
+
+	if req, ok := p.spliceReqs[chanID]; ok {
+		req.Err <- reason
+		delete(p.spliceReqs, chanID)
+	}
+
+	if link := p.fetchLinkFromKeyAndCid(chanID); link != nil {
+		link.ResumeUpdates()
+	}
+}
+
+// onSpliceBroadcast notifies the requester of a splice about its broadcast,
+// resumes updates of the channel, and waits for the splice transaction to
+// confirm. Until then, every new commitment also gets a variant that spends
+// the funding output of the splice transaction.
+func (p *Brontide) onSpliceBroadcast(chanID lnwire.ChannelID,
+	splicer *splice.Splicer) {
+
+	spliceTx, err := splicer.SpliceTx().UnwrapOrErr(
+		errors.New("splice transaction unknown"),
+	)
+	if err != nil {
+		p.log.Errorf("Unable to watch splice: %v", err)
+		return
+	}
+	txid := spliceTx.TxHash()
+
+	if req, ok := p.spliceReqs[chanID]; ok {
+		req.Txid <- txid
+		delete(p.spliceReqs, chanID)
+	}
+
+	if link := p.fetchLinkFromKeyAndCid(chanID); link != nil {
+		link.ResumeUpdates()
+	}
+
+	height, err := p.cfg.BestBlockView.BestHeight()
+	if err != nil {
+		p.log.Errorf("Unable to fetch best height: %v", err)
+		return
+	}
+
+	lnChan, ok := p.activeChannels.Load(chanID)
+	if !ok || lnChan == nil {
+		p.log.Errorf("Unable to watch splice %v: channel %v not "+
+			"active", txid, chanID)
+
+		return
+	}
+
+	err = p.watchSplice(chanID, lnChan, spliceTx, height)
+	if err != nil {
+		p.log.Errorf("Unable to watch splice %v: %v", txid, err)
+	}
+}
+
+// watchSplice waits for the splice transaction to reach the confirmation
+// depth of the channel, and then notifies the channelManager.
+func (p *Brontide) watchSplice(chanID lnwire.ChannelID,
+	lnChan *lnwallet.LightningChannel, spliceTx *wire.MsgTx,
+	heightHint uint32) error {
+
+	// A splice doesn't change the funding keys, so the new funding output
+	// pays to the same script as the current one.
+	numConfs := max(uint32(lnChan.State().NumConfsRequired), 1)
+	txid := spliceTx.TxHash()
+	confNtfn, err := p.cfg.ChainNotifier.RegisterConfirmationsNtfn(
+		&txid, lnChan.FundingTxOut().PkScript, numConfs, heightHint,
+	)
+	if err != nil {
+		return err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer confNtfn.Cancel()
+
+		select {
+		case _, ok := <-confNtfn.Confirmed:
+			if !ok {
+				return
+			}
+
+		case <-p.quit:
+			return
+		}
+
+		select {
+		case p.spliceConfs <- &spliceConf{cid: chanID, txid: txid}:
+		case <-p.quit:
+		}
+	}()
+
+	return nil
+}
+
+// handleSpliceConf sends splice_locked once the splice transaction of a
+// channel has sufficient confirmations.
+func (p *Brontide) handleSpliceConf(conf *spliceConf) {
+	splicer, ok := p.activeSplices.Load(conf.cid)
+	if !ok {
+		return
+	}
+
+	spliceLocked, err := splicer.SpliceConfirmed(conf.txid)
+	if err != nil {
+		p.log.Errorf("Unable to lock splice %v: %v", conf.txid, err)
+		return
+	}
+	p.queueMsg(spliceLocked, nil)
+
+	if splicer.IsLocked() {
+		p.finalizeSplice(conf.cid, splicer)
+	}
+}
+
+// finalizeSplice switches the channel over to the funding output of the
+// splice once both parties sent splice_locked.
+func (p *Brontide) finalizeSplice(chanID lnwire.ChannelID,
+	splicer *splice.Splicer) {
+
+	p.activeSplices.Delete(chanID)
+
+	spliceTx, err := splicer.SpliceTx().UnwrapOrErr(
+		errors.New("splice transaction unknown"),
+	)
+	if err != nil {
+		p.log.Errorf("Unable to finalize splice: %v", err)
+		return
+	}
+	txid := spliceTx.TxHash()
+
+	lnChan, ok := p.activeChannels.Load(chanID)
+	if !ok || lnChan == nil {
+		p.log.Errorf("Unable to finalize splice %v: channel %v not "+
+			"active", txid, chanID)
+
+		return
+	}
+
+	if err := lnChan.PromoteSplice(txid); err != nil {
+		p.log.Errorf("Unable to promote splice %v: %v", txid, err)
+		return
+	}
+
+	p.log.Infof("ChannelPoint(%v) spliced with transaction %v, new "+
+		"capacity=%v", lnChan.ChannelPoint(), txid, lnChan.Capacity)
+}
+
+// loadSplice drops the splice candidates of a channel whose transaction
+// hasn't been broadcast, as their negotiation can't be resumed. A splice
+// whose transaction was broadcast is resumed, and waits for the transaction
+// to confirm.
+func (p *Brontide) loadSplice(lnChan *lnwallet.LightningChannel) error {
+	chanState := lnChan.State()
+	candidates, err := chanState.SpliceCandidates()
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	candidate := candidates[len(candidates)-1]
+	if candidate.BroadcastHeight == 0 {
+		p.log.Infof("Dropping unbroadcast splice of ChannelPoint(%v)",
+			chanState.FundingOutpoint)
+
+		return lnChan.RemoveSpliceCandidates()
+	}
+
+	chanID := lnwire.NewChanIDFromOutPoint(chanState.FundingOutpoint)
+	splicer := splice.ResumeSplicer(p.spliceCfg(lnChan), candidate)
+	p.activeSplices.Store(chanID, splicer)
+
+	return p.watchSplice(
+		chanID, lnChan, candidate.FundingTx, candidate.BroadcastHeight,
+	)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lntest/channels"
 	"github.com/lightningnetwork/lnd/lntest/mock"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -408,6 +409,11 @@ func (m *mockUpdateHandler) EligibleToForward() bool { return false }
 // MayAddOutgoingHtlc currently returns nil.
 func (m *mockUpdateHandler) MayAddOutgoingHtlc(lnwire.MilliSatoshi) error { return nil }
 
+// AvailableOutgoingHtlcSlots currently returns zero slots.
+func (m *mockUpdateHandler) AvailableOutgoingHtlcSlots() (uint16, error) {
+	return 0, nil
+}
+
 type mockMessageConn struct {
 	t *testing.T
 
@@ -470,6 +476,21 @@ func (m *mockUpdateHandler) OnCommitOnce(
 	hook()
 }
 
+func (m *mockUpdateHandler) InitStfu() <-chan fn.Result[lntypes.ChannelParty] {
+	out := make(chan fn.Result[lntypes.ChannelParty], 1)
+	out <- fn.Ok(lntypes.Local)
+
+	return out
+}
+
+func (m *mockUpdateHandler) QuiescenceInitiator() (
+	r fn.Result[lntypes.ChannelParty]) {
+
+	return fn.Ok(lntypes.Local)
+}
+
+func (m *mockUpdateHandler) ResumeUpdates() {}
+
 func newMockConn(t *testing.T, expectedMessages int) *mockMessageConn {
 	return &mockMessageConn{
 		t:               t,
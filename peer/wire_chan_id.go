@@ -0,0 +1,162 @@
+package peer
+
+import (
+	"reflect"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnutils"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// wireChanIDs maps the channel IDs we use internally to the channel IDs a
+// channel is referred to by on the wire, and back. Internally, every channel
+// is identified by the channel ID derived from its funding outpoint, while
+// dual funded channels are identified on the wire by the channel ID derived
+// from the revocation basepoints of both parties.
+type wireChanIDs struct {
+	// toWire maps an internal channel ID to its wire channel ID.
+	toWire lnutils.SyncMap[lnwire.ChannelID, lnwire.ChannelID]
+
+	// fromWire maps a wire channel ID to its internal channel ID.
+	fromWire lnutils.SyncMap[lnwire.ChannelID, lnwire.ChannelID]
+}
+
+// add registers the wire channel ID of the passed channel if it differs from
+// the channel ID we use internally.
+func (w *wireChanIDs) add(c *channeldb.OpenChannel) {
+	if !c.ChanType.IsDualFunder() {
+		return
+	}
+
+	chanID := lnwire.NewChanIDFromOutPoint(c.FundingOutpoint)
+	wireID := lnwire.NewChanIDFromRevocationBasepoints(
+		c.LocalChanCfg.RevocationBasePoint.PubKey,
+		c.RemoteChanCfg.RevocationBasePoint.PubKey,
+	)
+
+	w.toWire.Store(chanID, wireID)
+	w.fromWire.Store(wireID, chanID)
+}
+
+// remove forgets the wire channel ID of the channel with the passed internal
+// channel ID.
+func (w *wireChanIDs) remove(chanID lnwire.ChannelID) {
+	wireID, ok := w.toWire.LoadAndDelete(chanID)
+	if !ok {
+		return
+	}
+
+	w.fromWire.Delete(wireID)
+}
+
+// fromWireMsg replaces the wire channel ID of an incoming message with the
+// channel ID we use internally. The message is modified in place.
+func (w *wireChanIDs) fromWireMsg(msg lnwire.Message) {
+	chanID := msgChanID(msg)
+	if chanID == nil {
+		return
+	}
+
+	if internalID, ok := w.fromWire.Load(*chanID); ok {
+		*chanID = internalID
+	}
+}
+
+// toWireMsg returns the passed outgoing message with our internal channel ID
+// replaced by the wire channel ID. As the message may still be referenced by
+// its sender, a copy is returned instead of modifying it in place.
+func (w *wireChanIDs) toWireMsg(msg lnwire.Message) lnwire.Message {
+	chanID := msgChanID(msg)
+	if chanID == nil {
+		return msg
+	}
+
+	wireID, ok := w.toWire.Load(*chanID)
+	if !ok {
+		return msg
+	}
+
+	// All channel scoped messages are pointers to structs, so a shallow
+	// copy is enough to leave the channel ID of the original untouched.
+	msgVal := reflect.ValueOf(msg).Elem()
+	msgCopy := reflect.New(msgVal.Type())
+	msgCopy.Elem().Set(msgVal)
+
+	wireMsg, _ := msgCopy.Interface().(lnwire.Message)
+	*msgChanID(wireMsg) = wireID
+
+	return wireMsg
+}
+
+// msgChanID returns a pointer to the channel ID of the passed message, or nil
+// if the message isn't scoped to an open channel.
+//
+//nolint:gocyclo
+func msgChanID(msg lnwire.Message) *lnwire.ChannelID {
+	switch m := msg.(type) {
+	case *lnwire.AnnounceSignatures:
+		return &m.ChannelID
+	case *lnwire.ChannelReady:
+		return &m.ChanID
+	case *lnwire.ChannelReestablish:
+		return &m.ChanID
+	case *lnwire.ClosingComplete:
+		return &m.ChannelID
+	case *lnwire.ClosingSig:
+		return &m.ChannelID
+	case *lnwire.ClosingSigned:
+		return &m.ChannelID
+	case *lnwire.CommitSig:
+		return &m.ChanID
+	case *lnwire.DynAck:
+		return &m.ChanID
+	case *lnwire.DynPropose:
+		return &m.ChanID
+	case *lnwire.DynReject:
+		return &m.ChanID
+	case *lnwire.Error:
+		return &m.ChanID
+	case *lnwire.KickoffSig:
+		return &m.ChanID
+	case *lnwire.RevokeAndAck:
+		return &m.ChanID
+	case *lnwire.Shutdown:
+		return &m.ChannelID
+	case *lnwire.SpliceAck:
+		return &m.ChannelID
+	case *lnwire.SpliceInit:
+		return &m.ChannelID
+	case *lnwire.SpliceLocked:
+		return &m.ChannelID
+	case *lnwire.Stfu:
+		return &m.ChanID
+	case *lnwire.TxAbort:
+		return &m.ChannelID
+	case *lnwire.TxAddInput:
+		return &m.ChannelID
+	case *lnwire.TxAddOutput:
+		return &m.ChannelID
+	case *lnwire.TxComplete:
+		return &m.ChannelID
+	case *lnwire.TxRemoveInput:
+		return &m.ChannelID
+	case *lnwire.TxRemoveOutput:
+		return &m.ChannelID
+	case *lnwire.TxSignatures:
+		return &m.ChannelID
+	case *lnwire.UpdateAddHTLC:
+		return &m.ChanID
+	case *lnwire.UpdateFailHTLC:
+		return &m.ChanID
+	case *lnwire.UpdateFailMalformedHTLC:
+		return &m.ChanID
+	case *lnwire.UpdateFee:
+		return &m.ChanID
+	case *lnwire.UpdateFulfillHTLC:
+		return &m.ChanID
+	case *lnwire.Warning:
+		return &m.ChanID
+	default:
+		return nil
+	}
+}
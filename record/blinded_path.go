@@ -0,0 +1,244 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+var (
+	// ErrSCIDIntroductionNode is returned when we attempt to decode a
+	// blinded path that identifies its introduction node by a short
+	// channel ID and direction rather than a public key. This compact
+	// form isn't currently supported.
+	ErrSCIDIntroductionNode = errors.New("blinded paths with a short " +
+		"channel id introduction node are not supported")
+
+	// ErrNoBlindedHops is returned when a blinded path doesn't contain
+	// any hops.
+	ErrNoBlindedHops = errors.New("blinded path has no hops")
+)
+
+// BlindedPath is the BOLT 04 encoding of a blinded path that is handed out by
+// a recipient so that a sender can reach it without learning its identity.
+// It is used for onion message reply paths and within BOLT 12 offers and
+// invoices.
+type BlindedPath struct {
+	// IntroductionNode is the real public key of the first node in the
+	// blinded path.
+	IntroductionNode *btcec.PublicKey
+
+	// PathKey is the first ephemeral path key of the blinded path. This
+	// is provided to the introduction node so that it is able to decrypt
+	// its encrypted data.
+	PathKey *btcec.PublicKey
+
+	// Hops is the set of blinded hops in the path, starting with the
+	// introduction node.
+	Hops []*sphinx.BlindedHopInfo
+}
+
+// NewBlindedPath creates a BlindedPath from a blinded path created by the
+// sphinx package.
+func NewBlindedPath(path *sphinx.BlindedPath) *BlindedPath {
+	return &BlindedPath{
+		IntroductionNode: path.IntroductionPoint,
+		PathKey:          path.BlindingPoint,
+		Hops:             path.BlindedHops,
+	}
+}
+
+// Size returns the number of bytes that the encoded blinded path occupies.
+func (b *BlindedPath) Size() uint64 {
+	// The introduction node, path key and number of hops.
+	size := uint64(btcec.PubKeyBytesLenCompressed*2 + 1)
+
+	// Each hop has a blinded node ID, a two byte length and the encrypted
+	// data itself.
+	for _, hop := range b.Hops {
+		size += btcec.PubKeyBytesLenCompressed + 2 +
+			uint64(len(hop.CipherText))
+	}
+
+	return size
+}
+
+// Encode serializes the blinded path to the passed io.Writer.
+//
+// 1) Introduction node public key: 33 bytes.
+// 2) First path key: 33 bytes.
+// 3) Number of hops: 1 byte.
+// 4) For each hop:
+//   - Blinded node public key: 33 bytes.
+//   - Encrypted data length: uint16 (2 bytes).
+//   - Encrypted data.
+func (b *BlindedPath) Encode(w io.Writer) error {
+	if len(b.Hops) == 0 {
+		return ErrNoBlindedHops
+	}
+
+	if len(b.Hops) > math.MaxUint8 {
+		return fmt.Errorf("blinded path has %d hops, max is %d",
+			len(b.Hops), math.MaxUint8)
+	}
+
+	_, err := w.Write(b.IntroductionNode.SerializeCompressed())
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b.PathKey.SerializeCompressed()); err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte{uint8(len(b.Hops))}); err != nil {
+		return err
+	}
+
+	for _, hop := range b.Hops {
+		if len(hop.CipherText) > math.MaxUint16 {
+			return fmt.Errorf("encrypted data of %d bytes "+
+				"exceeds maximum of %d", len(hop.CipherText),
+				math.MaxUint16)
+		}
+
+		_, err := w.Write(hop.BlindedNodePub.SerializeCompressed())
+		if err != nil {
+			return err
+		}
+
+		var dataLen [2]byte
+		binary.BigEndian.PutUint16(
+			dataLen[:], uint16(len(hop.CipherText)),
+		)
+		if _, err := w.Write(dataLen[:]); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(hop.CipherText); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeBlindedPath parses a blinded path from the passed io.Reader.
+func DecodeBlindedPath(r io.Reader) (*BlindedPath, error) {
+	var (
+		path BlindedPath
+		err  error
+	)
+
+	path.IntroductionNode, err = readPubKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	path.PathKey, err = readPubKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var numHops [1]byte
+	if _, err := io.ReadFull(r, numHops[:]); err != nil {
+		return nil, err
+	}
+
+	if numHops[0] == 0 {
+		return nil, ErrNoBlindedHops
+	}
+
+	path.Hops = make([]*sphinx.BlindedHopInfo, numHops[0])
+	for i := range path.Hops {
+		nodeID, err := readPubKey(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var dataLen [2]byte
+		if _, err := io.ReadFull(r, dataLen[:]); err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, binary.BigEndian.Uint16(dataLen[:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		path.Hops[i] = &sphinx.BlindedHopInfo{
+			BlindedNodePub: nodeID,
+			CipherText:     data,
+		}
+	}
+
+	return &path, nil
+}
+
+// readPubKey reads a compressed public key from the passed io.Reader.
+func readPubKey(r io.Reader) (*btcec.PublicKey, error) {
+	var keyBytes [btcec.PubKeyBytesLenCompressed]byte
+	if _, err := io.ReadFull(r, keyBytes[:]); err != nil {
+		return nil, err
+	}
+
+	// A leading 0x00 or 0x01 byte signals the compact short channel ID
+	// encoding of the node, which we don't support.
+	if keyBytes[0] == 0x00 || keyBytes[0] == 0x01 {
+		return nil, ErrSCIDIntroductionNode
+	}
+
+	return btcec.ParsePubKey(keyBytes[:])
+}
+
+// NewBlindedPathRecord returns a tlv.Record that encodes a single blinded path
+// using the given type.
+func NewBlindedPathRecord(typ tlv.Type, path *BlindedPath) tlv.Record {
+	return tlv.MakeDynamicRecord(
+		typ, path, path.Size, encodeBlindedPath, decodeBlindedPath,
+	)
+}
+
+// encodeBlindedPath is a tlv.Encoder for a BlindedPath.
+func encodeBlindedPath(w io.Writer, val interface{}, _ *[8]byte) error {
+	if v, ok := val.(*BlindedPath); ok {
+		return v.Encode(w)
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "*record.BlindedPath")
+}
+
+// decodeBlindedPath is a tlv.Decoder for a BlindedPath.
+func decodeBlindedPath(r io.Reader, val interface{}, _ *[8]byte,
+	l uint64) error {
+
+	v, ok := val.(*BlindedPath)
+	if !ok {
+		return tlv.NewTypeForDecodingErr(
+			val, "*record.BlindedPath", l, l,
+		)
+	}
+
+	lr := io.LimitedReader{R: r, N: int64(l)}
+	path, err := DecodeBlindedPath(&lr)
+	if err != nil {
+		return err
+	}
+
+	// Make sure that the record didn't contain any trailing bytes.
+	if lr.N != 0 {
+		return tlv.NewTypeForDecodingErr(
+			val, "*record.BlindedPath", l, l-uint64(lr.N),
+		)
+	}
+
+	*v = *path
+
+	return nil
+}
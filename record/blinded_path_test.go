@@ -0,0 +1,79 @@
+package record
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlindedPathEncoding tests encoding and decoding of blinded paths, both
+// directly and as a TLV record.
+func TestBlindedPathEncoding(t *testing.T) {
+	t.Parallel()
+
+	sessionKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	hops := make([]*sphinx.HopInfo, 3)
+	for i := range hops {
+		nodeKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		hops[i] = &sphinx.HopInfo{
+			NodePub:   nodeKey.PubKey(),
+			PlainText: bytes.Repeat([]byte{byte(i)}, i*10),
+		}
+	}
+
+	sphinxPath, err := sphinx.BuildBlindedPath(sessionKey, hops)
+	require.NoError(t, err)
+
+	path := NewBlindedPath(sphinxPath)
+
+	var b bytes.Buffer
+	require.NoError(t, path.Encode(&b))
+	require.EqualValues(t, path.Size(), b.Len())
+
+	decoded, err := DecodeBlindedPath(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, path, decoded)
+
+	// Now wrap the path in a TLV stream and make sure that it survives
+	// the round trip.
+	stream, err := tlv.NewStream(NewBlindedPathRecord(2, path))
+	require.NoError(t, err)
+
+	b.Reset()
+	require.NoError(t, stream.Encode(&b))
+
+	var decodedRecord BlindedPath
+	stream, err = tlv.NewStream(NewBlindedPathRecord(2, &decodedRecord))
+	require.NoError(t, err)
+	require.NoError(t, stream.Decode(bytes.NewReader(b.Bytes())))
+	require.Equal(t, path, &decodedRecord)
+
+	// A path without any hops can't be encoded or decoded.
+	emptyPath := &BlindedPath{
+		IntroductionNode: path.IntroductionNode,
+		PathKey:          path.PathKey,
+	}
+	require.ErrorIs(t, emptyPath.Encode(&b), ErrNoBlindedHops)
+
+	var noHops bytes.Buffer
+	noHops.Write(path.IntroductionNode.SerializeCompressed())
+	noHops.Write(path.PathKey.SerializeCompressed())
+	noHops.WriteByte(0)
+	_, err = DecodeBlindedPath(&noHops)
+	require.ErrorIs(t, err, ErrNoBlindedHops)
+
+	// Introduction nodes that are expressed as a short channel ID are not
+	// supported.
+	scidPath := make([]byte, 100)
+	scidPath[0] = 0x01
+	_, err = DecodeBlindedPath(bytes.NewReader(scidPath))
+	require.ErrorIs(t, err, ErrSCIDIntroductionNode)
+}
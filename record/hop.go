@@ -33,6 +33,16 @@ const (
 	// TotalAmtMsatBlindedType is the type used in the onion for the total
 	// amount field that is included in the final hop for blinded payments.
 	TotalAmtMsatBlindedType tlv.Type = 18
+
+	// TrampolineOnionType is the type used in the onion to include an
+	// inner trampoline onion packet for the final hop of the outer route.
+	TrampolineOnionType tlv.Type = 20
+
+	// OutgoingNodeIDOnionType is the type used in a trampoline onion
+	// payload to reference the node that the trampoline should forward
+	// the payment to. It is placed in the custom range so that it does not
+	// clash with the AMP record which already uses type 14.
+	OutgoingNodeIDOnionType tlv.Type = 66098
 )
 
 // NewAmtToFwdRecord creates a tlv.Record that encodes the amount_to_forward
@@ -97,3 +107,15 @@ func NewTotalAmtMsatBlinded(amt *uint64) tlv.Record {
 		tlv.ETUint64, tlv.DTUint64,
 	)
 }
+
+// NewTrampolineOnionRecord creates a tlv.Record that encodes the
+// trampoline_onion_packet (type 20) for an onion payload.
+func NewTrampolineOnionRecord(onion *[]byte) tlv.Record {
+	return tlv.MakePrimitiveRecord(TrampolineOnionType, onion)
+}
+
+// NewOutgoingNodeIDRecord creates a tlv.Record that encodes the
+// outgoing_node_id for a trampoline onion payload.
+func NewOutgoingNodeIDRecord(nodeID **btcec.PublicKey) tlv.Record {
+	return tlv.MakePrimitiveRecord(OutgoingNodeIDOnionType, nodeID)
+}
@@ -1,8 +1,12 @@
 package routing
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
 )
@@ -21,6 +25,119 @@ type bandwidthHints interface {
 		amount lnwire.MilliSatoshi) (lnwire.MilliSatoshi, bool)
 }
 
+// ChannelSkipReason describes why one of our channels can't carry an outgoing
+// htlc.
+type ChannelSkipReason uint8
+
+const (
+	// ChannelSkipUnknown is used if the link rejected the htlc for a
+	// reason that isn't covered by the other values.
+	ChannelSkipUnknown ChannelSkipReason = iota
+
+	// ChannelSkipLinkNotFound is used if the link of the channel isn't
+	// online.
+	ChannelSkipLinkNotFound
+
+	// ChannelSkipNotEligible is used if the link isn't yet eligible to
+	// forward htlcs.
+	ChannelSkipNotEligible
+
+	// ChannelSkipMaxHtlcs is used if the htlc would exceed the maximum
+	// number of pending htlcs of the channel.
+	ChannelSkipMaxHtlcs
+
+	// ChannelSkipMaxInFlight is used if the htlc would exceed the maximum
+	// value in flight of the channel.
+	ChannelSkipMaxInFlight
+
+	// ChannelSkipChannelReserve is used if the htlc would dip our balance
+	// below the channel reserve.
+	ChannelSkipChannelReserve
+
+	// ChannelSkipBelowMinHtlc is used if the htlc is below the minimum
+	// htlc value of the channel.
+	ChannelSkipBelowMinHtlc
+
+	// ChannelSkipDustExposure is used if the htlc would push the dust
+	// exposure of the channel over the fee exposure threshold.
+	ChannelSkipDustExposure
+
+	// ChannelSkipInsufficientBandwidth is used if the channel lacks the
+	// bandwidth to carry the htlc.
+	ChannelSkipInsufficientBandwidth
+
+	// ChannelSkipDustExposureCheckFailed is used if the dust exposure of
+	// the channel couldn't be checked.
+	ChannelSkipDustExposureCheckFailed
+)
+
+// String returns a human-readable representation of the skip reason.
+func (r ChannelSkipReason) String() string {
+	switch r {
+	case ChannelSkipLinkNotFound:
+		return "link not found"
+
+	case ChannelSkipNotEligible:
+		return "not eligible to forward"
+
+	case ChannelSkipMaxHtlcs:
+		return "max htlcs"
+
+	case ChannelSkipMaxInFlight:
+		return "max in flight"
+
+	case ChannelSkipChannelReserve:
+		return "channel reserve"
+
+	case ChannelSkipBelowMinHtlc:
+		return "below min htlc"
+
+	case ChannelSkipDustExposure:
+		return "dust exposure"
+
+	case ChannelSkipInsufficientBandwidth:
+		return "insufficient bandwidth"
+
+	case ChannelSkipDustExposureCheckFailed:
+		return "dust exposure check failed"
+
+	default:
+		return "unknown"
+	}
+}
+
+// addHtlcSkipReason maps the error returned by a link that can't add an
+// outgoing htlc to the reason why the channel is skipped.
+func addHtlcSkipReason(err error) ChannelSkipReason {
+	switch {
+	case errors.Is(err, lnwallet.ErrMaxHTLCNumber):
+		return ChannelSkipMaxHtlcs
+
+	case errors.Is(err, lnwallet.ErrMaxPendingAmount):
+		return ChannelSkipMaxInFlight
+
+	case errors.Is(err, lnwallet.ErrBelowChanReserve):
+		return ChannelSkipChannelReserve
+
+	case errors.Is(err, lnwallet.ErrBelowMinHTLC):
+		return ChannelSkipBelowMinHtlc
+
+	default:
+		return ChannelSkipUnknown
+	}
+}
+
+// channelSkip describes why one of our channels can't carry an outgoing htlc.
+type channelSkip struct {
+	reason ChannelSkipReason
+	err    error
+}
+
+// Error returns the error that caused the channel to be skipped.
+func (c *channelSkip) Error() string {
+	return c.err.Error()
+}
+
 // getLinkQuery is the function signature used to lookup a link.
 type getLinkQuery func(lnwire.ShortChannelID) (
 	htlcswitch.ChannelLink, error)
@@ -73,33 +190,53 @@ func newBandwidthManager(graph Graph, sourceNode route.Vertex,
 func (b *bandwidthManager) getBandwidth(cid lnwire.ShortChannelID,
 	amount lnwire.MilliSatoshi) lnwire.MilliSatoshi {
 
+	bandwidth, skip := b.queryBandwidth(cid, amount)
+	if skip != nil {
+		log.Warnf("ShortChannelID=%v: %v", cid, skip)
+		return 0
+	}
+
+	return bandwidth
+}
+
+// queryBandwidth queries the current state of a link and gets its currently
+// available bandwidth. If the link can't carry an htlc of the given amount, the
+// reason why the channel has to be skipped is returned instead.
+func (b *bandwidthManager) queryBandwidth(cid lnwire.ShortChannelID,
+	amount lnwire.MilliSatoshi) (lnwire.MilliSatoshi, *channelSkip) {
+
 	link, err := b.getLink(cid)
 	if err != nil {
 		// If the link isn't online, then we'll report that it has
 		// zero bandwidth.
-		log.Warnf("ShortChannelID=%v: link not found: %v", cid, err)
-		return 0
+		return 0, &channelSkip{
+			reason: ChannelSkipLinkNotFound,
+			err:    fmt.Errorf("link not found: %w", err),
+		}
 	}
 
 	// If the link is found within the switch, but it isn't yet eligible
 	// to forward any HTLCs, then we'll treat it as if it isn't online in
 	// the first place.
 	if !link.EligibleToForward() {
-		log.Warnf("ShortChannelID=%v: not eligible to forward", cid)
-		return 0
+		return 0, &channelSkip{
+			reason: ChannelSkipNotEligible,
+			err:    errors.New("not eligible to forward"),
+		}
 	}
 
 	// If our link isn't currently in a state where it can  add another
 	// outgoing htlc, treat the link as unusable.
 	if err := link.MayAddOutgoingHtlc(amount); err != nil {
-		log.Warnf("ShortChannelID=%v: cannot add outgoing htlc: %v",
-			cid, err)
-		return 0
+		return 0, &channelSkip{
+			reason: addHtlcSkipReason(err),
+			err:    fmt.Errorf("cannot add outgoing htlc: %w", err),
+		}
 	}
 
 	// Otherwise, we'll return the current best estimate for the available
 	// bandwidth for the link.
-	return link.Bandwidth()
+	return link.Bandwidth(), nil
 }
 
 // availableChanBandwidth returns the total available bandwidth for a channel
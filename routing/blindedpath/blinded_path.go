@@ -109,11 +109,45 @@ type BuildBlindedPathCfg struct {
 	DefaultDummyHopPolicy *BlindedHopPolicy
 }
 
+// BlindedPathPreview describes a blinded payment path along with the real
+// route that it was built from. It lets the receiver inspect the paths before
+// they are added to an invoice.
+type BlindedPathPreview struct {
+	// Route is the real route from the introduction node to this node.
+	Route *route.Route
+
+	// NumDummyHops is the number of dummy hops that were appended to the
+	// real route.
+	NumDummyHops uint8
+
+	// Path is the blinded payment path that was built from the route.
+	Path *zpay32.BlindedPaymentPath
+}
+
 // BuildBlindedPaymentPaths uses the passed config to construct a set of blinded
 // payment paths that can be added to the invoice.
 func BuildBlindedPaymentPaths(cfg *BuildBlindedPathCfg) (
 	[]*zpay32.BlindedPaymentPath, error) {
 
+	previews, err := BuildBlindedPathPreviews(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]*zpay32.BlindedPaymentPath, len(previews))
+	for i, preview := range previews {
+		paths[i] = preview.Path
+	}
+
+	return paths, nil
+}
+
+// BuildBlindedPathPreviews uses the passed config to construct a set of blinded
+// payment paths, and returns each of them along with the real route that it
+// was built from.
+func BuildBlindedPathPreviews(cfg *BuildBlindedPathCfg) (
+	[]*BlindedPathPreview, error) {
+
 	// Find some appropriate routes for the value to be routed. This will
 	// return a set of routes made up of real nodes.
 	routes, err := cfg.FindRoutes(cfg.ValueMsat)
@@ -129,7 +163,7 @@ func BuildBlindedPaymentPaths(cfg *BuildBlindedPathCfg) (
 	// Not every route returned will necessarily result in a usable blinded
 	// path and so the number of paths returned might be less than the
 	// number of real routes returned by FindRoutes above.
-	paths := make([]*zpay32.BlindedPaymentPath, 0, len(routes))
+	previews := make([]*BlindedPathPreview, 0, len(routes))
 
 	// For each route returned, we will construct the associated blinded
 	// payment path.
@@ -139,6 +173,7 @@ func BuildBlindedPaymentPaths(cfg *BuildBlindedPathCfg) (
 
 		// Pad the given route with dummy hops until the minimum number
 		// of hops is met.
+		numRealHops := len(candidatePath.hops)
 		candidatePath.padWithDummyHops(cfg.MinNumHops)
 
 		path, err := buildBlindedPaymentPath(cfg, candidatePath)
@@ -157,14 +192,20 @@ func BuildBlindedPaymentPaths(cfg *BuildBlindedPathCfg) (
 
 		log.Debugf("Route selected for blinded path: %s", candidatePath)
 
-		paths = append(paths, path)
+		previews = append(previews, &BlindedPathPreview{
+			Route: route,
+			NumDummyHops: uint8(
+				len(candidatePath.hops) - numRealHops,
+			),
+			Path: path,
+		})
 	}
 
-	if len(paths) == 0 {
+	if len(previews) == 0 {
 		return nil, fmt.Errorf("could not build any blinded paths")
 	}
 
-	return paths, nil
+	return previews, nil
 }
 
 // buildBlindedPaymentPath takes a route from an introduction node to this node
@@ -993,6 +993,141 @@ func TestBuildBlindedPathWithDummyHops(t *testing.T) {
 	require.Len(t, paths, 1)
 }
 
+// TestBuildBlindedPathPreviews tests that the previews of blinded paths reveal
+// the real route that each path was built from and the number of dummy hops
+// that were appended to it, and that routes that can't be used are skipped.
+func TestBuildBlindedPathPreviews(t *testing.T) {
+	t.Parallel()
+
+	var (
+		_, pkC = btcec.PrivKeyFromBytes([]byte{1})
+		_, pkB = btcec.PrivKeyFromBytes([]byte{2})
+		_, pkA = btcec.PrivKeyFromBytes([]byte{3})
+
+		carol = route.NewVertex(pkC)
+		bob   = route.NewVertex(pkB)
+		alice = route.NewVertex(pkA)
+
+		chanCB      = uint64(1)
+		chanBA      = uint64(2)
+		chanUnknown = uint64(3)
+	)
+
+	// Carol -> Bob -> Alice.
+	longRoute := &route.Route{
+		SourcePubKey: carol,
+		Hops: []*route.Hop{
+			{
+				PubKeyBytes: bob,
+				ChannelID:   chanCB,
+			},
+			{
+				PubKeyBytes: alice,
+				ChannelID:   chanBA,
+			},
+		},
+	}
+
+	// Bob -> Alice.
+	shortRoute := &route.Route{
+		SourcePubKey: bob,
+		Hops: []*route.Hop{
+			{
+				PubKeyBytes: alice,
+				ChannelID:   chanBA,
+			},
+		},
+	}
+
+	// Carol -> Alice over a channel that we don't know the policy of.
+	unusableRoute := &route.Route{
+		SourcePubKey: carol,
+		Hops: []*route.Hop{
+			{
+				PubKeyBytes: alice,
+				ChannelID:   chanUnknown,
+			},
+		},
+	}
+
+	policies := map[uint64]*models.ChannelEdgePolicy{
+		chanCB: {
+			ChannelID: chanCB,
+			ToNode:    bob,
+		},
+		chanBA: {
+			ChannelID: chanBA,
+			ToNode:    alice,
+		},
+	}
+
+	hopPolicy := &BlindedHopPolicy{
+		FeeRate:         500,
+		BaseFee:         100,
+		CLTVExpiryDelta: 144,
+		MinHTLCMsat:     1000,
+		MaxHTLCMsat:     lnwire.MaxMilliSatoshi,
+	}
+
+	previews, err := BuildBlindedPathPreviews(&BuildBlindedPathCfg{
+		FindRoutes: func(_ lnwire.MilliSatoshi) ([]*route.Route,
+			error) {
+
+			return []*route.Route{
+				longRoute, unusableRoute, shortRoute,
+			}, nil
+		},
+		FetchChannelEdgesByID: func(chanID uint64) (
+			*models.ChannelEdgeInfo, *models.ChannelEdgePolicy,
+			*models.ChannelEdgePolicy, error) {
+
+			policy, ok := policies[chanID]
+			if !ok {
+				return nil, nil, nil,
+					fmt.Errorf("edge not found")
+			}
+
+			return nil, policy, nil, nil
+		},
+		BestHeight: func() (uint32, error) {
+			return 1000, nil
+		},
+		AddPolicyBuffer: func(_ *BlindedHopPolicy) (
+			*BlindedHopPolicy, error) {
+
+			return hopPolicy, nil
+		},
+		PathID:                  []byte{1, 2, 3},
+		ValueMsat:               1000,
+		MinFinalCLTVExpiryDelta: 12,
+		BlocksUntilExpiry:       200,
+		MinNumHops:              3,
+		DefaultDummyHopPolicy:   hopPolicy,
+	})
+	require.NoError(t, err)
+	require.Len(t, previews, 2)
+
+	// Both usable routes are padded to the same number of hops, which
+	// takes one dummy hop for the long and two for the short route.
+	require.Equal(t, longRoute, previews[0].Route)
+	require.EqualValues(t, 1, previews[0].NumDummyHops)
+	require.Len(t, previews[0].Path.Hops, 4)
+
+	require.Equal(t, shortRoute, previews[1].Route)
+	require.EqualValues(t, 2, previews[1].NumDummyHops)
+	require.Len(t, previews[1].Path.Hops, 4)
+
+	// The introduction node of each path is the first node of its route.
+	require.Equal(
+		t, pkC.SerializeCompressed(),
+		previews[0].Path.Hops[0].BlindedNodePub.SerializeCompressed(),
+	)
+	require.Equal(
+		t, pkB.SerializeCompressed(),
+		previews[1].Path.Hops[0].BlindedNodePub.SerializeCompressed(),
+	)
+}
+
 // TestSingleHopBlindedPath tests that blinded path construction is done
 // correctly for the case where the destination node is also the introduction
 // node.
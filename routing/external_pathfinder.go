@@ -0,0 +1,469 @@
+package routing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// errNoExternalPathFinder is returned when the route request of a payment
+// session can't be handed to an external path finder, either because none is
+// registered or because the payment isn't supported.
+var errNoExternalPathFinder = errors.New("no external path finder")
+
+// ExternalRouteRequest is a request for routes that is handed to an external
+// path finder.
+type ExternalRouteRequest struct {
+	// PaymentIdentifier is the payment hash, or the payment identifier for
+	// AMP payments.
+	PaymentIdentifier lntypes.Hash
+
+	// Target is the node that the payment is sent to.
+	Target route.Vertex
+
+	// Amount is the maximum amount that the routes may deliver to the
+	// target in total.
+	Amount lnwire.MilliSatoshi
+
+	// TotalAmount is the total amount of the payment.
+	TotalAmount lnwire.MilliSatoshi
+
+	// FeeLimit is the maximum fee that the routes may pay in total.
+	FeeLimit lnwire.MilliSatoshi
+
+	// ActiveShards is the number of htlcs of the payment that are in
+	// flight.
+	ActiveShards uint32
+
+	// MaxParts is the maximum number of htlcs that the payment may be
+	// split into.
+	MaxParts uint32
+
+	// MaxShardAmt is the largest amount that a single route may deliver,
+	// if set.
+	MaxShardAmt *lnwire.MilliSatoshi
+
+	// Height is the current block height.
+	Height uint32
+
+	// CltvLimit is the absolute block height that the time lock of the
+	// routes must not exceed.
+	CltvLimit uint32
+
+	// FinalCLTVDelta is the minimum number of blocks between the current
+	// block height and the expiry of the htlc at the final hop.
+	FinalCLTVDelta uint16
+
+	// OutgoingChannelIDs are the channels that the first hop of the routes
+	// is restricted to. Any channel may be used if this is empty.
+	OutgoingChannelIDs []uint64
+
+	// LastHop is the node that the last hop of the routes is restricted
+	// to, if set.
+	LastHop *route.Vertex
+
+	// RouteHints are the route hints to reach the target.
+	RouteHints [][]zpay32.HopHint
+
+	// ExcludedPairs are the node pairs that failed to forward the amount
+	// of the request during the earlier attempts of the payment.
+	ExcludedPairs []DirectedNodePair
+}
+
+// ExternalPathFinder finds routes for payments outside of lnd.
+type ExternalPathFinder interface {
+	// FindRoutes returns candidate routes for the given request, in the
+	// order in which they should be tried. An empty set of routes signals
+	// that no route could be found.
+	FindRoutes(req *ExternalRouteRequest) ([]*route.Route, error)
+}
+
+// ExternalPathFinding dispatches the route requests of payment sessions to an
+// external path finder while one is registered.
+type ExternalPathFinding struct {
+	// getPairHistory returns the last results of mission control for the
+	// given node pair.
+	getPairHistory func(fromNode, toNode route.Vertex) TimedPairResult
+
+	pathFinder ExternalPathFinder
+	mu         sync.RWMutex
+}
+
+// NewExternalPathFinding creates a new dispatcher of route requests to an
+// external path finder. The pair history of mission control is used to tell
+// the path finder which pairs failed during the earlier attempts of a payment.
+func NewExternalPathFinding(getPairHistory func(fromNode,
+	toNode route.Vertex) TimedPairResult) *ExternalPathFinding {
+
+	return &ExternalPathFinding{
+		getPairHistory: getPairHistory,
+	}
+}
+
+// SetPathFinder registers the external path finder that route requests are
+// dispatched to. A nil path finder unregisters the current one, after which
+// payment sessions fall back to our own path finding.
+func (e *ExternalPathFinding) SetPathFinder(pathFinder ExternalPathFinder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pathFinder = pathFinder
+}
+
+// getPathFinder returns the registered external path finder, or nil if there
+// is none.
+func (e *ExternalPathFinding) getPathFinder() ExternalPathFinder {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.pathFinder
+}
+
+// requestExternalRoute returns the next route for the payment from the
+// candidates of the external path finder. Candidates are handed out in order,
+// skipping those that no longer fit into the remaining amount and fee budget
+// of the payment or that use a pair which failed in the meantime. New
+// candidates are requested once they are used up.
+func (p *paymentSession) requestExternalRoute(maxAmt,
+	feeLimit lnwire.MilliSatoshi, activeShards,
+	height uint32) (*route.Route, error) {
+
+	// Payments to blinded paths can't be described to the external path
+	// finder, so we find the routes for them ourselves.
+	pathFinder := p.externalPathFinding.getPathFinder()
+	if pathFinder == nil || p.payment.BlindedPathSet != nil {
+		return nil, errNoExternalPathFinder
+	}
+
+	if p.externalStart.IsZero() {
+		p.externalStart = time.Now()
+	}
+
+	excluded := p.excludedPairs(maxAmt)
+
+	// Hand out the next candidate that is still usable, if any.
+	for len(p.externalRoutes) > 0 {
+		rt := p.externalRoutes[0]
+		p.externalRoutes = p.externalRoutes[1:]
+
+		err := p.checkExternalRoute(
+			rt, maxAmt, feeLimit, activeShards, height, excluded,
+		)
+		if err != nil {
+			p.log.Debugf("Skipping external route: %v", err)
+
+			continue
+		}
+
+		p.addExternalPairs(rt)
+
+		return rt, nil
+	}
+
+	var maxShardAmt *lnwire.MilliSatoshi
+	if p.payment.MaxShardAmt != nil {
+		amt := *p.payment.MaxShardAmt
+		maxShardAmt = &amt
+	}
+
+	req := &ExternalRouteRequest{
+		PaymentIdentifier:  p.payment.Identifier(),
+		Target:             p.payment.Target,
+		Amount:             maxAmt,
+		TotalAmount:        p.payment.Amount,
+		FeeLimit:           feeLimit,
+		ActiveShards:       activeShards,
+		MaxParts:           p.payment.MaxParts,
+		MaxShardAmt:        maxShardAmt,
+		Height:             height,
+		CltvLimit:          p.cltvLimit(height),
+		FinalCLTVDelta:     p.payment.FinalCLTVDelta + BlockPadding,
+		OutgoingChannelIDs: p.payment.OutgoingChannelIDs,
+		LastHop:            p.payment.LastHop,
+		RouteHints:         p.payment.RouteHints,
+		ExcludedPairs:      excluded,
+	}
+
+	p.log.Debugf("Requesting routes from external path finder for "+
+		"amt=%v, fee_limit=%v, excluded_pairs=%v", maxAmt, feeLimit,
+		len(excluded))
+
+	routes, err := pathFinder.FindRoutes(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt *route.Route
+	for _, candidate := range routes {
+		if err := p.setFinalHopRecords(candidate); err != nil {
+			p.log.Debugf("Skipping external route: %v", err)
+
+			continue
+		}
+
+		// The first usable candidate is handed out right away, the
+		// others are kept for the next requests.
+		if rt != nil {
+			p.externalRoutes = append(p.externalRoutes, candidate)
+
+			continue
+		}
+
+		err := p.checkExternalRoute(
+			candidate, maxAmt, feeLimit, activeShards, height,
+			excluded,
+		)
+		if err != nil {
+			p.log.Debugf("Skipping external route: %v", err)
+
+			continue
+		}
+
+		rt = candidate
+	}
+
+	if rt == nil {
+		p.log.Debugf("External path finder returned no usable route "+
+			"out of %v", len(routes))
+
+		return nil, errNoPathFound
+	}
+
+	p.addExternalPairs(rt)
+
+	return rt, nil
+}
+
+// cltvLimit returns the absolute block height that the time lock of the
+// routes of the payment must not exceed.
+func (p *paymentSession) cltvLimit(height uint32) uint32 {
+	limit := uint64(height) + uint64(p.payment.CltvLimit)
+	if limit > uint64(^uint32(0)) {
+		return ^uint32(0)
+	}
+
+	return uint32(limit)
+}
+
+// setFinalHopRecords sets the records of the final hop of a route of the
+// external path finder, which are owned by the payment.
+func (p *paymentSession) setFinalHopRecords(rt *route.Route) error {
+	if len(rt.Hops) == 0 {
+		return errors.New("route has no hops")
+	}
+
+	finalHop := rt.FinalHop()
+	finalHop.CustomRecords = p.payment.DestCustomRecords
+	finalHop.Metadata = p.payment.Metadata
+	finalHop.AMP = nil
+	finalHop.MPP = nil
+	if p.payment.PaymentAddr != nil {
+		finalHop.MPP = record.NewMPP(
+			p.payment.Amount, *p.payment.PaymentAddr,
+		)
+	}
+
+	return nil
+}
+
+// checkExternalRoute validates a route of the external path finder against
+// the current state and the restrictions of the payment.
+func (p *paymentSession) checkExternalRoute(rt *route.Route, maxAmt,
+	feeLimit lnwire.MilliSatoshi, activeShards, height uint32,
+	excluded []DirectedNodePair) error {
+
+	if rt.SourcePubKey != p.selfNode {
+		return fmt.Errorf("route starts at %v instead of our node",
+			rt.SourcePubKey)
+	}
+
+	finalHop := rt.FinalHop()
+	if finalHop.PubKeyBytes != p.payment.Target {
+		return fmt.Errorf("route ends at %v instead of the target",
+			finalHop.PubKeyBytes)
+	}
+
+	amt := rt.ReceiverAmt()
+	switch {
+	case amt == 0:
+		return errors.New("route carries no amount")
+
+	case amt > maxAmt:
+		return fmt.Errorf("route amount %v exceeds remaining amount %v",
+			amt, maxAmt)
+
+	case p.payment.MaxShardAmt != nil && amt > *p.payment.MaxShardAmt:
+		return fmt.Errorf("route amount %v exceeds max shard amount "+
+			"%v", amt, *p.payment.MaxShardAmt)
+
+	// Partial amounts can only be sent with an mpp record.
+	case amt < maxAmt && p.payment.PaymentAddr == nil:
+		return errors.New("payment can't be split without payment " +
+			"address")
+
+	case amt < maxAmt && activeShards+1 >= p.payment.MaxParts:
+		return fmt.Errorf("route leaves %v unpaid with shard limit "+
+			"%v reached", maxAmt-amt, p.payment.MaxParts)
+	}
+
+	if rt.TotalFees() > feeLimit {
+		return fmt.Errorf("route fees %v exceed fee limit %v",
+			rt.TotalFees(), feeLimit)
+	}
+
+	// Every hop must forward at most what it receives and expire no
+	// later than its incoming htlc.
+	prevAmt, prevTimeLock := rt.TotalAmount, rt.TotalTimeLock
+	for _, hop := range rt.Hops {
+		if hop.AmtToForward > prevAmt {
+			return fmt.Errorf("hop %v forwards more than it "+
+				"receives", hop.ChannelID)
+		}
+		if hop.OutgoingTimeLock > prevTimeLock {
+			return fmt.Errorf("hop %v has a time lock beyond its "+
+				"incoming htlc", hop.ChannelID)
+		}
+
+		prevAmt, prevTimeLock = hop.AmtToForward, hop.OutgoingTimeLock
+	}
+
+	finalCltvDelta := uint32(p.payment.FinalCLTVDelta + BlockPadding)
+	if finalHop.OutgoingTimeLock < height+finalCltvDelta {
+		return fmt.Errorf("final hop time lock %v is below %v",
+			finalHop.OutgoingTimeLock, height+finalCltvDelta)
+	}
+
+	if rt.TotalTimeLock > p.cltvLimit(height) {
+		return fmt.Errorf("route time lock %v exceeds cltv limit %v",
+			rt.TotalTimeLock, p.cltvLimit(height))
+	}
+
+	if len(p.payment.OutgoingChannelIDs) > 0 {
+		var allowed bool
+		for _, chanID := range p.payment.OutgoingChannelIDs {
+			if rt.Hops[0].ChannelID == chanID {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("outgoing channel %v isn't allowed",
+				rt.Hops[0].ChannelID)
+		}
+	}
+
+	if p.payment.LastHop != nil {
+		lastHop := rt.SourcePubKey
+		if len(rt.Hops) > 1 {
+			lastHop = rt.Hops[len(rt.Hops)-2].PubKeyBytes
+		}
+		if lastHop != *p.payment.LastHop {
+			return fmt.Errorf("last hop %v isn't allowed", lastHop)
+		}
+	}
+
+	for _, pair := range excluded {
+		if routeHasPair(rt, pair) {
+			return fmt.Errorf("route uses excluded pair %v -> %v",
+				pair.From, pair.To)
+		}
+	}
+
+	return checkPayloadSize(rt)
+}
+
+// checkPayloadSize returns an error if the hop payloads of the route don't fit
+// into the onion.
+func checkPayloadSize(rt *route.Route) error {
+	var payloadSize uint64
+	for i, hop := range rt.Hops {
+		var nextChanID uint64
+		if i < len(rt.Hops)-1 {
+			nextChanID = rt.Hops[i+1].ChannelID
+		}
+
+		payloadSize += hop.PayloadSize(nextChanID)
+	}
+	if payloadSize > sphinx.MaxPayloadSize {
+		return fmt.Errorf("route payload size %v exceeds maximum",
+			payloadSize)
+	}
+
+	return nil
+}
+
+// routeHasPair returns whether the route forwards from one node of the pair to
+// the other.
+func routeHasPair(rt *route.Route, pair DirectedNodePair) bool {
+	from := rt.SourcePubKey
+	for _, hop := range rt.Hops {
+		if from == pair.From && hop.PubKeyBytes == pair.To {
+			return true
+		}
+
+		from = hop.PubKeyBytes
+	}
+
+	return false
+}
+
+// addExternalPairs remembers the pairs of a route that was handed out, so that
+// failures along them are reported to the external path finder.
+func (p *paymentSession) addExternalPairs(rt *route.Route) {
+	if p.externalPairs == nil {
+		p.externalPairs = make(map[DirectedNodePair]struct{})
+	}
+
+	from := rt.SourcePubKey
+	for _, hop := range rt.Hops {
+		p.externalPairs[NewDirectedNodePair(from, hop.PubKeyBytes)] =
+			struct{}{}
+
+		from = hop.PubKeyBytes
+	}
+}
+
+// excludedPairs returns the pairs of the routes handed out so far that failed
+// to forward the given amount since the session started using the external
+// path finder.
+func (p *paymentSession) excludedPairs(
+	amt lnwire.MilliSatoshi) []DirectedNodePair {
+
+	var excluded []DirectedNodePair
+	for pair := range p.externalPairs {
+		result := p.externalPathFinding.getPairHistory(
+			pair.From, pair.To,
+		)
+		if !result.FailTime.After(p.externalStart) {
+			continue
+		}
+
+		if result.FailAmt > amt {
+			continue
+		}
+
+		excluded = append(excluded, pair)
+	}
+
+	// Sort the pairs to present them in a stable order.
+	sort.Slice(excluded, func(i, j int) bool {
+		if excluded[i].From != excluded[j].From {
+			return bytes.Compare(
+				excluded[i].From[:], excluded[j].From[:],
+			) < 0
+		}
+
+		return bytes.Compare(excluded[i].To[:], excluded[j].To[:]) < 0
+	})
+
+	return excluded
+}
@@ -0,0 +1,363 @@
+package routing
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// mockExternalPathFinder is an external path finder that responds to all
+// route requests with the same routes.
+type mockExternalPathFinder struct {
+	routes   func() []*route.Route
+	err      error
+	requests []*ExternalRouteRequest
+}
+
+// FindRoutes records the request and returns the routes of the mock.
+func (m *mockExternalPathFinder) FindRoutes(
+	req *ExternalRouteRequest) ([]*route.Route, error) {
+
+	m.requests = append(m.requests, req)
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.routes(), nil
+}
+
+// newExternalTestSession creates a payment session to the node "target" of a
+// graph with a single path via node "a", along with the external path finding
+// that it uses. The returned pair history is reported to the session as the
+// results of mission control.
+func newExternalTestSession(t *testing.T, payment *LightningPayment) (
+	*paymentSession, *ExternalPathFinding,
+	map[DirectedNodePair]TimedPairResult) {
+
+	policy := &testChannelPolicy{
+		Expiry:  144,
+		MinHTLC: 1,
+		MaxHTLC: lnwire.NewMSatFromSatoshis(500_000),
+	}
+
+	channels := []*testChannel{
+		symmetricTestChannel("source", "a", 500_000, policy, 1),
+		symmetricTestChannel("a", "target", 500_000, policy, 2),
+	}
+
+	session := newFlowSplitTestSession(t, channels, payment)
+
+	history := make(map[DirectedNodePair]TimedPairResult)
+	externalPathFinding := NewExternalPathFinding(
+		func(fromNode, toNode route.Vertex) TimedPairResult {
+			return history[NewDirectedNodePair(fromNode, toNode)]
+		},
+	)
+	session.externalPathFinding = externalPathFinding
+
+	return session, externalPathFinding, history
+}
+
+// newExternalTestRoute creates a route from self to the target via the given
+// node that delivers the given amount.
+func newExternalTestRoute(t *testing.T, self, via, target route.Vertex,
+	amt, fee lnwire.MilliSatoshi, height uint32) *route.Route {
+
+	rt, err := route.NewRouteFromHops(
+		amt+fee, height+200, self, []*route.Hop{
+			{
+				PubKeyBytes:      via,
+				ChannelID:        1,
+				AmtToForward:     amt,
+				OutgoingTimeLock: height + 100,
+			},
+			{
+				PubKeyBytes:      target,
+				ChannelID:        2,
+				AmtToForward:     amt,
+				OutgoingTimeLock: height + 100,
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	return rt
+}
+
+// TestExternalPathFinder asserts that the candidate routes of an external path
+// finder are handed out in order, with the records of the final hop set by the
+// session.
+func TestExternalPathFinder(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	var paymentAddr [32]byte
+	payment := &LightningPayment{
+		Amount:         lnwire.NewMSatFromSatoshis(100_000),
+		FeeLimit:       lnwire.NewMSatFromSatoshis(1_000),
+		CltvLimit:      math.MaxUint32 - height,
+		FinalCLTVDelta: 40,
+		DestFeatures: lnwire.NewFeatureVector(
+			mppFeatures, lnwire.Features,
+		),
+		PaymentAddr: &paymentAddr,
+		MaxParts:    16,
+	}
+
+	session, externalPathFinding, _ := newExternalTestSession(t, payment)
+
+	via := route.Vertex{2}
+	pathFinder := &mockExternalPathFinder{
+		routes: func() []*route.Route {
+			return []*route.Route{
+				newExternalTestRoute(
+					t, session.selfNode, via,
+					payment.Target, 60_000_000, 1_000,
+					height,
+				),
+				newExternalTestRoute(
+					t, session.selfNode, via,
+					payment.Target, 40_000_000, 1_000,
+					height,
+				),
+			}
+		},
+	}
+	externalPathFinding.SetPathFinder(pathFinder)
+
+	// The first route is handed out after requesting routes for the full
+	// amount.
+	rt, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, lnwire.MilliSatoshi(60_000_000), rt.ReceiverAmt())
+	require.Equal(t, payment.Amount, rt.FinalHop().MPP.TotalMsat())
+	require.Equal(t, paymentAddr, rt.FinalHop().MPP.PaymentAddr())
+
+	require.Len(t, pathFinder.requests, 1)
+	req := pathFinder.requests[0]
+	require.Equal(t, payment.Target, req.Target)
+	require.Equal(t, payment.Amount, req.Amount)
+	require.Equal(t, payment.FeeLimit, req.FeeLimit)
+	require.Equal(t, uint32(math.MaxUint32), req.CltvLimit)
+	require.Equal(t, uint16(40+BlockPadding), req.FinalCLTVDelta)
+	require.Empty(t, req.ExcludedPairs)
+
+	// The second route fits into the remaining amount and is handed out
+	// without another request.
+	remaining := payment.Amount - rt.ReceiverAmt()
+	rt, err = session.RequestRoute(
+		remaining, payment.FeeLimit-rt.TotalFees(), 1, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, remaining, rt.ReceiverAmt())
+	require.Len(t, pathFinder.requests, 1)
+
+	// Once the external path finder is unregistered, we fall back to our
+	// own path finding.
+	externalPathFinding.SetPathFinder(nil)
+	rt, err = session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, payment.Amount, rt.ReceiverAmt())
+	require.Len(t, pathFinder.requests, 1)
+}
+
+// TestExternalPathFinderExcludedPairs asserts that pairs that failed after
+// being handed out are reported to the external path finder, and that the
+// remaining candidates along them are skipped.
+func TestExternalPathFinderExcludedPairs(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	payment := &LightningPayment{
+		Amount:         lnwire.NewMSatFromSatoshis(100_000),
+		FeeLimit:       lnwire.NewMSatFromSatoshis(1_000),
+		CltvLimit:      math.MaxUint32 - height,
+		FinalCLTVDelta: 40,
+		MaxParts:       16,
+	}
+
+	session, externalPathFinding, history := newExternalTestSession(
+		t, payment,
+	)
+
+	viaA, viaB := route.Vertex{2}, route.Vertex{3}
+	pathFinder := &mockExternalPathFinder{
+		routes: func() []*route.Route {
+			return []*route.Route{
+				newExternalTestRoute(
+					t, session.selfNode, viaA,
+					payment.Target, payment.Amount, 1_000,
+					height,
+				),
+				newExternalTestRoute(
+					t, session.selfNode, viaA,
+					payment.Target, payment.Amount, 2_000,
+					height,
+				),
+				newExternalTestRoute(
+					t, session.selfNode, viaB,
+					payment.Target, payment.Amount, 3_000,
+					height,
+				),
+			}
+		},
+	}
+	externalPathFinding.SetPathFinder(pathFinder)
+
+	rt, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, viaA, rt.Hops[0].PubKeyBytes)
+
+	// Fail the pair from node a to the target. The next candidate along
+	// the same pair should be skipped.
+	failedPair := NewDirectedNodePair(viaA, payment.Target)
+	history[failedPair] = TimedPairResult{
+		FailTime: time.Now().Add(time.Second),
+	}
+
+	rt, err = session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, viaB, rt.Hops[0].PubKeyBytes)
+	require.Len(t, pathFinder.requests, 1)
+
+	// With the candidates used up, new routes are requested, and the
+	// failed pair is reported.
+	rt, err = session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, viaB, rt.Hops[0].PubKeyBytes)
+	require.Len(t, pathFinder.requests, 2)
+	require.Equal(
+		t, []DirectedNodePair{failedPair},
+		pathFinder.requests[1].ExcludedPairs,
+	)
+}
+
+// TestExternalPathFinderValidation asserts that routes of the external path
+// finder that violate the restrictions of the payment aren't used.
+func TestExternalPathFinderValidation(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	payment := &LightningPayment{
+		Amount:         lnwire.NewMSatFromSatoshis(100_000),
+		FeeLimit:       lnwire.NewMSatFromSatoshis(1),
+		CltvLimit:      1_000,
+		FinalCLTVDelta: 40,
+		MaxParts:       16,
+	}
+
+	session, externalPathFinding, _ := newExternalTestSession(t, payment)
+
+	via := route.Vertex{2}
+	newRoute := func(amt, fee lnwire.MilliSatoshi) *route.Route {
+		return newExternalTestRoute(
+			t, session.selfNode, via, payment.Target, amt, fee,
+			height,
+		)
+	}
+
+	testCases := []struct {
+		name  string
+		route func() *route.Route
+	}{
+		{
+			name: "fee limit exceeded",
+			route: func() *route.Route {
+				return newRoute(payment.Amount, 2_000)
+			},
+		},
+		{
+			name: "amount exceeded",
+			route: func() *route.Route {
+				return newRoute(payment.Amount+1, 0)
+			},
+		},
+		{
+			name: "split without payment address",
+			route: func() *route.Route {
+				return newRoute(payment.Amount/2, 0)
+			},
+		},
+		{
+			name: "wrong target",
+			route: func() *route.Route {
+				rt := newRoute(payment.Amount, 0)
+				rt.Hops[1].PubKeyBytes = route.Vertex{4}
+
+				return rt
+			},
+		},
+		{
+			name: "final cltv delta too small",
+			route: func() *route.Route {
+				rt := newRoute(payment.Amount, 0)
+				rt.Hops[1].OutgoingTimeLock = height + 40
+
+				return rt
+			},
+		},
+		{
+			name: "cltv limit exceeded",
+			route: func() *route.Route {
+				rt := newRoute(payment.Amount, 0)
+				rt.TotalTimeLock = height + 1_001
+
+				return rt
+			},
+		},
+		{
+			name: "hop forwards more than it receives",
+			route: func() *route.Route {
+				rt := newRoute(payment.Amount, 0)
+				rt.Hops[0].AmtToForward = payment.Amount - 1
+
+				return rt
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		pathFinder := &mockExternalPathFinder{
+			routes: func() []*route.Route {
+				return []*route.Route{tc.route()}
+			},
+		}
+		externalPathFinding.SetPathFinder(pathFinder)
+
+		_, err := session.RequestRoute(
+			payment.Amount, payment.FeeLimit, 0, height,
+		)
+		require.ErrorIs(t, err, errNoPathFound, tc.name)
+	}
+
+	// If the external path finder fails, we fall back to our own path
+	// finding.
+	externalPathFinding.SetPathFinder(&mockExternalPathFinder{
+		err: errors.New("unavailable"),
+	})
+
+	rt, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, payment.Amount, rt.ReceiverAmt())
+}
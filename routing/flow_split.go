@@ -0,0 +1,637 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/feature"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// maxFlowUnits is the maximum number of units a payment is divided
+	// into when splitting it by min-cost flow. Every path of the flow
+	// carries at least one unit, so this also bounds the number of shards.
+	maxFlowUnits = 32
+
+	// flowArcPieces is the number of pieces the convex cost function of a
+	// channel is linearized into.
+	flowArcPieces = 4
+
+	// maxFlowArcCost caps the per unit cost of an arc, so that the path
+	// costs of the min-cost flow computation can't overflow.
+	maxFlowArcCost = int64(1e11)
+)
+
+var (
+	// errFlowSplitUnsupported is returned when a payment can't be split by
+	// min-cost flow.
+	errFlowSplitUnsupported = errors.New("payment can't be split by " +
+		"min-cost flow")
+
+	// errFlowSplitExhausted is returned when the splitting a payment by
+	// min-cost flow would exceed the limits of the payment.
+	errFlowSplitExhausted = errors.New("min-cost flow split exceeds " +
+		"payment limits")
+)
+
+// flowEdge is an edge of the flow network of a payment. Network edges unify
+// all channels between a pair of nodes, since routing nodes may forward
+// non-strictly. Local edges are individual channels of the source node.
+type flowEdge struct {
+	// fromNode is the node the edge starts at.
+	fromNode route.Vertex
+
+	// unifier holds the channels between the node pair of a network edge.
+	unifier *edgeUnifier
+
+	// localEdge is the channel of a local edge.
+	localEdge *unifiedEdge
+}
+
+// paymentFlowNetwork is the flow network of a payment, which is built from the
+// channel graph and the probabilities of mission control.
+type paymentFlowNetwork struct {
+	*flowNetwork
+
+	// edges are the edges of the network, indexed by the edge index of
+	// the arcs.
+	edges []*flowEdge
+
+	// source and sink are the node indices of the sender and the receiver
+	// of the payment.
+	source, sink int
+}
+
+// newPaymentFlowNetwork builds the flow network of a payment to the target. The
+// capacities of the arcs are expressed in multiples of the given unit amount.
+// Every edge is represented by a number of arcs whose costs linearize the sum
+// of the fees of the edge and its probability of failure, which is priced at
+// the given attempt cost.
+func newPaymentFlowNetwork(g *graphParams, r *RestrictParams,
+	self, target route.Vertex, unit lnwire.MilliSatoshi,
+	attemptCost float64) (*paymentFlowNetwork, error) {
+
+	n := &paymentFlowNetwork{
+		flowNetwork: newFlowNetwork(),
+	}
+
+	nodes := make(map[route.Vertex]int, estimatedNodeCount)
+	nodeIndex := func(node route.Vertex) int {
+		index, ok := nodes[node]
+		if !ok {
+			index = n.addNode()
+			nodes[node] = index
+		}
+
+		return index
+	}
+
+	n.source = nodeIndex(self)
+	n.sink = nodeIndex(target)
+
+	// Set up outgoing channel map for quicker access.
+	var outgoingChanMap map[uint64]struct{}
+	if len(r.OutgoingChannelIDs) > 0 {
+		outgoingChanMap = make(map[uint64]struct{})
+		for _, outChan := range r.OutgoingChannelIDs {
+			outgoingChanMap[outChan] = struct{}{}
+		}
+	}
+
+	additionalEdgesWithSrc := reverseAdditionalEdges(g.additionalEdges, self)
+
+	// Cache the validity of node features, because nodes are visited once
+	// for every channel towards an already visited node.
+	validFeatures := make(map[route.Vertex]bool)
+	hasValidFeatures := func(node route.Vertex) (bool, error) {
+		valid, ok := validFeatures[node]
+		if ok {
+			return valid, nil
+		}
+
+		features, err := g.graph.FetchNodeFeatures(node)
+		if err != nil {
+			return false, err
+		}
+
+		valid = feature.ValidateRequired(features) == nil &&
+			feature.ValidateDeps(features) == nil
+		validFeatures[node] = valid
+
+		return valid, nil
+	}
+
+	// Walk the graph backwards from the target, so that the policies of
+	// the channels towards each node can be looked up.
+	visited := map[route.Vertex]struct{}{target: {}}
+	queue := []route.Vertex{target}
+	for len(queue) > 0 {
+		pivot := queue[0]
+		queue = queue[1:]
+
+		// Create unified policies for all incoming connections. Don't
+		// use inbound fees for the exit hop.
+		u := newNodeEdgeUnifier(
+			self, pivot, pivot != target, outgoingChanMap,
+		)
+
+		err := u.addGraphPolicies(g.graph)
+		if err != nil {
+			return nil, err
+		}
+
+		// We add hop hints that were supplied externally, in the same
+		// way as path finding does.
+		for _, reverseEdge := range additionalEdgesWithSrc[pivot] {
+			u.addPolicy(
+				reverseEdge.sourceNode,
+				reverseEdge.edge.EdgePolicy(),
+				models.InboundFee{}, fakeHopHintCapacity,
+				reverseEdge.edge.IntermediatePayloadSize,
+				reverseEdge.edge.BlindedPayment(),
+			)
+		}
+
+		for fromNode, edgeUnifier := range u.edgeUnifiers {
+			// Flow into the target never needs to leave it again.
+			if fromNode == target {
+				continue
+			}
+
+			// Apply last hop restriction if set.
+			if r.LastHop != nil &&
+				pivot == target && fromNode != *r.LastHop {
+
+				continue
+			}
+
+			valid, err := hasValidFeatures(fromNode)
+			if err != nil {
+				return nil, err
+			}
+			if !valid {
+				continue
+			}
+
+			from := nodeIndex(fromNode)
+			to := nodeIndex(pivot)
+
+			if fromNode == self {
+				for _, edge := range edgeUnifier.edges {
+					n.addLocalEdge(
+						from, to, edge, g.bandwidthHints,
+						r, self, pivot, unit,
+						attemptCost,
+					)
+				}
+
+				continue
+			}
+
+			n.addNetworkEdge(
+				from, to, fromNode, pivot, edgeUnifier, r,
+				unit, attemptCost,
+			)
+
+			if _, ok := visited[fromNode]; !ok {
+				visited[fromNode] = struct{}{}
+				queue = append(queue, fromNode)
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// addLocalEdge adds the arcs of a channel of the source node. Its capacity is
+// the bandwidth currently available in the channel, and no fees are paid.
+func (n *paymentFlowNetwork) addLocalEdge(from, to int, edge *unifiedEdge,
+	bandwidthHints bandwidthHints, r *RestrictParams,
+	self, toNode route.Vertex, unit lnwire.MilliSatoshi,
+	attemptCost float64) {
+
+	if !edge.amtInRange(unit) {
+		return
+	}
+
+	bandwidth, ok := bandwidthHints.availableChanBandwidth(
+		edge.policy.ChannelID, unit,
+	)
+	if !ok {
+		bandwidth = lnwire.NewMSatFromSatoshis(edge.capacity)
+	}
+
+	index := len(n.edges)
+	n.edges = append(n.edges, &flowEdge{
+		fromNode:  self,
+		localEdge: edge,
+	})
+
+	n.addEdgeArcs(
+		from, to, index, self, toNode, int64(bandwidth/unit),
+		edge.capacity, 0, r, unit, attemptCost,
+	)
+}
+
+// addNetworkEdge adds the arcs of all channels between a pair of nodes. The
+// capacity of the pair is bounded by the largest channel, and by its maximum
+// HTLC size, since every path can only use a single channel.
+func (n *paymentFlowNetwork) addNetworkEdge(from, to int,
+	fromNode, toNode route.Vertex, edgeUnifier *edgeUnifier,
+	r *RestrictParams, unit lnwire.MilliSatoshi, attemptCost float64) {
+
+	edge := edgeUnifier.getEdgeNetwork(unit, 0)
+	if edge == nil {
+		return
+	}
+
+	capacity := lnwire.NewMSatFromSatoshis(edge.capacity)
+	if edge.policy.MessageFlags.HasMaxHtlc() &&
+		edge.policy.MaxHTLC < capacity {
+
+		capacity = edge.policy.MaxHTLC
+	}
+
+	index := len(n.edges)
+	n.edges = append(n.edges, &flowEdge{
+		fromNode: fromNode,
+		unifier:  edgeUnifier,
+	})
+
+	n.addEdgeArcs(
+		from, to, index, fromNode, toNode, int64(capacity/unit),
+		edge.capacity, edge.policy.FeeProportionalMillionths, r, unit,
+		attemptCost,
+	)
+}
+
+// addEdgeArcs adds the arcs of an edge with the given capacity in units. The
+// capacity is split into pieces, whose costs are the proportional fee and the
+// increase of the failure probability within the piece. Costs of later pieces
+// are never lower than those of earlier ones, which keeps the cost function
+// convex. Base fees aren't linear in the amount and are therefore left out.
+func (n *paymentFlowNetwork) addEdgeArcs(from, to, index int,
+	fromNode, toNode route.Vertex, units int64, capacity btcutil.Amount,
+	feeRate lnwire.MilliSatoshi, r *RestrictParams,
+	unit lnwire.MilliSatoshi, attemptCost float64) {
+
+	if units <= 0 {
+		return
+	}
+
+	feeCost := float64(unit) * float64(feeRate) / 1_000_000
+
+	pieces := int64(flowArcPieces)
+	if units < pieces {
+		pieces = units
+	}
+
+	var (
+		lower     int64
+		lowerProb = r.ProbabilitySource(fromNode, toNode, 0, capacity)
+		prevCost  int64
+	)
+	for i := int64(1); i <= pieces; i++ {
+		upper := units * i / pieces
+
+		upperProb := r.ProbabilitySource(
+			fromNode, toNode, unit*lnwire.MilliSatoshi(upper),
+			capacity,
+		)
+
+		// If the upper end of this piece can't succeed, there is no
+		// point in using it or any of the following ones.
+		if upperProb == 0 || lowerProb == 0 {
+			return
+		}
+
+		failureCost := attemptCost *
+			(math.Log(lowerProb) - math.Log(upperProb)) /
+			float64(upper-lower)
+
+		cost := int64(math.Min(
+			math.Max(feeCost+failureCost, 0),
+			float64(maxFlowArcCost),
+		))
+		if cost < prevCost {
+			cost = prevCost
+		}
+
+		n.addArc(from, to, upper-lower, cost, index)
+
+		lower, lowerProb, prevCost = upper, upperProb, cost
+	}
+}
+
+// findFlowRoutes splits the given amount into a set of routes by solving a
+// min-cost flow over the channel graph. Instead of finding one path at a time,
+// all shards of the amount are determined up front, trading off fees against
+// the success probabilities of the channels and the amounts they would carry.
+// The number of routes is bounded by maxParts.
+func (p *paymentSession) findFlowRoutes(amt, feeLimit lnwire.MilliSatoshi,
+	maxParts, height uint32) ([]*route.Route, error) {
+
+	// Splitting requires the receiver to accept multi-part payments.
+	// Payments to blinded paths are left to the default splitter.
+	payFeatures := p.payment.DestFeatures
+	switch {
+	case p.payment.BlindedPathSet != nil:
+		return nil, errFlowSplitUnsupported
+
+	case p.payment.PaymentAddr == nil:
+		return nil, errFlowSplitUnsupported
+
+	case payFeatures == nil:
+		return nil, errFlowSplitUnsupported
+
+	case !payFeatures.HasFeature(lnwire.MPPOptional) &&
+		!payFeatures.HasFeature(lnwire.AMPOptional):
+
+		return nil, errFlowSplitUnsupported
+	}
+
+	if maxParts == 0 {
+		return nil, errFlowSplitExhausted
+	}
+
+	start := time.Now()
+
+	// Divide the amount into units, which are never smaller than the
+	// minimum shard amount unless the amount itself is.
+	numUnits := lnwire.MilliSatoshi(maxFlowUnits)
+	if numUnits > lnwire.MilliSatoshi(maxParts) {
+		numUnits = lnwire.MilliSatoshi(maxParts)
+	}
+
+	unit := (amt + numUnits - 1) / numUnits
+	if unit < p.minShardAmt {
+		unit = p.minShardAmt
+	}
+	if unit > amt {
+		unit = amt
+	}
+	numUnits = (amt + unit - 1) / unit
+
+	// Add BlockPadding to the finalCltvDelta so that the receiving node
+	// does not reject the HTLC if some blocks are mined while it's in-flight.
+	finalCltvDelta := p.payment.FinalCLTVDelta + BlockPadding
+
+	restrictions := &RestrictParams{
+		ProbabilitySource:  p.missionControl.GetProbability,
+		FeeLimit:           feeLimit,
+		OutgoingChannelIDs: p.payment.OutgoingChannelIDs,
+		LastHop:            p.payment.LastHop,
+		DestFeatures:       p.payment.DestFeatures,
+		PaymentAddr:        p.payment.PaymentAddr,
+	}
+
+	attemptCost, err := getAbsoluteAttemptCost(
+		&p.pathFindingConfig, amt, p.payment.TimePref,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get a routing graph session.
+	graph, closeGraph, err := p.graphSessFactory.NewGraphSession()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := closeGraph(); err != nil {
+			log.Errorf("could not close graph session: %v", err)
+		}
+	}()
+
+	bandwidthHints, err := p.getBandwidthHints(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := destFeatures(graph, restrictions, p.payment.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := newPaymentFlowNetwork(
+		&graphParams{
+			additionalEdges: p.additionalEdges,
+			bandwidthHints:  bandwidthHints,
+			graph:           graph,
+		},
+		restrictions, p.selfNode, p.payment.Target, unit, attemptCost,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.minCostFlow(n.source, n.sink, int64(numUnits))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := n.decompose(n.source, n.sink)
+
+	// The units carry a little more than the amount, so take the excess
+	// off the largest path. It is smaller than a unit, so the path keeps a
+	// positive amount.
+	amounts := make([]lnwire.MilliSatoshi, len(paths))
+	largest := 0
+	for i, path := range paths {
+		amounts[i] = unit * lnwire.MilliSatoshi(path.units)
+		if amounts[i] > amounts[largest] {
+			largest = i
+		}
+	}
+	amounts[largest] -= numUnits*unit - amt
+
+	var (
+		routes    []*route.Route
+		totalFees lnwire.MilliSatoshi
+	)
+	for i, path := range paths {
+		// Respect the maximum shard size by sending the amount of the
+		// path in multiple shards if needed.
+		for _, shardAmt := range splitShardAmt(
+			amounts[i], p.payment.MaxShardAmt,
+		) {
+
+			if uint32(len(routes)) >= maxParts {
+				return nil, errFlowSplitExhausted
+			}
+
+			rt, err := n.newFlowRoute(
+				path, shardAmt, features, p.selfNode,
+				height, finalHopParams{
+					amt:         shardAmt,
+					totalAmt:    p.payment.Amount,
+					cltvDelta:   finalCltvDelta,
+					records:     p.payment.DestCustomRecords,
+					paymentAddr: p.payment.PaymentAddr,
+					metadata:    p.payment.Metadata,
+
+					trampolineOnion: p.payment.trampolineOnion,
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			if rt.TotalTimeLock > height+p.payment.CltvLimit {
+				return nil, fmt.Errorf("route exceeds cltv "+
+					"limit: %v", rt.TotalTimeLock)
+			}
+
+			totalFees += rt.TotalFees()
+			routes = append(routes, rt)
+		}
+	}
+
+	if totalFees > feeLimit {
+		return nil, fmt.Errorf("fees of %v exceed fee limit of %v",
+			totalFees, feeLimit)
+	}
+
+	// Hand out the largest shards first.
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].ReceiverAmt() > routes[j].ReceiverAmt()
+	})
+
+	p.log.Debugf("Split amt=%v into %v shards by min-cost flow with "+
+		"fees=%v, unit=%v, time=%v", amt, len(routes), totalFees, unit,
+		time.Since(start))
+
+	return routes, nil
+}
+
+// newFlowRoute constructs the route of a shard along the given path of the
+// flow. The channels of the network edges are picked for the exact amounts of
+// the shard, in the same way as path finding does.
+func (n *paymentFlowNetwork) newFlowRoute(path *flowPath,
+	amt lnwire.MilliSatoshi, features *lnwire.FeatureVector,
+	self route.Vertex, height uint32,
+	finalHop finalHopParams) (*route.Route, error) {
+
+	pathEdges := make([]*unifiedEdge, len(path.edges))
+
+	var (
+		netAmountReceived = amt
+		nextOutFee        lnwire.MilliSatoshi
+	)
+	for i := len(path.edges) - 1; i >= 0; i-- {
+		flowEdge := n.edges[path.edges[i]]
+
+		edge := flowEdge.localEdge
+		if edge == nil {
+			edge = flowEdge.unifier.getEdgeNetwork(
+				netAmountReceived, nextOutFee,
+			)
+			if edge == nil {
+				return nil, errNoPathFound
+			}
+		}
+
+		inboundFee := calcCappedInboundFee(
+			edge, netAmountReceived, nextOutFee,
+		)
+		amountToSend := netAmountReceived +
+			lnwire.MilliSatoshi(inboundFee)
+
+		var outboundFee lnwire.MilliSatoshi
+		if flowEdge.fromNode != self {
+			outboundFee = edge.policy.ComputeFee(amountToSend)
+		}
+
+		pathEdges[i] = edge
+		netAmountReceived = amountToSend + outboundFee
+		nextOutFee = outboundFee
+	}
+
+	// For the final hop, we'll set the node features to those of the
+	// destination. The policy is copied, because it may be shared with the
+	// graph.
+	lastEdge := *pathEdges[len(pathEdges)-1]
+	lastPolicy := *lastEdge.policy
+	lastPolicy.ToNodeFeatures = features
+	lastEdge.policy = &lastPolicy
+	pathEdges[len(pathEdges)-1] = &lastEdge
+
+	rt, err := newRoute(self, pathEdges, height, finalHop, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the route fits into the onion.
+	if err := checkPayloadSize(rt); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+// splitShardAmt splits the given amount into shards that don't exceed the
+// maximum shard amount, if set.
+func splitShardAmt(amt lnwire.MilliSatoshi,
+	maxShardAmt *lnwire.MilliSatoshi) []lnwire.MilliSatoshi {
+
+	if maxShardAmt == nil || *maxShardAmt == 0 || amt <= *maxShardAmt {
+		return []lnwire.MilliSatoshi{amt}
+	}
+
+	numShards := (amt + *maxShardAmt - 1) / *maxShardAmt
+	shards := make([]lnwire.MilliSatoshi, 0, numShards)
+	for i := lnwire.MilliSatoshi(0); i < numShards; i++ {
+		shard := amt / numShards
+		if i < amt%numShards {
+			shard++
+		}
+
+		shards = append(shards, shard)
+	}
+
+	return shards
+}
+
+// requestFlowRoute returns the next route of the min-cost flow split of the
+// payment. The split is computed for the full remaining amount, and its routes
+// are handed out as long as the payment progresses as planned. Once an attempt
+// fails or a block is mined, the remaining amount is split anew, taking into
+// account what mission control learned in the meantime.
+func (p *paymentSession) requestFlowRoute(maxAmt, feeLimit lnwire.MilliSatoshi,
+	activeShards, height uint32) (*route.Route, error) {
+
+	if len(p.flowRoutes) == 0 || maxAmt != p.flowRemaining ||
+		height != p.flowHeight {
+
+		p.flowRoutes = nil
+
+		if activeShards >= p.payment.MaxParts {
+			return nil, errFlowSplitExhausted
+		}
+
+		routes, err := p.findFlowRoutes(
+			maxAmt, feeLimit, p.payment.MaxParts-activeShards,
+			height,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		p.flowRoutes = routes
+		p.flowRemaining = maxAmt
+		p.flowHeight = height
+	}
+
+	rt := p.flowRoutes[0]
+	p.flowRoutes = p.flowRoutes[1:]
+	p.flowRemaining -= rt.ReceiverAmt()
+
+	return rt, nil
+}
@@ -0,0 +1,211 @@
+package routing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlowSplitTestSession creates a payment session for the given payment to
+// the node "target" of a test graph in which all channels are certain to
+// succeed.
+func newFlowSplitTestSession(t *testing.T, channels []*testChannel,
+	payment *LightningPayment) *paymentSession {
+
+	testGraph, err := createTestGraphFromChannels(
+		t, true, channels, "source",
+	)
+	require.NoError(t, err)
+
+	payment.Target = testGraph.aliasMap["target"]
+	require.NoError(t, payment.SetPaymentHash(lntypes.Hash{}))
+
+	missionControl := &mockMissionControl{}
+	missionControl.On(
+		"GetProbability", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything,
+	).Return(1.0)
+
+	session, err := newPaymentSession(
+		payment, testGraph.aliasMap["source"],
+		func(Graph) (bandwidthHints, error) {
+			return &mockBandwidthHints{}, nil
+		},
+		newMockGraphSessionFactoryFromChanDB(testGraph.graph),
+		missionControl, PathFindingConfig{},
+	)
+	require.NoError(t, err)
+
+	return session
+}
+
+// TestFlowSplit asserts that a payment that doesn't fit through a single path
+// is split up front, with the cheaper path carrying as much as it can.
+func TestFlowSplit(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	policy := func(feeRate lnwire.MilliSatoshi,
+		capacity btcutil.Amount) *testChannelPolicy {
+
+		return &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: feeRate,
+			MinHTLC: 1,
+			MaxHTLC: lnwire.NewMSatFromSatoshis(capacity),
+		}
+	}
+
+	channels := []*testChannel{
+		symmetricTestChannel(
+			"source", "a", 200_000, policy(0, 200_000), 1,
+		),
+		symmetricTestChannel(
+			"source", "b", 200_000, policy(0, 200_000), 2,
+		),
+		symmetricTestChannel(
+			"a", "target", 100_000, policy(100, 100_000), 3,
+		),
+		symmetricTestChannel(
+			"b", "target", 100_000, policy(1000, 100_000), 4,
+		),
+	}
+
+	var paymentAddr [32]byte
+	payment := &LightningPayment{
+		Amount:         lnwire.NewMSatFromSatoshis(150_000),
+		FeeLimit:       noFeeLimit,
+		CltvLimit:      math.MaxUint32 - height,
+		FinalCLTVDelta: 40,
+		DestFeatures: lnwire.NewFeatureVector(
+			mppFeatures, lnwire.Features,
+		),
+		PaymentAddr: &paymentAddr,
+		MaxParts:    16,
+		MinCostFlow: true,
+	}
+
+	session := newFlowSplitTestSession(t, channels, payment)
+
+	// The first route should use the cheap path up to its capacity.
+	rt, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), rt.Hops[1].ChannelID)
+	require.Equal(
+		t, lnwire.NewMSatFromSatoshis(100_000), rt.ReceiverAmt(),
+	)
+	require.Equal(t, payment.Amount, rt.FinalHop().MPP.TotalMsat())
+
+	// The second route carries the rest through the expensive path. It is
+	// handed out from the same split, since the remaining amount matches.
+	remaining := payment.Amount - rt.ReceiverAmt()
+	require.Len(t, session.flowRoutes, 1)
+
+	rt, err = session.RequestRoute(remaining, payment.FeeLimit, 1, height)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), rt.Hops[1].ChannelID)
+	require.Equal(t, remaining, rt.ReceiverAmt())
+	require.Empty(t, session.flowRoutes)
+
+	// If the payment didn't progress as planned, the remaining amount is
+	// split anew. With the full amount remaining, we should get the same
+	// first route again.
+	rt, err = session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), rt.Hops[1].ChannelID)
+	require.Len(t, session.flowRoutes, 1)
+}
+
+// TestFlowSplitMaxShardAmt asserts that the paths of the flow are sent in
+// multiple shards if they exceed the maximum shard amount, and that the split
+// fails if it would exceed the maximum number of parts.
+func TestFlowSplitMaxShardAmt(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	policy := &testChannelPolicy{
+		Expiry:  144,
+		MinHTLC: 1,
+		MaxHTLC: lnwire.NewMSatFromSatoshis(500_000),
+	}
+
+	channels := []*testChannel{
+		symmetricTestChannel("source", "a", 500_000, policy, 1),
+		symmetricTestChannel("a", "target", 500_000, policy, 2),
+	}
+
+	var paymentAddr [32]byte
+	maxShardAmt := lnwire.NewMSatFromSatoshis(40_000)
+	payment := &LightningPayment{
+		Amount:         lnwire.NewMSatFromSatoshis(100_000),
+		FeeLimit:       noFeeLimit,
+		CltvLimit:      math.MaxUint32 - height,
+		FinalCLTVDelta: 40,
+		DestFeatures: lnwire.NewFeatureVector(
+			mppFeatures, lnwire.Features,
+		),
+		PaymentAddr: &paymentAddr,
+		MaxParts:    16,
+		MaxShardAmt: &maxShardAmt,
+		MinCostFlow: true,
+	}
+
+	session := newFlowSplitTestSession(t, channels, payment)
+
+	routes, err := session.findFlowRoutes(
+		payment.Amount, payment.FeeLimit, payment.MaxParts, height,
+	)
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+
+	var total lnwire.MilliSatoshi
+	for _, rt := range routes {
+		require.LessOrEqual(t, rt.ReceiverAmt(), maxShardAmt)
+		total += rt.ReceiverAmt()
+	}
+	require.Equal(t, payment.Amount, total)
+
+	_, err = session.findFlowRoutes(
+		payment.Amount, payment.FeeLimit, 2, height,
+	)
+	require.ErrorIs(t, err, errFlowSplitExhausted)
+}
+
+// TestFlowSplitUnsupported asserts that payments that can't be split by
+// min-cost flow are rejected, so that the session falls back to halving.
+func TestFlowSplitUnsupported(t *testing.T) {
+	t.Parallel()
+
+	payment := &LightningPayment{
+		Amount:      1000,
+		MaxParts:    16,
+		MinCostFlow: true,
+	}
+	require.NoError(t, payment.SetPaymentHash(lntypes.Hash{}))
+
+	session, err := newPaymentSession(
+		payment, route.Vertex{},
+		func(Graph) (bandwidthHints, error) {
+			return &mockBandwidthHints{}, nil
+		},
+		newMockGraphSessionFactory(&sessionGraph{}),
+		&MissionControl{}, PathFindingConfig{},
+	)
+	require.NoError(t, err)
+
+	// Without a payment address, the payment can't be split.
+	_, err = session.findFlowRoutes(payment.Amount, 1000, 16, 0)
+	require.ErrorIs(t, err, errFlowSplitUnsupported)
+}
@@ -0,0 +1,416 @@
+package localchans
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing"
+	"github.com/lightningnetwork/lnd/ticker"
+)
+
+const (
+	// maxForwardsPerQuery is the number of forwarding events that the fee
+	// autopilot reads from the forwarding log at once.
+	maxForwardsPerQuery = 10000
+)
+
+// FeeAutopilotConfig holds the dependencies and the parameters of the fee
+// autopilot.
+type FeeAutopilotConfig struct {
+	// Manager is used to list our channels and to apply the new policies.
+	Manager *Manager
+
+	// QueryForwards queries the forwarding log for the events that were
+	// settled in a time slice.
+	QueryForwards func(q channeldb.ForwardingEventQuery) (
+		channeldb.ForwardingLogTimeSlice, error)
+
+	// Ticker determines how often the fees of our channels are
+	// reconsidered.
+	Ticker ticker.Ticker
+
+	// Clock is used to determine the forwarding window and to enforce the
+	// cool-down period.
+	Clock clock.Clock
+
+	// CoolDown is the minimum time between two fee updates of the same
+	// channel.
+	CoolDown time.Duration
+
+	// ForwardingWindow is the time span of the forwarding history that is
+	// taken into account.
+	ForwardingWindow time.Duration
+
+	// LowLiquidityRatio is the ratio of local balance to capacity below
+	// which a channel is considered depleted.
+	LowLiquidityRatio float64
+
+	// HighLiquidityRatio is the ratio of local balance to capacity above
+	// which a channel is considered to have excess local liquidity.
+	HighLiquidityRatio float64
+
+	// MinDailyFlowRatio is the ratio of the amount forwarded per day in
+	// one direction of a channel to its capacity at or above which the
+	// channel counts as forwarding in that direction.
+	MinDailyFlowRatio float64
+
+	// MinFeeRate and MaxFeeRate bound the outbound fee rate of our
+	// channels, in parts per million.
+	MinFeeRate uint32
+	MaxFeeRate uint32
+
+	// FeeRateStep is the fraction by which the outbound fee rate is raised
+	// or lowered in a single update.
+	FeeRateStep float64
+
+	// MinInboundFeeRate is the largest inbound discount that is given, as
+	// a negative rate in parts per million. If it is zero, the inbound fees
+	// of our channels are left untouched.
+	MinInboundFeeRate int32
+
+	// InboundFeeRateStep is the amount by which the inbound fee rate is
+	// changed in a single update, in parts per million.
+	InboundFeeRateStep uint32
+
+	// DryRun, if set, only logs the fee updates instead of applying them.
+	DryRun bool
+}
+
+// channelFlows sums up the amounts that were forwarded through a channel.
+type channelFlows struct {
+	// in is the amount that came in through the channel.
+	in lnwire.MilliSatoshi
+
+	// out is the amount that went out through the channel.
+	out lnwire.MilliSatoshi
+}
+
+// channelState is a snapshot of a local channel and its current policy.
+type channelState struct {
+	chanPoint    wire.OutPoint
+	chanID       uint64
+	capacity     lnwire.MilliSatoshi
+	localBalance lnwire.MilliSatoshi
+	baseFee      lnwire.MilliSatoshi
+	feeRate      uint32
+	inboundFee   models.InboundFee
+	timeLock     uint32
+	maxHTLC      lnwire.MilliSatoshi
+}
+
+// FeeAutopilot periodically adjusts the outbound and inbound fee rates of our
+// channels. Channels that are being depleted while still forwarding get more
+// expensive and a growing inbound discount, while channels with unused local
+// liquidity get cheaper. All rates stay within the configured bounds, and a
+// channel isn't updated again before its cool-down period has passed.
+type FeeAutopilot struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *FeeAutopilotConfig
+
+	// lastUpdates holds the time of the last fee update that the
+	// autopilot made to each channel.
+	lastUpdates map[wire.OutPoint]time.Time
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// NewFeeAutopilot creates a new fee autopilot from the given config.
+func NewFeeAutopilot(cfg *FeeAutopilotConfig) *FeeAutopilot {
+	return &FeeAutopilot{
+		cfg:         cfg,
+		lastUpdates: make(map[wire.OutPoint]time.Time),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start starts the fee autopilot.
+func (f *FeeAutopilot) Start() error {
+	f.started.Do(func() {
+		log.Infof("FeeAutopilot starting (dry run: %v)", f.cfg.DryRun)
+
+		f.cfg.Ticker.Resume()
+
+		f.wg.Add(1)
+		go f.run()
+	})
+
+	return nil
+}
+
+// Stop stops the fee autopilot.
+func (f *FeeAutopilot) Stop() error {
+	f.stopped.Do(func() {
+		log.Info("FeeAutopilot shutting down...")
+		defer log.Debug("FeeAutopilot shutdown complete")
+
+		close(f.quit)
+		f.wg.Wait()
+
+		f.cfg.Ticker.Stop()
+	})
+
+	return nil
+}
+
+// run reconsiders the fees of our channels on every tick.
+//
+// NOTE: This MUST be run as a goroutine.
+func (f *FeeAutopilot) run() {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case <-f.cfg.Ticker.Ticks():
+			if err := f.adjustFees(); err != nil {
+				log.Errorf("Unable to adjust channel fees: %v",
+					err)
+			}
+
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// adjustFees computes new fee rates for all our channels that are out of their
+// cool-down period and applies them, unless we are in dry-run mode.
+func (f *FeeAutopilot) adjustFees() error {
+	now := f.cfg.Clock.Now()
+
+	channels, err := f.fetchChannels()
+	if err != nil {
+		return err
+	}
+
+	flows, err := f.fetchFlows(now.Add(-f.cfg.ForwardingWindow), now)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		lastUpdate, ok := f.lastUpdates[c.chanPoint]
+		if ok && now.Sub(lastUpdate) < f.cfg.CoolDown {
+			continue
+		}
+
+		feeRate, inboundFee := f.newFees(c, flows[c.chanID])
+		if feeRate == c.feeRate && inboundFee == c.inboundFee {
+			continue
+		}
+
+		log.Infof("Updating fees of channel %v (local balance %v of "+
+			"%v, forwarded in %v, out %v): fee rate %v -> %v ppm, "+
+			"inbound fee rate %v -> %v ppm (dry run: %v)",
+			c.chanPoint, c.localBalance, c.capacity,
+			flows[c.chanID].in, flows[c.chanID].out, c.feeRate,
+			feeRate, c.inboundFee.Rate, inboundFee.Rate,
+			f.cfg.DryRun)
+
+		f.lastUpdates[c.chanPoint] = now
+
+		if f.cfg.DryRun {
+			continue
+		}
+
+		policy := routing.ChannelPolicy{
+			FeeSchema: routing.FeeSchema{
+				BaseFee:    c.baseFee,
+				FeeRate:    feeRate,
+				InboundFee: fn.Some(inboundFee),
+			},
+			TimeLockDelta: c.timeLock,
+			MaxHTLC:       c.maxHTLC,
+		}
+		failed, err := f.cfg.Manager.UpdatePolicy(policy, c.chanPoint)
+		if err != nil {
+			return err
+		}
+
+		for _, failure := range failed {
+			log.Warnf("Unable to update fees of channel %v: %v",
+				c.chanPoint, failure.UpdateError)
+		}
+	}
+
+	return nil
+}
+
+// newFees returns the new outbound fee rate and inbound fee of a channel
+// given the amounts that were forwarded through it.
+func (f *FeeAutopilot) newFees(c *channelState,
+	flows channelFlows) (uint32, models.InboundFee) {
+
+	var ratio float64
+	if c.capacity > 0 {
+		ratio = float64(c.localBalance) / float64(c.capacity)
+	}
+
+	// A depleted channel that still forwards gets more expensive to slow
+	// down the outflow, while a channel with excess local liquidity that
+	// isn't used gets cheaper to attract forwards.
+	feeRate := c.feeRate
+	step := uint32(float64(feeRate) * f.cfg.FeeRateStep)
+	if step == 0 {
+		step = 1
+	}
+
+	flowingOut := f.flowing(flows.out, c.capacity)
+	switch {
+	case ratio < f.cfg.LowLiquidityRatio && flowingOut:
+		feeRate += step
+
+	case ratio > f.cfg.HighLiquidityRatio && !flowingOut:
+		if feeRate > step {
+			feeRate -= step
+		} else {
+			feeRate = 0
+		}
+	}
+
+	feeRate = max(feeRate, f.cfg.MinFeeRate)
+	feeRate = min(feeRate, f.cfg.MaxFeeRate)
+
+	// If inbound discounts are disabled, the inbound fee is left as is.
+	inboundFee := c.inboundFee
+	if f.cfg.MinInboundFeeRate == 0 {
+		return feeRate, inboundFee
+	}
+
+	// A depleted channel that doesn't see any inbound flow gets a larger
+	// discount for HTLCs coming in through it, which refills our side of
+	// the channel. The discount is withdrawn again once the channel has
+	// excess local liquidity.
+	inboundStep := int32(f.cfg.InboundFeeRateStep)
+	switch {
+	case ratio < f.cfg.LowLiquidityRatio &&
+		!f.flowing(flows.in, c.capacity):
+
+		inboundFee.Rate -= inboundStep
+
+	case ratio > f.cfg.HighLiquidityRatio:
+		inboundFee.Rate += inboundStep
+	}
+
+	inboundFee.Rate = max(inboundFee.Rate, f.cfg.MinInboundFeeRate)
+	inboundFee.Rate = min(inboundFee.Rate, 0)
+
+	return feeRate, inboundFee
+}
+
+// flowing returns whether the amount that was forwarded in one direction of a
+// channel within the forwarding window makes the channel count as forwarding
+// in that direction. The amount is scaled to a daily volume and compared to
+// the capacity of the channel, so that a few tiny forwards don't count.
+func (f *FeeAutopilot) flowing(amt, capacity lnwire.MilliSatoshi) bool {
+	if amt == 0 {
+		return false
+	}
+
+	days := f.cfg.ForwardingWindow.Hours() / 24
+	if capacity == 0 || days <= 0 {
+		return true
+	}
+
+	dailyAmt := float64(amt) / days
+
+	return dailyAmt/float64(capacity) >= f.cfg.MinDailyFlowRatio
+}
+
+// fetchChannels returns a snapshot of all our confirmed channels.
+func (f *FeeAutopilot) fetchChannels() ([]*channelState, error) {
+	var channels []*channelState
+	err := f.cfg.Manager.ForAllOutgoingChannels(func(tx kvdb.RTx,
+		info *models.ChannelEdgeInfo,
+		edge *models.ChannelEdgePolicy) error {
+
+		channel, err := f.cfg.Manager.FetchChannel(
+			tx, info.ChannelPoint,
+		)
+		if err != nil {
+			log.Debugf("Skipping channel %v: %v", info.ChannelPoint,
+				err)
+
+			return nil
+		}
+		if channel.IsPending {
+			return nil
+		}
+
+		var inboundWireFee lnwire.Fee
+		_, err = edge.ExtraOpaqueData.ExtractRecords(&inboundWireFee)
+		if err != nil {
+			return err
+		}
+
+		channels = append(channels, &channelState{
+			chanPoint: info.ChannelPoint,
+			chanID:    info.ChannelID,
+			capacity: lnwire.NewMSatFromSatoshis(
+				channel.Capacity,
+			),
+			localBalance: channel.LocalCommitment.LocalBalance,
+			baseFee:      edge.FeeBaseMSat,
+			feeRate:      uint32(edge.FeeProportionalMillionths),
+			inboundFee: models.NewInboundFeeFromWire(
+				inboundWireFee,
+			),
+			timeLock: uint32(edge.TimeLockDelta),
+			maxHTLC:  edge.MaxHTLC,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+// fetchFlows sums up the amounts forwarded through each channel in the given
+// time slice, keyed by short channel ID.
+func (f *FeeAutopilot) fetchFlows(start,
+	end time.Time) (map[uint64]channelFlows, error) {
+
+	flows := make(map[uint64]channelFlows)
+
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    start,
+		EndTime:      end,
+		NumMaxEvents: maxForwardsPerQuery,
+	}
+	for {
+		slice, err := f.cfg.QueryForwards(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range slice.ForwardingEvents {
+			inID := event.IncomingChanID.ToUint64()
+			in := flows[inID]
+			in.in += event.AmtIn
+			flows[inID] = in
+
+			outID := event.OutgoingChanID.ToUint64()
+			out := flows[outID]
+			out.out += event.AmtOut
+			flows[outID] = out
+		}
+
+		if len(slice.ForwardingEvents) < maxForwardsPerQuery {
+			return flows, nil
+		}
+
+		query.IndexOffset = slice.LastIndexOffset
+	}
+}
@@ -0,0 +1,341 @@
+package localchans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/discovery"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/ticker"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFeeAutopilotConfig returns a fee autopilot config with the default
+// parameters.
+func newTestFeeAutopilotConfig() *FeeAutopilotConfig {
+	return &FeeAutopilotConfig{
+		Ticker:             ticker.NewForce(time.Hour),
+		Clock:              clock.NewTestClock(time.Unix(1e9, 0)),
+		CoolDown:           time.Hour,
+		ForwardingWindow:   24 * time.Hour,
+		LowLiquidityRatio:  0.2,
+		HighLiquidityRatio: 0.8,
+		MinDailyFlowRatio:  0.001,
+		MinFeeRate:         10,
+		MaxFeeRate:         1000,
+		FeeRateStep:        0.1,
+		MinInboundFeeRate:  -100,
+		InboundFeeRateStep: 20,
+	}
+}
+
+// TestFeeAutopilotNewFees tests the fee rates that the fee autopilot derives
+// from the liquidity and forwards of a channel.
+func TestFeeAutopilotNewFees(t *testing.T) {
+	t.Parallel()
+
+	const capacity = lnwire.MilliSatoshi(1_000_000)
+
+	tests := []struct {
+		name               string
+		localBalance       lnwire.MilliSatoshi
+		feeRate            uint32
+		inboundRate        int32
+		flows              channelFlows
+		disableInbound     bool
+		expectedFeeRate    uint32
+		expectedInboundFee int32
+	}{
+		{
+			name:               "depleted and forwarding",
+			localBalance:       100_000,
+			feeRate:            500,
+			flows:              channelFlows{out: 1000},
+			expectedFeeRate:    550,
+			expectedInboundFee: -20,
+		},
+		{
+			name:               "depleted with inbound flow",
+			localBalance:       100_000,
+			feeRate:            500,
+			inboundRate:        -20,
+			flows:              channelFlows{in: 1000, out: 1000},
+			expectedFeeRate:    550,
+			expectedInboundFee: -20,
+		},
+		{
+			name:               "depleted with tiny forwards",
+			localBalance:       100_000,
+			feeRate:            500,
+			inboundRate:        -20,
+			flows:              channelFlows{in: 999, out: 999},
+			expectedFeeRate:    500,
+			expectedInboundFee: -40,
+		},
+		{
+			name:               "depleted without forwards",
+			localBalance:       100_000,
+			feeRate:            500,
+			inboundRate:        -90,
+			expectedFeeRate:    500,
+			expectedInboundFee: -100,
+		},
+		{
+			name:               "balanced",
+			localBalance:       500_000,
+			feeRate:            500,
+			inboundRate:        -40,
+			expectedFeeRate:    500,
+			expectedInboundFee: -40,
+		},
+		{
+			name:               "excess liquidity without forwards",
+			localBalance:       900_000,
+			feeRate:            500,
+			inboundRate:        -40,
+			expectedFeeRate:    450,
+			expectedInboundFee: -20,
+		},
+		{
+			name:               "excess liquidity with tiny forwards",
+			localBalance:       900_000,
+			feeRate:            500,
+			flows:              channelFlows{out: 999},
+			expectedFeeRate:    450,
+			expectedInboundFee: 0,
+		},
+		{
+			name:               "excess liquidity and forwarding",
+			localBalance:       900_000,
+			feeRate:            500,
+			inboundRate:        -10,
+			flows:              channelFlows{out: 1000},
+			expectedFeeRate:    500,
+			expectedInboundFee: 0,
+		},
+		{
+			name:               "raised to max fee rate",
+			localBalance:       100_000,
+			feeRate:            990,
+			flows:              channelFlows{out: 1000},
+			expectedFeeRate:    1000,
+			expectedInboundFee: -20,
+		},
+		{
+			name:               "lowered to min fee rate",
+			localBalance:       900_000,
+			feeRate:            5,
+			expectedFeeRate:    10,
+			expectedInboundFee: 0,
+		},
+		{
+			name:               "inbound fees disabled",
+			localBalance:       100_000,
+			feeRate:            500,
+			inboundRate:        25,
+			disableInbound:     true,
+			expectedFeeRate:    500,
+			expectedInboundFee: 25,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newTestFeeAutopilotConfig()
+			if test.disableInbound {
+				cfg.MinInboundFeeRate = 0
+			}
+			autopilot := NewFeeAutopilot(cfg)
+
+			feeRate, inboundFee := autopilot.newFees(&channelState{
+				capacity:     capacity,
+				localBalance: test.localBalance,
+				feeRate:      test.feeRate,
+				inboundFee: models.InboundFee{
+					Base: -1,
+					Rate: test.inboundRate,
+				},
+			}, test.flows)
+
+			require.Equal(t, test.expectedFeeRate, feeRate)
+			require.Equal(
+				t, test.expectedInboundFee, inboundFee.Rate,
+			)
+			require.EqualValues(t, -1, inboundFee.Base)
+		})
+	}
+}
+
+// TestFeeAutopilotFlowing tests that the forwarded amount of a channel is
+// scaled to a daily volume before it is compared to the capacity.
+func TestFeeAutopilotFlowing(t *testing.T) {
+	t.Parallel()
+
+	const capacity = lnwire.MilliSatoshi(1_000_000)
+
+	cfg := newTestFeeAutopilotConfig()
+	autopilot := NewFeeAutopilot(cfg)
+
+	require.False(t, autopilot.flowing(0, capacity))
+	require.False(t, autopilot.flowing(999, capacity))
+	require.True(t, autopilot.flowing(1000, capacity))
+
+	// Over a window of two days, twice the amount needs to be forwarded.
+	cfg.ForwardingWindow = 48 * time.Hour
+	require.False(t, autopilot.flowing(1999, capacity))
+	require.True(t, autopilot.flowing(2000, capacity))
+}
+
+// TestFeeAutopilotAdjustFees tests that the fee autopilot applies the new fees
+// of our channels through the channel manager, respecting the cool-down
+// period and the dry-run mode.
+func TestFeeAutopilotAdjustFees(t *testing.T) {
+	t.Parallel()
+
+	var (
+		depletedPoint = wire.OutPoint{Hash: chainhash.Hash{1}}
+		fullPoint     = wire.OutPoint{Hash: chainhash.Hash{2}}
+		depletedID    = lnwire.NewShortChanIDFromInt(1)
+		fullID        = lnwire.NewShortChanIDFromInt(2)
+		capacity      = btcutil.Amount(1_000_000)
+	)
+
+	infos := []*models.ChannelEdgeInfo{
+		{ChannelID: depletedID.ToUint64(), ChannelPoint: depletedPoint},
+		{ChannelID: fullID.ToUint64(), ChannelPoint: fullPoint},
+	}
+	edges := make(map[wire.OutPoint]*models.ChannelEdgePolicy)
+	for _, info := range infos {
+		edges[info.ChannelPoint] = &models.ChannelEdgePolicy{
+			FeeBaseMSat:               1000,
+			FeeProportionalMillionths: 500,
+			TimeLockDelta:             80,
+			MinHTLC:                   1000,
+			MaxHTLC:                   100_000_000,
+		}
+		edges[info.ChannelPoint].MessageFlags =
+			lnwire.ChanUpdateRequiredMaxHtlc
+	}
+
+	localBalances := map[wire.OutPoint]lnwire.MilliSatoshi{
+		depletedPoint: 100_000_000,
+		fullPoint:     900_000_000,
+	}
+
+	var propagated []discovery.EdgeWithInfo
+	manager := &Manager{
+		UpdateForwardingPolicies: func(
+			map[wire.OutPoint]models.ForwardingPolicy) {
+		},
+		PropagateChanPolicyUpdate: func(
+			edgesToUpdate []discovery.EdgeWithInfo) error {
+
+			propagated = append(propagated, edgesToUpdate...)
+
+			return nil
+		},
+		ForAllOutgoingChannels: func(cb func(kvdb.RTx,
+			*models.ChannelEdgeInfo,
+			*models.ChannelEdgePolicy) error) error {
+
+			for _, info := range infos {
+				err := cb(nil, info, edges[info.ChannelPoint])
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		FetchChannel: func(_ kvdb.RTx, chanPoint wire.OutPoint) (
+			*channeldb.OpenChannel, error) {
+
+			bounds := channeldb.ChannelStateBounds{
+				MaxPendingAmount: 990_000_000,
+				MinHTLC:          1000,
+			}
+
+			return &channeldb.OpenChannel{
+				Capacity: capacity,
+				LocalChanCfg: channeldb.ChannelConfig{
+					ChannelStateBounds: bounds,
+				},
+				LocalCommitment: channeldb.ChannelCommitment{
+					LocalBalance: localBalances[chanPoint],
+				},
+			}, nil
+		},
+	}
+
+	cfg := newTestFeeAutopilotConfig()
+	cfg.Manager = manager
+	cfg.QueryForwards = func(q channeldb.ForwardingEventQuery) (
+		channeldb.ForwardingLogTimeSlice, error) {
+
+		require.Equal(t, cfg.Clock.Now(), q.EndTime)
+		require.Equal(
+			t, cfg.Clock.Now().Add(-cfg.ForwardingWindow),
+			q.StartTime,
+		)
+
+		return channeldb.ForwardingLogTimeSlice{
+			ForwardingEvents: []channeldb.ForwardingEvent{{
+				IncomingChanID: fullID,
+				OutgoingChanID: depletedID,
+				AmtIn:          10_000_001,
+				AmtOut:         10_000_000,
+			}},
+		}, nil
+	}
+	testClock := cfg.Clock.(*clock.TestClock)
+
+	autopilot := NewFeeAutopilot(cfg)
+
+	// The depleted channel forwarded, so its fee rate is raised and, as
+	// nothing came in through it, it is given an inbound discount. The
+	// full channel only received and gets cheaper.
+	require.NoError(t, autopilot.adjustFees())
+	require.Len(t, propagated, 2)
+
+	depletedEdge := edges[depletedPoint]
+	require.EqualValues(t, 550, depletedEdge.FeeProportionalMillionths)
+	require.EqualValues(t, 1000, depletedEdge.FeeBaseMSat)
+	require.EqualValues(t, 80, depletedEdge.TimeLockDelta)
+
+	var inboundFee lnwire.Fee
+	_, err := depletedEdge.ExtraOpaqueData.ExtractRecords(&inboundFee)
+	require.NoError(t, err)
+	require.EqualValues(t, -20, inboundFee.FeeRate)
+
+	require.EqualValues(t, 450, edges[fullPoint].FeeProportionalMillionths)
+
+	// Within the cool-down period, the channels are left alone.
+	propagated = nil
+	testClock.SetTime(testClock.Now().Add(cfg.CoolDown / 2))
+	require.NoError(t, autopilot.adjustFees())
+	require.Empty(t, propagated)
+
+	// In dry-run mode, the fee updates are only logged.
+	testClock.SetTime(testClock.Now().Add(cfg.CoolDown))
+	cfg.DryRun = true
+	require.NoError(t, autopilot.adjustFees())
+	require.Empty(t, propagated)
+	require.EqualValues(t, 550, depletedEdge.FeeProportionalMillionths)
+
+	// Once the cool-down period has passed again, the fees are updated.
+	testClock.SetTime(testClock.Now().Add(cfg.CoolDown))
+	cfg.DryRun = false
+	require.NoError(t, autopilot.adjustFees())
+	require.Len(t, propagated, 2)
+	require.EqualValues(t, 605, depletedEdge.FeeProportionalMillionths)
+	require.EqualValues(t, 405, edges[fullPoint].FeeProportionalMillionths)
+}
@@ -0,0 +1,336 @@
+package routing
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errInsufficientFlow is returned when the flow network can't carry the
+// requested amount from the source to the sink.
+var errInsufficientFlow = errors.New("insufficient flow capacity")
+
+// flowArc is a directed arc of a flowNetwork. Every arc is paired with a
+// reverse arc in the adjacency list of its head node, which holds the flow that
+// can be pushed back.
+type flowArc struct {
+	// to is the index of the head node of the arc.
+	to int
+
+	// rev is the index of the paired reverse arc in the adjacency list of
+	// the head node.
+	rev int
+
+	// capacity is the residual capacity of the arc in flow units.
+	capacity int64
+
+	// initialCapacity is the capacity the arc was created with. It is
+	// zero for reverse arcs.
+	initialCapacity int64
+
+	// cost is the cost of pushing a single unit of flow along the arc.
+	cost int64
+
+	// edge is the index of the edge the arc belongs to. Multiple arcs can
+	// belong to the same edge, which is how convex edge costs are
+	// expressed. It is -1 for reverse arcs.
+	edge int
+}
+
+// flow returns the number of units currently flowing along the arc.
+func (a *flowArc) flow() int64 {
+	return a.initialCapacity - a.capacity
+}
+
+// flowNetwork is a directed network with integer arc capacities and per unit
+// arc costs, in which a min-cost flow can be computed. All arc costs must be
+// non-negative.
+type flowNetwork struct {
+	arcs [][]flowArc
+}
+
+// newFlowNetwork creates an empty flow network.
+func newFlowNetwork() *flowNetwork {
+	return &flowNetwork{}
+}
+
+// addNode adds a new node to the network and returns its index.
+func (n *flowNetwork) addNode() int {
+	n.arcs = append(n.arcs, nil)
+
+	return len(n.arcs) - 1
+}
+
+// addArc adds an arc of the given edge from one node to another, along with
+// its reverse arc.
+func (n *flowNetwork) addArc(from, to int, capacity, cost int64, edge int) {
+	n.arcs[from] = append(n.arcs[from], flowArc{
+		to:              to,
+		rev:             len(n.arcs[to]),
+		capacity:        capacity,
+		initialCapacity: capacity,
+		cost:            cost,
+		edge:            edge,
+	})
+
+	n.arcs[to] = append(n.arcs[to], flowArc{
+		to:   from,
+		rev:  len(n.arcs[from]) - 1,
+		cost: -cost,
+		edge: -1,
+	})
+}
+
+// minCostFlow pushes the given amount of flow units from the source to the
+// sink at minimal total cost. It uses successive shortest paths, where every
+// shortest path is found with Dijkstra's algorithm on reduced costs. An error
+// is returned if the network can't carry the full amount.
+func (n *flowNetwork) minCostFlow(source, sink int, amount int64) error {
+	numNodes := len(n.arcs)
+
+	// The node potentials keep the reduced costs of all residual arcs
+	// non-negative. Since all arc costs start out non-negative, zero
+	// potentials are a valid starting point.
+	potential := make([]int64, numNodes)
+
+	dist := make([]int64, numNodes)
+	prevNode := make([]int, numNodes)
+	prevArc := make([]int, numNodes)
+
+	for amount > 0 {
+		for i := range dist {
+			dist[i] = infinity
+			prevNode[i] = -1
+		}
+		dist[source] = 0
+
+		queue := &flowQueue{{node: source}}
+		for queue.Len() > 0 {
+			item := heap.Pop(queue).(flowQueueItem)
+			if item.dist > dist[item.node] {
+				continue
+			}
+
+			for i := range n.arcs[item.node] {
+				arc := &n.arcs[item.node][i]
+				if arc.capacity == 0 {
+					continue
+				}
+
+				reducedCost := arc.cost + potential[item.node] -
+					potential[arc.to]
+
+				newDist := item.dist + reducedCost
+				if newDist >= dist[arc.to] {
+					continue
+				}
+
+				dist[arc.to] = newDist
+				prevNode[arc.to] = item.node
+				prevArc[arc.to] = i
+
+				heap.Push(queue, flowQueueItem{
+					node: arc.to,
+					dist: newDist,
+				})
+			}
+		}
+
+		if dist[sink] == infinity {
+			return errInsufficientFlow
+		}
+
+		// Update the potentials so that the reduced costs of all
+		// residual arcs stay non-negative. Distances are capped at the
+		// distance of the sink, which also covers the nodes that
+		// weren't reached or settled by the search.
+		for i := range potential {
+			if dist[i] < dist[sink] {
+				potential[i] += dist[i]
+			} else {
+				potential[i] += dist[sink]
+			}
+		}
+
+		// Find the bottleneck of the shortest path and push as much
+		// flow along it as possible.
+		push := amount
+		for node := sink; node != source; node = prevNode[node] {
+			arc := &n.arcs[prevNode[node]][prevArc[node]]
+			if arc.capacity < push {
+				push = arc.capacity
+			}
+		}
+
+		for node := sink; node != source; node = prevNode[node] {
+			arc := &n.arcs[prevNode[node]][prevArc[node]]
+			arc.capacity -= push
+			n.arcs[arc.to][arc.rev].capacity += push
+		}
+
+		amount -= push
+	}
+
+	return nil
+}
+
+// flowPath is a path from the source to the sink of a flow network, along with
+// the number of flow units it carries.
+type flowPath struct {
+	// edges are the indices of the edges along the path, in forward
+	// order.
+	edges []int
+
+	// units is the number of flow units carried by the path.
+	units int64
+}
+
+// decompose splits the flow of the network into paths from the source to the
+// sink. Cycles in the flow don't carry anything to the sink and are dropped.
+// Paths along the same edges are merged, even if they use different arcs of
+// those edges. The flow of the network is consumed in the process.
+func (n *flowNetwork) decompose(source, sink int) []*flowPath {
+	var paths []*flowPath
+
+	// pathIndex maps the edges of a path to its index in paths.
+	pathIndex := make(map[string]int)
+
+	// nextArc returns the index of an arc leaving the given node that
+	// still carries flow, or -1 if there is none.
+	nextArc := func(node int) int {
+		for i := range n.arcs[node] {
+			if n.arcs[node][i].edge >= 0 &&
+				n.arcs[node][i].flow() > 0 {
+
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	for nextArc(source) != -1 {
+		var (
+			nodes = []int{source}
+			arcs  []int
+			seen  = map[int]int{source: 0}
+		)
+
+		for node := source; node != sink; {
+			i := nextArc(node)
+
+			// Flow is conserved at all nodes other than the source
+			// and the sink, so this can't happen unless the flow was
+			// tampered with.
+			if i == -1 {
+				return paths
+			}
+
+			arcs = append(arcs, i)
+			node = n.arcs[node][i].to
+
+			// If we've been at this node before, the walk contains
+			// a cycle. Remove the flow of the cycle and continue the
+			// walk from where it started.
+			pos, ok := seen[node]
+			if !ok {
+				seen[node] = len(nodes)
+				nodes = append(nodes, node)
+
+				continue
+			}
+
+			n.removeFlow(nodes[pos:], arcs[pos:])
+			for _, cycleNode := range nodes[pos+1:] {
+				delete(seen, cycleNode)
+			}
+			nodes = nodes[:pos+1]
+			arcs = arcs[:pos]
+		}
+
+		units := n.removeFlow(nodes, arcs)
+
+		edges := make([]int, 0, len(arcs))
+		for i, arc := range arcs {
+			edges = append(edges, n.arcs[nodes[i]][arc].edge)
+		}
+
+		key := fmt.Sprint(edges)
+		if i, ok := pathIndex[key]; ok {
+			paths[i].units += units
+			continue
+		}
+
+		pathIndex[key] = len(paths)
+		paths = append(paths, &flowPath{
+			edges: edges,
+			units: units,
+		})
+	}
+
+	return paths
+}
+
+// removeFlow removes the largest possible amount of flow along the given walk,
+// which is described by the nodes it visits and the arc taken at each of them.
+// The amount removed is returned.
+func (n *flowNetwork) removeFlow(nodes []int, arcs []int) int64 {
+	units := int64(math.MaxInt64)
+	for i, arc := range arcs {
+		if flow := n.arcs[nodes[i]][arc].flow(); flow < units {
+			units = flow
+		}
+	}
+
+	for i, arc := range arcs {
+		n.arcs[nodes[i]][arc].capacity += units
+	}
+
+	return units
+}
+
+// flowQueueItem is a node in the priority queue of the shortest path search of
+// the min-cost flow computation.
+type flowQueueItem struct {
+	node int
+	dist int64
+}
+
+// flowQueue is a min-distance heap of nodes. Nodes may be pushed multiple
+// times, stale entries are skipped when popped.
+type flowQueue []flowQueueItem
+
+// Len returns the number of items in the queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (q flowQueue) Len() int { return len(q) }
+
+// Less returns whether the item at index i has a smaller distance than the item
+// at index j.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (q flowQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+
+// Swap swaps the items at the given indices.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (q flowQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+// Push adds an item to the queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (q *flowQueue) Push(x interface{}) {
+	*q = append(*q, x.(flowQueueItem))
+}
+
+// Pop removes the last item of the queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (q *flowQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return item
+}
@@ -0,0 +1,131 @@
+package routing
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMinCostFlow asserts that the min-cost flow fills the cheapest paths first
+// and that the flow is decomposed into paths that carry the full amount.
+func TestMinCostFlow(t *testing.T) {
+	t.Parallel()
+
+	// Create a network with two paths from the source to the sink. The
+	// upper path is cheap but only has room for three units, and the
+	// convex cost of its second arc makes the lower path preferable for
+	// everything beyond two units.
+	//
+	//        1
+	//      /   \
+	//     0     3
+	//      \   /
+	//        2
+	n := newFlowNetwork()
+	for i := 0; i < 4; i++ {
+		n.addNode()
+	}
+
+	n.addArc(0, 1, 3, 1, 0)
+	n.addArc(1, 3, 2, 1, 1)
+	n.addArc(1, 3, 1, 10, 1)
+	n.addArc(0, 2, 10, 2, 2)
+	n.addArc(2, 3, 10, 2, 3)
+
+	require.NoError(t, n.minCostFlow(0, 3, 5))
+
+	paths := n.decompose(0, 3)
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].edges[0] < paths[j].edges[0]
+	})
+
+	require.Equal(t, []*flowPath{
+		{edges: []int{0, 1}, units: 2},
+		{edges: []int{2, 3}, units: 3},
+	}, paths)
+}
+
+// TestMinCostFlowRerouting asserts that the min-cost flow pushes back flow
+// that was sent along an earlier shortest path, if that is needed to carry the
+// full amount.
+func TestMinCostFlowRerouting(t *testing.T) {
+	t.Parallel()
+
+	// The cheapest path 0 -> 1 -> 2 -> 3 blocks both the arcs that the
+	// second unit needs. Carrying two units requires using 0 -> 1 -> 3
+	// and 0 -> 2 -> 3 instead.
+	//
+	//     0 ---> 1
+	//     |    / |
+	//     v  v   v
+	//     2 ---> 3
+	n := newFlowNetwork()
+	for i := 0; i < 4; i++ {
+		n.addNode()
+	}
+
+	n.addArc(0, 1, 1, 1, 0)
+	n.addArc(1, 2, 1, 1, 1)
+	n.addArc(2, 3, 1, 1, 2)
+	n.addArc(1, 3, 1, 5, 3)
+	n.addArc(0, 2, 1, 5, 4)
+
+	require.NoError(t, n.minCostFlow(0, 3, 2))
+
+	paths := n.decompose(0, 3)
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].edges[0] < paths[j].edges[0]
+	})
+
+	require.Equal(t, []*flowPath{
+		{edges: []int{0, 3}, units: 1},
+		{edges: []int{4, 2}, units: 1},
+	}, paths)
+}
+
+// TestMinCostFlowInsufficient asserts that an error is returned if the network
+// can't carry the requested amount.
+func TestMinCostFlowInsufficient(t *testing.T) {
+	t.Parallel()
+
+	n := newFlowNetwork()
+	for i := 0; i < 3; i++ {
+		n.addNode()
+	}
+
+	n.addArc(0, 1, 5, 1, 0)
+	n.addArc(1, 2, 2, 1, 1)
+
+	require.ErrorIs(t, n.minCostFlow(0, 2, 3), errInsufficientFlow)
+}
+
+// TestFlowDecomposeCycle asserts that cycles in the flow are dropped when the
+// flow is decomposed into paths.
+func TestFlowDecomposeCycle(t *testing.T) {
+	t.Parallel()
+
+	n := newFlowNetwork()
+	for i := 0; i < 4; i++ {
+		n.addNode()
+	}
+
+	// Set up a flow of two units from 0 to 3 through 1, with a cycle of
+	// one unit between 1 and 2.
+	n.addArc(0, 1, 2, 0, 0)
+	n.addArc(1, 2, 1, 0, 1)
+	n.addArc(2, 1, 1, 0, 2)
+	n.addArc(1, 3, 2, 0, 3)
+
+	for node := range n.arcs {
+		for i := range n.arcs[node] {
+			if n.arcs[node][i].edge >= 0 {
+				n.arcs[node][i].capacity = 0
+			}
+		}
+	}
+
+	require.Equal(t, []*flowPath{
+		{edges: []int{0, 3}, units: 2},
+	}, n.decompose(0, 3))
+}
@@ -68,6 +68,10 @@ const (
 	// FeeEstimationTimeout. It defines the maximum duration that the
 	// probing fee estimation is allowed to take.
 	DefaultFeeEstimationTimeout = time.Minute
+
+	// DefaultMissionControlNamespace is the namespace of the mission
+	// control that is used when no namespace is specified.
+	DefaultMissionControlNamespace = "default"
 )
 
 var (
@@ -105,6 +109,10 @@ type MissionControl struct {
 	// selfNode is our pubkey.
 	selfNode route.Vertex
 
+	// namespace is the namespace of this mission control. The results of
+	// each namespace are collected and persisted separately.
+	namespace string
+
 	store *missionControlStore
 
 	// estimator is the probability estimator that is used with the payment
@@ -115,6 +123,11 @@ type MissionControl struct {
 	// mission control state is updated.
 	onConfigUpdate fn.Option[func(cfg *MissionControlConfig)]
 
+	// onPaymentFailure is a function that is called whenever a failure is
+	// recorded for a node or node pairs.
+	onPaymentFailure fn.Option[func(nodeFailure *route.Vertex,
+		pairFailures []DirectedNodePair)]
+
 	sync.Mutex
 
 	// TODO(roasbeef): further counters, if vertex continually unavailable,
@@ -133,6 +146,11 @@ type MissionControlConfig struct {
 	// mission control state is updated.
 	OnConfigUpdate fn.Option[func(cfg *MissionControlConfig)]
 
+	// OnPaymentFailure is a function that is called whenever a payment
+	// result leads to a failure being recorded for a node or node pairs.
+	OnPaymentFailure fn.Option[func(nodeFailure *route.Vertex,
+		pairFailures []DirectedNodePair)]
+
 	// MaxMcHistory defines the maximum number of payment results that are
 	// held on disk.
 	MaxMcHistory int
@@ -214,19 +232,28 @@ type paymentResult struct {
 	failure            lnwire.FailureMessage
 }
 
-// NewMissionControl returns a new instance of missionControl.
+// NewMissionControl returns a new instance of missionControl for the default
+// namespace.
 func NewMissionControl(db kvdb.Backend, self route.Vertex,
 	cfg *MissionControlConfig) (*MissionControl, error) {
 
-	log.Debugf("Instantiating mission control with config: %v, %v", cfg,
-		cfg.Estimator)
+	return newMissionControl(db, DefaultMissionControlNamespace, self, cfg)
+}
+
+// newMissionControl returns a new instance of missionControl for the given
+// namespace.
+func newMissionControl(db kvdb.Backend, namespace string, self route.Vertex,
+	cfg *MissionControlConfig) (*MissionControl, error) {
+
+	log.Debugf("Instantiating mission control of namespace %v with "+
+		"config: %v, %v", namespace, cfg, cfg.Estimator)
 
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
 
 	store, err := newMissionControlStore(
-		db, cfg.MaxMcHistory, cfg.McFlushInterval,
+		db, namespace, cfg.MaxMcHistory, cfg.McFlushInterval,
 	)
 	if err != nil {
 		return nil, err
@@ -236,11 +263,13 @@ func NewMissionControl(db kvdb.Backend, self route.Vertex,
 		state: newMissionControlState(
 			cfg.MinFailureRelaxInterval,
 		),
-		now:            time.Now,
-		selfNode:       self,
-		store:          store,
-		estimator:      cfg.Estimator,
-		onConfigUpdate: cfg.OnConfigUpdate,
+		now:              time.Now,
+		selfNode:         self,
+		namespace:        namespace,
+		store:            store,
+		estimator:        cfg.Estimator,
+		onConfigUpdate:   cfg.OnConfigUpdate,
+		onPaymentFailure: cfg.OnPaymentFailure,
 	}
 
 	if err := mc.init(); err != nil {
@@ -313,8 +342,8 @@ func (m *MissionControl) SetConfig(cfg *MissionControlConfig) error {
 	m.Lock()
 	defer m.Unlock()
 
-	log.Infof("Active mission control cfg: %v, estimator: %v", cfg,
-		cfg.Estimator)
+	log.Infof("Active mission control cfg of namespace %v: %v, "+
+		"estimator: %v", m.namespace, cfg, cfg.Estimator)
 
 	m.store.maxRecords = cfg.MaxMcHistory
 	m.state.minFailureRelaxInterval = cfg.MinFailureRelaxInterval
@@ -525,6 +554,7 @@ func (m *MissionControl) applyPaymentResult(
 		m.state.setAllFail(*i.nodeFailure, result.timeReply)
 	}
 
+	var pairFailures []DirectedNodePair
 	for pair, pairResult := range i.pairResults {
 		pairResult := pairResult
 
@@ -536,6 +566,8 @@ func (m *MissionControl) applyPaymentResult(
 			log.Debugf("Reporting pair failure to Mission "+
 				"Control: pair=%v, amt=%v",
 				pair, pairResult.amt)
+
+			pairFailures = append(pairFailures, pair)
 		}
 
 		m.state.setLastPairResult(
@@ -543,5 +575,13 @@ func (m *MissionControl) applyPaymentResult(
 		)
 	}
 
+	if i.nodeFailure != nil || len(pairFailures) > 0 {
+		m.onPaymentFailure.WhenSome(func(f func(*route.Vertex,
+			[]DirectedNodePair)) {
+
+			f(i.nodeFailure, pairFailures)
+		})
+	}
+
 	return i.finalFailureReason
 }
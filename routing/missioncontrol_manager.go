@@ -0,0 +1,183 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ErrEmptyMissionControlNamespace is returned when a mission control
+// namespace without a name is requested.
+var ErrEmptyMissionControlNamespace = errors.New("mission control namespace " +
+	"must not be empty")
+
+// ErrUnknownMissionControlNamespace is returned when a mission control
+// namespace that doesn't exist is looked up without creating it.
+var ErrUnknownMissionControlNamespace = errors.New("unknown mission control " +
+	"namespace")
+
+// MissionControlManager manages a set of mission controls that are each
+// identified by a namespace. Every namespace collects its own payment results
+// and has its own config, so that for example probes don't affect the success
+// estimates of regular payments. The results of each namespace are persisted
+// separately.
+type MissionControlManager struct {
+	db       kvdb.Backend
+	selfNode route.Vertex
+
+	// cfg is the config that new namespaces are created with. Their config
+	// can be changed independently afterwards.
+	cfg *MissionControlConfig
+
+	// mcs holds the mission control of each namespace.
+	mcs map[string]*MissionControl
+
+	// running indicates whether the store tickers are running, in which
+	// case the tickers of new namespaces are started right away.
+	running bool
+
+	sync.Mutex
+}
+
+// NewMissionControlManager creates a new mission control manager and loads the
+// mission controls of the default namespace and all namespaces that were
+// persisted before. Only the mission control of the default namespace reports
+// config updates and failures through the OnConfigUpdate and OnPaymentFailure
+// callbacks of the config.
+func NewMissionControlManager(db kvdb.Backend, self route.Vertex,
+	cfg *MissionControlConfig) (*MissionControlManager, error) {
+
+	m := &MissionControlManager{
+		db:       db,
+		selfNode: self,
+		cfg:      cfg,
+		mcs:      make(map[string]*MissionControl),
+	}
+
+	defaultMc, err := NewMissionControl(db, self, cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.mcs[DefaultMissionControlNamespace] = defaultMc
+
+	namespaces, err := fetchNamespaces(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namespace := range namespaces {
+		if _, err := m.createNamespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// createNamespace creates the mission control of the given namespace. The
+// caller must either hold the lock of the manager or have exclusive access to
+// it.
+func (m *MissionControlManager) createNamespace(
+	namespace string) (*MissionControl, error) {
+
+	cfg := *m.cfg
+	cfg.OnConfigUpdate = fn.None[func(cfg *MissionControlConfig)]()
+	cfg.OnPaymentFailure = fn.None[func(*route.Vertex,
+		[]DirectedNodePair)]()
+
+	mc, err := newMissionControl(m.db, namespace, m.selfNode, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.mcs[namespace] = mc
+
+	if m.running {
+		mc.RunStoreTicker()
+	}
+
+	return mc, nil
+}
+
+// DefaultMissionControl returns the mission control of the default namespace.
+func (m *MissionControlManager) DefaultMissionControl() *MissionControl {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.mcs[DefaultMissionControlNamespace]
+}
+
+// GetNamespacedStore returns the mission control of the given namespace. If
+// the namespace doesn't exist yet, it is created with the config that the
+// manager was created with.
+func (m *MissionControlManager) GetNamespacedStore(
+	namespace string) (*MissionControl, error) {
+
+	if namespace == "" {
+		return nil, ErrEmptyMissionControlNamespace
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if mc, ok := m.mcs[namespace]; ok {
+		return mc, nil
+	}
+
+	log.Infof("Creating mission control namespace %v", namespace)
+
+	return m.createNamespace(namespace)
+}
+
+// FetchNamespacedStore returns the mission control of an existing namespace.
+// Unlike GetNamespacedStore, it doesn't create the namespace, so that callers
+// that only read from mission control can't create namespaces by accident.
+func (m *MissionControlManager) FetchNamespacedStore(
+	namespace string) (*MissionControl, error) {
+
+	if namespace == "" {
+		return nil, ErrEmptyMissionControlNamespace
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	mc, ok := m.mcs[namespace]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v",
+			ErrUnknownMissionControlNamespace, namespace)
+	}
+
+	return mc, nil
+}
+
+// RunStoreTickers runs the store tickers of the mission controls of all
+// namespaces.
+func (m *MissionControlManager) RunStoreTickers() {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, mc := range m.mcs {
+		mc.RunStoreTicker()
+	}
+	m.running = true
+}
+
+// StopStoreTickers stops the store tickers of the mission controls of all
+// namespaces.
+func (m *MissionControlManager) StopStoreTickers() {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.running {
+		return
+	}
+
+	for _, mc := range m.mcs {
+		mc.StopStoreTicker()
+	}
+	m.running = false
+}
@@ -0,0 +1,114 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMissionControlNamespaces asserts that the mission controls of different
+// namespaces collect and persist their results separately, and that their
+// configs can be changed independently.
+func TestMissionControlNamespaces(t *testing.T) {
+	ctx := createMcTestContext(t)
+
+	estimator, err := NewAprioriEstimator(AprioriConfig{
+		PenaltyHalfLife:       testPenaltyHalfLife,
+		AprioriHopProbability: testAprioriHopProbability,
+		AprioriWeight:         testAprioriWeight,
+		CapacityFraction:      testCapacityFraction,
+	})
+	require.NoError(t, err)
+
+	cfg := &MissionControlConfig{Estimator: estimator}
+	newManager := func() *MissionControlManager {
+		mgr, err := NewMissionControlManager(ctx.db, mcTestSelf, cfg)
+		require.NoError(t, err)
+
+		return mgr
+	}
+	mgr := newManager()
+
+	_, err = mgr.GetNamespacedStore("")
+	require.ErrorIs(t, err, ErrEmptyMissionControlNamespace)
+	_, err = mgr.FetchNamespacedStore("")
+	require.ErrorIs(t, err, ErrEmptyMissionControlNamespace)
+
+	// Fetching a namespace that doesn't exist doesn't create it.
+	_, err = mgr.FetchNamespacedStore("probes")
+	require.ErrorIs(t, err, ErrUnknownMissionControlNamespace)
+	_, err = mgr.FetchNamespacedStore("probes")
+	require.ErrorIs(t, err, ErrUnknownMissionControlNamespace)
+
+	defaultMc := mgr.DefaultMissionControl()
+	probeMc, err := mgr.GetNamespacedStore("probes")
+	require.NoError(t, err)
+	require.NotSame(t, defaultMc, probeMc)
+
+	// Asking for the same namespace again returns the same instance.
+	sameMc, err := mgr.GetNamespacedStore("probes")
+	require.NoError(t, err)
+	require.Same(t, probeMc, sameMc)
+	sameMc, err = mgr.FetchNamespacedStore("probes")
+	require.NoError(t, err)
+	require.Same(t, probeMc, sameMc)
+
+	// A failure reported to the probe namespace doesn't affect the
+	// estimates of the default namespace.
+	errorSourceIdx := 1
+	_, err = probeMc.ReportPaymentFail(
+		0, mcTestRoute, &errorSourceIdx,
+		lnwire.NewTemporaryChannelFailure(nil),
+	)
+	require.NoError(t, err)
+
+	probability := func(mc *MissionControl) float64 {
+		return mc.GetProbability(
+			mcTestNode1, mcTestNode2, 1000, testCapacity,
+		)
+	}
+	require.InDelta(t, 0, probability(probeMc), 0.001)
+	require.InDelta(
+		t, testAprioriHopProbability, probability(defaultMc), 0.001,
+	)
+
+	// The results of each namespace are persisted separately, and the
+	// namespaces are loaded again on startup.
+	require.NoError(t, probeMc.store.storeResults())
+	require.NoError(t, defaultMc.store.storeResults())
+
+	mgr = newManager()
+	defaultMc = mgr.DefaultMissionControl()
+	probeMc, err = mgr.GetNamespacedStore("probes")
+	require.NoError(t, err)
+
+	require.InDelta(t, 0, probability(probeMc), 0.001)
+	require.Len(t, probeMc.GetHistorySnapshot().Pairs, 2)
+	require.Empty(t, defaultMc.GetHistorySnapshot().Pairs)
+
+	// The config of a namespace can be changed without affecting the
+	// other namespaces.
+	probeCfg := probeMc.GetConfig()
+	probeCfg.MaxMcHistory = 10
+	require.NoError(t, probeMc.SetConfig(probeCfg))
+	require.Equal(t, 10, probeMc.GetConfig().MaxMcHistory)
+	require.Equal(t, cfg.MaxMcHistory, defaultMc.GetConfig().MaxMcHistory)
+
+	// Resetting a namespace only clears its own results.
+	_, err = defaultMc.ReportPaymentFail(
+		1, mcTestRoute, &errorSourceIdx,
+		lnwire.NewTemporaryChannelFailure(nil),
+	)
+	require.NoError(t, err)
+	require.NoError(t, defaultMc.store.storeResults())
+
+	require.NoError(t, probeMc.ResetHistory())
+
+	mgr = newManager()
+	probeMc, err = mgr.GetNamespacedStore("probes")
+	require.NoError(t, err)
+
+	require.Empty(t, probeMc.GetHistorySnapshot().Pairs)
+	require.Len(t, mgr.DefaultMissionControl().GetHistorySnapshot().Pairs, 2)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -20,6 +21,12 @@ var (
 	// stored.
 	resultsKey = []byte("missioncontrol-results")
 
+	// namespacesKey is the fixed key of the bucket that holds a nested
+	// results bucket for each mission control namespace other than the
+	// default one. The results of the default namespace are kept in the
+	// results bucket to stay compatible with existing databases.
+	namespacesKey = []byte("missioncontrol-namespaces")
+
 	// Big endian is the preferred byte order, due to cursor scans over
 	// integer keys iterating in order.
 	byteOrder = binary.BigEndian
@@ -42,6 +49,10 @@ type missionControlStore struct {
 	wg   sync.WaitGroup
 	db   kvdb.Backend
 
+	// namespace is the mission control namespace that the results of this
+	// store belong to.
+	namespace string
+
 	// queueCond is signalled when items are put into the queue.
 	queueCond *sync.Cond
 
@@ -66,7 +77,7 @@ type missionControlStore struct {
 	flushInterval time.Duration
 }
 
-func newMissionControlStore(db kvdb.Backend, maxRecords int,
+func newMissionControlStore(db kvdb.Backend, namespace string, maxRecords int,
 	flushInterval time.Duration) (*missionControlStore, error) {
 
 	var (
@@ -76,7 +87,7 @@ func newMissionControlStore(db kvdb.Backend, maxRecords int,
 
 	// Create buckets if not yet existing.
 	err := kvdb.Update(db, func(tx kvdb.RwTx) error {
-		resultsBucket, err := tx.CreateTopLevelBucket(resultsKey)
+		resultsBucket, err := createResultsBucket(tx, namespace)
 		if err != nil {
 			return fmt.Errorf("cannot create results bucket: %w",
 				err)
@@ -99,11 +110,13 @@ func newMissionControlStore(db kvdb.Backend, maxRecords int,
 		return nil, err
 	}
 
-	log.Infof("Loaded %d mission control entries", len(keysMap))
+	log.Infof("Loaded %d mission control entries of namespace %v",
+		len(keysMap), namespace)
 
 	return &missionControlStore{
 		done:          make(chan struct{}),
 		db:            db,
+		namespace:     namespace,
 		queueCond:     sync.NewCond(&sync.Mutex{}),
 		queue:         list.New(),
 		keys:          keys,
@@ -113,17 +126,115 @@ func newMissionControlStore(db kvdb.Backend, maxRecords int,
 	}, nil
 }
 
+// createResultsBucket creates the results bucket of the given namespace if it
+// doesn't exist yet and returns it.
+func createResultsBucket(tx kvdb.RwTx, namespace string) (kvdb.RwBucket,
+	error) {
+
+	if namespace == DefaultMissionControlNamespace {
+		return tx.CreateTopLevelBucket(resultsKey)
+	}
+
+	namespacesBucket, err := tx.CreateTopLevelBucket(namespacesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return namespacesBucket.CreateBucketIfNotExists([]byte(namespace))
+}
+
+// deleteResultsBucket deletes the results bucket of the given namespace.
+func deleteResultsBucket(tx kvdb.RwTx, namespace string) error {
+	if namespace == DefaultMissionControlNamespace {
+		return tx.DeleteTopLevelBucket(resultsKey)
+	}
+
+	namespacesBucket := tx.ReadWriteBucket(namespacesKey)
+	if namespacesBucket == nil {
+		return nil
+	}
+
+	err := namespacesBucket.DeleteNestedBucket([]byte(namespace))
+	if err != nil && !errors.Is(err, kvdb.ErrBucketNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// fetchResultsBucket returns the results bucket of the given namespace, or nil
+// if it doesn't exist.
+func fetchResultsBucket(tx kvdb.RTx, namespace string) kvdb.RBucket {
+	if namespace == DefaultMissionControlNamespace {
+		return tx.ReadBucket(resultsKey)
+	}
+
+	namespacesBucket := tx.ReadBucket(namespacesKey)
+	if namespacesBucket == nil {
+		return nil
+	}
+
+	return namespacesBucket.NestedReadBucket([]byte(namespace))
+}
+
+// fetchRwResultsBucket returns the results bucket of the given namespace for
+// writing, or nil if it doesn't exist.
+func fetchRwResultsBucket(tx kvdb.RwTx, namespace string) kvdb.RwBucket {
+	if namespace == DefaultMissionControlNamespace {
+		return tx.ReadWriteBucket(resultsKey)
+	}
+
+	namespacesBucket := tx.ReadWriteBucket(namespacesKey)
+	if namespacesBucket == nil {
+		return nil
+	}
+
+	return namespacesBucket.NestedReadWriteBucket([]byte(namespace))
+}
+
+// fetchNamespaces returns the names of all mission control namespaces other
+// than the default one that have results buckets in the db.
+func fetchNamespaces(db kvdb.Backend) ([]string, error) {
+	var namespaces []string
+
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		namespacesBucket := tx.ReadBucket(namespacesKey)
+		if namespacesBucket == nil {
+			return nil
+		}
+
+		return namespacesBucket.ForEach(func(k, v []byte) error {
+			// Namespaces are stored as nested buckets, which
+			// don't have a value.
+			if v != nil {
+				return nil
+			}
+
+			namespaces = append(namespaces, string(k))
+
+			return nil
+		})
+	}, func() {
+		namespaces = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
 // clear removes all results from the db.
 func (b *missionControlStore) clear() error {
 	b.queueCond.L.Lock()
 	defer b.queueCond.L.Unlock()
 
 	err := kvdb.Update(b.db, func(tx kvdb.RwTx) error {
-		if err := tx.DeleteTopLevelBucket(resultsKey); err != nil {
+		if err := deleteResultsBucket(tx, b.namespace); err != nil {
 			return err
 		}
 
-		_, err := tx.CreateTopLevelBucket(resultsKey)
+		_, err := createResultsBucket(tx, b.namespace)
 		return err
 	}, func() {})
 
@@ -140,7 +251,11 @@ func (b *missionControlStore) fetchAll() ([]*paymentResult, error) {
 	var results []*paymentResult
 
 	err := kvdb.View(b.db, func(tx kvdb.RTx) error {
-		resultBucket := tx.ReadBucket(resultsKey)
+		resultBucket := fetchResultsBucket(tx, b.namespace)
+		if resultBucket == nil {
+			return fmt.Errorf("results bucket of namespace %v "+
+				"not found", b.namespace)
+		}
 		results = make([]*paymentResult, 0)
 
 		return resultBucket.ForEach(func(k, v []byte) error {
@@ -427,7 +542,11 @@ func (b *missionControlStore) storeResults() error {
 	}
 
 	err := kvdb.Update(b.db, func(tx kvdb.RwTx) error {
-		bucket := tx.ReadWriteBucket(resultsKey)
+		bucket := fetchRwResultsBucket(tx, b.namespace)
+		if bucket == nil {
+			return fmt.Errorf("results bucket of namespace %v "+
+				"not found", b.namespace)
+		}
 
 		for e := l.Front(); e != nil; e = e.Next() {
 			pr, ok := e.Value.(*paymentResult)
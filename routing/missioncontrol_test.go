@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/kvdb"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
@@ -230,3 +231,37 @@ func TestMissionControlChannelUpdate(t *testing.T) {
 	)
 	ctx.expectP(100, 0)
 }
+
+// TestMissionControlOnPaymentFailure tests that the failures recorded by
+// mission control are reported through the OnPaymentFailure callback.
+func TestMissionControlOnPaymentFailure(t *testing.T) {
+	ctx := createMcTestContext(t)
+
+	var (
+		nodeFailures []*route.Vertex
+		pairFailures [][]DirectedNodePair
+	)
+	ctx.mc.onPaymentFailure = fn.Some(func(node *route.Vertex,
+		pairs []DirectedNodePair) {
+
+		nodeFailures = append(nodeFailures, node)
+		pairFailures = append(pairFailures, pairs)
+	})
+
+	// Successes aren't reported.
+	ctx.reportSuccess()
+	require.Empty(t, pairFailures)
+
+	// A channel failure is reported as a failure of the pair.
+	ctx.reportFailure(1000, lnwire.NewTemporaryChannelFailure(nil))
+	require.Len(t, pairFailures, 1)
+	require.Nil(t, nodeFailures[0])
+	require.Equal(t, []DirectedNodePair{
+		NewDirectedNodePair(mcTestNode1, mcTestNode2),
+	}, pairFailures[0])
+
+	// A node level failure is reported as a failure of the node.
+	ctx.reportFailure(0, lnwire.NewExpiryTooSoon(lnwire.ChannelUpdate{}))
+	require.Len(t, nodeFailures, 2)
+	require.Equal(t, &mcTestNode1, nodeFailures[1])
+}
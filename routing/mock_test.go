@@ -9,7 +9,9 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/record"
@@ -880,6 +882,10 @@ type mockLink struct {
 	bandwidth         lnwire.MilliSatoshi
 	mayAddOutgoingErr error
 	ineligible        bool
+
+	// htlcSlots is the number of free htlc slots of the link. If unset,
+	// the link has the maximum number of free slots.
+	htlcSlots fn.Option[uint16]
 }
 
 // Bandwidth returns the bandwidth the mock was configured with.
@@ -897,6 +903,12 @@ func (m *mockLink) MayAddOutgoingHtlc(_ lnwire.MilliSatoshi) error {
 	return m.mayAddOutgoingErr
 }
 
+// AvailableOutgoingHtlcSlots returns the number of free htlc slots configured
+// in our mock.
+func (m *mockLink) AvailableOutgoingHtlcSlots() (uint16, error) {
+	return m.htlcSlots.UnwrapOr(input.MaxHTLCNumber / 2), nil
+}
+
 type mockShardTracker struct {
 	mock.Mock
 }
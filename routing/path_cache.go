@@ -0,0 +1,699 @@
+package routing
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/graph"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	// errPathCacheMiss is returned when the path cache holds no usable
+	// path for a payment.
+	errPathCacheMiss = errors.New("path cache miss")
+
+	// pathCacheBucket is the top level bucket that holds the destinations
+	// of the path cache, so that their paths can be pre-computed after a
+	// restart.
+	pathCacheBucket = []byte("path-cache-destinations")
+)
+
+const (
+	// pathCacheKeyLen is the length of a serialized path cache key: the
+	// target, the amount bucket and the time preference.
+	pathCacheKeyLen = route.VertexSize + 1 + 8
+)
+
+// PathCacheConfig holds the configuration and dependencies of the path
+// cache.
+type PathCacheConfig struct {
+	// Size is the maximum number of destinations that the cache holds. If
+	// the cache is full, the least recently used destination is evicted.
+	Size int
+
+	// TTL is the time after which a cached path expires. Paths expire so
+	// that better paths, for example through new channels or through pairs
+	// whose penalty has decayed in mission control, are eventually picked
+	// up. Expired paths are pre-computed again.
+	TTL time.Duration
+
+	// DB is the database that the destinations of the cache are persisted
+	// in. If it is nil, the cache starts empty after every restart.
+	DB kvdb.Backend
+
+	// SubscribeTopology returns a subscription to the changes of the
+	// channel graph, which are used to invalidate cached paths.
+	SubscribeTopology func() (*graph.TopologyClient, error)
+
+	// NewPaymentSession creates the payment session that pre-computes the
+	// path to a cached destination. The session is expected to add the
+	// path it finds to the cache. If it is nil, paths are only cached once
+	// a payment found them.
+	NewPaymentSession func(*LightningPayment) (PaymentSession, error)
+
+	// BestHeight returns the current block height, which the paths are
+	// pre-computed for.
+	BestHeight func() (uint32, error)
+
+	// CltvLimit is the maximum total time lock of the pre-computed paths.
+	CltvLimit uint32
+
+	// FinalCltvDelta is the final cltv delta that the paths are
+	// pre-computed for.
+	FinalCltvDelta uint16
+
+	// Clock is used to expire cached paths.
+	Clock clock.Clock
+}
+
+// pathCacheKey identifies a cached path. Payments to the same destination
+// whose amounts lie in the same power of two bucket share their cached path.
+type pathCacheKey struct {
+	target    route.Vertex
+	amtBucket int
+	timePref  float64
+}
+
+// newPathCacheKey returns the cache key of a payment of the given amount.
+func newPathCacheKey(target route.Vertex, amt lnwire.MilliSatoshi,
+	timePref float64) pathCacheKey {
+
+	return pathCacheKey{
+		target:    target,
+		amtBucket: bits.Len64(uint64(amt)),
+		timePref:  timePref,
+	}
+}
+
+// serialize returns the database key of the cache key.
+func (k pathCacheKey) serialize() []byte {
+	var b [pathCacheKeyLen]byte
+	copy(b[:], k.target[:])
+	b[route.VertexSize] = byte(k.amtBucket)
+	byteOrder.PutUint64(
+		b[route.VertexSize+1:], math.Float64bits(k.timePref),
+	)
+
+	return b[:]
+}
+
+// deserializePathCacheKey parses a cache key from its database key.
+func deserializePathCacheKey(b []byte) (pathCacheKey, error) {
+	if len(b) != pathCacheKeyLen {
+		return pathCacheKey{}, fmt.Errorf("invalid path cache key "+
+			"length %v", len(b))
+	}
+
+	var key pathCacheKey
+	copy(key.target[:], b)
+	key.amtBucket = int(b[route.VertexSize])
+	key.timePref = math.Float64frombits(
+		byteOrder.Uint64(b[route.VertexSize+1:]),
+	)
+
+	return key, nil
+}
+
+// pathCacheEntry is a destination held by the path cache.
+type pathCacheEntry struct {
+	key pathCacheKey
+
+	// amt is the amount of the last payment to the destination, which its
+	// path is pre-computed for.
+	amt lnwire.MilliSatoshi
+
+	// path is the path to the target in forward order, starting with our
+	// own channel. It is nil if the path was invalidated or expired and
+	// still needs to be pre-computed.
+	path []*unifiedEdge
+
+	// expiry is the time at which the path expires.
+	expiry time.Time
+
+	// retry is the time after which a path that couldn't be pre-computed
+	// is tried again.
+	retry time.Time
+}
+
+// hasChannel returns true if the path of the entry uses the given channel.
+func (e *pathCacheEntry) hasChannel(chanID uint64) bool {
+	for _, edge := range e.path {
+		if edge.policy.ChannelID == chanID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasPair returns true if the path of the entry crosses the given pair of
+// nodes in the given direction.
+func (e *pathCacheEntry) hasPair(selfNode route.Vertex,
+	pair DirectedNodePair) bool {
+
+	from := selfNode
+	for _, edge := range e.path {
+		to := edge.policy.ToNodePubKey()
+		if from == pair.From && to == pair.To {
+			return true
+		}
+		from = to
+	}
+
+	return false
+}
+
+// hasNode returns true if the path of the entry passes through the given
+// node.
+func (e *pathCacheEntry) hasNode(node route.Vertex) bool {
+	for _, edge := range e.path {
+		if edge.policy.ToNodePubKey() == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PathCache is a persistent cache of pre-computed paths to the destinations
+// that were paid before, so that repeated payments to the same destination
+// can skip path finding. The destinations are kept in an lru list and are
+// persisted, so that their paths are pre-computed again after a restart.
+//
+// Cached paths are invalidated when a channel on the path is updated or
+// closed, or when mission control records a failure of a pair or node on the
+// path. Invalidated and expired paths are pre-computed again in the
+// background, so that the next payment to the destination finds a fresh path
+// in the cache. A cached path is always validated against the restrictions of
+// the payment, the current bandwidth of our channel and the current mission
+// control estimates before it is used.
+//
+// Only paths found with the default mission control are cached, as the paths
+// found with other mission control namespaces are based on different
+// estimates.
+type PathCache struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *PathCacheConfig
+
+	selfNode route.Vertex
+
+	// entries maps the cache keys to the elements of the lru list.
+	entries map[pathCacheKey]*list.Element
+
+	// lru holds the entries ordered from most to least recently used.
+	lru *list.List
+
+	// precompute is signalled when paths need to be pre-computed.
+	precompute chan struct{}
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+// NewPathCache creates a new path cache for paths starting at the given
+// node.
+func NewPathCache(cfg *PathCacheConfig,
+	selfNode route.Vertex) *PathCache {
+
+	return &PathCache{
+		cfg:        cfg,
+		selfNode:   selfNode,
+		entries:    make(map[pathCacheKey]*list.Element),
+		lru:        list.New(),
+		precompute: make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start loads the persisted destinations, starts to pre-compute their paths
+// and subscribes the path cache to the changes of the channel graph.
+func (c *PathCache) Start() error {
+	var err error
+	c.started.Do(func() {
+		log.Info("Path cache starting")
+
+		err = c.loadDestinations()
+		if err != nil {
+			return
+		}
+
+		var client *graph.TopologyClient
+		client, err = c.cfg.SubscribeTopology()
+		if err != nil {
+			return
+		}
+
+		c.wg.Add(2)
+		go c.handleTopologyChanges(client)
+		go c.precomputePaths()
+
+		c.schedulePrecompute()
+	})
+
+	return err
+}
+
+// Stop stops the path cache.
+func (c *PathCache) Stop() error {
+	c.stopped.Do(func() {
+		log.Info("Path cache shutting down...")
+		defer log.Debug("Path cache shutdown complete")
+
+		close(c.quit)
+		c.wg.Wait()
+	})
+
+	return nil
+}
+
+// loadDestinations adds the persisted destinations to the cache. Their paths
+// still need to be pre-computed.
+func (c *PathCache) loadDestinations() error {
+	if c.cfg.DB == nil {
+		return nil
+	}
+
+	var entries []*pathCacheEntry
+	err := kvdb.Update(c.cfg.DB, func(tx kvdb.RwTx) error {
+		bucket, err := tx.CreateTopLevelBucket(pathCacheBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			key, err := deserializePathCacheKey(k)
+			if err != nil {
+				return err
+			}
+
+			if len(v) != 8 {
+				return fmt.Errorf("invalid path cache amount "+
+					"length %v", len(v))
+			}
+
+			entries = append(entries, &pathCacheEntry{
+				key: key,
+				amt: lnwire.MilliSatoshi(byteOrder.Uint64(v)),
+			})
+
+			return nil
+		})
+	}, func() {
+		entries = nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to load path cache: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Destinations beyond the size of the cache are evicted, which may be
+	// the case if the size was reduced since the last start.
+	var evicted []pathCacheKey
+	for i, entry := range entries {
+		if i >= c.cfg.Size {
+			evicted = append(evicted, entry.key)
+			continue
+		}
+
+		c.entries[entry.key] = c.lru.PushBack(entry)
+	}
+
+	log.Infof("Path cache loaded %v destinations", c.lru.Len())
+
+	c.persist(nil, evicted)
+
+	return nil
+}
+
+// persist adds the given destinations to the database and removes the
+// evicted ones from it. Failures are only logged, as they merely affect the
+// pre-computation of paths after a restart.
+func (c *PathCache) persist(added []*pathCacheEntry,
+	evicted []pathCacheKey) {
+
+	if c.cfg.DB == nil || (len(added) == 0 && len(evicted) == 0) {
+		return
+	}
+
+	err := kvdb.Update(c.cfg.DB, func(tx kvdb.RwTx) error {
+		bucket, err := tx.CreateTopLevelBucket(pathCacheBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range added {
+			var amt [8]byte
+			byteOrder.PutUint64(amt[:], uint64(entry.amt))
+
+			err := bucket.Put(entry.key.serialize(), amt[:])
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, key := range evicted {
+			if err := bucket.Delete(key.serialize()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func() {})
+	if err != nil {
+		log.Errorf("Unable to persist path cache destinations: %v",
+			err)
+	}
+}
+
+// handleTopologyChanges invalidates the cached paths that use channels which
+// are updated or closed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (c *PathCache) handleTopologyChanges(client *graph.TopologyClient) {
+	defer c.wg.Done()
+	defer client.Cancel()
+
+	for {
+		select {
+		case change, ok := <-client.TopologyChanges:
+			if !ok {
+				return
+			}
+
+			for _, update := range change.ChannelEdgeUpdates {
+				c.InvalidateChannel(update.ChanID)
+			}
+			for _, closed := range change.ClosedChannels {
+				c.InvalidateChannel(closed.ChanID)
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// schedulePrecompute signals that paths need to be pre-computed.
+func (c *PathCache) schedulePrecompute() {
+	select {
+	case c.precompute <- struct{}{}:
+	default:
+	}
+}
+
+// precomputePaths pre-computes the paths of the destinations whose paths
+// were invalidated or expired.
+//
+// NOTE: This MUST be run as a goroutine.
+func (c *PathCache) precomputePaths() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.precompute:
+
+		case <-c.quit:
+			return
+		}
+
+		if c.cfg.NewPaymentSession == nil {
+			continue
+		}
+
+		for _, entry := range c.pendingEntries() {
+			select {
+			case <-c.quit:
+				return
+			default:
+			}
+
+			err := c.precomputePath(entry.key, entry.amt)
+			if err == nil {
+				continue
+			}
+
+			log.Debugf("Unable to pre-compute path to %v: %v",
+				entry.key.target, err)
+
+			// Don't try again before the ttl has passed, so
+			// that unreachable destinations don't cause path
+			// finding on every graph update.
+			c.mu.Lock()
+			if elem, ok := c.entries[entry.key]; ok {
+				e := elem.Value.(*pathCacheEntry)
+				e.retry = c.cfg.Clock.Now().Add(c.cfg.TTL)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// pendingEntries returns copies of the entries whose paths need to be
+// pre-computed.
+func (c *PathCache) pendingEntries() []pathCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.cfg.Clock.Now()
+
+	var pending []pathCacheEntry
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*pathCacheEntry)
+		if entry.path == nil && !now.Before(entry.retry) {
+			pending = append(pending, *entry)
+		}
+	}
+
+	return pending
+}
+
+// precomputePath runs path finding for a payment of the given amount to the
+// destination of the given key. The payment session adds the path it finds
+// to the cache.
+func (c *PathCache) precomputePath(key pathCacheKey,
+	amt lnwire.MilliSatoshi) error {
+
+	height, err := c.cfg.BestHeight()
+	if err != nil {
+		return err
+	}
+
+	payment := &LightningPayment{
+		Target:         key.target,
+		Amount:         amt,
+		FeeLimit:       lnwire.MaxMilliSatoshi,
+		CltvLimit:      c.cfg.CltvLimit,
+		FinalCLTVDelta: c.cfg.FinalCltvDelta,
+		TimePref:       key.timePref,
+	}
+	if err := payment.SetPaymentHash(lntypes.Hash{}); err != nil {
+		return err
+	}
+
+	session, err := c.cfg.NewPaymentSession(payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = session.RequestRoute(amt, payment.FeeLimit, 0, height)
+
+	return err
+}
+
+// lookup returns the path cached for the given key. It returns
+// errPathCacheMiss if no unexpired path is cached. Expired paths are
+// pre-computed again.
+func (c *PathCache) lookup(key pathCacheKey) ([]*unifiedEdge, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, errPathCacheMiss
+	}
+
+	c.lru.MoveToFront(elem)
+
+	entry := elem.Value.(*pathCacheEntry)
+	if entry.path == nil {
+		return nil, errPathCacheMiss
+	}
+
+	if !c.cfg.Clock.Now().Before(entry.expiry) {
+		entry.path = nil
+		c.schedulePrecompute()
+
+		return nil, errPathCacheMiss
+	}
+
+	return entry.path, nil
+}
+
+// store adds a path that was found for a payment of the given amount to the
+// cache, replacing the path that was cached for the same key before.
+func (c *PathCache) store(key pathCacheKey, amt lnwire.MilliSatoshi,
+	path []*unifiedEdge) {
+
+	if len(path) == 0 {
+		return
+	}
+
+	var (
+		added   []*pathCacheEntry
+		evicted []pathCacheKey
+	)
+	defer func() {
+		c.persist(added, evicted)
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiry := c.cfg.Clock.Now().Add(c.cfg.TTL)
+
+	// Known destinations are only persisted again if the amount their
+	// path is pre-computed for changes.
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*pathCacheEntry)
+		entry.path = path
+		entry.expiry = expiry
+		c.lru.MoveToFront(elem)
+
+		if entry.amt != amt {
+			entry.amt = amt
+			added = append(added, entry)
+		}
+
+		return
+	}
+
+	entry := &pathCacheEntry{
+		key:    key,
+		amt:    amt,
+		path:   path,
+		expiry: expiry,
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	added = append(added, entry)
+
+	for c.lru.Len() > c.cfg.Size {
+		evicted = append(evicted, c.remove(c.lru.Back()))
+	}
+}
+
+// remove removes an element from the cache and returns its key. The caller
+// must hold the mutex.
+func (c *PathCache) remove(elem *list.Element) pathCacheKey {
+	entry := elem.Value.(*pathCacheEntry)
+
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+
+	return entry.key
+}
+
+// invalidateIf invalidates the paths of all entries for which the predicate
+// returns true and schedules their pre-computation. The destinations are
+// kept in the cache.
+func (c *PathCache) invalidateIf(pred func(*pathCacheEntry) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var invalidated int
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*pathCacheEntry)
+		if entry.path != nil && pred(entry) {
+			entry.path = nil
+			entry.retry = time.Time{}
+			invalidated++
+		}
+	}
+
+	if invalidated > 0 {
+		c.schedulePrecompute()
+	}
+
+	return invalidated
+}
+
+// InvalidateChannel invalidates the cached paths that use the given channel.
+func (c *PathCache) InvalidateChannel(chanID uint64) {
+	invalidated := c.invalidateIf(func(entry *pathCacheEntry) bool {
+		return entry.hasChannel(chanID)
+	})
+	if invalidated > 0 {
+		log.Debugf("Path cache: invalidated %v paths using channel %v",
+			invalidated, lnwire.NewShortChanIDFromInt(chanID))
+	}
+}
+
+// InvalidateFailures invalidates the cached paths that cross a failed pair or
+// node. It is called by mission control whenever it records a failure.
+func (c *PathCache) InvalidateFailures(nodeFailure *route.Vertex,
+	pairFailures []DirectedNodePair) {
+
+	invalidated := c.invalidateIf(func(entry *pathCacheEntry) bool {
+		if nodeFailure != nil && entry.hasNode(*nodeFailure) {
+			return true
+		}
+
+		for _, pair := range pairFailures {
+			if entry.hasPair(c.selfNode, pair) {
+				return true
+			}
+		}
+
+		return false
+	})
+	if invalidated > 0 {
+		log.Debugf("Path cache: invalidated %v paths after mission "+
+			"control failure", invalidated)
+	}
+}
+
+// Reset invalidates all cached paths, so that they are pre-computed again.
+// The destinations are kept.
+func (c *PathCache) Reset() {
+	c.invalidateIf(func(*pathCacheEntry) bool {
+		return true
+	})
+}
+
+// Len returns the number of cached paths.
+func (c *PathCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var paths int
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		if elem.Value.(*pathCacheEntry).path != nil {
+			paths++
+		}
+	}
+
+	return paths
+}
+
+// Destinations returns the number of destinations in the cache, including
+// those whose paths still need to be pre-computed.
+func (c *PathCache) Destinations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
@@ -0,0 +1,361 @@
+package routing
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/graph"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	cacheTestSelf   = route.Vertex{1}
+	cacheTestHop    = route.Vertex{2}
+	cacheTestTarget = route.Vertex{3}
+)
+
+// newCacheTestEdge returns an edge of the given channel towards the given
+// node.
+func newCacheTestEdge(chanID uint64, to route.Vertex) *unifiedEdge {
+	return &unifiedEdge{
+		policy: &models.CachedEdgePolicy{
+			ChannelID: chanID,
+			ToNodePubKey: func() route.Vertex {
+				return to
+			},
+			ToNodeFeatures: lnwire.EmptyFeatureVector(),
+		},
+		capacity: 100_000,
+	}
+}
+
+// newTestPathCache returns a path cache of the given size and a test clock
+// that drives its expiry.
+func newTestPathCache(size int) (*PathCache, *clock.TestClock) {
+	testClock := clock.NewTestClock(time.Unix(1, 0))
+
+	cache := NewPathCache(&PathCacheConfig{
+		Size:  size,
+		TTL:   time.Minute,
+		Clock: testClock,
+	}, cacheTestSelf)
+
+	return cache, testClock
+}
+
+// TestPathCacheLookup tests that cached paths are found by destination and
+// amount bucket, and that they expire and are evicted.
+func TestPathCacheLookup(t *testing.T) {
+	t.Parallel()
+
+	cache, testClock := newTestPathCache(2)
+
+	path := []*unifiedEdge{newCacheTestEdge(1, cacheTestTarget)}
+	cache.store(newPathCacheKey(cacheTestTarget, 1000, 0), 1000, path)
+
+	// Amounts in the same power of two bucket share the path.
+	cached, err := cache.lookup(newPathCacheKey(cacheTestTarget, 600, 0))
+	require.NoError(t, err)
+	require.Equal(t, path, cached)
+
+	// Other amount buckets, time preferences and destinations don't.
+	_, err = cache.lookup(newPathCacheKey(cacheTestTarget, 3000, 0))
+	require.ErrorIs(t, err, errPathCacheMiss)
+
+	_, err = cache.lookup(newPathCacheKey(cacheTestTarget, 1000, 0.5))
+	require.ErrorIs(t, err, errPathCacheMiss)
+
+	_, err = cache.lookup(newPathCacheKey(cacheTestHop, 1000, 0))
+	require.ErrorIs(t, err, errPathCacheMiss)
+
+	// Adding two more paths evicts the least recently used one.
+	cache.store(newPathCacheKey(cacheTestHop, 1000, 0), 1000, path)
+	cache.store(newPathCacheKey(cacheTestTarget, 3000, 0), 3000, path)
+	require.Equal(t, 2, cache.Len())
+
+	_, err = cache.lookup(newPathCacheKey(cacheTestTarget, 1000, 0))
+	require.ErrorIs(t, err, errPathCacheMiss)
+
+	// Once the ttl has passed, the paths expire, while their destinations
+	// are kept so that the paths can be pre-computed again.
+	testClock.SetTime(testClock.Now().Add(time.Minute))
+
+	_, err = cache.lookup(newPathCacheKey(cacheTestHop, 1000, 0))
+	require.ErrorIs(t, err, errPathCacheMiss)
+	require.Equal(t, 1, cache.Len())
+	require.Equal(t, 2, cache.Destinations())
+}
+
+// TestPathCacheInvalidation tests that cached paths are invalidated when one
+// of their channels changes or mission control records a failure on them.
+func TestPathCacheInvalidation(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := newTestPathCache(10)
+
+	key1 := newPathCacheKey(cacheTestTarget, 1000, 0)
+	key2 := newPathCacheKey(cacheTestHop, 1000, 0)
+
+	storePaths := func() {
+		cache.store(key1, 1000, []*unifiedEdge{
+			newCacheTestEdge(1, cacheTestHop),
+			newCacheTestEdge(2, cacheTestTarget),
+		})
+		cache.store(key2, 1000, []*unifiedEdge{
+			newCacheTestEdge(1, cacheTestHop),
+		})
+	}
+
+	// A channel update only invalidates the paths that use the channel.
+	storePaths()
+	cache.InvalidateChannel(2)
+
+	_, err := cache.lookup(key1)
+	require.ErrorIs(t, err, errPathCacheMiss)
+	_, err = cache.lookup(key2)
+	require.NoError(t, err)
+
+	// A pair failure only invalidates the paths that cross the pair in the
+	// failed direction.
+	storePaths()
+	cache.InvalidateFailures(nil, []DirectedNodePair{
+		NewDirectedNodePair(cacheTestTarget, cacheTestHop),
+	})
+	require.Equal(t, 2, cache.Len())
+
+	cache.InvalidateFailures(nil, []DirectedNodePair{
+		NewDirectedNodePair(cacheTestHop, cacheTestTarget),
+	})
+
+	_, err = cache.lookup(key1)
+	require.ErrorIs(t, err, errPathCacheMiss)
+	_, err = cache.lookup(key2)
+	require.NoError(t, err)
+
+	// A node failure invalidates all paths through the node.
+	storePaths()
+	cache.InvalidateFailures(&cacheTestHop, nil)
+	require.Zero(t, cache.Len())
+	require.Equal(t, 2, cache.Destinations())
+}
+
+// TestRequestPathCached tests that payment sessions reuse the cached path of
+// an earlier payment to the same destination, unless that path has become
+// unusable.
+func TestRequestPathCached(t *testing.T) {
+	t.Parallel()
+
+	const height = 10
+
+	cache, _ := newTestPathCache(10)
+
+	missionControl := &mockMissionControl{}
+	missionControl.On(
+		"GetProbability", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything,
+	).Return(0.8)
+
+	bandwidth := &mockBandwidthHints{
+		hints: map[uint64]lnwire.MilliSatoshi{1: 100_000},
+	}
+
+	var pathFindingCalls int
+	requestRoute := func(amt lnwire.MilliSatoshi) *route.Route {
+		payment := &LightningPayment{
+			Target:         cacheTestTarget,
+			CltvLimit:      100,
+			FinalCLTVDelta: 8,
+			Amount:         amt,
+			FeeLimit:       1000,
+		}
+		require.NoError(t, payment.SetPaymentHash(lntypes.Hash{}))
+
+		session, err := newPaymentSession(
+			payment, cacheTestSelf,
+			func(Graph) (bandwidthHints, error) {
+				return bandwidth, nil
+			},
+			newMockGraphSessionFactory(&sessionGraph{}),
+			missionControl, PathFindingConfig{
+				MinProbability: 0.5,
+			},
+		)
+		require.NoError(t, err)
+		session.pathCache = cache
+
+		session.pathFinder = func(_ *graphParams, _ *RestrictParams,
+			_ *PathFindingConfig, _, _, _ route.Vertex,
+			_ lnwire.MilliSatoshi, _ float64, _ int32) (
+			[]*unifiedEdge, float64, error) {
+
+			pathFindingCalls++
+
+			return []*unifiedEdge{
+				newCacheTestEdge(1, cacheTestTarget),
+			}, 1.0, nil
+		}
+
+		rt, err := session.RequestRoute(
+			payment.Amount, payment.FeeLimit, 0, height,
+		)
+		require.NoError(t, err)
+
+		return rt
+	}
+
+	// The first payment runs path finding and caches the path.
+	rt := requestRoute(1000)
+	require.Equal(t, 1, pathFindingCalls)
+	require.Equal(t, 1, cache.Len())
+
+	// A second payment of a similar amount reuses the path.
+	cachedRt := requestRoute(900)
+	require.Equal(t, 1, pathFindingCalls)
+	require.Equal(t, rt.Hops[0].ChannelID, cachedRt.Hops[0].ChannelID)
+	require.Equal(t, lnwire.MilliSatoshi(900), cachedRt.ReceiverAmt())
+
+	// If our channel lacks the bandwidth, path finding runs again.
+	bandwidth.hints[1] = 500
+	requestRoute(900)
+	require.Equal(t, 2, pathFindingCalls)
+
+	// After a failure of the pair, the path is no longer cached.
+	bandwidth.hints[1] = 100_000
+	cache.InvalidateFailures(nil, []DirectedNodePair{
+		NewDirectedNodePair(cacheTestSelf, cacheTestTarget),
+	})
+	require.Zero(t, cache.Len())
+
+	requestRoute(900)
+	require.Equal(t, 3, pathFindingCalls)
+}
+
+// TestPathCachePrecompute tests that the destinations of the path cache are
+// persisted, and that their paths are pre-computed after a restart and after
+// they were invalidated.
+func TestPathCachePrecompute(t *testing.T) {
+	t.Parallel()
+
+	db, err := kvdb.Create(
+		kvdb.BoltBackendName, filepath.Join(t.TempDir(), "cache.db"),
+		true, kvdb.DefaultDBTimeout,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	missionControl := &mockMissionControl{}
+	missionControl.On(
+		"GetProbability", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything,
+	).Return(0.8)
+
+	getBandwidth := func(Graph) (bandwidthHints, error) {
+		return &mockBandwidthHints{}, nil
+	}
+
+	// Path finding reports the amounts it was run for.
+	pathFindings := make(chan lnwire.MilliSatoshi, 10)
+
+	newCache := func() *PathCache {
+		var cache *PathCache
+		cache = NewPathCache(&PathCacheConfig{
+			Size: 10,
+			TTL:  time.Minute,
+			DB:   db,
+			SubscribeTopology: func() (*graph.TopologyClient,
+				error) {
+
+				return &graph.TopologyClient{
+					TopologyChanges: make(
+						chan *graph.TopologyChange,
+					),
+					Cancel: func() {},
+				}, nil
+			},
+			NewPaymentSession: func(p *LightningPayment) (
+				PaymentSession, error) {
+
+				session, err := newPaymentSession(
+					p, cacheTestSelf, getBandwidth,
+					newMockGraphSessionFactory(
+						&sessionGraph{},
+					),
+					missionControl, PathFindingConfig{},
+				)
+				if err != nil {
+					return nil, err
+				}
+				session.pathCache = cache
+
+				session.pathFinder = func(_ *graphParams,
+					_ *RestrictParams, _ *PathFindingConfig,
+					_, _, _ route.Vertex,
+					amt lnwire.MilliSatoshi, _ float64,
+					_ int32) ([]*unifiedEdge, float64,
+					error) {
+
+					pathFindings <- amt
+
+					return []*unifiedEdge{
+						newCacheTestEdge(
+							1, cacheTestTarget,
+						),
+					}, 1.0, nil
+				}
+
+				return session, nil
+			},
+			BestHeight: func() (uint32, error) {
+				return 10, nil
+			},
+			CltvLimit:      1000,
+			FinalCltvDelta: 18,
+			Clock:          clock.NewTestClock(time.Unix(1, 0)),
+		}, cacheTestSelf)
+
+		return cache
+	}
+
+	// A path found by a payment adds its destination to the cache.
+	cache := newCache()
+	require.NoError(t, cache.Start())
+
+	key := newPathCacheKey(cacheTestTarget, 1000, 0)
+	cache.store(key, 900, []*unifiedEdge{
+		newCacheTestEdge(1, cacheTestTarget),
+	})
+	require.NoError(t, cache.Stop())
+
+	// After a restart, the path to the destination is pre-computed for
+	// the amount of the last payment.
+	cache = newCache()
+	require.NoError(t, cache.Start())
+	t.Cleanup(func() {
+		require.NoError(t, cache.Stop())
+	})
+
+	require.Equal(t, lnwire.MilliSatoshi(900), <-pathFindings)
+	require.Eventually(t, func() bool {
+		return cache.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = cache.lookup(key)
+	require.NoError(t, err)
+
+	// Once the path is invalidated, it is pre-computed again.
+	cache.InvalidateChannel(1)
+	require.Equal(t, lnwire.MilliSatoshi(900), <-pathFindings)
+	require.Eventually(t, func() bool {
+		return cache.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -116,6 +116,10 @@ type finalHopParams struct {
 	// metadata is additional data that is sent along with the payment to
 	// the payee.
 	metadata []byte
+
+	// trampolineOnion is the serialized trampoline onion that is delivered
+	// to the final hop if the payment is sent via a trampoline node.
+	trampolineOnion []byte
 }
 
 // newRoute constructs a route using the provided path and final hop constraints.
@@ -185,6 +189,7 @@ func newRoute(sourceVertex route.Vertex,
 			customRecords       record.CustomSet
 			mpp                 *record.MPP
 			metadata            []byte
+			trampolineOnion     []byte
 		)
 
 		// Define a helper function that checks this edge's feature
@@ -242,6 +247,7 @@ func newRoute(sourceVertex route.Vertex,
 			}
 
 			metadata = finalHop.metadata
+			trampolineOnion = finalHop.trampolineOnion
 
 			if blindedPathSet != nil {
 				totalAmtMsatBlinded = finalHop.totalAmt
@@ -290,6 +296,7 @@ func newRoute(sourceVertex route.Vertex,
 			MPP:              mpp,
 			Metadata:         metadata,
 			TotalAmtMsat:     totalAmtMsatBlinded,
+			TrampolineOnion:  trampolineOnion,
 		}
 
 		hops = append([]*route.Hop{currentHop}, hops...)
@@ -463,6 +470,11 @@ type RestrictParams struct {
 	// the payee.
 	Metadata []byte
 
+	// TrampolineOnion is the trampoline onion that is delivered to the
+	// final hop, which needs to be accounted for in the final hop payload
+	// size calculation.
+	TrampolineOnion []byte
+
 	// BlindedPaymentPathSet is necessary to determine the hop size of the
 	// last/exit hop.
 	BlindedPaymentPathSet *BlindedPaymentPathSet
@@ -537,42 +549,21 @@ func getOutgoingBalance(node route.Vertex, outgoingChans map[uint64]struct{},
 	return max, total, err
 }
 
-// findPath attempts to find a path from the source node within the ChannelGraph
-// to the target node that's capable of supporting a payment of `amt` value. The
-// current approach implemented is modified version of Dijkstra's algorithm to
-// find a single shortest path between the source node and the destination. The
-// distance metric used for edges is related to the time-lock+fee costs along a
-// particular edge. If a path is found, this function returns a slice of
-// ChannelHop structs which encoded the chosen path from the target to the
-// source. The search is performed backwards from destination node back to
-// source. This is to properly accumulate fees that need to be paid along the
-// path and accurately check the amount to forward at every node against the
-// available bandwidth.
-func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
-	self, source, target route.Vertex, amt lnwire.MilliSatoshi,
-	timePref float64, finalHtlcExpiry int32) ([]*unifiedEdge, float64,
-	error) {
-
-	// Pathfinding can be a significant portion of the total payment
-	// latency, especially on low-powered devices. Log several metrics to
-	// aid in the analysis performance problems in this area.
-	start := time.Now()
-	nodesVisited := 0
-	edgesExpanded := 0
-	defer func() {
-		timeElapsed := time.Since(start)
-		log.Debugf("Pathfinding perf metrics: nodes=%v, edges=%v, "+
-			"time=%v", nodesVisited, edgesExpanded, timeElapsed)
-	}()
+// destFeatures returns the feature vector of the destination, which is taken
+// from the restrictions or loaded from the graph as a fallback. An error is
+// returned if the features aren't well-formed or don't support the payment
+// address of the restrictions.
+func destFeatures(g Graph, r *RestrictParams,
+	target route.Vertex) (*lnwire.FeatureVector, error) {
 
 	// If no destination features are provided, we will load what features
 	// we have for the target node from our graph.
 	features := r.DestFeatures
 	if features == nil {
 		var err error
-		features, err = g.graph.FetchNodeFeatures(target)
+		features, err = g.FetchNodeFeatures(target)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 	}
 
@@ -581,14 +572,14 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	err := feature.ValidateRequired(features)
 	if err != nil {
 		log.Warnf("Pathfinding destination node features: %v", err)
-		return nil, 0, errUnknownRequiredFeature
+		return nil, errUnknownRequiredFeature
 	}
 
 	// Ensure that all transitive dependencies are set.
 	err = feature.ValidateDeps(features)
 	if err != nil {
 		log.Warnf("Pathfinding destination node features: %v", err)
-		return nil, 0, errMissingDependentFeature
+		return nil, errMissingDependentFeature
 	}
 
 	// Now that we know the feature vector is well-formed, we'll proceed in
@@ -598,7 +589,104 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	if r.PaymentAddr != nil &&
 		!features.HasFeature(lnwire.PaymentAddrOptional) {
 
-		return nil, 0, errNoPaymentAddr
+		return nil, errNoPaymentAddr
+	}
+
+	return features, nil
+}
+
+// getAbsoluteAttemptCost returns the virtual cost of a failed payment attempt
+// for the given amount, scaled by the time preference of the payment.
+func getAbsoluteAttemptCost(cfg *PathFindingConfig, amt lnwire.MilliSatoshi,
+	timePref float64) (float64, error) {
+
+	// Calculate the default attempt cost as configured globally.
+	defaultAttemptCost := float64(
+		cfg.AttemptCost +
+			amt*lnwire.MilliSatoshi(cfg.AttemptCostPPM)/1000000,
+	)
+
+	// Validate time preference value.
+	if math.Abs(timePref) > 1 {
+		return 0, fmt.Errorf("time preference %v out of range "+
+			"[-1, 1]", timePref)
+	}
+
+	// Scale to avoid the extremes -1 and 1 which run into infinity issues.
+	timePref *= 0.9
+
+	// Apply time preference. At 0, the default attempt cost will
+	// be used.
+	return defaultAttemptCost * (1/(0.5-timePref/2) - 1), nil
+}
+
+// reverseAdditionalEdges builds a reverse lookup of the additional edges, which
+// maps every node to the additional edges towards it. This is needed because
+// path finding searches from the target to the source.
+func reverseAdditionalEdges(additionalEdges map[route.Vertex][]AdditionalEdge,
+	self route.Vertex) map[route.Vertex][]*edgePolicyWithSource {
+
+	additionalEdgesWithSrc := make(map[route.Vertex][]*edgePolicyWithSource)
+	for vertex, additionalEdges := range additionalEdges {
+		// Edges connected to self are always included in the graph,
+		// therefore can be skipped. This prevents us from trying
+		// routes to malformed hop hints.
+		if vertex == self {
+			continue
+		}
+
+		// Build reverse lookup to find incoming edges. Needed because
+		// search is taken place from target to source.
+		for _, additionalEdge := range additionalEdges {
+			outgoingEdgePolicy := additionalEdge.EdgePolicy()
+			toVertex := outgoingEdgePolicy.ToNodePubKey()
+
+			incomingEdgePolicy := &edgePolicyWithSource{
+				sourceNode: vertex,
+				edge:       additionalEdge,
+			}
+
+			additionalEdgesWithSrc[toVertex] =
+				append(additionalEdgesWithSrc[toVertex],
+					incomingEdgePolicy)
+		}
+	}
+
+	return additionalEdgesWithSrc
+}
+
+// findPath attempts to find a path from the source node within the ChannelGraph
+// to the target node that's capable of supporting a payment of `amt` value. The
+// current approach implemented is modified version of Dijkstra's algorithm to
+// find a single shortest path between the source node and the destination. The
+// distance metric used for edges is related to the time-lock+fee costs along a
+// particular edge. If a path is found, this function returns a slice of
+// ChannelHop structs which encoded the chosen path from the target to the
+// source. The search is performed backwards from destination node back to
+// source. This is to properly accumulate fees that need to be paid along the
+// path and accurately check the amount to forward at every node against the
+// available bandwidth.
+func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
+	self, source, target route.Vertex, amt lnwire.MilliSatoshi,
+	timePref float64, finalHtlcExpiry int32) ([]*unifiedEdge, float64,
+	error) {
+
+	// Pathfinding can be a significant portion of the total payment
+	// latency, especially on low-powered devices. Log several metrics to
+	// aid in the analysis performance problems in this area.
+	start := time.Now()
+	nodesVisited := 0
+	edgesExpanded := 0
+	defer func() {
+		timeElapsed := time.Since(start)
+		log.Debugf("Pathfinding perf metrics: nodes=%v, edges=%v, "+
+			"time=%v", nodesVisited, edgesExpanded, timeElapsed)
+	}()
+
+	// Load and validate the features of the destination.
+	features, err := destFeatures(g.graph, r, target)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Set up outgoing channel map for quicker access.
@@ -645,31 +733,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	// Holds the current best distance for a given node.
 	distance := make(map[route.Vertex]*nodeWithDist, estimatedNodeCount)
 
-	additionalEdgesWithSrc := make(map[route.Vertex][]*edgePolicyWithSource)
-	for vertex, additionalEdges := range g.additionalEdges {
-		// Edges connected to self are always included in the graph,
-		// therefore can be skipped. This prevents us from trying
-		// routes to malformed hop hints.
-		if vertex == self {
-			continue
-		}
-
-		// Build reverse lookup to find incoming edges. Needed because
-		// search is taken place from target to source.
-		for _, additionalEdge := range additionalEdges {
-			outgoingEdgePolicy := additionalEdge.EdgePolicy()
-			toVertex := outgoingEdgePolicy.ToNodePubKey()
-
-			incomingEdgePolicy := &edgePolicyWithSource{
-				sourceNode: vertex,
-				edge:       additionalEdge,
-			}
-
-			additionalEdgesWithSrc[toVertex] =
-				append(additionalEdgesWithSrc[toVertex],
-					incomingEdgePolicy)
-		}
-	}
+	additionalEdgesWithSrc := reverseAdditionalEdges(g.additionalEdges, self)
 
 	// The payload size of the final hop differ from intermediate hops
 	// and depends on whether the destination is blinded or not.
@@ -698,25 +762,13 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	// if the cltv limit is MaxUint32.
 	absoluteCltvLimit := uint64(r.CltvLimit) + uint64(finalHtlcExpiry)
 
-	// Calculate the default attempt cost as configured globally.
-	defaultAttemptCost := float64(
-		cfg.AttemptCost +
-			amt*lnwire.MilliSatoshi(cfg.AttemptCostPPM)/1000000,
-	)
-
-	// Validate time preference value.
-	if math.Abs(timePref) > 1 {
-		return nil, 0, fmt.Errorf("time preference %v out of range "+
-			"[-1, 1]", timePref)
+	// Calculate the attempt cost, taking the time preference into
+	// account.
+	absoluteAttemptCost, err := getAbsoluteAttemptCost(cfg, amt, timePref)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Scale to avoid the extremes -1 and 1 which run into infinity issues.
-	timePref *= 0.9
-
-	// Apply time preference. At 0, the default attempt cost will
-	// be used.
-	absoluteAttemptCost := defaultAttemptCost * (1/(0.5-timePref/2) - 1)
-
 	log.Debugf("Pathfinding absolute attempt cost: %v sats",
 		absoluteAttemptCost/1000)
 
@@ -1441,6 +1493,7 @@ func lastHopPayloadSize(r *RestrictParams, finalHtlcExpiry int32,
 		MPP:              mpp,
 		AMP:              amp,
 		Metadata:         r.Metadata,
+		TrampolineOnion:  r.TrampolineOnion,
 	}
 
 	// The final hop does not have a short chanID set.
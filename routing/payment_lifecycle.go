@@ -32,6 +32,11 @@ type paymentLifecycle struct {
 	shardTracker  shards.ShardTracker
 	currentHeight int32
 
+	// missionControl is the mission control that the results of the
+	// payment's attempts are reported to. It defaults to the mission
+	// control of the router.
+	missionControl MissionController
+
 	// quit is closed to signal the sub goroutines of the payment lifecycle
 	// to stop.
 	quit chan struct{}
@@ -62,6 +67,7 @@ func newPaymentLifecycle(r *ChannelRouter, feeLimit lnwire.MilliSatoshi,
 		paySession:      paySession,
 		shardTracker:    shardTracker,
 		currentHeight:   currentHeight,
+		missionControl:  r.cfg.MissionControl,
 		quit:            make(chan struct{}),
 		resultCollected: make(chan error, 1),
 	}
@@ -550,7 +556,7 @@ func (p *paymentLifecycle) collectResult(attempt *channeldb.HTLCAttempt) (
 		p.identifier, attempt.AttemptID)
 
 	// Report success to mission control.
-	err = p.router.cfg.MissionControl.ReportPaymentSuccess(
+	err = p.missionControl.ReportPaymentSuccess(
 		attempt.AttemptID, &attempt.Route,
 	)
 	if err != nil {
@@ -764,7 +770,7 @@ func (p *paymentLifecycle) handleSwitchErr(attempt *channeldb.HTLCAttempt,
 		msg lnwire.FailureMessage) (*attemptResult, error) {
 
 		// Report outcome to mission control.
-		reason, err := p.router.cfg.MissionControl.ReportPaymentFail(
+		reason, err := p.missionControl.ReportPaymentFail(
 			attemptID, &attempt.Route, srcIdx, msg,
 		)
 		if err != nil {
@@ -1,7 +1,9 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btclog"
@@ -190,6 +192,40 @@ type paymentSession struct {
 	// will happen and this value remains unused.
 	minShardAmt lnwire.MilliSatoshi
 
+	// flowRoutes are the routes of the current min-cost flow split of the
+	// payment that haven't been handed out yet.
+	flowRoutes []*route.Route
+
+	// flowRemaining is the amount that is expected to remain to be sent
+	// when the next route of the min-cost flow split is requested.
+	flowRemaining lnwire.MilliSatoshi
+
+	// flowHeight is the block height at which the current min-cost flow
+	// split was computed.
+	flowHeight uint32
+
+	// externalPathFinding dispatches route requests to an external path
+	// finder while one is registered. It is nil if external path finding
+	// isn't available.
+	externalPathFinding *ExternalPathFinding
+
+	// externalRoutes are the candidate routes of the external path finder
+	// that haven't been handed out yet.
+	externalRoutes []*route.Route
+
+	// externalPairs are the pairs of the routes of the external path
+	// finder that were handed out.
+	externalPairs map[DirectedNodePair]struct{}
+
+	// externalStart is the time at which the session started to use the
+	// external path finder. Pair failures before this time aren't
+	// attributed to the payment.
+	externalStart time.Time
+
+	// pathCache holds the paths of earlier payments to the same
+	// destination. It is nil if paths aren't cached for this payment.
+	pathCache *PathCache
+
 	// log is a payment session-specific logger.
 	log btclog.Logger
 }
@@ -249,6 +285,41 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliSatoshi,
 		return nil, errEmptyPaySession
 	}
 
+	// If an external path finder is registered, it takes over path
+	// finding. Should it fail, we fall back to our own path finding.
+	if p.externalPathFinding != nil {
+		rt, err := p.requestExternalRoute(
+			maxAmt, feeLimit, activeShards, height,
+		)
+		switch {
+		case err == nil:
+			return rt, nil
+
+		case errors.Is(err, errNoExternalPathFinder):
+
+		case errors.Is(err, errNoPathFound):
+			return nil, err
+
+		default:
+			p.log.Warnf("External path finding failed, falling "+
+				"back to own path finding: %v", err)
+		}
+	}
+
+	// If requested, split the payment by min-cost flow. Should that not
+	// be possible, we fall back to splitting by halving the amount.
+	if p.payment.MinCostFlow {
+		rt, err := p.requestFlowRoute(
+			maxAmt, feeLimit, activeShards, height,
+		)
+		if err == nil {
+			return rt, nil
+		}
+
+		p.log.Debugf("Unable to split payment by min-cost flow, "+
+			"falling back to halving: %v", err)
+	}
+
 	// Add BlockPadding to the finalCltvDelta so that the receiving node
 	// does not reject the HTLC if some blocks are mined while it's in-flight.
 	finalCltvDelta := p.payment.FinalCLTVDelta
@@ -275,6 +346,7 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliSatoshi,
 		PaymentAddr:        p.payment.PaymentAddr,
 		Amp:                p.payment.amp,
 		Metadata:           p.payment.Metadata,
+		TrampolineOnion:    p.payment.trampolineOnion,
 	}
 
 	finalHtlcExpiry := int32(height) + int32(finalCltvDelta)
@@ -291,6 +363,18 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliSatoshi,
 		maxAmt = *p.payment.MaxShardAmt
 	}
 
+	// Payments to destinations that were paid before may be able to reuse
+	// the path that was found back then.
+	cacheable := p.pathCacheable()
+	if cacheable {
+		route, err := p.requestCachedRoute(
+			maxAmt, restrictions, finalCltvDelta, height,
+		)
+		if err == nil {
+			return route, nil
+		}
+	}
+
 	for {
 		// Get a routing graph session.
 		graph, closeGraph, err := p.graphSessFactory.NewGraphSession()
@@ -418,16 +502,149 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliSatoshi,
 				records:     p.payment.DestCustomRecords,
 				paymentAddr: p.payment.PaymentAddr,
 				metadata:    p.payment.Metadata,
+
+				trampolineOnion: p.payment.trampolineOnion,
 			}, p.payment.BlindedPathSet,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if cacheable {
+			p.pathCache.store(
+				newPathCacheKey(
+					p.payment.Target, maxAmt,
+					p.payment.TimePref,
+				), maxAmt, path,
+			)
+		}
+
 		return route, err
 	}
 }
 
+// pathCacheable returns true if the paths of this payment can be taken from
+// and added to the path cache. This is only the case for payments whose
+// path finding isn't restricted beyond the destination and the amount.
+func (p *paymentSession) pathCacheable() bool {
+	return p.pathCache != nil &&
+		len(p.additionalEdges) == 0 &&
+		p.payment.BlindedPathSet == nil &&
+		len(p.payment.OutgoingChannelIDs) == 0 &&
+		p.payment.LastHop == nil &&
+		len(p.payment.DestCustomRecords) == 0 &&
+		len(p.payment.Metadata) == 0 &&
+		p.payment.amp == nil &&
+		p.payment.trampolineOnion == nil
+}
+
+// requestCachedRoute returns a route along the path that the path cache
+// holds for the payment, if that path is still usable. The path must satisfy
+// the restrictions of the payment, our channel must have the bandwidth for
+// the amount and the success probability of the path must still be above the
+// minimum. Otherwise errPathCacheMiss is returned.
+func (p *paymentSession) requestCachedRoute(amt lnwire.MilliSatoshi,
+	restrictions *RestrictParams, finalCltvDelta uint16,
+	height uint32) (*route.Route, error) {
+
+	key := newPathCacheKey(p.payment.Target, amt, p.payment.TimePref)
+	path, err := p.pathCache.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := newRoute(
+		p.selfNode, path, height,
+		finalHopParams{
+			amt:         amt,
+			totalAmt:    p.payment.Amount,
+			cltvDelta:   finalCltvDelta,
+			paymentAddr: p.payment.PaymentAddr,
+		}, nil,
+	)
+	if err != nil {
+		p.log.Debugf("Unable to build route along cached path: %v",
+			err)
+
+		return nil, errPathCacheMiss
+	}
+
+	if rt.TotalFees() > restrictions.FeeLimit {
+		p.log.Debugf("Cached path exceeds fee limit: fee=%v, limit=%v",
+			rt.TotalFees(), restrictions.FeeLimit)
+
+		return nil, errPathCacheMiss
+	}
+
+	cltvDelta := rt.TotalTimeLock - height - uint32(finalCltvDelta)
+	if cltvDelta > restrictions.CltvLimit {
+		p.log.Debugf("Cached path exceeds cltv limit: delta=%v, "+
+			"limit=%v", cltvDelta, restrictions.CltvLimit)
+
+		return nil, errPathCacheMiss
+	}
+
+	// The first hop must be one of our channels that currently has the
+	// bandwidth to carry the payment.
+	graph, closeGraph, err := p.graphSessFactory.NewGraphSession()
+	if err != nil {
+		return nil, err
+	}
+	bandwidthHints, err := p.getBandwidthHints(graph)
+	if err := closeGraph(); err != nil {
+		log.Errorf("could not close graph session: %v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bandwidth, ok := bandwidthHints.availableChanBandwidth(
+		path[0].policy.ChannelID, rt.TotalAmount,
+	)
+	if !ok || bandwidth < rt.TotalAmount {
+		p.log.Debugf("Insufficient bandwidth for cached path: "+
+			"chan_id=%v, bandwidth=%v, amt=%v",
+			path[0].policy.ChannelID, bandwidth, rt.TotalAmount)
+
+		return nil, errPathCacheMiss
+	}
+
+	// Finally, the path must still be within the capacity and htlc limits
+	// of its channels and likely enough to succeed with the current
+	// mission control estimates.
+	var (
+		from        = p.selfNode
+		amtIn       = rt.TotalAmount
+		probability = 1.0
+	)
+	for i, edge := range path {
+		if i > 0 {
+			amtIn = rt.Hops[i-1].AmtToForward
+		}
+
+		if !edge.amtInRange(amtIn) {
+			return nil, errPathCacheMiss
+		}
+
+		to := edge.policy.ToNodePubKey()
+		probability *= restrictions.ProbabilitySource(
+			from, to, amtIn, edge.capacity,
+		)
+		from = to
+	}
+
+	if probability < p.pathFindingConfig.MinProbability {
+		p.log.Debugf("Cached path probability %v below minimum %v",
+			probability, p.pathFindingConfig.MinProbability)
+
+		return nil, errPathCacheMiss
+	}
+
+	p.log.Debugf("Using cached path for amt=%v", amt)
+
+	return rt, nil
+}
+
 // UpdateAdditionalEdge updates the channel edge policy for a private edge. It
 // validates the message signature and checks it's up to date, then applies the
 // updates to the supplied policy. It returns a boolean to indicate whether
@@ -43,6 +43,14 @@ type SessionSource struct {
 	// PathFindingConfig defines global parameters that control the
 	// trade-off in path finding between fees and probability.
 	PathFindingConfig PathFindingConfig
+
+	// ExternalPathFinding optionally dispatches the route requests of
+	// payment sessions to an external path finder.
+	ExternalPathFinding *ExternalPathFinding
+
+	// PathCache optionally holds the paths of earlier payments, so that
+	// repeated payments to the same destination can skip path finding.
+	PathCache *PathCache
 }
 
 // NewPaymentSession creates a new payment session backed by the latest prune
@@ -58,13 +66,27 @@ func (m *SessionSource) NewPaymentSession(p *LightningPayment) (
 		)
 	}
 
+	// Payments may override the mission control that their path finding
+	// is based on.
+	missionControl := m.MissionControl
+	if p.MissionControl != nil {
+		missionControl = p.MissionControl
+	}
+
 	session, err := newPaymentSession(
 		p, m.SourceNode.PubKeyBytes, getBandwidthHints,
-		m.GraphSessionFactory, m.MissionControl, m.PathFindingConfig,
+		m.GraphSessionFactory, missionControl, m.PathFindingConfig,
 	)
 	if err != nil {
 		return nil, err
 	}
+	session.externalPathFinding = m.ExternalPathFinding
+
+	// The cached paths are based on the estimates of the default mission
+	// control, so payments that use a different one bypass the cache.
+	if p.MissionControl == nil {
+		session.pathCache = m.PathCache
+	}
 
 	return session, nil
 }
@@ -0,0 +1,443 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// errSimulationUnsupported is returned when the payment session source of the
+// router can't create sessions for payment simulations.
+var errSimulationUnsupported = errors.New("payment session source doesn't " +
+	"support payment simulation")
+
+// SimulatedShard is a shard of a simulated payment.
+type SimulatedShard struct {
+	// Route is the route that the shard would be sent along.
+	Route *route.Route
+
+	// Probability is the success probability of the shard as estimated by
+	// mission control.
+	Probability float64
+}
+
+// SkippedChannel is one of our channels that path finding skipped during a
+// payment simulation.
+type SkippedChannel struct {
+	// ChannelID is the short channel id of the channel.
+	ChannelID uint64
+
+	// Amount is the amount of the htlc that the channel couldn't carry.
+	// It is zero if the channel couldn't carry an htlc of any amount.
+	Amount lnwire.MilliSatoshi
+
+	// Reason is the reason why the channel was skipped.
+	Reason ChannelSkipReason
+
+	// Err is the error that caused the channel to be skipped.
+	Err error
+}
+
+// PaymentSimulation is the outcome of a payment simulation. It describes the
+// shards that the payment would be split into, without sending any of them.
+type PaymentSimulation struct {
+	// Shards are the shards that the payment would be split into.
+	Shards []*SimulatedShard
+
+	// TotalFees is the sum of the fees of all shards.
+	TotalFees lnwire.MilliSatoshi
+
+	// Probability is the probability that all shards succeed as estimated
+	// by mission control.
+	Probability float64
+
+	// SkippedChannels are the channels of ours that path finding skipped
+	// and that aren't used by any of the shards, ordered by channel id.
+	SkippedChannels []*SkippedChannel
+
+	// FailureReason is the reason why the payment would fail if the shards
+	// don't cover the full amount. It is nil otherwise.
+	FailureReason *channeldb.FailureReason
+}
+
+// simulationSessionSource is implemented by payment session sources that can
+// create sessions for payment simulations.
+type simulationSessionSource interface {
+	// newSimulationSession creates a payment session whose bandwidth hints
+	// are wrapped by the given function.
+	newSimulationSession(p *LightningPayment,
+		wrapHints func(*bandwidthManager) bandwidthHints) (
+		*paymentSession, error)
+}
+
+// newSimulationSession creates a payment session for the simulation of a
+// payment. Simulated payments neither use the path cache nor external path
+// finding, as both bypass our bandwidth hints.
+func (m *SessionSource) newSimulationSession(p *LightningPayment,
+	wrapHints func(*bandwidthManager) bandwidthHints) (*paymentSession,
+	error) {
+
+	getBandwidthHints := func(graph Graph) (bandwidthHints, error) {
+		manager, err := newBandwidthManager(
+			graph, m.SourceNode.PubKeyBytes, m.GetLink,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return wrapHints(manager), nil
+	}
+
+	missionControl := m.MissionControl
+	if p.MissionControl != nil {
+		missionControl = p.MissionControl
+	}
+
+	return newPaymentSession(
+		p, m.SourceNode.PubKeyBytes, getBandwidthHints,
+		m.GraphSessionFactory, missionControl, m.PathFindingConfig,
+	)
+}
+
+// paymentSimulator tracks the state of our channels across the shards of a
+// simulated payment.
+type paymentSimulator struct {
+	// dustExposureExceeded optionally checks whether an htlc would push
+	// the dust exposure of a channel over the fee exposure threshold.
+	dustExposureExceeded func(lnwire.ShortChannelID,
+		lnwire.MilliSatoshi) (bool, error)
+
+	// reserved is the amount that the already planned shards take from
+	// the bandwidth of our channels.
+	reserved map[uint64]lnwire.MilliSatoshi
+
+	// slots is the number of htlc slots that each of our channels had
+	// available before any of the shards were planned.
+	slots map[uint64]uint16
+
+	// usedSlots is the number of htlc slots that the already planned
+	// shards take from each of our channels.
+	usedSlots map[uint64]uint16
+
+	// skipped holds the latest reason why each of our channels was
+	// skipped.
+	skipped map[uint64]*SkippedChannel
+}
+
+// newPaymentSimulator creates a new payment simulator.
+func newPaymentSimulator(dustExposureExceeded func(lnwire.ShortChannelID,
+	lnwire.MilliSatoshi) (bool, error)) *paymentSimulator {
+
+	return &paymentSimulator{
+		dustExposureExceeded: dustExposureExceeded,
+		reserved:             make(map[uint64]lnwire.MilliSatoshi),
+		slots:                make(map[uint64]uint16),
+		usedSlots:            make(map[uint64]uint16),
+		skipped:              make(map[uint64]*SkippedChannel),
+	}
+}
+
+// wrapHints wraps the bandwidth hints of a path finding attempt.
+func (s *paymentSimulator) wrapHints(
+	manager *bandwidthManager) bandwidthHints {
+
+	return &simulationBandwidth{
+		bandwidthManager: manager,
+		sim:              s,
+	}
+}
+
+// skip records that a channel was skipped.
+func (s *paymentSimulator) skip(channelID uint64, amount lnwire.MilliSatoshi,
+	skip *channelSkip) {
+
+	s.skipped[channelID] = &SkippedChannel{
+		ChannelID: channelID,
+		Amount:    amount,
+		Reason:    skip.reason,
+		Err:       skip.err,
+	}
+}
+
+// reserve deducts the amount and the htlc slot of a planned shard from its
+// first hop.
+func (s *paymentSimulator) reserve(rt *route.Route) {
+	if len(rt.Hops) == 0 {
+		return
+	}
+
+	s.reserved[rt.Hops[0].ChannelID] += rt.TotalAmount
+	s.usedSlots[rt.Hops[0].ChannelID]++
+}
+
+// skippedChannels returns the skipped channels that aren't used by any of the
+// given shards, ordered by channel id.
+func (s *paymentSimulator) skippedChannels(
+	shards []*SimulatedShard) []*SkippedChannel {
+
+	used := make(map[uint64]struct{})
+	for _, shard := range shards {
+		if len(shard.Route.Hops) > 0 {
+			used[shard.Route.Hops[0].ChannelID] = struct{}{}
+		}
+	}
+
+	var skipped []*SkippedChannel
+	for channelID, channel := range s.skipped {
+		if _, ok := used[channelID]; ok {
+			continue
+		}
+
+		skipped = append(skipped, channel)
+	}
+
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].ChannelID < skipped[j].ChannelID
+	})
+
+	return skipped
+}
+
+// simulationBandwidth is the bandwidthHints implementation of simulated
+// payments. On top of the bandwidth manager it checks the dust exposure of our
+// channels, deducts the amounts and htlc slots of the already planned shards
+// and records why channels were skipped.
+type simulationBandwidth struct {
+	*bandwidthManager
+
+	sim *paymentSimulator
+}
+
+// availableChanBandwidth returns the total available bandwidth for a channel
+// and a bool indicating whether the channel hint was found.
+//
+// NOTE: Part of the bandwidthHints interface.
+func (b *simulationBandwidth) availableChanBandwidth(channelID uint64,
+	amount lnwire.MilliSatoshi) (lnwire.MilliSatoshi, bool) {
+
+	shortID := lnwire.NewShortChanIDFromInt(channelID)
+	if _, ok := b.localChans[shortID]; !ok {
+		return 0, false
+	}
+
+	bandwidth, skip := b.queryBandwidth(shortID, amount)
+	if skip == nil && amount > 0 && b.sim.dustExposureExceeded != nil {
+		exceeded, err := b.sim.dustExposureExceeded(shortID, amount)
+		switch {
+		case err != nil:
+			skip = &channelSkip{
+				reason: ChannelSkipDustExposureCheckFailed,
+				err: fmt.Errorf("unable to check dust "+
+					"exposure: %w", err),
+			}
+
+		case exceeded:
+			skip = &channelSkip{
+				reason: ChannelSkipDustExposure,
+				err: errors.New("dust exposure exceeds fee " +
+					"threshold"),
+			}
+		}
+	}
+	if skip == nil {
+		skip = b.checkSlots(shortID)
+	}
+	if skip != nil {
+		b.sim.skip(channelID, amount, skip)
+
+		return 0, true
+	}
+
+	reserved := b.sim.reserved[channelID]
+	if reserved >= bandwidth {
+		bandwidth = 0
+	} else {
+		bandwidth -= reserved
+	}
+
+	if amount > bandwidth {
+		b.sim.skip(channelID, amount, &channelSkip{
+			reason: ChannelSkipInsufficientBandwidth,
+			err: fmt.Errorf("available bandwidth %v below "+
+				"amount %v", bandwidth, amount),
+		})
+	}
+
+	return bandwidth, true
+}
+
+// checkSlots returns the reason why a channel has to be skipped if the already
+// planned shards use up all of its free htlc slots. The free slots of each
+// channel are only queried once, as the planned shards aren't added to the
+// channel.
+func (b *simulationBandwidth) checkSlots(
+	shortID lnwire.ShortChannelID) *channelSkip {
+
+	channelID := shortID.ToUint64()
+	slots, ok := b.sim.slots[channelID]
+	if !ok {
+		link, err := b.getLink(shortID)
+		if err != nil {
+			return &channelSkip{
+				reason: ChannelSkipLinkNotFound,
+				err:    fmt.Errorf("link not found: %w", err),
+			}
+		}
+
+		slots, err = link.AvailableOutgoingHtlcSlots()
+		if err != nil {
+			return &channelSkip{
+				reason: ChannelSkipUnknown,
+				err: fmt.Errorf("unable to get htlc slots: %w",
+					err),
+			}
+		}
+
+		b.sim.slots[channelID] = slots
+	}
+
+	used := b.sim.usedSlots[channelID]
+	if used < slots {
+		return nil
+	}
+
+	return &channelSkip{
+		reason: ChannelSkipMaxHtlcs,
+		err: fmt.Errorf("planned shards use all %v free htlc slots",
+			slots),
+	}
+}
+
+// routeProbability returns the success probability of a route as estimated by
+// the given mission control. Our own channels are assumed to succeed, as path
+// finding only picks them if they have the bandwidth to carry the amount.
+func routeProbability(graph Graph, missionControl MissionController,
+	selfNode route.Vertex, rt *route.Route) float64 {
+
+	probability := 1.0
+	fromNode := rt.SourcePubKey
+	amt := rt.TotalAmount
+	for _, hop := range rt.Hops {
+		if fromNode != selfNode {
+			// Channels that aren't in the graph, like the ones of
+			// route hints, are assumed to have the same capacity as
+			// during path finding.
+			capacity, err := FetchAmountPairCapacity(
+				graph, selfNode, fromNode, hop.PubKeyBytes, amt,
+			)
+			if err != nil {
+				capacity = fakeHopHintCapacity
+			}
+
+			probability *= missionControl.GetProbability(
+				fromNode, hop.PubKeyBytes, amt, capacity,
+			)
+		}
+
+		fromNode = hop.PubKeyBytes
+		amt = hop.AmtToForward
+	}
+
+	return probability
+}
+
+// SimulatePayment plans the shards of a payment the same way the payment
+// lifecycle would, without sending any of them. Each shard is planned with the
+// current bandwidth of our channels minus the amounts of the shards that were
+// planned before it, and our channels are checked against their htlc slots,
+// max in flight, channel reserve and dust exposure. Each planned shard takes
+// one of the free htlc slots of its first hop, so that a channel isn't planned
+// for more shards than it can carry. The channels that path
+// finding skipped are returned with the reason why.
+func (r *ChannelRouter) SimulatePayment(payment *LightningPayment) (
+	*PaymentSimulation, error) {
+
+	source, ok := r.cfg.SessionSource.(simulationSessionSource)
+	if !ok {
+		return nil, errSimulationUnsupported
+	}
+
+	// Payments through a trampoline node are simulated as the payment to
+	// the trampoline node that they are rewritten into.
+	if payment.TrampolineNode != nil {
+		if err := r.prepareTrampolinePayment(payment); err != nil {
+			return nil, err
+		}
+	}
+
+	_, height, err := r.cfg.Chain.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	simulator := newPaymentSimulator(r.cfg.DustExposureExceeded)
+	session, err := source.newSimulationSession(
+		payment, simulator.wrapHints,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &PaymentSimulation{
+		Probability: 1,
+	}
+
+	// Request routes until the full amount is covered or path finding
+	// gives up, mirroring the shard planning of the payment lifecycle.
+	remaining := payment.Amount
+	for remaining > 0 {
+		feeBudget := payment.FeeLimit - sim.TotalFees
+
+		rt, err := session.RequestRoute(
+			remaining, feeBudget, uint32(len(sim.Shards)),
+			uint32(height),
+		)
+		if err != nil {
+			var noRouteErr noRouteError
+			if !errors.As(err, &noRouteErr) {
+				return nil, err
+			}
+
+			reason := noRouteErr.FailureReason()
+			sim.FailureReason = &reason
+
+			break
+		}
+
+		sim.Shards = append(sim.Shards, &SimulatedShard{
+			Route: rt,
+		})
+		sim.TotalFees += rt.TotalFees()
+		remaining -= rt.ReceiverAmt()
+
+		simulator.reserve(rt)
+	}
+
+	// Estimate the success probabilities of the shards. All shards need to
+	// succeed for the payment to succeed.
+	graph, closeGraph, err := session.graphSessFactory.NewGraphSession()
+	if err != nil {
+		return nil, err
+	}
+	for _, shard := range sim.Shards {
+		shard.Probability = routeProbability(
+			graph, session.missionControl, r.cfg.SelfNode,
+			shard.Route,
+		)
+		sim.Probability *= shard.Probability
+	}
+	if err := closeGraph(); err != nil {
+		log.Errorf("could not close graph session: %v", err)
+	}
+
+	if sim.FailureReason != nil {
+		sim.Probability = 0
+	}
+	sim.SkippedChannels = simulator.skippedChannels(sim.Shards)
+
+	return sim, nil
+}
@@ -0,0 +1,251 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulatePayment tests that payment simulations plan the shards of a
+// payment against the state of our channels and report why channels were
+// skipped.
+func TestSimulatePayment(t *testing.T) {
+	t.Parallel()
+
+	const (
+		directChanID uint64 = 101
+		hopChanID    uint64 = 102
+		hopNodeID           = 3
+
+		chanCapacity btcutil.Amount = 100_000
+	)
+
+	testCases := []struct {
+		name          string
+		amt           lnwire.MilliSatoshi
+		links         map[uint64]*mockLink
+		dustExceeded  map[uint64]bool
+		dustCheckErr  map[uint64]bool
+		expectedChans []uint64
+		expectedSkips map[uint64]ChannelSkipReason
+		expectFailure bool
+	}{
+		{
+			name: "skip full channel",
+			amt:  20_000_000,
+			links: map[uint64]*mockLink{
+				directChanID: {
+					bandwidth: 50_000_000,
+					mayAddOutgoingErr: fmt.Errorf("%w: 483",
+						lnwallet.ErrMaxHTLCNumber),
+				},
+				hopChanID: {bandwidth: 50_000_000},
+			},
+			expectedChans: []uint64{hopChanID},
+			expectedSkips: map[uint64]ChannelSkipReason{
+				directChanID: ChannelSkipMaxHtlcs,
+			},
+		},
+		{
+			name: "split across channels",
+			amt:  80_000_000,
+			links: map[uint64]*mockLink{
+				directChanID: {bandwidth: 50_000_000},
+				hopChanID:    {bandwidth: 50_000_000},
+			},
+			expectedChans: []uint64{directChanID, hopChanID},
+			expectedSkips: map[uint64]ChannelSkipReason{},
+		},
+		{
+			// The payment needs more shards than the direct channel
+			// has free htlc slots. The first shard takes its only
+			// slot, so the rest of the payment exceeds the bandwidth
+			// that is left even though the direct channel has the
+			// balance for another shard. Whether the hop channel is
+			// skipped depends on the order in which path finding
+			// visits the channels, so the skips aren't checked.
+			name: "more shards than free htlc slots",
+			amt:  140_000_000,
+			links: map[uint64]*mockLink{
+				directChanID: {
+					bandwidth: 100_000_000,
+					htlcSlots: fn.Some[uint16](1),
+				},
+				hopChanID: {bandwidth: 50_000_000},
+			},
+			expectedChans: []uint64{directChanID},
+			expectFailure: true,
+		},
+		{
+			name: "dust exposure check failed",
+			amt:  20_000_000,
+			links: map[uint64]*mockLink{
+				directChanID: {bandwidth: 50_000_000},
+				hopChanID:    {bandwidth: 50_000_000},
+			},
+			dustCheckErr: map[uint64]bool{
+				directChanID: true,
+			},
+			expectedChans: []uint64{hopChanID},
+			expectedSkips: map[uint64]ChannelSkipReason{
+				directChanID: ChannelSkipDustExposureCheckFailed,
+			},
+		},
+		{
+			name: "no route",
+			amt:  20_000_000,
+			links: map[uint64]*mockLink{
+				directChanID: {ineligible: true},
+				hopChanID:    {bandwidth: 50_000_000},
+			},
+			dustExceeded: map[uint64]bool{
+				hopChanID: true,
+			},
+			expectedSkips: map[uint64]ChannelSkipReason{
+				directChanID: ChannelSkipNotEligible,
+				hopChanID:    ChannelSkipDustExposure,
+			},
+			expectFailure: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			source := newMockNode(sourceNodeID)
+			target := newMockNode(targetNodeID)
+			hopNode := newMockNode(hopNodeID)
+
+			g := newMockGraph(t)
+			g.addNode(source)
+			g.addNode(target)
+			g.addNode(hopNode)
+			g.source = source
+
+			g.addChannel(
+				directChanID, sourceNodeID, targetNodeID,
+				chanCapacity,
+			)
+			g.addChannel(
+				hopChanID, sourceNodeID, hopNodeID,
+				chanCapacity,
+			)
+			g.addChannel(
+				103, hopNodeID, targetNodeID, chanCapacity,
+			)
+
+			missionControl := &mockMissionControl{}
+			missionControl.On(
+				"GetProbability", mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything,
+			).Return(0.8)
+
+			getLink := func(scid lnwire.ShortChannelID) (
+				htlcswitch.ChannelLink, error) {
+
+				link, ok := testCase.links[scid.ToUint64()]
+				if !ok {
+					return nil, fmt.Errorf("no link")
+				}
+
+				return link, nil
+			}
+
+			dustExceeded := func(scid lnwire.ShortChannelID,
+				_ lnwire.MilliSatoshi) (bool, error) {
+
+				if testCase.dustCheckErr[scid.ToUint64()] {
+					return false, fmt.Errorf("no link")
+				}
+
+				return testCase.dustExceeded[scid.ToUint64()],
+					nil
+			}
+
+			sessionSource := &SessionSource{
+				GraphSessionFactory: newMockGraphSessionFactory(
+					g,
+				),
+				SourceNode: &channeldb.LightningNode{
+					PubKeyBytes: source.pubkey,
+				},
+				GetLink:        getLink,
+				MissionControl: missionControl,
+				PathFindingConfig: PathFindingConfig{
+					MinProbability: 0.01,
+				},
+			}
+
+			router := &ChannelRouter{
+				cfg: &Config{
+					SelfNode:             source.pubkey,
+					Chain:                newMockChain(100),
+					SessionSource:        sessionSource,
+					DustExposureExceeded: dustExceeded,
+				},
+			}
+
+			var paymentAddr [32]byte
+			payment := &LightningPayment{
+				Target:         target.pubkey,
+				Amount:         testCase.amt,
+				FeeLimit:       lnwire.MaxMilliSatoshi,
+				CltvLimit:      math.MaxUint32,
+				FinalCLTVDelta: 40,
+				MaxParts:       16,
+				PaymentAddr:    &paymentAddr,
+				DestFeatures: lnwire.NewFeatureVector(
+					mppFeatures, lnwire.Features,
+				),
+			}
+			err := payment.SetPaymentHash(lntypes.Hash{})
+			require.NoError(t, err)
+
+			sim, err := router.SimulatePayment(payment)
+			require.NoError(t, err)
+
+			var (
+				chans    []uint64
+				received lnwire.MilliSatoshi
+			)
+			for _, shard := range sim.Shards {
+				rt := shard.Route
+				chans = append(chans, rt.Hops[0].ChannelID)
+				received += rt.ReceiverAmt()
+			}
+			require.ElementsMatch(t, testCase.expectedChans, chans)
+
+			skips := make(map[uint64]ChannelSkipReason)
+			for _, skipped := range sim.SkippedChannels {
+				skips[skipped.ChannelID] = skipped.Reason
+			}
+			if testCase.expectedSkips != nil {
+				require.Equal(t, testCase.expectedSkips, skips)
+			}
+
+			if testCase.expectFailure {
+				require.NotNil(t, sim.FailureReason)
+				require.Zero(t, sim.Probability)
+
+				return
+			}
+
+			require.Nil(t, sim.FailureReason)
+			require.Equal(t, testCase.amt, received)
+			require.Greater(t, sim.Probability, 0.0)
+		})
+	}
+}
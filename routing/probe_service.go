@@ -0,0 +1,517 @@
+package routing
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+)
+
+const (
+	// DefaultProbeInterval is the default time that the probe service
+	// waits between two probes.
+	DefaultProbeInterval = time.Minute
+
+	// DefaultProbeTimeout is the default time that the probe service
+	// spends on a single probe before giving up on it.
+	DefaultProbeTimeout = time.Minute
+)
+
+var (
+	// ErrProbingActive is returned when probing is started while a
+	// probing run is already active.
+	ErrProbingActive = errors.New("probing already active")
+
+	// ErrProbingNotActive is returned when probing is stopped while no
+	// probing run is active.
+	ErrProbingNotActive = errors.New("probing not active")
+
+	// ErrNoProbeDestinations is returned when a probing run has no
+	// destinations to probe.
+	ErrNoProbeDestinations = errors.New("no probe destinations")
+)
+
+// ProbeConfig describes a probing run of the probe service.
+type ProbeConfig struct {
+	// Destinations is the set of nodes that are probed in turn. It is
+	// mutually exclusive with NumCentralityNodes.
+	Destinations []route.Vertex
+
+	// NumCentralityNodes is the number of nodes with the highest
+	// betweenness centrality in the graph that are probed in turn. The set
+	// of nodes is recalculated every time all of them have been probed.
+	NumCentralityNodes int
+
+	// Amount is the amount that each probe tries to deliver to its
+	// destination.
+	Amount lnwire.MilliSatoshi
+
+	// FeeLimit is the maximum routing fee of the routes that are probed.
+	FeeLimit lnwire.MilliSatoshi
+
+	// Interval is the time between the start of two consecutive probes,
+	// which limits the rate of probing.
+	Interval time.Duration
+
+	// ProbeTimeout is the maximum time that is spent on a single probe.
+	ProbeTimeout time.Duration
+
+	// MaxProbes is the budget of the probing run. Probing stops once this
+	// number of probes has been sent. Zero means no limit.
+	MaxProbes uint32
+
+	// MissionControlNamespace is the namespace of the mission control
+	// that the results of the probes are fed into. The probes also use its
+	// estimates to find routes. An empty namespace selects the default
+	// mission control.
+	MissionControlNamespace string
+}
+
+// validate checks that the probe config is sane.
+func (c *ProbeConfig) validate() error {
+	switch {
+	case len(c.Destinations) > 0 && c.NumCentralityNodes > 0:
+		return errors.New("destinations and centrality nodes are " +
+			"mutually exclusive")
+
+	case len(c.Destinations) == 0 && c.NumCentralityNodes <= 0:
+		return ErrNoProbeDestinations
+
+	case c.Amount == 0:
+		return errors.New("probe amount must be greater than zero")
+
+	case c.Interval <= 0:
+		return errors.New("probe interval must be greater than zero")
+
+	case c.ProbeTimeout <= 0:
+		return errors.New("probe timeout must be greater than zero")
+	}
+
+	return nil
+}
+
+// ProbeResult is the outcome of a single probe.
+type ProbeResult struct {
+	// Destination is the node that was probed.
+	Destination route.Vertex
+
+	// Amount is the amount that the probe tried to deliver.
+	Amount lnwire.MilliSatoshi
+
+	// PaymentHash is the random payment hash of the probe.
+	PaymentHash lntypes.Hash
+
+	// Reached is true if the probe arrived at the destination, which
+	// failed it because it doesn't know the payment hash.
+	Reached bool
+
+	// FailureReason is the reason why the probe didn't reach the
+	// destination. It is nil if the destination was reached.
+	FailureReason *channeldb.FailureReason
+
+	// Route is the route of the last attempt of the probe. It is nil if
+	// no attempt was made.
+	Route *route.Route
+
+	// Timestamp is the time at which the probe was started.
+	Timestamp time.Time
+
+	// Duration is the time that it took to resolve the probe.
+	Duration time.Duration
+}
+
+// ProbeServiceConfig holds the dependencies of the probe service.
+type ProbeServiceConfig struct {
+	// SelfNode is our own node, which is never probed.
+	SelfNode route.Vertex
+
+	// SendPayment sends a payment and blocks until it is resolved.
+	SendPayment func(payment *LightningPayment) ([32]byte, *route.Route,
+		error)
+
+	// FetchPayment fetches a payment from the payment database.
+	FetchPayment func(hash lntypes.Hash) (*channeldb.MPPayment, error)
+
+	// DeletePayment removes a resolved probe from the payment database,
+	// so that probes don't pile up in the payment history.
+	DeletePayment func(hash lntypes.Hash) error
+
+	// TopCentralityNodes returns the n nodes of the graph with the highest
+	// betweenness centrality.
+	TopCentralityNodes func(n int) ([]route.Vertex, error)
+
+	// GetMissionControl returns the mission control of the given
+	// namespace. An empty namespace selects the default mission control.
+	GetMissionControl func(namespace string) (MissionController, error)
+
+	// FinalCLTVDelta is the final cltv delta that the probes use.
+	FinalCLTVDelta uint16
+
+	// CltvLimit is the maximum time lock of the routes that are probed.
+	CltvLimit uint32
+
+	// Clock is used to time the probes.
+	Clock clock.Clock
+}
+
+// probeRun holds the state of an active probing run.
+type probeRun struct {
+	cfg *ProbeConfig
+	mc  MissionController
+
+	// numProbes is the number of probes that have been sent so far. It is
+	// guarded by the mutex of the probe service.
+	numProbes uint32
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// ProbeService periodically sends probes with a random payment hash to a set
+// of destinations. A probe can't be settled, but once it is failed by its
+// destination we know that the destination can be reached with the probe
+// amount. The results of the probes are fed into mission control, which
+// improves the success estimates of later payments, and are streamed to
+// subscribers.
+type ProbeService struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *ProbeServiceConfig
+
+	ntfnServer *subscribe.Server
+
+	// run is the active probing run, or nil if probing isn't active.
+	run *probeRun
+
+	wg sync.WaitGroup
+	mu sync.Mutex
+}
+
+// NewProbeService creates a new probe service.
+func NewProbeService(cfg *ProbeServiceConfig) *ProbeService {
+	return &ProbeService{
+		cfg:        cfg,
+		ntfnServer: subscribe.NewServer(),
+	}
+}
+
+// Start starts the probe service.
+func (p *ProbeService) Start() error {
+	var err error
+	p.started.Do(func() {
+		log.Info("ProbeService starting")
+		err = p.ntfnServer.Start()
+	})
+
+	return err
+}
+
+// Stop stops the active probing run, if any, and the probe service.
+func (p *ProbeService) Stop() error {
+	var err error
+	p.stopped.Do(func() {
+		log.Info("ProbeService shutting down...")
+		defer log.Debug("ProbeService shutdown complete")
+
+		if _, stopErr := p.StopProbing(); stopErr != nil &&
+			!errors.Is(stopErr, ErrProbingNotActive) {
+
+			log.Warnf("Unable to stop probing: %v", stopErr)
+		}
+		p.wg.Wait()
+
+		err = p.ntfnServer.Stop()
+	})
+
+	return err
+}
+
+// StartProbing starts a probing run with the given config. Only one probing
+// run can be active at a time.
+func (p *ProbeService) StartProbing(cfg *ProbeConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	mc, err := p.cfg.GetMissionControl(cfg.MissionControlNamespace)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.run != nil {
+		return ErrProbingActive
+	}
+
+	run := &probeRun{
+		cfg:  cfg,
+		mc:   mc,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	p.run = run
+
+	log.Infof("Starting probing of %v every %v with budget of %v probes",
+		cfg.Amount, cfg.Interval, cfg.MaxProbes)
+
+	p.wg.Add(1)
+	go p.probe(run)
+
+	return nil
+}
+
+// StopProbing stops the active probing run and returns the number of probes
+// that it has sent. It waits for a probe that is in flight to be resolved.
+func (p *ProbeService) StopProbing() (uint32, error) {
+	p.mu.Lock()
+	run := p.run
+	p.mu.Unlock()
+
+	if run == nil {
+		return 0, ErrProbingNotActive
+	}
+
+	run.stopOnce.Do(func() {
+		close(run.quit)
+	})
+	<-run.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return run.numProbes, nil
+}
+
+// Status returns the config of the active probing run and the number of
+// probes that it has sent. The config is nil if probing isn't active.
+func (p *ProbeService) Status() (*ProbeConfig, uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.run == nil {
+		return nil, 0
+	}
+
+	return p.run.cfg, p.run.numProbes
+}
+
+// SubscribeProbeResults returns a subscription client that receives a
+// *ProbeResult for every probe that is resolved.
+func (p *ProbeService) SubscribeProbeResults() (*subscribe.Client, error) {
+	return p.ntfnServer.Subscribe()
+}
+
+// probe sends the probes of a probing run until it is stopped or its budget
+// is exhausted.
+//
+// NOTE: This method must be run as a goroutine.
+func (p *ProbeService) probe(run *probeRun) {
+	defer p.wg.Done()
+
+	defer func() {
+		p.mu.Lock()
+		p.run = nil
+		p.mu.Unlock()
+
+		close(run.done)
+	}()
+
+	var destinations []route.Vertex
+	for {
+		p.mu.Lock()
+		numProbes := run.numProbes
+		p.mu.Unlock()
+
+		if run.cfg.MaxProbes != 0 && numProbes >= run.cfg.MaxProbes {
+			log.Infof("Probing budget of %v probes exhausted",
+				run.cfg.MaxProbes)
+
+			return
+		}
+
+		// Once all destinations have been probed, we start over with
+		// a fresh set of them.
+		if len(destinations) == 0 {
+			var err error
+			destinations, err = p.destinations(run.cfg)
+			if err != nil {
+				log.Errorf("Unable to select probe "+
+					"destinations: %v", err)
+
+				return
+			}
+		}
+
+		dest := destinations[0]
+		destinations = destinations[1:]
+
+		result, err := p.sendProbe(run, dest)
+		if err != nil {
+			log.Errorf("Unable to probe %v: %v", dest, err)
+		} else {
+			if err := p.ntfnServer.SendUpdate(result); err != nil {
+				log.Warnf("Unable to send probe result: %v",
+					err)
+			}
+		}
+
+		p.mu.Lock()
+		run.numProbes++
+		p.mu.Unlock()
+
+		select {
+		case <-p.cfg.Clock.TickAfter(run.cfg.Interval):
+
+		case <-run.quit:
+			log.Infof("Probing stopped")
+			return
+		}
+	}
+}
+
+// destinations returns the destinations that should be probed in the next
+// round of a probing run.
+func (p *ProbeService) destinations(cfg *ProbeConfig) ([]route.Vertex, error) {
+	candidates := cfg.Destinations
+	if len(candidates) == 0 {
+		// We ask for one more node, in case we are among the top
+		// nodes ourselves.
+		var err error
+		candidates, err = p.cfg.TopCentralityNodes(
+			cfg.NumCentralityNodes + 1,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	destinations := make([]route.Vertex, 0, len(candidates))
+	for _, node := range candidates {
+		if node == p.cfg.SelfNode {
+			continue
+		}
+
+		destinations = append(destinations, node)
+	}
+
+	if cfg.NumCentralityNodes > 0 &&
+		len(destinations) > cfg.NumCentralityNodes {
+
+		destinations = destinations[:cfg.NumCentralityNodes]
+	}
+
+	if len(destinations) == 0 {
+		return nil, ErrNoProbeDestinations
+	}
+
+	return destinations, nil
+}
+
+// sendProbe sends a single probe to the destination and returns its result.
+func (p *ProbeService) sendProbe(run *probeRun,
+	dest route.Vertex) (*ProbeResult, error) {
+
+	// Generate a random payment hash, so we can be sure that the
+	// destination doesn't have the preimage to settle the htlc.
+	var paymentHash lntypes.Hash
+	if _, err := rand.Read(paymentHash[:]); err != nil {
+		return nil, fmt.Errorf("cannot generate random probe "+
+			"payment hash: %w", err)
+	}
+
+	payment := &LightningPayment{
+		Target:            dest,
+		Amount:            run.cfg.Amount,
+		FeeLimit:          run.cfg.FeeLimit,
+		CltvLimit:         p.cfg.CltvLimit,
+		FinalCLTVDelta:    p.cfg.FinalCLTVDelta,
+		PayAttemptTimeout: run.cfg.ProbeTimeout,
+		MaxParts:          1,
+		MissionControl:    run.mc,
+	}
+	if err := payment.SetPaymentHash(paymentHash); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Probing %v with %v, payment hash %v", dest,
+		run.cfg.Amount, paymentHash)
+
+	start := p.cfg.Clock.Now()
+	_, _, err := p.cfg.SendPayment(payment)
+
+	result := &ProbeResult{
+		Destination: dest,
+		Amount:      run.cfg.Amount,
+		PaymentHash: paymentHash,
+		Timestamp:   start,
+		Duration:    p.cfg.Clock.Now().Sub(start),
+	}
+
+	var reason channeldb.FailureReason
+	switch {
+	// The destination can't settle the probe, so it should never succeed.
+	case err == nil:
+		log.Warnf("Probe %v to %v unexpectedly succeeded", paymentHash,
+			dest)
+
+		result.Reached = true
+
+	// Incorrect payment details are reported by the destination, so the
+	// probe reached it.
+	case errors.As(err, &reason) &&
+		reason == channeldb.FailureReasonPaymentDetails:
+
+		result.Reached = true
+
+	case errors.As(err, &reason):
+		result.FailureReason = &reason
+
+	// Any other error means that the probe couldn't be sent at all.
+	default:
+		return nil, err
+	}
+
+	result.Route, err = p.lastRoute(paymentHash)
+	if err != nil {
+		log.Warnf("Unable to fetch route of probe %v: %v", paymentHash,
+			err)
+	}
+
+	if err := p.cfg.DeletePayment(paymentHash); err != nil {
+		log.Warnf("Unable to delete probe %v: %v", paymentHash, err)
+	}
+
+	return result, nil
+}
+
+// lastRoute returns the route of the last attempt of a probe, or nil if the
+// probe didn't make any attempt.
+func (p *ProbeService) lastRoute(hash lntypes.Hash) (*route.Route, error) {
+	payment, err := p.cfg.FetchPayment(hash)
+	if errors.Is(err, channeldb.ErrPaymentNotInitiated) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payment.HTLCs) == 0 {
+		return nil, nil
+	}
+
+	rt := payment.HTLCs[len(payment.HTLCs)-1].Route
+
+	return &rt, nil
+}
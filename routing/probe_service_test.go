@@ -0,0 +1,264 @@
+package routing
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// probeServiceHarness wraps a probe service with mocked dependencies that
+// record the probes that are sent.
+type probeServiceHarness struct {
+	service *ProbeService
+	mc      *mockMissionControlOld
+
+	// reasons holds the failure reason of the probes to each destination.
+	// Destinations that are missing are reached by their probes.
+	reasons map[route.Vertex]channeldb.FailureReason
+
+	mu       sync.Mutex
+	payments []*LightningPayment
+	deleted  []lntypes.Hash
+}
+
+func newProbeServiceHarness(t *testing.T, self route.Vertex,
+	topNodes []route.Vertex) *probeServiceHarness {
+
+	h := &probeServiceHarness{
+		mc:      &mockMissionControlOld{},
+		reasons: make(map[route.Vertex]channeldb.FailureReason),
+	}
+
+	h.service = NewProbeService(&ProbeServiceConfig{
+		SelfNode: self,
+		SendPayment: func(p *LightningPayment) ([32]byte,
+			*route.Route, error) {
+
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			h.payments = append(h.payments, p)
+
+			reason, ok := h.reasons[p.Target]
+			if !ok {
+				reason = channeldb.FailureReasonPaymentDetails
+			}
+
+			return [32]byte{}, nil, reason
+		},
+		FetchPayment: func(lntypes.Hash) (*channeldb.MPPayment,
+			error) {
+
+			return nil, channeldb.ErrPaymentNotInitiated
+		},
+		DeletePayment: func(hash lntypes.Hash) error {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			h.deleted = append(h.deleted, hash)
+
+			return nil
+		},
+		TopCentralityNodes: func(n int) ([]route.Vertex, error) {
+			if n > len(topNodes) {
+				n = len(topNodes)
+			}
+
+			return topNodes[:n], nil
+		},
+		GetMissionControl: func(string) (MissionController, error) {
+			return h.mc, nil
+		},
+		FinalCLTVDelta: 40,
+		CltvLimit:      2016,
+		Clock:          clock.NewDefaultClock(),
+	})
+
+	require.NoError(t, h.service.Start())
+	t.Cleanup(func() {
+		require.NoError(t, h.service.Stop())
+	})
+
+	return h
+}
+
+// receiveResult waits for the next probe result of the subscription.
+func receiveResult(t *testing.T, updates <-chan interface{}) *ProbeResult {
+	t.Helper()
+
+	select {
+	case update := <-updates:
+		result, ok := update.(*ProbeResult)
+		require.True(t, ok)
+
+		return result
+
+	case <-time.After(time.Second):
+		t.Fatal("no probe result received")
+	}
+
+	return nil
+}
+
+// TestProbeServiceDestinations tests that a probing run probes its
+// destinations in turn until its budget is exhausted, and that the results are
+// streamed to subscribers.
+func TestProbeServiceDestinations(t *testing.T) {
+	t.Parallel()
+
+	var (
+		self  = route.Vertex{1}
+		nodeA = route.Vertex{2}
+		nodeB = route.Vertex{3}
+	)
+
+	h := newProbeServiceHarness(t, self, nil)
+	h.reasons[nodeB] = channeldb.FailureReasonNoRoute
+
+	sub, err := h.service.SubscribeProbeResults()
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	cfg := &ProbeConfig{
+		Destinations: []route.Vertex{self, nodeA, nodeB},
+		Amount:       1000,
+		FeeLimit:     100,
+		Interval:     time.Millisecond,
+		ProbeTimeout: time.Minute,
+		MaxProbes:    3,
+	}
+	require.NoError(t, h.service.StartProbing(cfg))
+
+	// Our own node is skipped, and the destinations are probed in turn.
+	result := receiveResult(t, sub.Updates())
+	require.Equal(t, nodeA, result.Destination)
+	require.True(t, result.Reached)
+	require.Nil(t, result.FailureReason)
+
+	result = receiveResult(t, sub.Updates())
+	require.Equal(t, nodeB, result.Destination)
+	require.False(t, result.Reached)
+	require.Equal(t, channeldb.FailureReasonNoRoute, *result.FailureReason)
+
+	result = receiveResult(t, sub.Updates())
+	require.Equal(t, nodeA, result.Destination)
+	require.True(t, result.Reached)
+
+	// Probing stops once the budget is exhausted.
+	require.Eventually(t, func() bool {
+		cfg, _ := h.service.Status()
+		return cfg == nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = h.service.StopProbing()
+	require.ErrorIs(t, err, ErrProbingNotActive)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Every probe was sent with a unique payment hash, reported to the
+	// selected mission control and deleted afterwards.
+	require.Len(t, h.payments, 3)
+	require.Len(t, h.deleted, 3)
+
+	hashes := make(map[lntypes.Hash]struct{})
+	for i, payment := range h.payments {
+		require.Equal(t, cfg.Amount, payment.Amount)
+		require.Equal(t, cfg.FeeLimit, payment.FeeLimit)
+		require.Equal(t, uint32(1), payment.MaxParts)
+		require.Equal(t, h.mc, payment.MissionControl)
+
+		hash := lntypes.Hash(payment.Identifier())
+		require.Equal(t, hash, h.deleted[i])
+
+		hashes[hash] = struct{}{}
+	}
+	require.Len(t, hashes, 3)
+}
+
+// TestProbeServiceCentralityNodes tests that the top centrality nodes other
+// than our own node are probed, and that an active probing run can be
+// stopped.
+func TestProbeServiceCentralityNodes(t *testing.T) {
+	t.Parallel()
+
+	var (
+		self  = route.Vertex{1}
+		nodeA = route.Vertex{2}
+		nodeB = route.Vertex{3}
+		nodeC = route.Vertex{4}
+	)
+
+	h := newProbeServiceHarness(
+		t, self, []route.Vertex{nodeA, self, nodeB, nodeC},
+	)
+
+	sub, err := h.service.SubscribeProbeResults()
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	cfg := &ProbeConfig{
+		NumCentralityNodes: 2,
+		Amount:             1000,
+		Interval:           time.Millisecond,
+		ProbeTimeout:       time.Minute,
+	}
+	require.NoError(t, h.service.StartProbing(cfg))
+
+	// Only one probing run can be active at a time.
+	require.ErrorIs(t, h.service.StartProbing(cfg), ErrProbingActive)
+
+	for _, dest := range []route.Vertex{nodeA, nodeB, nodeA, nodeB} {
+		result := receiveResult(t, sub.Updates())
+		require.Equal(t, dest, result.Destination)
+	}
+
+	numProbes, err := h.service.StopProbing()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, numProbes, uint32(4))
+
+	activeCfg, _ := h.service.Status()
+	require.Nil(t, activeCfg)
+}
+
+// TestProbeConfigValidation tests that invalid probe configs are rejected.
+func TestProbeConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	h := newProbeServiceHarness(t, route.Vertex{1}, nil)
+
+	valid := func() *ProbeConfig {
+		return &ProbeConfig{
+			Destinations: []route.Vertex{{2}},
+			Amount:       1000,
+			Interval:     time.Second,
+			ProbeTimeout: time.Second,
+		}
+	}
+
+	cfg := valid()
+	cfg.NumCentralityNodes = 1
+	require.Error(t, h.service.StartProbing(cfg))
+
+	cfg = valid()
+	cfg.Destinations = nil
+	require.ErrorIs(t, h.service.StartProbing(cfg), ErrNoProbeDestinations)
+
+	cfg = valid()
+	cfg.Amount = 0
+	require.Error(t, h.service.StartProbing(cfg))
+
+	cfg = valid()
+	cfg.Interval = 0
+	require.Error(t, h.service.StartProbing(cfg))
+
+	cfg = valid()
+	cfg.ProbeTimeout = 0
+	require.Error(t, h.service.StartProbing(cfg))
+}
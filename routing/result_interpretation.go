@@ -12,6 +12,7 @@ import (
 var (
 	reasonError            = channeldb.FailureReasonError
 	reasonIncorrectDetails = channeldb.FailureReasonPaymentDetails
+	reasonNoRoute          = channeldb.FailureReasonNoRoute
 )
 
 // pairResult contains the result of the interpretation of a payment attempt for
@@ -267,6 +268,18 @@ func (i *interpretedResult) processPaymentOutcomeFinal(
 		// destination correctly. Continue the payment process.
 		i.successPairRange(route, 0, n-1)
 
+	// The trampoline node that the payment was sent to could not find a
+	// route to the recipient within the fee and cltv budget that we gave
+	// it. The route to the trampoline worked, so we don't penalize it, but
+	// retrying with the same budget won't help so we fail the payment.
+	case *lnwire.FailTemporaryTrampolineFailure,
+		*lnwire.FailTrampolineFeeInsufficient,
+		*lnwire.FailTrampolineExpiryTooSoon:
+
+		i.successPairRange(route, 0, n-1)
+
+		i.finalFailureReason = &reasonNoRoute
+
 	// We do not expect to receive an invalid blinding error from the final
 	// node in the route. This could erroneously happen in the following
 	// cases:
@@ -45,6 +45,11 @@ var (
 	// ErrUnexpectedField is returned if a tlv field is included when it
 	// should not be.
 	ErrUnexpectedField = errors.New("unexpected tlv included")
+
+	// ErrIntermediateTrampolineHop is returned when a trampoline onion is
+	// destined for a hop that isn't the final hop of the route.
+	ErrIntermediateTrampolineHop = errors.New("cannot send trampoline " +
+		"onion to intermediate")
 )
 
 // Vertex is a simple alias for the serialization of a compressed Bitcoin
@@ -157,6 +162,11 @@ type Hop struct {
 	// spread over more than one HTLC. This field should only be set for
 	// the final hop in a blinded path.
 	TotalAmtMsat lnwire.MilliSatoshi
+
+	// TrampolineOnion is the serialized inner trampoline onion that is
+	// delivered to the final hop of the route when paying via a
+	// trampoline node. This field should only be set for the final hop.
+	TrampolineOnion []byte
 }
 
 // Copy returns a deep copy of the Hop.
@@ -303,6 +313,18 @@ func (h *Hop) PackHopPayload(w io.Writer, nextChanID uint64,
 		)
 	}
 
+	// A trampoline onion is only ever delivered to the final hop, which is
+	// the trampoline node that will unwrap it.
+	if h.TrampolineOnion != nil {
+		if !finalHop {
+			return ErrIntermediateTrampolineHop
+		}
+
+		records = append(records,
+			record.NewTrampolineOnionRecord(&h.TrampolineOnion),
+		)
+	}
+
 	// Append any custom types destined for this hop.
 	tlvRecords := tlv.MapToRecords(h.CustomRecords)
 	records = append(records, tlvRecords...)
@@ -445,6 +467,14 @@ func (h *Hop) PayloadSize(nextChanID uint64) uint64 {
 		)
 	}
 
+	// Add trampoline onion if present.
+	if h.TrampolineOnion != nil {
+		addRecord(
+			record.TrampolineOnionType,
+			uint64(len(h.TrampolineOnion)),
+		)
+	}
+
 	// Add custom records.
 	for k, v := range h.CustomRecords {
 		addRecord(tlv.Type(k), uint64(len(v)))
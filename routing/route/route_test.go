@@ -342,6 +342,25 @@ func TestPayloadSize(t *testing.T) {
 		},
 	}
 
+	trampolineHops := []*Hop{
+		{
+			PubKeyBytes:      testPubKeyBytes,
+			AmtToForward:     1500,
+			OutgoingTimeLock: 700000,
+			ChannelID:        63584534844,
+		},
+		{
+			// Final hop is the trampoline node, which receives
+			// the inner trampoline onion.
+			PubKeyBytes:      testPubKeyBytes,
+			AmtToForward:     1000,
+			OutgoingTimeLock: 700000,
+			ChannelID:        51784534844,
+			MPP:              record.NewMPP(1000, [32]byte{}),
+			TrampolineOnion:  bytes.Repeat([]byte{1}, 466),
+		},
+	}
+
 	testCases := []struct {
 		name string
 		hops []*Hop
@@ -354,6 +373,10 @@ func TestPayloadSize(t *testing.T) {
 			name: "blinded route",
 			hops: blindedHops,
 		},
+		{
+			name: "trampoline route",
+			hops: trampolineHops,
+		},
 	}
 
 	for _, testCase := range testCases {
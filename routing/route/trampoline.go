@@ -0,0 +1,79 @@
+package route
+
+import (
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// TrampolineHop represents a trampoline node within the inner trampoline
+// route of a payment. It holds the values that are delivered to the node in
+// the trampoline onion, which instruct it where to forward the payment to.
+type TrampolineHop struct {
+	// PubKeyBytes is the raw bytes of the public key of the trampoline
+	// node.
+	PubKeyBytes Vertex
+
+	// AmtToForward is the amount that the trampoline node should deliver
+	// to the outgoing node.
+	AmtToForward lnwire.MilliSatoshi
+
+	// OutgoingTimeLock is the absolute timelock of the HTLC that the
+	// outgoing node should receive.
+	OutgoingTimeLock uint32
+
+	// OutgoingNodeID is the node that the trampoline should forward the
+	// payment to. It is nil if the trampoline node is the recipient.
+	OutgoingNodeID *Vertex
+
+	// MPP holds the payment address and total amount of the recipient's
+	// invoice, which the trampoline node includes in the final hop of the
+	// route that it pays the recipient over.
+	MPP *record.MPP
+
+	// Metadata is the payment metadata for the recipient.
+	Metadata []byte
+}
+
+// PackHopPayload writes the TLV payload for the trampoline node to the passed
+// io.Writer.
+func (h *TrampolineHop) PackHopPayload(w io.Writer) error {
+	if h.AmtToForward == 0 || h.OutgoingTimeLock == 0 {
+		return ErrMissingField
+	}
+
+	amt := uint64(h.AmtToForward)
+	records := []tlv.Record{
+		record.NewAmtToFwdRecord(&amt),
+		record.NewLockTimeRecord(&h.OutgoingTimeLock),
+	}
+
+	if h.MPP != nil {
+		records = append(records, h.MPP.Record())
+	}
+
+	if h.Metadata != nil {
+		records = append(records, record.NewMetadataRecord(&h.Metadata))
+	}
+
+	if h.OutgoingNodeID != nil {
+		nodeID, err := btcec.ParsePubKey(h.OutgoingNodeID[:])
+		if err != nil {
+			return err
+		}
+
+		records = append(records, record.NewOutgoingNodeIDRecord(
+			&nodeID,
+		))
+	}
+
+	tlvStream, err := tlv.NewStream(records...)
+	if err != nil {
+		return err
+	}
+
+	return tlvStream.Encode(w)
+}
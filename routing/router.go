@@ -270,6 +270,12 @@ type Config struct {
 	// returned.
 	GetLink getLinkQuery
 
+	// DustExposureExceeded optionally checks whether an outgoing htlc of
+	// the given amount would push the dust exposure of one of our channels
+	// over the fee exposure threshold. It is used by payment simulations.
+	DustExposureExceeded func(lnwire.ShortChannelID,
+		lnwire.MilliSatoshi) (bool, error)
+
 	// NextPaymentID is a method that guarantees to return a new, unique ID
 	// each time it is called. This is used by the router to generate a
 	// unique payment ID for each payment it attempts to send, such that
@@ -603,6 +609,18 @@ type BlindedPathRestrictions struct {
 	// NodeOmissionSet is a set of nodes that should not be used within any
 	// of the blinded paths that we generate.
 	NodeOmissionSet fn.Set[route.Vertex]
+
+	// IntroNodes is the set of nodes that may be used as the introduction
+	// node of the blinded paths that we generate. If it is empty, any node
+	// may be used.
+	IntroNodes fn.Set[route.Vertex]
+
+	// IntroNodeScore optionally scores the introduction node of a path. If
+	// it is set, paths are selected by the score of their introduction
+	// node before their success probability. This allows receivers to
+	// prefer introduction nodes that hide them among many possible
+	// recipients, such as nodes with a high centrality.
+	IntroNodeScore func(node route.Vertex) float64
 }
 
 // FindBlindedPaths finds a selection of paths to the destination node that can
@@ -629,6 +647,7 @@ func (r *ChannelRouter) FindBlindedPaths(destination route.Vertex,
 	type routeWithProbability struct {
 		route       *route.Route
 		probability float64
+		introScore  float64
 	}
 
 	// Iterate over all the candidate paths and determine the success
@@ -641,6 +660,14 @@ func (r *ChannelRouter) FindBlindedPaths(destination route.Vertex,
 				"least one hop")
 		}
 
+		// Skip paths whose introduction node wasn't selected by the
+		// receiver.
+		if len(restrictions.IntroNodes) > 0 &&
+			!restrictions.IntroNodes.Contains(path[0].vertex) {
+
+			continue
+		}
+
 		var (
 			introNode = path[0].vertex
 			prevNode  = introNode
@@ -675,17 +702,28 @@ func (r *ChannelRouter) FindBlindedPaths(destination route.Vertex,
 			continue
 		}
 
+		var introScore float64
+		if restrictions.IntroNodeScore != nil {
+			introScore = restrictions.IntroNodeScore(introNode)
+		}
+
 		routes = append(routes, &routeWithProbability{
 			route: &route.Route{
 				SourcePubKey: introNode,
 				Hops:         hops,
 			},
 			probability: totalRouteProbability,
+			introScore:  introScore,
 		})
 	}
 
-	// Sort the routes based on probability.
+	// Sort the routes based on the score of their introduction node, if
+	// any, and then on probability.
 	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].introScore != routes[j].introScore {
+			return routes[i].introScore > routes[j].introScore
+		}
+
 		return routes[i].probability > routes[j].probability
 	})
 
@@ -885,6 +923,38 @@ type LightningPayment struct {
 	// Metadata is additional data that is sent along with the payment to
 	// the payee.
 	Metadata []byte
+
+	// MinCostFlow indicates whether the payment should be split up front
+	// by solving a min-cost flow over the graph, rather than by halving
+	// the amount whenever no route can be found. If the flow can't be
+	// solved, splitting falls back to halving.
+	MinCostFlow bool
+
+	// Rebalance indicates that this is a circular payment to ourselves
+	// that moves liquidity between our own channels. It is recorded with
+	// the payment to tell it apart from user payments.
+	Rebalance bool
+
+	// MissionControl optionally overrides the mission control of the
+	// router for this payment. If set, path finding uses its probability
+	// estimates and the results of the payment's attempts are reported to
+	// it. This allows payments of different classes to be tracked in
+	// separate mission control namespaces.
+	MissionControl MissionController
+
+	// TrampolineNode is an optional trampoline node that the payment is
+	// sent through. If set, we only find a route to the trampoline node,
+	// which finds the route to the final destination on our behalf.
+	TrampolineNode *route.Vertex
+
+	// TrampolineFee is the fee that the trampoline node is offered for
+	// forwarding the payment to the final destination. It is paid on top
+	// of the payment amount and counts towards the fee limit.
+	TrampolineFee lnwire.MilliSatoshi
+
+	// trampolineOnion is the trampoline onion that is delivered to the
+	// trampoline node. It is set when the payment is prepared.
+	trampolineOnion []byte
 }
 
 // AMPOptions houses information that must be known in order to send an AMP
@@ -948,6 +1018,7 @@ func (r *ChannelRouter) SendPayment(payment *LightningPayment) ([32]byte,
 	return r.sendPayment(
 		context.Background(), payment.FeeLimit, payment.Identifier(),
 		payment.PayAttemptTimeout, paySession, shardTracker,
+		payment.MissionControl,
 	)
 }
 
@@ -968,6 +1039,7 @@ func (r *ChannelRouter) SendPaymentAsync(ctx context.Context,
 		_, _, err := r.sendPayment(
 			ctx, payment.FeeLimit, payment.Identifier(),
 			payment.PayAttemptTimeout, ps, st,
+			payment.MissionControl,
 		)
 		if err != nil {
 			log.Errorf("Payment %x failed: %v",
@@ -993,6 +1065,11 @@ func spewPayment(payment *LightningPayment) lnutils.LogClosure {
 		}
 		p := *payment
 		p.RouteHints = routeHints
+
+		// The mission control would be dumped along with all of its
+		// state, so we leave it out.
+		p.MissionControl = nil
+
 		return spew.Sdump(p)
 	})
 }
@@ -1002,6 +1079,15 @@ func spewPayment(payment *LightningPayment) lnutils.LogClosure {
 func (r *ChannelRouter) PreparePayment(payment *LightningPayment) (
 	PaymentSession, shards.ShardTracker, error) {
 
+	// If the payment is sent through a trampoline node, we'll rewrite it
+	// into a payment to the trampoline node that carries the trampoline
+	// onion.
+	if payment.TrampolineNode != nil {
+		if err := r.prepareTrampolinePayment(payment); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Before starting the HTLC routing attempt, we'll create a fresh
 	// payment session which will report our errors back to mission
 	// control.
@@ -1019,6 +1105,7 @@ func (r *ChannelRouter) PreparePayment(payment *LightningPayment) (
 		Value:             payment.Amount,
 		CreationTime:      r.cfg.Clock.Now(),
 		PaymentRequest:    payment.PaymentRequest,
+		Rebalance:         payment.Rebalance,
 	}
 
 	// Create a new ShardTracker that we'll use during the life cycle of
@@ -1234,10 +1321,14 @@ func (r *ChannelRouter) sendToRoute(htlcHash lntypes.Hash, rt *route.Route,
 // carry out its execution. After restarts, it is safe, and assumed, that the
 // router will call this method for every payment still in-flight according to
 // the ControlTower.
+//
+// If missionControl is non-nil, the results of the payment's attempts are
+// reported to it instead of the router's mission control.
 func (r *ChannelRouter) sendPayment(ctx context.Context,
 	feeLimit lnwire.MilliSatoshi, identifier lntypes.Hash,
 	paymentAttemptTimeout time.Duration, paySession PaymentSession,
-	shardTracker shards.ShardTracker) ([32]byte, *route.Route, error) {
+	shardTracker shards.ShardTracker,
+	missionControl MissionController) ([32]byte, *route.Route, error) {
 
 	// If the user provides a timeout, we will additionally wrap the context
 	// in a deadline.
@@ -1264,6 +1355,9 @@ func (r *ChannelRouter) sendPayment(ctx context.Context,
 		r, feeLimit, identifier, paySession, shardTracker,
 		currentHeight,
 	)
+	if missionControl != nil {
+		p.missionControl = missionControl
+	}
 
 	return p.resumePayment(ctx)
 }
@@ -1465,7 +1559,7 @@ func (r *ChannelRouter) resumePayments() error {
 		noTimeout := time.Duration(0)
 		_, _, err := r.sendPayment(
 			context.Background(), 0, payHash, noTimeout, paySession,
-			shardTracker,
+			shardTracker, nil,
 		)
 		if err != nil {
 			log.Errorf("Resuming payment %v failed: %v", payHash,
@@ -3259,4 +3259,43 @@ func TestFindBlindedPathsWithMC(t *testing.T) {
 	assertPaths(routes, []string{
 		"alice,bob,dave",
 	})
+
+	// If the user selects the introduction nodes, then only paths that
+	// start at one of them are chosen.
+	routes, err = ctx.router.FindBlindedPaths(
+		dave, 1000, probabilitySrc, &BlindedPathRestrictions{
+			MinDistanceFromIntroNode: 1,
+			NumHops:                  2,
+			MaxNumPaths:              3,
+			IntroNodes:               fn.NewSet(bob, frank),
+		},
+	)
+	require.NoError(t, err)
+	assertPaths(routes, []string{
+		"bob,dave",
+		"frank,dave",
+	})
+
+	// An introduction node score takes precedence over the success
+	// probability of the paths.
+	introScores := map[route.Vertex]float64{
+		bob:   0.5,
+		frank: 1,
+	}
+	routes, err = ctx.router.FindBlindedPaths(
+		dave, 1000, probabilitySrc, &BlindedPathRestrictions{
+			MinDistanceFromIntroNode: 1,
+			NumHops:                  2,
+			MaxNumPaths:              3,
+			IntroNodes:               fn.NewSet(bob, frank),
+			IntroNodeScore: func(node route.Vertex) float64 {
+				return introScores[node]
+			},
+		},
+	)
+	require.NoError(t, err)
+	assertPaths(routes, []string{
+		"frank,dave",
+		"bob,dave",
+	})
 }
@@ -0,0 +1,395 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	switchhop "github.com/lightningnetwork/lnd/htlcswitch/hop"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TrampolineCLTVDelta is the CLTV delta that we leave to a trampoline node on
+// top of the final CLTV delta of the recipient. The trampoline node needs to
+// fit both its own CLTV delta and its route to the recipient within it.
+const TrampolineCLTVDelta uint16 = 288
+
+// trampolineMaxParts is the maximum number of parts that a payment which we
+// forward as a trampoline node is split into.
+const trampolineMaxParts = 16
+
+var (
+	// ErrTrampolineFeeLimit is returned when the fee offered to a
+	// trampoline node exceeds the fee limit of the payment.
+	ErrTrampolineFeeLimit = errors.New("trampoline fee exceeds fee limit")
+
+	// trampolineFeatures are the features that we assume a trampoline
+	// node to have for the payment that we send to it. Trampoline nodes
+	// collect the htlcs of a payment before forwarding it, so the payment
+	// can be split.
+	trampolineFeatures = lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.TLVOnionPayloadRequired,
+			lnwire.PaymentAddrRequired,
+			lnwire.MPPOptional,
+			lnwire.TrampolineRoutingOptional,
+		), lnwire.Features,
+	)
+
+	// trampolineRecipientFeatures are the features that we assume the
+	// recipient of a payment that we forward as a trampoline node to
+	// have, since the sender doesn't tell us the features of its invoice.
+	trampolineRecipientFeatures = lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.TLVOnionPayloadRequired,
+			lnwire.PaymentAddrRequired,
+			lnwire.MPPOptional,
+		), lnwire.Features,
+	)
+)
+
+// prepareTrampolinePayment rewrites a payment that is sent through a
+// trampoline node into a payment to the trampoline node. The trampoline onion
+// that is attached to the final hop instructs the trampoline node to forward
+// the original amount to the destination.
+func (r *ChannelRouter) prepareTrampolinePayment(
+	payment *LightningPayment) error {
+
+	switch {
+	case payment.paymentHash == nil:
+		return errors.New("trampoline payments require a payment hash")
+
+	case payment.BlindedPathSet != nil:
+		return errors.New("trampoline payments can't be sent to " +
+			"blinded paths")
+
+	case len(payment.RouteHints) > 0:
+		return errors.New("trampoline payments can't use route hints")
+
+	case len(payment.DestCustomRecords) > 0:
+		return errors.New("trampoline payments can't carry custom " +
+			"records")
+
+	case *payment.TrampolineNode == payment.Target:
+		return errors.New("trampoline node is the destination")
+
+	case payment.TrampolineFee > payment.FeeLimit:
+		return fmt.Errorf("%w: fee %v, limit %v", ErrTrampolineFeeLimit,
+			payment.TrampolineFee, payment.FeeLimit)
+	}
+
+	_, height, err := r.cfg.Chain.GetBestBlock()
+	if err != nil {
+		return err
+	}
+
+	// The trampoline node delivers the full payment amount to the
+	// destination, along with the payment address and metadata of the
+	// invoice.
+	target := payment.Target
+	trampolineHop := &route.TrampolineHop{
+		PubKeyBytes:  *payment.TrampolineNode,
+		AmtToForward: payment.Amount,
+		OutgoingTimeLock: uint32(height) +
+			uint32(payment.FinalCLTVDelta+BlockPadding),
+		OutgoingNodeID: &target,
+		Metadata:       payment.Metadata,
+	}
+	if payment.PaymentAddr != nil {
+		trampolineHop.MPP = record.NewMPP(
+			payment.Amount, *payment.PaymentAddr,
+		)
+	}
+
+	sessionKey, err := generateNewSessionKey()
+	if err != nil {
+		return err
+	}
+
+	onion, err := newTrampolineOnion(
+		[]*route.TrampolineHop{trampolineHop}, sessionKey,
+		payment.paymentHash[:],
+	)
+	if err != nil {
+		return err
+	}
+
+	// The payment to the trampoline node uses a payment address of our
+	// own, since the trampoline node doesn't have an invoice for it.
+	var paymentAddr [32]byte
+	if _, err := rand.Read(paymentAddr[:]); err != nil {
+		return err
+	}
+
+	log.Debugf("Sending payment %v to %v via trampoline node %v with "+
+		"fee %v", payment.paymentHash, payment.Target,
+		payment.TrampolineNode, payment.TrampolineFee)
+
+	payment.Target = *payment.TrampolineNode
+	payment.Amount += payment.TrampolineFee
+	payment.FeeLimit -= payment.TrampolineFee
+	payment.FinalCLTVDelta += TrampolineCLTVDelta
+	payment.PaymentAddr = &paymentAddr
+	payment.DestFeatures = trampolineFeatures
+	payment.Metadata = nil
+	payment.LastHop = nil
+	payment.MinCostFlow = false
+	payment.trampolineOnion = onion
+
+	return nil
+}
+
+// newTrampolineOnion constructs the serialized trampoline onion that routes
+// through the given trampoline hops. The onion commits to the payment hash so
+// that it can't be reused for a different payment.
+func newTrampolineOnion(hops []*route.TrampolineHop,
+	sessionKey *btcec.PrivateKey, paymentHash []byte) ([]byte, error) {
+
+	if len(hops) == 0 {
+		return nil, errors.New("no trampoline hops provided")
+	}
+
+	payloads := make([]switchhop.TrampolineHopPayload, len(hops))
+	for i, trampolineHop := range hops {
+		nodePub, err := btcec.ParsePubKey(trampolineHop.PubKeyBytes[:])
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := trampolineHop.PackHopPayload(&b); err != nil {
+			return nil, err
+		}
+
+		payloads[i] = switchhop.TrampolineHopPayload{
+			NodePub: nodePub,
+			Payload: b.Bytes(),
+		}
+	}
+
+	packet, err := switchhop.NewTrampolineOnionPacket(
+		payloads, sessionKey, paymentHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := packet.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// SendTrampolinePayment sends the payment that forwards a payment that was
+// delivered to us as a trampoline node. The payment goes through the regular
+// payment lifecycle, so it is split and retried along different routes until
+// it succeeds, runs out of routes within its fee and cltv budget or times out.
+// If the payment was already sent, the result of that payment is returned.
+func (r *ChannelRouter) SendTrampolinePayment(
+	req *htlcswitch.TrampolinePaymentRequest) (
+	<-chan *htlcswitch.TrampolinePaymentResult, error) {
+
+	log.Debugf("Forwarding trampoline payment %v to %v, sending %v",
+		req.PaymentHash, req.Target, req.Amount)
+
+	_, currentHeight, err := r.cfg.Chain.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	height := uint32(currentHeight)
+	switch {
+	case req.FinalCltv <= height:
+		return nil, fmt.Errorf("final cltv %v already expired at "+
+			"height %v", req.FinalCltv, height)
+
+	case req.FinalCltv-height > math.MaxUint16:
+		return nil, fmt.Errorf("final cltv %v too far in the future",
+			req.FinalCltv)
+
+	case req.CltvLimit <= req.FinalCltv:
+		return nil, fmt.Errorf("cltv limit %v below final cltv %v",
+			req.CltvLimit, req.FinalCltv)
+
+	// The payment lifecycle pays the full amount of the payment, so we
+	// can't deliver a part of a payment that is split over multiple
+	// trampoline nodes.
+	case req.MPP != nil && req.MPP.TotalMsat() != req.Amount:
+		return nil, fmt.Errorf("trampoline payment of %v is part of "+
+			"a payment of %v", req.Amount, req.MPP.TotalMsat())
+	}
+
+	// If the target is the final recipient, we pay to its invoice.
+	// Otherwise, the target is the next trampoline node, which we pay
+	// with a payment address of our own.
+	var (
+		paymentAddr  [32]byte
+		destFeatures = trampolineRecipientFeatures
+	)
+	if req.MPP != nil {
+		paymentAddr = req.MPP.PaymentAddr()
+	} else {
+		if _, err := rand.Read(paymentAddr[:]); err != nil {
+			return nil, err
+		}
+
+		destFeatures = trampolineFeatures
+	}
+
+	payment := &LightningPayment{
+		Target:            req.Target,
+		Amount:            req.Amount,
+		FeeLimit:          req.FeeLimit,
+		CltvLimit:         req.CltvLimit - height,
+		FinalCLTVDelta:    uint16(req.FinalCltv - height),
+		PayAttemptTimeout: DefaultPayAttemptTimeout,
+		DestFeatures:      destFeatures,
+		PaymentAddr:       &paymentAddr,
+		MaxParts:          trampolineMaxParts,
+		Metadata:          req.Metadata,
+		trampolineOnion:   req.TrampolineOnion,
+	}
+	if err := payment.SetPaymentHash(req.PaymentHash); err != nil {
+		return nil, err
+	}
+
+	paySession, shardTracker, err := r.PreparePayment(payment)
+	switch {
+	// If we already sent the payment, we wait for its result instead of
+	// sending it again.
+	case errors.Is(err, channeldb.ErrPaymentExists),
+		errors.Is(err, channeldb.ErrPaymentInFlight),
+		errors.Is(err, channeldb.ErrAlreadyPaid):
+
+		log.Debugf("Trampoline payment %v was already sent",
+			req.PaymentHash)
+
+	case err != nil:
+		return nil, err
+
+	default:
+		r.SendPaymentAsync(
+			context.Background(), payment, paySession, shardTracker,
+		)
+	}
+
+	return r.trampolinePaymentResult(
+		req.PaymentHash, req.TrampolineOnion == nil,
+	)
+}
+
+// LookupTrampolinePayment returns a channel that receives the result of the
+// payment with the given hash if it is in flight or succeeded. It returns a
+// nil channel if there is no such payment, or if it failed and may be sent
+// again.
+func (r *ChannelRouter) LookupTrampolinePayment(paymentHash lntypes.Hash) (
+	<-chan *htlcswitch.TrampolinePaymentResult, error) {
+
+	payment, err := r.cfg.Control.FetchPayment(paymentHash)
+	switch {
+	case errors.Is(err, channeldb.ErrPaymentNotInitiated):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+
+	case payment.GetStatus() == channeldb.StatusFailed:
+		return nil, nil
+	}
+
+	// We don't know whether the payment was sent to the final recipient,
+	// so payment detail failures are returned as trampoline failures.
+	return r.trampolinePaymentResult(paymentHash, false)
+}
+
+// trampolinePaymentResult subscribes to the payment with the given hash and
+// returns a channel that receives its result once the payment is resolved.
+func (r *ChannelRouter) trampolinePaymentResult(paymentHash lntypes.Hash,
+	finalRecipient bool) (<-chan *htlcswitch.TrampolinePaymentResult,
+	error) {
+
+	sub, err := r.cfg.Control.SubscribePayment(paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan *htlcswitch.TrampolinePaymentResult, 1)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer sub.Close()
+
+		for {
+			var item interface{}
+			select {
+			case item = <-sub.Updates():
+			case <-r.quit:
+				return
+			}
+
+			payment, ok := item.(*channeldb.MPPayment)
+			if !ok {
+				return
+			}
+
+			// As soon as one of the htlcs settles, we know the
+			// preimage and can settle the incoming htlcs.
+			settle, reason := payment.TerminalInfo()
+			switch {
+			case settle != nil:
+				preimage := settle.Settle.Preimage
+				results <- &htlcswitch.TrampolinePaymentResult{
+					Preimage: &preimage,
+				}
+
+				return
+
+			case payment.Terminated() && reason != nil:
+				failure := trampolineFailure(
+					*reason, payment.Info.Value,
+					finalRecipient,
+				)
+				results <- &htlcswitch.TrampolinePaymentResult{
+					Failure: failure,
+				}
+
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// trampolineFailure returns the failure that the incoming htlcs of a
+// trampoline payment are failed back with if the outgoing payment failed.
+func trampolineFailure(reason channeldb.FailureReason,
+	amt lnwire.MilliSatoshi, finalRecipient bool) lnwire.FailureMessage {
+
+	switch {
+	// If the final recipient rejected the payment, the sender learns that
+	// the payment details are wrong.
+	case reason == channeldb.FailureReasonPaymentDetails && finalRecipient:
+		return lnwire.NewFailIncorrectDetails(amt, 0)
+
+	// If we didn't find a route within the fee and cltv budget of the
+	// sender, it may try again with a higher budget.
+	case reason == channeldb.FailureReasonNoRoute:
+		return &lnwire.FailTrampolineFeeInsufficient{}
+
+	default:
+		return &lnwire.FailTemporaryTrampolineFailure{}
+	}
+}
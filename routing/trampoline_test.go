@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	switchhop "github.com/lightningnetwork/lnd/htlcswitch/hop"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrepareTrampolinePayment asserts that a payment through a trampoline
+// node is turned into a payment to the trampoline node, which carries an onion
+// that instructs the trampoline node to pay the original destination.
+func TestPrepareTrampolinePayment(t *testing.T) {
+	t.Parallel()
+
+	const height = 100
+
+	trampolineKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	destKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var (
+		trampolineNode = route.NewVertex(trampolineKey.PubKey())
+		dest           = route.NewVertex(destKey.PubKey())
+		paymentHash    = lntypes.Hash{1}
+		paymentAddr    = [32]byte{2}
+	)
+
+	router := &ChannelRouter{
+		cfg: &Config{
+			Chain: newMockChain(height),
+		},
+	}
+
+	newPayment := func() *LightningPayment {
+		payment := &LightningPayment{
+			Target:         dest,
+			Amount:         10_000,
+			FeeLimit:       1_000,
+			FinalCLTVDelta: 40,
+			PaymentAddr:    &paymentAddr,
+			Metadata:       []byte{3},
+			MaxParts:       16,
+			TrampolineNode: &trampolineNode,
+			TrampolineFee:  500,
+		}
+		require.NoError(t, payment.SetPaymentHash(paymentHash))
+
+		return payment
+	}
+
+	payment := newPayment()
+	require.NoError(t, router.prepareTrampolinePayment(payment))
+
+	// The outer payment goes to the trampoline node, and includes its
+	// fee.
+	require.Equal(t, trampolineNode, payment.Target)
+	require.Equal(t, lnwire.MilliSatoshi(10_500), payment.Amount)
+	require.Equal(t, lnwire.MilliSatoshi(500), payment.FeeLimit)
+	require.Equal(t, 40+TrampolineCLTVDelta, payment.FinalCLTVDelta)
+	require.NotEqual(t, paymentAddr, *payment.PaymentAddr)
+	require.Nil(t, payment.Metadata)
+
+	// The payment may still be split, as the trampoline node collects its
+	// htlcs before forwarding it.
+	require.Equal(t, uint32(16), payment.MaxParts)
+
+	// The trampoline node must be able to decode the onion and learn the
+	// original destination of the payment.
+	processor := switchhop.NewTrampolineProcessor(
+		&sphinx.PrivKeyECDH{PrivKey: trampolineKey},
+	)
+	payload, err := processor.DecodeTrampolineOnion(
+		payment.trampolineOnion, paymentHash[:],
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, lnwire.MilliSatoshi(10_000), payload.AmountToForward)
+	require.Equal(t, uint32(height+40+BlockPadding), payload.OutgoingCTLV)
+	require.True(t, destKey.PubKey().IsEqual(payload.OutgoingNodeID))
+	require.Equal(t, paymentAddr, payload.MPP.PaymentAddr())
+	require.Equal(t, lnwire.MilliSatoshi(10_000), payload.MPP.TotalMsat())
+	require.Equal(t, []byte{3}, payload.Metadata)
+	require.Nil(t, payload.NextOnion)
+
+	// A trampoline fee above the fee limit must be rejected.
+	payment = newPayment()
+	payment.TrampolineFee = 2_000
+	err = router.prepareTrampolinePayment(payment)
+	require.ErrorIs(t, err, ErrTrampolineFeeLimit)
+}
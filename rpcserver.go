@@ -3,6 +3,7 @@ package lnd
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -34,7 +35,9 @@ import (
 	"github.com/btcsuite/btcwallet/wallet/txauthor"
 	"github.com/davecgh/go-spew/spew"
 	proxy "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/autopilot"
+	"github.com/lightningnetwork/lnd/bolt12"
 	"github.com/lightningnetwork/lnd/build"
 	"github.com/lightningnetwork/lnd/chainreg"
 	"github.com/lightningnetwork/lnd/chanacceptor"
@@ -69,8 +72,11 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwallet/chancloser"
 	"github.com/lightningnetwork/lnd/lnwallet/chanfunding"
+	"github.com/lightningnetwork/lnd/lnwallet/dyncomm"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/macaroons"
+	"github.com/lightningnetwork/lnd/offers"
+	"github.com/lightningnetwork/lnd/onionmessage"
 	"github.com/lightningnetwork/lnd/peer"
 	"github.com/lightningnetwork/lnd/peernotifier"
 	"github.com/lightningnetwork/lnd/record"
@@ -337,6 +343,17 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SpliceChannel": {{
+			Entity: "onchain",
+			Action: "write",
+		}, {
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/UpgradeChannel": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/AbandonChannel": {{
 			Entity: "offchain",
 			Action: "write",
@@ -414,6 +431,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/PreviewBlindedPaths": {{
+			Entity: "invoices",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/LookupInvoice": {{
 			Entity: "invoices",
 			Action: "read",
@@ -568,6 +589,22 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/SendOnionMessage": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/SubscribeOnionMessages": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/AddOffer": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/FetchInvoice": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/LookupHtlcResolution": {{
 			Entity: "offchain",
 			Action: "read",
@@ -681,6 +718,20 @@ func (r *rpcServer) addDeps(s *server, macService *macaroons.Service,
 	}
 	graph := s.graphDB
 
+	// Payments and mission control RPCs may select a mission control
+	// namespace other than the default one. Only the calls that write to
+	// mission control create namespaces that don't exist yet.
+	getMissionControl := func(namespace string) (routerrpc.MissionControl,
+		error) {
+
+		return s.missionControlMgr.GetNamespacedStore(namespace)
+	}
+	fetchMissionControl := func(
+		namespace string) (routerrpc.MissionControl, error) {
+
+		return s.missionControlMgr.FetchNamespacedStore(namespace)
+	}
+
 	routerBackend := &routerrpc.RouterBackend{
 		SelfNode: selfNode.PubKeyBytes,
 		FetchChannelCapacity: func(chanID uint64) (btcutil.Amount,
@@ -717,12 +768,18 @@ func (r *rpcServer) addDeps(s *server, macService *macaroons.Service,
 		},
 		FindRoute:              s.chanRouter.FindRoute,
 		MissionControl:         s.missionControl,
+		GetMissionControl:      getMissionControl,
+		FetchMissionControl:    fetchMissionControl,
 		ActiveNetParams:        r.cfg.ActiveNetParams.Params,
 		Tower:                  s.controlTower,
 		MaxTotalTimelock:       r.cfg.MaxOutgoingCltvExpiry,
 		DefaultFinalCltvDelta:  uint16(r.cfg.Bitcoin.TimeLockDelta),
 		SubscribeHtlcEvents:    s.htlcNotifier.SubscribeHtlcEvents,
 		InterceptableForwarder: s.interceptableSwitch,
+		ExternalPathFinding:    s.externalPathFinding,
+		ProbeService:           s.probeService,
+		HtlcRateLimiter:        s.htlcRateLimiter,
+		AddInvoice:             s.invoices.AddInvoice,
 		SetChannelEnabled: func(outpoint wire.OutPoint) error {
 			return s.chanStatusMgr.RequestEnable(outpoint, true)
 		},
@@ -2331,6 +2388,7 @@ func (r *rpcServer) parseOpenChannelReq(in *lnrpc.OpenChannelRequest,
 		MinFundAmt:        minFundAmt,
 		Memo:              []byte(in.Memo),
 		Outpoints:         outpoints,
+		DualFund:          in.DualFund,
 	}, nil
 }
 
@@ -2606,6 +2664,13 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 		return fmt.Errorf("force closing a channel uses a pre-defined fee")
 	}
 
+	// Only the fee of a cooperative close can be bumped, and our delivery
+	// address has already been agreed on at that point.
+	if in.BumpFee && (in.Force || len(in.DeliveryAddress) > 0) {
+		return fmt.Errorf("bumping the fee of a close can't be " +
+			"combined with force or delivery_address")
+	}
+
 	force := in.Force
 	index := in.ChannelPoint.OutputIndex
 	txid, err := lnrpc.GetChanPointFundingTxid(in.GetChannelPoint())
@@ -2652,12 +2717,23 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 		return err
 	}
 
+	switch {
+	// If a fee bump was requested, we'll hand the request to the peer
+	// directly, as the link of the channel has been removed from the
+	// switch once the close negotiation started.
+	case in.BumpFee:
+		updateChan, errChan, err = r.bumpCoopCloseFee(
+			channel, in.SatPerVbyte, uint32(in.TargetConf),
+		)
+		if err != nil {
+			return err
+		}
+
 	// If a force closure was requested, then we'll handle all the details
 	// around the creation and broadcast of the unilateral closure
 	// transaction here rather than going to the switch as we don't require
 	// interaction from the peer.
-	if force {
-
+	case force:
 		// As we're force closing this channel, as a precaution, we'll
 		// ensure that the switch doesn't continue to see this channel
 		// as eligible for forwarding HTLC's. If the peer is online,
@@ -2706,7 +2782,8 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 					Success:     true,
 				}
 			})
-	} else {
+
+	default:
 		// If this is a frozen channel, then we only allow the co-op
 		// close to proceed if we were the responder to this channel if
 		// the absolute thaw height has not been met.
@@ -2854,6 +2931,44 @@ out:
 	return nil
 }
 
+// bumpCoopCloseFee asks the peer of the channel to replace our cooperative
+// closing transaction with one paying the passed fee rate, or the fee rate
+// estimated for the confirmation target.
+func (r *rpcServer) bumpCoopCloseFee(channel *channeldb.OpenChannel,
+	satPerVbyte uint64, targetConf uint32) (chan interface{}, chan error,
+	error) {
+
+	remotePeer, err := r.server.FindPeer(channel.IdentityPub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to bump fee of close while "+
+			"peer is offline: %w", err)
+	}
+
+	targetConf = maybeUseDefaultConf(0, satPerVbyte, targetConf)
+	feeRate, err := lnrpc.CalculateFeeRate(
+		0, satPerVbyte, targetConf, r.server.cc.FeeEstimator,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rpcsLog.Debugf("Bumping fee of closing transaction of "+
+		"ChannelPoint(%v) to %v sat/kw", channel.FundingOutpoint,
+		int64(feeRate))
+
+	updateChan := make(chan interface{}, 2)
+	errChan := make(chan error, 1)
+	remotePeer.HandleLocalCloseChanReqs(&htlcswitch.ChanClose{
+		CloseType:      contractcourt.CloseRegular,
+		ChanPoint:      &channel.FundingOutpoint,
+		Updates:        updateChan,
+		TargetFeePerKw: feeRate,
+		Err:            errChan,
+	})
+
+	return updateChan, errChan, nil
+}
+
 func createRPCCloseUpdate(update interface{}) (
 	*lnrpc.CloseStatusUpdate, error) {
 
@@ -2881,6 +2996,247 @@ func createRPCCloseUpdate(update interface{}) (
 	return nil, errors.New("unknown close status update")
 }
 
+// SpliceChannel resizes an active channel by splicing funds into or out of
+// it. The call returns once the splice transaction has been broadcast.
+func (r *rpcServer) SpliceChannel(ctx context.Context,
+	in *lnrpc.SpliceChannelRequest) (*lnrpc.SpliceChannelResponse, error) {
+
+	if !r.server.Started() {
+		return nil, ErrServerNotActive
+	}
+
+	if in.GetChannelPoint() == nil {
+		return nil, fmt.Errorf("must specify channel point in splice " +
+			"channel")
+	}
+
+	if in.Amount == 0 {
+		return nil, fmt.Errorf("splice amount must be non-zero")
+	}
+
+	txid, err := lnrpc.GetChanPointFundingTxid(in.GetChannelPoint())
+	if err != nil {
+		return nil, err
+	}
+	chanPoint := wire.NewOutPoint(txid, in.ChannelPoint.OutputIndex)
+
+	channel, err := r.server.chanStateDB.FetchChannel(nil, *chanPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// The resulting capacity of the channel must still be within the
+	// allowed channel size.
+	capacity := channel.Capacity + btcutil.Amount(in.Amount)
+	globalFeatureSet := r.server.featureMgr.Get(feature.SetNodeAnn)
+	wumboEnabled := globalFeatureSet.HasFeature(
+		lnwire.WumboChannelsOptional,
+	)
+	if !wumboEnabled && capacity > MaxFundingAmount {
+		return nil, fmt.Errorf("spliced channel capacity of %v "+
+			"exceeds the maximum channel size of %v", capacity,
+			MaxFundingAmount)
+	}
+
+	targetConf := maybeUseDefaultConf(
+		0, in.SatPerVbyte, uint32(in.TargetConf),
+	)
+	feeRate, err := lnrpc.CalculateFeeRate(
+		0, in.SatPerVbyte, targetConf, r.server.cc.FeeEstimator,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveryScript []byte
+	if in.Address != "" {
+		if in.Amount > 0 {
+			return nil, fmt.Errorf("an address can only be " +
+				"specified for a splice-out")
+		}
+
+		addr, err := btcutil.DecodeAddress(
+			in.Address, r.cfg.ActiveNetParams.Params,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+
+		if !addr.IsForNet(r.cfg.ActiveNetParams.Params) {
+			return nil, fmt.Errorf("address is not for %s",
+				r.cfg.ActiveNetParams.Params.Name)
+		}
+
+		deliveryScript, err = txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	remotePeer, err := r.server.FindPeer(channel.IdentityPub)
+	if err != nil {
+		return nil, fmt.Errorf("unable to splice channel while peer "+
+			"is offline: %w", err)
+	}
+
+	rpcsLog.Debugf("[splicechannel] request for ChannelPoint(%v), "+
+		"amount=%v, fee_rate=%v", chanPoint, in.Amount, feeRate)
+
+	req := &peer.SpliceReq{
+		ChanPoint:      *chanPoint,
+		Amount:         btcutil.Amount(in.Amount),
+		FeeRate:        feeRate,
+		DeliveryScript: deliveryScript,
+		Txid:           make(chan chainhash.Hash, 1),
+		Err:            make(chan error, 1),
+	}
+	if err := remotePeer.Splice(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case spliceTxid := <-req.Txid:
+		rpcsLog.Infof("[splicechannel] splice of ChannelPoint(%v) "+
+			"broadcast: txid(%v)", chanPoint, spliceTxid)
+
+		return &lnrpc.SpliceChannelResponse{
+			Txid: spliceTxid[:],
+		}, nil
+
+	case err := <-req.Err:
+		return nil, err
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-r.quit:
+		return nil, ErrServerShuttingDown
+	}
+}
+
+// UpgradeChannel changes the commitment type or the parameters of an active
+// channel with a dynamic commitment upgrade. The call returns once the
+// upgrade was applied by both parties.
+func (r *rpcServer) UpgradeChannel(ctx context.Context,
+	in *lnrpc.UpgradeChannelRequest) (*lnrpc.UpgradeChannelResponse,
+	error) {
+
+	if !r.server.Started() {
+		return nil, ErrServerNotActive
+	}
+
+	if in.GetChannelPoint() == nil {
+		return nil, fmt.Errorf("must specify channel point in upgrade " +
+			"channel")
+	}
+
+	var params dyncomm.Request
+	switch in.CommitmentType {
+	case lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE:
+
+	case lnrpc.CommitmentType_STATIC_REMOTE_KEY:
+		params.ChannelType = fn.Some(lnwire.ChannelType(
+			*lnwire.NewRawFeatureVector(
+				lnwire.StaticRemoteKeyRequired,
+			),
+		))
+
+	case lnrpc.CommitmentType_ANCHORS:
+		params.ChannelType = fn.Some(lnwire.ChannelType(
+			*lnwire.NewRawFeatureVector(
+				lnwire.StaticRemoteKeyRequired,
+				lnwire.AnchorsZeroFeeHtlcTxRequired,
+			),
+		))
+
+	// Taproot channels need a new funding output, which a dynamic
+	// commitment upgrade can't create.
+	case lnrpc.CommitmentType_SIMPLE_TAPROOT:
+		return nil, fmt.Errorf("channels can't be upgraded to "+
+			"taproot channels yet: %w",
+			lnwallet.ErrDynCommitUnsupportedChanType)
+
+	default:
+		return nil, fmt.Errorf("channels can't be upgraded to "+
+			"commitment type %v", in.CommitmentType)
+	}
+
+	if in.DustLimitSat != 0 {
+		params.DustLimit = fn.Some(btcutil.Amount(in.DustLimitSat))
+	}
+	if in.RemoteMaxValueInFlightMsat != 0 {
+		params.MaxValueInFlight = fn.Some(
+			lnwire.MilliSatoshi(in.RemoteMaxValueInFlightMsat),
+		)
+	}
+	if in.RemoteChanReserveSat != 0 {
+		params.ChannelReserve = fn.Some(
+			btcutil.Amount(in.RemoteChanReserveSat),
+		)
+	}
+	if in.RemoteCsvDelay != 0 {
+		if in.RemoteCsvDelay > math.MaxUint16 {
+			return nil, fmt.Errorf("remote csv delay too large")
+		}
+
+		params.CsvDelay = fn.Some(uint16(in.RemoteCsvDelay))
+	}
+	if in.RemoteMaxHtlcs != 0 {
+		if in.RemoteMaxHtlcs > math.MaxUint16 {
+			return nil, fmt.Errorf("remote max htlcs too large")
+		}
+
+		params.MaxAcceptedHTLCs = fn.Some(uint16(in.RemoteMaxHtlcs))
+	}
+
+	txid, err := lnrpc.GetChanPointFundingTxid(in.GetChannelPoint())
+	if err != nil {
+		return nil, err
+	}
+	chanPoint := wire.NewOutPoint(txid, in.ChannelPoint.OutputIndex)
+
+	channel, err := r.server.chanStateDB.FetchChannel(nil, *chanPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	remotePeer, err := r.server.FindPeer(channel.IdentityPub)
+	if err != nil {
+		return nil, fmt.Errorf("unable to upgrade channel while peer "+
+			"is offline: %w", err)
+	}
+
+	rpcsLog.Debugf("[upgradechannel] request for ChannelPoint(%v), "+
+		"commitment_type=%v", chanPoint, in.CommitmentType)
+
+	req := &peer.DynCommitReq{
+		ChanPoint: *chanPoint,
+		Params:    params,
+		Err:       make(chan error, 1),
+	}
+	if err := remotePeer.UpgradeChannel(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case err := <-req.Err:
+		if err != nil {
+			return nil, err
+		}
+
+		rpcsLog.Infof("[upgradechannel] upgraded ChannelPoint(%v)",
+			chanPoint)
+
+		return &lnrpc.UpgradeChannelResponse{}, nil
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-r.quit:
+		return nil, ErrServerShuttingDown
+	}
+}
+
 // abandonChanFromGraph attempts to remove a channel from the channel graph. If
 // we can't find the chanID in the graph, then we assume it has already been
 // removed, and will return a nop.
@@ -5765,17 +6121,12 @@ func (r *rpcServer) sendPaymentSync(
 	}, nil
 }
 
-// AddInvoice attempts to add a new invoice to the invoice database. Any
-// duplicated invoices are rejected, therefore all invoices *must* have a
-// unique payment preimage.
-func (r *rpcServer) AddInvoice(ctx context.Context,
-	invoice *lnrpc.Invoice) (*lnrpc.AddInvoiceResponse, error) {
-
-	var (
-		defaultDelta = r.cfg.Bitcoin.TimeLockDelta
-		blindCfg     = invoice.BlindedPathConfig
-		blind        = invoice.IsBlinded
-	)
+// blindedPathRestrictions derives the restrictions that the blinded paths of
+// an invoice must satisfy from the global config and the optional per-invoice
+// blinded path config.
+func (r *rpcServer) blindedPathRestrictions(
+	blindCfg *lnrpc.BlindedPathConfig) (*routing.BlindedPathRestrictions,
+	error) {
 
 	globalBlindCfg := r.server.cfg.Routing.BlindedPaths
 	blindingRestrictions := &routing.BlindedPathRestrictions{
@@ -5783,14 +6134,10 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		NumHops:                  globalBlindCfg.NumHops,
 		MaxNumPaths:              globalBlindCfg.MaxNumPaths,
 		NodeOmissionSet:          fn.NewSet[route.Vertex](),
+		IntroNodes:               fn.NewSet[route.Vertex](),
 	}
 
-	if blindCfg != nil && !blind {
-		return nil, fmt.Errorf("blinded path config provided but " +
-			"IsBlinded not set")
-	}
-
-	if blind && blindCfg != nil {
+	if blindCfg != nil {
 		if blindCfg.MinNumRealHops != nil {
 			blindingRestrictions.MinDistanceFromIntroNode =
 				uint8(*blindCfg.MinNumRealHops)
@@ -5811,6 +6158,35 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 
 			blindingRestrictions.NodeOmissionSet.Add(vertex)
 		}
+
+		for _, nodeIDBytes := range blindCfg.IntroductionNodes {
+			vertex, err := route.NewVertexFromBytes(nodeIDBytes)
+			if err != nil {
+				return nil, err
+			}
+
+			blindingRestrictions.IntroNodes.Add(vertex)
+		}
+
+		switch blindCfg.IntroNodeSelection {
+		// Paths are ranked by their success probability by default.
+		case lnrpc.IntroNodeSelection_INTRO_NODE_SELECTION_PROBABILITY:
+
+		// Otherwise, paths through well connected introduction nodes
+		// are preferred.
+		case lnrpc.IntroNodeSelection_INTRO_NODE_SELECTION_CENTRALITY:
+			score, err := r.introNodeCentralityScore()
+			if err != nil {
+				return nil, err
+			}
+
+			blindingRestrictions.IntroNodeScore = score
+
+		default:
+			return nil, fmt.Errorf("unknown introduction node "+
+				"selection policy: %v",
+				blindCfg.IntroNodeSelection)
+		}
 	}
 
 	if blindingRestrictions.MinDistanceFromIntroNode >
@@ -5822,12 +6198,47 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 			"be included in each path")
 	}
 
-	addInvoiceCfg := &invoicesrpc.AddInvoiceConfig{
+	return blindingRestrictions, nil
+}
+
+// introNodeCentralityScore computes the normalized betweenness centrality of
+// the nodes in the graph and returns it as an introduction node score, so that
+// well connected introduction nodes are preferred. Note that depending on the
+// graph size, this may take up to a few minutes.
+func (r *rpcServer) introNodeCentralityScore() (func(route.Vertex) float64,
+	error) {
+
+	channelGraph := autopilot.ChannelGraphFromDatabase(r.server.graphDB)
+	centralityMetric, err := autopilot.NewBetweennessCentralityMetric(
+		runtime.NumCPU(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := centralityMetric.Refresh(channelGraph); err != nil {
+		return nil, err
+	}
+
+	centrality := centralityMetric.GetMetric(true)
+
+	return func(node route.Vertex) float64 {
+		return centrality[autopilot.NodeID(node)]
+	}, nil
+}
+
+// addInvoiceConfig returns the config used to create invoices. If blind is
+// set, the invoices are created with blinded paths that satisfy the given
+// restrictions.
+func (r *rpcServer) addInvoiceConfig(blind bool,
+	blindingRestrictions *routing.BlindedPathRestrictions,
+) *invoicesrpc.AddInvoiceConfig {
+
+	return &invoicesrpc.AddInvoiceConfig{
 		AddInvoice:        r.server.invoices.AddInvoice,
 		IsChannelActive:   r.server.htlcSwitch.HasActiveLink,
 		ChainParams:       r.cfg.ActiveNetParams.Params,
 		NodeSigner:        r.server.nodeSigner,
-		DefaultCLTVExpiry: defaultDelta,
+		DefaultCLTVExpiry: r.cfg.Bitcoin.TimeLockDelta,
 		ChanDB:            r.server.chanStateDB,
 		Graph:             r.server.graphDB,
 		GenInvoiceFeatures: func() *lnwire.FeatureVector {
@@ -5866,6 +6277,64 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 			)
 		},
 	}
+}
+
+// blindedPathConfig returns the config used to build the blinded paths of an
+// invoice from the given restrictions.
+func (r *rpcServer) blindedPathConfig(
+	blindingRestrictions *routing.BlindedPathRestrictions,
+) *invoicesrpc.BlindedPathConfig {
+
+	bpConfig := r.server.cfg.Routing.BlindedPaths
+
+	return &invoicesrpc.BlindedPathConfig{
+		RoutePolicyIncrMultiplier: bpConfig.PolicyIncreaseMultiplier,
+		RoutePolicyDecrMultiplier: bpConfig.PolicyDecreaseMultiplier,
+		DefaultDummyHopPolicy: &blindedpath.BlindedHopPolicy{
+			CLTVExpiryDelta: uint16(r.cfg.Bitcoin.TimeLockDelta),
+			FeeRate:         uint32(r.server.cfg.Bitcoin.FeeRate),
+			BaseFee:         r.server.cfg.Bitcoin.BaseFee,
+			MinHTLCMsat:     r.server.cfg.Bitcoin.MinHTLCIn,
+
+			// MaxHTLCMsat will be calculated on the fly by using
+			// the introduction node's channel's capacities.
+			MaxHTLCMsat: 0,
+		},
+		MinNumPathHops: blindingRestrictions.NumHops,
+	}
+}
+
+// AddInvoice attempts to add a new invoice to the invoice database. Any
+// duplicated invoices are rejected, therefore all invoices *must* have a
+// unique payment preimage.
+func (r *rpcServer) AddInvoice(ctx context.Context,
+	invoice *lnrpc.Invoice) (*lnrpc.AddInvoiceResponse, error) {
+
+	var (
+		blindCfg = invoice.BlindedPathConfig
+		blind    = invoice.IsBlinded
+	)
+
+	if blindCfg != nil && !blind {
+		return nil, fmt.Errorf("blinded path config provided but " +
+			"IsBlinded not set")
+	}
+
+	var (
+		blindingRestrictions *routing.BlindedPathRestrictions
+		blindedPathCfg       *invoicesrpc.BlindedPathConfig
+		err                  error
+	)
+	if blind {
+		blindingRestrictions, err = r.blindedPathRestrictions(blindCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		blindedPathCfg = r.blindedPathConfig(blindingRestrictions)
+	}
+
+	addInvoiceCfg := r.addInvoiceConfig(blind, blindingRestrictions)
 
 	value, err := lnrpc.UnmarshallAmt(invoice.Value, invoice.ValueMsat)
 	if err != nil {
@@ -5878,32 +6347,6 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		return nil, err
 	}
 
-	var blindedPathCfg *invoicesrpc.BlindedPathConfig
-	if blind {
-		bpConfig := r.server.cfg.Routing.BlindedPaths
-
-		blindedPathCfg = &invoicesrpc.BlindedPathConfig{
-			RoutePolicyIncrMultiplier: bpConfig.
-				PolicyIncreaseMultiplier,
-			RoutePolicyDecrMultiplier: bpConfig.
-				PolicyDecreaseMultiplier,
-			DefaultDummyHopPolicy: &blindedpath.BlindedHopPolicy{
-				CLTVExpiryDelta: uint16(defaultDelta),
-				FeeRate: uint32(
-					r.server.cfg.Bitcoin.FeeRate,
-				),
-				BaseFee:     r.server.cfg.Bitcoin.BaseFee,
-				MinHTLCMsat: r.server.cfg.Bitcoin.MinHTLCIn,
-
-				// MaxHTLCMsat will be calculated on the fly by
-				// using the introduction node's channel's
-				// capacities.
-				MaxHTLCMsat: 0,
-			},
-			MinNumPathHops: blindingRestrictions.NumHops,
-		}
-	}
-
 	addInvoiceData := &invoicesrpc.AddInvoiceData{
 		Memo:            invoice.Memo,
 		Value:           value,
@@ -5940,6 +6383,74 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 	}, nil
 }
 
+// PreviewBlindedPaths builds the blinded paths that AddInvoice would include
+// in a blinded invoice with the given parameters, without creating the
+// invoice. Each path is returned along with the real hops it was built from.
+func (r *rpcServer) PreviewBlindedPaths(_ context.Context,
+	req *lnrpc.PreviewBlindedPathsRequest) (
+	*lnrpc.PreviewBlindedPathsResponse, error) {
+
+	blindingRestrictions, err := r.blindedPathRestrictions(
+		req.BlindedPathConfig,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ValueMsat < 0 {
+		return nil, fmt.Errorf("negative value_msat: %v",
+			req.ValueMsat)
+	}
+
+	addInvoiceCfg := r.addInvoiceConfig(true, blindingRestrictions)
+	addInvoiceData := &invoicesrpc.AddInvoiceData{
+		Value:          lnwire.MilliSatoshi(req.ValueMsat),
+		Expiry:         req.Expiry,
+		CltvExpiry:     req.CltvExpiry,
+		BlindedPathCfg: r.blindedPathConfig(blindingRestrictions),
+	}
+
+	previews, err := invoicesrpc.PreviewBlindedPaths(
+		addInvoiceCfg, addInvoiceData,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &lnrpc.PreviewBlindedPathsResponse{
+		Paths: make([]*lnrpc.BlindedPathPreview, 0, len(previews)),
+	}
+	for _, preview := range previews {
+		rpcPaths, err := invoicesrpc.CreateRPCBlindedPayments(
+			[]*zpay32.BlindedPaymentPath{preview.Path},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rpcPreview := &lnrpc.BlindedPathPreview{
+			RealHops: [][]byte{
+				preview.Route.SourcePubKey[:],
+			},
+			NumDummyHops: uint32(preview.NumDummyHops),
+			BlindedPath:  rpcPaths[0],
+		}
+		for _, hop := range preview.Route.Hops {
+			pubKey := hop.PubKeyBytes
+			rpcPreview.RealHops = append(
+				rpcPreview.RealHops, pubKey[:],
+			)
+			rpcPreview.ChanIds = append(
+				rpcPreview.ChanIds, hop.ChannelID,
+			)
+		}
+
+		resp.Paths = append(resp.Paths, rpcPreview)
+	}
+
+	return resp, nil
+}
+
 // LookupInvoice attempts to look up an invoice according to its payment hash.
 // The passed payment hash *must* be exactly 32 bytes, if not an error is
 // returned.
@@ -7068,6 +7579,10 @@ func (r *rpcServer) DecodePayReq(ctx context.Context,
 
 	rpcsLog.Tracef("[decodepayreq] decoding: %v", req.PayReq)
 
+	if strings.HasPrefix(strings.ToLower(req.PayReq), bolt12.InvoiceHRP) {
+		return decodeBolt12PayReq(req.PayReq)
+	}
+
 	// Fist we'll attempt to decode the payment request string, if the
 	// request is invalid or the checksum doesn't match, then we'll exit
 	// here with an error.
@@ -7137,6 +7652,43 @@ func (r *rpcServer) DecodePayReq(ctx context.Context,
 	}, nil
 }
 
+// decodeBolt12PayReq decodes a BOLT 12 invoice into the fields of a payment
+// request that apply to it.
+func decodeBolt12PayReq(payReqStr string) (*lnrpc.PayReq, error) {
+	invoice, err := bolt12.DecodeInvoiceString(payReqStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := invoice.Verify(); err != nil {
+		return nil, err
+	}
+
+	blindedPaymentPaths, err := invoicesrpc.CreateRPCBlindedPayments(
+		invoice.Paths,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	features := lnwire.NewFeatureVector(invoice.Features, lnwire.Features)
+	expiry := invoice.Expiry().Sub(invoice.CreatedAt)
+	dest := invoice.NodeID.SerializeCompressed()
+
+	return &lnrpc.PayReq{
+		Destination:  hex.EncodeToString(dest),
+		PaymentHash:  hex.EncodeToString(invoice.PaymentHash[:]),
+		NumSatoshis:  int64(invoice.Amount.ToSatoshis()),
+		NumMsat:      int64(invoice.Amount),
+		Timestamp:    invoice.CreatedAt.Unix(),
+		Description:  invoice.InvoiceRequest.Offer.Description,
+		Expiry:       int64(expiry.Seconds()),
+		CltvExpiry:   zpay32.DefaultAssumedFinalCLTVDelta,
+		BlindedPaths: blindedPaymentPaths,
+		Features:     invoicesrpc.CreateRPCFeatures(features),
+	}, nil
+}
+
 // feeBase is the fixed point that fee rate computation are performed over.
 // Nodes on the network advertise their fee rate using this point as a base.
 // This means that the minimal possible fee rate if 1e-6, or 0.000001, or
@@ -7499,7 +8051,40 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		IndexOffset:  req.IndexOffset,
 		NumMaxEvents: numEvents,
 	}
-	timeSlice, err := r.server.miscDB.ForwardingLog().Query(eventQuery)
+
+	// Apply the optional filters of the request. A zero value means that
+	// the events aren't filtered on that field.
+	if req.IncomingChanId != 0 {
+		eventQuery.IncomingChanID = fn.Some(
+			lnwire.NewShortChanIDFromInt(req.IncomingChanId),
+		)
+	}
+	if req.OutgoingChanId != 0 {
+		eventQuery.OutgoingChanID = fn.Some(
+			lnwire.NewShortChanIDFromInt(req.OutgoingChanId),
+		)
+	}
+	if req.Peer != "" {
+		peer, err := route.NewVertexFromStr(req.Peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer: %w", err)
+		}
+		eventQuery.Peer = fn.Some(peer)
+	}
+	msatFilter := func(amt uint64) fn.Option[lnwire.MilliSatoshi] {
+		if amt == 0 {
+			return fn.None[lnwire.MilliSatoshi]()
+		}
+
+		return fn.Some(lnwire.MilliSatoshi(amt))
+	}
+	eventQuery.MinAmtOut = msatFilter(req.MinAmtMsat)
+	eventQuery.MaxAmtOut = msatFilter(req.MaxAmtMsat)
+	eventQuery.MinFee = msatFilter(req.MinFeeMsat)
+	eventQuery.MaxFee = msatFilter(req.MaxFeeMsat)
+
+	fwdLog := r.server.miscDB.ForwardingLog()
+	timeSlice, err := fwdLog.Query(eventQuery)
 	if err != nil {
 		return nil, fmt.Errorf("unable to query forwarding log: %w",
 			err)
@@ -7559,6 +8144,27 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		ForwardingEvents: fwdingEvents,
 		LastOffsetIndex:  timeSlice.LastIndexOffset,
 	}
+
+	if req.AggregateFees {
+		summaries, err := fwdLog.FeeSummaries(eventQuery)
+		if err != nil {
+			return nil, fmt.Errorf("unable to query forwarding "+
+				"fees: %w", err)
+		}
+
+		for _, summary := range summaries {
+			resp.FeeSummaries = append(
+				resp.FeeSummaries, &lnrpc.ChannelFeeSummary{
+					ChanId:      summary.ChanID.ToUint64(),
+					Day:         uint64(summary.Day.Unix()),
+					NumForwards: summary.NumForwards,
+					AmtInMsat:   uint64(summary.AmtIn),
+					AmtOutMsat:  uint64(summary.AmtOut),
+					FeeMsat:     uint64(summary.Fee),
+				},
+			)
+		}
+	}
 	for i, event := range timeSlice.ForwardingEvents {
 		amtInMsat := event.AmtIn
 		amtOutMsat := event.AmtOut
@@ -8528,6 +9134,314 @@ func (r *rpcServer) SubscribeCustomMessages(req *lnrpc.SubscribeCustomMessagesRe
 	}
 }
 
+// errOnionMessagesDisabled is returned by the onion message RPCs when onion
+// messages haven't been enabled in the protocol options.
+var errOnionMessagesDisabled = errors.New("onion messages are not enabled, " +
+	"use protocol.onion-messages to enable them")
+
+// SendOnionMessage sends an onion message to a node or blinded path.
+func (r *rpcServer) SendOnionMessage(_ context.Context,
+	req *lnrpc.SendOnionMessageRequest) (*lnrpc.SendOnionMessageResponse,
+	error) {
+
+	if r.server.onionMessenger == nil {
+		return nil, errOnionMessagesDisabled
+	}
+
+	msg := &onionmessage.OutgoingMessage{
+		FinalHopTLVs: make(map[tlv.Type][]byte, len(req.FinalHopTlvs)),
+	}
+
+	var err error
+	if len(req.Destination) > 0 {
+		msg.Destination, err = btcec.ParsePubKey(req.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination: %w", err)
+		}
+	}
+
+	if req.BlindedPath != nil {
+		msg.BlindedPath, err = unmarshalOnionMessagePath(
+			req.BlindedPath,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blinded path: %w", err)
+		}
+	}
+
+	msg.Hops, err = parsePubKeys(req.Hops)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hops: %w", err)
+	}
+
+	for typ, value := range req.FinalHopTlvs {
+		if tlv.Type(typ) < onionmessage.MinFinalHopType {
+			return nil, fmt.Errorf("final hop TLV type %d is below "+
+				"the minimum of %d", typ,
+				onionmessage.MinFinalHopType)
+		}
+
+		msg.FinalHopTLVs[tlv.Type(typ)] = value
+	}
+
+	resp := &lnrpc.SendOnionMessageResponse{}
+	if req.IncludeReplyPath {
+		replyHops, err := parsePubKeys(req.ReplyPathHops)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply path hops: %w",
+				err)
+		}
+
+		// The path ID is random so that replies can be matched to the
+		// message that they respond to.
+		pathID := make([]byte, 32)
+		if _, err := rand.Read(pathID); err != nil {
+			return nil, err
+		}
+
+		msg.ReplyPath, err = r.server.onionMessenger.BuildReplyPath(
+			replyHops, pathID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build reply path: %w",
+				err)
+		}
+
+		resp.ReplyPathId = pathID
+	} else if len(req.ReplyPathHops) > 0 {
+		return nil, errors.New("reply path hops set without " +
+			"include_reply_path")
+	}
+
+	err = r.server.onionMessenger.SendOnionMessage(msg)
+	switch {
+	case errors.Is(err, ErrPeerNotConnected):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeOnionMessages subscribes to a stream of onion messages for which
+// our node is the final recipient.
+func (r *rpcServer) SubscribeOnionMessages(
+	_ *lnrpc.SubscribeOnionMessagesRequest,
+	server lnrpc.Lightning_SubscribeOnionMessagesServer) error {
+
+	if r.server.onionMessenger == nil {
+		return errOnionMessagesDisabled
+	}
+
+	client, err := r.server.onionMessenger.SubscribeMessages()
+	if err != nil {
+		return err
+	}
+	defer client.Cancel()
+
+	for {
+		select {
+		case <-client.Quit():
+			return errors.New("shutdown")
+
+		case <-server.Context().Done():
+			return server.Context().Err()
+
+		case update := <-client.Updates():
+			onionMsg := update.(*onionmessage.ReceivedMessage)
+
+			rpcMsg := &lnrpc.OnionMessageUpdate{
+				Peer:   onionMsg.Peer[:],
+				PathId: onionMsg.PathID,
+				FinalHopTlvs: make(
+					map[uint64][]byte,
+					len(onionMsg.FinalHopTLVs),
+				),
+			}
+			if onionMsg.ReplyPath != nil {
+				rpcMsg.ReplyPath = marshalOnionMessagePath(
+					onionMsg.ReplyPath,
+				)
+			}
+			for typ, value := range onionMsg.FinalHopTLVs {
+				rpcMsg.FinalHopTlvs[uint64(typ)] = value
+			}
+
+			if err := server.Send(rpcMsg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// errOffersDisabled is returned by the offer RPCs when either onion messages
+// or route blinding are disabled.
+var errOffersDisabled = errors.New("offers require onion messages and " +
+	"route blinding to be enabled")
+
+// AddOffer creates a BOLT 12 offer that invoices can be requested for.
+func (r *rpcServer) AddOffer(_ context.Context,
+	req *lnrpc.AddOfferRequest) (*lnrpc.AddOfferResponse, error) {
+
+	if r.server.offersMgr == nil {
+		return nil, errOffersDisabled
+	}
+
+	offer := &bolt12.Offer{
+		Description: req.Description,
+		Amount:      req.AmountMsat,
+		Issuer:      req.Issuer,
+	}
+	if req.AbsoluteExpiry != 0 {
+		offer.AbsoluteExpiry = time.Unix(req.AbsoluteExpiry, 0)
+	}
+	if req.QuantityMax != nil {
+		offer.QuantityMax = fn.Some(*req.QuantityMax)
+	}
+
+	encoded, err := r.server.offersMgr.CreateOffer(offer)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &lnrpc.AddOfferResponse{
+		Offer: encoded,
+	}, nil
+}
+
+// FetchInvoice requests an invoice for a BOLT 12 offer from its issuer.
+func (r *rpcServer) FetchInvoice(ctx context.Context,
+	req *lnrpc.FetchInvoiceRequest) (*lnrpc.FetchInvoiceResponse, error) {
+
+	if r.server.offersMgr == nil {
+		return nil, errOffersDisabled
+	}
+
+	offer, err := bolt12.DecodeOffer(req.Offer)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid "+
+			"offer: %v", err)
+	}
+
+	hops, err := parsePubKeys(req.Hops)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid "+
+			"hop: %v", err)
+	}
+
+	fetchReq := &offers.FetchRequest{
+		Offer:     offer,
+		Amount:    lnwire.MilliSatoshi(req.AmountMsat),
+		PayerNote: req.PayerNote,
+		Hops:      hops,
+	}
+	if req.Quantity != nil {
+		fetchReq.Quantity = fn.Some(*req.Quantity)
+	}
+
+	timeout := offers.DefaultFetchTimeout
+	if req.TimeoutSeconds != 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	invoice, err := r.server.offersMgr.FetchInvoice(ctx, fetchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := invoice.EncodeString()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.FetchInvoiceResponse{
+		Invoice:     encoded,
+		PaymentHash: invoice.PaymentHash[:],
+		AmountMsat:  uint64(invoice.Amount),
+		Expiry:      invoice.Expiry().Unix(),
+	}, nil
+}
+
+// parsePubKeys parses a list of serialized public keys.
+func parsePubKeys(keys [][]byte) ([]*btcec.PublicKey, error) {
+	pubKeys := make([]*btcec.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		pubKey, err := btcec.ParsePubKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	return pubKeys, nil
+}
+
+// unmarshalOnionMessagePath converts an RPC blinded path into the blinded path
+// that is used for onion messages.
+func unmarshalOnionMessagePath(rpcPath *lnrpc.BlindedPath) (
+	*record.BlindedPath, error) {
+
+	introNode, err := btcec.ParsePubKey(rpcPath.IntroductionNode)
+	if err != nil {
+		return nil, err
+	}
+
+	pathKey, err := btcec.ParsePubKey(rpcPath.BlindingPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rpcPath.BlindedHops) == 0 {
+		return nil, record.ErrNoBlindedHops
+	}
+
+	path := &record.BlindedPath{
+		IntroductionNode: introNode,
+		PathKey:          pathKey,
+		Hops: make(
+			[]*sphinx.BlindedHopInfo, 0, len(rpcPath.BlindedHops),
+		),
+	}
+	for _, hop := range rpcPath.BlindedHops {
+		nodeKey, err := btcec.ParsePubKey(hop.BlindedNode)
+		if err != nil {
+			return nil, err
+		}
+
+		path.Hops = append(path.Hops, &sphinx.BlindedHopInfo{
+			BlindedNodePub: nodeKey,
+			CipherText:     hop.EncryptedData,
+		})
+	}
+
+	return path, nil
+}
+
+// marshalOnionMessagePath converts a blinded path that is used for onion
+// messages into its RPC representation.
+func marshalOnionMessagePath(path *record.BlindedPath) *lnrpc.BlindedPath {
+	rpcPath := &lnrpc.BlindedPath{
+		IntroductionNode: path.IntroductionNode.SerializeCompressed(),
+		BlindingPoint:    path.PathKey.SerializeCompressed(),
+		BlindedHops:      make([]*lnrpc.BlindedHop, 0, len(path.Hops)),
+	}
+	for _, hop := range path.Hops {
+		rpcPath.BlindedHops = append(rpcPath.BlindedHops,
+			&lnrpc.BlindedHop{
+				BlindedNode:   hop.BlindedNodePub.SerializeCompressed(),
+				EncryptedData: hop.CipherText,
+			},
+		)
+	}
+
+	return rpcPath
+}
+
 // ListAliases returns the set of all aliases we have ever allocated along with
 // their base SCID's and possibly a separate confirmed SCID in the case of
 // zero-conf.
@@ -17,6 +17,7 @@ import (
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/connmgr"
@@ -26,6 +27,7 @@ import (
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/aliasmgr"
 	"github.com/lightningnetwork/lnd/autopilot"
+	"github.com/lightningnetwork/lnd/bolt12"
 	"github.com/lightningnetwork/lnd/brontide"
 	"github.com/lightningnetwork/lnd/chainreg"
 	"github.com/lightningnetwork/lnd/chanacceptor"
@@ -54,19 +56,26 @@ import (
 	"github.com/lightningnetwork/lnd/lnencrypt"
 	"github.com/lightningnetwork/lnd/lnpeer"
 	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwallet/chanfunding"
 	"github.com/lightningnetwork/lnd/lnwallet/rpcwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/monitoring"
+	"github.com/lightningnetwork/lnd/msgmux"
 	"github.com/lightningnetwork/lnd/nat"
 	"github.com/lightningnetwork/lnd/netann"
+	"github.com/lightningnetwork/lnd/offers"
+	"github.com/lightningnetwork/lnd/onionmessage"
 	"github.com/lightningnetwork/lnd/peer"
 	"github.com/lightningnetwork/lnd/peernotifier"
 	"github.com/lightningnetwork/lnd/pool"
 	"github.com/lightningnetwork/lnd/queue"
 	"github.com/lightningnetwork/lnd/routing"
+	"github.com/lightningnetwork/lnd/routing/blindedpath"
 	"github.com/lightningnetwork/lnd/routing/localchans"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/subscribe"
@@ -259,6 +268,17 @@ type server struct {
 
 	htlcSwitch *htlcswitch.Switch
 
+	// trampolineRouter forwards the payments that are delivered to us as
+	// a trampoline node if it is enabled.
+	trampolineRouter *htlcswitch.TrampolineRouter
+
+	// resourceMgr protects our channels against jamming if it is enabled.
+	resourceMgr *htlcswitch.ResourceManager
+
+	// htlcRateLimiter limits the rate at which peers add htlcs to our
+	// channels if it is enabled.
+	htlcRateLimiter *htlcswitch.HtlcRateLimiter
+
 	interceptableSwitch *htlcswitch.InterceptableSwitch
 
 	invoices *invoices.InvoiceRegistry
@@ -275,6 +295,18 @@ type server struct {
 
 	missionControl *routing.MissionControl
 
+	// missionControlMgr manages the mission controls of all namespaces,
+	// including missionControl which is the one of the default namespace.
+	missionControlMgr *routing.MissionControlManager
+
+	externalPathFinding *routing.ExternalPathFinding
+
+	probeService *routing.ProbeService
+
+	// pathCache holds the paths of recent payments if path caching is
+	// enabled.
+	pathCache *routing.PathCache
+
 	graphBuilder *graph.Builder
 
 	chanRouter *routing.ChannelRouter
@@ -285,6 +317,9 @@ type server struct {
 
 	localChanMgr *localchans.Manager
 
+	// feeAutopilot adjusts the fees of our channels if it is enabled.
+	feeAutopilot *localchans.FeeAutopilot
+
 	utxoNursery *contractcourt.UtxoNursery
 
 	sweeper *sweep.UtxoSweeper
@@ -334,6 +369,15 @@ type server struct {
 
 	customMessageServer *subscribe.Server
 
+	// onionMessenger sends, receives and relays onion messages. It is nil
+	// if onion messages are disabled.
+	onionMessenger *onionmessage.Messenger
+
+	// offersMgr creates BOLT 12 offers, replies to invoice requests for
+	// them and fetches invoices for remote offers. It is nil if onion
+	// messages or route blinding are disabled.
+	offersMgr *offers.Manager
+
 	// txPublisher is a publisher with fee-bumping capability.
 	txPublisher *sweep.TxPublisher
 
@@ -556,6 +600,12 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		CustomFeatures:           cfg.ProtocolOptions.CustomFeatures(),
 		NoTaprootChans:           !cfg.ProtocolOptions.TaprootChans,
 		NoRouteBlinding:          cfg.ProtocolOptions.NoRouteBlinding(),
+		NoOnionMessages:          !cfg.ProtocolOptions.OnionMessages(),
+		NoTrampolineRouting:      !cfg.ProtocolOptions.TrampolineRouting(),
+		NoSplice:                 !cfg.ProtocolOptions.Splice(),
+		NoDualFund:               !cfg.ProtocolOptions.DualFund(),
+		NoDynCommitments:         !cfg.ProtocolOptions.DynCommitments(),
+		NoSimpleClose:            !cfg.ProtocolOptions.SimpleClose(),
 	})
 	if err != nil {
 		return nil, err
@@ -570,6 +620,7 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		GcCanceledInvoicesOnStartup: cfg.GcCanceledInvoicesOnStartup,
 		GcCanceledInvoicesOnTheFly:  cfg.GcCanceledInvoicesOnTheFly,
 		KeysendHoldTime:             cfg.KeysendHoldTime,
+		HtlcAcceptorTimeout:         cfg.Invoices.HtlcAcceptorTimeout,
 	}
 
 	s := &server{
@@ -626,6 +677,37 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		quit:       make(chan struct{}),
 	}
 
+	// If onion messages are enabled, we'll create the messenger that
+	// sends, receives and relays them on behalf of our peers.
+	if cfg.ProtocolOptions.OnionMessages() {
+		s.onionMessenger = onionmessage.NewMessenger(
+			&onionmessage.Config{
+				NodeKeyECDH:  nodeKeyECDH,
+				SendToPeer:   s.sendOnionMessage,
+				ScidToPeer:   s.onionMessageScidToPeer,
+				PeerBurst:    cfg.OnionMsg.PeerBurst,
+				PeerInterval: cfg.OnionMsg.PeerInterval,
+				MaxPending:   cfg.OnionMsg.MaxPending,
+			},
+		)
+
+		// Offers are paid over blinded paths, so we only handle them
+		// if route blinding is enabled as well.
+		if !cfg.ProtocolOptions.NoRouteBlinding() {
+			s.offersMgr = offers.NewManager(&offers.Config{
+				ChainParams:   cfg.ActiveNetParams.Params,
+				NodeKeyECDH:   nodeKeyECDH,
+				Messenger:     s.onionMessenger,
+				CreateInvoice: s.createBolt12Invoice,
+				Clock:         clock.NewDefaultClock(),
+
+				MaxConcurrentRequests: cfg.OnionMsg.MaxPendingInvoiceRequests,  //nolint:lll
+				PeerRequestBurst:      cfg.OnionMsg.InvoiceRequestPeerBurst,    //nolint:lll
+				PeerRequestInterval:   cfg.OnionMsg.InvoiceRequestPeerInterval, //nolint:lll
+			})
+		}
+	}
+
 	currentHash, currentHeight, err := s.cc.ChainIO.GetBestBlock()
 	if err != nil {
 		return nil, err
@@ -644,11 +726,84 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	thresholdSats := btcutil.Amount(cfg.MaxFeeExposure)
 	thresholdMSats := lnwire.NewMSatFromSatoshis(thresholdSats)
 
+	if jamCfg := cfg.Htlcswitch.Jamming; jamCfg.Active {
+		//nolint:lll
+		s.resourceMgr, err = htlcswitch.NewResourceManager(
+			&htlcswitch.ResourceManagerConfig{
+				MonitorOnly:            jamCfg.MonitorOnly,
+				ProtectedPercentage:    jamCfg.ProtectedPercentage,
+				RevenueWindow:          jamCfg.RevenueWindow,
+				ReputationMultiplier:   jamCfg.ReputationMultiplier,
+				ResolutionPeriod:       jamCfg.ResolutionPeriod,
+				SubscribeHtlcEvents:    s.htlcNotifier.SubscribeHtlcEvents,
+				SubscribeChannelEvents: s.channelNotifier.SubscribeChannelEvents,
+				HasOpenChannels:        s.hasOpenChannels,
+				DB:                     dbs.ChanStateDB,
+				Clock:                  clock.NewDefaultClock(),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rlCfg := cfg.Htlcswitch.RateLimit; rlCfg.Active {
+		s.htlcRateLimiter, err = htlcswitch.NewHtlcRateLimiter(
+			&htlcswitch.HtlcRateLimiterConfig{
+				PeerAddRate:     rlCfg.PeerAddRate,
+				PeerAddBurst:    rlCfg.PeerAddBurst,
+				ChannelAddRate:  rlCfg.ChanAddRate,
+				ChannelAddBurst: rlCfg.ChanAddBurst,
+				PeerMaxInFlight: lnwire.MilliSatoshi(
+					rlCfg.PeerMaxInFlightMsat,
+				),
+				Clock: clock.NewDefaultClock(),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		monitoring.SetHtlcRateLimitSource(s.htlcRateLimiter.Stats)
+	}
+
 	s.aliasMgr, err = aliasmgr.NewManager(dbs.ChanStateDB)
 	if err != nil {
 		return nil, err
 	}
 
+	// If we forward payments as a trampoline node, the trampoline router
+	// decodes the trampoline onions with our node key and sends the
+	// outgoing payments using our router.
+	if cfg.ProtocolOptions.TrampolineRouting() {
+		trampolineProcessor := hop.NewTrampolineProcessor(nodeKeyECDH)
+
+		// The router is only created further below, so we look it up
+		// once a payment is forwarded.
+		sendPayment := func(req *htlcswitch.TrampolinePaymentRequest) (
+			<-chan *htlcswitch.TrampolinePaymentResult, error) {
+
+			return s.chanRouter.SendTrampolinePayment(req)
+		}
+		lookupPayment := func(hash lntypes.Hash) (
+			<-chan *htlcswitch.TrampolinePaymentResult, error) {
+
+			return s.chanRouter.LookupTrampolinePayment(hash)
+		}
+
+		decodeOnion := trampolineProcessor.DecodeTrampolineOnion
+		s.trampolineRouter = htlcswitch.NewTrampolineRouter(
+			&htlcswitch.TrampolineRouterConfig{
+				DecodeTrampolineOnion: decodeOnion,
+				SendPayment:           sendPayment,
+				LookupPayment:         lookupPayment,
+				MppTimeout: htlcswitch.
+					DefaultTrampolineMppTimeout,
+				Clock: clock.NewDefaultClock(),
+			},
+		)
+	}
+
 	s.htlcSwitch, err = htlcswitch.New(htlcswitch.Config{
 		DB:                   dbs.ChanStateDB,
 		FetchAllOpenChannels: s.chanStateDB.FetchAllOpenChannels,
@@ -684,6 +839,7 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		MaxFeeExposure:         thresholdMSats,
 		SignAliasUpdate:        s.signAliasUpdate,
 		IsAlias:                aliasmgr.IsAlias,
+		ResourceManager:        s.resourceMgr,
 	}, uint32(currentHeight))
 	if err != nil {
 		return nil, err
@@ -938,12 +1094,47 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		McFlushInterval:         routingConfig.McFlushInterval,
 		MinFailureRelaxInterval: routing.DefaultMinFailureRelaxInterval,
 	}
-	s.missionControl, err = routing.NewMissionControl(
+
+	// If enabled, the paths to destinations that were paid before are
+	// pre-computed and cached so that repeated payments to the same
+	// destination can skip path finding. Failures recorded by the default
+	// mission control invalidate cached paths. The payment session source
+	// is created below, and pre-computes the paths once the cache is
+	// started.
+	var paymentSessionSource *routing.SessionSource
+	if cacheCfg := cfg.Routing.PathCache; cacheCfg.Active {
+		s.pathCache = routing.NewPathCache(&routing.PathCacheConfig{
+			Size: cacheCfg.Size,
+			TTL:  cacheCfg.TTL,
+			DB:   dbs.ChanStateDB,
+			SubscribeTopology: func() (*graph.TopologyClient,
+				error) {
+
+				return s.graphBuilder.SubscribeTopology()
+			},
+			NewPaymentSession: func(p *routing.LightningPayment) (
+				routing.PaymentSession, error) {
+
+				return paymentSessionSource.NewPaymentSession(p)
+			},
+			BestHeight:     s.cc.BestBlockTracker.BestHeight,
+			CltvLimit:      cfg.MaxOutgoingCltvExpiry,
+			FinalCltvDelta: uint16(cfg.Bitcoin.TimeLockDelta),
+			Clock:          clock.NewDefaultClock(),
+		}, selfNode.PubKeyBytes)
+
+		mcCfg.OnPaymentFailure = fn.Some(
+			s.pathCache.InvalidateFailures,
+		)
+	}
+
+	s.missionControlMgr, err = routing.NewMissionControlManager(
 		dbs.ChanStateDB, selfNode.PubKeyBytes, mcCfg,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("can't create mission control: %w", err)
 	}
+	s.missionControl = s.missionControlMgr.DefaultMissionControl()
 
 	srvrLog.Debugf("Instantiating payment session source with config: "+
 		"AttemptCost=%v + %v%%, MinRouteProbability=%v",
@@ -963,14 +1154,22 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	if err != nil {
 		return nil, fmt.Errorf("error getting source node: %w", err)
 	}
-	paymentSessionSource := &routing.SessionSource{
+	// External path finders registered over RPC take over path finding
+	// from the payment sessions.
+	s.externalPathFinding = routing.NewExternalPathFinding(
+		s.missionControl.GetPairHistorySnapshot,
+	)
+
+	paymentSessionSource = &routing.SessionSource{
 		GraphSessionFactory: graphsession.NewGraphSessionFactory(
 			chanGraph,
 		),
-		SourceNode:        sourceNode,
-		MissionControl:    s.missionControl,
-		GetLink:           s.htlcSwitch.GetLinkByShortID,
-		PathFindingConfig: pathFindingConfig,
+		SourceNode:          sourceNode,
+		MissionControl:      s.missionControl,
+		GetLink:             s.htlcSwitch.GetLinkByShortID,
+		PathFindingConfig:   pathFindingConfig,
+		ExternalPathFinding: s.externalPathFinding,
+		PathCache:           s.pathCache,
 	}
 
 	paymentControl := channeldb.NewPaymentControl(dbs.ChanStateDB)
@@ -998,24 +1197,65 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	}
 
 	s.chanRouter, err = routing.New(routing.Config{
-		SelfNode:           selfNode.PubKeyBytes,
-		RoutingGraph:       graphsession.NewRoutingGraph(chanGraph),
-		Chain:              cc.ChainIO,
-		Payer:              s.htlcSwitch,
-		Control:            s.controlTower,
-		MissionControl:     s.missionControl,
-		SessionSource:      paymentSessionSource,
-		GetLink:            s.htlcSwitch.GetLinkByShortID,
-		NextPaymentID:      sequencer.NextID,
-		PathFindingConfig:  pathFindingConfig,
-		Clock:              clock.NewDefaultClock(),
-		ApplyChannelUpdate: s.graphBuilder.ApplyChannelUpdate,
-		ClosedSCIDs:        s.fetchClosedChannelSCIDs(),
+		SelfNode:             selfNode.PubKeyBytes,
+		RoutingGraph:         graphsession.NewRoutingGraph(chanGraph),
+		Chain:                cc.ChainIO,
+		Payer:                s.htlcSwitch,
+		Control:              s.controlTower,
+		MissionControl:       s.missionControl,
+		SessionSource:        paymentSessionSource,
+		GetLink:              s.htlcSwitch.GetLinkByShortID,
+		DustExposureExceeded: s.htlcSwitch.DustExposureExceeded,
+		NextPaymentID:        sequencer.NextID,
+		PathFindingConfig:    pathFindingConfig,
+		Clock:                clock.NewDefaultClock(),
+		ApplyChannelUpdate:   s.graphBuilder.ApplyChannelUpdate,
+		ClosedSCIDs:          s.fetchClosedChannelSCIDs(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("can't create router: %w", err)
 	}
 
+	// The probe service sends background probes to the nodes that are
+	// selected over RPC, or to the nodes with the highest centrality.
+	topCentrality := autopilot.NewTopCentrality()
+	s.probeService = routing.NewProbeService(&routing.ProbeServiceConfig{
+		SelfNode:     selfNode.PubKeyBytes,
+		SendPayment:  s.chanRouter.SendPayment,
+		FetchPayment: paymentControl.FetchPayment,
+		DeletePayment: func(hash lntypes.Hash) error {
+			return dbs.ChanStateDB.DeletePayment(hash, false)
+		},
+		TopCentralityNodes: func(n int) ([]route.Vertex, error) {
+			nodes, err := topCentrality.TopNodes(
+				autopilot.ChannelGraphFromDatabase(s.graphDB),
+				n,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			vertices := make([]route.Vertex, len(nodes))
+			for i, node := range nodes {
+				vertices[i] = route.Vertex(node)
+			}
+
+			return vertices, nil
+		},
+		GetMissionControl: func(
+			namespace string) (routing.MissionController, error) {
+
+			if namespace == "" {
+				return s.missionControl, nil
+			}
+
+			return s.missionControlMgr.GetNamespacedStore(namespace)
+		},
+		FinalCLTVDelta: uint16(cfg.Bitcoin.TimeLockDelta),
+		CltvLimit:      cfg.MaxOutgoingCltvExpiry,
+		Clock:          clock.NewDefaultClock(),
+	})
+
 	chanSeries := discovery.NewChanSeries(s.graphDB)
 	gossipMessageStore, err := discovery.NewMessageStore(dbs.ChanStateDB)
 	if err != nil {
@@ -1076,6 +1316,29 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		FetchChannel:              s.chanStateDB.FetchChannel,
 	}
 
+	if feeCfg := cfg.Routing.FeeAutopilot; feeCfg.Active {
+		fwdLog := dbs.ChanStateDB.ForwardingLog()
+		s.feeAutopilot = localchans.NewFeeAutopilot(
+			&localchans.FeeAutopilotConfig{
+				Manager:            s.localChanMgr,
+				QueryForwards:      fwdLog.Query,
+				Ticker:             ticker.New(feeCfg.Interval),
+				Clock:              clock.NewDefaultClock(),
+				CoolDown:           feeCfg.CoolDown,
+				ForwardingWindow:   feeCfg.ForwardingWindow,
+				LowLiquidityRatio:  feeCfg.LowLiquidityRatio,
+				HighLiquidityRatio: feeCfg.HighLiquidityRatio,
+				MinDailyFlowRatio:  feeCfg.MinDailyFlowRatio,
+				MinFeeRate:         feeCfg.MinFeeRate,
+				MaxFeeRate:         feeCfg.MaxFeeRate,
+				FeeRateStep:        feeCfg.FeeRateStep,
+				MinInboundFeeRate:  feeCfg.MinInboundFeeRate,
+				InboundFeeRateStep: feeCfg.InboundFeeRateStep,
+				DryRun:             feeCfg.DryRun,
+			},
+		)
+	}
+
 	utxnStore, err := contractcourt.NewNurseryStore(
 		s.cfg.ActiveNetParams.GenesisHash, dbs.ChanStateDB,
 	)
@@ -1520,6 +1783,9 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		DeleteAliasEdge:   deleteAliasEdge,
 		AliasManager:      s.aliasMgr,
 		IsSweeperOutpoint: s.sweeper.IsSweeperOutpoint,
+		DualFundContribution: btcutil.Amount(
+			cfg.DualFundContribution,
+		),
 	})
 	if err != nil {
 		return nil, err
@@ -1576,6 +1842,13 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 
 		policy.SweepFeeRate = sweepRateSatPerVByte.FeePerKWeight()
 
+		// If requested, also back up the revoked HTLC outputs of our
+		// channels. This flag is inherited by the anchor and taproot
+		// policies below.
+		if cfg.WtClient.SweepHtlcs {
+			policy.BlobType |= blob.Type(blob.FlagHtlcOutputs)
+		}
+
 		if err := policy.Validate(); err != nil {
 			return nil, err
 		}
@@ -1734,6 +2007,12 @@ func (s *server) UpdateRoutingConfig(cfg *routing.MissionControlConfig) {
 	}
 
 	routerCfg.MaxMcHistory = cfg.MaxMcHistory
+
+	// The cached paths were found with the previous estimator, so they
+	// are dropped.
+	if s.pathCache != nil {
+		s.pathCache.Reset()
+	}
 }
 
 // signAliasUpdate takes a ChannelUpdate and returns the signature. This is
@@ -1978,6 +2257,38 @@ func (s *server) Start() error {
 			return
 		}
 
+		if s.onionMessenger != nil {
+			cleanup = cleanup.add(s.onionMessenger.Stop)
+			if err := s.onionMessenger.Start(); err != nil {
+				startErr = err
+				return
+			}
+
+			// If all peers share a global message router, then we
+			// only need to register the messenger once. Otherwise
+			// it's registered with each peer's own router.
+			err := fn.MapOptionZ(
+				s.implCfg.MsgRouter,
+				func(router msgmux.Router) error {
+					return router.RegisterEndpoint(
+						s.onionMessenger,
+					)
+				},
+			)
+			if err != nil {
+				startErr = err
+				return
+			}
+		}
+
+		if s.offersMgr != nil {
+			cleanup = cleanup.add(s.offersMgr.Stop)
+			if err := s.offersMgr.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		if s.hostAnn != nil {
 			cleanup = cleanup.add(s.hostAnn.Stop)
 			if err := s.hostAnn.Start(); err != nil {
@@ -2049,6 +2360,16 @@ func (s *server) Start() error {
 			return
 		}
 
+		// The resource manager subscribes to the htlc notifier and
+		// therefore has to be started after it.
+		if s.resourceMgr != nil {
+			cleanup = cleanup.add(s.resourceMgr.Stop)
+			if err := s.resourceMgr.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		if s.towerClientMgr != nil {
 			cleanup = cleanup.add(s.towerClientMgr.Stop)
 			if err := s.towerClientMgr.Start(); err != nil {
@@ -2087,6 +2408,16 @@ func (s *server) Start() error {
 			return
 		}
 
+		// The trampoline router must be running before the links of
+		// the htlcSwitch hand their trampoline htlcs to it.
+		if s.trampolineRouter != nil {
+			cleanup = cleanup.add(s.trampolineRouter.Stop)
+			if err := s.trampolineRouter.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		// htlcSwitch must be started before chainArb since the latter
 		// relies on htlcSwitch to deliver resolution message upon
 		// start.
@@ -2119,6 +2450,21 @@ func (s *server) Start() error {
 			startErr = err
 			return
 		}
+
+		cleanup = cleanup.add(s.probeService.Stop)
+		if err := s.probeService.Start(); err != nil {
+			startErr = err
+			return
+		}
+
+		if s.pathCache != nil {
+			cleanup = cleanup.add(s.pathCache.Stop)
+			if err := s.pathCache.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		// The authGossiper depends on the chanRouter and therefore
 		// should be started after it.
 		cleanup = cleanup.add(s.authGossiper.Stop)
@@ -2145,6 +2491,14 @@ func (s *server) Start() error {
 			return
 		}
 
+		if s.feeAutopilot != nil {
+			cleanup = cleanup.add(s.feeAutopilot.Stop)
+			if err := s.feeAutopilot.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		cleanup = cleanup.add(s.chanEventStore.Stop)
 		if err := s.chanEventStore.Start(); err != nil {
 			startErr = err
@@ -2152,10 +2506,10 @@ func (s *server) Start() error {
 		}
 
 		cleanup.add(func() error {
-			s.missionControl.StopStoreTicker()
+			s.missionControlMgr.StopStoreTickers()
 			return nil
 		})
-		s.missionControl.RunStoreTicker()
+		s.missionControlMgr.RunStoreTickers()
 
 		// Before we start the connMgr, we'll check to see if we have
 		// any backups to recover. We do this now as we want to ensure
@@ -2368,6 +2722,15 @@ func (s *server) Stop() error {
 		// Shutdown connMgr first to prevent conns during shutdown.
 		s.connMgr.Stop()
 
+		// Stop the fee autopilot before the subsystems it queries and
+		// updates the channel policies through.
+		if s.feeAutopilot != nil {
+			if err := s.feeAutopilot.Stop(); err != nil {
+				srvrLog.Warnf("failed to stop feeAutopilot: %v",
+					err)
+			}
+		}
+
 		// Shutdown the wallet, funding manager, and the rpc server.
 		if err := s.chanStatusMgr.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop chanStatusMgr: %v", err)
@@ -2375,12 +2738,27 @@ func (s *server) Stop() error {
 		if err := s.htlcSwitch.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop htlcSwitch: %v", err)
 		}
+		if s.trampolineRouter != nil {
+			if err := s.trampolineRouter.Stop(); err != nil {
+				srvrLog.Warnf("failed to stop "+
+					"trampolineRouter: %v", err)
+			}
+		}
 		if err := s.sphinx.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop sphinx: %v", err)
 		}
 		if err := s.invoices.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop invoices: %v", err)
 		}
+		if err := s.probeService.Stop(); err != nil {
+			srvrLog.Warnf("failed to stop probeService: %v", err)
+		}
+		if s.pathCache != nil {
+			if err := s.pathCache.Stop(); err != nil {
+				srvrLog.Warnf("failed to stop pathCache: %v",
+					err)
+			}
+		}
 		if err := s.chanRouter.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop chanRouter: %v", err)
 		}
@@ -2412,6 +2790,12 @@ func (s *server) Stop() error {
 		if err := s.peerNotifier.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop peerNotifier: %v", err)
 		}
+		if s.resourceMgr != nil {
+			if err := s.resourceMgr.Stop(); err != nil {
+				srvrLog.Warnf("failed to stop resourceMgr: %v",
+					err)
+			}
+		}
 		if err := s.htlcNotifier.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop htlcNotifier: %v", err)
 		}
@@ -2429,7 +2813,7 @@ func (s *server) Stop() error {
 			srvrLog.Warnf("Unable to stop ChannelEventStore: %v",
 				err)
 		}
-		s.missionControl.StopStoreTicker()
+		s.missionControlMgr.StopStoreTickers()
 
 		// Disconnect from each active peers to ensure that
 		// peerTerminationWatchers signal completion to each peer.
@@ -2468,6 +2852,20 @@ func (s *server) Stop() error {
 			}
 		}
 
+		if s.offersMgr != nil {
+			if err := s.offersMgr.Stop(); err != nil {
+				srvrLog.Warnf("unable to shutdown offers "+
+					"manager: %v", err)
+			}
+		}
+
+		if s.onionMessenger != nil {
+			if err := s.onionMessenger.Stop(); err != nil {
+				srvrLog.Warnf("unable to shutdown onion "+
+					"messenger: %v", err)
+			}
+		}
+
 		// Wait for all lingering goroutines to quit.
 		srvrLog.Debug("Waiting for server to shutdown...")
 		s.wg.Wait()
@@ -3080,6 +3478,22 @@ func (s *server) findChannel(node *btcec.PublicKey, chanID lnwire.ChannelID) (
 	return nil, fmt.Errorf("unable to find channel")
 }
 
+// hasOpenChannels returns true if we have any open channels with the given
+// node.
+func (s *server) hasOpenChannels(node route.Vertex) (bool, error) {
+	pubKey, err := btcec.ParsePubKey(node[:])
+	if err != nil {
+		return false, err
+	}
+
+	nodeChans, err := s.chanStateDB.FetchOpenChannels(pubKey)
+	if err != nil {
+		return false, err
+	}
+
+	return len(nodeChans) > 0, nil
+}
+
 // getNodeAnnouncement fetches the current, fully signed node announcement.
 func (s *server) getNodeAnnouncement() lnwire.NodeAnnouncement {
 	s.mu.Lock()
@@ -3950,6 +4364,135 @@ func (s *server) SubscribeCustomMessages() (*subscribe.Client, error) {
 	return s.customMessageServer.Subscribe()
 }
 
+// sendOnionMessage sends an onion message to a connected peer that supports
+// onion messages. We don't wait for the peer to become active, as onion
+// messages are relayed on a best-effort basis.
+func (s *server) sendOnionMessage(peerPub route.Vertex,
+	msg *lnwire.OnionMessage) error {
+
+	peer, err := s.FindPeerByPubStr(string(peerPub[:]))
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-peer.ActiveSignal():
+	default:
+		return fmt.Errorf("peer %v is not yet active", peerPub)
+	}
+
+	if !peer.RemoteFeatures().HasFeature(lnwire.OnionMessagesOptional) {
+		return fmt.Errorf("peer %v doesn't support onion messages",
+			peerPub)
+	}
+
+	return peer.SendMessageLazy(false, msg)
+}
+
+// onionMessageScidToPeer resolves the short channel ID of one of our channels
+// to the peer on the other end, so that onion messages can be relayed over
+// it.
+func (s *server) onionMessageScidToPeer(
+	scid lnwire.ShortChannelID) (route.Vertex, error) {
+
+	info, _, _, err := s.graphDB.FetchChannelEdgesByID(scid.ToUint64())
+	if err != nil {
+		return route.Vertex{}, err
+	}
+
+	ourPub := route.NewVertex(s.identityECDH.PubKey())
+	switch ourPub {
+	case info.NodeKey1Bytes:
+		return info.NodeKey2Bytes, nil
+
+	case info.NodeKey2Bytes:
+		return info.NodeKey1Bytes, nil
+
+	default:
+		return route.Vertex{}, fmt.Errorf("channel %v is not one of "+
+			"our channels", scid)
+	}
+}
+
+// createBolt12Invoice creates and stores an invoice for an invoice request
+// that was received for one of our offers. The invoice's blinded paths are
+// built with the default blinded path configuration.
+func (s *server) createBolt12Invoice(
+	req *bolt12.InvoiceRequest) (*bolt12.Invoice, error) {
+
+	var (
+		defaultDelta = s.cfg.Bitcoin.TimeLockDelta
+		blindCfg     = s.cfg.Routing.BlindedPaths
+		ourVertex    = route.NewVertex(s.identityECDH.PubKey())
+	)
+
+	restrictions := &routing.BlindedPathRestrictions{
+		MinDistanceFromIntroNode: blindCfg.MinNumRealHops,
+		NumHops:                  blindCfg.NumHops,
+		MaxNumPaths:              blindCfg.MaxNumPaths,
+		NodeOmissionSet:          fn.NewSet[route.Vertex](),
+	}
+
+	addInvoiceCfg := &invoicesrpc.AddInvoiceConfig{
+		AddInvoice:        s.invoices.AddInvoice,
+		IsChannelActive:   s.htlcSwitch.HasActiveLink,
+		ChainParams:       s.cfg.ActiveNetParams.Params,
+		NodeSigner:        s.nodeSigner,
+		DefaultCLTVExpiry: defaultDelta,
+		ChanDB:            s.chanStateDB,
+		Graph:             s.graphDB,
+		GenInvoiceFeatures: func() *lnwire.FeatureVector {
+			// Payments to the invoice are made over blinded paths,
+			// so the path ID takes the place of the payment
+			// address.
+			v := s.featureMgr.Get(feature.SetInvoice)
+			v.Unset(lnwire.PaymentAddrRequired)
+			v.Set(lnwire.PaymentAddrOptional)
+
+			return v
+		},
+		GetAlias:   s.aliasMgr.GetPeerAlias,
+		BestHeight: s.cc.BestBlockTracker.BestHeight,
+		QueryBlindedRoutes: func(amt lnwire.MilliSatoshi) (
+			[]*route.Route, error) {
+
+			return s.chanRouter.FindBlindedPaths(
+				ourVertex, amt,
+				s.missionControl.GetProbability,
+				restrictions,
+			)
+		},
+	}
+
+	blindedPathCfg := &invoicesrpc.BlindedPathConfig{
+		RoutePolicyIncrMultiplier: blindCfg.PolicyIncreaseMultiplier,
+		RoutePolicyDecrMultiplier: blindCfg.PolicyDecreaseMultiplier,
+		DefaultDummyHopPolicy: &blindedpath.BlindedHopPolicy{
+			CLTVExpiryDelta: uint16(defaultDelta),
+			FeeRate:         uint32(s.cfg.Bitcoin.FeeRate),
+			BaseFee:         s.cfg.Bitcoin.BaseFee,
+			MinHTLCMsat:     s.cfg.Bitcoin.MinHTLCIn,
+		},
+		MinNumPathHops: blindCfg.NumHops,
+	}
+
+	signer := func(tag, msg []byte) (*schnorr.Signature, error) {
+		return s.cc.KeyRing.SignMessageSchnorr(
+			s.identityKeyLoc, msg, false, nil, tag,
+		)
+	}
+
+	return invoicesrpc.AddBolt12Invoice(
+		context.Background(), addInvoiceCfg,
+		&invoicesrpc.Bolt12InvoiceData{
+			Request:        req,
+			NodeID:         s.identityECDH.PubKey(),
+			Signer:         signer,
+			BlindedPathCfg: blindedPathCfg,
+		},
+	)
+}
+
 // peerConnected is a function that handles initialization a newly connected
 // peer by adding it to the server's global list of all active peers, and
 // starting all the goroutines the peer needs to function properly. The inbound
@@ -4072,8 +4615,21 @@ func (s *server) peerConnected(conn net.Conn, connReq *connmgr.ConnReq,
 		AddLocalAlias:          s.aliasMgr.AddLocalAlias,
 		DisallowRouteBlinding:  s.cfg.ProtocolOptions.NoRouteBlinding(),
 		MaxFeeExposure:         thresholdMSats,
+		MaxLocalCSVDelay:       s.cfg.Bitcoin.MaxLocalDelay,
 		Quit:                   s.quit,
 		MsgRouter:              s.implCfg.MsgRouter,
+
+		HtlcRateLimiter: s.htlcRateLimiter,
+	}
+
+	if s.onionMessenger != nil {
+		pCfg.OnionMessageEndpoint = fn.Some[msgmux.Endpoint](
+			s.onionMessenger,
+		)
+	}
+
+	if s.trampolineRouter != nil {
+		pCfg.TrampolineForwarder = s.trampolineRouter
 	}
 
 	copy(pCfg.PubKeyBytes[:], peerAddr.IdentityKey.SerializeCompressed())
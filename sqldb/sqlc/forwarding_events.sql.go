@@ -0,0 +1,231 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: forwarding_events.sql
+
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+)
+
+const filterForwardingEvents = `-- name: FilterForwardingEvents :many
+SELECT id, timestamp_ns, incoming_chan_id, outgoing_chan_id, incoming_peer, outgoing_peer, amt_in_msat, amt_out_msat, fee_msat
+FROM forwarding_events
+WHERE timestamp_ns >= $1 AND timestamp_ns <= $2 AND (
+    incoming_chan_id = $3 OR
+    $3 IS NULL
+) AND (
+    outgoing_chan_id = $4 OR
+    $4 IS NULL
+) AND (
+    incoming_peer = $5 OR
+    outgoing_peer = $5 OR
+    $5 IS NULL
+) AND (
+    amt_out_msat >= $6 OR
+    $6 IS NULL
+) AND (
+    amt_out_msat <= $7 OR
+    $7 IS NULL
+) AND (
+    fee_msat >= $8 OR
+    $8 IS NULL
+) AND (
+    fee_msat <= $9 OR
+    $9 IS NULL
+)
+ORDER BY timestamp_ns, id
+LIMIT $10 OFFSET $11
+`
+
+type FilterForwardingEventsParams struct {
+	StartTime      int64
+	EndTime        int64
+	IncomingChanID []byte
+	OutgoingChanID []byte
+	Peer           []byte
+	MinAmtMsat     sql.NullInt64
+	MaxAmtMsat     sql.NullInt64
+	MinFeeMsat     sql.NullInt64
+	MaxFeeMsat     sql.NullInt64
+	NumLimit       int32
+	NumOffset      int32
+}
+
+func (q *Queries) FilterForwardingEvents(ctx context.Context, arg FilterForwardingEventsParams) ([]ForwardingEvent, error) {
+	rows, err := q.db.QueryContext(ctx, filterForwardingEvents,
+		arg.StartTime,
+		arg.EndTime,
+		arg.IncomingChanID,
+		arg.OutgoingChanID,
+		arg.Peer,
+		arg.MinAmtMsat,
+		arg.MaxAmtMsat,
+		arg.MinFeeMsat,
+		arg.MaxFeeMsat,
+		arg.NumLimit,
+		arg.NumOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ForwardingEvent
+	for rows.Next() {
+		var i ForwardingEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimestampNs,
+			&i.IncomingChanID,
+			&i.OutgoingChanID,
+			&i.IncomingPeer,
+			&i.OutgoingPeer,
+			&i.AmtInMsat,
+			&i.AmtOutMsat,
+			&i.FeeMsat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const forwardingFeesPerChannelDay = `-- name: ForwardingFeesPerChannelDay :many
+SELECT
+    outgoing_chan_id,
+    CAST(timestamp_ns / 86400000000000 AS BIGINT) AS day,
+    COUNT(*) AS num_forwards,
+    CAST(SUM(amt_in_msat) AS BIGINT) AS total_amt_in_msat,
+    CAST(SUM(amt_out_msat) AS BIGINT) AS total_amt_out_msat,
+    CAST(SUM(fee_msat) AS BIGINT) AS total_fee_msat
+FROM forwarding_events
+WHERE timestamp_ns >= $1 AND timestamp_ns <= $2 AND (
+    incoming_chan_id = $3 OR
+    $3 IS NULL
+) AND (
+    outgoing_chan_id = $4 OR
+    $4 IS NULL
+) AND (
+    incoming_peer = $5 OR
+    outgoing_peer = $5 OR
+    $5 IS NULL
+) AND (
+    amt_out_msat >= $6 OR
+    $6 IS NULL
+) AND (
+    amt_out_msat <= $7 OR
+    $7 IS NULL
+) AND (
+    fee_msat >= $8 OR
+    $8 IS NULL
+) AND (
+    fee_msat <= $9 OR
+    $9 IS NULL
+)
+GROUP BY outgoing_chan_id, day
+ORDER BY day, outgoing_chan_id
+`
+
+type ForwardingFeesPerChannelDayParams struct {
+	StartTime      int64
+	EndTime        int64
+	IncomingChanID []byte
+	OutgoingChanID []byte
+	Peer           []byte
+	MinAmtMsat     sql.NullInt64
+	MaxAmtMsat     sql.NullInt64
+	MinFeeMsat     sql.NullInt64
+	MaxFeeMsat     sql.NullInt64
+}
+
+type ForwardingFeesPerChannelDayRow struct {
+	OutgoingChanID  []byte
+	Day             int64
+	NumForwards     int64
+	TotalAmtInMsat  int64
+	TotalAmtOutMsat int64
+	TotalFeeMsat    int64
+}
+
+func (q *Queries) ForwardingFeesPerChannelDay(ctx context.Context, arg ForwardingFeesPerChannelDayParams) ([]ForwardingFeesPerChannelDayRow, error) {
+	rows, err := q.db.QueryContext(ctx, forwardingFeesPerChannelDay,
+		arg.StartTime,
+		arg.EndTime,
+		arg.IncomingChanID,
+		arg.OutgoingChanID,
+		arg.Peer,
+		arg.MinAmtMsat,
+		arg.MaxAmtMsat,
+		arg.MinFeeMsat,
+		arg.MaxFeeMsat,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ForwardingFeesPerChannelDayRow
+	for rows.Next() {
+		var i ForwardingFeesPerChannelDayRow
+		if err := rows.Scan(
+			&i.OutgoingChanID,
+			&i.Day,
+			&i.NumForwards,
+			&i.TotalAmtInMsat,
+			&i.TotalAmtOutMsat,
+			&i.TotalFeeMsat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertForwardingEvent = `-- name: InsertForwardingEvent :exec
+INSERT INTO forwarding_events (
+    timestamp_ns, incoming_chan_id, outgoing_chan_id, incoming_peer,
+    outgoing_peer, amt_in_msat, amt_out_msat, fee_msat
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+`
+
+type InsertForwardingEventParams struct {
+	TimestampNs    int64
+	IncomingChanID []byte
+	OutgoingChanID []byte
+	IncomingPeer   []byte
+	OutgoingPeer   []byte
+	AmtInMsat      int64
+	AmtOutMsat     int64
+	FeeMsat        int64
+}
+
+func (q *Queries) InsertForwardingEvent(ctx context.Context, arg InsertForwardingEventParams) error {
+	_, err := q.db.ExecContext(ctx, insertForwardingEvent,
+		arg.TimestampNs,
+		arg.IncomingChanID,
+		arg.OutgoingChanID,
+		arg.IncomingPeer,
+		arg.OutgoingPeer,
+		arg.AmtInMsat,
+		arg.AmtOutMsat,
+		arg.FeeMsat,
+	)
+	return err
+}
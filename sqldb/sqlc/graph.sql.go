@@ -0,0 +1,1302 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: graph.sql
+
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+)
+
+const addSourceNode = `-- name: AddSourceNode :exec
+INSERT INTO graph_source_nodes (node_id)
+VALUES ($1)
+ON CONFLICT (node_id) DO NOTHING
+`
+
+func (q *Queries) AddSourceNode(ctx context.Context, nodeID int64) error {
+	_, err := q.db.ExecContext(ctx, addSourceNode, nodeID)
+	return err
+}
+
+const countZombieChannels = `-- name: CountZombieChannels :one
+SELECT COUNT(*)
+FROM graph_zombie_channels
+`
+
+func (q *Queries) CountZombieChannels(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countZombieChannels)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createChannel = `-- name: CreateChannel :one
+INSERT INTO graph_channels (
+    scid, chain_hash, node_id_1, node_id_2, outpoint, capacity,
+    bitcoin_key_1, bitcoin_key_2, features, node_1_signature,
+    node_2_signature, bitcoin_1_signature, bitcoin_2_signature,
+    extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING id
+`
+
+type CreateChannelParams struct {
+	Scid              []byte
+	ChainHash         []byte
+	NodeID1           int64
+	NodeID2           int64
+	Outpoint          string
+	Capacity          int64
+	BitcoinKey1       []byte
+	BitcoinKey2       []byte
+	Features          []byte
+	Node1Signature    []byte
+	Node2Signature    []byte
+	Bitcoin1Signature []byte
+	Bitcoin2Signature []byte
+	ExtraOpaqueData   []byte
+}
+
+func (q *Queries) CreateChannel(ctx context.Context, arg CreateChannelParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createChannel,
+		arg.Scid,
+		arg.ChainHash,
+		arg.NodeID1,
+		arg.NodeID2,
+		arg.Outpoint,
+		arg.Capacity,
+		arg.BitcoinKey1,
+		arg.BitcoinKey2,
+		arg.Features,
+		arg.Node1Signature,
+		arg.Node2Signature,
+		arg.Bitcoin1Signature,
+		arg.Bitcoin2Signature,
+		arg.ExtraOpaqueData,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deleteAllChannels = `-- name: DeleteAllChannels :exec
+DELETE FROM graph_channels
+`
+
+func (q *Queries) DeleteAllChannels(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllChannels)
+	return err
+}
+
+const deleteAllClosedSCIDs = `-- name: DeleteAllClosedSCIDs :exec
+DELETE FROM graph_closed_scids
+`
+
+func (q *Queries) DeleteAllClosedSCIDs(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllClosedSCIDs)
+	return err
+}
+
+const deleteAllNodes = `-- name: DeleteAllNodes :exec
+DELETE FROM graph_nodes
+`
+
+func (q *Queries) DeleteAllNodes(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllNodes)
+	return err
+}
+
+const deleteAllPruneLogEntries = `-- name: DeleteAllPruneLogEntries :exec
+DELETE FROM graph_prune_log
+`
+
+func (q *Queries) DeleteAllPruneLogEntries(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllPruneLogEntries)
+	return err
+}
+
+const deleteAllZombieChannels = `-- name: DeleteAllZombieChannels :exec
+DELETE FROM graph_zombie_channels
+`
+
+func (q *Queries) DeleteAllZombieChannels(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllZombieChannels)
+	return err
+}
+
+const deleteChannel = `-- name: DeleteChannel :exec
+DELETE FROM graph_channels
+WHERE id = $1
+`
+
+func (q *Queries) DeleteChannel(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteChannel, id)
+	return err
+}
+
+const deleteNodeAddresses = `-- name: DeleteNodeAddresses :exec
+DELETE FROM graph_node_addresses
+WHERE node_id = $1
+`
+
+func (q *Queries) DeleteNodeAddresses(ctx context.Context, nodeID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteNodeAddresses, nodeID)
+	return err
+}
+
+const deleteNodeByPubKey = `-- name: DeleteNodeByPubKey :execresult
+DELETE FROM graph_nodes
+WHERE pub_key = $1
+`
+
+func (q *Queries) DeleteNodeByPubKey(ctx context.Context, pubKey []byte) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteNodeByPubKey, pubKey)
+}
+
+const deleteNodeFeatures = `-- name: DeleteNodeFeatures :exec
+DELETE FROM graph_node_features
+WHERE node_id = $1
+`
+
+func (q *Queries) DeleteNodeFeatures(ctx context.Context, nodeID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteNodeFeatures, nodeID)
+	return err
+}
+
+const deletePruneLogEntriesFromHeight = `-- name: DeletePruneLogEntriesFromHeight :exec
+DELETE FROM graph_prune_log
+WHERE block_height >= $1
+`
+
+func (q *Queries) DeletePruneLogEntriesFromHeight(ctx context.Context, blockHeight int64) error {
+	_, err := q.db.ExecContext(ctx, deletePruneLogEntriesFromHeight, blockHeight)
+	return err
+}
+
+const deleteSourceNodes = `-- name: DeleteSourceNodes :exec
+DELETE FROM graph_source_nodes
+`
+
+func (q *Queries) DeleteSourceNodes(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteSourceNodes)
+	return err
+}
+
+const deleteUnconnectedNodes = `-- name: DeleteUnconnectedNodes :many
+DELETE FROM graph_nodes
+WHERE id NOT IN (
+    SELECT node_id_1 FROM graph_channels
+    UNION
+    SELECT node_id_2 FROM graph_channels
+) AND id NOT IN (
+    SELECT node_id FROM graph_source_nodes
+)
+RETURNING pub_key
+`
+
+func (q *Queries) DeleteUnconnectedNodes(ctx context.Context) ([][]byte, error) {
+	rows, err := q.db.QueryContext(ctx, deleteUnconnectedNodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items [][]byte
+	for rows.Next() {
+		var pubKey []byte
+		if err := rows.Scan(&pubKey); err != nil {
+			return nil, err
+		}
+		items = append(items, pubKey)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteZombieChannel = `-- name: DeleteZombieChannel :execresult
+DELETE FROM graph_zombie_channels
+WHERE scid = $1
+`
+
+func (q *Queries) DeleteZombieChannel(ctx context.Context, scid []byte) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteZombieChannel, scid)
+}
+
+const getChannelByOutpoint = `-- name: GetChannelByOutpoint :one
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.outpoint = $1
+`
+
+type GetChannelByOutpointRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) GetChannelByOutpoint(ctx context.Context, outpoint string) (GetChannelByOutpointRow, error) {
+	row := q.db.QueryRowContext(ctx, getChannelByOutpoint, outpoint)
+	var i GetChannelByOutpointRow
+	err := row.Scan(
+		&i.GraphChannel.ID,
+		&i.GraphChannel.Scid,
+		&i.GraphChannel.ChainHash,
+		&i.GraphChannel.NodeID1,
+		&i.GraphChannel.NodeID2,
+		&i.GraphChannel.Outpoint,
+		&i.GraphChannel.Capacity,
+		&i.GraphChannel.BitcoinKey1,
+		&i.GraphChannel.BitcoinKey2,
+		&i.GraphChannel.Features,
+		&i.GraphChannel.Node1Signature,
+		&i.GraphChannel.Node2Signature,
+		&i.GraphChannel.Bitcoin1Signature,
+		&i.GraphChannel.Bitcoin2Signature,
+		&i.GraphChannel.ExtraOpaqueData,
+		&i.Node1PubKey,
+		&i.Node2PubKey,
+	)
+	return i, err
+}
+
+const getChannelBySCID = `-- name: GetChannelBySCID :one
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.scid = $1
+`
+
+type GetChannelBySCIDRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) GetChannelBySCID(ctx context.Context, scid []byte) (GetChannelBySCIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getChannelBySCID, scid)
+	var i GetChannelBySCIDRow
+	err := row.Scan(
+		&i.GraphChannel.ID,
+		&i.GraphChannel.Scid,
+		&i.GraphChannel.ChainHash,
+		&i.GraphChannel.NodeID1,
+		&i.GraphChannel.NodeID2,
+		&i.GraphChannel.Outpoint,
+		&i.GraphChannel.Capacity,
+		&i.GraphChannel.BitcoinKey1,
+		&i.GraphChannel.BitcoinKey2,
+		&i.GraphChannel.Features,
+		&i.GraphChannel.Node1Signature,
+		&i.GraphChannel.Node2Signature,
+		&i.GraphChannel.Bitcoin1Signature,
+		&i.GraphChannel.Bitcoin2Signature,
+		&i.GraphChannel.ExtraOpaqueData,
+		&i.Node1PubKey,
+		&i.Node2PubKey,
+	)
+	return i, err
+}
+
+const getChannelPolicies = `-- name: GetChannelPolicies :many
+SELECT channel_id, node_id, last_update, message_flags, channel_flags, disabled, timelock, base_fee_msat, fee_ppm, min_htlc_msat, max_htlc_msat, signature, extra_opaque_data
+FROM graph_channel_policies
+WHERE channel_id = $1
+`
+
+func (q *Queries) GetChannelPolicies(ctx context.Context, channelID int64) ([]GraphChannelPolicy, error) {
+	rows, err := q.db.QueryContext(ctx, getChannelPolicies, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphChannelPolicy
+	for rows.Next() {
+		var i GraphChannelPolicy
+		if err := rows.Scan(
+			&i.ChannelID,
+			&i.NodeID,
+			&i.LastUpdate,
+			&i.MessageFlags,
+			&i.ChannelFlags,
+			&i.Disabled,
+			&i.Timelock,
+			&i.BaseFeeMsat,
+			&i.FeePpm,
+			&i.MinHtlcMsat,
+			&i.MaxHtlcMsat,
+			&i.Signature,
+			&i.ExtraOpaqueData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChannelsByPolicyLastUpdateRange = `-- name: GetChannelsByPolicyLastUpdateRange :many
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+JOIN (
+    SELECT channel_id, MIN(last_update) AS first_update
+    FROM graph_channel_policies
+    WHERE last_update >= $1
+      AND last_update <= $2
+    GROUP BY channel_id
+) u ON u.channel_id = c.id
+ORDER BY u.first_update, c.scid
+`
+
+type GetChannelsByPolicyLastUpdateRangeParams struct {
+	StartTime int64
+	EndTime   int64
+}
+
+type GetChannelsByPolicyLastUpdateRangeRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) GetChannelsByPolicyLastUpdateRange(ctx context.Context, arg GetChannelsByPolicyLastUpdateRangeParams) ([]GetChannelsByPolicyLastUpdateRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChannelsByPolicyLastUpdateRange,
+		arg.StartTime,
+		arg.EndTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChannelsByPolicyLastUpdateRangeRow
+	for rows.Next() {
+		var i GetChannelsByPolicyLastUpdateRangeRow
+		if err := rows.Scan(
+			&i.GraphChannel.ID,
+			&i.GraphChannel.Scid,
+			&i.GraphChannel.ChainHash,
+			&i.GraphChannel.NodeID1,
+			&i.GraphChannel.NodeID2,
+			&i.GraphChannel.Outpoint,
+			&i.GraphChannel.Capacity,
+			&i.GraphChannel.BitcoinKey1,
+			&i.GraphChannel.BitcoinKey2,
+			&i.GraphChannel.Features,
+			&i.GraphChannel.Node1Signature,
+			&i.GraphChannel.Node2Signature,
+			&i.GraphChannel.Bitcoin1Signature,
+			&i.GraphChannel.Bitcoin2Signature,
+			&i.GraphChannel.ExtraOpaqueData,
+			&i.Node1PubKey,
+			&i.Node2PubKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChannelsBySCIDRange = `-- name: GetChannelsBySCIDRange :many
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.scid >= $1
+  AND c.scid <= $2
+ORDER BY c.scid
+`
+
+type GetChannelsBySCIDRangeParams struct {
+	StartScid []byte
+	EndScid   []byte
+}
+
+type GetChannelsBySCIDRangeRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) GetChannelsBySCIDRange(ctx context.Context, arg GetChannelsBySCIDRangeParams) ([]GetChannelsBySCIDRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChannelsBySCIDRange,
+		arg.StartScid,
+		arg.EndScid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChannelsBySCIDRangeRow
+	for rows.Next() {
+		var i GetChannelsBySCIDRangeRow
+		if err := rows.Scan(
+			&i.GraphChannel.ID,
+			&i.GraphChannel.Scid,
+			&i.GraphChannel.ChainHash,
+			&i.GraphChannel.NodeID1,
+			&i.GraphChannel.NodeID2,
+			&i.GraphChannel.Outpoint,
+			&i.GraphChannel.Capacity,
+			&i.GraphChannel.BitcoinKey1,
+			&i.GraphChannel.BitcoinKey2,
+			&i.GraphChannel.Features,
+			&i.GraphChannel.Node1Signature,
+			&i.GraphChannel.Node2Signature,
+			&i.GraphChannel.Bitcoin1Signature,
+			&i.GraphChannel.Bitcoin2Signature,
+			&i.GraphChannel.ExtraOpaqueData,
+			&i.Node1PubKey,
+			&i.Node2PubKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDisabledChannelSCIDs = `-- name: GetDisabledChannelSCIDs :many
+SELECT c.scid
+FROM graph_channels c
+JOIN graph_channel_policies p1
+    ON p1.channel_id = c.id AND p1.node_id = c.node_id_1
+JOIN graph_channel_policies p2
+    ON p2.channel_id = c.id AND p2.node_id = c.node_id_2
+WHERE p1.disabled = TRUE
+  AND p2.disabled = TRUE
+`
+
+func (q *Queries) GetDisabledChannelSCIDs(ctx context.Context) ([][]byte, error) {
+	rows, err := q.db.QueryContext(ctx, getDisabledChannelSCIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items [][]byte
+	for rows.Next() {
+		var scid []byte
+		if err := rows.Scan(&scid); err != nil {
+			return nil, err
+		}
+		items = append(items, scid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNodeAddresses = `-- name: GetNodeAddresses :many
+SELECT node_id, position, address
+FROM graph_node_addresses
+WHERE node_id = $1
+ORDER BY position
+`
+
+func (q *Queries) GetNodeAddresses(ctx context.Context, nodeID int64) ([]GraphNodeAddress, error) {
+	rows, err := q.db.QueryContext(ctx, getNodeAddresses, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphNodeAddress
+	for rows.Next() {
+		var i GraphNodeAddress
+		if err := rows.Scan(
+			&i.NodeID,
+			&i.Position,
+			&i.Address,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNodeByPubKey = `-- name: GetNodeByPubKey :one
+SELECT id, pub_key, last_update, alias, color, signature, extra_opaque_data
+FROM graph_nodes
+WHERE pub_key = $1
+`
+
+func (q *Queries) GetNodeByPubKey(ctx context.Context, pubKey []byte) (GraphNode, error) {
+	row := q.db.QueryRowContext(ctx, getNodeByPubKey, pubKey)
+	var i GraphNode
+	err := row.Scan(
+		&i.ID,
+		&i.PubKey,
+		&i.LastUpdate,
+		&i.Alias,
+		&i.Color,
+		&i.Signature,
+		&i.ExtraOpaqueData,
+	)
+	return i, err
+}
+
+const getNodeFeatures = `-- name: GetNodeFeatures :many
+SELECT node_id, feature_bit
+FROM graph_node_features
+WHERE node_id = $1
+ORDER BY feature_bit
+`
+
+func (q *Queries) GetNodeFeatures(ctx context.Context, nodeID int64) ([]GraphNodeFeature, error) {
+	rows, err := q.db.QueryContext(ctx, getNodeFeatures, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphNodeFeature
+	for rows.Next() {
+		var i GraphNodeFeature
+		if err := rows.Scan(
+			&i.NodeID,
+			&i.FeatureBit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNodeIDByPubKey = `-- name: GetNodeIDByPubKey :one
+SELECT id
+FROM graph_nodes
+WHERE pub_key = $1
+`
+
+func (q *Queries) GetNodeIDByPubKey(ctx context.Context, pubKey []byte) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getNodeIDByPubKey, pubKey)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getNodesByLastUpdateRange = `-- name: GetNodesByLastUpdateRange :many
+SELECT id, pub_key, last_update, alias, color, signature, extra_opaque_data
+FROM graph_nodes
+WHERE last_update >= $1
+  AND last_update <= $2
+ORDER BY last_update, pub_key
+`
+
+type GetNodesByLastUpdateRangeParams struct {
+	StartTime sql.NullInt64
+	EndTime   sql.NullInt64
+}
+
+func (q *Queries) GetNodesByLastUpdateRange(ctx context.Context, arg GetNodesByLastUpdateRangeParams) ([]GraphNode, error) {
+	rows, err := q.db.QueryContext(ctx, getNodesByLastUpdateRange,
+		arg.StartTime,
+		arg.EndTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphNode
+	for rows.Next() {
+		var i GraphNode
+		if err := rows.Scan(
+			&i.ID,
+			&i.PubKey,
+			&i.LastUpdate,
+			&i.Alias,
+			&i.Color,
+			&i.Signature,
+			&i.ExtraOpaqueData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPruneTip = `-- name: GetPruneTip :one
+SELECT block_height, block_hash
+FROM graph_prune_log
+ORDER BY block_height DESC
+LIMIT 1
+`
+
+func (q *Queries) GetPruneTip(ctx context.Context) (GraphPruneLog, error) {
+	row := q.db.QueryRowContext(ctx, getPruneTip)
+	var i GraphPruneLog
+	err := row.Scan(
+		&i.BlockHeight,
+		&i.BlockHash,
+	)
+	return i, err
+}
+
+const getPublicChannelUpdateTimesBySCIDRange = `-- name: GetPublicChannelUpdateTimesBySCIDRange :many
+SELECT c.scid,
+       p1.last_update AS node_1_last_update,
+       p2.last_update AS node_2_last_update
+FROM graph_channels c
+LEFT JOIN graph_channel_policies p1
+    ON p1.channel_id = c.id AND p1.node_id = c.node_id_1
+LEFT JOIN graph_channel_policies p2
+    ON p2.channel_id = c.id AND p2.node_id = c.node_id_2
+WHERE c.scid >= $1
+  AND c.scid <= $2
+  AND c.node_1_signature IS NOT NULL
+ORDER BY c.scid
+`
+
+type GetPublicChannelUpdateTimesBySCIDRangeParams struct {
+	StartScid []byte
+	EndScid   []byte
+}
+
+type GetPublicChannelUpdateTimesBySCIDRangeRow struct {
+	Scid            []byte
+	Node1LastUpdate sql.NullInt64
+	Node2LastUpdate sql.NullInt64
+}
+
+func (q *Queries) GetPublicChannelUpdateTimesBySCIDRange(ctx context.Context, arg GetPublicChannelUpdateTimesBySCIDRangeParams) ([]GetPublicChannelUpdateTimesBySCIDRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicChannelUpdateTimesBySCIDRange,
+		arg.StartScid,
+		arg.EndScid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPublicChannelUpdateTimesBySCIDRangeRow
+	for rows.Next() {
+		var i GetPublicChannelUpdateTimesBySCIDRangeRow
+		if err := rows.Scan(
+			&i.Scid,
+			&i.Node1LastUpdate,
+			&i.Node2LastUpdate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSourceNodes = `-- name: GetSourceNodes :many
+SELECT n.pub_key
+FROM graph_source_nodes s
+JOIN graph_nodes n ON s.node_id = n.id
+`
+
+func (q *Queries) GetSourceNodes(ctx context.Context) ([][]byte, error) {
+	rows, err := q.db.QueryContext(ctx, getSourceNodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items [][]byte
+	for rows.Next() {
+		var pubKey []byte
+		if err := rows.Scan(&pubKey); err != nil {
+			return nil, err
+		}
+		items = append(items, pubKey)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getZombieChannel = `-- name: GetZombieChannel :one
+SELECT scid, node_key_1, node_key_2
+FROM graph_zombie_channels
+WHERE scid = $1
+`
+
+func (q *Queries) GetZombieChannel(ctx context.Context, scid []byte) (GraphZombieChannel, error) {
+	row := q.db.QueryRowContext(ctx, getZombieChannel, scid)
+	var i GraphZombieChannel
+	err := row.Scan(
+		&i.Scid,
+		&i.NodeKey1,
+		&i.NodeKey2,
+	)
+	return i, err
+}
+
+const highestSCID = `-- name: HighestSCID :one
+SELECT scid
+FROM graph_channels
+ORDER BY scid DESC
+LIMIT 1
+`
+
+func (q *Queries) HighestSCID(ctx context.Context) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, highestSCID)
+	var scid []byte
+	err := row.Scan(&scid)
+	return scid, err
+}
+
+const insertClosedSCID = `-- name: InsertClosedSCID :exec
+INSERT INTO graph_closed_scids (scid)
+VALUES ($1)
+ON CONFLICT (scid) DO NOTHING
+`
+
+func (q *Queries) InsertClosedSCID(ctx context.Context, scid []byte) error {
+	_, err := q.db.ExecContext(ctx, insertClosedSCID, scid)
+	return err
+}
+
+const insertNodeAddress = `-- name: InsertNodeAddress :exec
+INSERT INTO graph_node_addresses (
+    node_id, position, address
+) VALUES (
+    $1, $2, $3
+)
+`
+
+type InsertNodeAddressParams struct {
+	NodeID   int64
+	Position int32
+	Address  []byte
+}
+
+func (q *Queries) InsertNodeAddress(ctx context.Context, arg InsertNodeAddressParams) error {
+	_, err := q.db.ExecContext(ctx, insertNodeAddress,
+		arg.NodeID,
+		arg.Position,
+		arg.Address,
+	)
+	return err
+}
+
+const insertNodeFeature = `-- name: InsertNodeFeature :exec
+INSERT INTO graph_node_features (
+    node_id, feature_bit
+) VALUES (
+    $1, $2
+)
+`
+
+type InsertNodeFeatureParams struct {
+	NodeID     int64
+	FeatureBit int32
+}
+
+func (q *Queries) InsertNodeFeature(ctx context.Context, arg InsertNodeFeatureParams) error {
+	_, err := q.db.ExecContext(ctx, insertNodeFeature,
+		arg.NodeID,
+		arg.FeatureBit,
+	)
+	return err
+}
+
+const isClosedSCID = `-- name: IsClosedSCID :one
+SELECT EXISTS (
+    SELECT 1
+    FROM graph_closed_scids
+    WHERE scid = $1
+)
+`
+
+func (q *Queries) IsClosedSCID(ctx context.Context, scid []byte) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isClosedSCID, scid)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listChannelPoliciesByChannelIDRange = `-- name: ListChannelPoliciesByChannelIDRange :many
+SELECT channel_id, node_id, last_update, message_flags, channel_flags, disabled, timelock, base_fee_msat, fee_ppm, min_htlc_msat, max_htlc_msat, signature, extra_opaque_data
+FROM graph_channel_policies
+WHERE channel_id >= $1
+  AND channel_id <= $2
+`
+
+type ListChannelPoliciesByChannelIDRangeParams struct {
+	StartID int64
+	EndID   int64
+}
+
+func (q *Queries) ListChannelPoliciesByChannelIDRange(ctx context.Context, arg ListChannelPoliciesByChannelIDRangeParams) ([]GraphChannelPolicy, error) {
+	rows, err := q.db.QueryContext(ctx, listChannelPoliciesByChannelIDRange,
+		arg.StartID,
+		arg.EndID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphChannelPolicy
+	for rows.Next() {
+		var i GraphChannelPolicy
+		if err := rows.Scan(
+			&i.ChannelID,
+			&i.NodeID,
+			&i.LastUpdate,
+			&i.MessageFlags,
+			&i.ChannelFlags,
+			&i.Disabled,
+			&i.Timelock,
+			&i.BaseFeeMsat,
+			&i.FeePpm,
+			&i.MinHtlcMsat,
+			&i.MaxHtlcMsat,
+			&i.Signature,
+			&i.ExtraOpaqueData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChannelsByNodeID = `-- name: ListChannelsByNodeID :many
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.node_id_1 = $1
+   OR c.node_id_2 = $1
+ORDER BY c.scid
+`
+
+type ListChannelsByNodeIDRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) ListChannelsByNodeID(ctx context.Context, nodeID int64) ([]ListChannelsByNodeIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChannelsByNodeID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChannelsByNodeIDRow
+	for rows.Next() {
+		var i ListChannelsByNodeIDRow
+		if err := rows.Scan(
+			&i.GraphChannel.ID,
+			&i.GraphChannel.Scid,
+			&i.GraphChannel.ChainHash,
+			&i.GraphChannel.NodeID1,
+			&i.GraphChannel.NodeID2,
+			&i.GraphChannel.Outpoint,
+			&i.GraphChannel.Capacity,
+			&i.GraphChannel.BitcoinKey1,
+			&i.GraphChannel.BitcoinKey2,
+			&i.GraphChannel.Features,
+			&i.GraphChannel.Node1Signature,
+			&i.GraphChannel.Node2Signature,
+			&i.GraphChannel.Bitcoin1Signature,
+			&i.GraphChannel.Bitcoin2Signature,
+			&i.GraphChannel.ExtraOpaqueData,
+			&i.Node1PubKey,
+			&i.Node2PubKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChannelsPaginated = `-- name: ListChannelsPaginated :many
+SELECT c.id, c.scid, c.chain_hash, c.node_id_1, c.node_id_2, c.outpoint, c.capacity, c.bitcoin_key_1, c.bitcoin_key_2, c.features, c.node_1_signature, c.node_2_signature, c.bitcoin_1_signature, c.bitcoin_2_signature, c.extra_opaque_data,
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.id > $1
+ORDER BY c.id
+LIMIT $2
+`
+
+type ListChannelsPaginatedParams struct {
+	ID    int64
+	Limit int32
+}
+
+type ListChannelsPaginatedRow struct {
+	GraphChannel GraphChannel
+	Node1PubKey  []byte
+	Node2PubKey  []byte
+}
+
+func (q *Queries) ListChannelsPaginated(ctx context.Context, arg ListChannelsPaginatedParams) ([]ListChannelsPaginatedRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChannelsPaginated,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChannelsPaginatedRow
+	for rows.Next() {
+		var i ListChannelsPaginatedRow
+		if err := rows.Scan(
+			&i.GraphChannel.ID,
+			&i.GraphChannel.Scid,
+			&i.GraphChannel.ChainHash,
+			&i.GraphChannel.NodeID1,
+			&i.GraphChannel.NodeID2,
+			&i.GraphChannel.Outpoint,
+			&i.GraphChannel.Capacity,
+			&i.GraphChannel.BitcoinKey1,
+			&i.GraphChannel.BitcoinKey2,
+			&i.GraphChannel.Features,
+			&i.GraphChannel.Node1Signature,
+			&i.GraphChannel.Node2Signature,
+			&i.GraphChannel.Bitcoin1Signature,
+			&i.GraphChannel.Bitcoin2Signature,
+			&i.GraphChannel.ExtraOpaqueData,
+			&i.Node1PubKey,
+			&i.Node2PubKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNodesPaginated = `-- name: ListNodesPaginated :many
+SELECT id, pub_key, last_update, alias, color, signature, extra_opaque_data
+FROM graph_nodes
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListNodesPaginatedParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) ListNodesPaginated(ctx context.Context, arg ListNodesPaginatedParams) ([]GraphNode, error) {
+	rows, err := q.db.QueryContext(ctx, listNodesPaginated,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GraphNode
+	for rows.Next() {
+		var i GraphNode
+		if err := rows.Scan(
+			&i.ID,
+			&i.PubKey,
+			&i.LastUpdate,
+			&i.Alias,
+			&i.Color,
+			&i.Signature,
+			&i.ExtraOpaqueData,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChannel = `-- name: UpdateChannel :exec
+UPDATE graph_channels
+SET chain_hash = $2,
+    outpoint = $3,
+    capacity = $4,
+    bitcoin_key_1 = $5,
+    bitcoin_key_2 = $6,
+    features = $7,
+    node_1_signature = $8,
+    node_2_signature = $9,
+    bitcoin_1_signature = $10,
+    bitcoin_2_signature = $11,
+    extra_opaque_data = $12
+WHERE id = $1
+`
+
+type UpdateChannelParams struct {
+	ID                int64
+	ChainHash         []byte
+	Outpoint          string
+	Capacity          int64
+	BitcoinKey1       []byte
+	BitcoinKey2       []byte
+	Features          []byte
+	Node1Signature    []byte
+	Node2Signature    []byte
+	Bitcoin1Signature []byte
+	Bitcoin2Signature []byte
+	ExtraOpaqueData   []byte
+}
+
+func (q *Queries) UpdateChannel(ctx context.Context, arg UpdateChannelParams) error {
+	_, err := q.db.ExecContext(ctx, updateChannel,
+		arg.ID,
+		arg.ChainHash,
+		arg.Outpoint,
+		arg.Capacity,
+		arg.BitcoinKey1,
+		arg.BitcoinKey2,
+		arg.Features,
+		arg.Node1Signature,
+		arg.Node2Signature,
+		arg.Bitcoin1Signature,
+		arg.Bitcoin2Signature,
+		arg.ExtraOpaqueData,
+	)
+	return err
+}
+
+const upsertChannelPolicy = `-- name: UpsertChannelPolicy :exec
+INSERT INTO graph_channel_policies (
+    channel_id, node_id, last_update, message_flags, channel_flags,
+    disabled, timelock, base_fee_msat, fee_ppm, min_htlc_msat,
+    max_htlc_msat, signature, extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+ON CONFLICT (channel_id, node_id)
+    DO UPDATE SET
+        last_update = EXCLUDED.last_update,
+        message_flags = EXCLUDED.message_flags,
+        channel_flags = EXCLUDED.channel_flags,
+        disabled = EXCLUDED.disabled,
+        timelock = EXCLUDED.timelock,
+        base_fee_msat = EXCLUDED.base_fee_msat,
+        fee_ppm = EXCLUDED.fee_ppm,
+        min_htlc_msat = EXCLUDED.min_htlc_msat,
+        max_htlc_msat = EXCLUDED.max_htlc_msat,
+        signature = EXCLUDED.signature,
+        extra_opaque_data = EXCLUDED.extra_opaque_data
+`
+
+type UpsertChannelPolicyParams struct {
+	ChannelID       int64
+	NodeID          int64
+	LastUpdate      int64
+	MessageFlags    int16
+	ChannelFlags    int16
+	Disabled        bool
+	Timelock        int32
+	BaseFeeMsat     int64
+	FeePpm          int64
+	MinHtlcMsat     int64
+	MaxHtlcMsat     int64
+	Signature       []byte
+	ExtraOpaqueData []byte
+}
+
+func (q *Queries) UpsertChannelPolicy(ctx context.Context, arg UpsertChannelPolicyParams) error {
+	_, err := q.db.ExecContext(ctx, upsertChannelPolicy,
+		arg.ChannelID,
+		arg.NodeID,
+		arg.LastUpdate,
+		arg.MessageFlags,
+		arg.ChannelFlags,
+		arg.Disabled,
+		arg.Timelock,
+		arg.BaseFeeMsat,
+		arg.FeePpm,
+		arg.MinHtlcMsat,
+		arg.MaxHtlcMsat,
+		arg.Signature,
+		arg.ExtraOpaqueData,
+	)
+	return err
+}
+
+const upsertNode = `-- name: UpsertNode :one
+INSERT INTO graph_nodes (
+    pub_key, last_update, alias, color, signature, extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (pub_key)
+    DO UPDATE SET
+        last_update = EXCLUDED.last_update,
+        alias = EXCLUDED.alias,
+        color = EXCLUDED.color,
+        signature = EXCLUDED.signature,
+        extra_opaque_data = EXCLUDED.extra_opaque_data
+RETURNING id
+`
+
+type UpsertNodeParams struct {
+	PubKey          []byte
+	LastUpdate      sql.NullInt64
+	Alias           sql.NullString
+	Color           sql.NullInt32
+	Signature       []byte
+	ExtraOpaqueData []byte
+}
+
+func (q *Queries) UpsertNode(ctx context.Context, arg UpsertNodeParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, upsertNode,
+		arg.PubKey,
+		arg.LastUpdate,
+		arg.Alias,
+		arg.Color,
+		arg.Signature,
+		arg.ExtraOpaqueData,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const upsertPruneLogEntry = `-- name: UpsertPruneLogEntry :exec
+INSERT INTO graph_prune_log (
+    block_height, block_hash
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (block_height)
+    DO UPDATE SET block_hash = EXCLUDED.block_hash
+`
+
+type UpsertPruneLogEntryParams struct {
+	BlockHeight int64
+	BlockHash   []byte
+}
+
+func (q *Queries) UpsertPruneLogEntry(ctx context.Context, arg UpsertPruneLogEntryParams) error {
+	_, err := q.db.ExecContext(ctx, upsertPruneLogEntry,
+		arg.BlockHeight,
+		arg.BlockHash,
+	)
+	return err
+}
+
+const upsertZombieChannel = `-- name: UpsertZombieChannel :exec
+INSERT INTO graph_zombie_channels (
+    scid, node_key_1, node_key_2
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (scid)
+    DO UPDATE SET
+        node_key_1 = EXCLUDED.node_key_1,
+        node_key_2 = EXCLUDED.node_key_2
+`
+
+type UpsertZombieChannelParams struct {
+	Scid     []byte
+	NodeKey1 []byte
+	NodeKey2 []byte
+}
+
+func (q *Queries) UpsertZombieChannel(ctx context.Context, arg UpsertZombieChannelParams) error {
+	_, err := q.db.ExecContext(ctx, upsertZombieChannel,
+		arg.Scid,
+		arg.NodeKey1,
+		arg.NodeKey2,
+	)
+	return err
+}
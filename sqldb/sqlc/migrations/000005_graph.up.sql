@@ -0,0 +1,175 @@
+-- graph_nodes contains all the nodes of the channel graph. A node that we only
+-- know from a channel announcement is a shell node, which has no last_update.
+CREATE TABLE IF NOT EXISTS graph_nodes (
+    -- The id of the node.
+    id BIGINT PRIMARY KEY,
+
+    -- The compressed public key that identifies the node.
+    pub_key BLOB NOT NULL UNIQUE,
+
+    -- The unix timestamp of the last node announcement of the node. It is
+    -- NULL if we never received a node announcement for the node.
+    last_update BIGINT,
+
+    -- The alias the node announced.
+    alias TEXT,
+
+    -- The color the node announced, as a 0xRRGGBB integer.
+    color INTEGER,
+
+    -- The signature of the node announcement.
+    signature BLOB,
+
+    -- Any unknown trailing data of the node announcement, which is needed to
+    -- verify its signature.
+    extra_opaque_data BLOB
+);
+
+CREATE INDEX IF NOT EXISTS graph_nodes_last_update_idx ON graph_nodes(last_update);
+
+-- graph_node_features contains the feature bits announced by each node.
+CREATE TABLE IF NOT EXISTS graph_node_features (
+    -- The node that announced the feature bit.
+    node_id BIGINT NOT NULL REFERENCES graph_nodes(id) ON DELETE CASCADE,
+
+    -- The feature bit.
+    feature_bit INTEGER NOT NULL,
+
+    PRIMARY KEY(node_id, feature_bit)
+);
+
+-- graph_node_addresses contains the addresses announced by each node.
+CREATE TABLE IF NOT EXISTS graph_node_addresses (
+    -- The node that announced the address.
+    node_id BIGINT NOT NULL REFERENCES graph_nodes(id) ON DELETE CASCADE,
+
+    -- The position of the address in the node announcement.
+    position INTEGER NOT NULL,
+
+    -- The serialized address.
+    address BLOB NOT NULL,
+
+    PRIMARY KEY(node_id, position)
+);
+
+-- graph_source_nodes contains our own node, which is the source of all path
+-- finding attempts.
+CREATE TABLE IF NOT EXISTS graph_source_nodes (
+    node_id BIGINT NOT NULL PRIMARY KEY REFERENCES graph_nodes(id) ON DELETE CASCADE
+);
+
+-- graph_channels contains the static information of all channels of the
+-- channel graph.
+CREATE TABLE IF NOT EXISTS graph_channels (
+    -- The id of the channel.
+    id BIGINT PRIMARY KEY,
+
+    -- The short channel id of the channel, serialized as an 8 byte big endian
+    -- integer so that channels sort by block height.
+    scid BLOB NOT NULL UNIQUE,
+
+    -- The hash of the genesis block of the chain the channel is on.
+    chain_hash BLOB NOT NULL,
+
+    -- The lexicographically smaller node of the channel.
+    node_id_1 BIGINT NOT NULL REFERENCES graph_nodes(id),
+
+    -- The lexicographically larger node of the channel.
+    node_id_2 BIGINT NOT NULL REFERENCES graph_nodes(id),
+
+    -- The funding outpoint of the channel, formatted as txid:index.
+    outpoint TEXT NOT NULL UNIQUE,
+
+    -- The capacity of the channel in satoshis.
+    capacity BIGINT NOT NULL,
+
+    -- The keys of the funding output of node 1 and node 2.
+    bitcoin_key_1 BLOB NOT NULL,
+    bitcoin_key_2 BLOB NOT NULL,
+
+    -- The raw feature vector of the channel announcement.
+    features BLOB NOT NULL,
+
+    -- The signatures of the channel announcement. They are NULL for channels
+    -- that weren't announced.
+    node_1_signature BLOB,
+    node_2_signature BLOB,
+    bitcoin_1_signature BLOB,
+    bitcoin_2_signature BLOB,
+
+    -- Any unknown trailing data of the channel announcement.
+    extra_opaque_data BLOB
+);
+
+CREATE INDEX IF NOT EXISTS graph_channels_node_id_1_idx ON graph_channels(node_id_1);
+CREATE INDEX IF NOT EXISTS graph_channels_node_id_2_idx ON graph_channels(node_id_2);
+
+-- graph_channel_policies contains the routing policies the nodes of each
+-- channel announced for forwarding over it.
+CREATE TABLE IF NOT EXISTS graph_channel_policies (
+    -- The channel the policy applies to.
+    channel_id BIGINT NOT NULL REFERENCES graph_channels(id) ON DELETE CASCADE,
+
+    -- The node that announced the policy.
+    node_id BIGINT NOT NULL REFERENCES graph_nodes(id),
+
+    -- The unix timestamp of the channel update.
+    last_update BIGINT NOT NULL,
+
+    -- The message and channel flags of the channel update.
+    message_flags SMALLINT NOT NULL,
+    channel_flags SMALLINT NOT NULL,
+
+    -- Whether the node disabled the channel.
+    disabled BOOLEAN NOT NULL,
+
+    -- The CLTV delta the node requires for forwarding.
+    timelock INTEGER NOT NULL,
+
+    -- The fees the node charges for forwarding.
+    base_fee_msat BIGINT NOT NULL,
+    fee_ppm BIGINT NOT NULL,
+
+    -- The HTLC amount limits of the node.
+    min_htlc_msat BIGINT NOT NULL,
+    max_htlc_msat BIGINT NOT NULL,
+
+    -- The signature of the channel update.
+    signature BLOB NOT NULL,
+
+    -- Any unknown trailing data of the channel update, such as inbound fees.
+    extra_opaque_data BLOB,
+
+    PRIMARY KEY(channel_id, node_id)
+);
+
+CREATE INDEX IF NOT EXISTS graph_channel_policies_last_update_idx ON graph_channel_policies(last_update);
+
+-- graph_zombie_channels contains the channels we consider zombies, which are
+-- not re-added to the graph unless they are resurrected by a fresh update.
+CREATE TABLE IF NOT EXISTS graph_zombie_channels (
+    -- The short channel id of the channel, in the same format as in the
+    -- graph_channels table.
+    scid BLOB PRIMARY KEY,
+
+    -- The keys of the nodes that may resurrect the channel. A key is all
+    -- zeros if the node can't resurrect the channel on its own.
+    node_key_1 BLOB NOT NULL,
+    node_key_2 BLOB NOT NULL
+);
+
+-- graph_closed_scids contains the short channel ids of channels we know to be
+-- closed.
+CREATE TABLE IF NOT EXISTS graph_closed_scids (
+    scid BLOB PRIMARY KEY
+);
+
+-- graph_prune_log contains the blocks that were used to prune closed channels
+-- from the graph.
+CREATE TABLE IF NOT EXISTS graph_prune_log (
+    -- The height of the block.
+    block_height BIGINT NOT NULL PRIMARY KEY,
+
+    -- The hash of the block.
+    block_hash BLOB NOT NULL
+);
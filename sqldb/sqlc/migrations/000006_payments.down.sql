@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS payment_route_hop_custom_records;
+DROP TABLE IF EXISTS payment_route_hops;
+DROP TABLE IF EXISTS payment_htlc_attempt_failures;
+DROP TABLE IF EXISTS payment_htlc_attempts;
+DROP TABLE IF EXISTS payment_sequences;
+
+DROP INDEX IF EXISTS payments_destination_idx;
+DROP INDEX IF EXISTS payments_status_idx;
+DROP INDEX IF EXISTS payments_created_at_idx;
+DROP INDEX IF EXISTS payments_payment_identifier_idx;
+DROP TABLE IF EXISTS payments;
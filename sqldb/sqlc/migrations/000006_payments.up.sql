@@ -0,0 +1,182 @@
+-- payments contains all payments that were sent by our node. Older versions
+-- of lnd allowed several payments to the same payment hash, those are kept as
+-- duplicates next to the payment that owns the payment identifier.
+CREATE TABLE IF NOT EXISTS payments (
+    -- The id of the payment.
+    id BIGINT PRIMARY KEY,
+
+    -- The sequence number of the payment, which orders the payments by their
+    -- creation and is used as the index offset when paginating.
+    sequence_num BIGINT NOT NULL UNIQUE,
+
+    -- The payment hash of the payment, or the set id for AMP payments.
+    payment_identifier BLOB NOT NULL,
+
+    -- Whether this is a legacy duplicate payment of the payment identifier.
+    duplicate BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- The amount we are paying in millisatoshis.
+    amount_msat BIGINT NOT NULL,
+
+    -- The time the payment was initiated.
+    created_at TIMESTAMP NOT NULL,
+
+    -- The full payment request, if any.
+    payment_request BLOB,
+
+    -- The reason the payment failed. It is NULL as long as the payment wasn't
+    -- failed.
+    failure_reason SMALLINT,
+
+    -- The current status of the payment, which is derived from its HTLC
+    -- attempts and failure reason and is only stored to be able to filter
+    -- payments by it.
+    status SMALLINT NOT NULL,
+
+    -- The public key of the final hop of the first HTLC attempt of the
+    -- payment. It is NULL until the first attempt is registered.
+    destination BLOB
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_payment_identifier_idx
+ON payments(payment_identifier) WHERE duplicate = FALSE;
+CREATE INDEX IF NOT EXISTS payments_created_at_idx ON payments(created_at);
+CREATE INDEX IF NOT EXISTS payments_status_idx ON payments(status);
+CREATE INDEX IF NOT EXISTS payments_destination_idx ON payments(destination);
+
+-- payment_sequences contains all sequences used for payments.
+CREATE TABLE IF NOT EXISTS payment_sequences (
+    name TEXT PRIMARY KEY,
+    current_value BIGINT NOT NULL
+);
+
+-- Initialize the sequence that hands out payment sequence numbers. It is kept
+-- separately so that sequence numbers are never reused, even if the latest
+-- payments are deleted.
+INSERT INTO payment_sequences(name, current_value) VALUES ('sequence_num', 0);
+
+-- payment_htlc_attempts contains the HTLC attempts that were made for each
+-- payment.
+CREATE TABLE IF NOT EXISTS payment_htlc_attempts (
+    -- The id of the HTLC attempt.
+    id BIGINT PRIMARY KEY,
+
+    -- The payment the attempt belongs to.
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+
+    -- The attempt id, which is unique for all attempts of a payment.
+    attempt_id BIGINT NOT NULL,
+
+    -- The ephemeral session key of the attempt.
+    session_key BLOB NOT NULL,
+
+    -- The time the attempt was made.
+    attempt_time TIMESTAMP NOT NULL,
+
+    -- The hash used by the attempt. It is NULL for older attempts, in which
+    -- case the payment identifier was used.
+    hash BLOB,
+
+    -- The total time lock of the route of the attempt.
+    route_total_time_lock BIGINT NOT NULL,
+
+    -- The total amount of the route of the attempt in millisatoshis,
+    -- including fees.
+    route_total_amount BIGINT NOT NULL,
+
+    -- The public key of the source of the route, which is our own node.
+    route_source_key BLOB NOT NULL,
+
+    -- The preimage the attempt was settled with. It is NULL as long as the
+    -- attempt wasn't settled.
+    settle_preimage BLOB,
+
+    -- The time the attempt was settled.
+    settle_time TIMESTAMP,
+
+    UNIQUE(payment_id, attempt_id)
+);
+
+-- payment_htlc_attempt_failures contains the failure of each failed HTLC
+-- attempt.
+CREATE TABLE IF NOT EXISTS payment_htlc_attempt_failures (
+    -- The HTLC attempt that failed.
+    htlc_attempt_id BIGINT NOT NULL PRIMARY KEY REFERENCES payment_htlc_attempts(id) ON DELETE CASCADE,
+
+    -- The time the attempt failed.
+    fail_time TIMESTAMP NOT NULL,
+
+    -- The reason the attempt failed.
+    reason SMALLINT NOT NULL,
+
+    -- The position in the route of the node that generated the failure
+    -- message, where zero is our own node.
+    failure_source_index BIGINT NOT NULL,
+
+    -- The encoded wire failure message, if any.
+    failure_msg BLOB
+);
+
+-- payment_route_hops contains the hops of the route of each HTLC attempt.
+CREATE TABLE IF NOT EXISTS payment_route_hops (
+    -- The HTLC attempt the hop belongs to.
+    htlc_attempt_id BIGINT NOT NULL REFERENCES payment_htlc_attempts(id) ON DELETE CASCADE,
+
+    -- The position of the hop in the route.
+    hop_index INTEGER NOT NULL,
+
+    -- The public key of the node of the hop.
+    pub_key BLOB NOT NULL,
+
+    -- The short channel id of the channel that is used to reach the hop,
+    -- serialized as an 8 byte big endian integer.
+    scid BLOB NOT NULL,
+
+    -- The time lock of the HTLC that is forwarded by the hop.
+    outgoing_time_lock BIGINT NOT NULL,
+
+    -- The amount the hop forwards in millisatoshis.
+    amt_to_forward BIGINT NOT NULL,
+
+    -- Whether the hop uses the legacy onion payload.
+    legacy_payload BOOLEAN NOT NULL,
+
+    -- The MPP record of the final hop.
+    mpp_payment_addr BLOB,
+    mpp_total_msat BIGINT,
+
+    -- The AMP record of the final hop.
+    amp_root_share BLOB,
+    amp_set_id BLOB,
+    amp_child_index BIGINT,
+
+    -- The route blinding fields of the hop.
+    encrypted_data BLOB,
+    blinding_point BLOB,
+    total_amt_msat BIGINT,
+
+    -- The payment metadata that is passed to the final hop.
+    metadata BLOB,
+
+    PRIMARY KEY(htlc_attempt_id, hop_index)
+);
+
+-- payment_route_hop_custom_records contains the custom records that are sent
+-- to each hop.
+CREATE TABLE IF NOT EXISTS payment_route_hop_custom_records (
+    -- The HTLC attempt of the hop.
+    htlc_attempt_id BIGINT NOT NULL,
+
+    -- The position of the hop in the route.
+    hop_index INTEGER NOT NULL,
+
+    -- The custom record type.
+    key BIGINT NOT NULL,
+
+    -- The custom record value.
+    value BLOB NOT NULL,
+
+    PRIMARY KEY(htlc_attempt_id, hop_index, key),
+
+    FOREIGN KEY(htlc_attempt_id, hop_index) REFERENCES payment_route_hops(htlc_attempt_id, hop_index) ON DELETE CASCADE
+);
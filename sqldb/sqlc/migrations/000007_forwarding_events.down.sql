@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS forwarding_events_outgoing_peer_idx;
+DROP INDEX IF EXISTS forwarding_events_incoming_peer_idx;
+DROP INDEX IF EXISTS forwarding_events_outgoing_chan_id_idx;
+DROP INDEX IF EXISTS forwarding_events_incoming_chan_id_idx;
+DROP INDEX IF EXISTS forwarding_events_timestamp_idx;
+DROP TABLE IF EXISTS forwarding_events;
@@ -0,0 +1,46 @@
+-- forwarding_events contains the forwarding history of our node. Each row is
+-- a payment circuit that was settled, which means that we earned the fee of
+-- the forward.
+CREATE TABLE IF NOT EXISTS forwarding_events (
+    -- The id of the forwarding event.
+    id BIGINT PRIMARY KEY,
+
+    -- The time the circuit was settled in nanoseconds since the unix epoch.
+    timestamp_ns BIGINT NOT NULL,
+
+    -- The short channel id of the incoming channel, serialized as an 8 byte
+    -- big endian integer.
+    incoming_chan_id BLOB NOT NULL,
+
+    -- The short channel id of the outgoing channel, serialized as an 8 byte
+    -- big endian integer.
+    outgoing_chan_id BLOB NOT NULL,
+
+    -- The public key of the peer of the incoming channel, if known.
+    incoming_peer BLOB,
+
+    -- The public key of the peer of the outgoing channel, if known.
+    outgoing_peer BLOB,
+
+    -- The amount of the incoming HTLC in millisatoshis.
+    amt_in_msat BIGINT NOT NULL,
+
+    -- The amount of the outgoing HTLC in millisatoshis.
+    amt_out_msat BIGINT NOT NULL,
+
+    -- The fee that was earned in millisatoshis, which is the difference
+    -- between the incoming and the outgoing amount. It is stored to be able
+    -- to filter and aggregate by it.
+    fee_msat BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS forwarding_events_timestamp_idx
+ON forwarding_events(timestamp_ns);
+CREATE INDEX IF NOT EXISTS forwarding_events_incoming_chan_id_idx
+ON forwarding_events(incoming_chan_id, timestamp_ns);
+CREATE INDEX IF NOT EXISTS forwarding_events_outgoing_chan_id_idx
+ON forwarding_events(outgoing_chan_id, timestamp_ns);
+CREATE INDEX IF NOT EXISTS forwarding_events_incoming_peer_idx
+ON forwarding_events(incoming_peer);
+CREATE INDEX IF NOT EXISTS forwarding_events_outgoing_peer_idx
+ON forwarding_events(outgoing_peer);
@@ -0,0 +1,3 @@
+-- rebalance marks circular payments to ourselves that move liquidity between
+-- our own channels, so that they can be told apart from user payments.
+ALTER TABLE payments ADD COLUMN rebalance BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE payment_route_hops DROP COLUMN trampoline_onion;
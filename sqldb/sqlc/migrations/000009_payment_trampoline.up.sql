@@ -0,0 +1,3 @@
+-- trampoline_onion holds the inner trampoline onion that is delivered to the
+-- final hop of a route when a payment is sent via a trampoline node.
+ALTER TABLE payment_route_hops ADD COLUMN trampoline_onion BLOB;
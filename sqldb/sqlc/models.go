@@ -28,6 +28,92 @@ type AmpSubInvoiceHtlc struct {
 	Preimage   []byte
 }
 
+type ForwardingEvent struct {
+	ID             int64
+	TimestampNs    int64
+	IncomingChanID []byte
+	OutgoingChanID []byte
+	IncomingPeer   []byte
+	OutgoingPeer   []byte
+	AmtInMsat      int64
+	AmtOutMsat     int64
+	FeeMsat        int64
+}
+
+type GraphChannel struct {
+	ID                int64
+	Scid              []byte
+	ChainHash         []byte
+	NodeID1           int64
+	NodeID2           int64
+	Outpoint          string
+	Capacity          int64
+	BitcoinKey1       []byte
+	BitcoinKey2       []byte
+	Features          []byte
+	Node1Signature    []byte
+	Node2Signature    []byte
+	Bitcoin1Signature []byte
+	Bitcoin2Signature []byte
+	ExtraOpaqueData   []byte
+}
+
+type GraphChannelPolicy struct {
+	ChannelID       int64
+	NodeID          int64
+	LastUpdate      int64
+	MessageFlags    int16
+	ChannelFlags    int16
+	Disabled        bool
+	Timelock        int32
+	BaseFeeMsat     int64
+	FeePpm          int64
+	MinHtlcMsat     int64
+	MaxHtlcMsat     int64
+	Signature       []byte
+	ExtraOpaqueData []byte
+}
+
+type GraphClosedScid struct {
+	Scid []byte
+}
+
+type GraphNode struct {
+	ID              int64
+	PubKey          []byte
+	LastUpdate      sql.NullInt64
+	Alias           sql.NullString
+	Color           sql.NullInt32
+	Signature       []byte
+	ExtraOpaqueData []byte
+}
+
+type GraphNodeAddress struct {
+	NodeID   int64
+	Position int32
+	Address  []byte
+}
+
+type GraphNodeFeature struct {
+	NodeID     int64
+	FeatureBit int32
+}
+
+type GraphPruneLog struct {
+	BlockHeight int64
+	BlockHash   []byte
+}
+
+type GraphSourceNode struct {
+	NodeID int64
+}
+
+type GraphZombieChannel struct {
+	Scid     []byte
+	NodeKey1 []byte
+	NodeKey2 []byte
+}
+
 type Invoice struct {
 	ID                 int64
 	Hash               []byte
@@ -91,3 +177,71 @@ type InvoiceSequence struct {
 	Name         string
 	CurrentValue int64
 }
+
+type Payment struct {
+	ID                int64
+	SequenceNum       int64
+	PaymentIdentifier []byte
+	Duplicate         bool
+	AmountMsat        int64
+	CreatedAt         time.Time
+	PaymentRequest    []byte
+	FailureReason     sql.NullInt16
+	Status            int16
+	Destination       []byte
+	Rebalance         bool
+}
+
+type PaymentHtlcAttempt struct {
+	ID                 int64
+	PaymentID          int64
+	AttemptID          int64
+	SessionKey         []byte
+	AttemptTime        time.Time
+	Hash               []byte
+	RouteTotalTimeLock int64
+	RouteTotalAmount   int64
+	RouteSourceKey     []byte
+	SettlePreimage     []byte
+	SettleTime         sql.NullTime
+}
+
+type PaymentHtlcAttemptFailure struct {
+	HtlcAttemptID      int64
+	FailTime           time.Time
+	Reason             int16
+	FailureSourceIndex int64
+	FailureMsg         []byte
+}
+
+type PaymentRouteHop struct {
+	HtlcAttemptID    int64
+	HopIndex         int32
+	PubKey           []byte
+	Scid             []byte
+	OutgoingTimeLock int64
+	AmtToForward     int64
+	LegacyPayload    bool
+	MppPaymentAddr   []byte
+	MppTotalMsat     sql.NullInt64
+	AmpRootShare     []byte
+	AmpSetID         []byte
+	AmpChildIndex    sql.NullInt64
+	EncryptedData    []byte
+	BlindingPoint    []byte
+	TotalAmtMsat     sql.NullInt64
+	Metadata         []byte
+	TrampolineOnion  []byte
+}
+
+type PaymentRouteHopCustomRecord struct {
+	HtlcAttemptID int64
+	HopIndex      int32
+	Key           int64
+	Value         []byte
+}
+
+type PaymentSequence struct {
+	Name         string
+	CurrentValue int64
+}
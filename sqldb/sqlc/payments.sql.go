@@ -0,0 +1,722 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: payments.sql
+
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const countPayments = `-- name: CountPayments :one
+SELECT COUNT(*)
+FROM payments
+`
+
+func (q *Queries) CountPayments(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPayments)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteAllPayments = `-- name: DeleteAllPayments :exec
+DELETE FROM payments
+`
+
+func (q *Queries) DeleteAllPayments(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllPayments)
+	return err
+}
+
+const deleteFailedHTLCAttempts = `-- name: DeleteFailedHTLCAttempts :exec
+DELETE FROM payment_htlc_attempts
+WHERE payment_id = $1 AND id IN (
+    SELECT htlc_attempt_id
+    FROM payment_htlc_attempt_failures
+)
+`
+
+func (q *Queries) DeleteFailedHTLCAttempts(ctx context.Context, paymentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteFailedHTLCAttempts, paymentID)
+	return err
+}
+
+const deleteFailedHTLCAttemptsByStatus = `-- name: DeleteFailedHTLCAttemptsByStatus :exec
+DELETE FROM payment_htlc_attempts
+WHERE id IN (
+    SELECT htlc_attempt_id
+    FROM payment_htlc_attempt_failures
+) AND payment_id IN (
+    SELECT p.id
+    FROM payments p
+    WHERE p.status = $1 AND p.duplicate = FALSE
+)
+`
+
+func (q *Queries) DeleteFailedHTLCAttemptsByStatus(ctx context.Context, status int16) error {
+	_, err := q.db.ExecContext(ctx, deleteFailedHTLCAttemptsByStatus, status)
+	return err
+}
+
+const deletePaymentByID = `-- name: DeletePaymentByID :exec
+DELETE FROM payments
+WHERE id = $1
+`
+
+func (q *Queries) DeletePaymentByID(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deletePaymentByID, id)
+	return err
+}
+
+const deletePaymentsByIdentifier = `-- name: DeletePaymentsByIdentifier :exec
+DELETE FROM payments
+WHERE payment_identifier = $1
+`
+
+func (q *Queries) DeletePaymentsByIdentifier(ctx context.Context, paymentIdentifier []byte) error {
+	_, err := q.db.ExecContext(ctx, deletePaymentsByIdentifier, paymentIdentifier)
+	return err
+}
+
+const deletePaymentsByStatus = `-- name: DeletePaymentsByStatus :exec
+DELETE FROM payments
+WHERE payment_identifier IN (
+    SELECT p.payment_identifier
+    FROM payments p
+    WHERE p.status = $1 AND p.duplicate = FALSE
+)
+`
+
+func (q *Queries) DeletePaymentsByStatus(ctx context.Context, status int16) error {
+	_, err := q.db.ExecContext(ctx, deletePaymentsByStatus, status)
+	return err
+}
+
+const fetchHTLCAttemptFailures = `-- name: FetchHTLCAttemptFailures :many
+SELECT f.htlc_attempt_id, f.fail_time, f.reason, f.failure_source_index, f.failure_msg
+FROM payment_htlc_attempt_failures f
+JOIN payment_htlc_attempts a ON a.id = f.htlc_attempt_id
+WHERE a.payment_id = $1
+`
+
+func (q *Queries) FetchHTLCAttemptFailures(ctx context.Context, paymentID int64) ([]PaymentHtlcAttemptFailure, error) {
+	rows, err := q.db.QueryContext(ctx, fetchHTLCAttemptFailures, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentHtlcAttemptFailure
+	for rows.Next() {
+		var i PaymentHtlcAttemptFailure
+		if err := rows.Scan(
+			&i.HtlcAttemptID,
+			&i.FailTime,
+			&i.Reason,
+			&i.FailureSourceIndex,
+			&i.FailureMsg,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchHTLCAttempts = `-- name: FetchHTLCAttempts :many
+SELECT id, payment_id, attempt_id, session_key, attempt_time, hash, route_total_time_lock, route_total_amount, route_source_key, settle_preimage, settle_time
+FROM payment_htlc_attempts
+WHERE payment_id = $1
+ORDER BY attempt_id
+`
+
+func (q *Queries) FetchHTLCAttempts(ctx context.Context, paymentID int64) ([]PaymentHtlcAttempt, error) {
+	rows, err := q.db.QueryContext(ctx, fetchHTLCAttempts, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentHtlcAttempt
+	for rows.Next() {
+		var i PaymentHtlcAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.PaymentID,
+			&i.AttemptID,
+			&i.SessionKey,
+			&i.AttemptTime,
+			&i.Hash,
+			&i.RouteTotalTimeLock,
+			&i.RouteTotalAmount,
+			&i.RouteSourceKey,
+			&i.SettlePreimage,
+			&i.SettleTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchPayment = `-- name: FetchPayment :one
+SELECT id, sequence_num, payment_identifier, duplicate, amount_msat, created_at, payment_request, failure_reason, status, destination, rebalance
+FROM payments
+WHERE payment_identifier = $1 AND duplicate = FALSE
+`
+
+func (q *Queries) FetchPayment(ctx context.Context, paymentIdentifier []byte) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, fetchPayment, paymentIdentifier)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.SequenceNum,
+		&i.PaymentIdentifier,
+		&i.Duplicate,
+		&i.AmountMsat,
+		&i.CreatedAt,
+		&i.PaymentRequest,
+		&i.FailureReason,
+		&i.Status,
+		&i.Destination,
+		&i.Rebalance,
+	)
+	return i, err
+}
+
+const fetchPaymentsByStatus = `-- name: FetchPaymentsByStatus :many
+SELECT id, sequence_num, payment_identifier, duplicate, amount_msat, created_at, payment_request, failure_reason, status, destination, rebalance
+FROM payments
+WHERE status = $1 AND duplicate = FALSE
+ORDER BY sequence_num
+`
+
+func (q *Queries) FetchPaymentsByStatus(ctx context.Context, status int16) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, fetchPaymentsByStatus, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payment
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.SequenceNum,
+			&i.PaymentIdentifier,
+			&i.Duplicate,
+			&i.AmountMsat,
+			&i.CreatedAt,
+			&i.PaymentRequest,
+			&i.FailureReason,
+			&i.Status,
+			&i.Destination,
+			&i.Rebalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchRouteHopCustomRecords = `-- name: FetchRouteHopCustomRecords :many
+SELECT r.htlc_attempt_id, r.hop_index, r.key, r.value
+FROM payment_route_hop_custom_records r
+JOIN payment_htlc_attempts a ON a.id = r.htlc_attempt_id
+WHERE a.payment_id = $1
+`
+
+func (q *Queries) FetchRouteHopCustomRecords(ctx context.Context, paymentID int64) ([]PaymentRouteHopCustomRecord, error) {
+	rows, err := q.db.QueryContext(ctx, fetchRouteHopCustomRecords, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentRouteHopCustomRecord
+	for rows.Next() {
+		var i PaymentRouteHopCustomRecord
+		if err := rows.Scan(
+			&i.HtlcAttemptID,
+			&i.HopIndex,
+			&i.Key,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchRouteHops = `-- name: FetchRouteHops :many
+SELECT h.htlc_attempt_id, h.hop_index, h.pub_key, h.scid, h.outgoing_time_lock, h.amt_to_forward, h.legacy_payload, h.mpp_payment_addr, h.mpp_total_msat, h.amp_root_share, h.amp_set_id, h.amp_child_index, h.encrypted_data, h.blinding_point, h.total_amt_msat, h.metadata, h.trampoline_onion
+FROM payment_route_hops h
+JOIN payment_htlc_attempts a ON a.id = h.htlc_attempt_id
+WHERE a.payment_id = $1
+ORDER BY h.htlc_attempt_id, h.hop_index
+`
+
+func (q *Queries) FetchRouteHops(ctx context.Context, paymentID int64) ([]PaymentRouteHop, error) {
+	rows, err := q.db.QueryContext(ctx, fetchRouteHops, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PaymentRouteHop
+	for rows.Next() {
+		var i PaymentRouteHop
+		if err := rows.Scan(
+			&i.HtlcAttemptID,
+			&i.HopIndex,
+			&i.PubKey,
+			&i.Scid,
+			&i.OutgoingTimeLock,
+			&i.AmtToForward,
+			&i.LegacyPayload,
+			&i.MppPaymentAddr,
+			&i.MppTotalMsat,
+			&i.AmpRootShare,
+			&i.AmpSetID,
+			&i.AmpChildIndex,
+			&i.EncryptedData,
+			&i.BlindingPoint,
+			&i.TotalAmtMsat,
+			&i.Metadata,
+			&i.TrampolineOnion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const filterPayments = `-- name: FilterPayments :many
+SELECT id, sequence_num, payment_identifier, duplicate, amount_msat, created_at, payment_request, failure_reason, status, destination, rebalance
+FROM payments
+WHERE (
+    sequence_num > $1 OR
+    $1 IS NULL
+) AND (
+    sequence_num < $2 OR
+    $2 IS NULL
+) AND (
+    status = $3 OR
+    $3 IS NULL
+) AND (
+    created_at >= $4 OR
+    $4 IS NULL
+) AND (
+    created_at < $5 OR
+    $5 IS NULL
+)
+ORDER BY
+CASE
+    WHEN $6 = FALSE OR $6 IS NULL THEN sequence_num
+    ELSE NULL
+    END ASC,
+CASE
+    WHEN $6 = TRUE THEN sequence_num
+    ELSE NULL
+END DESC
+LIMIT $7
+`
+
+type FilterPaymentsParams struct {
+	IndexOffsetAfter  sql.NullInt64
+	IndexOffsetBefore sql.NullInt64
+	Status            sql.NullInt16
+	CreatedAfter      sql.NullTime
+	CreatedBefore     sql.NullTime
+	Reverse           interface{}
+	NumLimit          int32
+}
+
+func (q *Queries) FilterPayments(ctx context.Context, arg FilterPaymentsParams) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, filterPayments,
+		arg.IndexOffsetAfter,
+		arg.IndexOffsetBefore,
+		arg.Status,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Reverse,
+		arg.NumLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payment
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.ID,
+			&i.SequenceNum,
+			&i.PaymentIdentifier,
+			&i.Duplicate,
+			&i.AmountMsat,
+			&i.CreatedAt,
+			&i.PaymentRequest,
+			&i.FailureReason,
+			&i.Status,
+			&i.Destination,
+			&i.Rebalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHTLCAttemptID = `-- name: GetHTLCAttemptID :one
+SELECT id
+FROM payment_htlc_attempts
+WHERE payment_id = $1 AND attempt_id = $2
+`
+
+type GetHTLCAttemptIDParams struct {
+	PaymentID int64
+	AttemptID int64
+}
+
+func (q *Queries) GetHTLCAttemptID(ctx context.Context, arg GetHTLCAttemptIDParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getHTLCAttemptID,
+		arg.PaymentID,
+		arg.AttemptID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertHTLCAttempt = `-- name: InsertHTLCAttempt :one
+INSERT INTO payment_htlc_attempts (
+    payment_id, attempt_id, session_key, attempt_time, hash,
+    route_total_time_lock, route_total_amount, route_source_key,
+    settle_preimage, settle_time
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id
+`
+
+type InsertHTLCAttemptParams struct {
+	PaymentID          int64
+	AttemptID          int64
+	SessionKey         []byte
+	AttemptTime        time.Time
+	Hash               []byte
+	RouteTotalTimeLock int64
+	RouteTotalAmount   int64
+	RouteSourceKey     []byte
+	SettlePreimage     []byte
+	SettleTime         sql.NullTime
+}
+
+func (q *Queries) InsertHTLCAttempt(ctx context.Context, arg InsertHTLCAttemptParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertHTLCAttempt,
+		arg.PaymentID,
+		arg.AttemptID,
+		arg.SessionKey,
+		arg.AttemptTime,
+		arg.Hash,
+		arg.RouteTotalTimeLock,
+		arg.RouteTotalAmount,
+		arg.RouteSourceKey,
+		arg.SettlePreimage,
+		arg.SettleTime,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertHTLCAttemptFailure = `-- name: InsertHTLCAttemptFailure :exec
+INSERT INTO payment_htlc_attempt_failures (
+    htlc_attempt_id, fail_time, reason, failure_source_index, failure_msg
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+`
+
+type InsertHTLCAttemptFailureParams struct {
+	HtlcAttemptID      int64
+	FailTime           time.Time
+	Reason             int16
+	FailureSourceIndex int64
+	FailureMsg         []byte
+}
+
+func (q *Queries) InsertHTLCAttemptFailure(ctx context.Context, arg InsertHTLCAttemptFailureParams) error {
+	_, err := q.db.ExecContext(ctx, insertHTLCAttemptFailure,
+		arg.HtlcAttemptID,
+		arg.FailTime,
+		arg.Reason,
+		arg.FailureSourceIndex,
+		arg.FailureMsg,
+	)
+	return err
+}
+
+const insertPayment = `-- name: InsertPayment :one
+INSERT INTO payments (
+    sequence_num, payment_identifier, duplicate, amount_msat, created_at,
+    payment_request, failure_reason, status, destination, rebalance
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id
+`
+
+type InsertPaymentParams struct {
+	SequenceNum       int64
+	PaymentIdentifier []byte
+	Duplicate         bool
+	AmountMsat        int64
+	CreatedAt         time.Time
+	PaymentRequest    []byte
+	FailureReason     sql.NullInt16
+	Status            int16
+	Destination       []byte
+	Rebalance         bool
+}
+
+func (q *Queries) InsertPayment(ctx context.Context, arg InsertPaymentParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertPayment,
+		arg.SequenceNum,
+		arg.PaymentIdentifier,
+		arg.Duplicate,
+		arg.AmountMsat,
+		arg.CreatedAt,
+		arg.PaymentRequest,
+		arg.FailureReason,
+		arg.Status,
+		arg.Destination,
+		arg.Rebalance,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertRouteHop = `-- name: InsertRouteHop :exec
+INSERT INTO payment_route_hops (
+    htlc_attempt_id, hop_index, pub_key, scid, outgoing_time_lock,
+    amt_to_forward, legacy_payload, mpp_payment_addr, mpp_total_msat,
+    amp_root_share, amp_set_id, amp_child_index, encrypted_data,
+    blinding_point, total_amt_msat, metadata, trampoline_onion
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+    $17
+)
+`
+
+type InsertRouteHopParams struct {
+	HtlcAttemptID    int64
+	HopIndex         int32
+	PubKey           []byte
+	Scid             []byte
+	OutgoingTimeLock int64
+	AmtToForward     int64
+	LegacyPayload    bool
+	MppPaymentAddr   []byte
+	MppTotalMsat     sql.NullInt64
+	AmpRootShare     []byte
+	AmpSetID         []byte
+	AmpChildIndex    sql.NullInt64
+	EncryptedData    []byte
+	BlindingPoint    []byte
+	TotalAmtMsat     sql.NullInt64
+	Metadata         []byte
+	TrampolineOnion  []byte
+}
+
+func (q *Queries) InsertRouteHop(ctx context.Context, arg InsertRouteHopParams) error {
+	_, err := q.db.ExecContext(ctx, insertRouteHop,
+		arg.HtlcAttemptID,
+		arg.HopIndex,
+		arg.PubKey,
+		arg.Scid,
+		arg.OutgoingTimeLock,
+		arg.AmtToForward,
+		arg.LegacyPayload,
+		arg.MppPaymentAddr,
+		arg.MppTotalMsat,
+		arg.AmpRootShare,
+		arg.AmpSetID,
+		arg.AmpChildIndex,
+		arg.EncryptedData,
+		arg.BlindingPoint,
+		arg.TotalAmtMsat,
+		arg.Metadata,
+		arg.TrampolineOnion,
+	)
+	return err
+}
+
+const insertRouteHopCustomRecord = `-- name: InsertRouteHopCustomRecord :exec
+INSERT INTO payment_route_hop_custom_records (
+    htlc_attempt_id, hop_index, key, value
+) VALUES (
+    $1, $2, $3, $4
+)
+`
+
+type InsertRouteHopCustomRecordParams struct {
+	HtlcAttemptID int64
+	HopIndex      int32
+	Key           int64
+	Value         []byte
+}
+
+func (q *Queries) InsertRouteHopCustomRecord(ctx context.Context, arg InsertRouteHopCustomRecordParams) error {
+	_, err := q.db.ExecContext(ctx, insertRouteHopCustomRecord,
+		arg.HtlcAttemptID,
+		arg.HopIndex,
+		arg.Key,
+		arg.Value,
+	)
+	return err
+}
+
+const nextPaymentSequenceNum = `-- name: NextPaymentSequenceNum :one
+UPDATE payment_sequences SET current_value = current_value + 1
+WHERE name = 'sequence_num'
+RETURNING current_value
+`
+
+func (q *Queries) NextPaymentSequenceNum(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextPaymentSequenceNum)
+	var currentValue int64
+	err := row.Scan(&currentValue)
+	return currentValue, err
+}
+
+const setPaymentDestination = `-- name: SetPaymentDestination :exec
+UPDATE payments
+SET destination = $2
+WHERE id = $1 AND destination IS NULL
+`
+
+type SetPaymentDestinationParams struct {
+	ID          int64
+	Destination []byte
+}
+
+func (q *Queries) SetPaymentDestination(ctx context.Context, arg SetPaymentDestinationParams) error {
+	_, err := q.db.ExecContext(ctx, setPaymentDestination,
+		arg.ID,
+		arg.Destination,
+	)
+	return err
+}
+
+const setPaymentSequenceNum = `-- name: SetPaymentSequenceNum :exec
+UPDATE payment_sequences SET current_value = $1
+WHERE name = 'sequence_num' AND current_value < $1
+`
+
+func (q *Queries) SetPaymentSequenceNum(ctx context.Context, currentValue int64) error {
+	_, err := q.db.ExecContext(ctx, setPaymentSequenceNum, currentValue)
+	return err
+}
+
+const settleHTLCAttempt = `-- name: SettleHTLCAttempt :exec
+UPDATE payment_htlc_attempts
+SET settle_preimage = $2, settle_time = $3
+WHERE id = $1
+`
+
+type SettleHTLCAttemptParams struct {
+	ID             int64
+	SettlePreimage []byte
+	SettleTime     sql.NullTime
+}
+
+func (q *Queries) SettleHTLCAttempt(ctx context.Context, arg SettleHTLCAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, settleHTLCAttempt,
+		arg.ID,
+		arg.SettlePreimage,
+		arg.SettleTime,
+	)
+	return err
+}
+
+const updatePaymentFailureReason = `-- name: UpdatePaymentFailureReason :exec
+UPDATE payments
+SET failure_reason = $2
+WHERE id = $1
+`
+
+type UpdatePaymentFailureReasonParams struct {
+	ID            int64
+	FailureReason sql.NullInt16
+}
+
+func (q *Queries) UpdatePaymentFailureReason(ctx context.Context, arg UpdatePaymentFailureReasonParams) error {
+	_, err := q.db.ExecContext(ctx, updatePaymentFailureReason,
+		arg.ID,
+		arg.FailureReason,
+	)
+	return err
+}
+
+const updatePaymentStatus = `-- name: UpdatePaymentStatus :exec
+UPDATE payments
+SET status = $2
+WHERE id = $1
+`
+
+type UpdatePaymentStatusParams struct {
+	ID     int64
+	Status int16
+}
+
+func (q *Queries) UpdatePaymentStatus(ctx context.Context, arg UpdatePaymentStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updatePaymentStatus,
+		arg.ID,
+		arg.Status,
+	)
+	return err
+}
@@ -10,13 +10,52 @@ import (
 )
 
 type Querier interface {
+	AddSourceNode(ctx context.Context, nodeID int64) error
+	CountPayments(ctx context.Context) (int64, error)
+	CountZombieChannels(ctx context.Context) (int64, error)
+	CreateChannel(ctx context.Context, arg CreateChannelParams) (int64, error)
+	DeleteAllChannels(ctx context.Context) error
+	DeleteAllClosedSCIDs(ctx context.Context) error
+	DeleteAllNodes(ctx context.Context) error
+	DeleteAllPayments(ctx context.Context) error
+	DeleteAllPruneLogEntries(ctx context.Context) error
+	DeleteAllZombieChannels(ctx context.Context) error
 	DeleteCanceledInvoices(ctx context.Context) (sql.Result, error)
+	DeleteChannel(ctx context.Context, id int64) error
+	DeleteFailedHTLCAttempts(ctx context.Context, paymentID int64) error
+	DeleteFailedHTLCAttemptsByStatus(ctx context.Context, status int16) error
 	DeleteInvoice(ctx context.Context, arg DeleteInvoiceParams) (sql.Result, error)
+	DeleteNodeAddresses(ctx context.Context, nodeID int64) error
+	DeleteNodeByPubKey(ctx context.Context, pubKey []byte) (sql.Result, error)
+	DeleteNodeFeatures(ctx context.Context, nodeID int64) error
+	DeletePaymentByID(ctx context.Context, id int64) error
+	DeletePaymentsByIdentifier(ctx context.Context, paymentIdentifier []byte) error
+	DeletePaymentsByStatus(ctx context.Context, status int16) error
+	DeletePruneLogEntriesFromHeight(ctx context.Context, blockHeight int64) error
+	DeleteSourceNodes(ctx context.Context) error
+	DeleteUnconnectedNodes(ctx context.Context) ([][]byte, error)
+	DeleteZombieChannel(ctx context.Context, scid []byte) (sql.Result, error)
 	FetchAMPSubInvoiceHTLCs(ctx context.Context, arg FetchAMPSubInvoiceHTLCsParams) ([]FetchAMPSubInvoiceHTLCsRow, error)
 	FetchAMPSubInvoices(ctx context.Context, arg FetchAMPSubInvoicesParams) ([]AmpSubInvoice, error)
+	FetchHTLCAttemptFailures(ctx context.Context, paymentID int64) ([]PaymentHtlcAttemptFailure, error)
+	FetchHTLCAttempts(ctx context.Context, paymentID int64) ([]PaymentHtlcAttempt, error)
+	FetchPayment(ctx context.Context, paymentIdentifier []byte) (Payment, error)
+	FetchPaymentsByStatus(ctx context.Context, status int16) ([]Payment, error)
+	FetchRouteHopCustomRecords(ctx context.Context, paymentID int64) ([]PaymentRouteHopCustomRecord, error)
+	FetchRouteHops(ctx context.Context, paymentID int64) ([]PaymentRouteHop, error)
 	FetchSettledAMPSubInvoices(ctx context.Context, arg FetchSettledAMPSubInvoicesParams) ([]FetchSettledAMPSubInvoicesRow, error)
+	FilterForwardingEvents(ctx context.Context, arg FilterForwardingEventsParams) ([]ForwardingEvent, error)
 	FilterInvoices(ctx context.Context, arg FilterInvoicesParams) ([]Invoice, error)
+	FilterPayments(ctx context.Context, arg FilterPaymentsParams) ([]Payment, error)
+	ForwardingFeesPerChannelDay(ctx context.Context, arg ForwardingFeesPerChannelDayParams) ([]ForwardingFeesPerChannelDayRow, error)
 	GetAMPInvoiceID(ctx context.Context, setID []byte) (int64, error)
+	GetChannelByOutpoint(ctx context.Context, outpoint string) (GetChannelByOutpointRow, error)
+	GetChannelBySCID(ctx context.Context, scid []byte) (GetChannelBySCIDRow, error)
+	GetChannelPolicies(ctx context.Context, channelID int64) ([]GraphChannelPolicy, error)
+	GetChannelsByPolicyLastUpdateRange(ctx context.Context, arg GetChannelsByPolicyLastUpdateRangeParams) ([]GetChannelsByPolicyLastUpdateRangeRow, error)
+	GetChannelsBySCIDRange(ctx context.Context, arg GetChannelsBySCIDRangeParams) ([]GetChannelsBySCIDRangeRow, error)
+	GetDisabledChannelSCIDs(ctx context.Context) ([][]byte, error)
+	GetHTLCAttemptID(ctx context.Context, arg GetHTLCAttemptIDParams) (int64, error)
 	// This method may return more than one invoice if filter using multiple fields
 	// from different invoices. It is the caller's responsibility to ensure that
 	// we bubble up an error in those cases.
@@ -24,25 +63,60 @@ type Querier interface {
 	GetInvoiceFeatures(ctx context.Context, invoiceID int64) ([]InvoiceFeature, error)
 	GetInvoiceHTLCCustomRecords(ctx context.Context, invoiceID int64) ([]GetInvoiceHTLCCustomRecordsRow, error)
 	GetInvoiceHTLCs(ctx context.Context, invoiceID int64) ([]InvoiceHtlc, error)
+	GetNodeAddresses(ctx context.Context, nodeID int64) ([]GraphNodeAddress, error)
+	GetNodeByPubKey(ctx context.Context, pubKey []byte) (GraphNode, error)
+	GetNodeFeatures(ctx context.Context, nodeID int64) ([]GraphNodeFeature, error)
+	GetNodeIDByPubKey(ctx context.Context, pubKey []byte) (int64, error)
+	GetNodesByLastUpdateRange(ctx context.Context, arg GetNodesByLastUpdateRangeParams) ([]GraphNode, error)
+	GetPruneTip(ctx context.Context) (GraphPruneLog, error)
+	GetPublicChannelUpdateTimesBySCIDRange(ctx context.Context, arg GetPublicChannelUpdateTimesBySCIDRangeParams) ([]GetPublicChannelUpdateTimesBySCIDRangeRow, error)
+	GetSourceNodes(ctx context.Context) ([][]byte, error)
+	GetZombieChannel(ctx context.Context, scid []byte) (GraphZombieChannel, error)
+	HighestSCID(ctx context.Context) ([]byte, error)
 	InsertAMPSubInvoiceHTLC(ctx context.Context, arg InsertAMPSubInvoiceHTLCParams) error
+	InsertClosedSCID(ctx context.Context, scid []byte) error
+	InsertForwardingEvent(ctx context.Context, arg InsertForwardingEventParams) error
+	InsertHTLCAttempt(ctx context.Context, arg InsertHTLCAttemptParams) (int64, error)
+	InsertHTLCAttemptFailure(ctx context.Context, arg InsertHTLCAttemptFailureParams) error
 	InsertInvoice(ctx context.Context, arg InsertInvoiceParams) (int64, error)
 	InsertInvoiceFeature(ctx context.Context, arg InsertInvoiceFeatureParams) error
 	InsertInvoiceHTLC(ctx context.Context, arg InsertInvoiceHTLCParams) (int64, error)
 	InsertInvoiceHTLCCustomRecord(ctx context.Context, arg InsertInvoiceHTLCCustomRecordParams) error
+	InsertNodeAddress(ctx context.Context, arg InsertNodeAddressParams) error
+	InsertNodeFeature(ctx context.Context, arg InsertNodeFeatureParams) error
+	InsertPayment(ctx context.Context, arg InsertPaymentParams) (int64, error)
+	InsertRouteHop(ctx context.Context, arg InsertRouteHopParams) error
+	InsertRouteHopCustomRecord(ctx context.Context, arg InsertRouteHopCustomRecordParams) error
+	IsClosedSCID(ctx context.Context, scid []byte) (bool, error)
+	ListChannelPoliciesByChannelIDRange(ctx context.Context, arg ListChannelPoliciesByChannelIDRangeParams) ([]GraphChannelPolicy, error)
+	ListChannelsByNodeID(ctx context.Context, nodeID int64) ([]ListChannelsByNodeIDRow, error)
+	ListChannelsPaginated(ctx context.Context, arg ListChannelsPaginatedParams) ([]ListChannelsPaginatedRow, error)
+	ListNodesPaginated(ctx context.Context, arg ListNodesPaginatedParams) ([]GraphNode, error)
 	NextInvoiceSettleIndex(ctx context.Context) (int64, error)
+	NextPaymentSequenceNum(ctx context.Context) (int64, error)
 	OnAMPSubInvoiceCanceled(ctx context.Context, arg OnAMPSubInvoiceCanceledParams) error
 	OnAMPSubInvoiceCreated(ctx context.Context, arg OnAMPSubInvoiceCreatedParams) error
 	OnAMPSubInvoiceSettled(ctx context.Context, arg OnAMPSubInvoiceSettledParams) error
 	OnInvoiceCanceled(ctx context.Context, arg OnInvoiceCanceledParams) error
 	OnInvoiceCreated(ctx context.Context, arg OnInvoiceCreatedParams) error
 	OnInvoiceSettled(ctx context.Context, arg OnInvoiceSettledParams) error
+	SetPaymentDestination(ctx context.Context, arg SetPaymentDestinationParams) error
+	SetPaymentSequenceNum(ctx context.Context, currentValue int64) error
+	SettleHTLCAttempt(ctx context.Context, arg SettleHTLCAttemptParams) error
 	UpdateAMPSubInvoiceHTLCPreimage(ctx context.Context, arg UpdateAMPSubInvoiceHTLCPreimageParams) (sql.Result, error)
 	UpdateAMPSubInvoiceState(ctx context.Context, arg UpdateAMPSubInvoiceStateParams) error
+	UpdateChannel(ctx context.Context, arg UpdateChannelParams) error
 	UpdateInvoiceAmountPaid(ctx context.Context, arg UpdateInvoiceAmountPaidParams) (sql.Result, error)
 	UpdateInvoiceHTLC(ctx context.Context, arg UpdateInvoiceHTLCParams) error
 	UpdateInvoiceHTLCs(ctx context.Context, arg UpdateInvoiceHTLCsParams) error
 	UpdateInvoiceState(ctx context.Context, arg UpdateInvoiceStateParams) (sql.Result, error)
+	UpdatePaymentFailureReason(ctx context.Context, arg UpdatePaymentFailureReasonParams) error
+	UpdatePaymentStatus(ctx context.Context, arg UpdatePaymentStatusParams) error
 	UpsertAMPSubInvoice(ctx context.Context, arg UpsertAMPSubInvoiceParams) (sql.Result, error)
+	UpsertChannelPolicy(ctx context.Context, arg UpsertChannelPolicyParams) error
+	UpsertNode(ctx context.Context, arg UpsertNodeParams) (int64, error)
+	UpsertPruneLogEntry(ctx context.Context, arg UpsertPruneLogEntryParams) error
+	UpsertZombieChannel(ctx context.Context, arg UpsertZombieChannelParams) error
 }
 
 var _ Querier = (*Queries)(nil)
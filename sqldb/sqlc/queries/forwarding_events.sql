@@ -0,0 +1,71 @@
+-- name: InsertForwardingEvent :exec
+INSERT INTO forwarding_events (
+    timestamp_ns, incoming_chan_id, outgoing_chan_id, incoming_peer,
+    outgoing_peer, amt_in_msat, amt_out_msat, fee_msat
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: FilterForwardingEvents :many
+SELECT *
+FROM forwarding_events
+WHERE timestamp_ns >= @start_time AND timestamp_ns <= @end_time AND (
+    incoming_chan_id = sqlc.narg('incoming_chan_id') OR
+    sqlc.narg('incoming_chan_id') IS NULL
+) AND (
+    outgoing_chan_id = sqlc.narg('outgoing_chan_id') OR
+    sqlc.narg('outgoing_chan_id') IS NULL
+) AND (
+    incoming_peer = sqlc.narg('peer') OR
+    outgoing_peer = sqlc.narg('peer') OR
+    sqlc.narg('peer') IS NULL
+) AND (
+    amt_out_msat >= sqlc.narg('min_amt_msat') OR
+    sqlc.narg('min_amt_msat') IS NULL
+) AND (
+    amt_out_msat <= sqlc.narg('max_amt_msat') OR
+    sqlc.narg('max_amt_msat') IS NULL
+) AND (
+    fee_msat >= sqlc.narg('min_fee_msat') OR
+    sqlc.narg('min_fee_msat') IS NULL
+) AND (
+    fee_msat <= sqlc.narg('max_fee_msat') OR
+    sqlc.narg('max_fee_msat') IS NULL
+)
+ORDER BY timestamp_ns, id
+LIMIT @num_limit OFFSET @num_offset;
+
+-- name: ForwardingFeesPerChannelDay :many
+SELECT
+    outgoing_chan_id,
+    CAST(timestamp_ns / 86400000000000 AS BIGINT) AS day,
+    COUNT(*) AS num_forwards,
+    CAST(SUM(amt_in_msat) AS BIGINT) AS total_amt_in_msat,
+    CAST(SUM(amt_out_msat) AS BIGINT) AS total_amt_out_msat,
+    CAST(SUM(fee_msat) AS BIGINT) AS total_fee_msat
+FROM forwarding_events
+WHERE timestamp_ns >= @start_time AND timestamp_ns <= @end_time AND (
+    incoming_chan_id = sqlc.narg('incoming_chan_id') OR
+    sqlc.narg('incoming_chan_id') IS NULL
+) AND (
+    outgoing_chan_id = sqlc.narg('outgoing_chan_id') OR
+    sqlc.narg('outgoing_chan_id') IS NULL
+) AND (
+    incoming_peer = sqlc.narg('peer') OR
+    outgoing_peer = sqlc.narg('peer') OR
+    sqlc.narg('peer') IS NULL
+) AND (
+    amt_out_msat >= sqlc.narg('min_amt_msat') OR
+    sqlc.narg('min_amt_msat') IS NULL
+) AND (
+    amt_out_msat <= sqlc.narg('max_amt_msat') OR
+    sqlc.narg('max_amt_msat') IS NULL
+) AND (
+    fee_msat >= sqlc.narg('min_fee_msat') OR
+    sqlc.narg('min_fee_msat') IS NULL
+) AND (
+    fee_msat <= sqlc.narg('max_fee_msat') OR
+    sqlc.narg('max_fee_msat') IS NULL
+)
+GROUP BY outgoing_chan_id, day
+ORDER BY day, outgoing_chan_id;
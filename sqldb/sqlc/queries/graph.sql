@@ -0,0 +1,330 @@
+-- name: UpsertNode :one
+INSERT INTO graph_nodes (
+    pub_key, last_update, alias, color, signature, extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (pub_key)
+    DO UPDATE SET
+        last_update = EXCLUDED.last_update,
+        alias = EXCLUDED.alias,
+        color = EXCLUDED.color,
+        signature = EXCLUDED.signature,
+        extra_opaque_data = EXCLUDED.extra_opaque_data
+RETURNING id;
+
+-- name: GetNodeByPubKey :one
+SELECT *
+FROM graph_nodes
+WHERE pub_key = $1;
+
+-- name: GetNodeIDByPubKey :one
+SELECT id
+FROM graph_nodes
+WHERE pub_key = $1;
+
+-- name: ListNodesPaginated :many
+SELECT *
+FROM graph_nodes
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetNodesByLastUpdateRange :many
+SELECT *
+FROM graph_nodes
+WHERE last_update >= @start_time
+  AND last_update <= @end_time
+ORDER BY last_update, pub_key;
+
+-- name: DeleteNodeByPubKey :execresult
+DELETE FROM graph_nodes
+WHERE pub_key = $1;
+
+-- name: DeleteUnconnectedNodes :many
+DELETE FROM graph_nodes
+WHERE id NOT IN (
+    SELECT node_id_1 FROM graph_channels
+    UNION
+    SELECT node_id_2 FROM graph_channels
+) AND id NOT IN (
+    SELECT node_id FROM graph_source_nodes
+)
+RETURNING pub_key;
+
+-- name: DeleteAllNodes :exec
+DELETE FROM graph_nodes;
+
+-- name: InsertNodeFeature :exec
+INSERT INTO graph_node_features (
+    node_id, feature_bit
+) VALUES (
+    $1, $2
+);
+
+-- name: GetNodeFeatures :many
+SELECT *
+FROM graph_node_features
+WHERE node_id = $1
+ORDER BY feature_bit;
+
+-- name: DeleteNodeFeatures :exec
+DELETE FROM graph_node_features
+WHERE node_id = $1;
+
+-- name: InsertNodeAddress :exec
+INSERT INTO graph_node_addresses (
+    node_id, position, address
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: GetNodeAddresses :many
+SELECT *
+FROM graph_node_addresses
+WHERE node_id = $1
+ORDER BY position;
+
+-- name: DeleteNodeAddresses :exec
+DELETE FROM graph_node_addresses
+WHERE node_id = $1;
+
+-- name: AddSourceNode :exec
+INSERT INTO graph_source_nodes (node_id)
+VALUES ($1)
+ON CONFLICT (node_id) DO NOTHING;
+
+-- name: GetSourceNodes :many
+SELECT n.pub_key
+FROM graph_source_nodes s
+JOIN graph_nodes n ON s.node_id = n.id;
+
+-- name: DeleteSourceNodes :exec
+DELETE FROM graph_source_nodes;
+
+-- name: CreateChannel :one
+INSERT INTO graph_channels (
+    scid, chain_hash, node_id_1, node_id_2, outpoint, capacity,
+    bitcoin_key_1, bitcoin_key_2, features, node_1_signature,
+    node_2_signature, bitcoin_1_signature, bitcoin_2_signature,
+    extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+RETURNING id;
+
+-- name: UpdateChannel :exec
+UPDATE graph_channels
+SET chain_hash = $2,
+    outpoint = $3,
+    capacity = $4,
+    bitcoin_key_1 = $5,
+    bitcoin_key_2 = $6,
+    features = $7,
+    node_1_signature = $8,
+    node_2_signature = $9,
+    bitcoin_1_signature = $10,
+    bitcoin_2_signature = $11,
+    extra_opaque_data = $12
+WHERE id = $1;
+
+-- name: GetChannelBySCID :one
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.scid = $1;
+
+-- name: GetChannelByOutpoint :one
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.outpoint = $1;
+
+-- name: ListChannelsPaginated :many
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.id > $1
+ORDER BY c.id
+LIMIT $2;
+
+-- name: ListChannelsByNodeID :many
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.node_id_1 = @node_id
+   OR c.node_id_2 = @node_id
+ORDER BY c.scid;
+
+-- name: GetChannelsBySCIDRange :many
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+WHERE c.scid >= @start_scid
+  AND c.scid <= @end_scid
+ORDER BY c.scid;
+
+-- name: GetChannelsByPolicyLastUpdateRange :many
+SELECT sqlc.embed(c),
+       n1.pub_key AS node_1_pub_key,
+       n2.pub_key AS node_2_pub_key
+FROM graph_channels c
+JOIN graph_nodes n1 ON c.node_id_1 = n1.id
+JOIN graph_nodes n2 ON c.node_id_2 = n2.id
+JOIN (
+    SELECT channel_id, MIN(last_update) AS first_update
+    FROM graph_channel_policies
+    WHERE last_update >= @start_time
+      AND last_update <= @end_time
+    GROUP BY channel_id
+) u ON u.channel_id = c.id
+ORDER BY u.first_update, c.scid;
+
+-- name: GetPublicChannelUpdateTimesBySCIDRange :many
+SELECT c.scid,
+       p1.last_update AS node_1_last_update,
+       p2.last_update AS node_2_last_update
+FROM graph_channels c
+LEFT JOIN graph_channel_policies p1
+    ON p1.channel_id = c.id AND p1.node_id = c.node_id_1
+LEFT JOIN graph_channel_policies p2
+    ON p2.channel_id = c.id AND p2.node_id = c.node_id_2
+WHERE c.scid >= @start_scid
+  AND c.scid <= @end_scid
+  AND c.node_1_signature IS NOT NULL
+ORDER BY c.scid;
+
+-- name: GetDisabledChannelSCIDs :many
+SELECT c.scid
+FROM graph_channels c
+JOIN graph_channel_policies p1
+    ON p1.channel_id = c.id AND p1.node_id = c.node_id_1
+JOIN graph_channel_policies p2
+    ON p2.channel_id = c.id AND p2.node_id = c.node_id_2
+WHERE p1.disabled = TRUE
+  AND p2.disabled = TRUE;
+
+-- name: HighestSCID :one
+SELECT scid
+FROM graph_channels
+ORDER BY scid DESC
+LIMIT 1;
+
+-- name: DeleteChannel :exec
+DELETE FROM graph_channels
+WHERE id = $1;
+
+-- name: DeleteAllChannels :exec
+DELETE FROM graph_channels;
+
+-- name: UpsertChannelPolicy :exec
+INSERT INTO graph_channel_policies (
+    channel_id, node_id, last_update, message_flags, channel_flags,
+    disabled, timelock, base_fee_msat, fee_ppm, min_htlc_msat,
+    max_htlc_msat, signature, extra_opaque_data
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+ON CONFLICT (channel_id, node_id)
+    DO UPDATE SET
+        last_update = EXCLUDED.last_update,
+        message_flags = EXCLUDED.message_flags,
+        channel_flags = EXCLUDED.channel_flags,
+        disabled = EXCLUDED.disabled,
+        timelock = EXCLUDED.timelock,
+        base_fee_msat = EXCLUDED.base_fee_msat,
+        fee_ppm = EXCLUDED.fee_ppm,
+        min_htlc_msat = EXCLUDED.min_htlc_msat,
+        max_htlc_msat = EXCLUDED.max_htlc_msat,
+        signature = EXCLUDED.signature,
+        extra_opaque_data = EXCLUDED.extra_opaque_data;
+
+-- name: GetChannelPolicies :many
+SELECT *
+FROM graph_channel_policies
+WHERE channel_id = $1;
+
+-- name: ListChannelPoliciesByChannelIDRange :many
+SELECT *
+FROM graph_channel_policies
+WHERE channel_id >= @start_id
+  AND channel_id <= @end_id;
+
+-- name: UpsertZombieChannel :exec
+INSERT INTO graph_zombie_channels (
+    scid, node_key_1, node_key_2
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (scid)
+    DO UPDATE SET
+        node_key_1 = EXCLUDED.node_key_1,
+        node_key_2 = EXCLUDED.node_key_2;
+
+-- name: GetZombieChannel :one
+SELECT *
+FROM graph_zombie_channels
+WHERE scid = $1;
+
+-- name: DeleteZombieChannel :execresult
+DELETE FROM graph_zombie_channels
+WHERE scid = $1;
+
+-- name: CountZombieChannels :one
+SELECT COUNT(*)
+FROM graph_zombie_channels;
+
+-- name: DeleteAllZombieChannels :exec
+DELETE FROM graph_zombie_channels;
+
+-- name: InsertClosedSCID :exec
+INSERT INTO graph_closed_scids (scid)
+VALUES ($1)
+ON CONFLICT (scid) DO NOTHING;
+
+-- name: IsClosedSCID :one
+SELECT EXISTS (
+    SELECT 1
+    FROM graph_closed_scids
+    WHERE scid = $1
+);
+
+-- name: DeleteAllClosedSCIDs :exec
+DELETE FROM graph_closed_scids;
+
+-- name: UpsertPruneLogEntry :exec
+INSERT INTO graph_prune_log (
+    block_height, block_hash
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (block_height)
+    DO UPDATE SET block_hash = EXCLUDED.block_hash;
+
+-- name: GetPruneTip :one
+SELECT *
+FROM graph_prune_log
+ORDER BY block_height DESC
+LIMIT 1;
+
+-- name: DeletePruneLogEntriesFromHeight :exec
+DELETE FROM graph_prune_log
+WHERE block_height >= $1;
+
+-- name: DeleteAllPruneLogEntries :exec
+DELETE FROM graph_prune_log;
@@ -0,0 +1,184 @@
+-- name: NextPaymentSequenceNum :one
+UPDATE payment_sequences SET current_value = current_value + 1
+WHERE name = 'sequence_num'
+RETURNING current_value;
+
+-- name: SetPaymentSequenceNum :exec
+UPDATE payment_sequences SET current_value = $1
+WHERE name = 'sequence_num' AND current_value < $1;
+
+-- name: InsertPayment :one
+INSERT INTO payments (
+    sequence_num, payment_identifier, duplicate, amount_msat, created_at,
+    payment_request, failure_reason, status, destination, rebalance
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id;
+
+-- name: FetchPayment :one
+SELECT *
+FROM payments
+WHERE payment_identifier = $1 AND duplicate = FALSE;
+
+-- name: FetchPaymentsByStatus :many
+SELECT *
+FROM payments
+WHERE status = $1 AND duplicate = FALSE
+ORDER BY sequence_num;
+
+-- name: FilterPayments :many
+SELECT *
+FROM payments
+WHERE (
+    sequence_num > sqlc.narg('index_offset_after') OR
+    sqlc.narg('index_offset_after') IS NULL
+) AND (
+    sequence_num < sqlc.narg('index_offset_before') OR
+    sqlc.narg('index_offset_before') IS NULL
+) AND (
+    status = sqlc.narg('status') OR
+    sqlc.narg('status') IS NULL
+) AND (
+    created_at >= sqlc.narg('created_after') OR
+    sqlc.narg('created_after') IS NULL
+) AND (
+    created_at < sqlc.narg('created_before') OR
+    sqlc.narg('created_before') IS NULL
+)
+ORDER BY
+CASE
+    WHEN sqlc.narg('reverse') = FALSE OR sqlc.narg('reverse') IS NULL THEN sequence_num
+    ELSE NULL
+    END ASC,
+CASE
+    WHEN sqlc.narg('reverse') = TRUE THEN sequence_num
+    ELSE NULL
+END DESC
+LIMIT @num_limit;
+
+-- name: CountPayments :one
+SELECT COUNT(*)
+FROM payments;
+
+-- name: UpdatePaymentStatus :exec
+UPDATE payments
+SET status = $2
+WHERE id = $1;
+
+-- name: UpdatePaymentFailureReason :exec
+UPDATE payments
+SET failure_reason = $2
+WHERE id = $1;
+
+-- name: SetPaymentDestination :exec
+UPDATE payments
+SET destination = $2
+WHERE id = $1 AND destination IS NULL;
+
+-- name: DeletePaymentByID :exec
+DELETE FROM payments
+WHERE id = $1;
+
+-- name: DeletePaymentsByIdentifier :exec
+DELETE FROM payments
+WHERE payment_identifier = $1;
+
+-- name: DeletePaymentsByStatus :exec
+DELETE FROM payments
+WHERE payment_identifier IN (
+    SELECT p.payment_identifier
+    FROM payments p
+    WHERE p.status = $1 AND p.duplicate = FALSE
+);
+
+-- name: DeleteAllPayments :exec
+DELETE FROM payments;
+
+-- name: InsertHTLCAttempt :one
+INSERT INTO payment_htlc_attempts (
+    payment_id, attempt_id, session_key, attempt_time, hash,
+    route_total_time_lock, route_total_amount, route_source_key,
+    settle_preimage, settle_time
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id;
+
+-- name: GetHTLCAttemptID :one
+SELECT id
+FROM payment_htlc_attempts
+WHERE payment_id = $1 AND attempt_id = $2;
+
+-- name: FetchHTLCAttempts :many
+SELECT *
+FROM payment_htlc_attempts
+WHERE payment_id = $1
+ORDER BY attempt_id;
+
+-- name: SettleHTLCAttempt :exec
+UPDATE payment_htlc_attempts
+SET settle_preimage = $2, settle_time = $3
+WHERE id = $1;
+
+-- name: InsertHTLCAttemptFailure :exec
+INSERT INTO payment_htlc_attempt_failures (
+    htlc_attempt_id, fail_time, reason, failure_source_index, failure_msg
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: FetchHTLCAttemptFailures :many
+SELECT f.*
+FROM payment_htlc_attempt_failures f
+JOIN payment_htlc_attempts a ON a.id = f.htlc_attempt_id
+WHERE a.payment_id = $1;
+
+-- name: DeleteFailedHTLCAttempts :exec
+DELETE FROM payment_htlc_attempts
+WHERE payment_id = $1 AND id IN (
+    SELECT htlc_attempt_id
+    FROM payment_htlc_attempt_failures
+);
+
+-- name: DeleteFailedHTLCAttemptsByStatus :exec
+DELETE FROM payment_htlc_attempts
+WHERE id IN (
+    SELECT htlc_attempt_id
+    FROM payment_htlc_attempt_failures
+) AND payment_id IN (
+    SELECT p.id
+    FROM payments p
+    WHERE p.status = $1 AND p.duplicate = FALSE
+);
+
+-- name: InsertRouteHop :exec
+INSERT INTO payment_route_hops (
+    htlc_attempt_id, hop_index, pub_key, scid, outgoing_time_lock,
+    amt_to_forward, legacy_payload, mpp_payment_addr, mpp_total_msat,
+    amp_root_share, amp_set_id, amp_child_index, encrypted_data,
+    blinding_point, total_amt_msat, metadata, trampoline_onion
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+    $17
+);
+
+-- name: FetchRouteHops :many
+SELECT h.*
+FROM payment_route_hops h
+JOIN payment_htlc_attempts a ON a.id = h.htlc_attempt_id
+WHERE a.payment_id = $1
+ORDER BY h.htlc_attempt_id, h.hop_index;
+
+-- name: InsertRouteHopCustomRecord :exec
+INSERT INTO payment_route_hop_custom_records (
+    htlc_attempt_id, hop_index, key, value
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: FetchRouteHopCustomRecords :many
+SELECT r.*
+FROM payment_route_hop_custom_records r
+JOIN payment_htlc_attempts a ON a.id = r.htlc_attempt_id
+WHERE a.payment_id = $1;
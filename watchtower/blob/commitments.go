@@ -158,6 +158,85 @@ func (c CommitmentType) ToLocalWitnessSize() (lntypes.WeightUnit, error) {
 	}
 }
 
+// HtlcWitnessType is the input type of a revoked HTLC output. Incoming HTLCs
+// are the ones that were offered to us by the breaching party.
+func (c CommitmentType) HtlcWitnessType(incoming bool) (input.WitnessType,
+	error) {
+
+	switch c {
+	case LegacyTweaklessCommitment, LegacyCommitment, AnchorCommitment:
+		if incoming {
+			return input.HtlcAcceptedRevoke, nil
+		}
+
+		return input.HtlcOfferedRevoke, nil
+
+	case TaprootCommitment:
+		if incoming {
+			return input.TaprootHtlcAcceptedRevoke, nil
+		}
+
+		return input.TaprootHtlcOfferedRevoke, nil
+
+	default:
+		return nil, fmt.Errorf("unknown commitment type: %v", c)
+	}
+}
+
+// HtlcWitnessSize is the size of the witness that will be required to spend a
+// revoked HTLC output. Incoming HTLCs are the ones that were offered to us by
+// the breaching party, so they pay to the offered HTLC script of their
+// commitment.
+func (c CommitmentType) HtlcWitnessSize(incoming bool) (lntypes.WeightUnit,
+	error) {
+
+	switch c {
+	case LegacyTweaklessCommitment, LegacyCommitment:
+		if incoming {
+			return input.OfferedHtlcPenaltyWitnessSize, nil
+		}
+
+		return input.AcceptedHtlcPenaltyWitnessSize, nil
+
+	// The HTLC scripts of anchor channels carry an additional 1 block CSV
+	// on their non-revocation paths.
+	case AnchorCommitment:
+		if incoming {
+			return input.OfferedHtlcPenaltyWitnessSizeConfirmed, nil
+		}
+
+		return input.AcceptedHtlcPenaltyWitnessSizeConfirmed, nil
+
+	case TaprootCommitment:
+		if incoming {
+			return input.TaprootAcceptedRevokeWitnessSize, nil
+		}
+
+		return input.TaprootOfferedRevokeWitnessSize, nil
+
+	default:
+		return 0, fmt.Errorf("unknown commitment type: %v", c)
+	}
+}
+
+// HtlcSecondLevelWitnessSize is the size of the witness that will be required
+// to spend the revoked output of a second level HTLC transaction. Only the
+// second level transactions of legacy channels can be swept with a presigned
+// justice transaction, as those of later channel types are signed with
+// SIGHASH_SINGLE|ANYONECANPAY, which lets the breaching party change their
+// txid.
+func (c CommitmentType) HtlcSecondLevelWitnessSize() (lntypes.WeightUnit,
+	error) {
+
+	switch c {
+	case LegacyTweaklessCommitment, LegacyCommitment:
+		return input.ToLocalPenaltyWitnessSize, nil
+
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrNoSecondLevelSweep, c)
+	}
+}
+
 // ParseRawSig parses a wire.TxWitness and creates an lnwire.Sig.
 func (c CommitmentType) ParseRawSig(witness wire.TxWitness) (lnwire.Sig,
 	error) {
@@ -249,3 +328,32 @@ func (c CommitmentType) EmptyJusticeKit() (JusticeKit, error) {
 		return nil, fmt.Errorf("unknown commitment type: %v", c)
 	}
 }
+
+// NewHtlcJusticeKit can be used to construct a new JusticeKit depending on the
+// CommitmentType, which next to the commitment outputs can also sweep the HTLC
+// outputs of the breached commitment.
+func (c CommitmentType) NewHtlcJusticeKit(sweepScript []byte,
+	breachInfo *lnwallet.BreachRetribution, withToRemote bool) (JusticeKit,
+	error) {
+
+	kit, err := c.NewJusticeKit(sweepScript, breachInfo, withToRemote)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHtlcJusticeKit(kit, c, breachInfo.KeyRing), nil
+}
+
+// EmptyHtlcJusticeKit returns the appropriate empty justice kit that also
+// sweeps HTLC outputs for the given CommitmentType.
+func (c CommitmentType) EmptyHtlcJusticeKit() (JusticeKit, error) {
+	kit, err := c.EmptyJusticeKit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &htlcJusticeKit{
+		JusticeKit:     kit,
+		commitmentType: c,
+	}, nil
+}
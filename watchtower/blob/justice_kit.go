@@ -2,7 +2,9 @@ package blob
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
@@ -10,6 +12,7 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	secp "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -51,10 +54,63 @@ type JusticeKit interface {
 	// bytes.
 	PlainTextSize() int
 
+	// HtlcOutputsSpendInfo returns the info required to sweep each of the
+	// revoked HTLC outputs held by the kit.
+	HtlcOutputsSpendInfo() ([]*HtlcOutputSpendInfo, error)
+
+	// AddHtlcOutput adds the given revoked HTLC output to the kit, along
+	// with the signature of the justice transaction that sweeps it. The
+	// second level signature, if any, is the one of the justice
+	// transaction that sweeps the output of the breaching party's second
+	// level HTLC transaction.
+	AddHtlcOutput(htlc *lnwallet.HtlcRetribution, sig lnwire.Sig,
+		secondLevelSig fn.Option[lnwire.Sig]) error
+
+	// revocationKey returns the revocation public key of the breached
+	// commitment.
+	revocationKey() (*btcec.PublicKey, error)
+
 	encode(w io.Writer) error
 	decode(r io.Reader) error
 }
 
+// HtlcOutputSpendInfo holds the info required to sweep a revoked HTLC output
+// of a breached commitment transaction.
+type HtlcOutputSpendInfo struct {
+	// OutputIndex is the index of the HTLC output on the breached
+	// commitment transaction.
+	OutputIndex uint32
+
+	// Incoming is true if the HTLC was offered to us by the breaching
+	// party.
+	Incoming bool
+
+	// PkScript is the pkScript the HTLC output is expected to have.
+	PkScript *txscript.PkScript
+
+	// Witness is the witness that spends the HTLC output with the
+	// revocation key.
+	Witness wire.TxWitness
+
+	// SecondLevel holds the info required to sweep the output of the
+	// breaching party's second level HTLC transaction. It is nil if the
+	// kit has no signature for it.
+	SecondLevel *HtlcSecondLevelSpendInfo
+}
+
+// HtlcSecondLevelSpendInfo holds the info required to sweep the revoked output
+// of the second level HTLC transaction the breaching party spent an HTLC
+// output with.
+type HtlcSecondLevelSpendInfo struct {
+	// PkScript is the pkScript the second level output is expected to
+	// have.
+	PkScript *txscript.PkScript
+
+	// Witness is the witness that spends the second level output with the
+	// revocation key.
+	Witness wire.TxWitness
+}
+
 // legacyJusticeKit is an implementation of the JusticeKit interface which can
 // be used for backing up commitments of legacy (pre-anchor) channels.
 type legacyJusticeKit struct {
@@ -221,6 +277,33 @@ func (l *legacyJusticeKit) PlainTextSize() int {
 	return V0PlaintextSize
 }
 
+// HtlcOutputsSpendInfo returns the info required to sweep each of the revoked
+// HTLC outputs held by the kit. The legacy kit doesn't hold any.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (l *legacyJusticeKit) HtlcOutputsSpendInfo() ([]*HtlcOutputSpendInfo,
+	error) {
+
+	return nil, nil
+}
+
+// AddHtlcOutput adds the given revoked HTLC output to the kit, which isn't
+// supported by the legacy kit.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (l *legacyJusticeKit) AddHtlcOutput(_ *lnwallet.HtlcRetribution,
+	_ lnwire.Sig, _ fn.Option[lnwire.Sig]) error {
+
+	return ErrNoHtlcOutputs
+}
+
+// revocationKey returns the revocation public key of the breached commitment.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (l *legacyJusticeKit) revocationKey() (*btcec.PublicKey, error) {
+	return btcec.ParsePubKey(l.revocationPubKey[:])
+}
+
 // anchorJusticeKit is an implementation of the JusticeKit interface which can
 // be used for backing up commitments of anchor channels. It inherits most of
 // the methods from the legacyJusticeKit and overrides the
@@ -489,6 +572,340 @@ func (t *taprootJusticeKit) PlainTextSize() int {
 	return V1PlaintextSize
 }
 
+// HtlcOutputsSpendInfo returns the info required to sweep each of the revoked
+// HTLC outputs held by the kit. The taproot kit doesn't hold any.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (t *taprootJusticeKit) HtlcOutputsSpendInfo() ([]*HtlcOutputSpendInfo,
+	error) {
+
+	return nil, nil
+}
+
+// AddHtlcOutput adds the given revoked HTLC output to the kit, which isn't
+// supported by the taproot kit.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (t *taprootJusticeKit) AddHtlcOutput(_ *lnwallet.HtlcRetribution,
+	_ lnwire.Sig, _ fn.Option[lnwire.Sig]) error {
+
+	return ErrNoHtlcOutputs
+}
+
+// revocationKey returns the revocation public key of the breached commitment.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (t *taprootJusticeKit) revocationKey() (*btcec.PublicKey, error) {
+	return schnorr.ParsePubKey(t.revocationPubKey[:])
+}
+
+// htlcJusticeKit is an implementation of the JusticeKit interface which wraps
+// the justice kit of a commitment type, and next to the commitment outputs
+// also holds the info required to sweep the revoked HTLC outputs of the
+// breached commitment. Each HTLC output is swept by a justice transaction of
+// its own, so that the breaching party taking one of the HTLCs to the second
+// level doesn't invalidate the sweeps of the other outputs.
+type htlcJusticeKit struct {
+	JusticeKit
+
+	commitmentType CommitmentType
+
+	htlcs htlcOutputsPacket
+}
+
+// A compile-time check to ensure that htlcJusticeKit implements the
+// JusticeKit interface.
+var _ JusticeKit = (*htlcJusticeKit)(nil)
+
+// newHtlcJusticeKit wraps the given justice kit into a htlcJusticeKit.
+func newHtlcJusticeKit(kit JusticeKit, commitmentType CommitmentType,
+	keyRing *lnwallet.CommitmentKeyRing) *htlcJusticeKit {
+
+	return &htlcJusticeKit{
+		JusticeKit:     kit,
+		commitmentType: commitmentType,
+		htlcs: htlcOutputsPacket{
+			localHtlcPubKey:  toBlobPubKey(keyRing.LocalHtlcKey),
+			remoteHtlcPubKey: toBlobPubKey(keyRing.RemoteHtlcKey),
+		},
+	}
+}
+
+// AddHtlcOutput adds the given revoked HTLC output to the kit, along with the
+// signature of the justice transaction that sweeps it. The second level
+// signature can only be added for legacy channels.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (h *htlcJusticeKit) AddHtlcOutput(htlc *lnwallet.HtlcRetribution,
+	sig lnwire.Sig, secondLevelSig fn.Option[lnwire.Sig]) error {
+
+	if len(h.htlcs.htlcs) >= MaxHtlcOutputs {
+		return ErrTooManyHtlcOutputs
+	}
+
+	if secondLevelSig.IsSome() {
+		_, err := h.commitmentType.HtlcSecondLevelWitnessSize()
+		if err != nil {
+			return err
+		}
+	}
+
+	if htlc.OutPoint.Index > math.MaxUint16 {
+		return fmt.Errorf("htlc output index %d too large",
+			htlc.OutPoint.Index)
+	}
+
+	output := htlcOutput{
+		incoming:       htlc.IsIncoming,
+		outputIndex:    uint16(htlc.OutPoint.Index),
+		refundTimeout:  htlc.RefundTimeout,
+		sig:            sig,
+		secondLevelSig: secondLevelSig,
+	}
+
+	// Taproot HTLC outputs are swept with a key spend, for which only the
+	// tap tweak of the output is needed. The scripts of segwit v0 HTLC
+	// outputs are rebuilt from the payment hash instead.
+	if h.commitmentType == TaprootCommitment {
+		copy(output.hashOrTweak[:], htlc.SignDesc.TapTweak)
+	} else {
+		output.hashOrTweak = htlc.PaymentHash
+	}
+
+	h.htlcs.htlcs = append(h.htlcs.htlcs, output)
+
+	return nil
+}
+
+// HtlcOutputsSpendInfo returns the info required to sweep each of the revoked
+// HTLC outputs held by the kit.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (h *htlcJusticeKit) HtlcOutputsSpendInfo() ([]*HtlcOutputSpendInfo,
+	error) {
+
+	revocationKey, err := h.revocationKey()
+	if err != nil {
+		return nil, err
+	}
+
+	spendInfos := make([]*HtlcOutputSpendInfo, 0, len(h.htlcs.htlcs))
+	for _, htlc := range h.htlcs.htlcs {
+		var spendInfo *HtlcOutputSpendInfo
+		if h.commitmentType == TaprootCommitment {
+			spendInfo, err = taprootHtlcSpendInfo(
+				revocationKey, htlc,
+			)
+		} else {
+			spendInfo, err = h.segwitV0HtlcSpendInfo(
+				revocationKey, htlc,
+			)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		spendInfos = append(spendInfos, spendInfo)
+	}
+
+	return spendInfos, nil
+}
+
+// segwitV0HtlcSpendInfo returns the info required to sweep the given revoked
+// HTLC output of a legacy or anchor commitment. The HTLC script is rebuilt from
+// the remote commitment's point of view, which means that the HTLCs offered to
+// us were offered by the breaching party.
+func (h *htlcJusticeKit) segwitV0HtlcSpendInfo(revocationKey *btcec.PublicKey,
+	htlc htlcOutput) (*HtlcOutputSpendInfo, error) {
+
+	localHtlcKey, err := btcec.ParsePubKey(h.htlcs.localHtlcPubKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	remoteHtlcKey, err := btcec.ParsePubKey(h.htlcs.remoteHtlcPubKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	confirmedSpend := h.commitmentType == AnchorCommitment
+
+	var script []byte
+	if htlc.incoming {
+		script, err = input.SenderHTLCScript(
+			remoteHtlcKey, localHtlcKey, revocationKey,
+			htlc.hashOrTweak[:], confirmedSpend,
+		)
+	} else {
+		script, err = input.ReceiverHTLCScript(
+			htlc.refundTimeout, localHtlcKey, remoteHtlcKey,
+			revocationKey, htlc.hashOrTweak[:], confirmedSpend,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scriptPubKey, err := input.WitnessScriptHash(script)
+	if err != nil {
+		return nil, err
+	}
+
+	pkScript, err := txscript.ParsePkScript(scriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := htlc.sig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	witness := make(wire.TxWitness, 3)
+	witness[0] = append(sig.Serialize(), byte(txscript.SigHashAll))
+	witness[1] = revocationKey.SerializeCompressed()
+	witness[2] = script
+
+	spendInfo := &HtlcOutputSpendInfo{
+		OutputIndex: uint32(htlc.outputIndex),
+		Incoming:    htlc.incoming,
+		PkScript:    &pkScript,
+		Witness:     witness,
+	}
+
+	// If the client presigned the sweep of the second level output, add
+	// the info required to sweep it as well.
+	htlc.secondLevelSig.WhenSome(func(sig lnwire.Sig) {
+		spendInfo.SecondLevel, err = h.secondLevelSpendInfo(
+			revocationKey, sig,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return spendInfo, nil
+}
+
+// secondLevelSpendInfo returns the info required to sweep the revoked output
+// of the breaching party's second level HTLC transaction, which pays to the
+// same keys and delay as their to-local output. Only the second level
+// transactions of legacy channels can be swept with a presigned transaction.
+func (h *htlcJusticeKit) secondLevelSpendInfo(revocationKey *btcec.PublicKey,
+	sig lnwire.Sig) (*HtlcSecondLevelSpendInfo, error) {
+
+	kit, ok := h.JusticeKit.(*legacyJusticeKit)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoSecondLevelSweep,
+			h.commitmentType)
+	}
+
+	delayKey, err := btcec.ParsePubKey(kit.localDelayPubKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := input.SecondLevelHtlcScript(
+		revocationKey, delayKey, kit.csvDelay,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptPubKey, err := input.WitnessScriptHash(script)
+	if err != nil {
+		return nil, err
+	}
+
+	pkScript, err := txscript.ParsePkScript(scriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	// The witness selects the revocation path of the second level script
+	// with a one.
+	witness := make(wire.TxWitness, 3)
+	witness[0] = append(signature.Serialize(), byte(txscript.SigHashAll))
+	witness[1] = []byte{1}
+	witness[2] = script
+
+	return &HtlcSecondLevelSpendInfo{
+		PkScript: &pkScript,
+		Witness:  witness,
+	}, nil
+}
+
+// taprootHtlcSpendInfo returns the info required to sweep the given revoked
+// HTLC output of a taproot commitment. The output is spent with a key spend of
+// the revocation key, tweaked with the root of the HTLC's tapscript tree.
+func taprootHtlcSpendInfo(revocationKey *btcec.PublicKey,
+	htlc htlcOutput) (*HtlcOutputSpendInfo, error) {
+
+	outputKey := txscript.ComputeTaprootOutputKey(
+		revocationKey, htlc.hashOrTweak[:],
+	)
+
+	scriptPubKey, err := input.PayToTaprootScript(outputKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pkScript, err := txscript.ParsePkScript(scriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := htlc.sig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	return &HtlcOutputSpendInfo{
+		OutputIndex: uint32(htlc.outputIndex),
+		Incoming:    htlc.incoming,
+		PkScript:    &pkScript,
+		Witness:     wire.TxWitness{sig.Serialize()},
+	}, nil
+}
+
+// PlainTextSize is the size of the encoded-but-unencrypted blob in bytes. The
+// HTLC slots are padded to a multiple of HtlcOutputsBatchSize.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (h *htlcJusticeKit) PlainTextSize() int {
+	return h.JusticeKit.PlainTextSize() + HtlcOutputsHeaderSize +
+		numHtlcSlots(len(h.htlcs.htlcs))*HtlcOutputSize
+}
+
+// encode encodes the commitment outputs of the kit followed by its HTLC
+// outputs.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (h *htlcJusticeKit) encode(w io.Writer) error {
+	if err := h.JusticeKit.encode(w); err != nil {
+		return err
+	}
+
+	return h.htlcs.encode(w)
+}
+
+// decode decodes the commitment outputs of the kit followed by its HTLC
+// outputs.
+//
+// NOTE: This is part of the JusticeKit interface.
+func (h *htlcJusticeKit) decode(r io.Reader) error {
+	if err := h.JusticeKit.decode(r); err != nil {
+		return err
+	}
+
+	return h.htlcs.decode(r, h.commitmentType == TaprootCommitment)
+}
+
 func tapBranchHash(l, r []byte) chainhash.Hash {
 	if bytes.Compare(l, r) > 0 {
 		l, r = r, l
@@ -11,6 +11,7 @@ import (
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -51,6 +52,33 @@ const (
 	// MaxSweepAddrSize defines the maximum sweep address size that can be
 	// encoded in a blob.
 	MaxSweepAddrSize = 42
+
+	// HtlcOutputsHeaderSize is the plaintext size of the header of the
+	// HTLC outputs, which follow the commitment outputs in blobs that also
+	// sweep HTLC outputs.
+	//    local htlc pubkey:              33 bytes
+	//    remote htlc pubkey:             33 bytes
+	//    number of htlc outputs:          2 bytes
+	HtlcOutputsHeaderSize = 68
+
+	// HtlcOutputSize is the plaintext size of a single HTLC output slot.
+	//    flags:                           1 byte
+	//    output index:                    2 bytes
+	//    payment hash or tap tweak:      32 bytes
+	//    refund timeout:                  4 bytes
+	//    htlc revocation sig:            64 bytes
+	//    second level revocation sig:    64 bytes
+	HtlcOutputSize = 167
+
+	// HtlcOutputsBatchSize is the number of HTLC output slots a blob is
+	// padded to a multiple of. This way the tower only learns a coarse
+	// bound on the number of HTLCs of a revoked state.
+	HtlcOutputsBatchSize = 8
+
+	// MaxHtlcOutputs is the maximum number of HTLC outputs a blob can
+	// hold, which keeps the largest blob within the maximum size of a
+	// state update.
+	MaxHtlcOutputs = 384
 )
 
 var (
@@ -73,6 +101,21 @@ var (
 		"cannot obtain commit to-remote p2wkh output script from blob",
 	)
 
+	// ErrNoHtlcOutputs is returned when trying to add an HTLC output to a
+	// blob whose type doesn't sweep HTLC outputs.
+	ErrNoHtlcOutputs = errors.New("blob type doesn't sweep htlc outputs")
+
+	// ErrTooManyHtlcOutputs is returned when trying to add more than
+	// MaxHtlcOutputs HTLC outputs to a blob.
+	ErrTooManyHtlcOutputs = fmt.Errorf("blob can't hold more than %d "+
+		"htlc outputs", MaxHtlcOutputs)
+
+	// ErrNoSecondLevelSweep is returned when trying to sweep the output of
+	// a second level HTLC transaction of a commitment type whose second
+	// level transactions can't be presigned.
+	ErrNoSecondLevelSweep = errors.New("commitment type doesn't support " +
+		"second level htlc sweeps")
+
 	// ErrSweepAddressToLong is returned when trying to encode or decode a
 	// sweep address with length greater than the maximum length of 42
 	// bytes, which supports p2wkh and p2sh addresses.
@@ -91,6 +134,49 @@ func Size(kit JusticeKit) int {
 	return NonceSize + kit.PlainTextSize() + CiphertextExpansion
 }
 
+// IsValidSize returns true if the given size is a valid size of an encrypted
+// blob of the given type. Blobs that also sweep HTLC outputs carry a variable
+// number of HTLC output slots, all others have a constant size.
+func IsValidSize(blobType Type, size int) (bool, error) {
+	commitment, err := blobType.CommitmentType(nil)
+	if err != nil {
+		return false, err
+	}
+
+	kit, err := commitment.EmptyJusticeKit()
+	if err != nil {
+		return false, err
+	}
+
+	if !blobType.HasHtlcOutputs() {
+		return size == Size(kit), nil
+	}
+
+	slotsSize := size - Size(kit) - HtlcOutputsHeaderSize
+	if slotsSize <= 0 || slotsSize%HtlcOutputSize != 0 {
+		return false, nil
+	}
+
+	numSlots := slotsSize / HtlcOutputSize
+
+	return numSlots%HtlcOutputsBatchSize == 0 &&
+		numSlots <= MaxHtlcOutputs, nil
+}
+
+// numHtlcSlots returns the number of HTLC output slots a blob with the given
+// number of HTLC outputs is padded to. Blobs without any HTLC outputs still
+// carry a single batch of slots, so that they can't be told apart from blobs
+// with a few HTLC outputs.
+func numHtlcSlots(numHtlcs int) int {
+	numBatches := (numHtlcs + HtlcOutputsBatchSize - 1) /
+		HtlcOutputsBatchSize
+	if numBatches == 0 {
+		numBatches = 1
+	}
+
+	return numBatches * HtlcOutputsBatchSize
+}
+
 // schnorrPubKey is a 32-byte serialized x-only public key.
 type schnorrPubKey [32]byte
 
@@ -236,7 +322,12 @@ func Decrypt(key BreachKey, ciphertext []byte,
 		return nil, err
 	}
 
-	kit, err := commitment.EmptyJusticeKit()
+	var kit JusticeKit
+	if blobType.HasHtlcOutputs() {
+		kit, err = commitment.EmptyHtlcJusticeKit()
+	} else {
+		kit, err = commitment.EmptyJusticeKit()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -643,3 +734,256 @@ func (t *justiceKitPacketV1) decode(r io.Reader) error {
 
 	return nil
 }
+
+const (
+	// htlcFlagIncoming is the flag of an HTLC output slot that signals
+	// that the HTLC was offered to the client by the breaching party.
+	htlcFlagIncoming uint8 = 1
+
+	// htlcFlagSecondLevel is the flag of an HTLC output slot that signals
+	// that the slot holds a signature sweeping the output of the breaching
+	// party's second level HTLC transaction.
+	htlcFlagSecondLevel uint8 = 2
+)
+
+// htlcOutput holds the info required to sweep a single revoked HTLC output.
+type htlcOutput struct {
+	// incoming is true if the HTLC was offered to the client by the
+	// breaching party.
+	incoming bool
+
+	// outputIndex is the index of the HTLC output on the breached
+	// commitment transaction.
+	outputIndex uint16
+
+	// hashOrTweak is the payment hash of the HTLC for segwit v0 channels,
+	// and the tap tweak of the HTLC output for taproot channels.
+	hashOrTweak [32]byte
+
+	// refundTimeout is the absolute timeout of the HTLC, which is part of
+	// the script of HTLCs offered by the client.
+	refundTimeout uint32
+
+	// sig is the signature under the revocation key of the justice
+	// transaction that sweeps the HTLC output.
+	sig lnwire.Sig
+
+	// secondLevelSig is the signature under the revocation key of the
+	// justice transaction that sweeps the output of the breaching party's
+	// second level HTLC transaction, if the client could presign it.
+	secondLevelSig fn.Option[lnwire.Sig]
+}
+
+// htlcOutputsPacket holds the HTLC outputs of a blob that also sweeps HTLC
+// outputs. It is encoded after the commitment outputs of the blob.
+type htlcOutputsPacket struct {
+	// localHtlcPubKey is the HTLC public key of the client on the breached
+	// commitment.
+	localHtlcPubKey pubKey
+
+	// remoteHtlcPubKey is the HTLC public key of the breaching party on
+	// the breached commitment.
+	remoteHtlcPubKey pubKey
+
+	// htlcs are the revoked HTLC outputs to sweep.
+	htlcs []htlcOutput
+}
+
+// encode encodes the htlcOutputsPacket to the provided io.Writer. The HTLC
+// outputs are padded with blank slots to a multiple of HtlcOutputsBatchSize.
+//
+// htlc outputs plaintext encoding:
+//
+//	local htlc pubkey:              33 bytes
+//	remote htlc pubkey:             33 bytes
+//	number of htlc outputs:          2 bytes
+//	htlc output slots:             167 bytes each, maybe blank
+func (h *htlcOutputsPacket) encode(w io.Writer) error {
+	if len(h.htlcs) > MaxHtlcOutputs {
+		return ErrTooManyHtlcOutputs
+	}
+
+	// Write 33-byte local and remote htlc public keys.
+	_, err := w.Write(h.localHtlcPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(h.remoteHtlcPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	// Write 2-byte number of htlc outputs.
+	err = binary.Write(w, byteOrder, uint16(len(h.htlcs)))
+	if err != nil {
+		return err
+	}
+
+	for _, htlc := range h.htlcs {
+		var flags uint8
+		if htlc.incoming {
+			flags |= htlcFlagIncoming
+		}
+		if htlc.secondLevelSig.IsSome() {
+			flags |= htlcFlagSecondLevel
+		}
+
+		// Write 1-byte flags.
+		err := binary.Write(w, byteOrder, flags)
+		if err != nil {
+			return err
+		}
+
+		// Write 2-byte output index.
+		err = binary.Write(w, byteOrder, htlc.outputIndex)
+		if err != nil {
+			return err
+		}
+
+		// Write 32-byte payment hash or tap tweak.
+		_, err = w.Write(htlc.hashOrTweak[:])
+		if err != nil {
+			return err
+		}
+
+		// Write 4-byte refund timeout.
+		err = binary.Write(w, byteOrder, htlc.refundTimeout)
+		if err != nil {
+			return err
+		}
+
+		// Write 64-byte htlc revocation signature.
+		_, err = w.Write(htlc.sig.RawBytes())
+		if err != nil {
+			return err
+		}
+
+		// Write 64-byte second level revocation signature, which is
+		// left blank if the slot doesn't have one.
+		var secondLevelSig [64]byte
+		htlc.secondLevelSig.WhenSome(func(sig lnwire.Sig) {
+			copy(secondLevelSig[:], sig.RawBytes())
+		})
+		_, err = w.Write(secondLevelSig[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pad the remaining slots of the last batch with blank slots.
+	numBlank := numHtlcSlots(len(h.htlcs)) - len(h.htlcs)
+	_, err = w.Write(make([]byte, numBlank*HtlcOutputSize))
+
+	return err
+}
+
+// decode reconstructs a htlcOutputsPacket from the io.Reader. The blank slots
+// that pad the HTLC outputs are not read. The signatures are parsed as schnorr
+// signatures if taproot is true, and as ECDSA signatures otherwise.
+//
+// htlc outputs plaintext encoding:
+//
+//	local htlc pubkey:              33 bytes
+//	remote htlc pubkey:             33 bytes
+//	number of htlc outputs:          2 bytes
+//	htlc output slots:             167 bytes each, maybe blank
+func (h *htlcOutputsPacket) decode(r io.Reader, taproot bool) error {
+	// Read 33-byte local and remote htlc public keys.
+	_, err := io.ReadFull(r, h.localHtlcPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(r, h.remoteHtlcPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	// Read 2-byte number of htlc outputs.
+	var numHtlcs uint16
+	err = binary.Read(r, byteOrder, &numHtlcs)
+	if err != nil {
+		return err
+	}
+
+	if numHtlcs > MaxHtlcOutputs {
+		return ErrTooManyHtlcOutputs
+	}
+
+	h.htlcs = nil
+	if numHtlcs > 0 {
+		h.htlcs = make([]htlcOutput, numHtlcs)
+	}
+
+	for i := range h.htlcs {
+		htlc := &h.htlcs[i]
+
+		// Read 1-byte flags.
+		var flags uint8
+		err := binary.Read(r, byteOrder, &flags)
+		if err != nil {
+			return err
+		}
+		htlc.incoming = flags&htlcFlagIncoming != 0
+
+		// Read 2-byte output index.
+		err = binary.Read(r, byteOrder, &htlc.outputIndex)
+		if err != nil {
+			return err
+		}
+
+		// Read 32-byte payment hash or tap tweak.
+		_, err = io.ReadFull(r, htlc.hashOrTweak[:])
+		if err != nil {
+			return err
+		}
+
+		// Read 4-byte refund timeout.
+		err = binary.Read(r, byteOrder, &htlc.refundTimeout)
+		if err != nil {
+			return err
+		}
+
+		// Read 64-byte htlc revocation signature.
+		var sig [64]byte
+		_, err = io.ReadFull(r, sig[:])
+		if err != nil {
+			return err
+		}
+
+		if taproot {
+			htlc.sig, err = lnwire.NewSigFromSchnorrRawSignature(
+				sig[:],
+			)
+		} else {
+			htlc.sig, err = lnwire.NewSigFromWireECDSA(sig[:])
+		}
+		if err != nil {
+			return err
+		}
+
+		// Read 64-byte second level revocation signature, which is only
+		// parsed if the slot's flags signal that it has one.
+		_, err = io.ReadFull(r, sig[:])
+		if err != nil {
+			return err
+		}
+
+		if flags&htlcFlagSecondLevel == 0 {
+			continue
+		}
+
+		if taproot {
+			return ErrNoSecondLevelSweep
+		}
+
+		secondLevelSig, err := lnwire.NewSigFromWireECDSA(sig[:])
+		if err != nil {
+			return err
+		}
+		htlc.secondLevelSig = fn.Some(secondLevelSig)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"testing"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -541,3 +543,264 @@ func testJusticeKitToLocalWitnessConstruction(t *testing.T,
 	expWitnessStack := test.expWitnessStack(rawRevSig)
 	require.Equal(t, expWitnessStack, witness[:test.witnessScriptIndex])
 }
+
+// TestHtlcJusticeKitEncryptDecrypt asserts that encrypting and decrypting a
+// justice kit holding revoked HTLC outputs produces the original kit, and that
+// the encrypted blob has a valid size for the blob type.
+func TestHtlcJusticeKitEncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		name        string
+		blobType    Type
+		numHtlcs    int
+		makeSig     func(int) lnwire.Sig
+		secondLevel bool
+	}{
+		{
+			name:        "legacy no htlcs",
+			blobType:    TypeAltruistHtlcCommit,
+			numHtlcs:    0,
+			makeSig:     makeSig,
+			secondLevel: true,
+		},
+		{
+			name:        "legacy one batch",
+			blobType:    TypeAltruistHtlcCommit,
+			numHtlcs:    3,
+			makeSig:     makeSig,
+			secondLevel: true,
+		},
+		{
+			name:     "anchor two batches",
+			blobType: TypeAltruistAnchorHtlcCommit,
+			numHtlcs: HtlcOutputsBatchSize + 1,
+			makeSig:  makeSig,
+		},
+		{
+			name:     "taproot max htlcs",
+			blobType: TypeAltruistTaprootHtlcCommit,
+			numHtlcs: MaxHtlcOutputs,
+			makeSig:  makeSchnorrSig,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commitType, err := test.blobType.CommitmentType(nil)
+			require.NoError(t, err)
+
+			breachInfo := &lnwallet.BreachRetribution{
+				RemoteDelay: csvDelay,
+				KeyRing: &lnwallet.CommitmentKeyRing{
+					ToLocalKey:    makePubKey(),
+					ToRemoteKey:   makePubKey(),
+					RevocationKey: makePubKey(),
+					LocalHtlcKey:  makePubKey(),
+					RemoteHtlcKey: makePubKey(),
+				},
+			}
+
+			kit, err := commitType.NewHtlcJusticeKit(
+				makeAddr(22), breachInfo, true,
+			)
+			require.NoError(t, err)
+			kit.AddToLocalSig(test.makeSig(0))
+			kit.AddToRemoteSig(test.makeSig(1))
+
+			for i := 0; i < test.numHtlcs; i++ {
+				htlc := &lnwallet.HtlcRetribution{
+					OutPoint:      wire.OutPoint{Index: uint32(i)},
+					IsIncoming:    i%2 == 0,
+					RefundTimeout: uint32(1000 + i),
+				}
+				htlc.SignDesc.TapTweak = makeAddr(32)
+				copy(htlc.PaymentHash[:], makeAddr(32))
+
+				// Only the kits of legacy channels can hold
+				// the signatures of second level sweeps.
+				secondLevelSig := fn.Some(
+					test.makeSig(i + MaxHtlcOutputs),
+				)
+				err := kit.AddHtlcOutput(
+					htlc, test.makeSig(i+2), secondLevelSig,
+				)
+				if !test.secondLevel {
+					require.ErrorIs(
+						t, err, ErrNoSecondLevelSweep,
+					)
+
+					err = kit.AddHtlcOutput(
+						htlc, test.makeSig(i+2),
+						fn.None[lnwire.Sig](),
+					)
+				}
+				require.NoError(t, err)
+			}
+
+			// No more HTLC outputs can be added once the kit is
+			// full.
+			if test.numHtlcs == MaxHtlcOutputs {
+				err := kit.AddHtlcOutput(
+					&lnwallet.HtlcRetribution{},
+					test.makeSig(0), fn.None[lnwire.Sig](),
+				)
+				require.ErrorIs(t, err, ErrTooManyHtlcOutputs)
+			}
+
+			var key BreachKey
+			_, err = rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := Encrypt(kit, key)
+			require.NoError(t, err)
+			require.Len(t, ctxt, Size(kit))
+
+			valid, err := IsValidSize(test.blobType, len(ctxt))
+			require.NoError(t, err)
+			require.True(t, valid)
+
+			// A blob that isn't padded to a whole batch of HTLC
+			// outputs is never valid.
+			valid, err = IsValidSize(test.blobType, len(ctxt)+1)
+			require.NoError(t, err)
+			require.False(t, valid)
+
+			kit2, err := Decrypt(key, ctxt, test.blobType)
+			require.NoError(t, err)
+			require.Equal(t, kit, kit2)
+		})
+	}
+}
+
+// TestHtlcJusticeKitSpendInfo asserts that a justice kit rebuilds the scripts
+// and revocation witnesses of the revoked segwit v0 HTLC outputs it holds, and
+// of the second level outputs of legacy channels.
+func TestHtlcJusticeKitSpendInfo(t *testing.T) {
+	for _, blobType := range []Type{
+		TypeAltruistHtlcCommit, TypeAltruistAnchorHtlcCommit,
+	} {
+		for _, incoming := range []bool{true, false} {
+			name := fmt.Sprintf("%v incoming=%v", blobType,
+				incoming)
+			t.Run(name, func(t *testing.T) {
+				testHtlcJusticeKitSpendInfo(
+					t, blobType, incoming,
+				)
+			})
+		}
+	}
+}
+
+func testHtlcJusticeKitSpendInfo(t *testing.T, blobType Type, incoming bool) {
+	commitType, err := blobType.CommitmentType(nil)
+	require.NoError(t, err)
+
+	revKey := makePubKey()
+	localHtlcKey := makePubKey()
+	remoteHtlcKey := makePubKey()
+
+	breachInfo := &lnwallet.BreachRetribution{
+		RemoteDelay: csvDelay,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			ToLocalKey:    makePubKey(),
+			RevocationKey: revKey,
+			LocalHtlcKey:  localHtlcKey,
+			RemoteHtlcKey: remoteHtlcKey,
+		},
+	}
+
+	kit, err := commitType.NewHtlcJusticeKit(
+		makeAddr(22), breachInfo, false,
+	)
+	require.NoError(t, err)
+
+	htlc := &lnwallet.HtlcRetribution{
+		OutPoint:      wire.OutPoint{Index: 3},
+		IsIncoming:    incoming,
+		RefundTimeout: 500,
+	}
+	copy(htlc.PaymentHash[:], makeAddr(32))
+
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	rawSig := ecdsa.Sign(privKey, bytes.Repeat([]byte("a"), 32))
+	sig, err := lnwire.NewSigFromSignature(rawSig)
+	require.NoError(t, err)
+
+	// Only the kits of legacy channels can presign the sweep of the
+	// breaching party's second level transaction.
+	secondLevel := !blobType.IsAnchorChannel()
+	secondLevelSig := fn.None[lnwire.Sig]()
+	if secondLevel {
+		secondLevelSig = fn.Some(sig)
+	}
+
+	require.NoError(t, kit.AddHtlcOutput(htlc, sig, secondLevelSig))
+
+	// Rebuild the expected script, as seen from the breaching party's
+	// commitment transaction.
+	confirmed := blobType.Has(FlagAnchorChannel)
+
+	var expScript []byte
+	if incoming {
+		expScript, err = input.SenderHTLCScript(
+			remoteHtlcKey, localHtlcKey, revKey,
+			htlc.PaymentHash[:], confirmed,
+		)
+	} else {
+		expScript, err = input.ReceiverHTLCScript(
+			htlc.RefundTimeout, localHtlcKey, remoteHtlcKey,
+			revKey, htlc.PaymentHash[:], confirmed,
+		)
+	}
+	require.NoError(t, err)
+
+	expPkScript, err := input.WitnessScriptHash(expScript)
+	require.NoError(t, err)
+
+	spendInfos, err := kit.HtlcOutputsSpendInfo()
+	require.NoError(t, err)
+	require.Len(t, spendInfos, 1)
+
+	spendInfo := spendInfos[0]
+	require.Equal(t, htlc.OutPoint.Index, spendInfo.OutputIndex)
+	require.Equal(t, incoming, spendInfo.Incoming)
+	require.Equal(t, expPkScript, spendInfo.PkScript.Script())
+
+	expWitness := wire.TxWitness{
+		append(rawSig.Serialize(), byte(txscript.SigHashAll)),
+		revKey.SerializeCompressed(),
+		expScript,
+	}
+	require.Equal(t, expWitness, spendInfo.Witness)
+
+	if !secondLevel {
+		require.Nil(t, spendInfo.SecondLevel)
+		return
+	}
+
+	// The second level output pays to the to-local keys and delay of the
+	// breaching party, and is spent through its revocation path.
+	expSecondLevelScript, err := input.SecondLevelHtlcScript(
+		revKey, breachInfo.KeyRing.ToLocalKey, csvDelay,
+	)
+	require.NoError(t, err)
+
+	expSecondLevelPkScript, err := input.WitnessScriptHash(
+		expSecondLevelScript,
+	)
+	require.NoError(t, err)
+
+	require.NotNil(t, spendInfo.SecondLevel)
+	require.Equal(
+		t, expSecondLevelPkScript,
+		spendInfo.SecondLevel.PkScript.Script(),
+	)
+
+	expSecondLevelWitness := wire.TxWitness{
+		append(rawSig.Serialize(), byte(txscript.SigHashAll)),
+		{1},
+		expSecondLevelScript,
+	}
+	require.Equal(t, expSecondLevelWitness, spendInfo.SecondLevel.Witness)
+}
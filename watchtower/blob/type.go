@@ -30,6 +30,11 @@ const (
 	// FlagTaprootChannel signals that this blob is meant to spend a
 	// taproot channel and therefore must expect P2TR outputs.
 	FlagTaprootChannel Flag = 1 << 3
+
+	// FlagHtlcOutputs signals that the blob also contains the information
+	// required to sweep the HTLC outputs of the revoked commitment. Each
+	// HTLC output is swept by a justice transaction of its own.
+	FlagHtlcOutputs Flag = 1 << 4
 )
 
 // Type returns a Type consisting solely of this flag enabled.
@@ -48,6 +53,8 @@ func (f Flag) String() string {
 		return "FlagAnchorChannel"
 	case FlagTaprootChannel:
 		return "FlagTaprootChannel"
+	case FlagHtlcOutputs:
+		return "FlagHtlcOutputs"
 	default:
 		return "FlagUnknown"
 	}
@@ -78,6 +85,25 @@ const (
 	// taproot channel commitment to a sweep address controlled by the user,
 	// and does not give the tower a reward.
 	TypeAltruistTaprootCommit = Type(FlagCommitOutputs | FlagTaprootChannel)
+
+	// TypeAltruistHtlcCommit sweeps the commitment outputs and the HTLC
+	// outputs to a sweep address controlled by the user, and does not give
+	// the tower a reward.
+	TypeAltruistHtlcCommit = Type(FlagCommitOutputs | FlagHtlcOutputs)
+
+	// TypeAltruistAnchorHtlcCommit sweeps the commitment outputs and the
+	// HTLC outputs from an anchor commitment to a sweep address controlled
+	// by the user, and does not give the tower a reward.
+	TypeAltruistAnchorHtlcCommit = Type(
+		FlagCommitOutputs | FlagAnchorChannel | FlagHtlcOutputs,
+	)
+
+	// TypeAltruistTaprootHtlcCommit sweeps the commitment outputs and the
+	// HTLC outputs from a taproot channel commitment to a sweep address
+	// controlled by the user, and does not give the tower a reward.
+	TypeAltruistTaprootHtlcCommit = Type(
+		FlagCommitOutputs | FlagTaprootChannel | FlagHtlcOutputs,
+	)
 )
 
 // TypeFromChannel returns the appropriate blob Type for the given channel
@@ -104,6 +130,12 @@ func (t Type) Identifier() (string, error) {
 		return "reward", nil
 	case TypeAltruistTaprootCommit:
 		return "taproot", nil
+	case TypeAltruistHtlcCommit:
+		return "legacy-htlc", nil
+	case TypeAltruistAnchorHtlcCommit:
+		return "anchor-htlc", nil
+	case TypeAltruistTaprootHtlcCommit:
+		return "taproot-htlc", nil
 	default:
 		return "", fmt.Errorf("unknown blob type: %v", t)
 	}
@@ -158,12 +190,19 @@ func (t Type) IsTaprootChannel() bool {
 	return t.Has(FlagTaprootChannel)
 }
 
+// HasHtlcOutputs returns true if the blob type also sweeps the HTLC outputs
+// of the revoked commitment.
+func (t Type) HasHtlcOutputs() bool {
+	return t.Has(FlagHtlcOutputs)
+}
+
 // knownFlags maps the supported flags to their name.
 var knownFlags = map[Flag]struct{}{
 	FlagReward:         {},
 	FlagCommitOutputs:  {},
 	FlagAnchorChannel:  {},
 	FlagTaprootChannel: {},
+	FlagHtlcOutputs:    {},
 }
 
 // String returns a human-readable description of a Type.
@@ -210,10 +249,13 @@ func (t Type) String() string {
 // supportedTypes is the set of all configurations known to be supported by the
 // package.
 var supportedTypes = map[Type]struct{}{
-	TypeAltruistCommit:        {},
-	TypeRewardCommit:          {},
-	TypeAltruistAnchorCommit:  {},
-	TypeAltruistTaprootCommit: {},
+	TypeAltruistCommit:            {},
+	TypeRewardCommit:              {},
+	TypeAltruistAnchorCommit:      {},
+	TypeAltruistTaprootCommit:     {},
+	TypeAltruistHtlcCommit:        {},
+	TypeAltruistAnchorHtlcCommit:  {},
+	TypeAltruistTaprootHtlcCommit: {},
 }
 
 // IsSupportedType returns true if the given type is supported by the package.
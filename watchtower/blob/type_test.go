@@ -6,7 +6,7 @@ import (
 	"github.com/lightningnetwork/lnd/watchtower/blob"
 )
 
-var unknownFlag = blob.Flag(16)
+var unknownFlag = blob.Flag(32)
 
 type typeStringTest struct {
 	name   string
@@ -18,7 +18,8 @@ var typeStringTests = []typeStringTest{
 	{
 		name: "commit no-reward",
 		typ:  blob.TypeAltruistCommit,
-		expStr: "[No-FlagTaprootChannel|" +
+		expStr: "[No-FlagHtlcOutputs|" +
+			"No-FlagTaprootChannel|" +
 			"No-FlagAnchorChannel|" +
 			"FlagCommitOutputs|" +
 			"No-FlagReward]",
@@ -26,15 +27,26 @@ var typeStringTests = []typeStringTest{
 	{
 		name: "commit reward",
 		typ:  blob.TypeRewardCommit,
-		expStr: "[No-FlagTaprootChannel|" +
+		expStr: "[No-FlagHtlcOutputs|" +
+			"No-FlagTaprootChannel|" +
 			"No-FlagAnchorChannel|" +
 			"FlagCommitOutputs|" +
 			"FlagReward]",
 	},
+	{
+		name: "anchor commit htlcs no-reward",
+		typ:  blob.TypeAltruistAnchorHtlcCommit,
+		expStr: "[FlagHtlcOutputs|" +
+			"No-FlagTaprootChannel|" +
+			"FlagAnchorChannel|" +
+			"FlagCommitOutputs|" +
+			"No-FlagReward]",
+	},
 	{
 		name: "unknown flag",
 		typ:  unknownFlag.Type(),
-		expStr: "0000000000010000[No-FlagTaprootChannel|" +
+		expStr: "0000000000100000[No-FlagHtlcOutputs|" +
+			"No-FlagTaprootChannel|" +
 			"No-FlagAnchorChannel|" +
 			"No-FlagCommitOutputs|" +
 			"No-FlagReward]",
@@ -138,6 +150,12 @@ func TestSupportedTypes(t *testing.T) {
 			blob.TypeAltruistAnchorCommit)
 	}
 
+	// Assert that the altruist HTLC sweeping types are supported.
+	if !blob.IsSupportedType(blob.TypeAltruistTaprootHtlcCommit) {
+		t.Fatalf("default type %s is not supported",
+			blob.TypeAltruistTaprootHtlcCommit)
+	}
+
 	// Assert that all claimed supported types are actually supported.
 	for _, supType := range blob.SupportedTypes() {
 		if blob.IsSupportedType(supType) {
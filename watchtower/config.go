@@ -55,6 +55,11 @@ type Config struct {
 	// corresponding to newly created blocks.
 	EpochRegistrar lookout.EpochRegistrar
 
+	// SpendRegistrar supports the ability to register for the spends of
+	// revoked HTLC outputs, so that the outputs of second level HTLC
+	// transactions can be swept.
+	SpendRegistrar lookout.SpendRegistrar
+
 	// Net specifies the network type that the watchtower will use to listen
 	// for client connections. Either a clear net or Tor are supported.
 	Net tor.Net
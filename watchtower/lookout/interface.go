@@ -57,6 +57,16 @@ type EpochRegistrar interface {
 		*chainntnfs.BlockEpoch) (*chainntnfs.BlockEpochEvent, error)
 }
 
+// SpendRegistrar supports the ability to register for the spends of
+// outpoints.
+type SpendRegistrar interface {
+	// RegisterSpendNtfn registers for a notification once the given
+	// outpoint, which pays to the given pkScript, is spent. The height hint
+	// is the earliest height the outpoint could have been spent at.
+	RegisterSpendNtfn(outpoint *wire.OutPoint, pkScript []byte,
+		heightHint uint32) (*chainntnfs.SpendEvent, error)
+}
+
 // Punisher handles the construction and publication of justice transactions
 // once they have been detected by the Service.
 type Punisher interface {
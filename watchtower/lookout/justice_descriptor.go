@@ -1,6 +1,7 @@
 package lookout
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 
@@ -24,6 +25,10 @@ var (
 	// ErrUnknownSweepAddrType signals that client provided an output that
 	// was not p2wkh or p2wsh.
 	ErrUnknownSweepAddrType = errors.New("sweep addr is not p2wkh or p2wsh")
+
+	// ErrNoSecondLevelSig signals that the client didn't presign the sweep
+	// of the second level output of an HTLC.
+	ErrNoSecondLevelSig = errors.New("no second level sweep signature")
 )
 
 // JusticeDescriptor contains the information required to sweep a breached
@@ -35,6 +40,10 @@ type JusticeDescriptor struct {
 	// to be detected.
 	BreachedCommitTx *wire.MsgTx
 
+	// BreachHeight is the height of the block the breaching commitment
+	// transaction was found in.
+	BreachHeight uint32
+
 	// SessionInfo contains the contract with the watchtower client and
 	// the prenegotiated terms they agreed to.
 	SessionInfo *wtdb.SessionInfo
@@ -224,23 +233,10 @@ func (p *JusticeDescriptor) CreateJusticeTxn() (*wire.MsgTx, error) {
 		return nil, err
 	}
 
-	// Add the sweep address's contribution, depending on whether it is a
-	// p2wkh or p2wsh output.
-	switch len(p.JusticeKit.SweepAddress()) {
-	case input.P2WPKHSize:
-		weightEstimate.AddP2WKHOutput()
-
-	case input.P2WSHSize:
-		weightEstimate.AddP2WSHOutput()
-
-	default:
-		return nil, ErrUnknownSweepAddrType
-	}
-
-	// Add our reward address to the weight estimate if the policy's blob
-	// type specifies a reward output.
-	if p.SessionInfo.Policy.BlobType.Has(blob.FlagReward) {
-		weightEstimate.AddP2WKHOutput()
+	// Add the contribution of the sweep and reward outputs.
+	err = p.addOutputsWeight(&weightEstimate)
+	if err != nil {
+		return nil, err
 	}
 
 	// Assemble the breached to-local output from the justice descriptor and
@@ -286,13 +282,172 @@ func (p *JusticeDescriptor) CreateJusticeTxn() (*wire.MsgTx, error) {
 		weightEstimate.AddWitnessInput(toRemoteWitnessSize)
 	}
 
-	// TODO(conner): sweep htlc outputs
-
 	txWeight := weightEstimate.Weight()
 
 	return p.assembleJusticeTxn(txWeight, sweepInputs...)
 }
 
+// CreateHtlcJusticeTxns computes the justice transactions that sweep the
+// revoked HTLC outputs of a breaching commitment transaction, one for each HTLC
+// output held by the justice kit. Since each HTLC output is swept on its own,
+// the breaching party taking an HTLC to the second level only invalidates the
+// justice transaction of that HTLC. HTLC outputs that can't be swept are
+// logged and skipped, so that they don't prevent sweeping the others.
+func (p *JusticeDescriptor) CreateHtlcJusticeTxns() ([]*wire.MsgTx, error) {
+	commitmentType, err := p.SessionInfo.Policy.BlobType.CommitmentType(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	spendInfos, err := p.JusticeKit.HtlcOutputsSpendInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	justiceTxns := make([]*wire.MsgTx, 0, len(spendInfos))
+	for _, spendInfo := range spendInfos {
+		justiceTxn, err := p.createHtlcJusticeTxn(
+			commitmentType, spendInfo,
+		)
+		if err != nil {
+			log.Errorf("Unable to create justice txn for htlc "+
+				"output %d of breach-txid=%s: %v",
+				spendInfo.OutputIndex,
+				p.BreachedCommitTx.TxHash(), err)
+
+			continue
+		}
+
+		justiceTxns = append(justiceTxns, justiceTxn)
+	}
+
+	return justiceTxns, nil
+}
+
+// createHtlcJusticeTxn computes the justice transaction that sweeps the given
+// revoked HTLC output.
+func (p *JusticeDescriptor) createHtlcJusticeTxn(
+	commitmentType blob.CommitmentType,
+	spendInfo *blob.HtlcOutputSpendInfo) (*wire.MsgTx, error) {
+
+	// Locate the HTLC output on the breaching commitment transaction, and
+	// make sure it pays to the script rebuilt from the justice kit.
+	index := spendInfo.OutputIndex
+	if int(index) >= len(p.BreachedCommitTx.TxOut) {
+		return nil, ErrOutputNotFound
+	}
+
+	txOut := p.BreachedCommitTx.TxOut[index]
+	if !bytes.Equal(txOut.PkScript, spendInfo.PkScript.Script()) {
+		return nil, ErrOutputNotFound
+	}
+
+	htlcInput := &breachedInput{
+		txOut: txOut,
+		outPoint: wire.OutPoint{
+			Hash:  p.BreachedCommitTx.TxHash(),
+			Index: index,
+		},
+		witness: spendInfo.Witness,
+	}
+
+	var weightEstimate input.TxWeightEstimator
+
+	err := p.addOutputsWeight(&weightEstimate)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessSize, err := commitmentType.HtlcWitnessSize(spendInfo.Incoming)
+	if err != nil {
+		return nil, err
+	}
+	weightEstimate.AddWitnessInput(witnessSize)
+
+	return p.assembleJusticeTxn(weightEstimate.Weight(), htlcInput)
+}
+
+// CreateHtlcSecondLevelJusticeTxn computes the justice transaction that sweeps
+// the output of the second level transaction the breaching party spent the
+// given revoked HTLC output with. This is only possible if the client presigned
+// the justice transaction, which requires the second level transaction to be
+// known ahead of time. An error is returned if the given transaction doesn't
+// pay to the second level script of the HTLC.
+func (p *JusticeDescriptor) CreateHtlcSecondLevelJusticeTxn(
+	spendInfo *blob.HtlcOutputSpendInfo,
+	secondLevelTx *wire.MsgTx) (*wire.MsgTx, error) {
+
+	secondLevel := spendInfo.SecondLevel
+	if secondLevel == nil {
+		return nil, ErrNoSecondLevelSig
+	}
+
+	commitmentType, err := p.SessionInfo.Policy.BlobType.CommitmentType(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locate the second level output, and make sure it pays to the script
+	// rebuilt from the justice kit.
+	index, txOut, err := findTxOutByPkScript(
+		secondLevelTx, secondLevel.PkScript,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	secondLevelInput := &breachedInput{
+		txOut: txOut,
+		outPoint: wire.OutPoint{
+			Hash:  secondLevelTx.TxHash(),
+			Index: index,
+		},
+		witness: secondLevel.Witness,
+	}
+
+	var weightEstimate input.TxWeightEstimator
+
+	err = p.addOutputsWeight(&weightEstimate)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessSize, err := commitmentType.HtlcSecondLevelWitnessSize()
+	if err != nil {
+		return nil, err
+	}
+	weightEstimate.AddWitnessInput(witnessSize)
+
+	return p.assembleJusticeTxn(weightEstimate.Weight(), secondLevelInput)
+}
+
+// addOutputsWeight adds the weight of the sweep output, and of the reward
+// output if the policy specifies one, to the given weight estimate.
+func (p *JusticeDescriptor) addOutputsWeight(
+	weightEstimate *input.TxWeightEstimator) error {
+
+	// Add the sweep address's contribution, depending on whether it is a
+	// p2wkh or p2wsh output.
+	switch len(p.JusticeKit.SweepAddress()) {
+	case input.P2WPKHSize:
+		weightEstimate.AddP2WKHOutput()
+
+	case input.P2WSHSize:
+		weightEstimate.AddP2WSHOutput()
+
+	default:
+		return ErrUnknownSweepAddrType
+	}
+
+	// Add our reward address to the weight estimate if the policy's blob
+	// type specifies a reward output.
+	if p.SessionInfo.Policy.BlobType.Has(blob.FlagReward) {
+		weightEstimate.AddP2WKHOutput()
+	}
+
+	return nil
+}
+
 // findTxOutByPkScript searches the given transaction for an output whose
 // pkscript matches the query. If one is found, the TxOut is returned along with
 // the index.
@@ -1,6 +1,7 @@
 package lookout_test
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -11,8 +12,11 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	secp "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/watchtower/blob"
@@ -515,3 +519,603 @@ func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
 	// Assert that the watchtower derives the same justice txn.
 	require.Equal(t, justiceTxn, wtJusticeTxn)
 }
+
+// TestHtlcJusticeTxns asserts that a JusticeDescriptor is able to produce the
+// correct justice transactions for the revoked HTLC outputs of a breach, and
+// skips the HTLC outputs it can't sweep.
+func TestHtlcJusticeTxns(t *testing.T) {
+	tests := []struct {
+		name     string
+		blobType blob.Type
+	}{
+		{
+			name:     "legacy htlc commit type",
+			blobType: blob.TypeAltruistHtlcCommit,
+		},
+		{
+			name:     "reward htlc commit type",
+			blobType: rewardCommitType | blob.Type(blob.FlagHtlcOutputs),
+		},
+		{
+			name:     "anchor htlc commit type",
+			blobType: blob.TypeAltruistAnchorHtlcCommit,
+		},
+		{
+			name:     "taproot htlc commit type",
+			blobType: blob.TypeAltruistTaprootHtlcCommit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testHtlcJusticeTxns(t, test.blobType)
+		})
+	}
+}
+
+func testHtlcJusticeTxns(t *testing.T, blobType blob.Type) {
+	const (
+		incomingAmount = btcutil.Amount(50000)
+		outgoingAmount = btcutil.Amount(60000)
+		refundTimeout  = uint32(800)
+	)
+
+	revSK, revPK := btcec.PrivKeyFromBytes(revPrivBytes)
+	_, toLocalPK := btcec.PrivKeyFromBytes(toLocalPrivBytes)
+	localHtlcSK, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remoteHtlcSK, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	localHtlcPK := localHtlcSK.PubKey()
+	remoteHtlcPK := remoteHtlcSK.PubKey()
+
+	commitType, err := blobType.CommitmentType(nil)
+	require.NoError(t, err)
+
+	isTaproot := blobType.IsTaprootChannel()
+	confirmed := blobType.IsAnchorChannel()
+
+	signer := wtmock.NewMockSigner()
+	revKeyLoc := signer.AddPrivKey(revSK)
+
+	incomingHash := [32]byte{1}
+	outgoingHash := [32]byte{2}
+
+	// Construct the scripts of the HTLC outputs, as seen from the
+	// breaching party's commitment transaction.
+	var (
+		incomingScript, incomingPkScript, incomingTweak []byte
+		outgoingScript, outgoingPkScript, outgoingTweak []byte
+	)
+	if isTaproot {
+		incomingTree, err := input.SenderHTLCScriptTaproot(
+			remoteHtlcPK, localHtlcPK, revPK, incomingHash[:],
+			lntypes.Remote,
+		)
+		require.NoError(t, err)
+
+		incomingPkScript, err = input.PayToTaprootScript(
+			incomingTree.TaprootKey,
+		)
+		require.NoError(t, err)
+		incomingTweak = incomingTree.TapscriptRoot
+
+		outgoingTree, err := input.ReceiverHTLCScriptTaproot(
+			refundTimeout, localHtlcPK, remoteHtlcPK, revPK,
+			outgoingHash[:], lntypes.Remote,
+		)
+		require.NoError(t, err)
+
+		outgoingPkScript, err = input.PayToTaprootScript(
+			outgoingTree.TaprootKey,
+		)
+		require.NoError(t, err)
+		outgoingTweak = outgoingTree.TapscriptRoot
+	} else {
+		incomingScript, err = input.SenderHTLCScript(
+			remoteHtlcPK, localHtlcPK, revPK, incomingHash[:],
+			confirmed,
+		)
+		require.NoError(t, err)
+
+		incomingPkScript, err = input.WitnessScriptHash(incomingScript)
+		require.NoError(t, err)
+
+		outgoingScript, err = input.ReceiverHTLCScript(
+			refundTimeout, localHtlcPK, remoteHtlcPK, revPK,
+			outgoingHash[:], confirmed,
+		)
+		require.NoError(t, err)
+
+		outgoingPkScript, err = input.WitnessScriptHash(outgoingScript)
+		require.NoError(t, err)
+	}
+
+	breachTxn := &wire.MsgTx{
+		Version: 2,
+		TxIn:    []*wire.TxIn{},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    int64(incomingAmount),
+				PkScript: incomingPkScript,
+			},
+			{
+				Value:    int64(outgoingAmount),
+				PkScript: outgoingPkScript,
+			},
+		},
+	}
+	breachTxID := breachTxn.TxHash()
+
+	policy := wtpolicy.Policy{
+		TxPolicy: wtpolicy.TxPolicy{
+			BlobType:     blobType,
+			SweepFeeRate: 2000,
+			RewardRate:   900000,
+		},
+	}
+	sessionInfo := &wtdb.SessionInfo{
+		Policy:        policy,
+		RewardAddress: makeAddrSlice(22),
+	}
+
+	breachInfo := &lnwallet.BreachRetribution{
+		RemoteDelay: csvDelay,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			ToLocalKey:    toLocalPK,
+			RevocationKey: revPK,
+			LocalHtlcKey:  localHtlcPK,
+			RemoteHtlcKey: remoteHtlcPK,
+		},
+	}
+
+	justiceKit, err := commitType.NewHtlcJusticeKit(
+		makeAddrSlice(22), breachInfo, false,
+	)
+	require.NoError(t, err)
+
+	// signHtlc creates the justice transaction sweeping the HTLC output at
+	// the given index, and adds its signature to the justice kit. The
+	// expected justice transaction is returned.
+	var lastSig lnwire.Sig
+	signHtlc := func(index uint32, incoming bool, hash [32]byte,
+		witnessScript, tapTweak []byte) *wire.MsgTx {
+
+		txOut := breachTxn.TxOut[index]
+		outPoint := wire.OutPoint{Hash: breachTxID, Index: index}
+
+		witnessSize, err := commitType.HtlcWitnessSize(incoming)
+		require.NoError(t, err)
+
+		var weightEstimate input.TxWeightEstimator
+		weightEstimate.AddWitnessInput(witnessSize)
+		weightEstimate.AddP2WKHOutput()
+		if blobType.Has(blob.FlagReward) {
+			weightEstimate.AddP2WKHOutput()
+		}
+
+		outputs, err := policy.ComputeJusticeTxOuts(
+			btcutil.Amount(txOut.Value), weightEstimate.Weight(),
+			justiceKit.SweepAddress(), sessionInfo.RewardAddress,
+		)
+		require.NoError(t, err)
+
+		justiceTxn := &wire.MsgTx{
+			Version: 2,
+			TxIn: []*wire.TxIn{{
+				PreviousOutPoint: outPoint,
+			}},
+			TxOut: outputs,
+		}
+		txsort.InPlaceSort(justiceTxn)
+
+		prevOutputFetcher := txscript.NewCannedPrevOutputFetcher(
+			txOut.PkScript, txOut.Value,
+		)
+		signDesc := &input.SignDescriptor{
+			KeyDesc: keychain.KeyDescriptor{
+				KeyLocator: revKeyLoc,
+			},
+			WitnessScript:     witnessScript,
+			Output:            txOut,
+			PrevOutputFetcher: prevOutputFetcher,
+			SigHashes: txscript.NewTxSigHashes(
+				justiceTxn, prevOutputFetcher,
+			),
+			HashType: txscript.SigHashAll,
+		}
+		if isTaproot {
+			signDesc.HashType = txscript.SigHashDefault
+			signDesc.SignMethod = input.TaprootKeySpendSignMethod
+			signDesc.TapTweak = tapTweak
+		}
+
+		rawSig, err := signer.SignOutputRaw(justiceTxn, signDesc)
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromSignature(rawSig)
+		require.NoError(t, err)
+
+		htlc := &lnwallet.HtlcRetribution{
+			SignDesc:      *signDesc,
+			OutPoint:      outPoint,
+			IsIncoming:    incoming,
+			PaymentHash:   hash,
+			RefundTimeout: refundTimeout,
+		}
+		err = justiceKit.AddHtlcOutput(htlc, sig, fn.None[lnwire.Sig]())
+		require.NoError(t, err)
+		lastSig = sig
+
+		if isTaproot {
+			justiceTxn.TxIn[0].Witness = wire.TxWitness{
+				rawSig.Serialize(),
+			}
+		} else {
+			justiceTxn.TxIn[0].Witness = wire.TxWitness{
+				append(
+					rawSig.Serialize(),
+					byte(txscript.SigHashAll),
+				),
+				revPK.SerializeCompressed(),
+				witnessScript,
+			}
+		}
+
+		return justiceTxn
+	}
+
+	expJusticeTxns := []*wire.MsgTx{
+		signHtlc(0, true, incomingHash, incomingScript, incomingTweak),
+		signHtlc(1, false, outgoingHash, outgoingScript, outgoingTweak),
+	}
+
+	// Add an HTLC output that isn't present on the breach transaction,
+	// which the tower should skip.
+	err = justiceKit.AddHtlcOutput(&lnwallet.HtlcRetribution{
+		OutPoint: wire.OutPoint{Hash: breachTxID, Index: 5},
+	}, lastSig, fn.None[lnwire.Sig]())
+	require.NoError(t, err)
+
+	justiceDesc := &lookout.JusticeDescriptor{
+		BreachedCommitTx: breachTxn,
+		SessionInfo:      sessionInfo,
+		JusticeKit:       justiceKit,
+	}
+
+	justiceTxns, err := justiceDesc.CreateHtlcJusticeTxns()
+	require.NoError(t, err)
+	require.Equal(t, expJusticeTxns, justiceTxns)
+}
+
+// mockSpendRegistrar is a lookout.SpendRegistrar that delivers the spends sent
+// over the channel of an outpoint to its spend registration.
+type mockSpendRegistrar struct {
+	mu     sync.Mutex
+	spends map[wire.OutPoint]chan *chainntnfs.SpendDetail
+}
+
+func newMockSpendRegistrar() *mockSpendRegistrar {
+	return &mockSpendRegistrar{
+		spends: make(map[wire.OutPoint]chan *chainntnfs.SpendDetail),
+	}
+}
+
+// spendChan returns the channel the spends of the given outpoint are delivered
+// over.
+func (m *mockSpendRegistrar) spendChan(
+	outPoint wire.OutPoint) chan *chainntnfs.SpendDetail {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	spends, ok := m.spends[outPoint]
+	if !ok {
+		spends = make(chan *chainntnfs.SpendDetail, 1)
+		m.spends[outPoint] = spends
+	}
+
+	return spends
+}
+
+// RegisterSpendNtfn registers for the spend of the given outpoint.
+func (m *mockSpendRegistrar) RegisterSpendNtfn(outPoint *wire.OutPoint,
+	_ []byte, _ uint32) (*chainntnfs.SpendEvent, error) {
+
+	return &chainntnfs.SpendEvent{
+		Spend:  m.spendChan(*outPoint),
+		Cancel: func() {},
+	}, nil
+}
+
+// TestHtlcSecondLevelJustice asserts that the punisher sweeps the output of the
+// second level transaction the breaching party spends a revoked HTLC output
+// with, and that it doesn't publish anything else if the HTLC output is swept
+// by its own justice transaction.
+func TestHtlcSecondLevelJustice(t *testing.T) {
+	tests := []struct {
+		name        string
+		blobType    blob.Type
+		secondLevel bool
+	}{
+		{
+			name:        "altruist second level spend",
+			blobType:    blob.TypeAltruistHtlcCommit,
+			secondLevel: true,
+		},
+		{
+			name: "reward second level spend",
+			blobType: rewardCommitType |
+				blob.Type(blob.FlagHtlcOutputs),
+			secondLevel: true,
+		},
+		{
+			name:        "altruist justice spend",
+			blobType:    blob.TypeAltruistHtlcCommit,
+			secondLevel: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testHtlcSecondLevelJustice(
+				t, test.blobType, test.secondLevel,
+			)
+		})
+	}
+}
+
+func testHtlcSecondLevelJustice(t *testing.T, blobType blob.Type,
+	secondLevel bool) {
+
+	const (
+		htlcAmount     = btcutil.Amount(100000)
+		secondLevelFee = btcutil.Amount(1000)
+		refundTimeout  = uint32(800)
+	)
+
+	revSK, revPK := btcec.PrivKeyFromBytes(revPrivBytes)
+	_, toLocalPK := btcec.PrivKeyFromBytes(toLocalPrivBytes)
+	localHtlcSK, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remoteHtlcSK, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	localHtlcPK := localHtlcSK.PubKey()
+	remoteHtlcPK := remoteHtlcSK.PubKey()
+
+	signer := wtmock.NewMockSigner()
+	revKeyLoc := signer.AddPrivKey(revSK)
+
+	commitType, err := blobType.CommitmentType(nil)
+	require.NoError(t, err)
+
+	// The breaching party offered the HTLC to us, so they can take it to
+	// the second level with their HTLC timeout transaction.
+	paymentHash := [32]byte{1}
+	htlcScript, err := input.SenderHTLCScript(
+		remoteHtlcPK, localHtlcPK, revPK, paymentHash[:], false,
+	)
+	require.NoError(t, err)
+
+	htlcPkScript, err := input.WitnessScriptHash(htlcScript)
+	require.NoError(t, err)
+
+	breachTxn := &wire.MsgTx{
+		Version: 2,
+		TxIn:    []*wire.TxIn{},
+		TxOut: []*wire.TxOut{{
+			Value:    int64(htlcAmount),
+			PkScript: htlcPkScript,
+		}},
+	}
+	htlcOutPoint := wire.OutPoint{Hash: breachTxn.TxHash()}
+
+	secondLevelScript, err := input.SecondLevelHtlcScript(
+		revPK, toLocalPK, csvDelay,
+	)
+	require.NoError(t, err)
+
+	secondLevelPkScript, err := input.WitnessScriptHash(secondLevelScript)
+	require.NoError(t, err)
+
+	secondLevelTx := &wire.MsgTx{
+		Version: 2,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: htlcOutPoint,
+		}},
+		TxOut: []*wire.TxOut{{
+			Value:    int64(htlcAmount - secondLevelFee),
+			PkScript: secondLevelPkScript,
+		}},
+		LockTime: refundTimeout,
+	}
+	secondLevelOutPoint := wire.OutPoint{Hash: secondLevelTx.TxHash()}
+
+	policy := wtpolicy.Policy{
+		TxPolicy: wtpolicy.TxPolicy{
+			BlobType:     blobType,
+			SweepFeeRate: 2000,
+			RewardRate:   900000,
+		},
+	}
+	sessionInfo := &wtdb.SessionInfo{
+		Policy:        policy,
+		RewardAddress: makeAddrSlice(22),
+	}
+
+	breachInfo := &lnwallet.BreachRetribution{
+		RemoteDelay: csvDelay,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			ToLocalKey:    toLocalPK,
+			RevocationKey: revPK,
+			LocalHtlcKey:  localHtlcPK,
+			RemoteHtlcKey: remoteHtlcPK,
+		},
+	}
+
+	justiceKit, err := commitType.NewHtlcJusticeKit(
+		makeAddrSlice(22), breachInfo, false,
+	)
+	require.NoError(t, err)
+
+	// signJusticeTxn creates the justice transaction sweeping the given
+	// output, and signs it with the revocation key as the client would.
+	signJusticeTxn := func(outPoint wire.OutPoint, txOut *wire.TxOut,
+		witnessScript []byte,
+		witnessSize lntypes.WeightUnit) (*wire.MsgTx, input.Signature) {
+
+		var weightEstimate input.TxWeightEstimator
+		weightEstimate.AddWitnessInput(witnessSize)
+		weightEstimate.AddP2WKHOutput()
+		if blobType.Has(blob.FlagReward) {
+			weightEstimate.AddP2WKHOutput()
+		}
+
+		outputs, err := policy.ComputeJusticeTxOuts(
+			btcutil.Amount(txOut.Value), weightEstimate.Weight(),
+			justiceKit.SweepAddress(), sessionInfo.RewardAddress,
+		)
+		require.NoError(t, err)
+
+		justiceTxn := &wire.MsgTx{
+			Version: 2,
+			TxIn: []*wire.TxIn{{
+				PreviousOutPoint: outPoint,
+			}},
+			TxOut: outputs,
+		}
+		txsort.InPlaceSort(justiceTxn)
+
+		prevOutputFetcher := txscript.NewCannedPrevOutputFetcher(
+			txOut.PkScript, txOut.Value,
+		)
+		signDesc := &input.SignDescriptor{
+			KeyDesc: keychain.KeyDescriptor{
+				KeyLocator: revKeyLoc,
+			},
+			WitnessScript:     witnessScript,
+			Output:            txOut,
+			PrevOutputFetcher: prevOutputFetcher,
+			SigHashes: txscript.NewTxSigHashes(
+				justiceTxn, prevOutputFetcher,
+			),
+			HashType: txscript.SigHashAll,
+		}
+
+		rawSig, err := signer.SignOutputRaw(justiceTxn, signDesc)
+		require.NoError(t, err)
+
+		return justiceTxn, rawSig
+	}
+
+	htlcWitnessSize, err := commitType.HtlcWitnessSize(true)
+	require.NoError(t, err)
+
+	htlcJusticeTxn, htlcSig := signJusticeTxn(
+		htlcOutPoint, breachTxn.TxOut[0], htlcScript, htlcWitnessSize,
+	)
+	htlcJusticeTxn.TxIn[0].Witness = wire.TxWitness{
+		append(htlcSig.Serialize(), byte(txscript.SigHashAll)),
+		revPK.SerializeCompressed(),
+		htlcScript,
+	}
+
+	secondLevelWitnessSize, err := commitType.HtlcSecondLevelWitnessSize()
+	require.NoError(t, err)
+
+	secondLevelJusticeTxn, secondLevelSig := signJusticeTxn(
+		secondLevelOutPoint, secondLevelTx.TxOut[0], secondLevelScript,
+		secondLevelWitnessSize,
+	)
+	secondLevelJusticeTxn.TxIn[0].Witness = wire.TxWitness{
+		append(secondLevelSig.Serialize(), byte(txscript.SigHashAll)),
+		{1},
+		secondLevelScript,
+	}
+
+	htlcWireSig, err := lnwire.NewSigFromSignature(htlcSig)
+	require.NoError(t, err)
+
+	secondLevelWireSig, err := lnwire.NewSigFromSignature(secondLevelSig)
+	require.NoError(t, err)
+
+	err = justiceKit.AddHtlcOutput(&lnwallet.HtlcRetribution{
+		OutPoint:      htlcOutPoint,
+		IsIncoming:    true,
+		PaymentHash:   paymentHash,
+		RefundTimeout: refundTimeout,
+	}, htlcWireSig, fn.Some(secondLevelWireSig))
+	require.NoError(t, err)
+
+	justiceDesc := &lookout.JusticeDescriptor{
+		BreachedCommitTx: breachTxn,
+		BreachHeight:     100,
+		SessionInfo:      sessionInfo,
+		JusticeKit:       justiceKit,
+	}
+
+	publications := make(chan *wire.MsgTx, 2)
+	spends := newMockSpendRegistrar()
+	punisher := lookout.NewBreachPunisher(&lookout.PunisherConfig{
+		PublishTx: func(tx *wire.MsgTx, _ string) error {
+			publications <- tx
+			return nil
+		},
+		SpendRegistrar: spends,
+	})
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	punishErr := make(chan error, 1)
+	go func() {
+		punishErr <- punisher.Punish(justiceDesc, quit)
+	}()
+
+	// The justice transaction of the HTLC output is published right away.
+	select {
+	case tx := <-publications:
+		require.Equal(t, htlcJusticeTxn, tx)
+
+	case <-time.After(time.Second):
+		t.Fatalf("punisher did not publish htlc justice txn")
+	}
+
+	// Spend the HTLC output, either with the breaching party's second
+	// level transaction or with its justice transaction.
+	spendTx := htlcJusticeTxn
+	if secondLevel {
+		spendTx = secondLevelTx
+	}
+	spends.spendChan(htlcOutPoint) <- &chainntnfs.SpendDetail{
+		SpentOutPoint: &htlcOutPoint,
+		SpendingTx:    spendTx,
+	}
+
+	// If the breaching party went to the second level, its output is swept
+	// with the justice transaction presigned by the client.
+	if secondLevel {
+		select {
+		case tx := <-publications:
+			require.Equal(t, secondLevelJusticeTxn, tx)
+
+		case <-time.After(time.Second):
+			t.Fatalf("punisher did not publish second level " +
+				"justice txn")
+		}
+	}
+
+	// Punish returns once the HTLC output is spent. As the kit only backs
+	// up the HTLC output, no commitment justice transaction is created.
+	select {
+	case err := <-punishErr:
+		require.Error(t, err)
+
+	case <-time.After(time.Second):
+		t.Fatalf("punisher did not return")
+	}
+
+	require.Empty(t, publications)
+}
@@ -227,6 +227,7 @@ func (l *Lookout) processEpoch(epoch *chainntnfs.BlockEpoch,
 
 		justiceDesc := &JusticeDescriptor{
 			BreachedCommitTx: commitTx,
+			BreachHeight:     uint32(epoch.Height),
 			SessionInfo:      match.SessionInfo,
 			JusticeKit:       justiceKit,
 		}
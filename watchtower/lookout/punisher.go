@@ -1,8 +1,12 @@
 package lookout
 
 import (
+	"sync"
+
 	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
 )
 
 // PunisherConfig houses the resources required by the Punisher.
@@ -11,6 +15,11 @@ type PunisherConfig struct {
 	// network.
 	PublishTx func(*wire.MsgTx, string) error
 
+	// SpendRegistrar allows the punisher to watch the revoked HTLC
+	// outputs, so that it can sweep the outputs of the second level
+	// transactions the breaching party spends them with.
+	SpendRegistrar SpendRegistrar
+
 	// TODO(conner) add DB tracking and spend ntfn registration to see if
 	// ours confirmed or not
 }
@@ -30,8 +39,31 @@ func NewBreachPunisher(cfg *PunisherConfig) *BreachPunisher {
 }
 
 // Punish constructs a justice transaction given a JusticeDescriptor and
-// publishes is it to the network.
+// publishes is it to the network. If the justice kit also holds revoked HTLC
+// outputs, a justice transaction is published for each of them as well. The
+// HTLC justice transactions are published even if the commitment justice
+// transaction fails, since they spend independent outputs. If the breaching
+// party takes an HTLC to the second level before its justice transaction
+// confirms, the output of their second level transaction is swept instead,
+// which is why Punish only returns once all of those HTLC outputs are spent or
+// the quit channel is closed.
 func (p *BreachPunisher) Punish(desc *JusticeDescriptor, quit <-chan struct{}) error {
+	err := p.punishCommitment(desc)
+
+	// Sweeping the HTLC outputs is best effort, so failures are only
+	// logged.
+	p.punishHtlcs(desc)
+	p.punishSecondLevelHtlcs(desc, quit)
+
+	// TODO(conner): register for spend and remove from db after
+	// confirmation
+
+	return err
+}
+
+// punishCommitment constructs and publishes the justice transaction sweeping
+// the commitment outputs of the breaching transaction.
+func (p *BreachPunisher) punishCommitment(desc *JusticeDescriptor) error {
 	justiceTxn, err := desc.CreateJusticeTxn()
 	if err != nil {
 		log.Errorf("Unable to create justice txn for "+
@@ -52,8 +84,138 @@ func (p *BreachPunisher) Punish(desc *JusticeDescriptor, quit <-chan struct{}) e
 		return err
 	}
 
-	// TODO(conner): register for spend and remove from db after
-	// confirmation
-
 	return nil
 }
+
+// punishHtlcs constructs and publishes the justice transactions sweeping the
+// revoked HTLC outputs of the breaching transaction, if the session's policy
+// backs them up.
+func (p *BreachPunisher) punishHtlcs(desc *JusticeDescriptor) {
+	if !desc.SessionInfo.Policy.BlobType.HasHtlcOutputs() {
+		return
+	}
+
+	justiceTxns, err := desc.CreateHtlcJusticeTxns()
+	if err != nil {
+		log.Errorf("Unable to create htlc justice txns for "+
+			"client=%s with breach-txid=%s: %v",
+			desc.SessionInfo.ID, desc.BreachedCommitTx.TxHash(), err)
+		return
+	}
+
+	label := labels.MakeLabel(labels.LabelTypeJusticeTransaction, nil)
+	for _, justiceTxn := range justiceTxns {
+		log.Infof("Publishing htlc justice transaction for "+
+			"client=%s with txid=%s", desc.SessionInfo.ID,
+			justiceTxn.TxHash())
+
+		err := p.cfg.PublishTx(justiceTxn, label)
+		if err != nil {
+			log.Errorf("Unable to publish htlc justice txn "+
+				"txid=%s for client=%s with breach-txid=%s: "+
+				"%v", justiceTxn.TxHash(), desc.SessionInfo.ID,
+				desc.BreachedCommitTx.TxHash(), err)
+		}
+	}
+}
+
+// punishSecondLevelHtlcs watches the revoked HTLC outputs for which the client
+// presigned the sweep of the second level output. Once the breaching party
+// spends one of them with their second level transaction, the justice
+// transaction sweeping its output is published. This method blocks until all
+// the watched HTLC outputs are spent or the quit channel is closed.
+func (p *BreachPunisher) punishSecondLevelHtlcs(desc *JusticeDescriptor,
+	quit <-chan struct{}) {
+
+	if !desc.SessionInfo.Policy.BlobType.HasHtlcOutputs() {
+		return
+	}
+
+	spendInfos, err := desc.JusticeKit.HtlcOutputsSpendInfo()
+	if err != nil {
+		log.Errorf("Unable to get htlc spend info for client=%s with "+
+			"breach-txid=%s: %v", desc.SessionInfo.ID,
+			desc.BreachedCommitTx.TxHash(), err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, spendInfo := range spendInfos {
+		if spendInfo.SecondLevel == nil {
+			continue
+		}
+
+		outPoint := wire.OutPoint{
+			Hash:  desc.BreachedCommitTx.TxHash(),
+			Index: spendInfo.OutputIndex,
+		}
+		spendEvent, err := p.cfg.SpendRegistrar.RegisterSpendNtfn(
+			&outPoint, spendInfo.PkScript.Script(),
+			desc.BreachHeight,
+		)
+		if err != nil {
+			log.Errorf("Unable to register spend of htlc output "+
+				"%v for client=%s: %v", outPoint,
+				desc.SessionInfo.ID, err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(spendInfo *blob.HtlcOutputSpendInfo) {
+			defer wg.Done()
+			defer spendEvent.Cancel()
+
+			p.punishSecondLevelHtlc(
+				desc, spendInfo, spendEvent, quit,
+			)
+		}(spendInfo)
+	}
+
+	wg.Wait()
+}
+
+// punishSecondLevelHtlc waits for the given revoked HTLC output to be spent. If
+// it is spent by the breaching party's second level transaction, the justice
+// transaction sweeping the second level output is published.
+func (p *BreachPunisher) punishSecondLevelHtlc(desc *JusticeDescriptor,
+	spendInfo *blob.HtlcOutputSpendInfo, spendEvent *chainntnfs.SpendEvent,
+	quit <-chan struct{}) {
+
+	var spendTx *wire.MsgTx
+	select {
+	case spend, ok := <-spendEvent.Spend:
+		if !ok {
+			return
+		}
+		spendTx = spend.SpendingTx
+
+	case <-quit:
+		return
+	}
+
+	// If the HTLC output was spent by its justice transaction, the
+	// spending transaction doesn't pay to the second level script and
+	// there is nothing left to sweep.
+	justiceTxn, err := desc.CreateHtlcSecondLevelJusticeTxn(
+		spendInfo, spendTx,
+	)
+	if err != nil {
+		log.Debugf("Htlc output %d of breach-txid=%s not spent by "+
+			"second level txid=%s: %v", spendInfo.OutputIndex,
+			desc.BreachedCommitTx.TxHash(), spendTx.TxHash(), err)
+		return
+	}
+
+	log.Infof("Publishing second level justice transaction for "+
+		"client=%s with txid=%s", desc.SessionInfo.ID,
+		justiceTxn.TxHash())
+
+	label := labels.MakeLabel(labels.LabelTypeJusticeTransaction, nil)
+	err = p.cfg.PublishTx(justiceTxn, label)
+	if err != nil {
+		log.Errorf("Unable to publish second level justice txn "+
+			"txid=%s for client=%s with breach-txid=%s: %v",
+			justiceTxn.TxHash(), desc.SessionInfo.ID,
+			desc.BreachedCommitTx.TxHash(), err)
+	}
+}
@@ -56,7 +56,8 @@ func New(cfg *Config) (*Standalone, error) {
 	}
 
 	punisher := lookout.NewBreachPunisher(&lookout.PunisherConfig{
-		PublishTx: cfg.PublishTx,
+		PublishTx:      cfg.PublishTx,
+		SpendRegistrar: cfg.SpendRegistrar,
 	})
 
 	// Initialize the lookout service with its required resources.
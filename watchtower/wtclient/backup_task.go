@@ -2,6 +2,7 @@ package wtclient
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcutil"
@@ -9,8 +10,11 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/watchtower/blob"
 	"github.com/lightningnetwork/lnd/watchtower/wtdb"
 )
@@ -50,6 +54,22 @@ type backupTask struct {
 
 	blobType blob.Type
 	outputs  []*wire.TxOut
+	htlcs    []*htlcBackup
+}
+
+// htlcBackup holds the values needed to sign the justice transaction of a
+// revoked HTLC output. Each HTLC output is swept by its own justice
+// transaction, so that the breaching party taking one HTLC to the second level
+// doesn't invalidate the sweep of the others. If the breaching party's second
+// level transaction is known, the justice transaction sweeping its output is
+// signed as well.
+type htlcBackup struct {
+	retribution *lnwallet.HtlcRetribution
+	input       input.Input
+	outputs     []*wire.TxOut
+
+	secondLevelInput   input.Input
+	secondLevelOutputs []*wire.TxOut
 }
 
 // newBackupTask initializes a new backupTask.
@@ -223,12 +243,161 @@ func (t *backupTask) bindSession(session *wtdb.ClientSessionBody,
 		return err
 	}
 
+	// If the session's policy backs up the revoked HTLC outputs, compute
+	// the outputs of their justice transactions as well.
+	var htlcs []*htlcBackup
+	if session.Policy.BlobType.HasHtlcOutputs() {
+		htlcs, err = t.bindHtlcs(session)
+		if err != nil {
+			return err
+		}
+	}
+
 	t.blobType = session.Policy.BlobType
 	t.outputs = outputs
+	t.htlcs = htlcs
 
 	return nil
 }
 
+// bindHtlcs computes the outputs of the justice transactions of the revoked
+// HTLC outputs under the given session's policy. HTLC outputs that aren't
+// worth sweeping at the session's fee rate are skipped. If there are more HTLC
+// outputs than a justice kit can hold, only the largest ones are backed up.
+func (t *backupTask) bindHtlcs(
+	session *wtdb.ClientSessionBody) ([]*htlcBackup, error) {
+
+	var htlcs []*htlcBackup
+	for i := range t.breachInfo.HtlcRetributions {
+		htlc := &t.breachInfo.HtlcRetributions[i]
+
+		witnessType, err := t.commitmentType.HtlcWitnessType(
+			htlc.IsIncoming,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		witnessSize, err := t.commitmentType.HtlcWitnessSize(
+			htlc.IsIncoming,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		amt := btcutil.Amount(htlc.SignDesc.Output.Value)
+		outputs, err := t.htlcJusticeTxOuts(session, amt, witnessSize)
+		if err != nil {
+			log.Debugf("Skipping htlc output %v of %v: %v",
+				htlc.OutPoint, t.id, err)
+
+			continue
+		}
+
+		backup := &htlcBackup{
+			retribution: htlc,
+			input: input.NewBaseInput(
+				&htlc.OutPoint, witnessType, &htlc.SignDesc, 0,
+			),
+			outputs: outputs,
+		}
+
+		// If the breaching party's second level transaction is known,
+		// the output it creates can be swept with a presigned justice
+		// transaction as well.
+		if htlc.SecondLevelTx != nil {
+			err := t.bindSecondLevel(session, backup)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		htlcs = append(htlcs, backup)
+	}
+
+	if len(htlcs) > blob.MaxHtlcOutputs {
+		sort.SliceStable(htlcs, func(i, j int) bool {
+			return htlcs[i].retribution.SignDesc.Output.Value >
+				htlcs[j].retribution.SignDesc.Output.Value
+		})
+		htlcs = htlcs[:blob.MaxHtlcOutputs]
+	}
+
+	return htlcs, nil
+}
+
+// bindSecondLevel computes the outputs of the justice transaction sweeping the
+// output of the breaching party's second level transaction for the given HTLC.
+// The second level output is skipped if it isn't worth sweeping at the
+// session's fee rate.
+func (t *backupTask) bindSecondLevel(session *wtdb.ClientSessionBody,
+	backup *htlcBackup) error {
+
+	htlc := backup.retribution
+
+	witnessSize, err := t.commitmentType.HtlcSecondLevelWitnessSize()
+	if err != nil {
+		return err
+	}
+
+	// The second level transaction has a single output, which pays to the
+	// second level script of the HTLC.
+	txOut := htlc.SecondLevelTx.TxOut[0]
+	outPoint := wire.OutPoint{
+		Hash:  htlc.SecondLevelTx.TxHash(),
+		Index: 0,
+	}
+
+	outputs, err := t.htlcJusticeTxOuts(
+		session, btcutil.Amount(txOut.Value), witnessSize,
+	)
+	if err != nil {
+		log.Debugf("Skipping second level output %v of %v: %v",
+			outPoint, t.id, err)
+
+		return nil
+	}
+
+	signDesc := htlc.SignDesc
+	signDesc.WitnessScript = htlc.SecondLevelWitnessScript
+	signDesc.Output = txOut
+
+	backup.secondLevelInput = input.NewBaseInput(
+		&outPoint, input.HtlcSecondLevelRevoke, &signDesc, 0,
+	)
+	backup.secondLevelOutputs = outputs
+
+	return nil
+}
+
+// htlcJusticeTxOuts computes the outputs of a justice transaction that sweeps
+// a single input of the given amount and witness size under the given
+// session's policy.
+func (t *backupTask) htlcJusticeTxOuts(session *wtdb.ClientSessionBody,
+	amt btcutil.Amount, witnessSize lntypes.WeightUnit) ([]*wire.TxOut,
+	error) {
+
+	var weightEstimate input.TxWeightEstimator
+	weightEstimate.AddWitnessInput(witnessSize)
+
+	err := addScriptWeight(&weightEstimate, t.sweepPkScript)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Policy.BlobType.Has(blob.FlagReward) {
+		err := addScriptWeight(&weightEstimate, session.RewardPkScript)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return session.Policy.ComputeJusticeTxOuts(
+		amt, weightEstimate.Weight(), t.sweepPkScript,
+		session.RewardPkScript,
+	)
+}
+
 // craftSessionPayload is the final stage for a backupTask, and generates the
 // encrypted payload and breach hint that should be sent to the tower. This
 // method computes the final justice transaction using the bound
@@ -240,7 +409,12 @@ func (t *backupTask) craftSessionPayload(
 
 	var hint blob.BreachHint
 
-	justiceKit, err := t.commitmentType.NewJusticeKit(
+	newJusticeKit := t.commitmentType.NewJusticeKit
+	if t.blobType.HasHtlcOutputs() {
+		newJusticeKit = t.commitmentType.NewHtlcJusticeKit
+	}
+
+	justiceKit, err := newJusticeKit(
 		t.sweepPkScript, t.breachInfo, t.toRemoteInput != nil,
 	)
 	if err != nil {
@@ -337,6 +511,36 @@ func (t *backupTask) craftSessionPayload(
 		}
 	}
 
+	// Sign the justice transactions of the revoked HTLC outputs, if any,
+	// and add them to the justice kit.
+	for _, htlc := range t.htlcs {
+		signature, err := t.signHtlcJusticeTxn(
+			signer, htlc.input, htlc.outputs,
+		)
+		if err != nil {
+			return hint, nil, err
+		}
+
+		secondLevelSig := fn.None[lnwire.Sig]()
+		if htlc.secondLevelInput != nil {
+			sig, err := t.signHtlcJusticeTxn(
+				signer, htlc.secondLevelInput,
+				htlc.secondLevelOutputs,
+			)
+			if err != nil {
+				return hint, nil, err
+			}
+			secondLevelSig = fn.Some(sig)
+		}
+
+		err = justiceKit.AddHtlcOutput(
+			htlc.retribution, signature, secondLevelSig,
+		)
+		if err != nil {
+			return hint, nil, err
+		}
+	}
+
 	breachTxID := t.breachInfo.BreachTxHash
 
 	// Compute the breach key as SHA256(txid).
@@ -352,3 +556,42 @@ func (t *backupTask) craftSessionPayload(
 
 	return hint, encBlob, nil
 }
+
+// signHtlcJusticeTxn constructs the justice transaction sweeping the given
+// revoked HTLC or second level output to the given outputs, and returns the
+// signature spending it.
+func (t *backupTask) signHtlcJusticeTxn(signer input.Signer, inp input.Input,
+	outputs []*wire.TxOut) (lnwire.Sig, error) {
+
+	prevOutPoint := inp.OutPoint()
+
+	justiceTxn := wire.NewMsgTx(2)
+	justiceTxn.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: prevOutPoint,
+		Sequence:         inp.BlocksToMaturity(),
+	})
+	justiceTxn.TxOut = outputs
+
+	// Sort the justice transaction according to BIP69, which only affects
+	// the outputs since there is a single input.
+	txsort.InPlaceSort(justiceTxn)
+
+	btx := btcutil.NewTx(justiceTxn)
+	if err := blockchain.CheckTransactionSanity(btx); err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	prevOutputFetcher := txscript.NewCannedPrevOutputFetcher(
+		inp.SignDesc().Output.PkScript, inp.SignDesc().Output.Value,
+	)
+	hashCache := txscript.NewTxSigHashes(justiceTxn, prevOutputFetcher)
+
+	inputScript, err := inp.CraftInputScript(
+		signer, justiceTxn, hashCache, prevOutputFetcher, 0,
+	)
+	if err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	return t.commitmentType.ParseRawSig(inputScript.Witness)
+}
@@ -694,3 +694,80 @@ func makeSig(i int) lnwire.Sig {
 
 	return sig
 }
+
+// TestBackupTaskBindHtlcs asserts that binding a backup task to a session that
+// backs up revoked HTLC outputs skips the HTLC outputs that aren't worth
+// sweeping, and keeps only the largest ones if there are too many of them.
+func TestBackupTaskBindHtlcs(t *testing.T) {
+	t.Parallel()
+
+	blobType := blob.TypeAltruistAnchorHtlcCommit
+	commitType, err := blobType.CommitmentType(nil)
+	require.NoError(t, err)
+
+	session := &wtdb.ClientSessionBody{
+		Policy: wtpolicy.Policy{
+			TxPolicy: wtpolicy.TxPolicy{
+				BlobType:     blobType,
+				SweepFeeRate: 1000,
+			},
+		},
+	}
+
+	// Create one more HTLC output than a justice kit can hold, with
+	// increasing values, followed by an HTLC output that is too small to
+	// be swept.
+	breachInfo := &lnwallet.BreachRetribution{}
+	for i := 0; i <= blob.MaxHtlcOutputs; i++ {
+		var htlc lnwallet.HtlcRetribution
+		htlc.OutPoint.Index = uint32(i)
+		htlc.IsIncoming = i%2 == 0
+		htlc.SignDesc.Output = &wire.TxOut{
+			Value: int64(10000 + i),
+		}
+
+		breachInfo.HtlcRetributions = append(
+			breachInfo.HtlcRetributions, htlc,
+		)
+	}
+	dustHtlc := lnwallet.HtlcRetribution{
+		OutPoint: wire.OutPoint{Index: blob.MaxHtlcOutputs + 1},
+		SignDesc: input.SignDescriptor{
+			Output: &wire.TxOut{Value: 500},
+		},
+	}
+	breachInfo.HtlcRetributions = append(
+		breachInfo.HtlcRetributions, dustHtlc,
+	)
+
+	task := newBackupTask(wtdb.BackupID{}, addrScript)
+	task.breachInfo = breachInfo
+	task.commitmentType = commitType
+
+	htlcs, err := task.bindHtlcs(session)
+	require.NoError(t, err)
+	require.Len(t, htlcs, blob.MaxHtlcOutputs)
+
+	for _, htlc := range htlcs {
+		index := htlc.retribution.OutPoint.Index
+
+		// Neither the smallest nor the dust HTLC output should have
+		// been kept.
+		require.NotZero(t, index)
+		require.NotEqual(t, dustHtlc.OutPoint.Index, index)
+
+		expWitnessType, err := commitType.HtlcWitnessType(
+			htlc.retribution.IsIncoming,
+		)
+		require.NoError(t, err)
+		require.Equal(t, expWitnessType, htlc.input.WitnessType())
+
+		// The single sweep output pays back the HTLC value minus the
+		// justice transaction's fee.
+		require.Len(t, htlc.outputs, 1)
+		require.Less(
+			t, htlc.outputs[0].Value,
+			htlc.retribution.SignDesc.Output.Value,
+		)
+	}
+}
@@ -585,7 +585,7 @@ func (m *Manager) Policy(blobType blob.Type) (wtpolicy.Policy, error) {
 	defer m.clientsMu.Unlock()
 
 	var policy wtpolicy.Policy
-	client, ok := m.clients[blobType]
+	client, ok := m.clients[m.resolveBlobType(blobType)]
 	if !ok {
 		return policy, fmt.Errorf("no client for the given blob type")
 	}
@@ -593,6 +593,20 @@ func (m *Manager) Policy(blobType blob.Type) (wtpolicy.Policy, error) {
 	return client.policy(), nil
 }
 
+// resolveBlobType returns the blob type of the client that should be used for
+// the given blob type. A client that also backs up revoked HTLC outputs is
+// preferred, if one is registered.
+//
+// NOTE: The clientsMu lock MUST be held when calling this method.
+func (m *Manager) resolveBlobType(blobType blob.Type) blob.Type {
+	htlcBlobType := blobType | blob.Type(blob.FlagHtlcOutputs)
+	if _, ok := m.clients[htlcBlobType]; ok {
+		return htlcBlobType
+	}
+
+	return blobType
+}
+
 // RegisterChannel persistently initializes any channel-dependent parameters
 // within the client. This should be called during link startup to ensure that
 // the client is able to support the link during operation.
@@ -602,6 +616,7 @@ func (m *Manager) RegisterChannel(id lnwire.ChannelID,
 	blobType := blob.TypeFromChannel(chanType)
 
 	m.clientsMu.Lock()
+	blobType = m.resolveBlobType(blobType)
 	if _, ok := m.clients[blobType]; !ok {
 		m.clientsMu.Unlock()
 
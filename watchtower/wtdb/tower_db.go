@@ -240,20 +240,15 @@ func (t *TowerDB) InsertStateUpdate(update *SessionStateUpdate) (uint16, error)
 			return err
 		}
 
-		commitType, err := session.Policy.BlobType.CommitmentType(nil)
-		if err != nil {
-			return err
-		}
-
-		kit, err := commitType.EmptyJusticeKit()
+		// Assert that the blob is the correct size for the session's
+		// blob type.
+		validSize, err := blob.IsValidSize(
+			session.Policy.BlobType, len(update.EncryptedBlob),
+		)
 		if err != nil {
 			return err
 		}
-
-		// Assert that the blob is the correct size for the session's
-		// blob type.
-		expBlobSize := blob.Size(kit)
-		if len(update.EncryptedBlob) != expBlobSize {
+		if !validSize {
 			return ErrInvalidBlobSize
 		}
 
@@ -37,18 +37,14 @@ func (db *TowerDB) InsertStateUpdate(update *wtdb.SessionStateUpdate) (uint16, e
 		return 0, wtdb.ErrSessionNotFound
 	}
 
-	commitType, err := info.Policy.BlobType.CommitmentType(nil)
-	if err != nil {
-		return 0, err
-	}
-
-	kit, err := commitType.EmptyJusticeKit()
+	// Assert that the blob is the correct size for the session's blob type.
+	validSize, err := blob.IsValidSize(
+		info.Policy.BlobType, len(update.EncryptedBlob),
+	)
 	if err != nil {
 		return 0, err
 	}
-
-	// Assert that the blob is the correct size for the session's blob type.
-	if len(update.EncryptedBlob) != blob.Size(kit) {
+	if !validSize {
 		return 0, wtdb.ErrInvalidBlobSize
 	}
 
@@ -138,6 +138,10 @@ func (p *Policy) FeatureBits() []lnwire.FeatureBit {
 		features = append(features, wtwire.AnchorCommitRequired)
 	}
 
+	if t.HasHtlcOutputs() {
+		features = append(features, wtwire.HtlcOutputsRequired)
+	}
+
 	return features
 }
 
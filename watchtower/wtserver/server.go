@@ -99,6 +99,7 @@ func New(cfg *Config) (*Server, error) {
 		lnwire.NewRawFeatureVector(
 			wtwire.AltruistSessionsOptional,
 			wtwire.AnchorCommitOptional,
+			wtwire.HtlcOutputsOptional,
 		),
 		cfg.ChainHash,
 	)
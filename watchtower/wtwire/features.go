@@ -11,6 +11,8 @@ var FeatureNames = map[lnwire.FeatureBit]string{
 	AnchorCommitOptional:     "anchor-commit",
 	TaprootCommitRequired:    "taproot-commit",
 	TaprootCommitOptional:    "taproot-commit",
+	HtlcOutputsRequired:      "htlc-outputs",
+	HtlcOutputsOptional:      "htlc-outputs",
 }
 
 const (
@@ -41,4 +43,14 @@ const (
 	// TaprootCommitOptional specifies that the advertising tower allows the
 	// remote party to negotiate sessions for protecting taproot channels.
 	TaprootCommitOptional lnwire.FeatureBit = 5
+
+	// HtlcOutputsRequired specifies that the advertising tower requires the
+	// remote party to negotiate sessions whose blobs also sweep the HTLC
+	// outputs of revoked commitments.
+	HtlcOutputsRequired lnwire.FeatureBit = 6
+
+	// HtlcOutputsOptional specifies that the advertising tower allows the
+	// remote party to negotiate sessions whose blobs also sweep the HTLC
+	// outputs of revoked commitments.
+	HtlcOutputsOptional lnwire.FeatureBit = 7
 )